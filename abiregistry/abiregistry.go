@@ -0,0 +1,453 @@
+package abiregistry
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+const (
+	// maxABISize is the maximum size of a registered ABI.
+	maxABISize = 256 * 1024
+
+	// maxContracts is the maximum number of contracts a tenant may register.
+	maxContracts = 1024
+)
+
+var (
+	legacyRegistryKey = []byte("abi-registry")   // All the contracts in one blob, migrated on load
+	registryIndexKey  = []byte("abi-registry-i") // Tenants and addresses of the contracts
+	registryABIPrefix = []byte("abi-registry-c") // registryABIPrefix + address + tenant -> ABI
+
+	errABITooLarge      = errors.New("ABI too large")
+	errTooManyContracts = errors.New("too many contracts registered")
+)
+
+// Registry holds the ABIs of the contracts registered by the tenants of the
+// node, to decode the transactions and logs of the contracts for them. The
+// contracts of a tenant are only ever used for that tenant, those registered
+// without a tenant, by the operator of the node, for the unauthenticated calls.
+type Registry struct {
+	db ethdb.Database
+
+	lock      sync.RWMutex
+	contracts map[string]map[common.Address]*contract // By tenant
+}
+
+// contract is the ABI of a registered contract.
+type contract struct {
+	json   json.RawMessage
+	events map[common.Hash]abi.Event    // Non-anonymous events by ID
+	errors map[[4]byte]*abi.CustomError // Custom errors by selector
+}
+
+// abiEntry is an entry of an ABI, as far as needed to parse the custom errors
+// the abi package doesn't.
+type abiEntry struct {
+	Type   string `json:"type"`
+	Name   string `json:"name"`
+	Inputs []struct {
+		Type string `json:"type"`
+	} `json:"inputs"`
+}
+
+// storedContract is the database encoding of a registered contract, the ABI
+// only being set in the legacy encoding of the registry as a single blob.
+type storedContract struct {
+	Tenant  string          `json:"tenant,omitempty"`
+	Address common.Address  `json:"address"`
+	ABI     json.RawMessage `json:"abi,omitempty"`
+}
+
+// abiKey returns the database key of the ABI of a contract registered by a
+// tenant.
+func abiKey(tenant string, address common.Address) []byte {
+	key := append(append([]byte{}, registryABIPrefix...), address[:]...)
+	return append(key, tenant...)
+}
+
+// New creates the registry, loading the contracts registered in the database.
+func New(db ethdb.Database) (*Registry, error) {
+	r := &Registry{
+		db:        db,
+		contracts: make(map[string]map[common.Address]*contract),
+	}
+	if blob, err := db.Get(legacyRegistryKey); err == nil && len(blob) > 0 {
+		if err := r.migrate(blob); err != nil {
+			return nil, err
+		}
+	}
+	blob, err := db.Get(registryIndexKey)
+	if err != nil || len(blob) == 0 {
+		return r, nil
+	}
+	var stored []storedContract
+	if err := json.Unmarshal(blob, &stored); err != nil {
+		return nil, fmt.Errorf("corrupt ABI registry: %v", err)
+	}
+	for _, s := range stored {
+		abiJSON, err := db.Get(abiKey(s.Tenant, s.Address))
+		if err != nil {
+			return nil, fmt.Errorf("missing ABI of %s: %v", s.Address.Hex(), err)
+		}
+		c, err := parse(abiJSON)
+		if err != nil {
+			return nil, fmt.Errorf("corrupt ABI of %s: %v", s.Address.Hex(), err)
+		}
+		r.tenant(s.Tenant)[s.Address] = c
+	}
+	return r, nil
+}
+
+// migrate moves the contracts of a registry stored as a single blob to their
+// own keys.
+func (r *Registry) migrate(blob []byte) error {
+	var stored []storedContract
+	if err := json.Unmarshal(blob, &stored); err != nil {
+		return fmt.Errorf("corrupt ABI registry: %v", err)
+	}
+	batch := r.db.NewBatch()
+	for i, s := range stored {
+		if err := batch.Put(abiKey(s.Tenant, s.Address), s.ABI); err != nil {
+			return err
+		}
+		stored[i].ABI = nil
+	}
+	index, err := json.Marshal(stored)
+	if err != nil {
+		return err
+	}
+	if err := batch.Put(registryIndexKey, index); err != nil {
+		return err
+	}
+	if err := batch.Delete(legacyRegistryKey); err != nil {
+		return err
+	}
+	return batch.Write()
+}
+
+// parse parses the ABI of a contract.
+func parse(abiJSON json.RawMessage) (*contract, error) {
+	parsed, err := abi.JSON(bytes.NewReader(abiJSON))
+	if err != nil {
+		return nil, err
+	}
+	c := &contract{
+		json:   abiJSON,
+		events: make(map[common.Hash]abi.Event),
+		errors: make(map[[4]byte]*abi.CustomError),
+	}
+	for _, ev := range parsed.Events {
+		if !ev.Anonymous {
+			c.events[ev.Id()] = ev
+		}
+	}
+	var entries []abiEntry
+	if err := json.Unmarshal(abiJSON, &entries); err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if entry.Type != "error" {
+			continue
+		}
+		kinds := make([]string, len(entry.Inputs))
+		for i, input := range entry.Inputs {
+			kinds[i] = input.Type
+		}
+		custom, err := abi.NewCustomError(fmt.Sprintf("%s(%s)", entry.Name, strings.Join(kinds, ",")))
+		if err != nil {
+			return nil, err
+		}
+		c.errors[custom.ID] = custom
+	}
+	return c, nil
+}
+
+// tenant returns the contracts of a tenant, creating the set if needed.
+//
+// Note, this method assumes the lock is held!
+func (r *Registry) tenant(tenant string) map[common.Address]*contract {
+	contracts := r.contracts[tenant]
+	if contracts == nil {
+		contracts = make(map[common.Address]*contract)
+		r.contracts[tenant] = contracts
+	}
+	return contracts
+}
+
+// Register registers the ABI of a contract for a tenant, replacing the one
+// registered before if any.
+func (r *Registry) Register(tenant string, address common.Address, abiJSON json.RawMessage) error {
+	if len(abiJSON) > maxABISize {
+		return errABITooLarge
+	}
+	c, err := parse(abiJSON)
+	if err != nil {
+		return fmt.Errorf("invalid ABI: %v", err)
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	contracts := r.tenant(tenant)
+	_, exists := contracts[address]
+	if !exists && len(contracts) >= maxContracts {
+		return errTooManyContracts
+	}
+	// Replacing an ABI only rewrites it, the index only changing with the
+	// contracts registered
+	if exists {
+		if err := r.db.Put(abiKey(tenant, address), abiJSON); err != nil {
+			return err
+		}
+		contracts[address] = c
+		return nil
+	}
+	contracts[address] = c
+	batch := r.db.NewBatch()
+	if err := batch.Put(abiKey(tenant, address), abiJSON); err != nil {
+		return err
+	}
+	if err := r.storeIndex(batch); err != nil {
+		delete(contracts, address)
+		return err
+	}
+	if err := batch.Write(); err != nil {
+		delete(contracts, address)
+		return err
+	}
+	return nil
+}
+
+// Unregister removes the ABI of a contract registered by a tenant, reporting
+// whether there was one.
+func (r *Registry) Unregister(tenant string, address common.Address) (bool, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	c, ok := r.contracts[tenant][address]
+	if !ok {
+		return false, nil
+	}
+	delete(r.contracts[tenant], address)
+
+	batch := r.db.NewBatch()
+	if err := batch.Delete(abiKey(tenant, address)); err != nil {
+		r.contracts[tenant][address] = c
+		return false, err
+	}
+	if err := r.storeIndex(batch); err != nil {
+		r.contracts[tenant][address] = c
+		return false, err
+	}
+	if err := batch.Write(); err != nil {
+		r.contracts[tenant][address] = c
+		return false, err
+	}
+	return true, nil
+}
+
+// Contracts returns the ABIs of the contracts registered by a tenant, by
+// address.
+func (r *Registry) Contracts(tenant string) map[common.Address]json.RawMessage {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	abis := make(map[common.Address]json.RawMessage)
+	for address, c := range r.contracts[tenant] {
+		abis[address] = c.json
+	}
+	return abis
+}
+
+// storeIndex adds the index of the registered contracts to a batch.
+//
+// Note, this method assumes the lock is held!
+func (r *Registry) storeIndex(batch ethdb.Batch) error {
+	stored := []storedContract{}
+	for tenant, contracts := range r.contracts {
+		for address := range contracts {
+			stored = append(stored, storedContract{Tenant: tenant, Address: address})
+		}
+	}
+	sort.Slice(stored, func(i, j int) bool {
+		if stored[i].Tenant != stored[j].Tenant {
+			return stored[i].Tenant < stored[j].Tenant
+		}
+		return bytes.Compare(stored[i].Address[:], stored[j].Address[:]) < 0
+	})
+	blob, err := json.Marshal(stored)
+	if err != nil {
+		return err
+	}
+	return batch.Put(registryIndexKey, blob)
+}
+
+// lookup returns the contract registered at an address by a tenant.
+func (r *Registry) lookup(tenant string, address common.Address) *contract {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	return r.contracts[tenant][address]
+}
+
+// UnpackRevert decodes the data returned by a reverted call to a contract for a
+// tenant, as a reason given to require or revert, a panic code, or one of the
+// custom errors of the contracts the tenant registered. Those of the contract
+// called take precedence, as the errors of its callees may share selectors.
+func (r *Registry) UnpackRevert(tenant string, to *common.Address, data []byte) (*abi.Revert, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	custom := make(map[[4]byte]*abi.CustomError)
+	for _, c := range r.contracts[tenant] {
+		for id, e := range c.errors {
+			custom[id] = e
+		}
+	}
+	if to != nil {
+		if c := r.contracts[tenant][*to]; c != nil {
+			for id, e := range c.errors {
+				custom[id] = e
+			}
+		}
+	}
+	return abi.UnpackRevert(data, custom)
+}
+
+// DecodedLog is a log with its event and arguments decoded, if the ABI of the
+// contract emitting it is registered.
+type DecodedLog struct {
+	*types.Log
+	Event string                 // Name of the event, empty if unknown
+	Args  map[string]interface{} // Arguments of the event by name
+}
+
+// MarshalJSON encodes the log with the additional event and args fields.
+func (l *DecodedLog) MarshalJSON() ([]byte, error) {
+	blob, err := json.Marshal(l.Log)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(blob, &fields); err != nil {
+		return nil, err
+	}
+	if l.Event != "" {
+		if fields["event"], err = json.Marshal(l.Event); err != nil {
+			return nil, err
+		}
+		if fields["args"], err = json.Marshal(l.Args); err != nil {
+			return nil, err
+		}
+	}
+	return json.Marshal(fields)
+}
+
+// DecodeLog decodes a log for a tenant. Logs of unregistered contracts or
+// events, or failing to decode, are returned undecoded.
+func (r *Registry) DecodeLog(tenant string, log *types.Log) *DecodedLog {
+	decoded := &DecodedLog{Log: log}
+	if len(log.Topics) == 0 {
+		return decoded
+	}
+	c := r.lookup(tenant, log.Address)
+	if c == nil {
+		return decoded
+	}
+	ev, ok := c.events[log.Topics[0]]
+	if !ok {
+		return decoded
+	}
+	args := make(map[string]interface{})
+	values, err := ev.Inputs.UnpackValues(log.Data)
+	if err != nil {
+		return decoded
+	}
+	topic := 1
+	for _, input := range ev.Inputs {
+		if input.Indexed {
+			if topic >= len(log.Topics) {
+				return decoded
+			}
+			args[input.Name] = decodeTopic(input.Type, log.Topics[topic])
+			topic++
+			continue
+		}
+		args[input.Name] = readable(reflect.ValueOf(values[0]))
+		values = values[1:]
+	}
+	decoded.Event, decoded.Args = ev.Name, args
+	return decoded
+}
+
+// decodeTopic decodes an indexed argument from its topic. The arguments of the
+// dynamic types are only known by their hash.
+func decodeTopic(typ abi.Type, topic common.Hash) interface{} {
+	switch typ.T {
+	case abi.UintTy:
+		return new(big.Int).SetBytes(topic[:]).String()
+	case abi.IntTy:
+		return math.S256(new(big.Int).SetBytes(topic[:])).String()
+	case abi.BoolTy:
+		return topic[common.HashLength-1] != 0
+	case abi.AddressTy:
+		return common.BytesToAddress(topic[:])
+	case abi.FixedBytesTy:
+		return hexutil.Bytes(topic[:typ.Size])
+	default:
+		return topic
+	}
+}
+
+// readable converts an unpacked value for the JSON encoding, the numbers as
+// decimal strings and the byte arrays in hex.
+func readable(v reflect.Value) interface{} {
+	switch value := v.Interface().(type) {
+	case *big.Int:
+		return value.String()
+	case common.Address:
+		return value
+	case []byte:
+		return hexutil.Bytes(value)
+	}
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return fmt.Sprint(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return fmt.Sprint(v.Uint())
+	case reflect.Array, reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			blob := make([]byte, v.Len())
+			reflect.Copy(reflect.ValueOf(blob), v)
+			return hexutil.Bytes(blob)
+		}
+		items := make([]interface{}, v.Len())
+		for i := range items {
+			items[i] = readable(v.Index(i))
+		}
+		return items
+	}
+	return v.Interface()
+}
+
+// DecodeLogs decodes the logs of a receipt for a tenant.
+func (r *Registry) DecodeLogs(tenant string, logs []*types.Log) []*DecodedLog {
+	decoded := make([]*DecodedLog, len(logs))
+	for i, log := range logs {
+		decoded[i] = r.DecodeLog(tenant, log)
+	}
+	return decoded
+}
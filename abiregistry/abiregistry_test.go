@@ -0,0 +1,186 @@
+package abiregistry
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+const tokenABI = `[
+	{"type":"event","name":"Transfer","inputs":[{"name":"from","type":"address","indexed":true},{"name":"to","type":"address","indexed":true},{"name":"value","type":"uint256","indexed":false}]},
+	{"type":"event","name":"Note","inputs":[{"name":"delta","type":"int32","indexed":true},{"name":"memo","type":"bytes","indexed":false},{"name":"tags","type":"bytes4[2]","indexed":false}]}
+]`
+
+func TestDecodeLogs(t *testing.T) {
+	var (
+		db     = ethdb.NewMemDatabase()
+		token  = common.HexToAddress("0x1000000000000000000000000000000000000001")
+		shared = common.HexToAddress("0x1000000000000000000000000000000000000002")
+		alice  = common.HexToAddress("0xa11ce00000000000000000000000000000000000")
+		bob    = common.HexToAddress("0xb0b0000000000000000000000000000000000000")
+	)
+	registry, _ := New(db)
+	if err := registry.Register("acme", token, json.RawMessage(tokenABI)); err != nil {
+		t.Fatalf("failed to register: %v", err)
+	}
+	if err := registry.Register("", shared, json.RawMessage(tokenABI)); err != nil {
+		t.Fatalf("failed to register shared contract: %v", err)
+	}
+	if err := registry.Register("acme", token, json.RawMessage(`[{"type":"event"`)); err == nil {
+		t.Errorf("invalid ABI registered")
+	}
+	transfer := func(address common.Address) *types.Log {
+		return &types.Log{
+			Address: address,
+			Topics:  []common.Hash{crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)")), alice.Hash(), bob.Hash()},
+			Data:    common.BigToHash(big.NewInt(1000)).Bytes(),
+		}
+	}
+	// The contracts of a tenant are only decoded for the tenant
+	decoded := registry.DecodeLog("acme", transfer(token))
+	if decoded.Event != "Transfer" || decoded.Args["from"] != alice || decoded.Args["to"] != bob || decoded.Args["value"] != "1000" {
+		t.Errorf("transfer decoding mismatch: have %s %v", decoded.Event, decoded.Args)
+	}
+	if decoded := registry.DecodeLog("other", transfer(token)); decoded.Event != "" {
+		t.Errorf("contract of another tenant decoded")
+	}
+	if decoded := registry.DecodeLog("other", transfer(shared)); decoded.Event != "" {
+		t.Errorf("contract of the operator decoded for a tenant")
+	}
+	if decoded := registry.DecodeLog("", transfer(shared)); decoded.Event != "Transfer" {
+		t.Errorf("contract of the operator not decoded")
+	}
+	// Signed, dynamic and array arguments are decoded readably
+	data := append(common.BigToHash(big.NewInt(96)).Bytes(), common.RightPadBytes([]byte{0xca, 0xfe}, 32)...)
+	data = append(data, common.RightPadBytes([]byte{0xbe, 0xef}, 32)...)
+	data = append(data, common.BigToHash(big.NewInt(1)).Bytes()...)
+	data = append(data, common.RightPadBytes([]byte{0x42}, 32)...)
+	note := &types.Log{
+		Address: token,
+		Topics:  []common.Hash{crypto.Keccak256Hash([]byte("Note(int32,bytes,bytes4[2])")), common.BigToHash(math.U256(big.NewInt(-3)))},
+		Data:    data,
+	}
+	blob, err := json.Marshal(registry.DecodeLog("acme", note))
+	if err != nil {
+		t.Fatalf("failed to encode decoded log: %v", err)
+	}
+	var fields map[string]interface{}
+	json.Unmarshal(blob, &fields)
+	if fields["event"] != "Note" || fields["address"] != "0x1000000000000000000000000000000000000001" {
+		t.Errorf("decoded log fields mismatch: have %s", blob)
+	}
+	args, _ := fields["args"].(map[string]interface{})
+	if args["delta"] != "-3" || args["memo"] != "0x42" {
+		t.Errorf("decoded arguments mismatch: have %s", blob)
+	}
+	if tags, _ := args["tags"].([]interface{}); len(tags) != 2 || tags[0] != "0xcafe0000" || tags[1] != "0xbeef0000" {
+		t.Errorf("decoded array mismatch: have %s", blob)
+	}
+	// The registrations persist, until unregistered
+	registry, err = New(db)
+	if err != nil {
+		t.Fatalf("failed to reload registry: %v", err)
+	}
+	if abis := registry.Contracts("acme"); len(abis) != 1 || abis[token] == nil {
+		t.Errorf("registered contracts mismatch: have %v", abis)
+	}
+	if removed, err := registry.Unregister("acme", token); !removed || err != nil {
+		t.Errorf("failed to unregister: %v %v", removed, err)
+	}
+	if decoded := registry.DecodeLog("acme", transfer(token)); decoded.Event != "" {
+		t.Errorf("unregistered contract decoded")
+	}
+	if registry, err = New(db); err != nil {
+		t.Fatalf("failed to reload registry: %v", err)
+	}
+	if abis := registry.Contracts("acme"); len(abis) != 0 {
+		t.Errorf("unregistered contract reloaded: %v", abis)
+	}
+	if abis := registry.Contracts(""); len(abis) != 1 || abis[shared] == nil {
+		t.Errorf("contracts of the operator mismatch: have %v", abis)
+	}
+}
+
+func TestUnpackRevert(t *testing.T) {
+	var (
+		db    = ethdb.NewMemDatabase()
+		vault = common.HexToAddress("0x1000000000000000000000000000000000000001")
+	)
+	registry, _ := New(db)
+	vaultABI := `[{"type":"error","name":"InsufficientBalance","inputs":[{"name":"available","type":"uint256"},{"name":"required","type":"uint256"}]}]`
+	if err := registry.Register("acme", vault, json.RawMessage(vaultABI)); err != nil {
+		t.Fatalf("failed to register: %v", err)
+	}
+	data := append(crypto.Keccak256([]byte("InsufficientBalance(uint256,uint256)"))[:4], common.BigToHash(big.NewInt(1)).Bytes()...)
+	data = append(data, common.BigToHash(big.NewInt(2)).Bytes()...)
+
+	// The custom errors of a tenant are only decoded for the tenant
+	revert, err := registry.UnpackRevert("acme", &vault, data)
+	if err != nil {
+		t.Fatalf("failed to decode custom error: %v", err)
+	}
+	if revert.Signature != "InsufficientBalance(uint256,uint256)" || revert.Reason != "InsufficientBalance(1, 2)" {
+		t.Errorf("custom error mismatch: have %s %s", revert.Signature, revert.Reason)
+	}
+	if _, err := registry.UnpackRevert("other", &vault, data); err == nil {
+		t.Errorf("custom error of another tenant decoded")
+	}
+	// The standard errors are decoded for all
+	reason := append(crypto.Keccak256([]byte("Error(string)"))[:4], common.BigToHash(big.NewInt(32)).Bytes()...)
+	reason = append(reason, common.BigToHash(big.NewInt(2)).Bytes()...)
+	reason = append(reason, common.RightPadBytes([]byte("no"), 32)...)
+	if revert, err := registry.UnpackRevert("other", nil, reason); err != nil || revert.Reason != "no" {
+		t.Errorf("revert reason mismatch: have %v, %v", revert, err)
+	}
+}
+
+func TestRegistryMigration(t *testing.T) {
+	var (
+		db    = ethdb.NewMemDatabase()
+		token = common.HexToAddress("0x1000000000000000000000000000000000000001")
+	)
+	legacy, _ := json.Marshal([]storedContract{{Tenant: "acme", Address: token, ABI: json.RawMessage(tokenABI)}})
+	db.Put(legacyRegistryKey, legacy)
+
+	registry, err := New(db)
+	if err != nil {
+		t.Fatalf("failed to load legacy registry: %v", err)
+	}
+	if abis := registry.Contracts("acme"); len(abis) != 1 || abis[token] == nil {
+		t.Errorf("migrated contracts mismatch: have %v", abis)
+	}
+	if has, _ := db.Has(legacyRegistryKey); has {
+		t.Errorf("legacy registry left behind")
+	}
+	if blob, _ := db.Get(abiKey("acme", token)); len(blob) == 0 {
+		t.Errorf("ABI not stored under its own key: %s", blob)
+	}
+}
+
+func TestRegistrationAuthentication(t *testing.T) {
+	var (
+		registry, _ = New(ethdb.NewMemDatabase())
+		token       = common.HexToAddress("0x1000000000000000000000000000000000000001")
+	)
+	// Unauthenticated callers can't register through the public API, only the
+	// operator through the private one
+	if _, err := NewPublicABIRegistryAPI(registry).RegisterABI(context.Background(), token, json.RawMessage(tokenABI)); err != errUnauthenticated {
+		t.Errorf("unauthenticated registration error mismatch: have %v, want %v", err, errUnauthenticated)
+	}
+	if _, err := NewPublicABIRegistryAPI(registry).UnregisterABI(context.Background(), token); err != errUnauthenticated {
+		t.Errorf("unauthenticated removal error mismatch: have %v, want %v", err, errUnauthenticated)
+	}
+	if ok, err := NewPrivateABIRegistryAPI(registry).RegisterABI(token, json.RawMessage(tokenABI)); !ok || err != nil {
+		t.Fatalf("operator registration failed: %v", err)
+	}
+	if abis := NewPublicABIRegistryAPI(registry).RegisteredABIs(context.Background()); len(abis) != 1 {
+		t.Errorf("contracts of the operator mismatch: have %v", abis)
+	}
+}
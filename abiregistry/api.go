@@ -0,0 +1,80 @@
+package abiregistry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// errUnauthenticated is returned when an unauthenticated caller tries to change
+// the contracts of a tenant through the public API.
+var errUnauthenticated = errors.New("ABI registration requires an authenticated tenant")
+
+// PublicABIRegistryAPI offers the registration of contract ABIs, for the node
+// to decode the receipts and logs of the contracts. The tenant calling is the
+// subject of the token authenticating the connection, the unauthenticated
+// callers only reading the contracts registered by the operator.
+type PublicABIRegistryAPI struct {
+	registry *Registry
+}
+
+// NewPublicABIRegistryAPI creates the API of an ABI registry.
+func NewPublicABIRegistryAPI(registry *Registry) *PublicABIRegistryAPI {
+	return &PublicABIRegistryAPI{registry: registry}
+}
+
+// RegisterABI registers the ABI of a contract for the calling tenant.
+func (api *PublicABIRegistryAPI) RegisterABI(ctx context.Context, address common.Address, abi json.RawMessage) (bool, error) {
+	tenant := rpc.TenantFromContext(ctx)
+	if tenant == "" {
+		return false, errUnauthenticated
+	}
+	if err := api.registry.Register(tenant, address, abi); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// UnregisterABI removes the ABI of a contract registered by the calling tenant,
+// reporting whether there was one.
+func (api *PublicABIRegistryAPI) UnregisterABI(ctx context.Context, address common.Address) (bool, error) {
+	tenant := rpc.TenantFromContext(ctx)
+	if tenant == "" {
+		return false, errUnauthenticated
+	}
+	return api.registry.Unregister(tenant, address)
+}
+
+// RegisteredABIs returns the ABIs registered by the calling tenant, by contract
+// address.
+func (api *PublicABIRegistryAPI) RegisteredABIs(ctx context.Context) map[common.Address]json.RawMessage {
+	return api.registry.Contracts(rpc.TenantFromContext(ctx))
+}
+
+// PrivateABIRegistryAPI offers the operator of the node the registration of the
+// contract ABIs used for the unauthenticated callers.
+type PrivateABIRegistryAPI struct {
+	registry *Registry
+}
+
+// NewPrivateABIRegistryAPI creates the operator API of an ABI registry.
+func NewPrivateABIRegistryAPI(registry *Registry) *PrivateABIRegistryAPI {
+	return &PrivateABIRegistryAPI{registry: registry}
+}
+
+// RegisterABI registers the ABI of a contract for the unauthenticated callers.
+func (api *PrivateABIRegistryAPI) RegisterABI(address common.Address, abi json.RawMessage) (bool, error) {
+	if err := api.registry.Register("", address, abi); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// UnregisterABI removes the ABI of a contract registered for the unauthenticated
+// callers, reporting whether there was one.
+func (api *PrivateABIRegistryAPI) UnregisterABI(address common.Address) (bool, error) {
+	return api.registry.Unregister("", address)
+}
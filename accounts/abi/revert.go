@@ -0,0 +1,168 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package abi
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+var (
+	// revertErrorSelector is the selector of Error(string), the encoding of the
+	// reasons given to require and revert.
+	revertErrorSelector = crypto.Keccak256([]byte("Error(string)"))[:4]
+
+	// revertPanicSelector is the selector of Panic(uint256), the encoding of the
+	// failed assertions and runtime errors of Solidity 0.8.
+	revertPanicSelector = crypto.Keccak256([]byte("Panic(uint256)"))[:4]
+
+	errNoRevertData = errors.New("abi: no revert data")
+)
+
+// panicReasons are the meanings of the Solidity panic codes.
+var panicReasons = map[uint64]string{
+	0x00: "generic panic",
+	0x01: "assert(false)",
+	0x11: "arithmetic underflow or overflow",
+	0x12: "division or modulo by zero",
+	0x21: "enum overflow",
+	0x22: "invalid encoded storage byte array accessed",
+	0x31: "out-of-bounds array access; popping on an empty array",
+	0x32: "out-of-bounds access of an array or bytesN",
+	0x41: "out of memory",
+	0x51: "uninitialized function",
+}
+
+// CustomError is a custom error a contract may revert with, declared by its
+// signature, such as InsufficientBalance(uint256,uint256).
+type CustomError struct {
+	Name   string
+	Inputs Arguments
+	ID     [4]byte // Selector of the error, the first 4 bytes of the hash of its signature
+}
+
+// NewCustomError parses the signature of a custom error. The types of the
+// arguments are given without names, and tuples aren't supported.
+func NewCustomError(signature string) (*CustomError, error) {
+	signature = strings.Replace(signature, " ", "", -1)
+	open := strings.Index(signature, "(")
+	if open <= 0 || !strings.HasSuffix(signature, ")") {
+		return nil, fmt.Errorf("abi: invalid error signature %q", signature)
+	}
+	custom := &CustomError{Name: signature[:open]}
+	if args := signature[open+1 : len(signature)-1]; args != "" {
+		for i, kind := range strings.Split(args, ",") {
+			typ, err := NewType(kind)
+			if err != nil {
+				return nil, fmt.Errorf("abi: invalid type of argument %d of %q: %v", i, signature, err)
+			}
+			custom.Inputs = append(custom.Inputs, Argument{Name: fmt.Sprintf("arg%d", i), Type: typ})
+		}
+	}
+	copy(custom.ID[:], crypto.Keccak256([]byte(custom.Sig())))
+	return custom, nil
+}
+
+// Sig returns the canonical signature of the error.
+func (e *CustomError) Sig() string {
+	types := make([]string, len(e.Inputs))
+	for i, input := range e.Inputs {
+		types[i] = input.Type.String()
+	}
+	return fmt.Sprintf("%s(%s)", e.Name, strings.Join(types, ","))
+}
+
+// Revert is the decoded data returned by a reverted execution.
+type Revert struct {
+	Signature string        // Error(string), Panic(uint256) or the signature of the custom error
+	Args      []interface{} // Arguments of the error
+	Reason    string        // Human readable reason
+}
+
+// UnpackRevert decodes the data returned by a reverted execution, as a reason
+// given to require or revert, a panic code, or one of the custom errors given
+// by selector.
+func UnpackRevert(data []byte, custom map[[4]byte]*CustomError) (*Revert, error) {
+	if len(data) == 0 {
+		return nil, errNoRevertData
+	}
+	if len(data) < 4 {
+		return nil, fmt.Errorf("abi: revert data too short: %d bytes", len(data))
+	}
+	switch {
+	case bytes.Equal(data[:4], revertErrorSelector):
+		str, _ := NewType("string")
+		values, err := Arguments{{Type: str}}.UnpackValues(data[4:])
+		if err != nil {
+			return nil, err
+		}
+		reason := values[0].(string)
+		return &Revert{Signature: "Error(string)", Args: values, Reason: reason}, nil
+
+	case bytes.Equal(data[:4], revertPanicSelector):
+		uint256, _ := NewType("uint256")
+		values, err := Arguments{{Type: uint256}}.UnpackValues(data[4:])
+		if err != nil {
+			return nil, err
+		}
+		code := values[0].(*big.Int)
+		reason, ok := panicReasons[code.Uint64()]
+		if !ok || !code.IsUint64() {
+			reason = "unknown panic code"
+		}
+		return &Revert{Signature: "Panic(uint256)", Args: values, Reason: fmt.Sprintf("%s (0x%x)", reason, code)}, nil
+	}
+	var id [4]byte
+	copy(id[:], data[:4])
+	if e, ok := custom[id]; ok {
+		values, err := e.Inputs.UnpackValues(data[4:])
+		if err != nil {
+			return nil, err
+		}
+		args := make([]string, len(values))
+		for i, value := range values {
+			args[i] = formatRevertArg(value)
+		}
+		return &Revert{Signature: e.Sig(), Args: values, Reason: fmt.Sprintf("%s(%s)", e.Name, strings.Join(args, ", "))}, nil
+	}
+	return nil, fmt.Errorf("abi: unknown revert selector %x", id)
+}
+
+// formatRevertArg formats an argument of a custom error for its reason, the
+// addresses and bytes in hex.
+func formatRevertArg(value interface{}) string {
+	switch value := value.(type) {
+	case common.Address:
+		return value.Hex()
+	case []byte:
+		return hexutil.Encode(value)
+	}
+	if v := reflect.ValueOf(value); v.Kind() == reflect.Array && v.Type().Elem().Kind() == reflect.Uint8 {
+		blob := make([]byte, v.Len())
+		reflect.Copy(reflect.ValueOf(blob), v)
+		return hexutil.Encode(blob)
+	}
+	return fmt.Sprint(value)
+}
@@ -0,0 +1,77 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package abi
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+func TestUnpackRevert(t *testing.T) {
+	custom, err := NewCustomError("InsufficientBalance(uint256, address)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sig := custom.Sig(); sig != "InsufficientBalance(uint256,address)" {
+		t.Fatalf("signature mismatch: have %s", sig)
+	}
+	customData, err := custom.Inputs.Pack(big.NewInt(5), common.HexToAddress("0x01"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	customData = append(custom.ID[:], customData...)
+	registered := map[[4]byte]*CustomError{custom.ID: custom}
+
+	tests := []struct {
+		data      []byte
+		signature string
+		reason    string
+		fails     bool
+	}{
+		// require(false, "Not enough Ether provided.")
+		{hexutil.MustDecode("0x08c379a00000000000000000000000000000000000000000000000000000000000000020000000000000000000000000000000000000000000000000000000000000001a4e6f7420656e6f7567682045746865722070726f76696465642e000000000000"), "Error(string)", "Not enough Ether provided.", false},
+		// assert(false) in Solidity 0.8
+		{hexutil.MustDecode("0x4e487b710000000000000000000000000000000000000000000000000000000000000001"), "Panic(uint256)", "assert(false) (0x1)", false},
+		{hexutil.MustDecode("0x4e487b710000000000000000000000000000000000000000000000000000000000000099"), "Panic(uint256)", "unknown panic code (0x99)", false},
+		{customData, "InsufficientBalance(uint256,address)", "InsufficientBalance(5, 0x0000000000000000000000000000000000000001)", false},
+		{hexutil.MustDecode("0xdeadbeef"), "", "", true},
+		{hexutil.MustDecode("0x08c379a0"), "", "", true},
+		{nil, "", "", true},
+	}
+	for i, tt := range tests {
+		revert, err := UnpackRevert(tt.data, registered)
+		if tt.fails {
+			if err == nil {
+				t.Errorf("test %d: undecodable data decoded: %+v", i, revert)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("test %d: failed to decode: %v", i, err)
+			continue
+		}
+		if revert.Signature != tt.signature || revert.Reason != tt.reason {
+			t.Errorf("test %d: revert mismatch: have %s %q, want %s %q", i, revert.Signature, revert.Reason, tt.signature, tt.reason)
+		}
+	}
+	if _, err := NewCustomError("Broken(uint256"); err == nil {
+		t.Errorf("invalid signature accepted")
+	}
+}
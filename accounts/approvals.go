@@ -0,0 +1,453 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package accounts
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/adminauth"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// Actions on an account which may require approvals.
+const (
+	ApprovalUnlock = "unlock" // Unlocking the account
+	ApprovalSend   = "send"   // Sending or signing a transaction from the account
+)
+
+// Statuses of an approval.
+const (
+	ApprovalPending  = "pending"  // Collecting the approvals
+	ApprovalExecuted = "executed" // Approved, the action succeeded
+	ApprovalFailed   = "failed"   // Approved, the action failed
+	ApprovalRejected = "rejected" // Rejected by an approver
+	ApprovalExpired  = "expired"  // Not approved in time
+)
+
+const (
+	// defaultApprovalExpiry is the time the approvals of an action may be
+	// collected in, if the policy doesn't say.
+	defaultApprovalExpiry = 24 * time.Hour
+
+	// approvalRetention is the time the decided approvals are kept for their
+	// requesters to learn the outcome.
+	approvalRetention = 24 * time.Hour
+
+	// maxPendingApprovals is the number of actions awaiting approvals per
+	// account, beyond which the requests are refused.
+	maxPendingApprovals = 64
+)
+
+var (
+	errApprovalUnknown  = errors.New("unknown approval")
+	errApprovalDecided  = errors.New("approval already decided")
+	errApprovalApprover = errors.New("signer is not an approver of the account")
+	errApprovalsFull    = errors.New("too many actions awaiting approvals")
+)
+
+// ApprovalPolicy requires the designated actions on an account to be approved
+// by a number of approvers before they execute, for no single operator to be
+// able to unlock or spend from the account.
+type ApprovalPolicy struct {
+	// Approvers are the addresses whose signatures approve the actions.
+	Approvers []common.Address `json:"approvers"`
+
+	// Threshold is the number of approvers who must approve an action.
+	Threshold int `json:"threshold"`
+
+	// Actions are the actions requiring approvals, "unlock" and "send", empty
+	// for both.
+	Actions []string `json:"actions,omitempty"`
+
+	// Expiry is the time the approvals of an action may be collected in, as a
+	// Go duration, 24h if empty.
+	Expiry string `json:"expiry,omitempty"`
+}
+
+// approvalPolicy is a parsed approval policy.
+type approvalPolicy struct {
+	approvers map[common.Address]bool
+	threshold int
+	actions   map[string]bool
+	expiry    time.Duration
+}
+
+// LoadApprovalPolicies reads the approval policies of the accounts from a JSON
+// file mapping the account addresses to their policy.
+func LoadApprovalPolicies(path string) (map[common.Address]*ApprovalPolicy, error) {
+	blob, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	policies := make(map[common.Address]*ApprovalPolicy)
+	if err := json.Unmarshal(blob, &policies); err != nil {
+		return nil, fmt.Errorf("invalid approval policies %s: %v", path, err)
+	}
+	return policies, nil
+}
+
+// parse validates the policy and converts it to its lookup form.
+func (p *ApprovalPolicy) parse() (*approvalPolicy, error) {
+	parsed := &approvalPolicy{
+		approvers: make(map[common.Address]bool),
+		threshold: p.Threshold,
+		actions:   make(map[string]bool),
+		expiry:    defaultApprovalExpiry,
+	}
+	for _, approver := range p.Approvers {
+		if parsed.approvers[approver] {
+			return nil, fmt.Errorf("duplicate approver %x", approver)
+		}
+		parsed.approvers[approver] = true
+	}
+	if p.Threshold < 1 || p.Threshold > len(p.Approvers) {
+		return nil, fmt.Errorf("threshold %d out of range [1, %d]", p.Threshold, len(p.Approvers))
+	}
+	for _, action := range p.Actions {
+		if action != ApprovalUnlock && action != ApprovalSend {
+			return nil, fmt.Errorf("unknown action %q", action)
+		}
+		parsed.actions[action] = true
+	}
+	if len(parsed.actions) == 0 {
+		parsed.actions[ApprovalUnlock], parsed.actions[ApprovalSend] = true, true
+	}
+	if p.Expiry != "" {
+		expiry, err := time.ParseDuration(p.Expiry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid expiry %q: %v", p.Expiry, err)
+		}
+		if expiry <= 0 {
+			return nil, fmt.Errorf("non-positive expiry %q", p.Expiry)
+		}
+		parsed.expiry = expiry
+	}
+	return parsed, nil
+}
+
+// Approval is an action on an account awaiting, or decided by, the approvals
+// of its approvers.
+type Approval struct {
+	ID        common.Hash      `json:"id"` // Id the approvers sign their decision over
+	Action    string           `json:"action"`
+	Account   common.Address   `json:"account"`
+	Method    string           `json:"method"`              // RPC method requesting the action
+	Params    interface{}      `json:"params"`              // Parameters of the action, secrets excluded
+	Requester string           `json:"requester,omitempty"` // Remote address of the requesting RPC client, empty for IPC
+	Requested time.Time        `json:"requested"`
+	Expires   time.Time        `json:"expires"`
+	Threshold int              `json:"threshold"`
+	Approvers []common.Address `json:"approvers"` // Approvers who approved so far
+	Rejecter  *common.Address  `json:"rejecter,omitempty"`
+	Status    string           `json:"status"`
+	Decided   *time.Time       `json:"decided,omitempty"`
+	Result    interface{}      `json:"result,omitempty"` // Result of the executed action
+	Error     string           `json:"error,omitempty"`  // Error of the failed action
+
+	execute func(context.Context) (interface{}, error)
+}
+
+// The RPC methods the approvers sign their decisions for, over the id of the
+// approval, in the operator signature format of the adminauth package.
+const (
+	ApproveMethod = "admin_approve"
+	RejectMethod  = "admin_rejectApproval"
+)
+
+// approvedKey is the context key marking the execution of an approved action.
+type approvedKey struct{}
+
+// approvals tracks the actions awaiting approvals and the decided ones.
+type approvals struct {
+	policies map[common.Address]*approvalPolicy
+	nonce    uint64
+	list     map[common.Hash]*Approval
+
+	lock sync.Mutex
+}
+
+// RequireApproval checks whether an action on an account requires approvals.
+// If it does, the action is recorded to be executed once approved, and an
+// *ApprovalRequiredError carrying its id is returned. It returns nil for the
+// actions without a policy, and within the execution of an approved action.
+//
+// The params are shown to the approvers, and must leave out the secrets held by
+// the execute function, such as passwords. The function runs with a context of
+// its own rather than the one of the request.
+func (am *Manager) RequireApproval(ctx context.Context, action string, account Account, method, remote string, params interface{}, execute func(context.Context) (interface{}, error)) error {
+	if ctx.Value(approvedKey{}) != nil {
+		return nil
+	}
+	am.approvals.lock.Lock()
+	defer am.approvals.lock.Unlock()
+
+	policy := am.approvals.policies[account.Address]
+	if policy == nil || !policy.actions[action] {
+		return nil
+	}
+	now := time.Now()
+	am.approvals.expire(now)
+
+	pending := 0
+	for _, approval := range am.approvals.list {
+		if approval.Account == account.Address && approval.Status == ApprovalPending {
+			pending++
+		}
+	}
+	if pending >= maxPendingApprovals {
+		return errApprovalsFull
+	}
+	// Derive the id from the request and a nonce, for signatures not to carry
+	// over to another request, even across restarts
+	encoded, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	var seed [16]byte
+	binary.BigEndian.PutUint64(seed[:], uint64(now.UnixNano()))
+	binary.BigEndian.PutUint64(seed[8:], am.approvals.nonce)
+	am.approvals.nonce++
+
+	approval := &Approval{
+		ID:        crypto.Keccak256Hash(seed[:], []byte(action), account.Address[:], []byte(method), encoded),
+		Action:    action,
+		Account:   account.Address,
+		Method:    method,
+		Params:    json.RawMessage(encoded),
+		Requester: remote,
+		Requested: now,
+		Expires:   now.Add(policy.expiry),
+		Threshold: policy.threshold,
+		Approvers: []common.Address{},
+		Status:    ApprovalPending,
+		execute:   execute,
+	}
+	am.approvals.list[approval.ID] = approval
+
+	log.Info("Action awaiting approvals", "id", approval.ID, "action", action, "account", account.Address, "method", method, "threshold", policy.threshold)
+	return &ApprovalRequiredError{ID: approval.ID, Account: account.Address, Action: action, Threshold: policy.threshold}
+}
+
+// SetApprovalPolicies replaces the policies requiring approvals for actions on
+// the accounts. The actions awaiting approvals are kept, against the approvers
+// of the new policies.
+func (am *Manager) SetApprovalPolicies(policies map[common.Address]*ApprovalPolicy) error {
+	parsed := make(map[common.Address]*approvalPolicy, len(policies))
+	for addr, policy := range policies {
+		p, err := policy.parse()
+		if err != nil {
+			return fmt.Errorf("approval policy of %x: %v", addr, err)
+		}
+		parsed[addr] = p
+	}
+	am.approvals.lock.Lock()
+	defer am.approvals.lock.Unlock()
+
+	am.approvals.policies = parsed
+	return nil
+}
+
+// Approvals returns the actions awaiting approvals, along with the decided ones
+// if all is set, by request time.
+func (am *Manager) Approvals(all bool) []*Approval {
+	am.approvals.lock.Lock()
+	defer am.approvals.lock.Unlock()
+
+	am.approvals.expire(time.Now())
+
+	list := make([]*Approval, 0, len(am.approvals.list))
+	for _, approval := range am.approvals.list {
+		if all || approval.Status == ApprovalPending {
+			list = append(list, approval.copy())
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Requested.Before(list[j].Requested) })
+	return list
+}
+
+// Approval returns an action awaiting approvals or decided recently.
+func (am *Manager) Approval(id common.Hash) (*Approval, error) {
+	am.approvals.lock.Lock()
+	defer am.approvals.lock.Unlock()
+
+	am.approvals.expire(time.Now())
+
+	approval, ok := am.approvals.list[id]
+	if !ok {
+		return nil, errApprovalUnknown
+	}
+	return approval.copy(), nil
+}
+
+// Approve records the approval of an action by the approver who signed it,
+// executing the action once approved by the threshold of its approvers. The
+// action is executed before returning, its outcome reported in the approval.
+func (am *Manager) Approve(id common.Hash, sig *adminauth.Signature) (*Approval, error) {
+	am.approvals.lock.Lock()
+	approval, approver, err := am.approvals.decide(id, ApproveMethod, sig)
+	if err != nil {
+		am.approvals.lock.Unlock()
+		return nil, err
+	}
+	execute := approval.approve(approver)
+	report := approval.copy()
+	am.approvals.lock.Unlock()
+
+	if execute == nil {
+		return report, nil
+	}
+	// Run the action out of the lock, for it to go through the account manager
+	result, err := execute(context.WithValue(context.Background(), approvedKey{}, id))
+
+	am.approvals.lock.Lock()
+	defer am.approvals.lock.Unlock()
+
+	if err != nil {
+		approval.Status, approval.Error = ApprovalFailed, err.Error()
+		log.Warn("Approved action failed", "id", id, "action", approval.Action, "account", approval.Account, "err", err)
+	} else {
+		approval.Result = result
+		log.Info("Approved action executed", "id", id, "action", approval.Action, "account", approval.Account)
+	}
+	return approval.copy(), nil
+}
+
+// RejectApproval rejects an action awaiting approvals by the approver who
+// signed the rejection. A single rejection cancels the action.
+func (am *Manager) RejectApproval(id common.Hash, sig *adminauth.Signature) (*Approval, error) {
+	am.approvals.lock.Lock()
+	defer am.approvals.lock.Unlock()
+
+	approval, approver, err := am.approvals.decide(id, RejectMethod, sig)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	approval.Status, approval.Decided, approval.Rejecter = ApprovalRejected, &now, &approver
+	approval.execute = nil
+
+	log.Info("Action rejected", "id", id, "approver", approver, "action", approval.Action, "account", approval.Account)
+	return approval.copy(), nil
+}
+
+// decide looks up an action awaiting approvals and the approver who signed the
+// decision on it. The lock must be held.
+func (a *approvals) decide(id common.Hash, method string, sig *adminauth.Signature) (*Approval, common.Address, error) {
+	now := time.Now()
+	a.expire(now)
+
+	approval, ok := a.list[id]
+	if !ok {
+		return nil, common.Address{}, errApprovalUnknown
+	}
+	if approval.Status != ApprovalPending {
+		return nil, common.Address{}, errApprovalDecided
+	}
+	approver, err := recoverApprover(sig, now, method, id)
+	if err != nil {
+		return nil, common.Address{}, err
+	}
+	if policy := a.policies[approval.Account]; policy == nil || !policy.approvers[approver] {
+		return nil, common.Address{}, errApprovalApprover
+	}
+	return approval, approver, nil
+}
+
+// expire marks the actions not approved in time expired, and drops the ones
+// decided past the retention. The lock must be held.
+func (a *approvals) expire(now time.Time) {
+	for id, approval := range a.list {
+		switch {
+		case approval.Status == ApprovalPending && now.After(approval.Expires):
+			expired := approval.Expires
+			approval.Status, approval.Decided, approval.execute = ApprovalExpired, &expired, nil
+			log.Info("Action approvals expired", "id", id, "action", approval.Action, "account", approval.Account)
+
+		case approval.Decided != nil && now.Sub(*approval.Decided) > approvalRetention:
+			delete(a.list, id)
+		}
+	}
+}
+
+// recoverApprover returns the signer of a decision on an approval, in the
+// operator signature format.
+func recoverApprover(sig *adminauth.Signature, now time.Time, method string, id common.Hash) (common.Address, error) {
+	if sig == nil {
+		return common.Address{}, adminauth.ErrSignatureRequired
+	}
+	signed := time.Unix(int64(sig.Timestamp), 0)
+	if signed.Before(now.Add(-adminauth.MaxClockSkew)) || signed.After(now.Add(adminauth.MaxClockSkew)) {
+		return common.Address{}, adminauth.ErrSignatureExpired
+	}
+	hash, _, err := adminauth.SigningHash(method, uint64(sig.Timestamp), id)
+	if err != nil {
+		return common.Address{}, err
+	}
+	if len(sig.Signature) != 65 {
+		return common.Address{}, fmt.Errorf("invalid approval signature length %d", len(sig.Signature))
+	}
+	raw := common.CopyBytes(sig.Signature)
+	if raw[64] >= 27 {
+		raw[64] -= 27 // Accept the legacy Ethereum V values too
+	}
+	pubkey, err := crypto.SigToPub(hash.Bytes(), raw)
+	if err != nil {
+		return common.Address{}, err
+	}
+	return crypto.PubkeyToAddress(*pubkey), nil
+}
+
+// approve records the approval of an approver, returning the action to execute
+// if the threshold is reached. The action is marked executed already, for it to
+// run once only. The lock must be held.
+func (a *Approval) approve(approver common.Address) func(context.Context) (interface{}, error) {
+	for _, have := range a.Approvers {
+		if have == approver {
+			return nil // Approving twice doesn't count twice
+		}
+	}
+	a.Approvers = append(a.Approvers, approver)
+	log.Info("Action approved", "id", a.ID, "approver", approver, "approvals", len(a.Approvers), "threshold", a.Threshold)
+
+	if len(a.Approvers) < a.Threshold {
+		return nil
+	}
+	now := time.Now()
+	a.Status, a.Decided = ApprovalExecuted, &now
+
+	execute := a.execute
+	a.execute = nil
+	return execute
+}
+
+// copy returns a copy of the approval safe to hand out of the lock.
+func (a *Approval) copy() *Approval {
+	cpy := *a
+	cpy.Approvers = append([]common.Address{}, a.Approvers...)
+	cpy.execute = nil
+	return &cpy
+}
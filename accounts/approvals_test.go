@@ -0,0 +1,132 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package accounts
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/adminauth"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// signDecision signs a decision on an approval as an approver.
+func signDecision(t *testing.T, key *ecdsa.PrivateKey, method string, id common.Hash) *adminauth.Signature {
+	now := uint64(time.Now().Unix())
+	hash, _, err := adminauth.SigningHash(method, now, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, err := crypto.Sign(hash.Bytes(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &adminauth.Signature{Timestamp: hexutil.Uint64(now), Signature: sig}
+}
+
+// Tests that the designated actions execute once approved by the threshold of
+// the approvers, and never if rejected.
+func TestApprovals(t *testing.T) {
+	var (
+		keys      = make([]*ecdsa.PrivateKey, 3)
+		approvers = make([]common.Address, 3)
+		treasury  = Account{Address: common.HexToAddress("0x01")}
+	)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		approvers[i] = crypto.PubkeyToAddress(keys[i].PublicKey)
+	}
+	am := NewManager()
+	defer am.Close()
+
+	if err := am.SetApprovalPolicies(map[common.Address]*ApprovalPolicy{treasury.Address: {Approvers: approvers, Threshold: 4}}); err == nil {
+		t.Fatalf("threshold over the approvers accepted")
+	}
+	if err := am.SetApprovalPolicies(map[common.Address]*ApprovalPolicy{treasury.Address: {Approvers: approvers[:2], Threshold: 2, Actions: []string{ApprovalSend}}}); err != nil {
+		t.Fatal(err)
+	}
+	executed := 0
+	execute := func(ctx context.Context) (interface{}, error) {
+		// The executing action must pass through the approvals
+		if err := am.RequireApproval(ctx, ApprovalSend, treasury, "eth_sendTransaction", "", nil, nil); err != nil {
+			t.Errorf("approved action not let through: %v", err)
+		}
+		executed++
+		return "0xhash", nil
+	}
+	// Actions without a policy go through
+	if err := am.RequireApproval(context.Background(), ApprovalUnlock, treasury, "personal_unlockAccount", "", nil, execute); err != nil {
+		t.Fatalf("action without policy held: %v", err)
+	}
+	err := am.RequireApproval(context.Background(), ApprovalSend, treasury, "eth_sendTransaction", "10.0.0.1:1234", []interface{}{"args"}, execute)
+	required, ok := err.(*ApprovalRequiredError)
+	if !ok {
+		t.Fatalf("action under policy not held: %v", err)
+	}
+	id := required.ID
+	if pending := am.Approvals(false); len(pending) != 1 || pending[0].ID != id || pending[0].Requester != "10.0.0.1:1234" {
+		t.Fatalf("pending approvals mismatch: %+v", pending)
+	}
+	// Signatures of others or over another method don't count
+	if _, err := am.Approve(id, signDecision(t, keys[2], ApproveMethod, id)); err != errApprovalApprover {
+		t.Fatalf("approval by a stranger: have %v, want %v", err, errApprovalApprover)
+	}
+	if _, err := am.Approve(id, signDecision(t, keys[0], RejectMethod, id)); err != errApprovalApprover {
+		t.Fatalf("approval signed as a rejection: have %v, want %v", err, errApprovalApprover)
+	}
+	// Approving twice doesn't reach the threshold
+	for i := 0; i < 2; i++ {
+		approval, err := am.Approve(id, signDecision(t, keys[0], ApproveMethod, id))
+		if err != nil {
+			t.Fatalf("approval failed: %v", err)
+		}
+		if approval.Status != ApprovalPending || len(approval.Approvers) != 1 || executed != 0 {
+			t.Fatalf("approval %d mismatch: %+v, %d executed", i, approval, executed)
+		}
+	}
+	approval, err := am.Approve(id, signDecision(t, keys[1], ApproveMethod, id))
+	if err != nil {
+		t.Fatalf("approval failed: %v", err)
+	}
+	if approval.Status != ApprovalExecuted || approval.Result != "0xhash" || executed != 1 {
+		t.Fatalf("approved action mismatch: %+v, %d executed", approval, executed)
+	}
+	if _, err := am.Approve(id, signDecision(t, keys[1], ApproveMethod, id)); err != errApprovalDecided {
+		t.Fatalf("approval of an executed action: have %v, want %v", err, errApprovalDecided)
+	}
+	if pending := am.Approvals(false); len(pending) != 0 {
+		t.Fatalf("executed action still pending: %+v", pending)
+	}
+	// A single rejection cancels an action
+	err = am.RequireApproval(context.Background(), ApprovalSend, treasury, "eth_sendTransaction", "", nil, execute)
+	id = err.(*ApprovalRequiredError).ID
+
+	am.Approve(id, signDecision(t, keys[0], ApproveMethod, id))
+	if approval, err = am.RejectApproval(id, signDecision(t, keys[1], RejectMethod, id)); err != nil || approval.Status != ApprovalRejected {
+		t.Fatalf("rejection failed: %v, %+v", err, approval)
+	}
+	if _, err := am.Approve(id, signDecision(t, keys[1], ApproveMethod, id)); err != errApprovalDecided || executed != 1 {
+		t.Fatalf("approval of a rejected action: have %v, %d executed", err, executed)
+	}
+	if all := am.Approvals(true); len(all) != 2 {
+		t.Fatalf("decided approvals mismatch: have %d, want 2", len(all))
+	}
+}
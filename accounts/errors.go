@@ -19,6 +19,8 @@ package accounts
 import (
 	"errors"
 	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
 )
 
 // ErrUnknownAccount is returned for any requested operation for which no backend
@@ -66,3 +68,30 @@ func NewAuthNeededError(needed string) error {
 func (err *AuthNeededError) Error() string {
 	return fmt.Sprintf("authentication needed: %s", err.Needed)
 }
+
+// PolicyError is returned for signing requests with an unlocked account denied
+// by the unlock policy of the account.
+type PolicyError struct {
+	Account common.Address
+	Reason  error
+}
+
+// Error implements the standard error interface.
+func (err *PolicyError) Error() string {
+	return fmt.Sprintf("account %x denied by unlock policy: %v", err.Account, err.Reason)
+}
+
+// ApprovalRequiredError is returned for actions on an account requiring the
+// approvals of its approvers, the action being recorded to execute once
+// approved.
+type ApprovalRequiredError struct {
+	ID        common.Hash // Id of the approval the approvers sign
+	Account   common.Address
+	Action    string
+	Threshold int
+}
+
+// Error implements the standard error interface.
+func (err *ApprovalRequiredError) Error() string {
+	return fmt.Sprintf("%s of account %x requires %d approvals, awaiting approval %x", err.Action, err.Account, err.Threshold, err.ID)
+}
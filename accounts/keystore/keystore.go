@@ -21,6 +21,7 @@
 package keystore
 
 import (
+	"bytes"
 	"crypto/ecdsa"
 	crand "crypto/rand"
 	"errors"
@@ -31,6 +32,7 @@ import (
 	"path/filepath"
 	"reflect"
 	"runtime"
+	"sort"
 	"sync"
 	"time"
 
@@ -79,7 +81,17 @@ type unlocked struct {
 // NewKeyStore creates a keystore for the given directory.
 func NewKeyStore(keydir string, scryptN, scryptP int) *KeyStore {
 	keydir, _ = filepath.Abs(keydir)
-	ks := &KeyStore{storage: &keyStorePassphrase{keydir, scryptN, scryptP, false}}
+	ks := &KeyStore{storage: &keyStorePassphrase{keydir, scryptN, scryptP, nil, false}}
+	ks.init(keydir)
+	return ks
+}
+
+// Quorum
+// NewKeyStoreArgon2 creates a keystore for the given directory, encrypting the
+// keys it stores with Argon2id.
+func NewKeyStoreArgon2(keydir string, params Argon2Params) *KeyStore {
+	keydir, _ = filepath.Abs(keydir)
+	ks := &KeyStore{storage: &keyStorePassphrase{keydir, 0, 0, &params, false}}
 	ks.init(keydir)
 	return ks
 }
@@ -376,6 +388,21 @@ func (ks *KeyStore) TimedUnlock(a accounts.Account, passphrase string, timeout t
 	return nil
 }
 
+// Quorum
+//
+// Unlocked returns the addresses of the unlocked accounts, sorted.
+func (ks *KeyStore) Unlocked() []common.Address {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	addrs := make([]common.Address, 0, len(ks.unlocked))
+	for addr := range ks.unlocked {
+		addrs = append(addrs, addr)
+	}
+	sort.Slice(addrs, func(i, j int) bool { return bytes.Compare(addrs[i][:], addrs[j][:]) < 0 })
+	return addrs
+}
+
 // Find resolves the given account into a unique entry in the keystore.
 func (ks *KeyStore) Find(a accounts.Account) (accounts.Account, error) {
 	ks.cache.maybeReload()
@@ -434,13 +461,10 @@ func (ks *KeyStore) Export(a accounts.Account, passphrase, newPassphrase string)
 	if err != nil {
 		return nil, err
 	}
-	var N, P int
 	if store, ok := ks.storage.(*keyStorePassphrase); ok {
-		N, P = store.scryptN, store.scryptP
-	} else {
-		N, P = StandardScryptN, StandardScryptP
+		return store.encryptKey(key, newPassphrase)
 	}
-	return EncryptKey(key, newPassphrase, N, P)
+	return EncryptKey(key, newPassphrase, StandardScryptN, StandardScryptP)
 }
 
 // Import stores the given encrypted JSON key into the key directory.
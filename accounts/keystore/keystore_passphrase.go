@@ -42,6 +42,7 @@ import (
 	"github.com/ethereum/go-ethereum/common/math"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/pborman/uuid"
+	"golang.org/x/crypto/argon2"
 	"golang.org/x/crypto/pbkdf2"
 	"golang.org/x/crypto/scrypt"
 )
@@ -49,6 +50,9 @@ import (
 const (
 	keyHeaderKDF = "scrypt"
 
+	// Quorum
+	keyHeaderKDFArgon2 = "argon2id"
+
 	// StandardScryptN is the N parameter of Scrypt encryption algorithm, using 256MB
 	// memory and taking approximately 1s CPU time on a modern processor.
 	StandardScryptN = 1 << 18
@@ -67,12 +71,47 @@ const (
 
 	scryptR     = 8
 	scryptDKLen = 32
+
+	// Quorum
+	// StandardArgon2Time, StandardArgon2Memory and StandardArgon2Threads are the
+	// parameters of the Argon2id encryption algorithm recommended by RFC 9106 for
+	// memory constrained environments, using 64MB memory.
+	StandardArgon2Time    = 3
+	StandardArgon2Memory  = 64 * 1024
+	StandardArgon2Threads = 4
+
+	argon2DKLen = 32
+
+	// Quorum
+	// MaxArgon2Time and MaxArgon2Memory bound the cost of the Argon2id key
+	// derivation, for a crafted key file not to exhaust the memory or hang the
+	// node when decrypted.
+	MaxArgon2Time   = 64
+	MaxArgon2Memory = 4 * 1024 * 1024 // 4GB, in KiB
 )
 
+// Quorum
+// Argon2Params are the parameters of the Argon2id key derivation, encrypting the
+// keys instead of scrypt when configured.
+type Argon2Params struct {
+	Time    uint32 // Number of passes over the memory
+	Memory  uint32 // Memory used, in KiB
+	Threads uint8  // Degree of parallelism
+}
+
+// validate checks the parameters are set and within the cost bounds.
+func (p Argon2Params) validate() error {
+	if p.Time == 0 || p.Memory == 0 || p.Threads == 0 || p.Time > MaxArgon2Time || p.Memory > MaxArgon2Memory {
+		return fmt.Errorf("invalid Argon2 parameters: time %d, memory %d, threads %d", p.Time, p.Memory, p.Threads)
+	}
+	return nil
+}
+
 type keyStorePassphrase struct {
 	keysDirPath string
 	scryptN     int
 	scryptP     int
+	argon2      *Argon2Params // Quorum: Argon2id parameters, scrypt used if nil
 	// skipKeyFileVerification disables the security-feature which does
 	// reads and decrypts any newly created keyfiles. This should be 'false' in all
 	// cases except tests -- setting this to 'true' is not recommended.
@@ -98,12 +137,20 @@ func (ks keyStorePassphrase) GetKey(addr common.Address, filename, auth string)
 
 // StoreKey generates a key, encrypts with 'auth' and stores in the given directory
 func StoreKey(dir, auth string, scryptN, scryptP int) (common.Address, error) {
-	_, a, err := storeNewKey(&keyStorePassphrase{dir, scryptN, scryptP, false}, rand.Reader, auth)
+	_, a, err := storeNewKey(&keyStorePassphrase{dir, scryptN, scryptP, nil, false}, rand.Reader, auth)
+	return a.Address, err
+}
+
+// Quorum
+// StoreKeyArgon2 generates a key, encrypts with 'auth' using Argon2id and stores
+// in the given directory.
+func StoreKeyArgon2(dir, auth string, params Argon2Params) (common.Address, error) {
+	_, a, err := storeNewKey(&keyStorePassphrase{dir, 0, 0, &params, false}, rand.Reader, auth)
 	return a.Address, err
 }
 
 func (ks keyStorePassphrase) StoreKey(filename string, key *Key, auth string) error {
-	keyjson, err := EncryptKey(key, auth, ks.scryptN, ks.scryptP)
+	keyjson, err := ks.encryptKey(key, auth)
 	if err != nil {
 		return err
 	}
@@ -128,6 +175,14 @@ func (ks keyStorePassphrase) StoreKey(filename string, key *Key, auth string) er
 	return os.Rename(tmpName, filename)
 }
 
+// encryptKey encrypts a key with the configured key derivation.
+func (ks keyStorePassphrase) encryptKey(key *Key, auth string) ([]byte, error) {
+	if ks.argon2 != nil {
+		return EncryptKeyArgon2(key, auth, *ks.argon2)
+	}
+	return EncryptKey(key, auth, ks.scryptN, ks.scryptP)
+}
+
 func (ks keyStorePassphrase) JoinPath(filename string) string {
 	if filepath.IsAbs(filename) {
 		return filename
@@ -146,6 +201,42 @@ func EncryptDataV3(data, auth []byte, scryptN, scryptP int) (CryptoJSON, error)
 	if err != nil {
 		return CryptoJSON{}, err
 	}
+	scryptParamsJSON := make(map[string]interface{}, 5)
+	scryptParamsJSON["n"] = scryptN
+	scryptParamsJSON["r"] = scryptR
+	scryptParamsJSON["p"] = scryptP
+	scryptParamsJSON["dklen"] = scryptDKLen
+	scryptParamsJSON["salt"] = hex.EncodeToString(salt)
+
+	return encryptDataV3(data, derivedKey, keyHeaderKDF, scryptParamsJSON)
+}
+
+// Quorum
+// EncryptDataArgon2 encrypts the data given as 'data' with the password 'auth',
+// deriving the encryption key with Argon2id.
+func EncryptDataArgon2(data, auth []byte, params Argon2Params) (CryptoJSON, error) {
+	if err := params.validate(); err != nil {
+		return CryptoJSON{}, err
+	}
+	salt := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		panic("reading from crypto/rand failed: " + err.Error())
+	}
+	derivedKey := argon2.IDKey(auth, salt, params.Time, params.Memory, params.Threads, argon2DKLen)
+
+	argon2ParamsJSON := make(map[string]interface{}, 5)
+	argon2ParamsJSON["t"] = int(params.Time)
+	argon2ParamsJSON["m"] = int(params.Memory)
+	argon2ParamsJSON["p"] = int(params.Threads)
+	argon2ParamsJSON["dklen"] = argon2DKLen
+	argon2ParamsJSON["salt"] = hex.EncodeToString(salt)
+
+	return encryptDataV3(data, derivedKey, keyHeaderKDFArgon2, argon2ParamsJSON)
+}
+
+// encryptDataV3 encrypts the data with a derived key, recording the parameters
+// of its derivation.
+func encryptDataV3(data, derivedKey []byte, kdf string, kdfParams map[string]interface{}) (CryptoJSON, error) {
 	encryptKey := derivedKey[:16]
 
 	iv := make([]byte, aes.BlockSize) // 16
@@ -158,12 +249,6 @@ func EncryptDataV3(data, auth []byte, scryptN, scryptP int) (CryptoJSON, error)
 	}
 	mac := crypto.Keccak256(derivedKey[16:32], cipherText)
 
-	scryptParamsJSON := make(map[string]interface{}, 5)
-	scryptParamsJSON["n"] = scryptN
-	scryptParamsJSON["r"] = scryptR
-	scryptParamsJSON["p"] = scryptP
-	scryptParamsJSON["dklen"] = scryptDKLen
-	scryptParamsJSON["salt"] = hex.EncodeToString(salt)
 	cipherParamsJSON := cipherparamsJSON{
 		IV: hex.EncodeToString(iv),
 	}
@@ -172,8 +257,8 @@ func EncryptDataV3(data, auth []byte, scryptN, scryptP int) (CryptoJSON, error)
 		Cipher:       "aes-128-ctr",
 		CipherText:   hex.EncodeToString(cipherText),
 		CipherParams: cipherParamsJSON,
-		KDF:          keyHeaderKDF,
-		KDFParams:    scryptParamsJSON,
+		KDF:          kdf,
+		KDFParams:    kdfParams,
 		MAC:          hex.EncodeToString(mac),
 	}
 	return cryptoStruct, nil
@@ -187,6 +272,22 @@ func EncryptKey(key *Key, auth string, scryptN, scryptP int) ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
+	return marshalKeyV3(key, cryptoStruct)
+}
+
+// Quorum
+// EncryptKeyArgon2 encrypts a key using the specified Argon2id parameters into a
+// json blob that can be decrypted later on.
+func EncryptKeyArgon2(key *Key, auth string, params Argon2Params) ([]byte, error) {
+	keyBytes := math.PaddedBigBytes(key.PrivateKey.D, 32)
+	cryptoStruct, err := EncryptDataArgon2(keyBytes, []byte(auth), params)
+	if err != nil {
+		return nil, err
+	}
+	return marshalKeyV3(key, cryptoStruct)
+}
+
+func marshalKeyV3(key *Key, cryptoStruct CryptoJSON) ([]byte, error) {
 	encryptedKeyJSONV3 := encryptedKeyJSONV3{
 		hex.EncodeToString(key.Address[:]),
 		cryptoStruct,
@@ -329,6 +430,18 @@ func getKDFKey(cryptoJSON CryptoJSON, auth string) ([]byte, error) {
 		p := ensureInt(cryptoJSON.KDFParams["p"])
 		return scrypt.Key(authArray, salt, n, r, p, dkLen)
 
+	} else if cryptoJSON.KDF == keyHeaderKDFArgon2 {
+		t := ensureInt(cryptoJSON.KDFParams["t"])
+		m := ensureInt(cryptoJSON.KDFParams["m"])
+		p := ensureInt(cryptoJSON.KDFParams["p"])
+		if t <= 0 || t > MaxArgon2Time || m <= 0 || m > MaxArgon2Memory || p <= 0 || p > 255 {
+			return nil, fmt.Errorf("Invalid Argon2 parameters: t %d, m %d, p %d", t, m, p)
+		}
+		if dkLen != argon2DKLen {
+			return nil, fmt.Errorf("Invalid Argon2 key length: %d", dkLen)
+		}
+		return argon2.IDKey(authArray, salt, uint32(t), uint32(m), uint8(p), uint32(dkLen)), nil
+
 	} else if cryptoJSON.KDF == "pbkdf2" {
 		c := ensureInt(cryptoJSON.KDFParams["c"])
 		prf := cryptoJSON.KDFParams["prf"].(string)
@@ -17,7 +17,9 @@
 package keystore
 
 import (
+	"encoding/json"
 	"io/ioutil"
+	"os"
 	"testing"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -58,3 +60,91 @@ func TestKeyEncryptDecrypt(t *testing.T) {
 		}
 	}
 }
+
+// Tests that a key encrypted with Argon2id decrypts only with its password, and
+// that the encryption records the Argon2id parameters.
+func TestKeyEncryptDecryptArgon2(t *testing.T) {
+	keyjson, err := ioutil.ReadFile("testdata/very-light-scrypt.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := DecryptKey(keyjson, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	params := Argon2Params{Time: 1, Memory: 64, Threads: 1}
+	if keyjson, err = EncryptKeyArgon2(key, "argon", params); err != nil {
+		t.Fatalf("failed to encrypt key: %v", err)
+	}
+	var encrypted encryptedKeyJSONV3
+	if err := json.Unmarshal(keyjson, &encrypted); err != nil {
+		t.Fatal(err)
+	}
+	if encrypted.Crypto.KDF != keyHeaderKDFArgon2 {
+		t.Errorf("kdf mismatch: have %s, want %s", encrypted.Crypto.KDF, keyHeaderKDFArgon2)
+	}
+	if m := ensureInt(encrypted.Crypto.KDFParams["m"]); m != 64 {
+		t.Errorf("memory mismatch: have %d, want 64", m)
+	}
+	if _, err := DecryptKey(keyjson, "bad"); err != ErrDecrypt {
+		t.Errorf("json key decrypted with bad password: %v", err)
+	}
+	decrypted, err := DecryptKey(keyjson, "argon")
+	if err != nil {
+		t.Fatalf("json key failed to decrypt: %v", err)
+	}
+	if decrypted.Address != key.Address {
+		t.Errorf("key address mismatch: have %x, want %x", decrypted.Address, key.Address)
+	}
+	if _, err := EncryptKeyArgon2(key, "argon", Argon2Params{}); err == nil {
+		t.Errorf("key encrypted with empty Argon2 parameters")
+	}
+	if _, err := EncryptKeyArgon2(key, "argon", Argon2Params{Time: 1, Memory: MaxArgon2Memory + 1, Threads: 1}); err == nil {
+		t.Errorf("key encrypted with Argon2 memory over the bound")
+	}
+	// Key files requiring more than the bounded cost are refused before deriving the key
+	for param, value := range map[string]int{"m": MaxArgon2Memory + 1, "t": MaxArgon2Time + 1, "dklen": 16} {
+		var crafted encryptedKeyJSONV3
+		if err := json.Unmarshal(keyjson, &crafted); err != nil {
+			t.Fatal(err)
+		}
+		crafted.Crypto.KDFParams[param] = value
+		blob, _ := json.Marshal(crafted)
+		if _, err := DecryptKey(blob, "argon"); err == nil || err == ErrDecrypt {
+			t.Errorf("key file with %s %d not refused: %v", param, value, err)
+		}
+	}
+}
+
+// Tests that updating an account of an Argon2id keystore re-encrypts its scrypt
+// key file with Argon2id.
+func TestKeyStoreReencryptArgon2(t *testing.T) {
+	dir, err := ioutil.TempDir("", "keystore-argon2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	account, err := NewKeyStore(dir, veryLightScryptN, veryLightScryptP).NewAccount("pass")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ks := NewKeyStoreArgon2(dir, Argon2Params{Time: 1, Memory: 64, Threads: 1})
+	if err := ks.Update(account, "pass", "pass"); err != nil {
+		t.Fatalf("failed to re-encrypt account: %v", err)
+	}
+	keyjson, err := ioutil.ReadFile(account.URL.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var encrypted encryptedKeyJSONV3
+	if err := json.Unmarshal(keyjson, &encrypted); err != nil {
+		t.Fatal(err)
+	}
+	if encrypted.Crypto.KDF != keyHeaderKDFArgon2 {
+		t.Errorf("kdf mismatch: have %s, want %s", encrypted.Crypto.KDF, keyHeaderKDFArgon2)
+	}
+	if err := ks.Unlock(account, "pass"); err != nil {
+		t.Errorf("failed to unlock re-encrypted account: %v", err)
+	}
+}
@@ -17,10 +17,13 @@
 package accounts
 
 import (
+	"fmt"
 	"reflect"
 	"sort"
 	"sync"
+	"time"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/event"
 )
 
@@ -34,6 +37,11 @@ type Manager struct {
 
 	feed event.Feed // Wallet feed notifying of arrivals/departures
 
+	// Quorum
+	policies   map[common.Address]*unlockPolicy // Restrictions on the use of the unlocked accounts
+	policyLock sync.Mutex
+	approvals  approvals // Actions on the accounts awaiting approvals
+
 	quit chan chan error
 	lock sync.RWMutex
 }
@@ -61,6 +69,7 @@ func NewManager(backends ...Backend) *Manager {
 		wallets:  wallets,
 		quit:     make(chan chan error),
 	}
+	am.approvals.list = make(map[common.Hash]*Approval)
 	for _, backend := range backends {
 		kind := reflect.TypeOf(backend)
 		am.backends[kind] = append(am.backends[kind], backend)
@@ -168,6 +177,96 @@ func (am *Manager) Subscribe(sink chan<- WalletEvent) event.Subscription {
 	return am.feed.Subscribe(sink)
 }
 
+// Quorum
+//
+// SetUnlockPolicies replaces the policies restricting the use of the unlocked
+// accounts, leaving the accounts without one unrestricted.
+func (am *Manager) SetUnlockPolicies(policies map[common.Address]*UnlockPolicy) error {
+	parsed := make(map[common.Address]*unlockPolicy, len(policies))
+	for addr, policy := range policies {
+		p, err := policy.parse()
+		if err != nil {
+			return fmt.Errorf("unlock policy of %x: %v", addr, err)
+		}
+		parsed[addr] = p
+	}
+	am.policyLock.Lock()
+	defer am.policyLock.Unlock()
+
+	am.policies = parsed
+	return nil
+}
+
+// Quorum
+//
+// AuthorizeTx checks the unlock policy of an account allows the RPC client at
+// the given remote address, empty for IPC and in-process calls, to have the
+// account sign a transaction to the given recipient. The transaction is only
+// counted against the hourly allowance of the account once accepted by the
+// transaction pool, which checks the policy again.
+func (am *Manager) AuthorizeTx(remote string, account Account, to *common.Address) error {
+	am.policyLock.Lock()
+	defer am.policyLock.Unlock()
+
+	if policy := am.policies[account.Address]; policy != nil {
+		now := time.Now()
+		if err := policy.authorize(remote, now); err != nil {
+			return &PolicyError{Account: account.Address, Reason: err}
+		}
+		if err := policy.checkTx(to, now); err != nil {
+			return &PolicyError{Account: account.Address, Reason: err}
+		}
+	}
+	return nil
+}
+
+// Quorum
+//
+// CheckTx checks the unlock policy of an account allows a transaction to the
+// given recipient, whatever its origin, without counting it. Together with
+// RecordTx, it restricts the transactions entering the transaction pool.
+func (am *Manager) CheckTx(from common.Address, to *common.Address) error {
+	am.policyLock.Lock()
+	defer am.policyLock.Unlock()
+
+	if policy := am.policies[from]; policy != nil {
+		if err := policy.checkTx(to, time.Now()); err != nil {
+			return &PolicyError{Account: from, Reason: err}
+		}
+	}
+	return nil
+}
+
+// Quorum
+//
+// RecordTx counts a transaction of an account accepted by the transaction pool
+// against the hourly allowance of its unlock policy.
+func (am *Manager) RecordTx(from common.Address, hash common.Hash) {
+	am.policyLock.Lock()
+	defer am.policyLock.Unlock()
+
+	if policy := am.policies[from]; policy != nil {
+		policy.recordTx(hash, time.Now())
+	}
+}
+
+// Quorum
+//
+// AuthorizeSign checks the unlock policy of an account allows the RPC client at
+// the given remote address, empty for IPC and in-process calls, to have the
+// account sign a message.
+func (am *Manager) AuthorizeSign(remote string, account Account) error {
+	am.policyLock.Lock()
+	defer am.policyLock.Unlock()
+
+	if policy := am.policies[account.Address]; policy != nil {
+		if err := policy.authorize(remote, time.Now()); err != nil {
+			return &PolicyError{Account: account.Address, Reason: err}
+		}
+	}
+	return nil
+}
+
 // merge is a sorted analogue of append for wallets, where the ordering of the
 // origin list is preserved by inserting new wallets at the correct position.
 //
@@ -0,0 +1,203 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package accounts
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/p2p/netutil"
+)
+
+// UnlockPolicy restricts the use of an unlocked account, so that it can't be
+// abused by whoever gains access to the RPC endpoints.
+type UnlockPolicy struct {
+	// Sources are the CIDR masks of the RPC clients allowed to use the account,
+	// empty to allow any. The calls over IPC and in-process are always allowed.
+	Sources []string `json:"sources,omitempty"`
+
+	// Targets are the contracts the account may send transactions to, empty to
+	// allow any. Contract creations are denied if set.
+	Targets []common.Address `json:"targets,omitempty"`
+
+	// MaxPerHour is the number of transactions the account may sign within any
+	// hour, zero if unlimited.
+	MaxPerHour int `json:"maxPerHour,omitempty"`
+
+	// Window is the time of the day the account may be used, as HH:MM-HH:MM in
+	// UTC, empty if any. A window ending before it starts spans midnight.
+	Window string `json:"window,omitempty"`
+}
+
+// unlockPolicy is a parsed unlock policy along with the transactions accepted
+// within the last hour.
+type unlockPolicy struct {
+	sources    *netutil.Netlist
+	targets    map[common.Address]bool
+	maxPerHour int
+	from, to   time.Duration // Window as offsets from midnight, equal if none
+	sent       []sentTx
+}
+
+// sentTx is a transaction of an account counted against its hourly allowance.
+type sentTx struct {
+	hash common.Hash
+	time time.Time
+}
+
+// LoadUnlockPolicies reads the unlock policies of the accounts from a JSON file
+// mapping the account addresses to their policy.
+func LoadUnlockPolicies(path string) (map[common.Address]*UnlockPolicy, error) {
+	blob, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	policies := make(map[common.Address]*UnlockPolicy)
+	if err := json.Unmarshal(blob, &policies); err != nil {
+		return nil, fmt.Errorf("invalid unlock policies %s: %v", path, err)
+	}
+	return policies, nil
+}
+
+// parseWindowTime parses a time of the day as HH:MM.
+func parseWindowTime(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// parse validates an unlock policy.
+func (p *UnlockPolicy) parse() (*unlockPolicy, error) {
+	policy := &unlockPolicy{maxPerHour: p.MaxPerHour}
+	if len(p.Sources) > 0 {
+		sources, err := netutil.ParseNetlist(strings.Join(p.Sources, ","))
+		if err != nil {
+			return nil, fmt.Errorf("invalid sources: %v", err)
+		}
+		policy.sources = sources
+	}
+	if len(p.Targets) > 0 {
+		policy.targets = make(map[common.Address]bool)
+		for _, target := range p.Targets {
+			policy.targets[target] = true
+		}
+	}
+	if p.MaxPerHour < 0 {
+		return nil, fmt.Errorf("invalid max transactions per hour: %d", p.MaxPerHour)
+	}
+	if p.Window != "" {
+		bounds := strings.Split(p.Window, "-")
+		if len(bounds) != 2 {
+			return nil, fmt.Errorf("invalid window %q, want HH:MM-HH:MM", p.Window)
+		}
+		var err error
+		if policy.from, err = parseWindowTime(bounds[0]); err != nil {
+			return nil, fmt.Errorf("invalid window %q: %v", p.Window, err)
+		}
+		if policy.to, err = parseWindowTime(bounds[1]); err != nil {
+			return nil, fmt.Errorf("invalid window %q: %v", p.Window, err)
+		}
+		if policy.from == policy.to {
+			return nil, fmt.Errorf("empty window %q", p.Window)
+		}
+	}
+	return policy, nil
+}
+
+// authorize checks the policy allows the account to be used by the given RPC
+// client at the given time.
+func (p *unlockPolicy) authorize(remote string, now time.Time) error {
+	if p.sources != nil && remote != "" {
+		host, _, err := net.SplitHostPort(remote)
+		if err != nil {
+			host = remote
+		}
+		if ip := net.ParseIP(host); ip == nil || !p.sources.Contains(ip) {
+			return fmt.Errorf("RPC client %s not allowed", host)
+		}
+	}
+	if p.from != p.to {
+		now = now.UTC()
+		since := now.Sub(time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC))
+
+		inside := since >= p.from && since < p.to
+		if p.from > p.to {
+			inside = since >= p.from || since < p.to
+		}
+		if !inside {
+			return fmt.Errorf("outside of the %s-%s UTC window", fmtWindowTime(p.from), fmtWindowTime(p.to))
+		}
+	}
+	return nil
+}
+
+// checkTx checks the policy allows the account to send a transaction to the
+// given recipient, within its hourly allowance. The transaction is only counted
+// once accepted, by recordTx.
+func (p *unlockPolicy) checkTx(to *common.Address, now time.Time) error {
+	if err := p.authorize("", now); err != nil {
+		return err
+	}
+	if p.targets != nil {
+		if to == nil {
+			return fmt.Errorf("contract creation not allowed")
+		}
+		if !p.targets[*to] {
+			return fmt.Errorf("target %x not allowed", *to)
+		}
+	}
+	if p.maxPerHour > 0 && len(p.lastHour(now)) >= p.maxPerHour {
+		return fmt.Errorf("over %d transactions per hour", p.maxPerHour)
+	}
+	return nil
+}
+
+// recordTx counts an accepted transaction against the hourly allowance, once
+// even if accepted again, e.g. after a reorg.
+func (p *unlockPolicy) recordTx(hash common.Hash, now time.Time) {
+	if p.maxPerHour == 0 {
+		return
+	}
+	for _, tx := range p.lastHour(now) {
+		if tx.hash == hash {
+			return
+		}
+	}
+	p.sent = append(p.sent, sentTx{hash, now})
+}
+
+// lastHour drops the transactions sent over an hour ago, returning the others.
+func (p *unlockPolicy) lastHour(now time.Time) []sentTx {
+	i := 0
+	for i < len(p.sent) && now.Sub(p.sent[i].time) >= time.Hour {
+		i++
+	}
+	p.sent = p.sent[i:]
+	return p.sent
+}
+
+// fmtWindowTime formats an offset from midnight as HH:MM.
+func fmtWindowTime(d time.Duration) string {
+	return fmt.Sprintf("%02d:%02d", int(d/time.Hour), int(d%time.Hour/time.Minute))
+}
@@ -0,0 +1,106 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package accounts
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestUnlockPolicyParse(t *testing.T) {
+	tests := []UnlockPolicy{
+		{Sources: []string{"10.0.0.1"}},
+		{MaxPerHour: -1},
+		{Window: "09:00"},
+		{Window: "09:00-25:00"},
+		{Window: "09:00-09:00"},
+	}
+	for i, policy := range tests {
+		if _, err := policy.parse(); err == nil {
+			t.Errorf("test %d: invalid policy accepted", i)
+		}
+	}
+}
+
+func TestUnlockPolicyAuthorize(t *testing.T) {
+	target := common.Address{0x01}
+	policy, err := (&UnlockPolicy{
+		Sources:    []string{"10.0.0.0/8"},
+		Targets:    []common.Address{target},
+		MaxPerHour: 2,
+		Window:     "22:00-06:00",
+	}).parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	night := time.Date(2019, 5, 1, 23, 0, 0, 0, time.UTC)
+	day := time.Date(2019, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		remote string
+		to     *common.Address
+		now    time.Time
+		ok     bool
+	}{
+		{"10.1.2.3:4000", &target, night, true},
+		{"", &target, night.Add(time.Minute), true},                    // IPC and in-process calls are local
+		{"192.168.0.1:4000", &target, night, false},                    // Source not allowed
+		{"10.1.2.3:4000", &common.Address{0x02}, night, false},         // Target not allowed
+		{"10.1.2.3:4000", nil, night, false},                           // Contract creation
+		{"10.1.2.3:4000", &target, day, false},                         // Outside of the window
+		{"10.1.2.3:4000", &target, night.Add(30 * time.Minute), false}, // Over the hourly allowance
+		{"10.1.2.3:4000", &target, night.Add(time.Hour), true},         // Allowance renewed
+	}
+	for i, tt := range tests {
+		err := policy.authorize(tt.remote, tt.now)
+		if err == nil {
+			err = policy.checkTx(tt.to, tt.now)
+		}
+		if (err == nil) != tt.ok {
+			t.Errorf("test %d: authorization mismatch: have %v, want ok %v", i, err, tt.ok)
+		}
+		if err == nil {
+			policy.recordTx(common.Hash{byte(i)}, tt.now)
+		}
+	}
+}
+
+// Tests that only the transactions recorded count against the hourly allowance,
+// once each.
+func TestUnlockPolicyRecord(t *testing.T) {
+	policy, err := (&UnlockPolicy{MaxPerHour: 2}).parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2019, 5, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		if err := policy.checkTx(nil, now); err != nil {
+			t.Fatalf("check %d: unrecorded transaction counted: %v", i, err)
+		}
+	}
+	policy.recordTx(common.Hash{0x01}, now)
+	policy.recordTx(common.Hash{0x01}, now) // Accepted again, e.g. after a reorg
+	if err := policy.checkTx(nil, now); err != nil {
+		t.Fatalf("transaction counted twice: %v", err)
+	}
+	policy.recordTx(common.Hash{0x02}, now)
+	if err := policy.checkTx(nil, now); err == nil {
+		t.Fatalf("transaction over the hourly allowance allowed")
+	}
+}
@@ -0,0 +1,149 @@
+package adminauth
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func sign(t *testing.T, method string, timestamp uint64, params ...interface{}) *Signature {
+	key, _ := crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	hash, _, err := SigningHash(method, timestamp, params...)
+	if err != nil {
+		t.Fatalf("failed to compute signing hash: %v", err)
+	}
+	sig, err := crypto.Sign(hash.Bytes(), key)
+	if err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	sig[64] += 27
+	return &Signature{Timestamp: hexutil.Uint64(timestamp), Signature: sig}
+}
+
+func TestVerify(t *testing.T) {
+	dir, err := ioutil.TempDir("", "adminauth")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var (
+		archive  = filepath.Join(dir, "audit.log")
+		operator = common.HexToAddress("0x71562b71999873DB5b286dF957af199Ec94617F7")
+		auth     = New([]common.Address{operator}, archive)
+		now      = uint64(time.Now().Unix())
+	)
+	if err := auth.Verify("raft_addPeer", nil, "enode://a"); err != ErrSignatureRequired {
+		t.Fatalf("unsigned call: have %v, want %v", err, ErrSignatureRequired)
+	}
+	sig := sign(t, "raft_addPeer", now, "enode://a")
+	if err := auth.Verify("raft_addPeer", sig, "enode://b"); err != ErrUnknownOperator {
+		t.Fatalf("tampered params: have %v, want %v", err, ErrUnknownOperator)
+	}
+	if err := auth.Verify("raft_addPeer", sig, "enode://a"); err != nil {
+		t.Fatalf("valid call rejected: %v", err)
+	}
+	if err := auth.Verify("raft_addPeer", sig, "enode://a"); err != ErrSignatureReplayed {
+		t.Fatalf("replayed call: have %v, want %v", err, ErrSignatureReplayed)
+	}
+	stale := sign(t, "raft_addPeer", now-uint64(2*MaxClockSkew/time.Second), "enode://a")
+	if err := auth.Verify("raft_addPeer", stale, "enode://a"); err != ErrSignatureExpired {
+		t.Fatalf("stale call: have %v, want %v", err, ErrSignatureExpired)
+	}
+
+	// Only the accepted call must have been archived
+	f, err := os.Open(archive)
+	if err != nil {
+		t.Fatalf("failed to open archive: %v", err)
+	}
+	defer f.Close()
+
+	var records []Record
+	for scanner := bufio.NewScanner(f); scanner.Scan(); {
+		var record Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatalf("invalid archive record: %v", err)
+		}
+		records = append(records, record)
+	}
+	if len(records) != 1 {
+		t.Fatalf("archived records mismatch: have %d, want 1", len(records))
+	}
+	if records[0].Operator != operator || records[0].Method != "raft_addPeer" {
+		t.Fatalf("archived record mismatch: %+v", records[0])
+	}
+}
+
+func TestVerifyDisabled(t *testing.T) {
+	var auth *Authenticator
+	if err := auth.Verify("istanbul_propose", nil); err != nil {
+		t.Fatalf("nil authenticator rejected call: %v", err)
+	}
+	if err := New(nil, "").Verify("istanbul_propose", nil); err != nil {
+		t.Fatalf("empty authenticator rejected call: %v", err)
+	}
+}
+
+func TestSigner(t *testing.T) {
+	var (
+		operator = common.HexToAddress("0x71562b71999873DB5b286dF957af199Ec94617F7")
+		auth     = New([]common.Address{operator}, "")
+		stale    = uint64(time.Now().Add(-time.Hour).Unix())
+	)
+	// Signatures of any age are accepted, and may be checked again
+	sig := sign(t, "permsync_addNode", stale, "enode://a")
+	for i := 0; i < 2; i++ {
+		signer, err := auth.Signer("permsync_addNode", sig, "enode://a")
+		if err != nil {
+			t.Fatalf("valid signature rejected: %v", err)
+		}
+		if signer != operator {
+			t.Fatalf("signer mismatch: have %x, want %x", signer, operator)
+		}
+	}
+	if _, err := auth.Signer("permsync_addNode", sig, "enode://b"); err != ErrUnknownOperator {
+		t.Fatalf("tampered params: have %v, want %v", err, ErrUnknownOperator)
+	}
+	if _, err := New(nil, "").Signer("permsync_addNode", sig, "enode://a"); err != ErrUnknownOperator {
+		t.Fatalf("no operators: have %v, want %v", err, ErrUnknownOperator)
+	}
+}
+
+func TestVerifyInternal(t *testing.T) {
+	dir, err := ioutil.TempDir("", "adminauth")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var (
+		archive  = filepath.Join(dir, "audit.log")
+		operator = common.HexToAddress("0x71562b71999873DB5b286dF957af199Ec94617F7")
+		auth     = New([]common.Address{operator}, archive)
+	)
+	if err := auth.VerifyInternal("", "raft_removePeer", uint16(2)); err != ErrSignatureRequired {
+		t.Fatalf("anonymous call: have %v, want %v", err, ErrSignatureRequired)
+	}
+	if err := auth.VerifyInternal("permission", "raft_removePeer", uint16(2)); err != nil {
+		t.Fatalf("internal call rejected: %v", err)
+	}
+	blob, err := ioutil.ReadFile(archive)
+	if err != nil {
+		t.Fatalf("failed to read archive: %v", err)
+	}
+	var record Record
+	if err := json.Unmarshal(blob, &record); err != nil {
+		t.Fatalf("invalid archive record: %v", err)
+	}
+	if record.Method != "raft_removePeer" || record.Origin != "permission" || string(record.Params) != "[2]" {
+		t.Fatalf("archived record mismatch: %+v", record)
+	}
+}
@@ -0,0 +1,216 @@
+// Package bootstrap implements the signed configuration bundle a node can be
+// bootstrapped from before its first start.
+//
+// A consortium publishes the genesis file, the static node list and the
+// permissioning configuration as one bundle, signed by its members. The node
+// fetches the bundle from a URL, checks that enough of the configured member
+// keys signed it, then initialises its data directory from it, so that every
+// member starts from the very same files.
+package bootstrap
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+const (
+	GenesisFile     = "genesis.json"
+	StaticNodesFile = "static-nodes.json"
+
+	fetchTimeout  = 30 * time.Second
+	maxBundleSize = 16 * 1024 * 1024
+)
+
+// knownFiles are the files a bundle may carry.
+var knownFiles = map[string]bool{
+	GenesisFile:                    true,
+	StaticNodesFile:                true,
+	params.PERMISSIONED_CONFIG:     true,
+	params.BLACKLIST_CONFIG:        true,
+	params.PERMISSION_MODEL_CONFIG: true,
+}
+
+var (
+	ErrGenesisMissing     = errors.New("bootstrap bundle carries no genesis file")
+	ErrNotEnoughSigners   = errors.New("bootstrap bundle not signed by enough configured keys")
+	ErrConflictingFile    = errors.New("existing file differs from the bootstrap bundle")
+	ErrNoSignersSpecified = errors.New("no bootstrap signer keys configured")
+)
+
+// Bundle is the signed set of configuration files a node bootstraps from.
+type Bundle struct {
+	Files      map[string]string `json:"files"`      // File contents by name
+	Signatures []hexutil.Bytes   `json:"signatures"` // 65 byte [R || S || V] signatures over SigningHash
+}
+
+// SigningHash returns the hash the consortium members sign to approve a set of
+// files. The signed message lists "<name>:<keccak256 of contents>" lines in the
+// order of the file names, and is hashed the same way as eth_sign so that any
+// standard signer can produce the signatures.
+func SigningHash(files map[string]string) common.Hash {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var msg bytes.Buffer
+	for _, name := range names {
+		fmt.Fprintf(&msg, "%s:%x\n", name, crypto.Keccak256([]byte(files[name])))
+	}
+	prefixed := fmt.Sprintf("\x19Ethereum Signed Message:\n%d%s", msg.Len(), msg.Bytes())
+	return crypto.Keccak256Hash([]byte(prefixed))
+}
+
+// ParseSigner parses the hex encoded public key of a signer, compressed or not,
+// into its address.
+func ParseSigner(key string) (common.Address, error) {
+	raw, err := hexutil.Decode(key)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("invalid signer key %q: %v", key, err)
+	}
+	var pub *ecdsa.PublicKey
+	if len(raw) == 33 {
+		pub, err = crypto.DecompressPubkey(raw)
+	} else {
+		pub, err = crypto.UnmarshalPubkey(raw)
+	}
+	if err != nil {
+		return common.Address{}, fmt.Errorf("invalid signer key %q: %v", key, err)
+	}
+	return crypto.PubkeyToAddress(*pub), nil
+}
+
+// ParseSigners parses a comma separated list of signer public keys.
+func ParseSigners(list string) ([]common.Address, error) {
+	var signers []common.Address
+	for _, key := range strings.Split(list, ",") {
+		if key = strings.TrimSpace(key); key == "" {
+			continue
+		}
+		signer, err := ParseSigner(key)
+		if err != nil {
+			return nil, err
+		}
+		signers = append(signers, signer)
+	}
+	return signers, nil
+}
+
+// Fetch downloads the bundle published at the given URL.
+func Fetch(url string) (*Bundle, error) {
+	client := &http.Client{Timeout: fetchTimeout}
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch bootstrap bundle: %s", resp.Status)
+	}
+	data, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxBundleSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxBundleSize {
+		return nil, fmt.Errorf("bootstrap bundle exceeds %d bytes", maxBundleSize)
+	}
+	bundle := new(Bundle)
+	if err := json.Unmarshal(data, bundle); err != nil {
+		return nil, fmt.Errorf("invalid bootstrap bundle: %v", err)
+	}
+	return bundle, nil
+}
+
+// Verify checks that the bundle only carries known files, including a genesis
+// file, and that at least threshold of the given signers signed it.
+func (b *Bundle) Verify(signers []common.Address, threshold int) error {
+	if len(signers) == 0 {
+		return ErrNoSignersSpecified
+	}
+	if threshold < 1 || threshold > len(signers) {
+		return fmt.Errorf("invalid bootstrap threshold %d for %d signers", threshold, len(signers))
+	}
+	for name := range b.Files {
+		if !knownFiles[name] {
+			return fmt.Errorf("unknown file %q in bootstrap bundle", name)
+		}
+	}
+	if _, ok := b.Files[GenesisFile]; !ok {
+		return ErrGenesisMissing
+	}
+	allowed := make(map[common.Address]bool, len(signers))
+	for _, signer := range signers {
+		allowed[signer] = true
+	}
+	hash := SigningHash(b.Files)
+	approved := make(map[common.Address]bool)
+	for _, sig := range b.Signatures {
+		if len(sig) != 65 {
+			continue
+		}
+		raw := common.CopyBytes(sig)
+		if raw[64] >= 27 {
+			raw[64] -= 27 // Accept the legacy Ethereum V values too
+		}
+		pub, err := crypto.SigToPub(hash.Bytes(), raw)
+		if err != nil {
+			continue
+		}
+		if signer := crypto.PubkeyToAddress(*pub); allowed[signer] {
+			approved[signer] = true
+		}
+	}
+	if len(approved) < threshold {
+		return ErrNotEnoughSigners
+	}
+	return nil
+}
+
+// Genesis decodes the genesis file of the bundle.
+func (b *Bundle) Genesis() (*core.Genesis, error) {
+	data, ok := b.Files[GenesisFile]
+	if !ok {
+		return nil, ErrGenesisMissing
+	}
+	genesis := new(core.Genesis)
+	if err := json.Unmarshal([]byte(data), genesis); err != nil {
+		return nil, fmt.Errorf("invalid genesis file: %v", err)
+	}
+	return genesis, nil
+}
+
+// Install writes a file of the bundle to the given path, if the bundle carries
+// it. An existing file is left alone if identical, and refused otherwise.
+func (b *Bundle) Install(name, path string) error {
+	data, ok := b.Files[name]
+	if !ok {
+		return nil
+	}
+	if existing, err := ioutil.ReadFile(path); err == nil {
+		if !bytes.Equal(existing, []byte(data)) {
+			return fmt.Errorf("%v: %s", ErrConflictingFile, path)
+		}
+		return nil
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	return ioutil.WriteFile(path, []byte(data), 0644)
+}
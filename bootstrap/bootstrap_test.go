@@ -0,0 +1,145 @@
+package bootstrap
+
+import (
+	"crypto/ecdsa"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+var (
+	testKey1, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	testKey2, _ = crypto.HexToECDSA("8a1f9a8f95be41cd7ccb6168179afb4504aefe388d1e14474d32c45c72ce7b7a")
+	testKey3, _ = crypto.HexToECDSA("49a7b37aa6f6645917e7b807e9d1c00d4fa71f18343b0d4122a4d2df64dd6fee")
+)
+
+func testBundle(t *testing.T) *Bundle {
+	b := &Bundle{Files: map[string]string{
+		GenesisFile:                `{"config":{"chainId":10,"isQuorum":true},"difficulty":"0x0","gasLimit":"0xE0000000","alloc":{}}`,
+		StaticNodesFile:            `["enode://a"]`,
+		params.PERMISSIONED_CONFIG: `["enode://a"]`,
+	}}
+	for _, key := range []*ecdsa.PrivateKey{testKey1, testKey2} {
+		sig, err := crypto.Sign(SigningHash(b.Files).Bytes(), key)
+		if err != nil {
+			t.Fatalf("failed to sign: %v", err)
+		}
+		sig[64] += 27
+		b.Signatures = append(b.Signatures, sig)
+	}
+	return b
+}
+
+func TestVerify(t *testing.T) {
+	var (
+		signer1 = crypto.PubkeyToAddress(testKey1.PublicKey)
+		signer2 = crypto.PubkeyToAddress(testKey2.PublicKey)
+		signer3 = crypto.PubkeyToAddress(testKey3.PublicKey)
+		all     = []common.Address{signer1, signer2, signer3}
+	)
+	b := testBundle(t)
+	if err := b.Verify(all, 2); err != nil {
+		t.Fatalf("valid bundle rejected: %v", err)
+	}
+	if err := b.Verify(all, 3); err != ErrNotEnoughSigners {
+		t.Fatalf("threshold not met: have %v, want %v", err, ErrNotEnoughSigners)
+	}
+	if err := b.Verify([]common.Address{signer3}, 1); err != ErrNotEnoughSigners {
+		t.Fatalf("unknown signers: have %v, want %v", err, ErrNotEnoughSigners)
+	}
+	if err := b.Verify(nil, 1); err != ErrNoSignersSpecified {
+		t.Fatalf("no signers: have %v, want %v", err, ErrNoSignersSpecified)
+	}
+	// Signatures must not be counted twice
+	b.Signatures = []hexutil.Bytes{b.Signatures[0], b.Signatures[0]}
+	if err := b.Verify(all, 2); err != ErrNotEnoughSigners {
+		t.Fatalf("duplicate signatures: have %v, want %v", err, ErrNotEnoughSigners)
+	}
+	// Any change to the files invalidates the signatures
+	b = testBundle(t)
+	b.Files[StaticNodesFile] = `["enode://b"]`
+	if err := b.Verify(all, 1); err != ErrNotEnoughSigners {
+		t.Fatalf("tampered bundle: have %v, want %v", err, ErrNotEnoughSigners)
+	}
+	b = testBundle(t)
+	b.Files["nodekey"] = "00"
+	if err := b.Verify(all, 1); err == nil {
+		t.Fatalf("unknown file accepted")
+	}
+}
+
+func TestParseSigners(t *testing.T) {
+	uncompressed := hexutil.Encode(crypto.FromECDSAPub(&testKey1.PublicKey))
+	compressed := hexutil.Encode(crypto.CompressPubkey(&testKey2.PublicKey))
+
+	signers, err := ParseSigners(uncompressed + ", " + compressed + ",")
+	if err != nil {
+		t.Fatalf("failed to parse signers: %v", err)
+	}
+	want := []common.Address{crypto.PubkeyToAddress(testKey1.PublicKey), crypto.PubkeyToAddress(testKey2.PublicKey)}
+	if len(signers) != 2 || signers[0] != want[0] || signers[1] != want[1] {
+		t.Fatalf("signers mismatch: have %x, want %x", signers, want)
+	}
+	if _, err := ParseSigners("0x1234"); err == nil {
+		t.Fatalf("invalid key accepted")
+	}
+}
+
+func TestFetchAndInstall(t *testing.T) {
+	b := testBundle(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(b)
+	}))
+	defer server.Close()
+
+	fetched, err := Fetch(server.URL)
+	if err != nil {
+		t.Fatalf("failed to fetch: %v", err)
+	}
+	if err := fetched.Verify([]common.Address{crypto.PubkeyToAddress(testKey1.PublicKey)}, 1); err != nil {
+		t.Fatalf("fetched bundle rejected: %v", err)
+	}
+	genesis, err := fetched.Genesis()
+	if err != nil {
+		t.Fatalf("failed to decode genesis: %v", err)
+	}
+	if genesis.Config.ChainID.Uint64() != 10 {
+		t.Fatalf("chain id mismatch: have %v, want 10", genesis.Config.ChainID)
+	}
+
+	dir, err := ioutil.TempDir("", "bootstrap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, StaticNodesFile)
+	if err := fetched.Install(StaticNodesFile, path); err != nil {
+		t.Fatalf("failed to install: %v", err)
+	}
+	if data, _ := ioutil.ReadFile(path); string(data) != b.Files[StaticNodesFile] {
+		t.Fatalf("installed file mismatch: %s", data)
+	}
+	if err := fetched.Install(StaticNodesFile, path); err != nil {
+		t.Fatalf("identical file refused: %v", err)
+	}
+	ioutil.WriteFile(path, []byte(`["enode://b"]`), 0644)
+	if err := fetched.Install(StaticNodesFile, path); err == nil {
+		t.Fatalf("conflicting file overwritten")
+	}
+	if err := fetched.Install(params.BLACKLIST_CONFIG, filepath.Join(dir, params.BLACKLIST_CONFIG)); err != nil {
+		t.Fatalf("absent file: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, params.BLACKLIST_CONFIG)); !os.IsNotExist(err) {
+		t.Fatalf("absent file installed")
+	}
+}
@@ -23,6 +23,7 @@ import (
 	"github.com/ethereum/go-ethereum/accounts"
 	"github.com/ethereum/go-ethereum/accounts/keystore"
 	"github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/console"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/log"
@@ -110,6 +111,12 @@ Print a short summary of all accounts`,
 					utils.KeyStoreDirFlag,
 					utils.PasswordFileFlag,
 					utils.LightKDFFlag,
+					utils.KeyStoreScryptNFlag,
+					utils.KeyStoreScryptPFlag,
+					utils.KeyStoreArgon2Flag,
+					utils.KeyStoreArgon2TimeFlag,
+					utils.KeyStoreArgon2MemoryFlag,
+					utils.KeyStoreArgon2ThreadsFlag,
 				},
 				Description: `
     geth account new
@@ -135,6 +142,12 @@ password to file or expose in any other way.
 					utils.DataDirFlag,
 					utils.KeyStoreDirFlag,
 					utils.LightKDFFlag,
+					utils.KeyStoreScryptNFlag,
+					utils.KeyStoreScryptPFlag,
+					utils.KeyStoreArgon2Flag,
+					utils.KeyStoreArgon2TimeFlag,
+					utils.KeyStoreArgon2MemoryFlag,
+					utils.KeyStoreArgon2ThreadsFlag,
 				},
 				Description: `
     geth account update <address>
@@ -164,6 +177,12 @@ changing your password is only possible interactively.
 					utils.KeyStoreDirFlag,
 					utils.PasswordFileFlag,
 					utils.LightKDFFlag,
+					utils.KeyStoreScryptNFlag,
+					utils.KeyStoreScryptPFlag,
+					utils.KeyStoreArgon2Flag,
+					utils.KeyStoreArgon2TimeFlag,
+					utils.KeyStoreArgon2MemoryFlag,
+					utils.KeyStoreArgon2ThreadsFlag,
 				},
 				ArgsUsage: "<keyFile>",
 				Description: `
@@ -186,6 +205,36 @@ Note:
 As you can directly copy your encrypted accounts to another ethereum instance,
 this import mechanism is not needed when you transfer an account between
 nodes.
+`,
+			},
+			{
+				Name:   "reencrypt",
+				Usage:  "Re-encrypt all accounts with the configured key derivation",
+				Action: utils.MigrateFlags(accountReencrypt),
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.KeyStoreDirFlag,
+					utils.PasswordFileFlag,
+					utils.LightKDFFlag,
+					utils.KeyStoreScryptNFlag,
+					utils.KeyStoreScryptPFlag,
+					utils.KeyStoreArgon2Flag,
+					utils.KeyStoreArgon2TimeFlag,
+					utils.KeyStoreArgon2MemoryFlag,
+					utils.KeyStoreArgon2ThreadsFlag,
+				},
+				Description: `
+    geth account reencrypt [options]
+
+Re-encrypts the key file of every account with the key derivation parameters
+of the node, as set by the --keystore.scrypt.*, --keystore.argon2* and
+--lightkdf flags or the config file, keeping the passwords unchanged. This
+migrates the existing keys to a new encryption policy, for example from scrypt
+to Argon2id.
+
+You are prompted for the password of each account. For non-interactive use the
+passwords can be given with the --password flag, one per line in the order of
+'geth account list', the last one used for the remaining accounts.
 `,
 			},
 		},
@@ -307,7 +356,12 @@ func accountCreate(ctx *cli.Context) error {
 
 	password := getPassPhrase("Your new account is locked with a password. Please give a password. Do not forget this password.", true, 0, utils.MakePasswordList(ctx))
 
-	address, err := keystore.StoreKey(keydir, password, scryptN, scryptP)
+	var address common.Address
+	if cfg.Node.KeyStoreArgon2 != nil {
+		address, err = keystore.StoreKeyArgon2(keydir, password, *cfg.Node.KeyStoreArgon2)
+	} else {
+		address, err = keystore.StoreKey(keydir, password, scryptN, scryptP)
+	}
 
 	if err != nil {
 		utils.Fatalf("Failed to create account: %v", err)
@@ -335,6 +389,29 @@ func accountUpdate(ctx *cli.Context) error {
 	return nil
 }
 
+// accountReencrypt re-encrypts every account of the keystore with the configured
+// key derivation parameters.
+func accountReencrypt(ctx *cli.Context) error {
+	stack, _ := makeConfigNode(ctx)
+	ks := stack.AccountManager().Backends(keystore.KeyStoreType)[0].(*keystore.KeyStore)
+	passwords := utils.MakePasswordList(ctx)
+
+	var failed int
+	for i, account := range ks.Accounts() {
+		password := getPassPhrase(fmt.Sprintf("Re-encrypting account %x", account.Address), false, i, passwords)
+		if err := ks.Update(account, password, password); err != nil {
+			log.Error("Failed to re-encrypt account", "address", account.Address, "err", err)
+			failed++
+			continue
+		}
+		fmt.Printf("Re-encrypted account #%d: {%x} %s\n", i, account.Address, &account.URL)
+	}
+	if failed > 0 {
+		utils.Fatalf("Failed to re-encrypt %d accounts", failed)
+	}
+	return nil
+}
+
 func importWallet(ctx *cli.Context) error {
 	keyfile := ctx.Args().First()
 	if len(keyfile) == 0 {
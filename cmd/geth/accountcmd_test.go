@@ -106,6 +106,38 @@ Repeat passphrase: {{.InputLine "foobar2"}}
 `)
 }
 
+func TestAccountReencrypt(t *testing.T) {
+	datadir := tmpDatadirWithKeystore(t)
+	geth := runGeth(t, "account", "reencrypt",
+		"--datadir", datadir, "--password", "testdata/passwords.txt",
+		"--keystore.argon2", "--keystore.argon2.time", "1",
+		"--keystore.argon2.memory", "64", "--keystore.argon2.threads", "1")
+	if runtime.GOOS == "windows" {
+		geth.Expect(`
+Re-encrypted account #0: {7ef5a6135f1fd6a02593eedc869c6d41d934aef8} keystore://{{.Datadir}}\keystore\UTC--2016-03-22T12-57-55.920751759Z--7ef5a6135f1fd6a02593eedc869c6d41d934aef8
+Re-encrypted account #1: {f466859ead1932d743d622cb74fc058882e8648a} keystore://{{.Datadir}}\keystore\aaa
+Re-encrypted account #2: {289d485d9771714cce91d3393d764e1311907acc} keystore://{{.Datadir}}\keystore\zzz
+`)
+	} else {
+		geth.Expect(`
+Re-encrypted account #0: {7ef5a6135f1fd6a02593eedc869c6d41d934aef8} keystore://{{.Datadir}}/keystore/UTC--2016-03-22T12-57-55.920751759Z--7ef5a6135f1fd6a02593eedc869c6d41d934aef8
+Re-encrypted account #1: {f466859ead1932d743d622cb74fc058882e8648a} keystore://{{.Datadir}}/keystore/aaa
+Re-encrypted account #2: {289d485d9771714cce91d3393d764e1311907acc} keystore://{{.Datadir}}/keystore/zzz
+`)
+	}
+	geth.ExpectExit()
+
+	for _, name := range []string{"UTC--2016-03-22T12-57-55.920751759Z--7ef5a6135f1fd6a02593eedc869c6d41d934aef8", "aaa", "zzz"} {
+		keyjson, err := ioutil.ReadFile(filepath.Join(datadir, "keystore", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(keyjson), `"kdf":"argon2id"`) {
+			t.Errorf("key file %s not re-encrypted with Argon2id", name)
+		}
+	}
+}
+
 func TestWalletImport(t *testing.T) {
 	geth := runGeth(t, "wallet", "import", "--lightkdf", "testdata/guswallet.json")
 	defer geth.ExpectExit()
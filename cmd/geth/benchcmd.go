@@ -0,0 +1,273 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	istanbulBackend "github.com/ethereum/go-ethereum/consensus/istanbul/backend"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	benchFromFlag = cli.Uint64Flag{
+		Name:  "from",
+		Usage: "First block to re-execute",
+	}
+	benchToFlag = cli.Uint64Flag{
+		Name:  "to",
+		Usage: "Last block to re-execute (default = head of the chain)",
+	}
+	benchParallelFlag = cli.IntFlag{
+		Name:  "parallel",
+		Usage: "Number of segments of the range re-executed concurrently",
+		Value: 1,
+	}
+	benchCommand = cli.Command{
+		Name:     "bench",
+		Usage:    "Benchmark the node against its own chain",
+		Category: "BLOCKCHAIN COMMANDS",
+		Description: `
+The bench commands measure the hardware of a stopped node on the chain held in
+its datadir, so that it can be validated before a migration.`,
+		Subcommands: []cli.Command{
+			{
+				Name:      "replay",
+				Usage:     "Re-execute a historical block range and report its timings",
+				ArgsUsage: " ",
+				Action:    utils.MigrateFlags(benchReplay),
+				Category:  "BLOCKCHAIN COMMANDS",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.CacheFlag,
+					utils.CacheDatabaseFlag,
+					utils.GCModeFlag,
+					benchFromFlag,
+					benchToFlag,
+					benchParallelFlag,
+				},
+				Description: `
+    geth bench replay --from <block> [--to <block>] [--parallel <n>]
+
+re-executes the blocks of the canonical chain in the range, checks the state
+roots they result in, and reports for every block its execution time, its gas
+per second and the time taken to commit its state tries, followed by a summary.
+
+The state of the parent of the range must be available, as it is on an archive
+node. With --parallel the range is split in as many segments, re-executed
+concurrently, each of them requiring the state of its parent. The database
+cache is set with --cache and --cache.database. Nothing is written to the
+database: the committed tries are kept in memory and released block by block.`,
+			},
+		},
+	}
+)
+
+// replayResult is the timings of the re-execution of a block.
+type replayResult struct {
+	number  uint64
+	txs     int
+	gas     uint64
+	exec    time.Duration // Processing of the transactions, up to the state root
+	commit  time.Duration // Commit of the public and private state tries
+	failure error
+}
+
+func benchReplay(ctx *cli.Context) error {
+	stack := makeFullNode(ctx)
+	chain, chainDb := utils.MakeChain(ctx, stack)
+	defer chainDb.Close()
+	defer chain.Stop()
+
+	from, to := ctx.Uint64(benchFromFlag.Name), chain.CurrentBlock().NumberU64()
+	if ctx.IsSet(benchToFlag.Name) {
+		to = ctx.Uint64(benchToFlag.Name)
+	}
+	if from == 0 || from > to || to > chain.CurrentBlock().NumberU64() {
+		utils.Fatalf("Invalid range %d-%d, the chain is at block %d", from, to, chain.CurrentBlock().NumberU64())
+	}
+	segments := ctx.Int(benchParallelFlag.Name)
+	if segments < 1 {
+		segments = 1
+	}
+	if total := int(to - from + 1); segments > total {
+		segments = total
+	}
+	engine := replayEngine(chain, chainDb)
+
+	// Re-execute the segments concurrently, each from the state of its parent
+	var (
+		results = make([][]*replayResult, segments)
+		errs    = make([]error, segments)
+		size    = (to - from + 1) / uint64(segments)
+		wg      sync.WaitGroup
+		start   = time.Now()
+	)
+	for i := 0; i < segments; i++ {
+		first, last := from+uint64(i)*size, from+uint64(i+1)*size-1
+		if i == segments-1 {
+			last = to
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], errs[i] = replaySegment(chain, chainDb, engine, first, last)
+		}(i)
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	// Report the blocks in order, then the totals
+	var (
+		blocks, txs, failed int
+		gas                 uint64
+		exec, commit        time.Duration
+	)
+	fmt.Printf("%-10s %6s %12s %12s %12s %10s\n", "block", "txs", "gas", "exec", "commit", "Mgas/s")
+	for i, segment := range results {
+		for _, res := range segment {
+			if res.failure != nil {
+				fmt.Printf("%-10d %6d %12d failed: %v\n", res.number, res.txs, res.gas, res.failure)
+				failed++
+				continue
+			}
+			fmt.Printf("%-10d %6d %12d %12v %12v %10.2f\n", res.number, res.txs, res.gas,
+				common.PrettyDuration(res.exec), common.PrettyDuration(res.commit), mgasPerSecond(res.gas, res.exec))
+			blocks, txs, gas = blocks+1, txs+res.txs, gas+res.gas
+			exec, commit = exec+res.exec, commit+res.commit
+		}
+		if errs[i] != nil {
+			utils.Fatalf("Replay aborted: %v", errs[i])
+		}
+	}
+	fmt.Printf("\nRe-executed %d blocks, %d transactions, %d gas in %v (%d segments)\n", blocks, txs, gas, common.PrettyDuration(elapsed), segments)
+	if blocks > 0 {
+		fmt.Printf("Execution:   %v total, %v per block, %.2f Mgas/s\n", common.PrettyDuration(exec), common.PrettyDuration(exec/time.Duration(blocks)), mgasPerSecond(gas, exec))
+		fmt.Printf("Trie commit: %v total, %v per block\n", common.PrettyDuration(commit), common.PrettyDuration(commit/time.Duration(blocks)))
+		fmt.Printf("Throughput:  %.2f Mgas/s over the wall clock\n", mgasPerSecond(gas, elapsed))
+	}
+	if failed > 0 {
+		utils.Fatalf("%d blocks failed to re-execute", failed)
+	}
+	return nil
+}
+
+// replayEngine returns the consensus engine finalizing the re-executed blocks.
+// The chain made from the flags has no Istanbul engine, whose blocks carry no
+// rewards: one is created with a throwaway key, never sealing anything.
+func replayEngine(chain *core.BlockChain, chainDb ethdb.Database) consensus.Engine {
+	if chain.Config().Istanbul == nil {
+		return chain.Engine()
+	}
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		utils.Fatalf("Failed to generate key: %v", err)
+	}
+	config := eth.DefaultConfig.Istanbul
+	return istanbulBackend.New(&config, key, chainDb)
+}
+
+// replaySegment re-executes the blocks first to last on top of the state of
+// the parent of first. The tries are committed to an in-memory database, from
+// which the state of each block is released once its child is committed.
+func replaySegment(chain *core.BlockChain, chainDb ethdb.Database, engine consensus.Engine, first, last uint64) ([]*replayResult, error) {
+	var (
+		processor = core.NewStateProcessor(chain.Config(), chain, engine)
+		publicDb  = state.NewDatabase(chainDb)
+		privateDb = state.NewDatabase(chainDb)
+		results   []*replayResult
+	)
+	parent := chain.GetBlockByNumber(first - 1)
+	if parent == nil {
+		return nil, fmt.Errorf("block %d not found", first-1)
+	}
+	publicRoot, privateRoot := parent.Root(), core.GetPrivateStateRoot(chainDb, parent.Root())
+	for number := first; number <= last; number++ {
+		block := chain.GetBlockByNumber(number)
+		if block == nil {
+			return results, fmt.Errorf("block %d not found", number)
+		}
+		publicState, err := state.New(publicRoot, publicDb)
+		if err != nil {
+			return results, fmt.Errorf("state of block %d unavailable, the replay requires an archive node: %v", number-1, err)
+		}
+		privateState, err := state.New(privateRoot, privateDb)
+		if err != nil {
+			return results, fmt.Errorf("private state of block %d unavailable: %v", number-1, err)
+		}
+		res := &replayResult{number: number, txs: len(block.Transactions())}
+		results = append(results, res)
+
+		start := time.Now()
+		receipts, _, _, usedGas, err := processor.Process(block, publicState, privateState, vm.Config{})
+		if err == nil {
+			err = replayValidate(chain, block, publicState, receipts, usedGas)
+		}
+		res.gas, res.exec = usedGas, time.Since(start)
+		if err != nil {
+			// The following blocks can't be executed on a diverging state
+			res.failure = err
+			return results, nil
+		}
+		start = time.Now()
+		newPublic, err := publicState.Commit(chain.Config().IsEIP158(block.Number()))
+		if err != nil {
+			return results, err
+		}
+		newPrivate, err := privateState.Commit(chain.Config().IsEIP158(block.Number()))
+		if err != nil {
+			return results, err
+		}
+		res.commit = time.Since(start)
+
+		// Keep the state of the block in memory, release the one of its parent
+		publicDb.TrieDB().Reference(newPublic, common.Hash{})
+		privateDb.TrieDB().Reference(newPrivate, common.Hash{})
+		if number > first {
+			publicDb.TrieDB().Dereference(publicRoot)
+			privateDb.TrieDB().Dereference(privateRoot)
+		}
+		publicRoot, privateRoot = newPublic, newPrivate
+	}
+	return results, nil
+}
+
+// replayValidate checks the outcome of a re-executed block against its header.
+func replayValidate(chain *core.BlockChain, block *types.Block, statedb *state.StateDB, receipts types.Receipts, usedGas uint64) error {
+	parent := chain.GetBlock(block.ParentHash(), block.NumberU64()-1)
+	return chain.Validator().ValidateState(block, parent, statedb, receipts, usedGas)
+}
+
+// mgasPerSecond returns the rate of gas processed, in millions per second.
+func mgasPerSecond(gas uint64, elapsed time.Duration) float64 {
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(gas) / 1e6 / elapsed.Seconds()
+}
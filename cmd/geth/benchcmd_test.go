@@ -0,0 +1,123 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// unrewardedEngine finalizes the blocks without the rewards of the miners, for
+// their re-execution to diverge from the chain.
+type unrewardedEngine struct {
+	consensus.Engine
+}
+
+func (e unrewardedEngine) Finalize(chain consensus.ChainReader, header *types.Header, statedb *state.StateDB, txs []*types.Transaction, uncles []*types.Header, receipts []*types.Receipt) (*types.Block, error) {
+	header.Root = statedb.IntermediateRoot(chain.Config().IsEIP158(header.Number))
+	return types.NewBlock(header, txs, uncles, receipts), nil
+}
+
+// newReplayChain returns an archive chain of blocks each holding a transfer.
+func newReplayChain(t *testing.T, blocks int) (*core.BlockChain, ethdb.Database) {
+	var (
+		key, _  = crypto.GenerateKey()
+		sender  = crypto.PubkeyToAddress(key.PublicKey)
+		db      = ethdb.NewMemDatabase()
+		gspec   = &core.Genesis{Config: params.TestChainConfig, Alloc: core.GenesisAlloc{sender: {Balance: big.NewInt(1000000000)}}}
+		genesis = gspec.MustCommit(db)
+		signer  = types.NewEIP155Signer(gspec.Config.ChainID)
+	)
+	chain, _ := core.GenerateChain(gspec.Config, genesis, ethash.NewFaker(), db, blocks, func(i int, gen *core.BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(gen.TxNonce(sender), common.Address{0x01}, big.NewInt(1), params.TxGas, big.NewInt(1), nil), signer, key)
+		gen.AddTx(tx)
+	})
+	blockchain, err := core.NewBlockChain(db, &core.CacheConfig{Disabled: true}, gspec.Config, ethash.NewFaker(), vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	if _, err := blockchain.InsertChain(chain); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	return blockchain, db
+}
+
+// Tests that the blocks of a range are re-executed against their state roots,
+// and that the replay stops at the first diverging block.
+func TestReplaySegment(t *testing.T) {
+	chain, db := newReplayChain(t, 6)
+	defer chain.Stop()
+
+	for _, segment := range [][2]uint64{{1, 6}, {3, 4}} {
+		results, err := replaySegment(chain, db, chain.Engine(), segment[0], segment[1])
+		if err != nil {
+			t.Fatalf("segment %v: failed to replay: %v", segment, err)
+		}
+		if want := int(segment[1] - segment[0] + 1); len(results) != want {
+			t.Fatalf("segment %v: results mismatch: have %d, want %d", segment, len(results), want)
+		}
+		for i, res := range results {
+			if res.failure != nil {
+				t.Errorf("segment %v: block %d failed: %v", segment, res.number, res.failure)
+			}
+			if res.number != segment[0]+uint64(i) || res.txs != 1 || res.gas != params.TxGas {
+				t.Errorf("segment %v: result %d mismatch: block %d, %d txs, %d gas", segment, i, res.number, res.txs, res.gas)
+			}
+		}
+	}
+	// The blocks beyond the chain aren't found
+	if _, err := replaySegment(chain, db, chain.Engine(), 6, 7); err == nil {
+		t.Errorf("block beyond the chain replayed")
+	}
+	// A diverging block is reported, and stops the segment
+	results, err := replaySegment(chain, db, unrewardedEngine{chain.Engine()}, 2, 5)
+	if err != nil {
+		t.Fatalf("failed to replay: %v", err)
+	}
+	if len(results) != 1 || results[0].number != 2 || results[0].failure == nil {
+		t.Errorf("diverging block not reported: %d results", len(results))
+	}
+}
+
+func TestMgasPerSecond(t *testing.T) {
+	tests := []struct {
+		gas     uint64
+		elapsed time.Duration
+		want    float64
+	}{
+		{3000000, time.Second, 3},
+		{3000000, 500 * time.Millisecond, 6},
+		{3000000, 0, 0},
+	}
+	for i, test := range tests {
+		if have := mgasPerSecond(test.gas, test.elapsed); have != test.want {
+			t.Errorf("test %d: rate mismatch: have %v, want %v", i, have, test.want)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/bootstrap"
+	"github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params"
+	"gopkg.in/urfave/cli.v1"
+)
+
+// bootstrapNode initialises the data directory of a node that was never started
+// from the signed bundle published at the bootstrap URL: the genesis block, the
+// static node list and the permissioning configuration. Nodes already holding
+// a genesis block are left alone.
+func bootstrapNode(ctx *cli.Context) {
+	stack, _ := makeConfigNode(ctx)
+	if stack.DataDir() == "" {
+		utils.Fatalf("Bootstrapping requires a data directory")
+	}
+	chaindb, err := stack.OpenDatabase("chaindata", 0, 0)
+	if err != nil {
+		utils.Fatalf("Failed to open database: %v", err)
+	}
+	initialised := rawdb.ReadCanonicalHash(chaindb, 0) != (common.Hash{})
+	chaindb.Close()
+	if initialised {
+		log.Info("Data directory already initialised, skipping bootstrap")
+		return
+	}
+	signers, err := bootstrap.ParseSigners(ctx.GlobalString(utils.BootstrapSignersFlag.Name))
+	if err != nil {
+		utils.Fatalf("%v", err)
+	}
+	url := ctx.GlobalString(utils.BootstrapURLFlag.Name)
+	bundle, err := bootstrap.Fetch(url)
+	if err != nil {
+		utils.Fatalf("Failed to fetch bootstrap bundle from %s: %v", url, err)
+	}
+	if err := bundle.Verify(signers, ctx.GlobalInt(utils.BootstrapThresholdFlag.Name)); err != nil {
+		utils.Fatalf("Failed to verify bootstrap bundle: %v", err)
+	}
+	genesis, err := bundle.Genesis()
+	if err != nil {
+		utils.Fatalf("%v", err)
+	}
+	genesis.Config.IsQuorum = getIsQuorum(strings.NewReader(bundle.Files[bootstrap.GenesisFile]))
+
+	// Install the configuration files before the genesis, which marks the data
+	// directory as initialised
+	if err := os.MkdirAll(stack.InstanceDir(), 0700); err != nil {
+		utils.Fatalf("Failed to create instance directory: %v", err)
+	}
+	paths := map[string]string{
+		bootstrap.StaticNodesFile:      stack.ResolvePath(bootstrap.StaticNodesFile),
+		params.PERMISSIONED_CONFIG:     filepath.Join(stack.DataDir(), params.PERMISSIONED_CONFIG),
+		params.BLACKLIST_CONFIG:        filepath.Join(stack.DataDir(), params.BLACKLIST_CONFIG),
+		params.PERMISSION_MODEL_CONFIG: filepath.Join(stack.DataDir(), params.PERMISSION_MODEL_CONFIG),
+	}
+	for name, path := range paths {
+		if err := bundle.Install(name, path); err != nil {
+			utils.Fatalf("Failed to install %s: %v", name, err)
+		}
+	}
+	writeGenesis(stack, genesis)
+	log.Info("Bootstrapped data directory from signed bundle", "url", url, "files", len(bundle.Files))
+}
@@ -20,6 +20,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 	"runtime"
 	"strconv"
@@ -32,16 +33,23 @@ import (
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/eth"
 	"github.com/ethereum/go-ethereum/eth/downloader"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/private"
 	"github.com/ethereum/go-ethereum/trie"
 	"github.com/syndtr/goleveldb/leveldb/util"
 	"gopkg.in/urfave/cli.v1"
 )
 
 var (
+	sqlCheckpointFlag = cli.StringFlag{
+		Name:  "checkpoint",
+		Usage: "File recording the last block exported, to resume from",
+	}
 	initCommand = cli.Command{
 		Action:    utils.MigrateFlags(initGenesis),
 		Name:      "init",
@@ -57,6 +65,29 @@ This is a destructive action and changes the network in which you will be
 participating.
 
 It expects the genesis file as argument.`,
+	}
+	privateFromFlag = cli.StringFlag{
+		Name:  "privatefrom",
+		Usage: "Public key of the private transaction manager sending the private contracts",
+	}
+	distributePrivateGenesisCommand = cli.Command{
+		Action:    utils.MigrateFlags(distributePrivateGenesis),
+		Name:      "distribute-private-genesis",
+		Usage:     "Distribute the private genesis contracts to their parties",
+		ArgsUsage: "<contractsPath>",
+		Flags: []cli.Flag{
+			privateFromFlag,
+		},
+		Category: "BLOCKCHAIN COMMANDS",
+		Description: `
+The distribute-private-genesis command sends the private contracts of the genesis
+block to their parties through the private transaction manager configured by
+PRIVATE_CONFIG, and prints the "privateAlloc" section of the genesis referencing
+them by the hashes of their payloads.
+
+It expects as argument a JSON file mapping the addresses of the contracts to
+their "code", "storage", "nonce" and "privateFor" parties. The contracts aren't
+included in the genesis, only the parties can retrieve them on init.`,
 	}
 	importCommand = cli.Command{
 		Action:    utils.MigrateFlags(importChain),
@@ -70,6 +101,7 @@ It expects the genesis file as argument.`,
 			utils.GCModeFlag,
 			utils.CacheDatabaseFlag,
 			utils.CacheGCFlag,
+			utils.ImportPipelineFlag,
 		},
 		Category: "BLOCKCHAIN COMMANDS",
 		Description: `
@@ -96,6 +128,42 @@ Optional second and third arguments control the first and
 last block to write. In this mode, the file will be appended
 if already existing. If the file ends with .gz, the output will
 be gzipped.`,
+		Subcommands: []cli.Command{
+			{
+				Action:    utils.MigrateFlags(exportSQL),
+				Name:      "sql",
+				Usage:     "Export blocks, transactions, receipts and logs as Postgres statements",
+				ArgsUsage: "<filename|-> [<blockNumFirst> <blockNumLast>]",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.CacheFlag,
+					utils.SyncModeFlag,
+					utils.SQLExportPrivateFlag,
+					sqlCheckpointFlag,
+				},
+				Category: "BLOCKCHAIN COMMANDS",
+				Description: `
+    geth export sql <filename|-> [<blockNumFirst> <blockNumLast>]
+
+appends to the file, or writes to the standard output, the statements creating
+the tables missing and inserting the blocks, transactions, receipts and logs, to
+be piped into psql. The tables are documented in the schema written first.
+
+The blocks are exported in batches, each committed along with the checkpoint
+table recording the last block exported. Exporting a block replaces its rows,
+so that an interrupted export can be run again from any earlier block. Without
+a block range, the export resumes after the --checkpoint file, if any, and runs
+up to the current head.
+
+Private transactions are exported with the hash of their payload only, unless
+--sqlexport.private is set, exporting the payloads and the private receipts and
+logs of the local party.
+
+Use the --sqlexport flag of a running node to export the new blocks instead,
+to a Postgres database directly or to a file or pipe. Blocks reorganised out
+of the chain are deleted by hash and replaced by the canonical ones.`,
+			},
+		},
 	}
 	importPreimagesCommand = cli.Command{
 		Action:    utils.MigrateFlags(importPreimages),
@@ -213,6 +281,43 @@ func initGenesis(ctx *cli.Context) error {
 
 	// Open an initialise both full and light databases
 	stack := makeFullNode(ctx)
+	writeGenesis(stack, genesis)
+	return nil
+}
+
+// distributePrivateGenesis sends the private genesis contracts to their parties,
+// printing the references to them to declare in the genesis.
+func distributePrivateGenesis(ctx *cli.Context) error {
+	path := ctx.Args().First()
+	if len(path) == 0 {
+		utils.Fatalf("Must supply path to private contracts JSON file")
+	}
+	blob, err := ioutil.ReadFile(path)
+	if err != nil {
+		utils.Fatalf("Failed to read private contracts file: %v", err)
+	}
+	var contracts core.GenesisPrivateContracts
+	if err := json.Unmarshal(blob, &contracts); err != nil {
+		utils.Fatalf("Invalid private contracts file: %v", err)
+	}
+	if private.P == nil {
+		utils.Fatalf("No private transaction manager configured, set PRIVATE_CONFIG")
+	}
+	alloc, err := core.DistributeGenesisPrivateContracts(private.P, ctx.String(privateFromFlag.Name), contracts)
+	if err != nil {
+		utils.Fatalf("Failed to distribute private contracts: %v", err)
+	}
+	out, err := json.MarshalIndent(map[string]core.GenesisPrivateAlloc{"privateAlloc": alloc}, "", "  ")
+	if err != nil {
+		utils.Fatalf("Failed to encode private allocation: %v", err)
+	}
+	fmt.Println(string(out))
+	return nil
+}
+
+// writeGenesis writes the genesis block and state into both the full and light
+// databases of the node.
+func writeGenesis(stack *node.Node, genesis *core.Genesis) {
 	for _, name := range []string{"chaindata", "lightchaindata"} {
 		chaindb, err := stack.OpenDatabase(name, 0, 0)
 		if err != nil {
@@ -222,9 +327,9 @@ func initGenesis(ctx *cli.Context) error {
 		if err != nil {
 			utils.Fatalf("Failed to write genesis block: %v", err)
 		}
+		chaindb.Close()
 		log.Info("Successfully wrote genesis state", "database", name, "hash", hash)
 	}
-	return nil
 }
 
 func importChain(ctx *cli.Context) error {
@@ -353,6 +458,57 @@ func exportChain(ctx *cli.Context) error {
 	return nil
 }
 
+// exportSQL exports a range of blocks as Postgres statements.
+func exportSQL(ctx *cli.Context) error {
+	if len(ctx.Args()) < 1 {
+		utils.Fatalf("This command requires an argument.")
+	}
+	stack := makeFullNode(ctx)
+	chain, _ := utils.MakeChain(ctx, stack)
+	start := time.Now()
+
+	var out io.Writer = os.Stdout
+	if fn := ctx.Args().First(); fn != "-" {
+		fh, err := os.OpenFile(fn, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			utils.Fatalf("Export error: %v\n", err)
+		}
+		defer fh.Close()
+		out = fh
+	}
+	exporter := eth.NewSQLExporter(chain, out, ctx.GlobalBool(utils.SQLExportPrivateFlag.Name), ctx.String(sqlCheckpointFlag.Name))
+
+	first, last := uint64(0), chain.CurrentBlock().NumberU64()
+	if len(ctx.Args()) >= 3 {
+		var ferr, lerr error
+		first, ferr = strconv.ParseUint(ctx.Args().Get(1), 10, 64)
+		last, lerr = strconv.ParseUint(ctx.Args().Get(2), 10, 64)
+		if ferr != nil || lerr != nil {
+			utils.Fatalf("Export error in parsing parameters: block number not an integer\n")
+		}
+	} else {
+		number, ok, err := exporter.Checkpoint()
+		if err != nil {
+			utils.Fatalf("Export error: %v\n", err)
+		}
+		if ok {
+			first = number + 1
+		}
+	}
+	if first > last {
+		fmt.Fprintf(os.Stderr, "Nothing to export after block #%d\n", last)
+		return nil
+	}
+	if err := exporter.WriteSchema(); err != nil {
+		utils.Fatalf("Export error: %v\n", err)
+	}
+	if err := exporter.Export(first, last); err != nil {
+		utils.Fatalf("Export error: %v\n", err)
+	}
+	fmt.Fprintf(os.Stderr, "Exported blocks #%d to #%d in %v\n", first, last, time.Since(start))
+	return nil
+}
+
 // importPreimages imports preimage data from the specified file.
 func importPreimages(ctx *cli.Context) error {
 	if len(ctx.Args()) < 1 {
@@ -18,11 +18,13 @@ package main
 
 import (
 	"bufio"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"os"
 	"reflect"
+	"strings"
 	"time"
 	"unicode"
 
@@ -31,6 +33,7 @@ import (
 	"github.com/ethereum/go-ethereum/cmd/utils"
 	"github.com/ethereum/go-ethereum/dashboard"
 	"github.com/ethereum/go-ethereum/eth"
+	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/node"
 	"github.com/ethereum/go-ethereum/p2p/enode"
 	"github.com/ethereum/go-ethereum/params"
@@ -45,17 +48,164 @@ var (
 		Name:        "dumpconfig",
 		Usage:       "Show configuration values",
 		ArgsUsage:   "",
-		Flags:       append(append(nodeFlags, rpcFlags...), whisperFlags...),
+		Flags:       append(append(append(nodeFlags, rpcFlags...), whisperFlags...), effectiveConfigFlag),
 		Category:    "MISCELLANEOUS COMMANDS",
-		Description: `The dumpconfig command shows configuration values.`,
+		Description: `The dumpconfig command shows configuration values, or with --effective the full effective configuration as JSON with secrets redacted.`,
 	}
 
 	configFileFlag = cli.StringFlag{
 		Name:  "config",
 		Usage: "TOML configuration file",
 	}
+	effectiveConfigFlag = cli.BoolFlag{
+		Name:  "effective",
+		Usage: "Dump the full effective configuration as JSON, with secrets redacted",
+	}
 )
 
+// redacted replaces secrets in the exported configuration.
+const redacted = "<redacted>"
+
+// exportedFlags are the command line flags, besides the boolean ones, whose
+// values hold no secret and are exported. The values of the other flags, such
+// as the keys, passwords and the URLs possibly carrying credentials, are
+// redacted, including those of the flags added without being listed here.
+var exportedFlags = map[string]bool{
+	utils.DataDirFlag.Name:                 true,
+	utils.KeyStoreDirFlag.Name:             true,
+	utils.NetworkIdFlag.Name:               true,
+	utils.SyncModeFlag.Name:                true,
+	utils.GCModeFlag.Name:                  true,
+	utils.IdentityFlag.Name:                true,
+	utils.LightServFlag.Name:               true,
+	utils.LightPeersFlag.Name:              true,
+	utils.CacheFlag.Name:                   true,
+	utils.CacheDatabaseFlag.Name:           true,
+	utils.CacheGCFlag.Name:                 true,
+	utils.TxPoolPriceLimitFlag.Name:        true,
+	utils.TxPoolPriceBumpFlag.Name:         true,
+	utils.TxPoolAccountSlotsFlag.Name:      true,
+	utils.TxPoolGlobalSlotsFlag.Name:       true,
+	utils.TxPoolAccountQueueFlag.Name:      true,
+	utils.TxPoolGlobalQueueFlag.Name:       true,
+	utils.TxPoolLifetimeFlag.Name:          true,
+	utils.MinerGasTargetFlag.Name:          true,
+	utils.MinerGasLimitFlag.Name:           true,
+	utils.MinerGasPriceFlag.Name:           true,
+	utils.MinerEtherbaseFlag.Name:          true,
+	utils.RPCListenAddrFlag.Name:           true,
+	utils.RPCPortFlag.Name:                 true,
+	utils.RPCCORSDomainFlag.Name:           true,
+	utils.RPCVirtualHostsFlag.Name:         true,
+	utils.RPCApiFlag.Name:                  true,
+	utils.IPCPathFlag.Name:                 true,
+	utils.WSListenAddrFlag.Name:            true,
+	utils.WSPortFlag.Name:                  true,
+	utils.WSApiFlag.Name:                   true,
+	utils.WSAllowedOriginsFlag.Name:        true,
+	utils.MaxPeersFlag.Name:                true,
+	utils.MaxPendingPeersFlag.Name:         true,
+	utils.ListenPortFlag.Name:              true,
+	utils.BootnodesFlag.Name:               true,
+	utils.NATFlag.Name:                     true,
+	utils.NetrestrictFlag.Name:             true,
+	"verbosity":                            true,
+	utils.RaftBlockTimeFlag.Name:           true,
+	utils.RaftJoinExistingFlag.Name:        true,
+	utils.RaftPortFlag.Name:                true,
+	utils.IstanbulRequestTimeoutFlag.Name:  true,
+	utils.IstanbulBlockPeriodFlag.Name:     true,
+	utils.MetricsInfluxDBDatabaseFlag.Name: true,
+	configFileFlag.Name:                    true,
+}
+
+// exportedConfigFields are the configuration fields, or whole sections, which
+// hold no secret and are exported. The other fields, such as the keys, the
+// passwords and the URLs or DSNs possibly carrying credentials, are left out,
+// including those of the fields added without being listed here.
+var exportedConfigFields = map[string]bool{
+	"Eth.NetworkId":                 true,
+	"Eth.SyncMode":                  true,
+	"Eth.NoPruning":                 true,
+	"Eth.LightServ":                 true,
+	"Eth.LightPeers":                true,
+	"Eth.SkipBcVersionCheck":        true,
+	"Eth.DatabaseHandles":           true,
+	"Eth.DatabaseCache":             true,
+	"Eth.TrieCache":                 true,
+	"Eth.TrieTimeout":               true,
+	"Eth.Etherbase":                 true,
+	"Eth.MinerGasFloor":             true,
+	"Eth.MinerGasCeil":              true,
+	"Eth.MinerGasPrice":             true,
+	"Eth.MinerRecommit":             true,
+	"Eth.MinerNoverify":             true,
+	"Eth.Ethash":                    true,
+	"Eth.TxPool":                    true,
+	"Eth.GPO":                       true,
+	"Eth.EnablePreimageRecording":   true,
+	"Eth.EVMInterpreter":            true,
+	"Eth.EWASMInterpreter":          true,
+	"Eth.RaftMode":                  true,
+	"Eth.EnableNodePermission":      true,
+	"Eth.Istanbul":                  true,
+	"Eth.Gossip":                    true,
+	"Eth.Health":                    true,
+	"Eth.FinalityConfirmations":     true,
+	"Eth.RPCCallWorkers":            true,
+	"Eth.RPCCallCPUQuota":           true,
+	"Eth.RPCCacheSize":              true,
+	"Shh":                           true,
+	"Node.Name":                     true,
+	"Node.UserIdent":                true,
+	"Node.Version":                  true,
+	"Node.DataDir":                  true,
+	"Node.KeyStoreDir":              true,
+	"Node.UseLightweightKDF":        true,
+	"Node.NoUSB":                    true,
+	"Node.IPCPath":                  true,
+	"Node.HTTPHost":                 true,
+	"Node.HTTPPort":                 true,
+	"Node.HTTPCors":                 true,
+	"Node.HTTPVirtualHosts":         true,
+	"Node.HTTPModules":              true,
+	"Node.HTTPTimeouts":             true,
+	"Node.WSHost":                   true,
+	"Node.WSPort":                   true,
+	"Node.WSOrigins":                true,
+	"Node.WSModules":                true,
+	"Node.WSExposeAll":              true,
+	"Node.EnableNodePermission":     true,
+	"Node.RPCWorkers":               true,
+	"Node.SelfMonitor":              true,
+	"Node.P2P.MaxPeers":             true,
+	"Node.P2P.MaxPendingPeers":      true,
+	"Node.P2P.DialRatio":            true,
+	"Node.P2P.NoDiscovery":          true,
+	"Node.P2P.DiscoveryV5":          true,
+	"Node.P2P.Name":                 true,
+	"Node.P2P.BootstrapNodes":       true,
+	"Node.P2P.BootstrapNodesV5":     true,
+	"Node.P2P.StaticNodes":          true,
+	"Node.P2P.TrustedNodes":         true,
+	"Node.P2P.NetRestrict":          true,
+	"Node.P2P.ListenAddr":           true,
+	"Node.P2P.NoDial":               true,
+	"Node.P2P.EnableNodePermission": true,
+	"Node.P2P.MaxHandshakesPerIP":   true,
+	"Dashboard":                     true,
+}
+
+// exportedFlag returns whether the value of a command line flag is exported:
+// the boolean flags hold no secret, the others must be listed in exportedFlags.
+func exportedFlag(ctx *cli.Context, name string) bool {
+	if exportedFlags[name] {
+		return true
+	}
+	boolean, ok := ctx.GlobalGeneric(name).(interface{ IsBoolFlag() bool })
+	return ok && boolean.IsBoolFlag()
+}
+
 // These settings ensure that TOML keys use the same names as Go struct fields.
 var tomlSettings = toml.Config{
 	NormFieldName: func(rt reflect.Type, key string) string {
@@ -85,6 +235,15 @@ type gethConfig struct {
 	Dashboard dashboard.Config
 }
 
+// effectiveConfig is the full configuration a node runs with, as exported by
+// dumpconfig --effective and admin_exportConfiguration.
+type effectiveConfig struct {
+	Version    string                 `json:"version"`
+	ConfigFile string                 `json:"configFile,omitempty"`
+	Flags      map[string]string      `json:"flags"`  // Command line flags explicitly set
+	Config     map[string]interface{} `json:"config"` // Defaults overridden by the file and the flags
+}
+
 func loadConfig(file string, cfg *gethConfig) error {
 	f, err := os.Open(file)
 	if err != nil {
@@ -104,6 +263,7 @@ func defaultNodeConfig() node.Config {
 	cfg := node.DefaultConfig
 	cfg.Name = clientIdentifier
 	cfg.Version = params.VersionWithCommit(gitCommit)
+	cfg.GitCommit = gitCommit
 	cfg.HTTPModules = append(cfg.HTTPModules, "eth", "shh")
 	cfg.WSModules = append(cfg.WSModules, "eth", "shh")
 	cfg.IPCPath = "geth.ipc"
@@ -169,6 +329,13 @@ func makeFullNode(ctx *cli.Context) *node.Node {
 		utils.RegisterPermissionService(ctx, stack)
 	}
 
+	if ctx.GlobalBool(utils.PermissionedSyncFlag.Name) {
+		if !cfg.Node.EnableNodePermission {
+			utils.Fatalf("Option %q requires %q", utils.PermissionedSyncFlag.Name, utils.EnableNodePermissionFlag.Name)
+		}
+		utils.RegisterPermissionedSyncService(stack)
+	}
+
 	if ctx.GlobalBool(utils.RaftModeFlag.Name) {
 		RegisterRaftService(stack, ctx, cfg, ethChan)
 	}
@@ -197,12 +364,19 @@ func makeFullNode(ctx *cli.Context) *node.Node {
 	if cfg.Ethstats.URL != "" {
 		utils.RegisterEthStatsService(stack, cfg.Ethstats.URL)
 	}
+	stack.SetConfigurationExport(makeEffectiveConfig(ctx, cfg))
 	return stack
 }
 
 // dumpConfig is the dumpconfig command.
 func dumpConfig(ctx *cli.Context) error {
 	_, cfg := makeConfigNode(ctx)
+	if ctx.Bool(effectiveConfigFlag.Name) {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		return enc.Encode(makeEffectiveConfig(ctx, cfg))
+	}
 	comment := ""
 
 	if cfg.Eth.Genesis != nil {
@@ -219,6 +393,72 @@ func dumpConfig(ctx *cli.Context) error {
 	return nil
 }
 
+// makeEffectiveConfig assembles the effective configuration of a node with the
+// secrets redacted.
+func makeEffectiveConfig(ctx *cli.Context, cfg gethConfig) *effectiveConfig {
+	export := &effectiveConfig{
+		Version:    params.VersionWithCommit(gitCommit),
+		ConfigFile: ctx.GlobalString(configFileFlag.Name),
+		Flags:      make(map[string]string),
+		Config:     exportConfig(cfg),
+	}
+	for _, name := range ctx.GlobalFlagNames() {
+		if !ctx.GlobalIsSet(name) || name == effectiveConfigFlag.Name {
+			continue
+		}
+		if exportedFlag(ctx, name) {
+			export.Flags[name] = fmt.Sprint(ctx.GlobalGeneric(name))
+		} else {
+			export.Flags[name] = redacted
+		}
+	}
+	return export
+}
+
+// exportConfig converts the configuration to its JSON form and keeps only the
+// fields listed in exportedConfigFields.
+func exportConfig(cfg gethConfig) map[string]interface{} {
+	blob, err := json.Marshal(cfg)
+	if err != nil {
+		log.Warn("Failed to export configuration", "err", err)
+		return nil
+	}
+	fields := make(map[string]interface{})
+	if err := json.Unmarshal(blob, &fields); err != nil {
+		log.Warn("Failed to export configuration", "err", err)
+		return nil
+	}
+	return filterConfig("", fields)
+}
+
+// filterConfig drops the fields of a configuration section which are neither
+// listed in exportedConfigFields nor contain a listed field.
+func filterConfig(section string, fields map[string]interface{}) map[string]interface{} {
+	filtered := make(map[string]interface{})
+	for name, value := range fields {
+		path := section + name
+		if exportedConfigFields[path] {
+			filtered[name] = value
+			continue
+		}
+		if nested, ok := value.(map[string]interface{}); ok && exportedConfigSection(path) {
+			filtered[name] = filterConfig(path+".", nested)
+		}
+	}
+	return filtered
+}
+
+// exportedConfigSection returns whether a configuration section contains any
+// of the fields listed in exportedConfigFields.
+func exportedConfigSection(section string) bool {
+	for path := range exportedConfigFields {
+		if strings.HasPrefix(path, section+".") {
+			return true
+		}
+	}
+	return false
+}
+
 func RegisterRaftService(stack *node.Node, ctx *cli.Context, cfg gethConfig, ethChan <-chan *eth.Ethereum) {
 	blockTimeMillis := ctx.GlobalInt(utils.RaftBlockTimeFlag.Name)
 	datadir := ctx.GlobalString(utils.DataDirFlag.Name)
@@ -227,8 +467,7 @@ func RegisterRaftService(stack *node.Node, ctx *cli.Context, cfg gethConfig, eth
 	raftPort := uint16(ctx.GlobalInt(utils.RaftPortFlag.Name))
 
 	if err := stack.Register(func(ctx *node.ServiceContext) (node.Service, error) {
-		privkey := cfg.Node.NodeKey()
-		strId := enode.PubkeyToIDV4(&privkey.PublicKey).String()
+		strId := enode.PubkeyToIDV4(ctx.NodeSigner().PublicKey()).String()
 		blockTimeNanos := time.Duration(blockTimeMillis) * time.Millisecond
 		peers := cfg.Node.StaticNodes()
 
@@ -51,13 +51,14 @@ See https://github.com/ethereum/go-ethereum/wiki/JavaScript-Console.`,
 		Name:      "attach",
 		Usage:     "Start an interactive JavaScript environment (connect to node)",
 		ArgsUsage: "[endpoint]",
-		Flags:     append(consoleFlags, utils.DataDirFlag),
+		Flags:     append(consoleFlags, utils.DataDirFlag, utils.AttachEndpointsFlag, utils.AttachProbeFlag),
 		Category:  "CONSOLE COMMANDS",
 		Description: `
 The Geth console is an interactive shell for the JavaScript runtime environment
 which exposes a node admin interface as well as the Ðapp JavaScript API.
 See https://github.com/ethereum/go-ethereum/wiki/JavaScript-Console.
-This command allows to open a console on a running geth node.`,
+This command allows to open a console on a running geth node, or to fail over
+between several with --endpoints, such as the nodes of a pair.`,
 	}
 
 	javascriptCommand = cli.Command{
@@ -130,7 +131,7 @@ func remoteConsole(ctx *cli.Context) error {
 		}
 		endpoint = fmt.Sprintf("%s/geth.ipc", path)
 	}
-	client, err := dialRPC(endpoint)
+	client, err := dialConsole(ctx, endpoint)
 	if err != nil {
 		utils.Fatalf("Unable to attach to remote geth: %v", err)
 	}
@@ -159,6 +160,32 @@ func remoteConsole(ctx *cli.Context) error {
 	return nil
 }
 
+// Quorum
+//
+// dialConsole returns the client of the console attached to an endpoint, or
+// failing over between the endpoints given by --endpoints.
+func dialConsole(ctx *cli.Context, endpoint string) (console.Client, error) {
+	endpoints := ctx.GlobalString(utils.AttachEndpointsFlag.Name)
+	if endpoints == "" {
+		client, err := dialRPC(endpoint)
+		if err != nil {
+			return nil, err
+		}
+		return client, nil
+	}
+	var urls []string
+	for _, url := range strings.Split(endpoints, ",") {
+		if url = strings.TrimSpace(url); url != "" {
+			urls = append(urls, url)
+		}
+	}
+	client, err := utils.NewFailoverClient(urls, ctx.GlobalDuration(utils.AttachProbeFlag.Name))
+	if err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
 // dialRPC returns a RPC client which connects to the given endpoint.
 // The check for empty endpoint implements the defaulting logic
 // for "geth attach" and "geth monitor" with no argument.
@@ -0,0 +1,68 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/core/logindex"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var indexCommand = cli.Command{
+	Name:     "index",
+	Usage:    "Manage the log index",
+	Category: "BLOCKCHAIN COMMANDS",
+	Description: `
+The log index lists the blocks holding the logs of each contract, to serve
+eth_getLogs without scanning the bloom bits. It is maintained at import time
+when the node runs with --logindex.`,
+	Subcommands: []cli.Command{
+		{
+			Name:      "rebuild",
+			Usage:     "Index the logs of the whole canonical chain",
+			ArgsUsage: " ",
+			Action:    utils.MigrateFlags(rebuildIndex),
+			Category:  "BLOCKCHAIN COMMANDS",
+			Flags: []cli.Flag{
+				utils.DataDirFlag,
+				utils.CacheFlag,
+			},
+			Description: `
+    geth index rebuild
+
+indexes the logs of every block of the canonical chain, including the blocks
+imported before the index was enabled. The node must be stopped meanwhile.`,
+		},
+	},
+}
+
+func rebuildIndex(ctx *cli.Context) error {
+	stack, _ := makeConfigNode(ctx)
+	chaindb := utils.MakeChainDatabase(ctx, stack)
+	defer chaindb.Close()
+
+	hash := rawdb.ReadHeadBlockHash(chaindb)
+	number := rawdb.ReadHeaderNumber(chaindb, hash)
+	if number == nil {
+		utils.Fatalf("Failed to read the head block of the chain")
+	}
+	if err := logindex.Rebuild(chaindb, *number); err != nil {
+		utils.Fatalf("Failed to rebuild the log index: %v", err)
+	}
+	return nil
+}
@@ -84,18 +84,40 @@ var (
 		utils.TxPoolAccountQueueFlag,
 		utils.TxPoolGlobalQueueFlag,
 		utils.TxPoolLifetimeFlag,
+		utils.TxPoolPrioritySendersFlag,
+		utils.TxPoolPriorityRecipientsFlag,
+		utils.TxPoolPriorityTypesFlag,
+		utils.TxPoolPrioritySlotsFlag,
+		utils.TxPoolPriorityQueueFlag,
+		utils.TxPoolAnchorSlotsFlag,
+		utils.TxPoolGlobalAnchorSlotsFlag,
+		utils.TxPoolHeapLimitFlag,
 		utils.SyncModeFlag,
 		utils.GCModeFlag,
 		utils.LightServFlag,
 		utils.LightPeersFlag,
 		utils.LightKDFFlag,
+		utils.KeyStoreScryptNFlag,
+		utils.KeyStoreScryptPFlag,
+		utils.KeyStoreArgon2Flag,
+		utils.KeyStoreArgon2TimeFlag,
+		utils.KeyStoreArgon2MemoryFlag,
+		utils.KeyStoreArgon2ThreadsFlag,
 		utils.CacheFlag,
 		utils.CacheDatabaseFlag,
 		utils.CacheGCFlag,
+		utils.SnapshotFlag,
+		utils.LogIndexFlag,
+		utils.StateSizeFlag,
+		utils.StateRangeRateFlag,
+		utils.ImportPipelineFlag,
 		utils.TrieCacheGenFlag,
 		utils.ListenPortFlag,
 		utils.MaxPeersFlag,
 		utils.MaxPendingPeersFlag,
+		utils.InboundRateLimitFlag,
+		utils.MaxHandshakesPerIPFlag,
+		utils.HandshakePuzzleFlag,
 		utils.MiningEnabledFlag,
 		utils.MinerThreadsFlag,
 		utils.MinerLegacyThreadsFlag,
@@ -111,18 +133,42 @@ var (
 		utils.MinerLegacyExtraDataFlag,
 		utils.MinerRecommitIntervalFlag,
 		utils.MinerNoVerfiyFlag,
+		utils.MinerBuilderFlag,
+		utils.MinerBuilderBudgetFlag,
 		utils.NATFlag,
 		utils.NoDiscoverFlag,
 		utils.DiscoveryV5Flag,
 		utils.NetrestrictFlag,
+		utils.P2PProxyProtocolFlag,
+		utils.P2PProxyTrustedFlag,
+		utils.LatencyAwareFlag,
+		utils.CrossRegionRTTFlag,
+		utils.CrossRegionPeersFlag,
+		utils.PeerCAFlag,
+		utils.PeerCertFlag,
+		utils.GossipFlag,
+		utils.GossipBlockFanoutFlag,
+		utils.GossipTxFanoutFlag,
+		utils.GossipWantDelayFlag,
+		utils.GossipPrivateValidatorFlag,
+		utils.GossipPrivateObserverFlag,
+		utils.GossipPrivateRelaysFlag,
+		utils.CompressionFlag,
+		utils.CompressionThresholdFlag,
 		utils.NodeKeyFileFlag,
 		utils.NodeKeyHexFlag,
+		utils.NodeKeyKMSFlag,
+		utils.NodeKeyKMSBudgetFlag,
+		utils.NodeKeyKMSFailoverFlag,
 		utils.DeveloperFlag,
 		utils.DeveloperPeriodFlag,
 		utils.TestnetFlag,
 		utils.RinkebyFlag,
 		utils.OttomanFlag,
 		utils.VMEnableDebugFlag,
+		utils.PreimagesFlag,
+		utils.PreimagesMaxSizeFlag,
+		utils.PreimagesRetentionFlag,
 		utils.NetworkIdFlag,
 		utils.RPCCORSDomainFlag,
 		utils.RPCVirtualHostsFlag,
@@ -137,6 +183,36 @@ var (
 		configFileFlag,
 		// Quorum
 		utils.EnableNodePermissionFlag,
+		utils.PermissionedSyncFlag,
+		utils.GasAccountingFlag,
+		utils.GasAccountingExportDirFlag,
+		utils.HealthBlockStallFlag,
+		utils.HealthMaxRoundFlag,
+		utils.HealthLeaderChangesFlag,
+		utils.HealthChurnWindowFlag,
+		utils.HealthRaftLagFlag,
+		utils.WebhooksFlag,
+		utils.IntegritySamplesFlag,
+		utils.DryRunConfigFlag,
+		utils.SQLExportFlag,
+		utils.SQLExportPrivateFlag,
+		utils.PTMPushAddrFlag,
+		utils.PTMPushSecretFlag,
+		utils.PTMOffloadStoreFlag,
+		utils.PTMOffloadThresholdFlag,
+		utils.PTMCacheSizeFlag,
+		utils.PTMCacheTTLFlag,
+		utils.PTMCacheEncryptFlag,
+		utils.FinalityConfirmationsFlag,
+		utils.HistoryRetentionFlag,
+		utils.HistoryProtectFlag,
+		utils.StandbyRoleFlag,
+		utils.StandbyPeerFlag,
+		utils.StandbyEscrowKeyFlag,
+		utils.StandbyLeaseFlag,
+		utils.BootstrapURLFlag,
+		utils.BootstrapSignersFlag,
+		utils.BootstrapThresholdFlag,
 		utils.RaftModeFlag,
 		utils.RaftBlockTimeFlag,
 		utils.RaftJoinExistingFlag,
@@ -145,6 +221,17 @@ var (
 		utils.EmitCheckpointsFlag,
 		utils.IstanbulRequestTimeoutFlag,
 		utils.IstanbulBlockPeriodFlag,
+		utils.IstanbulShadowFlag,
+		utils.IstanbulRefuseUnsafeFlag,
+		utils.IstanbulRelayFlag,
+		utils.IstanbulSigningPolicyFlag,
+		utils.IstanbulCheckpointSinkFlag,
+		utils.IstanbulTimeAttestationFlag,
+		utils.IstanbulNTPServerFlag,
+		utils.IstanbulVanityFlag,
+		utils.IstanbulVanityPatternFlag,
+		utils.IstanbulVanityIdentifiersFlag,
+		utils.IstanbulProposalValidationTimeoutFlag,
 		utils.PluginSettingsFlag,
 		utils.PluginSkipVerifyFlag,
 		utils.PluginLocalVerifyFlag,
@@ -157,11 +244,33 @@ var (
 		utils.RPCListenAddrFlag,
 		utils.RPCPortFlag,
 		utils.RPCApiFlag,
+		utils.RPCCacheFlag,
+		utils.RPCCallWorkersFlag,
+		utils.RPCCallQuotaFlag,
 		utils.WSEnabledFlag,
 		utils.WSListenAddrFlag,
 		utils.WSPortFlag,
 		utils.WSApiFlag,
 		utils.WSAllowedOriginsFlag,
+		utils.RPCJWTSecretFlag,
+		utils.RPCJWTPublicKeyFlag,
+		utils.RPCJWTJWKSFlag,
+		utils.RPCJWTClaimFlag,
+		utils.RPCAPIKeysFlag,
+		utils.RPCMethodRulesFlag,
+		utils.RPCListenersFlag,
+		utils.RPCProxyProtocolFlag,
+		utils.RPCProxyTrustedFlag,
+		utils.RPCWorkersFlag,
+		utils.RPCWorkerQueueFlag,
+		utils.RPCMethodLimitsFlag,
+		utils.SelfMonitorIntervalFlag,
+		utils.SelfMonitorGoroutinesFlag,
+		utils.SelfMonitorFilesFlag,
+		utils.SelfMonitorHeapFlag,
+		utils.SelfMonitorGCPauseFlag,
+		utils.SelfMonitorPauseRPCFlag,
+		utils.SelfMonitorRejectSubsFlag,
 		utils.IPCDisabledFlag,
 		utils.IPCPathFlag,
 	}
@@ -191,6 +300,7 @@ func init() {
 	app.Commands = []cli.Command{
 		// See chaincmd.go:
 		initCommand,
+		distributePrivateGenesisCommand,
 		importCommand,
 		exportCommand,
 		importPreimagesCommand,
@@ -198,6 +308,14 @@ func init() {
 		copydbCommand,
 		removedbCommand,
 		dumpCommand,
+		// See benchcmd.go:
+		benchCommand,
+		// See indexcmd.go:
+		indexCommand,
+		// See statecmd.go:
+		stateCommand,
+		// See raftcmd.go:
+		raftCommand,
 		// See monitorcmd.go:
 		monitorCommand,
 		// See accountcmd.go:
@@ -284,6 +402,9 @@ func geth(ctx *cli.Context) error {
 		return errors.New("the PRIVATE_CONFIG environment variable must be specified for Quorum")
 	}
 
+	if ctx.GlobalIsSet(utils.BootstrapURLFlag.Name) {
+		bootstrapNode(ctx)
+	}
 	node := makeFullNode(ctx)
 	startNode(ctx, node)
 
@@ -370,7 +491,7 @@ func startNode(ctx *cli.Context, stack *node.Node) {
 	}
 
 	// Start auxiliary services if enabled
-	if ctx.GlobalBool(utils.MiningEnabledFlag.Name) || ctx.GlobalBool(utils.DeveloperFlag.Name) {
+	if ctx.GlobalBool(utils.MiningEnabledFlag.Name) || ctx.GlobalBool(utils.DeveloperFlag.Name) || persistedMining(ctx, stack) {
 		// Mining only makes sense if a full Ethereum node is running
 		if ctx.GlobalString(utils.SyncModeFlag.Name) == "light" {
 			utils.Fatalf("Light clients do not support mining")
@@ -396,3 +517,20 @@ func startNode(ctx *cli.Context, stack *node.Node) {
 	}
 
 }
+
+// persistedMining reports whether mining was switched on at runtime through
+// admin_setNodeMode, in which case it is resumed after a restart.
+func persistedMining(ctx *cli.Context, stack *node.Node) bool {
+	if ctx.GlobalBool(utils.RaftModeFlag.Name) || ctx.GlobalString(utils.SyncModeFlag.Name) == "light" {
+		return false
+	}
+	var ethereum *eth.Ethereum
+	if err := stack.Service(&ethereum); err != nil {
+		return false
+	}
+	if mode := ethereum.PersistedNodeMode(); mode != nil && mode.Mining {
+		log.Info("Resuming mining as set through admin_setNodeMode")
+		return true
+	}
+	return false
+}
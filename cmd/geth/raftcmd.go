@@ -0,0 +1,131 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"crypto/ecdsa"
+	"fmt"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/console"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/raft"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	raftForceNewClusterFlag = cli.BoolFlag{
+		Name:  "force-new-cluster",
+		Usage: "Acknowledge that the other members are permanently removed from the cluster",
+	}
+	raftRecoverReasonFlag = cli.StringFlag{
+		Name:  "reason",
+		Usage: "Justification of the recovery, kept in the audit record",
+	}
+	raftCommand = cli.Command{
+		Name:     "raft",
+		Usage:    "Manage the raft consensus state",
+		Category: "BLOCKCHAIN COMMANDS",
+		Description: `
+The raft commands operate on the raft log and snapshots of a stopped node.`,
+		Subcommands: []cli.Command{
+			{
+				Name:      "recover",
+				Usage:     "Rebuild a single-node raft cluster after the loss of its majority",
+				ArgsUsage: " ",
+				Action:    utils.MigrateFlags(raftRecover),
+				Category:  "BLOCKCHAIN COMMANDS",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					raftForceNewClusterFlag,
+					raftRecoverReasonFlag,
+				},
+				Description: `
+    geth raft recover --force-new-cluster --reason <text>
+
+rebuilds the raft cluster with this node as its only member, from the entries
+the node applied, when the majority of the cluster is permanently lost. The
+other members are permanently removed: they can only rejoin as new peers with
+raft.addPeer. The committed entries the node didn't apply yet are kept, while
+the uncommitted ones are discarded.
+
+The node must be stopped. The recovery is described and must be confirmed by
+typing the raft ID of the node. The previous raft state is moved aside and the
+recovery is appended to the raft-recovery.log audit record of the datadir.`,
+			},
+		},
+	}
+)
+
+func raftRecover(ctx *cli.Context) error {
+	if !ctx.Bool(raftForceNewClusterFlag.Name) {
+		utils.Fatalf("Recovering removes all the other members of the cluster, confirm with --%s", raftForceNewClusterFlag.Name)
+	}
+	reason := strings.TrimSpace(ctx.String(raftRecoverReasonFlag.Name))
+	if reason == "" {
+		utils.Fatalf("The reason of the recovery must be given with --%s", raftRecoverReasonFlag.Name)
+	}
+	// Check the raft log before loading the node key, which is created if missing
+	datadir := ctx.GlobalString(utils.DataDirFlag.Name)
+	if _, err := os.Stat(datadir + "/raft-wal"); err != nil {
+		utils.Fatalf("No raft log to recover from: %v", err)
+	}
+	_, cfg := makeConfigNode(ctx)
+	var pub *ecdsa.PublicKey
+	if cfg.Node.NodeKeyKMS != nil {
+		signer, _, err := cfg.Node.NodeKeySigner()
+		if err != nil {
+			utils.Fatalf("Failed to connect the node key in the KMS: %v", err)
+		}
+		pub = signer.PublicKey()
+	} else {
+		pub = &cfg.Node.NodeKey().PublicKey
+	}
+	self, err := enode.RaftHexID(fmt.Sprintf("%x", crypto.FromECDSAPub(pub)[1:]))
+	if err != nil {
+		utils.Fatalf("Invalid node key: %v", err)
+	}
+	operator := "unknown"
+	if u, err := user.Current(); err == nil {
+		operator = u.Username
+	}
+	confirm := func(record *raft.RecoveryRecord) bool {
+		fmt.Printf("Raft ID of this node:       %d\n", record.RaftId)
+		fmt.Printf("Members to remove:          %v\n", record.DroppedMembers)
+		fmt.Printf("Last applied entry:         %d\n", record.SnapshotIndex)
+		fmt.Printf("Last committed entry:       %d\n", record.CommitIndex)
+		fmt.Printf("Uncommitted entries lost:   %d\n", record.DiscardedEntries)
+		fmt.Printf("Head block:                 %x\n", record.HeadBlockHash)
+
+		input, err := console.Stdin.PromptInput("Type the raft ID of this node to force a new cluster: ")
+		if err != nil {
+			utils.Fatalf("%v", err)
+		}
+		return strings.TrimSpace(input) == strconv.Itoa(int(record.RaftId))
+	}
+	record, err := raft.ForceNewCluster(datadir, self, operator, reason, confirm)
+	if err != nil {
+		utils.Fatalf("Failed to recover the raft cluster: %v", err)
+	}
+	fmt.Printf("Recovered a single-node raft cluster, previous state moved to %s\n", strings.Join(record.Backups, ", "))
+	return nil
+}
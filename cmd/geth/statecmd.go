@@ -0,0 +1,211 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	stateContractsFlag = cli.StringFlag{
+		Name:  "contracts",
+		Usage: "Comma separated addresses of the contracts to export",
+	}
+	stateBlockFlag = cli.Uint64Flag{
+		Name:  "block",
+		Usage: "Number of the block whose state is exported (default = head of the chain)",
+	}
+	stateOutputFlag = cli.StringFlag{
+		Name:  "output",
+		Usage: "File the export is written to (default = standard output)",
+	}
+	stateCommand = cli.Command{
+		Name:     "state",
+		Usage:    "Export and verify the state of individual contracts",
+		Category: "BLOCKCHAIN COMMANDS",
+		Description: `
+The state commands move the state of selected contracts out of a stopped node,
+for the migration of individual applications to a new network.`,
+		Subcommands: []cli.Command{
+			{
+				Name:      "export",
+				Usage:     "Export the state of contracts at a block",
+				ArgsUsage: " ",
+				Action:    utils.MigrateFlags(stateExport),
+				Category:  "BLOCKCHAIN COMMANDS",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.CacheFlag,
+					stateContractsFlag,
+					stateBlockFlag,
+					stateOutputFlag,
+				},
+				Description: `
+    geth state export --contracts <address>[,<address>...] [--block <number>] [--output <file>]
+
+exports the balance, nonce, code and full storage of the contracts, as JSON,
+along with the header of the block and the Merkle proof of every contract
+against its state root. The private state of the contracts the node is party
+to is exported too, with proofs against the private state root of the block.
+
+The state of the block must be available, as it is on an archive node or for
+the recent blocks. The storage slots are keyed by their hashes, the slots
+themselves being included when their preimages were recorded.`,
+			},
+			{
+				Name:      "verify",
+				Usage:     "Verify a state export",
+				ArgsUsage: "<file>",
+				Action:    utils.MigrateFlags(stateVerify),
+				Category:  "BLOCKCHAIN COMMANDS",
+				Description: `
+    geth state verify <file>
+
+checks that the contracts of an export are proven against the state roots it
+records, and that their code and storage match their account. The public state
+root is checked against the exported header, whose hash must then be checked
+against the source network. The private state root can't be checked, as it is
+only known to the parties of the private contracts.`,
+			},
+		},
+	}
+)
+
+// stateExportFile is the content of a state export.
+type stateExportFile struct {
+	Header           *types.Header           `json:"header"`
+	PrivateRoot      *common.Hash            `json:"privateRoot,omitempty"`
+	Contracts        []*state.ContractExport `json:"contracts"`
+	PrivateContracts []*state.ContractExport `json:"privateContracts,omitempty"`
+}
+
+func stateExport(ctx *cli.Context) error {
+	var addrs []common.Address
+	for _, addr := range strings.Split(ctx.String(stateContractsFlag.Name), ",") {
+		if addr = strings.TrimSpace(addr); !common.IsHexAddress(addr) {
+			utils.Fatalf("Invalid contract address %q", addr)
+		}
+		addrs = append(addrs, common.HexToAddress(addr))
+	}
+	stack, _ := makeConfigNode(ctx)
+	chaindb := utils.MakeChainDatabase(ctx, stack)
+	defer chaindb.Close()
+
+	// Resolve the block, the head of the chain by default
+	hash := rawdb.ReadHeadBlockHash(chaindb)
+	if ctx.IsSet(stateBlockFlag.Name) {
+		hash = rawdb.ReadCanonicalHash(chaindb, ctx.Uint64(stateBlockFlag.Name))
+	}
+	number := rawdb.ReadHeaderNumber(chaindb, hash)
+	if number == nil {
+		utils.Fatalf("Block not found")
+	}
+	header := rawdb.ReadHeader(chaindb, hash, *number)
+	if header == nil {
+		utils.Fatalf("Block %d not found", *number)
+	}
+	export := &stateExportFile{Header: header, Contracts: []*state.ContractExport{}}
+
+	// Export the public and private states of the contracts
+	statedb, err := state.New(header.Root, state.NewDatabase(chaindb))
+	if err != nil {
+		utils.Fatalf("State of block %d unavailable: %v", *number, err)
+	}
+	var privateState *state.StateDB
+	if root := core.GetPrivateStateRoot(chaindb, header.Root); root != (common.Hash{}) {
+		if privateState, err = state.New(root, state.NewDatabase(chaindb)); err != nil {
+			utils.Fatalf("Private state of block %d unavailable: %v", *number, err)
+		}
+		export.PrivateRoot = &root
+	}
+	for _, addr := range addrs {
+		found := false
+		contract, err := statedb.ExportContract(addr)
+		if err != nil {
+			utils.Fatalf("Failed to export contract %x: %v", addr, err)
+		}
+		if contract != nil {
+			export.Contracts, found = append(export.Contracts, contract), true
+		}
+		if privateState != nil {
+			contract, err := privateState.ExportContract(addr)
+			if err != nil {
+				utils.Fatalf("Failed to export private contract %x: %v", addr, err)
+			}
+			if contract != nil {
+				export.PrivateContracts, found = append(export.PrivateContracts, contract), true
+			}
+		}
+		if !found {
+			utils.Fatalf("Contract %x not found at block %d", addr, *number)
+		}
+	}
+	out, err := json.MarshalIndent(export, "", "  ")
+	if err != nil {
+		return err
+	}
+	if output := ctx.String(stateOutputFlag.Name); output != "" {
+		return ioutil.WriteFile(output, append(out, '\n'), 0600)
+	}
+	_, err = fmt.Fprintln(os.Stdout, string(out))
+	return err
+}
+
+func stateVerify(ctx *cli.Context) error {
+	if len(ctx.Args()) != 1 {
+		utils.Fatalf("This command requires an argument.")
+	}
+	blob, err := ioutil.ReadFile(ctx.Args().First())
+	if err != nil {
+		utils.Fatalf("Failed to read the export: %v", err)
+	}
+	export := new(stateExportFile)
+	if err := json.Unmarshal(blob, export); err != nil {
+		utils.Fatalf("Invalid export: %v", err)
+	}
+	if export.Header == nil {
+		utils.Fatalf("Invalid export: header missing")
+	}
+	for _, contract := range export.Contracts {
+		if err := state.VerifyContractExport(export.Header.Root, contract); err != nil {
+			utils.Fatalf("Contract %x: %v", contract.Address, err)
+		}
+	}
+	if len(export.PrivateContracts) > 0 && export.PrivateRoot == nil {
+		utils.Fatalf("Invalid export: private state root missing")
+	}
+	for _, contract := range export.PrivateContracts {
+		if err := state.VerifyContractExport(*export.PrivateRoot, contract); err != nil {
+			utils.Fatalf("Private contract %x: %v", contract.Address, err)
+		}
+	}
+	fmt.Printf("Verified %d public and %d private contracts at block %d, hash %x\n",
+		len(export.Contracts), len(export.PrivateContracts), export.Header.Number, export.Header.Hash())
+	return nil
+}
@@ -126,6 +126,14 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.TxPoolAccountQueueFlag,
 			utils.TxPoolGlobalQueueFlag,
 			utils.TxPoolLifetimeFlag,
+			utils.TxPoolPrioritySendersFlag,
+			utils.TxPoolPriorityRecipientsFlag,
+			utils.TxPoolPriorityTypesFlag,
+			utils.TxPoolPrioritySlotsFlag,
+			utils.TxPoolPriorityQueueFlag,
+			utils.TxPoolAnchorSlotsFlag,
+			utils.TxPoolGlobalAnchorSlotsFlag,
+			utils.TxPoolHeapLimitFlag,
 		},
 	},
 	{
@@ -134,6 +142,11 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.CacheFlag,
 			utils.CacheDatabaseFlag,
 			utils.CacheGCFlag,
+			utils.SnapshotFlag,
+			utils.LogIndexFlag,
+			utils.StateSizeFlag,
+			utils.StateRangeRateFlag,
+			utils.ImportPipelineFlag,
 			utils.TrieCacheGenFlag,
 		},
 	},
@@ -141,6 +154,36 @@ var AppHelpFlagGroups = []flagGroup{
 		Name: "QUORUM",
 		Flags: []cli.Flag{
 			utils.EnableNodePermissionFlag,
+			utils.PermissionedSyncFlag,
+			utils.GasAccountingFlag,
+			utils.GasAccountingExportDirFlag,
+			utils.HealthBlockStallFlag,
+			utils.HealthMaxRoundFlag,
+			utils.HealthLeaderChangesFlag,
+			utils.HealthChurnWindowFlag,
+			utils.HealthRaftLagFlag,
+			utils.WebhooksFlag,
+			utils.IntegritySamplesFlag,
+			utils.DryRunConfigFlag,
+			utils.SQLExportFlag,
+			utils.SQLExportPrivateFlag,
+			utils.PTMPushAddrFlag,
+			utils.PTMPushSecretFlag,
+			utils.PTMOffloadStoreFlag,
+			utils.PTMOffloadThresholdFlag,
+			utils.PTMCacheSizeFlag,
+			utils.PTMCacheTTLFlag,
+			utils.PTMCacheEncryptFlag,
+			utils.FinalityConfirmationsFlag,
+			utils.HistoryRetentionFlag,
+			utils.HistoryProtectFlag,
+			utils.StandbyRoleFlag,
+			utils.StandbyPeerFlag,
+			utils.StandbyEscrowKeyFlag,
+			utils.StandbyLeaseFlag,
+			utils.BootstrapURLFlag,
+			utils.BootstrapSignersFlag,
+			utils.BootstrapThresholdFlag,
 			utils.PluginSettingsFlag,
 			utils.PluginSkipVerifyFlag,
 			utils.PluginLocalVerifyFlag,
@@ -162,6 +205,12 @@ var AppHelpFlagGroups = []flagGroup{
 		Flags: []cli.Flag{
 			utils.UnlockedAccountFlag,
 			utils.PasswordFileFlag,
+			utils.KeyStoreScryptNFlag,
+			utils.KeyStoreScryptPFlag,
+			utils.KeyStoreArgon2Flag,
+			utils.KeyStoreArgon2TimeFlag,
+			utils.KeyStoreArgon2MemoryFlag,
+			utils.KeyStoreArgon2ThreadsFlag,
 		},
 	},
 	{
@@ -171,6 +220,9 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.RPCListenAddrFlag,
 			utils.RPCPortFlag,
 			utils.RPCApiFlag,
+			utils.RPCCacheFlag,
+			utils.RPCCallWorkersFlag,
+			utils.RPCCallQuotaFlag,
 			utils.WSEnabledFlag,
 			utils.WSListenAddrFlag,
 			utils.WSPortFlag,
@@ -180,6 +232,25 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.IPCPathFlag,
 			utils.RPCCORSDomainFlag,
 			utils.RPCVirtualHostsFlag,
+			utils.RPCJWTSecretFlag,
+			utils.RPCJWTPublicKeyFlag,
+			utils.RPCJWTJWKSFlag,
+			utils.RPCJWTClaimFlag,
+			utils.RPCAPIKeysFlag,
+			utils.RPCMethodRulesFlag,
+			utils.RPCListenersFlag,
+			utils.RPCProxyProtocolFlag,
+			utils.RPCProxyTrustedFlag,
+			utils.RPCWorkersFlag,
+			utils.RPCWorkerQueueFlag,
+			utils.RPCMethodLimitsFlag,
+			utils.SelfMonitorIntervalFlag,
+			utils.SelfMonitorGoroutinesFlag,
+			utils.SelfMonitorFilesFlag,
+			utils.SelfMonitorHeapFlag,
+			utils.SelfMonitorGCPauseFlag,
+			utils.SelfMonitorPauseRPCFlag,
+			utils.SelfMonitorRejectSubsFlag,
 			utils.JSpathFlag,
 			utils.ExecFlag,
 			utils.PreloadJSFlag,
@@ -194,12 +265,34 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.ListenPortFlag,
 			utils.MaxPeersFlag,
 			utils.MaxPendingPeersFlag,
+			utils.InboundRateLimitFlag,
+			utils.MaxHandshakesPerIPFlag,
+			utils.HandshakePuzzleFlag,
 			utils.NATFlag,
 			utils.NoDiscoverFlag,
 			utils.DiscoveryV5Flag,
 			utils.NetrestrictFlag,
+			utils.P2PProxyProtocolFlag,
+			utils.P2PProxyTrustedFlag,
+			utils.LatencyAwareFlag,
+			utils.CrossRegionRTTFlag,
+			utils.CrossRegionPeersFlag,
+			utils.PeerCAFlag,
+			utils.PeerCertFlag,
+			utils.GossipFlag,
+			utils.GossipBlockFanoutFlag,
+			utils.GossipTxFanoutFlag,
+			utils.GossipWantDelayFlag,
+			utils.GossipPrivateValidatorFlag,
+			utils.GossipPrivateObserverFlag,
+			utils.GossipPrivateRelaysFlag,
+			utils.CompressionFlag,
+			utils.CompressionThresholdFlag,
 			utils.NodeKeyFileFlag,
 			utils.NodeKeyHexFlag,
+			utils.NodeKeyKMSFlag,
+			utils.NodeKeyKMSBudgetFlag,
+			utils.NodeKeyKMSFailoverFlag,
 		},
 	},
 	{
@@ -215,6 +308,8 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.MinerExtraDataFlag,
 			utils.MinerRecommitIntervalFlag,
 			utils.MinerNoVerfiyFlag,
+			utils.MinerBuilderFlag,
+			utils.MinerBuilderBudgetFlag,
 		},
 	},
 	{
@@ -228,6 +323,9 @@ var AppHelpFlagGroups = []flagGroup{
 		Name: "VIRTUAL MACHINE",
 		Flags: []cli.Flag{
 			utils.VMEnableDebugFlag,
+			utils.PreimagesFlag,
+			utils.PreimagesMaxSizeFlag,
+			utils.PreimagesRetentionFlag,
 			utils.EVMInterpreterFlag,
 			utils.EWASMInterpreterFlag,
 		},
@@ -269,6 +367,17 @@ var AppHelpFlagGroups = []flagGroup{
 		Flags: []cli.Flag{
 			utils.IstanbulRequestTimeoutFlag,
 			utils.IstanbulBlockPeriodFlag,
+			utils.IstanbulShadowFlag,
+			utils.IstanbulRefuseUnsafeFlag,
+			utils.IstanbulRelayFlag,
+			utils.IstanbulSigningPolicyFlag,
+			utils.IstanbulCheckpointSinkFlag,
+			utils.IstanbulTimeAttestationFlag,
+			utils.IstanbulNTPServerFlag,
+			utils.IstanbulVanityFlag,
+			utils.IstanbulVanityPatternFlag,
+			utils.IstanbulVanityIdentifiersFlag,
+			utils.IstanbulProposalValidationTimeoutFlag,
 		},
 	},
 	{
@@ -0,0 +1,288 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// failoverProbeTimeout is the time an endpoint has to answer a health probe.
+const failoverProbeTimeout = 5 * time.Second
+
+var errNoEndpoints = errors.New("no RPC endpoint given")
+
+// FailoverClient is a JSON-RPC client of several endpoints, such as the nodes
+// of a pair, keeping the operational scripts working through their rolling
+// restarts. The calls go to a single endpoint until it becomes unreachable,
+// upon which the client fails over to the next reachable endpoint. The failed
+// call is only sent again if it never reached the endpoint, its connection
+// refused, or if its method only reads the node: a transaction sent when the
+// connection broke may have been received, and must not be sent twice.
+type FailoverClient struct {
+	endpoints []string
+	dial      func(endpoint string) (*rpc.Client, error)
+
+	lock    sync.Mutex
+	current int         // Index of the endpoint in use
+	client  *rpc.Client // Client of the endpoint in use, nil if none is reachable
+
+	quit chan struct{}
+}
+
+// NewFailoverClient connects to the first reachable endpoint, in order of
+// preference. The endpoint in use is probed every interval to fail over before
+// a call fails, or only on the failed calls if zero.
+func NewFailoverClient(endpoints []string, probe time.Duration) (*FailoverClient, error) {
+	return newFailoverClient(endpoints, probe, rpc.Dial)
+}
+
+func newFailoverClient(endpoints []string, probe time.Duration, dial func(string) (*rpc.Client, error)) (*FailoverClient, error) {
+	if len(endpoints) == 0 {
+		return nil, errNoEndpoints
+	}
+	c := &FailoverClient{
+		endpoints: endpoints,
+		dial:      dial,
+		current:   len(endpoints) - 1, // The first endpoint is tried first
+		quit:      make(chan struct{}),
+	}
+	if _, err := c.failover(nil); err != nil {
+		return nil, err
+	}
+	if probe > 0 {
+		go c.loop(probe)
+	}
+	return c, nil
+}
+
+// Endpoint returns the endpoint in use.
+func (c *FailoverClient) Endpoint() string {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.endpoints[c.current]
+}
+
+// Close stops the health probes and closes the connection to the endpoint in
+// use.
+func (c *FailoverClient) Close() {
+	close(c.quit)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.client != nil {
+		c.client.Close()
+		c.client = nil
+	}
+}
+
+// active returns the client of the endpoint in use, failing over if none is.
+func (c *FailoverClient) active() (*rpc.Client, error) {
+	c.lock.Lock()
+	client := c.client
+	c.lock.Unlock()
+
+	if client == nil {
+		return c.failover(nil)
+	}
+	return client, nil
+}
+
+// failover drops the client of a failed endpoint, and connects to the next
+// reachable endpoint, returning its client. If another call failed over
+// already, the client of the new endpoint is returned as is.
+func (c *FailoverClient) failover(failed *rpc.Client) (*rpc.Client, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.client != nil && c.client != failed {
+		return c.client, nil
+	}
+	if c.client != nil {
+		c.client.Close()
+		c.client = nil
+	}
+	var errs []string
+	for i := 1; i <= len(c.endpoints); i++ {
+		next := (c.current + i) % len(c.endpoints)
+
+		client, err := c.dial(c.endpoints[next])
+		if err == nil {
+			if err = probeEndpoint(client); err != nil {
+				client.Close()
+			}
+		}
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", c.endpoints[next], err))
+			continue
+		}
+		if failed != nil {
+			log.Warn("Failed over to another RPC endpoint", "endpoint", c.endpoints[next], "failed", c.endpoints[c.current])
+		}
+		c.current, c.client = next, client
+		return client, nil
+	}
+	return nil, fmt.Errorf("no RPC endpoint reachable: %s", strings.Join(errs, "; "))
+}
+
+// loop probes the endpoint in use every interval, failing over if unhealthy.
+func (c *FailoverClient) loop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-c.quit:
+			return
+		}
+		client, err := c.active()
+		if err != nil {
+			continue
+		}
+		if err := probeEndpoint(client); err != nil {
+			log.Debug("RPC endpoint failed its health probe", "endpoint", c.Endpoint(), "err", err)
+			c.failover(client)
+		}
+	}
+}
+
+// Call performs a JSON-RPC call on the endpoint in use.
+func (c *FailoverClient) Call(result interface{}, method string, args ...interface{}) error {
+	return c.CallContext(context.Background(), result, method, args...)
+}
+
+// CallContext performs a JSON-RPC call on the endpoint in use. If the endpoint
+// didn't answer, the client fails over to the next reachable endpoint, and
+// sends the call again if it never reached the failed endpoint or only reads.
+func (c *FailoverClient) CallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+	client, err := c.active()
+	if err != nil {
+		return err
+	}
+	err = client.CallContext(ctx, result, method, args...)
+	if !unreachable(err) || ctx.Err() != nil {
+		return err
+	}
+	next, ferr := c.failover(client)
+	if ferr != nil {
+		return ferr
+	}
+	if !notSent(err) && !readMethod(method) {
+		return err
+	}
+	return next.CallContext(ctx, result, method, args...)
+}
+
+// SupportedModules returns the RPC modules of the endpoint in use.
+func (c *FailoverClient) SupportedModules() (map[string]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), failoverProbeTimeout)
+	defer cancel()
+
+	var result map[string]string
+	err := c.CallContext(ctx, &result, "rpc_modules")
+	return result, err
+}
+
+// probeEndpoint checks an endpoint answers the calls, even with an error.
+func probeEndpoint(client *rpc.Client) error {
+	ctx, cancel := context.WithTimeout(context.Background(), failoverProbeTimeout)
+	defer cancel()
+
+	var version string
+	if err := client.CallContext(ctx, &version, "web3_clientVersion"); unreachable(err) {
+		return err
+	}
+	return nil
+}
+
+// failoverReadMethods are the methods only reading the node, safe to send again
+// to another endpoint, besides the getters.
+var failoverReadMethods = map[string]bool{
+	"eth_call":           true,
+	"eth_estimateGas":    true,
+	"eth_blockNumber":    true,
+	"eth_chainId":        true,
+	"eth_syncing":        true,
+	"eth_gasPrice":       true,
+	"eth_accounts":       true,
+	"net_version":        true,
+	"net_listening":      true,
+	"net_peerCount":      true,
+	"web3_clientVersion": true,
+	"rpc_modules":        true,
+	"txpool_content":     true,
+	"txpool_inspect":     true,
+	"txpool_status":      true,
+	"admin_nodeInfo":     true,
+	"admin_peers":        true,
+	"raft_role":          true,
+	"raft_leader":        true,
+	"raft_cluster":       true,
+}
+
+// readMethod returns whether a method only reads the node: a getter, such as
+// eth_getBalance, or one of failoverReadMethods. Polling a filter consumes its
+// changes, so it isn't one.
+func readMethod(method string) bool {
+	if failoverReadMethods[method] {
+		return true
+	}
+	if method == "eth_getFilterChanges" {
+		return false
+	}
+	i := strings.IndexByte(method, '_')
+	return i > 0 && strings.HasPrefix(method[i+1:], "get")
+}
+
+// notSent returns whether the error of a call means the call never reached the
+// endpoint, the connection to it refused or closed before the call was sent.
+func notSent(err error) bool {
+	if err == rpc.ErrClientQuit {
+		return true
+	}
+	if uerr, ok := err.(*url.Error); ok {
+		err = uerr.Err
+	}
+	if operr, ok := err.(*net.OpError); ok {
+		return operr.Op == "dial"
+	}
+	return false
+}
+
+// unreachable returns whether the error of a call means the endpoint didn't
+// answer it, as opposed to having answered with an error or a result not
+// decoding.
+func unreachable(err error) bool {
+	switch err.(type) {
+	case nil, rpc.Error, *json.UnmarshalTypeError, *json.SyntaxError, *json.InvalidUnmarshalError:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,112 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+type FailoverTestService struct {
+	name string
+}
+
+func (s *FailoverTestService) Name() string {
+	return s.name
+}
+
+func (s *FailoverTestService) Fail() error {
+	return fmt.Errorf("failed on %s", s.name)
+}
+
+// Tests that the failover client sends the calls to the first reachable
+// endpoint, failing over to the next one when it becomes unreachable, but not
+// on the errors the endpoint answers with.
+func TestFailoverClient(t *testing.T) {
+	servers := make(map[string]*rpc.Server)
+	for _, name := range []string{"a", "b", "c"} {
+		servers[name] = rpc.NewServer()
+		if err := servers[name].RegisterName("test", &FailoverTestService{name}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	dial := func(endpoint string) (*rpc.Client, error) {
+		server, ok := servers[endpoint]
+		if !ok {
+			return nil, fmt.Errorf("unknown endpoint %s", endpoint)
+		}
+		return rpc.DialInProc(server), nil
+	}
+	if _, err := newFailoverClient([]string{"x", "y"}, 0, dial); err == nil {
+		t.Fatalf("client of unreachable endpoints created")
+	}
+	client, err := newFailoverClient([]string{"x", "a", "b", "c"}, 0, dial)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	call := func(want string) {
+		t.Helper()
+		var name string
+		if err := client.Call(&name, "test_name"); err != nil {
+			t.Fatalf("call failed: %v", err)
+		}
+		if name != want || client.Endpoint() != want {
+			t.Fatalf("endpoint mismatch: have %s (%s), want %s", name, client.Endpoint(), want)
+		}
+	}
+	call("a")
+
+	// The errors answered by the endpoint are returned as is
+	if err := client.Call(nil, "test_fail"); err == nil || err.Error() != "failed on a" {
+		t.Fatalf("answered error mismatch: have %v, want failed on a", err)
+	}
+	call("a")
+
+	// Once the endpoint is down, the calls go to the next reachable one, the
+	// failed call sent again as it only reads
+	servers["a"].Stop()
+	var modules map[string]string
+	if err := client.Call(&modules, "rpc_modules"); err != nil || modules["test"] == "" {
+		t.Fatalf("read call not sent again: %v, %v", err, modules)
+	}
+	call("b")
+
+	// The other calls may have been received, and aren't sent again
+	servers["b"].Stop()
+	if err := client.Call(nil, "test_name"); err == nil {
+		t.Fatalf("call sent again after the endpoint failed")
+	}
+	call("c")
+
+	servers["c"].Stop()
+	if err := client.Call(nil, "test_name"); err == nil {
+		t.Fatalf("call succeeded without reachable endpoint")
+	}
+}
+
+// Tests that the calls are only deemed safe to send again if they never reached
+// the endpoint or only read the node.
+func TestFailoverResend(t *testing.T) {
+	for method, want := range map[string]bool{
+		"eth_getBalance":         true,
+		"eth_call":               true,
+		"eth_getFilterChanges":   false,
+		"eth_sendRawTransaction": false,
+		"personal_unlockAccount": false,
+		"getter":                 false,
+	} {
+		if have := readMethod(method); have != want {
+			t.Errorf("%s: read method mismatch: have %v, want %v", method, have, want)
+		}
+	}
+	refused := &url.Error{Op: "Post", URL: "http://localhost:1", Err: &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}}
+	broken := &url.Error{Op: "Post", URL: "http://localhost:1", Err: &net.OpError{Op: "read", Net: "tcp", Err: errors.New("connection reset")}}
+	if !notSent(refused) || notSent(broken) || notSent(errors.New("EOF")) {
+		t.Errorf("sent calls mismatch: refused %v, broken %v", notSent(refused), notSent(broken))
+	}
+}
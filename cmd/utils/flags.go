@@ -31,6 +31,7 @@ import (
 	"strings"
 
 	"github.com/ethereum/go-ethereum/permission"
+	"github.com/ethereum/go-ethereum/permsync"
 	"github.com/ethereum/go-ethereum/plugin"
 
 	"time"
@@ -42,6 +43,7 @@ import (
 	"github.com/ethereum/go-ethereum/consensus"
 	"github.com/ethereum/go-ethereum/consensus/clique"
 	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/consensus/istanbul"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/vm"
@@ -52,6 +54,7 @@ import (
 	"github.com/ethereum/go-ethereum/eth/gasprice"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/ethstats"
+	"github.com/ethereum/go-ethereum/kms"
 	"github.com/ethereum/go-ethereum/les"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/metrics"
@@ -63,6 +66,7 @@ import (
 	"github.com/ethereum/go-ethereum/p2p/nat"
 	"github.com/ethereum/go-ethereum/p2p/netutil"
 	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
 	whisper "github.com/ethereum/go-ethereum/whisper/whisperv6"
 	"gopkg.in/urfave/cli.v1"
 )
@@ -193,6 +197,34 @@ var (
 		Name:  "lightkdf",
 		Usage: "Reduce key-derivation RAM & CPU usage at some expense of KDF strength",
 	}
+	// Quorum
+	KeyStoreScryptNFlag = cli.IntFlag{
+		Name:  "keystore.scrypt.n",
+		Usage: "Scrypt N parameter encrypting the keys, a power of two (default = 262144, 4096 with --lightkdf)",
+	}
+	KeyStoreScryptPFlag = cli.IntFlag{
+		Name:  "keystore.scrypt.p",
+		Usage: "Scrypt P parameter encrypting the keys (default = 1, 6 with --lightkdf)",
+	}
+	KeyStoreArgon2Flag = cli.BoolFlag{
+		Name:  "keystore.argon2",
+		Usage: "Encrypt the keys with Argon2id instead of scrypt",
+	}
+	KeyStoreArgon2TimeFlag = cli.IntFlag{
+		Name:  "keystore.argon2.time",
+		Usage: "Argon2id number of passes over the memory",
+		Value: keystore.StandardArgon2Time,
+	}
+	KeyStoreArgon2MemoryFlag = cli.IntFlag{
+		Name:  "keystore.argon2.memory",
+		Usage: "Argon2id memory in KiB",
+		Value: keystore.StandardArgon2Memory,
+	}
+	KeyStoreArgon2ThreadsFlag = cli.IntFlag{
+		Name:  "keystore.argon2.threads",
+		Usage: "Argon2id degree of parallelism",
+		Value: keystore.StandardArgon2Threads,
+	}
 	// Dashboard settings
 	DashboardEnabledFlag = cli.BoolFlag{
 		Name:  metrics.DashboardEnabledFlag,
@@ -297,6 +329,42 @@ var (
 		Usage: "Maximum amount of time non-executable transaction are queued",
 		Value: eth.DefaultConfig.TxPool.Lifetime,
 	}
+	TxPoolPrioritySendersFlag = cli.StringFlag{
+		Name:  "txpool.prioritysenders",
+		Usage: "Comma separated accounts whose transactions are served ahead of bulk traffic",
+	}
+	TxPoolPriorityRecipientsFlag = cli.StringFlag{
+		Name:  "txpool.priorityrecipients",
+		Usage: "Comma separated contracts whose invocations are served ahead of bulk traffic (e.g. permissioning)",
+	}
+	TxPoolPriorityTypesFlag = cli.StringFlag{
+		Name:  "txpool.prioritytypes",
+		Usage: "Comma separated transaction types served ahead of bulk traffic (transfer, call, create, private)",
+	}
+	TxPoolPrioritySlotsFlag = cli.Uint64Flag{
+		Name:  "txpool.priorityslots",
+		Usage: "Maximum number of executable transaction slots for priority traffic",
+		Value: 1024,
+	}
+	TxPoolPriorityQueueFlag = cli.Uint64Flag{
+		Name:  "txpool.priorityqueue",
+		Usage: "Maximum number of non-executable transaction slots for priority traffic",
+		Value: 256,
+	}
+	TxPoolAnchorSlotsFlag = cli.Uint64Flag{
+		Name:  "txpool.anchorslots",
+		Usage: "Maximum number of document anchoring transactions per account",
+		Value: eth.DefaultConfig.TxPool.AnchorSlots,
+	}
+	TxPoolGlobalAnchorSlotsFlag = cli.Uint64Flag{
+		Name:  "txpool.globalanchorslots",
+		Usage: "Maximum number of document anchoring transactions for all accounts",
+		Value: eth.DefaultConfig.TxPool.GlobalAnchorSlots,
+	}
+	TxPoolHeapLimitFlag = cli.Uint64Flag{
+		Name:  "txpool.heaplimit",
+		Usage: "Heap usage in megabytes the slot limits are scaled down towards, shedding transactions (0 = fixed limits)",
+	}
 	// Performance tuning settings
 	CacheFlag = cli.IntFlag{
 		Name:  "cache",
@@ -313,6 +381,27 @@ var (
 		Usage: "Percentage of cache memory allowance to use for trie pruning",
 		Value: 25,
 	}
+	SnapshotFlag = cli.BoolFlag{
+		Name:  "snapshot",
+		Usage: "Generate flat snapshots of the public and private states, to read them without trie traversal",
+	}
+	LogIndexFlag = cli.BoolFlag{
+		Name:  "logindex",
+		Usage: "Index the blocks holding the logs of each contract, to serve eth_getLogs without scanning the blooms",
+	}
+	StateSizeFlag = cli.BoolFlag{
+		Name:  "statesize",
+		Usage: "Count the storage slots, code size and storage trie bytes of each contract of the canonical chain, for debug_stateSize",
+	}
+	StateRangeRateFlag = cli.IntFlag{
+		Name:  "staterange.rate",
+		Usage: "Trie entries per second read by debug_accountRange and debug_storageRange (0 = unlimited)",
+		Value: eth.DefaultConfig.StateRangeRate,
+	}
+	ImportPipelineFlag = cli.IntFlag{
+		Name:  "import.pipeline",
+		Usage: "Number of blocks executed ahead of their database write when importing chain segments (0 = sequential)",
+	}
 	TrieCacheGenFlag = cli.IntFlag{
 		Name:  "trie-cache-gens",
 		Usage: "Number of trie node generations to keep in memory",
@@ -389,6 +478,15 @@ var (
 		Name:  "miner.noverify",
 		Usage: "Disable remote sealing verification",
 	}
+	MinerBuilderFlag = cli.StringFlag{
+		Name:  "miner.builder",
+		Usage: "JSON-RPC endpoint of an external block builder ordering the transactions of the blocks produced",
+	}
+	MinerBuilderBudgetFlag = cli.DurationFlag{
+		Name:  "miner.builderbudget",
+		Usage: "Time the block builder is given to order the transactions of a block before falling back to the default order",
+		Value: eth.DefaultConfig.MinerBuilderBudget,
+	}
 	// Account settings
 	UnlockedAccountFlag = cli.StringFlag{
 		Name:  "unlock",
@@ -405,6 +503,20 @@ var (
 		Name:  "vmdebug",
 		Usage: "Record information useful for VM and contract debugging",
 	}
+	PreimagesFlag = cli.BoolFlag{
+		Name:  "preimages",
+		Usage: "Store the SHA3 preimages computed by public and private contracts (debug_preimage)",
+	}
+	PreimagesMaxSizeFlag = cli.IntFlag{
+		Name:  "preimages.maxsize",
+		Usage: "Maximum size in bytes of a stored SHA3 preimage (0 = unlimited)",
+		Value: eth.DefaultConfig.PreimageMaxSize,
+	}
+	PreimagesRetentionFlag = cli.Uint64Flag{
+		Name:  "preimages.retention",
+		Usage: "Number of blocks stored SHA3 preimages are retained for (0 = forever)",
+		Value: eth.DefaultConfig.PreimageRetention,
+	}
 	// Logging and debug settings
 	EthStatsURLFlag = cli.StringFlag{
 		Name:  "ethstats",
@@ -448,6 +560,21 @@ var (
 		Usage: "API's offered over the HTTP-RPC interface",
 		Value: "",
 	}
+	RPCCacheFlag = cli.IntFlag{
+		Name:  "rpccache",
+		Usage: "Megabytes of memory caching the responses to immutable RPC queries (0 = disabled)",
+		Value: eth.DefaultConfig.RPCCacheSize,
+	}
+	RPCCallWorkersFlag = cli.IntFlag{
+		Name:  "rpccallworkers",
+		Usage: "Threads executing eth_call and eth_estimateGas apart from block processing (0 = disabled)",
+		Value: eth.DefaultConfig.RPCCallWorkers,
+	}
+	RPCCallQuotaFlag = cli.DurationFlag{
+		Name:  "rpccallquota",
+		Usage: "CPU time after which an eth_call or eth_estimateGas executed by the call workers is aborted (0 = unlimited)",
+		Value: eth.DefaultConfig.RPCCallCPUQuota,
+	}
 	IPCDisabledFlag = cli.BoolFlag{
 		Name:  "ipcdisable",
 		Usage: "Disable the IPC-RPC server",
@@ -480,6 +607,86 @@ var (
 		Usage: "Origins from which to accept websockets requests",
 		Value: "",
 	}
+	// Quorum
+	RPCJWTSecretFlag = cli.StringFlag{
+		Name:  "rpc.jwt.secret",
+		Usage: "File holding the shared secret of the HS256 bearer tokens authenticating HTTP and WS-RPC requests",
+	}
+	RPCJWTPublicKeyFlag = cli.StringFlag{
+		Name:  "rpc.jwt.pubkey",
+		Usage: "PEM file of the RSA public key of the RS256 bearer tokens authenticating HTTP and WS-RPC requests",
+	}
+	RPCJWTJWKSFlag = cli.StringFlag{
+		Name:  "rpc.jwt.jwks",
+		Usage: "URL of the JSON web key set of the RS256 bearer tokens authenticating HTTP and WS-RPC requests",
+	}
+	RPCJWTClaimFlag = cli.StringFlag{
+		Name:  "rpc.jwt.claim",
+		Usage: "Bearer token claim listing the RPC namespaces the token may call (unrestricted if empty)",
+	}
+	RPCAPIKeysFlag = cli.StringFlag{
+		Name:  "rpc.apikeys",
+		Usage: "JSON file of the API keys required on HTTP and WS-RPC requests, with their daily quotas",
+	}
+	RPCMethodRulesFlag = cli.StringFlag{
+		Name:  "rpc.methodrules",
+		Usage: "JSON file of the HTTP and WS-RPC methods or namespaces disabled, aliased to other methods or deprecated",
+	}
+	RPCListenersFlag = cli.IntFlag{
+		Name:  "rpc.listeners",
+		Usage: "Number of accept loops of the HTTP and WS-RPC endpoints, each with its own SO_REUSEPORT socket where supported",
+		Value: 1,
+	}
+	RPCProxyProtocolFlag = cli.BoolFlag{
+		Name:  "rpc.proxyprotocol",
+		Usage: "Read the PROXY protocol v2 header of the HTTP and WS-RPC connections, for the real client IP behind layer-4 load balancers",
+	}
+	RPCProxyTrustedFlag = cli.StringFlag{
+		Name:  "rpc.proxytrusted",
+		Usage: "IP networks (CIDR masks) of the proxies sending the PROXY protocol header on HTTP and WS-RPC connections, required with --rpc.proxyprotocol",
+	}
+	RPCWorkersFlag = cli.IntFlag{
+		Name:  "rpc.workers",
+		Usage: "Number of HTTP and WS-RPC calls executed concurrently per endpoint (0 = unbounded)",
+	}
+	RPCWorkerQueueFlag = cli.IntFlag{
+		Name:  "rpc.workerqueue",
+		Usage: "Number of HTTP and WS-RPC calls waiting for a worker before new ones are rejected",
+		Value: 1024,
+	}
+	RPCMethodLimitsFlag = cli.StringFlag{
+		Name:  "rpc.methodlimits",
+		Usage: "Comma separated concurrent call limits of RPC methods or namespaces (e.g. debug_*=2,eth_call=16)",
+	}
+	SelfMonitorIntervalFlag = cli.DurationFlag{
+		Name:  "selfmonitor.interval",
+		Usage: "Interval between two samples of the goroutines, file descriptors and heap of the node",
+		Value: 10 * time.Second,
+	}
+	SelfMonitorGoroutinesFlag = cli.IntFlag{
+		Name:  "selfmonitor.goroutines",
+		Usage: "Number of goroutines from which the node protects itself (0 = unlimited)",
+	}
+	SelfMonitorFilesFlag = cli.IntFlag{
+		Name:  "selfmonitor.fds",
+		Usage: "Number of open file descriptors from which the node protects itself (0 = unlimited)",
+	}
+	SelfMonitorHeapFlag = cli.Uint64Flag{
+		Name:  "selfmonitor.heap",
+		Usage: "Megabytes of heap from which the node protects itself (0 = unlimited)",
+	}
+	SelfMonitorGCPauseFlag = cli.DurationFlag{
+		Name:  "selfmonitor.gcpause",
+		Usage: "Garbage collection pause from which the node protects itself (0 = unlimited)",
+	}
+	SelfMonitorPauseRPCFlag = cli.BoolFlag{
+		Name:  "selfmonitor.pauserpc",
+		Usage: "Reject the HTTP and WS-RPC calls while the node protects itself",
+	}
+	SelfMonitorRejectSubsFlag = cli.BoolFlag{
+		Name:  "selfmonitor.rejectsubs",
+		Usage: "Reject the new WS-RPC subscriptions while the node protects itself",
+	}
 	ExecFlag = cli.StringFlag{
 		Name:  "exec",
 		Usage: "Execute JavaScript statement",
@@ -488,6 +695,15 @@ var (
 		Name:  "preload",
 		Usage: "Comma separated list of JavaScript files to preload into the console",
 	}
+	AttachEndpointsFlag = cli.StringFlag{
+		Name:  "endpoints",
+		Usage: "Comma separated RPC endpoints to attach to in order of preference, failing over to the next one when unreachable",
+	}
+	AttachProbeFlag = cli.DurationFlag{
+		Name:  "endpoints.probe",
+		Usage: "Interval of the health probes of the endpoint attached to, failing over before a call fails (0 = on failed calls only)",
+		Value: 10 * time.Second,
+	}
 
 	// Network Settings
 	MaxPeersFlag = cli.IntFlag{
@@ -500,6 +716,18 @@ var (
 		Usage: "Maximum number of pending connection attempts (defaults used if set to 0)",
 		Value: 0,
 	}
+	InboundRateLimitFlag = cli.IntFlag{
+		Name:  "p2p.inboundrate",
+		Usage: "Maximum number of inbound connections accepted from an IP per minute (0 = unlimited)",
+	}
+	MaxHandshakesPerIPFlag = cli.IntFlag{
+		Name:  "p2p.maxhandshakes",
+		Usage: "Maximum number of handshakes run concurrently with an IP (0 = unlimited)",
+	}
+	HandshakePuzzleFlag = cli.UintFlag{
+		Name:  "p2p.puzzle",
+		Usage: "Difficulty in bits of the proof of work solved by the connecting nodes before the handshake, the same on all the nodes (0 = disabled)",
+	}
 	ListenPortFlag = cli.IntFlag{
 		Name:  "port",
 		Usage: "Network listening port",
@@ -528,6 +756,21 @@ var (
 		Name:  "nodekeyhex",
 		Usage: "P2P node key as hex (for testing)",
 	}
+	// Quorum
+	NodeKeyKMSFlag = cli.StringFlag{
+		Name:  "nodekey.kms",
+		Usage: "URL of the node key in a KMS signing on its behalf, the node key file being still needed for the handshake (awskms://<key>, gcpkms://<key version>, azurekv://<vault>/keys/<name>/<version>)",
+	}
+	NodeKeyKMSBudgetFlag = cli.DurationFlag{
+		Name:  "nodekey.kms.budget",
+		Usage: "Maximum latency of a signature by the KMS",
+		Value: kms.DefaultBudget,
+	}
+	NodeKeyKMSFailoverFlag = cli.StringFlag{
+		Name:  "nodekey.kms.failover",
+		Usage: `Policy when the KMS fails to sign within the budget ("none" fails the signature, "local" signs with the node key file)`,
+		Value: kms.FailoverNone,
+	}
 	NATFlag = cli.StringFlag{
 		Name:  "nat",
 		Usage: "NAT port mapping mechanism (any|none|upnp|pmp|extip:<IP>)",
@@ -545,6 +788,77 @@ var (
 		Name:  "netrestrict",
 		Usage: "Restricts network communication to the given IP networks (CIDR masks)",
 	}
+	// Quorum
+	P2PProxyProtocolFlag = cli.BoolFlag{
+		Name:  "p2p.proxyprotocol",
+		Usage: "Read the PROXY protocol v2 header of the inbound p2p connections, for the real peer IP behind layer-4 load balancers",
+	}
+	P2PProxyTrustedFlag = cli.StringFlag{
+		Name:  "p2p.proxytrusted",
+		Usage: "IP networks (CIDR masks) of the proxies sending the PROXY protocol header on p2p connections, required with --p2p.proxyprotocol",
+	}
+	LatencyAwareFlag = cli.BoolFlag{
+		Name:  "p2p.latencyaware",
+		Usage: "Prefer the peers of lowest measured round-trip time for dialing and block propagation",
+	}
+	CrossRegionRTTFlag = cli.DurationFlag{
+		Name:  "p2p.crossregionrtt",
+		Usage: "Round-trip time from which a peer is considered in another region, with --p2p.latencyaware",
+		Value: 100 * time.Millisecond,
+	}
+	CrossRegionPeersFlag = cli.IntFlag{
+		Name:  "p2p.crossregionpeers",
+		Usage: "Number of peers in other regions to keep connected, with --p2p.latencyaware",
+		Value: 2,
+	}
+	PeerCAFlag = cli.StringFlag{
+		Name:  "p2p.ca",
+		Usage: "PEM file of the consortium CA certificates, which the peers must present a certificate issued by",
+	}
+	PeerCertFlag = cli.StringFlag{
+		Name:  "p2p.cert",
+		Usage: "PEM file of the certificate chain of the node, naming its node key, presented to the peers with --p2p.ca",
+	}
+	GossipFlag = cli.BoolFlag{
+		Name:  "gossip",
+		Usage: "Propagate blocks and transactions through gossip topics instead of flooding the peers",
+	}
+	GossipBlockFanoutFlag = cli.IntFlag{
+		Name:  "gossip.blockfanout",
+		Usage: "Number of peers new blocks are pushed to, the others being announced them, with --gossip",
+		Value: eth.DefaultConfig.Gossip.BlockFanout,
+	}
+	GossipTxFanoutFlag = cli.IntFlag{
+		Name:  "gossip.txfanout",
+		Usage: "Number of peers new transactions are pushed to, the others being announced them, with --gossip",
+		Value: eth.DefaultConfig.Gossip.TxFanout,
+	}
+	GossipWantDelayFlag = cli.DurationFlag{
+		Name:  "gossip.wantdelay",
+		Usage: "Time an announced transaction is awaited before requesting it, with --gossip",
+		Value: eth.DefaultConfig.Gossip.WantDelay,
+	}
+	GossipPrivateValidatorFlag = cli.StringFlag{
+		Name:  "gossip.private.validator",
+		Usage: `Peers a validator sends the private transactions to ("all", "validators" with Istanbul, "relays" or "none")`,
+	}
+	GossipPrivateObserverFlag = cli.StringFlag{
+		Name:  "gossip.private.observer",
+		Usage: `Peers a non-validator sends the private transactions to ("all", "validators" with Istanbul, "relays" or "none")`,
+	}
+	GossipPrivateRelaysFlag = cli.StringFlag{
+		Name:  "gossip.private.relays",
+		Usage: "Comma separated enode URLs of the relays of the private transactions",
+	}
+	CompressionFlag = cli.StringFlag{
+		Name:  "compression",
+		Usage: `Codec of the large block and transaction messages sent to the peers supporting it ("snappy" or "deflate")`,
+	}
+	CompressionThresholdFlag = cli.IntFlag{
+		Name:  "compression.threshold",
+		Usage: "Size in bytes of the messages from which they are compressed, with --compression",
+		Value: eth.DefaultConfig.Compression.Threshold,
+	}
 
 	// ATM the url is left to the user and deployment to
 	JSpathFlag = cli.StringFlag{
@@ -618,6 +932,137 @@ var (
 		Name:  "permissioned",
 		Usage: "If enabled, the node will allow only a defined list of nodes to connect",
 	}
+	PermissionedSyncFlag = cli.BoolFlag{
+		Name:  "permissioned.sync",
+		Usage: "Gossip the changes of the permissioned nodes signed by the operators, applying those of the other nodes (requires operator keys)",
+	}
+	GasAccountingFlag = cli.BoolFlag{
+		Name:  "gasaccounting",
+		Usage: "Meter the cumulative gas used by each sender, also on zero gas price networks (accounting RPC API)",
+	}
+	GasAccountingExportDirFlag = DirectoryFlag{
+		Name:  "gasaccounting.exportdir",
+		Usage: "Directory the gas accounting CSV exports are written to (export disabled if empty)",
+	}
+	HealthBlockStallFlag = cli.DurationFlag{
+		Name:  "health.blockstall",
+		Usage: "Time without new block raising a consensus health alert, with transactions pending for Raft (0 = disabled)",
+		Value: eth.DefaultConfig.Health.BlockStall,
+	}
+	HealthMaxRoundFlag = cli.Uint64Flag{
+		Name:  "health.maxround",
+		Usage: "Istanbul round at a single height raising a consensus health alert (0 = disabled)",
+		Value: eth.DefaultConfig.Health.MaxRound,
+	}
+	HealthLeaderChangesFlag = cli.Uint64Flag{
+		Name:  "health.leaderchanges",
+		Usage: "Raft leader changes within the churn window raising a consensus health alert (0 = disabled)",
+		Value: eth.DefaultConfig.Health.LeaderChanges,
+	}
+	HealthChurnWindowFlag = cli.DurationFlag{
+		Name:  "health.churnwindow",
+		Usage: "Window over which Raft leader changes are counted",
+		Value: eth.DefaultConfig.Health.ChurnWindow,
+	}
+	HealthRaftLagFlag = cli.Uint64Flag{
+		Name:  "health.raftlag",
+		Usage: "Raft entries a follower may lag behind the leader before raising a consensus health alert (0 = disabled)",
+		Value: eth.DefaultConfig.Health.RaftLag,
+	}
+	WebhooksFlag = cli.StringFlag{
+		Name:  "webhooks",
+		Usage: "JSON file of the URLs notified of node events (newBlock, contractLog, consensusFault, ptmFailure)",
+	}
+	IntegritySamplesFlag = cli.IntFlag{
+		Name:  "integrity.samples",
+		Usage: "Historical blocks re-validated per hour by the chain data integrity verifier (0 = disabled)",
+		Value: eth.DefaultConfig.IntegritySamples,
+	}
+	DryRunConfigFlag = cli.StringFlag{
+		Name:  "dryrun.config",
+		Usage: "Genesis or chain configuration file the new blocks are replayed under, its scheduled transitions activated, to report the blocks it would reject",
+	}
+	SQLExportFlag = cli.StringFlag{
+		Name:  "sqlexport",
+		Usage: "Postgres URL (postgres://...) the blocks are exported to, or file or named pipe they are streamed to as statements, resuming from the last block exported",
+	}
+	SQLExportPrivateFlag = cli.BoolFlag{
+		Name:  "sqlexport.private",
+		Usage: "Export the payloads, receipts and logs of the private transactions of the local party",
+	}
+	PTMPushAddrFlag = cli.StringFlag{
+		Name:  "ptm.push.addr",
+		Usage: "Listening address of the endpoint the private transaction manager pushes available payloads to (empty = disabled)",
+	}
+	PTMPushSecretFlag = cli.StringFlag{
+		Name:  "ptm.push.secret",
+		Usage: "File holding the bearer token authenticating the pushes of the private transaction manager",
+	}
+	FinalityConfirmationsFlag = cli.Uint64Flag{
+		Name:  "finality.confirmations",
+		Usage: "Number of blocks on top of a block for it to be final, on chains without instant finality (0 = 64)",
+	}
+	PTMOffloadStoreFlag = cli.StringFlag{
+		Name:  "ptm.offload.store",
+		Usage: "External store of the large private payloads, encrypted: s3://bucket/prefix, azure://account/container or file:///path. Required on every party of a network offloading payloads, the blocks with unresolved ones failing (empty = disabled)",
+	}
+	PTMOffloadThresholdFlag = cli.IntFlag{
+		Name:  "ptm.offload.threshold",
+		Usage: "Size in bytes from which the private payloads sent are held in the external store (0 = only resolve the ones received)",
+		Value: eth.DefaultConfig.PTMOffloadThreshold,
+	}
+	PTMCacheSizeFlag = cli.IntFlag{
+		Name:  "ptm.cache.size",
+		Usage: "Megabytes of disk the received private payloads are cached on, saving their retrieval on re-execution (0 = disabled)",
+	}
+	PTMCacheTTLFlag = cli.DurationFlag{
+		Name:  "ptm.cache.ttl",
+		Usage: "Time a received private payload is cached for (0 = until evicted)",
+		Value: eth.DefaultConfig.PTMCacheTTL,
+	}
+	PTMCacheEncryptFlag = cli.BoolFlag{
+		Name:  "ptm.cache.encrypt",
+		Usage: "Encrypt the cached private payloads with a key derived from the node key",
+	}
+	HistoryRetentionFlag = cli.Uint64Flag{
+		Name:  "history.retention",
+		Usage: "Number of recent blocks whose transaction bodies and receipts are retained, older ones are pruned (0 = all)",
+	}
+	HistoryProtectFlag = cli.StringFlag{
+		Name:  "history.protect",
+		Usage: "Comma separated contract addresses whose blocks are never pruned by the history retention",
+	}
+	StandbyRoleFlag = cli.StringFlag{
+		Name:  "standby.role",
+		Usage: `Role of the node in a hot standby pair of Istanbul validators: "primary" or "standby" (empty = no pair)`,
+	}
+	StandbyPeerFlag = cli.StringFlag{
+		Name:  "standby.peer",
+		Usage: "RPC endpoint of the standby node, the primary renews its signing lease with",
+	}
+	StandbyEscrowKeyFlag = cli.StringFlag{
+		Name:  "standby.escrowkey",
+		Usage: "File holding the node key of the primary, taken over by the standby on promotion",
+	}
+	StandbyLeaseFlag = cli.DurationFlag{
+		Name:  "standby.lease",
+		Usage: "Time the primary signs for after renewing its lease with the standby",
+		Value: eth.DefaultConfig.StandbyLease,
+	}
+	// Bootstrap settings
+	BootstrapURLFlag = cli.StringFlag{
+		Name:  "bootstrap.url",
+		Usage: "URL of the signed genesis and configuration bundle to initialise the data directory from on first start",
+	}
+	BootstrapSignersFlag = cli.StringFlag{
+		Name:  "bootstrap.signers",
+		Usage: "Comma separated public keys of the consortium members allowed to sign the bootstrap bundle",
+	}
+	BootstrapThresholdFlag = cli.IntFlag{
+		Name:  "bootstrap.threshold",
+		Usage: "Number of configured members that must have signed the bootstrap bundle",
+		Value: 1,
+	}
 	// Plugins settings
 	PluginSettingsFlag = cli.StringFlag{
 		Name:  "plugins",
@@ -646,6 +1091,51 @@ var (
 		Usage: "Default minimum difference between two consecutive block's timestamps in seconds",
 		Value: eth.DefaultConfig.Istanbul.BlockPeriod,
 	}
+	IstanbulShadowFlag = cli.BoolFlag{
+		Name:  "istanbul.shadow",
+		Usage: "Run the Istanbul state machine without sending consensus messages, reporting whether its votes match the network (requires --mine)",
+	}
+	IstanbulRefuseUnsafeFlag = cli.BoolFlag{
+		Name:  "istanbul.refuseunsafe",
+		Usage: "Refuse the proposed validator removals leaving too few validators online for the quorum, instead of warning",
+	}
+	IstanbulRelayFlag = cli.BoolFlag{
+		Name:  "istanbul.relay",
+		Usage: "Relay the consensus messages of the other validators to the connected validators, for validators not connected to each other",
+	}
+	IstanbulSigningPolicyFlag = cli.StringFlag{
+		Name:  "istanbul.signingpolicy",
+		Usage: "JSON file of the invariants of the blocks the validator seals or commits (gas limit range, chain config hash, locally imported parent)",
+	}
+	IstanbulCheckpointSinkFlag = cli.StringFlag{
+		Name:  "istanbul.checkpointsink",
+		Usage: "Export a signed checkpoint of each epoch block to a sink (file:///dir, http(s)://webhook or s3://bucket/prefix)",
+	}
+	IstanbulTimeAttestationFlag = cli.DurationFlag{
+		Name:  "istanbul.timeattestation",
+		Usage: "Interval of the signed timestamps sent to the other validators, tracking the skew of their clocks (0 = disabled)",
+	}
+	IstanbulNTPServerFlag = cli.StringFlag{
+		Name:  "istanbul.ntpserver",
+		Usage: "NTP server the attested timestamps are derived from (empty = local clock)",
+	}
+	IstanbulVanityFlag = cli.StringFlag{
+		Name:  "istanbul.vanity",
+		Usage: "Vanity of the blocks proposed by the validator, up to 32 bytes (empty = miner extra-data)",
+	}
+	IstanbulVanityPatternFlag = cli.StringFlag{
+		Name:  "istanbul.vanitypattern",
+		Usage: "Regular expression the vanity of the blocks committed must match, e.g. the identifier of the organisation of the proposer",
+	}
+	IstanbulVanityIdentifiersFlag = cli.StringFlag{
+		Name:  "istanbul.vanityidentifiers",
+		Usage: "Comma separated proposer=identifier pairs, the vanity of the blocks committed being the identifier of their proposer, e.g. its organisation",
+	}
+	IstanbulProposalValidationTimeoutFlag = cli.DurationFlag{
+		Name:  "istanbul.proposalvalidationtimeout",
+		Usage: "Time the proposal validator plugin has to vet a proposed block, accepted if it fails to",
+		Value: eth.DefaultConfig.Istanbul.ProposalValidationTimeout,
+	}
 
 	// Metrics flags
 	MetricsEnabledFlag = cli.BoolFlag{
@@ -744,6 +1234,37 @@ func setNodeKey(ctx *cli.Context, cfg *p2p.Config) {
 	}
 }
 
+// Quorum
+// setKeyStoreKDF applies the key derivation parameters of the key store from
+// the CLI flags.
+func setKeyStoreKDF(ctx *cli.Context, cfg *node.Config) {
+	if ctx.GlobalIsSet(KeyStoreScryptNFlag.Name) {
+		n := ctx.GlobalInt(KeyStoreScryptNFlag.Name)
+		if n <= 1 || n&(n-1) != 0 {
+			Fatalf("Option %q must be a power of two greater than 1", KeyStoreScryptNFlag.Name)
+		}
+		cfg.KeyStoreScryptN = n
+	}
+	if ctx.GlobalIsSet(KeyStoreScryptPFlag.Name) {
+		p := ctx.GlobalInt(KeyStoreScryptPFlag.Name)
+		if p <= 0 {
+			Fatalf("Option %q must be positive", KeyStoreScryptPFlag.Name)
+		}
+		cfg.KeyStoreScryptP = p
+	}
+	if ctx.GlobalBool(KeyStoreArgon2Flag.Name) {
+		var (
+			time    = ctx.GlobalInt(KeyStoreArgon2TimeFlag.Name)
+			memory  = ctx.GlobalInt(KeyStoreArgon2MemoryFlag.Name)
+			threads = ctx.GlobalInt(KeyStoreArgon2ThreadsFlag.Name)
+		)
+		if time <= 0 || time > keystore.MaxArgon2Time || memory <= 0 || memory > keystore.MaxArgon2Memory || threads <= 0 || threads > 255 {
+			Fatalf("Invalid Argon2id parameters: time %d, memory %d, threads %d", time, memory, threads)
+		}
+		cfg.KeyStoreArgon2 = &keystore.Argon2Params{Time: uint32(time), Memory: uint32(memory), Threads: uint8(threads)}
+	}
+}
+
 // setNodeUserIdent creates the user identifier from CLI flags.
 func setNodeUserIdent(ctx *cli.Context, cfg *node.Config) {
 	if identity := ctx.GlobalString(IdentityFlag.Name); len(identity) > 0 {
@@ -884,6 +1405,125 @@ func setWS(ctx *cli.Context, cfg *node.Config) {
 	}
 }
 
+// Quorum
+// setRPCAuth creates the JWT authentication of the HTTP and WS-RPC endpoints from
+// the command line flags, leaving it disabled unless a key is given.
+func setRPCAuth(ctx *cli.Context, cfg *node.Config) {
+	if !ctx.GlobalIsSet(RPCJWTSecretFlag.Name) && !ctx.GlobalIsSet(RPCJWTPublicKeyFlag.Name) && !ctx.GlobalIsSet(RPCJWTJWKSFlag.Name) {
+		return
+	}
+	cfg.RPCAuth = &rpc.JWTConfig{
+		SecretFile:     ctx.GlobalString(RPCJWTSecretFlag.Name),
+		PublicKeyFile:  ctx.GlobalString(RPCJWTPublicKeyFlag.Name),
+		JWKSURL:        ctx.GlobalString(RPCJWTJWKSFlag.Name),
+		NamespaceClaim: ctx.GlobalString(RPCJWTClaimFlag.Name),
+	}
+}
+
+// Quorum
+// setRPCAPIKeys configures the API keys of the HTTP and WS-RPC endpoints from
+// the command line flags.
+func setRPCAPIKeys(ctx *cli.Context, cfg *node.Config) {
+	if ctx.GlobalIsSet(RPCAPIKeysFlag.Name) {
+		cfg.RPCAPIKeys = ctx.GlobalString(RPCAPIKeysFlag.Name)
+	}
+}
+
+// Quorum
+// setRPCMethodRules configures the rules of the methods of the HTTP and WS-RPC
+// endpoints from the command line flags.
+func setRPCMethodRules(ctx *cli.Context, cfg *node.Config) {
+	if ctx.GlobalIsSet(RPCMethodRulesFlag.Name) {
+		cfg.RPCMethodRules = ctx.GlobalString(RPCMethodRulesFlag.Name)
+	}
+}
+
+// Quorum
+// setRPCProxyProtocol configures the reading of the PROXY protocol headers on the
+// HTTP and WS-RPC endpoints from the command line flags.
+func setRPCProxyProtocol(ctx *cli.Context, cfg *node.Config) {
+	if ctx.GlobalBool(RPCProxyProtocolFlag.Name) {
+		cfg.RPCProxyProtocol = true
+		cfg.RPCProxyTrusted = parseProxyTrusted(ctx, RPCProxyTrustedFlag)
+	}
+}
+
+// Quorum
+// parseProxyTrusted parses the networks of the trusted proxies from a command
+// line flag, failing if none are given as any source could then spoof its IP.
+func parseProxyTrusted(ctx *cli.Context, flag cli.StringFlag) *netutil.Netlist {
+	list, err := netutil.ParseNetlist(ctx.GlobalString(flag.Name))
+	if err != nil {
+		Fatalf("Option %q: %v", flag.Name, err)
+	}
+	if len(*list) == 0 {
+		Fatalf("Option %q is required with the PROXY protocol", flag.Name)
+	}
+	return list
+}
+
+// Quorum
+// setNodeKeyKMS configures the signing with the node key held in a KMS from the
+// command line flags, leaving it disabled unless a key is given.
+func setNodeKeyKMS(ctx *cli.Context, cfg *node.Config) {
+	if !ctx.GlobalIsSet(NodeKeyKMSFlag.Name) {
+		return
+	}
+	cfg.NodeKeyKMS = &kms.Config{
+		URL:      ctx.GlobalString(NodeKeyKMSFlag.Name),
+		Budget:   ctx.GlobalDuration(NodeKeyKMSBudgetFlag.Name),
+		Failover: ctx.GlobalString(NodeKeyKMSFailoverFlag.Name),
+	}
+}
+
+// Quorum
+// setRPCWorkers configures the accept loops and the worker pools of the HTTP
+// and WS-RPC endpoints from the command line flags.
+func setRPCWorkers(ctx *cli.Context, cfg *node.Config) {
+	if ctx.GlobalIsSet(RPCListenersFlag.Name) {
+		cfg.RPCListeners = ctx.GlobalInt(RPCListenersFlag.Name)
+	}
+	if !ctx.GlobalIsSet(RPCWorkersFlag.Name) && !ctx.GlobalIsSet(RPCMethodLimitsFlag.Name) {
+		return
+	}
+	limits, err := rpc.ParseMethodLimits(ctx.GlobalString(RPCMethodLimitsFlag.Name))
+	if err != nil {
+		Fatalf("Option %q: %v", RPCMethodLimitsFlag.Name, err)
+	}
+	cfg.RPCWorkers = &rpc.WorkerConfig{
+		Workers:      ctx.GlobalInt(RPCWorkersFlag.Name),
+		QueueSize:    ctx.GlobalInt(RPCWorkerQueueFlag.Name),
+		MethodLimits: limits,
+	}
+}
+
+// Quorum
+// setSelfMonitor configures the thresholds of the resources of the node, and
+// the protections engaged beyond them, from the command line flags.
+func setSelfMonitor(ctx *cli.Context, cfg *node.Config) {
+	if ctx.GlobalIsSet(SelfMonitorIntervalFlag.Name) {
+		cfg.SelfMonitor.Interval = ctx.GlobalDuration(SelfMonitorIntervalFlag.Name)
+	}
+	if ctx.GlobalIsSet(SelfMonitorGoroutinesFlag.Name) {
+		cfg.SelfMonitor.MaxGoroutines = ctx.GlobalInt(SelfMonitorGoroutinesFlag.Name)
+	}
+	if ctx.GlobalIsSet(SelfMonitorFilesFlag.Name) {
+		cfg.SelfMonitor.MaxOpenFiles = ctx.GlobalInt(SelfMonitorFilesFlag.Name)
+	}
+	if ctx.GlobalIsSet(SelfMonitorHeapFlag.Name) {
+		cfg.SelfMonitor.MaxHeap = ctx.GlobalUint64(SelfMonitorHeapFlag.Name) * 1024 * 1024
+	}
+	if ctx.GlobalIsSet(SelfMonitorGCPauseFlag.Name) {
+		cfg.SelfMonitor.MaxGCPause = ctx.GlobalDuration(SelfMonitorGCPauseFlag.Name)
+	}
+	if ctx.GlobalIsSet(SelfMonitorPauseRPCFlag.Name) {
+		cfg.SelfMonitor.PauseRPC = ctx.GlobalBool(SelfMonitorPauseRPCFlag.Name)
+	}
+	if ctx.GlobalIsSet(SelfMonitorRejectSubsFlag.Name) {
+		cfg.SelfMonitor.RejectSubscriptions = ctx.GlobalBool(SelfMonitorRejectSubsFlag.Name)
+	}
+}
+
 // setIPC creates an IPC path configuration from the set command line flags,
 // returning an empty string if IPC was explicitly disabled, or the set path.
 func setIPC(ctx *cli.Context, cfg *node.Config) {
@@ -1010,6 +1650,16 @@ func SetP2PConfig(ctx *cli.Context, cfg *p2p.Config) {
 	if ctx.GlobalIsSet(MaxPendingPeersFlag.Name) {
 		cfg.MaxPendingPeers = ctx.GlobalInt(MaxPendingPeersFlag.Name)
 	}
+	// Quorum
+	if ctx.GlobalIsSet(InboundRateLimitFlag.Name) {
+		cfg.InboundRateLimit = ctx.GlobalInt(InboundRateLimitFlag.Name)
+	}
+	if ctx.GlobalIsSet(MaxHandshakesPerIPFlag.Name) {
+		cfg.MaxHandshakesPerIP = ctx.GlobalInt(MaxHandshakesPerIPFlag.Name)
+	}
+	if ctx.GlobalIsSet(HandshakePuzzleFlag.Name) {
+		cfg.HandshakePuzzle = ctx.GlobalUint(HandshakePuzzleFlag.Name)
+	}
 	if ctx.GlobalIsSet(NoDiscoverFlag.Name) || lightClient {
 		cfg.NoDiscovery = true
 	}
@@ -1031,6 +1681,20 @@ func SetP2PConfig(ctx *cli.Context, cfg *p2p.Config) {
 		}
 		cfg.NetRestrict = list
 	}
+	// Quorum
+	if ctx.GlobalBool(P2PProxyProtocolFlag.Name) {
+		cfg.ProxyProtocol = true
+		cfg.ProxyTrusted = parseProxyTrusted(ctx, P2PProxyTrustedFlag)
+	}
+	if ctx.GlobalBool(LatencyAwareFlag.Name) {
+		cfg.LatencyAware = true
+		cfg.CrossRegionRTT = ctx.GlobalDuration(CrossRegionRTTFlag.Name)
+		cfg.CrossRegionPeers = ctx.GlobalInt(CrossRegionPeersFlag.Name)
+	}
+	if ctx.GlobalIsSet(PeerCAFlag.Name) {
+		cfg.PeerCAFile = ctx.GlobalString(PeerCAFlag.Name)
+		cfg.PeerCertFile = ctx.GlobalString(PeerCertFlag.Name)
+	}
 
 	if ctx.GlobalBool(DeveloperFlag.Name) {
 		// --dev mode can't use p2p networking.
@@ -1047,6 +1711,13 @@ func SetNodeConfig(ctx *cli.Context, cfg *node.Config) {
 	setIPC(ctx, cfg)
 	setHTTP(ctx, cfg)
 	setWS(ctx, cfg)
+	setRPCAuth(ctx, cfg)
+	setRPCAPIKeys(ctx, cfg)
+	setRPCMethodRules(ctx, cfg)
+	setRPCWorkers(ctx, cfg)
+	setRPCProxyProtocol(ctx, cfg)
+	setSelfMonitor(ctx, cfg)
+	setNodeKeyKMS(ctx, cfg)
 	setNodeUserIdent(ctx, cfg)
 
 	cfg.EnableNodePermission = ctx.GlobalBool(EnableNodePermissionFlag.Name)
@@ -1070,6 +1741,7 @@ func SetNodeConfig(ctx *cli.Context, cfg *node.Config) {
 	if ctx.GlobalIsSet(LightKDFFlag.Name) {
 		cfg.UseLightweightKDF = ctx.GlobalBool(LightKDFFlag.Name)
 	}
+	setKeyStoreKDF(ctx, cfg)
 	if ctx.GlobalIsSet(NoUSBFlag.Name) {
 		cfg.NoUSB = ctx.GlobalBool(NoUSBFlag.Name)
 	}
@@ -1170,6 +1842,58 @@ func setTxPool(ctx *cli.Context, cfg *core.TxPoolConfig) {
 	if ctx.GlobalIsSet(TxPoolLifetimeFlag.Name) {
 		cfg.Lifetime = ctx.GlobalDuration(TxPoolLifetimeFlag.Name)
 	}
+	if ctx.GlobalIsSet(TxPoolPrioritySendersFlag.Name) || ctx.GlobalIsSet(TxPoolPriorityRecipientsFlag.Name) || ctx.GlobalIsSet(TxPoolPriorityTypesFlag.Name) {
+		class := core.TxPriorityClass{
+			Name:       "priority",
+			Senders:    splitAccounts(ctx, TxPoolPrioritySendersFlag),
+			Recipients: splitAccounts(ctx, TxPoolPriorityRecipientsFlag),
+			Kinds:      splitTxKinds(ctx, TxPoolPriorityTypesFlag),
+			Slots:      ctx.GlobalUint64(TxPoolPrioritySlotsFlag.Name),
+			Queue:      ctx.GlobalUint64(TxPoolPriorityQueueFlag.Name),
+		}
+		cfg.PriorityClasses = append([]core.TxPriorityClass{class}, cfg.PriorityClasses...)
+	}
+	if ctx.GlobalIsSet(TxPoolAnchorSlotsFlag.Name) {
+		cfg.AnchorSlots = ctx.GlobalUint64(TxPoolAnchorSlotsFlag.Name)
+	}
+	if ctx.GlobalIsSet(TxPoolGlobalAnchorSlotsFlag.Name) {
+		cfg.GlobalAnchorSlots = ctx.GlobalUint64(TxPoolGlobalAnchorSlotsFlag.Name)
+	}
+	if ctx.GlobalIsSet(TxPoolHeapLimitFlag.Name) {
+		cfg.HeapLimit = ctx.GlobalUint64(TxPoolHeapLimitFlag.Name) * 1024 * 1024
+	}
+}
+
+// splitTxKinds parses a comma separated transaction type list flag.
+func splitTxKinds(ctx *cli.Context, flag cli.StringFlag) core.TxKind {
+	var kinds core.TxKind
+	if !ctx.GlobalIsSet(flag.Name) {
+		return kinds
+	}
+	for _, name := range strings.Split(ctx.GlobalString(flag.Name), ",") {
+		kind, err := core.ParseTxKind(strings.TrimSpace(name))
+		if err != nil {
+			Fatalf("Invalid transaction type in --%s: %v", flag.Name, err)
+		}
+		kinds |= kind
+	}
+	return kinds
+}
+
+// splitAccounts parses a comma separated account list flag.
+func splitAccounts(ctx *cli.Context, flag cli.StringFlag) []common.Address {
+	var accounts []common.Address
+	if !ctx.GlobalIsSet(flag.Name) {
+		return accounts
+	}
+	for _, account := range strings.Split(ctx.GlobalString(flag.Name), ",") {
+		if trimmed := strings.TrimSpace(account); !common.IsHexAddress(trimmed) {
+			Fatalf("Invalid account in --%s: %s", flag.Name, trimmed)
+		} else {
+			accounts = append(accounts, common.HexToAddress(trimmed))
+		}
+	}
+	return accounts
 }
 
 func setEthash(ctx *cli.Context, cfg *eth.Config) {
@@ -1200,6 +1924,76 @@ func setIstanbul(ctx *cli.Context, cfg *eth.Config) {
 	if ctx.GlobalIsSet(IstanbulBlockPeriodFlag.Name) {
 		cfg.Istanbul.BlockPeriod = ctx.GlobalUint64(IstanbulBlockPeriodFlag.Name)
 	}
+	if ctx.GlobalIsSet(IstanbulShadowFlag.Name) {
+		cfg.Istanbul.Shadow = ctx.GlobalBool(IstanbulShadowFlag.Name)
+	}
+	if ctx.GlobalIsSet(IstanbulRefuseUnsafeFlag.Name) {
+		cfg.Istanbul.RefuseUnsafe = ctx.GlobalBool(IstanbulRefuseUnsafeFlag.Name)
+	}
+	if ctx.GlobalIsSet(IstanbulRelayFlag.Name) {
+		cfg.Istanbul.Relay = ctx.GlobalBool(IstanbulRelayFlag.Name)
+	}
+	if ctx.GlobalIsSet(IstanbulSigningPolicyFlag.Name) {
+		policy, err := istanbul.LoadSigningPolicy(ctx.GlobalString(IstanbulSigningPolicyFlag.Name))
+		if err != nil {
+			Fatalf("Option %q: %v", IstanbulSigningPolicyFlag.Name, err)
+		}
+		cfg.Istanbul.SigningPolicy = policy
+	}
+	if ctx.GlobalIsSet(IstanbulCheckpointSinkFlag.Name) {
+		cfg.IstanbulCheckpointSink = ctx.GlobalString(IstanbulCheckpointSinkFlag.Name)
+	}
+	if ctx.GlobalIsSet(IstanbulTimeAttestationFlag.Name) {
+		cfg.Istanbul.TimeAttestation = ctx.GlobalDuration(IstanbulTimeAttestationFlag.Name)
+	}
+	if ctx.GlobalIsSet(IstanbulNTPServerFlag.Name) {
+		cfg.Istanbul.NTPServer = ctx.GlobalString(IstanbulNTPServerFlag.Name)
+	}
+	if ctx.GlobalIsSet(IstanbulVanityFlag.Name) {
+		vanity := ctx.GlobalString(IstanbulVanityFlag.Name)
+		if err := istanbul.ValidateVanity([]byte(vanity)); err != nil {
+			Fatalf("Option %q: %v", IstanbulVanityFlag.Name, err)
+		}
+		cfg.Istanbul.Vanity = vanity
+	}
+	if ctx.GlobalIsSet(IstanbulVanityPatternFlag.Name) {
+		pattern := ctx.GlobalString(IstanbulVanityPatternFlag.Name)
+		if _, err := istanbul.NewVanityPattern(pattern); err != nil {
+			Fatalf("Option %q: %v", IstanbulVanityPatternFlag.Name, err)
+		}
+		cfg.Istanbul.VanityPattern = pattern
+	}
+	if ctx.GlobalIsSet(IstanbulVanityIdentifiersFlag.Name) {
+		identifiers, err := parseVanityIdentifiers(ctx.GlobalString(IstanbulVanityIdentifiersFlag.Name))
+		if err != nil {
+			Fatalf("Option %q: %v", IstanbulVanityIdentifiersFlag.Name, err)
+		}
+		cfg.Istanbul.VanityIdentifiers = identifiers
+	}
+	if ctx.GlobalIsSet(IstanbulProposalValidationTimeoutFlag.Name) {
+		cfg.Istanbul.ProposalValidationTimeout = ctx.GlobalDuration(IstanbulProposalValidationTimeoutFlag.Name)
+	}
+}
+
+// Quorum
+// parseVanityIdentifiers parses the comma separated proposer=identifier pairs of
+// the Istanbul vanity policy.
+func parseVanityIdentifiers(list string) (map[common.Address]string, error) {
+	identifiers := make(map[common.Address]string)
+	for _, pair := range strings.Split(list, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 || !common.IsHexAddress(parts[0]) {
+			return nil, fmt.Errorf("invalid proposer=identifier pair %q", pair)
+		}
+		identifiers[common.HexToAddress(parts[0])] = parts[1]
+	}
+	if _, err := istanbul.NewVanityIdentifiers(identifiers); err != nil {
+		return nil, err
+	}
+	return identifiers, nil
 }
 
 // checkExclusive verifies that only a single instance of the provided flags was
@@ -1296,6 +2090,60 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *eth.Config) {
 	if ctx.GlobalIsSet(CacheFlag.Name) || ctx.GlobalIsSet(CacheGCFlag.Name) {
 		cfg.TrieCache = ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheGCFlag.Name) / 100
 	}
+	if ctx.GlobalIsSet(SnapshotFlag.Name) {
+		cfg.Snapshot = ctx.GlobalBool(SnapshotFlag.Name)
+	}
+	if ctx.GlobalIsSet(LogIndexFlag.Name) {
+		cfg.LogIndex = ctx.GlobalBool(LogIndexFlag.Name)
+	}
+	if ctx.GlobalIsSet(StateSizeFlag.Name) {
+		cfg.StateSize = ctx.GlobalBool(StateSizeFlag.Name)
+	}
+	if ctx.GlobalIsSet(StateRangeRateFlag.Name) {
+		cfg.StateRangeRate = ctx.GlobalInt(StateRangeRateFlag.Name)
+	}
+	if ctx.GlobalIsSet(ImportPipelineFlag.Name) {
+		cfg.ImportPipeline = ctx.GlobalInt(ImportPipelineFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCCacheFlag.Name) {
+		cfg.RPCCacheSize = ctx.GlobalInt(RPCCacheFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCCallWorkersFlag.Name) {
+		cfg.RPCCallWorkers = ctx.GlobalInt(RPCCallWorkersFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCCallQuotaFlag.Name) {
+		cfg.RPCCallCPUQuota = ctx.GlobalDuration(RPCCallQuotaFlag.Name)
+	}
+	if ctx.GlobalIsSet(LatencyAwareFlag.Name) {
+		cfg.LatencyAwarePropagation = ctx.GlobalBool(LatencyAwareFlag.Name)
+	}
+	if ctx.GlobalIsSet(GossipFlag.Name) {
+		cfg.Gossip.Enabled = ctx.GlobalBool(GossipFlag.Name)
+	}
+	if ctx.GlobalIsSet(GossipBlockFanoutFlag.Name) {
+		cfg.Gossip.BlockFanout = ctx.GlobalInt(GossipBlockFanoutFlag.Name)
+	}
+	if ctx.GlobalIsSet(GossipTxFanoutFlag.Name) {
+		cfg.Gossip.TxFanout = ctx.GlobalInt(GossipTxFanoutFlag.Name)
+	}
+	if ctx.GlobalIsSet(GossipWantDelayFlag.Name) {
+		cfg.Gossip.WantDelay = ctx.GlobalDuration(GossipWantDelayFlag.Name)
+	}
+	if ctx.GlobalIsSet(GossipPrivateValidatorFlag.Name) {
+		cfg.PrivateGossip.Validator = ctx.GlobalString(GossipPrivateValidatorFlag.Name)
+	}
+	if ctx.GlobalIsSet(GossipPrivateObserverFlag.Name) {
+		cfg.PrivateGossip.Observer = ctx.GlobalString(GossipPrivateObserverFlag.Name)
+	}
+	if ctx.GlobalIsSet(GossipPrivateRelaysFlag.Name) {
+		cfg.PrivateGossip.Relays = splitAndTrim(ctx.GlobalString(GossipPrivateRelaysFlag.Name))
+	}
+	if ctx.GlobalIsSet(CompressionFlag.Name) {
+		cfg.Compression.Codec = ctx.GlobalString(CompressionFlag.Name)
+	}
+	if ctx.GlobalIsSet(CompressionThresholdFlag.Name) {
+		cfg.Compression.Threshold = ctx.GlobalInt(CompressionThresholdFlag.Name)
+	}
 	if ctx.GlobalIsSet(MinerNotifyFlag.Name) {
 		cfg.MinerNotify = strings.Split(ctx.GlobalString(MinerNotifyFlag.Name), ",")
 	}
@@ -1329,10 +2177,112 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *eth.Config) {
 	if ctx.GlobalIsSet(MinerNoVerfiyFlag.Name) {
 		cfg.MinerNoverify = ctx.Bool(MinerNoVerfiyFlag.Name)
 	}
+	if ctx.GlobalIsSet(MinerBuilderFlag.Name) {
+		cfg.MinerBuilder = ctx.GlobalString(MinerBuilderFlag.Name)
+	}
+	if ctx.GlobalIsSet(MinerBuilderBudgetFlag.Name) {
+		cfg.MinerBuilderBudget = ctx.GlobalDuration(MinerBuilderBudgetFlag.Name)
+	}
+	if ctx.GlobalIsSet(GasAccountingFlag.Name) {
+		cfg.GasAccounting = ctx.GlobalBool(GasAccountingFlag.Name)
+	}
+	if ctx.GlobalIsSet(GasAccountingExportDirFlag.Name) {
+		cfg.GasAccountingExportDir = ctx.GlobalString(GasAccountingExportDirFlag.Name)
+	}
+	if ctx.GlobalIsSet(HealthBlockStallFlag.Name) {
+		cfg.Health.BlockStall = ctx.GlobalDuration(HealthBlockStallFlag.Name)
+	}
+	if ctx.GlobalIsSet(HealthMaxRoundFlag.Name) {
+		cfg.Health.MaxRound = ctx.GlobalUint64(HealthMaxRoundFlag.Name)
+	}
+	if ctx.GlobalIsSet(HealthLeaderChangesFlag.Name) {
+		cfg.Health.LeaderChanges = ctx.GlobalUint64(HealthLeaderChangesFlag.Name)
+	}
+	if ctx.GlobalIsSet(HealthChurnWindowFlag.Name) {
+		cfg.Health.ChurnWindow = ctx.GlobalDuration(HealthChurnWindowFlag.Name)
+	}
+	if ctx.GlobalIsSet(HealthRaftLagFlag.Name) {
+		cfg.Health.RaftLag = ctx.GlobalUint64(HealthRaftLagFlag.Name)
+	}
+	if ctx.GlobalIsSet(WebhooksFlag.Name) {
+		hooks, err := eth.LoadWebhooks(ctx.GlobalString(WebhooksFlag.Name))
+		if err != nil {
+			Fatalf("Failed to load webhooks: %v", err)
+		}
+		cfg.Webhooks = hooks
+	}
+	if ctx.GlobalIsSet(IntegritySamplesFlag.Name) {
+		cfg.IntegritySamples = ctx.GlobalInt(IntegritySamplesFlag.Name)
+	}
+	if ctx.GlobalIsSet(DryRunConfigFlag.Name) {
+		config, err := eth.LoadDryRunConfig(ctx.GlobalString(DryRunConfigFlag.Name))
+		if err != nil {
+			Fatalf("Failed to load dry run chain configuration: %v", err)
+		}
+		cfg.DryRunConfig = config
+	}
+	if ctx.GlobalIsSet(SQLExportFlag.Name) {
+		cfg.SQLExport = ctx.GlobalString(SQLExportFlag.Name)
+	}
+	if ctx.GlobalIsSet(SQLExportPrivateFlag.Name) {
+		cfg.SQLExportPrivate = ctx.GlobalBool(SQLExportPrivateFlag.Name)
+	}
+	if ctx.GlobalIsSet(PTMPushAddrFlag.Name) {
+		cfg.PTMPushAddr = ctx.GlobalString(PTMPushAddrFlag.Name)
+	}
+	if ctx.GlobalIsSet(PTMPushSecretFlag.Name) {
+		cfg.PTMPushSecret = ctx.GlobalString(PTMPushSecretFlag.Name)
+	}
+	if ctx.GlobalIsSet(FinalityConfirmationsFlag.Name) {
+		cfg.FinalityConfirmations = ctx.GlobalUint64(FinalityConfirmationsFlag.Name)
+	}
+	if ctx.GlobalIsSet(PTMOffloadStoreFlag.Name) {
+		cfg.PTMOffloadStore = ctx.GlobalString(PTMOffloadStoreFlag.Name)
+	}
+	if ctx.GlobalIsSet(PTMOffloadThresholdFlag.Name) {
+		cfg.PTMOffloadThreshold = ctx.GlobalInt(PTMOffloadThresholdFlag.Name)
+	}
+	if ctx.GlobalIsSet(PTMCacheSizeFlag.Name) {
+		cfg.PTMCacheSize = ctx.GlobalInt(PTMCacheSizeFlag.Name)
+	}
+	if ctx.GlobalIsSet(PTMCacheTTLFlag.Name) {
+		cfg.PTMCacheTTL = ctx.GlobalDuration(PTMCacheTTLFlag.Name)
+	}
+	if ctx.GlobalIsSet(PTMCacheEncryptFlag.Name) {
+		cfg.PTMCacheEncrypt = ctx.GlobalBool(PTMCacheEncryptFlag.Name)
+	}
+	if ctx.GlobalIsSet(HistoryRetentionFlag.Name) {
+		cfg.HistoryRetention = ctx.GlobalUint64(HistoryRetentionFlag.Name)
+	}
+	if ctx.GlobalIsSet(HistoryProtectFlag.Name) {
+		cfg.HistoryProtected = splitAccounts(ctx, HistoryProtectFlag)
+	}
+	if ctx.GlobalIsSet(StandbyRoleFlag.Name) {
+		cfg.StandbyRole = ctx.GlobalString(StandbyRoleFlag.Name)
+		cfg.StandbyPasswords = MakePasswordList(ctx)
+	}
+	if ctx.GlobalIsSet(StandbyPeerFlag.Name) {
+		cfg.StandbyPeer = ctx.GlobalString(StandbyPeerFlag.Name)
+	}
+	if ctx.GlobalIsSet(StandbyEscrowKeyFlag.Name) {
+		cfg.StandbyEscrowKey = ctx.GlobalString(StandbyEscrowKeyFlag.Name)
+	}
+	if ctx.GlobalIsSet(StandbyLeaseFlag.Name) {
+		cfg.StandbyLease = ctx.GlobalDuration(StandbyLeaseFlag.Name)
+	}
 	if ctx.GlobalIsSet(VMEnableDebugFlag.Name) {
 		// TODO(fjl): force-enable this in --dev mode
 		cfg.EnablePreimageRecording = ctx.GlobalBool(VMEnableDebugFlag.Name)
 	}
+	if ctx.GlobalBool(PreimagesFlag.Name) {
+		cfg.EnablePreimageRecording = true
+	}
+	if ctx.GlobalIsSet(PreimagesMaxSizeFlag.Name) {
+		cfg.PreimageMaxSize = ctx.GlobalInt(PreimagesMaxSizeFlag.Name)
+	}
+	if ctx.GlobalIsSet(PreimagesRetentionFlag.Name) {
+		cfg.PreimageRetention = ctx.GlobalUint64(PreimagesRetentionFlag.Name)
+	}
 
 	if ctx.GlobalIsSet(EWASMInterpreterFlag.Name) {
 		cfg.EWASMInterpreter = ctx.GlobalString(EWASMInterpreterFlag.Name)
@@ -1472,6 +2422,17 @@ func RegisterPluginService(stack *node.Node, cfg *node.Config, skipVerify bool,
 	}
 }
 
+// Quorum
+//
+// Register the gossip of the changes of the permissioned nodes
+func RegisterPermissionedSyncService(stack *node.Node) {
+	if err := stack.Register(func(ctx *node.ServiceContext) (node.Service, error) {
+		return permsync.New(stack.DataDir(), ctx.OperatorAuthenticator())
+	}); err != nil {
+		Fatalf("Failed to register the permissioned nodes sync service: %v", err)
+	}
+}
+
 // Configure smart-contract-based permissioning service
 func RegisterPermissionService(ctx *cli.Context, stack *node.Node) {
 	if err := stack.Register(func(sctx *node.ServiceContext) (node.Service, error) {
@@ -1581,6 +2542,7 @@ func MakeChain(ctx *cli.Context, stack *node.Node) (chain *core.BlockChain, chai
 	if ctx.GlobalIsSet(CacheFlag.Name) || ctx.GlobalIsSet(CacheGCFlag.Name) {
 		cache.TrieNodeLimit = ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheGCFlag.Name) / 100
 	}
+	cache.ImportPipeline = ctx.GlobalInt(ImportPipelineFlag.Name)
 	vmcfg := vm.Config{EnablePreimageRecording: ctx.GlobalBool(VMEnableDebugFlag.Name)}
 	chain, err = core.NewBlockChain(chainDb, cache, config, engine, vmcfg, nil)
 	if err != nil {
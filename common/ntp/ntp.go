@@ -0,0 +1,100 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package ntp measures the offset of the local clock from the time of an NTP
+// server via the SNTP protocol, RFC 4330.
+package ntp
+
+import (
+	"net"
+	"sort"
+	"time"
+)
+
+// Offset does a naive time resolution against an NTP server and returns the
+// offset of its time from the local clock, positive if the local clock is
+// behind. This method uses the simple version of NTP. It's not precise but
+// should be fine for the sanity checks and the timestamps of the validators.
+//
+// Note, it executes two extra measurements compared to the number of requested
+// ones to be able to discard the two extremes as outliers.
+func Offset(server string, measurements int) (time.Duration, error) {
+	// Resolve the address of the NTP server
+	addr, err := net.ResolveUDPAddr("udp", net.JoinHostPort(server, "123"))
+	if err != nil {
+		return 0, err
+	}
+	return offset(addr, measurements)
+}
+
+// offset measures the offset of the time of the NTP server at an address.
+func offset(addr *net.UDPAddr, measurements int) (time.Duration, error) {
+	// Construct the time request (empty package with only 2 fields set):
+	//   Bits 3-5: Protocol version, 3
+	//   Bits 6-8: Mode of operation, client, 3
+	request := make([]byte, 48)
+	request[0] = 3<<3 | 3
+
+	// Execute each of the measurements
+	offsets := []time.Duration{}
+	for i := 0; i < measurements+2; i++ {
+		sent, elapsed, reply, err := query(addr, request)
+		if err != nil {
+			return 0, err
+		}
+		// Reconstruct the time from the reply data
+		sec := uint64(reply[43]) | uint64(reply[42])<<8 | uint64(reply[41])<<16 | uint64(reply[40])<<24
+		frac := uint64(reply[47]) | uint64(reply[46])<<8 | uint64(reply[45])<<16 | uint64(reply[44])<<24
+
+		nanosec := sec*1e9 + (frac*1e9)>>32
+
+		t := time.Date(1900, 1, 1, 0, 0, 0, 0, time.UTC).Add(time.Duration(nanosec))
+
+		// Calculate the offset based on an assumed answer time of RTT/2
+		offsets = append(offsets, t.Sub(sent)-elapsed/2)
+	}
+	// Calculate average offset (drop two extremities to avoid outliers)
+	sort.Slice(offsets, func(i, j int) bool { return offsets[i] < offsets[j] })
+
+	offset := time.Duration(0)
+	for i := 1; i < len(offsets)-1; i++ {
+		offset += offsets[i]
+	}
+	return offset / time.Duration(measurements), nil
+}
+
+// query sends a time request to the NTP server, returning the time it was sent
+// at, the round trip time and the reply.
+func query(addr *net.UDPAddr, request []byte) (time.Time, time.Duration, []byte, error) {
+	// Dial the NTP server and send the time retrieval request
+	conn, err := net.DialUDP("udp", nil, addr)
+	if err != nil {
+		return time.Time{}, 0, nil, err
+	}
+	defer conn.Close()
+
+	sent := time.Now()
+	conn.SetDeadline(sent.Add(5 * time.Second))
+	if _, err = conn.Write(request); err != nil {
+		return time.Time{}, 0, nil, err
+	}
+	// Retrieve the reply and calculate the elapsed time
+	reply := make([]byte, 48)
+	if _, err = conn.Read(reply); err != nil {
+		return time.Time{}, 0, nil, err
+	}
+	return sent, time.Since(sent), reply, nil
+}
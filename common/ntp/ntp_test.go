@@ -0,0 +1,58 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ntp
+
+import (
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+)
+
+// Tests that the offset of the time of a server from the local clock is
+// measured from its replies.
+func TestOffset(t *testing.T) {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer conn.Close()
+
+	// Serve the time an hour ahead of the local clock
+	go func() {
+		request := make([]byte, 48)
+		for {
+			_, from, err := conn.ReadFromUDP(request)
+			if err != nil {
+				return
+			}
+			since := time.Now().Add(time.Hour).Sub(time.Date(1900, 1, 1, 0, 0, 0, 0, time.UTC))
+
+			reply := make([]byte, 48)
+			binary.BigEndian.PutUint32(reply[40:], uint32(since/time.Second))
+			binary.BigEndian.PutUint32(reply[44:], uint32((uint64(since%time.Second)<<32)/uint64(time.Second)))
+			conn.WriteToUDP(reply, from)
+		}
+	}()
+	measured, err := offset(conn.LocalAddr().(*net.UDPAddr), 3)
+	if err != nil {
+		t.Fatalf("failed to measure offset: %v", err)
+	}
+	if measured < time.Hour-time.Second || measured > time.Hour+time.Second {
+		t.Errorf("offset mismatch: have %v, want %v", measured, time.Hour)
+	}
+}
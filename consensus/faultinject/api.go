@@ -0,0 +1,60 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package faultinject
+
+import "time"
+
+// PrivateFaultInjectAPI drives the fault injection layer of the consensus
+// engines. It is only exposed by the binaries built with the faultinject tag.
+type PrivateFaultInjectAPI struct {
+	injector *injector
+}
+
+// AddRule puts a fault injection rule in force, returning its id.
+func (api *PrivateFaultInjectAPI) AddRule(rule Rule) (uint64, error) {
+	return api.injector.add(rule)
+}
+
+// RemoveRule lifts a fault injection rule, releasing the message it holds.
+func (api *PrivateFaultInjectAPI) RemoveRule(id uint64) bool {
+	return api.injector.remove(id)
+}
+
+// Rules returns the fault injection rules in force.
+func (api *PrivateFaultInjectAPI) Rules() []Rule {
+	return api.injector.list()
+}
+
+// SetClockSkew offsets the clock read by the consensus engines by the given
+// number of milliseconds, which may be negative.
+func (api *PrivateFaultInjectAPI) SetClockSkew(millis int64) {
+	api.injector.setSkew(time.Duration(millis) * time.Millisecond)
+}
+
+// ClockSkew returns the offset in milliseconds of the clock read by the
+// consensus engines.
+func (api *PrivateFaultInjectAPI) ClockSkew() int64 {
+	api.injector.lock.Lock()
+	defer api.injector.lock.Unlock()
+
+	return int64(api.injector.skew / time.Millisecond)
+}
+
+// Reset lifts all the fault injection rules and the clock skew.
+func (api *PrivateFaultInjectAPI) Reset() {
+	api.injector.clear()
+}
@@ -0,0 +1,289 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package faultinject implements fault injection into the messages exchanged
+// by the Istanbul and Raft consensus engines, and into the clock they read, for
+// the chaos testing of consensus on real networks.
+//
+// The layer is only active in binaries built with the faultinject build tag,
+// which then expose the faultinject RPC namespace to drive it. In other builds
+// the hooks pass the messages and the clock through untouched.
+package faultinject
+
+import (
+	"errors"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+// Consensus engines the messages of which can be faulted.
+const (
+	Istanbul = "istanbul"
+	Raft     = "raft"
+)
+
+// Directions of the messages, relative to the node.
+const (
+	Inbound  = "in"
+	Outbound = "out"
+)
+
+// Faults injected into the matching messages.
+const (
+	Drop      = "drop"      // The message is discarded
+	Delay     = "delay"     // The message is handled after the delay of the rule
+	Duplicate = "duplicate" // The message is handled twice, the copy after the delay of the rule
+	Reorder   = "reorder"   // The message is held until the next matching one has been handled
+)
+
+// defaultReorderWait is the time a message held for reordering is released
+// after if no other message matches its rule.
+const defaultReorderWait = time.Second
+
+var (
+	droppedCounter    = metrics.NewRegisteredCounter("consensus/faultinject/dropped", nil)
+	delayedCounter    = metrics.NewRegisteredCounter("consensus/faultinject/delayed", nil)
+	duplicatedCounter = metrics.NewRegisteredCounter("consensus/faultinject/duplicated", nil)
+	reorderedCounter  = metrics.NewRegisteredCounter("consensus/faultinject/reordered", nil)
+
+	errUnknownFault = errors.New("unknown fault, expected drop, delay, duplicate or reorder")
+)
+
+// Rule injects a fault into the consensus messages it matches. The empty
+// fields of its filter match any engine, direction or peer.
+type Rule struct {
+	ID          uint64  `json:"id"`
+	Engine      string  `json:"engine"`      // istanbul, raft or any
+	Direction   string  `json:"direction"`   // in, out or any
+	Peer        string  `json:"peer"`        // Address of the Istanbul validator or id of the Raft peer
+	Fault       string  `json:"fault"`       // drop, delay, duplicate or reorder
+	Probability float64 `json:"probability"` // Probability of the fault for a matching message (0 = always)
+	Delay       uint64  `json:"delay"`       // Delay in milliseconds of the delay, duplicate and reorder faults
+
+	held  func()      // Message held for reordering
+	timer *time.Timer // Timer releasing the held message
+}
+
+// matches returns whether the rule applies to a message.
+func (r *Rule) matches(engine, direction, peer string) bool {
+	return (r.Engine == "" || r.Engine == engine) &&
+		(r.Direction == "" || r.Direction == direction) &&
+		(r.Peer == "" || strings.EqualFold(r.Peer, peer))
+}
+
+// delay returns the delay of the rule, or the given default if it has none.
+func (r *Rule) delay(fallback time.Duration) time.Duration {
+	if r.Delay == 0 {
+		return fallback
+	}
+	return time.Duration(r.Delay) * time.Millisecond
+}
+
+// injector holds the rules and the clock skew in force.
+type injector struct {
+	lock   sync.Mutex
+	rules  []*Rule
+	nextID uint64
+	skew   time.Duration
+}
+
+// active is the injector the hooks of the consensus engines go through.
+var active = new(injector)
+
+// add validates a rule and puts it in force, returning its id.
+func (in *injector) add(rule Rule) (uint64, error) {
+	switch rule.Fault {
+	case Drop, Delay, Duplicate, Reorder:
+	default:
+		return 0, errUnknownFault
+	}
+	if rule.Probability < 0 || rule.Probability > 1 {
+		return 0, errors.New("probability out of [0, 1]")
+	}
+	in.lock.Lock()
+	defer in.lock.Unlock()
+
+	in.nextID++
+	rule.ID, rule.held, rule.timer = in.nextID, nil, nil
+	in.rules = append(in.rules, &rule)
+
+	log.Warn("Injecting consensus fault", "id", rule.ID, "engine", rule.Engine, "direction", rule.Direction,
+		"peer", rule.Peer, "fault", rule.Fault, "probability", rule.Probability, "delay", rule.Delay)
+	return rule.ID, nil
+}
+
+// remove lifts a rule, releasing the message it holds. It returns whether the
+// rule existed.
+func (in *injector) remove(id uint64) bool {
+	in.lock.Lock()
+	var removed *Rule
+	for i, rule := range in.rules {
+		if rule.ID == id {
+			removed = rule
+			in.rules = append(in.rules[:i], in.rules[i+1:]...)
+			break
+		}
+	}
+	in.lock.Unlock()
+
+	if removed == nil {
+		return false
+	}
+	in.release(removed)
+	log.Info("Lifted consensus fault", "id", id)
+	return true
+}
+
+// clear lifts all the rules and the clock skew.
+func (in *injector) clear() {
+	in.lock.Lock()
+	rules := in.rules
+	in.rules, in.skew = nil, 0
+	in.lock.Unlock()
+
+	for _, rule := range rules {
+		in.release(rule)
+	}
+}
+
+// list returns copies of the rules in force.
+func (in *injector) list() []Rule {
+	in.lock.Lock()
+	defer in.lock.Unlock()
+
+	rules := make([]Rule, 0, len(in.rules))
+	for _, rule := range in.rules {
+		rules = append(rules, Rule{
+			ID:          rule.ID,
+			Engine:      rule.Engine,
+			Direction:   rule.Direction,
+			Peer:        rule.Peer,
+			Fault:       rule.Fault,
+			Probability: rule.Probability,
+			Delay:       rule.Delay,
+		})
+	}
+	return rules
+}
+
+// release handles the message held by a rule, if any.
+func (in *injector) release(rule *Rule) {
+	in.lock.Lock()
+	held := rule.held
+	if rule.timer != nil {
+		rule.timer.Stop()
+	}
+	rule.held, rule.timer = nil, nil
+	in.lock.Unlock()
+
+	if held != nil {
+		held()
+	}
+}
+
+// deliver handles a message through the first rule matching it, calling the
+// given function as many times and when the fault of the rule dictates.
+func (in *injector) deliver(engine, direction, peer string, handle func()) {
+	in.lock.Lock()
+	var rule *Rule
+	for _, r := range in.rules {
+		if r.matches(engine, direction, peer) && (r.Probability == 0 || rand.Float64() < r.Probability) {
+			rule = r
+			break
+		}
+	}
+	if rule == nil {
+		in.lock.Unlock()
+		handle()
+		return
+	}
+	switch rule.Fault {
+	case Drop:
+		in.lock.Unlock()
+		droppedCounter.Inc(1)
+
+	case Delay:
+		in.lock.Unlock()
+		delayedCounter.Inc(1)
+		time.AfterFunc(rule.delay(0), handle)
+
+	case Duplicate:
+		in.lock.Unlock()
+		duplicatedCounter.Inc(1)
+		handle()
+		time.AfterFunc(rule.delay(0), handle)
+
+	case Reorder:
+		if rule.held == nil {
+			// Hold the message until the next one, or the time out
+			rule.held = handle
+			rule.timer = time.AfterFunc(rule.delay(defaultReorderWait), func() { in.release(rule) })
+			in.lock.Unlock()
+			return
+		}
+		held := rule.held
+		rule.timer.Stop()
+		rule.held, rule.timer = nil, nil
+		in.lock.Unlock()
+
+		reorderedCounter.Inc(1)
+		handle()
+		held()
+	}
+}
+
+// setSkew sets the offset of the clock read by the consensus engines.
+func (in *injector) setSkew(skew time.Duration) {
+	in.lock.Lock()
+	defer in.lock.Unlock()
+
+	in.skew = skew
+	log.Warn("Skewing consensus clock", "skew", skew)
+}
+
+// now returns the current time, skewed.
+func (in *injector) now() time.Time {
+	in.lock.Lock()
+	defer in.lock.Unlock()
+
+	return time.Now().Add(in.skew)
+}
+
+// Deliver passes a consensus message of an engine, exchanged in the given
+// direction with a peer, through the fault injection layer. The handle function
+// sends or processes the message, and may be called later, several times or not
+// at all; callers must not block on it.
+func Deliver(engine, direction, peer string, handle func()) {
+	if !Enabled {
+		handle()
+		return
+	}
+	active.deliver(engine, direction, peer, handle)
+}
+
+// Now returns the current time as read by the consensus engines, skewed by the
+// fault injection layer.
+func Now() time.Time {
+	if !Enabled {
+		return time.Now()
+	}
+	return active.now()
+}
@@ -0,0 +1,95 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package faultinject
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recorder records the messages handled through an injector.
+type recorder struct {
+	lock    sync.Mutex
+	handled []int
+}
+
+func (r *recorder) handler(msg int) func() {
+	return func() {
+		r.lock.Lock()
+		defer r.lock.Unlock()
+		r.handled = append(r.handled, msg)
+	}
+}
+
+func (r *recorder) messages() []int {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return append([]int{}, r.handled...)
+}
+
+// Tests that the faults are injected into the matching messages only.
+func TestFaults(t *testing.T) {
+	in := new(injector)
+	if _, err := in.add(Rule{Fault: "corrupt"}); err != errUnknownFault {
+		t.Fatalf("unknown fault accepted: %v", err)
+	}
+	in.add(Rule{Engine: Istanbul, Direction: Inbound, Peer: "0xAB", Fault: Drop})
+	dup, _ := in.add(Rule{Engine: Istanbul, Direction: Outbound, Fault: Duplicate})
+	in.add(Rule{Engine: Raft, Fault: Reorder, Delay: 50})
+
+	rec := new(recorder)
+	in.deliver(Istanbul, Inbound, "0xab", rec.handler(1))  // dropped
+	in.deliver(Istanbul, Inbound, "0xcd", rec.handler(2))  // passed
+	in.deliver(Istanbul, Outbound, "0xab", rec.handler(3)) // duplicated
+	time.Sleep(10 * time.Millisecond)
+
+	in.deliver(Raft, Outbound, "1", rec.handler(4)) // held
+	in.deliver(Raft, Inbound, "2", rec.handler(5))  // handled before the held one
+	in.deliver(Raft, Inbound, "2", rec.handler(6))  // held until the timeout
+
+	time.Sleep(20 * time.Millisecond)
+	if have, want := rec.messages(), []int{2, 3, 3, 5, 4}; !reflect.DeepEqual(have, want) {
+		t.Fatalf("handled messages mismatch: have %v, want %v", have, want)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if have, want := rec.messages(), []int{2, 3, 3, 5, 4, 6}; !reflect.DeepEqual(have, want) {
+		t.Fatalf("held message not released: have %v, want %v", have, want)
+	}
+	// Lift the duplication, the rules left must not match
+	if !in.remove(dup) || in.remove(dup) {
+		t.Fatalf("rule removal mismatch")
+	}
+	if rules := in.list(); len(rules) != 2 {
+		t.Fatalf("rule count mismatch: have %d, want 2", len(rules))
+	}
+	in.deliver(Istanbul, Outbound, "0xab", rec.handler(7))
+	if have, want := rec.messages(), []int{2, 3, 3, 5, 4, 6, 7}; !reflect.DeepEqual(have, want) {
+		t.Fatalf("handled messages mismatch: have %v, want %v", have, want)
+	}
+	// Reset the injector, releasing the held messages
+	in.deliver(Raft, Outbound, "1", rec.handler(8))
+	in.setSkew(time.Hour)
+	in.clear()
+	if have, want := rec.messages(), []int{2, 3, 3, 5, 4, 6, 7, 8}; !reflect.DeepEqual(have, want) {
+		t.Fatalf("held message not released on reset: have %v, want %v", have, want)
+	}
+	if skew := in.now().Sub(time.Now()); skew > time.Minute {
+		t.Fatalf("clock skew not reset: %v", skew)
+	}
+}
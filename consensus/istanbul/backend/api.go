@@ -17,9 +17,12 @@
 package backend
 
 import (
+	"github.com/ethereum/go-ethereum/adminauth"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/consensus"
+	istanbulCore "github.com/ethereum/go-ethereum/consensus/istanbul/core"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/rpc"
 )
 
@@ -42,6 +45,47 @@ func (api *API) NodeAddress() common.Address {
 	return api.istanbul.Address()
 }
 
+// ShadowReport returns how the messages this node would have sent as a shadow
+// validator compare with the blocks committed by the network.
+func (api *API) ShadowReport() (*istanbulCore.ShadowReport, error) {
+	report := api.istanbul.core.ShadowReport()
+	if report == nil {
+		return nil, errNotShadow
+	}
+	return report, nil
+}
+
+// NetworkTime returns the median of the clocks of the current validators, as
+// attested by their signed timestamps, and the skew of each from it.
+func (api *API) NetworkTime() (*NetworkTime, error) {
+	block := api.istanbul.currentBlock()
+	valSet := api.istanbul.getValidators(block.Number().Uint64(), block.Hash())
+	return api.istanbul.times.networkTime(validatorAddresses(valSet), api.istanbul.Address(), now())
+}
+
+// Vanity returns the vanity of the blocks proposed by the validator, empty if
+// taken from the miner extra-data.
+func (api *API) Vanity() string {
+	return string(api.istanbul.Vanity())
+}
+
+// SetVanity replaces the vanity of the blocks proposed by the validator, empty
+// to take it from the miner extra-data. If operator keys are configured the
+// call must carry an operator signature.
+func (api *API) SetVanity(vanity string, sig *adminauth.Signature) error {
+	if err := api.istanbul.operatorAuth.Verify("istanbul_setVanity", sig, vanity); err != nil {
+		return err
+	}
+	if vanity == "" {
+		return api.istanbul.SetVanity(nil)
+	}
+	if err := api.istanbul.SetVanity([]byte(vanity)); err != nil {
+		return err
+	}
+	log.Info("Updated the vanity of the proposed blocks", "vanity", vanity)
+	return nil
+}
+
 // GetSignersFromBlock returns the signers and minter for a given block number, or the
 // latest block available if none is specified
 func (api *API) GetSignersFromBlock(number *rpc.BlockNumber) (*BlockSigners, error) {
@@ -160,19 +204,49 @@ func (api *API) Candidates() map[common.Address]bool {
 }
 
 // Propose injects a new authorization candidate that the validator will attempt to
-// push through.
-func (api *API) Propose(address common.Address, auth bool) {
+// push through. If operator keys are configured the call must carry an operator
+// signature.
+func (api *API) Propose(address common.Address, auth bool, sig *adminauth.Signature) error {
+	if err := api.istanbul.operatorAuth.Verify("istanbul_propose", sig, address, auth); err != nil {
+		return err
+	}
+	// Quorum: check the removals don't stop the network given the offline validators
+	if !auth {
+		report, err := api.istanbul.simulateChange(api.chain, address, auth)
+		if err != nil {
+			return err
+		}
+		if !report.Safe {
+			if api.istanbul.config.RefuseUnsafe {
+				return &errUnsafeValidatorChange{report}
+			}
+			log.Warn("Proposed validator removal leaves too few validators online", "address", address, "online", report.Online, "validators", report.Validators, "quorum", report.Quorum, "offline", report.Offline)
+		}
+	}
 	api.istanbul.candidatesLock.Lock()
 	defer api.istanbul.candidatesLock.Unlock()
 
 	api.istanbul.candidates[address] = auth
+	return nil
+}
+
+// SimulateValidatorChange previews the fault tolerance of the validator set if
+// the given validator were added or removed, given the validators that didn't
+// take part in the recent blocks.
+func (api *API) SimulateValidatorChange(address common.Address, auth bool) (*ValidatorChangeReport, error) {
+	return api.istanbul.simulateChange(api.chain, address, auth)
 }
 
 // Discard drops a currently running candidate, stopping the validator from casting
-// further votes (either for or against).
-func (api *API) Discard(address common.Address) {
+// further votes (either for or against). If operator keys are configured the
+// call must carry an operator signature.
+func (api *API) Discard(address common.Address, sig *adminauth.Signature) error {
+	if err := api.istanbul.operatorAuth.Verify("istanbul_discard", sig, address); err != nil {
+		return err
+	}
 	api.istanbul.candidatesLock.Lock()
 	defer api.istanbul.candidatesLock.Unlock()
 
 	delete(api.istanbul.candidates, address)
+	return nil
 }
@@ -22,8 +22,10 @@ import (
 	"sync"
 	"time"
 
+	"github.com/ethereum/go-ethereum/adminauth"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/consensus/faultinject"
 	"github.com/ethereum/go-ethereum/consensus/istanbul"
 	istanbulCore "github.com/ethereum/go-ethereum/consensus/istanbul/core"
 	"github.com/ethereum/go-ethereum/consensus/istanbul/validator"
@@ -32,7 +34,9 @@ import (
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/kms"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params"
 	lru "github.com/hashicorp/golang-lru"
 )
 
@@ -51,7 +55,6 @@ func New(config *istanbul.Config, privateKey *ecdsa.PrivateKey, db ethdb.Databas
 		config:           config,
 		istanbulEventMux: new(event.TypeMux),
 		privateKey:       privateKey,
-		address:          crypto.PubkeyToAddress(privateKey.PublicKey),
 		logger:           log.New(),
 		db:               db,
 		commitCh:         make(chan *types.Block, 1),
@@ -60,6 +63,21 @@ func New(config *istanbul.Config, privateKey *ecdsa.PrivateKey, db ethdb.Databas
 		coreStarted:      false,
 		recentMessages:   recentMessages,
 		knownMessages:    knownMessages,
+		times:            newTimeService(),
+	}
+	if config.Vanity != "" {
+		backend.vanity = []byte(config.Vanity)
+	}
+	// The vanity policy is checked on startup, an invalid one refusing any block
+	// rather than accepting them all
+	policy, err := config.VanityPolicy()
+	if err != nil {
+		log.Error("Refusing all blocks for the invalid vanity policy", "err", err)
+		policy = func(common.Address, []byte) error { return err }
+	}
+	backend.vanityPolicy = policy
+	if privateKey != nil {
+		backend.address = crypto.PubkeyToAddress(privateKey.PublicKey)
 	}
 	backend.core = istanbulCore.New(backend, backend.config)
 	return backend
@@ -71,6 +89,7 @@ type backend struct {
 	config           *istanbul.Config
 	istanbulEventMux *event.TypeMux
 	privateKey       *ecdsa.PrivateKey
+	signer           kms.Signer // Signs the messages and seals with the private key in a KMS, nil to sign locally
 	address          common.Address
 	core             istanbulCore.Engine
 	logger           log.Logger
@@ -98,6 +117,56 @@ type backend struct {
 
 	recentMessages *lru.ARCCache // the cache of peer's messages
 	knownMessages  *lru.ARCCache // the cache of self messages
+
+	operatorAuth *adminauth.Authenticator // verifier of operator signatures on administrative calls
+
+	times    *timeService  // Timestamps attested by the validators
+	timeQuit chan struct{} // Stops the attestation of the local timestamps
+
+	vanity       []byte                // Vanity of the blocks proposed, nil to take it from the miner extra-data
+	vanityPolicy istanbul.VanityPolicy // Policy the vanity of the blocks committed must satisfy, nil to accept any
+	vanityLock   sync.RWMutex
+
+	proposalValidator istanbul.ProposalValidator // Vets the blocks proposed before committing to them, nil to commit to any valid block
+	proposalLock      sync.RWMutex
+
+	configHash     common.Hash         // Hash of the chain configuration, checked by the signing policy
+	configHashOf   *params.ChainConfig // Chain configuration the hash was computed of
+	configHashLock sync.Mutex
+}
+
+// SetOperatorAuthenticator sets the verifier used to authorise administrative
+// API calls such as istanbul_propose.
+func (sb *backend) SetOperatorAuthenticator(auth *adminauth.Authenticator) {
+	sb.operatorAuth = auth
+}
+
+// SetNodeSigner sets the signer of the messages and seals, whose key is the
+// validator key of the backend, the private key possibly being held in a KMS
+// only.
+func (sb *backend) SetNodeSigner(signer kms.Signer) {
+	sb.coreMu.Lock()
+	defer sb.coreMu.Unlock()
+
+	sb.signer = signer
+	if address := crypto.PubkeyToAddress(*signer.PublicKey()); address != sb.address && !sb.coreStarted {
+		sb.address = address
+		sb.core = istanbulCore.New(sb, sb.config)
+	}
+}
+
+// SetNodeKey replaces the validator key of the engine, as a standby node takes
+// over the identity of its primary. The engine must not be started.
+func (sb *backend) SetNodeKey(key *ecdsa.PrivateKey) error {
+	sb.coreMu.Lock()
+	defer sb.coreMu.Unlock()
+
+	if sb.coreStarted {
+		return istanbul.ErrStartedEngine
+	}
+	sb.privateKey, sb.address, sb.signer = key, crypto.PubkeyToAddress(key.PublicKey), nil
+	sb.core = istanbulCore.New(sb, sb.config)
+	return nil
 }
 
 // zekun: HACK
@@ -115,6 +184,20 @@ func (sb *backend) Validators(proposal istanbul.Proposal) istanbul.ValidatorSet
 	return sb.getValidators(proposal.Number().Uint64(), proposal.Hash())
 }
 
+// CurrentValidators returns the validators of the head block, nil if the engine
+// isn't started.
+func (sb *backend) CurrentValidators() []common.Address {
+	sb.coreMu.RLock()
+	currentBlock := sb.currentBlock
+	sb.coreMu.RUnlock()
+
+	if currentBlock == nil {
+		return nil
+	}
+	block := currentBlock()
+	return validatorAddresses(sb.getValidators(block.Number().Uint64(), block.Hash()))
+}
+
 // Broadcast implements istanbul.Backend.Broadcast
 func (sb *backend) Broadcast(valSet istanbul.ValidatorSet, payload []byte) error {
 	// send to others
@@ -157,7 +240,8 @@ func (sb *backend) Gossip(valSet istanbul.ValidatorSet, payload []byte) error {
 			m.Add(hash, true)
 			sb.recentMessages.Add(addr, m)
 
-			go p.Send(istanbulMsg, payload)
+			p := p
+			go faultinject.Deliver(faultinject.Istanbul, faultinject.Outbound, addr.Hex(), func() { p.Send(istanbulMsg, payload) })
 		}
 	}
 	return nil
@@ -235,7 +319,15 @@ func (sb *backend) Verify(proposal istanbul.Proposal) (time.Duration, error) {
 	err := sb.VerifyHeader(sb.chain, block.Header(), false)
 	// ignore errEmptyCommittedSeals error because we don't have the committed seals yet
 	if err == nil || err == errEmptyCommittedSeals {
-		return 0, nil
+		// Quorum: refuse to commit the blocks violating the signing or vanity policies
+		if err := sb.checkSigningPolicy(sb.chain, block.Header()); err != nil {
+			return 0, err
+		}
+		if err := sb.checkVanityPolicy(block.Header()); err != nil {
+			return 0, err
+		}
+		// Quorum: let the proposal validator plugin vet the block before the PREPARE and COMMIT
+		return 0, sb.checkProposal(block)
 	} else if err == consensus.ErrFutureBlock {
 		return time.Unix(block.Header().Time.Int64(), 0).Sub(now()), consensus.ErrFutureBlock
 	}
@@ -245,6 +337,9 @@ func (sb *backend) Verify(proposal istanbul.Proposal) (time.Duration, error) {
 // Sign implements istanbul.Backend.Sign
 func (sb *backend) Sign(data []byte) ([]byte, error) {
 	hashData := crypto.Keccak256([]byte(data))
+	if sb.signer != nil {
+		return sb.signer.Sign(hashData)
+	}
 	return crypto.Sign(hashData, sb.privateKey)
 }
 
@@ -316,6 +411,18 @@ func (sb *backend) HasBadProposal(hash common.Hash) bool {
 	return sb.hasBadBlock(hash)
 }
 
+// CurrentRound returns the round of the current sequence, zero while the core
+// is stopped.
+func (sb *backend) CurrentRound() uint64 {
+	sb.coreMu.RLock()
+	defer sb.coreMu.RUnlock()
+
+	if !sb.coreStarted {
+		return 0
+	}
+	return sb.core.CurrentRound()
+}
+
 func (sb *backend) Close() error {
 	return nil
 }
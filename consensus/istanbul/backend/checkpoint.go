@@ -0,0 +1,136 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package backend
+
+import (
+	"errors"
+	"math"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/istanbul"
+	istanbulCore "github.com/ethereum/go-ethereum/consensus/istanbul/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/kms"
+)
+
+var (
+	// errCheckpointMismatch is returned if the fields of a checkpoint don't match
+	// its header.
+	errCheckpointMismatch = errors.New("checkpoint fields don't match the header")
+
+	// errCheckpointSeals is returned if a checkpoint isn't committed by enough of
+	// its validators.
+	errCheckpointSeals = errors.New("checkpoint not committed by enough validators")
+)
+
+// Checkpoint is the artifact exported at each epoch boundary, for systems off the
+// chain to anchor its history. It carries the header of the epoch block, so that
+// the block hash, the state root, the validator set and the committed seals can
+// all be verified without access to the chain.
+type Checkpoint struct {
+	Number         hexutil.Uint64   `json:"number"`
+	Hash           common.Hash      `json:"hash"`
+	StateRoot      common.Hash      `json:"stateRoot"`
+	Validators     []common.Address `json:"validators"`
+	CommittedSeals []hexutil.Bytes  `json:"committedSeals"`
+	Header         *types.Header    `json:"header"`
+	Signature      hexutil.Bytes    `json:"signature"` // Exporting node signature over SigningHash
+}
+
+// NewCheckpoint creates the unsigned checkpoint of an Istanbul block.
+func NewCheckpoint(header *types.Header) (*Checkpoint, error) {
+	extra, err := types.ExtractIstanbulExtra(header)
+	if err != nil {
+		return nil, err
+	}
+	cp := &Checkpoint{
+		Number:     hexutil.Uint64(header.Number.Uint64()),
+		Hash:       header.Hash(),
+		StateRoot:  header.Root,
+		Validators: extra.Validators,
+		Header:     header,
+	}
+	for _, seal := range extra.CommittedSeal {
+		cp.CommittedSeals = append(cp.CommittedSeals, seal)
+	}
+	return cp, nil
+}
+
+// SigningHash returns the hash signed by the exporting node, the keccak256 hash of
+// the RLP list [number, hash, stateRoot, validators, committedSeals].
+func (cp *Checkpoint) SigningHash() common.Hash {
+	seals := make([][]byte, len(cp.CommittedSeals))
+	for i, seal := range cp.CommittedSeals {
+		seals[i] = seal
+	}
+	return istanbul.RLPHash([]interface{}{uint64(cp.Number), cp.Hash, cp.StateRoot, cp.Validators, seals})
+}
+
+// Sign signs the checkpoint with the key of the exporting node, possibly held
+// in a KMS.
+func (cp *Checkpoint) Sign(signer kms.Signer) error {
+	sig, err := signer.Sign(cp.SigningHash().Bytes())
+	if err != nil {
+		return err
+	}
+	cp.Signature = sig
+	return nil
+}
+
+// Verify checks that the checkpoint matches its header and was committed by more
+// than a third of its validators, which the consensus guarantees to include an
+// honest one. It returns the address of the node which exported the checkpoint.
+func (cp *Checkpoint) Verify() (common.Address, error) {
+	if cp.Header == nil || cp.Header.Number == nil {
+		return common.Address{}, errCheckpointMismatch
+	}
+	expected, err := NewCheckpoint(cp.Header)
+	if err != nil {
+		return common.Address{}, err
+	}
+	if expected.SigningHash() != cp.SigningHash() {
+		return common.Address{}, errCheckpointMismatch
+	}
+	// Count the distinct validators having committed the block
+	validators := make(map[common.Address]bool, len(cp.Validators))
+	for _, validator := range cp.Validators {
+		validators[validator] = true
+	}
+	committed := make(map[common.Address]bool)
+	proposalSeal := istanbulCore.PrepareCommittedSeal(cp.Hash)
+	for _, seal := range cp.CommittedSeals {
+		addr, err := istanbul.GetSignatureAddress(proposalSeal, seal)
+		if err != nil {
+			return common.Address{}, errInvalidSignature
+		}
+		if !validators[addr] {
+			return common.Address{}, errInvalidCommittedSeals
+		}
+		committed[addr] = true
+	}
+	if faulty := int(math.Ceil(float64(len(cp.Validators))/3)) - 1; len(committed) <= faulty {
+		return common.Address{}, errCheckpointSeals
+	}
+	// Recover the exporting node
+	pub, err := crypto.SigToPub(cp.SigningHash().Bytes(), cp.Signature)
+	if err != nil {
+		return common.Address{}, err
+	}
+	return crypto.PubkeyToAddress(*pub), nil
+}
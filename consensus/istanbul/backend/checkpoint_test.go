@@ -0,0 +1,76 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package backend
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	istanbulCore "github.com/ethereum/go-ethereum/consensus/istanbul/core"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/kms"
+)
+
+func TestCheckpoint(t *testing.T) {
+	chain, engine := newBlockChain(1)
+	header := makeBlockWithoutSeal(chain, engine, chain.Genesis()).Header()
+	seal, err := engine.Sign(istanbulCore.PrepareCommittedSeal(header.Hash()))
+	if err != nil {
+		t.Fatalf("failed to sign committed seal: %v", err)
+	}
+	if err := writeCommittedSeals(header, [][]byte{seal}); err != nil {
+		t.Fatalf("failed to write committed seals: %v", err)
+	}
+	cp, err := NewCheckpoint(header)
+	if err != nil {
+		t.Fatalf("failed to create checkpoint: %v", err)
+	}
+	if cp.Hash != header.Hash() || cp.StateRoot != header.Root || len(cp.Validators) != 1 || len(cp.CommittedSeals) != 1 {
+		t.Fatalf("checkpoint fields mismatch: %+v", cp)
+	}
+	key, _ := crypto.GenerateKey()
+	if err := cp.Sign(kms.NewLocalSigner(key)); err != nil {
+		t.Fatalf("failed to sign checkpoint: %v", err)
+	}
+	// The checkpoint must verify once exported
+	blob, err := json.Marshal(cp)
+	if err != nil {
+		t.Fatalf("failed to encode checkpoint: %v", err)
+	}
+	exported := new(Checkpoint)
+	if err := json.Unmarshal(blob, exported); err != nil {
+		t.Fatalf("failed to decode checkpoint: %v", err)
+	}
+	signer, err := exported.Verify()
+	if err != nil {
+		t.Fatalf("failed to verify checkpoint: %v", err)
+	}
+	if signer != crypto.PubkeyToAddress(key.PublicKey) {
+		t.Fatalf("signer mismatch: have %x, want %x", signer, crypto.PubkeyToAddress(key.PublicKey))
+	}
+	// Checkpoints not matching their header or not committed must be rejected
+	tampered := *exported
+	tampered.StateRoot = common.HexToHash("0x01")
+	if _, err := tampered.Verify(); err != errCheckpointMismatch {
+		t.Fatalf("tampered checkpoint: have %v, want %v", err, errCheckpointMismatch)
+	}
+	uncommitted, _ := NewCheckpoint(chain.Genesis().Header())
+	if _, err := uncommitted.Verify(); err != errCheckpointSeals {
+		t.Fatalf("uncommitted checkpoint: have %v, want %v", err, errCheckpointSeals)
+	}
+}
@@ -26,6 +26,7 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/consensus/faultinject"
 	"github.com/ethereum/go-ethereum/consensus/istanbul"
 	"github.com/ethereum/go-ethereum/consensus/istanbul/validator"
 	istanbulCore "github.com/ethereum/go-ethereum/consensus/istanbul/core"
@@ -82,12 +83,15 @@ var (
 	errEmptyCommittedSeals = errors.New("zero committed seals")
 	// errMismatchTxhashes is returned if the TxHash in header is mismatch.
 	errMismatchTxhashes = errors.New("mismatch transcations hashes")
+	// errNotShadow is returned when the shadow report is requested from a node
+	// not running as a shadow validator.
+	errNotShadow = errors.New("not running as a shadow validator")
 )
 var (
 	defaultDifficulty = big.NewInt(1)
 	nilUncleHash      = types.CalcUncleHash(nil) // Always Keccak256(RLP([])) as uncles are meaningless outside of PoW.
 	emptyNonce        = types.BlockNonce{}
-	now               = time.Now
+	now               = faultinject.Now
 
 	nonceAuthVote = hexutil.MustDecode("0xffffffffffffffff") // Magic nonce number to vote on adding a new validator
 	nonceDropVote = hexutil.MustDecode("0x0000000000000000") // Magic nonce number to vote on removing a validator.
@@ -379,6 +383,10 @@ func (sb *backend) Prepare(chain consensus.ChainReader, header *types.Header) er
 		}
 	}
 
+	// Quorum: use the vanity of the validator over the miner extra-data
+	if vanity := sb.Vanity(); vanity != nil {
+		header.Extra = vanity
+	}
 	// add validators in snapshot to extraData's validators section
 	extra, err := prepareExtra(header, snap.validators())
 	if err != nil {
@@ -388,8 +396,8 @@ func (sb *backend) Prepare(chain consensus.ChainReader, header *types.Header) er
 
 	// set header's timestamp
 	header.Time = new(big.Int).Add(parent.Time, new(big.Int).SetUint64(sb.config.BlockPeriod))
-	if header.Time.Int64() < time.Now().Unix() {
-		header.Time = big.NewInt(time.Now().Unix())
+	if header.Time.Int64() < faultinject.Now().Unix() {
+		header.Time = big.NewInt(faultinject.Now().Unix())
 	}
 	return nil
 }
@@ -430,6 +438,10 @@ func (sb *backend) Seal(chain consensus.ChainReader, block *types.Block, results
 	if parent == nil {
 		return consensus.ErrUnknownAncestor
 	}
+	// Quorum: refuse to propose the blocks violating the signing policy
+	if err := sb.checkSigningPolicy(chain, header); err != nil {
+		return err
+	}
 	block, err = sb.updateBlock(parent, block)
 	if err != nil {
 		return err
@@ -525,6 +537,10 @@ func (sb *backend) Start(chain consensus.ChainReader, currentBlock func() *types
 	if err := sb.core.Start(); err != nil {
 		return err
 	}
+	if sb.config.TimeAttestation > 0 {
+		sb.timeQuit = make(chan struct{})
+		go sb.attestTime(sb.config.TimeAttestation, sb.timeQuit)
+	}
 
 	sb.coreStarted = true
 	return nil
@@ -540,6 +556,10 @@ func (sb *backend) Stop() error {
 	if err := sb.core.Stop(); err != nil {
 		return err
 	}
+	if sb.timeQuit != nil {
+		close(sb.timeQuit)
+		sb.timeQuit = nil
+	}
 	sb.coreStarted = false
 	return nil
 }
@@ -582,6 +602,22 @@ func (sb *backend) snapshot(chain consensus.ChainReader, number uint64, hash com
 			log.Trace("Stored genesis voting snapshot to disk")
 			break
 		}
+		// If we're at an epoch block whose ancestors are unknown, the header was
+		// retrieved via a trusted checkpoint (e.g. the CHT of a light client). Its
+		// extra-data lists the validators of its parent and votes are reset at the
+		// epoch anyway, so the snapshot can be rebuilt from it.
+		if number%sb.config.Epoch == 0 && len(parents) == 0 {
+			if header := chain.GetHeader(hash, number); header != nil && chain.GetHeader(header.ParentHash, number-1) == nil {
+				istanbulExtra, err := types.ExtractIstanbulExtra(header)
+				if err != nil {
+					return nil, err
+				}
+				snap = newSnapshot(sb.config.Epoch, number-1, header.ParentHash, validator.NewSet(istanbulExtra.Validators, sb.config.ProposerPolicy))
+				headers = append(headers, header)
+				log.Debug("Loaded voting snapshot from checkpoint", "number", number, "hash", hash)
+				break
+			}
+		}
 		// No snapshot for this header, gather the header and move backward
 		var header *types.Header
 		if len(parents) > 0 {
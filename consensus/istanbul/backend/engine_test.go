@@ -28,7 +28,9 @@ import (
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/consensus"
 	"github.com/ethereum/go-ethereum/consensus/istanbul"
+	istanbulCore "github.com/ethereum/go-ethereum/consensus/istanbul/core"
 	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/crypto"
@@ -336,6 +338,64 @@ func TestVerifyHeader(t *testing.T) {
 	}
 }
 
+// Tests that a light client which joined the chain via a trusted checkpoint
+// can verify the headers following it, without knowing any of its ancestors.
+func TestVerifyHeaderFromCheckpoint(t *testing.T) {
+	chain, engine := newBlockChain(1)
+
+	// Blocks are timestamped with the current time, so accept them as such
+	defer func(old func() time.Time) { now = old }(now)
+	now = func() time.Time { return time.Now().Add(time.Minute) }
+
+	blocks := []*types.Block{chain.Genesis()}
+	for i := 0; i < 3; i++ {
+		block := makeBlockWithoutSeal(chain, engine, blocks[i])
+		block, _ = engine.updateBlock(blocks[i].Header(), block)
+
+		header := block.Header()
+		seal, err := engine.Sign(istanbulCore.PrepareCommittedSeal(header.Hash()))
+		if err != nil {
+			t.Fatalf("failed to sign committed seal: %v", err)
+		}
+		if err := writeCommittedSeals(header, [][]byte{seal}); err != nil {
+			t.Fatalf("failed to write committed seals: %v", err)
+		}
+		block = block.WithSeal(header)
+		if _, err := chain.InsertChain(types.Blocks{block}); err != nil {
+			t.Fatalf("failed to insert block %d: %v", block.NumberU64(), err)
+		}
+		blocks = append(blocks, block)
+	}
+	// Create a light client database knowing only the genesis and the epoch
+	// block retrieved from the checkpoint
+	db := ethdb.NewMemDatabase()
+	for _, block := range []*types.Block{blocks[0], blocks[2]} {
+		rawdb.WriteHeader(db, block.Header())
+		rawdb.WriteCanonicalHash(db, block.Hash(), block.NumberU64())
+	}
+	rawdb.WriteHeadBlockHash(db, blocks[2].Hash())
+
+	config := *engine.config
+	config.Epoch = 2
+	key, _ := crypto.GenerateKey()
+	light := New(&config, key, db).(*backend)
+
+	headers, err := core.NewHeaderChain(db, chain.Config(), light, func() bool { return false })
+	if err != nil {
+		t.Fatalf("failed to create header chain: %v", err)
+	}
+	if err := light.VerifyHeader(headers, blocks[3].Header(), true); err != nil {
+		t.Errorf("failed to verify header after checkpoint: %v", err)
+	}
+	snap, err := light.snapshot(headers, 2, blocks[2].Hash(), nil)
+	if err != nil {
+		t.Fatalf("failed to retrieve snapshot: %v", err)
+	}
+	if validators := snap.validators(); len(validators) != 1 || validators[0] != engine.Address() {
+		t.Errorf("validators mismatch: have %x, want [%x]", validators, engine.Address())
+	}
+}
+
 func TestVerifySeal(t *testing.T) {
 	chain, engine := newBlockChain(1)
 	genesis := chain.Genesis()
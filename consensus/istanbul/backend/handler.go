@@ -27,7 +27,9 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/consensus/faultinject"
 	"github.com/ethereum/go-ethereum/consensus/istanbul"
+	istanbulCore "github.com/ethereum/go-ethereum/consensus/istanbul/core"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/p2p"
 	"github.com/hashicorp/golang-lru"
@@ -93,8 +95,21 @@ func (sb *backend) HandleMsg(addr common.Address, msg p2p.Msg) (bool, error) {
 		}
 		sb.knownMessages.Add(hash, true)
 
-		go sb.istanbulEventMux.Post(istanbul.MessageEvent{
-			Payload: data,
+		// Quorum: relay the message regardless of its handling, for the validators
+		// behind this one to receive the messages of past and future rounds too
+		if sb.config.Relay && !sb.config.Shadow {
+			go sb.relay(addr, data)
+		}
+		// Quorum: the timestamp attestations are kept by the backend, outside of
+		// the rounds
+		if code, err := istanbulCore.MessageCode(data); err == nil && code == istanbulCore.MsgTimestamp {
+			go sb.handleTimeAttestation(data)
+			return true, nil
+		}
+		go faultinject.Deliver(faultinject.Istanbul, faultinject.Inbound, addr.Hex(), func() {
+			sb.istanbulEventMux.Post(istanbul.MessageEvent{
+				Payload: data,
+			})
 		})
 
 		return true, nil
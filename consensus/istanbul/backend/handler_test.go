@@ -18,14 +18,18 @@ package backend
 
 import (
 	"bytes"
+	"crypto/ecdsa"
 	"io/ioutil"
 	"math/big"
 	"testing"
+	"time"
 
 	"github.com/ethereum/go-ethereum/core/types"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
 	"github.com/ethereum/go-ethereum/consensus/istanbul"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/p2p"
 	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/hashicorp/golang-lru"
@@ -182,3 +186,85 @@ func buildArbitraryP2PNewBlockMessage(t *testing.T, invalidMsg bool) (*types.Blo
 	arbitraryP2PMessage := p2p.Msg{Code: 0x07, Size: uint32(size), Payload: bytes.NewReader(payload)}
 	return arbitraryBlock, arbitraryP2PMessage
 }
+
+// relayPeer records the consensus messages sent to a validator.
+type relayPeer chan []byte
+
+func (p relayPeer) Send(msgcode uint64, data interface{}) error {
+	p <- data.([]byte)
+	return nil
+}
+
+// relayBroadcaster connects the validators of a relaying test.
+type relayBroadcaster map[common.Address]relayPeer
+
+func (b relayBroadcaster) Enqueue(id string, block *types.Block) {}
+
+func (b relayBroadcaster) FindPeers(targets map[common.Address]bool) map[common.Address]consensus.Peer {
+	peers := make(map[common.Address]consensus.Peer)
+	for addr, p := range b {
+		if targets[addr] {
+			peers[addr] = p
+		}
+	}
+	return peers
+}
+
+// Tests that the consensus messages signed by a validator are relayed to the
+// connected validators but the sender, once only, and that the others aren't.
+func TestRelayMessage(t *testing.T) {
+	chain, backend := newBlockChain(3)
+	config := *backend.config
+	config.Relay = true
+	backend.config = &config
+
+	var (
+		validators = backend.getValidators(0, chain.Genesis().Hash()).List()
+		sender     common.Address
+		receiver   common.Address
+		peers      = make(relayBroadcaster)
+	)
+	for _, val := range validators {
+		if val.Address() == backend.Address() {
+			continue
+		}
+		if sender == (common.Address{}) {
+			sender = val.Address()
+		} else {
+			receiver = val.Address()
+		}
+		peers[val.Address()] = make(relayPeer, 2)
+	}
+	backend.SetBroadcaster(peers)
+
+	sign := func(key *ecdsa.PrivateKey, data []byte) []byte {
+		author := crypto.PubkeyToAddress(key.PublicKey)
+		unsigned, _ := rlp.EncodeToBytes([]interface{}{uint64(1), data, author, []byte{}, []byte{}})
+		sig, _ := crypto.Sign(crypto.Keccak256(unsigned), key)
+		payload, _ := rlp.EncodeToBytes([]interface{}{uint64(1), data, author, sig, []byte{}})
+		return payload
+	}
+	stranger, _ := crypto.GenerateKey()
+	valid, invalid := sign(backend.privateKey, []byte("prepare")), sign(stranger, []byte("prepare"))
+
+	for i := 0; i < 2; i++ {
+		if _, err := backend.HandleMsg(sender, makeMsg(istanbulMsg, valid)); err != nil {
+			t.Fatalf("failed to handle message: %v", err)
+		}
+	}
+	if _, err := backend.HandleMsg(sender, makeMsg(istanbulMsg, invalid)); err != nil {
+		t.Fatalf("failed to handle message: %v", err)
+	}
+	select {
+	case payload := <-peers[receiver]:
+		if !bytes.Equal(payload, valid) {
+			t.Fatalf("relayed message mismatch: have %x, want %x", payload, valid)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("message not relayed")
+	}
+	time.Sleep(50 * time.Millisecond)
+	if len(peers[receiver]) != 0 || len(peers[sender]) != 0 {
+		t.Fatalf("unexpected messages relayed: %d to the receiver, %d back to the sender", len(peers[receiver]), len(peers[sender]))
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package backend
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+var refusedBlockMeter = metrics.NewRegisteredMeter("consensus/istanbul/policy/refused", nil)
+
+// errSigningPolicy is returned when a block violates the signing policy.
+type errSigningPolicy struct {
+	reason string
+}
+
+func (e *errSigningPolicy) Error() string {
+	return "signing policy violated: " + e.reason
+}
+
+// checkSigningPolicy returns an error if the validator must refuse to sign the
+// given block header, as proposer or committer, under its signing policy.
+func (sb *backend) checkSigningPolicy(chain consensus.ChainReader, header *types.Header) error {
+	policy := sb.config.SigningPolicy
+	if policy == nil {
+		return nil
+	}
+	err := sb.violatesSigningPolicy(chain, header)
+	if err != nil {
+		refusedBlockMeter.Mark(1)
+		sb.logger.Error("Refusing to sign block violating the signing policy", "number", header.Number, "hash", header.Hash(), "err", err)
+	}
+	return err
+}
+
+func (sb *backend) violatesSigningPolicy(chain consensus.ChainReader, header *types.Header) error {
+	policy := sb.config.SigningPolicy
+
+	if policy.MinGasLimit != 0 && header.GasLimit < policy.MinGasLimit {
+		return &errSigningPolicy{fmt.Sprintf("gas limit %d below %d", header.GasLimit, policy.MinGasLimit)}
+	}
+	if policy.MaxGasLimit != 0 && header.GasLimit > policy.MaxGasLimit {
+		return &errSigningPolicy{fmt.Sprintf("gas limit %d above %d", header.GasLimit, policy.MaxGasLimit)}
+	}
+	if policy.ChainConfigHash != (common.Hash{}) {
+		hash, err := sb.chainConfigHash(chain.Config())
+		if err != nil {
+			return &errSigningPolicy{fmt.Sprintf("chain config not encodable: %v", err)}
+		}
+		if hash != policy.ChainConfigHash {
+			return &errSigningPolicy{fmt.Sprintf("chain config hash %x, want %x", hash, policy.ChainConfigHash)}
+		}
+	}
+	if policy.VerifiedParent {
+		// The headers may be known unverified, the full blocks only once imported
+		number := header.Number.Uint64()
+		if number == 0 || chain.GetBlock(header.ParentHash, number-1) == nil {
+			return &errSigningPolicy{fmt.Sprintf("parent %x not imported locally", header.ParentHash)}
+		}
+		if sb.hasBadBlock != nil && sb.hasBadBlock(header.ParentHash) {
+			return &errSigningPolicy{fmt.Sprintf("parent %x is a bad block", header.ParentHash)}
+		}
+	}
+	return nil
+}
+
+// chainConfigHash returns the hash of a chain configuration, as exchanged with
+// the peers. It is cached, the configuration of the chain not changing while
+// the node runs.
+func (sb *backend) chainConfigHash(config *params.ChainConfig) (common.Hash, error) {
+	sb.configHashLock.Lock()
+	defer sb.configHashLock.Unlock()
+
+	if sb.configHashOf != config {
+		_, hash, err := config.EncodeJSON()
+		if err != nil {
+			return common.Hash{}, err
+		}
+		sb.configHash, sb.configHashOf = hash, config
+	}
+	return sb.configHash, nil
+}
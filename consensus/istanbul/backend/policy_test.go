@@ -0,0 +1,71 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package backend
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/istanbul"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+func TestSigningPolicy(t *testing.T) {
+	chain, engine := newBlockChain(1)
+	block, err := engine.updateBlock(chain.Genesis().Header(), makeBlockWithoutSeal(chain, engine, chain.Genesis()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	gasLimit := block.GasLimit()
+
+	_, configHash, _ := chain.Config().EncodeJSON()
+
+	tests := []struct {
+		policy  *istanbul.SigningPolicy
+		refused bool
+	}{
+		{nil, false},
+		{&istanbul.SigningPolicy{MinGasLimit: gasLimit, MaxGasLimit: gasLimit, ChainConfigHash: configHash, VerifiedParent: true}, false},
+		{&istanbul.SigningPolicy{MinGasLimit: gasLimit + 1}, true},
+		{&istanbul.SigningPolicy{MaxGasLimit: gasLimit - 1}, true},
+		{&istanbul.SigningPolicy{ChainConfigHash: common.HexToHash("0x01")}, true},
+	}
+	stop := make(chan struct{})
+	defer close(stop)
+
+	for i, tt := range tests {
+		config := *istanbul.DefaultConfig
+		config.SigningPolicy = tt.policy
+		engine.config = &config
+
+		if _, err := engine.Verify(block); (err != nil) != tt.refused {
+			t.Errorf("test %d: verify refusal mismatch: have %v, want refused %v", i, err, tt.refused)
+		}
+		if err := engine.Seal(chain, block, make(chan *types.Block, 1), stop); (err != nil) != tt.refused {
+			t.Errorf("test %d: seal refusal mismatch: have %v, want refused %v", i, err, tt.refused)
+		}
+	}
+	// The blocks on top of a parent unknown locally are refused
+	engine.config = &istanbul.Config{SigningPolicy: &istanbul.SigningPolicy{VerifiedParent: true}}
+	defer func() { engine.config = istanbul.DefaultConfig }()
+
+	orphan := block.Header()
+	orphan.ParentHash = common.HexToHash("0xdead")
+	if err := engine.checkSigningPolicy(chain, orphan); err == nil {
+		t.Errorf("block of an unknown parent accepted")
+	}
+}
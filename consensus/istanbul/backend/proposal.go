@@ -0,0 +1,102 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package backend
+
+import (
+	"context"
+	"time"
+
+	"github.com/ethereum/go-ethereum/consensus/istanbul"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+var (
+	refusedProposalMeter  = metrics.NewRegisteredMeter("consensus/istanbul/proposal/refused", nil)
+	unvettedProposalMeter = metrics.NewRegisteredMeter("consensus/istanbul/proposal/unvetted", nil)
+)
+
+// errProposalRefused is returned when the proposal validator rejects a block.
+type errProposalRefused struct {
+	reason string
+}
+
+func (e *errProposalRefused) Error() string {
+	return "proposal refused by validator: " + e.reason
+}
+
+// proposalVerdict is the outcome of the vetting of a block.
+type proposalVerdict struct {
+	accepted bool
+	reason   string
+	err      error
+}
+
+// SetProposalValidator replaces the validator vetting the blocks proposed
+// before committing to them, nil to commit to any valid block.
+func (sb *backend) SetProposalValidator(validator istanbul.ProposalValidator) {
+	sb.proposalLock.Lock()
+	defer sb.proposalLock.Unlock()
+
+	sb.proposalValidator = validator
+}
+
+// checkProposal returns an error if the proposal validator rejects the given
+// block. The validator has a limited time to vet the block, which is accepted
+// if the validator fails or doesn't respond in time, lest a faulty plugin halt
+// the chain.
+func (sb *backend) checkProposal(block *types.Block) error {
+	sb.proposalLock.RLock()
+	validator := sb.proposalValidator
+	sb.proposalLock.RUnlock()
+
+	if validator == nil {
+		return nil
+	}
+	timeout := sb.config.ProposalValidationTimeout
+	if timeout <= 0 {
+		timeout = istanbul.DefaultConfig.ProposalValidationTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	// Wait for the verdict apart, the validator may not honour the deadline
+	start := time.Now()
+	verdictCh := make(chan proposalVerdict, 1)
+	go func() {
+		accepted, reason, err := validator(ctx, block)
+		verdictCh <- proposalVerdict{accepted, reason, err}
+	}()
+	select {
+	case verdict := <-verdictCh:
+		switch {
+		case verdict.err != nil:
+			unvettedProposalMeter.Mark(1)
+			sb.logger.Warn("Failed to vet proposed block, accepting", "number", block.Number(), "hash", block.Hash(), "err", verdict.err)
+		case !verdict.accepted:
+			refusedProposalMeter.Mark(1)
+			sb.logger.Warn("Refusing to commit block rejected by the proposal validator", "number", block.Number(), "hash", block.Hash(), "reason", verdict.reason)
+			return &errProposalRefused{verdict.reason}
+		default:
+			sb.logger.Trace("Vetted proposed block", "number", block.Number(), "hash", block.Hash(), "elapsed", time.Since(start))
+		}
+	case <-ctx.Done():
+		unvettedProposalMeter.Mark(1)
+		sb.logger.Warn("Proposed block not vetted in time, accepting", "number", block.Number(), "hash", block.Hash(), "timeout", timeout)
+	}
+	return nil
+}
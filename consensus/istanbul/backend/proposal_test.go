@@ -0,0 +1,82 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package backend
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// Tests that the blocks rejected by the proposal validator aren't committed,
+// and that the blocks it fails to vet in time are.
+func TestProposalValidator(t *testing.T) {
+	chain, engine := newBlockChain(1)
+	defer engine.SetProposalValidator(nil)
+
+	config := *engine.config
+	config.ProposalValidationTimeout = 50 * time.Millisecond
+	engine.config = &config
+
+	block, err := engine.updateBlock(chain.Genesis().Header(), makeBlockWithoutSeal(chain, engine, chain.Genesis()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var vetted *types.Block
+	engine.SetProposalValidator(func(ctx context.Context, block *types.Block) (bool, string, error) {
+		vetted = block
+		return true, "", nil
+	})
+	if _, err := engine.Verify(block); err != nil {
+		t.Fatalf("block accepted by the validator refused: %v", err)
+	}
+	if vetted == nil || vetted.Hash() != block.Hash() {
+		t.Fatalf("proposed block not vetted")
+	}
+	// The blocks rejected aren't committed
+	engine.SetProposalValidator(func(ctx context.Context, block *types.Block) (bool, string, error) {
+		return false, "sanctioned address", nil
+	})
+	if _, err := engine.Verify(block); err == nil {
+		t.Fatalf("block rejected by the validator committed")
+	} else if _, ok := err.(*errProposalRefused); !ok {
+		t.Fatalf("refusal error mismatch: have %v", err)
+	}
+	// The blocks the validator fails to vet, or in time, are committed
+	engine.SetProposalValidator(func(ctx context.Context, block *types.Block) (bool, string, error) {
+		return false, "", errors.New("plugin unavailable")
+	})
+	if _, err := engine.Verify(block); err != nil {
+		t.Fatalf("block not vetted refused: %v", err)
+	}
+	release := make(chan struct{})
+	defer close(release)
+	engine.SetProposalValidator(func(ctx context.Context, block *types.Block) (bool, string, error) {
+		<-release // ignores the deadline
+		return false, "too late", nil
+	})
+	start := time.Now()
+	if _, err := engine.Verify(block); err != nil {
+		t.Fatalf("block not vetted in time refused: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("vetting exceeded its timeout: %v", elapsed)
+	}
+}
@@ -0,0 +1,51 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package backend
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	istanbulCore "github.com/ethereum/go-ethereum/consensus/istanbul/core"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+var (
+	relayedMessageMeter = metrics.NewRegisteredMeter("consensus/istanbul/relay/messages", nil)
+	invalidRelayMeter   = metrics.NewRegisteredMeter("consensus/istanbul/relay/invalid", nil)
+)
+
+// relay forwards a consensus message received from a peer to the connected
+// validators, for the validators not connected to its author to receive it
+// through the ones in between. Only the messages signed by a validator are
+// relayed. The duplicates are suppressed by the message caches, each message
+// being relayed once and never back to a peer known to have it.
+func (sb *backend) relay(from common.Address, payload []byte) {
+	author, err := istanbulCore.MessageAuthor(payload)
+	if err != nil {
+		invalidRelayMeter.Mark(1)
+		sb.logger.Debug("Not relaying invalid consensus message", "from", from, "err", err)
+		return
+	}
+	block := sb.currentBlock()
+	valSet := sb.getValidators(block.Number().Uint64(), block.Hash())
+	if _, val := valSet.GetByAddress(author); val == nil {
+		invalidRelayMeter.Mark(1)
+		sb.logger.Debug("Not relaying consensus message of a non-validator", "from", from, "author", author)
+		return
+	}
+	relayedMessageMeter.Mark(1)
+	sb.Gossip(valSet, payload)
+}
@@ -0,0 +1,116 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package backend
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+)
+
+// livenessWindow is the number of recent blocks a validator must have sealed or
+// committed to be considered online.
+const livenessWindow = 64
+
+// ValidatorChangeReport is the fault tolerance of the validator set resulting
+// from a change, given the validators currently offline.
+type ValidatorChangeReport struct {
+	Address    common.Address   `json:"address"`
+	Auth       bool             `json:"auth"`       // Whether the change adds or removes the validator
+	Validators int              `json:"validators"` // Size of the validator set after the change
+	Online     int              `json:"online"`     // Validators seen online after the change
+	Offline    []common.Address `json:"offline"`    // Validators unseen in the recent blocks, after the change
+	Quorum     int              `json:"quorum"`     // Committed seals required after the change
+	MaxFaulty  int              `json:"maxFaulty"`  // Faulty validators tolerated by the set after the change
+	Margin     int              `json:"margin"`     // Validators that may still go offline before blocks stop
+	Safe       bool             `json:"safe"`       // Whether enough validators remain online to reach the quorum
+}
+
+// errUnsafeValidatorChange is returned when a validator removal is proposed that
+// would leave too few validators online to reach the quorum.
+type errUnsafeValidatorChange struct {
+	report *ValidatorChangeReport
+}
+
+func (e *errUnsafeValidatorChange) Error() string {
+	return fmt.Sprintf("removing %s leaves %d of %d validators online, below the quorum of %d", e.report.Address.Hex(), e.report.Online, e.report.Validators, e.report.Quorum)
+}
+
+// simulateChange reports the fault tolerance of the validator set at the head
+// of the chain if the given validator were added or removed. The validators
+// neither sealing nor committing any of the last livenessWindow blocks are
+// considered offline, as is an added validator until it takes part.
+func (sb *backend) simulateChange(chain consensus.ChainReader, address common.Address, auth bool) (*ValidatorChangeReport, error) {
+	head := chain.CurrentHeader()
+	snap, err := sb.snapshot(chain, head.Number.Uint64(), head.Hash(), nil)
+	if err != nil {
+		return nil, err
+	}
+	// Collect the validators taking part in the recent blocks
+	online := map[common.Address]bool{sb.Address(): true}
+	for header, i := head, 0; header != nil && header.Number.Sign() > 0 && i < livenessWindow; i++ {
+		if author, err := sb.Author(header); err == nil {
+			online[author] = true
+		}
+		if committers, err := sb.Signers(header); err == nil {
+			for _, addr := range committers {
+				online[addr] = true
+			}
+		}
+		header = chain.GetHeader(header.ParentHash, header.Number.Uint64()-1)
+	}
+	// Apply the change to the validator set
+	validators := make(map[common.Address]bool)
+	for _, addr := range snap.validators() {
+		validators[addr] = true
+	}
+	added := auth && !validators[address]
+	if auth {
+		validators[address] = true
+	} else {
+		delete(validators, address)
+	}
+	report := &ValidatorChangeReport{
+		Address:    address,
+		Auth:       auth,
+		Validators: len(validators),
+		Offline:    []common.Address{},
+	}
+	for addr := range validators {
+		if online[addr] && !(added && addr == address) {
+			report.Online++
+		} else {
+			report.Offline = append(report.Offline, addr)
+		}
+	}
+	sort.Slice(report.Offline, func(i, j int) bool {
+		return bytes.Compare(report.Offline[i][:], report.Offline[j][:]) < 0
+	})
+	report.MaxFaulty = int(math.Ceil(float64(report.Validators)/3)) - 1
+	report.Quorum = 2*report.MaxFaulty + 1
+	if ceil := sb.config.Ceil2Nby3Block; ceil != nil && new(big.Int).Add(head.Number, common.Big1).Cmp(ceil) >= 0 {
+		report.Quorum = int(math.Ceil(float64(2*report.Validators) / 3))
+	}
+	report.Margin = report.Online - report.Quorum
+	report.Safe = report.Validators > 0 && report.Margin >= 0
+	return report, nil
+}
@@ -0,0 +1,102 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package backend
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/istanbul"
+	istanbulCore "github.com/ethereum/go-ethereum/consensus/istanbul/core"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+// headChain serves a head header not inserted in the chain.
+type headChain struct {
+	*core.BlockChain
+	head *types.Header
+}
+
+func (c *headChain) CurrentHeader() *types.Header { return c.head }
+
+func (c *headChain) GetHeader(hash common.Hash, number uint64) *types.Header {
+	if hash == c.head.Hash() {
+		return c.head
+	}
+	return c.BlockChain.GetHeader(hash, number)
+}
+
+func TestSimulateValidatorChange(t *testing.T) {
+	genesis, keys := getGenesisAndKeys(7)
+	db := ethdb.NewMemDatabase()
+	config := *istanbul.DefaultConfig
+	b, _ := New(&config, keys[0], db).(*backend)
+	genesis.MustCommit(db)
+	blockchain, err := core.NewBlockChain(db, nil, genesis.Config, b, vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer blockchain.Stop()
+
+	// Seal a block by the local validator, committed by three others only
+	header := makeBlockWithoutSeal(blockchain, b, blockchain.Genesis()).Header()
+	seal, _ := b.Sign(sigHash(header).Bytes())
+	writeSeal(header, seal)
+
+	var committed [][]byte
+	for _, key := range keys[1:4] {
+		sig, _ := crypto.Sign(crypto.Keccak256(istanbulCore.PrepareCommittedSeal(header.Hash())), key)
+		committed = append(committed, sig)
+	}
+	writeCommittedSeals(header, committed)
+
+	chain := &headChain{blockchain, header}
+	addr := func(i int) common.Address { return crypto.PubkeyToAddress(keys[i].PublicKey) }
+
+	// Removing an offline validator leaves just enough online for the quorum
+	report, err := b.simulateChange(chain, addr(6), false)
+	if err != nil {
+		t.Fatalf("failed to simulate: %v", err)
+	}
+	if report.Validators != 6 || report.Online != 4 || len(report.Offline) != 2 || report.Quorum != 4 || report.MaxFaulty != 1 || !report.Safe {
+		t.Errorf("offline removal: wrong report %+v", report)
+	}
+	// Removing an online one doesn't
+	if report, _ = b.simulateChange(chain, addr(1), false); report.Online != 3 || report.Margin != -1 || report.Safe {
+		t.Errorf("online removal: wrong report %+v", report)
+	}
+	// Nor does adding a validator, offline until it joins
+	if report, _ = b.simulateChange(chain, common.Address{0x01}, true); report.Validators != 8 || report.Quorum != 6 || report.Safe {
+		t.Errorf("addition: wrong report %+v", report)
+	}
+	// Unsafe removals are refused if so configured, proposed with a warning otherwise
+	api := &API{chain: chain, istanbul: b}
+	if err := api.Propose(addr(1), false, nil); err != nil {
+		t.Errorf("unsafe removal not proposed: %v", err)
+	}
+	config.RefuseUnsafe = true
+	if err := api.Propose(addr(1), false, nil); err == nil {
+		t.Errorf("unsafe removal proposed")
+	}
+	if err := api.Propose(addr(6), false, nil); err != nil {
+		t.Errorf("safe removal refused: %v", err)
+	}
+}
@@ -0,0 +1,265 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package backend
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/ntp"
+	"github.com/ethereum/go-ethereum/consensus/istanbul"
+	istanbulCore "github.com/ethereum/go-ethereum/consensus/istanbul/core"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+const (
+	// timeAttestationExpiry is the age from which the timestamp attested by a
+	// validator is no longer counted in the network time.
+	timeAttestationExpiry = 5 * time.Minute
+
+	// timeSkewWarning is the skew of the local clock from the network time from
+	// which a warning is logged, the blocks from the future being rejected.
+	timeSkewWarning = time.Second
+
+	ntpResync = 10 * time.Minute // Interval between two queries of the NTP server
+	ntpChecks = 3                // Number of measurements to do against the NTP server
+)
+
+var (
+	timeAttestationsMeter = metrics.NewRegisteredMeter("consensus/istanbul/time/attestations", nil)
+	timeInvalidMeter      = metrics.NewRegisteredMeter("consensus/istanbul/time/invalid", nil)
+	timeSkewGauge         = metrics.NewRegisteredGauge("consensus/istanbul/time/skew", nil) // Skew of the local clock from the network time, in ms
+
+	// errNoNetworkTime is returned when the network time is requested before any
+	// timestamp was attested by a validator.
+	errNoNetworkTime = errors.New("no timestamp attested by the validators")
+)
+
+// timeAttestation is the content of the timestamp attestation of a validator.
+type timeAttestation struct {
+	Time uint64 // Unix time of the validator in nanoseconds, derived from NTP if configured
+}
+
+// NetworkTime is the median of the clocks of the validators, as attested by
+// them, and the skew of each from it.
+type NetworkTime struct {
+	Median     time.Time                         `json:"median"`             // Median of the clocks of the validators
+	LocalSkew  time.Duration                     `json:"localSkew"`          // Skew of the local clock from the median
+	NTPOffset  time.Duration                     `json:"ntpOffset"`          // Offset of the NTP time from the local clock
+	NTPError   string                            `json:"ntpError,omitempty"` // Error of the last NTP query
+	Validators map[common.Address]*ValidatorTime `json:"validators"`
+}
+
+// ValidatorTime is the last timestamp attested by a validator.
+type ValidatorTime struct {
+	Attested time.Time     `json:"attested"` // Timestamp attested
+	Received time.Time     `json:"received"` // Local time of the receipt of the attestation
+	Skew     time.Duration `json:"skew"`     // Skew of the clock of the validator from the median, network latency included
+}
+
+// timeService tracks the timestamps attested by the validators, and the offset
+// of the NTP time the local ones are derived from.
+type timeService struct {
+	lock       sync.RWMutex
+	attested   map[common.Address]*ValidatorTime
+	ntpOffset  time.Duration
+	ntpErr     error
+	ntpSynced  time.Time
+	lastWarned time.Time
+}
+
+func newTimeService() *timeService {
+	return &timeService{attested: make(map[common.Address]*ValidatorTime)}
+}
+
+// record keeps the timestamp attested by a validator, unless older than the
+// last one it attested.
+func (s *timeService) record(validator common.Address, attested, received time.Time) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if last, ok := s.attested[validator]; ok && !attested.After(last.Attested) {
+		return false
+	}
+	s.attested[validator] = &ValidatorTime{Attested: attested, Received: received}
+	return true
+}
+
+// networkTime returns the median of the clocks of the given validators at the
+// given local time, from their last attestations. The local node attests its
+// NTP offset if a validator.
+func (s *timeService) networkTime(validators []common.Address, local common.Address, now time.Time) (*NetworkTime, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	result := &NetworkTime{NTPOffset: s.ntpOffset, Validators: make(map[common.Address]*ValidatorTime)}
+	if s.ntpErr != nil {
+		result.NTPError = s.ntpErr.Error()
+	}
+	var skews []time.Duration
+	for _, validator := range validators {
+		if validator == local {
+			skews = append(skews, s.ntpOffset)
+			result.Validators[validator] = &ValidatorTime{Attested: now.Add(s.ntpOffset), Received: now, Skew: s.ntpOffset}
+			continue
+		}
+		last, ok := s.attested[validator]
+		if !ok || now.Sub(last.Received) > timeAttestationExpiry {
+			continue
+		}
+		skew := last.Attested.Sub(last.Received)
+		skews = append(skews, skew)
+		result.Validators[validator] = &ValidatorTime{Attested: last.Attested, Received: last.Received, Skew: skew}
+	}
+	if len(skews) == 0 {
+		return nil, errNoNetworkTime
+	}
+	sort.Slice(skews, func(i, j int) bool { return skews[i] < skews[j] })
+	median := skews[len(skews)/2]
+	if len(skews)%2 == 0 {
+		median = (skews[len(skews)/2-1] + median) / 2
+	}
+	for _, validator := range result.Validators {
+		validator.Skew -= median
+	}
+	result.Median, result.LocalSkew = now.Add(median), -median
+	return result, nil
+}
+
+// syncNTP queries the NTP server for the offset of its time from the local
+// clock, if not done recently.
+func (s *timeService) syncNTP(server string, now time.Time) {
+	s.lock.RLock()
+	synced := s.ntpSynced
+	s.lock.RUnlock()
+
+	if server == "" || now.Sub(synced) < ntpResync {
+		return
+	}
+	offset, err := ntp.Offset(server, ntpChecks)
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.ntpSynced, s.ntpErr = now, err
+	if err != nil {
+		log.Warn("Failed to query the NTP server", "server", server, "err", err)
+		return
+	}
+	s.ntpOffset = offset
+}
+
+// attestTime sends the timestamp of the local node to the other validators
+// every interval, and checks the skew of the local clock from the network
+// time, until quit is closed.
+func (sb *backend) attestTime(interval time.Duration, quit chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-quit:
+			return
+		}
+		sb.times.syncNTP(sb.config.NTPServer, now())
+		sb.checkTimeSkew()
+
+		block := sb.currentBlock()
+		valSet := sb.getValidators(block.Number().Uint64(), block.Hash())
+		if _, val := valSet.GetByAddress(sb.Address()); val == nil || sb.config.Shadow {
+			continue
+		}
+		sb.times.lock.RLock()
+		attested := now().Add(sb.times.ntpOffset)
+		sb.times.lock.RUnlock()
+
+		content, err := rlp.EncodeToBytes(&timeAttestation{Time: uint64(attested.UnixNano())})
+		if err != nil {
+			continue
+		}
+		payload, err := istanbulCore.SignMessage(istanbulCore.MsgTimestamp, content, sb.Address(), sb.Sign)
+		if err != nil {
+			sb.logger.Warn("Failed to sign timestamp attestation", "err", err)
+			continue
+		}
+		sb.Gossip(valSet, payload)
+	}
+}
+
+// checkTimeSkew exports the skew of the local clock from the network time,
+// warning of the skews the blocks may be rejected for.
+func (sb *backend) checkTimeSkew() {
+	block := sb.currentBlock()
+	network, err := sb.times.networkTime(validatorAddresses(sb.getValidators(block.Number().Uint64(), block.Hash())), sb.Address(), now())
+	if err != nil {
+		return
+	}
+	timeSkewGauge.Update(int64(network.LocalSkew / time.Millisecond))
+
+	sb.times.lock.Lock()
+	defer sb.times.lock.Unlock()
+
+	if (network.LocalSkew > timeSkewWarning || network.LocalSkew < -timeSkewWarning) && time.Since(sb.times.lastWarned) > ntpResync {
+		sb.times.lastWarned = time.Now()
+		log.Warn("Local clock skewed from the validators, which can cause round changes", "skew", network.LocalSkew, "validators", len(network.Validators))
+	}
+}
+
+// handleTimeAttestation records the timestamp attested by a validator, and
+// gossips it to the validators not connected to it.
+func (sb *backend) handleTimeAttestation(payload []byte) {
+	received := now()
+	_, content, author, err := istanbulCore.DecodeMessage(payload)
+	if err != nil {
+		timeInvalidMeter.Mark(1)
+		sb.logger.Debug("Invalid timestamp attestation", "err", err)
+		return
+	}
+	block := sb.currentBlock()
+	valSet := sb.getValidators(block.Number().Uint64(), block.Hash())
+	if _, val := valSet.GetByAddress(author); val == nil {
+		timeInvalidMeter.Mark(1)
+		sb.logger.Debug("Timestamp attested by a non-validator", "author", author)
+		return
+	}
+	var attestation timeAttestation
+	if err := rlp.DecodeBytes(content, &attestation); err != nil {
+		timeInvalidMeter.Mark(1)
+		sb.logger.Debug("Invalid timestamp attestation", "author", author, "err", err)
+		return
+	}
+	if !sb.times.record(author, time.Unix(0, int64(attestation.Time)), received) {
+		return
+	}
+	timeAttestationsMeter.Mark(1)
+	sb.Gossip(valSet, payload)
+}
+
+// validatorAddresses returns the addresses of the validators of a set.
+func validatorAddresses(valSet istanbul.ValidatorSet) []common.Address {
+	addresses := make([]common.Address, 0, valSet.Size())
+	for _, val := range valSet.List() {
+		addresses = append(addresses, val.Address())
+	}
+	return addresses
+}
@@ -0,0 +1,107 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package backend
+
+import (
+	"crypto/ecdsa"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	istanbulCore "github.com/ethereum/go-ethereum/consensus/istanbul/core"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// Tests that the network time is the median of the clocks of the validators,
+// ignoring the attestations of non-validators and the expired ones.
+func TestNetworkTime(t *testing.T) {
+	var (
+		service = newTimeService()
+		now     = time.Unix(1000000, 0)
+		local   = common.Address{0}
+		vals    = []common.Address{local, {1}, {2}, {3}, {4}}
+	)
+	service.ntpOffset = 100 * time.Millisecond
+	if _, err := service.networkTime(vals[1:], local, now); err != errNoNetworkTime {
+		t.Fatalf("network time without attestation: have %v, want %v", err, errNoNetworkTime)
+	}
+	service.record(vals[1], now.Add(-2*time.Second), now.Add(-time.Second))                    // 1s behind
+	service.record(vals[2], now.Add(-time.Second+300*time.Millisecond), now.Add(-time.Second)) // 300ms ahead
+	service.record(vals[3], now.Add(-time.Hour), now.Add(-time.Hour))                          // Expired
+	service.record(vals[4], now.Add(10*time.Second), now)                                      // 10s ahead
+	service.record(common.Address{5}, now.Add(time.Hour), now)                                 // Not a validator
+
+	if service.record(vals[4], now.Add(5*time.Second), now) {
+		t.Errorf("older attestation recorded")
+	}
+	network, err := service.networkTime(vals, local, now)
+	if err != nil {
+		t.Fatalf("failed to compute the network time: %v", err)
+	}
+	// Skews of -1s, 100ms, 300ms and 10s from the local clock
+	median := 200 * time.Millisecond
+	if !network.Median.Equal(now.Add(median)) || network.LocalSkew != -median {
+		t.Errorf("median mismatch: have %v (local skew %v), want %v", network.Median, network.LocalSkew, now.Add(median))
+	}
+	want := map[common.Address]time.Duration{
+		vals[0]: -100 * time.Millisecond,
+		vals[1]: -1200 * time.Millisecond,
+		vals[2]: 100 * time.Millisecond,
+		vals[4]: 9800 * time.Millisecond,
+	}
+	if len(network.Validators) != len(want) {
+		t.Fatalf("validator count mismatch: have %d, want %d", len(network.Validators), len(want))
+	}
+	for addr, skew := range want {
+		if val := network.Validators[addr]; val == nil || val.Skew != skew {
+			t.Errorf("validator %x: skew mismatch: have %+v, want %v", addr, val, skew)
+		}
+	}
+}
+
+// Tests that the timestamps attested by the validators are recorded, and those
+// of the other nodes dropped.
+func TestHandleTimeAttestation(t *testing.T) {
+	_, backend := newBlockChain(1)
+	defer backend.Stop()
+
+	attest := func(key *ecdsa.PrivateKey, at time.Time) []byte {
+		content, _ := rlp.EncodeToBytes(&timeAttestation{Time: uint64(at.UnixNano())})
+		sign := func(data []byte) ([]byte, error) { return crypto.Sign(crypto.Keccak256(data), key) }
+		payload, err := istanbulCore.SignMessage(istanbulCore.MsgTimestamp, content, crypto.PubkeyToAddress(key.PublicKey), sign)
+		if err != nil {
+			t.Fatalf("failed to sign attestation: %v", err)
+		}
+		return payload
+	}
+	stranger, _ := crypto.GenerateKey()
+	at := time.Now().Add(time.Minute)
+
+	backend.handleTimeAttestation(attest(stranger, at))
+	backend.handleTimeAttestation(attest(backend.privateKey, at))
+
+	if len(backend.times.attested) != 1 {
+		t.Fatalf("attestation count mismatch: have %d, want 1", len(backend.times.attested))
+	}
+	if val := backend.times.attested[backend.Address()]; val == nil || !val.Attested.Equal(time.Unix(0, at.UnixNano())) {
+		t.Errorf("attestation mismatch: have %+v, want %v", val, at)
+	}
+	if code, err := istanbulCore.MessageCode(attest(stranger, at)); err != nil || code != istanbulCore.MsgTimestamp {
+		t.Errorf("message code mismatch: have %d (%v), want %d", code, err, istanbulCore.MsgTimestamp)
+	}
+}
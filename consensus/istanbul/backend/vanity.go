@@ -0,0 +1,98 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package backend
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/istanbul"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+var refusedVanityMeter = metrics.NewRegisteredMeter("consensus/istanbul/vanity/refused", nil)
+
+// errVanityPolicy is returned when the vanity of a block violates the vanity
+// policy.
+type errVanityPolicy struct {
+	err error
+}
+
+func (e *errVanityPolicy) Error() string {
+	return "vanity policy violated: " + e.err.Error()
+}
+
+// SetVanityPolicy replaces the policy the vanity of the blocks committed must
+// satisfy, nil to accept any.
+func (sb *backend) SetVanityPolicy(policy istanbul.VanityPolicy) {
+	sb.vanityLock.Lock()
+	defer sb.vanityLock.Unlock()
+
+	sb.vanityPolicy = policy
+}
+
+// Vanity returns the vanity of the blocks proposed, nil if taken from the miner
+// extra-data.
+func (sb *backend) Vanity() []byte {
+	sb.vanityLock.RLock()
+	defer sb.vanityLock.RUnlock()
+
+	return common.CopyBytes(sb.vanity)
+}
+
+// SetVanity replaces the vanity of the blocks proposed, nil to take it from the
+// miner extra-data. The vanity must satisfy the vanity policy.
+func (sb *backend) SetVanity(vanity []byte) error {
+	if err := istanbul.ValidateVanity(vanity); err != nil {
+		return err
+	}
+	sb.vanityLock.Lock()
+	defer sb.vanityLock.Unlock()
+
+	if sb.vanityPolicy != nil && vanity != nil {
+		if err := sb.vanityPolicy(sb.Address(), vanity); err != nil {
+			return &errVanityPolicy{err}
+		}
+	}
+	sb.vanity = common.CopyBytes(vanity)
+	return nil
+}
+
+// checkVanityPolicy returns an error if the validator must refuse to commit the
+// given block header for its vanity.
+func (sb *backend) checkVanityPolicy(header *types.Header) error {
+	sb.vanityLock.RLock()
+	policy := sb.vanityPolicy
+	sb.vanityLock.RUnlock()
+
+	if policy == nil {
+		return nil
+	}
+	proposer, err := sb.Author(header)
+	if err != nil {
+		return err
+	}
+	vanity := header.Extra
+	if len(vanity) > types.IstanbulExtraVanity {
+		vanity = vanity[:types.IstanbulExtraVanity]
+	}
+	if err := policy(proposer, vanity); err != nil {
+		refusedVanityMeter.Mark(1)
+		sb.logger.Warn("Refusing to commit block violating the vanity policy", "number", header.Number, "hash", header.Hash(), "proposer", proposer, "err", err)
+		return &errVanityPolicy{err}
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package backend
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/istanbul"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+func TestVanityPolicy(t *testing.T) {
+	chain, engine := newBlockChain(1)
+	defer engine.SetVanityPolicy(nil)
+	defer engine.SetVanity(nil)
+
+	policy, err := istanbul.NewVanityPattern("^org:acme$")
+	if err != nil {
+		t.Fatalf("failed to create vanity policy: %v", err)
+	}
+	engine.SetVanityPolicy(policy)
+
+	// The vanity of the validator must satisfy the policy
+	if err := engine.SetVanity([]byte("org:other")); err == nil {
+		t.Fatalf("vanity violating the policy accepted")
+	}
+	if err := engine.SetVanity(bytes.Repeat([]byte{'a'}, types.IstanbulExtraVanity+1)); err == nil {
+		t.Fatalf("oversized vanity accepted")
+	}
+	if err := engine.SetVanity([]byte("org:acme")); err != nil {
+		t.Fatalf("failed to set vanity: %v", err)
+	}
+	// The blocks proposed carry it, and are committed
+	block, err := engine.updateBlock(chain.Genesis().Header(), makeBlockWithoutSeal(chain, engine, chain.Genesis()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := append([]byte("org:acme"), make([]byte, types.IstanbulExtraVanity-8)...); !bytes.Equal(block.Extra()[:types.IstanbulExtraVanity], want) {
+		t.Fatalf("vanity mismatch: have %q, want %q", block.Extra()[:types.IstanbulExtraVanity], want)
+	}
+	if _, err := engine.Verify(block); err != nil {
+		t.Fatalf("block satisfying the vanity policy refused: %v", err)
+	}
+	// The blocks refused by the policy aren't committed
+	var proposer common.Address
+	engine.SetVanityPolicy(func(author common.Address, vanity []byte) error {
+		proposer = author
+		return errors.New("unknown organisation")
+	})
+	if _, err := engine.Verify(block); err == nil {
+		t.Fatalf("block violating the vanity policy committed")
+	}
+	if proposer != engine.Address() {
+		t.Errorf("proposer mismatch: have %x, want %x", proposer, engine.Address())
+	}
+}
+
+func TestVanityIdentifiers(t *testing.T) {
+	chain, engine := newBlockChain(1)
+	defer engine.SetVanityPolicy(nil)
+	defer engine.SetVanity(nil)
+
+	// Invalid policies must be reported for the node to refuse to start
+	if _, err := (&istanbul.Config{VanityPattern: "("}).VanityPolicy(); err == nil {
+		t.Fatalf("invalid vanity pattern accepted")
+	}
+	oversized := map[common.Address]string{engine.Address(): string(bytes.Repeat([]byte{'a'}, types.IstanbulExtraVanity+1))}
+	if _, err := (&istanbul.Config{VanityIdentifiers: oversized}).VanityPolicy(); err == nil {
+		t.Fatalf("oversized vanity identifier accepted")
+	}
+	config := &istanbul.Config{
+		VanityPattern:     "^org:",
+		VanityIdentifiers: map[common.Address]string{engine.Address(): "org:acme"},
+	}
+	policy, err := config.VanityPolicy()
+	if err != nil {
+		t.Fatalf("failed to create vanity policy: %v", err)
+	}
+	engine.SetVanityPolicy(policy)
+
+	// The vanity of the validator must be its identifier
+	if err := engine.SetVanity([]byte("org:other")); err == nil {
+		t.Fatalf("vanity other than the identifier accepted")
+	}
+	if err := engine.SetVanity([]byte("org:acme")); err != nil {
+		t.Fatalf("failed to set vanity: %v", err)
+	}
+	block, err := engine.updateBlock(chain.Genesis().Header(), makeBlockWithoutSeal(chain, engine, chain.Genesis()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := engine.Verify(block); err != nil {
+		t.Fatalf("block of the identified proposer refused: %v", err)
+	}
+	// The blocks of the proposers without identifier aren't committed
+	config.VanityIdentifiers = map[common.Address]string{common.HexToAddress("0x01"): "org:acme"}
+	if policy, err = config.VanityPolicy(); err != nil {
+		t.Fatalf("failed to create vanity policy: %v", err)
+	}
+	engine.SetVanityPolicy(policy)
+	if _, err := engine.Verify(block); err == nil {
+		t.Fatalf("block of an unidentified proposer committed")
+	}
+}
@@ -16,7 +16,12 @@
 
 package istanbul
 
-import "math/big"
+import (
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
 
 type ProposerPolicy uint64
 
@@ -26,17 +31,28 @@ const (
 )
 
 type Config struct {
-	RequestTimeout uint64         `toml:",omitempty"` // The timeout for each Istanbul round in milliseconds.
-	BlockPeriod    uint64         `toml:",omitempty"` // Default minimum difference between two consecutive block's timestamps in second
-	ProposerPolicy ProposerPolicy `toml:",omitempty"` // The policy for proposer selection
-	Epoch          uint64         `toml:",omitempty"` // The number of blocks after which to checkpoint and reset the pending votes
-	Ceil2Nby3Block *big.Int       `toml:",omitempty"` // Number of confirmations required to move from one state to next [2F + 1 to Ceil(2N/3)]
+	RequestTimeout            uint64                    `toml:",omitempty"` // The timeout for each Istanbul round in milliseconds.
+	BlockPeriod               uint64                    `toml:",omitempty"` // Default minimum difference between two consecutive block's timestamps in second
+	ProposerPolicy            ProposerPolicy            `toml:",omitempty"` // The policy for proposer selection
+	Epoch                     uint64                    `toml:",omitempty"` // The number of blocks after which to checkpoint and reset the pending votes
+	Ceil2Nby3Block            *big.Int                  `toml:",omitempty"` // Number of confirmations required to move from one state to next [2F + 1 to Ceil(2N/3)]
+	Shadow                    bool                      `toml:",omitempty"` // Follow consensus without sending messages, comparing the would-be votes with the network
+	RefuseUnsafe              bool                      `toml:",omitempty"` // Refuse, rather than warn about, the validator removals leaving too few validators online for the quorum
+	Relay                     bool                      `toml:",omitempty"` // Relay the messages of the other validators on receipt, for the validators not connected to each other
+	SigningPolicy             *SigningPolicy            `toml:",omitempty"` // Invariants of the blocks sealed or committed, nil to sign any valid block
+	TimeAttestation           time.Duration             `toml:",omitempty"` // Interval of the signed timestamps sent to the other validators, 0 to send none
+	NTPServer                 string                    `toml:",omitempty"` // NTP server the attested timestamps are derived from, empty to use the local clock
+	Vanity                    string                    `toml:",omitempty"` // Vanity of the blocks proposed, empty to take it from the miner extra-data
+	VanityPattern             string                    `toml:",omitempty"` // Regular expression the vanity of the blocks committed must match, empty to accept any
+	VanityIdentifiers         map[common.Address]string `toml:",omitempty"` // Identifier of each proposer, such as its organisation, the vanity of its blocks must be, empty to accept any
+	ProposalValidationTimeout time.Duration             `toml:",omitempty"` // Time the proposal validator plugin has to vet a proposed block, accepted if it fails to
 }
 
 var DefaultConfig = &Config{
-	RequestTimeout: 10000,
-	BlockPeriod:    1,
-	ProposerPolicy: RoundRobin,
-	Epoch:          30000,
-	Ceil2Nby3Block: big.NewInt(0),
+	RequestTimeout:            10000,
+	BlockPeriod:               1,
+	ProposerPolicy:            RoundRobin,
+	Epoch:                     30000,
+	Ceil2Nby3Block:            big.NewInt(0),
+	ProposalValidationTimeout: 500 * time.Millisecond,
 }
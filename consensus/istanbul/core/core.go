@@ -21,6 +21,7 @@ import (
 	"math"
 	"math/big"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -57,6 +58,9 @@ func New(backend istanbul.Backend, config *istanbul.Config) Engine {
 	r.Register("consensus/istanbul/core/consensus", c.consensusTimer)
 
 	c.validateFn = c.checkValidatorSignature
+	if config.Shadow {
+		c.shadow = newShadowTracker()
+	}
 	return c
 }
 
@@ -97,6 +101,11 @@ type core struct {
 	sequenceMeter metrics.Meter
 	// the timer to record consensus duration (from accepting a preprepare to final committed stage)
 	consensusTimer metrics.Timer
+
+	// the recorder of the messages not sent in shadow mode, nil otherwise
+	shadow *shadowTracker
+	// the round of the current sequence, readable outside of the core goroutine
+	round uint64
 }
 
 func (c *core) finalizeMessage(msg *message) ([]byte, error) {
@@ -137,6 +146,13 @@ func (c *core) finalizeMessage(msg *message) ([]byte, error) {
 func (c *core) broadcast(msg *message) {
 	logger := c.logger.New("state", c.state)
 
+	// A shadow validator keeps its messages to itself, so that the network
+	// never counts them
+	if c.shadow != nil {
+		c.shadow.record(msg)
+		return
+	}
+
 	payload, err := c.finalizeMessage(msg)
 	if err != nil {
 		logger.Error("Failed to finalize message", "msg", msg, "err", err)
@@ -165,6 +181,17 @@ func (c *core) IsProposer() bool {
 	return v.IsProposer(c.backend.Address())
 }
 
+func (c *core) ShadowReport() *ShadowReport {
+	if c.shadow == nil {
+		return nil
+	}
+	return c.shadow.Report()
+}
+
+func (c *core) CurrentRound() uint64 {
+	return atomic.LoadUint64(&c.round)
+}
+
 func (c *core) IsCurrentProposal(blockHash common.Hash) bool {
 	return c.current != nil && c.current.pendingRequest != nil && c.current.pendingRequest.Proposal.Hash() == blockHash
 }
@@ -294,6 +321,7 @@ func (c *core) updateRoundState(view *istanbul.View, validatorSet istanbul.Valid
 	} else {
 		c.current = newRoundState(view, validatorSet, common.Hash{}, nil, nil, c.backend.HasBadProposal)
 	}
+	atomic.StoreUint64(&c.round, view.Round.Uint64())
 }
 
 func (c *core) setState(state State) {
@@ -21,6 +21,11 @@ import "github.com/ethereum/go-ethereum/common"
 func (c *core) handleFinalCommitted() error {
 	logger := c.logger.New("state", c.state)
 	logger.Trace("Received a final committed proposal")
+	if c.shadow != nil {
+		if lastProposal, _ := c.backend.LastProposal(); lastProposal != nil {
+			c.shadow.compare(lastProposal, c.backend.HasPropsal)
+		}
+	}
 	c.startNewRound(common.Big0)
 	return nil
 }
@@ -0,0 +1,182 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/istanbul"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// ShadowReport summarises how the messages a shadow validator would have sent
+// compare with the blocks committed by the network.
+type ShadowReport struct {
+	Sequences       uint64          `json:"sequences"`       // Committed sequences compared
+	Matches         uint64          `json:"matches"`         // Sequences with all votes for the committed block
+	Mismatches      uint64          `json:"mismatches"`      // Sequences with a vote for another block
+	Missed          uint64          `json:"missed"`          // Sequences committed without any vote
+	Proposals       uint64          `json:"proposals"`       // Blocks proposed
+	ProposalMatches uint64          `json:"proposalMatches"` // Proposals with the transactions of the committed block
+	RoundChanges    uint64          `json:"roundChanges"`    // Round changes requested
+	LastMismatch    *ShadowMismatch `json:"lastMismatch,omitempty"`
+}
+
+// ShadowMismatch describes the votes of a shadow validator for a sequence
+// which disagree with the committed block.
+type ShadowMismatch struct {
+	Number    uint64      `json:"number"`
+	Committed common.Hash `json:"committed"`
+	Prepare   common.Hash `json:"prepare"`
+	Commit    common.Hash `json:"commit"`
+}
+
+// shadowVotes are the messages a shadow validator would have sent for a
+// sequence, the latest round overriding the previous ones.
+type shadowVotes struct {
+	proposal *types.Block
+	prepare  common.Hash
+	commit   common.Hash
+}
+
+// shadowTracker records the messages of a shadow validator instead of sending
+// them, and compares them with the blocks the network commits.
+type shadowTracker struct {
+	lock   sync.Mutex
+	votes  map[uint64]*shadowVotes
+	head   uint64 // Last committed sequence compared
+	report ShadowReport
+}
+
+func newShadowTracker() *shadowTracker {
+	return &shadowTracker{votes: make(map[uint64]*shadowVotes)}
+}
+
+// record stores a message the shadow validator would have sent.
+func (s *shadowTracker) record(msg *message) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var view *istanbul.View
+	switch msg.Code {
+	case msgPreprepare:
+		var preprepare *istanbul.Preprepare
+		if err := msg.Decode(&preprepare); err != nil {
+			return
+		}
+		if block, ok := preprepare.Proposal.(*types.Block); ok {
+			s.votesAt(preprepare.View.Sequence).proposal = block
+		}
+		view = preprepare.View
+	case msgPrepare, msgCommit:
+		var subject *istanbul.Subject
+		if err := msg.Decode(&subject); err != nil {
+			return
+		}
+		if msg.Code == msgPrepare {
+			s.votesAt(subject.View.Sequence).prepare = subject.Digest
+		} else {
+			s.votesAt(subject.View.Sequence).commit = subject.Digest
+		}
+		view = subject.View
+	case msgRoundChange:
+		s.report.RoundChanges++
+	}
+	if view != nil {
+		log.Debug("Recorded shadow consensus message", "code", msg.Code, "number", view.Sequence, "round", view.Round)
+	}
+}
+
+// votesAt returns the votes for a sequence, assuming the lock is held.
+func (s *shadowTracker) votesAt(sequence *big.Int) *shadowVotes {
+	number := sequence.Uint64()
+	if s.votes[number] == nil {
+		s.votes[number] = new(shadowVotes)
+	}
+	return s.votes[number]
+}
+
+// compare checks the recorded votes of all sequences up to the given committed
+// proposal. Sequences committed before the proposal are looked up with the
+// committed function.
+func (s *shadowTracker) compare(last istanbul.Proposal, committed func(common.Hash, *big.Int) bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	number := last.Number().Uint64()
+	if number == 0 {
+		return
+	}
+	if s.head == 0 {
+		// Don't count the chain preceding the first committed sequence
+		s.head = number - 1
+	}
+	for n := s.head + 1; n <= number; n++ {
+		isCommitted := func(hash common.Hash) bool {
+			if n == number {
+				return hash == last.Hash()
+			}
+			return committed(hash, new(big.Int).SetUint64(n))
+		}
+		s.report.Sequences++
+
+		votes := s.votes[n]
+		if votes == nil || (votes.prepare == (common.Hash{}) && votes.commit == (common.Hash{})) {
+			s.report.Missed++
+		} else if (votes.prepare == (common.Hash{}) || isCommitted(votes.prepare)) && (votes.commit == (common.Hash{}) || isCommitted(votes.commit)) {
+			s.report.Matches++
+		} else {
+			s.report.Mismatches++
+			s.report.LastMismatch = &ShadowMismatch{Number: n, Prepare: votes.prepare, Commit: votes.commit}
+			if n == number {
+				s.report.LastMismatch.Committed = last.Hash()
+			}
+			log.Warn("Shadow votes mismatch committed block", "number", n, "prepare", votes.prepare, "commit", votes.commit)
+		}
+		if votes != nil && votes.proposal != nil {
+			s.report.Proposals++
+			if block, ok := last.(*types.Block); ok && n == number && block.TxHash() == votes.proposal.TxHash() {
+				s.report.ProposalMatches++
+			}
+		}
+	}
+	// Drop the compared votes, and the ones of sequences decided on another fork
+	for n := range s.votes {
+		if n <= number {
+			delete(s.votes, n)
+		}
+	}
+	if number > s.head {
+		s.head = number
+	}
+}
+
+// Report returns a copy of the comparison report.
+func (s *shadowTracker) Report() *ShadowReport {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	report := s.report
+	if report.LastMismatch != nil {
+		mismatch := *report.LastMismatch
+		report.LastMismatch = &mismatch
+	}
+	return &report
+}
@@ -0,0 +1,66 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/istanbul"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+func TestShadowVotes(t *testing.T) {
+	sys := newTestSystem(1)
+	backend := sys.NewBackend(0)
+	backend.peers = newTestValidatorSet(4)
+	backend.address = backend.peers.GetByIndex(0).Address()
+
+	config := *istanbul.DefaultConfig
+	config.Shadow = true
+	c := New(backend, &config).(*core)
+	c.valSet = backend.peers
+	c.logger = testLogger
+
+	// vote casts the PREPARE and COMMIT votes of a sequence for a proposal
+	vote := func(proposal istanbul.Proposal) {
+		view := &istanbul.View{Sequence: proposal.Number(), Round: big.NewInt(0)}
+		c.current = newRoundState(view, c.valSet, common.Hash{}, nil, nil, backend.HasBadProposal)
+		c.current.SetPreprepare(&istanbul.Preprepare{View: view, Proposal: proposal})
+		c.sendPrepare()
+		c.sendCommit()
+	}
+	other := makeBlock(2).WithSeal(&types.Header{Number: big.NewInt(2), Time: big.NewInt(1)})
+
+	vote(makeBlock(1))
+	c.shadow.compare(makeBlock(1), backend.HasPropsal)
+	vote(other)
+	c.shadow.compare(makeBlock(2), backend.HasPropsal)
+	c.shadow.compare(makeBlock(4), backend.HasPropsal)
+
+	if len(backend.sentMsgs) != 0 {
+		t.Errorf("shadow validator sent %d messages", len(backend.sentMsgs))
+	}
+	report := c.ShadowReport()
+	if report.Sequences != 4 || report.Matches != 1 || report.Mismatches != 1 || report.Missed != 2 {
+		t.Errorf("report mismatch: have %+v", report)
+	}
+	if report.LastMismatch == nil || report.LastMismatch.Number != 2 || report.LastMismatch.Prepare != other.Hash() || report.LastMismatch.Committed != makeBlock(2).Hash() {
+		t.Errorf("last mismatch mismatch: have %+v", report.LastMismatch)
+	}
+}
@@ -22,6 +22,7 @@ import (
 	"io"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/istanbul"
 	"github.com/ethereum/go-ethereum/rlp"
 )
 
@@ -38,6 +39,14 @@ type Engine interface {
 	// pending request is populated right at the preprepare stage so this would give us the earliest verification
 	// to avoid any race condition of coming propagated blocks
 	IsCurrentProposal(blockHash common.Hash) bool
+
+	// ShadowReport returns how the messages withheld in shadow mode compare
+	// with the blocks committed by the network, or nil if not in shadow mode.
+	ShadowReport() *ShadowReport
+
+	// CurrentRound returns the round of the current sequence, rising with
+	// each round change until a block is committed.
+	CurrentRound() uint64
 }
 
 type State uint64
@@ -163,6 +172,53 @@ func (m *message) PayloadNoSig() ([]byte, error) {
 	})
 }
 
+// MessageAuthor decodes the payload of a consensus message and returns the
+// address of its author, checked against the signature of the message.
+func MessageAuthor(payload []byte) (common.Address, error) {
+	_, _, author, err := DecodeMessage(payload)
+	return author, err
+}
+
+// Quorum
+// MsgTimestamp is the code of the timestamp attestations the validators send
+// besides the consensus messages. They're handled by the backend, outside of
+// the rounds, and dropped as invalid by the nodes not supporting them.
+const MsgTimestamp uint64 = 0x10
+
+// MessageCode returns the code of the payload of a consensus message, without
+// checking its signature.
+func MessageCode(payload []byte) (uint64, error) {
+	msg := new(message)
+	if err := msg.FromPayload(payload, nil); err != nil {
+		return 0, err
+	}
+	return msg.Code, nil
+}
+
+// DecodeMessage decodes the payload of a consensus message and returns its
+// code, content and author, checked against the signature of the message.
+func DecodeMessage(payload []byte) (uint64, []byte, common.Address, error) {
+	msg := new(message)
+	if err := msg.FromPayload(payload, istanbul.GetSignatureAddress); err != nil {
+		return 0, nil, common.Address{}, err
+	}
+	return msg.Code, msg.Msg, msg.Address, nil
+}
+
+// SignMessage returns the payload of a message of the given code and content
+// from the given author, signed with the given function.
+func SignMessage(code uint64, content []byte, author common.Address, sign func([]byte) ([]byte, error)) ([]byte, error) {
+	msg := &message{Code: code, Msg: content, Address: author, CommittedSeal: []byte{}}
+	data, err := msg.PayloadNoSig()
+	if err != nil {
+		return nil, err
+	}
+	if msg.Signature, err = sign(data); err != nil {
+		return nil, err
+	}
+	return msg.Payload()
+}
+
 func (m *message) Decode(val interface{}) error {
 	return rlp.DecodeBytes(m.Msg, val)
 }
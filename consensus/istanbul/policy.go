@@ -0,0 +1,51 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package istanbul
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// SigningPolicy are the invariants the blocks must satisfy for the validator to
+// seal them as proposer or to accept them for committing, as a last line of
+// defense against the bugs producing bad proposals.
+type SigningPolicy struct {
+	MinGasLimit     uint64      `json:"minGasLimit,omitempty" toml:",omitempty"`     // Lowest gas limit of the blocks, zero for unchecked
+	MaxGasLimit     uint64      `json:"maxGasLimit,omitempty" toml:",omitempty"`     // Highest gas limit of the blocks, zero for unchecked
+	ChainConfigHash common.Hash `json:"chainConfigHash,omitempty" toml:",omitempty"` // Hash of the JSON chain configuration expected, zero for unchecked
+	VerifiedParent  bool        `json:"verifiedParent,omitempty" toml:",omitempty"`  // Whether the parent must have been imported locally
+}
+
+// LoadSigningPolicy reads a signing policy from a JSON file.
+func LoadSigningPolicy(path string) (*SigningPolicy, error) {
+	blob, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	policy := new(SigningPolicy)
+	if err := json.Unmarshal(blob, policy); err != nil {
+		return nil, fmt.Errorf("invalid signing policy %s: %v", path, err)
+	}
+	if policy.MaxGasLimit != 0 && policy.MinGasLimit > policy.MaxGasLimit {
+		return nil, fmt.Errorf("invalid signing policy %s: gas limit range %d-%d empty", path, policy.MinGasLimit, policy.MaxGasLimit)
+	}
+	return policy, nil
+}
@@ -0,0 +1,117 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package istanbul
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// VanityPolicy validates the vanity of a block, the bytes leading its
+// extra-data, given its proposer. Consortiums requiring the blocks to be traced
+// to the organisations producing them refuse to commit the proposals whose
+// vanity the policy rejects.
+type VanityPolicy func(proposer common.Address, vanity []byte) error
+
+// NewVanityPattern returns the policy requiring the vanity, its trailing zero
+// bytes trimmed, to match a regular expression, such as "^org:[a-z0-9-]+$".
+func NewVanityPattern(pattern string) (VanityPolicy, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid vanity pattern %q: %v", pattern, err)
+	}
+	return func(proposer common.Address, vanity []byte) error {
+		if trimmed := bytes.TrimRight(vanity, "\x00"); !re.Match(trimmed) {
+			return fmt.Errorf("vanity %q not matching %q", trimmed, pattern)
+		}
+		return nil
+	}, nil
+}
+
+// NewVanityIdentifiers returns the policy requiring the vanity, its trailing
+// zero bytes trimmed, to be the identifier of the proposer, such as the
+// organisation running it. The blocks of the proposers without identifier are
+// refused.
+func NewVanityIdentifiers(identifiers map[common.Address]string) (VanityPolicy, error) {
+	ids := make(map[common.Address][]byte, len(identifiers))
+	for proposer, id := range identifiers {
+		if id == "" {
+			return nil, fmt.Errorf("empty vanity identifier of proposer %x", proposer)
+		}
+		if err := ValidateVanity([]byte(id)); err != nil {
+			return nil, fmt.Errorf("vanity identifier of proposer %x: %v", proposer, err)
+		}
+		ids[proposer] = []byte(id)
+	}
+	return func(proposer common.Address, vanity []byte) error {
+		id, ok := ids[proposer]
+		if !ok {
+			return fmt.Errorf("no vanity identifier for proposer %x", proposer)
+		}
+		if trimmed := bytes.TrimRight(vanity, "\x00"); !bytes.Equal(trimmed, id) {
+			return fmt.Errorf("vanity %q not the identifier %q of proposer %x", trimmed, id, proposer)
+		}
+		return nil
+	}, nil
+}
+
+// VanityPolicy returns the policy the vanity of the blocks committed must
+// satisfy, both the pattern and the identifier of the proposer if configured,
+// nil to accept any.
+func (c *Config) VanityPolicy() (VanityPolicy, error) {
+	var policies []VanityPolicy
+	if c.VanityPattern != "" {
+		policy, err := NewVanityPattern(c.VanityPattern)
+		if err != nil {
+			return nil, err
+		}
+		policies = append(policies, policy)
+	}
+	if len(c.VanityIdentifiers) > 0 {
+		policy, err := NewVanityIdentifiers(c.VanityIdentifiers)
+		if err != nil {
+			return nil, err
+		}
+		policies = append(policies, policy)
+	}
+	switch len(policies) {
+	case 0:
+		return nil, nil
+	case 1:
+		return policies[0], nil
+	}
+	return func(proposer common.Address, vanity []byte) error {
+		for _, policy := range policies {
+			if err := policy(proposer, vanity); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, nil
+}
+
+// ValidateVanity checks a vanity fits in the extra-data of the blocks.
+func ValidateVanity(vanity []byte) error {
+	if len(vanity) > types.IstanbulExtraVanity {
+		return fmt.Errorf("vanity of %d bytes exceeds %d", len(vanity), types.IstanbulExtraVanity)
+	}
+	return nil
+}
@@ -31,7 +31,6 @@ import (
 
 	"github.com/ethereum/go-ethereum/internal/jsre"
 	"github.com/ethereum/go-ethereum/internal/web3ext"
-	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/mattn/go-colorable"
 	"github.com/peterh/liner"
 	"github.com/robertkrimen/otto"
@@ -49,12 +48,20 @@ const HistoryFile = "history"
 // DefaultPrompt is the default prompt line prefix to use for user input querying.
 const DefaultPrompt = "> "
 
+// Client is the RPC client the console executes the requests through, which an
+// *rpc.Client is, or one failing over several endpoints.
+type Client interface {
+	Call(result interface{}, method string, args ...interface{}) error
+	CallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error
+	SupportedModules() (map[string]string, error)
+}
+
 // Config is the collection of configurations to fine tune the behavior of the
 // JavaScript console.
 type Config struct {
 	DataDir  string       // Data directory to store the console history at
 	DocRoot  string       // Filesystem path from where to load JavaScript files from
-	Client   *rpc.Client  // RPC client to execute Ethereum requests through
+	Client   Client       // RPC client to execute Ethereum requests through
 	Prompt   string       // Input prompt prefix string (defaults to DefaultPrompt)
 	Prompter UserPrompter // Input prompter to allow interactive user feedback (defaults to TerminalPrompter)
 	Printer  io.Writer    // Output writer to serialize any display strings to (defaults to os.Stdout)
@@ -65,7 +72,7 @@ type Config struct {
 // JavaScript console attached to a running node via an external or in-process RPC
 // client.
 type Console struct {
-	client   *rpc.Client  // RPC client to execute Ethereum requests through
+	client   Client       // RPC client to execute Ethereum requests through
 	jsre     *jsre.JSRE   // JavaScript runtime environment running the interpreter
 	prompt   string       // Input prompt prefix string
 	prompter UserPrompter // Input prompter to allow interactive user feedback
@@ -141,7 +148,13 @@ func (c *Console) init(preload []string) error {
 	flatten := "var eth = web3.eth; var personal = web3.personal; "
 	for api := range apis {
 		if api == "web3" {
-			continue // manually mapped or ignore
+			// Quorum: extend web3 itself, which is mapped already
+			if file, ok := web3ext.Modules[api]; ok {
+				if err = c.jsre.Compile("web3ext.js", file); err != nil {
+					return fmt.Errorf("web3ext.js: %v", err)
+				}
+			}
+			continue
 		}
 		if file, ok := web3ext.Modules[api]; ok {
 			// Load our extension for the module.
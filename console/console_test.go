@@ -193,6 +193,17 @@ func TestEvaluate(t *testing.T) {
 	}
 }
 
+// Tests that the Quorum extensions of web3 itself are mapped into the console.
+func TestWeb3Extensions(t *testing.T) {
+	tester := newTester(t, nil)
+	defer tester.Close(t)
+
+	tester.console.Evaluate("web3.nodeInfoExtended().gitCommit !== undefined")
+	if output := tester.output.String(); !strings.Contains(output, "true") {
+		t.Fatalf("extended node info not served: have %s", output)
+	}
+}
+
 // Tests that the console can be used in interactive mode.
 func TestInteractive(t *testing.T) {
 	// Create a tester and run an interactive console in the background
@@ -18,6 +18,7 @@ package core
 
 import (
 	"fmt"
+	"math/big"
 
 	"github.com/ethereum/go-ethereum/consensus"
 	"github.com/ethereum/go-ethereum/core/state"
@@ -49,13 +50,16 @@ func NewBlockValidator(config *params.ChainConfig, blockchain *BlockChain, engin
 // header's transaction and uncle roots. The headers are assumed to be already
 // validated at this point.
 func (v *BlockValidator) ValidateBody(block *types.Block) error {
+	// Quorum: the ancestors may be in the import pipeline
+	chain := importChain{v.bc}
+
 	// Check whether the block's known, and if not, that it's linkable
-	if v.bc.HasBlockAndState(block.Hash(), block.NumberU64()) {
+	if chain.HasBlockAndState(block.Hash(), block.NumberU64()) {
 		return ErrKnownBlock
 	}
 	// Header validity is known at this point, check the uncles and transactions
 	header := block.Header()
-	if err := v.engine.VerifyUncles(v.bc, block); err != nil {
+	if err := v.engine.VerifyUncles(chain, block); err != nil {
 		return err
 	}
 	if hash := types.CalcUncleHash(block.Uncles()); hash != header.UncleHash {
@@ -64,8 +68,18 @@ func (v *BlockValidator) ValidateBody(block *types.Block) error {
 	if hash := types.DeriveSha(block.Transactions()); hash != header.TxHash {
 		return fmt.Errorf("transaction root hash mismatch: have %x, want %x", hash, header.TxHash)
 	}
-	if !v.bc.HasBlockAndState(block.ParentHash(), block.NumberU64()-1) {
-		if !v.bc.HasBlock(block.ParentHash(), block.NumberU64()-1) {
+	// Quorum - reject the legacy signatures once replay protection is required,
+	// and the invalid document anchors
+	for _, tx := range block.Transactions() {
+		if err := validateReplayProtection(v.config, header.Number, tx); err != nil {
+			return fmt.Errorf("transaction %x: %v", tx.Hash(), err)
+		}
+		if err := validateDocumentAnchor(v.config, header.Number, tx); err != nil {
+			return fmt.Errorf("transaction %x: %v", tx.Hash(), err)
+		}
+	}
+	if !chain.HasBlockAndState(block.ParentHash(), block.NumberU64()-1) {
+		if !chain.HasBlock(block.ParentHash(), block.NumberU64()-1) {
 			return consensus.ErrUnknownAncestor
 		}
 		return consensus.ErrPrunedAncestor
@@ -103,6 +117,18 @@ func (v *BlockValidator) ValidateState(block, parent *types.Block, statedb *stat
 	return nil
 }
 
+// Quorum
+//
+// validateReplayProtection checks that a public transaction of the block num is
+// signed with EIP-155 replay protection if the chain requires it. Private
+// transactions are exempt, their signatures having no chain ID.
+func validateReplayProtection(config *params.ChainConfig, num *big.Int, tx *types.Transaction) error {
+	if config.IsStrictEIP155(num) && !tx.Protected() && !tx.IsPrivate() {
+		return ErrUnprotectedTransaction
+	}
+	return nil
+}
+
 // CalcGasLimit computes the gas limit of the next block after parent. It aims
 // to keep the baseline gas above the provided floor, and increase it towards the
 // ceil if the blocks are full. If the ceil is exceeded, it will always decrease
@@ -32,8 +32,12 @@ import (
 	"github.com/ethereum/go-ethereum/common/mclock"
 	"github.com/ethereum/go-ethereum/common/prque"
 	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core/logindex"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/state/snapshot"
+	"github.com/ethereum/go-ethereum/core/statesize"
+	"github.com/ethereum/go-ethereum/core/txtrail"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/crypto"
@@ -72,6 +76,16 @@ type CacheConfig struct {
 	Disabled      bool          // Whether to disable trie write caching (archive node)
 	TrieNodeLimit int           // Memory limit (MB) at which to flush the current in-memory trie to disk
 	TrieTimeLimit time.Duration // Time limit after which to flush the current in-memory trie to disk
+
+	// Quorum
+	PreimageMaxSize   int    // Maximum size of a stored SHA3 preimage (0 = unlimited)
+	PreimageRetention uint64 // Number of blocks stored SHA3 preimages are retained for (0 = forever)
+
+	Snapshot  bool // Whether to read the public and private states from flat snapshots
+	LogIndex  bool // Whether to index the blocks holding the logs of each contract at import
+	StateSize bool // Whether to count the storage and code size of each contract at import
+
+	ImportPipeline int // Number of blocks executed ahead of their write during chain imports (0 = sequential)
 }
 
 // BlockChain represents the canonical chain given a database with a genesis
@@ -119,6 +133,7 @@ type BlockChain struct {
 	receiptsCache *lru.Cache     // Cache for the most recent receipts per block
 	blockCache    *lru.Cache     // Cache for the most recent entire blocks
 	futureBlocks  *lru.Cache     // future blocks are blocks added for later processing
+	importing     sync.Map       // Quorum: blocks executed but not yet written by the import pipeline
 
 	quit    chan struct{} // blockchain quit channel
 	running int32         // running must be called atomically
@@ -135,6 +150,15 @@ type BlockChain struct {
 	shouldPreserve func(*types.Block) bool // Function used to determine whether should preserve the given block.
 
 	privateStateCache state.Database // Private state database to reuse between imports (contains state cache)
+
+	snaps        *snapshot.Tree   // Snapshots of the recent public states, nil if disabled
+	privateSnaps *snapshot.Tree   // Snapshots of the recent private states, nil if disabled
+	logIndex     *logindex.Index  // Blocks holding the logs of each contract, nil if disabled
+	stateSizes   *statesize.Index // Storage and code size counters of each contract, nil if disabled
+
+	tombstones map[common.Address]struct{} // Purged private contracts, erased from every new private state
+
+	stateCommitted uint64 // Quorum: number of the newest block whose state was flushed to disk (atomic)
 }
 
 // NewBlockChain returns a fully initialised block chain using information
@@ -171,6 +195,10 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, chainConfig *par
 		vmConfig:          vmConfig,
 		badBlocks:         badBlocks,
 		privateStateCache: state.NewDatabase(db),
+		tombstones:        make(map[common.Address]struct{}),
+	}
+	for _, contract := range ReadPrivateTombstoneList(db) {
+		bc.tombstones[contract] = struct{}{}
 	}
 	bc.SetValidator(NewBlockValidator(chainConfig, bc, engine))
 	bc.SetProcessor(NewStateProcessor(chainConfig, bc, engine))
@@ -200,11 +228,53 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, chainConfig *par
 			}
 		}
 	}
+	if cacheConfig.Snapshot {
+		if err := bc.openSnapshots(); err != nil {
+			return nil, err
+		}
+	}
+	if cacheConfig.LogIndex {
+		bc.logIndex = logindex.New(bc.db)
+		if err := bc.logIndex.Open(bc.CurrentBlock().NumberU64()); err != nil {
+			return nil, err
+		}
+	}
+	if cacheConfig.StateSize {
+		bc.stateSizes = statesize.New(bc.db)
+		if err := bc.stateSizes.Open(bc.CurrentBlock().NumberU64()); err != nil {
+			return nil, err
+		}
+	}
 	// Take ownership of this particular state
 	go bc.update()
 	return bc, nil
 }
 
+// LogIndex retrieves the index of the blocks holding the logs of each contract,
+// nil if disabled.
+func (bc *BlockChain) LogIndex() *logindex.Index { return bc.logIndex }
+
+// StateSizes retrieves the storage and code size counters of each contract, nil
+// if disabled.
+func (bc *BlockChain) StateSizes() *statesize.Index { return bc.stateSizes }
+
+// openSnapshots opens the snapshots of the public and private states of the
+// head block, to read the states from them.
+func (bc *BlockChain) openSnapshots() error {
+	head := bc.CurrentBlock().Root()
+
+	var err error
+	if bc.snaps, err = snapshot.New(bc.db, bc.stateCache.TrieDB(), snapshot.PublicNamespace, head); err != nil {
+		return err
+	}
+	if bc.privateSnaps, err = snapshot.New(bc.db, bc.privateStateCache.TrieDB(), snapshot.PrivateNamespace, GetPrivateStateRoot(bc.db, head)); err != nil {
+		return err
+	}
+	bc.stateCache = state.NewDatabaseWithSnapshots(bc.stateCache, bc.snaps)
+	bc.privateStateCache = state.NewDatabaseWithSnapshots(bc.privateStateCache, bc.privateSnaps)
+	return nil
+}
+
 func (bc *BlockChain) getProcInterrupt() bool {
 	return atomic.LoadInt32(&bc.procInterrupt) == 1
 }
@@ -244,6 +314,7 @@ func (bc *BlockChain) loadLastState() error {
 
 	// Everything seems to be fine, set as the head block
 	bc.currentBlock.Store(currentBlock)
+	atomic.StoreUint64(&bc.stateCommitted, currentBlock.NumberU64()) // Quorum
 
 	// Restore the last known head header
 	currentHeader := currentBlock.Header()
@@ -289,6 +360,7 @@ func (bc *BlockChain) SetHead(head uint64) error {
 	// Rewind the header chain, deleting all block bodies until then
 	delFn := func(db rawdb.DatabaseDeleter, hash common.Hash, num uint64) {
 		rawdb.DeleteBody(db, hash, num)
+		bc.revertStateSizes(hash, num) // Quorum
 	}
 	bc.hc.SetHead(head, delFn)
 	currentHeader := bc.hc.CurrentHeader()
@@ -362,6 +434,42 @@ func (bc *BlockChain) GasLimit() uint64 {
 	}
 }
 
+// ArchiveMode reports whether trie write caching is disabled, i.e. whether the
+// state of every block is flushed to disk.
+func (bc *BlockChain) ArchiveMode() bool {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	return bc.cacheConfig.Disabled
+}
+
+// SetArchiveMode toggles between archive and full (pruning) operation. The
+// switch takes the chain write lock, so it always happens between two block
+// writes. When moving into archive mode the tries still held in memory are
+// flushed, so that no gap is left in the persisted state history.
+func (bc *BlockChain) SetArchiveMode(archive bool) error {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+
+	if bc.cacheConfig.Disabled == archive {
+		return nil
+	}
+	if archive {
+		triedb := bc.stateCache.TrieDB()
+		for !bc.triegc.Empty() {
+			root := bc.triegc.PopItem().(common.Hash)
+			if err := triedb.Commit(root, false); err != nil {
+				return err
+			}
+			triedb.Dereference(root)
+		}
+		atomic.StoreUint64(&bc.stateCommitted, bc.CurrentBlock().NumberU64())
+	}
+	bc.cacheConfig.Disabled = archive
+	log.Info("Switched state pruning mode", "archive", archive, "number", bc.CurrentBlock().NumberU64())
+	return nil
+}
+
 // CurrentBlock retrieves the current head block of the canonical chain. The
 // block is retrieved from the blockchain's internal cache.
 func (bc *BlockChain) CurrentBlock() *types.Block {
@@ -459,14 +567,27 @@ func (bc *BlockChain) ResetWithGenesisBlock(genesis *types.Block) error {
 // This method only rolls back the current block. The current header and current
 // fast block are left intact.
 func (bc *BlockChain) repair(head **types.Block) error {
+	// Quorum - rewind through the headers, as the history retention may have
+	// pruned the bodies of the blocks without state
+	header := (*head).Header()
 	for {
 		// Abort if we've rewound to a head block that does have associated state
-		if _, err := state.New((*head).Root(), bc.stateCache); err == nil {
-			log.Info("Rewound blockchain to past state", "number", (*head).Number(), "hash", (*head).Hash())
+		if _, err := state.New(header.Root, bc.stateCache); err == nil {
+			block := bc.GetBlock(header.Hash(), header.Number.Uint64())
+			if block == nil {
+				return fmt.Errorf("block #%d [%x…] with state is missing its body", header.Number, header.Hash().Bytes()[:4])
+			}
+			log.Info("Rewound blockchain to past state", "number", block.Number(), "hash", block.Hash())
+			(*head) = block
 			return nil
 		}
 		// Otherwise rewind one block and recheck state availability there
-		(*head) = bc.GetBlock((*head).ParentHash(), (*head).NumberU64()-1)
+		if header.Number.Sign() == 0 {
+			return fmt.Errorf("no state found down to the genesis block")
+		}
+		if header = bc.GetHeader(header.ParentHash, header.Number.Uint64()-1); header == nil {
+			return fmt.Errorf("missing header of block #%d while repairing the chain", (*head).NumberU64())
+		}
 	}
 }
 
@@ -686,6 +807,15 @@ func (bc *BlockChain) TrieNode(hash common.Hash) ([]byte, error) {
 	return bc.stateCache.TrieDB().Node(hash)
 }
 
+// Quorum
+//
+// PrivateTrieNode retrieves a blob of data associated with a private trie node
+// (or code hash) either from ephemeral in-memory cache, or from persistent
+// storage.
+func (bc *BlockChain) PrivateTrieNode(hash common.Hash) ([]byte, error) {
+	return bc.privateStateCache.TrieDB().Node(hash)
+}
+
 // Stop stops the blockchain service. If any imports are currently in progress
 // it will abort them using the procInterrupt.
 func (bc *BlockChain) Stop() {
@@ -699,6 +829,12 @@ func (bc *BlockChain) Stop() {
 
 	bc.wg.Wait()
 
+	// Persist the state snapshots of the head block, to reuse them on restart
+	if bc.snaps != nil {
+		head := bc.CurrentBlock().Root()
+		bc.snaps.Stop(head)
+		bc.privateSnaps.Stop(GetPrivateStateRoot(bc.db, head))
+	}
 	// Ensure the state of a recent block is also stored to disk before exiting.
 	// We're writing three different states to catch different restart scenarios:
 	//  - HEAD:     So we don't need to reprocess any blocks in the general case
@@ -862,6 +998,11 @@ func (bc *BlockChain) InsertReceiptChain(blockChain types.Blocks, receiptChain [
 		rawdb.WriteBody(batch, block.Hash(), block.NumberU64(), block.Body())
 		rawdb.WriteReceipts(batch, block.Hash(), block.NumberU64(), receipts)
 		rawdb.WriteTxLookupEntries(batch, block)
+		if bc.logIndex != nil {
+			if err := bc.logIndex.Index(block.NumberU64(), receipts); err != nil {
+				return i, err
+			}
+		}
 
 		stats.processed++
 
@@ -927,6 +1068,61 @@ func (bc *BlockChain) WriteBlockWithState(block *types.Block, receipts []*types.
 	bc.wg.Add(1)
 	defer bc.wg.Done()
 
+	// Calculate the total difficulty of the block
+	if ptd := bc.GetTd(block.ParentHash(), block.NumberU64()-1); ptd == nil {
+		return NonStatTy, consensus.ErrUnknownAncestor
+	}
+	root, privateRoot, err := bc.commitState(block, state, privateState)
+	if err != nil {
+		return NonStatTy, err
+	}
+	return bc.writeBlockWithCommittedState(block, receipts, root, privateRoot, state.Preimages(), privateState.Preimages())
+}
+
+// commitState commits the public and private state tries resulting from the
+// execution of a block to the in-memory trie databases.
+func (bc *BlockChain) commitState(block *types.Block, state, privateState *state.StateDB) (root common.Hash, privateRoot common.Hash, err error) {
+	// Quorum
+	// Write private state changes to database
+	bc.erasePurgedContracts(privateState)
+	if privateRoot, err = privateState.Commit(bc.chainConfig.IsEIP158(block.Number())); err != nil {
+		return common.Hash{}, common.Hash{}, err
+	}
+	// /Quorum
+	if root, err = state.Commit(bc.chainConfig.IsEIP158(block.Number())); err != nil {
+		return common.Hash{}, common.Hash{}, err
+	}
+	if bc.stateSizes != nil {
+		if err := bc.stateSizes.Record(block.Hash(), block.NumberU64(), state.SizeDeltas()); err != nil {
+			return common.Hash{}, common.Hash{}, err
+		}
+	}
+	return root, privateRoot, nil
+}
+
+// applyStateSizes counts the size changes of a block made canonical in the
+// state size counters, if enabled.
+func (bc *BlockChain) applyStateSizes(block *types.Block) {
+	if bc.stateSizes != nil {
+		if err := bc.stateSizes.Apply(block.Hash(), block.NumberU64()); err != nil {
+			log.Error("Failed to update state size counters", "number", block.Number(), "hash", block.Hash(), "err", err)
+		}
+	}
+}
+
+// revertStateSizes reverts the size changes of a block leaving the canonical
+// chain from the state size counters, if enabled.
+func (bc *BlockChain) revertStateSizes(hash common.Hash, number uint64) {
+	if bc.stateSizes != nil {
+		if err := bc.stateSizes.Revert(hash, number); err != nil {
+			log.Error("Failed to revert state size counters", "number", number, "hash", hash, "err", err)
+		}
+	}
+}
+
+// writeBlockWithCommittedState writes the block and the state tries committed
+// by commitState to the database, along with the preimages of the execution.
+func (bc *BlockChain) writeBlockWithCommittedState(block *types.Block, receipts []*types.Receipt, root, privateRoot common.Hash, preimages ...map[common.Hash][]byte) (status WriteStatus, err error) {
 	// Calculate the total difficulty of the block
 	ptd := bc.GetTd(block.ParentHash(), block.NumberU64()-1)
 	if ptd == nil {
@@ -937,11 +1133,6 @@ func (bc *BlockChain) WriteBlockWithState(block *types.Block, receipts []*types.
 	defer bc.mu.Unlock()
 
 	// Quorum
-	// Write private state changes to database
-	privateRoot, err := privateState.Commit(bc.chainConfig.IsEIP158(block.Number()))
-	if err != nil {
-		return NonStatTy, err
-	}
 	if err := WritePrivateStateRoot(bc.db, block.Root(), privateRoot); err != nil {
 		log.Error("Failed writing private state root", "err", err)
 		return NonStatTy, err
@@ -951,6 +1142,11 @@ func (bc *BlockChain) WriteBlockWithState(block *types.Block, receipts []*types.
 	if err := privateTriedb.Commit(privateRoot, false); err != nil {
 		return NonStatTy, err
 	}
+	if bc.privateSnaps != nil {
+		if err := bc.privateSnaps.Cap(privateRoot, triesInMemory); err != nil {
+			log.Debug("Failed to cap private state snapshots", "root", privateRoot, "err", err)
+		}
+	}
 	// /Quorum
 
 	currentBlock := bc.CurrentBlock()
@@ -963,10 +1159,10 @@ func (bc *BlockChain) WriteBlockWithState(block *types.Block, receipts []*types.
 	}
 	rawdb.WriteBlock(bc.db, block)
 
-	root, err := state.Commit(bc.chainConfig.IsEIP158(block.Number()))
-
-	if err != nil {
-		return NonStatTy, err
+	if bc.snaps != nil {
+		if err := bc.snaps.Cap(root, triesInMemory); err != nil {
+			log.Debug("Failed to cap state snapshots", "root", root, "err", err)
+		}
 	}
 	triedb := bc.stateCache.TrieDB()
 
@@ -976,6 +1172,7 @@ func (bc *BlockChain) WriteBlockWithState(block *types.Block, receipts []*types.
 		if err := triedb.Commit(root, false); err != nil {
 			return NonStatTy, err
 		}
+		atomic.StoreUint64(&bc.stateCommitted, block.NumberU64()) // Quorum
 
 	} else {
 		// Full but not archive node, do proper garbage collection
@@ -1005,6 +1202,7 @@ func (bc *BlockChain) WriteBlockWithState(block *types.Block, receipts []*types.
 				// Flush an entire trie and restart the counters
 				triedb.Commit(header.Root, true)
 				lastWrite = chosen
+				atomic.StoreUint64(&bc.stateCommitted, chosen) // Quorum
 				bc.gcproc = 0
 			}
 			// Garbage collect anything below our required write retention
@@ -1022,6 +1220,11 @@ func (bc *BlockChain) WriteBlockWithState(block *types.Block, receipts []*types.
 	// Write other block data using a batch.
 	batch := bc.db.NewBatch()
 	rawdb.WriteReceipts(batch, block.Hash(), block.NumberU64(), receipts)
+	if bc.logIndex != nil {
+		if err := bc.logIndex.Index(block.NumberU64(), receipts); err != nil {
+			return NonStatTy, err
+		}
+	}
 
 	// If the total difficulty is higher than our known, add it to the canonical chain
 	// Second clause in the if statement reduces the vulnerability to selfish mining.
@@ -1050,7 +1253,7 @@ func (bc *BlockChain) WriteBlockWithState(block *types.Block, receipts []*types.
 		}
 		// Write the positional metadata for transaction/receipt lookups and preimages
 		rawdb.WriteTxLookupEntries(batch, block)
-		rawdb.WritePreimages(batch, state.Preimages())
+		bc.writePreimages(batch, block.NumberU64(), block.Hash(), preimages...)
 
 		status = CanonStatTy
 	} else {
@@ -1063,6 +1266,12 @@ func (bc *BlockChain) WriteBlockWithState(block *types.Block, receipts []*types.
 	// Set new head.
 	if status == CanonStatTy {
 		bc.insert(block)
+		bc.applyStateSizes(block)
+	}
+	// Quorum: record the inclusion of traced transactions
+	for _, receipt := range receipts {
+		txtrail.Record(receipt.TxHash, txtrail.StageIncluded, "number", block.Number(), "block", block.Hash(), "canonical", status == CanonStatTy)
+		txtrail.Record(receipt.TxHash, txtrail.StageReceipt, "status", receipt.Status, "gasUsed", receipt.GasUsed)
 	}
 	bc.futureBlocks.Remove(block.Hash())
 	return status, nil
@@ -1129,13 +1338,11 @@ func (bc *BlockChain) insertChain(chain types.Blocks) (int, []interface{}, []*ty
 
 	// A queued approach to delivering events. This is generally
 	// faster than direct delivery and requires much less mutex
-	// acquiring.
-	var (
-		stats         = insertStats{startTime: mclock.Now()}
-		events        = make([]interface{}, 0, len(chain))
-		lastCanon     *types.Block
-		coalescedLogs []*types.Log
-	)
+	// acquiring. The events are accumulated by the writer of the
+	// executed blocks, pipelined with their execution if configured.
+	writer := bc.newImportWriter(chain)
+	defer writer.close()
+
 	// Start the parallel header verifier
 	headers := make([]*types.Header, len(chain))
 	seals := make([]bool, len(chain))
@@ -1158,7 +1365,7 @@ func (bc *BlockChain) insertChain(chain types.Blocks) (int, []interface{}, []*ty
 			// QUORUM
 			if bc.chainConfig.IsQuorum && bc.chainConfig.Istanbul == nil && bc.chainConfig.Clique == nil {
 				// Only returns an error for raft mode
-				return i, events, coalescedLogs, ErrAbortBlocksProcessing
+				return writer.fail(i, ErrAbortBlocksProcessing)
 			}
 			// END QUORUM
 			break
@@ -1166,7 +1373,7 @@ func (bc *BlockChain) insertChain(chain types.Blocks) (int, []interface{}, []*ty
 		// If the header is a banned one, straight out abort
 		if BadHashes[block.Hash()] {
 			bc.reportBlock(block, nil, ErrBlacklistedHash)
-			return i, events, coalescedLogs, ErrBlacklistedHash
+			return writer.fail(i, ErrBlacklistedHash)
 		}
 		// Wait for the block's verification to complete
 		bstart := time.Now()
@@ -1175,12 +1382,18 @@ func (bc *BlockChain) insertChain(chain types.Blocks) (int, []interface{}, []*ty
 		if err == nil {
 			err = bc.Validator().ValidateBody(block)
 		}
+		if err != nil {
+			// Known, future and side blocks are handled against the written chain
+			if index, werr := writer.drain(); werr != nil {
+				return index, writer.events, writer.logs, werr
+			}
+		}
 		switch {
 		case err == ErrKnownBlock:
 			// Block and state both already known. However if the current block is below
 			// this number we did a rollback and we should reimport it nonetheless.
 			if bc.CurrentBlock().NumberU64() >= block.NumberU64() {
-				stats.ignored++
+				writer.stats.ignored++
 				continue
 			}
 
@@ -1189,15 +1402,15 @@ func (bc *BlockChain) insertChain(chain types.Blocks) (int, []interface{}, []*ty
 			// the chain is discarded and processed at a later time if given.
 			max := big.NewInt(time.Now().Unix() + maxTimeFutureBlocks)
 			if block.Time().Cmp(max) > 0 && !bc.chainConfig.IsQuorum {
-				return i, events, coalescedLogs, fmt.Errorf("future block: %v > %v", block.Time(), max)
+				return writer.fail(i, fmt.Errorf("future block: %v > %v", block.Time(), max))
 			}
 			bc.futureBlocks.Add(block.Hash(), block)
-			stats.queued++
+			writer.stats.queued++
 			continue
 
 		case err == consensus.ErrUnknownAncestor && bc.futureBlocks.Contains(block.ParentHash()):
 			bc.futureBlocks.Add(block.Hash(), block)
-			stats.queued++
+			writer.stats.queued++
 			continue
 
 		case err == consensus.ErrPrunedAncestor:
@@ -1208,7 +1421,7 @@ func (bc *BlockChain) insertChain(chain types.Blocks) (int, []interface{}, []*ty
 			externTd := new(big.Int).Add(bc.GetTd(block.ParentHash(), block.NumberU64()-1), block.Difficulty())
 			if localTd.Cmp(externTd) > 0 {
 				if err = bc.WriteBlockWithoutState(block, externTd); err != nil {
-					return i, events, coalescedLogs, err
+					return writer.fail(i, err)
 				}
 				continue
 			}
@@ -1227,15 +1440,15 @@ func (bc *BlockChain) insertChain(chain types.Blocks) (int, []interface{}, []*ty
 			bc.chainmu.Unlock()
 			_, evs, logs, err := bc.insertChain(winner)
 			bc.chainmu.Lock()
-			events, coalescedLogs = evs, logs
+			writer.events, writer.logs = evs, logs
 
 			if err != nil {
-				return i, events, coalescedLogs, err
+				return writer.fail(i, err)
 			}
 
 		case err != nil:
 			bc.reportBlock(block, nil, err)
-			return i, events, coalescedLogs, err
+			return writer.fail(i, err)
 		}
 		// Create a new statedb using the parent block and report an
 		// error if it fails.
@@ -1251,14 +1464,14 @@ func (bc *BlockChain) insertChain(chain types.Blocks) (int, []interface{}, []*ty
 
 		state, err := state.New(parent.Root(), bc.stateCache)
 		if err != nil {
-			return i, events, coalescedLogs, err
+			return writer.fail(i, err)
 		}
 
 		// Quorum
-		privateStateRoot := GetPrivateStateRoot(bc.db, parent.Root())
+		privateStateRoot := writer.parentPrivateRoot(parent)
 		privateState, err := stateNew(privateStateRoot, bc.privateStateCache)
 		if err != nil {
-			return i, events, coalescedLogs, err
+			return writer.fail(i, err)
 		}
 		// /Quorum
 
@@ -1266,57 +1479,39 @@ func (bc *BlockChain) insertChain(chain types.Blocks) (int, []interface{}, []*ty
 		receipts, privateReceipts, logs, usedGas, err := bc.processor.Process(block, state, privateState, bc.vmConfig)
 		if err != nil {
 			bc.reportBlock(block, receipts, err)
-			return i, events, coalescedLogs, err
+			return writer.fail(i, err)
 		}
-		// Validate the state using the default validator
-		err = bc.Validator().ValidateState(block, parent, state, receipts, usedGas)
-		if err != nil {
-			bc.reportBlock(block, receipts, err)
-			return i, events, coalescedLogs, err
-		}
-
 		allReceipts := mergeReceipts(receipts, privateReceipts)
 		proctime := time.Since(bstart)
 
-		// Write the block to the chain and get the status.
-		status, err := bc.WriteBlockWithState(block, allReceipts, state, privateState)
+		// Validate the state using the default validator and write the block to
+		// the chain, or queue it for both
+		err = writer.submit(&executedBlock{
+			index:           i,
+			block:           block,
+			parent:          parent,
+			receipts:        allReceipts,
+			publicReceipts:  receipts,
+			privateReceipts: privateReceipts,
+			logs:            logs,
+			usedGas:         usedGas,
+			state:           state,
+			privateState:    privateState,
+			start:           bstart,
+			proctime:        proctime,
+		})
 		if err != nil {
-			return i, events, coalescedLogs, err
-		}
-		if err := WritePrivateBlockBloom(bc.db, block.NumberU64(), privateReceipts); err != nil {
-			return i, events, coalescedLogs, err
-		}
-		switch status {
-		case CanonStatTy:
-			log.Debug("Inserted new block", "number", block.Number(), "hash", block.Hash(), "uncles", len(block.Uncles()),
-				"txs", len(block.Transactions()), "gas", block.GasUsed(), "elapsed", common.PrettyDuration(time.Since(bstart)))
-
-			coalescedLogs = append(coalescedLogs, logs...)
-			blockInsertTimer.UpdateSince(bstart)
-			events = append(events, ChainEvent{block, block.Hash(), logs})
-			lastCanon = block
-
-			// Only count canonical blocks for GC processing time
-			bc.gcproc += proctime
-
-		case SideStatTy:
-			log.Debug("Inserted forked block", "number", block.Number(), "hash", block.Hash(), "diff", block.Difficulty(), "elapsed",
-				common.PrettyDuration(time.Since(bstart)), "txs", len(block.Transactions()), "gas", block.GasUsed(), "uncles", len(block.Uncles()))
-
-			blockInsertTimer.UpdateSince(bstart)
-			events = append(events, ChainSideEvent{block})
+			return writer.fail(i, err)
 		}
-		stats.processed++
-		stats.usedGas += usedGas
-
-		cache, _ := bc.stateCache.TrieDB().Size()
-		stats.report(chain, i, cache)
+	}
+	if index, err := writer.drain(); err != nil {
+		return index, writer.events, writer.logs, err
 	}
 	// Append a single chain head event if we've progressed the chain
-	if lastCanon != nil && bc.CurrentBlock().Hash() == lastCanon.Hash() {
-		events = append(events, ChainHeadEvent{lastCanon})
+	if writer.lastCanon != nil && bc.CurrentBlock().Hash() == writer.lastCanon.Hash() {
+		writer.events = append(writer.events, ChainHeadEvent{writer.lastCanon})
 	}
-	return 0, events, coalescedLogs, nil
+	return 0, writer.events, writer.logs, nil
 }
 
 // insertStats tracks and reports on block insertion.
@@ -1456,11 +1651,16 @@ func (bc *BlockChain) reorg(oldBlock, newBlock *types.Block) error {
 	} else {
 		log.Error("Impossible reorg, please file an issue", "oldnum", oldBlock.Number(), "oldhash", oldBlock.Hash(), "newnum", newBlock.Number(), "newhash", newBlock.Hash())
 	}
+	// Quorum: revert the state size counters of the old chain, from its head down
+	for _, block := range oldChain {
+		bc.revertStateSizes(block.Hash(), block.NumberU64())
+	}
 	// Insert the new chain, taking care of the proper incremental order
 	var addedTxs types.Transactions
 	for i := len(newChain) - 1; i >= 0; i-- {
 		// insert the block in the canonical way, re-writing history
 		bc.insert(newChain[i])
+		bc.applyStateSizes(newChain[i]) // Quorum
 		// write lookup entries for hash based transaction/receipt searches
 		rawdb.WriteTxLookupEntries(bc.db, newChain[i])
 		addedTxs = append(addedTxs, newChain[i].Transactions()...)
@@ -0,0 +1,368 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/mclock"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+// errImportWriteFailed is returned when a block can't be queued for validation
+// as an earlier one failed to be validated or written, whose error is reported
+// instead.
+var errImportWriteFailed = errors.New("import pipeline write failed")
+
+// Metrics for the import pipeline
+var importPipelineWaitTimer = metrics.NewRegisteredTimer("chain/import/pipeline/wait", nil) // Execution stalled on a full write queue
+
+// executedBlock is a block executed during a chain import, along with the
+// states and receipts it resulted in, waiting to be validated and written.
+type executedBlock struct {
+	index  int // Position of the block in the imported chain
+	block  *types.Block
+	parent *types.Block
+
+	receipts        types.Receipts // Public receipts merged with the private ones
+	publicReceipts  types.Receipts
+	privateReceipts types.Receipts
+	logs            []*types.Log
+	usedGas         uint64
+
+	state        *state.StateDB
+	privateState *state.StateDB
+	root         common.Hash // Roots of the states, once committed
+	privateRoot  common.Hash
+
+	start    time.Time     // Start of the verification of the block
+	proctime time.Duration // Time taken to execute the block
+}
+
+// importChain is the view of the chain during an import, serving the blocks
+// executed but not yet written by the import pipeline to the validation and
+// execution of their descendants. The other users of the chain only see the
+// blocks once written.
+type importChain struct {
+	*BlockChain
+}
+
+// GetBlock retrieves a block from the import pipeline, or else the database.
+func (c importChain) GetBlock(hash common.Hash, number uint64) *types.Block {
+	if block, ok := c.importing.Load(hash); ok {
+		return block.(*types.Block)
+	}
+	return c.BlockChain.GetBlock(hash, number)
+}
+
+// GetHeader retrieves a block header from the import pipeline, or else the
+// database.
+func (c importChain) GetHeader(hash common.Hash, number uint64) *types.Header {
+	if block, ok := c.importing.Load(hash); ok {
+		return block.(*types.Block).Header()
+	}
+	return c.BlockChain.GetHeader(hash, number)
+}
+
+// GetHeaderByHash retrieves a block header from the import pipeline, or else
+// the database.
+func (c importChain) GetHeaderByHash(hash common.Hash) *types.Header {
+	if block, ok := c.importing.Load(hash); ok {
+		return block.(*types.Block).Header()
+	}
+	return c.BlockChain.GetHeaderByHash(hash)
+}
+
+// HasBlock checks if a block is in the import pipeline or the database.
+func (c importChain) HasBlock(hash common.Hash, number uint64) bool {
+	if _, ok := c.importing.Load(hash); ok {
+		return true
+	}
+	return c.BlockChain.HasBlock(hash, number)
+}
+
+// HasBlockAndState checks if a block and its state trie are in the import
+// pipeline, whose states are committed to the trie database, or the database.
+func (c importChain) HasBlockAndState(hash common.Hash, number uint64) bool {
+	block := c.GetBlock(hash, number)
+	if block == nil {
+		return false
+	}
+	return c.HasState(block.Root())
+}
+
+// importWriter runs the last stages of the chain import, validating the states
+// and receipts of the executed blocks, writing them to the database and
+// accumulating the chain events they produce.
+//
+// The blocks of a chain are imported in stages: the headers are verified and
+// the senders recovered in parallel ahead of the execution, the blocks are then
+// executed in order, each on top of the state of its parent, validated and
+// written. Without pipelining, every block is validated and written before the
+// next one is executed. With pipelining, the state tries of a block are
+// committed to the in-memory trie database right after its execution, so that
+// its child executes on top of them while the block itself is validated, then
+// written, by separate goroutines, each at most depth blocks behind. The blocks
+// not yet written are only served by the importChain view, to the validation
+// and the BLOCKHASH lookups of their descendants. A block failing validation
+// drops its descendants, executed on top of its invalid state.
+type importWriter struct {
+	bc    *BlockChain
+	chain types.Blocks
+	depth int
+
+	queue   chan *executedBlock // Blocks executed, to validate
+	writes  chan *executedBlock // Blocks validated, to write
+	pending sync.WaitGroup      // Blocks queued and not yet written
+	failed  chan struct{}       // Closed when a block failed to be validated or written
+	done    chan struct{}       // Closed when the write loop exited
+
+	// Tip of the execution, the parent of the next executed block
+	lastHash    common.Hash
+	privateRoot common.Hash
+
+	// First block which failed to be validated or written
+	errLock  sync.Mutex
+	err      error
+	errIndex int
+
+	// Owned by the write loop while blocks are pending, by the import otherwise
+	stats     insertStats
+	events    []interface{}
+	logs      []*types.Log
+	lastCanon *types.Block
+}
+
+// newImportWriter creates the writer of the blocks of a chain import, running
+// the validation and write loops if the import is pipelined. Chains of a single
+// block, as propagated by the consensus, are never pipelined.
+func (bc *BlockChain) newImportWriter(chain types.Blocks) *importWriter {
+	w := &importWriter{
+		bc:     bc,
+		chain:  chain,
+		stats:  insertStats{startTime: mclock.Now()},
+		events: make([]interface{}, 0, len(chain)),
+	}
+	if bc.cacheConfig.ImportPipeline > 0 && len(chain) > 1 {
+		w.depth = bc.cacheConfig.ImportPipeline
+		w.queue = make(chan *executedBlock, w.depth)
+		w.writes = make(chan *executedBlock, w.depth)
+		w.failed = make(chan struct{})
+		w.done = make(chan struct{})
+		go w.validateLoop()
+		go w.writeLoop()
+	}
+	return w
+}
+
+// validateLoop validates the queued blocks in order, handing them over to the
+// write loop.
+func (w *importWriter) validateLoop() {
+	defer close(w.writes)
+
+	for b := range w.queue {
+		if w.keep(b.index) {
+			if err := w.validate(b); err != nil {
+				w.abort(b.index, err)
+			}
+		}
+		w.writes <- b
+	}
+}
+
+// writeLoop writes the validated blocks in order. Once a block failed to be
+// validated or written, the blocks executed on top of it are dropped.
+func (w *importWriter) writeLoop() {
+	defer close(w.done)
+
+	for b := range w.writes {
+		if w.keep(b.index) {
+			if err := w.write(b); err != nil {
+				w.abort(b.index, err)
+			}
+		}
+		w.bc.importing.Delete(b.block.Hash())
+		w.pending.Done()
+	}
+}
+
+// keep reports whether the block of the given index is still to be validated
+// and written, coming before any block which failed to be.
+func (w *importWriter) keep(index int) bool {
+	w.errLock.Lock()
+	defer w.errLock.Unlock()
+
+	return w.err == nil || index < w.errIndex
+}
+
+// abort records the failure of a block, dropping the blocks after it. The
+// failure of the earliest block is reported.
+func (w *importWriter) abort(index int, err error) {
+	w.errLock.Lock()
+	defer w.errLock.Unlock()
+
+	if w.err == nil {
+		close(w.failed)
+	} else if index >= w.errIndex {
+		return
+	}
+	w.err, w.errIndex = err, index
+}
+
+// parentPrivateRoot returns the private state root of the parent of the block
+// to execute, which may not be written yet if it was executed last.
+func (w *importWriter) parentPrivateRoot(parent *types.Block) common.Hash {
+	if w.depth > 0 && parent.Hash() == w.lastHash {
+		return w.privateRoot
+	}
+	return GetPrivateStateRoot(w.bc.db, parent.Root())
+}
+
+// submit hands over an executed block. Without pipelining it is validated, its
+// state tries committed, and written right away. Otherwise its state tries are
+// committed for its child to execute on top of them, and it is queued for
+// validation, blocking while depth blocks are already waiting.
+func (w *importWriter) submit(b *executedBlock) (err error) {
+	if w.depth == 0 {
+		if err := w.validate(b); err != nil {
+			return err
+		}
+		if b.root, b.privateRoot, err = w.bc.commitState(b.block, b.state, b.privateState); err != nil {
+			return err
+		}
+		return w.write(b)
+	}
+	if b.root, b.privateRoot, err = w.bc.commitState(b.block, b.state, b.privateState); err != nil {
+		return err
+	}
+	// The child executes on top of the committed tries
+	w.lastHash, w.privateRoot = b.block.Hash(), b.privateRoot
+
+	w.bc.importing.Store(b.block.Hash(), b.block)
+	w.pending.Add(1)
+	select {
+	case w.queue <- b:
+		return nil
+	default:
+	}
+	start := time.Now()
+	defer importPipelineWaitTimer.UpdateSince(start)
+
+	select {
+	case w.queue <- b:
+		return nil
+	case <-w.failed:
+		w.bc.importing.Delete(b.block.Hash())
+		w.pending.Done()
+		return errImportWriteFailed
+	}
+}
+
+// validate checks the state and receipts resulting from the execution of a
+// block against its header. Once committed, the state tries of the block are
+// shared with the state cache, so they are opened anew for the validation.
+func (w *importWriter) validate(b *executedBlock) error {
+	statedb := b.state
+	if w.depth > 0 {
+		var err error
+		if statedb, err = state.New(b.root, w.bc.stateCache); err != nil {
+			return err
+		}
+	}
+	if err := w.bc.Validator().ValidateState(b.block, b.parent, statedb, b.publicReceipts, b.usedGas); err != nil {
+		w.bc.reportBlock(b.block, b.publicReceipts, err)
+		return err
+	}
+	return nil
+}
+
+// write writes an executed block with its states and receipts, recording the
+// chain events it produces.
+func (w *importWriter) write(b *executedBlock) error {
+	bc, block := w.bc, b.block
+
+	// Write the block to the chain and get the status.
+	status, err := bc.writeBlockWithCommittedState(block, b.receipts, b.root, b.privateRoot, b.state.Preimages(), b.privateState.Preimages())
+	if err != nil {
+		return err
+	}
+	if err := WritePrivateBlockBloom(bc.db, block.NumberU64(), b.privateReceipts); err != nil {
+		return err
+	}
+	switch status {
+	case CanonStatTy:
+		log.Debug("Inserted new block", "number", block.Number(), "hash", block.Hash(), "uncles", len(block.Uncles()),
+			"txs", len(block.Transactions()), "gas", block.GasUsed(), "elapsed", common.PrettyDuration(time.Since(b.start)))
+
+		w.logs = append(w.logs, b.logs...)
+		blockInsertTimer.UpdateSince(b.start)
+		w.events = append(w.events, ChainEvent{block, block.Hash(), b.logs})
+		w.lastCanon = block
+
+		// Only count canonical blocks for GC processing time
+		bc.gcproc += b.proctime
+
+	case SideStatTy:
+		log.Debug("Inserted forked block", "number", block.Number(), "hash", block.Hash(), "diff", block.Difficulty(), "elapsed",
+			common.PrettyDuration(time.Since(b.start)), "txs", len(block.Transactions()), "gas", block.GasUsed(), "uncles", len(block.Uncles()))
+
+		blockInsertTimer.UpdateSince(b.start)
+		w.events = append(w.events, ChainSideEvent{block})
+	}
+	w.stats.processed++
+	w.stats.usedGas += b.usedGas
+
+	cache, _ := bc.stateCache.TrieDB().Size()
+	w.stats.report(w.chain, b.index, cache)
+	return nil
+}
+
+// drain waits for the queued blocks to be validated and written, returning the
+// index of the first one that failed to be, and its error.
+func (w *importWriter) drain() (int, error) {
+	if w.depth > 0 {
+		w.pending.Wait()
+	}
+	w.errLock.Lock()
+	defer w.errLock.Unlock()
+
+	return w.errIndex, w.err
+}
+
+// fail aborts the import at the given block, reporting instead an earlier block
+// that failed to be validated or written if any.
+func (w *importWriter) fail(index int, err error) (int, []interface{}, []*types.Log, error) {
+	if windex, werr := w.drain(); werr != nil {
+		index, err = windex, werr
+	}
+	return index, w.events, w.logs, err
+}
+
+// close stops the validation and write loops, once all the queued blocks are
+// handled.
+func (w *importWriter) close() {
+	if w.depth > 0 {
+		close(w.queue)
+		<-w.done
+	}
+}
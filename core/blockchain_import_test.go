@@ -0,0 +1,173 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// newPipelineTestChain generates blocks calling a contract which stores the hash
+// of the grandparent of every block, looked up through the chain as its parent
+// may not be written yet when the import is pipelined.
+func newPipelineTestChain(n int) (*Genesis, types.Blocks) {
+	var (
+		key, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		address = crypto.PubkeyToAddress(key.PublicKey)
+		// sstore(number, blockhash(number - 2))
+		contract = common.HexToAddress("0xc0de")
+		code     = common.Hex2Bytes("4360029003404355")
+		gspec    = &Genesis{
+			Config: params.TestChainConfig,
+			Alloc: GenesisAlloc{
+				address:  {Balance: big.NewInt(1000000000)},
+				contract: {Balance: new(big.Int), Code: code},
+			},
+		}
+		signer = types.NewEIP155Signer(gspec.Config.ChainID)
+		blocks types.Blocks
+	)
+	// Generate the blocks one by one, the contract looking up their ancestors
+	generator := newPipelineBlockChain(gspec, 0)
+	defer generator.Stop()
+
+	parent := generator.Genesis()
+	for i := 0; i < n; i++ {
+		block, _ := GenerateChain(gspec.Config, parent, ethash.NewFaker(), generator.db, 1, func(i int, block *BlockGen) {
+			tx, err := types.SignTx(types.NewTransaction(block.TxNonce(address), contract, new(big.Int), 100000, nil, nil), signer, key)
+			if err != nil {
+				panic(err)
+			}
+			block.AddTxWithChain(generator, tx)
+		})
+		if _, err := generator.InsertChain(block); err != nil {
+			panic(err)
+		}
+		blocks, parent = append(blocks, block[0]), block[0]
+	}
+	return gspec, blocks
+}
+
+func newPipelineBlockChain(gspec *Genesis, depth int) *BlockChain {
+	db := ethdb.NewMemDatabase()
+	gspec.MustCommit(db)
+	chain, _ := NewBlockChain(db, &CacheConfig{TrieNodeLimit: 256, TrieTimeLimit: 5 * time.Minute, ImportPipeline: depth}, gspec.Config, ethash.NewFaker(), vm.Config{}, nil)
+	return chain
+}
+
+// Tests that a pipelined import results in the same chain and states as a
+// sequential one.
+func TestPipelinedImport(t *testing.T) {
+	gspec, blocks := newPipelineTestChain(2 * triesInMemory)
+
+	sequential := newPipelineBlockChain(gspec, 0)
+	defer sequential.Stop()
+	pipelined := newPipelineBlockChain(gspec, 4)
+	defer pipelined.Stop()
+
+	for _, chain := range []*BlockChain{sequential, pipelined} {
+		if n, err := chain.InsertChain(blocks); err != nil {
+			t.Fatalf("failed to insert block %d: %v", n, err)
+		}
+	}
+	if have, want := pipelined.CurrentBlock().Hash(), blocks[len(blocks)-1].Hash(); have != want {
+		t.Fatalf("head mismatch: have %x, want %x", have, want)
+	}
+	statedb, _, err := pipelined.State()
+	if err != nil {
+		t.Fatalf("failed to open head state: %v", err)
+	}
+	for _, number := range []uint64{3, triesInMemory, uint64(len(blocks))} {
+		slot := common.BigToHash(new(big.Int).SetUint64(number))
+		if have, want := statedb.GetState(common.HexToAddress("0xc0de"), slot), blocks[number-3].Hash(); have != want {
+			t.Errorf("block %d: stored hash mismatch: have %x, want %x", number, have, want)
+		}
+	}
+	for _, block := range blocks {
+		if have, want := len(pipelined.GetReceiptsByHash(block.Hash())), len(block.Transactions()); have != want {
+			t.Fatalf("block %d: receipt count mismatch: have %d, want %d", block.NumberU64(), have, want)
+		}
+	}
+	if _, ok := pipelined.importing.Load(blocks[len(blocks)-1].Hash()); ok {
+		t.Errorf("written header still pending")
+	}
+}
+
+// Tests that a pipelined import stops at the first invalid block, reporting it,
+// with all the blocks before it written.
+func TestPipelinedImportFailure(t *testing.T) {
+	gspec, blocks := newPipelineTestChain(64)
+
+	corruptions := map[string]func(header *types.Header){
+		"state root":   func(header *types.Header) { header.Root = common.Hash{0x01} },
+		"receipt root": func(header *types.Header) { header.ReceiptHash = common.Hash{0x01} },
+	}
+	for name, corrupt := range corruptions {
+		// Corrupt a block in the middle of the chain
+		invalid := make(types.Blocks, 41)
+		copy(invalid, blocks)
+		header := types.CopyHeader(blocks[40].Header())
+		corrupt(header)
+		invalid[40] = types.NewBlockWithHeader(header).WithBody(blocks[40].Transactions(), blocks[40].Uncles())
+
+		chain := newPipelineBlockChain(gspec, 8)
+		n, err := chain.InsertChain(invalid)
+		if err == nil || n != 40 {
+			t.Errorf("%s: invalid block error mismatch: have %d, %v, want 40", name, n, err)
+		}
+		if have, want := chain.CurrentBlock().Hash(), blocks[39].Hash(); have != want {
+			t.Errorf("%s: head mismatch: have %x, want %x", name, have, want)
+		}
+		chain.Stop()
+	}
+}
+
+// Tests that the blocks in the import pipeline are only served to the import,
+// until they are written.
+func TestPipelinedImportVisibility(t *testing.T) {
+	gspec, blocks := newPipelineTestChain(2)
+
+	chain := newPipelineBlockChain(gspec, 4)
+	defer chain.Stop()
+
+	block := blocks[0]
+	chain.importing.Store(block.Hash(), block)
+	defer chain.importing.Delete(block.Hash())
+
+	if chain.GetBlock(block.Hash(), block.NumberU64()) != nil || chain.GetBlockByHash(block.Hash()) != nil {
+		t.Errorf("unwritten block served")
+	}
+	if chain.GetHeader(block.Hash(), block.NumberU64()) != nil || chain.GetHeaderByHash(block.Hash()) != nil {
+		t.Errorf("unwritten header served")
+	}
+	view := importChain{chain}
+	if view.GetBlock(block.Hash(), block.NumberU64()) == nil || view.GetHeaderByHash(block.Hash()) == nil {
+		t.Errorf("unwritten block not served to the import")
+	}
+	if !view.HasBlock(block.Hash(), block.NumberU64()) {
+		t.Errorf("unwritten block unknown to the import")
+	}
+}
@@ -191,6 +191,9 @@ func GenerateChain(config *params.ChainConfig, parent *types.Block, engine conse
 		if config.DAOForkSupport && config.DAOForkBlock != nil && config.DAOForkBlock.Cmp(b.header.Number) == 0 {
 			misc.ApplyDAOHardFork(statedb)
 		}
+		if config.IsDeterministicDeploymentBlock(b.header.Number) {
+			misc.ApplyDeterministicDeployment(statedb)
+		}
 		// Execute any user modifications to the block
 		if gen != nil {
 			gen(i, b)
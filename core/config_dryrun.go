@@ -0,0 +1,186 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// DryRunResult is the outcome of the replay of a block under a candidate chain
+// configuration.
+type DryRunResult struct {
+	Number uint64      `json:"number"`
+	Hash   common.Hash `json:"hash"`
+	Error  string      `json:"error,omitempty"` // Reason the block would be rejected, empty if accepted
+}
+
+// DryRunReport is the outcome of the replay of a range of blocks under a
+// candidate chain configuration.
+type DryRunReport struct {
+	From         uint64          `json:"from"`
+	To           uint64          `json:"to"`
+	Activated    []string        `json:"activated"` // Transitions scheduled after the head, activated from the first block
+	Rejected     int             `json:"rejected"`
+	Blocks       []*DryRunResult `json:"blocks"`
+	Incompatible string          `json:"incompatible,omitempty"` // Conflict of the candidate with the imported blocks
+}
+
+// DryRunEngine creates the consensus engine verifying the blocks replayed under
+// a chain configuration, with the engine settings of that configuration rather
+// than those the running engine was created with.
+type DryRunEngine func(config *params.ChainConfig) consensus.Engine
+
+// dryRunChain is the chain seen by the consensus engine during a dry run, with
+// the candidate configuration and without the replayed block and its
+// descendants, for the engine not to take the block as already verified.
+type dryRunChain struct {
+	*BlockChain
+	config *params.ChainConfig
+	engine consensus.Engine // Engine created for the candidate configuration
+	number uint64           // Number of the replayed block
+}
+
+func (c *dryRunChain) Config() *params.ChainConfig { return c.config }
+
+func (c *dryRunChain) GetHeader(hash common.Hash, number uint64) *types.Header {
+	if number >= c.number {
+		return nil
+	}
+	return c.BlockChain.GetHeader(hash, number)
+}
+
+func (c *dryRunChain) GetHeaderByNumber(number uint64) *types.Header {
+	if number >= c.number {
+		return nil
+	}
+	return c.BlockChain.GetHeaderByNumber(number)
+}
+
+func (c *dryRunChain) GetHeaderByHash(hash common.Hash) *types.Header {
+	if header := c.BlockChain.GetHeaderByHash(hash); header != nil && header.Number.Uint64() < c.number {
+		return header
+	}
+	return nil
+}
+
+func (c *dryRunChain) GetBlock(hash common.Hash, number uint64) *types.Block {
+	if number >= c.number {
+		return nil
+	}
+	return c.BlockChain.GetBlock(hash, number)
+}
+
+func (c *dryRunChain) CurrentHeader() *types.Header {
+	return c.GetHeaderByNumber(c.number - 1)
+}
+
+// DryRun replays the canonical blocks from..to under a candidate chain
+// configuration, reporting whether this node would accept them. The
+// transitions the candidate schedules after the head are activated from the
+// first replayed block, to catch misconfigurations before they activate.
+//
+// Each block is verified and executed on the state of its parent as imported,
+// which must be available, so a rejected block doesn't affect the following
+// ones. Nothing is written to the database.
+//
+// The blocks are verified by the engine created for the candidate with the
+// activated transitions, or by the running engine if newEngine is nil, which
+// only suits the engines configured by the chain configuration passed along.
+func (bc *BlockChain) DryRun(candidate *params.ChainConfig, newEngine DryRunEngine, from, to uint64) (*DryRunReport, error) {
+	if from == 0 || from > to {
+		return nil, fmt.Errorf("invalid block range %d..%d", from, to)
+	}
+	head := bc.CurrentBlock().NumberU64()
+	if to > head {
+		return nil, fmt.Errorf("block #%d beyond the head #%d", to, head)
+	}
+	if err := candidate.IsValid(); err != nil {
+		return nil, err
+	}
+	report := &DryRunReport{From: from, To: to, Blocks: make([]*DryRunResult, 0, to-from+1)}
+	if compatErr := bc.chainConfig.CheckCompatible(candidate, from-1, GetIsQuorumEIP155Activated(bc.db)); compatErr != nil {
+		report.Incompatible = compatErr.Error()
+	}
+	config, activated, err := candidate.ActivateScheduled(new(big.Int).SetUint64(head), new(big.Int).SetUint64(from))
+	if err != nil {
+		return nil, err
+	}
+	report.Activated = activated
+
+	engine := bc.engine
+	if newEngine != nil {
+		engine = newEngine(config)
+	}
+	var (
+		chain     = &dryRunChain{BlockChain: bc, config: config, engine: engine}
+		processor = NewStateProcessor(config, bc, engine)
+		validator = NewBlockValidator(config, bc, engine)
+	)
+	for number := from; number <= to; number++ {
+		block := bc.GetBlockByNumber(number)
+		if block == nil {
+			return nil, fmt.Errorf("block #%d not found", number)
+		}
+		parent := bc.GetBlock(block.ParentHash(), number-1)
+		if parent == nil {
+			return nil, fmt.Errorf("parent of block #%d not found", number)
+		}
+		statedb, privateState, err := bc.StateAt(parent.Root())
+		if err != nil {
+			return nil, fmt.Errorf("state of block #%d unavailable: %v", number-1, err)
+		}
+		chain.number = number
+		result := &DryRunResult{Number: number, Hash: block.Hash()}
+		if err := dryRunBlock(chain, processor, validator, block, parent, statedb, privateState); err != nil {
+			result.Error = err.Error()
+			report.Rejected++
+		}
+		report.Blocks = append(report.Blocks, result)
+	}
+	return report, nil
+}
+
+// dryRunBlock verifies and executes a block the way it's imported, without
+// writing it.
+func dryRunBlock(chain *dryRunChain, processor *StateProcessor, validator *BlockValidator, block, parent *types.Block, statedb, privateState *state.StateDB) error {
+	if err := chain.engine.VerifyHeader(chain, block.Header(), false); err != nil {
+		return err
+	}
+	if err := chain.engine.VerifyUncles(chain, block); err != nil {
+		return err
+	}
+	for _, tx := range block.Transactions() {
+		if err := validateReplayProtection(chain.config, block.Number(), tx); err != nil {
+			return fmt.Errorf("transaction %x: %v", tx.Hash(), err)
+		}
+		if err := validateDocumentAnchor(chain.config, block.Number(), tx); err != nil {
+			return fmt.Errorf("transaction %x: %v", tx.Hash(), err)
+		}
+	}
+	receipts, _, _, usedGas, err := processor.Process(block, statedb, privateState, chain.vmConfig)
+	if err != nil {
+		return err
+	}
+	return validator.ValidateState(block, parent, statedb, receipts, usedGas)
+}
@@ -0,0 +1,95 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+func TestDryRun(t *testing.T) {
+	var (
+		db      = ethdb.NewMemDatabase()
+		key, _  = crypto.GenerateKey()
+		address = crypto.PubkeyToAddress(key.PublicKey)
+		gspec   = &Genesis{
+			Config: params.TestChainConfig,
+			Alloc:  GenesisAlloc{address: {Balance: big.NewInt(1000000000)}},
+		}
+		genesis = gspec.MustCommit(db)
+	)
+	// Every block sends a transaction without replay protection
+	blocks, _ := GenerateChain(gspec.Config, genesis, ethash.NewFaker(), db, 5, func(i int, block *BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(block.TxNonce(address), common.Address{0x01}, big.NewInt(1000), params.TxGas, nil, nil), types.HomesteadSigner{}, key)
+		block.AddTx(tx)
+	})
+	chain, _ := NewBlockChain(db, nil, gspec.Config, ethash.NewFaker(), vm.Config{}, nil)
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	// The current configuration accepts the blocks
+	report, err := chain.DryRun(gspec.Config, nil, 1, 5)
+	if err != nil {
+		t.Fatalf("failed to dry run the current configuration: %v", err)
+	}
+	if report.Rejected != 0 || len(report.Blocks) != 5 || len(report.Activated) != 0 || report.Incompatible != "" {
+		t.Fatalf("current configuration report mismatch: %+v", report)
+	}
+	// Requiring replay protection in the future rejects them once activated
+	candidate := *gspec.Config
+	candidate.StrictEIP155Block = big.NewInt(100)
+	if report, err = chain.DryRun(&candidate, nil, 3, 5); err != nil {
+		t.Fatalf("failed to dry run the candidate configuration: %v", err)
+	}
+	if report.Rejected != 3 || len(report.Blocks) != 3 || report.Incompatible != "" {
+		t.Fatalf("candidate configuration report mismatch: %+v", report)
+	}
+	if want := []string{"strictEIP155Block"}; !reflect.DeepEqual(report.Activated, want) {
+		t.Errorf("activated transitions mismatch: have %v, want %v", report.Activated, want)
+	}
+	for _, block := range report.Blocks {
+		if block.Error == "" {
+			t.Errorf("block %d accepted", block.Number)
+		}
+	}
+	if chain.CurrentBlock().NumberU64() != 5 {
+		t.Errorf("dry run changed the head")
+	}
+	// Changing an activated transition conflicts with the imported blocks
+	candidate = *gspec.Config
+	candidate.ByzantiumBlock = big.NewInt(4)
+	if report, err = chain.DryRun(&candidate, nil, 5, 5); err != nil {
+		t.Fatalf("failed to dry run the conflicting configuration: %v", err)
+	}
+	if report.Incompatible == "" {
+		t.Errorf("conflicting configuration not reported")
+	}
+	if _, err := chain.DryRun(gspec.Config, nil, 4, 6); err == nil {
+		t.Errorf("dry run beyond the head succeeded")
+	}
+}
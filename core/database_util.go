@@ -77,6 +77,9 @@ var (
 	privateblockReceiptsPrefix = []byte("Pr") // blockReceiptsPrefix + num (uint64 big endian) + hash -> block receipts
 	privateReceiptPrefix       = []byte("Prs")
 	privateBloomPrefix         = []byte("Pb")
+	privateTombstonePrefix     = []byte("private-tombstone-") // privateTombstonePrefix + address -> tombstone of the purged contract
+	privateTombstoneListKey    = []byte("PrivateTombstones")  // addresses of the purged contracts
+	historyTailKey             = []byte("HistoryTail")        // number of the first block whose body and receipts weren't pruned
 
 	quorumEIP155ActivatedPrefix = []byte("quorum155active")
 )
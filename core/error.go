@@ -33,6 +33,18 @@ var (
 	// next one expected based on the local chain.
 	ErrNonceTooHigh = errors.New("nonce too high")
 
+	// ErrStateAccessLimitReached is returned if the public transactions of a
+	// block access more public state than allowed by the chain configuration.
+	ErrStateAccessLimitReached = errors.New("state access limit reached")
+
+	// ErrUnprotectedTransaction is returned if a public transaction has a legacy
+	// signature once the chain requires EIP-155 replay protection.
+	ErrUnprotectedTransaction = errors.New("transaction not replay protected")
+
+	// ErrHistoryPruned is returned if the body or the receipts of a block were
+	// pruned by the history retention.
+	ErrHistoryPruned = errors.New("pruned: block body and receipts no longer retained")
+
 	// ErrAbortBlocksProcessing is returned if bc.insertChain is interrupted under raft mode
 	ErrAbortBlocksProcessing = errors.New("abort during blocks processing")
 )
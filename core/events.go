@@ -24,6 +24,9 @@ import (
 // NewTxsEvent is posted when a batch of transactions enter the transaction pool.
 type NewTxsEvent struct{ Txs []*types.Transaction }
 
+// TxPoolDiffEvent is posted when transactions enter or leave the transaction pool.
+type TxPoolDiffEvent struct{ Diffs []TxPoolDiff }
+
 // PendingLogsEvent is posted pre mining and notifies of pending logs.
 type PendingLogsEvent struct {
 	Logs []*types.Log
@@ -17,18 +17,19 @@ var _ = (*genesisSpecMarshaling)(nil)
 
 func (g Genesis) MarshalJSON() ([]byte, error) {
 	type Genesis struct {
-		Config     *params.ChainConfig                         `json:"config"`
-		Nonce      math.HexOrDecimal64                         `json:"nonce"`
-		Timestamp  math.HexOrDecimal64                         `json:"timestamp"`
-		ExtraData  hexutil.Bytes                               `json:"extraData"`
-		GasLimit   math.HexOrDecimal64                         `json:"gasLimit"   gencodec:"required"`
-		Difficulty *math.HexOrDecimal256                       `json:"difficulty" gencodec:"required"`
-		Mixhash    common.Hash                                 `json:"mixHash"`
-		Coinbase   common.Address                              `json:"coinbase"`
-		Alloc      map[common.UnprefixedAddress]GenesisAccount `json:"alloc"      gencodec:"required"`
-		Number     math.HexOrDecimal64                         `json:"number"`
-		GasUsed    math.HexOrDecimal64                         `json:"gasUsed"`
-		ParentHash common.Hash                                 `json:"parentHash"`
+		Config       *params.ChainConfig                                `json:"config"`
+		Nonce        math.HexOrDecimal64                                `json:"nonce"`
+		Timestamp    math.HexOrDecimal64                                `json:"timestamp"`
+		ExtraData    hexutil.Bytes                                      `json:"extraData"`
+		GasLimit     math.HexOrDecimal64                                `json:"gasLimit"   gencodec:"required"`
+		Difficulty   *math.HexOrDecimal256                              `json:"difficulty" gencodec:"required"`
+		Mixhash      common.Hash                                        `json:"mixHash"`
+		Coinbase     common.Address                                     `json:"coinbase"`
+		Alloc        map[common.UnprefixedAddress]GenesisAccount        `json:"alloc"      gencodec:"required"`
+		PrivateAlloc map[common.UnprefixedAddress]GenesisPrivateAccount `json:"privateAlloc,omitempty"`
+		Number       math.HexOrDecimal64                                `json:"number"`
+		GasUsed      math.HexOrDecimal64                                `json:"gasUsed"`
+		ParentHash   common.Hash                                        `json:"parentHash"`
 	}
 	var enc Genesis
 	enc.Config = g.Config
@@ -45,6 +46,12 @@ func (g Genesis) MarshalJSON() ([]byte, error) {
 			enc.Alloc[common.UnprefixedAddress(k)] = v
 		}
 	}
+	if g.PrivateAlloc != nil {
+		enc.PrivateAlloc = make(map[common.UnprefixedAddress]GenesisPrivateAccount, len(g.PrivateAlloc))
+		for k, v := range g.PrivateAlloc {
+			enc.PrivateAlloc[common.UnprefixedAddress(k)] = v
+		}
+	}
 	enc.Number = math.HexOrDecimal64(g.Number)
 	enc.GasUsed = math.HexOrDecimal64(g.GasUsed)
 	enc.ParentHash = g.ParentHash
@@ -53,18 +60,19 @@ func (g Genesis) MarshalJSON() ([]byte, error) {
 
 func (g *Genesis) UnmarshalJSON(input []byte) error {
 	type Genesis struct {
-		Config     *params.ChainConfig                         `json:"config"`
-		Nonce      *math.HexOrDecimal64                        `json:"nonce"`
-		Timestamp  *math.HexOrDecimal64                        `json:"timestamp"`
-		ExtraData  *hexutil.Bytes                              `json:"extraData"`
-		GasLimit   *math.HexOrDecimal64                        `json:"gasLimit"   gencodec:"required"`
-		Difficulty *math.HexOrDecimal256                       `json:"difficulty" gencodec:"required"`
-		Mixhash    *common.Hash                                `json:"mixHash"`
-		Coinbase   *common.Address                             `json:"coinbase"`
-		Alloc      map[common.UnprefixedAddress]GenesisAccount `json:"alloc"      gencodec:"required"`
-		Number     *math.HexOrDecimal64                        `json:"number"`
-		GasUsed    *math.HexOrDecimal64                        `json:"gasUsed"`
-		ParentHash *common.Hash                                `json:"parentHash"`
+		Config       *params.ChainConfig                                `json:"config"`
+		Nonce        *math.HexOrDecimal64                               `json:"nonce"`
+		Timestamp    *math.HexOrDecimal64                               `json:"timestamp"`
+		ExtraData    *hexutil.Bytes                                     `json:"extraData"`
+		GasLimit     *math.HexOrDecimal64                               `json:"gasLimit"   gencodec:"required"`
+		Difficulty   *math.HexOrDecimal256                              `json:"difficulty" gencodec:"required"`
+		Mixhash      *common.Hash                                       `json:"mixHash"`
+		Coinbase     *common.Address                                    `json:"coinbase"`
+		Alloc        map[common.UnprefixedAddress]GenesisAccount        `json:"alloc"      gencodec:"required"`
+		PrivateAlloc map[common.UnprefixedAddress]GenesisPrivateAccount `json:"privateAlloc,omitempty"`
+		Number       *math.HexOrDecimal64                               `json:"number"`
+		GasUsed      *math.HexOrDecimal64                               `json:"gasUsed"`
+		ParentHash   *common.Hash                                       `json:"parentHash"`
 	}
 	var dec Genesis
 	if err := json.Unmarshal(input, &dec); err != nil {
@@ -103,6 +111,12 @@ func (g *Genesis) UnmarshalJSON(input []byte) error {
 	for k, v := range dec.Alloc {
 		g.Alloc[common.Address(k)] = v
 	}
+	if dec.PrivateAlloc != nil {
+		g.PrivateAlloc = make(GenesisPrivateAlloc, len(dec.PrivateAlloc))
+		for k, v := range dec.PrivateAlloc {
+			g.PrivateAlloc[common.Address(k)] = v
+		}
+	}
 	if dec.Number != nil {
 		g.Number = uint64(*dec.Number)
 	}
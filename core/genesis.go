@@ -55,6 +55,11 @@ type Genesis struct {
 	Coinbase   common.Address      `json:"coinbase"`
 	Alloc      GenesisAlloc        `json:"alloc"      gencodec:"required"`
 
+	// Quorum
+	// PrivateAlloc holds the private contracts, written to the private state of
+	// their parties only.
+	PrivateAlloc GenesisPrivateAlloc `json:"privateAlloc,omitempty"`
+
 	// These fields are used for consensus tests. Please don't use them
 	// in actual genesis blocks.
 	Number     uint64      `json:"number"`
@@ -96,6 +101,8 @@ type genesisSpecMarshaling struct {
 	Number     math.HexOrDecimal64
 	Difficulty *math.HexOrDecimal256
 	Alloc      map[common.UnprefixedAddress]GenesisAccount
+
+	PrivateAlloc map[common.UnprefixedAddress]GenesisPrivateAccount
 }
 
 type genesisAccountMarshaling struct {
@@ -282,6 +289,10 @@ func (g *Genesis) Commit(db ethdb.Database) (*types.Block, error) {
 	if block.Number().Sign() != 0 {
 		return nil, fmt.Errorf("can't commit genesis block with number > 0")
 	}
+	// Quorum
+	if err := g.commitPrivateState(db, block.Root()); err != nil {
+		return nil, err
+	}
 	rawdb.WriteTd(db, block.Hash(), block.NumberU64(), g.Difficulty)
 	rawdb.WriteBlock(db, block)
 	rawdb.WriteReceipts(db, block.Hash(), block.NumberU64(), nil)
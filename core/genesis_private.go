@@ -0,0 +1,252 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/private"
+)
+
+// Quorum
+//
+// GenesisPrivateAlloc specifies the private contracts of the genesis block, in
+// the private state of the parties only. The contracts are distributed to their
+// parties through the private transaction managers beforehand, the genesis only
+// referencing them by the hashes of their payloads.
+type GenesisPrivateAlloc map[common.Address]GenesisPrivateAccount
+
+func (ga *GenesisPrivateAlloc) UnmarshalJSON(data []byte) error {
+	m := make(map[common.UnprefixedAddress]GenesisPrivateAccount)
+	if err := json.Unmarshal(data, &m); err != nil {
+		return err
+	}
+	*ga = make(GenesisPrivateAlloc)
+	for addr, a := range m {
+		(*ga)[common.Address(addr)] = a
+	}
+	return nil
+}
+
+// GenesisPrivateAccount is the reference to a private contract of the genesis
+// block: the hash of its payload in the private transaction managers.
+type GenesisPrivateAccount struct {
+	Payload hexutil.Bytes `json:"payload"`
+}
+
+func (a *GenesisPrivateAccount) UnmarshalJSON(input []byte) error {
+	var dec struct {
+		Payload hexutil.Bytes `json:"payload"`
+	}
+	if err := json.Unmarshal(input, &dec); err != nil {
+		return err
+	}
+	if len(dec.Payload) == 0 {
+		return errors.New("missing payload hash of private genesis contract")
+	}
+	a.Payload = dec.Payload
+	return nil
+}
+
+// GenesisPrivateContracts are the private contracts to distribute to their
+// parties before the genesis block is written.
+type GenesisPrivateContracts map[common.Address]GenesisPrivateContract
+
+func (gc *GenesisPrivateContracts) UnmarshalJSON(data []byte) error {
+	m := make(map[common.UnprefixedAddress]GenesisPrivateContract)
+	if err := json.Unmarshal(data, &m); err != nil {
+		return err
+	}
+	*gc = make(GenesisPrivateContracts)
+	for addr, c := range m {
+		(*gc)[common.Address(addr)] = c
+	}
+	return nil
+}
+
+// GenesisPrivateContract is a private contract of the genesis block, and the
+// public keys of the private transaction managers of its parties.
+type GenesisPrivateContract struct {
+	Code       []byte
+	Storage    map[common.Hash]common.Hash
+	Nonce      uint64
+	PrivateFor []string
+}
+
+// genesisPrivateContractJSON is the JSON format of a private genesis contract.
+type genesisPrivateContractJSON struct {
+	Code       hexutil.Bytes               `json:"code"`
+	Storage    map[storageJSON]storageJSON `json:"storage,omitempty"`
+	Nonce      math.HexOrDecimal64         `json:"nonce,omitempty"`
+	PrivateFor []string                    `json:"privateFor,omitempty"`
+}
+
+func (c GenesisPrivateContract) MarshalJSON() ([]byte, error) {
+	enc := genesisPrivateContractJSON{
+		Code:       c.Code,
+		Nonce:      math.HexOrDecimal64(c.Nonce),
+		PrivateFor: c.PrivateFor,
+	}
+	if c.Storage != nil {
+		enc.Storage = make(map[storageJSON]storageJSON, len(c.Storage))
+		for k, v := range c.Storage {
+			enc.Storage[storageJSON(k)] = storageJSON(v)
+		}
+	}
+	return json.Marshal(&enc)
+}
+
+func (c *GenesisPrivateContract) UnmarshalJSON(input []byte) error {
+	var dec genesisPrivateContractJSON
+	if err := json.Unmarshal(input, &dec); err != nil {
+		return err
+	}
+	if len(dec.Code) == 0 {
+		return errors.New("missing code of private genesis contract")
+	}
+	c.Code, c.Nonce, c.PrivateFor = dec.Code, uint64(dec.Nonce), dec.PrivateFor
+	c.Storage = nil
+	if dec.Storage != nil {
+		c.Storage = make(map[common.Hash]common.Hash, len(dec.Storage))
+		for k, v := range dec.Storage {
+			c.Storage[common.Hash(k)] = common.Hash(v)
+		}
+	}
+	return nil
+}
+
+// genesisPrivatePayload is the payload of a private genesis contract sent to
+// the private transaction managers of its parties. The parties are left out,
+// and the address is included for the payload not to be used for another.
+type genesisPrivatePayload struct {
+	Address  common.Address         `json:"address"`
+	Contract GenesisPrivateContract `json:"contract"`
+}
+
+// DistributeGenesisPrivateContracts sends the private genesis contracts from the
+// given key to their parties through the private transaction manager, returning
+// the references to them to declare in the genesis. Any failure to send one
+// fails the distribution.
+func DistributeGenesisPrivateContracts(ptm private.PrivateTransactionManager, from string, contracts GenesisPrivateContracts) (GenesisPrivateAlloc, error) {
+	addrs := make([]common.Address, 0, len(contracts))
+	for addr := range contracts {
+		addrs = append(addrs, addr)
+	}
+	sortAddresses(addrs)
+
+	alloc := make(GenesisPrivateAlloc, len(contracts))
+	for _, addr := range addrs {
+		contract := contracts[addr]
+		if len(contract.PrivateFor) == 0 {
+			return nil, fmt.Errorf("private genesis contract %x: no parties", addr)
+		}
+		payload := genesisPrivatePayload{Address: addr, Contract: contract}
+		payload.Contract.PrivateFor = nil
+
+		blob, err := json.Marshal(&payload)
+		if err != nil {
+			return nil, err
+		}
+		hash, err := ptm.Send(blob, from, contract.PrivateFor)
+		if err != nil {
+			return nil, fmt.Errorf("private genesis contract %x: %v", addr, err)
+		}
+		alloc[addr] = GenesisPrivateAccount{Payload: hash}
+	}
+	return alloc, nil
+}
+
+// resolve retrieves the private contract referenced from the private
+// transaction manager, returning nil if the node isn't one of its parties.
+func (a GenesisPrivateAccount) resolve(ptm private.PrivateTransactionManager, addr common.Address) (*GenesisPrivateContract, error) {
+	blob, err := ptm.Receive(a.Payload)
+	if err != nil {
+		return nil, err
+	}
+	if len(blob) == 0 {
+		return nil, nil
+	}
+	var payload genesisPrivatePayload
+	if err := json.Unmarshal(blob, &payload); err != nil {
+		return nil, fmt.Errorf("invalid payload: %v", err)
+	}
+	if payload.Address != addr {
+		return nil, fmt.Errorf("payload of contract %x", payload.Address)
+	}
+	return &payload.Contract, nil
+}
+
+// commitPrivateState writes the private state of the genesis block, holding
+// the private contracts the node is a party of, and links it to the public
+// state root. The genesis block hash doesn't cover the private contracts, the
+// parties alone knowing of them. Failing to retrieve a contract fails the
+// initialisation, lest the node start without the private state of its parties.
+func (g *Genesis) commitPrivateState(db ethdb.Database, root common.Hash) error {
+	if len(g.PrivateAlloc) == 0 {
+		return nil
+	}
+	if private.P == nil {
+		return fmt.Errorf("no private transaction manager to retrieve the %d private genesis contracts", len(g.PrivateAlloc))
+	}
+	addrs := make([]common.Address, 0, len(g.PrivateAlloc))
+	for addr := range g.PrivateAlloc {
+		addrs = append(addrs, addr)
+	}
+	sortAddresses(addrs)
+
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	var parties int
+	for _, addr := range addrs {
+		contract, err := g.PrivateAlloc[addr].resolve(private.P, addr)
+		if err != nil {
+			return fmt.Errorf("private genesis contract %x: %v", addr, err)
+		}
+		if contract == nil {
+			continue
+		}
+		statedb.SetCode(addr, contract.Code)
+		statedb.SetNonce(addr, contract.Nonce)
+		for key, value := range contract.Storage {
+			statedb.SetState(addr, key, value)
+		}
+		parties++
+	}
+	privateRoot, err := statedb.Commit(false)
+	if err != nil {
+		return err
+	}
+	if err := statedb.Database().TrieDB().Commit(privateRoot, true); err != nil {
+		return err
+	}
+	log.Info("Wrote private genesis contracts", "parties", parties, "total", len(g.PrivateAlloc), "root", privateRoot)
+	return WritePrivateStateRoot(db, root, privateRoot)
+}
+
+// sortAddresses sorts addresses in ascending order.
+func sortAddresses(addrs []common.Address) {
+	sort.Slice(addrs, func(i, j int) bool { return bytes.Compare(addrs[i][:], addrs[j][:]) < 0 })
+}
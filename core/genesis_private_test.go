@@ -0,0 +1,155 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/private"
+)
+
+// distributingPTM is a private transaction manager network in a box, handing
+// out the payloads sent to the recipients whose keys it holds only.
+type distributingPTM struct {
+	StubPrivateTransactionManager
+	keys     map[string]bool
+	payloads map[string][]byte
+	parties  map[string][]string
+	err      error
+}
+
+func newDistributingPTM() *distributingPTM {
+	return &distributingPTM{payloads: make(map[string][]byte), parties: make(map[string][]string)}
+}
+
+func (ptm *distributingPTM) Send(data []byte, from string, to []string) ([]byte, error) {
+	hash := []byte{byte(len(ptm.payloads) + 1)}
+	ptm.payloads[string(hash)] = data
+	ptm.parties[string(hash)] = append([]string{from}, to...)
+	return hash, nil
+}
+
+func (ptm *distributingPTM) Receive(hash []byte) ([]byte, error) {
+	if ptm.err != nil {
+		return nil, ptm.err
+	}
+	for _, party := range ptm.parties[string(hash)] {
+		if ptm.keys[party] {
+			return ptm.payloads[string(hash)], nil
+		}
+	}
+	return nil, nil
+}
+
+const privateGenesisContractsJSON = `{
+	"0x000000000000000000000000000000000000aaaa": {
+		"code": "0x6001",
+		"storage": {"0x01": "0x2a"},
+		"privateFor": ["B"]
+	},
+	"0x000000000000000000000000000000000000bbbb": {
+		"code": "0x6002",
+		"privateFor": ["C"]
+	}
+}`
+
+// Tests that the private genesis contracts are distributed through the private
+// transaction managers, the genesis only referencing them, and written to the
+// private state of their parties only, without altering the genesis block.
+func TestGenesisPrivateAlloc(t *testing.T) {
+	var contracts GenesisPrivateContracts
+	if err := json.Unmarshal([]byte(privateGenesisContractsJSON), &contracts); err != nil {
+		t.Fatalf("failed to decode private contracts: %v", err)
+	}
+	ptm := newDistributingPTM()
+	alloc, err := DistributeGenesisPrivateContracts(ptm, "A", contracts)
+	if err != nil {
+		t.Fatalf("failed to distribute private contracts: %v", err)
+	}
+	genesis := Genesis{
+		Config:       &params.ChainConfig{ChainID: big.NewInt(10), IsQuorum: true},
+		GasLimit:     4700000,
+		Difficulty:   big.NewInt(1),
+		Alloc:        GenesisAlloc{},
+		PrivateAlloc: alloc,
+	}
+	// The contracts are left out of the genesis, shared with every node
+	blob, err := json.Marshal(&genesis)
+	if err != nil {
+		t.Fatalf("failed to encode genesis: %v", err)
+	}
+	if bytes.Contains(blob, []byte("6001")) || bytes.Contains(blob, []byte("2a")) {
+		t.Fatalf("private contract leaked in genesis: %s", blob)
+	}
+	if err := json.Unmarshal(blob, &genesis); err != nil {
+		t.Fatalf("failed to decode genesis: %v", err)
+	}
+	saved := private.P
+	defer func() { private.P = saved }()
+
+	ptm.keys = map[string]bool{"B": true}
+	private.P = ptm
+
+	db := ethdb.NewMemDatabase()
+	block, err := genesis.Commit(db)
+	if err != nil {
+		t.Fatalf("failed to commit genesis: %v", err)
+	}
+	public := genesis
+	public.PrivateAlloc = nil
+	if hash := public.ToBlock(nil).Hash(); hash != block.Hash() {
+		t.Fatalf("genesis hash altered by private contracts: have %x, want %x", block.Hash(), hash)
+	}
+	root := GetPrivateStateRoot(db, block.Root())
+	if root == (common.Hash{}) {
+		t.Fatalf("private state root not written")
+	}
+	privateState, err := state.New(root, state.NewDatabase(db))
+	if err != nil {
+		t.Fatalf("failed to open private state: %v", err)
+	}
+	a, b := common.HexToAddress("0xaaaa"), common.HexToAddress("0xbbbb")
+	if code := privateState.GetCode(a); len(code) != 2 || code[1] != 0x01 {
+		t.Errorf("party contract code mismatch: have %x", code)
+	}
+	if value := privateState.GetState(a, common.HexToHash("0x01")); value != common.HexToHash("0x2a") {
+		t.Errorf("party contract storage mismatch: have %x", value)
+	}
+	if code := privateState.GetCode(b); len(code) != 0 {
+		t.Errorf("non-party contract written: %x", code)
+	}
+	// Any failure of the private transaction manager fails the initialisation
+	ptm.err = errors.New("500 internal server error")
+	if _, err := genesis.Commit(ethdb.NewMemDatabase()); err == nil {
+		t.Errorf("genesis committed despite the private transaction manager failure")
+	}
+	ptm.err = nil
+
+	// A payload can't be referenced for another contract
+	genesis.PrivateAlloc = GenesisPrivateAlloc{b: alloc[a]}
+	if _, err := genesis.Commit(ethdb.NewMemDatabase()); err == nil {
+		t.Errorf("genesis committed with the payload of another contract")
+	}
+}
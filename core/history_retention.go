@@ -0,0 +1,125 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"encoding/binary"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+// ReadHistoryTail returns the number of the first block whose body and receipts
+// weren't considered for pruning by the history retention. The blocks below it
+// only keep their body and receipts if they involve a protected address.
+func ReadHistoryTail(db DatabaseReader) uint64 {
+	data, _ := db.Get(historyTailKey)
+	if len(data) != 8 {
+		return 0
+	}
+	return binary.BigEndian.Uint64(data)
+}
+
+func writeHistoryTail(db ethdb.Putter, number uint64) error {
+	return db.Put(historyTailKey, encodeBlockNumber(number))
+}
+
+// HistoryPruned tells whether the body and receipts of the given block may have
+// been pruned by the history retention.
+func HistoryPruned(db DatabaseReader, number uint64) bool {
+	return number < ReadHistoryTail(db)
+}
+
+// PruneHistory deletes the bodies and receipts of at most max canonical blocks
+// from the history tail up to the given block number (exclusive), keeping the
+// headers and the transaction lookup entries. The blocks with a transaction
+// sent to, creating or logged by a protected address are kept whole. It returns
+// the number of blocks whose body and receipts were deleted.
+//
+// The blocks above the newest one whose state was flushed to disk are never
+// pruned, whatever the limit, as the chain is rewound and re-executed from it
+// after an unclean shutdown.
+func (bc *BlockChain) PruneHistory(limit uint64, protected map[common.Address]bool, max int) (int, error) {
+	bc.chainmu.Lock()
+	defer bc.chainmu.Unlock()
+
+	if committed := atomic.LoadUint64(&bc.stateCommitted); limit > committed {
+		limit = committed
+	}
+	tail := ReadHistoryTail(bc.db)
+	if tail == 0 {
+		tail = 1 // The genesis block is always kept
+	}
+	batch := bc.db.NewBatch()
+	pruned := 0
+	for ; tail < limit && max > 0; tail, max = tail+1, max-1 {
+		hash := rawdb.ReadCanonicalHash(bc.db, tail)
+		if hash == (common.Hash{}) {
+			break
+		}
+		body := rawdb.ReadBody(bc.db, hash, tail)
+		if body == nil {
+			continue // Already pruned, or never downloaded
+		}
+		receipts := rawdb.ReadReceipts(bc.db, hash, tail)
+		if involves(body, receipts, protected) {
+			continue
+		}
+		rawdb.DeleteBody(batch, hash, tail)
+		rawdb.DeleteReceipts(batch, hash, tail)
+
+		bc.bodyCache.Remove(hash)
+		bc.bodyRLPCache.Remove(hash)
+		bc.receiptsCache.Remove(hash)
+		bc.blockCache.Remove(hash)
+		pruned++
+	}
+	if err := writeHistoryTail(batch, tail); err != nil {
+		return 0, err
+	}
+	if err := batch.Write(); err != nil {
+		return 0, err
+	}
+	return pruned, nil
+}
+
+// involves tells whether the transactions of a block involve any of the given
+// addresses, as recipient, created contract or log emitter.
+func involves(body *types.Body, receipts types.Receipts, addrs map[common.Address]bool) bool {
+	if len(addrs) == 0 {
+		return false
+	}
+	for _, tx := range body.Transactions {
+		if to := tx.To(); to != nil && addrs[*to] {
+			return true
+		}
+	}
+	for _, receipt := range receipts {
+		if addrs[receipt.ContractAddress] {
+			return true
+		}
+		for _, log := range receipt.Logs {
+			if addrs[log.Address] {
+				return true
+			}
+		}
+	}
+	return false
+}
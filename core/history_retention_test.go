@@ -0,0 +1,96 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+func TestPruneHistory(t *testing.T) {
+	var (
+		db        = ethdb.NewMemDatabase()
+		key, _    = crypto.GenerateKey()
+		address   = crypto.PubkeyToAddress(key.PublicKey)
+		protected = common.Address{0xaa}
+		gspec     = &Genesis{
+			Config: params.TestChainConfig,
+			Alloc:  GenesisAlloc{address: {Balance: big.NewInt(1000000000)}},
+		}
+		genesis = gspec.MustCommit(db)
+		signer  = types.NewEIP155Signer(gspec.Config.ChainID)
+	)
+	// Every block sends a transaction, the fourth one to the protected address
+	blocks, _ := GenerateChain(gspec.Config, genesis, ethash.NewFaker(), db, 10, func(i int, block *BlockGen) {
+		to := common.Address{0x01}
+		if i == 3 {
+			to = protected
+		}
+		tx, _ := types.SignTx(types.NewTransaction(block.TxNonce(address), to, big.NewInt(1000), params.TxGas, nil, nil), signer, key)
+		block.AddTx(tx)
+	})
+	chain, _ := NewBlockChain(db, nil, gspec.Config, ethash.NewFaker(), vm.Config{}, nil)
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	// Nothing is pruned above the state flushed to disk, the chain being rewound
+	// to it after a crash
+	protect := map[common.Address]bool{protected: true}
+	if pruned, err := chain.PruneHistory(8, protect, 4); err != nil || pruned != 0 {
+		t.Fatalf("unflushed state: have %d pruned (%v), want 0", pruned, err)
+	}
+	if err := chain.SetArchiveMode(true); err != nil {
+		t.Fatalf("failed to flush state: %v", err)
+	}
+	// Prune in two batches up to block 8
+	if pruned, err := chain.PruneHistory(8, protect, 4); err != nil || pruned != 3 {
+		t.Fatalf("first batch: have %d pruned (%v), want 3", pruned, err)
+	}
+	if tail := ReadHistoryTail(db); tail != 5 {
+		t.Fatalf("tail after first batch: have %d, want 5", tail)
+	}
+	if pruned, err := chain.PruneHistory(8, protect, 4); err != nil || pruned != 3 {
+		t.Fatalf("second batch: have %d pruned (%v), want 3", pruned, err)
+	}
+	for number := uint64(1); number <= 10; number++ {
+		hash := rawdb.ReadCanonicalHash(db, number)
+		if chain.GetHeaderByNumber(number) == nil {
+			t.Errorf("block %d: header pruned", number)
+		}
+		kept := number >= 8 || number == 4
+		if have := chain.GetBlockByNumber(number) != nil; have != kept {
+			t.Errorf("block %d: body kept %v, want %v", number, have, kept)
+		}
+		if have := chain.GetReceiptsByHash(hash) != nil; have != kept {
+			t.Errorf("block %d: receipts kept %v, want %v", number, have, kept)
+		}
+		if pruned := HistoryPruned(db, number); pruned != (number < 8) {
+			t.Errorf("block %d: pruned %v, want %v", number, pruned, number < 8)
+		}
+	}
+}
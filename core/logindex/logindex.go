@@ -0,0 +1,276 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package logindex implements an index of the blocks containing the logs of
+// each contract, and of each event signature (first topic) of each contract.
+//
+// The bloom bits answer log queries in time proportional to the queried range,
+// which is slow on chains made of many small blocks. The index answers them in
+// time proportional to the number of sections of the range, reading for each
+// section the list of the blocks holding matching logs.
+package logindex
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// SectionSize is the number of blocks the postings of a key are grouped by.
+const SectionSize = 4096
+
+var (
+	addressPrefix = []byte("Lia") // addressPrefix + address + section (uint64 big endian) -> block offsets
+	topicPrefix   = []byte("Lit") // topicPrefix + address + topic0 + section (uint64 big endian) -> block offsets
+
+	tailKey = []byte("LogIndexTail") // First block of the range covered by the index
+	headKey = []byte("LogIndexHead") // Last block indexed
+
+	errCorruptPostings = errors.New("corrupt log index postings")
+)
+
+// Index is the log index stored in a database. Blocks are indexed by number, so
+// the index holds the blocks of every imported branch, a superset of the blocks
+// of the canonical chain holding matching logs.
+type Index struct {
+	db ethdb.Database
+}
+
+// New opens the log index of the database.
+func New(db ethdb.Database) *Index {
+	return &Index{db: db}
+}
+
+// Open prepares the index for the blocks imported on top of the given head. If
+// it misses blocks imported while indexing was disabled, the range covered by
+// the index restarts after the head, the older blocks being left to a rebuild.
+func (idx *Index) Open(head uint64) error {
+	tail, ok := idx.Tail()
+	if ok && readNumber(idx.db, headKey) >= head {
+		log.Info("Opened log index", "tail", tail)
+		return nil
+	}
+	if ok {
+		log.Warn("Log index missing recent blocks, rebuild to cover them", "tail", head+1)
+	}
+	batch := idx.db.NewBatch()
+	writeNumber(batch, tailKey, head+1)
+	writeNumber(batch, headKey, head)
+	return batch.Write()
+}
+
+// Tail returns the first block of the range covered by the index, up to the
+// last imported block, and false if the index was never opened.
+func (idx *Index) Tail() (uint64, bool) {
+	if ok, _ := idx.db.Has(tailKey); !ok {
+		return 0, false
+	}
+	return readNumber(idx.db, tailKey), true
+}
+
+// Index adds the logs of an imported block to the index.
+func (idx *Index) Index(number uint64, receipts types.Receipts) error {
+	var (
+		section = number / SectionSize
+		offset  = uint16(number % SectionSize)
+		batch   = idx.db.NewBatch()
+		seen    = make(map[string]bool)
+	)
+	for _, receipt := range receipts {
+		for _, l := range receipt.Logs {
+			keys := [][]byte{addressKey(l.Address, section)}
+			if len(l.Topics) > 0 {
+				keys = append(keys, topicKey(l.Address, l.Topics[0], section))
+			}
+			for _, key := range keys {
+				if seen[string(key)] {
+					continue
+				}
+				seen[string(key)] = true
+
+				blob, _ := idx.db.Get(key)
+				offsets, err := decodePostings(blob)
+				if err != nil {
+					return err
+				}
+				i := sort.Search(len(offsets), func(i int) bool { return offsets[i] >= offset })
+				if i < len(offsets) && offsets[i] == offset {
+					continue // Block reimported
+				}
+				offsets = append(offsets, 0)
+				copy(offsets[i+1:], offsets[i:])
+				offsets[i] = offset
+				batch.Put(key, encodePostings(offsets))
+			}
+		}
+	}
+	if number > readNumber(idx.db, headKey) {
+		writeNumber(batch, headKey, number)
+	}
+	return batch.Write()
+}
+
+// Blocks returns the numbers of the blocks within the given range holding logs
+// of the given contracts, with any of the given first topics if any. The block
+// numbers are sorted and unique.
+func (idx *Index) Blocks(addresses []common.Address, topics []common.Hash, from, to uint64) ([]uint64, error) {
+	var numbers []uint64
+	for section := from / SectionSize; section <= to/SectionSize; section++ {
+		var keys [][]byte
+		for _, addr := range addresses {
+			if len(topics) == 0 {
+				keys = append(keys, addressKey(addr, section))
+			}
+			for _, topic := range topics {
+				keys = append(keys, topicKey(addr, topic, section))
+			}
+		}
+		found := make(map[uint64]bool)
+		for _, key := range keys {
+			blob, _ := idx.db.Get(key)
+			offsets, err := decodePostings(blob)
+			if err != nil {
+				return nil, err
+			}
+			for _, offset := range offsets {
+				if number := section*SectionSize + uint64(offset); number >= from && number <= to {
+					found[number] = true
+				}
+			}
+		}
+		start := len(numbers)
+		for number := range found {
+			numbers = append(numbers, number)
+		}
+		sort.Slice(numbers[start:], func(i, j int) bool { return numbers[start+i] < numbers[start+j] })
+	}
+	return numbers, nil
+}
+
+// Rebuild indexes the logs of the canonical chain up to the given head, making
+// the index cover the whole chain. The index can't be updated concurrently.
+func Rebuild(db ethdb.Database, head uint64) error {
+	var (
+		start    = time.Now()
+		logged   = time.Now()
+		postings = make(map[string][]uint16)
+	)
+	flush := func() error {
+		batch := db.NewBatch()
+		for key, offsets := range postings {
+			batch.Put([]byte(key), encodePostings(offsets))
+		}
+		postings = make(map[string][]uint16)
+		return batch.Write()
+	}
+	for number := uint64(0); number <= head; number++ {
+		hash := rawdb.ReadCanonicalHash(db, number)
+		if hash == (common.Hash{}) {
+			return fmt.Errorf("canonical chain incomplete, missing block #%d", number)
+		}
+		section, offset := number/SectionSize, uint16(number%SectionSize)
+		for _, receipt := range rawdb.ReadReceipts(db, hash, number) {
+			for _, l := range receipt.Logs {
+				keys := []string{string(addressKey(l.Address, section))}
+				if len(l.Topics) > 0 {
+					keys = append(keys, string(topicKey(l.Address, l.Topics[0], section)))
+				}
+				for _, key := range keys {
+					if offsets := postings[key]; len(offsets) == 0 || offsets[len(offsets)-1] != offset {
+						postings[key] = append(offsets, offset)
+					}
+				}
+			}
+		}
+		if offset == SectionSize-1 || number == head {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Rebuilding log index", "number", number, "head", head, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	batch := db.NewBatch()
+	writeNumber(batch, tailKey, 0)
+	if head > readNumber(db, headKey) {
+		writeNumber(batch, headKey, head)
+	}
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	log.Info("Rebuilt log index", "head", head, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+func addressKey(addr common.Address, section uint64) []byte {
+	key := append(append([]byte{}, addressPrefix...), addr.Bytes()...)
+	return appendSection(key, section)
+}
+
+func topicKey(addr common.Address, topic common.Hash, section uint64) []byte {
+	key := append(append(append([]byte{}, topicPrefix...), addr.Bytes()...), topic.Bytes()...)
+	return appendSection(key, section)
+}
+
+func appendSection(key []byte, section uint64) []byte {
+	var enc [8]byte
+	binary.BigEndian.PutUint64(enc[:], section)
+	return append(key, enc[:]...)
+}
+
+// encodePostings encodes the sorted offsets of the blocks of a section.
+func encodePostings(offsets []uint16) []byte {
+	blob := make([]byte, 2*len(offsets))
+	for i, offset := range offsets {
+		binary.BigEndian.PutUint16(blob[2*i:], offset)
+	}
+	return blob
+}
+
+func decodePostings(blob []byte) ([]uint16, error) {
+	if len(blob)%2 != 0 {
+		return nil, errCorruptPostings
+	}
+	offsets := make([]uint16, len(blob)/2)
+	for i := range offsets {
+		offsets[i] = binary.BigEndian.Uint16(blob[2*i:])
+	}
+	return offsets, nil
+}
+
+func readNumber(db ethdb.Database, key []byte) uint64 {
+	blob, err := db.Get(key)
+	if err != nil || len(blob) != 8 {
+		return 0
+	}
+	return binary.BigEndian.Uint64(blob)
+}
+
+func writeNumber(db ethdb.Putter, key []byte, number uint64) {
+	var enc [8]byte
+	binary.BigEndian.PutUint64(enc[:], number)
+	db.Put(key, enc[:])
+}
@@ -0,0 +1,134 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package logindex
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+var (
+	testAddr1  = common.BytesToAddress([]byte("contract1"))
+	testAddr2  = common.BytesToAddress([]byte("contract2"))
+	testTopic1 = common.BytesToHash([]byte("topic1"))
+	testTopic2 = common.BytesToHash([]byte("topic2"))
+)
+
+func testReceipts(logs ...*types.Log) types.Receipts {
+	return types.Receipts{{Logs: logs}}
+}
+
+// Tests that the indexed blocks are returned for the queried contracts and
+// topics, across sections.
+func TestIndexBlocks(t *testing.T) {
+	idx := New(ethdb.NewMemDatabase())
+	if _, ok := idx.Tail(); ok {
+		t.Fatalf("unopened index reports a tail")
+	}
+	if err := idx.Open(0); err != nil {
+		t.Fatalf("failed to open index: %v", err)
+	}
+	if tail, _ := idx.Tail(); tail != 1 {
+		t.Fatalf("tail mismatch: have %d, want 1", tail)
+	}
+	blocks := map[uint64]types.Receipts{
+		5:               testReceipts(&types.Log{Address: testAddr1, Topics: []common.Hash{testTopic1}}),
+		SectionSize - 1: testReceipts(&types.Log{Address: testAddr2, Topics: []common.Hash{testTopic1}}),
+		SectionSize + 3: testReceipts(&types.Log{Address: testAddr1, Topics: []common.Hash{testTopic2}}, &types.Log{Address: testAddr1}),
+		3 * SectionSize: testReceipts(&types.Log{Address: testAddr1, Topics: []common.Hash{testTopic1}}),
+	}
+	// Index out of order, and twice, to check the postings stay sorted and unique
+	for _, number := range []uint64{3 * SectionSize, 5, SectionSize + 3, SectionSize - 1, 5} {
+		if err := idx.Index(number, blocks[number]); err != nil {
+			t.Fatalf("failed to index block %d: %v", number, err)
+		}
+	}
+	tests := []struct {
+		addresses []common.Address
+		topics    []common.Hash
+		from, to  uint64
+		want      []uint64
+	}{
+		{[]common.Address{testAddr1}, nil, 0, 4 * SectionSize, []uint64{5, SectionSize + 3, 3 * SectionSize}},
+		{[]common.Address{testAddr1}, []common.Hash{testTopic1}, 0, 4 * SectionSize, []uint64{5, 3 * SectionSize}},
+		{[]common.Address{testAddr1, testAddr2}, []common.Hash{testTopic1}, 0, 4 * SectionSize, []uint64{5, SectionSize - 1, 3 * SectionSize}},
+		{[]common.Address{testAddr1, testAddr2}, []common.Hash{testTopic1, testTopic2}, 6, SectionSize + 3, []uint64{SectionSize - 1, SectionSize + 3}},
+		{[]common.Address{testAddr2}, []common.Hash{testTopic2}, 0, 4 * SectionSize, nil},
+	}
+	for i, tt := range tests {
+		have, err := idx.Blocks(tt.addresses, tt.topics, tt.from, tt.to)
+		if err != nil {
+			t.Fatalf("test %d: failed to query index: %v", i, err)
+		}
+		if !reflect.DeepEqual(have, tt.want) {
+			t.Errorf("test %d: blocks mismatch: have %v, want %v", i, have, tt.want)
+		}
+	}
+}
+
+// Tests that an index missing blocks restarts after the head, and that a rebuild
+// covers the whole canonical chain.
+func TestIndexRebuild(t *testing.T) {
+	db := ethdb.NewMemDatabase()
+	for number := uint64(0); number <= 10; number++ {
+		header := &types.Header{Number: new(big.Int).SetUint64(number)}
+		hash := header.Hash()
+		rawdb.WriteCanonicalHash(db, hash, number)
+		if number%3 == 0 {
+			rawdb.WriteReceipts(db, hash, number, testReceipts(&types.Log{Address: testAddr1, Topics: []common.Hash{testTopic1}}))
+		}
+	}
+	idx := New(db)
+	if err := idx.Open(4); err != nil {
+		t.Fatalf("failed to open index: %v", err)
+	}
+	if err := idx.Open(8); err != nil {
+		t.Fatalf("failed to reopen index: %v", err)
+	}
+	if tail, _ := idx.Tail(); tail != 9 {
+		t.Fatalf("tail mismatch: have %d, want 9", tail)
+	}
+	if err := Rebuild(db, 10); err != nil {
+		t.Fatalf("failed to rebuild index: %v", err)
+	}
+	if tail, _ := idx.Tail(); tail != 0 {
+		t.Fatalf("tail mismatch: have %d, want 0", tail)
+	}
+	have, err := idx.Blocks([]common.Address{testAddr1}, []common.Hash{testTopic1}, 0, 10)
+	if err != nil {
+		t.Fatalf("failed to query index: %v", err)
+	}
+	if want := []uint64{0, 3, 6, 9}; !reflect.DeepEqual(have, want) {
+		t.Errorf("blocks mismatch: have %v, want %v", have, want)
+	}
+	// The rebuilt index is up to date, reopening it keeps the tail
+	if err := idx.Open(10); err != nil {
+		t.Fatalf("failed to reopen index: %v", err)
+	}
+	if tail, _ := idx.Tail(); tail != 0 {
+		t.Fatalf("tail mismatch after reopen: have %d, want 0", tail)
+	}
+	if err := Rebuild(db, 11); err == nil {
+		t.Fatalf("rebuild beyond the canonical chain succeeded")
+	}
+}
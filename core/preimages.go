@@ -0,0 +1,98 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+var preimageSkipCounter = metrics.NewRegisteredCounter("chain/preimages/skipped", nil)
+
+// writePreimages stores the SHA3 preimages recorded by the EVM while processing
+// a block, from both the public and the private state, so that hashed keys such
+// as mapping slots can be decoded later on.
+//
+// Preimages larger than the configured size cap are dropped. If a retention is
+// configured, the preimages are indexed by block, and the ones not recorded
+// again within the retention window are deleted as new blocks are written. The
+// preimages of addresses and storage slots are never deleted, the state tries
+// record them in the same table as the secure keys of the accounts and slots.
+func (bc *BlockChain) writePreimages(batch ethdb.Batch, number uint64, blockHash common.Hash, sets ...map[common.Hash][]byte) {
+	var (
+		limit     = bc.cacheConfig.PreimageMaxSize
+		retention = bc.cacheConfig.PreimageRetention
+	)
+	preimages := make(map[common.Hash][]byte)
+	for _, set := range sets {
+		for hash, preimage := range set {
+			if limit > 0 && len(preimage) > limit {
+				preimageSkipCounter.Inc(1)
+				continue
+			}
+			preimages[hash] = preimage
+		}
+	}
+	rawdb.WritePreimages(batch, preimages)
+	if retention == 0 {
+		return
+	}
+	// Index the preimages by block, a block replacing another one of the same
+	// height in a reorg leaving the index of the replaced one in place
+	var hashes []common.Hash
+	for hash, preimage := range preimages {
+		if secureKey(preimage) {
+			continue
+		}
+		rawdb.WritePreimageBlock(batch, hash, number)
+		hashes = append(hashes, hash)
+	}
+	if len(hashes) > 0 {
+		var entries []rawdb.PreimageIndexEntry
+		for _, entry := range rawdb.ReadPreimageIndex(bc.db, number) {
+			if entry.Block != blockHash {
+				entries = append(entries, entry)
+			}
+		}
+		entries = append(entries, rawdb.PreimageIndexEntry{Block: blockHash, Preimages: hashes})
+		rawdb.WritePreimageIndex(batch, number, entries)
+	}
+	// Drop the preimages which went out of the retention window
+	if number <= retention {
+		return
+	}
+	expired := number - retention
+	for _, entry := range rawdb.ReadPreimageIndex(bc.db, expired) {
+		for _, hash := range entry.Preimages {
+			if _, ok := preimages[hash]; ok {
+				continue
+			}
+			if last := rawdb.ReadPreimageBlock(bc.db, hash); last != nil && *last <= expired {
+				rawdb.DeletePreimage(batch, hash)
+			}
+		}
+	}
+	rawdb.DeletePreimageIndex(batch, expired)
+}
+
+// secureKey reports whether a preimage may be the secure key of an account or a
+// storage slot, shared with the state tries.
+func secureKey(preimage []byte) bool {
+	return len(preimage) == common.AddressLength || len(preimage) == common.HashLength
+}
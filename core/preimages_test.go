@@ -0,0 +1,95 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Tests that recorded preimages are capped in size and dropped once they are
+// not recorded again within the retention window.
+func TestPreimageRetention(t *testing.T) {
+	db, chain, err := newCanonical(ethash.NewFaker(), 0, true)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	chain.cacheConfig.PreimageMaxSize = 64
+	chain.cacheConfig.PreimageRetention = 2
+
+	var (
+		public  = []byte("public key")
+		private = []byte("private key")
+		large   = bytes.Repeat([]byte{1}, 65)
+		slot    = common.Hash{2}.Bytes()
+	)
+	record := func(number uint64, block common.Hash, public, private [][]byte) {
+		sets := [2]map[common.Hash][]byte{{}, {}}
+		for i, preimages := range [][][]byte{public, private} {
+			for _, preimage := range preimages {
+				sets[i][crypto.Keccak256Hash(preimage)] = preimage
+			}
+		}
+		batch := db.NewBatch()
+		chain.writePreimages(batch, number, block, sets[0], sets[1])
+		if err := batch.Write(); err != nil {
+			t.Fatalf("failed to write preimages: %v", err)
+		}
+	}
+	stored := func(preimage []byte) bool {
+		return rawdb.ReadPreimage(db, crypto.Keccak256Hash(preimage)) != nil
+	}
+	record(1, common.Hash{1}, [][]byte{public, large, slot}, [][]byte{private})
+	if !stored(public) || !stored(private) || !stored(slot) {
+		t.Fatalf("preimages not stored")
+	}
+	if stored(large) {
+		t.Errorf("oversized preimage stored")
+	}
+	// Record the private preimage again, public one must expire first
+	record(2, common.Hash{2}, nil, [][]byte{private})
+	record(3, common.Hash{3}, nil, nil)
+	if stored(public) {
+		t.Errorf("expired preimage retained")
+	}
+	if !stored(private) {
+		t.Errorf("recently recorded preimage dropped")
+	}
+	record(4, common.Hash{4}, nil, nil)
+	if stored(private) {
+		t.Errorf("expired preimage retained")
+	}
+	// The preimages shared with the state tries are never deleted
+	if !stored(slot) {
+		t.Errorf("secure key preimage dropped")
+	}
+	// Both blocks of a height replaced in a reorg have their preimages expired
+	record(5, common.Hash{5}, [][]byte{public}, nil)
+	record(5, common.Hash{0x55}, [][]byte{private}, nil)
+	record(6, common.Hash{6}, nil, nil)
+	record(7, common.Hash{7}, nil, nil)
+	if stored(public) || stored(private) {
+		t.Errorf("expired preimage of a reorged block retained")
+	}
+}
@@ -0,0 +1,252 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+var (
+	// ErrContractPurged is returned if a private contract was already purged.
+	ErrContractPurged = errors.New("private contract already purged")
+
+	// ErrPurgeNotAgreed is returned if a party of a purge agreement didn't sign it.
+	ErrPurgeNotAgreed = errors.New("purge agreement not signed by all its parties")
+
+	// ErrPurgeUnknownSigner is returned if a purge agreement is signed by an
+	// account which isn't one of its parties.
+	ErrPurgeUnknownSigner = errors.New("purge agreement signed by a non-party")
+)
+
+// PurgeAgreement is the request of the parties of a private contract to erase
+// its private state and the payloads of its transactions from their nodes.
+type PurgeAgreement struct {
+	Contract     common.Address   `json:"contract"`     // Private contract to purge
+	Transactions []common.Hash    `json:"transactions"` // Private transactions whose payloads to erase
+	Parties      []common.Address `json:"parties"`      // Accounts which must all sign the agreement
+	Recipients   []string         `json:"recipients"`   // Private transaction manager keys of the parties, covering all the participants of the transactions
+	Reason       string           `json:"reason"`       // Free form justification kept in the tombstone
+}
+
+// PurgeHash returns the hash the parties of an agreement sign to agree to it.
+// The signed message is "purge:<contract>:<agreement digest>", where the digest
+// is the Keccak256 hash of the RLP encoded agreement, hashed the same way as
+// eth_sign so that any standard signer can produce the signatures.
+func PurgeHash(agreement *PurgeAgreement) common.Hash {
+	encoded, _ := rlp.EncodeToBytes(agreement)
+	msg := fmt.Sprintf("purge:%s:%s", agreement.Contract.Hex(), crypto.Keccak256Hash(encoded).Hex())
+	prefixed := fmt.Sprintf("\x19Ethereum Signed Message:\n%d%s", len(msg), msg)
+	return crypto.Keccak256Hash([]byte(prefixed))
+}
+
+// VerifyPurgeAgreement checks that every party of an agreement signed it, and
+// that nobody else did.
+func VerifyPurgeAgreement(agreement *PurgeAgreement, sigs []hexutil.Bytes) error {
+	if len(agreement.Parties) == 0 {
+		return errors.New("purge agreement without parties")
+	}
+	parties := make(map[common.Address]bool)
+	for _, party := range agreement.Parties {
+		parties[party] = false
+	}
+	hash := PurgeHash(agreement)
+	for _, sig := range sigs {
+		if len(sig) != 65 {
+			return fmt.Errorf("invalid purge signature length %d", len(sig))
+		}
+		raw := common.CopyBytes(sig)
+		if raw[64] >= 27 {
+			raw[64] -= 27 // Accept the legacy Ethereum V values too
+		}
+		pub, err := crypto.SigToPub(hash.Bytes(), raw)
+		if err != nil {
+			return err
+		}
+		signer := crypto.PubkeyToAddress(*pub)
+		if _, ok := parties[signer]; !ok {
+			return ErrPurgeUnknownSigner
+		}
+		parties[signer] = true
+	}
+	for _, signed := range parties {
+		if !signed {
+			return ErrPurgeNotAgreed
+		}
+	}
+	return nil
+}
+
+// PrivateTombstone is the audit record of a purged private contract, kept after
+// its private state and payloads are gone.
+type PrivateTombstone struct {
+	Contract     common.Address   `json:"contract"`
+	Agreement    common.Hash      `json:"agreement"` // PurgeHash of the agreement
+	Transactions []common.Hash    `json:"transactions"`
+	Parties      []common.Address `json:"parties"`
+	Recipients   []string         `json:"recipients"`
+	Signatures   []hexutil.Bytes  `json:"signatures"`
+	Reason       string           `json:"reason"`
+	Erased       []common.Hash    `json:"erased"` // Transactions whose payloads the private transaction manager erased
+	Block        hexutil.Uint64   `json:"block"`  // Head block when the contract was purged
+	Time         hexutil.Uint64   `json:"time"`   // Unix time in seconds when the contract was purged
+}
+
+// ReadPrivateTombstone retrieves the tombstone of a purged private contract, nil
+// if the contract wasn't purged.
+func ReadPrivateTombstone(db ethdb.Database, contract common.Address) *PrivateTombstone {
+	data, _ := db.Get(append(privateTombstonePrefix, contract[:]...))
+	if len(data) == 0 {
+		return nil
+	}
+	tombstone := new(PrivateTombstone)
+	if err := rlp.DecodeBytes(data, tombstone); err != nil {
+		log.Error("Invalid private contract tombstone", "contract", contract, "err", err)
+		return nil
+	}
+	return tombstone
+}
+
+// ReadPrivateTombstoneList retrieves the addresses of the purged private
+// contracts, in the order they were purged.
+func ReadPrivateTombstoneList(db ethdb.Database) []common.Address {
+	data, _ := db.Get(privateTombstoneListKey)
+	if len(data) == 0 {
+		return nil
+	}
+	var contracts []common.Address
+	if err := rlp.DecodeBytes(data, &contracts); err != nil {
+		log.Error("Invalid private contract tombstone list", "err", err)
+		return nil
+	}
+	return contracts
+}
+
+// writePrivateTombstone stores the tombstone of a purged private contract and
+// adds the contract to the tombstone list.
+func writePrivateTombstone(db ethdb.Database, tombstone *PrivateTombstone) error {
+	data, err := rlp.EncodeToBytes(tombstone)
+	if err != nil {
+		return err
+	}
+	list, err := rlp.EncodeToBytes(append(ReadPrivateTombstoneList(db), tombstone.Contract))
+	if err != nil {
+		return err
+	}
+	batch := db.NewBatch()
+	batch.Put(append(privateTombstonePrefix, tombstone.Contract[:]...), data)
+	batch.Put(privateTombstoneListKey, list)
+	return batch.Write()
+}
+
+// PrivateTombstone retrieves the tombstone of a purged private contract, nil if
+// the contract wasn't purged.
+func (bc *BlockChain) PrivateTombstone(contract common.Address) *PrivateTombstone {
+	return ReadPrivateTombstone(bc.db, contract)
+}
+
+// PurgePrivateContract erases a private contract from the private state of the
+// head block and scrubs the logs of the given transactions from their receipts,
+// then records the tombstone. The contract is also erased from the private state
+// of every block imported from then on, so that transactions sent to it later or
+// blocks being mined concurrently can't bring its state back.
+//
+// The purge is head-only: the private states of the blocks before the head are
+// left untouched, the state of the contract remaining readable at those blocks
+// until their tries are pruned. Archive nodes keep it for good.
+func (bc *BlockChain) PurgePrivateContract(tombstone *PrivateTombstone) error {
+	bc.chainmu.Lock()
+	defer bc.chainmu.Unlock()
+
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+
+	if _, ok := bc.tombstones[tombstone.Contract]; ok {
+		return ErrContractPurged
+	}
+	head := bc.CurrentBlock()
+	privateState, err := state.New(GetPrivateStateRoot(bc.db, head.Root()), bc.privateStateCache)
+	if err != nil {
+		return err
+	}
+	privateState.Suicide(tombstone.Contract)
+	root, err := privateState.Commit(bc.chainConfig.IsEIP158(head.Number()))
+	if err != nil {
+		return err
+	}
+	if err := bc.privateStateCache.TrieDB().Commit(root, false); err != nil {
+		return err
+	}
+	if bc.privateSnaps != nil {
+		if err := bc.privateSnaps.Cap(root, triesInMemory); err != nil {
+			log.Debug("Failed to cap private state snapshots", "root", root, "err", err)
+		}
+	}
+	if err := WritePrivateStateRoot(bc.db, head.Root(), root); err != nil {
+		return err
+	}
+	for _, hash := range tombstone.Transactions {
+		bc.scrubPrivateReceipt(hash)
+	}
+	tombstone.Block = hexutil.Uint64(head.NumberU64())
+	if err := writePrivateTombstone(bc.db, tombstone); err != nil {
+		return err
+	}
+	bc.tombstones[tombstone.Contract] = struct{}{}
+
+	log.Info("Purged private contract", "contract", tombstone.Contract, "agreement", tombstone.Agreement, "number", head.Number(), "root", root)
+	return nil
+}
+
+// scrubPrivateReceipt removes the logs and revert reason of a private
+// transaction from its stored receipt.
+func (bc *BlockChain) scrubPrivateReceipt(hash common.Hash) {
+	blockHash, number, _ := rawdb.ReadTxLookupEntry(bc.db, hash)
+	if blockHash == (common.Hash{}) {
+		return
+	}
+	receipts := rawdb.ReadReceipts(bc.db, blockHash, number)
+	for _, receipt := range receipts {
+		if receipt.TxHash == hash {
+			receipt.Logs = []*types.Log{}
+			receipt.Bloom = types.CreateBloom(types.Receipts{receipt})
+			receipt.RevertReason = ""
+		}
+	}
+	rawdb.WriteReceipts(bc.db, blockHash, number, receipts)
+	bc.receiptsCache.Remove(blockHash)
+}
+
+// erasePurgedContracts removes the purged private contracts from a private state
+// about to be committed.
+func (bc *BlockChain) erasePurgedContracts(privateState *state.StateDB) {
+	for contract := range bc.tombstones {
+		if privateState.Exist(contract) {
+			privateState.Suicide(contract)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that purge agreements are only accepted when signed by all their
+// parties and nobody else.
+func TestVerifyPurgeAgreement(t *testing.T) {
+	keyA, _ := crypto.GenerateKey()
+	keyB, _ := crypto.GenerateKey()
+	keyC, _ := crypto.GenerateKey()
+
+	agreement := &PurgeAgreement{
+		Contract:     common.Address{0x01},
+		Transactions: []common.Hash{{0x02}},
+		Parties:      []common.Address{crypto.PubkeyToAddress(keyA.PublicKey), crypto.PubkeyToAddress(keyB.PublicKey)},
+		Reason:       "retention period over",
+	}
+	sign := func(keys ...*ecdsa.PrivateKey) []hexutil.Bytes {
+		var sigs []hexutil.Bytes
+		for _, key := range keys {
+			sig, _ := crypto.Sign(PurgeHash(agreement).Bytes(), key)
+			sigs = append(sigs, sig)
+		}
+		return sigs
+	}
+	if err := VerifyPurgeAgreement(agreement, sign(keyB, keyA)); err != nil {
+		t.Errorf("agreement of all parties rejected: %v", err)
+	}
+	if err := VerifyPurgeAgreement(agreement, sign(keyA)); err != ErrPurgeNotAgreed {
+		t.Errorf("error mismatch: have %v, want %v", err, ErrPurgeNotAgreed)
+	}
+	if err := VerifyPurgeAgreement(agreement, sign(keyA, keyB, keyC)); err != ErrPurgeUnknownSigner {
+		t.Errorf("error mismatch: have %v, want %v", err, ErrPurgeUnknownSigner)
+	}
+	// Signatures are bound to the whole agreement
+	sigs := sign(keyA, keyB)
+	agreement.Reason = "changed"
+	if err := VerifyPurgeAgreement(agreement, sigs); err == nil {
+		t.Errorf("signatures of another agreement accepted")
+	}
+}
+
+// seedPrivateContract stores a contract in the private state of the head block.
+func seedPrivateContract(t *testing.T, db ethdb.Database, bc *BlockChain, contract common.Address) {
+	head := bc.CurrentBlock()
+	privateState, _ := state.New(GetPrivateStateRoot(db, head.Root()), bc.privateStateCache)
+	privateState.SetCode(contract, []byte{0x60, 0x00})
+	privateState.SetState(contract, common.Hash{0x01}, common.Hash{0x02})
+	root, err := privateState.Commit(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bc.privateStateCache.TrieDB().Commit(root, false)
+	WritePrivateStateRoot(db, head.Root(), root)
+}
+
+// Tests that purging a private contract erases it from the private state of the
+// head and of the blocks imported afterwards, and scrubs the logs of its
+// transactions.
+func TestPurgePrivateContract(t *testing.T) {
+	var (
+		key, _   = crypto.GenerateKey()
+		address  = crypto.PubkeyToAddress(key.PublicKey)
+		contract = common.Address{0xc0}
+		db       = ethdb.NewMemDatabase()
+		gspec    = &Genesis{Config: params.TestChainConfig, Alloc: GenesisAlloc{address: {Balance: big.NewInt(1000000000)}}}
+		genesis  = gspec.MustCommit(db)
+		signer   = types.NewEIP155Signer(gspec.Config.ChainID)
+	)
+	// Emit a log from a contract creation, standing for a private transaction
+	var logged common.Hash
+	blocks, _ := GenerateChain(gspec.Config, genesis, ethash.NewFaker(), db, 3, func(i int, block *BlockGen) {
+		if i == 0 {
+			tx, _ := types.SignTx(types.NewContractCreation(block.TxNonce(address), new(big.Int), 100000, nil, []byte{0x60, 0x00, 0x60, 0x00, 0xa0}), signer, key)
+			block.AddTx(tx)
+			logged = tx.Hash()
+		}
+	})
+	bc, _ := NewBlockChain(db, nil, gspec.Config, ethash.NewFaker(), vm.Config{}, nil)
+	defer bc.Stop()
+
+	if _, err := bc.InsertChain(blocks[:2]); err != nil {
+		t.Fatalf("failed to import chain: %v", err)
+	}
+	if receipt, _, _, _ := rawdb.ReadReceipt(db, logged); receipt == nil || len(receipt.Logs) != 1 {
+		t.Fatalf("logs of the transaction missing")
+	}
+	seedPrivateContract(t, db, bc, contract)
+
+	tombstone := &PrivateTombstone{Contract: contract, Transactions: []common.Hash{logged}, Reason: "test"}
+	if err := bc.PurgePrivateContract(tombstone); err != nil {
+		t.Fatalf("failed to purge contract: %v", err)
+	}
+	_, privateState, _ := bc.State()
+	if privateState.Exist(contract) {
+		t.Errorf("purged contract still in the private state")
+	}
+	if receipt, _, _, _ := rawdb.ReadReceipt(db, logged); receipt == nil || len(receipt.Logs) != 0 {
+		t.Errorf("logs of the purged transaction not scrubbed")
+	}
+	if stored := bc.PrivateTombstone(contract); stored == nil || stored.Reason != "test" || uint64(stored.Block) != 2 {
+		t.Errorf("tombstone mismatch: have %+v", stored)
+	}
+	if err := bc.PurgePrivateContract(tombstone); err != ErrContractPurged {
+		t.Errorf("error mismatch: have %v, want %v", err, ErrContractPurged)
+	}
+	// A block built on a state still holding the contract must not bring it back
+	seedPrivateContract(t, db, bc, contract)
+	if _, err := bc.InsertChain(blocks[2:]); err != nil {
+		t.Fatalf("failed to import chain: %v", err)
+	}
+	if _, privateState, _ := bc.State(); privateState.Exist(contract) {
+		t.Errorf("purged contract restored by a new block")
+	}
+	// The tombstones survive restarts
+	restarted, _ := NewBlockChain(db, nil, gspec.Config, ethash.NewFaker(), vm.Config{}, nil)
+	defer restarted.Stop()
+
+	if _, ok := restarted.tombstones[contract]; !ok {
+		t.Errorf("tombstone not loaded on restart")
+	}
+}
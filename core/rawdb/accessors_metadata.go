@@ -17,6 +17,7 @@
 package rawdb
 
 import (
+	"encoding/binary"
 	"encoding/json"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -87,3 +88,74 @@ func WritePreimages(db DatabaseWriter, preimages map[common.Hash][]byte) {
 	preimageCounter.Inc(int64(len(preimages)))
 	preimageHitCounter.Inc(int64(len(preimages)))
 }
+
+// DeletePreimage removes a preimage and the number of the last block which
+// recorded it.
+func DeletePreimage(db DatabaseDeleter, hash common.Hash) {
+	if err := db.Delete(preimageKey(hash)); err != nil {
+		log.Crit("Failed to delete trie preimage", "err", err)
+	}
+	if err := db.Delete(preimageBlockKey(hash)); err != nil {
+		log.Crit("Failed to delete preimage block number", "err", err)
+	}
+}
+
+// ReadPreimageBlock retrieves the number of the last block which recorded a
+// preimage, if it was tracked.
+func ReadPreimageBlock(db DatabaseReader, hash common.Hash) *uint64 {
+	data, _ := db.Get(preimageBlockKey(hash))
+	if len(data) != 8 {
+		return nil
+	}
+	number := binary.BigEndian.Uint64(data)
+	return &number
+}
+
+// WritePreimageBlock stores the number of the last block which recorded a
+// preimage.
+func WritePreimageBlock(db DatabaseWriter, hash common.Hash, number uint64) {
+	if err := db.Put(preimageBlockKey(hash), encodeBlockNumber(number)); err != nil {
+		log.Crit("Failed to store preimage block number", "err", err)
+	}
+}
+
+// PreimageIndexEntry holds the hashes of the preimages recorded in a block.
+type PreimageIndexEntry struct {
+	Block     common.Hash
+	Preimages []common.Hash
+}
+
+// ReadPreimageIndex retrieves the hashes of the preimages recorded in the
+// blocks of a height, one entry per block.
+func ReadPreimageIndex(db DatabaseReader, number uint64) []PreimageIndexEntry {
+	data, _ := db.Get(preimageIndexKey(number))
+	if len(data) == 0 {
+		return nil
+	}
+	var entries []PreimageIndexEntry
+	if err := rlp.DecodeBytes(data, &entries); err != nil {
+		log.Error("Invalid preimage index RLP", "number", number, "err", err)
+		return nil
+	}
+	return entries
+}
+
+// WritePreimageIndex stores the hashes of the preimages recorded in the blocks
+// of a height.
+func WritePreimageIndex(db DatabaseWriter, number uint64, entries []PreimageIndexEntry) {
+	data, err := rlp.EncodeToBytes(entries)
+	if err != nil {
+		log.Crit("Failed to RLP encode preimage index", "err", err)
+	}
+	if err := db.Put(preimageIndexKey(number), data); err != nil {
+		log.Crit("Failed to store preimage index", "err", err)
+	}
+}
+
+// DeletePreimageIndex removes the hashes of the preimages recorded in the
+// blocks of a height.
+func DeletePreimageIndex(db DatabaseDeleter, number uint64) {
+	if err := db.Delete(preimageIndexKey(number)); err != nil {
+		log.Crit("Failed to delete preimage index", "err", err)
+	}
+}
@@ -56,6 +56,9 @@ var (
 	preimagePrefix = []byte("secure-key-")      // preimagePrefix + hash -> preimage
 	configPrefix   = []byte("ethereum-config-") // config prefix for the db
 
+	preimageIndexPrefix = []byte("preimage-index-") // preimageIndexPrefix + num (uint64 big endian) -> hashes of the preimages recorded in the blocks, by block hash
+	preimageBlockPrefix = []byte("preimage-block-") // preimageBlockPrefix + hash -> num (uint64 big endian) of the last block recording the preimage
+
 	// Chain index prefixes (use `i` + single byte to avoid mixing data types).
 	BloomBitsIndexPrefix = []byte("iB") // BloomBitsIndexPrefix is the data table of a chain indexer to track its progress
 
@@ -128,6 +131,16 @@ func preimageKey(hash common.Hash) []byte {
 	return append(preimagePrefix, hash.Bytes()...)
 }
 
+// preimageIndexKey = preimageIndexPrefix + num (uint64 big endian)
+func preimageIndexKey(number uint64) []byte {
+	return append(preimageIndexPrefix, encodeBlockNumber(number)...)
+}
+
+// preimageBlockKey = preimageBlockPrefix + hash
+func preimageBlockKey(hash common.Hash) []byte {
+	return append(preimageBlockPrefix, hash.Bytes()...)
+}
+
 // configKey = configPrefix + hash
 func configKey(hash common.Hash) []byte {
 	return append(configPrefix, hash.Bytes()...)
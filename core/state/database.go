@@ -21,6 +21,7 @@ import (
 	"sync"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state/snapshot"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/trie"
 	lru "github.com/hashicorp/golang-lru"
@@ -83,6 +84,17 @@ func NewDatabase(db ethdb.Database) Database {
 	}
 }
 
+// NewDatabaseWithSnapshots wraps a state database, so that the states opened on
+// it read accounts and storage slots from the snapshots when available.
+func NewDatabaseWithSnapshots(db Database, snaps *snapshot.Tree) Database {
+	return &snapshotDB{Database: db, snaps: snaps}
+}
+
+type snapshotDB struct {
+	Database
+	snaps *snapshot.Tree
+}
+
 type cachingDB struct {
 	db            *trie.Database
 	mu            sync.Mutex
@@ -0,0 +1,139 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// ContractExport is the full state of a contract, along with the proof of its
+// account against the root of the state it was exported from.
+type ContractExport struct {
+	Address      common.Address  `json:"address"`
+	Nonce        hexutil.Uint64  `json:"nonce"`
+	Balance      *hexutil.Big    `json:"balance"`
+	CodeHash     common.Hash     `json:"codeHash"`
+	Code         hexutil.Bytes   `json:"code"`
+	StorageRoot  common.Hash     `json:"storageRoot"`
+	Storage      []StorageEntry  `json:"storage"`
+	AccountProof []hexutil.Bytes `json:"accountProof"`
+}
+
+// StorageEntry is a slot of the storage of a contract, keyed by the hash of the
+// slot as in the storage trie. The slot itself is only known if its preimage was
+// recorded.
+type StorageEntry struct {
+	Key   common.Hash  `json:"key"`
+	Slot  *common.Hash `json:"slot,omitempty"`
+	Value common.Hash  `json:"value"`
+}
+
+// ExportContract exports the state of a contract, or returns nil if there is no
+// such account.
+func (self *StateDB) ExportContract(addr common.Address) (*ContractExport, error) {
+	obj := self.getStateObject(addr)
+	if obj == nil {
+		return nil, nil
+	}
+	proof, err := self.GetProof(addr)
+	if err != nil {
+		return nil, err
+	}
+	export := &ContractExport{
+		Address:     addr,
+		Nonce:       hexutil.Uint64(obj.Nonce()),
+		Balance:     (*hexutil.Big)(obj.Balance()),
+		CodeHash:    common.BytesToHash(obj.CodeHash()),
+		Code:        obj.Code(self.db),
+		StorageRoot: obj.data.Root,
+		Storage:     []StorageEntry{},
+	}
+	for _, node := range proof {
+		export.AccountProof = append(export.AccountProof, node)
+	}
+	it := trie.NewIterator(obj.getTrie(self.db).NodeIterator(nil))
+	for it.Next() {
+		_, content, _, err := rlp.Split(it.Value)
+		if err != nil {
+			return nil, err
+		}
+		entry := StorageEntry{Key: common.BytesToHash(it.Key), Value: common.BytesToHash(content)}
+		if preimage := self.trie.GetKey(it.Key); preimage != nil {
+			slot := common.BytesToHash(preimage)
+			entry.Slot = &slot
+		}
+		export.Storage = append(export.Storage, entry)
+	}
+	if it.Err != nil {
+		return nil, it.Err
+	}
+	return export, nil
+}
+
+// VerifyContractExport checks that an exported contract is the one held by the
+// state of the given root: its account must be proven against the root, and its
+// code and storage must match the hashes of the account.
+func VerifyContractExport(root common.Hash, export *ContractExport) error {
+	proofDb := ethdb.NewMemDatabase()
+	for _, node := range export.AccountProof {
+		proofDb.Put(crypto.Keccak256(node), node)
+	}
+	blob, _, err := trie.VerifyProof(root, crypto.Keccak256(export.Address[:]), proofDb)
+	if err != nil {
+		return fmt.Errorf("invalid account proof: %v", err)
+	}
+	if blob == nil {
+		return fmt.Errorf("account %x not in state %x", export.Address, root)
+	}
+	var account Account
+	if err := rlp.DecodeBytes(blob, &account); err != nil {
+		return err
+	}
+	switch {
+	case account.Nonce != uint64(export.Nonce):
+		return fmt.Errorf("nonce mismatch: have %d, want %d", export.Nonce, account.Nonce)
+	case export.Balance == nil || account.Balance.Cmp(export.Balance.ToInt()) != 0:
+		return fmt.Errorf("balance mismatch: have %v, want %v", export.Balance, account.Balance)
+	case !bytes.Equal(account.CodeHash, export.CodeHash[:]):
+		return fmt.Errorf("code hash mismatch: have %x, want %x", export.CodeHash, account.CodeHash)
+	case crypto.Keccak256Hash(export.Code) != export.CodeHash:
+		return fmt.Errorf("code doesn't match its hash %x", export.CodeHash)
+	case account.Root != export.StorageRoot:
+		return fmt.Errorf("storage root mismatch: have %x, want %x", export.StorageRoot, account.Root)
+	}
+	// Rebuild the storage trie from the entries
+	storage, _ := trie.New(common.Hash{}, trie.NewDatabase(ethdb.NewMemDatabase()))
+	for _, entry := range export.Storage {
+		if entry.Slot != nil && crypto.Keccak256Hash(entry.Slot[:]) != entry.Key {
+			return fmt.Errorf("slot %x doesn't match its key %x", *entry.Slot, entry.Key)
+		}
+		value, _ := rlp.EncodeToBytes(bytes.TrimLeft(entry.Value[:], "\x00"))
+		storage.Update(entry.Key[:], value)
+	}
+	if hash := storage.Hash(); hash != export.StorageRoot {
+		return fmt.Errorf("storage doesn't match its root: have %x, want %x", hash, export.StorageRoot)
+	}
+	return nil
+}
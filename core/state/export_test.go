@@ -0,0 +1,70 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+// Tests that an exported contract verifies against the root of its state, and
+// that tampering with it is detected.
+func TestExportContract(t *testing.T) {
+	db := NewDatabase(ethdb.NewMemDatabase())
+	statedb, _ := New(common.Hash{}, db)
+
+	contract := common.HexToAddress("0xc0de")
+	statedb.SetCode(contract, []byte{0x60, 0x00})
+	statedb.SetNonce(contract, 1)
+	statedb.AddBalance(contract, big.NewInt(42))
+	for i := int64(0); i < 64; i++ {
+		statedb.SetState(contract, common.BigToHash(big.NewInt(i)), common.BigToHash(big.NewInt(i+1)))
+	}
+	statedb.AddBalance(common.HexToAddress("0x01"), big.NewInt(1))
+
+	root, _ := statedb.Commit(false)
+	db.TrieDB().Commit(root, false)
+	statedb, _ = New(root, db)
+
+	export, err := statedb.ExportContract(contract)
+	if err != nil {
+		t.Fatalf("failed to export contract: %v", err)
+	}
+	if len(export.Storage) != 64 {
+		t.Fatalf("storage size mismatch: have %d, want 64", len(export.Storage))
+	}
+	if err := VerifyContractExport(root, export); err != nil {
+		t.Fatalf("failed to verify export: %v", err)
+	}
+	if missing, _ := statedb.ExportContract(common.HexToAddress("0xdead")); missing != nil {
+		t.Fatalf("exported missing contract")
+	}
+	// Tamper with the export
+	value := export.Storage[3].Value
+	export.Storage[3].Value = common.HexToHash("0xff")
+	if err := VerifyContractExport(root, export); err == nil {
+		t.Fatalf("tampered storage verified")
+	}
+	export.Storage[3].Value = value
+	export.Code = []byte{0x60, 0x01}
+	if err := VerifyContractExport(root, export); err == nil {
+		t.Fatalf("tampered code verified")
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snapshot
+
+import (
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// diffLayer is the snapshot of a state kept in memory, as the changes made to
+// the state of its parent layer.
+type diffLayer struct {
+	root      common.Hash
+	destructs map[common.Hash]struct{}               // Accounts deleted, along with their storage
+	accounts  map[common.Hash][]byte                 // Account trie values, nil if deleted
+	storage   map[common.Hash]map[common.Hash][]byte // Storage trie values, nil if emptied
+
+	lock   sync.RWMutex
+	parent snapshot // Layer the changes apply to
+	stale  bool     // Whether the layer has been flattened into the disk layer or dropped
+}
+
+func newDiffLayer(parent snapshot, root common.Hash, destructs map[common.Hash]struct{}, accounts map[common.Hash][]byte, storage map[common.Hash]map[common.Hash][]byte) *diffLayer {
+	return &diffLayer{
+		root:      root,
+		destructs: destructs,
+		accounts:  accounts,
+		storage:   storage,
+		parent:    parent,
+	}
+}
+
+// Root returns the root of the state the layer is at.
+func (dl *diffLayer) Root() common.Hash {
+	return dl.root
+}
+
+// Parent returns the layer the changes apply to.
+func (dl *diffLayer) Parent() snapshot {
+	dl.lock.RLock()
+	defer dl.lock.RUnlock()
+
+	return dl.parent
+}
+
+// Stale reports whether the layer has been flattened or dropped.
+func (dl *diffLayer) Stale() bool {
+	dl.lock.RLock()
+	defer dl.lock.RUnlock()
+
+	return dl.stale
+}
+
+// Account retrieves the trie value of an account, nil if it doesn't exist.
+func (dl *diffLayer) Account(hash common.Hash) ([]byte, error) {
+	dl.lock.RLock()
+	if dl.stale {
+		dl.lock.RUnlock()
+		return nil, ErrSnapshotStale
+	}
+	if data, ok := dl.accounts[hash]; ok {
+		dl.lock.RUnlock()
+		return data, nil
+	}
+	if _, ok := dl.destructs[hash]; ok {
+		dl.lock.RUnlock()
+		return nil, nil
+	}
+	parent := dl.parent
+	dl.lock.RUnlock()
+
+	return parent.Account(hash)
+}
+
+// Storage retrieves the trie value of a storage slot, nil if it is empty.
+func (dl *diffLayer) Storage(accountHash, storageHash common.Hash) ([]byte, error) {
+	dl.lock.RLock()
+	if dl.stale {
+		dl.lock.RUnlock()
+		return nil, ErrSnapshotStale
+	}
+	if data, ok := dl.storage[accountHash][storageHash]; ok {
+		dl.lock.RUnlock()
+		return data, nil
+	}
+	if _, ok := dl.destructs[accountHash]; ok {
+		dl.lock.RUnlock()
+		return nil, nil
+	}
+	parent := dl.parent
+	dl.lock.RUnlock()
+
+	return parent.Storage(accountHash, storageHash)
+}
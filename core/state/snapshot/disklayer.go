@@ -0,0 +1,90 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snapshot
+
+import (
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+// diskLayer is the snapshot of a state persisted in the database, at the bottom
+// of the in-memory diff layers.
+type diskLayer struct {
+	diskdb ethdb.Database
+	ns     Namespace
+	root   common.Hash
+
+	lock      sync.RWMutex
+	genMarker []byte // Last account generated, nil once the snapshot is generated
+	stale     bool   // Whether the layer has been flattened into a newer one
+}
+
+// Root returns the root of the state the layer is at.
+func (dl *diskLayer) Root() common.Hash {
+	return dl.root
+}
+
+// Parent returns nil, as the disk layer is the bottom one.
+func (dl *diskLayer) Parent() snapshot {
+	return nil
+}
+
+// Stale reports whether the layer has been flattened into a newer one.
+func (dl *diskLayer) Stale() bool {
+	dl.lock.RLock()
+	defer dl.lock.RUnlock()
+
+	return dl.stale
+}
+
+// Account retrieves the trie value of an account, nil if it doesn't exist.
+func (dl *diskLayer) Account(hash common.Hash) ([]byte, error) {
+	dl.lock.RLock()
+	defer dl.lock.RUnlock()
+
+	if dl.stale {
+		return nil, ErrSnapshotStale
+	}
+	if !covered(dl.genMarker, hash) {
+		return nil, ErrNotCoveredYet
+	}
+	blob, _ := dl.diskdb.Get(dl.ns.accountKey(hash))
+	if len(blob) == 0 {
+		return nil, nil
+	}
+	return blob, nil
+}
+
+// Storage retrieves the trie value of a storage slot, nil if it is empty.
+func (dl *diskLayer) Storage(accountHash, storageHash common.Hash) ([]byte, error) {
+	dl.lock.RLock()
+	defer dl.lock.RUnlock()
+
+	if dl.stale {
+		return nil, ErrSnapshotStale
+	}
+	if !covered(dl.genMarker, accountHash) {
+		return nil, ErrNotCoveredYet
+	}
+	blob, _ := dl.diskdb.Get(dl.ns.storageKey(accountHash, storageHash))
+	if len(blob) == 0 {
+		return nil, nil
+	}
+	return blob, nil
+}
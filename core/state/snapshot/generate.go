@@ -0,0 +1,214 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snapshot
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+var (
+	// emptyRoot is the known root hash of an empty trie.
+	emptyRoot = common.HexToHash("56e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421")
+
+	errAborted = errors.New("snapshot generation aborted")
+)
+
+// account is the state trie value of an account, to find its storage trie.
+type account struct {
+	Nonce    uint64
+	Balance  *big.Int
+	Root     common.Hash
+	CodeHash []byte
+}
+
+// generate deletes the outdated snapshot if any, then generates the snapshot of
+// the disk layer from its state trie. The disk layer moving to another state,
+// the generation resumes on the trie of the new state.
+func (t *Tree) generate() {
+	defer close(t.genDone)
+
+	t.lock.RLock()
+	wiping := t.wiping
+	t.lock.RUnlock()
+
+	if wiping {
+		if err := t.wipe(); err != nil {
+			if err != errAborted {
+				log.Error("Failed to delete outdated state snapshot", "err", err)
+			}
+			return
+		}
+	}
+	start := time.Now()
+	for {
+		t.lock.RLock()
+		dl := t.disk
+		t.lock.RUnlock()
+
+		done, err := t.generateLayer(dl)
+		switch {
+		case err == errAborted:
+			return
+		case done:
+			log.Info("Generated state snapshot", "root", dl.root, "elapsed", common.PrettyDuration(time.Since(start)))
+			return
+		case err != nil:
+			// The trie of the disk layer may have been pruned, retry on the next one
+			log.Debug("State snapshot generation paused", "root", dl.root, "err", err)
+			select {
+			case <-t.genNotify:
+			case <-t.genAbort:
+				return
+			}
+		}
+	}
+}
+
+// wipe deletes the outdated snapshot, then records the root of the current disk
+// layer to generate it.
+func (t *Tree) wipe() error {
+	for _, prefix := range [][]byte{t.ns.accountPrefix, t.ns.storagePrefix} {
+		length := len(prefix) + common.HashLength
+		if bytes.Equal(prefix, t.ns.storagePrefix) {
+			length += common.HashLength
+		}
+		batch := t.diskdb.NewBatch()
+		err := iteratePrefix(t.diskdb, prefix, length, func(key []byte) error {
+			batch.Delete(key)
+			if batch.ValueSize() < ethdb.IdealBatchSize {
+				return nil
+			}
+			if err := batch.Write(); err != nil {
+				return err
+			}
+			batch.Reset()
+
+			select {
+			case <-t.genAbort:
+				return errAborted
+			default:
+				return nil
+			}
+		})
+		if err != nil {
+			return err
+		}
+		if err := batch.Write(); err != nil {
+			return err
+		}
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.wiping = false
+	return t.persist()
+}
+
+// generateLayer generates the snapshot of a disk layer from its state trie, from
+// its generation marker on. It returns whether the snapshot is generated, or
+// false without error if the disk layer has moved to another state.
+func (t *Tree) generateLayer(dl *diskLayer) (bool, error) {
+	dl.lock.RLock()
+	marker := dl.genMarker
+	dl.lock.RUnlock()
+
+	tr, err := trie.New(dl.root, t.triedb)
+	if err != nil {
+		return false, err
+	}
+	var (
+		it    = trie.NewIterator(tr.NodeIterator(marker))
+		batch = t.diskdb.NewBatch()
+		last  []byte
+	)
+	for it.Next() {
+		if len(marker) > 0 && bytes.Equal(it.Key, marker) {
+			continue
+		}
+		hash := common.BytesToHash(it.Key)
+		batch.Put(t.ns.accountKey(hash), it.Value)
+
+		var acc account
+		if err := rlp.DecodeBytes(it.Value, &acc); err != nil {
+			return false, err
+		}
+		if acc.Root != emptyRoot && acc.Root != (common.Hash{}) {
+			storage, err := trie.New(acc.Root, t.triedb)
+			if err != nil {
+				return false, err
+			}
+			sit := trie.NewIterator(storage.NodeIterator(nil))
+			for sit.Next() {
+				batch.Put(t.ns.storageKey(hash, common.BytesToHash(sit.Key)), sit.Value)
+			}
+			if sit.Err != nil {
+				return false, sit.Err
+			}
+		}
+		last = common.CopyBytes(it.Key)
+
+		if batch.ValueSize() >= ethdb.IdealBatchSize {
+			if ok, err := t.commitGenerated(dl, batch, last); !ok || err != nil {
+				return false, err
+			}
+			batch.Reset()
+
+			select {
+			case <-t.genAbort:
+				return false, errAborted
+			default:
+			}
+		}
+	}
+	if it.Err != nil {
+		return false, it.Err
+	}
+	return t.commitGenerated(dl, batch, nil)
+}
+
+// commitGenerated writes the snapshot generated up to the given account, or the
+// whole snapshot if nil, unless the disk layer has moved to another state.
+func (t *Tree) commitGenerated(dl *diskLayer, batch ethdb.Batch, marker []byte) (bool, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.disk != dl {
+		return false, nil
+	}
+	if marker != nil {
+		batch.Put(t.ns.generatorKey, marker)
+	} else {
+		batch.Delete(t.ns.generatorKey)
+	}
+	dl.lock.Lock()
+	defer dl.lock.Unlock()
+
+	if err := batch.Write(); err != nil {
+		return false, err
+	}
+	dl.genMarker = marker
+	return true, nil
+}
@@ -0,0 +1,119 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snapshot
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/syndtr/goleveldb/leveldb/iterator"
+)
+
+var errUnsupportedDatabase = errors.New("database can't iterate over its keys")
+
+// Namespace is the set of database keys a snapshot is stored under, so that the
+// public and private states are snapshotted in the same database.
+type Namespace struct {
+	accountPrefix []byte // accountPrefix + account hash -> account trie value
+	storagePrefix []byte // storagePrefix + account hash + storage hash -> storage trie value
+	rootKey       []byte // Root of the state the snapshot is at
+	generatorKey  []byte // Last account generated, missing once the snapshot is generated
+}
+
+var (
+	// PublicNamespace is the namespace of the public state snapshot.
+	PublicNamespace = Namespace{
+		accountPrefix: []byte("a"),
+		storagePrefix: []byte("o"),
+		rootKey:       []byte("SnapshotRoot"),
+		generatorKey:  []byte("SnapshotGenerator"),
+	}
+	// PrivateNamespace is the namespace of the private state snapshot.
+	PrivateNamespace = Namespace{
+		accountPrefix: []byte("pa"),
+		storagePrefix: []byte("po"),
+		rootKey:       []byte("PrivateSnapshotRoot"),
+		generatorKey:  []byte("PrivateSnapshotGenerator"),
+	}
+)
+
+// accountKey = accountPrefix + account hash
+func (ns Namespace) accountKey(hash common.Hash) []byte {
+	return append(append([]byte{}, ns.accountPrefix...), hash.Bytes()...)
+}
+
+// storageKey = storagePrefix + account hash + storage hash
+func (ns Namespace) storageKey(accountHash, storageHash common.Hash) []byte {
+	return append(append(append([]byte{}, ns.storagePrefix...), accountHash.Bytes()...), storageHash.Bytes()...)
+}
+
+// iterable reports whether the keys of a database can be iterated over, which
+// the snapshot needs to delete whole accounts.
+func iterable(db ethdb.Database) bool {
+	switch db.(type) {
+	case interface {
+		NewIteratorWithPrefix([]byte) iterator.Iterator
+	}:
+		return true
+	case interface{ Keys() [][]byte }:
+		return true
+	}
+	return false
+}
+
+// iteratePrefix calls fn with the keys of the given length starting with the
+// given prefix. Trie nodes share the key space of the snapshot, but not its key
+// lengths.
+func iteratePrefix(db ethdb.Database, prefix []byte, length int, fn func(key []byte) error) error {
+	switch db := db.(type) {
+	case interface {
+		NewIteratorWithPrefix([]byte) iterator.Iterator
+	}:
+		it := db.NewIteratorWithPrefix(prefix)
+		defer it.Release()
+
+		for it.Next() {
+			if len(it.Key()) != length {
+				continue
+			}
+			if err := fn(common.CopyBytes(it.Key())); err != nil {
+				return err
+			}
+		}
+		return it.Error()
+
+	case interface{ Keys() [][]byte }:
+		for _, key := range db.Keys() {
+			if len(key) != length || !bytes.HasPrefix(key, prefix) {
+				continue
+			}
+			if err := fn(key); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return errUnsupportedDatabase
+}
+
+// covered reports whether an account has been generated, given the generation
+// marker.
+func covered(marker []byte, hash common.Hash) bool {
+	return marker == nil || bytes.Compare(hash[:], marker) <= 0
+}
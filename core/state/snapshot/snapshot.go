@@ -0,0 +1,320 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package snapshot implements a flat snapshot of the state, to read accounts and
+// storage slots without traversing the state trie.
+//
+// The snapshot of the state at the chain head is persisted in the database, and
+// generated from the state trie in the background. The states of the blocks on
+// top of it are kept in memory as diff layers, until they are flattened into the
+// persisted one.
+package snapshot
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+var (
+	// ErrSnapshotStale is returned from data accessors if the layer has been
+	// flattened or dropped, so its state is no longer available.
+	ErrSnapshotStale = errors.New("snapshot stale")
+
+	// ErrNotCoveredYet is returned from data accessors if the snapshot generation
+	// hasn't reached the requested account yet.
+	ErrNotCoveredYet = errors.New("not covered yet")
+)
+
+// Snapshot is the flat snapshot of a state. The values returned are the trie
+// values, in their RLP encoding. Reads failing with an error must be served from
+// the state trie instead.
+type Snapshot interface {
+	// Root returns the root of the state the snapshot is at.
+	Root() common.Hash
+
+	// Account retrieves the trie value of an account, nil if it doesn't exist.
+	Account(hash common.Hash) ([]byte, error)
+
+	// Storage retrieves the trie value of a storage slot, nil if it is empty.
+	Storage(accountHash, storageHash common.Hash) ([]byte, error)
+}
+
+// snapshot is a layer of the snapshot tree.
+type snapshot interface {
+	Snapshot
+
+	// Parent returns the layer below, nil for the disk layer.
+	Parent() snapshot
+
+	// Stale reports whether the layer has been flattened or dropped.
+	Stale() bool
+}
+
+// Tree is the set of snapshots of the recent states of a state trie: the disk
+// layer, with the diff layers of the blocks built on it.
+type Tree struct {
+	diskdb ethdb.Database
+	triedb *trie.Database
+	ns     Namespace
+
+	lock   sync.RWMutex
+	layers map[common.Hash]snapshot // Snapshots by state root
+	disk   *diskLayer               // Current disk layer
+	wiping bool                     // Whether an outdated snapshot is being deleted
+
+	genAbort  chan struct{} // Closed to abort the generation
+	genDone   chan struct{} // Closed once the generation ends
+	genNotify chan struct{} // Notified when the disk layer moves to another state
+}
+
+// New opens the snapshot of the state with the given root, in the namespace of
+// the database. If the persisted snapshot is at another state, it is deleted and
+// the snapshot of the given state is generated in the background.
+func New(diskdb ethdb.Database, triedb *trie.Database, ns Namespace, root common.Hash) (*Tree, error) {
+	if !iterable(diskdb) {
+		return nil, errUnsupportedDatabase
+	}
+	t := &Tree{
+		diskdb:    diskdb,
+		triedb:    triedb,
+		ns:        ns,
+		layers:    make(map[common.Hash]snapshot),
+		genNotify: make(chan struct{}, 1),
+	}
+	var marker []byte
+	if stored, _ := diskdb.Get(ns.rootKey); len(stored) == common.HashLength && common.BytesToHash(stored) == root {
+		if m, err := diskdb.Get(ns.generatorKey); err == nil {
+			marker = append([]byte{}, m...)
+		}
+	} else {
+		// Delete the root first, so an interrupted deletion is resumed on restart
+		log.Info("Outdated state snapshot, regenerating", "root", root)
+		if err := diskdb.Delete(ns.rootKey); err != nil {
+			return nil, err
+		}
+		marker, t.wiping = []byte{}, true
+	}
+	t.disk = &diskLayer{diskdb: diskdb, ns: ns, root: root, genMarker: marker}
+	t.layers[root] = t.disk
+
+	if marker != nil {
+		t.genAbort, t.genDone = make(chan struct{}), make(chan struct{})
+		go t.generate()
+	}
+	return t, nil
+}
+
+// Snapshot retrieves the snapshot of the state with the given root, nil if it
+// isn't available.
+func (t *Tree) Snapshot(root common.Hash) Snapshot {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	if snap, ok := t.layers[root]; ok {
+		return snap
+	}
+	return nil
+}
+
+// Update adds the snapshot of a state, as the changes made to the state of its
+// parent: the accounts deleted, and the trie values of the accounts and storage
+// slots updated, by hash.
+func (t *Tree) Update(root, parent common.Hash, destructs map[common.Hash]struct{}, accounts map[common.Hash][]byte, storage map[common.Hash]map[common.Hash][]byte) error {
+	// Blocks without state change yield the same root, already snapshotted
+	if root == parent {
+		return nil
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if _, ok := t.layers[root]; ok {
+		return nil
+	}
+	parentSnap, ok := t.layers[parent]
+	if !ok {
+		return fmt.Errorf("parent snapshot [%#x] missing", parent)
+	}
+	t.layers[root] = newDiffLayer(parentSnap, root, destructs, accounts, storage)
+	return nil
+}
+
+// Cap flattens the diff layers the given state is built on into the disk layer,
+// keeping at most the given number of them in memory. The snapshots of the
+// states not built on the new disk layer are dropped.
+func (t *Tree) Cap(root common.Hash, layers int) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	snap, ok := t.layers[root]
+	if !ok {
+		return fmt.Errorf("snapshot [%#x] missing", root)
+	}
+	var chain []*diffLayer
+	for snap != t.disk {
+		diff, ok := snap.(*diffLayer)
+		if !ok {
+			return fmt.Errorf("snapshot [%#x] stale", root)
+		}
+		chain = append(chain, diff)
+		snap = diff.Parent()
+	}
+	if len(chain) <= layers {
+		return nil
+	}
+	flattened := make([]*diffLayer, 0, len(chain)-layers)
+	for i := len(chain) - 1; i >= layers; i-- {
+		flattened = append(flattened, chain[i])
+	}
+	if err := t.flatten(flattened); err != nil {
+		return err
+	}
+	if layers > 0 {
+		bottom := chain[layers-1]
+		bottom.lock.Lock()
+		bottom.parent = t.disk
+		bottom.lock.Unlock()
+	}
+	// Drop the snapshots of the states on other branches
+	for r, snap := range t.layers {
+		if !t.descends(snap) {
+			if diff, ok := snap.(*diffLayer); ok {
+				diff.lock.Lock()
+				diff.stale = true
+				diff.lock.Unlock()
+			}
+			delete(t.layers, r)
+		}
+	}
+	t.layers[t.disk.root] = t.disk
+
+	select {
+	case t.genNotify <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// descends reports whether a layer is built on the current disk layer.
+//
+// Note, this method assumes the tree lock is held!
+func (t *Tree) descends(snap snapshot) bool {
+	for snap != nil {
+		if snap == t.disk {
+			return true
+		}
+		snap = snap.Parent()
+	}
+	return false
+}
+
+// flatten writes the given diff layers, from the bottom one up, into the disk
+// layer. The accounts not generated yet are skipped, as the generation picks up
+// their latest state from the trie.
+//
+// Note, this method assumes the tree lock is held!
+func (t *Tree) flatten(diffs []*diffLayer) error {
+	base := t.disk
+	base.lock.Lock()
+	base.stale = true
+	marker := base.genMarker
+	base.lock.Unlock()
+
+	// Delete the root first, so an interrupted flattening regenerates the snapshot
+	if err := t.diskdb.Delete(t.ns.rootKey); err != nil {
+		return err
+	}
+	for _, diff := range diffs {
+		diff.lock.Lock()
+		diff.stale = true
+		diff.lock.Unlock()
+
+		batch := t.diskdb.NewBatch()
+		for hash := range diff.destructs {
+			if !covered(marker, hash) {
+				continue
+			}
+			batch.Delete(t.ns.accountKey(hash))
+			prefix := append(append([]byte{}, t.ns.storagePrefix...), hash.Bytes()...)
+			if err := iteratePrefix(t.diskdb, prefix, len(prefix)+common.HashLength, batch.Delete); err != nil {
+				return err
+			}
+		}
+		for hash, data := range diff.accounts {
+			if !covered(marker, hash) {
+				continue
+			}
+			if len(data) == 0 {
+				batch.Delete(t.ns.accountKey(hash))
+			} else {
+				batch.Put(t.ns.accountKey(hash), data)
+			}
+		}
+		for hash, slots := range diff.storage {
+			if !covered(marker, hash) {
+				continue
+			}
+			for slot, data := range slots {
+				if len(data) == 0 {
+					batch.Delete(t.ns.storageKey(hash, slot))
+				} else {
+					batch.Put(t.ns.storageKey(hash, slot), data)
+				}
+			}
+		}
+		if err := batch.Write(); err != nil {
+			return err
+		}
+	}
+	t.disk = &diskLayer{diskdb: t.diskdb, ns: t.ns, root: diffs[len(diffs)-1].root, genMarker: marker}
+
+	// An outdated snapshot being deleted gets its root once deleted
+	if t.wiping {
+		return nil
+	}
+	return t.persist()
+}
+
+// persist writes the root and generation marker of the disk layer.
+//
+// Note, this method assumes the tree lock is held!
+func (t *Tree) persist() error {
+	batch := t.diskdb.NewBatch()
+	batch.Put(t.ns.rootKey, t.disk.root.Bytes())
+	if t.disk.genMarker != nil {
+		batch.Put(t.ns.generatorKey, t.disk.genMarker)
+	} else {
+		batch.Delete(t.ns.generatorKey)
+	}
+	return batch.Write()
+}
+
+// Stop aborts the generation and flattens the diff layers the given state is
+// built on into the disk layer, so that the snapshot is reused on restart.
+func (t *Tree) Stop(root common.Hash) {
+	if t.genAbort != nil {
+		close(t.genAbort)
+		<-t.genDone
+	}
+	if err := t.Cap(root, 0); err != nil {
+		log.Warn("Failed to persist state snapshot", "root", root, "err", err)
+	}
+}
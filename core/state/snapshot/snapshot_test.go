@@ -0,0 +1,218 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snapshot
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// makeState commits a state trie with the given accounts, each holding a storage
+// slot of its own value, and returns its root.
+func makeState(t *testing.T, triedb *trie.Database, n int) common.Hash {
+	accTrie, _ := trie.New(common.Hash{}, triedb)
+	for i := 1; i <= n; i++ {
+		storage, _ := trie.New(common.Hash{}, triedb)
+		storage.Update(hashKey(i).Bytes(), encodeSlot(i))
+		root, err := storage.Commit(nil)
+		if err != nil {
+			t.Fatalf("failed to commit storage: %v", err)
+		}
+		accTrie.Update(hashKey(i).Bytes(), encodeAccount(i, root))
+	}
+	root, err := accTrie.Commit(nil)
+	if err != nil {
+		t.Fatalf("failed to commit accounts: %v", err)
+	}
+	return root
+}
+
+func hashKey(i int) common.Hash {
+	return crypto.Keccak256Hash(big.NewInt(int64(i)).Bytes())
+}
+
+func encodeSlot(i int) []byte {
+	blob, _ := rlp.EncodeToBytes(big.NewInt(int64(i)).Bytes())
+	return blob
+}
+
+func encodeAccount(nonce int, root common.Hash) []byte {
+	blob, _ := rlp.EncodeToBytes(&account{Nonce: uint64(nonce), Balance: new(big.Int), Root: root, CodeHash: crypto.Keccak256(nil)})
+	return blob
+}
+
+func waitGenerated(t *testing.T, tree *Tree) {
+	<-tree.genDone
+	if tree.disk.genMarker != nil {
+		t.Fatalf("snapshot not generated, marker %x", tree.disk.genMarker)
+	}
+}
+
+func TestGeneration(t *testing.T) {
+	var (
+		diskdb = ethdb.NewMemDatabase()
+		triedb = trie.NewDatabase(diskdb)
+		root   = makeState(t, triedb, 100)
+	)
+	tree, err := New(diskdb, triedb, PublicNamespace, root)
+	if err != nil {
+		t.Fatalf("failed to create snapshot tree: %v", err)
+	}
+	waitGenerated(t, tree)
+
+	snap := tree.Snapshot(root)
+	for i := 1; i <= 100; i++ {
+		acc, err := snap.Account(hashKey(i))
+		if err != nil || len(acc) == 0 {
+			t.Fatalf("account %d: have %x, %v", i, acc, err)
+		}
+		var dec account
+		if err := rlp.DecodeBytes(acc, &dec); err != nil || dec.Nonce != uint64(i) {
+			t.Fatalf("account %d: nonce %d, %v", i, dec.Nonce, err)
+		}
+		if slot, err := snap.Storage(hashKey(i), hashKey(i)); err != nil || !bytes.Equal(slot, encodeSlot(i)) {
+			t.Fatalf("slot %d: have %x, %v", i, slot, err)
+		}
+	}
+	if acc, err := snap.Account(hashKey(101)); acc != nil || err != nil {
+		t.Fatalf("missing account: have %x, %v", acc, err)
+	}
+	// The private state snapshot is kept apart
+	private, err := New(diskdb, triedb, PrivateNamespace, makeState(t, triedb, 1))
+	if err != nil {
+		t.Fatalf("failed to create private snapshot tree: %v", err)
+	}
+	waitGenerated(t, private)
+	if acc, _ := private.disk.Account(hashKey(2)); acc != nil {
+		t.Fatalf("public account in private snapshot: %x", acc)
+	}
+	if acc, _ := tree.disk.Account(hashKey(2)); acc == nil {
+		t.Fatalf("public account wiped by private snapshot")
+	}
+}
+
+func TestLayers(t *testing.T) {
+	var (
+		diskdb = ethdb.NewMemDatabase()
+		triedb = trie.NewDatabase(diskdb)
+		root   = makeState(t, triedb, 3)
+	)
+	tree, _ := New(diskdb, triedb, PublicNamespace, root)
+	waitGenerated(t, tree)
+
+	// Update an account, destruct another along with its storage
+	var (
+		root1 = common.HexToHash("0x01")
+		root2 = common.HexToHash("0x02")
+		fork  = common.HexToHash("0x03")
+	)
+	if err := tree.Update(root1, root, map[common.Hash]struct{}{hashKey(2): {}}, map[common.Hash][]byte{hashKey(1): encodeAccount(10, emptyRoot)}, nil); err != nil {
+		t.Fatalf("failed to update: %v", err)
+	}
+	if err := tree.Update(root2, root1, nil, nil, map[common.Hash]map[common.Hash][]byte{hashKey(3): {hashKey(3): nil, hashKey(4): encodeSlot(4)}}); err != nil {
+		t.Fatalf("failed to update: %v", err)
+	}
+	if err := tree.Update(fork, root, nil, nil, nil); err != nil {
+		t.Fatalf("failed to update: %v", err)
+	}
+	if err := tree.Update(root, root, nil, nil, nil); err != nil {
+		t.Fatalf("unchanged state rejected: %v", err)
+	}
+	if err := tree.Update(common.HexToHash("0x04"), common.HexToHash("0x05"), nil, nil, nil); err == nil {
+		t.Fatalf("unknown parent accepted")
+	}
+	check := func(snap Snapshot) {
+		if acc, _ := snap.Account(hashKey(1)); !bytes.Equal(acc, encodeAccount(10, emptyRoot)) {
+			t.Errorf("updated account mismatch: %x", acc)
+		}
+		if acc, _ := snap.Account(hashKey(2)); acc != nil {
+			t.Errorf("destructed account present: %x", acc)
+		}
+		if slot, _ := snap.Storage(hashKey(2), hashKey(2)); slot != nil {
+			t.Errorf("destructed storage present: %x", slot)
+		}
+		if slot, _ := snap.Storage(hashKey(3), hashKey(3)); slot != nil {
+			t.Errorf("emptied slot present: %x", slot)
+		}
+		if slot, _ := snap.Storage(hashKey(3), hashKey(4)); !bytes.Equal(slot, encodeSlot(4)) {
+			t.Errorf("updated slot mismatch: %x", slot)
+		}
+	}
+	check(tree.Snapshot(root2))
+	if slot, _ := tree.Snapshot(root1).Storage(hashKey(3), hashKey(3)); !bytes.Equal(slot, encodeSlot(3)) {
+		t.Errorf("parent layer slot mismatch: %x", slot)
+	}
+	// Flatten the first layer, dropping the fork
+	snap1, snapFork := tree.Snapshot(root1), tree.Snapshot(fork)
+	if err := tree.Cap(root2, 1); err != nil {
+		t.Fatalf("failed to cap: %v", err)
+	}
+	if tree.disk.root != root1 {
+		t.Fatalf("disk layer root mismatch: have %x, want %x", tree.disk.root, root1)
+	}
+	if _, err := snap1.Account(hashKey(1)); err != ErrSnapshotStale {
+		t.Errorf("flattened layer error mismatch: have %v", err)
+	}
+	if _, err := snapFork.Account(hashKey(1)); err != ErrSnapshotStale {
+		t.Errorf("dropped layer error mismatch: have %v", err)
+	}
+	if tree.Snapshot(fork) != nil {
+		t.Errorf("dropped layer still available")
+	}
+	check(tree.Snapshot(root2))
+
+	// Persist everything and reopen
+	tree.Stop(root2)
+	check(tree.disk)
+
+	reopened, _ := New(diskdb, triedb, PublicNamespace, root2)
+	if reopened.genDone != nil {
+		t.Fatalf("persisted snapshot regenerated")
+	}
+	check(reopened.Snapshot(root2))
+
+	// Outdated snapshots are wiped and regenerated
+	regenerated, _ := New(diskdb, triedb, PublicNamespace, root)
+	waitGenerated(t, regenerated)
+	if slot, _ := regenerated.disk.Storage(hashKey(3), hashKey(4)); slot != nil {
+		t.Errorf("outdated slot present: %x", slot)
+	}
+	if acc, _ := regenerated.disk.Account(hashKey(2)); acc == nil {
+		t.Errorf("regenerated account missing")
+	}
+}
+
+func TestNotCoveredYet(t *testing.T) {
+	dl := &diskLayer{diskdb: ethdb.NewMemDatabase(), ns: PublicNamespace, genMarker: hashKey(1).Bytes()}
+	before, after := common.Hash{}, common.BytesToHash(bytes.Repeat([]byte{0xff}, common.HashLength))
+	if _, err := dl.Account(before); err != nil {
+		t.Errorf("generated account error: %v", err)
+	}
+	if _, err := dl.Account(after); err != ErrNotCoveredYet {
+		t.Errorf("account error mismatch: have %v, want %v", err, ErrNotCoveredYet)
+	}
+	if _, err := dl.Storage(after, before); err != ErrNotCoveredYet {
+		t.Errorf("storage error mismatch: have %v, want %v", err, ErrNotCoveredYet)
+	}
+}
@@ -183,11 +183,23 @@ func (self *stateObject) GetCommittedState(db Database, key common.Hash) common.
 	if cached {
 		return value
 	}
-	// Otherwise load the value from the database
-	enc, err := self.getTrie(db).TryGet(key[:])
-	if err != nil {
-		self.setError(err)
-		return common.Hash{}
+	// Otherwise load the value from the snapshot if available, from the database otherwise
+	var (
+		enc []byte
+		err error
+	)
+	if self.db.snap != nil {
+		// The storage of a recreated account is gone
+		if _, destructed := self.db.snapDestructs[self.addrHash]; destructed {
+			return common.Hash{}
+		}
+		enc, err = self.db.snap.Storage(self.addrHash, crypto.Keccak256Hash(key[:]))
+	}
+	if self.db.snap == nil || err != nil {
+		if enc, err = self.getTrie(db).TryGet(key[:]); err != nil {
+			self.setError(err)
+			return common.Hash{}
+		}
 	}
 	if len(enc) > 0 {
 		_, content, _, err := rlp.Split(enc)
@@ -227,18 +239,30 @@ func (self *stateObject) updateTrie(db Database) Trie {
 		delete(self.dirtyStorage, key)
 
 		// Skip noop changes, persist actual changes
-		if value == self.originStorage[key] {
+		prev := self.originStorage[key]
+		if value == prev {
 			continue
 		}
 		self.originStorage[key] = value
+		self.db.recordStorageChange(self.address, prev, value)
 
+		var v []byte
 		if (value == common.Hash{}) {
 			self.setError(tr.TryDelete(key[:]))
-			continue
+		} else {
+			// Encoding []byte cannot fail, ok to ignore the error.
+			v, _ = rlp.EncodeToBytes(bytes.TrimLeft(value[:], "\x00"))
+			self.setError(tr.TryUpdate(key[:], v))
+		}
+		// Record the change for the snapshot
+		if self.db.snap != nil {
+			storage := self.db.snapStorage[self.addrHash]
+			if storage == nil {
+				storage = make(map[common.Hash][]byte)
+				self.db.snapStorage[self.addrHash] = storage
+			}
+			storage[crypto.Keccak256Hash(key[:])] = v
 		}
-		// Encoding []byte cannot fail, ok to ignore the error.
-		v, _ := rlp.EncodeToBytes(bytes.TrimLeft(value[:], "\x00"))
-		self.setError(tr.TryUpdate(key[:], v))
 	}
 	return tr
 }
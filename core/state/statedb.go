@@ -24,6 +24,7 @@ import (
 	"sort"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state/snapshot"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/log"
@@ -60,6 +61,13 @@ type StateDB struct {
 	db   Database
 	trie Trie
 
+	// Flat snapshot of the state, along with the changes made to it
+	snaps         *snapshot.Tree
+	snap          snapshot.Snapshot
+	snapDestructs map[common.Hash]struct{}
+	snapAccounts  map[common.Hash][]byte
+	snapStorage   map[common.Hash]map[common.Hash][]byte
+
 	// This map holds 'live' objects, which will get modified while processing a state transition.
 	stateObjects      map[common.Address]*stateObject
 	stateObjectsDirty map[common.Address]struct{}
@@ -81,6 +89,12 @@ type StateDB struct {
 
 	preimages map[common.Hash][]byte
 
+	// Quorum - state accessed by the transactions, for per-block limits
+	accesses StateAccesses
+
+	// Quorum - changes of the storage and code size of the contracts committed
+	sizeDeltas map[common.Address]*SizeDelta
+
 	// Journal of state modifications. This is the backbone of
 	// Snapshot and RevertToSnapshot.
 	journal        *journal
@@ -94,15 +108,34 @@ func New(root common.Hash, db Database) (*StateDB, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &StateDB{
+	sdb := &StateDB{
 		db:                db,
 		trie:              tr,
 		stateObjects:      make(map[common.Address]*stateObject),
 		stateObjectsDirty: make(map[common.Address]struct{}),
 		logs:              make(map[common.Hash][]*types.Log),
 		preimages:         make(map[common.Hash][]byte),
+		sizeDeltas:        make(map[common.Address]*SizeDelta),
 		journal:           newJournal(),
-	}, nil
+	}
+	if db, ok := db.(*snapshotDB); ok {
+		sdb.snaps = db.snaps
+		sdb.openSnapshot(root)
+	}
+	return sdb, nil
+}
+
+// openSnapshot starts reading the state from its snapshot, if available.
+func (self *StateDB) openSnapshot(root common.Hash) {
+	self.snap, self.snapDestructs, self.snapAccounts, self.snapStorage = nil, nil, nil, nil
+	if self.snaps == nil {
+		return
+	}
+	if self.snap = self.snaps.Snapshot(root); self.snap != nil {
+		self.snapDestructs = make(map[common.Hash]struct{})
+		self.snapAccounts = make(map[common.Hash][]byte)
+		self.snapStorage = make(map[common.Hash]map[common.Hash][]byte)
+	}
 }
 
 // setError remembers the first non-nil error it is called with.
@@ -132,6 +165,8 @@ func (self *StateDB) Reset(root common.Hash) error {
 	self.logs = make(map[common.Hash][]*types.Log)
 	self.logSize = 0
 	self.preimages = make(map[common.Hash][]byte)
+	self.sizeDeltas = make(map[common.Address]*SizeDelta)
+	self.openSnapshot(root)
 	self.clearJournalAndRefund()
 	return nil
 }
@@ -174,6 +209,44 @@ func (self *StateDB) Preimages() map[common.Hash][]byte {
 	return self.preimages
 }
 
+// StateAccesses counts the state accessed through a StateDB. Accesses are
+// counted whether or not they are served from cache and are not reverted with
+// snapshots, so that the same transactions always yield the same counts.
+type StateAccesses struct {
+	Reads           uint64 // Storage slots read
+	Writes          uint64 // Storage slots written
+	CreatedAccounts uint64 // Accounts created
+}
+
+// Accesses returns the state accessed so far.
+func (self *StateDB) Accesses() StateAccesses {
+	return self.accesses
+}
+
+// Add returns the sum of two access counts.
+func (a StateAccesses) Add(b StateAccesses) StateAccesses {
+	return StateAccesses{
+		Reads:           a.Reads + b.Reads,
+		Writes:          a.Writes + b.Writes,
+		CreatedAccounts: a.CreatedAccounts + b.CreatedAccounts,
+	}
+}
+
+// Sub returns the accesses counted since an earlier count.
+func (a StateAccesses) Sub(earlier StateAccesses) StateAccesses {
+	return StateAccesses{
+		Reads:           a.Reads - earlier.Reads,
+		Writes:          a.Writes - earlier.Writes,
+		CreatedAccounts: a.CreatedAccounts - earlier.CreatedAccounts,
+	}
+}
+
+// SetAccesses overrides the state access counters, to discard the accesses of
+// a transaction which is not included after all.
+func (self *StateDB) SetAccesses(accesses StateAccesses) {
+	self.accesses = accesses
+}
+
 // AddRefund adds gas to the refund counter
 func (self *StateDB) AddRefund(gas uint64) {
 	self.journal.append(refundChange{prev: self.refund})
@@ -254,6 +327,7 @@ func (self *StateDB) GetCodeHash(addr common.Address) common.Hash {
 
 // GetState retrieves a value from the given account's storage trie.
 func (self *StateDB) GetState(addr common.Address, hash common.Hash) common.Hash {
+	self.accesses.Reads++
 	stateObject := self.getStateObject(addr)
 	if stateObject != nil {
 		return stateObject.GetState(self.db, hash)
@@ -363,6 +437,7 @@ func (self *StateDB) SetCode(addr common.Address, code []byte) {
 }
 
 func (self *StateDB) SetState(addr common.Address, key, value common.Hash) {
+	self.accesses.Writes++
 	stateObject := self.GetOrNewStateObject(addr)
 	if stateObject != nil {
 		stateObject.SetState(self.db, key, value)
@@ -402,6 +477,10 @@ func (self *StateDB) updateStateObject(stateObject *stateObject) {
 		panic(fmt.Errorf("can't encode object at %x: %v", addr[:], err))
 	}
 	self.setError(self.trie.TryUpdate(addr[:], data))
+
+	if self.snap != nil {
+		self.snapAccounts[stateObject.addrHash] = data
+	}
 }
 
 // deleteStateObject removes the given object from the state trie.
@@ -409,6 +488,15 @@ func (self *StateDB) deleteStateObject(stateObject *stateObject) {
 	stateObject.deleted = true
 	addr := stateObject.Address()
 	self.setError(self.trie.TryDelete(addr[:]))
+
+	// Quorum: the storage and code of the contract are gone
+	self.sizeDeltas[addr] = &SizeDelta{Destructed: true}
+
+	if self.snap != nil {
+		self.snapDestructs[stateObject.addrHash] = struct{}{}
+		delete(self.snapAccounts, stateObject.addrHash)
+		delete(self.snapStorage, stateObject.addrHash)
+	}
 }
 
 // Retrieve a state object given by the address. Returns nil if not found.
@@ -421,8 +509,17 @@ func (self *StateDB) getStateObject(addr common.Address) (stateObject *stateObje
 		return obj
 	}
 
-	// Load the object from the database.
-	enc, err := self.trie.TryGet(addr[:])
+	// Load the object from the snapshot if available, from the database otherwise.
+	var (
+		enc []byte
+		err error
+	)
+	if self.snap != nil {
+		enc, err = self.snap.Account(crypto.Keccak256Hash(addr[:]))
+	}
+	if self.snap == nil || err != nil {
+		enc, err = self.trie.TryGet(addr[:])
+	}
 	if len(enc) == 0 {
 		self.setError(err)
 		return nil
@@ -458,9 +555,16 @@ func (self *StateDB) createObject(addr common.Address) (newobj, prev *stateObjec
 	newobj = newObject(self, addr, Account{})
 	newobj.setNonce(0) // sets the object to dirty
 	if prev == nil {
+		self.accesses.CreatedAccounts++
 		self.journal.append(createObjectChange{account: &addr})
 	} else {
-		self.journal.append(resetObjectChange{prev: prev})
+		// The storage of the previous account is gone
+		var prevdestruct bool
+		if self.snap != nil {
+			_, prevdestruct = self.snapDestructs[prev.addrHash]
+			self.snapDestructs[prev.addrHash] = struct{}{}
+		}
+		self.journal.append(resetObjectChange{prev: prev, prevdestruct: prevdestruct})
 	}
 	self.setStateObject(newobj)
 	return newobj, prev
@@ -512,7 +616,33 @@ func (self *StateDB) Copy() *StateDB {
 		logs:              make(map[common.Hash][]*types.Log, len(self.logs)),
 		logSize:           self.logSize,
 		preimages:         make(map[common.Hash][]byte),
+		accesses:          self.accesses,
+		sizeDeltas:        make(map[common.Address]*SizeDelta, len(self.sizeDeltas)),
 		journal:           newJournal(),
+		snaps:             self.snaps,
+		snap:              self.snap,
+	}
+	for addr, delta := range self.sizeDeltas {
+		cpy := *delta
+		state.sizeDeltas[addr] = &cpy
+	}
+	if self.snap != nil {
+		state.snapDestructs = make(map[common.Hash]struct{}, len(self.snapDestructs))
+		for hash := range self.snapDestructs {
+			state.snapDestructs[hash] = struct{}{}
+		}
+		state.snapAccounts = make(map[common.Hash][]byte, len(self.snapAccounts))
+		for hash, data := range self.snapAccounts {
+			state.snapAccounts[hash] = data
+		}
+		state.snapStorage = make(map[common.Hash]map[common.Hash][]byte, len(self.snapStorage))
+		for hash, slots := range self.snapStorage {
+			cpy := make(map[common.Hash][]byte, len(slots))
+			for slot, data := range slots {
+				cpy[slot] = data
+			}
+			state.snapStorage[hash] = cpy
+		}
 	}
 	// Copy the dirty states, logs, and preimages
 	for addr := range self.journal.dirties {
@@ -646,6 +776,9 @@ func (s *StateDB) Commit(deleteEmptyObjects bool) (root common.Hash, err error)
 			if stateObject.code != nil && stateObject.dirtyCode {
 				s.db.TrieDB().InsertBlob(common.BytesToHash(stateObject.CodeHash()), stateObject.code)
 				stateObject.dirtyCode = false
+
+				delta := s.sizeDelta(addr)
+				delta.Code, delta.CodeSet = len(stateObject.code), true
 			}
 			// Write any storage changes in the state object to its storage trie.
 			if err := stateObject.CommitTrie(s.db); err != nil {
@@ -672,5 +805,15 @@ func (s *StateDB) Commit(deleteEmptyObjects bool) (root common.Hash, err error)
 		return nil
 	})
 	log.Debug("Trie cache stats after commit", "misses", trie.CacheMisses(), "unloads", trie.CacheUnloads())
+
+	// Add the snapshot of the new state on top of the one it was built on
+	if s.snap != nil && err == nil {
+		if parent := s.snap.Root(); parent != root {
+			if err := s.snaps.Update(root, parent, s.snapDestructs, s.snapAccounts, s.snapStorage); err != nil {
+				log.Warn("Failed to update state snapshot", "root", root, "parent", parent, "err", err)
+			}
+		}
+		s.snap, s.snapDestructs, s.snapAccounts, s.snapStorage = nil, nil, nil, nil
+	}
 	return root, err
 }
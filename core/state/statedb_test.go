@@ -27,10 +27,12 @@ import (
 	"strings"
 	"testing"
 	"testing/quick"
+	"time"
 
 	check "gopkg.in/check.v1"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state/snapshot"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethdb"
 )
@@ -466,3 +468,64 @@ func TestCopyOfCopy(t *testing.T) {
 		t.Fatalf("2nd copy fail, expected 42, got %v", got)
 	}
 }
+
+// Tests that the states read from snapshots match the states read from the trie.
+func TestSnapshotReads(t *testing.T) {
+	db := ethdb.NewMemDatabase()
+	sdb := NewDatabase(db)
+	state, _ := New(common.Hash{}, sdb)
+
+	addrs := make([]common.Address, 10)
+	for i := range addrs {
+		addrs[i] = common.BytesToAddress([]byte{byte(i + 1)})
+		state.SetNonce(addrs[i], uint64(i))
+		state.SetState(addrs[i], common.Hash{byte(i)}, common.Hash{byte(i + 1)})
+	}
+	root, _ := state.Commit(false)
+
+	snaps, err := snapshot.New(db, sdb.TrieDB(), snapshot.PublicNamespace, root)
+	if err != nil {
+		t.Fatalf("failed to create snapshots: %v", err)
+	}
+	for start := time.Now(); ; time.Sleep(10 * time.Millisecond) {
+		if _, err := snaps.Snapshot(root).Account(common.BytesToHash(bytes.Repeat([]byte{0xff}, common.HashLength))); err == nil {
+			break
+		}
+		if time.Since(start) > 5*time.Second {
+			t.Fatalf("snapshot not generated")
+		}
+	}
+	// Modify the state read from the snapshot
+	state, _ = New(root, NewDatabaseWithSnapshots(sdb, snaps))
+	if state.snap == nil {
+		t.Fatalf("snapshot not used")
+	}
+	state.SetNonce(addrs[0], 100)
+	state.SetState(addrs[1], common.Hash{1}, common.Hash{})
+	state.SetState(addrs[1], common.Hash{100}, common.Hash{100})
+	state.Suicide(addrs[2])
+	state.Finalise(false)
+	state.CreateAccount(addrs[2])
+	state.SetState(addrs[3], common.Hash{3}, common.Hash{30})
+	state.CreateAccount(addrs[3])
+	root, _ = state.Commit(false)
+
+	if snaps.Snapshot(root) == nil {
+		t.Fatalf("snapshot of the new state missing")
+	}
+	fromSnap, _ := New(root, NewDatabaseWithSnapshots(sdb, snaps))
+	fromTrie, _ := New(root, sdb)
+	for i, addr := range addrs {
+		if have, want := fromSnap.GetNonce(addr), fromTrie.GetNonce(addr); have != want {
+			t.Errorf("account %d: nonce mismatch: have %d, want %d", i, have, want)
+		}
+		for _, key := range []common.Hash{{byte(i)}, {100}} {
+			if have, want := fromSnap.GetState(addr, key), fromTrie.GetState(addr, key); have != want {
+				t.Errorf("account %d: slot %x mismatch: have %x, want %x", i, key, have, want)
+			}
+		}
+	}
+	if fromTrie.GetState(addrs[3], common.Hash{3}) != (common.Hash{}) {
+		t.Errorf("storage of recreated account present")
+	}
+}
@@ -0,0 +1,71 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"bytes"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// SizeDelta is the change of the storage and code of a contract made by the
+// state transitions applied to the tries.
+type SizeDelta struct {
+	Slots      int64 // Storage slots set less the ones cleared
+	Bytes      int64 // Storage trie bytes (hashed keys and encoded values) added less the ones removed
+	Code       int   // Size of the code deployed, if CodeSet
+	CodeSet    bool  // Whether code was deployed
+	Destructed bool  // Whether the previous storage and code of the contract are gone
+}
+
+// SizeDeltas returns the changes of the storage and code of the contracts
+// applied to the tries so far, by IntermediateRoot or Commit.
+func (self *StateDB) SizeDeltas() map[common.Address]*SizeDelta {
+	return self.sizeDeltas
+}
+
+// sizeDelta returns the size change of a contract, created if none.
+func (self *StateDB) sizeDelta(addr common.Address) *SizeDelta {
+	delta := self.sizeDeltas[addr]
+	if delta == nil {
+		delta = new(SizeDelta)
+		self.sizeDeltas[addr] = delta
+	}
+	return delta
+}
+
+// recordStorageChange accounts for a storage slot of a contract changed from
+// prev to value in its storage trie.
+func (self *StateDB) recordStorageChange(addr common.Address, prev, value common.Hash) {
+	delta := self.sizeDelta(addr)
+	if prev != (common.Hash{}) {
+		delta.Slots--
+		delta.Bytes -= storageEntrySize(prev)
+	}
+	if value != (common.Hash{}) {
+		delta.Slots++
+		delta.Bytes += storageEntrySize(value)
+	}
+}
+
+// storageEntrySize returns the size of the storage trie leaf of a non-zero
+// value, its hashed key and its encoded value.
+func storageEntrySize(value common.Hash) int64 {
+	enc, _ := rlp.EncodeToBytes(bytes.TrimLeft(value[:], "\x00"))
+	return int64(common.HashLength + len(enc))
+}
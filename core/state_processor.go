@@ -17,6 +17,9 @@
 package core
 
 import (
+	"bytes"
+	"math/big"
+
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/consensus"
 	"github.com/ethereum/go-ethereum/consensus/misc"
@@ -63,20 +66,33 @@ func (p *StateProcessor) Process(block *types.Block, statedb, privateState *stat
 		gp       = new(GasPool).AddGas(block.GasLimit())
 
 		privateReceipts types.Receipts
+
+		chain = importChain{p.bc} // Quorum: the ancestors may be in the import pipeline
+
+		accessed state.StateAccesses // Quorum: public state accessed by the public transactions
 	)
 	// Mutate the block and state according to any hard-fork specs
 	if p.config.DAOForkSupport && p.config.DAOForkBlock != nil && p.config.DAOForkBlock.Cmp(block.Number()) == 0 {
 		misc.ApplyDAOHardFork(statedb)
 	}
+	// Quorum
+	if p.config.IsDeterministicDeploymentBlock(block.Number()) {
+		misc.ApplyDeterministicDeployment(statedb)
+		misc.ApplyDeterministicDeployment(privateState)
+	}
 	// Iterate over and process the individual transactions
 	for i, tx := range block.Transactions() {
 		statedb.Prepare(tx.Hash(), block.Hash(), i)
 		privateState.Prepare(tx.Hash(), block.Hash(), i)
 
-		receipt, privateReceipt, _, err := ApplyTransaction(p.config, p.bc, nil, gp, statedb, privateState, header, tx, usedGas, cfg)
+		accesses := statedb.Accesses()
+		receipt, privateReceipt, _, err := ApplyTransaction(p.config, chain, nil, gp, statedb, privateState, header, tx, usedGas, cfg)
 		if err != nil {
 			return nil, nil, nil, 0, err
 		}
+		if !tx.IsPrivate() {
+			accessed = accessed.Add(statedb.Accesses().Sub(accesses))
+		}
 		receipts = append(receipts, receipt)
 		allLogs = append(allLogs, receipt.Logs...)
 
@@ -87,17 +103,81 @@ func (p *StateProcessor) Process(block *types.Block, statedb, privateState *stat
 			allLogs = append(allLogs, privateReceipt.Logs...)
 		}
 	}
+	// Quorum - the public transactions must stay within the state access limits
+	if limits := p.config.StateAccessLimitsAt(header.Number); limits != nil && ExceedsStateAccessLimits(accessed, limits) {
+		return nil, nil, nil, 0, ErrStateAccessLimitReached
+	}
 	// Finalize the block, applying any consensus engine specific extras (e.g. block rewards)
-	p.engine.Finalize(p.bc, header, statedb, block.Transactions(), block.Uncles(), receipts)
+	p.engine.Finalize(chain, header, statedb, block.Transactions(), block.Uncles(), receipts)
 
 	return receipts, privateReceipts, allLogs, *usedGas, nil
 }
 
+// ExceedsStateAccessLimits returns whether the accessed state exceeds any of
+// the non-zero limits.
+//
+// Only the public transactions are counted: the public state read by private
+// transactions differs between their parties and the other nodes.
+func ExceedsStateAccessLimits(accesses state.StateAccesses, limits *params.StateAccessLimitsConfig) bool {
+	return (limits.MaxReads > 0 && accesses.Reads > limits.MaxReads) ||
+		(limits.MaxWrites > 0 && accesses.Writes > limits.MaxWrites) ||
+		(limits.MaxCreatedAccounts > 0 && accesses.CreatedAccounts > limits.MaxCreatedAccounts)
+}
+
+// CheckStateAccessLimits returns the public state accessed by the public
+// transactions of a block once it includes the given one, which the state
+// accessed so far excludes. The transaction is run on a copy of the public
+// state, failing with ErrStateAccessLimitReached if it would make the block
+// exceed the limits, so that the miners can skip it before applying it. The
+// private transactions don't count, and are not run.
+func CheckStateAccessLimits(config *params.ChainConfig, bc ChainContext, author *common.Address, gp *GasPool, statedb *state.StateDB, header *types.Header, tx *types.Transaction, accessed state.StateAccesses, cfg vm.Config) (state.StateAccesses, error) {
+	limits := config.StateAccessLimitsAt(header.Number)
+	if limits == nil || tx.IsPrivate() {
+		return accessed, nil
+	}
+	var (
+		copied  = statedb.Copy()
+		pool    = *gp
+		usedGas = header.GasUsed
+	)
+	if _, _, _, err := ApplyTransaction(config, bc, author, &pool, copied, copied, header, tx, &usedGas, cfg); err != nil {
+		return accessed, err
+	}
+	accessed = accessed.Add(copied.Accesses().Sub(statedb.Accesses()))
+	if ExceedsStateAccessLimits(accessed, limits) {
+		return accessed, ErrStateAccessLimitReached
+	}
+	return accessed, nil
+}
+
+// revertSelector is the selector of Error(string), which the reasons given to
+// REVERT are encoded as.
+var revertSelector = crypto.Keccak256([]byte("Error(string)"))[:4]
+
+// revertReason decodes the reason returned by a reverted execution, or returns
+// an empty string if it reverted without one.
+func revertReason(ret []byte) string {
+	if len(ret) < 4+64 || !bytes.Equal(ret[:4], revertSelector) {
+		return ""
+	}
+	data := ret[4:]
+	offset := new(big.Int).SetBytes(data[:32])
+	if !offset.IsUint64() || offset.Uint64() > uint64(len(data)-32) {
+		return ""
+	}
+	start := offset.Uint64() + 32
+	size := new(big.Int).SetBytes(data[start-32 : start])
+	if !size.IsUint64() || size.Uint64() > uint64(len(data))-start {
+		return ""
+	}
+	return string(data[start : start+size.Uint64()])
+}
+
 // ApplyTransaction attempts to apply a transaction to the given state database
 // and uses the input parameters for its environment. It returns the receipt
 // for the transaction, gas used and an error if the transaction failed,
 // indicating the block was invalid.
-func ApplyTransaction(config *params.ChainConfig, bc *BlockChain, author *common.Address, gp *GasPool, statedb, privateState *state.StateDB, header *types.Header, tx *types.Transaction, usedGas *uint64, cfg vm.Config) (*types.Receipt, *types.Receipt, uint64, error) {
+func ApplyTransaction(config *params.ChainConfig, bc ChainContext, author *common.Address, gp *GasPool, statedb, privateState *state.StateDB, header *types.Header, tx *types.Transaction, usedGas *uint64, cfg vm.Config) (*types.Receipt, *types.Receipt, uint64, error) {
 	if !config.IsQuorum || !tx.IsPrivate() {
 		privateState = statedb
 	}
@@ -105,6 +185,12 @@ func ApplyTransaction(config *params.ChainConfig, bc *BlockChain, author *common
 	if config.IsQuorum && tx.GasPrice() != nil && tx.GasPrice().Cmp(common.Big0) > 0 {
 		return nil, nil, 0, ErrInvalidGasPrice
 	}
+	if err := validateReplayProtection(config, header.Number, tx); err != nil {
+		return nil, nil, 0, err
+	}
+	if err := validateDocumentAnchor(config, header.Number, tx); err != nil {
+		return nil, nil, 0, err
+	}
 
 	msg, err := tx.AsMessage(types.MakeSigner(config, header.Number))
 	if err != nil {
@@ -117,7 +203,7 @@ func ApplyTransaction(config *params.ChainConfig, bc *BlockChain, author *common
 	vmenv := vm.NewEVM(context, statedb, privateState, config, cfg)
 
 	// Apply the transaction to the current state (included in the env)
-	_, gas, failed, err := ApplyMessage(vmenv, msg, gp)
+	ret, gas, failed, err := ApplyMessage(vmenv, msg, gp)
 	if err != nil {
 		return nil, nil, 0, err
 	}
@@ -164,6 +250,9 @@ func ApplyTransaction(config *params.ChainConfig, bc *BlockChain, author *common
 
 		privateReceipt.Logs = privateState.GetLogs(tx.Hash())
 		privateReceipt.Bloom = types.CreateBloom(types.Receipts{privateReceipt})
+		if failed {
+			privateReceipt.RevertReason = revertReason(ret)
+		}
 	}
 
 	return receipt, privateReceipt, gas, err
@@ -0,0 +1,260 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/private"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+func TestStateAccessLimits(t *testing.T) {
+	var (
+		key, _   = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		sender   = crypto.PubkeyToAddress(key.PublicKey)
+		contract = common.HexToAddress("0xc0de")
+		// Writes the slots 0 and 1
+		code = common.FromHex("6001600055600160015500")
+	)
+	apply := func(limits *params.StateAccessLimitsConfig) (*state.StateDB, error) {
+		config := *params.TestChainConfig
+		config.StateAccessLimits = limits
+
+		statedb, _ := state.New(common.Hash{}, state.NewDatabase(ethdb.NewMemDatabase()))
+		statedb.SetBalance(sender, big.NewInt(params.Ether))
+		statedb.SetCode(contract, code)
+		statedb.SetAccesses(state.StateAccesses{})
+
+		tx, _ := types.SignTx(types.NewTransaction(0, contract, new(big.Int), 100000, new(big.Int), nil), types.HomesteadSigner{}, key)
+		header := &types.Header{Number: big.NewInt(1), GasLimit: 1000000, Difficulty: new(big.Int), Time: new(big.Int)}
+		gp := new(GasPool).AddGas(header.GasLimit)
+		_, _, _, err := ApplyTransaction(&config, nil, &common.Address{}, gp, statedb, statedb, header, tx, &header.GasUsed, vm.Config{})
+		return statedb, err
+	}
+	// Single transactions count their accesses, the limits apply to the blocks
+	for _, limits := range []*params.StateAccessLimitsConfig{
+		nil,
+		{Block: big.NewInt(0), MaxWrites: 2},
+		{Block: big.NewInt(0), MaxWrites: 1},
+	} {
+		statedb, err := apply(limits)
+		if err != nil {
+			t.Fatalf("limits %+v: transaction rejected: %v", limits, err)
+		}
+		if have := statedb.Accesses(); have.Writes != 2 {
+			t.Errorf("limits %+v: write count mismatch: have %d, want 2", limits, have.Writes)
+		}
+	}
+	// The miners check the transactions on a copy of the state before applying them
+	config := *params.TestChainConfig
+	config.StateAccessLimits = &params.StateAccessLimitsConfig{Block: big.NewInt(0), MaxWrites: 3}
+
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(ethdb.NewMemDatabase()))
+	statedb.SetBalance(sender, big.NewInt(params.Ether))
+	statedb.SetCode(contract, code)
+	statedb.SetAccesses(state.StateAccesses{})
+
+	tx, _ := types.SignTx(types.NewTransaction(0, contract, new(big.Int), 100000, new(big.Int), nil), types.HomesteadSigner{}, key)
+	header := &types.Header{Number: big.NewInt(1), GasLimit: 1000000, Difficulty: new(big.Int), Time: new(big.Int)}
+	gp := new(GasPool).AddGas(header.GasLimit)
+
+	accessed, err := CheckStateAccessLimits(&config, nil, &common.Address{}, gp, statedb, header, tx, state.StateAccesses{}, vm.Config{})
+	if err != nil || accessed.Writes != 2 {
+		t.Fatalf("transaction within limits: have %+v, %v", accessed, err)
+	}
+	if _, err := CheckStateAccessLimits(&config, nil, &common.Address{}, gp, statedb, header, tx, accessed, vm.Config{}); err != ErrStateAccessLimitReached {
+		t.Fatalf("error mismatch: have %v, want %v", err, ErrStateAccessLimitReached)
+	}
+	if statedb.GetNonce(sender) != 0 || statedb.Accesses() != (state.StateAccesses{}) || gp.Gas() != header.GasLimit || header.GasUsed != 0 {
+		t.Errorf("checked transaction applied to the state")
+	}
+	// Only the non-zero limits exceeded are reported
+	accesses := state.StateAccesses{Reads: 10, Writes: 2}
+	for _, tt := range []struct {
+		limits params.StateAccessLimitsConfig
+		want   bool
+	}{
+		{params.StateAccessLimitsConfig{}, false},
+		{params.StateAccessLimitsConfig{MaxReads: 10, MaxWrites: 2}, false},
+		{params.StateAccessLimitsConfig{MaxWrites: 1}, true},
+		{params.StateAccessLimitsConfig{MaxReads: 9}, true},
+	} {
+		if have := ExceedsStateAccessLimits(accesses, &tt.limits); have != tt.want {
+			t.Errorf("limits %+v: exceeded mismatch: have %v, want %v", tt.limits, have, tt.want)
+		}
+	}
+}
+
+// Tests that the blocks whose public transactions exceed the state access limits
+// are rejected on import.
+func TestStateAccessLimitsImport(t *testing.T) {
+	var (
+		key, _   = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		address  = crypto.PubkeyToAddress(key.PublicKey)
+		contract = common.HexToAddress("0xc0de")
+	)
+	for _, tt := range []struct {
+		maxWrites uint64
+		err       error
+	}{
+		{2, nil},
+		{1, ErrStateAccessLimitReached},
+	} {
+		db := ethdb.NewMemDatabase()
+		config := *params.TestChainConfig
+		config.StateAccessLimits = &params.StateAccessLimitsConfig{Block: big.NewInt(0), MaxWrites: tt.maxWrites}
+
+		// Writes the slots 0 and 1
+		gspec := &Genesis{Config: &config, Alloc: GenesisAlloc{
+			address:  {Balance: big.NewInt(params.Ether)},
+			contract: {Code: common.FromHex("6001600055600160015500"), Balance: new(big.Int)},
+		}}
+		genesis := gspec.MustCommit(db)
+		blocks, _ := GenerateChain(gspec.Config, genesis, ethash.NewFaker(), db, 1, func(i int, block *BlockGen) {
+			tx, _ := types.SignTx(types.NewTransaction(block.TxNonce(address), contract, new(big.Int), 100000, new(big.Int), nil), types.HomesteadSigner{}, key)
+			block.AddTx(tx)
+		})
+		blockchain, _ := NewBlockChain(db, nil, gspec.Config, ethash.NewFaker(), vm.Config{}, nil)
+		if _, err := blockchain.InsertChain(blocks); err != tt.err {
+			t.Errorf("max writes %d: import error mismatch: have %v, want %v", tt.maxWrites, err, tt.err)
+		}
+		blockchain.Stop()
+	}
+}
+
+func TestDeterministicDeployment(t *testing.T) {
+	var (
+		db      = ethdb.NewMemDatabase()
+		key, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		address = crypto.PubkeyToAddress(key.PublicKey)
+		config  = *params.TestChainConfig
+		// Deploys a contract returning 42
+		initCode = common.FromHex("600a600c600039600a6000f3602a60005260206000f3")
+		salt     = common.HexToHash("0x01")
+	)
+	config.ConstantinopleBlock = big.NewInt(0)
+	config.DeterministicDeploymentBlock = big.NewInt(2)
+
+	gspec := &Genesis{Config: &config, Alloc: GenesisAlloc{address: {Balance: big.NewInt(params.Ether)}}}
+	genesis := gspec.MustCommit(db)
+	blocks, _ := GenerateChain(gspec.Config, genesis, ethash.NewFaker(), db, 2, func(i int, block *BlockGen) {
+		if i == 1 {
+			tx, _ := types.SignTx(types.NewTransaction(block.TxNonce(address), params.DeterministicDeploymentProxy, new(big.Int), 200000, new(big.Int), append(salt.Bytes(), initCode...)), types.HomesteadSigner{}, key)
+			block.AddTx(tx)
+		}
+	})
+	blockchain, _ := NewBlockChain(db, nil, gspec.Config, ethash.NewFaker(), vm.Config{}, nil)
+	defer blockchain.Stop()
+
+	if _, err := blockchain.InsertChain(blocks[:1]); err != nil {
+		t.Fatalf("failed to insert block 1: %v", err)
+	}
+	if pub, _, _ := blockchain.State(); pub.GetCodeSize(params.DeterministicDeploymentProxy) != 0 {
+		t.Fatalf("proxy installed before its activation block")
+	}
+	if _, err := blockchain.InsertChain(blocks[1:]); err != nil {
+		t.Fatalf("failed to insert block 2: %v", err)
+	}
+	pub, priv, _ := blockchain.State()
+	for name, statedb := range map[string]*state.StateDB{"public": pub, "private": priv} {
+		if code := statedb.GetCode(params.DeterministicDeploymentProxy); !bytes.Equal(code, params.DeterministicDeploymentProxyCode) {
+			t.Errorf("%s state: proxy code mismatch: have %x", name, code)
+		}
+	}
+	deployed := crypto.CreateAddress2(params.DeterministicDeploymentProxy, salt, crypto.Keccak256(initCode))
+	if code := pub.GetCode(deployed); !bytes.Equal(code, initCode[12:]) {
+		t.Errorf("deployed code mismatch: have %x, want %x", code, initCode[12:])
+	}
+}
+
+// Tests that the reason a private transaction reverted with is stored in its
+// private receipt only.
+func TestPrivateRevertReason(t *testing.T) {
+	var (
+		key, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		config = *params.TestChainConfig
+		reason = "insufficient allowance"
+	)
+	config.IsQuorum = true
+
+	// Deploys a contract reverting with the reason
+	payload := append(common.CopyBytes(revertSelector), common.LeftPadBytes([]byte{0x20}, 32)...)
+	payload = append(payload, common.LeftPadBytes([]byte{byte(len(reason))}, 32)...)
+	payload = append(payload, common.RightPadBytes([]byte(reason), 32)...)
+	var code []byte
+	for i := 0; i < len(payload); i += 32 {
+		code = append(code, byte(vm.PUSH32))
+		code = append(code, common.RightPadBytes(payload[i:], 32)[:32]...)
+		code = append(code, byte(vm.PUSH1), byte(i), byte(vm.MSTORE))
+	}
+	code = append(code, byte(vm.PUSH1), byte(len(payload)), byte(vm.PUSH1), 0, byte(vm.REVERT))
+
+	saved := private.P
+	defer func() { private.P = saved }()
+	private.P = &StubPrivateTransactionManager{responses: map[string][]interface{}{"Receive": {code, nil}}}
+
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(ethdb.NewMemDatabase()))
+	privateState, _ := state.New(common.Hash{}, state.NewDatabase(ethdb.NewMemDatabase()))
+	tx, _ := types.SignTx(types.NewContractCreation(0, new(big.Int), 1000000, new(big.Int), common.FromHex("0xc0de")), types.HomesteadSigner{}, key)
+	tx.SetPrivate()
+
+	header := &types.Header{Number: big.NewInt(1), GasLimit: 10000000, Difficulty: new(big.Int), Time: new(big.Int)}
+	gp := new(GasPool).AddGas(header.GasLimit)
+	receipt, privateReceipt, _, err := ApplyTransaction(&config, nil, &common.Address{}, gp, statedb, privateState, header, tx, &header.GasUsed, vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to apply transaction: %v", err)
+	}
+	if receipt.Status != types.ReceiptStatusSuccessful || receipt.RevertReason != "" {
+		t.Errorf("public receipt mismatch: status %d, revert reason %q", receipt.Status, receipt.RevertReason)
+	}
+	if privateReceipt.Status != types.ReceiptStatusFailed || privateReceipt.RevertReason != reason {
+		t.Errorf("private receipt mismatch: status %d, revert reason %q", privateReceipt.Status, privateReceipt.RevertReason)
+	}
+	// The reason survives storage, which keeps the legacy encoding without one
+	blob, err := rlp.EncodeToBytes((*types.ReceiptForStorage)(privateReceipt))
+	if err != nil {
+		t.Fatalf("failed to encode receipt: %v", err)
+	}
+	stored := new(types.ReceiptForStorage)
+	if err := rlp.DecodeBytes(blob, stored); err != nil {
+		t.Fatalf("failed to decode receipt: %v", err)
+	}
+	if stored.RevertReason != reason {
+		t.Errorf("stored revert reason mismatch: have %q, want %q", stored.RevertReason, reason)
+	}
+	legacy, _ := rlp.EncodeToBytes((*types.ReceiptForStorage)(receipt))
+	if err := rlp.DecodeBytes(legacy, stored); err != nil || stored.RevertReason != "" {
+		t.Errorf("legacy receipt mismatch: revert reason %q, err %v", stored.RevertReason, err)
+	}
+	// Reverts without a well-formed reason have none
+	for _, ret := range [][]byte{nil, payload[:4+64], append([]byte{0x01}, payload[1:]...)} {
+		if have := revertReason(ret); have != "" {
+			t.Errorf("revert reason of %x mismatch: have %q, want none", ret, have)
+		}
+	}
+}
@@ -210,6 +210,11 @@ func (st *StateTransition) TransitionDb() (ret []byte, usedGas uint64, failed bo
 	if msg, ok := msg.(PrivateMessage); ok && isQuorum && msg.IsPrivate() {
 		isPrivate = true
 		data, err = private.P.Receive(st.data)
+		// Quorum - a party failing to resolve the payload can't go on as if it
+		// wasn't one, the block is failed instead for its processing to be retried
+		if _, ok := err.(*private.OffloadError); ok {
+			return nil, 0, false, err
+		}
 		// Increment the public account nonce if:
 		// 1. Tx is private and *not* a participant of the group and either call or create
 		// 2. Tx is private we are part of the group and is a call
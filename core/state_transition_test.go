@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"github.com/ethereum/go-ethereum/private"
+	"github.com/ethereum/go-ethereum/private/privatetransactionmanager"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/state"
@@ -103,6 +104,58 @@ func (spm *StubPrivateTransactionManager) SendSignedTx(data []byte, to []string)
 	return nil, fmt.Errorf("to be implemented")
 }
 
+func (spm *StubPrivateTransactionManager) StoreRaw(data []byte, from string) ([]byte, error) {
+	return nil, fmt.Errorf("to be implemented")
+}
+
+func (spm *StubPrivateTransactionManager) Participants(data []byte) ([]string, error) {
+	return nil, fmt.Errorf("to be implemented")
+}
+
+func (spm *StubPrivateTransactionManager) Delete(data []byte) error {
+	return fmt.Errorf("to be implemented")
+}
+
+func (spm *StubPrivateTransactionManager) PendingDistributions() []privatetransactionmanager.PendingDistribution {
+	return nil
+}
+
+func (spm *StubPrivateTransactionManager) Close() error {
+	return nil
+}
+
+func (spm *StubPrivateTransactionManager) Notifications(since uint64) ([]privatetransactionmanager.Notification, error) {
+	return nil, fmt.Errorf("to be implemented")
+}
+
+func (spm *StubPrivateTransactionManager) CreatePrivacyGroup(from, name, description string, members []string) (*privatetransactionmanager.PrivacyGroup, error) {
+	return nil, fmt.Errorf("to be implemented")
+}
+
+func (spm *StubPrivateTransactionManager) FindPrivacyGroups(members []string) ([]*privatetransactionmanager.PrivacyGroup, error) {
+	return nil, fmt.Errorf("to be implemented")
+}
+
+func (spm *StubPrivateTransactionManager) RetrievePrivacyGroup(id string) (*privatetransactionmanager.PrivacyGroup, error) {
+	return nil, fmt.Errorf("to be implemented")
+}
+
+func (spm *StubPrivateTransactionManager) ResolvePrivacyGroup(id string) (*privatetransactionmanager.PrivacyGroup, error) {
+	return nil, fmt.Errorf("to be implemented")
+}
+
+func (spm *StubPrivateTransactionManager) AddToPrivacyGroup(from, id string, members []string) (*privatetransactionmanager.PrivacyGroup, error) {
+	return nil, fmt.Errorf("to be implemented")
+}
+
+func (spm *StubPrivateTransactionManager) RemoveFromPrivacyGroup(from, id string, members []string) (*privatetransactionmanager.PrivacyGroup, error) {
+	return nil, fmt.Errorf("to be implemented")
+}
+
+func (spm *StubPrivateTransactionManager) DeletePrivacyGroup(from, id string) error {
+	return fmt.Errorf("to be implemented")
+}
+
 func (spm *StubPrivateTransactionManager) Receive(data []byte) ([]byte, error) {
 	res := spm.responses["Receive"]
 	if err, ok := res[1].(error); ok {
@@ -0,0 +1,424 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package statesize keeps counters of the storage slots, code size and storage
+// trie bytes of each contract of the public state, updated as blocks become
+// canonical, to identify the applications growing the shared state the most.
+//
+// The size changes of every imported block are journaled by block hash, and
+// only applied to the counters once the block is canonical. Applying a block
+// records the counters it changed, for a reorg or a rewind to revert them, as
+// long as the block is less than journalDepth blocks deep.
+//
+// The counters start from zero when the accounting is enabled, so the storage
+// of a contract predating it is missing from its counters, which are flagged
+// partial until the contract is destructed.
+package statesize
+
+import (
+	"encoding/binary"
+	"sort"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+const (
+	// pageSize is the number of contract addresses the list of the contracts
+	// with counters is stored by.
+	pageSize = 1024
+
+	// journalDepth is the number of blocks the size changes and the reverts of
+	// are kept for.
+	journalDepth = 128
+)
+
+var (
+	contractPrefix = []byte("Ssc") // contractPrefix + address -> RLP counters
+	pagePrefix     = []byte("Ssp") // pagePrefix + page (uint64 big endian) -> addresses
+	deltaPrefix    = []byte("Ssd") // deltaPrefix + block hash -> RLP size changes
+	undoPrefix     = []byte("Ssu") // undoPrefix + block hash -> RLP counters before the block
+	heightPrefix   = []byte("Ssh") // heightPrefix + number (uint64 big endian) -> hashes of the blocks journaled
+
+	tailKey = []byte("StateSizeTail") // First block accounted for
+	headKey = []byte("StateSizeHead") // Last block accounted for
+)
+
+// ContractSize are the size counters of a contract.
+type ContractSize struct {
+	Address common.Address `json:"address"`
+	Slots   uint64         `json:"slots"`   // Non-zero storage slots
+	Code    uint64         `json:"code"`    // Size of the code
+	Bytes   uint64         `json:"bytes"`   // Storage trie bytes (hashed keys and encoded values) and code
+	Updated uint64         `json:"updated"` // Last block changing the counters
+	Partial bool           `json:"partial"` // Whether the contract predates the accounting, its earlier storage uncounted
+}
+
+// journalEntry is the size change of a contract made by a block, the signed
+// counts stored in two's complement.
+type journalEntry struct {
+	Address    common.Address
+	Slots      uint64
+	Bytes      uint64
+	Code       uint64
+	CodeSet    bool
+	Destructed bool
+}
+
+// undoEntry are the counters of a contract before a block changed them.
+type undoEntry struct {
+	Address common.Address
+	Present bool // Whether the contract had counters
+	Size    ContractSize
+}
+
+// Index holds the size counters of the contracts, stored in a database and
+// cached in memory for the reports.
+type Index struct {
+	db ethdb.Database
+
+	lock   sync.RWMutex
+	sizes  map[common.Address]*ContractSize
+	order  []common.Address        // Contracts in the order stored in the pages
+	listed map[common.Address]bool // Contracts stored in the pages, with counters or reverted
+}
+
+// New opens the size counters of the database.
+func New(db ethdb.Database) *Index {
+	return &Index{
+		db:     db,
+		sizes:  make(map[common.Address]*ContractSize),
+		listed: make(map[common.Address]bool),
+	}
+}
+
+// Open loads the counters and prepares them for the blocks imported on top of
+// the given head.
+func (idx *Index) Open(head uint64) error {
+	for page := uint64(0); ; page++ {
+		blob, err := idx.db.Get(pageKey(page))
+		if err != nil {
+			break
+		}
+		var addrs []common.Address
+		if err := rlp.DecodeBytes(blob, &addrs); err != nil {
+			return err
+		}
+		for _, addr := range addrs {
+			idx.order = append(idx.order, addr)
+			idx.listed[addr] = true
+
+			blob, err := idx.db.Get(contractKey(addr))
+			if err != nil {
+				continue // Counters reverted away
+			}
+			size := new(ContractSize)
+			if err := rlp.DecodeBytes(blob, size); err != nil {
+				return err
+			}
+			size.Address = addr
+			idx.sizes[addr] = size
+		}
+	}
+	if tail, ok := idx.Tail(); ok {
+		if last := readNumber(idx.db, headKey); last < head {
+			log.Warn("State size counters miss blocks imported while disabled", "tail", tail, "missing", head-last)
+		}
+		log.Info("Opened state size counters", "tail", tail, "contracts", len(idx.sizes))
+		return nil
+	}
+	batch := idx.db.NewBatch()
+	writeNumber(batch, tailKey, head+1)
+	writeNumber(batch, headKey, head)
+	return batch.Write()
+}
+
+// Tail returns the first block accounted for, and false if the counters were
+// never opened.
+func (idx *Index) Tail() (uint64, bool) {
+	if ok, _ := idx.db.Has(tailKey); !ok {
+		return 0, false
+	}
+	return readNumber(idx.db, tailKey), true
+}
+
+// Record journals the size changes of an imported block, canonical or not, for
+// Apply to count them once the block is canonical. The journal of the blocks
+// journalDepth blocks below is dropped.
+func (idx *Index) Record(hash common.Hash, number uint64, deltas map[common.Address]*state.SizeDelta) error {
+	idx.lock.Lock()
+	defer idx.lock.Unlock()
+
+	entries := make([]journalEntry, 0, len(deltas))
+	for addr, delta := range deltas {
+		entries = append(entries, journalEntry{
+			Address:    addr,
+			Slots:      uint64(delta.Slots),
+			Bytes:      uint64(delta.Bytes),
+			Code:       uint64(delta.Code),
+			CodeSet:    delta.CodeSet,
+			Destructed: delta.Destructed,
+		})
+	}
+	blob, err := rlp.EncodeToBytes(entries)
+	if err != nil {
+		return err
+	}
+	batch := idx.db.NewBatch()
+	batch.Put(journalKey(deltaPrefix, hash), blob)
+
+	hashes := idx.journaled(number)
+	for _, journaled := range hashes {
+		if journaled == hash {
+			return batch.Write()
+		}
+	}
+	if blob, err = rlp.EncodeToBytes(append(hashes, hash)); err != nil {
+		return err
+	}
+	batch.Put(heightKey(number), blob)
+
+	if number >= journalDepth {
+		old := number - journalDepth
+		for _, hash := range idx.journaled(old) {
+			batch.Delete(journalKey(deltaPrefix, hash))
+			batch.Delete(journalKey(undoPrefix, hash))
+		}
+		batch.Delete(heightKey(old))
+	}
+	return batch.Write()
+}
+
+// Apply counts the size changes journaled for a block made canonical, unless
+// already counted, recording the counters it changes for Revert.
+func (idx *Index) Apply(hash common.Hash, number uint64) error {
+	idx.lock.Lock()
+	defer idx.lock.Unlock()
+
+	if ok, _ := idx.db.Has(journalKey(undoPrefix, hash)); ok {
+		return nil
+	}
+	blob, err := idx.db.Get(journalKey(deltaPrefix, hash))
+	if err != nil {
+		log.Warn("State size changes of canonical block missing", "number", number, "hash", hash)
+		return nil
+	}
+	var entries []journalEntry
+	if err := rlp.DecodeBytes(blob, &entries); err != nil {
+		return err
+	}
+	var (
+		batch = idx.db.NewBatch()
+		first = len(idx.order)
+		undo  = make([]undoEntry, 0, len(entries))
+	)
+	for _, entry := range entries {
+		addr := entry.Address
+		size := idx.sizes[addr]
+		if size == nil {
+			if entry.Destructed && entry.Slots == 0 && !entry.CodeSet {
+				continue // Never tracked
+			}
+			undo = append(undo, undoEntry{Address: addr})
+
+			// Storage changed without the code deployed predates the accounting
+			size = &ContractSize{Address: addr, Partial: !entry.CodeSet && !entry.Destructed}
+			idx.sizes[addr] = size
+			if !idx.listed[addr] {
+				idx.order = append(idx.order, addr)
+				idx.listed[addr] = true
+			}
+		} else {
+			undo = append(undo, undoEntry{Address: addr, Present: true, Size: *size})
+		}
+		if entry.Destructed {
+			size.Slots, size.Code, size.Bytes, size.Partial = 0, 0, 0, false
+		}
+		storage := addClamped(size.Bytes-size.Code, int64(entry.Bytes))
+		if entry.CodeSet {
+			size.Code = entry.Code
+		}
+		size.Slots = addClamped(size.Slots, int64(entry.Slots))
+		size.Bytes = size.Code + storage
+		size.Updated = number
+
+		blob, err := rlp.EncodeToBytes(size)
+		if err != nil {
+			return err
+		}
+		batch.Put(contractKey(addr), blob)
+	}
+	if len(idx.order) > first {
+		// Rewrite the pages holding the new contracts
+		for page := uint64(first) / pageSize; page*pageSize < uint64(len(idx.order)); page++ {
+			end := (page + 1) * pageSize
+			if end > uint64(len(idx.order)) {
+				end = uint64(len(idx.order))
+			}
+			blob, err := rlp.EncodeToBytes(idx.order[page*pageSize : end])
+			if err != nil {
+				return err
+			}
+			batch.Put(pageKey(page), blob)
+		}
+	}
+	if blob, err = rlp.EncodeToBytes(undo); err != nil {
+		return err
+	}
+	batch.Put(journalKey(undoPrefix, hash), blob)
+	writeNumber(batch, headKey, number)
+	return batch.Write()
+}
+
+// Revert restores the counters a canonical block changed, as it is reorganised
+// or rewound out of the chain. The blocks must be reverted from the head down.
+func (idx *Index) Revert(hash common.Hash, number uint64) error {
+	idx.lock.Lock()
+	defer idx.lock.Unlock()
+
+	blob, err := idx.db.Get(journalKey(undoPrefix, hash))
+	if err != nil {
+		if tail := readNumber(idx.db, tailKey); number >= tail {
+			log.Warn("State size counters not reverted, block too deep", "number", number, "hash", hash)
+		}
+		return nil
+	}
+	var undo []undoEntry
+	if err := rlp.DecodeBytes(blob, &undo); err != nil {
+		return err
+	}
+	batch := idx.db.NewBatch()
+	for _, entry := range undo {
+		if !entry.Present {
+			delete(idx.sizes, entry.Address)
+			batch.Delete(contractKey(entry.Address))
+			continue
+		}
+		size := entry.Size
+		idx.sizes[entry.Address] = &size
+
+		blob, err := rlp.EncodeToBytes(&size)
+		if err != nil {
+			return err
+		}
+		batch.Put(contractKey(entry.Address), blob)
+	}
+	batch.Delete(journalKey(undoPrefix, hash))
+	writeNumber(batch, headKey, number-1)
+	return batch.Write()
+}
+
+// journaled returns the hashes of the blocks journaled at a height.
+func (idx *Index) journaled(number uint64) []common.Hash {
+	var hashes []common.Hash
+	if blob, err := idx.db.Get(heightKey(number)); err == nil {
+		rlp.DecodeBytes(blob, &hashes)
+	}
+	return hashes
+}
+
+// Size returns the counters of a contract, nil if it has none.
+func (idx *Index) Size(addr common.Address) *ContractSize {
+	idx.lock.RLock()
+	defer idx.lock.RUnlock()
+
+	if size := idx.sizes[addr]; size != nil {
+		cpy := *size
+		return &cpy
+	}
+	return nil
+}
+
+// Top returns the counters of the n contracts with the most storage trie and
+// code bytes, largest first.
+func (idx *Index) Top(n int) []*ContractSize {
+	idx.lock.RLock()
+	defer idx.lock.RUnlock()
+
+	sizes := make([]*ContractSize, 0, len(idx.sizes))
+	for _, size := range idx.sizes {
+		cpy := *size
+		sizes = append(sizes, &cpy)
+	}
+	sort.Slice(sizes, func(i, j int) bool {
+		if sizes[i].Bytes != sizes[j].Bytes {
+			return sizes[i].Bytes > sizes[j].Bytes
+		}
+		return bytesLess(sizes[i].Address, sizes[j].Address)
+	})
+	if n >= 0 && len(sizes) > n {
+		sizes = sizes[:n]
+	}
+	return sizes
+}
+
+// addClamped adds a signed delta to a counter, without going below zero as
+// the storage predating the accounting may be cleared.
+func addClamped(counter uint64, delta int64) uint64 {
+	if delta < 0 && uint64(-delta) > counter {
+		return 0
+	}
+	return uint64(int64(counter) + delta)
+}
+
+func bytesLess(a, b common.Address) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return a[i] < b[i]
+		}
+	}
+	return false
+}
+
+func contractKey(addr common.Address) []byte {
+	return append(append([]byte{}, contractPrefix...), addr[:]...)
+}
+
+func journalKey(prefix []byte, hash common.Hash) []byte {
+	return append(append([]byte{}, prefix...), hash[:]...)
+}
+
+func heightKey(number uint64) []byte {
+	key := make([]byte, len(heightPrefix)+8)
+	copy(key, heightPrefix)
+	binary.BigEndian.PutUint64(key[len(heightPrefix):], number)
+	return key
+}
+
+func pageKey(page uint64) []byte {
+	key := make([]byte, len(pagePrefix)+8)
+	copy(key, pagePrefix)
+	binary.BigEndian.PutUint64(key[len(pagePrefix):], page)
+	return key
+}
+
+func readNumber(db ethdb.Database, key []byte) uint64 {
+	blob, err := db.Get(key)
+	if err != nil || len(blob) != 8 {
+		return 0
+	}
+	return binary.BigEndian.Uint64(blob)
+}
+
+func writeNumber(db ethdb.Putter, key []byte, number uint64) {
+	var enc [8]byte
+	binary.BigEndian.PutUint64(enc[:], number)
+	db.Put(key, enc[:])
+}
@@ -0,0 +1,161 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package statesize
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+var (
+	testAddr1 = common.BytesToAddress([]byte("contract1"))
+	testAddr2 = common.BytesToAddress([]byte("contract2"))
+	testAddr3 = common.BytesToAddress([]byte("contract3"))
+)
+
+// Tests that the counters add up the size changes of the canonical blocks, reset
+// on destruction, clamp at zero, flag the contracts predating the accounting and
+// survive a reopen.
+func TestIndexApply(t *testing.T) {
+	db := ethdb.NewMemDatabase()
+	idx := New(db)
+	if _, ok := idx.Tail(); ok {
+		t.Fatalf("unopened counters report a tail")
+	}
+	if err := idx.Open(10); err != nil {
+		t.Fatalf("failed to open counters: %v", err)
+	}
+	if tail, _ := idx.Tail(); tail != 11 {
+		t.Fatalf("tail mismatch: have %d, want 11", tail)
+	}
+	blocks := []map[common.Address]*state.SizeDelta{
+		{
+			testAddr1: {Slots: 3, Bytes: 100, Code: 500, CodeSet: true},
+			testAddr2: {Slots: 1, Bytes: 40, Code: 50, CodeSet: true},
+		},
+		{
+			testAddr1: {Slots: -1, Bytes: -30},
+			testAddr2: {Destructed: true},
+			testAddr3: {Slots: -2, Bytes: -70}, // Storage predating the accounting
+		},
+	}
+	for i, deltas := range blocks {
+		hash := common.BytesToHash([]byte{byte(i)})
+		if err := idx.Record(hash, uint64(11+i), deltas); err != nil {
+			t.Fatalf("block %d: failed to record: %v", i, err)
+		}
+		if err := idx.Apply(hash, uint64(11+i)); err != nil {
+			t.Fatalf("block %d: failed to apply: %v", i, err)
+		}
+		// Applying a block twice, as the head of a reorg, counts it once
+		if err := idx.Apply(hash, uint64(11+i)); err != nil {
+			t.Fatalf("block %d: failed to reapply: %v", i, err)
+		}
+	}
+	want := map[common.Address]ContractSize{
+		testAddr1: {Address: testAddr1, Slots: 2, Code: 500, Bytes: 570, Updated: 12},
+		testAddr2: {Address: testAddr2, Updated: 12},
+		testAddr3: {Address: testAddr3, Updated: 12, Partial: true},
+	}
+	check := func(idx *Index) {
+		for addr, size := range want {
+			if have := idx.Size(addr); have == nil || *have != size {
+				t.Errorf("%x: counters mismatch: have %+v, want %+v", addr, have, size)
+			}
+		}
+		top := idx.Top(1)
+		if len(top) != 1 || top[0].Address != testAddr1 {
+			t.Errorf("top contract mismatch: have %+v", top)
+		}
+	}
+	check(idx)
+
+	reopened := New(db)
+	if err := reopened.Open(12); err != nil {
+		t.Fatalf("failed to reopen counters: %v", err)
+	}
+	check(reopened)
+	if tail, _ := reopened.Tail(); tail != 11 {
+		t.Errorf("reopened tail mismatch: have %d, want 11", tail)
+	}
+	if n := len(reopened.Top(-1)); n != 3 {
+		t.Errorf("reopened contract count mismatch: have %d, want 3", n)
+	}
+}
+
+// Tests that the side chain blocks are only counted once canonical, and that a
+// reorg reverts the counters of the blocks it drops.
+func TestIndexReorg(t *testing.T) {
+	idx := New(ethdb.NewMemDatabase())
+	if err := idx.Open(0); err != nil {
+		t.Fatalf("failed to open counters: %v", err)
+	}
+	var (
+		common1 = common.HexToHash("0x01")
+		old2    = common.HexToHash("0x02")
+		new2    = common.HexToHash("0x12")
+	)
+	record := func(hash common.Hash, number uint64, deltas map[common.Address]*state.SizeDelta) {
+		if err := idx.Record(hash, number, deltas); err != nil {
+			t.Fatalf("failed to record block %x: %v", hash, err)
+		}
+	}
+	record(common1, 1, map[common.Address]*state.SizeDelta{
+		testAddr1: {Slots: 1, Bytes: 40, Code: 100, CodeSet: true},
+	})
+	record(old2, 2, map[common.Address]*state.SizeDelta{
+		testAddr1: {Slots: 2, Bytes: 80},
+		testAddr2: {Slots: 1, Bytes: 40, Code: 10, CodeSet: true},
+	})
+	record(new2, 2, map[common.Address]*state.SizeDelta{
+		testAddr1: {Destructed: true},
+	})
+	idx.Apply(common1, 1)
+	idx.Apply(old2, 2)
+
+	if have := idx.Size(testAddr1); have == nil || have.Slots != 3 || have.Bytes != 220 {
+		t.Fatalf("counters before the reorg mismatch: have %+v", have)
+	}
+	// Reorg onto the side chain block, destructing the contract instead
+	if err := idx.Revert(old2, 2); err != nil {
+		t.Fatalf("failed to revert: %v", err)
+	}
+	if have := idx.Size(testAddr1); have == nil || *have != (ContractSize{Address: testAddr1, Slots: 1, Code: 100, Bytes: 140, Updated: 1}) {
+		t.Errorf("reverted counters mismatch: have %+v", have)
+	}
+	if have := idx.Size(testAddr2); have != nil {
+		t.Errorf("contract of the dropped block still counted: %+v", have)
+	}
+	idx.Apply(new2, 2)
+	if have := idx.Size(testAddr1); have == nil || *have != (ContractSize{Address: testAddr1, Updated: 2}) {
+		t.Errorf("counters after the reorg mismatch: have %+v", have)
+	}
+	// Reopening drops the counters of the contract reverted away
+	reopened := New(idx.db)
+	if err := reopened.Open(2); err != nil {
+		t.Fatalf("failed to reopen counters: %v", err)
+	}
+	if have := reopened.Size(testAddr2); have != nil {
+		t.Errorf("reopened counters of reverted contract: %+v", have)
+	}
+	if n := len(reopened.Top(-1)); n != 1 {
+		t.Errorf("reopened contract count mismatch: have %d, want 1", n)
+	}
+}
@@ -0,0 +1,149 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+var (
+	// ErrInvalidAnchor is returned if a transaction sent to the anchor address
+	// of the chain doesn't carry a valid document anchor.
+	ErrInvalidAnchor = errors.New("invalid document anchor")
+
+	// ErrAnchorLimit is returned if the pool already holds as many document
+	// anchors as allowed, for the sender or in total.
+	ErrAnchorLimit = errors.New("document anchor limit reached")
+)
+
+// Metrics for the document anchors
+var anchorRejectCounter = metrics.NewRegisteredCounter("txpool/anchor/rejected", nil)
+
+// AnchorValidator checks a document anchor before its transaction is accepted
+// in the pool, e.g. that its URI points to an approved storage or that its
+// sender may anchor documents of that size.
+type AnchorValidator func(from common.Address, anchor *types.DocumentAnchor) error
+
+// AddAnchorValidator registers a validator run on every document anchor entering
+// the pool, after the anchor was decoded and checked for completeness.
+func (pool *TxPool) AddAnchorValidator(validator AnchorValidator) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	pool.anchorValidators = append(pool.anchorValidators, validator)
+}
+
+// validateAnchor checks a document anchoring transaction: its anchor must be
+// valid and accepted by the registered validators, and the pool must have room
+// for it within the anchor limits. Anchors are public and carry no value.
+// The rejections are metered by the admission check running it.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxPool) validateAnchor(from common.Address, tx *types.Transaction) error {
+	anchor, err := checkAnchor(tx)
+	if err != nil {
+		return err
+	}
+	for _, validator := range pool.anchorValidators {
+		if err := validator(from, anchor); err != nil {
+			return fmt.Errorf("%v: %v", ErrInvalidAnchor, err)
+		}
+	}
+	// Replacements don't take up any additional slots
+	if list := pool.pending[from]; list != nil && list.Overlaps(tx) {
+		return nil
+	}
+	if list := pool.queue[from]; list != nil && list.Overlaps(tx) {
+		return nil
+	}
+	if pool.anchors.accounts[from] >= pool.config.AnchorSlots || pool.anchors.total >= pool.config.GlobalAnchorSlots {
+		return ErrAnchorLimit
+	}
+	return nil
+}
+
+// checkAnchor decodes the anchor of a document anchoring transaction, public
+// and carrying no value.
+func checkAnchor(tx *types.Transaction) (*types.DocumentAnchor, error) {
+	if tx.IsPrivate() || tx.Value().Sign() != 0 {
+		return nil, fmt.Errorf("%v: anchors must be public and carry no value", ErrInvalidAnchor)
+	}
+	anchor, err := tx.DocumentAnchor()
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", ErrInvalidAnchor, err)
+	}
+	return anchor, nil
+}
+
+// Quorum
+//
+// validateDocumentAnchor checks that a transaction of the block num sent to the
+// anchor address of the chain is a valid document anchor, the anchors being a
+// rule of the chain once activated.
+func validateDocumentAnchor(config *params.ChainConfig, num *big.Int, tx *types.Transaction) error {
+	if addr, to := config.DocumentAnchorAddress(num), tx.To(); addr != nil && to != nil && *to == *addr {
+		_, err := checkAnchor(tx)
+		return err
+	}
+	return nil
+}
+
+// anchorIndex counts the pooled document anchors of each sender, for the pool
+// anchor limits.
+type anchorIndex struct {
+	signer   types.Signer
+	address  common.Address            // Recipient of the document anchors
+	accounts map[common.Address]uint64 // Number of pooled anchors by sender
+	total    uint64                    // Number of pooled anchors
+}
+
+func newAnchorIndex(signer types.Signer, address common.Address) *anchorIndex {
+	return &anchorIndex{
+		signer:   signer,
+		address:  address,
+		accounts: make(map[common.Address]uint64),
+	}
+}
+
+// added implements txIndexer, counting a document anchor entering the pool.
+func (idx *anchorIndex) added(tx *types.Transaction) {
+	if to := tx.To(); to == nil || *to != idx.address {
+		return
+	}
+	from, _ := types.Sender(idx.signer, tx) // already validated during insertion
+	idx.accounts[from]++
+	idx.total++
+}
+
+// removed implements txIndexer, counting a document anchor leaving the pool.
+func (idx *anchorIndex) removed(tx *types.Transaction) {
+	if to := tx.To(); to == nil || *to != idx.address {
+		return
+	}
+	from, _ := types.Sender(idx.signer, tx)
+	if idx.accounts[from]--; idx.accounts[from] == 0 {
+		delete(idx.accounts, from)
+	}
+	idx.total--
+}
@@ -0,0 +1,93 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/txtrail"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// maxCancelledTxs is the number of cancelled transaction hashes remembered to
+// refuse them when propagated again.
+const maxCancelledTxs = 4096
+
+var (
+	// ErrTxCancelled is returned if a transaction was cancelled by its sender.
+	ErrTxCancelled = errors.New("transaction cancelled by its sender")
+
+	// ErrCancelUnknownTx is returned if the transaction to cancel isn't in the
+	// pool, either never seen or already included or dropped.
+	ErrCancelUnknownTx = errors.New("transaction to cancel not in the pool")
+
+	// ErrCancelNotSender is returned if a cancellation isn't signed by the sender
+	// of the transaction.
+	ErrCancelNotSender = errors.New("cancellation not signed by the transaction sender")
+)
+
+// CancellationHash returns the hash the sender of a transaction signs to cancel
+// it. The signed message is "cancel:<transaction hash>", hashed the same way as
+// eth_sign so that any standard signer can produce the signature.
+func CancellationHash(txHash common.Hash) common.Hash {
+	msg := fmt.Sprintf("cancel:%s", txHash.Hex())
+	prefixed := fmt.Sprintf("\x19Ethereum Signed Message:\n%d%s", len(msg), msg)
+	return crypto.Keccak256Hash([]byte(prefixed))
+}
+
+// Cancel removes a pending or queued transaction from the pool, given the
+// signature of its sender over CancellationHash, and refuses it from then on.
+// The transactions of the sender queued behind it are moved back to the future
+// queue, until its nonce is taken by another transaction. This allows replacing
+// transactions on networks without gas price, where they can't be outbid.
+func (pool *TxPool) Cancel(hash common.Hash, sig []byte) (*types.Transaction, error) {
+	if len(sig) != 65 {
+		return nil, fmt.Errorf("invalid cancellation signature length %d", len(sig))
+	}
+	raw := common.CopyBytes(sig)
+	if raw[64] >= 27 {
+		raw[64] -= 27 // Accept the legacy Ethereum V values too
+	}
+	pub, err := crypto.SigToPub(CancellationHash(hash).Bytes(), raw)
+	if err != nil {
+		return nil, err
+	}
+	signer := crypto.PubkeyToAddress(*pub)
+
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+	defer pool.flushDiffs()
+
+	tx := pool.all.Get(hash)
+	if tx == nil {
+		return nil, ErrCancelUnknownTx
+	}
+	if from, _ := types.Sender(pool.signer, tx); from != signer {
+		return nil, ErrCancelNotSender
+	}
+	pool.cancelled.Add(hash, struct{}{})
+	pool.removeTx(hash, true)
+	pool.recordDiff(TxDropped, tx, TxDropCancelled, nil)
+	txtrail.Record(hash, txtrail.StageCancelled)
+
+	log.Debug("Cancelled pooled transaction", "hash", hash, "from", signer)
+	return tx, nil
+}
@@ -0,0 +1,267 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+// txCheckContext is the transaction going through the admission checks, with
+// the sender recovered by the signature check for the checks run after it.
+type txCheckContext struct {
+	tx       *types.Transaction
+	from     common.Address
+	local    bool
+	simulate bool // Simulated checks don't update the metrics of the pool
+}
+
+// txAdmissionCheck is one of the checks a transaction must pass to enter the
+// pool, in the order they are run.
+type txAdmissionCheck struct {
+	name     string
+	recovers bool            // Whether the check recovers the sender of the transaction
+	sender   bool            // Whether the check requires the sender of the transaction
+	meter    metrics.Counter // Incremented when the check rejects a transaction, if any
+
+	applies func(pool *TxPool, tx *types.Transaction) bool // Whether the check is run at all, always if nil
+	run     func(pool *TxPool, c *txCheckContext) error
+}
+
+// admissionChecks are the validation checks of a transaction, built once rather
+// than for every transaction validated.
+//
+// Note, the checks assume the pool lock is held!
+var admissionChecks = []txAdmissionCheck{
+	{name: "gasPrice", run: func(pool *TxPool, c *txCheckContext) error {
+		if pool.chainconfig.IsQuorum && c.tx.GasPrice().Cmp(common.Big0) != 0 {
+			return ErrInvalidGasPrice
+		}
+		return nil
+	}},
+	{name: "size", run: func(pool *TxPool, c *txCheckContext) error {
+		sizeLimit := pool.chainconfig.TransactionSizeLimit
+		if sizeLimit == 0 {
+			sizeLimit = DefaultTxPoolConfig.TransactionSizeLimit
+		}
+		// Reject transactions over 32KB (or manually set limit) to prevent DOS attacks
+		if float64(c.tx.Size()) > float64(sizeLimit*1024) {
+			return ErrOversizedData
+		}
+		return nil
+	}},
+	{name: "value", run: func(pool *TxPool, c *txCheckContext) error {
+		// Transactions can't be negative. This may never happen using RLP decoded
+		// transactions but may occur if you create a transaction using the RPC.
+		if c.tx.Value().Sign() < 0 {
+			return ErrNegativeValue
+		}
+		return nil
+	}},
+	{name: "gasLimit", run: func(pool *TxPool, c *txCheckContext) error {
+		// Ensure the transaction doesn't exceed the current block limit gas.
+		if pool.currentMaxGas < c.tx.Gas() {
+			return ErrGasLimit
+		}
+		return nil
+	}},
+	{name: "signature", recovers: true, run: func(pool *TxPool, c *txCheckContext) error {
+		// Make sure the transaction is signed properly
+		var err error
+		if c.from, err = types.Sender(pool.signer, c.tx); err != nil {
+			return ErrInvalidSender
+		}
+		return nil
+	}},
+	{name: "replayProtection", sender: true, run: func(pool *TxPool, c *txCheckContext) error {
+		// Quorum - meter the legacy signatures, and reject them once the chain
+		// requires replay protection
+		if !c.tx.Protected() && !c.tx.IsPrivate() {
+			if !c.simulate {
+				unprotectedTxCounter.Inc(1)
+			}
+			if pool.strictEIP155 {
+				return ErrUnprotectedTransaction
+			}
+		}
+		return nil
+	}},
+	{name: "minGasPrice", sender: true, run: func(pool *TxPool, c *txCheckContext) error {
+		// Drop non-local transactions under our own minimal accepted gas price
+		local := c.local || pool.locals.contains(c.from) // account may be local even if the transaction arrived from the network
+		if !pool.chainconfig.IsQuorum && !local && pool.gasPrice.Cmp(c.tx.GasPrice()) > 0 {
+			return ErrUnderpriced
+		}
+		return nil
+	}},
+	{name: "nonce", sender: true, run: func(pool *TxPool, c *txCheckContext) error {
+		// Ensure the transaction adheres to nonce ordering
+		if pool.currentState.GetNonce(c.from) > c.tx.Nonce() {
+			return ErrNonceTooLow
+		}
+		return nil
+	}},
+	{name: "privateValue", run: func(pool *TxPool, c *txCheckContext) error {
+		// Ether value is not currently supported on private transactions
+		if c.tx.IsPrivate() && (len(c.tx.Data()) == 0 || c.tx.Value().Sign() != 0) {
+			return ErrEtherValueUnsupported
+		}
+		return nil
+	}},
+	{name: "balance", sender: true, run: func(pool *TxPool, c *txCheckContext) error {
+		// Transactor should have enough funds to cover the costs
+		// cost == V + GP * GL
+		if pool.currentState.GetBalance(c.from).Cmp(c.tx.Cost()) < 0 {
+			return ErrInsufficientFunds
+		}
+		return nil
+	}},
+	{name: "intrinsicGas", run: func(pool *TxPool, c *txCheckContext) error {
+		intrGas, err := IntrinsicGas(c.tx.Data(), c.tx.To() == nil, pool.homestead)
+		if err != nil {
+			return err
+		}
+		if c.tx.Gas() < intrGas {
+			return ErrIntrinsicGas
+		}
+		return nil
+	}},
+	// Document anchors are held to their own validation and limits
+	{name: "anchor", sender: true, meter: anchorRejectCounter,
+		applies: func(pool *TxPool, tx *types.Transaction) bool {
+			to := tx.To()
+			return to != nil && pool.anchorAddress != nil && *to == *pool.anchorAddress
+		},
+		run: func(pool *TxPool, c *txCheckContext) error {
+			return pool.validateAnchor(c.from, c.tx)
+		},
+	},
+	// Check if the sender account is authorized to perform the transaction
+	{name: "permission", sender: true, applies: quorumOnly, run: func(pool *TxPool, c *txCheckContext) error {
+		return checkAccount(c.from, c.tx.To())
+	}},
+	{name: "orgQuota", sender: true, applies: quorumOnly, run: func(pool *TxPool, c *txCheckContext) error {
+		return pool.checkOrgQuota(c.from, c.tx)
+	}},
+	// Check the restrictions on the transactions of the sender, whatever their origin
+	{name: "policy", sender: true,
+		applies: func(pool *TxPool, tx *types.Transaction) bool {
+			return pool.policy != nil
+		},
+		run: func(pool *TxPool, c *txCheckContext) error {
+			return pool.policy.CheckTx(c.from, c.tx.To())
+		},
+	},
+}
+
+// quorumOnly restricts an admission check to the Quorum chains.
+func quorumOnly(pool *TxPool, tx *types.Transaction) bool {
+	return pool.chainconfig.IsQuorum
+}
+
+// TxCheckResult is the outcome of one of the admission checks of the pool.
+type TxCheckResult struct {
+	Check   string `json:"check"`
+	Passed  bool   `json:"passed"`
+	Skipped bool   `json:"skipped,omitempty"` // Not run, requiring the sender which couldn't be recovered
+	Error   string `json:"error,omitempty"`
+}
+
+// Check runs all the admission checks of the pool on a transaction without
+// adding it, reporting the outcome of each instead of stopping at the first
+// failure. Besides the validation of the transaction, the checks against the
+// content of the pool are run: whether it is known or was cancelled, whether it
+// can replace the pooled transaction of the same nonce, and whether it can make
+// room for itself in a full pool.
+func (pool *TxPool) Check(tx *types.Transaction, local bool) []TxCheckResult {
+	// The price heap drops its stale entries when queried
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	var (
+		results []TxCheckResult
+		hash    = tx.Hash()
+	)
+	record := func(name string, skipped bool, err error) {
+		result := TxCheckResult{Check: name, Passed: !skipped && err == nil, Skipped: skipped}
+		if err != nil {
+			result.Error = err.Error()
+		}
+		results = append(results, result)
+	}
+	if pool.all.Get(hash) != nil {
+		record("known", false, fmt.Errorf("known transaction: %x", hash))
+	} else {
+		record("known", false, nil)
+	}
+	if pool.cancelled.Contains(hash) {
+		record("cancelled", false, ErrTxCancelled)
+	} else {
+		record("cancelled", false, nil)
+	}
+	// Run the validation, skipping the checks of the sender if it is unknown
+	c := &txCheckContext{tx: tx, local: local, simulate: true}
+	sender := true
+	for _, check := range admissionChecks {
+		if check.applies != nil && !check.applies(pool, tx) {
+			continue
+		}
+		if check.sender && !sender {
+			record(check.name, true, nil)
+			continue
+		}
+		err := check.run(pool, c)
+		if check.recovers {
+			sender = err == nil
+		}
+		record(check.name, false, err)
+	}
+	from := c.from
+	if !sender {
+		record("replacement", true, nil)
+		record("capacity", true, nil)
+		return results
+	}
+	// Replacements must bump the price of the transaction they replace
+	var replaceErr error
+	for _, list := range []*txList{pool.pending[from], pool.queue[from]} {
+		if list == nil {
+			continue
+		}
+		if old := list.txs.Get(tx.Nonce()); old != nil && old.Hash() != hash {
+			threshold := new(big.Int).Div(new(big.Int).Mul(old.GasPrice(), big.NewInt(100+int64(pool.config.PriceBump))), big.NewInt(100))
+			if old.GasPrice().Cmp(tx.GasPrice()) >= 0 || threshold.Cmp(tx.GasPrice()) > 0 {
+				replaceErr = ErrReplaceUnderpriced
+			}
+		}
+	}
+	record("replacement", false, replaceErr)
+
+	// A full pool only takes transactions outbidding its cheapest ones
+	var capacityErr error
+	capacity := pool.limits.globalSlots + pool.limits.globalQueue + pool.lanes.capacity()
+	local = local || pool.locals.contains(from)
+	if uint64(pool.all.Count()) >= capacity && !pool.chainconfig.IsQuorum && !local && pool.priced.Underpriced(tx, pool.locals) {
+		capacityErr = ErrUnderpriced
+	}
+	record("capacity", false, capacityErr)
+	return results
+}
@@ -0,0 +1,52 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// TxPolicy restricts the transactions of some accounts entering the pool,
+// whether sent over RPC, raw or received from the network, e.g. the unlock
+// policies of the account manager.
+type TxPolicy interface {
+	// CheckTx checks a transaction of an account to a recipient, nil for a
+	// contract creation, is allowed, without counting it.
+	CheckTx(from common.Address, to *common.Address) error
+
+	// RecordTx counts a transaction accepted in the pool against the allowances
+	// of its sender, once even if accepted again.
+	RecordTx(from common.Address, hash common.Hash)
+}
+
+// SetTxPolicy sets the restrictions on the transactions entering the pool.
+func (pool *TxPool) SetTxPolicy(policy TxPolicy) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	pool.policy = policy
+}
+
+// recordPolicy counts a transaction accepted in the pool against the policy.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxPool) recordPolicy(from common.Address, tx *types.Transaction) {
+	if pool.policy != nil {
+		pool.policy.RecordTx(from, tx.Hash())
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// testTxPolicy allows a number of transactions per account.
+type testTxPolicy struct {
+	allowance int
+	recorded  map[common.Address][]common.Hash
+}
+
+func (p *testTxPolicy) CheckTx(from common.Address, to *common.Address) error {
+	if len(p.recorded[from]) >= p.allowance {
+		return errors.New("allowance exhausted")
+	}
+	return nil
+}
+
+func (p *testTxPolicy) RecordTx(from common.Address, hash common.Hash) {
+	p.recorded[from] = append(p.recorded[from], hash)
+}
+
+// Tests that the policy restricts the transactions of every origin, and only
+// counts the ones accepted.
+func TestTransactionPolicy(t *testing.T) {
+	t.Parallel()
+
+	pool, key := setupTxPool()
+	defer pool.Stop()
+
+	from := crypto.PubkeyToAddress(key.PublicKey)
+	pool.currentState.AddBalance(from, big.NewInt(1000000))
+
+	policy := &testTxPolicy{allowance: 2, recorded: make(map[common.Address][]common.Hash)}
+	pool.SetTxPolicy(policy)
+
+	// Rejected transactions aren't counted
+	if err := pool.AddRemote(transaction(0, 100, key)); err != ErrIntrinsicGas {
+		t.Fatalf("error mismatch: have %v, want %v", err, ErrIntrinsicGas)
+	}
+	if len(policy.recorded[from]) != 0 {
+		t.Fatalf("rejected transaction counted: %v", policy.recorded[from])
+	}
+	// The local and remote transactions are counted alike
+	if err := pool.AddLocal(transaction(0, 100000, key)); err != nil {
+		t.Fatalf("failed to add local transaction: %v", err)
+	}
+	if err := pool.AddRemote(transaction(1, 100000, key)); err != nil {
+		t.Fatalf("failed to add remote transaction: %v", err)
+	}
+	if len(policy.recorded[from]) != 2 {
+		t.Fatalf("accepted transactions not counted: %v", policy.recorded[from])
+	}
+	if err := pool.AddRemote(transaction(2, 100000, key)); err == nil || err.Error() != "allowance exhausted" {
+		t.Fatalf("transaction over the allowance accepted: %v", err)
+	}
+	if pending, queued := pool.Stats(); pending != 2 || queued != 0 {
+		t.Fatalf("pooled transactions mismatch: have %d/%d, want 2/0", pending, queued)
+	}
+}
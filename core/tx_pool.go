@@ -28,11 +28,13 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/prque"
 	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/txtrail"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/metrics"
 	"github.com/ethereum/go-ethereum/params"
+	"github.com/hashicorp/golang-lru"
 )
 
 const (
@@ -82,6 +84,18 @@ var (
 	// ErrEtherValueUnsupported is returned if a transaction specifies an Ether Value
 	// for a private Quorum transaction.
 	ErrEtherValueUnsupported = errors.New("ether value is not supported for private transactions")
+
+	// ErrOrgQuotaExceeded is returned if the organization of the sender already
+	// uses all the pool slots or per block gas granted by its quota.
+	ErrOrgQuotaExceeded = errors.New("organization quota exceeded")
+
+	// ErrReadOnlyAccount is returned if the sender of a transaction only has
+	// read access in the permissions.
+	ErrReadOnlyAccount = errors.New("read only account. cannot transact")
+
+	// ErrContractCreateDenied is returned if the sender of a contract creation
+	// doesn't have the permission to deploy contracts.
+	ErrContractCreateDenied = errors.New("account does not have contract create permissions")
 )
 
 var (
@@ -105,6 +119,7 @@ var (
 	// General tx metrics
 	invalidTxCounter     = metrics.NewRegisteredCounter("txpool/invalid", nil)
 	underpricedTxCounter = metrics.NewRegisteredCounter("txpool/underpriced", nil)
+	unprotectedTxCounter = metrics.NewRegisteredCounter("txpool/unprotected", nil) // Quorum - public transactions submitted with legacy signatures
 )
 
 // TxStatus is the current status of a transaction as seen by the pool.
@@ -146,6 +161,13 @@ type TxPoolConfig struct {
 	GlobalQueue  uint64 // Maximum number of non-executable transaction slots for all accounts
 
 	Lifetime time.Duration // Maximum amount of time non-executable transaction are queued
+
+	PriorityClasses []TxPriorityClass // Transaction classes served ahead of bulk traffic, highest priority first
+
+	AnchorSlots       uint64 // Maximum number of document anchors per account
+	GlobalAnchorSlots uint64 // Maximum number of document anchors for all accounts
+
+	HeapLimit uint64 // Heap usage in bytes the slot limits are scaled down towards (0 = fixed limits)
 }
 
 // DefaultTxPoolConfig contains the default configurations for the transaction
@@ -166,6 +188,9 @@ var DefaultTxPoolConfig = TxPoolConfig{
 	GlobalQueue:  1024,
 
 	Lifetime: 3 * time.Hour,
+
+	AnchorSlots:       16,
+	GlobalAnchorSlots: 1024,
 }
 
 // sanitize checks the provided user configurations and changes anything that's
@@ -184,6 +209,28 @@ func (config *TxPoolConfig) sanitize() TxPoolConfig {
 		log.Warn("Sanitizing invalid txpool price bump", "provided", conf.PriceBump, "updated", DefaultTxPoolConfig.PriceBump)
 		conf.PriceBump = DefaultTxPoolConfig.PriceBump
 	}
+	if conf.AnchorSlots < 1 {
+		log.Warn("Sanitizing invalid txpool anchor slots", "provided", conf.AnchorSlots, "updated", DefaultTxPoolConfig.AnchorSlots)
+		conf.AnchorSlots = DefaultTxPoolConfig.AnchorSlots
+	}
+	if conf.GlobalAnchorSlots < 1 {
+		log.Warn("Sanitizing invalid txpool global anchor slots", "provided", conf.GlobalAnchorSlots, "updated", DefaultTxPoolConfig.GlobalAnchorSlots)
+		conf.GlobalAnchorSlots = DefaultTxPoolConfig.GlobalAnchorSlots
+	}
+	if len(conf.PriorityClasses) > 0 {
+		conf.PriorityClasses = append([]TxPriorityClass{}, conf.PriorityClasses...)
+		for i := range conf.PriorityClasses {
+			class := &conf.PriorityClasses[i]
+			if class.Slots < 1 {
+				log.Warn("Sanitizing invalid txpool priority slots", "class", class.Name, "provided", class.Slots, "updated", defaultPrioritySlots)
+				class.Slots = defaultPrioritySlots
+			}
+			if class.Queue < 1 {
+				log.Warn("Sanitizing invalid txpool priority queue", "class", class.Name, "provided", class.Queue, "updated", defaultPriorityQueue)
+				class.Queue = defaultPriorityQueue
+			}
+		}
+	}
 	return conf
 }
 
@@ -201,6 +248,8 @@ type TxPool struct {
 	gasPrice     *big.Int
 	txFeed       event.Feed
 	scope        event.SubscriptionScope
+	diffFeed     event.Feed
+	diffScope    event.SubscriptionScope
 	chainHeadCh  chan ChainHeadEvent
 	chainHeadSub event.Subscription
 	signer       types.Signer
@@ -210,8 +259,11 @@ type TxPool struct {
 	pendingState  *state.ManagedState // Pending state tracking virtual nonces
 	currentMaxGas uint64              // Current gas limit for transaction caps
 
-	locals  *accountSet // Set of local transaction to exempt from eviction rules
-	journal *txJournal  // Journal of local transaction to back up to disk
+	locals  *accountSet  // Set of local transaction to exempt from eviction rules
+	journal *txJournal   // Journal of local transaction to back up to disk
+	lanes   *txLanes     // Priority classes served ahead of bulk traffic
+	orgs    *orgIndex    // Pooled transactions by organization, for their quotas
+	limits  txPoolLimits // Slot limits in force, tightened under memory pressure
 
 	pending map[common.Address]*txList   // All currently processable transactions
 	queue   map[common.Address]*txList   // Queued but non-processable transactions
@@ -219,9 +271,25 @@ type TxPool struct {
 	all     *txLookup                    // All transactions to allow lookups
 	priced  *txPricedList                // All transactions sorted by price
 
+	diffs     []TxPoolDiff         // Content changes not yet sent to subscribers
+	diffQueue []TxPoolDiffEvent    // Content changes flushed, in order, for the diff loop to send
+	diffLock  sync.Mutex           // Protects the diff queue, the pool lock is not held while sending
+	diffWake  chan struct{}        // Notifies the diff loop of flushed changes
+	diffQuit  chan struct{}        // Terminates the diff loop
+	included  map[common.Hash]bool // Transactions included by the chain since the last reset
+
+	cancelled *lru.Cache // Transactions cancelled by their senders, refused if seen again
+
+	anchorValidators []AnchorValidator // Hooks checking the document anchors entering the pool
+	anchors          *anchorIndex      // Pooled document anchors, nil if the chain has none
+	anchorAddress    *common.Address   // Recipient of the document anchors of the next block, nil if inactive
+
+	policy TxPolicy // Restrictions on the transactions of some accounts, nil if none
+
 	wg sync.WaitGroup // for shutdown sync
 
-	homestead bool
+	homestead    bool
+	strictEIP155 bool // Quorum - whether the next block rejects legacy signatures
 }
 
 // NewTxPool creates a new transaction pool to gather, sort and filter inbound
@@ -242,12 +310,24 @@ func NewTxPool(config TxPoolConfig, chainconfig *params.ChainConfig, chain block
 		all:         newTxLookup(),
 		chainHeadCh: make(chan ChainHeadEvent, chainHeadChanSize),
 		gasPrice:    new(big.Int).SetUint64(config.PriceLimit),
+		diffWake:    make(chan struct{}, 1),
+		diffQuit:    make(chan struct{}),
 	}
+	pool.cancelled, _ = lru.New(maxCancelledTxs)
 	pool.locals = newAccountSet(pool.signer)
 	for _, addr := range config.Locals {
 		log.Info("Setting new local account", "address", addr)
 		pool.locals.add(addr)
 	}
+	pool.lanes = newTxLanes(config.PriorityClasses, pool.signer)
+	pool.all.index(pool.lanes)
+	pool.orgs = newOrgIndex(pool.signer)
+	pool.all.index(pool.orgs)
+	if chainconfig.DocumentAnchors != nil {
+		pool.anchors = newAnchorIndex(pool.signer, chainconfig.DocumentAnchors.Address)
+		pool.all.index(pool.anchors)
+	}
+	pool.limits = config.scaledLimits(1)
 	pool.priced = newTxPricedList(pool.all)
 	pool.reset(nil, chain.CurrentBlock().Header())
 
@@ -266,8 +346,9 @@ func NewTxPool(config TxPoolConfig, chainconfig *params.ChainConfig, chain block
 	pool.chainHeadSub = pool.chain.SubscribeChainHeadEvent(pool.chainHeadCh)
 
 	// Start the event loop and return
-	pool.wg.Add(1)
+	pool.wg.Add(2)
 	go pool.loop()
+	go pool.diffLoop()
 
 	return pool
 }
@@ -290,6 +371,14 @@ func (pool *TxPool) loop() {
 	journal := time.NewTicker(pool.config.Rejournal)
 	defer journal.Stop()
 
+	// Sample the heap usage if the limits adapt to it
+	var pressure <-chan time.Time
+	if pool.config.HeapLimit > 0 {
+		ticker := time.NewTicker(pressureInterval)
+		defer ticker.Stop()
+		pressure = ticker.C
+	}
+
 	// Track the previous head headers for transaction reorgs
 	head := pool.chain.CurrentBlock()
 
@@ -306,6 +395,7 @@ func (pool *TxPool) loop() {
 				pool.reset(head.Header(), ev.Block.Header())
 				head = ev.Block
 
+				pool.flushDiffs()
 				pool.mu.Unlock()
 			}
 		// Be unsubscribed due to system stopped
@@ -335,12 +425,18 @@ func (pool *TxPool) loop() {
 				// Any non-locals old enough should be removed
 				if time.Since(pool.beats[addr]) > pool.config.Lifetime {
 					for _, tx := range pool.queue[addr].Flatten() {
+						pool.recordDiff(TxDropped, tx, TxDropNonceGap, nil)
 						pool.removeTx(tx.Hash(), true)
 					}
 				}
 			}
+			pool.flushDiffs()
 			pool.mu.Unlock()
 
+		// Handle memory pressure ticks
+		case <-pressure:
+			pool.adaptLimits(heapUsage())
+
 		// Handle local transaction journal rotation
 		case <-journal.C:
 			if pool.journal != nil {
@@ -359,6 +455,7 @@ func (pool *TxPool) loop() {
 func (pool *TxPool) lockedReset(oldHead, newHead *types.Header) {
 	pool.mu.Lock()
 	defer pool.mu.Unlock()
+	defer pool.flushDiffs()
 
 	pool.reset(oldHead, newHead)
 }
@@ -425,6 +522,18 @@ func (pool *TxPool) reset(oldHead, newHead *types.Header) {
 	pool.currentState = statedb
 	pool.pendingState = state.ManageState(statedb)
 	pool.currentMaxGas = newHead.GasLimit
+	pool.included = pool.includedSince(oldHead, newHead)
+
+	// Quorum - once the next block requires replay protection, drop the pooled
+	// transactions with legacy signatures as they can't be included anymore
+	next := new(big.Int).Add(newHead.Number, big.NewInt(1))
+	if strict := pool.chainconfig.IsStrictEIP155(next); strict != pool.strictEIP155 {
+		pool.strictEIP155 = strict
+		if strict {
+			pool.dropUnprotected()
+		}
+	}
+	pool.anchorAddress = pool.chainconfig.DocumentAnchorAddress(next)
 
 	// Inject any transactions discarded due to reorgs
 	log.Debug("Reinjecting stale transactions", "count", len(reinject))
@@ -445,15 +554,18 @@ func (pool *TxPool) reset(oldHead, newHead *types.Header) {
 	// Check the queue and move transactions over to the pending if possible
 	// or remove those that have become invalid
 	pool.promoteExecutables(nil)
+	pool.included = nil
 }
 
 // Stop terminates the transaction pool.
 func (pool *TxPool) Stop() {
 	// Unsubscribe all subscriptions registered from txpool
 	pool.scope.Close()
+	pool.diffScope.Close()
 
 	// Unsubscribe subscriptions registered from blockchain
 	pool.chainHeadSub.Unsubscribe()
+	close(pool.diffQuit)
 	pool.wg.Wait()
 
 	if pool.journal != nil {
@@ -481,9 +593,11 @@ func (pool *TxPool) GasPrice() *big.Int {
 func (pool *TxPool) SetGasPrice(price *big.Int) {
 	pool.mu.Lock()
 	defer pool.mu.Unlock()
+	defer pool.flushDiffs()
 
 	pool.gasPrice = price
 	for _, tx := range pool.priced.Cap(price, pool.locals) {
+		pool.recordDiff(TxDropped, tx, TxDropUnderpriced, nil)
 		pool.removeTx(tx.Hash(), false)
 	}
 	log.Info("Transaction pool price threshold updated", "price", price)
@@ -578,66 +692,18 @@ func (pool *TxPool) local() map[common.Address]types.Transactions {
 // validateTx checks whether a transaction is valid according to the consensus
 // rules and adheres to some heuristic limits of the local node (price and size).
 func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
-	isQuorum := pool.chainconfig.IsQuorum
-	sizeLimit := pool.chainconfig.TransactionSizeLimit
-	if sizeLimit == 0 {
-		sizeLimit = DefaultTxPoolConfig.TransactionSizeLimit
-	}
-
-	if isQuorum && tx.GasPrice().Cmp(common.Big0) != 0 {
-		return ErrInvalidGasPrice
-	}
-	// Reject transactions over 32KB (or manually set limit) to prevent DOS attacks
-	if float64(tx.Size()) > float64(sizeLimit*1024) {
-		return ErrOversizedData
-	}
-	// Transactions can't be negative. This may never happen using RLP decoded
-	// transactions but may occur if you create a transaction using the RPC.
-	if tx.Value().Sign() < 0 {
-		return ErrNegativeValue
-	}
-	// Ensure the transaction doesn't exceed the current block limit gas.
-	if pool.currentMaxGas < tx.Gas() {
-		return ErrGasLimit
-	}
-	// Make sure the transaction is signed properly
-	from, err := types.Sender(pool.signer, tx)
-	if err != nil {
-		return ErrInvalidSender
-	}
-	// Drop non-local transactions under our own minimal accepted gas price
-	local = local || pool.locals.contains(from) // account may be local even if the transaction arrived from the network
-	if !isQuorum && !local && pool.gasPrice.Cmp(tx.GasPrice()) > 0 {
-		return ErrUnderpriced
-	}
-	// Ensure the transaction adheres to nonce ordering
-	if pool.currentState.GetNonce(from) > tx.Nonce() {
-		return ErrNonceTooLow
-	}
-	// Ether value is not currently supported on private transactions
-	if tx.IsPrivate() && (len(tx.Data()) == 0 || tx.Value().Sign() != 0) {
-		return ErrEtherValueUnsupported
-	}
-	// Transactor should have enough funds to cover the costs
-	// cost == V + GP * GL
-	if pool.currentState.GetBalance(from).Cmp(tx.Cost()) < 0 {
-		return ErrInsufficientFunds
-	}
-	intrGas, err := IntrinsicGas(tx.Data(), tx.To() == nil, pool.homestead)
-	if err != nil {
-		return err
-	}
-	if tx.Gas() < intrGas {
-		return ErrIntrinsicGas
-	}
-
-	// Check if the sender account is authorized to perform the transaction
-	if isQuorum {
-		if err := checkAccount(from, tx.To()); err != nil {
+	c := &txCheckContext{tx: tx, local: local}
+	for _, check := range admissionChecks {
+		if check.applies != nil && !check.applies(pool, tx) {
+			continue
+		}
+		if err := check.run(pool, c); err != nil {
+			if check.meter != nil {
+				check.meter.Inc(1)
+			}
 			return err
 		}
 	}
-
 	return nil
 }
 
@@ -656,6 +722,11 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (bool, error) {
 		log.Trace("Discarding already known transaction", "hash", hash)
 		return false, fmt.Errorf("known transaction: %x", hash)
 	}
+	// If the sender cancelled the transaction, don't let it back in
+	if pool.cancelled.Contains(hash) {
+		log.Trace("Discarding cancelled transaction", "hash", hash)
+		return false, ErrTxCancelled
+	}
 	// If the transaction fails basic validation, discard it
 	if err := pool.validateTx(tx, local); err != nil {
 		log.Trace("Discarding invalid transaction", "hash", hash, "err", err)
@@ -663,7 +734,8 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (bool, error) {
 		return false, err
 	}
 	// If the transaction pool is full, discard underpriced transactions
-	if uint64(pool.all.Count()) >= pool.config.GlobalSlots+pool.config.GlobalQueue {
+	capacity := pool.limits.globalSlots + pool.limits.globalQueue + pool.lanes.capacity()
+	if uint64(pool.all.Count()) >= capacity {
 		// If the new transaction is underpriced, don't accept it
 		if !pool.chainconfig.IsQuorum && !local && pool.priced.Underpriced(tx, pool.locals) {
 			log.Trace("Discarding underpriced transaction", "hash", hash, "price", tx.GasPrice())
@@ -671,10 +743,11 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (bool, error) {
 			return false, ErrUnderpriced
 		}
 		// New transaction is better than our worse ones, make room for it
-		drop := pool.priced.Discard(pool.all.Count()-int(pool.config.GlobalSlots+pool.config.GlobalQueue-1), pool.locals)
+		drop := pool.priced.Discard(pool.all.Count()-int(capacity-1), pool.locals)
 		for _, tx := range drop {
 			log.Trace("Discarding freshly underpriced transaction", "hash", tx.Hash(), "price", tx.GasPrice())
 			underpricedTxCounter.Inc(1)
+			pool.recordDiff(TxDropped, tx, TxDropUnderpriced, nil)
 			pool.removeTx(tx.Hash(), false)
 		}
 	}
@@ -692,16 +765,22 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (bool, error) {
 			pool.all.Remove(old.Hash())
 			pool.priced.Removed()
 			pendingReplaceCounter.Inc(1)
+			pool.recordDiff(TxReplaced, old, "", tx)
 		}
 		pool.all.Add(tx)
 		pool.priced.Put(tx)
 		pool.journalTx(from, tx)
+		pool.recordDiff(TxAdded, tx, "", nil)
 
 		log.Trace("Pooled new executable transaction", "hash", hash, "from", from, "to", tx.To())
+		if local {
+			txtrail.Record(hash, txtrail.StagePooled, "queue", "pending")
+		}
 
 		// We've directly injected a replacement transaction, notify subsystems
 		go pool.txFeed.Send(NewTxsEvent{types.Transactions{tx}})
 
+		pool.recordPolicy(from, tx)
 		return old != nil, nil
 	}
 	// New transaction isn't replacing a pending one, push into queue
@@ -709,6 +788,7 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (bool, error) {
 	if err != nil {
 		return false, err
 	}
+	pool.recordDiff(TxAdded, tx, "", nil)
 	// Mark local addresses and journal local transactions
 	if local {
 		if !pool.locals.contains(from) {
@@ -719,6 +799,10 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (bool, error) {
 	pool.journalTx(from, tx)
 
 	log.Trace("Pooled new future transaction", "hash", hash, "from", from, "to", tx.To())
+	if local {
+		txtrail.Record(hash, txtrail.StagePooled, "queue", "future")
+	}
+	pool.recordPolicy(from, tx)
 	return replace, nil
 }
 
@@ -742,6 +826,7 @@ func (pool *TxPool) enqueueTx(hash common.Hash, tx *types.Transaction) (bool, er
 		pool.all.Remove(old.Hash())
 		pool.priced.Removed()
 		queuedReplaceCounter.Inc(1)
+		pool.recordDiff(TxReplaced, old, "", tx)
 	}
 	if pool.all.Get(hash) == nil {
 		pool.all.Add(tx)
@@ -780,6 +865,7 @@ func (pool *TxPool) promoteTx(addr common.Address, hash common.Hash, tx *types.T
 		pool.priced.Removed()
 
 		pendingDiscardCounter.Inc(1)
+		pool.recordDiff(TxReplaced, tx, "", list.txs.Get(tx.Nonce()))
 		return false
 	}
 	// Otherwise discard any previous transaction and mark this
@@ -788,6 +874,7 @@ func (pool *TxPool) promoteTx(addr common.Address, hash common.Hash, tx *types.T
 		pool.priced.Removed()
 
 		pendingReplaceCounter.Inc(1)
+		pool.recordDiff(TxReplaced, old, "", tx)
 	}
 	// Failsafe to work around direct pending inserts (tests)
 	if pool.all.Get(hash) == nil {
@@ -833,6 +920,7 @@ func (pool *TxPool) AddRemotes(txs []*types.Transaction) []error {
 func (pool *TxPool) addTx(tx *types.Transaction, local bool) error {
 	pool.mu.Lock()
 	defer pool.mu.Unlock()
+	defer pool.flushDiffs()
 
 	// Try to inject the transaction and update any state
 	replace, err := pool.add(tx, local)
@@ -851,6 +939,7 @@ func (pool *TxPool) addTx(tx *types.Transaction, local bool) error {
 func (pool *TxPool) addTxs(txs []*types.Transaction, local bool) []error {
 	pool.mu.Lock()
 	defer pool.mu.Unlock()
+	defer pool.flushDiffs()
 
 	return pool.addTxsLocked(txs, local)
 }
@@ -906,6 +995,25 @@ func (pool *TxPool) Get(hash common.Hash) *types.Transaction {
 	return pool.all.Get(hash)
 }
 
+// dropUnprotected removes the public transactions with legacy signatures from
+// the pool.
+func (pool *TxPool) dropUnprotected() {
+	var hashes []common.Hash
+	pool.all.Range(func(hash common.Hash, tx *types.Transaction) bool {
+		if !tx.Protected() && !tx.IsPrivate() {
+			hashes = append(hashes, hash)
+		}
+		return true
+	})
+	for _, hash := range hashes {
+		log.Trace("Removing unprotected transaction", "hash", hash)
+		pool.removeTx(hash, true)
+	}
+	if len(hashes) > 0 {
+		log.Info("Dropped transactions without replay protection", "count", len(hashes))
+	}
+}
+
 // removeTx removes a single transaction from the queue, moving all subsequent
 // transactions back to the future queue.
 func (pool *TxPool) removeTx(hash common.Hash, outofbound bool) {
@@ -978,6 +1086,7 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 			log.Trace("Removed old queued transaction", "hash", hash)
 			pool.all.Remove(hash)
 			pool.priced.Removed()
+			pool.recordStale(tx)
 		}
 		if !isQuorum {
 			// Drop all transactions that are too costly (low balance or out of gas)
@@ -988,6 +1097,7 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 				pool.all.Remove(hash)
 				pool.priced.Removed()
 				queuedNofundsCounter.Inc(1)
+				pool.recordDiff(TxDropped, tx, TxDropUnpayable, nil)
 			}
 		}
 		// Gather all executable transactions and promote them
@@ -1001,11 +1111,12 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 		}
 		// Drop all transactions over the allowed limit
 		if !pool.locals.contains(addr) {
-			for _, tx := range list.Cap(int(pool.config.AccountQueue)) {
+			for _, tx := range list.Cap(int(pool.limits.accountQueue)) {
 				hash := tx.Hash()
 				pool.all.Remove(hash)
 				pool.priced.Removed()
 				queuedRateLimitCounter.Inc(1)
+				pool.recordDiff(TxDropped, tx, TxDropCapacity, nil)
 				log.Trace("Removed cap-exceeding queued transaction", "hash", hash)
 			}
 		}
@@ -1018,24 +1129,29 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 	if len(promoted) > 0 {
 		go pool.txFeed.Send(NewTxsEvent{promoted})
 	}
+	// Priority lanes are bounded by their own class limits
+	pool.capPriorityLanes()
+
 	// If the pending limit is overflown, start equalizing allowances
 	pending := uint64(0)
-	for _, list := range pool.pending {
-		pending += uint64(list.Len())
+	for addr, list := range pool.pending {
+		if !pool.prioritised(addr) {
+			pending += uint64(list.Len())
+		}
 	}
-	if pending > pool.config.GlobalSlots {
+	if pending > pool.limits.globalSlots {
 		pendingBeforeCap := pending
 		// Assemble a spam order to penalize large transactors first
 		spammers := prque.New(nil)
 		for addr, list := range pool.pending {
 			// Only evict transactions from high rollers
-			if !pool.locals.contains(addr) && uint64(list.Len()) > pool.config.AccountSlots {
+			if !pool.locals.contains(addr) && !pool.prioritised(addr) && uint64(list.Len()) > pool.limits.accountSlots {
 				spammers.Push(addr, int64(list.Len()))
 			}
 		}
 		// Gradually drop transactions from offenders
 		offenders := []common.Address{}
-		for pending > pool.config.GlobalSlots && !spammers.Empty() {
+		for pending > pool.limits.globalSlots && !spammers.Empty() {
 			// Retrieve the next offender if not local address
 			offender, _ := spammers.Pop()
 			offenders = append(offenders, offender.(common.Address))
@@ -1046,7 +1162,7 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 				threshold := pool.pending[offender.(common.Address)].Len()
 
 				// Iteratively reduce all offenders until below limit or threshold reached
-				for pending > pool.config.GlobalSlots && pool.pending[offenders[len(offenders)-2]].Len() > threshold {
+				for pending > pool.limits.globalSlots && pool.pending[offenders[len(offenders)-2]].Len() > threshold {
 					for i := 0; i < len(offenders)-1; i++ {
 						list := pool.pending[offenders[i]]
 						for _, tx := range list.Cap(list.Len() - 1) {
@@ -1054,6 +1170,7 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 							hash := tx.Hash()
 							pool.all.Remove(hash)
 							pool.priced.Removed()
+							pool.recordDiff(TxDropped, tx, TxDropCapacity, nil)
 
 							// Update the account nonce to the dropped transaction
 							if nonce := tx.Nonce(); pool.pendingState.GetNonce(offenders[i]) > nonce {
@@ -1067,8 +1184,8 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 			}
 		}
 		// If still above threshold, reduce to limit or min allowance
-		if pending > pool.config.GlobalSlots && len(offenders) > 0 {
-			for pending > pool.config.GlobalSlots && uint64(pool.pending[offenders[len(offenders)-1]].Len()) > pool.config.AccountSlots {
+		if pending > pool.limits.globalSlots && len(offenders) > 0 {
+			for pending > pool.limits.globalSlots && uint64(pool.pending[offenders[len(offenders)-1]].Len()) > pool.limits.accountSlots {
 				for _, addr := range offenders {
 					list := pool.pending[addr]
 					for _, tx := range list.Cap(list.Len() - 1) {
@@ -1076,6 +1193,7 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 						hash := tx.Hash()
 						pool.all.Remove(hash)
 						pool.priced.Removed()
+						pool.recordDiff(TxDropped, tx, TxDropCapacity, nil)
 
 						// Update the account nonce to the dropped transaction
 						if nonce := tx.Nonce(); pool.pendingState.GetNonce(addr) > nonce {
@@ -1091,21 +1209,23 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 	}
 	// If we've queued more transactions than the hard limit, drop oldest ones
 	queued := uint64(0)
-	for _, list := range pool.queue {
-		queued += uint64(list.Len())
+	for addr, list := range pool.queue {
+		if !pool.prioritised(addr) {
+			queued += uint64(list.Len())
+		}
 	}
-	if queued > pool.config.GlobalQueue {
+	if queued > pool.limits.globalQueue {
 		// Sort all accounts with queued transactions by heartbeat
 		addresses := make(addressesByHeartbeat, 0, len(pool.queue))
 		for addr := range pool.queue {
-			if !pool.locals.contains(addr) { // don't drop locals
+			if !pool.locals.contains(addr) && !pool.prioritised(addr) { // don't drop locals or priority traffic
 				addresses = append(addresses, addressByHeartbeat{addr, pool.beats[addr]})
 			}
 		}
 		sort.Sort(addresses)
 
 		// Drop transactions until the total is below the limit or only locals remain
-		for drop := queued - pool.config.GlobalQueue; drop > 0 && len(addresses) > 0; {
+		for drop := queued - pool.limits.globalQueue; drop > 0 && len(addresses) > 0; {
 			addr := addresses[len(addresses)-1]
 			list := pool.queue[addr.address]
 
@@ -1114,6 +1234,7 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 			// Drop all transactions if they are less than the overflow
 			if size := uint64(list.Len()); size <= drop {
 				for _, tx := range list.Flatten() {
+					pool.recordDiff(TxDropped, tx, TxDropCapacity, nil)
 					pool.removeTx(tx.Hash(), true)
 				}
 				drop -= size
@@ -1123,6 +1244,7 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 			// Otherwise drop only last few transactions
 			txs := list.Flatten()
 			for i := len(txs) - 1; i >= 0 && drop > 0; i-- {
+				pool.recordDiff(TxDropped, txs[i], TxDropCapacity, nil)
 				pool.removeTx(txs[i].Hash(), true)
 				drop--
 				queuedRateLimitCounter.Inc(1)
@@ -1145,6 +1267,7 @@ func (pool *TxPool) demoteUnexecutables() {
 			log.Trace("Removed old pending transaction", "hash", hash)
 			pool.all.Remove(hash)
 			pool.priced.Removed()
+			pool.recordStale(tx)
 		}
 		// Drop all transactions that are too costly (low balance or out of gas), and queue any invalids back for later
 		drops, invalids := list.Filter(pool.currentState.GetBalance(addr), pool.currentMaxGas)
@@ -1154,6 +1277,7 @@ func (pool *TxPool) demoteUnexecutables() {
 			pool.all.Remove(hash)
 			pool.priced.Removed()
 			pendingNofundsCounter.Inc(1)
+			pool.recordDiff(TxDropped, tx, TxDropUnpayable, nil)
 		}
 		for _, tx := range invalids {
 			hash := tx.Hash()
@@ -1249,8 +1373,19 @@ func (as *accountSet) flatten() []common.Address {
 // peeking into the pool in TxPool.Get without having to acquire the widely scoped
 // TxPool.mu mutex.
 type txLookup struct {
-	all  map[common.Hash]*types.Transaction
-	lock sync.RWMutex
+	all      map[common.Hash]*types.Transaction
+	indexers []txIndexer // Indexes kept in sync with the transactions in the lookup
+	lock     sync.RWMutex
+}
+
+// txIndexer is an index of the pooled transactions, updated as the transactions
+// enter and leave the lookup so that the pool never has to rescan itself.
+//
+// Note, the lookup is only mutated with the pool lock held, which also guards
+// the indexes.
+type txIndexer interface {
+	added(tx *types.Transaction)
+	removed(tx *types.Transaction)
 }
 
 // newTxLookup returns a new txLookup structure.
@@ -1293,7 +1428,13 @@ func (t *txLookup) Add(tx *types.Transaction) {
 	t.lock.Lock()
 	defer t.lock.Unlock()
 
+	if _, ok := t.all[tx.Hash()]; ok {
+		return
+	}
 	t.all[tx.Hash()] = tx
+	for _, indexer := range t.indexers {
+		indexer.added(tx)
+	}
 }
 
 // Remove removes a transaction from the lookup.
@@ -1301,7 +1442,26 @@ func (t *txLookup) Remove(hash common.Hash) {
 	t.lock.Lock()
 	defer t.lock.Unlock()
 
+	tx, ok := t.all[hash]
+	if !ok {
+		return
+	}
 	delete(t.all, hash)
+	for _, indexer := range t.indexers {
+		indexer.removed(tx)
+	}
+}
+
+// index registers an index to keep in sync with the lookup, seeding it with
+// the transactions already contained.
+func (t *txLookup) index(indexer txIndexer) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.indexers = append(t.indexers, indexer)
+	for _, tx := range t.all {
+		indexer.added(tx)
+	}
 }
 
 // checks if the account is has the necessary access for the transaction
@@ -1310,11 +1470,11 @@ func checkAccount(fromAcct common.Address, toAcct *common.Address) error {
 
 	switch access {
 	case types.ReadOnly:
-		return errors.New("read only account. cannot transact")
+		return ErrReadOnlyAccount
 
 	case types.Transact:
 		if toAcct == nil {
-			return errors.New("account does not have contract create permissions")
+			return ErrContractCreateDenied
 		}
 
 	case types.FullAccess, types.ContractDeploy:
@@ -1324,6 +1484,99 @@ func checkAccount(fromAcct common.Address, toAcct *common.Address) error {
 	return nil
 }
 
+// checkOrgQuota verifies that the organization of the sender has pool slots
+// left in its quota, and that the transaction fits in its per block gas.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxPool) checkOrgQuota(from common.Address, tx *types.Transaction) error {
+	_, quota := types.GetAcctQuota(from)
+	if quota == nil {
+		return nil
+	}
+	if quota.GasPerBlock > 0 && tx.Gas() > quota.GasPerBlock {
+		return ErrOrgQuotaExceeded
+	}
+	if quota.MaxPending == 0 {
+		return nil
+	}
+	// Replacements don't take up any additional slots
+	if list := pool.pending[from]; list != nil && list.Overlaps(tx) {
+		return nil
+	}
+	if list := pool.queue[from]; list != nil && list.Overlaps(tx) {
+		return nil
+	}
+	if pool.orgs.count(from) >= quota.MaxPending {
+		return ErrOrgQuotaExceeded
+	}
+	return nil
+}
+
+// orgIndex counts the pooled transactions of each organization, for the pool
+// slot quotas. An account is accounted against the organization it belonged
+// to when its first pooled transaction entered the pool, until it has none
+// left, so that the counts stay consistent as the accounts change organization.
+type orgIndex struct {
+	signer   types.Signer
+	orgs     map[string]uint64          // Number of pooled transactions by organization
+	accounts map[common.Address]*orgTxs // Pooled accounts with a known organization
+}
+
+// orgTxs is the organization a pooled account is accounted against.
+type orgTxs struct {
+	org string
+	txs uint64
+}
+
+func newOrgIndex(signer types.Signer) *orgIndex {
+	return &orgIndex{
+		signer:   signer,
+		orgs:     make(map[string]uint64),
+		accounts: make(map[common.Address]*orgTxs),
+	}
+}
+
+// added implements txIndexer, accounting for a transaction entering the pool.
+func (idx *orgIndex) added(tx *types.Transaction) {
+	from, _ := types.Sender(idx.signer, tx) // already validated during insertion
+	account := idx.accounts[from]
+	if account == nil {
+		org := types.GetAcctOrg(from)
+		if org == "" {
+			return
+		}
+		account = &orgTxs{org: org}
+		idx.accounts[from] = account
+	}
+	account.txs++
+	idx.orgs[account.org]++
+}
+
+// removed implements txIndexer, accounting for a transaction leaving the pool.
+func (idx *orgIndex) removed(tx *types.Transaction) {
+	from, _ := types.Sender(idx.signer, tx)
+	account := idx.accounts[from]
+	if account == nil {
+		return
+	}
+	account.txs--
+	if idx.orgs[account.org]--; idx.orgs[account.org] == 0 {
+		delete(idx.orgs, account.org)
+	}
+	if account.txs == 0 {
+		delete(idx.accounts, from)
+	}
+}
+
+// count returns the number of pooled transactions of the organization of an
+// account.
+func (idx *orgIndex) count(from common.Address) uint64 {
+	if account := idx.accounts[from]; account != nil {
+		return idx.orgs[account.org]
+	}
+	return idx.orgs[types.GetAcctOrg(from)]
+}
+
 // helper function to return chainHeadChannel size
 func GetChainHeadChannleSize() int {
 	return chainHeadChanSize
@@ -0,0 +1,160 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+)
+
+// TxDiffType is the kind of change of the transaction pool content.
+type TxDiffType string
+
+const (
+	TxAdded    TxDiffType = "added"    // Transaction entered the pool
+	TxDropped  TxDiffType = "dropped"  // Transaction left the pool
+	TxReplaced TxDiffType = "replaced" // Transaction lost its nonce to another one
+)
+
+// TxDropReason tells why a transaction left the pool.
+type TxDropReason string
+
+const (
+	TxDropIncluded    TxDropReason = "included"    // Included in a block
+	TxDropNonceTooLow TxDropReason = "nonceTooLow" // Nonce used by another transaction of the chain
+	TxDropUnderpriced TxDropReason = "underpriced" // Below the pool price threshold, or evicted by better paying ones
+	TxDropUnpayable   TxDropReason = "unpayable"   // Balance or block gas limit too low
+	TxDropNonceGap    TxDropReason = "nonceGap"    // Queued behind a nonce gap for longer than the pool lifetime
+	TxDropCapacity    TxDropReason = "capacity"    // Evicted to honour the pool slot limits
+	TxDropCancelled   TxDropReason = "cancelled"   // Cancelled by its sender
+)
+
+// maxIncludedDepth is the number of blocks searched for included transactions
+// when the pool is reset to a new head.
+const maxIncludedDepth = 64
+
+// TxPoolDiff is a change of the transaction pool content.
+type TxPoolDiff struct {
+	Type        TxDiffType
+	Tx          *types.Transaction
+	From        common.Address
+	Reason      TxDropReason       // Why a dropped transaction left the pool
+	Replacement *types.Transaction // Transaction taking the nonce of a replaced one
+}
+
+// SubscribeTxPoolDiffEvent registers a subscription of TxPoolDiffEvent and
+// starts sending the changes of the pool content to the given channel.
+func (pool *TxPool) SubscribeTxPoolDiffEvent(ch chan<- TxPoolDiffEvent) event.Subscription {
+	return pool.diffScope.Track(pool.diffFeed.Subscribe(ch))
+}
+
+// recordDiff records a change of the pool content, to be sent to subscribers
+// once the running operation completes. Nothing is recorded without subscribers.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxPool) recordDiff(typ TxDiffType, tx *types.Transaction, reason TxDropReason, replacement *types.Transaction) {
+	if pool.diffScope.Count() == 0 {
+		return
+	}
+	from, _ := types.Sender(pool.signer, tx) // already validated
+	pool.diffs = append(pool.diffs, TxPoolDiff{
+		Type:        typ,
+		Tx:          tx,
+		From:        from,
+		Reason:      reason,
+		Replacement: replacement,
+	})
+}
+
+// recordStale records a transaction leaving the pool because its nonce has been
+// used by the chain.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxPool) recordStale(tx *types.Transaction) {
+	if pool.included[tx.Hash()] {
+		pool.recordDiff(TxDropped, tx, TxDropIncluded, nil)
+	} else {
+		pool.recordDiff(TxDropped, tx, TxDropNonceTooLow, nil)
+	}
+}
+
+// flushDiffs queues the recorded changes of the pool content for the diff loop
+// to send to subscribers, in the order the pool made them.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxPool) flushDiffs() {
+	if len(pool.diffs) == 0 {
+		return
+	}
+	pool.diffLock.Lock()
+	pool.diffQueue = append(pool.diffQueue, TxPoolDiffEvent{pool.diffs})
+	pool.diffLock.Unlock()
+
+	select {
+	case pool.diffWake <- struct{}{}:
+	default:
+	}
+	pool.diffs = nil
+}
+
+// diffLoop sends the flushed changes of the pool content to subscribers one
+// event at a time, so a slow subscriber never blocks the pool nor sees the
+// changes out of order.
+func (pool *TxPool) diffLoop() {
+	defer pool.wg.Done()
+
+	for {
+		select {
+		case <-pool.diffWake:
+			pool.diffLock.Lock()
+			queue := pool.diffQueue
+			pool.diffQueue = nil
+			pool.diffLock.Unlock()
+
+			for _, ev := range queue {
+				pool.diffFeed.Send(ev)
+			}
+		case <-pool.diffQuit:
+			return
+		}
+	}
+}
+
+// includedSince gathers the hashes of the transactions included by the chain
+// from oldHead (exclusive) to newHead, looking at most maxIncludedDepth blocks
+// back. It returns nil without subscribers.
+func (pool *TxPool) includedSince(oldHead, newHead *types.Header) map[common.Hash]bool {
+	if pool.diffScope.Count() == 0 || newHead == nil {
+		return nil
+	}
+	included := make(map[common.Hash]bool)
+	block := pool.chain.GetBlock(newHead.Hash(), newHead.Number.Uint64())
+	for i := 0; block != nil && i < maxIncludedDepth; i++ {
+		if oldHead != nil && block.Hash() == oldHead.Hash() {
+			break
+		}
+		for _, tx := range block.Transactions() {
+			included[tx.Hash()] = true
+		}
+		if block.NumberU64() == 0 {
+			break
+		}
+		block = pool.chain.GetBlock(block.ParentHash(), block.NumberU64()-1)
+	}
+	return included
+}
@@ -18,12 +18,14 @@ package core
 
 import (
 	"crypto/ecdsa"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"math/big"
 	"math/rand"
 	"os"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -126,6 +128,35 @@ func validateTxPoolInternals(pool *TxPool) error {
 			return fmt.Errorf("pending nonce mismatch: have %v, want %v", nonce, last+1)
 		}
 	}
+	// Ensure the priority lanes index the pooled transactions
+	if len(pool.lanes.classes) > 0 {
+		accounts := make(map[common.Address]int)
+		for _, lists := range []map[common.Address]*txList{pool.pending, pool.queue} {
+			for addr, list := range lists {
+				lane, ok := pool.lanes.senders[addr]
+				if !ok {
+					lane = pool.lanes.bulk()
+				}
+				if prev, ok := accounts[addr]; ok && prev < lane {
+					lane = prev
+				}
+				for _, tx := range list.txs.items {
+					if i := pool.lanes.classify(addr, tx); i < lane {
+						lane = i
+					}
+				}
+				accounts[addr] = lane
+			}
+		}
+		if len(pool.lanes.accounts) != len(accounts) {
+			return fmt.Errorf("lane account count mismatch: have %d, want %d", len(pool.lanes.accounts), len(accounts))
+		}
+		for addr, lane := range accounts {
+			if have := pool.lanes.lane(addr); have != lane {
+				return fmt.Errorf("lane mismatch for %x: have %d, want %d", addr, have, lane)
+			}
+		}
+	}
 	return nil
 }
 
@@ -1192,6 +1223,130 @@ func TestTransactionPendingGlobalLimiting(t *testing.T) {
 	}
 }
 
+// Tests that transactions of priority classes are limited separately from, and
+// served ahead of, the bulk traffic of the pool.
+func TestTransactionPriorityLanes(t *testing.T) {
+	t.Parallel()
+
+	// Create the pool with a sender and a recipient based priority class
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(ethdb.NewMemDatabase()))
+	blockchain := &testBlockChain{statedb, statedb, 1000000, new(event.Feed)}
+
+	operator, _ := crypto.GenerateKey()
+	oracle := common.Address{0x01}
+
+	config := testTxPoolConfig
+	config.GlobalSlots = config.AccountSlots * 4
+	config.PriorityClasses = []TxPriorityClass{{
+		Name:       "operations",
+		Senders:    []common.Address{crypto.PubkeyToAddress(operator.PublicKey)},
+		Recipients: []common.Address{oracle},
+		Slots:      config.AccountSlots * 2,
+	}}
+	pool := NewTxPool(config, params.TestChainConfig, blockchain)
+	defer pool.Stop()
+
+	// Create a number of bulk accounts and fund them along with the operator
+	keys := make([]*ecdsa.PrivateKey, 5)
+	for i := 0; i < len(keys); i++ {
+		keys[i], _ = crypto.GenerateKey()
+		pool.currentState.AddBalance(crypto.PubkeyToAddress(keys[i].PublicKey), big.NewInt(1000000))
+	}
+	pool.currentState.AddBalance(crypto.PubkeyToAddress(operator.PublicKey), big.NewInt(1000000))
+
+	// Flood the pool with bulk traffic, the operator and an oracle update
+	txs := types.Transactions{}
+	for _, key := range keys[1:] {
+		for j := uint64(0); j < config.GlobalSlots; j++ {
+			txs = append(txs, transaction(j, 100000, key))
+		}
+	}
+	for j := uint64(0); j < config.PriorityClasses[0].Slots*2; j++ {
+		txs = append(txs, transaction(j, 100000, operator))
+	}
+	update, _ := types.SignTx(types.NewTransaction(0, oracle, big.NewInt(100), 100000, big.NewInt(1), nil), types.HomesteadSigner{}, keys[0])
+	txs = append(txs, update)
+
+	pool.AddRemotes(txs)
+
+	// Verify that both lanes were limited separately
+	lanes, _ := pool.PendingLanes()
+	if len(lanes) != 2 {
+		t.Fatalf("lane count mismatch: have %d, want %d", len(lanes), 2)
+	}
+	var priority, bulk int
+	for _, txs := range lanes[0] {
+		priority += len(txs)
+	}
+	for _, txs := range lanes[1] {
+		bulk += len(txs)
+	}
+	if priority != int(config.PriorityClasses[0].Slots) {
+		t.Fatalf("priority pending transactions mismatch: have %d, want %d", priority, config.PriorityClasses[0].Slots)
+	}
+	if bulk > int(config.GlobalSlots) {
+		t.Fatalf("bulk pending transactions overflow allowance: %d > %d", bulk, config.GlobalSlots)
+	}
+	if txs := lanes[0][crypto.PubkeyToAddress(keys[0].PublicKey)]; len(txs) != 1 || txs[0].Hash() != update.Hash() {
+		t.Fatalf("oracle update not prioritised: %v", txs)
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+	// Verify that the account leaves the priority lane with its oracle update
+	pool.mu.Lock()
+	pool.removeTx(update.Hash(), true)
+	lane := pool.accountLane(crypto.PubkeyToAddress(keys[0].PublicKey))
+	pool.mu.Unlock()
+
+	if lane != pool.lanes.bulk() {
+		t.Fatalf("lane mismatch after removal: have %d, want %d", lane, pool.lanes.bulk())
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
+// Tests that transactions are placed into priority classes by their type.
+func TestTransactionPriorityKinds(t *testing.T) {
+	t.Parallel()
+
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(ethdb.NewMemDatabase()))
+	blockchain := &testBlockChain{statedb, statedb, 1000000, new(event.Feed)}
+
+	kind, err := ParseTxKind("create")
+	if err != nil {
+		t.Fatalf("failed to parse transaction type: %v", err)
+	}
+	if _, err := ParseTxKind("bulk"); err == nil {
+		t.Fatalf("unknown transaction type accepted")
+	}
+	config := testTxPoolConfig
+	config.PriorityClasses = []TxPriorityClass{{Name: "deployments", Kinds: kind}}
+
+	pool := NewTxPool(config, params.TestChainConfig, blockchain)
+	defer pool.Stop()
+
+	deployer, _ := crypto.GenerateKey()
+	sender, _ := crypto.GenerateKey()
+	pool.currentState.AddBalance(crypto.PubkeyToAddress(deployer.PublicKey), big.NewInt(1000000))
+	pool.currentState.AddBalance(crypto.PubkeyToAddress(sender.PublicKey), big.NewInt(1000000))
+
+	create, _ := types.SignTx(types.NewContractCreation(0, big.NewInt(0), 100000, big.NewInt(1), []byte{0x00}), types.HomesteadSigner{}, deployer)
+	pool.AddRemotes(types.Transactions{create, transaction(0, 100000, sender)})
+
+	lanes, _ := pool.PendingLanes()
+	if txs := lanes[0][crypto.PubkeyToAddress(deployer.PublicKey)]; len(txs) != 1 || txs[0].Hash() != create.Hash() {
+		t.Fatalf("contract creation not prioritised: %v", txs)
+	}
+	if txs := lanes[1][crypto.PubkeyToAddress(sender.PublicKey)]; len(txs) != 1 {
+		t.Fatalf("transfer not served as bulk traffic: %v", txs)
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
 // Tests that if transactions start being capped, transactions are also removed from 'all'
 func TestTransactionCapClearsFromAll(t *testing.T) {
 	t.Parallel()
@@ -2016,3 +2171,413 @@ func TestEIP155SignerOnTxPool(t *testing.T) {
 	}
 
 }
+
+// Tests that subscribers are told why transactions enter and leave the pool.
+func TestTransactionPoolDiffs(t *testing.T) {
+	t.Parallel()
+
+	pool, key := setupTxPool()
+	defer pool.Stop()
+
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+	pool.currentState.AddBalance(addr, big.NewInt(1000000000))
+
+	diffs := make(chan TxPoolDiffEvent, 32)
+	sub := pool.SubscribeTxPoolDiffEvent(diffs)
+	defer sub.Unsubscribe()
+
+	check := func(step string, want ...TxPoolDiff) {
+		select {
+		case ev := <-diffs:
+			if len(ev.Diffs) != len(want) {
+				t.Fatalf("%s: diff count mismatch: have %d, want %d", step, len(ev.Diffs), len(want))
+			}
+			for i, diff := range ev.Diffs {
+				if diff.Type != want[i].Type || diff.Tx != want[i].Tx || diff.Reason != want[i].Reason || diff.Replacement != want[i].Replacement {
+					t.Errorf("%s: diff %d mismatch: have %+v, want %+v", step, i, diff, want[i])
+				}
+				if diff.From != addr {
+					t.Errorf("%s: diff %d sender mismatch: have %x, want %x", step, i, diff.From, addr)
+				}
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("%s: diffs not fired", step)
+		}
+	}
+	cheap, pricey := pricedTransaction(0, 100000, big.NewInt(1), key), pricedTransaction(0, 100000, big.NewInt(2), key)
+	if err := pool.AddRemote(cheap); err != nil {
+		t.Fatalf("failed to add transaction: %v", err)
+	}
+	check("add", TxPoolDiff{Type: TxAdded, Tx: cheap})
+
+	if err := pool.AddRemote(pricey); err != nil {
+		t.Fatalf("failed to replace transaction: %v", err)
+	}
+	check("replace", TxPoolDiff{Type: TxReplaced, Tx: cheap, Replacement: pricey}, TxPoolDiff{Type: TxAdded, Tx: pricey})
+
+	pool.SetGasPrice(big.NewInt(3))
+	check("reprice", TxPoolDiff{Type: TxDropped, Tx: pricey, Reason: TxDropUnderpriced})
+
+	stale := pricedTransaction(0, 100000, big.NewInt(3), key)
+	if err := pool.AddRemote(stale); err != nil {
+		t.Fatalf("failed to add transaction: %v", err)
+	}
+	check("re-add", TxPoolDiff{Type: TxAdded, Tx: stale})
+
+	// The test chain includes no transactions, the nonce was used by another one
+	pool.chain.(*testBlockChain).statedb.SetNonce(addr, 1)
+	pool.lockedReset(nil, nil)
+	check("reset", TxPoolDiff{Type: TxDropped, Tx: stale, Reason: TxDropNonceTooLow})
+}
+
+// Tests that the pool content changes reach subscribers in the order they were
+// made, even when the subscriber lags behind.
+func TestTransactionPoolDiffOrder(t *testing.T) {
+	t.Parallel()
+
+	pool, key := setupTxPool()
+	defer pool.Stop()
+
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+	pool.currentState.AddBalance(addr, big.NewInt(1000000000))
+
+	diffs := make(chan TxPoolDiffEvent)
+	sub := pool.SubscribeTxPoolDiffEvent(diffs)
+	defer sub.Unsubscribe()
+
+	txs := make([]*types.Transaction, 64)
+	for i := range txs {
+		txs[i] = transaction(uint64(i), 100000, key)
+		if err := pool.AddRemote(txs[i]); err != nil {
+			t.Fatalf("failed to add transaction %d: %v", i, err)
+		}
+	}
+	for i := 0; i < len(txs); {
+		select {
+		case ev := <-diffs:
+			for _, diff := range ev.Diffs {
+				if diff.Tx != txs[i] {
+					t.Fatalf("diff %d out of order: have nonce %d", i, diff.Tx.Nonce())
+				}
+				i++
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("diffs not fired after %d transactions", i)
+		}
+	}
+}
+
+// Tests that transactions can be cancelled by their senders only, and that
+// cancelled transactions are refused afterwards while their nonce can be reused.
+func TestTransactionCancel(t *testing.T) {
+	t.Parallel()
+
+	pool, key := setupTxPool()
+	defer pool.Stop()
+
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+	pool.currentState.AddBalance(addr, big.NewInt(1000000000))
+
+	txs := []*types.Transaction{transaction(0, 100000, key), transaction(1, 100000, key), transaction(2, 100000, key)}
+	for i, err := range pool.AddRemotes(txs) {
+		if err != nil {
+			t.Fatalf("tx %d: failed to add: %v", i, err)
+		}
+	}
+	sign := func(hash common.Hash, key *ecdsa.PrivateKey) []byte {
+		sig, err := crypto.Sign(CancellationHash(hash).Bytes(), key)
+		if err != nil {
+			t.Fatalf("failed to sign cancellation: %v", err)
+		}
+		return sig
+	}
+	other, _ := crypto.GenerateKey()
+	if _, err := pool.Cancel(txs[1].Hash(), sign(txs[1].Hash(), other)); err != ErrCancelNotSender {
+		t.Fatalf("foreign cancellation: have %v, want %v", err, ErrCancelNotSender)
+	}
+	if _, err := pool.Cancel(txs[1].Hash(), sign(txs[1].Hash(), key)); err != nil {
+		t.Fatalf("failed to cancel: %v", err)
+	}
+	if _, err := pool.Cancel(txs[1].Hash(), sign(txs[1].Hash(), key)); err != ErrCancelUnknownTx {
+		t.Fatalf("repeated cancellation: have %v, want %v", err, ErrCancelUnknownTx)
+	}
+	if pending, queued := pool.Stats(); pending != 1 || queued != 1 {
+		t.Fatalf("pool content mismatch: have %d pending, %d queued, want 1, 1", pending, queued)
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+	if err := pool.AddRemote(txs[1]); err != ErrTxCancelled {
+		t.Fatalf("cancelled transaction re-added: have %v, want %v", err, ErrTxCancelled)
+	}
+	// A replacement takes the freed nonce and unblocks the queued transaction
+	if err := pool.AddRemote(transaction(1, 200000, key)); err != nil {
+		t.Fatalf("failed to add replacement: %v", err)
+	}
+	if pending, queued := pool.Stats(); pending != 3 || queued != 0 {
+		t.Fatalf("pool content mismatch: have %d pending, %d queued, want 3, 0", pending, queued)
+	}
+}
+
+// Tests that public transactions with legacy signatures are rejected once the
+// chain requires replay protection, and dropped from the pool at the transition.
+func TestTransactionStrictEIP155(t *testing.T) {
+	t.Parallel()
+
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(ethdb.NewMemDatabase()))
+	blockchain := &testBlockChain{statedb, statedb, 1000000, new(event.Feed)}
+
+	config := *params.TestChainConfig
+	config.StrictEIP155Block = big.NewInt(2)
+	pool := NewTxPool(testTxPoolConfig, &config, blockchain)
+	defer pool.Stop()
+
+	key, _ := crypto.GenerateKey()
+	pool.currentState.AddBalance(crypto.PubkeyToAddress(key.PublicKey), big.NewInt(1000000000))
+
+	protected := func(nonce uint64, chainID *big.Int) *types.Transaction {
+		tx, _ := types.SignTx(types.NewTransaction(nonce, common.Address{}, big.NewInt(100), 100000, big.NewInt(1), nil), types.NewEIP155Signer(chainID), key)
+		return tx
+	}
+	// Legacy signatures are accepted before the transition
+	if err := pool.AddRemote(transaction(0, 100000, key)); err != nil {
+		t.Fatalf("legacy transaction rejected before the transition: %v", err)
+	}
+	if err := pool.AddRemote(protected(1, config.ChainID)); err != nil {
+		t.Fatalf("protected transaction rejected: %v", err)
+	}
+	// Reaching the block before the transition drops the legacy transaction
+	pool.lockedReset(nil, &types.Header{Number: big.NewInt(1), GasLimit: 1000000})
+	if pool.all.Get(transaction(0, 100000, key).Hash()) != nil {
+		t.Fatalf("legacy transaction kept after the transition")
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+	if err := pool.AddRemote(transaction(0, 100000, key)); err != ErrUnprotectedTransaction {
+		t.Fatalf("legacy transaction after the transition: have %v, want %v", err, ErrUnprotectedTransaction)
+	}
+	if err := pool.AddRemote(protected(0, config.ChainID)); err != nil {
+		t.Fatalf("protected transaction rejected after the transition: %v", err)
+	}
+	// Signatures of other chains are refused
+	if err := pool.AddRemote(protected(2, big.NewInt(4321))); err != ErrInvalidSender {
+		t.Fatalf("transaction of another chain: have %v, want %v", err, ErrInvalidSender)
+	}
+}
+
+// Tests that document anchors are validated, run through the registered hooks,
+// and held to their own pool limits.
+func TestTransactionAnchors(t *testing.T) {
+	t.Parallel()
+
+	config := *params.QuorumTestChainConfig
+	config.DocumentAnchors = &params.DocumentAnchorsConfig{Address: common.HexToAddress("0x0a0c40"), Block: big.NewInt(0)}
+
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(ethdb.NewMemDatabase()))
+	pool := NewTxPool(testTxPoolConfig, &config, &testBlockChain{statedb, statedb, 1000000, new(event.Feed)})
+	defer pool.Stop()
+	key, _ := crypto.GenerateKey()
+
+	pool.config.AnchorSlots, pool.config.GlobalAnchorSlots = 2, 3
+
+	other, _ := crypto.GenerateKey()
+	for _, k := range []*ecdsa.PrivateKey{key, other} {
+		pool.currentState.AddBalance(crypto.PubkeyToAddress(k.PublicKey), big.NewInt(1000000000))
+	}
+	anchor := func(nonce uint64, uri string, key *ecdsa.PrivateKey) *types.Transaction {
+		tx, err := types.NewAnchorTransaction(nonce, config.DocumentAnchors.Address, &types.DocumentAnchor{Hash: common.HexToHash("0x01"), Size: 1 << 20, URI: uri}, 100000)
+		if err != nil {
+			t.Fatalf("failed to create anchor: %v", err)
+		}
+		tx, _ = types.SignTx(tx, types.HomesteadSigner{}, key)
+		return tx
+	}
+	if err := pool.AddRemote(anchor(0, "", key)); err == nil || !strings.HasPrefix(err.Error(), ErrInvalidAnchor.Error()) {
+		t.Fatalf("anchor without URI: have %v, want %v", err, ErrInvalidAnchor)
+	}
+	pool.AddAnchorValidator(func(from common.Address, anchor *types.DocumentAnchor) error {
+		if !strings.HasPrefix(anchor.URI, "s3://") {
+			return errors.New("unapproved storage")
+		}
+		return nil
+	})
+	if err := pool.AddRemote(anchor(0, "http://example.com/doc", key)); err == nil || !strings.HasPrefix(err.Error(), ErrInvalidAnchor.Error()) {
+		t.Fatalf("anchor refused by validator: have %v, want %v", err, ErrInvalidAnchor)
+	}
+	for i := uint64(0); i < 2; i++ {
+		if err := pool.AddRemote(anchor(i, "s3://docs/a", key)); err != nil {
+			t.Fatalf("anchor %d: failed to add: %v", i, err)
+		}
+	}
+	if err := pool.AddRemote(anchor(2, "s3://docs/a", key)); err != ErrAnchorLimit {
+		t.Fatalf("anchor over account limit: have %v, want %v", err, ErrAnchorLimit)
+	}
+	// Replacements and other transactions aren't limited
+	if err := pool.AddRemote(anchor(1, "s3://docs/b", key)); err != ErrReplaceUnderpriced {
+		t.Fatalf("anchor replacement: have %v, want %v", err, ErrReplaceUnderpriced)
+	}
+	if err := pool.AddRemote(pricedTransaction(2, 100000, big.NewInt(0), key)); err != nil {
+		t.Fatalf("plain transaction: failed to add: %v", err)
+	}
+	if err := pool.AddRemote(anchor(0, "s3://docs/c", other)); err != nil {
+		t.Fatalf("anchor of other account: failed to add: %v", err)
+	}
+	if err := pool.AddRemote(anchor(1, "s3://docs/c", other)); err != ErrAnchorLimit {
+		t.Fatalf("anchor over global limit: have %v, want %v", err, ErrAnchorLimit)
+	}
+	// Dropping an anchor frees its slot
+	pool.removeTx(pool.pending[crypto.PubkeyToAddress(key.PublicKey)].txs.items[1].Hash(), true)
+	if pool.anchors.total != 2 || pool.anchors.accounts[crypto.PubkeyToAddress(key.PublicKey)] != 1 {
+		t.Fatalf("anchor counts mismatch after removal: %d total, %d for the account", pool.anchors.total, pool.anchors.accounts[crypto.PubkeyToAddress(key.PublicKey)])
+	}
+	// The blocks hold the anchors to the same rules, once activated
+	invalid := anchor(5, "", key)
+	if err := validateDocumentAnchor(&config, big.NewInt(1), invalid); err == nil {
+		t.Errorf("block accepted invalid anchor")
+	}
+	config.DocumentAnchors.Block = big.NewInt(2)
+	if err := validateDocumentAnchor(&config, big.NewInt(1), invalid); err != nil {
+		t.Errorf("block rejected anchor before activation: %v", err)
+	}
+}
+
+// Tests that checking a transaction reports the outcome of every admission
+// check, without adding it to the pool.
+func TestTransactionCheck(t *testing.T) {
+	t.Parallel()
+
+	pool, key := setupTxPool()
+	defer pool.Stop()
+
+	failed := func(results []TxCheckResult) (failed []string, skipped []string) {
+		for _, result := range results {
+			switch {
+			case result.Skipped:
+				skipped = append(skipped, result.Check)
+			case !result.Passed:
+				failed = append(failed, result.Check)
+			}
+		}
+		return failed, skipped
+	}
+	// All the failing checks are reported at once
+	tx := transaction(0, 100, key)
+	if have, skipped := failed(pool.Check(tx, false)); !reflect.DeepEqual(have, []string{"balance", "intrinsicGas"}) || len(skipped) != 0 {
+		t.Fatalf("failed checks mismatch: have %v (skipped %v), want [balance intrinsicGas]", have, skipped)
+	}
+	if pending, queued := pool.Stats(); pending+queued != 0 {
+		t.Fatalf("checked transaction pooled: pending %d, queued %d", pending, queued)
+	}
+	// The checks against the content of the pool are run too
+	pool.currentState.AddBalance(crypto.PubkeyToAddress(key.PublicKey), big.NewInt(1000000))
+	tx = transaction(0, 100000, key)
+	if err := pool.AddRemote(tx); err != nil {
+		t.Fatalf("failed to add transaction: %v", err)
+	}
+	if have, _ := failed(pool.Check(tx, false)); !reflect.DeepEqual(have, []string{"known"}) {
+		t.Fatalf("failed checks mismatch: have %v, want [known]", have)
+	}
+	if have, _ := failed(pool.Check(transaction(0, 100001, key), false)); !reflect.DeepEqual(have, []string{"replacement"}) {
+		t.Fatalf("failed checks mismatch: have %v, want [replacement]", have)
+	}
+	// The checks of the sender are skipped if it can't be recovered
+	unsigned := types.NewTransaction(1, common.Address{}, big.NewInt(100), 100000, big.NewInt(1), nil)
+	have, skipped := failed(pool.Check(unsigned, false))
+	if !reflect.DeepEqual(have, []string{"signature"}) {
+		t.Fatalf("failed checks mismatch: have %v, want [signature]", have)
+	}
+	if want := []string{"replayProtection", "minGasPrice", "nonce", "balance", "replacement", "capacity"}; !reflect.DeepEqual(skipped, want) {
+		t.Fatalf("skipped checks mismatch: have %v, want %v", skipped, want)
+	}
+}
+
+// Tests that the slot limits tighten under memory pressure, shedding the remote
+// transactions exceeding them, and relax once the pressure is gone.
+func TestTransactionPoolPressure(t *testing.T) {
+	t.Parallel()
+
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(ethdb.NewMemDatabase()))
+	blockchain := &testBlockChain{statedb, statedb, 1000000, new(event.Feed)}
+
+	config := testTxPoolConfig
+	config.AccountSlots, config.GlobalSlots = 4, 40
+	config.AccountQueue, config.GlobalQueue = 4, 40
+	config.HeapLimit = 1000
+
+	pool := NewTxPool(config, params.TestChainConfig, blockchain)
+	defer pool.Stop()
+
+	// Fill the pool with pending and queued transactions of remote accounts,
+	// along with the transactions of a local one
+	keys := make([]*ecdsa.PrivateKey, 10)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		pool.currentState.AddBalance(crypto.PubkeyToAddress(keys[i].PublicKey), big.NewInt(1000000))
+	}
+	for _, key := range keys[1:] {
+		for nonce := uint64(0); nonce < 4; nonce++ {
+			pool.AddRemote(transaction(nonce, 100000, key))
+			pool.AddRemote(transaction(nonce+5, 100000, key))
+		}
+	}
+	for nonce := uint64(0); nonce < 8; nonce++ {
+		pool.AddLocal(transaction(nonce, 100000, keys[0]))
+	}
+	pending, queued := pool.Stats()
+	if pending != 44 || queued != 36 {
+		t.Fatalf("pool content mismatch: have %d pending, %d queued, want 44, 36", pending, queued)
+	}
+	// No pressure below the onset
+	pool.adaptLimits(700)
+	if pending, queued = pool.Stats(); pending != 44 || queued != 36 {
+		t.Fatalf("pool shed without pressure: have %d pending, %d queued", pending, queued)
+	}
+	// Tighten the limits to half of the configured ones
+	if scale := pressureScale(888, config.HeapLimit); scale != 0.5 {
+		t.Fatalf("scale mismatch: have %v, want 0.5", scale)
+	}
+	pool.adaptLimits(888)
+	if pending, queued = pool.Stats(); pending > 20+8 || queued > 20 {
+		t.Fatalf("pool not shed under pressure: have %d pending, %d queued, want at most 28, 20", pending, queued)
+	}
+	if list := pool.pending[crypto.PubkeyToAddress(keys[0].PublicKey)]; list == nil || list.Len() != 8 {
+		t.Fatalf("local transactions shed")
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+	// Relax the limits once the pressure is gone
+	pool.adaptLimits(0)
+	if pool.limits != config.scaledLimits(1) {
+		t.Fatalf("limits not restored: have %+v", pool.limits)
+	}
+}
+
+// Tests that the pooled transactions are counted against the organization their
+// sender belonged to when entering the pool.
+func TestOrgIndex(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	from := crypto.PubkeyToAddress(key.PublicKey)
+	types.OrgInfoMap.UpsertOrg("IDXORG", "", "IDXORG", big.NewInt(1), types.OrgApproved)
+	types.AcctInfoMap.UpsertAccount("IDXORG", "", from, false, types.AcctActive)
+
+	signer := types.HomesteadSigner{}
+	idx := newOrgIndex(signer)
+	first, second := transaction(0, 100000, key), transaction(1, 100000, key)
+	idx.added(first)
+	idx.added(second)
+	if count := idx.count(from); count != 2 {
+		t.Fatalf("org count mismatch: have %d, want 2", count)
+	}
+	// Moving the account doesn't unbalance the counts of its pooled transactions
+	types.AcctInfoMap.UpsertAccount("OTHERORG", "", from, false, types.AcctActive)
+	idx.removed(first)
+	if count := idx.count(from); count != 1 {
+		t.Fatalf("org count mismatch: have %d, want 1", count)
+	}
+	idx.removed(second)
+	if len(idx.orgs) != 0 || len(idx.accounts) != 0 {
+		t.Fatalf("index not emptied: %v %v", idx.orgs, idx.accounts)
+	}
+}
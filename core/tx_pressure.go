@@ -0,0 +1,130 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math"
+	"runtime"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+const (
+	// pressureInterval is the interval the heap usage is sampled at.
+	pressureInterval = 3 * time.Second
+
+	// pressureOnset is the fraction of the heap limit from which the limits of
+	// the pool tighten, down to minLimitScale of their configured value when the
+	// heap reaches the limit.
+	pressureOnset = 0.75
+	minLimitScale = 0.1
+)
+
+var (
+	// Metrics for the adaptive limits
+	shedTxCounter   = metrics.NewRegisteredCounter("txpool/pressure/shed", nil) // Transactions dropped as the limits tightened
+	limitScaleGauge = metrics.NewRegisteredGauge("txpool/pressure/scale", nil)  // Percentage of the configured limits in force
+	heapUsageGauge  = metrics.NewRegisteredGauge("txpool/pressure/heap", nil)   // Heap usage sampled
+)
+
+// txPoolLimits are the slot limits of the pool in force, the configured ones
+// scaled down under memory pressure.
+type txPoolLimits struct {
+	accountSlots uint64
+	globalSlots  uint64
+	accountQueue uint64
+	globalQueue  uint64
+	scale        float64
+}
+
+// scaledLimits returns the configured slot limits scaled by the given factor,
+// keeping at least a slot for each.
+func (config *TxPoolConfig) scaledLimits(scale float64) txPoolLimits {
+	limit := func(slots uint64) uint64 {
+		if scaled := uint64(float64(slots) * scale); scaled > 0 {
+			return scaled
+		}
+		return 1
+	}
+	return txPoolLimits{
+		accountSlots: limit(config.AccountSlots),
+		globalSlots:  limit(config.GlobalSlots),
+		accountQueue: limit(config.AccountQueue),
+		globalQueue:  limit(config.GlobalQueue),
+		scale:        scale,
+	}
+}
+
+// pressureScale returns the factor the limits are scaled by for the given heap
+// usage: the limits are kept until the heap reaches pressureOnset of its limit,
+// then decrease linearly to minLimitScale at the limit, in steps of 5% for
+// small variations of the heap not to churn the pool.
+func pressureScale(heap, limit uint64) float64 {
+	onset := uint64(float64(limit) * pressureOnset)
+	switch {
+	case limit == 0 || heap <= onset:
+		return 1
+	case heap >= limit:
+		return minLimitScale
+	}
+	scale := 1 - (1-minLimitScale)*float64(heap-onset)/float64(limit-onset)
+	return math.Max(math.Floor(scale*20)/20, minLimitScale)
+}
+
+// heapUsage returns the bytes allocated on the heap.
+func heapUsage() uint64 {
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	return stats.HeapAlloc
+}
+
+// adaptLimits scales the slot limits of the pool to the given heap usage. When
+// they tighten, the transactions exceeding them are shed the way the pool caps
+// its content: the queued transactions of the least recently active accounts
+// and the pending ones of the largest accounts, sparing the local transactions
+// and the priority lanes.
+func (pool *TxPool) adaptLimits(heap uint64) {
+	scale := pressureScale(heap, pool.config.HeapLimit)
+	heapUsageGauge.Update(int64(heap))
+	limitScaleGauge.Update(int64(scale * 100))
+
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	if scale == pool.limits.scale {
+		return
+	}
+	tighter := scale < pool.limits.scale
+	pool.limits = pool.config.scaledLimits(scale)
+
+	log.Debug("Adapted transaction pool limits", "heap", heap, "scale", scale,
+		"accountslots", pool.limits.accountSlots, "globalslots", pool.limits.globalSlots,
+		"accountqueue", pool.limits.accountQueue, "globalqueue", pool.limits.globalQueue)
+
+	if tighter {
+		before := pool.all.Count()
+		pool.promoteExecutables(nil)
+		pool.flushDiffs()
+
+		if shed := before - pool.all.Count(); shed > 0 {
+			shedTxCounter.Inc(int64(shed))
+			log.Warn("Shed transactions under memory pressure", "count", shed, "heap", heap, "scale", scale)
+		}
+	}
+}
@@ -0,0 +1,353 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+var (
+	defaultPrioritySlots uint64 = 1024 // Executable slots of a priority class if none configured
+	defaultPriorityQueue uint64 = 256  // Non-executable slots of a priority class if none configured
+)
+
+// Metrics for the priority lanes
+var priorityRateLimitCounter = metrics.NewRegisteredCounter("txpool/priority/ratelimit", nil) // Dropped due to class limits
+
+// TxKind is a set of transaction types a priority class selects.
+type TxKind uint8
+
+const (
+	TxKindTransfer TxKind = 1 << iota // Plain value transfer to an account without input data
+	TxKindCall                        // Invocation of a contract, carrying input data
+	TxKindCreate                      // Contract creation
+	TxKindPrivate                     // Private transaction, whatever its payload
+)
+
+// txKindNames maps the transaction type names accepted in the configuration
+// to their kinds.
+var txKindNames = map[string]TxKind{
+	"transfer": TxKindTransfer,
+	"call":     TxKindCall,
+	"create":   TxKindCreate,
+	"private":  TxKindPrivate,
+}
+
+// ParseTxKind converts a transaction type name (transfer, call, create or
+// private) into its kind.
+func ParseTxKind(name string) (TxKind, error) {
+	if kind, ok := txKindNames[name]; ok {
+		return kind, nil
+	}
+	return 0, fmt.Errorf("unknown transaction type %q", name)
+}
+
+// String implements fmt.Stringer, listing the names of the kinds in the set.
+func (k TxKind) String() string {
+	var names []string
+	for _, name := range []string{"transfer", "call", "create", "private"} {
+		if k&txKindNames[name] != 0 {
+			names = append(names, name)
+		}
+	}
+	return strings.Join(names, ",")
+}
+
+// txKinds returns the kinds a transaction is of.
+func txKinds(tx *types.Transaction) TxKind {
+	var kinds TxKind
+	switch {
+	case tx.To() == nil:
+		kinds = TxKindCreate
+	case len(tx.Data()) > 0:
+		kinds = TxKindCall
+	default:
+		kinds = TxKindTransfer
+	}
+	if tx.IsPrivate() {
+		kinds |= TxKindPrivate
+	}
+	return kinds
+}
+
+// TxPriorityClass is a set of transactions, identified by sender, by recipient
+// or by transaction type, that is served ahead of the bulk traffic of the pool. Operational
+// transactions such as permissioning or oracle updates can be placed in a
+// class so they are neither evicted by nor queued behind application load.
+type TxPriorityClass struct {
+	Name       string           // Human readable name of the class, used in logs
+	Senders    []common.Address // Accounts whose transactions belong to the class
+	Recipients []common.Address // Contracts whose invocations belong to the class (e.g. permissioning)
+	Kinds      TxKind           // Transaction types belonging to the class (e.g. private)
+
+	Slots uint64 // Maximum number of executable transaction slots for the class
+	Queue uint64 // Maximum number of non-executable transaction slots for the class
+}
+
+// txLanes classifies transactions into the configured priority classes. Lane i
+// is the i-th priority class, the last lane holds all other (bulk) traffic.
+//
+// Transactions of one account must be included in nonce order, so an account
+// is always placed as a whole into the best lane any of its transactions
+// qualifies for. The lanes index the pooled transactions as they enter and
+// leave the pool, so the lane of an account is known without rescanning it.
+type txLanes struct {
+	classes    []TxPriorityClass
+	senders    map[common.Address]int
+	recipients map[common.Address]int
+
+	signer   types.Signer
+	accounts map[common.Address]*laneAccount // Pooled accounts, by sender
+	members  []map[common.Address]struct{}   // Accounts served in each priority lane
+}
+
+// laneAccount tracks the pooled transactions of an account qualifying for each
+// priority lane.
+type laneAccount struct {
+	txs   int   // Number of pooled transactions of the account
+	lanes []int // Number of pooled transactions qualifying for each priority lane
+	lane  int   // Lane the account is served in
+}
+
+// newTxLanes creates the lane classifier for the given priority classes, the
+// earlier a class is listed the higher its priority.
+func newTxLanes(classes []TxPriorityClass, signer types.Signer) *txLanes {
+	lanes := &txLanes{
+		classes:    classes,
+		senders:    make(map[common.Address]int),
+		recipients: make(map[common.Address]int),
+		signer:     signer,
+		accounts:   make(map[common.Address]*laneAccount),
+		members:    make([]map[common.Address]struct{}, len(classes)),
+	}
+	for i := len(classes) - 1; i >= 0; i-- {
+		for _, addr := range classes[i].Senders {
+			lanes.senders[addr] = i
+		}
+		for _, addr := range classes[i].Recipients {
+			lanes.recipients[addr] = i
+		}
+		lanes.members[i] = make(map[common.Address]struct{})
+		log.Info("Configured transaction priority class", "name", classes[i].Name, "senders", len(classes[i].Senders), "recipients", len(classes[i].Recipients), "kinds", classes[i].Kinds, "slots", classes[i].Slots, "queue", classes[i].Queue)
+	}
+	return lanes
+}
+
+// added implements txIndexer, accounting for a transaction entering the pool.
+func (l *txLanes) added(tx *types.Transaction) {
+	if len(l.classes) == 0 {
+		return
+	}
+	from, _ := types.Sender(l.signer, tx) // already validated during insertion
+	account := l.accounts[from]
+	if account == nil {
+		account = &laneAccount{lanes: make([]int, len(l.classes)), lane: l.bulk()}
+		l.accounts[from] = account
+	}
+	account.txs++
+	if lane := l.classify(from, tx); lane < l.bulk() {
+		account.lanes[lane]++
+	}
+	l.update(from, account)
+}
+
+// removed implements txIndexer, accounting for a transaction leaving the pool.
+func (l *txLanes) removed(tx *types.Transaction) {
+	if len(l.classes) == 0 {
+		return
+	}
+	from, _ := types.Sender(l.signer, tx)
+	account := l.accounts[from]
+	if account == nil {
+		return
+	}
+	account.txs--
+	if lane := l.classify(from, tx); lane < l.bulk() {
+		account.lanes[lane]--
+	}
+	l.update(from, account)
+}
+
+// update recomputes the lane of an account after its transactions changed,
+// moving it between the lane members.
+func (l *txLanes) update(addr common.Address, account *laneAccount) {
+	lane := l.bulk()
+	if account.txs > 0 {
+		if i, ok := l.senders[addr]; ok {
+			lane = i
+		} else {
+			for i, count := range account.lanes {
+				if count > 0 {
+					lane = i
+					break
+				}
+			}
+		}
+	}
+	if lane != account.lane {
+		if account.lane < l.bulk() {
+			delete(l.members[account.lane], addr)
+		}
+		if lane < l.bulk() {
+			l.members[lane][addr] = struct{}{}
+		}
+		account.lane = lane
+	}
+	if account.txs == 0 {
+		delete(l.accounts, addr)
+	}
+}
+
+// lane returns the lane an account is served in.
+func (l *txLanes) lane(addr common.Address) int {
+	if account := l.accounts[addr]; account != nil {
+		return account.lane
+	}
+	if i, ok := l.senders[addr]; ok {
+		return i
+	}
+	return l.bulk()
+}
+
+// bulk returns the index of the lane holding unprioritised traffic.
+func (l *txLanes) bulk() int {
+	return len(l.classes)
+}
+
+// classify returns the lane of a single transaction sent by from.
+func (l *txLanes) classify(from common.Address, tx *types.Transaction) int {
+	lane := l.bulk()
+	if i, ok := l.senders[from]; ok {
+		lane = i
+	}
+	if to := tx.To(); to != nil {
+		if i, ok := l.recipients[*to]; ok && i < lane {
+			lane = i
+		}
+	}
+	kinds := txKinds(tx)
+	for i := 0; i < lane; i++ {
+		if l.classes[i].Kinds&kinds != 0 {
+			return i
+		}
+	}
+	return lane
+}
+
+// capacity returns the total number of transaction slots reserved for the
+// priority classes.
+func (l *txLanes) capacity() uint64 {
+	var slots uint64
+	for _, class := range l.classes {
+		slots += class.Slots + class.Queue
+	}
+	return slots
+}
+
+// accountLane returns the lane an account is served in, based on all of its
+// pooled transactions.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxPool) accountLane(addr common.Address) int {
+	return pool.lanes.lane(addr)
+}
+
+// prioritised reports whether an account is served in one of the priority
+// lanes, exempting it from the bulk traffic limits.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxPool) prioritised(addr common.Address) bool {
+	return pool.accountLane(addr) < pool.lanes.bulk()
+}
+
+// PendingLanes retrieves all currently processable transactions, split by
+// priority lane (highest priority first, bulk traffic last), grouped by origin
+// account and sorted by nonce. The returned transaction sets are copies and can
+// be freely modified by calling code.
+func (pool *TxPool) PendingLanes() ([]map[common.Address]types.Transactions, error) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	lanes := make([]map[common.Address]types.Transactions, pool.lanes.bulk()+1)
+	for i := range lanes {
+		lanes[i] = make(map[common.Address]types.Transactions)
+	}
+	for addr, list := range pool.pending {
+		lanes[pool.accountLane(addr)][addr] = list.Flatten()
+	}
+	return lanes, nil
+}
+
+// capPriorityLanes enforces the per-class slot limits of the priority lanes,
+// dropping the highest nonce transactions of the largest accounts first.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxPool) capPriorityLanes() {
+	if len(pool.lanes.classes) == 0 {
+		return
+	}
+	for i, class := range pool.lanes.classes {
+		var pending, queued []common.Address
+		for addr := range pool.lanes.members[i] {
+			if pool.pending[addr] != nil {
+				pending = append(pending, addr)
+			}
+			if pool.queue[addr] != nil {
+				queued = append(queued, addr)
+			}
+		}
+		pool.capLane(pool.pending, pending, class.Slots)
+		pool.capLane(pool.queue, queued, class.Queue)
+	}
+}
+
+// capLane drops transactions of the given accounts from lists until at most
+// limit remain.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxPool) capLane(lists map[common.Address]*txList, accounts []common.Address, limit uint64) {
+	var count uint64
+	for _, addr := range accounts {
+		count += uint64(lists[addr].Len())
+	}
+	for count > limit {
+		// Pick the account with the most transactions in the lane
+		sort.Slice(accounts, func(i, j int) bool {
+			return lists[accounts[i]].Len() > lists[accounts[j]].Len()
+		})
+		txs := lists[accounts[0]].Flatten()
+		tx := txs[len(txs)-1]
+
+		// Removing the last pending transaction never demotes any others
+		pool.recordDiff(TxDropped, tx, TxDropCapacity, nil)
+		pool.removeTx(tx.Hash(), true)
+		priorityRateLimitCounter.Inc(1)
+		log.Trace("Removed class-limit-exceeding transaction", "hash", tx.Hash())
+
+		if len(txs) == 1 {
+			accounts = accounts[1:]
+		}
+		count--
+	}
+}
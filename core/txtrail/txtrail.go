@@ -0,0 +1,158 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package txtrail records the lifecycle of the transactions submitted through
+// the RPC APIs, from ingress to receipt storage, for support investigations.
+//
+// Each transaction is assigned a trace ID when its request is received. The ID
+// is carried by the request context until the transaction hash is known, and
+// looked up by hash in the later stages. Every recorded stage is also logged
+// with the trace ID, so the logs of a transaction can be correlated.
+package txtrail
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// maxTrails is the number of transaction trails retained, the oldest ones
+// being dropped first.
+const maxTrails = 4096
+
+// Stages of the transaction lifecycle.
+const (
+	StageReceived  = "received"  // Request received by the RPC API
+	StagePrivacy   = "ptm"       // Private payload stored in the transaction manager
+	StageSubmit    = "submit"    // Transaction signed and submitted to the pool
+	StageRejected  = "rejected"  // Transaction refused by the pool
+	StagePooled    = "pooled"    // Transaction accepted by the pool
+	StageCancelled = "cancelled" // Transaction cancelled by its sender
+	StageIncluded  = "included"  // Transaction included in a block written to the chain
+	StageReceipt   = "receipt"   // Receipt of the transaction stored
+)
+
+// Event is a stage reached by a transaction.
+type Event struct {
+	Stage   string            `json:"stage"`
+	Time    time.Time         `json:"time"`
+	Details map[string]string `json:"details,omitempty"`
+}
+
+// Trail is the recorded lifecycle of a transaction.
+type Trail struct {
+	ID     string      `json:"id"`
+	Hash   common.Hash `json:"hash"`
+	Events []Event     `json:"events"`
+}
+
+var (
+	lock   sync.Mutex
+	trails = make(map[common.Hash]*Trail)
+	order  []common.Hash // Bound transaction hashes, oldest first
+)
+
+type contextKey struct{}
+
+// New creates a trail with a fresh trace ID, recording its first stage.
+func New(stage string, ctx ...interface{}) *Trail {
+	var id [8]byte
+	rand.Read(id[:])
+
+	t := &Trail{ID: hex.EncodeToString(id[:])}
+	t.Record(stage, ctx...)
+	return t
+}
+
+// NewContext returns a copy of ctx carrying the trail.
+func NewContext(ctx context.Context, t *Trail) context.Context {
+	return context.WithValue(ctx, contextKey{}, t)
+}
+
+// FromContext returns the trail carried by ctx, or nil.
+func FromContext(ctx context.Context) *Trail {
+	t, _ := ctx.Value(contextKey{}).(*Trail)
+	return t
+}
+
+// Record appends a stage to the trail and logs it. The context is a list of
+// alternating keys and values, as taken by the loggers.
+func (t *Trail) Record(stage string, ctx ...interface{}) {
+	event := Event{Stage: stage, Time: time.Now()}
+	if len(ctx) > 0 {
+		event.Details = make(map[string]string, len(ctx)/2)
+		for i := 0; i+1 < len(ctx); i += 2 {
+			event.Details[fmt.Sprint(ctx[i])] = fmt.Sprint(ctx[i+1])
+		}
+	}
+	lock.Lock()
+	t.Events = append(t.Events, event)
+	hash := t.Hash
+	lock.Unlock()
+
+	log.Debug("Transaction trail", append([]interface{}{"trace", t.ID, "stage", stage, "hash", hash}, ctx...)...)
+}
+
+// Bind associates the trail with the hash of its transaction, making the later
+// stages recordable by hash.
+func (t *Trail) Bind(hash common.Hash) {
+	lock.Lock()
+	defer lock.Unlock()
+
+	if _, ok := trails[hash]; ok {
+		return
+	}
+	t.Hash = hash
+	trails[hash] = t
+	order = append(order, hash)
+	if len(order) > maxTrails {
+		delete(trails, order[0])
+		order = order[1:]
+	}
+}
+
+// Record appends a stage to the trail of a transaction, if it has one.
+func Record(hash common.Hash, stage string, ctx ...interface{}) {
+	lock.Lock()
+	t := trails[hash]
+	lock.Unlock()
+
+	if t != nil {
+		t.Record(stage, ctx...)
+	}
+}
+
+// Get returns a copy of the trail of a transaction, or nil if none was recorded.
+func Get(hash common.Hash) *Trail {
+	lock.Lock()
+	defer lock.Unlock()
+
+	t := trails[hash]
+	if t == nil {
+		return nil
+	}
+	return &Trail{
+		ID:     t.ID,
+		Hash:   t.Hash,
+		Events: append([]Event{}, t.Events...),
+	}
+}
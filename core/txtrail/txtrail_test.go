@@ -0,0 +1,68 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package txtrail
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestTrail(t *testing.T) {
+	ctx := NewContext(context.Background(), New(StageReceived, "method", "eth_sendTransaction"))
+
+	trail := FromContext(ctx)
+	if trail == nil || len(trail.ID) != 16 {
+		t.Fatalf("trail not carried by context: %v", trail)
+	}
+	hash := common.HexToHash("0x01")
+	Record(hash, StagePooled) // not bound yet, dropped
+
+	trail.Bind(hash)
+	Record(hash, StageIncluded, "number", big.NewInt(5))
+
+	have := Get(hash)
+	if have == nil || have.ID != trail.ID || have.Hash != hash {
+		t.Fatalf("trail mismatch: have %+v, want id %s", have, trail.ID)
+	}
+	if len(have.Events) != 2 {
+		t.Fatalf("event count mismatch: have %d, want 2", len(have.Events))
+	}
+	if have.Events[0].Details["method"] != "eth_sendTransaction" {
+		t.Errorf("ingress details mismatch: have %v", have.Events[0].Details)
+	}
+	if have.Events[1].Stage != StageIncluded || have.Events[1].Details["number"] != "5" {
+		t.Errorf("inclusion event mismatch: have %+v", have.Events[1])
+	}
+}
+
+// Tests that only the most recent trails are retained.
+func TestTrailEviction(t *testing.T) {
+	first := common.HexToHash("0xff01")
+	New(StageReceived).Bind(first)
+	for i := 0; i < maxTrails; i++ {
+		New(StageReceived).Bind(common.BigToHash(big.NewInt(int64(0x10000 + i))))
+	}
+	if Get(first) != nil {
+		t.Errorf("oldest trail not evicted")
+	}
+	if Get(common.BigToHash(big.NewInt(0x10000+maxTrails-1))) == nil {
+		t.Errorf("newest trail evicted")
+	}
+}
@@ -0,0 +1,65 @@
+package types
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// MaxAnchorURISize is the maximum length of the storage URI of a document anchor.
+const MaxAnchorURISize = 1024
+
+var (
+	errAnchorNoHash  = errors.New("document anchor without hash")
+	errAnchorNoURI   = errors.New("document anchor without storage URI")
+	errAnchorURISize = errors.New("document anchor storage URI too long")
+)
+
+// DocumentAnchor references a document stored off-chain, by the hash and the
+// size of its content and the URI it can be retrieved from. It is carried in
+// the data of a transaction sent to the anchor address of the chain
+// configuration, in place of the document.
+type DocumentAnchor struct {
+	Hash common.Hash `json:"hash"`
+	Size uint64      `json:"size"`
+	URI  string      `json:"uri"`
+}
+
+// Validate checks the anchor is complete, with a URI of at most MaxAnchorURISize.
+func (a *DocumentAnchor) Validate() error {
+	switch {
+	case a.Hash == (common.Hash{}):
+		return errAnchorNoHash
+	case a.URI == "":
+		return errAnchorNoURI
+	case len(a.URI) > MaxAnchorURISize:
+		return errAnchorURISize
+	}
+	return nil
+}
+
+// NewAnchorTransaction creates a transaction anchoring the document, sent to the
+// anchor address of the chain.
+func NewAnchorTransaction(nonce uint64, to common.Address, anchor *DocumentAnchor, gasLimit uint64) (*Transaction, error) {
+	data, err := rlp.EncodeToBytes(anchor)
+	if err != nil {
+		return nil, err
+	}
+	return NewTransaction(nonce, to, new(big.Int), gasLimit, new(big.Int), data), nil
+}
+
+// DocumentAnchor decodes the anchor carried by the data of the transaction,
+// failing if it isn't valid. Whether the transaction is a document anchor at
+// all depends on its recipient being the anchor address of the chain.
+func (tx *Transaction) DocumentAnchor() (*DocumentAnchor, error) {
+	anchor := new(DocumentAnchor)
+	if err := rlp.DecodeBytes(tx.Data(), anchor); err != nil {
+		return nil, err
+	}
+	if err := anchor.Validate(); err != nil {
+		return nil, err
+	}
+	return anchor, nil
+}
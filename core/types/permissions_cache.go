@@ -101,6 +101,7 @@ type PermissionConfig struct {
 	RoleAddress    common.Address `json:"roleMgrAddress"`
 	VoterAddress   common.Address `json:"voterMgrAddress"`
 	OrgAddress     common.Address `json:"orgMgrAddress"`
+	QuotaAddress   common.Address `json:"quotaMgrAddress,omitempty"` // Quorum: optional org quota manager
 	NwAdminOrg     string         `json:"nwAdminOrg"`
 	NwAdminRole    string         `json:"nwAdminRole"`
 	OrgAdminRole   string         `json:"orgAdminRole"`
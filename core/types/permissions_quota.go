@@ -0,0 +1,143 @@
+package types
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// OrgQuota limits the block space and pool slots used by an organization,
+// including all of its sub organizations. Zero values mean no limit.
+type OrgQuota struct {
+	TxPerBlock  uint64 `json:"txPerBlock"`  // Maximum number of transactions per block
+	GasPerBlock uint64 `json:"gasPerBlock"` // Maximum gas used per block
+	MaxPending  uint64 `json:"maxPending"`  // Maximum number of transactions in the pool
+}
+
+// Validate checks that the quota limits anything, and that the transactions of
+// the organization can fit in its block gas.
+func (q OrgQuota) Validate() error {
+	if q.TxPerBlock == 0 && q.GasPerBlock == 0 && q.MaxPending == 0 {
+		return errors.New("quota sets no limit")
+	}
+	if q.GasPerBlock > 0 && q.GasPerBlock < params.TxGas {
+		return errors.New("gasPerBlock below the gas of a transaction")
+	}
+	return nil
+}
+
+var (
+	orgQuotas   = make(map[string]OrgQuota)
+	orgQuotasMu sync.RWMutex
+)
+
+// SetOrgQuotas replaces the organization quotas, keyed by the
+// id of the ultimate parent organization.
+func SetOrgQuotas(quotas map[string]OrgQuota) {
+	orgQuotasMu.Lock()
+	defer orgQuotasMu.Unlock()
+
+	orgQuotas = make(map[string]OrgQuota, len(quotas))
+	for org, quota := range quotas {
+		orgQuotas[org] = quota
+	}
+}
+
+// SetOrgQuota sets the quota of an organization, replacing its existing one.
+func SetOrgQuota(org string, quota OrgQuota) {
+	orgQuotasMu.Lock()
+	defer orgQuotasMu.Unlock()
+
+	orgQuotas[org] = quota
+}
+
+// RemoveOrgQuota removes the quota of an organization.
+func RemoveOrgQuota(org string) {
+	orgQuotasMu.Lock()
+	defer orgQuotasMu.Unlock()
+
+	delete(orgQuotas, org)
+}
+
+// GetOrgQuotas returns a copy of the organization quotas.
+func GetOrgQuotas() map[string]OrgQuota {
+	orgQuotasMu.RLock()
+	defer orgQuotasMu.RUnlock()
+
+	quotas := make(map[string]OrgQuota, len(orgQuotas))
+	for org, quota := range orgQuotas {
+		quotas[org] = quota
+	}
+	return quotas
+}
+
+// GetAcctQuota returns the ultimate parent organization of an account along
+// with its quota. If the account is unknown or its organization has no quota
+// nil is returned.
+func GetAcctQuota(acct common.Address) (string, *OrgQuota) {
+	orgQuotasMu.RLock()
+	defer orgQuotasMu.RUnlock()
+
+	if len(orgQuotas) == 0 {
+		return "", nil
+	}
+	org := GetAcctOrg(acct)
+	if quota, ok := orgQuotas[org]; ok {
+		return org, &quota
+	}
+	return "", nil
+}
+
+// GetAcctOrg returns the ultimate parent organization of an account, the one
+// its quota is accounted against, or an empty string if the account is unknown.
+func GetAcctOrg(acct common.Address) string {
+	a := AcctInfoMap.GetAccount(acct)
+	if a == nil {
+		return ""
+	}
+	if o := OrgInfoMap.GetOrg(a.OrgId); o != nil && o.UltimateParent != "" {
+		return o.UltimateParent
+	}
+	return a.OrgId
+}
+
+// OrgQuotaUsage tracks the block space used by each organization while a
+// block is assembled.
+type OrgQuotaUsage struct {
+	txs map[string]uint64
+	gas map[string]uint64
+}
+
+func NewOrgQuotaUsage() *OrgQuotaUsage {
+	return &OrgQuotaUsage{
+		txs: make(map[string]uint64),
+		gas: make(map[string]uint64),
+	}
+}
+
+// Allow reports whether a transaction of the given gas limit sent by from
+// still fits in the quota of its organization.
+func (u *OrgQuotaUsage) Allow(from common.Address, gas uint64) bool {
+	org, quota := GetAcctQuota(from)
+	if quota == nil {
+		return true
+	}
+	if quota.TxPerBlock > 0 && u.txs[org] >= quota.TxPerBlock {
+		return false
+	}
+	if quota.GasPerBlock > 0 && u.gas[org]+gas > quota.GasPerBlock {
+		return false
+	}
+	return true
+}
+
+// Record accounts an included transaction sent by from against the quota of
+// its organization.
+func (u *OrgQuotaUsage) Record(from common.Address, gasUsed uint64) {
+	if org, quota := GetAcctQuota(from); quota != nil {
+		u.txs[org]++
+		u.gas[org] += gasUsed
+	}
+}
@@ -0,0 +1,67 @@
+package types
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	testifyassert "github.com/stretchr/testify/assert"
+)
+
+func TestOrgQuotaUsage(t *testing.T) {
+	assert := testifyassert.New(t)
+
+	var (
+		parentAcct = common.BytesToAddress([]byte("quota-parent"))
+		subAcct    = common.BytesToAddress([]byte("quota-sub"))
+		otherAcct  = common.BytesToAddress([]byte("quota-other"))
+	)
+	OrgInfoMap.UpsertOrg("QORG", "", "QORG", big.NewInt(1), OrgApproved)
+	OrgInfoMap.UpsertOrg("SUB", "QORG", "QORG", big.NewInt(2), OrgApproved)
+	AcctInfoMap.UpsertAccount("QORG", ORGADMIN, parentAcct, true, AcctActive)
+	AcctInfoMap.UpsertAccount("QORG.SUB", ORGADMIN, subAcct, false, AcctActive)
+
+	SetOrgQuotas(map[string]OrgQuota{"QORG": {TxPerBlock: 2, GasPerBlock: 100000}})
+	defer SetOrgQuotas(nil)
+
+	// sub organizations share the quota of their ultimate parent
+	org, quota := GetAcctQuota(subAcct)
+	assert.Equal("QORG", org)
+	assert.NotNil(quota)
+
+	org, quota = GetAcctQuota(otherAcct)
+	assert.Equal("", org)
+	assert.Nil(quota)
+
+	usage := NewOrgQuotaUsage()
+	assert.False(usage.Allow(parentAcct, 100001), "expected gas quota to be enforced")
+	assert.True(usage.Allow(parentAcct, 60000))
+	usage.Record(parentAcct, 60000)
+	assert.False(usage.Allow(subAcct, 50000), "expected gas quota to be shared with sub org")
+	assert.True(usage.Allow(subAcct, 21000))
+	usage.Record(subAcct, 21000)
+	assert.False(usage.Allow(parentAcct, 1), "expected transaction quota to be enforced")
+	assert.True(usage.Allow(otherAcct, 1000000), "expected accounts without quota to be unrestricted")
+}
+
+func TestOrgQuotaValidate(t *testing.T) {
+	assert := testifyassert.New(t)
+
+	assert.NoError(OrgQuota{MaxPending: 10}.Validate())
+	assert.Error(OrgQuota{}.Validate(), "expected empty quota to be rejected")
+	assert.Error(OrgQuota{GasPerBlock: 20000}.Validate(), "expected gas quota below a transfer to be rejected")
+}
+
+func TestSetOrgQuota(t *testing.T) {
+	assert := testifyassert.New(t)
+
+	SetOrgQuotas(map[string]OrgQuota{"QORG": {TxPerBlock: 2}})
+	defer SetOrgQuotas(nil)
+
+	SetOrgQuota("QORG", OrgQuota{MaxPending: 10})
+	SetOrgQuota("OTHER", OrgQuota{TxPerBlock: 1})
+	assert.Equal(map[string]OrgQuota{"QORG": {MaxPending: 10}, "OTHER": {TxPerBlock: 1}}, GetOrgQuotas())
+
+	RemoveOrgQuota("QORG")
+	assert.Equal(map[string]OrgQuota{"OTHER": {TxPerBlock: 1}}, GetOrgQuotas())
+}
@@ -55,6 +55,9 @@ type Receipt struct {
 	TxHash          common.Hash    `json:"transactionHash" gencodec:"required"`
 	ContractAddress common.Address `json:"contractAddress"`
 	GasUsed         uint64         `json:"gasUsed" gencodec:"required"`
+
+	// Quorum: reason a private transaction reverted with, only known to its parties
+	RevertReason string `json:"revertReason,omitempty"`
 }
 
 type receiptMarshaling struct {
@@ -80,6 +83,7 @@ type receiptStorageRLP struct {
 	ContractAddress   common.Address
 	Logs              []*LogForStorage
 	GasUsed           uint64
+	RevertReason      []string `rlp:"tail"` // Quorum: only present if set, keeping the legacy encoding of the other receipts
 }
 
 // NewReceipt creates a barebone transaction receipt, copying the init fields.
@@ -140,7 +144,7 @@ func (r *Receipt) statusEncoding() []byte {
 // Size returns the approximate memory used by all internal contents. It is used
 // to approximate and limit the memory consumption of various caches.
 func (r *Receipt) Size() common.StorageSize {
-	size := common.StorageSize(unsafe.Sizeof(*r)) + common.StorageSize(len(r.PostState))
+	size := common.StorageSize(unsafe.Sizeof(*r)) + common.StorageSize(len(r.PostState)+len(r.RevertReason))
 
 	size += common.StorageSize(len(r.Logs)) * common.StorageSize(unsafe.Sizeof(Log{}))
 	for _, log := range r.Logs {
@@ -168,6 +172,9 @@ func (r *ReceiptForStorage) EncodeRLP(w io.Writer) error {
 	for i, log := range r.Logs {
 		enc.Logs[i] = (*LogForStorage)(log)
 	}
+	if r.RevertReason != "" {
+		enc.RevertReason = []string{r.RevertReason}
+	}
 	return rlp.Encode(w, enc)
 }
 
@@ -189,6 +196,10 @@ func (r *ReceiptForStorage) DecodeRLP(s *rlp.Stream) error {
 	}
 	// Assign the implementation fields
 	r.TxHash, r.ContractAddress, r.GasUsed = dec.TxHash, dec.ContractAddress, dec.GasUsed
+	r.RevertReason = ""
+	if len(dec.RevertReason) > 0 {
+		r.RevertReason = dec.RevertReason[0]
+	}
 	return nil
 }
 
@@ -0,0 +1,138 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"bytes"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// AccessTuple is an account along with the storage slots of it accessed.
+type AccessTuple struct {
+	Address     common.Address `json:"address"`
+	StorageKeys []common.Hash  `json:"storageKeys"`
+}
+
+// AccessList is the list of the accounts and storage slots accessed by a
+// transaction, in the format of EIP-2930.
+type AccessList []AccessTuple
+
+// accessSet is the set of the storage slots accessed by account.
+type accessSet map[common.Address]map[common.Hash]struct{}
+
+func (s accessSet) addAddress(addr common.Address) {
+	if _, ok := s[addr]; !ok {
+		s[addr] = make(map[common.Hash]struct{})
+	}
+}
+
+func (s accessSet) addSlot(addr common.Address, slot common.Hash) {
+	s.addAddress(addr)
+	s[addr][slot] = struct{}{}
+}
+
+// list returns the accessed accounts and slots, sorted.
+func (s accessSet) list() AccessList {
+	list := make(AccessList, 0, len(s))
+	for addr, slots := range s {
+		tuple := AccessTuple{Address: addr, StorageKeys: make([]common.Hash, 0, len(slots))}
+		for slot := range slots {
+			tuple.StorageKeys = append(tuple.StorageKeys, slot)
+		}
+		sort.Slice(tuple.StorageKeys, func(i, j int) bool {
+			return bytes.Compare(tuple.StorageKeys[i][:], tuple.StorageKeys[j][:]) < 0
+		})
+		list = append(list, tuple)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return bytes.Compare(list[i].Address[:], list[j].Address[:]) < 0
+	})
+	return list
+}
+
+// AccessListTracer is a tracer collecting the accounts and storage slots a
+// transaction accesses. The accesses to the public and the private state are
+// collected apart. The sender, the recipient and the precompiled contracts are
+// accessed by every transaction and left out, but not the slots of the
+// recipient.
+type AccessListTracer struct {
+	excluded map[common.Address]bool
+	public   accessSet
+	private  accessSet
+}
+
+// NewAccessListTracer creates a tracer collecting the accesses of a transaction.
+func NewAccessListTracer() *AccessListTracer {
+	return &AccessListTracer{
+		excluded: make(map[common.Address]bool),
+		public:   make(accessSet),
+		private:  make(accessSet),
+	}
+}
+
+// CaptureStart records the sender and the recipient of the transaction, or the
+// contract it creates.
+func (a *AccessListTracer) CaptureStart(from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) error {
+	a.excluded[from], a.excluded[to] = true, true
+	return nil
+}
+
+// CaptureState records the accounts and slots accessed by an opcode, in the
+// state of the running contract.
+func (a *AccessListTracer) CaptureState(env *EVM, pc uint64, op OpCode, gas, cost uint64, memory *Memory, stack *Stack, contract *Contract, depth int, err error) error {
+	accesses := a.public
+	if env.StateDB != env.PublicState() {
+		accesses = a.private
+	}
+	size := len(stack.Data())
+	switch {
+	case (op == SLOAD || op == SSTORE) && size >= 1:
+		accesses.addSlot(contract.Address(), common.BigToHash(stack.Back(0)))
+	case (op == EXTCODECOPY || op == EXTCODEHASH || op == EXTCODESIZE || op == BALANCE || op == SELFDESTRUCT) && size >= 1:
+		a.addAddress(env, accesses, common.BigToAddress(stack.Back(0)))
+	case (op == CALL || op == CALLCODE || op == DELEGATECALL || op == STATICCALL) && size >= 2:
+		a.addAddress(env, accesses, common.BigToAddress(stack.Back(1)))
+	}
+	return nil
+}
+
+// addAddress records the access of an account, unless every transaction
+// accesses it.
+func (a *AccessListTracer) addAddress(env *EVM, accesses accessSet, addr common.Address) {
+	if a.excluded[addr] || env.precompile(addr) != nil {
+		return
+	}
+	accesses.addAddress(addr)
+}
+
+func (a *AccessListTracer) CaptureFault(env *EVM, pc uint64, op OpCode, gas, cost uint64, memory *Memory, stack *Stack, contract *Contract, depth int, err error) error {
+	return nil
+}
+
+func (a *AccessListTracer) CaptureEnd(output []byte, gasUsed uint64, t time.Duration, err error) error {
+	return nil
+}
+
+// AccessList returns the accounts and slots of the public state accessed.
+func (a *AccessListTracer) AccessList() AccessList { return a.public.list() }
+
+// PrivateAccessList returns the accounts and slots of the private state accessed.
+func (a *AccessListTracer) PrivateAccessList() AccessList { return a.private.list() }
@@ -0,0 +1,80 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that the accesses of a private contract calling a public one are
+// collected apart, leaving out the sender, the recipient and the precompiles.
+func TestAccessListTracer(t *testing.T) {
+	var (
+		sender  = common.HexToAddress("0xff")
+		private = common.HexToAddress("0x0a")
+		public  = common.HexToAddress("0x0b")
+		other   = common.HexToAddress("0xbb")
+	)
+	publicState, _ := state.New(common.Hash{}, state.NewDatabase(ethdb.NewMemDatabase()))
+	privateState, _ := state.New(common.Hash{}, state.NewDatabase(ethdb.NewMemDatabase()))
+
+	// The private contract reads slot 1, then calls the ecrecover precompile and
+	// the public contract, which reads slot 2 and the balance of another account
+	call := func(addr common.Address) []byte {
+		code := []byte{byte(PUSH1), 0, byte(PUSH1), 0, byte(PUSH1), 0, byte(PUSH1), 0, byte(PUSH1), 0, byte(PUSH20)}
+		return append(append(code, addr.Bytes()...), byte(GAS), byte(CALL), byte(POP))
+	}
+	code := []byte{byte(PUSH1), 1, byte(SLOAD), byte(POP)}
+	code = append(code, call(common.BytesToAddress([]byte{1}))...)
+	code = append(code, call(public)...)
+	privateState.SetCode(private, append(code, byte(STOP)))
+
+	code = []byte{byte(PUSH1), 2, byte(SLOAD), byte(POP), byte(PUSH20)}
+	publicState.SetCode(public, append(append(code, other.Bytes()...), byte(BALANCE), byte(POP), byte(STOP)))
+
+	tracer := NewAccessListTracer()
+	ctx := Context{
+		CanTransfer: func(StateDB, common.Address, *big.Int) bool { return true },
+		Transfer:    func(StateDB, common.Address, common.Address, *big.Int) {},
+		BlockNumber: big.NewInt(0),
+	}
+	evm := NewEVM(ctx, publicState, privateState, params.TestChainConfig, Config{Debug: true, Tracer: tracer})
+	if _, _, err := evm.Call(AccountRef(sender), private, nil, 1000000, new(big.Int)); err != nil {
+		t.Fatalf("failed to call: %v", err)
+	}
+	wantPrivate := AccessList{
+		{Address: private, StorageKeys: []common.Hash{common.BigToHash(big.NewInt(1))}},
+		{Address: public, StorageKeys: []common.Hash{}},
+	}
+	if have := tracer.PrivateAccessList(); !reflect.DeepEqual(have, wantPrivate) {
+		t.Errorf("private access list mismatch: have %+v, want %+v", have, wantPrivate)
+	}
+	wantPublic := AccessList{
+		{Address: public, StorageKeys: []common.Hash{common.BigToHash(big.NewInt(2))}},
+		{Address: other, StorageKeys: []common.Hash{}},
+	}
+	if have := tracer.AccessList(); !reflect.DeepEqual(have, wantPublic) {
+		t.Errorf("public access list mismatch: have %+v, want %+v", have, wantPublic)
+	}
+}
@@ -0,0 +1,244 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/crypto/sha3"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// Operations of the bridge pre-compiled contracts, the first byte of their input.
+const (
+	bridgeVerifyHeader byte = 1 // Verify a header against a validator set
+	bridgeVerifyProof  byte = 2 // Verify a Merkle proof against a trie root
+)
+
+// istanbulCommitCode is the code of the Istanbul commit messages, appended to
+// the hash of the blocks signed by the committed seals.
+const istanbulCommitCode = 2
+
+// cliqueSealLength is the length of the signature of the clique headers at the
+// end of their extra-data.
+const cliqueSealLength = 65
+
+var (
+	errBadBridgeInput    = errors.New("bad bridge input")
+	errBridgeNoQuorum    = errors.New("bridge header not signed by a quorum of the validators")
+	errBridgeBadSigner   = errors.New("bridge header signed by a non-validator")
+	errBridgeDoubleSeal  = errors.New("bridge header sealed twice by a validator")
+	errBridgeNotIstanbul = errors.New("bridge header not an istanbul header")
+)
+
+// bridgeHeaderInput is the input of the header verification, the RLP encoded
+// header and the validators it must be signed by.
+type bridgeHeaderInput struct {
+	Header     *types.Header
+	Validators []common.Address
+}
+
+// bridgeProofInput is the input of the Merkle proof verification, the root of
+// the trie, the key and the RLP encoded nodes on the path to it.
+type bridgeProofInput struct {
+	Root  common.Hash
+	Key   []byte
+	Proof [][]byte
+}
+
+// bridgeVerify implements the verification of the headers and Merkle proofs of
+// another chain as a native contract, for bridges to trust the other chain
+// through its validators rather than through relayers.
+//
+// Given a header and a validator set, it returns the hash, parent hash, number,
+// time, state, transaction and receipt roots of the header followed by the hash
+// of the validators, each as a word, if signed by the validators. The hash of
+// the validators is the one of their addresses padded to words, as
+// keccak256(abi.encodePacked(validators)) in Solidity, for the caller to check
+// them against the trusted ones.
+//
+// Given a trie root, a key and a proof, it returns the value proven under the
+// key, empty if proven absent.
+//
+// The headers of proof-of-work (ethash) chains can't be verified, they are
+// only trusted along with the total difficulty of their chain, see
+// params.BridgeConfig. Their Merkle proofs can still be, against roots the
+// caller trusts otherwise.
+type bridgeVerify struct {
+	config *params.BridgeConfig
+}
+
+// RequiredGas returns the gas required to execute the pre-compiled contract.
+func (c *bridgeVerify) RequiredGas(input []byte) uint64 {
+	return params.BridgeVerifyBaseGas + uint64(len(input)+31)/32*params.BridgeVerifyPerWordGas
+}
+
+func (c *bridgeVerify) Run(input []byte) ([]byte, error) {
+	if len(input) == 0 {
+		return nil, errBadBridgeInput
+	}
+	switch input[0] {
+	case bridgeVerifyHeader:
+		var args bridgeHeaderInput
+		if err := rlp.DecodeBytes(input[1:], &args); err != nil {
+			return nil, errBadBridgeInput
+		}
+		return c.verifyHeader(args.Header, args.Validators)
+
+	case bridgeVerifyProof:
+		var args bridgeProofInput
+		if err := rlp.DecodeBytes(input[1:], &args); err != nil {
+			return nil, errBadBridgeInput
+		}
+		proofs := ethdb.NewMemDatabase()
+		for _, node := range args.Proof {
+			proofs.Put(crypto.Keccak256(node), node)
+		}
+		value, _, err := trie.VerifyProof(args.Root, args.Key, proofs)
+		if err != nil {
+			return nil, err
+		}
+		return value, nil
+	}
+	return nil, errBadBridgeInput
+}
+
+// verifyHeader checks the signatures of a header against the validators under
+// the consensus of the other chain, returning the fields of the header.
+func (c *bridgeVerify) verifyHeader(header *types.Header, validators []common.Address) ([]byte, error) {
+	if len(validators) == 0 {
+		return nil, errBadBridgeInput
+	}
+	valSet := make(map[common.Address]bool, len(validators))
+	for _, val := range validators {
+		valSet[val] = true
+	}
+	switch c.config.Consensus {
+	case "istanbul":
+		if header.MixDigest != types.IstanbulDigest {
+			return nil, errBridgeNotIstanbul
+		}
+		extra, err := types.ExtractIstanbulExtra(header)
+		if err != nil {
+			return nil, err
+		}
+		// Count the distinct validators committing to the header
+		seal := append(header.Hash().Bytes(), istanbulCommitCode)
+		committers := make(map[common.Address]bool)
+		for _, committed := range extra.CommittedSeal {
+			signer, err := recoverSigner(crypto.Keccak256(seal), committed)
+			if err != nil {
+				return nil, err
+			}
+			if !valSet[signer] {
+				return nil, errBridgeBadSigner
+			}
+			if committers[signer] {
+				return nil, errBridgeDoubleSeal
+			}
+			committers[signer] = true
+		}
+		if len(committers) < c.istanbulQuorum(header.Number, len(valSet)) {
+			return nil, errBridgeNoQuorum
+		}
+
+	case "clique":
+		if len(header.Extra) < cliqueSealLength {
+			return nil, errBadBridgeInput
+		}
+		signer, err := recoverSigner(cliqueSigHash(header).Bytes(), header.Extra[len(header.Extra)-cliqueSealLength:])
+		if err != nil {
+			return nil, err
+		}
+		if !valSet[signer] {
+			return nil, errBridgeBadSigner
+		}
+
+	default:
+		return nil, fmt.Errorf("unsupported bridge consensus %q", c.config.Consensus)
+	}
+	// Return the fields of the header the bridges verify their messages with
+	packed := make([]byte, 0, 32*len(validators))
+	for _, val := range validators {
+		packed = append(packed, common.LeftPadBytes(val[:], 32)...)
+	}
+	ret := make([]byte, 0, 8*32)
+	ret = append(ret, header.Hash().Bytes()...)
+	ret = append(ret, header.ParentHash.Bytes()...)
+	ret = append(ret, common.BigToHash(header.Number).Bytes()...)
+	ret = append(ret, common.BigToHash(header.Time).Bytes()...)
+	ret = append(ret, header.Root.Bytes()...)
+	ret = append(ret, header.TxHash.Bytes()...)
+	ret = append(ret, header.ReceiptHash.Bytes()...)
+	ret = append(ret, crypto.Keccak256(packed)...)
+	return ret, nil
+}
+
+// istanbulQuorum returns the number of committed seals an Istanbul header of
+// the other chain requires with the given number of validators.
+func (c *bridgeVerify) istanbulQuorum(number *big.Int, validators int) int {
+	if c.config.Ceil2Nby3Block == nil || number.Cmp(c.config.Ceil2Nby3Block) < 0 {
+		f := int(math.Ceil(float64(validators)/3)) - 1
+		return 2*f + 1
+	}
+	return int(math.Ceil(float64(2*validators) / 3))
+}
+
+// recoverSigner returns the address of the signer of a hash.
+func recoverSigner(hash, sig []byte) (common.Address, error) {
+	pubkey, err := crypto.Ecrecover(hash, sig)
+	if err != nil {
+		return common.Address{}, err
+	}
+	var signer common.Address
+	copy(signer[:], crypto.Keccak256(pubkey[1:])[12:])
+	return signer, nil
+}
+
+// cliqueSigHash returns the hash signed by the sealer of a clique header, the
+// one of the header without the signature.
+func cliqueSigHash(header *types.Header) (hash common.Hash) {
+	hasher := sha3.NewKeccak256()
+	rlp.Encode(hasher, []interface{}{
+		header.ParentHash,
+		header.UncleHash,
+		header.Coinbase,
+		header.Root,
+		header.TxHash,
+		header.ReceiptHash,
+		header.Bloom,
+		header.Difficulty,
+		header.Number,
+		header.GasLimit,
+		header.GasUsed,
+		header.Time,
+		header.Extra[:len(header.Extra)-cliqueSealLength],
+		header.MixDigest,
+		header.Nonce,
+	})
+	hasher.Sum(hash[:0])
+	return hash
+}
@@ -0,0 +1,160 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// istanbulHeader creates an Istanbul header committed to by the given keys.
+func istanbulHeader(t *testing.T, validators []common.Address, committers []*ecdsa.PrivateKey) *types.Header {
+	header := &types.Header{
+		ParentHash: common.HexToHash("0x01"),
+		Root:       common.HexToHash("0x02"),
+		Number:     big.NewInt(10),
+		Time:       big.NewInt(1000),
+		Difficulty: big.NewInt(1),
+		MixDigest:  types.IstanbulDigest,
+	}
+	extra := &types.IstanbulExtra{Validators: validators, Seal: []byte{}, CommittedSeal: [][]byte{}}
+	encode := func() {
+		payload, err := rlp.EncodeToBytes(extra)
+		if err != nil {
+			t.Fatal(err)
+		}
+		header.Extra = append(make([]byte, types.IstanbulExtraVanity), payload...)
+	}
+	encode()
+	seal := append(header.Hash().Bytes(), istanbulCommitCode)
+	for _, key := range committers {
+		sig, err := crypto.Sign(crypto.Keccak256(seal), key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		extra.CommittedSeal = append(extra.CommittedSeal, sig)
+	}
+	encode()
+	return header
+}
+
+func bridgeInput(t *testing.T, op byte, args interface{}) []byte {
+	payload, err := rlp.EncodeToBytes(args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return append([]byte{op}, payload...)
+}
+
+func TestBridgeVerifyIstanbulHeader(t *testing.T) {
+	keys := make([]*ecdsa.PrivateKey, 4)
+	validators := make([]common.Address, 4)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		validators[i] = crypto.PubkeyToAddress(keys[i].PublicKey)
+	}
+	outsider, _ := crypto.GenerateKey()
+	bridge := &bridgeVerify{config: &params.BridgeConfig{Consensus: "istanbul"}}
+
+	tests := []struct {
+		committers []*ecdsa.PrivateKey
+		err        error
+	}{
+		{keys[:3], nil},
+		{keys, nil},
+		{keys[:2], errBridgeNoQuorum},
+		{[]*ecdsa.PrivateKey{keys[0], keys[1], outsider}, errBridgeBadSigner},
+		{[]*ecdsa.PrivateKey{keys[0], keys[1], keys[1]}, errBridgeDoubleSeal},
+	}
+	for i, tt := range tests {
+		header := istanbulHeader(t, validators, tt.committers)
+		ret, err := bridge.Run(bridgeInput(t, bridgeVerifyHeader, &bridgeHeaderInput{header, validators}))
+		if err != tt.err {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if len(ret) != 8*32 {
+			t.Fatalf("test %d: output length mismatch: have %d", i, len(ret))
+		}
+		if hash := common.BytesToHash(ret[:32]); hash != header.Hash() {
+			t.Errorf("test %d: hash mismatch: have %x, want %x", i, hash, header.Hash())
+		}
+		if root := common.BytesToHash(ret[4*32 : 5*32]); root != header.Root {
+			t.Errorf("test %d: state root mismatch: have %x, want %x", i, root, header.Root)
+		}
+	}
+}
+
+func TestBridgeVerifyCliqueHeader(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	signer := crypto.PubkeyToAddress(key.PublicKey)
+	bridge := &bridgeVerify{config: &params.BridgeConfig{Consensus: "clique"}}
+
+	header := &types.Header{Number: big.NewInt(1), Time: big.NewInt(1), Difficulty: big.NewInt(2), Extra: make([]byte, 32+cliqueSealLength)}
+	sig, _ := crypto.Sign(cliqueSigHash(header).Bytes(), key)
+	copy(header.Extra[32:], sig)
+
+	if _, err := bridge.Run(bridgeInput(t, bridgeVerifyHeader, &bridgeHeaderInput{header, []common.Address{signer}})); err != nil {
+		t.Fatalf("header of the signer rejected: %v", err)
+	}
+	other := common.HexToAddress("0x1234")
+	if _, err := bridge.Run(bridgeInput(t, bridgeVerifyHeader, &bridgeHeaderInput{header, []common.Address{other}})); err != errBridgeBadSigner {
+		t.Fatalf("header of a non-validator error mismatch: have %v, want %v", err, errBridgeBadSigner)
+	}
+}
+
+func TestBridgeVerifyProof(t *testing.T) {
+	tr, _ := trie.New(common.Hash{}, trie.NewDatabase(ethdb.NewMemDatabase()))
+	for i := byte(0); i < 16; i++ {
+		tr.Update([]byte{i}, bytes.Repeat([]byte{i + 1}, 40))
+	}
+	root := tr.Hash()
+	bridge := &bridgeVerify{config: &params.BridgeConfig{Consensus: "istanbul"}}
+
+	proofs := ethdb.NewMemDatabase()
+	if err := tr.Prove([]byte{5}, 0, proofs); err != nil {
+		t.Fatal(err)
+	}
+	var proof [][]byte
+	for _, key := range proofs.Keys() {
+		node, _ := proofs.Get(key)
+		proof = append(proof, node)
+	}
+	value, err := bridge.Run(bridgeInput(t, bridgeVerifyProof, &bridgeProofInput{root, []byte{5}, proof}))
+	if err != nil {
+		t.Fatalf("failed to verify the proof: %v", err)
+	}
+	if want := bytes.Repeat([]byte{6}, 40); !bytes.Equal(value, want) {
+		t.Errorf("value mismatch: have %x, want %x", value, want)
+	}
+	if _, err := bridge.Run(bridgeInput(t, bridgeVerifyProof, &bridgeProofInput{common.HexToHash("0xbad"), []byte{5}, proof})); err == nil {
+		t.Errorf("proof of another root verified")
+	}
+}
@@ -25,6 +25,7 @@ import (
 	"github.com/ethereum/go-ethereum/common/math"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/crypto/bn256"
+	"github.com/ethereum/go-ethereum/crypto/poseidon"
 	"github.com/ethereum/go-ethereum/params"
 	"golang.org/x/crypto/ripemd160"
 )
@@ -59,6 +60,23 @@ var PrecompiledContractsByzantium = map[common.Address]PrecompiledContract{
 	common.BytesToAddress([]byte{8}): &bn256Pairing{},
 }
 
+// Quorum
+//
+// PrecompiledContractsZK contains the Byzantium pre-compiled contracts along
+// with the zero knowledge friendly ones enabled by the zk precompiles fork. The
+// latter live outside the address range used by Ethereum hard forks.
+var PrecompiledContractsZK = map[common.Address]PrecompiledContract{
+	common.BytesToAddress([]byte{1}):    &ecrecover{},
+	common.BytesToAddress([]byte{2}):    &sha256hash{},
+	common.BytesToAddress([]byte{3}):    &ripemd160hash{},
+	common.BytesToAddress([]byte{4}):    &dataCopy{},
+	common.BytesToAddress([]byte{5}):    &bigModExp{},
+	common.BytesToAddress([]byte{6}):    &bn256Add{},
+	common.BytesToAddress([]byte{7}):    &bn256ScalarMul{},
+	common.BytesToAddress([]byte{8}):    &bn256Pairing{},
+	common.BytesToAddress([]byte{1, 0}): &poseidonHash{},
+}
+
 // RunPrecompiledContract runs and evaluates the output of a precompiled contract.
 func RunPrecompiledContract(p PrecompiledContract, input []byte, contract *Contract) (ret []byte, err error) {
 	gas := p.RequiredGas(input)
@@ -358,3 +376,41 @@ func (c *bn256Pairing) Run(input []byte) ([]byte, error) {
 	}
 	return false32Byte, nil
 }
+
+var (
+	// errBadPoseidonInput is returned if the poseidon input is not a whole
+	// number of field elements.
+	errBadPoseidonInput = errors.New("bad poseidon input size")
+)
+
+// poseidonHash implements the Poseidon hash over the bn256 scalar field as a
+// native contract.
+type poseidonHash struct{}
+
+// RequiredGas returns the gas required to execute the pre-compiled contract.
+//
+// The cost grows with the square of the number of inputs, matching the size of
+// the MDS multiplications. Oversized inputs are charged as the maximum since
+// they are rejected anyway.
+func (c *poseidonHash) RequiredGas(input []byte) uint64 {
+	words := uint64(len(input)+31) / 32
+	if words > poseidon.MaxInputs {
+		words = poseidon.MaxInputs
+	}
+	return params.PoseidonBaseGas + words*words*params.PoseidonQuadInputGas
+}
+
+func (c *poseidonHash) Run(input []byte) ([]byte, error) {
+	if len(input) == 0 || len(input)%32 > 0 {
+		return nil, errBadPoseidonInput
+	}
+	inputs := make([]*big.Int, len(input)/32)
+	for i := range inputs {
+		inputs[i] = new(big.Int).SetBytes(input[i*32 : (i+1)*32])
+	}
+	hash, err := poseidon.Hash(inputs)
+	if err != nil {
+		return nil, err
+	}
+	return common.LeftPadBytes(hash.Bytes(), 32), nil
+}
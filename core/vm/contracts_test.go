@@ -336,8 +336,30 @@ var bn256PairingTests = []precompiledTest{
 	},
 }
 
+// poseidonTests are the test and benchmark data for the poseidon precompiled
+// contract, matching the circomlib implementation.
+var poseidonTests = []precompiledTest{
+	{
+		input:    "0000000000000000000000000000000000000000000000000000000000000001",
+		expected: "29176100eaa962bdc1fe6c654d6a3c130e96a4d1168b33848b897dc502820133",
+		name:     "one",
+	}, {
+		input: "0000000000000000000000000000000000000000000000000000000000000001" +
+			"0000000000000000000000000000000000000000000000000000000000000002",
+		expected: "115cc0f5e7d690413df64c6b9662e9cf2a3617f2743245519e19607a4417189a",
+		name:     "two",
+	}, {
+		input: "0000000000000000000000000000000000000000000000000000000000000001" +
+			"0000000000000000000000000000000000000000000000000000000000000002" +
+			"0000000000000000000000000000000000000000000000000000000000000003" +
+			"0000000000000000000000000000000000000000000000000000000000000004",
+		expected: "299c867db6c1fdd79dcefa40e4510b9837e60ebb1ce0663dbaa525df65250465",
+		name:     "four",
+	},
+}
+
 func testPrecompiled(addr string, test precompiledTest, t *testing.T) {
-	p := PrecompiledContractsByzantium[common.HexToAddress(addr)]
+	p := PrecompiledContractsZK[common.HexToAddress(addr)]
 	in := common.Hex2Bytes(test.input)
 	contract := NewContract(AccountRef(common.HexToAddress("1337")),
 		nil, new(big.Int), p.RequiredGas(in))
@@ -354,7 +376,7 @@ func benchmarkPrecompiled(addr string, test precompiledTest, bench *testing.B) {
 	if test.noBenchmark {
 		return
 	}
-	p := PrecompiledContractsByzantium[common.HexToAddress(addr)]
+	p := PrecompiledContractsZK[common.HexToAddress(addr)]
 	in := common.Hex2Bytes(test.input)
 	reqGas := p.RequiredGas(in)
 	contract := NewContract(AccountRef(common.HexToAddress("1337")),
@@ -481,3 +503,17 @@ func BenchmarkPrecompiledBn256Pairing(bench *testing.B) {
 		benchmarkPrecompiled("08", test, bench)
 	}
 }
+
+// Tests the sample inputs of the poseidon hash.
+func TestPrecompiledPoseidon(t *testing.T) {
+	for _, test := range poseidonTests {
+		testPrecompiled("0100", test, t)
+	}
+}
+
+// Benchmarks the sample inputs of the poseidon hash.
+func BenchmarkPrecompiledPoseidon(bench *testing.B) {
+	for _, test := range poseidonTests {
+		benchmarkPrecompiled("0100", test, bench)
+	}
+}
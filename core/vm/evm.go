@@ -52,18 +52,39 @@ type (
 	GetHashFunc func(uint64) common.Hash
 )
 
+// precompile returns the precompiled contract active at the given address, nil
+// if there is none.
+func (evm *EVM) precompile(addr common.Address) PrecompiledContract {
+	precompiles := PrecompiledContractsHomestead
+	if evm.ChainConfig().IsByzantium(evm.BlockNumber) {
+		precompiles = PrecompiledContractsByzantium
+		if evm.ChainConfig().IsZKPrecompiles(evm.BlockNumber) {
+			precompiles = PrecompiledContractsZK
+		}
+	}
+	if p := precompiles[addr]; p != nil {
+		return p
+	}
+	// Quorum: the bridges to other chains live at configured addresses
+	if bridge := evm.ChainConfig().Bridge(addr, evm.BlockNumber); bridge != nil {
+		return &bridgeVerify{config: bridge}
+	}
+	return nil
+}
+
 // run runs the given contract and takes care of running precompiles with a fallback to the byte code interpreter.
 func run(evm *EVM, contract *Contract, input []byte, readOnly bool) ([]byte, error) {
 	if contract.CodeAddr != nil {
-		precompiles := PrecompiledContractsHomestead
-		if evm.ChainConfig().IsByzantium(evm.BlockNumber) {
-			precompiles = PrecompiledContractsByzantium
-		}
-		if p := precompiles[*contract.CodeAddr]; p != nil {
+		if p := evm.precompile(*contract.CodeAddr); p != nil {
 			return RunPrecompiledContract(p, input, contract)
 		}
 	}
 	for _, interpreter := range evm.interpreters {
+		// Quorum - alternative interpreters only claim the contracts deployed
+		// since their activation
+		if alt, ok := interpreter.(*prefixedInterpreter); ok && !alt.claims(evm, contract) {
+			continue
+		}
 		if interpreter.CanRun(contract.Code) {
 			if evm.interpreter != interpreter {
 				// Ensure that the interpreter pointer is set back
@@ -190,6 +211,12 @@ func NewEVM(ctx Context, statedb, privateState StateDB, chainConfig *params.Chai
 	evm.interpreters = append(evm.interpreters, NewEVMInterpreter(evm, vmConfig))
 	evm.interpreter = evm.interpreters[0]
 
+	// Quorum - alternative interpreters activated by the chain configuration
+	// claim their contracts ahead of the EVM
+	if alt := experimentalInterpreters(evm, vmConfig); len(alt) > 0 {
+		evm.interpreters = append(alt, evm.interpreters...)
+	}
+
 	return evm
 }
 
@@ -230,11 +257,7 @@ func (evm *EVM) Call(caller ContractRef, addr common.Address, input []byte, gas
 		snapshot = evm.StateDB.Snapshot()
 	)
 	if !evm.StateDB.Exist(addr) {
-		precompiles := PrecompiledContractsHomestead
-		if evm.ChainConfig().IsByzantium(evm.BlockNumber) {
-			precompiles = PrecompiledContractsByzantium
-		}
-		if precompiles[addr] == nil && evm.ChainConfig().IsEIP158(evm.BlockNumber) && value.Sign() == 0 {
+		if evm.precompile(addr) == nil && evm.ChainConfig().IsEIP158(evm.BlockNumber) && value.Sign() == 0 {
 			// Calling a non existing account, don't do anything, but ping the tracer
 			if evm.vmConfig.Debug && evm.depth == 0 {
 				evm.vmConfig.Tracer.CaptureStart(caller.Address(), addr, false, input, gas, value)
@@ -505,6 +528,7 @@ func (evm *EVM) create(caller ContractRef, codeAndHash *codeAndHash, gas uint64,
 		createDataGas := uint64(len(ret)) * params.CreateDataGas
 		if contract.UseGas(createDataGas) {
 			evm.StateDB.SetCode(address, ret)
+			markInterpreted(evm, address, ret)
 		} else {
 			err = ErrCodeStoreOutOfGas
 		}
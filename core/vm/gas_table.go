@@ -130,12 +130,12 @@ func gasSStore(gt params.GasTable, evm *EVM, contract *Contract, stack *Stack, m
 		// 3. From a non-zero to a non-zero                         (CHANGE)
 		switch {
 		case current == (common.Hash{}) && y.Sign() != 0: // 0 => non 0
-			return params.SstoreSetGas, nil
+			return sstoreCost(gt.SStoreSet, params.SstoreSetGas), nil
 		case current != (common.Hash{}) && y.Sign() == 0: // non 0 => 0
-			evm.StateDB.AddRefund(params.SstoreRefundGas)
-			return params.SstoreClearGas, nil
+			evm.StateDB.AddRefund(sstoreClearRefund(gt, params.SstoreRefundGas))
+			return sstoreCost(gt.SStoreReset, params.SstoreClearGas), nil
 		default: // non 0 => non 0 (or 0 => 0)
-			return params.SstoreResetGas, nil
+			return sstoreCost(gt.SStoreReset, params.SstoreResetGas), nil
 		}
 	}
 	// The new gas metering is based on net gas costs (EIP-1283):
@@ -159,30 +159,67 @@ func gasSStore(gt params.GasTable, evm *EVM, contract *Contract, stack *Stack, m
 	original := evm.StateDB.GetCommittedState(contract.Address(), common.BigToHash(x))
 	if original == current {
 		if original == (common.Hash{}) { // create slot (2.1.1)
-			return params.NetSstoreInitGas, nil
+			return sstoreCost(gt.SStoreSet, params.NetSstoreInitGas), nil
 		}
 		if value == (common.Hash{}) { // delete slot (2.1.2b)
-			evm.StateDB.AddRefund(params.NetSstoreClearRefund)
+			evm.StateDB.AddRefund(sstoreClearRefund(gt, params.NetSstoreClearRefund))
 		}
-		return params.NetSstoreCleanGas, nil // write existing slot (2.1.2)
+		return sstoreCost(gt.SStoreReset, params.NetSstoreCleanGas), nil // write existing slot (2.1.2)
 	}
 	if original != (common.Hash{}) {
 		if current == (common.Hash{}) { // recreate slot (2.2.1.1)
-			evm.StateDB.SubRefund(params.NetSstoreClearRefund)
+			evm.StateDB.SubRefund(sstoreClearRefund(gt, params.NetSstoreClearRefund))
 		} else if value == (common.Hash{}) { // delete slot (2.2.1.2)
-			evm.StateDB.AddRefund(params.NetSstoreClearRefund)
+			evm.StateDB.AddRefund(sstoreClearRefund(gt, params.NetSstoreClearRefund))
 		}
 	}
 	if original == value {
 		if original == (common.Hash{}) { // reset to original inexistent slot (2.2.2.1)
-			evm.StateDB.AddRefund(params.NetSstoreResetClearRefund)
+			evm.StateDB.AddRefund(sstoreResetRefund(gt.SStoreSet, params.NetSstoreResetClearRefund))
 		} else { // reset to original existing slot (2.2.2.2)
-			evm.StateDB.AddRefund(params.NetSstoreResetRefund)
+			evm.StateDB.AddRefund(sstoreResetRefund(gt.SStoreReset, params.NetSstoreResetRefund))
 		}
 	}
 	return params.NetSstoreDirtyGas, nil
 }
 
+// Quorum
+//
+// sstoreCost returns the overridden cost of a storage write, or the protocol one
+// if not overridden.
+func sstoreCost(override, cost uint64) uint64 {
+	if override != 0 {
+		return override
+	}
+	return cost
+}
+
+// sstoreClearRefund returns the refund of a slot cleared, which the protocol sets
+// to the cost of setting a zero slot but the one of changing an existing slot,
+// repriced alike by the overrides.
+func sstoreClearRefund(gt params.GasTable, refund uint64) uint64 {
+	if gt.SStoreSet == 0 && gt.SStoreReset == 0 {
+		return refund
+	}
+	set, reset := sstoreCost(gt.SStoreSet, params.SstoreSetGas), sstoreCost(gt.SStoreReset, params.SstoreResetGas)
+	if set <= reset {
+		return 0
+	}
+	return set - reset
+}
+
+// sstoreResetRefund returns the refund of a slot reset to its original value,
+// which gives back the overridden cost of its first write but the dirty write.
+func sstoreResetRefund(override, refund uint64) uint64 {
+	if override == 0 {
+		return refund
+	}
+	if override <= params.NetSstoreDirtyGas {
+		return 0
+	}
+	return override - params.NetSstoreDirtyGas
+}
+
 func makeGasLog(n uint64) gasFunc {
 	return func(gt params.GasTable, evm *EVM, contract *Contract, stack *Stack, mem *Memory, memorySize uint64) (uint64, error) {
 		requestedSize, overflow := bigUint64(stack.Back(1))
@@ -0,0 +1,166 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// interpreterMarkerKey is the storage slot marking a contract deployed while an
+// alternative interpreter was active, holding the hash of the interpreter name.
+// Only the contracts marked are run by the interpreter, so that no contract
+// deployed earlier is ever taken over, whatever its code.
+var interpreterMarkerKey = crypto.Keccak256Hash([]byte("quorum.interpreter"))
+
+// InterpreterFactory creates an alternative interpreter bound to an EVM
+// instance.
+type InterpreterFactory func(evm *EVM, cfg Config) Interpreter
+
+// registeredInterpreter is an alternative interpreter along with the code
+// prefix identifying the contracts it runs.
+type registeredInterpreter struct {
+	name    string
+	prefix  []byte
+	factory InterpreterFactory
+}
+
+var (
+	registryLock sync.RWMutex
+	registry     = make(map[string]*registeredInterpreter)
+)
+
+// RegisterInterpreter makes an alternative interpreter (e.g. a WASM runtime)
+// available under the given name. Once the chain configuration activates the
+// name, contracts deployed with code starting with prefix are run by the
+// interpreter instead of the EVM, including their init code. The contracts
+// deployed before the activation keep running on the EVM.
+//
+// The first byte of the prefix must be an undefined EVM opcode, so that no
+// valid EVM contract deployed since the activation is mistaken for one of the
+// interpreter.
+func RegisterInterpreter(name string, prefix []byte, factory InterpreterFactory) error {
+	if len(prefix) == 0 {
+		return errors.New("empty interpreter code prefix")
+	}
+	if constantinopleInstructionSet[prefix[0]].valid {
+		return fmt.Errorf("interpreter code prefix starts with EVM opcode %v", OpCode(prefix[0]))
+	}
+	registryLock.Lock()
+	defer registryLock.Unlock()
+
+	if _, exists := registry[name]; exists {
+		return fmt.Errorf("interpreter %q already registered", name)
+	}
+	for _, other := range registry {
+		if bytes.HasPrefix(prefix, other.prefix) || bytes.HasPrefix(other.prefix, prefix) {
+			return fmt.Errorf("interpreter code prefix %x overlaps %q (%x)", prefix, other.name, other.prefix)
+		}
+	}
+	registry[name] = &registeredInterpreter{
+		name:    name,
+		prefix:  common.CopyBytes(prefix),
+		factory: factory,
+	}
+	return nil
+}
+
+// CheckInterpreters verifies that every alternative interpreter activated by
+// the chain configuration is registered, since a node lacking one would
+// compute different state for the contracts it runs.
+func CheckInterpreters(config *params.ChainConfig) error {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+
+	for name := range config.ExperimentalInterpreters {
+		if _, ok := registry[name]; !ok {
+			return fmt.Errorf("chain activates unknown interpreter %q", name)
+		}
+	}
+	return nil
+}
+
+// experimentalInterpreters creates the alternative interpreters active at the
+// block of the given EVM, ordered by name.
+func experimentalInterpreters(evm *EVM, cfg Config) []Interpreter {
+	if len(evm.chainConfig.ExperimentalInterpreters) == 0 {
+		return nil
+	}
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+
+	var active []*registeredInterpreter
+	for name, reg := range registry {
+		if evm.chainConfig.IsExperimentalInterpreter(name, evm.BlockNumber) {
+			active = append(active, reg)
+		}
+	}
+	sort.Slice(active, func(i, j int) bool { return active[i].name < active[j].name })
+
+	interpreters := make([]Interpreter, len(active))
+	for i, reg := range active {
+		interpreters[i] = &prefixedInterpreter{
+			Interpreter: reg.factory(evm, cfg),
+			prefix:      reg.prefix,
+			marker:      crypto.Keccak256Hash([]byte(reg.name)),
+		}
+	}
+	return interpreters
+}
+
+// prefixedInterpreter restricts an alternative interpreter to the contracts
+// carrying its code prefix.
+type prefixedInterpreter struct {
+	Interpreter
+	prefix []byte
+	marker common.Hash // Storage marker of the contracts deployed for the interpreter
+}
+
+// claims reports whether the interpreter may run the code of a contract: init
+// code being deployed, or the code of a contract marked as deployed for it.
+func (in *prefixedInterpreter) claims(evm *EVM, contract *Contract) bool {
+	if !bytes.HasPrefix(contract.Code, in.prefix) || contract.CodeAddr == nil {
+		return false
+	}
+	if evm.StateDB.GetCodeSize(*contract.CodeAddr) == 0 {
+		return true // Init code, the contract is being deployed
+	}
+	return evm.StateDB.GetState(*contract.CodeAddr, interpreterMarkerKey) == in.marker
+}
+
+// markInterpreted marks a contract just deployed with the code prefix of an
+// active alternative interpreter as run by it.
+func markInterpreted(evm *EVM, address common.Address, code []byte) {
+	for _, interpreter := range evm.interpreters {
+		if alt, ok := interpreter.(*prefixedInterpreter); ok && bytes.HasPrefix(code, alt.prefix) {
+			evm.StateDB.SetState(address, interpreterMarkerKey, alt.marker)
+			return
+		}
+	}
+}
+
+// CanRun implements Interpreter, claiming only code with the registered prefix.
+func (in *prefixedInterpreter) CanRun(code []byte) bool {
+	return bytes.HasPrefix(code, in.prefix) && in.Interpreter.CanRun(code)
+}
@@ -117,6 +117,145 @@ func TestCall(t *testing.T) {
 	}
 }
 
+// echoInterpreter is an alternative interpreter returning its input, deploying
+// its init code as is.
+type echoInterpreter struct{}
+
+func (echoInterpreter) Run(contract *vm.Contract, input []byte, static bool) ([]byte, error) {
+	if len(input) == 0 {
+		return contract.Code, nil
+	}
+	return input, nil
+}
+
+func (echoInterpreter) CanRun(code []byte) bool { return true }
+
+func TestExperimentalInterpreter(t *testing.T) {
+	prefix := []byte{0xef, 0x01}
+	if err := vm.RegisterInterpreter("invalid", []byte{byte(vm.PUSH1)}, nil); err == nil {
+		t.Fatal("expected EVM opcode prefix to be rejected")
+	}
+	factory := func(evm *vm.EVM, cfg vm.Config) vm.Interpreter { return echoInterpreter{} }
+	if err := vm.RegisterInterpreter("echo", prefix, factory); err != nil {
+		t.Fatal("didn't expect error", err)
+	}
+	if err := vm.RegisterInterpreter("echo2", prefix[:1], factory); err == nil {
+		t.Fatal("expected overlapping prefix to be rejected")
+	}
+	config := *params.TestChainConfig
+	config.ExperimentalInterpreters = map[string]*big.Int{"echo": big.NewInt(1)}
+	if err := vm.CheckInterpreters(&config); err != nil {
+		t.Fatal("didn't expect error", err)
+	}
+	code := append(prefix, 0x00)
+	input := []byte("experiment")
+
+	// Before activation the EVM runs the code and hits the invalid opcode
+	if _, _, _, err := Create(code, &Config{ChainConfig: &config, BlockNumber: big.NewInt(0)}); err == nil {
+		t.Fatal("expected EVM to reject prefixed code before activation")
+	}
+	// Contracts deployed before the activation are never taken over
+	if _, _, err := Execute(code, input, &Config{ChainConfig: &config, BlockNumber: big.NewInt(1)}); err == nil {
+		t.Fatal("expected EVM to run prefixed code deployed before activation")
+	}
+	cfg := &Config{ChainConfig: &config, BlockNumber: big.NewInt(1)}
+	_, address, _, err := Create(code, cfg)
+	if err != nil {
+		t.Fatal("didn't expect error", err)
+	}
+	ret, _, err := Call(address, input, cfg)
+	if err != nil {
+		t.Fatal("didn't expect error", err)
+	}
+	if string(ret) != string(input) {
+		t.Errorf("Expected %q, got %q", input, ret)
+	}
+	// Plain EVM contracts are unaffected
+	ret, _, err = Execute([]byte{
+		byte(vm.PUSH1), 10,
+		byte(vm.PUSH1), 0,
+		byte(vm.MSTORE),
+		byte(vm.PUSH1), 32,
+		byte(vm.PUSH1), 0,
+		byte(vm.RETURN),
+	}, nil, &Config{ChainConfig: &config, BlockNumber: big.NewInt(1)})
+	if err != nil {
+		t.Fatal("didn't expect error", err)
+	}
+	if num := new(big.Int).SetBytes(ret); num.Cmp(big.NewInt(10)) != 0 {
+		t.Error("Expected 10, got", num)
+	}
+}
+
+func TestGasScheduleOverrides(t *testing.T) {
+	sstore, sload := uint64(40000), uint64(800)
+	config := *params.TestChainConfig
+	config.GasScheduleOverrides = []*params.GasScheduleOverride{{Block: big.NewInt(1), SLoad: &sload, SStoreSet: &sstore}}
+
+	gasUsed := func(number int64) uint64 {
+		state, _ := state.New(common.Hash{}, state.NewDatabase(ethdb.NewMemDatabase()))
+		address := common.HexToAddress("0x0a")
+		state.SetCode(address, []byte{
+			byte(vm.PUSH1), 1,
+			byte(vm.PUSH1), 0,
+			byte(vm.SSTORE),
+			byte(vm.PUSH1), 0,
+			byte(vm.SLOAD),
+			byte(vm.STOP),
+		})
+		_, left, err := Call(address, nil, &Config{ChainConfig: &config, BlockNumber: big.NewInt(number), GasLimit: 100000, State: state})
+		if err != nil {
+			t.Fatal("didn't expect error", err)
+		}
+		return 100000 - left
+	}
+	// Three pushes, then a slot created and read
+	if used, want := gasUsed(0), 3*3+params.NetSstoreInitGas+params.GasTableConstantinople.SLoad; used != want {
+		t.Errorf("gas used before the override mismatch: have %d, want %d", used, want)
+	}
+	if used, want := gasUsed(1), 3*3+sstore+sload; used != want {
+		t.Errorf("gas used after the override mismatch: have %d, want %d", used, want)
+	}
+}
+
+// Tests that the refunds of the cleared slots are repriced with the costs of the
+// storage writes.
+func TestGasScheduleOverrideRefunds(t *testing.T) {
+	set, reset := uint64(40000), uint64(10000)
+	config := *params.TestChainConfig
+	config.GasScheduleOverrides = []*params.GasScheduleOverride{{Block: big.NewInt(1), SStoreSet: &set, SStoreReset: &reset}}
+
+	refund := func(number int64, constantinople bool) uint64 {
+		config := config
+		if !constantinople {
+			config.ConstantinopleBlock = nil
+		}
+		state, _ := state.New(common.Hash{}, state.NewDatabase(ethdb.NewMemDatabase()))
+		address := common.HexToAddress("0x0a")
+		state.SetCode(address, []byte{
+			byte(vm.PUSH1), 0,
+			byte(vm.PUSH1), 0,
+			byte(vm.SSTORE),
+			byte(vm.STOP),
+		})
+		state.SetState(address, common.Hash{}, common.BytesToHash([]byte{1}))
+		state.Commit(false)
+
+		if _, _, err := Call(address, nil, &Config{ChainConfig: &config, BlockNumber: big.NewInt(number), GasLimit: 100000, State: state}); err != nil {
+			t.Fatal("didn't expect error", err)
+		}
+		return state.GetRefund()
+	}
+	for _, constantinople := range []bool{false, true} {
+		if have, want := refund(0, constantinople), params.SstoreRefundGas; have != want {
+			t.Errorf("constantinople %v: refund before the override mismatch: have %d, want %d", constantinople, have, want)
+		}
+		if have, want := refund(1, constantinople), set-reset; have != want {
+			t.Errorf("constantinople %v: refund after the override mismatch: have %d, want %d", constantinople, have, want)
+		}
+	}
+}
+
 func BenchmarkCall(b *testing.B) {
 	var definition = `[{"constant":true,"inputs":[],"name":"seller","outputs":[{"name":"","type":"address"}],"type":"function"},{"constant":false,"inputs":[],"name":"abort","outputs":[],"type":"function"},{"constant":true,"inputs":[],"name":"value","outputs":[{"name":"","type":"uint256"}],"type":"function"},{"constant":false,"inputs":[],"name":"refund","outputs":[],"type":"function"},{"constant":true,"inputs":[],"name":"buyer","outputs":[{"name":"","type":"address"}],"type":"function"},{"constant":false,"inputs":[],"name":"confirmReceived","outputs":[],"type":"function"},{"constant":true,"inputs":[],"name":"state","outputs":[{"name":"","type":"uint8"}],"type":"function"},{"constant":false,"inputs":[],"name":"confirmPurchase","outputs":[],"type":"function"},{"inputs":[],"type":"constructor"},{"anonymous":false,"inputs":[],"name":"Aborted","type":"event"},{"anonymous":false,"inputs":[],"name":"PurchaseConfirmed","type":"event"},{"anonymous":false,"inputs":[],"name":"ItemReceived","type":"event"},{"anonymous":false,"inputs":[],"name":"Refunded","type":"event"}]`
 
@@ -293,12 +293,26 @@ func Encrypt(rand io.Reader, pub *PublicKey, m, s1, s2 []byte) (ct []byte, err e
 
 // Decrypt decrypts an ECIES ciphertext.
 func (prv *PrivateKey) Decrypt(c, s1, s2 []byte) (m []byte, err error) {
+	return DecryptShared(&prv.PublicKey, prv.GenerateShared, c, s1, s2)
+}
+
+// Quorum
+//
+// SharedKeyFunc performs the ECDH key agreement of GenerateShared with a
+// private key held elsewhere, such as in a KMS.
+type SharedKeyFunc func(pub *PublicKey, skLen, macLen int) ([]byte, error)
+
+// Quorum
+//
+// DecryptShared decrypts an ECIES ciphertext sent to a public key, the key
+// agreement being performed by the holder of its private key.
+func DecryptShared(pub *PublicKey, shared SharedKeyFunc, c, s1, s2 []byte) (m []byte, err error) {
 	if len(c) == 0 {
 		return nil, ErrInvalidMessage
 	}
-	params := prv.PublicKey.Params
+	params := pub.Params
 	if params == nil {
-		if params = ParamsFromCurve(prv.PublicKey.Curve); params == nil {
+		if params = ParamsFromCurve(pub.Curve); params == nil {
 			err = ErrUnsupportedECIESParameters
 			return
 		}
@@ -314,7 +328,7 @@ func (prv *PrivateKey) Decrypt(c, s1, s2 []byte) (m []byte, err error) {
 
 	switch c[0] {
 	case 2, 3, 4:
-		rLen = (prv.PublicKey.Curve.Params().BitSize + 7) / 4
+		rLen = (pub.Curve.Params().BitSize + 7) / 4
 		if len(c) < (rLen + hLen + 1) {
 			err = ErrInvalidMessage
 			return
@@ -328,7 +342,7 @@ func (prv *PrivateKey) Decrypt(c, s1, s2 []byte) (m []byte, err error) {
 	mEnd = len(c) - hLen
 
 	R := new(PublicKey)
-	R.Curve = prv.PublicKey.Curve
+	R.Curve = pub.Curve
 	R.X, R.Y = elliptic.Unmarshal(R.Curve, c[:rLen])
 	if R.X == nil {
 		err = ErrInvalidPublicKey
@@ -339,7 +353,7 @@ func (prv *PrivateKey) Decrypt(c, s1, s2 []byte) (m []byte, err error) {
 		return
 	}
 
-	z, err := prv.GenerateShared(R, params.KeyLen, params.KeyLen)
+	z, err := shared(R, params.KeyLen, params.KeyLen)
 	if err != nil {
 		return
 	}
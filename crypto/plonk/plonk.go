@@ -0,0 +1,348 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package plonk implements a verifier for PLONK proofs with KZG commitments over
+// the BN254 (alt_bn128) curve.
+//
+// The verifier follows the protocol and Keccak256 Fiat-Shamir transcript of the
+// snarkjs PLONK prover, so the verifying key is the one exported for the
+// Solidity verifier, while it works for any circuit.
+package plonk
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/crypto/bn256"
+)
+
+const (
+	// MaxPower is the base 2 logarithm of the largest supported evaluation
+	// domain, bounded by the 2-adicity of the scalar field.
+	MaxPower = 28
+
+	scalarSize = 32
+	g1Size     = 64
+	g2Size     = 128
+
+	// VerifyingKeySize is the size of an encoded verifying key.
+	VerifyingKeySize = 5*scalarSize + 8*g1Size + g2Size
+
+	// ProofSize is the size of an encoded proof.
+	ProofSize = 9*g1Size + 6*scalarSize
+)
+
+// Order is the order of the scalar field of BN254 the circuits are defined over.
+var Order, _ = new(big.Int).SetString("21888242871839275222246405745257275088548364400416034343698204186575808495617", 10)
+
+var (
+	errVerifyingKeySize = errors.New("invalid verifying key length")
+	errProofSize        = errors.New("invalid proof length")
+	errInvalidPower     = errors.New("invalid evaluation domain size")
+	errInvalidOmega     = errors.New("omega is not a generator of the evaluation domain")
+	errPublicCount      = errors.New("public input count mismatch")
+	errScalarRange      = errors.New("scalar not in field")
+)
+
+// VerifyingKey is the circuit specific key a proof is verified against.
+type VerifyingKey struct {
+	Power  uint64   // Base 2 logarithm of the evaluation domain size
+	Public uint64   // Number of public inputs of the circuit
+	K1, K2 *big.Int // Coset shifts of the permutation argument
+	Omega  *big.Int // Generator of the evaluation domain
+
+	Qm, Ql, Qr, Qo, Qc *bn256.G1 // Selector polynomial commitments
+	S1, S2, S3         *bn256.G1 // Permutation polynomial commitments
+
+	X2 *bn256.G2 // Secret evaluation point of the setup, [x]_2
+}
+
+// Proof is a PLONK proof, made of the commitments and evaluations of the
+// prover's polynomials.
+type Proof struct {
+	A, B, C, Z *bn256.G1 // Wire and permutation polynomial commitments
+	T1, T2, T3 *bn256.G1 // Quotient polynomial commitments
+	Wxi, Wxiw  *bn256.G1 // Opening proofs at xi and xi*omega
+
+	EvalA, EvalB, EvalC *big.Int // Wire polynomial evaluations at xi
+	EvalS1, EvalS2      *big.Int // Permutation polynomial evaluations at xi
+	EvalZw              *big.Int // Permutation accumulator evaluation at xi*omega
+}
+
+// Unmarshal decodes a verifying key: the domain power, public input count, k1,
+// k2 and omega as 32 byte integers, followed by the Qm, Ql, Qr, Qo, Qc, S1, S2
+// and S3 commitments as 64 byte G1 points and the 128 byte G2 point [x]_2, all
+// in the encoding of the alt_bn128 precompiles.
+func (vk *VerifyingKey) Unmarshal(data []byte) error {
+	if len(data) != VerifyingKeySize {
+		return errVerifyingKeySize
+	}
+	power, public := new(big.Int).SetBytes(data[:32]), new(big.Int).SetBytes(data[32:64])
+	if power.Sign() == 0 || power.Cmp(big.NewInt(MaxPower)) > 0 {
+		return errInvalidPower
+	}
+	vk.Power = power.Uint64()
+	if public.Cmp(new(big.Int).Lsh(big.NewInt(1), uint(vk.Power))) > 0 {
+		return errPublicCount
+	}
+	vk.Public = public.Uint64()
+
+	var err error
+	data = data[64:]
+	for _, s := range []**big.Int{&vk.K1, &vk.K2, &vk.Omega} {
+		if *s, data, err = unmarshalScalar(data); err != nil {
+			return err
+		}
+	}
+	for _, p := range []**bn256.G1{&vk.Qm, &vk.Ql, &vk.Qr, &vk.Qo, &vk.Qc, &vk.S1, &vk.S2, &vk.S3} {
+		if *p, data, err = unmarshalG1(data); err != nil {
+			return err
+		}
+	}
+	vk.X2 = new(bn256.G2)
+	if _, err := vk.X2.Unmarshal(data); err != nil {
+		return err
+	}
+	// Omega must generate a domain of exactly the declared size
+	half := new(big.Int).Exp(vk.Omega, new(big.Int).Lsh(big.NewInt(1), uint(vk.Power-1)), Order)
+	if half.Cmp(new(big.Int).Sub(Order, big.NewInt(1))) != 0 {
+		return errInvalidOmega
+	}
+	return nil
+}
+
+// Unmarshal decodes a proof: the A, B, C, Z, T1, T2, T3, Wxi and Wxiw
+// commitments as 64 byte G1 points, followed by the evaluations of a, b, c, s1,
+// s2 and zw as 32 byte integers.
+func (p *Proof) Unmarshal(data []byte) error {
+	if len(data) != ProofSize {
+		return errProofSize
+	}
+	var err error
+	for _, c := range []**bn256.G1{&p.A, &p.B, &p.C, &p.Z, &p.T1, &p.T2, &p.T3, &p.Wxi, &p.Wxiw} {
+		if *c, data, err = unmarshalG1(data); err != nil {
+			return err
+		}
+	}
+	for _, s := range []**big.Int{&p.EvalA, &p.EvalB, &p.EvalC, &p.EvalS1, &p.EvalS2, &p.EvalZw} {
+		if *s, data, err = unmarshalScalar(data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func unmarshalScalar(data []byte) (*big.Int, []byte, error) {
+	s := new(big.Int).SetBytes(data[:scalarSize])
+	if s.Cmp(Order) >= 0 {
+		return nil, nil, errScalarRange
+	}
+	return s, data[scalarSize:], nil
+}
+
+func unmarshalG1(data []byte) (*bn256.G1, []byte, error) {
+	p := new(bn256.G1)
+	if _, err := p.Unmarshal(data[:g1Size]); err != nil {
+		return nil, nil, err
+	}
+	return p, data[g1Size:], nil
+}
+
+// challenges are the Fiat-Shamir challenges of a proof.
+type challenges struct {
+	beta, gamma, alpha, xi, v1, u *big.Int
+}
+
+// transcript hashes commitments and scalars into a challenge.
+type transcript []byte
+
+func (t *transcript) addPoints(points ...*bn256.G1) {
+	for _, p := range points {
+		*t = append(*t, p.Marshal()...)
+	}
+}
+
+func (t *transcript) addScalars(scalars ...*big.Int) {
+	for _, s := range scalars {
+		*t = append(*t, math.PaddedBigBytes(s, scalarSize)...)
+	}
+}
+
+// challenge returns the hash of the transcript, reduced into the field, and
+// resets it.
+func (t *transcript) challenge() *big.Int {
+	c := new(big.Int).SetBytes(crypto.Keccak256(*t))
+	*t = (*t)[:0]
+	return c.Mod(c, Order)
+}
+
+// computeChallenges derives the challenges of the verifier from the transcript
+// of the proof.
+func computeChallenges(vk *VerifyingKey, proof *Proof, public []*big.Int) *challenges {
+	var (
+		ch = new(challenges)
+		t  transcript
+	)
+	t.addPoints(vk.Qm, vk.Ql, vk.Qr, vk.Qo, vk.Qc, vk.S1, vk.S2, vk.S3)
+	t.addScalars(public...)
+	t.addPoints(proof.A, proof.B, proof.C)
+	ch.beta = t.challenge()
+
+	t.addScalars(ch.beta)
+	ch.gamma = t.challenge()
+
+	t.addScalars(ch.beta, ch.gamma)
+	t.addPoints(proof.Z)
+	ch.alpha = t.challenge()
+
+	t.addScalars(ch.alpha)
+	t.addPoints(proof.T1, proof.T2, proof.T3)
+	ch.xi = t.challenge()
+
+	t.addScalars(ch.xi, proof.EvalA, proof.EvalB, proof.EvalC, proof.EvalS1, proof.EvalS2, proof.EvalZw)
+	ch.v1 = t.challenge()
+
+	t.addPoints(proof.Wxi, proof.Wxiw)
+	ch.u = t.challenge()
+
+	return ch
+}
+
+// Verify checks a proof of the circuit identified by the verifying key for the
+// given public inputs.
+func Verify(vk *VerifyingKey, proof *Proof, public []*big.Int) (bool, error) {
+	if uint64(len(public)) != vk.Public {
+		return false, errPublicCount
+	}
+	for _, input := range public {
+		if input.Sign() < 0 || input.Cmp(Order) >= 0 {
+			return false, errScalarRange
+		}
+	}
+	ch := computeChallenges(vk, proof, public)
+
+	f, e, ok := linearise(vk, proof, public, ch)
+	if !ok {
+		return false, nil
+	}
+	// Batch the openings at xi and xi*omega into a single pairing check:
+	//   e(-(Wxi + u*Wxiw), [x]_2) * e(xi*Wxi + u*xi*omega*Wxiw + F - E, [1]_2) == 1
+	a1 := new(bn256.G1).Add(proof.Wxi, new(bn256.G1).ScalarMult(proof.Wxiw, ch.u))
+	a1.Neg(a1)
+
+	xiw := mulmod(ch.xi, vk.Omega)
+	b1 := new(bn256.G1).ScalarMult(proof.Wxi, ch.xi)
+	b1.Add(b1, new(bn256.G1).ScalarMult(proof.Wxiw, mulmod(ch.u, xiw)))
+	b1.Add(b1, f)
+	b1.Add(b1, new(bn256.G1).Neg(e))
+
+	g2 := new(bn256.G2).ScalarBaseMult(big.NewInt(1))
+	return bn256.PairingCheck([]*bn256.G1{a1, b1}, []*bn256.G2{vk.X2, g2}), nil
+}
+
+// linearise computes the commitment F of the batched polynomial opened at xi
+// and xi*omega and the commitment E of its claimed evaluation. False is
+// returned if xi hits the evaluation domain.
+func linearise(vk *VerifyingKey, proof *Proof, public []*big.Int, ch *challenges) (f, e *bn256.G1, ok bool) {
+	n := new(big.Int).Lsh(big.NewInt(1), uint(vk.Power))
+	xin := new(big.Int).Exp(ch.xi, n, Order)
+	zh := submod(xin, big.NewInt(1))
+
+	// Evaluate the Lagrange polynomials at xi for the public inputs
+	count := len(public)
+	if count == 0 {
+		count = 1
+	}
+	lagrange := make([]*big.Int, count)
+	w := big.NewInt(1)
+	for i := range lagrange {
+		den := mulmod(new(big.Int).Mod(n, Order), submod(ch.xi, w))
+		if den.Sign() == 0 {
+			return nil, nil, false
+		}
+		lagrange[i] = mulmod(mulmod(w, zh), den.ModInverse(den, Order))
+		w = mulmod(w, vk.Omega)
+	}
+	pi := new(big.Int)
+	for i, input := range public {
+		pi = submod(pi, mulmod(input, lagrange[i]))
+	}
+	alpha2 := mulmod(ch.alpha, ch.alpha)
+
+	// Constant term of the linearisation polynomial
+	e3a := addmod(addmod(proof.EvalA, mulmod(ch.beta, proof.EvalS1)), ch.gamma)
+	e3b := addmod(addmod(proof.EvalB, mulmod(ch.beta, proof.EvalS2)), ch.gamma)
+	e3c := addmod(proof.EvalC, ch.gamma)
+	e3 := mulmod(mulmod(mulmod(mulmod(e3a, e3b), e3c), proof.EvalZw), ch.alpha)
+	r0 := submod(submod(pi, mulmod(lagrange[0], alpha2)), e3)
+
+	// Commitment of the linearisation polynomial
+	d := new(bn256.G1).ScalarMult(vk.Qm, mulmod(proof.EvalA, proof.EvalB))
+	d.Add(d, new(bn256.G1).ScalarMult(vk.Ql, proof.EvalA))
+	d.Add(d, new(bn256.G1).ScalarMult(vk.Qr, proof.EvalB))
+	d.Add(d, new(bn256.G1).ScalarMult(vk.Qo, proof.EvalC))
+	d.Add(d, vk.Qc)
+
+	betaxi := mulmod(ch.beta, ch.xi)
+	d2a := addmod(addmod(proof.EvalA, betaxi), ch.gamma)
+	d2a = mulmod(d2a, addmod(addmod(proof.EvalB, mulmod(betaxi, vk.K1)), ch.gamma))
+	d2a = mulmod(d2a, addmod(addmod(proof.EvalC, mulmod(betaxi, vk.K2)), ch.gamma))
+	d2a = mulmod(d2a, ch.alpha)
+	d2 := addmod(addmod(d2a, mulmod(lagrange[0], alpha2)), ch.u)
+	d.Add(d, new(bn256.G1).ScalarMult(proof.Z, d2))
+
+	d3 := mulmod(mulmod(mulmod(e3a, e3b), ch.alpha), mulmod(ch.beta, proof.EvalZw))
+	d.Add(d, new(bn256.G1).Neg(new(bn256.G1).ScalarMult(vk.S3, d3)))
+
+	d4 := new(bn256.G1).Add(proof.T1, new(bn256.G1).ScalarMult(proof.T2, xin))
+	d4.Add(d4, new(bn256.G1).ScalarMult(proof.T3, mulmod(xin, xin)))
+	d.Add(d, new(bn256.G1).Neg(new(bn256.G1).ScalarMult(d4, zh)))
+
+	// Batch the opened polynomials with powers of v
+	v := []*big.Int{ch.v1}
+	for i := 1; i < 5; i++ {
+		v = append(v, mulmod(v[i-1], ch.v1))
+	}
+	f = d
+	es := new(big.Int).Neg(r0)
+	for i, c := range []*bn256.G1{proof.A, proof.B, proof.C, vk.S1, vk.S2} {
+		f.Add(f, new(bn256.G1).ScalarMult(c, v[i]))
+	}
+	for i, s := range []*big.Int{proof.EvalA, proof.EvalB, proof.EvalC, proof.EvalS1, proof.EvalS2} {
+		es = addmod(es, mulmod(s, v[i]))
+	}
+	es = addmod(es, mulmod(ch.u, proof.EvalZw))
+	e = new(bn256.G1).ScalarBaseMult(es)
+
+	return f, e, true
+}
+
+func addmod(a, b *big.Int) *big.Int {
+	r := new(big.Int).Add(a, b)
+	return r.Mod(r, Order)
+}
+
+func submod(a, b *big.Int) *big.Int {
+	r := new(big.Int).Sub(a, b)
+	return r.Mod(r, Order)
+}
+
+func mulmod(a, b *big.Int) *big.Int {
+	r := new(big.Int).Mul(a, b)
+	return r.Mod(r, Order)
+}
@@ -0,0 +1,316 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package plonk
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto/bn256"
+)
+
+// testScalar returns a deterministic pseudo random field element.
+func testScalar(seed int64) *big.Int {
+	s := new(big.Int).Exp(big.NewInt(seed+7), big.NewInt(12345), Order)
+	return s
+}
+
+func testPoint(seed int64) *bn256.G1 {
+	return new(bn256.G1).ScalarBaseMult(testScalar(seed))
+}
+
+// newTestProof creates a verifying key and a proof accepted by it. Knowing the
+// secret of the setup, the openings can be computed for arbitrary commitments,
+// which exercises the whole verifier without running a prover.
+func newTestProof(public []*big.Int) (*VerifyingKey, *Proof) {
+	tau := testScalar(1000)
+
+	vk := &VerifyingKey{
+		Power:  3,
+		Public: uint64(len(public)),
+		K1:     big.NewInt(2),
+		K2:     big.NewInt(3),
+		Omega:  new(big.Int).Exp(big.NewInt(5), new(big.Int).Rsh(new(big.Int).Sub(Order, big.NewInt(1)), 3), Order),
+		Qm:     testPoint(1),
+		Ql:     testPoint(2),
+		Qr:     testPoint(3),
+		Qo:     testPoint(4),
+		Qc:     testPoint(5),
+		S1:     testPoint(6),
+		S2:     testPoint(7),
+		S3:     testPoint(8),
+		X2:     new(bn256.G2).ScalarBaseMult(tau),
+	}
+	proof := &Proof{
+		A:      testPoint(11),
+		B:      testPoint(12),
+		C:      testPoint(13),
+		Z:      testPoint(14),
+		T1:     testPoint(15),
+		T2:     testPoint(16),
+		T3:     testPoint(17),
+		EvalA:  testScalar(21),
+		EvalB:  testScalar(22),
+		EvalC:  testScalar(23),
+		EvalS1: testScalar(24),
+		EvalS2: testScalar(25),
+		EvalZw: testScalar(26),
+	}
+	// The openings don't depend on u, so compute them for u = 0
+	proof.Wxi, proof.Wxiw = new(bn256.G1).ScalarBaseMult(big.NewInt(0)), new(bn256.G1).ScalarBaseMult(big.NewInt(0))
+	ch := computeChallenges(vk, proof, public)
+	ch.u = new(big.Int)
+
+	f, e, _ := linearise(vk, proof, public, ch)
+	opening := new(bn256.G1).Add(f, new(bn256.G1).Neg(e))
+	proof.Wxi = new(bn256.G1).ScalarMult(opening, new(big.Int).ModInverse(submod(tau, ch.xi), Order))
+
+	opening = new(bn256.G1).Add(proof.Z, new(bn256.G1).Neg(new(bn256.G1).ScalarBaseMult(proof.EvalZw)))
+	proof.Wxiw = new(bn256.G1).ScalarMult(opening, new(big.Int).ModInverse(submod(tau, mulmod(ch.xi, vk.Omega)), Order))
+
+	return vk, proof
+}
+
+func marshalVerifyingKey(vk *VerifyingKey) []byte {
+	var data []byte
+	for _, s := range []*big.Int{new(big.Int).SetUint64(vk.Power), new(big.Int).SetUint64(vk.Public), vk.K1, vk.K2, vk.Omega} {
+		data = append(data, math.PaddedBigBytes(s, scalarSize)...)
+	}
+	for _, p := range []*bn256.G1{vk.Qm, vk.Ql, vk.Qr, vk.Qo, vk.Qc, vk.S1, vk.S2, vk.S3} {
+		data = append(data, p.Marshal()...)
+	}
+	return append(data, vk.X2.Marshal()...)
+}
+
+func marshalProof(proof *Proof) []byte {
+	var data []byte
+	for _, p := range []*bn256.G1{proof.A, proof.B, proof.C, proof.Z, proof.T1, proof.T2, proof.T3, proof.Wxi, proof.Wxiw} {
+		data = append(data, p.Marshal()...)
+	}
+	for _, s := range []*big.Int{proof.EvalA, proof.EvalB, proof.EvalC, proof.EvalS1, proof.EvalS2, proof.EvalZw} {
+		data = append(data, math.PaddedBigBytes(s, scalarSize)...)
+	}
+	return data
+}
+
+func TestVerify(t *testing.T) {
+	public := []*big.Int{big.NewInt(1), big.NewInt(2)}
+	vk, proof := newTestProof(public)
+
+	if ok, err := Verify(vk, proof, public); err != nil || !ok {
+		t.Fatalf("valid proof rejected: %v, %v", ok, err)
+	}
+	// Changing any public input, evaluation or commitment must fail
+	if ok, _ := Verify(vk, proof, []*big.Int{big.NewInt(1), big.NewInt(3)}); ok {
+		t.Errorf("proof accepted for wrong public inputs")
+	}
+	tampered := *proof
+	tampered.EvalA = addmod(proof.EvalA, big.NewInt(1))
+	if ok, _ := Verify(vk, &tampered, public); ok {
+		t.Errorf("proof accepted with tampered evaluation")
+	}
+	tampered = *proof
+	tampered.T2 = testPoint(99)
+	if ok, _ := Verify(vk, &tampered, public); ok {
+		t.Errorf("proof accepted with tampered commitment")
+	}
+	if _, err := Verify(vk, proof, public[:1]); err != errPublicCount {
+		t.Errorf("error mismatch: have %v, want %v", err, errPublicCount)
+	}
+}
+
+func TestUnmarshal(t *testing.T) {
+	public := []*big.Int{big.NewInt(42)}
+	vk, proof := newTestProof(public)
+
+	encVK, encProof := marshalVerifyingKey(vk), marshalProof(proof)
+	if len(encVK) != VerifyingKeySize || len(encProof) != ProofSize {
+		t.Fatalf("encoding size mismatch: have %d/%d, want %d/%d", len(encVK), len(encProof), VerifyingKeySize, ProofSize)
+	}
+	var (
+		decVK    VerifyingKey
+		decProof Proof
+	)
+	if err := decVK.Unmarshal(encVK); err != nil {
+		t.Fatalf("failed to decode verifying key: %v", err)
+	}
+	if err := decProof.Unmarshal(encProof); err != nil {
+		t.Fatalf("failed to decode proof: %v", err)
+	}
+	if !bytes.Equal(marshalVerifyingKey(&decVK), encVK) || !bytes.Equal(marshalProof(&decProof), encProof) {
+		t.Fatalf("decoding round trip mismatch")
+	}
+	if ok, err := Verify(&decVK, &decProof, public); err != nil || !ok {
+		t.Fatalf("decoded proof rejected: %v, %v", ok, err)
+	}
+	// Omega must generate the declared domain
+	bad := append([]byte{}, encVK...)
+	copy(bad[4*scalarSize:5*scalarSize], math.PaddedBigBytes(big.NewInt(1), scalarSize))
+	if err := decVK.Unmarshal(bad); err != errInvalidOmega {
+		t.Errorf("error mismatch: have %v, want %v", err, errInvalidOmega)
+	}
+	// Evaluations must be reduced
+	bad = append([]byte{}, encProof...)
+	copy(bad[ProofSize-scalarSize:], math.PaddedBigBytes(Order, scalarSize))
+	if err := decProof.Unmarshal(bad); err != errScalarRange {
+		t.Errorf("error mismatch: have %v, want %v", err, errScalarRange)
+	}
+}
+
+// snarkjsKey is a verifying key as exported by snarkjs.
+type snarkjsKey struct {
+	Power   uint64     `json:"power"`
+	NPublic uint64     `json:"nPublic"`
+	K1      string     `json:"k1"`
+	K2      string     `json:"k2"`
+	Omega   string     `json:"w"`
+	Qm      []string   `json:"Qm"`
+	Ql      []string   `json:"Ql"`
+	Qr      []string   `json:"Qr"`
+	Qo      []string   `json:"Qo"`
+	Qc      []string   `json:"Qc"`
+	S1      []string   `json:"S1"`
+	S2      []string   `json:"S2"`
+	S3      []string   `json:"S3"`
+	X2      [][]string `json:"X_2"`
+}
+
+// snarkjsProof is a proof as exported by snarkjs.
+type snarkjsProof struct {
+	A      []string `json:"A"`
+	B      []string `json:"B"`
+	C      []string `json:"C"`
+	Z      []string `json:"Z"`
+	T1     []string `json:"T1"`
+	T2     []string `json:"T2"`
+	T3     []string `json:"T3"`
+	Wxi    []string `json:"Wxi"`
+	Wxiw   []string `json:"Wxiw"`
+	EvalA  string   `json:"eval_a"`
+	EvalB  string   `json:"eval_b"`
+	EvalC  string   `json:"eval_c"`
+	EvalS1 string   `json:"eval_s1"`
+	EvalS2 string   `json:"eval_s2"`
+	EvalZw string   `json:"eval_zw"`
+}
+
+// loadSnarkJS reads a verifying key, a proof and its public inputs exported by
+// snarkjs into their precompile encodings.
+func loadSnarkJS(t *testing.T, dir string) ([]byte, []byte, []*big.Int) {
+	var (
+		key    snarkjsKey
+		proof  snarkjsProof
+		inputs []string
+	)
+	for file, v := range map[string]interface{}{"verification_key.json": &key, "proof.json": &proof, "public.json": &inputs} {
+		blob, err := ioutil.ReadFile(filepath.Join(dir, file))
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", file, err)
+		}
+		if err := json.Unmarshal(blob, v); err != nil {
+			t.Fatalf("failed to decode %s: %v", file, err)
+		}
+	}
+	scalar := func(s string) []byte {
+		n, ok := new(big.Int).SetString(s, 10)
+		if !ok {
+			t.Fatalf("invalid scalar %q", s)
+		}
+		return math.PaddedBigBytes(n, scalarSize)
+	}
+	// The points are affine, the G2 coordinates encoded imaginary part first
+	g1 := func(p []string) []byte {
+		if len(p) != 3 || p[2] != "1" {
+			t.Fatalf("unsupported G1 point %v", p)
+		}
+		return append(scalar(p[0]), scalar(p[1])...)
+	}
+	var vk []byte
+	for _, s := range []string{new(big.Int).SetUint64(key.Power).String(), new(big.Int).SetUint64(key.NPublic).String(), key.K1, key.K2, key.Omega} {
+		vk = append(vk, scalar(s)...)
+	}
+	for _, p := range [][]string{key.Qm, key.Ql, key.Qr, key.Qo, key.Qc, key.S1, key.S2, key.S3} {
+		vk = append(vk, g1(p)...)
+	}
+	if len(key.X2) != 3 || key.X2[2][0] != "1" || key.X2[2][1] != "0" {
+		t.Fatalf("unsupported G2 point %v", key.X2)
+	}
+	vk = append(vk, scalar(key.X2[0][1])...)
+	vk = append(vk, scalar(key.X2[0][0])...)
+	vk = append(vk, scalar(key.X2[1][1])...)
+	vk = append(vk, scalar(key.X2[1][0])...)
+
+	var enc []byte
+	for _, p := range [][]string{proof.A, proof.B, proof.C, proof.Z, proof.T1, proof.T2, proof.T3, proof.Wxi, proof.Wxiw} {
+		enc = append(enc, g1(p)...)
+	}
+	for _, s := range []string{proof.EvalA, proof.EvalB, proof.EvalC, proof.EvalS1, proof.EvalS2, proof.EvalZw} {
+		enc = append(enc, scalar(s)...)
+	}
+	public := make([]*big.Int, len(inputs))
+	for i, s := range inputs {
+		public[i] = new(big.Int).SetBytes(scalar(s))
+	}
+	return vk, enc, public
+}
+
+// Tests the verifier against the proofs of snarkjs, generated by testdata/gen.sh
+// with a setup whose secret is unknown to the test.
+func TestVerifySnarkJS(t *testing.T) {
+	keys, _ := filepath.Glob(filepath.Join("testdata", "*", "verification_key.json"))
+	if len(keys) == 0 {
+		t.Skip("no snarkjs vectors, generate them with testdata/gen.sh")
+	}
+	for _, key := range keys {
+		dir := filepath.Dir(key)
+		encVK, encProof, public := loadSnarkJS(t, dir)
+
+		var (
+			vk    VerifyingKey
+			proof Proof
+		)
+		if err := vk.Unmarshal(encVK); err != nil {
+			t.Fatalf("%s: failed to decode verifying key: %v", dir, err)
+		}
+		if err := proof.Unmarshal(encProof); err != nil {
+			t.Fatalf("%s: failed to decode proof: %v", dir, err)
+		}
+		if ok, err := Verify(&vk, &proof, public); err != nil || !ok {
+			t.Errorf("%s: snarkjs proof rejected: %v, %v", dir, ok, err)
+		}
+		tampered := append([]*big.Int{}, public...)
+		tampered[0] = addmod(tampered[0], big.NewInt(1))
+		if ok, _ := Verify(&vk, &proof, tampered); ok {
+			t.Errorf("%s: snarkjs proof accepted for wrong public inputs", dir)
+		}
+	}
+}
+
+func BenchmarkVerify(b *testing.B) {
+	public := []*big.Int{big.NewInt(1), big.NewInt(2)}
+	vk, proof := newTestProof(public)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		Verify(vk, proof, public)
+	}
+}
@@ -0,0 +1,23 @@
+#!/bin/sh
+# Generates the snarkjs PLONK test vectors of the circuits in this directory:
+# the verifying key, proof and public inputs exported by snarkjs, which the
+# verifier is tested against. Requires circom 2 and snarkjs in the PATH.
+set -e
+
+cd "$(dirname "$0")"
+WORK=$(mktemp -d)
+trap 'rm -rf "$WORK"' EXIT
+
+snarkjs powersoftau new bn128 8 "$WORK/pot_0.ptau"
+snarkjs powersoftau contribute "$WORK/pot_0.ptau" "$WORK/pot_1.ptau" -e="plonk test vectors"
+snarkjs powersoftau prepare phase2 "$WORK/pot_1.ptau" "$WORK/pot.ptau"
+
+for dir in */; do
+    circuit=${dir%/}
+    circom "$circuit/circuit.circom" --r1cs --wasm -o "$WORK"
+    snarkjs wtns calculate "$WORK/circuit_js/circuit.wasm" "$circuit/input.json" "$WORK/witness.wtns"
+    snarkjs plonk setup "$WORK/circuit.r1cs" "$WORK/pot.ptau" "$WORK/circuit.zkey"
+    snarkjs plonk prove "$WORK/circuit.zkey" "$WORK/witness.wtns" "$circuit/proof.json" "$circuit/public.json"
+    snarkjs zkey export verificationkey "$WORK/circuit.zkey" "$circuit/verification_key.json"
+    snarkjs plonk verify "$circuit/verification_key.json" "$circuit/public.json" "$circuit/proof.json"
+done
@@ -0,0 +1,12 @@
+pragma circom 2.0.0;
+
+// Proves the knowledge of two factors of the public output.
+template Multiplier() {
+    signal input a;
+    signal input b;
+    signal output c;
+
+    c <== a * b;
+}
+
+component main = Multiplier();
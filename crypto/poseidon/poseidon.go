@@ -0,0 +1,243 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package poseidon implements the Poseidon hash function over the scalar field
+// of the BN254 (alt_bn128) curve.
+//
+// The instances use the x^5 S-box, 8 full rounds and the partial round counts
+// of the reference parameters, with round constants and MDS matrices derived by
+// the Grain LFSR of the reference implementation. Hashes are thus compatible with
+// the circomlib circuits and their on-chain counterparts.
+package poseidon
+
+import (
+	"errors"
+	"math/big"
+	"sync"
+)
+
+// MaxInputs is the maximum number of field elements hashed at once.
+const MaxInputs = 16
+
+const fullRounds = 8
+
+// partialRounds holds the number of partial rounds for the state widths
+// 2 to MaxInputs+1.
+var partialRounds = [MaxInputs]int{56, 57, 56, 60, 60, 63, 64, 63, 60, 66, 60, 65, 70, 60, 64, 68}
+
+// Modulus is the order of the scalar field of BN254 the hash operates in.
+var Modulus, _ = new(big.Int).SetString("21888242871839275222246405745257275088548364400416034343698204186575808495617", 10)
+
+var (
+	errInputCount = errors.New("invalid number of poseidon inputs")
+	errInputRange = errors.New("poseidon input not in field")
+)
+
+// parameters are the round constants and MDS matrix of an instance.
+type parameters struct {
+	width     int
+	partial   int
+	constants []*big.Int
+	mds       [][]*big.Int
+}
+
+var (
+	instanceOnce [MaxInputs]sync.Once
+	instances    [MaxInputs]*parameters
+)
+
+// instance returns the parameters hashing the given number of inputs,
+// deriving them on first use.
+func instance(inputs int) *parameters {
+	instanceOnce[inputs-1].Do(func() {
+		instances[inputs-1] = newParameters(inputs+1, partialRounds[inputs-1])
+	})
+	return instances[inputs-1]
+}
+
+// Hash computes the Poseidon hash of 1 to MaxInputs field elements.
+func Hash(inputs []*big.Int) (*big.Int, error) {
+	if len(inputs) == 0 || len(inputs) > MaxInputs {
+		return nil, errInputCount
+	}
+	for _, input := range inputs {
+		if input.Sign() < 0 || input.Cmp(Modulus) >= 0 {
+			return nil, errInputRange
+		}
+	}
+	params := instance(len(inputs))
+
+	state := make([]*big.Int, params.width)
+	state[0] = new(big.Int)
+	for i, input := range inputs {
+		state[i+1] = new(big.Int).Set(input)
+	}
+	next := make([]*big.Int, params.width)
+	for i := range next {
+		next[i] = new(big.Int)
+	}
+	prod := new(big.Int)
+
+	rounds := fullRounds + params.partial
+	for r := 0; r < rounds; r++ {
+		for i := range state {
+			state[i].Add(state[i], params.constants[r*params.width+i])
+		}
+		if r < fullRounds/2 || r >= fullRounds/2+params.partial {
+			for i := range state {
+				sbox(state[i])
+			}
+		} else {
+			sbox(state[0])
+		}
+		for i, row := range params.mds {
+			next[i].SetUint64(0)
+			for j, elem := range row {
+				next[i].Add(next[i], prod.Mul(elem, state[j]))
+			}
+			next[i].Mod(next[i], Modulus)
+		}
+		state, next = next, state
+	}
+	return state[0], nil
+}
+
+// sbox raises x to the fifth power in place.
+func sbox(x *big.Int) {
+	x.Mod(x, Modulus)
+	sq := new(big.Int).Mul(x, x)
+	sq.Mod(sq, Modulus)
+	sq.Mul(sq, sq)
+	sq.Mod(sq, Modulus)
+	x.Mul(x, sq)
+	x.Mod(x, Modulus)
+}
+
+// newParameters derives the round constants and the Cauchy MDS matrix of the
+// instance with the given state width and partial rounds.
+func newParameters(width, partial int) *parameters {
+	bits := Modulus.BitLen()
+	gen := newGrain(width, fullRounds, partial, bits)
+
+	params := &parameters{
+		width:     width,
+		partial:   partial,
+		constants: make([]*big.Int, (fullRounds+partial)*width),
+	}
+	for i := range params.constants {
+		for {
+			if c := gen.next(bits); c.Cmp(Modulus) < 0 {
+				params.constants[i] = c
+				break
+			}
+		}
+	}
+	for params.mds == nil {
+		// Sample 2*width distinct field elements for the Cauchy matrix
+		var elems []*big.Int
+		for elems == nil {
+			elems = make([]*big.Int, 2*width)
+			seen := make(map[string]bool)
+			for i := range elems {
+				elems[i] = gen.next(bits)
+				elems[i].Mod(elems[i], Modulus)
+				seen[string(elems[i].Bytes())] = true
+			}
+			if len(seen) != len(elems) {
+				elems = nil
+			}
+		}
+		mds := make([][]*big.Int, width)
+		for i := range mds {
+			mds[i] = make([]*big.Int, width)
+			for j := range mds[i] {
+				sum := new(big.Int).Add(elems[i], elems[width+j])
+				if sum.Mod(sum, Modulus).Sign() == 0 {
+					mds = nil
+					break
+				}
+				mds[i][j] = sum.ModInverse(sum, Modulus)
+			}
+			if mds == nil {
+				break
+			}
+		}
+		params.mds = mds
+	}
+	return params
+}
+
+// grain is the self-shrinking Grain LFSR used to derive the parameters.
+type grain struct {
+	state [80]bool
+	pos   int
+}
+
+// newGrain initialises the LFSR with the instance description and discards the
+// first 160 output bits.
+func newGrain(width, full, partial, bits int) *grain {
+	g := new(grain)
+	n := 0
+	push := func(v uint64, size int) {
+		for i := size - 1; i >= 0; i-- {
+			g.state[n] = v>>uint(i)&1 == 1
+			n++
+		}
+	}
+	push(1, 2) // prime field
+	push(0, 4) // x^alpha S-box
+	push(uint64(bits), 12)
+	push(uint64(width), 12)
+	push(uint64(full), 10)
+	push(uint64(partial), 10)
+	push(1<<30-1, 30)
+
+	for i := 0; i < 160; i++ {
+		g.clock()
+	}
+	return g
+}
+
+// clock shifts the LFSR by one bit, returning the new bit.
+func (g *grain) clock() bool {
+	at := func(i int) bool { return g.state[(g.pos+i)%80] }
+	bit := at(62) != at(51) != at(38) != at(23) != at(13) != at(0)
+	g.state[g.pos] = bit
+	g.pos = (g.pos + 1) % 80
+	return bit
+}
+
+// bit returns the next output bit: of each pair of LFSR bits, the second one
+// is emitted if the first one is set.
+func (g *grain) bit() bool {
+	for !g.clock() {
+		g.clock()
+	}
+	return g.clock()
+}
+
+// next returns an integer made of the next n output bits, most significant
+// bit first.
+func (g *grain) next(n int) *big.Int {
+	v := new(big.Int)
+	for i := 0; i < n; i++ {
+		v.Lsh(v, 1)
+		if g.bit() {
+			v.SetBit(v, 0, 1)
+		}
+	}
+	return v
+}
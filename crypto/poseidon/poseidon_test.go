@@ -0,0 +1,77 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package poseidon
+
+import (
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// Tests the hashes against the circomlib reference vectors.
+func TestHash(t *testing.T) {
+	tests := []struct {
+		inputs []int64
+		hash   string
+	}{
+		{[]int64{1}, "0x29176100eaa962bdc1fe6c654d6a3c130e96a4d1168b33848b897dc502820133"},
+		{[]int64{1, 2}, "0x115cc0f5e7d690413df64c6b9662e9cf2a3617f2743245519e19607a4417189a"},
+		{[]int64{1, 2, 3, 4}, "0x299c867db6c1fdd79dcefa40e4510b9837e60ebb1ce0663dbaa525df65250465"},
+	}
+	for i, tt := range tests {
+		inputs := make([]*big.Int, len(tt.inputs))
+		for j, input := range tt.inputs {
+			inputs[j] = big.NewInt(input)
+		}
+		hash, err := Hash(inputs)
+		if err != nil {
+			t.Fatalf("test %d: failed to hash: %v", i, err)
+		}
+		if have := hexutil.EncodeBig(hash); have != hexutil.EncodeBig(hexutil.MustDecodeBig(tt.hash)) {
+			t.Errorf("test %d: hash mismatch: have %s, want %s", i, have, tt.hash)
+		}
+	}
+}
+
+func TestHashInvalidInputs(t *testing.T) {
+	if _, err := Hash(nil); err != errInputCount {
+		t.Errorf("empty input: error mismatch: have %v, want %v", err, errInputCount)
+	}
+	if _, err := Hash(make([]*big.Int, MaxInputs+1)); err != errInputCount {
+		t.Errorf("oversized input: error mismatch: have %v, want %v", err, errInputCount)
+	}
+	if _, err := Hash([]*big.Int{new(big.Int).Set(Modulus)}); err != errInputRange {
+		t.Errorf("unreduced input: error mismatch: have %v, want %v", err, errInputRange)
+	}
+}
+
+func BenchmarkHash(b *testing.B) {
+	for _, n := range []int{1, 2, 4, 8, 16} {
+		inputs := make([]*big.Int, n)
+		for i := range inputs {
+			inputs[i] = big.NewInt(int64(i))
+		}
+		Hash(inputs)
+		b.Run(fmt.Sprintf("%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				Hash(inputs)
+			}
+		})
+	}
+}
@@ -33,6 +33,7 @@ import (
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/statesize"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/internal/ethapi"
 	"github.com/ethereum/go-ethereum/params"
@@ -90,6 +91,62 @@ func (s *PublicEthereumAPI) StorageRoot(addr common.Address, blockNr *rpc.BlockN
 	return pub.GetStorageRoot(addr)
 }
 
+// rpcTxPoolDiff is a change of the transaction pool content, as sent to the
+// subscribers of txpoolDiff.
+type rpcTxPoolDiff struct {
+	Type       core.TxDiffType   `json:"type"`
+	Hash       common.Hash       `json:"hash"`
+	From       common.Address    `json:"from"`
+	Nonce      hexutil.Uint64    `json:"nonce"`
+	Reason     core.TxDropReason `json:"reason,omitempty"`
+	ReplacedBy *common.Hash      `json:"replacedBy,omitempty"`
+}
+
+// TxpoolDiff creates a subscription notifying each transaction added to,
+// dropped from or replaced in the transaction pool, along with the reason why
+// dropped transactions left it.
+func (api *PublicEthereumAPI) TxpoolDiff(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		diffs := make(chan core.TxPoolDiffEvent, 128)
+		diffSub := api.e.txPool.SubscribeTxPoolDiffEvent(diffs)
+
+		for {
+			select {
+			case ev := <-diffs:
+				for _, diff := range ev.Diffs {
+					out := &rpcTxPoolDiff{
+						Type:   diff.Type,
+						Hash:   diff.Tx.Hash(),
+						From:   diff.From,
+						Nonce:  hexutil.Uint64(diff.Tx.Nonce()),
+						Reason: diff.Reason,
+					}
+					if diff.Replacement != nil {
+						hash := diff.Replacement.Hash()
+						out.ReplacedBy = &hash
+					}
+					notifier.Notify(rpcSub.ID, out)
+				}
+			case <-rpcSub.Err():
+				diffSub.Unsubscribe()
+				return
+			case <-notifier.Closed():
+				diffSub.Unsubscribe()
+				return
+			}
+		}
+	}()
+
+	return rpcSub, nil
+}
+
 // Hashrate returns the POW hashrate
 func (api *PublicEthereumAPI) Hashrate() hexutil.Uint64 {
 	return hexutil.Uint64(api.e.Miner().HashRate())
@@ -104,6 +161,17 @@ func (api *PublicEthereumAPI) ChainId() hexutil.Uint64 {
 	return (hexutil.Uint64)(chainID.Uint64())
 }
 
+// CancelTransaction removes a pending transaction from the pool of this node and
+// its peers, given the signature of its sender over the eth_sign hash of
+// "cancel:<transaction hash>". The transaction is refused from then on, so its
+// nonce can be taken by a replacement even on networks without gas price.
+func (api *PublicEthereumAPI) CancelTransaction(hash common.Hash, sig hexutil.Bytes) (bool, error) {
+	if err := api.e.txCancel.Cancel(hash, sig); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
 // PublicMinerAPI provides an API to control the miner.
 // It offers only methods that operate on data that pose no security risk when it is publicly accessible.
 type PublicMinerAPI struct {
@@ -217,6 +285,33 @@ func (api *PrivateAdminAPI) ExportChain(file string) (bool, error) {
 	return true, nil
 }
 
+// NodeMode returns the current operational mode of the node.
+func (api *PrivateAdminAPI) NodeMode() *NodeMode {
+	return api.eth.NodeMode()
+}
+
+// SetNodeMode switches the node between archive and full pruning, mining on
+// and off, and validator and observer roles where the consensus engine allows
+// it, without a restart. The mining intent is resumed on the next start, while
+// the pruning mode reverts to the configured --gcmode.
+func (api *PrivateAdminAPI) SetNodeMode(args NodeModeArgs) (*NodeMode, error) {
+	return api.eth.SetNodeMode(args)
+}
+
+// CompareChainConfig fetches the chain configuration of a connected peer, given
+// by its node id or an unambiguous prefix of it, and reports the fields which
+// differ from the local configuration.
+func (api *PrivateAdminAPI) CompareChainConfig(ctx context.Context, peerID string) (*ChainConfigComparison, error) {
+	return api.eth.chainConfigChk.Compare(ctx, peerID)
+}
+
+// NetworkVersions collects the software versions, chain configuration hashes
+// and consensus parameters attested by the connected peers, to check that the
+// whole network upgraded before a transition activates.
+func (api *PrivateAdminAPI) NetworkVersions(ctx context.Context) (*NetworkVersions, error) {
+	return api.eth.chainConfigChk.NetworkVersions(ctx)
+}
+
 func hasAllBlocks(chain *core.BlockChain, bs []*types.Block) bool {
 	for _, b := range bs {
 		if !chain.HasBlock(b.Hash(), b.NumberU64()) {
@@ -288,6 +383,22 @@ func NewPublicDebugAPI(eth *Ethereum) *PublicDebugAPI {
 	return &PublicDebugAPI{eth: eth}
 }
 
+// Quorum
+// blockByNumber returns the block of a number of the debug APIs, resolving the
+// latest and finalized tags. The pending block is left to the callers.
+func (s *Ethereum) blockByNumber(number rpc.BlockNumber) *types.Block {
+	switch number {
+	case rpc.LatestBlockNumber:
+		return s.blockchain.CurrentBlock()
+	case rpc.FinalizedBlockNumber:
+		number = s.APIBackend.finalizedNumber()
+	}
+	if height, ok := number.Height(); ok {
+		return s.blockchain.GetBlockByNumber(height)
+	}
+	return nil
+}
+
 // DumpBlock retrieves the entire state of the database at a given block.
 func (api *PublicDebugAPI) DumpBlock(blockNr rpc.BlockNumber, typ string) (state.Dump, error) {
 	var publicState, privateState *state.StateDB
@@ -298,12 +409,7 @@ func (api *PublicDebugAPI) DumpBlock(blockNr rpc.BlockNumber, typ string) (state
 		// the miner and operate on those
 		_, publicState, privateState = api.eth.miner.Pending()
 	} else {
-		var block *types.Block
-		if blockNr == rpc.LatestBlockNumber {
-			block = api.eth.blockchain.CurrentBlock()
-		} else {
-			block = api.eth.blockchain.GetBlockByNumber(uint64(blockNr))
-		}
+		block := api.eth.blockByNumber(blockNr)
 		if block == nil {
 			return state.Dump{}, fmt.Errorf("block #%d not found", blockNr)
 		}
@@ -337,13 +443,98 @@ func NewPrivateDebugAPI(config *params.ChainConfig, eth *Ethereum) *PrivateDebug
 }
 
 // Preimage is a debug API function that returns the preimage for a sha3 hash, if known.
+// Preimages are only stored when recording is enabled (--preimages or --vmdebug),
+// for public and private contracts alike, and within the configured retention.
 func (api *PrivateDebugAPI) Preimage(ctx context.Context, hash common.Hash) (hexutil.Bytes, error) {
 	if preimage := rawdb.ReadPreimage(api.eth.ChainDb(), hash); preimage != nil {
 		return preimage, nil
 	}
+	if !api.eth.config.EnablePreimageRecording {
+		return nil, errors.New("unknown preimage, recording is disabled")
+	}
 	return nil, errors.New("unknown preimage")
 }
 
+// MempoolDivergence samples the pending transactions of the connected peers
+// and reports how they differ from the local transaction pool.
+func (api *PrivateDebugAPI) MempoolDivergence(ctx context.Context) ([]*TxDivergence, error) {
+	return api.eth.txDiag.Divergence(ctx)
+}
+
+// HealStateReport sums up the healing of the public state of a block and the
+// check of its private state.
+type HealStateReport struct {
+	Number       uint64           `json:"number"`
+	Hash         common.Hash      `json:"hash"`
+	State        *HealStateResult `json:"state"`
+	PrivateState *HealStateResult `json:"privateState,omitempty"`
+}
+
+// HealState walks the public state of a block, the head by default, and fetches
+// the trie nodes and contract codes missing from the database from the peers,
+// verified against their hash. It repairs a partially corrupted database without
+// a full resync, and is refused while the node synchronises. The private state
+// is only checked, its missing entries reported: they are never requested from
+// the peers, which would learn the hashes of the private state.
+func (api *PrivateDebugAPI) HealState(ctx context.Context, blockNr *rpc.BlockNumber) (*HealStateReport, error) {
+	block := api.eth.blockchain.CurrentBlock()
+	if blockNr != nil && *blockNr >= 0 {
+		if block = api.eth.blockchain.GetBlockByNumber(uint64(*blockNr)); block == nil {
+			return nil, fmt.Errorf("block #%d not found", *blockNr)
+		}
+	}
+	var (
+		healer = api.eth.protocolManager.healer
+		report = &HealStateReport{Number: block.NumberU64(), Hash: block.Hash()}
+		err    error
+	)
+	if report.State, err = healer.heal(ctx, block.Root(), api.eth.blockchain.TrieNode, api.eth.chainDb, true); err != nil {
+		return report, err
+	}
+	if root := core.GetPrivateStateRoot(api.eth.chainDb, block.Root()); root != (common.Hash{}) {
+		if report.PrivateState, err = healer.heal(ctx, root, api.eth.blockchain.PrivateTrieNode, api.eth.chainDb, false); err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+// errStateSizeDisabled is returned by the state size methods when the
+// accounting is disabled.
+var errStateSizeDisabled = errors.New("state size accounting disabled (--statesize)")
+
+// StateSize returns the storage slots, code and trie bytes of a contract
+// counted since the accounting was enabled.
+func (api *PrivateDebugAPI) StateSize(ctx context.Context, address common.Address) (*statesize.ContractSize, error) {
+	idx := api.eth.blockchain.StateSizes()
+	if idx == nil {
+		return nil, errStateSizeDisabled
+	}
+	if size := idx.Size(address); size != nil {
+		return size, nil
+	}
+	return &statesize.ContractSize{Address: address}, nil
+}
+
+// StateSizeReport lists the contracts with the largest storage and code, and
+// the first block they were counted from.
+type StateSizeReport struct {
+	Since     uint64                    `json:"since"`
+	Contracts []*statesize.ContractSize `json:"contracts"`
+}
+
+// StateSizeReport returns the n contracts with the most storage trie and code
+// bytes, largest first. The counters of the contracts created before the
+// accounting was enabled miss their earlier storage, and are flagged partial.
+func (api *PrivateDebugAPI) StateSizeReport(ctx context.Context, n int) (*StateSizeReport, error) {
+	idx := api.eth.blockchain.StateSizes()
+	if idx == nil {
+		return nil, errStateSizeDisabled
+	}
+	since, _ := idx.Tail()
+	return &StateSizeReport{Since: since, Contracts: idx.Top(n)}, nil
+}
+
 // BadBlockArgs represents the entries in the list returned when bad blocks are queried.
 type BadBlockArgs struct {
 	Hash  common.Hash            `json:"hash"`
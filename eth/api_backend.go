@@ -21,11 +21,14 @@ import (
 	"errors"
 	"math/big"
 
+	"github.com/ethereum/go-ethereum/abiregistry"
 	"github.com/ethereum/go-ethereum/accounts"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/math"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/bloombits"
+	"github.com/ethereum/go-ethereum/core/logindex"
+	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
@@ -33,19 +36,25 @@ import (
 	"github.com/ethereum/go-ethereum/eth/gasprice"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rpc"
 )
 
 // EthAPIBackend implements ethapi.Backend for full nodes
 type EthAPIBackend struct {
-	eth *Ethereum
-	gpo *gasprice.Oracle
+	eth   *Ethereum
+	gpo   *gasprice.Oracle
+	cache *ethapi.ResponseCache
+	calls *ethapi.CallPool
 
 	// Quorum
 	//
 	// hex node id from node public key
 	hexNodeId string
+
+	finality  uint64            // Number of blocks on top of a block for it to be final
+	nonceLock ethapi.AddrLocker // Serialises the nonce assignment of the transactions sent
 }
 
 // ChainConfig returns the active chain configuration.
@@ -63,52 +72,68 @@ func (b *EthAPIBackend) SetHead(number uint64) {
 }
 
 func (b *EthAPIBackend) HeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*types.Header, error) {
-	// Pending block is only known by the miner
+	// Quorum: the pending block executes the pending transactions on the latest block
 	if blockNr == rpc.PendingBlockNumber {
-		block := b.eth.miner.PendingBlock()
+		block, _, _, err := b.eth.pending.get()
+		if err != nil {
+			return nil, err
+		}
 		return block.Header(), nil
 	}
 	// Otherwise resolve and return the block
 	if blockNr == rpc.LatestBlockNumber {
 		return b.eth.blockchain.CurrentBlock().Header(), nil
 	}
+	if blockNr == rpc.FinalizedBlockNumber {
+		blockNr = b.finalizedNumber()
+	}
 	return b.eth.blockchain.GetHeaderByNumber(uint64(blockNr)), nil
 }
 
+// finalizedNumber returns the number of the latest final block.
+func (b *EthAPIBackend) finalizedNumber() rpc.BlockNumber {
+	head := b.eth.blockchain.CurrentBlock().NumberU64()
+	if head < b.finality {
+		return rpc.EarliestBlockNumber
+	}
+	return rpc.BlockNumber(head - b.finality)
+}
+
 func (b *EthAPIBackend) HeaderByHash(ctx context.Context, hash common.Hash) (*types.Header, error) {
 	return b.eth.blockchain.GetHeaderByHash(hash), nil
 }
 
 func (b *EthAPIBackend) BlockByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*types.Block, error) {
-	// Pending block is only known by the miner
+	// Quorum: the pending block executes the pending transactions on the latest block
 	if blockNr == rpc.PendingBlockNumber {
-		if b.eth.protocolManager.raftMode {
-			// Use latest instead.
-			return b.eth.blockchain.CurrentBlock(), nil
-		}
-		block := b.eth.miner.PendingBlock()
-		return block, nil
+		block, _, _, err := b.eth.pending.get()
+		return block, err
 	}
 	// Otherwise resolve and return the block
 	if blockNr == rpc.LatestBlockNumber {
 		return b.eth.blockchain.CurrentBlock(), nil
 	}
-	return b.eth.blockchain.GetBlockByNumber(uint64(blockNr)), nil
+	if blockNr == rpc.FinalizedBlockNumber {
+		blockNr = b.finalizedNumber()
+	}
+	block := b.eth.blockchain.GetBlockByNumber(uint64(blockNr))
+	if block == nil {
+		// Quorum: tell the pruned blocks from the unknown ones
+		if header := b.eth.blockchain.GetHeaderByNumber(uint64(blockNr)); header != nil && core.HistoryPruned(b.eth.chainDb, uint64(blockNr)) {
+			return nil, core.ErrHistoryPruned
+		}
+	}
+	return block, nil
 }
 
 func (b *EthAPIBackend) StateAndHeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (vm.MinimalApiState, *types.Header, error) {
-	// Pending state is only known by the miner
+	// Quorum: the pending state is the one of the pending transactions executed
+	// on the latest block, whether or not the node mines
 	if blockNr == rpc.PendingBlockNumber {
-		if b.eth.protocolManager.raftMode {
-			// Use latest instead.
-			header, err := b.HeaderByNumber(ctx, rpc.LatestBlockNumber)
-			if header == nil || err != nil {
-				return nil, nil, err
-			}
-			publicState, privateState, err := b.eth.BlockChain().StateAt(header.Root)
-			return EthAPIState{publicState, privateState}, header, err
+		block, publicState, privateState, err := b.eth.pending.get()
+		if err != nil {
+			return nil, nil, err
 		}
-		block, publicState, privateState := b.eth.miner.Pending()
 		return EthAPIState{publicState, privateState}, block.Header(), nil
 	}
 	// Otherwise resolve the block number and return its state
@@ -121,16 +146,27 @@ func (b *EthAPIBackend) StateAndHeaderByNumber(ctx context.Context, blockNr rpc.
 }
 
 func (b *EthAPIBackend) GetBlock(ctx context.Context, hash common.Hash) (*types.Block, error) {
-	return b.eth.blockchain.GetBlockByHash(hash), nil
+	block := b.eth.blockchain.GetBlockByHash(hash)
+	if block == nil && b.historyPruned(hash) {
+		return nil, core.ErrHistoryPruned
+	}
+	return block, nil
 }
 
 func (b *EthAPIBackend) GetReceipts(ctx context.Context, hash common.Hash) (types.Receipts, error) {
-	return b.eth.blockchain.GetReceiptsByHash(hash), nil
+	receipts := b.eth.blockchain.GetReceiptsByHash(hash)
+	if receipts == nil && b.historyPruned(hash) {
+		return nil, core.ErrHistoryPruned
+	}
+	return receipts, nil
 }
 
 func (b *EthAPIBackend) GetLogs(ctx context.Context, hash common.Hash) ([][]*types.Log, error) {
 	receipts := b.eth.blockchain.GetReceiptsByHash(hash)
 	if receipts == nil {
+		if b.historyPruned(hash) {
+			return nil, core.ErrHistoryPruned
+		}
 		return nil, nil
 	}
 	logs := make([][]*types.Log, len(receipts))
@@ -140,6 +176,14 @@ func (b *EthAPIBackend) GetLogs(ctx context.Context, hash common.Hash) ([][]*typ
 	return logs, nil
 }
 
+// Quorum
+// historyPruned tells whether the block of the given hash is known by its
+// header, but its body and receipts were pruned by the history retention.
+func (b *EthAPIBackend) historyPruned(hash common.Hash) bool {
+	number := rawdb.ReadHeaderNumber(b.eth.chainDb, hash)
+	return number != nil && core.HistoryPruned(b.eth.chainDb, *number)
+}
+
 func (b *EthAPIBackend) GetTd(blockHash common.Hash) *big.Int {
 	return b.eth.blockchain.GetTdByHash(blockHash)
 }
@@ -152,14 +196,15 @@ func (b *EthAPIBackend) GetEVM(ctx context.Context, msg core.Message, state vm.M
 
 	context := core.NewEVMContext(msg, header, b.eth.BlockChain(), nil)
 
-	// Set the private state to public state if contract address is not present in the private state
+	// Set the private state to public state if contract address is not present in the private state,
+	// unless the message is private, so that it sees the private contracts wherever it calls them
 	to := common.Address{}
 	if msg.To() != nil {
 		to = *msg.To()
 	}
 
 	privateState := statedb.privateState
-	if !privateState.Exist(to) {
+	if pm, ok := msg.(core.PrivateMessage); (!ok || !pm.IsPrivate()) && !privateState.Exist(to) {
 		privateState = statedb.state
 	}
 
@@ -215,6 +260,13 @@ func (b *EthAPIBackend) GetPoolNonce(ctx context.Context, addr common.Address) (
 	return b.eth.txPool.State().GetNonce(addr), nil
 }
 
+// Quorum
+// PendingNonce returns the nonce of the account after its pending transactions,
+// leaving out the ones that fail to execute on the pending block.
+func (b *EthAPIBackend) PendingNonce(ctx context.Context, addr common.Address) (uint64, error) {
+	return b.eth.pending.nonce(addr)
+}
+
 func (b *EthAPIBackend) Stats() (pending int, queued int) {
 	return b.eth.txPool.Stats()
 }
@@ -223,6 +275,10 @@ func (b *EthAPIBackend) TxPoolContent() (map[common.Address]types.Transactions,
 	return b.eth.TxPool().Content()
 }
 
+func (b *EthAPIBackend) CheckTx(tx *types.Transaction) ([]core.TxCheckResult, error) {
+	return b.eth.TxPool().Check(tx, false), nil
+}
+
 func (b *EthAPIBackend) SubscribeNewTxsEvent(ch chan<- core.NewTxsEvent) event.Subscription {
 	return b.eth.TxPool().SubscribeNewTxsEvent(ch)
 }
@@ -255,6 +311,14 @@ func (b *EthAPIBackend) AccountManager() *accounts.Manager {
 	return b.eth.AccountManager()
 }
 
+func (b *EthAPIBackend) ResponseCache() *ethapi.ResponseCache {
+	return b.cache
+}
+
+func (b *EthAPIBackend) CallPool() *ethapi.CallPool {
+	return b.calls
+}
+
 func (b *EthAPIBackend) BloomStatus() (uint64, uint64) {
 	sections, _, _ := b.eth.bloomIndexer.Sections()
 	return params.BloomBitsBlocks, sections
@@ -266,6 +330,20 @@ func (b *EthAPIBackend) ServiceFilter(ctx context.Context, session *bloombits.Ma
 	}
 }
 
+// Quorum
+func (b *EthAPIBackend) LogIndex() *logindex.Index {
+	return b.eth.blockchain.LogIndex()
+}
+
+// Quorum
+func (b *EthAPIBackend) ABIRegistry() *abiregistry.Registry {
+	return b.eth.abiRegistry
+}
+
+func (b *EthAPIBackend) NonceLock() *ethapi.AddrLocker {
+	return &b.nonceLock
+}
+
 // used by Quorum
 type EthAPIState struct {
 	state, privateState *state.StateDB
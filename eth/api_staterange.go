@@ -0,0 +1,213 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// stateRangeMaxResults is the maximum number of trie entries returned by a
+// state range call.
+const stateRangeMaxResults = 1024
+
+var errStateRangeCursor = errors.New("invalid state range cursor")
+
+// stateRangeCursor resumes a state range from the next key of the trie, in the
+// state it was started on, so that a crawl spanning many calls reads a single
+// consistent state.
+type stateRangeCursor struct {
+	Root common.Hash // Root of the state trie the range was started on
+	Next common.Hash // Hashed key of the next entry
+}
+
+func (c *stateRangeCursor) encode() hexutil.Bytes {
+	return append(c.Root.Bytes(), c.Next.Bytes()...)
+}
+
+// decodeStateRangeCursor decodes the cursor of a range of the state with the
+// given root, nil to start from its first entry.
+func decodeStateRangeCursor(blob hexutil.Bytes, root common.Hash) (*stateRangeCursor, error) {
+	if len(blob) == 0 {
+		return &stateRangeCursor{Root: root}, nil
+	}
+	if len(blob) != 2*common.HashLength {
+		return nil, errStateRangeCursor
+	}
+	cursor := &stateRangeCursor{
+		Root: common.BytesToHash(blob[:common.HashLength]),
+		Next: common.BytesToHash(blob[common.HashLength:]),
+	}
+	if cursor.Root != root {
+		return nil, fmt.Errorf("cursor of state %x, not %x", cursor.Root, root)
+	}
+	return cursor, nil
+}
+
+// RangeAccount is an account of the state returned by debug_accountRange.
+type RangeAccount struct {
+	Hash     common.Hash     `json:"hash"`    // Hashed address, the key of the account in the trie
+	Address  *common.Address `json:"address"` // nil if the preimage of the hash is unknown
+	Nonce    hexutil.Uint64  `json:"nonce"`
+	Balance  *hexutil.Big    `json:"balance"`
+	Root     common.Hash     `json:"root"`
+	CodeHash common.Hash     `json:"codeHash"`
+}
+
+// AccountRangeResult is the result of a debug_accountRange call.
+type AccountRangeResult struct {
+	Root     common.Hash     `json:"root"`
+	Accounts []*RangeAccount `json:"accounts"`
+	Next     hexutil.Bytes   `json:"next"` // Cursor resuming the range, nil after the last account
+}
+
+// StateStorageRangeResult is the result of a debug_storageRange call.
+type StateStorageRangeResult struct {
+	Root    common.Hash   `json:"root"`
+	Storage storageMap    `json:"storage"`
+	Next    hexutil.Bytes `json:"next"` // Cursor resuming the range, nil after the last slot
+}
+
+// AccountRange returns up to maxResults accounts of the public or private state
+// of a block, resuming from the cursor returned by the previous call if any.
+// The cursor pins the state the range was started on, which must remain
+// available until the crawl completes. The calls are rate limited by the
+// number of accounts read.
+func (api *PrivateDebugAPI) AccountRange(ctx context.Context, blockNr rpc.BlockNumber, typ string, cursor hexutil.Bytes, maxResults int) (*AccountRangeResult, error) {
+	statedb, root, err := api.stateRangeAt(blockNr, typ)
+	if err != nil {
+		return nil, err
+	}
+	start, err := decodeStateRangeCursor(cursor, root)
+	if err != nil {
+		return nil, err
+	}
+	if maxResults, err = api.waitStateRange(ctx, maxResults); err != nil {
+		return nil, err
+	}
+	tr, err := statedb.Database().OpenTrie(root)
+	if err != nil {
+		return nil, err
+	}
+	return accountRange(tr, root, start.Next, maxResults)
+}
+
+// accountRange returns up to maxResults accounts of a state trie from the given
+// hashed key on.
+func accountRange(tr state.Trie, root common.Hash, start common.Hash, maxResults int) (*AccountRangeResult, error) {
+	result := &AccountRangeResult{Root: root, Accounts: []*RangeAccount{}}
+	it := trie.NewIterator(tr.NodeIterator(start[:]))
+	for i := 0; i < maxResults && it.Next(); i++ {
+		var data state.Account
+		if err := rlp.DecodeBytes(it.Value, &data); err != nil {
+			return nil, err
+		}
+		account := &RangeAccount{
+			Hash:     common.BytesToHash(it.Key),
+			Nonce:    hexutil.Uint64(data.Nonce),
+			Balance:  (*hexutil.Big)(data.Balance),
+			Root:     data.Root,
+			CodeHash: common.BytesToHash(data.CodeHash),
+		}
+		if preimage := tr.GetKey(it.Key); preimage != nil {
+			addr := common.BytesToAddress(preimage)
+			account.Address = &addr
+		}
+		result.Accounts = append(result.Accounts, account)
+	}
+	if it.Next() {
+		result.Next = (&stateRangeCursor{Root: root, Next: common.BytesToHash(it.Key)}).encode()
+	}
+	return result, it.Err
+}
+
+// StorageRange returns up to maxResults storage slots of a contract in the
+// public or private state of a block, resuming from the cursor returned by the
+// previous call if any. Unlike debug_storageRangeAt, it reads the state of the
+// block rather than replaying its transactions.
+func (api *PrivateDebugAPI) StorageRange(ctx context.Context, blockNr rpc.BlockNumber, typ string, address common.Address, cursor hexutil.Bytes, maxResults int) (*StateStorageRangeResult, error) {
+	statedb, root, err := api.stateRangeAt(blockNr, typ)
+	if err != nil {
+		return nil, err
+	}
+	start, err := decodeStateRangeCursor(cursor, root)
+	if err != nil {
+		return nil, err
+	}
+	st := statedb.StorageTrie(address)
+	if st == nil {
+		return nil, fmt.Errorf("account %x doesn't exist", address)
+	}
+	if maxResults, err = api.waitStateRange(ctx, maxResults); err != nil {
+		return nil, err
+	}
+	storage, err := storageRangeAt(st, start.Next[:], maxResults)
+	if err != nil {
+		return nil, err
+	}
+	result := &StateStorageRangeResult{Root: root, Storage: storage.Storage}
+	if storage.NextKey != nil {
+		result.Next = (&stateRangeCursor{Root: root, Next: *storage.NextKey}).encode()
+	}
+	return result, nil
+}
+
+// stateRangeAt returns the public or private state of a block, and its root.
+func (api *PrivateDebugAPI) stateRangeAt(blockNr rpc.BlockNumber, typ string) (*state.StateDB, common.Hash, error) {
+	if blockNr == rpc.PendingBlockNumber {
+		return nil, common.Hash{}, errors.New("state range of the pending block not supported")
+	}
+	block := api.eth.blockByNumber(blockNr)
+	if block == nil {
+		return nil, common.Hash{}, fmt.Errorf("block #%d not found", blockNr)
+	}
+	publicState, privateState, err := api.eth.blockchain.StateAt(block.Root())
+	if err != nil {
+		return nil, common.Hash{}, err
+	}
+	switch typ {
+	case "", "public":
+		return publicState, block.Root(), nil
+	case "private":
+		return privateState, core.GetPrivateStateRoot(api.eth.chainDb, block.Root()), nil
+	default:
+		return nil, common.Hash{}, fmt.Errorf("unknown type: '%s'", typ)
+	}
+}
+
+// waitStateRange caps the number of entries of a state range call, and waits
+// for the rate limiter to allow reading them.
+func (api *PrivateDebugAPI) waitStateRange(ctx context.Context, maxResults int) (int, error) {
+	if maxResults <= 0 || maxResults > stateRangeMaxResults {
+		maxResults = stateRangeMaxResults
+	}
+	if limiter := api.eth.stateRangeLimiter; limiter != nil {
+		if err := limiter.WaitN(ctx, maxResults); err != nil {
+			return 0, err
+		}
+	}
+	return maxResults, nil
+}
@@ -0,0 +1,87 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+// Tests that a state is crawled in full across calls resumed from the cursors,
+// and that the cursors are refused on another state.
+func TestAccountRangeCursor(t *testing.T) {
+	db := state.NewDatabase(ethdb.NewMemDatabase())
+	statedb, _ := state.New(common.Hash{}, db)
+	for i := byte(1); i <= 5; i++ {
+		statedb.SetNonce(common.Address{i}, uint64(i))
+		statedb.AddBalance(common.Address{i}, big.NewInt(int64(i)))
+	}
+	root, err := statedb.Commit(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr, err := db.OpenTrie(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var (
+		cursor []byte
+		seen   = make(map[common.Address]bool)
+		calls  int
+	)
+	for {
+		start, err := decodeStateRangeCursor(cursor, root)
+		if err != nil {
+			t.Fatalf("failed to decode cursor: %v", err)
+		}
+		result, err := accountRange(tr, root, start.Next, 2)
+		if err != nil {
+			t.Fatalf("failed to read range: %v", err)
+		}
+		calls++
+		for _, account := range result.Accounts {
+			if account.Address == nil {
+				t.Fatalf("account %x without address", account.Hash)
+			}
+			if uint64(account.Nonce) != uint64(account.Address[0]) {
+				t.Errorf("account %x: nonce mismatch: have %d", account.Address, account.Nonce)
+			}
+			seen[*account.Address] = true
+		}
+		if result.Next == nil {
+			break
+		}
+		cursor = result.Next
+	}
+	if len(seen) != 5 || calls != 3 {
+		t.Errorf("crawl mismatch: have %d accounts in %d calls, want 5 in 3", len(seen), calls)
+	}
+	// A cursor of another state, or malformed, is refused
+	statedb.SetNonce(common.Address{6}, 6)
+	other, _ := statedb.Commit(false)
+	stale := (&stateRangeCursor{Root: root, Next: common.Hash{0x80}}).encode()
+	if _, err := decodeStateRangeCursor(stale, other); err == nil {
+		t.Errorf("cursor of another state accepted")
+	}
+	if _, err := decodeStateRangeCursor([]byte{1, 2, 3}, root); err != errStateRangeCursor {
+		t.Errorf("malformed cursor error mismatch: have %v, want %v", err, errStateRangeCursor)
+	}
+}
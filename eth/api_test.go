@@ -22,8 +22,13 @@ import (
 
 	"github.com/davecgh/go-spew/spew"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
 )
 
 var dumper = spew.ConfigState{Indent: "    "}
@@ -88,3 +93,42 @@ func TestStorageRangeAt(t *testing.T) {
 		}
 	}
 }
+
+// Tests that the block numbers of the debug APIs resolve the tags, instead of
+// wrapping them into heights.
+func TestBlockByNumber(t *testing.T) {
+	var (
+		db      = ethdb.NewMemDatabase()
+		gspec   = &core.Genesis{Config: params.TestChainConfig}
+		genesis = gspec.MustCommit(db)
+	)
+	blocks, _ := core.GenerateChain(gspec.Config, genesis, ethash.NewFaker(), db, 5, nil)
+	chain, _ := core.NewBlockChain(db, nil, gspec.Config, ethash.NewFaker(), vm.Config{}, nil)
+	defer chain.Stop()
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	eth := &Ethereum{blockchain: chain}
+	eth.APIBackend = &EthAPIBackend{eth: eth, finality: 2}
+
+	tests := []struct {
+		number rpc.BlockNumber
+		want   uint64
+	}{
+		{rpc.EarliestBlockNumber, 0},
+		{rpc.BlockNumber(2), 2},
+		{rpc.LatestBlockNumber, 5},
+		{rpc.FinalizedBlockNumber, 3},
+	}
+	for i, test := range tests {
+		block := eth.blockByNumber(test.number)
+		if block == nil || block.NumberU64() != test.want {
+			t.Errorf("test %d: block mismatch: have %v, want #%d", i, block, test.want)
+		}
+	}
+	for _, number := range []rpc.BlockNumber{rpc.BlockNumber(6), rpc.BlockNumber(-4)} {
+		if block := eth.blockByNumber(number); block != nil {
+			t.Errorf("block %d: unknown block found: #%d", number, block.NumberU64())
+		}
+	}
+}
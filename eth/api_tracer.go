@@ -101,18 +101,14 @@ func (api *PrivateDebugAPI) TraceChain(ctx context.Context, start, end rpc.Block
 	switch start {
 	case rpc.PendingBlockNumber:
 		from = api.eth.miner.PendingBlock()
-	case rpc.LatestBlockNumber:
-		from = api.eth.blockchain.CurrentBlock()
 	default:
-		from = api.eth.blockchain.GetBlockByNumber(uint64(start))
+		from = api.eth.blockByNumber(start)
 	}
 	switch end {
 	case rpc.PendingBlockNumber:
 		to = api.eth.miner.PendingBlock()
-	case rpc.LatestBlockNumber:
-		to = api.eth.blockchain.CurrentBlock()
 	default:
-		to = api.eth.blockchain.GetBlockByNumber(uint64(end))
+		to = api.eth.blockByNumber(end)
 	}
 	// Trace the chain if we've found all our blocks
 	if from == nil {
@@ -363,10 +359,8 @@ func (api *PrivateDebugAPI) TraceBlockByNumber(ctx context.Context, number rpc.B
 	switch number {
 	case rpc.PendingBlockNumber:
 		block = api.eth.miner.PendingBlock()
-	case rpc.LatestBlockNumber:
-		block = api.eth.blockchain.CurrentBlock()
 	default:
-		block = api.eth.blockchain.GetBlockByNumber(uint64(number))
+		block = api.eth.blockByNumber(number)
 	}
 	// Trace the block if it was found
 	if block == nil {
@@ -24,13 +24,17 @@ import (
 	"runtime"
 	"sync"
 	"sync/atomic"
+	"time"
 
+	"github.com/ethereum/go-ethereum/abiregistry"
 	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/adminauth"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/consensus"
 	"github.com/ethereum/go-ethereum/consensus/clique"
 	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/consensus/faultinject"
 	"github.com/ethereum/go-ethereum/consensus/istanbul"
 	istanbulBackend "github.com/ethereum/go-ethereum/consensus/istanbul/backend"
 	"github.com/ethereum/go-ethereum/core"
@@ -45,15 +49,40 @@ import (
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"github.com/ethereum/go-ethereum/kms"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/miner"
 	"github.com/ethereum/go-ethereum/node"
 	"github.com/ethereum/go-ethereum/p2p"
 	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/plugin"
+	"github.com/ethereum/go-ethereum/private"
 	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ethereum/go-ethereum/rpc"
+	"golang.org/x/time/rate"
 )
 
+// errPTMCacheNoKey is returned when the encryption of the private payload cache
+// is requested without a node key.
+var errPTMCacheNoKey = errors.New("private payload cache encryption requires a local node key")
+
+// defaultFinalityConfirmations is the number of blocks on top of a block for
+// it to be considered final, on chains without instant finality.
+const defaultFinalityConfirmations = 64
+
+// FinalityConfirmations returns the number of blocks on top of a block for it to
+// be final: none with Raft and Istanbul, whose blocks are final as soon as they
+// are imported, the configured number otherwise.
+func FinalityConfirmations(config *Config, chainConfig *params.ChainConfig) uint64 {
+	if config.RaftMode || chainConfig.Istanbul != nil {
+		return 0
+	}
+	if config.FinalityConfirmations > 0 {
+		return config.FinalityConfirmations
+	}
+	return defaultFinalityConfirmations
+}
+
 type LesServer interface {
 	Start(srvr *p2p.Server)
 	Stop()
@@ -74,6 +103,25 @@ type Ethereum struct {
 	blockchain      *core.BlockChain
 	protocolManager *ProtocolManager
 	lesServer       LesServer
+	txDiag          *txDiagnostics
+	txCancel        *txCancellations
+	chainConfigChk  *chainConfigChecker
+	gasAccountant   *gasAccountant // Quorum: nil unless gas accounting is enabled
+	health          *healthWatchdog
+	checkpoints     *checkpointExporter      // Quorum: nil unless Istanbul checkpoints are exported
+	webhooks        *webhookDispatcher       // Quorum: nil unless webhooks are registered
+	integrity       *integrityVerifier       // Quorum: nil unless historical blocks are verified
+	dryRun          *configDryRun            // Quorum: nil unless a candidate chain configuration is dry run
+	sqlExport       *sqlExportStream         // Quorum: nil unless blocks are streamed to SQL
+	pending         *pendingView             // Quorum: pending block served over RPC
+	ptmPush         *private.PushEndpoint    // Quorum: nil unless the private transaction manager pushes payloads
+	historyPruner   *historyPruner           // Quorum: nil unless block history is retained for a period
+	abiRegistry     *abiregistry.Registry    // Quorum: contract ABIs decoding receipts and logs
+	standby         *standbyPair             // Quorum: nil unless the node is part of a hot standby pair
+	operatorAuth    *adminauth.Authenticator // Quorum: verifier of operator signatures on administrative calls
+	p2pServer       *p2p.Server              // Quorum: networking rekeyed by a promoted standby
+
+	stateRangeLimiter *rate.Limiter // Quorum: nil if the state range calls are unlimited
 
 	// DB interfaces
 	chainDb ethdb.Database // Block chain database
@@ -94,7 +142,8 @@ type Ethereum struct {
 	networkID     uint64
 	netRPCService *ethapi.PublicNetAPI
 
-	lock sync.RWMutex // Protects the variadic fields (e.g. gas price and etherbase)
+	lock     sync.RWMutex // Protects the variadic fields (e.g. gas price and etherbase)
+	modeLock sync.Mutex   // Serialises operational mode transitions
 }
 
 // HACK(joel) this was added just to make the eth chain config visible to RegisterRaftService
@@ -132,6 +181,10 @@ func New(ctx *node.ServiceContext, config *Config) (*Ethereum, error) {
 	}
 	log.Info("Initialised chain configuration", "config", chainConfig)
 
+	if err := vm.CheckInterpreters(chainConfig); err != nil {
+		return nil, err
+	}
+
 	// changes to manipulate the chain id for migration from 2.0.2 and below version to 2.0.3
 	// version of Quorum  - this is applicable for v2.0.3 onwards
 	if chainConfig.IsQuorum {
@@ -162,7 +215,15 @@ func New(ctx *node.ServiceContext, config *Config) (*Ethereum, error) {
 
 	// force to set the istanbul etherbase to node key address
 	if chainConfig.Istanbul != nil {
-		eth.etherbase = crypto.PubkeyToAddress(ctx.NodeKey().PublicKey)
+		// Quorum: refuse to start with a vanity policy the engine can't enforce
+		if _, err := config.Istanbul.VanityPolicy(); err != nil {
+			return nil, err
+		}
+		eth.etherbase = crypto.PubkeyToAddress(*ctx.NodeSigner().PublicKey())
+	}
+	// Quorum
+	if config.StateRangeRate > 0 {
+		eth.stateRangeLimiter = rate.NewLimiter(rate.Limit(config.StateRangeRate), stateRangeMaxResults)
 	}
 
 	log.Info("Initialising Ethereum protocol", "versions", ProtocolVersions, "network", config.NetworkId)
@@ -174,13 +235,26 @@ func New(ctx *node.ServiceContext, config *Config) (*Ethereum, error) {
 		}
 		rawdb.WriteDatabaseVersion(chainDb, core.BlockChainVersion)
 	}
+	// The configured pruning mode always wins over the one set at runtime, the
+	// latter only warns of a switch leaving gaps in the state history
+	checkGCModeSwitch(chainDb, config.NoPruning)
 	var (
 		vmConfig = vm.Config{
 			EnablePreimageRecording: config.EnablePreimageRecording,
 			EWASMInterpreter:        config.EWASMInterpreter,
 			EVMInterpreter:          config.EVMInterpreter,
 		}
-		cacheConfig = &core.CacheConfig{Disabled: config.NoPruning, TrieNodeLimit: config.TrieCache, TrieTimeLimit: config.TrieTimeout}
+		cacheConfig = &core.CacheConfig{
+			Disabled:          config.NoPruning,
+			TrieNodeLimit:     config.TrieCache,
+			TrieTimeLimit:     config.TrieTimeout,
+			PreimageMaxSize:   config.PreimageMaxSize,
+			PreimageRetention: config.PreimageRetention,
+			Snapshot:          config.Snapshot,
+			LogIndex:          config.LogIndex,
+			StateSize:         config.StateSize,
+			ImportPipeline:    config.ImportPipeline,
+		}
 	)
 	eth.blockchain, err = core.NewBlockChain(chainDb, cacheConfig, eth.chainConfig, eth.engine, vmConfig, eth.shouldPreserve)
 	if err != nil {
@@ -198,16 +272,161 @@ func New(ctx *node.ServiceContext, config *Config) (*Ethereum, error) {
 		config.TxPool.Journal = ctx.ResolvePath(config.TxPool.Journal)
 	}
 	eth.txPool = core.NewTxPool(config.TxPool, eth.chainConfig, eth.blockchain)
+	if ctx.AccountManager != nil {
+		eth.txPool.SetTxPolicy(ctx.AccountManager) // Quorum: unlock policies of the accounts
+	}
 
 	if eth.protocolManager, err = NewProtocolManager(eth.chainConfig, config.SyncMode, config.NetworkId, eth.eventMux, eth.txPool, eth.engine, eth.blockchain, chainDb, config.RaftMode); err != nil {
 		return nil, err
 	}
+	eth.protocolManager.latencyAware = config.LatencyAwarePropagation
+	if eth.protocolManager.compression, err = compressionCodec(config.Compression); err != nil {
+		return nil, err
+	}
+	eth.protocolManager.compressionThreshold = config.Compression.Threshold
+	if config.Gossip.Enabled {
+		if eth.protocolManager.gossip, err = newGossipRouter(config.Gossip, eth.txPool, eth.protocolManager.peers); err != nil {
+			return nil, err
+		}
+	}
+	if config.PrivateGossip.enabled() {
+		// Only Istanbul knows its validators, any Raft node may become the minter
+		var validators func() []common.Address
+		type validatorSet interface {
+			CurrentValidators() []common.Address
+		}
+		if vs, ok := eth.engine.(validatorSet); ok && !config.RaftMode {
+			validators = vs.CurrentValidators
+		}
+		role := func() string { return eth.NodeMode().Role }
+		if eth.protocolManager.privateGossip, err = newPrivateGossip(config.PrivateGossip, role, validators); err != nil {
+			return nil, err
+		}
+		if eth.protocolManager.gossip != nil {
+			eth.protocolManager.gossip.private = eth.protocolManager.privateGossip
+		}
+	}
+	eth.txDiag = newTxDiagnostics(eth.txPool)
+	eth.txCancel = newTxCancellations(eth.txPool)
+	eth.chainConfigChk = newChainConfigChecker(eth.chainConfig)
+	consensus, consensusParams := consensusSetup(eth.chainConfig, config)
+	attestor, err := newNodeAttestor(ctx.NodeSigner(), eth.chainConfig, consensus, consensusParams)
+	if err != nil {
+		return nil, err
+	}
+	eth.chainConfigChk.setAttestor(attestor)
+	if config.GasAccounting {
+		eth.gasAccountant = newGasAccountant(eth.chainConfig, eth.blockchain, chainDb, config.GasAccountingExportDir)
+	}
+	eth.operatorAuth = ctx.OperatorAuthenticator()
+	if eth.abiRegistry, err = abiregistry.New(chainDb); err != nil {
+		return nil, err
+	}
+	eth.health = newHealthWatchdog(config.Health, eth.blockchain, eth.txPool, eth.engine, config.RaftMode)
+	if chainConfig.Istanbul != nil && config.IstanbulCheckpointSink != "" {
+		sink, err := newCheckpointSink(config.IstanbulCheckpointSink)
+		if err != nil {
+			return nil, err
+		}
+		eth.checkpoints = newCheckpointExporter(eth.blockchain, config.Istanbul.Epoch, ctx.NodeSigner(), sink)
+	}
+	if len(config.Webhooks) > 0 {
+		if eth.webhooks, err = newWebhookDispatcher(config.Webhooks, eth.blockchain, eth.health, chainDb); err != nil {
+			return nil, err
+		}
+	}
+	if config.IntegritySamples > 0 {
+		eth.integrity = newIntegrityVerifier(eth.blockchain, eth.engine, chainDb, config.NoPruning, config.IntegritySamples)
+	}
+	if config.DryRunConfig != nil {
+		if eth.dryRun, err = newConfigDryRun(eth.blockchain, config.DryRunConfig, config, chainDb); err != nil {
+			return nil, err
+		}
+	}
+	if config.SQLExport != "" {
+		eth.sqlExport = &sqlExportStream{
+			chain:      eth.blockchain,
+			path:       config.SQLExport,
+			private:    config.SQLExportPrivate,
+			checkpoint: ctx.ResolvePath("sqlexport.checkpoint"),
+		}
+	}
+	if config.PTMOffloadStore != "" {
+		store, err := private.NewPayloadStore(config.PTMOffloadStore)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open the private payload store: %v", err)
+		}
+		private.OffloadPayloads(store, config.PTMOffloadThreshold)
+	}
+	if config.PTMCacheSize > 0 {
+		cacheConfig := private.ReceiveCacheConfig{
+			Dir:     ctx.ResolvePath("ptmcache"),
+			MaxSize: int64(config.PTMCacheSize) * 1024 * 1024,
+			TTL:     config.PTMCacheTTL,
+		}
+		if config.PTMCacheEncrypt {
+			if ctx.NodeKey() == nil {
+				return nil, errPTMCacheNoKey
+			}
+			cacheConfig.Key = crypto.Keccak256([]byte("ptmcache"), crypto.FromECDSA(ctx.NodeKey()))
+		}
+		cache, err := private.NewReceiveCache(cacheConfig)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open the private payload cache: %v", err)
+		}
+		private.CacheReceives(cache)
+	}
+	if config.PTMPushAddr != "" {
+		eth.ptmPush, err = private.NewPushEndpoint(private.PushConfig{
+			Addr:       config.PTMPushAddr,
+			SecretFile: config.PTMPushSecret,
+			SeqFile:    ctx.ResolvePath("ptmpush.seq"),
+		}, private.P)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create the private transaction manager push endpoint: %v", err)
+		}
+	}
+	if config.HistoryRetention > 0 {
+		if config.NoPruning {
+			return nil, errors.New("history retention requires --gcmode=full")
+		}
+		if config.HistoryRetention < minHistoryRetention {
+			return nil, fmt.Errorf("history retention %d below the minimum of %d blocks", config.HistoryRetention, minHistoryRetention)
+		}
+		eth.historyPruner = newHistoryPruner(eth.blockchain, chainDb, config.HistoryRetention, config.HistoryProtected)
+	}
+	if config.StandbyRole != "" {
+		if eth.standby, err = eth.newStandbyPair(ctx, config); err != nil {
+			return nil, err
+		}
+	}
 
 	eth.miner = miner.New(eth, eth.chainConfig, eth.EventMux(), eth.engine, config.MinerRecommit, config.MinerGasFloor, config.MinerGasCeil, eth.isLocalBlock)
 	eth.miner.SetExtra(makeExtraData(config.MinerExtraData, eth.chainConfig.IsQuorum))
+	if config.MinerBuilder != "" {
+		builder, err := miner.NewRPCBlockBuilder(config.MinerBuilder, func(number *big.Int) types.Signer {
+			return types.MakeSigner(eth.chainConfig, number)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to block builder %s: %v", config.MinerBuilder, err)
+		}
+		eth.miner.SetBlockBuilder(builder, config.MinerBuilderBudget)
+	}
+
+	eth.pending = newPendingView(eth.blockchain, eth.txPool, func() common.Address {
+		eth.lock.RLock()
+		defer eth.lock.RUnlock()
+		return eth.etherbase
+	}, eth.CalcGasLimit, config.RaftMode)
 
-	hexNodeId := fmt.Sprintf("%x", crypto.FromECDSAPub(&ctx.NodeKey().PublicKey)[1:]) // Quorum
-	eth.APIBackend = &EthAPIBackend{eth, nil, hexNodeId}
+	hexNodeId := fmt.Sprintf("%x", crypto.FromECDSAPub(ctx.NodeSigner().PublicKey())[1:]) // Quorum
+	eth.APIBackend = &EthAPIBackend{eth, nil, nil, nil, hexNodeId, FinalityConfirmations(config, chainConfig), ethapi.AddrLocker{}}
+	if config.RPCCacheSize > 0 {
+		eth.APIBackend.cache = ethapi.NewResponseCache(config.RPCCacheSize*1024*1024, eth.APIBackend.finality)
+	}
+	if config.RPCCallWorkers > 0 {
+		eth.APIBackend.calls = ethapi.NewCallPool(config.RPCCallWorkers, config.RPCCallCPUQuota)
+	}
 	gpoParams := config.GPO
 	if gpoParams.Default == nil {
 		gpoParams.Default = config.MinerGasPrice
@@ -246,6 +465,37 @@ func CreateDB(ctx *node.ServiceContext, config *Config, name string) (ethdb.Data
 	return db, nil
 }
 
+// proposalValidatorPlugin returns the validator vetting the blocks proposed to
+// Istanbul with the proposal validator plugin, nil if not configured.
+func proposalValidatorPlugin(ctx *node.ServiceContext, timeout time.Duration) istanbul.ProposalValidator {
+	var pm *plugin.PluginManager
+	if err := ctx.Service(&pm); err != nil {
+		return nil
+	}
+	template := new(plugin.ProposalValidatorPluginTemplate)
+	if err := pm.GetPluginTemplate(plugin.ProposalValidatorPluginInterfaceName, template); err != nil {
+		return nil
+	}
+	instance, err := template.Get()
+	if err != nil {
+		log.Error("Proposal validator plugin not ready, committing to any valid block", "err", err)
+		return nil
+	}
+	log.Info("Vetting the proposed blocks with plugin", "timeout", timeout)
+	return instance.ValidateProposal
+}
+
+// istanbulConfig returns the Istanbul engine configuration with the settings of
+// the chain configuration.
+func istanbulConfig(config istanbul.Config, chainConfig *params.IstanbulConfig) istanbul.Config {
+	if chainConfig.Epoch != 0 {
+		config.Epoch = chainConfig.Epoch
+	}
+	config.ProposerPolicy = istanbul.ProposerPolicy(chainConfig.ProposerPolicy)
+	config.Ceil2Nby3Block = chainConfig.Ceil2Nby3Block
+	return config
+}
+
 // CreateConsensusEngine creates the required type of consensus engine instance for an Ethereum service
 func CreateConsensusEngine(ctx *node.ServiceContext, chainConfig *params.ChainConfig, config *Config, notify []string, noverify bool, db ethdb.Database) consensus.Engine {
 	// If proof-of-authority is requested, set it up
@@ -254,13 +504,33 @@ func CreateConsensusEngine(ctx *node.ServiceContext, chainConfig *params.ChainCo
 	}
 	// If Istanbul is requested, set it up
 	if chainConfig.Istanbul != nil {
-		if chainConfig.Istanbul.Epoch != 0 {
-			config.Istanbul.Epoch = chainConfig.Istanbul.Epoch
-		}
-		config.Istanbul.ProposerPolicy = istanbul.ProposerPolicy(chainConfig.Istanbul.ProposerPolicy)
-		config.Istanbul.Ceil2Nby3Block = chainConfig.Istanbul.Ceil2Nby3Block
+		config.Istanbul = istanbulConfig(config.Istanbul, chainConfig.Istanbul)
 
-		return istanbulBackend.New(&config.Istanbul, ctx.NodeKey(), db)
+		engine := istanbulBackend.New(&config.Istanbul, ctx.NodeKey(), db)
+		// Administrative calls such as istanbul_propose may require operator signatures
+		type operatorAuthorised interface {
+			SetOperatorAuthenticator(auth *adminauth.Authenticator)
+		}
+		if oa, ok := engine.(operatorAuthorised); ok {
+			oa.SetOperatorAuthenticator(ctx.OperatorAuthenticator())
+		}
+		// The messages and seals are signed with the node key, possibly in a KMS
+		type nodeSigned interface {
+			SetNodeSigner(signer kms.Signer)
+		}
+		if ns, ok := engine.(nodeSigned); ok {
+			ns.SetNodeSigner(ctx.NodeSigner())
+		}
+		// The blocks proposed may be vetted by a plugin before committing to them
+		type proposalValidated interface {
+			SetProposalValidator(validator istanbul.ProposalValidator)
+		}
+		if pv, ok := engine.(proposalValidated); ok {
+			if validator := proposalValidatorPlugin(ctx, config.Istanbul.ProposalValidationTimeout); validator != nil {
+				pv.SetProposalValidator(validator)
+			}
+		}
+		return engine
 	}
 
 	// Otherwise assume proof-of-work
@@ -291,6 +561,9 @@ func (s *Ethereum) APIs() []rpc.API {
 	// Append any APIs exposed explicitly by the consensus engine
 	apis = append(apis, s.engine.APIs(s.BlockChain())...)
 
+	// Append the fault injection API of the chaos testing builds
+	apis = append(apis, faultinject.APIs()...)
+
 	// Append all the local APIs and return
 	apis = append(apis, []rpc.API{
 		{
@@ -338,6 +611,73 @@ func (s *Ethereum) APIs() []rpc.API {
 			Public:    true,
 		},
 	}...)
+
+	// Quorum
+	if s.gasAccountant != nil {
+		apis = append(apis, rpc.API{
+			Namespace: "accounting",
+			Version:   "1.0",
+			Service:   NewPrivateGasAccountingAPI(s.gasAccountant),
+		})
+	}
+	if s.webhooks != nil {
+		apis = append(apis, rpc.API{
+			Namespace: "admin",
+			Version:   "1.0",
+			Service:   NewPrivateWebhookAPI(s.webhooks),
+		})
+	}
+	if s.integrity != nil {
+		apis = append(apis, rpc.API{
+			Namespace: "debug",
+			Version:   "1.0",
+			Service:   NewPrivateIntegrityAPI(s.integrity),
+		})
+	}
+	if s.dryRun != nil {
+		apis = append(apis, rpc.API{
+			Namespace: "debug",
+			Version:   "1.0",
+			Service:   NewPrivateConfigDryRunAPI(s.dryRun),
+		})
+	}
+	if s.standby != nil {
+		apis = append(apis, rpc.API{
+			Namespace: "standby",
+			Version:   "1.0",
+			Service:   NewPublicStandbyAPI(s.standby, s.operatorAuth),
+			Public:    true,
+		})
+	}
+	apis = append(apis, rpc.API{
+		Namespace: "quorum",
+		Version:   "1.0",
+		Service:   NewPublicNetworkHealthAPI(s.health),
+		Public:    true,
+	}, rpc.API{
+		Namespace: "quorum",
+		Version:   "1.0",
+		Service:   filters.NewPublicLogQueryAPI(s.APIBackend),
+		Public:    true,
+	}, rpc.API{
+		Namespace: "quorum",
+		Version:   "1.0",
+		Service:   abiregistry.NewPublicABIRegistryAPI(s.abiRegistry),
+		Public:    true,
+	}, rpc.API{
+		Namespace: "admin",
+		Version:   "1.0",
+		Service:   abiregistry.NewPrivateABIRegistryAPI(s.abiRegistry),
+	}, rpc.API{
+		Namespace: "quorumPrivacy",
+		Version:   "1.0",
+		Service:   NewPublicPurgeAPI(s),
+		Public:    true,
+	}, rpc.API{
+		Namespace: "quorumPrivacy",
+		Version:   "1.0",
+		Service:   NewPrivatePurgeAPI(s),
+	})
 	return apis
 }
 
@@ -439,6 +779,10 @@ func (s *Ethereum) SetEtherbase(etherbase common.Address) {
 // is already running, this method adjust the number of threads allowed to use
 // and updates the minimum price required by the transaction pool.
 func (s *Ethereum) StartMining(threads int) error {
+	// Quorum: a standby signs nothing until promoted
+	if s.standby != nil && s.standby.passive() {
+		return errStandbyPassive
+	}
 	// Update the thread count within the consensus engine
 	type threaded interface {
 		SetThreads(threads int)
@@ -509,13 +853,76 @@ func (s *Ethereum) EthVersion() int                    { return int(s.protocolMa
 func (s *Ethereum) NetVersion() uint64                 { return s.networkID }
 func (s *Ethereum) Downloader() *downloader.Downloader { return s.protocolManager.downloader }
 
+// ReportLeaderChange records the Raft leader, for the consensus health watchdog
+// to detect leader churn.
+func (s *Ethereum) ReportLeaderChange(leader uint64) { s.health.reportLeader(leader) }
+
+// ReportRaftLag records the largest number of entries a Raft follower is behind
+// the leader, for the consensus health watchdog to raise lag alerts.
+func (s *Ethereum) ReportRaftLag(lag uint64) { s.health.reportRaftLag(lag) }
+
 // Protocols implements node.Service, returning all the currently configured
 // network protocols to start.
 func (s *Ethereum) Protocols() []p2p.Protocol {
+	protos := append(append([]p2p.Protocol{}, s.protocolManager.SubProtocols...), s.txDiag.Protocol(), s.txCancel.Protocol())
+	protos = append(protos, s.chainConfigChk.Protocols()...)
+	if s.protocolManager.gossip != nil {
+		protos = append(protos, s.protocolManager.gossip.Protocol())
+	}
+	if s.protocolManager.compression != 0 {
+		protos = append(protos, compressionProtocol())
+	}
 	if s.lesServer == nil {
-		return s.protocolManager.SubProtocols
+		return protos
+	}
+	return append(protos, s.lesServer.Protocols()...)
+}
+
+// Quorum
+//
+// QuorumCapabilities implements node.QuorumCapabilitiesProvider, describing the
+// consensus, chain configuration and privacy setup advertised to peers.
+func (s *Ethereum) QuorumCapabilities() *p2p.QuorumCapabilities {
+	caps := &p2p.QuorumCapabilities{
+		Consensus: "ethash",
+		Privacy:   private.IsEnabled(),
+	}
+	switch {
+	case s.config.RaftMode:
+		caps.Consensus = "raft"
+	case s.chainConfig.Istanbul != nil:
+		caps.Consensus = "istanbul"
+	case s.chainConfig.Clique != nil:
+		caps.Consensus = "clique"
+	}
+	if _, hash, err := s.chainConfig.EncodeJSON(); err == nil {
+		caps.ChainConfigHash = hash
+	}
+	return caps
+}
+
+// Quorum
+//
+// Features implements node.FeatureProvider, reporting the optional privacy,
+// consensus and operational features enabled.
+func (s *Ethereum) Features() map[string]bool {
+	return map[string]bool{
+		"privacy":                 private.IsEnabled(),
+		"raft":                    s.config.RaftMode,
+		"istanbul":                s.chainConfig.Istanbul != nil,
+		"istanbulShadow":          s.chainConfig.Istanbul != nil && s.config.Istanbul.Shadow,
+		"istanbulCheckpoints":     s.checkpoints != nil,
+		"gasAccounting":           s.gasAccountant != nil,
+		"latencyAwarePropagation": s.config.LatencyAwarePropagation,
+		"gossip":                  s.protocolManager.gossip != nil,
+		"privateGossipPolicy":     s.protocolManager.privateGossip != nil,
+		"compression":             s.protocolManager.compression != 0,
+		"blockBuilder":            s.config.MinerBuilder != "",
+		"webhooks":                s.webhooks != nil,
+		"integrityVerifier":       s.integrity != nil,
+		"configDryRun":            s.dryRun != nil,
+		"sqlExport":               s.sqlExport != nil,
 	}
-	return append(s.protocolManager.SubProtocols, s.lesServer.Protocols()...)
 }
 
 // Start implements node.Service, starting all internal goroutines needed by the
@@ -526,6 +933,7 @@ func (s *Ethereum) Start(srvr *p2p.Server) error {
 
 	// Start the RPC service
 	s.netRPCService = ethapi.NewPublicNetAPI(srvr, s.NetVersion())
+	s.p2pServer = srvr
 
 	// Figure out a max peers count based on the server limits
 	maxPeers := srvr.MaxPeers
@@ -540,6 +948,42 @@ func (s *Ethereum) Start(srvr *p2p.Server) error {
 	if s.lesServer != nil {
 		s.lesServer.Start(srvr)
 	}
+	// Quorum: periodically check that peers run the same chain configuration
+	go s.chainConfigChk.loop(s.shutdownChan)
+	if s.gasAccountant != nil {
+		go s.gasAccountant.loop(s.shutdownChan)
+	}
+	go s.health.loop(s.shutdownChan)
+	if s.protocolManager.gossip != nil {
+		go s.protocolManager.gossip.loop(s.shutdownChan)
+	}
+	if s.checkpoints != nil {
+		go s.checkpoints.loop(s.shutdownChan)
+	}
+	if s.webhooks != nil {
+		go s.webhooks.loop(s.shutdownChan)
+	}
+	if s.integrity != nil {
+		go s.integrity.loop(s.shutdownChan)
+	}
+	if s.dryRun != nil {
+		go s.dryRun.loop(s.shutdownChan)
+	}
+	if s.sqlExport != nil {
+		go s.sqlExport.loop(s.shutdownChan)
+	}
+	go s.pending.loop(s.shutdownChan)
+	if s.historyPruner != nil {
+		go s.historyPruner.loop(s.shutdownChan)
+	}
+	if s.standby != nil && s.standby.primary != nil {
+		go s.standby.primary.loop(s.shutdownChan)
+	}
+	if s.ptmPush != nil {
+		if err := s.ptmPush.Start(); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -556,6 +1000,13 @@ func (s *Ethereum) Stop() error {
 	s.txPool.Stop()
 	s.miner.Stop()
 	s.eventMux.Stop()
+	s.APIBackend.calls.Stop()
+	if s.ptmPush != nil {
+		s.ptmPush.Stop()
+	}
+	if private.P != nil {
+		private.P.Close()
+	}
 
 	s.chainDb.Close()
 	close(s.shutdownChan)
@@ -0,0 +1,441 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// The chaincfg protocol lets nodes exchange their chain configurations, to
+// catch nodes which missed a transition update (e.g. a new fork block) before
+// they fork off the network. Version 2 adds the attestation of the software
+// version and consensus parameters of the nodes.
+const (
+	chainConfigProtocolName    = "chaincfg"
+	chainConfigProtocolVersion = 2
+
+	GetChainConfigMsg = 0x00
+	ChainConfigMsg    = 0x01
+	GetNodeVersionMsg = 0x02
+	NodeVersionMsg    = 0x03
+)
+
+// chainConfigProtocolLengths are the number of messages of each supported
+// version of the chaincfg protocol.
+var chainConfigProtocolLengths = map[uint]uint64{1: 2, 2: 4}
+
+const (
+	chainConfigTimeout       = 5 * time.Second // Maximum time to wait for the configuration of a peer
+	chainConfigCheckInterval = 5 * time.Minute // Time between two consistency checks of all peers
+)
+
+var (
+	errChainConfigTimeout = errors.New("chain config request timed out")
+	errUnknownConfigPeer  = errors.New("unknown peer or peer not speaking chaincfg")
+	errAmbiguousPeer      = errors.New("ambiguous peer id prefix")
+)
+
+// chainConfigRequest is the payload of GetChainConfigMsg.
+type chainConfigRequest struct {
+	ID uint64
+}
+
+// chainConfigResponse is the payload of ChainConfigMsg, carrying the JSON
+// encoded chain configuration.
+type chainConfigResponse struct {
+	ID     uint64
+	Config []byte
+}
+
+// ChainConfigDifference is a chain configuration field with a different value
+// on the local node and a peer. Missing fields are reported as null.
+type ChainConfigDifference struct {
+	Field  string          `json:"field"`
+	Local  json.RawMessage `json:"local"`
+	Remote json.RawMessage `json:"remote"`
+}
+
+// ChainConfigComparison reports whether the chain configuration of a peer is
+// the same as the local one.
+type ChainConfigComparison struct {
+	Peer        string                  `json:"peer"`
+	LocalHash   common.Hash             `json:"localHash"`
+	RemoteHash  common.Hash             `json:"remoteHash"`
+	Match       bool                    `json:"match"`
+	Differences []ChainConfigDifference `json:"differences,omitempty"`
+}
+
+// chainConfigPeer is a connected peer speaking the chaincfg protocol.
+type chainConfigPeer struct {
+	id      enode.ID
+	version uint
+	rw      p2p.MsgReadWriter
+
+	lock     sync.Mutex
+	pending  map[uint64]chan []byte               // Outstanding config requests by id
+	attested map[uint64]chan *nodeVersionResponse // Outstanding version requests by id
+}
+
+// chainConfigChecker serves the local chain configuration and compares it with
+// the ones of the peers.
+type chainConfigChecker struct {
+	config *params.ChainConfig
+	attest *nodeAttestor // Local version attested to the peers, nil if not set up
+
+	lock       sync.RWMutex
+	peers      map[enode.ID]*chainConfigPeer
+	mismatches map[enode.ID]common.Hash // Peers currently known to run another configuration
+	nextID     uint64
+}
+
+func newChainConfigChecker(config *params.ChainConfig) *chainConfigChecker {
+	return &chainConfigChecker{
+		config:     config,
+		peers:      make(map[enode.ID]*chainConfigPeer),
+		mismatches: make(map[enode.ID]common.Hash),
+	}
+}
+
+// Protocols returns the supported versions of the chaincfg devp2p sub-protocol,
+// the latest first.
+func (c *chainConfigChecker) Protocols() []p2p.Protocol {
+	var protos []p2p.Protocol
+	for version := uint(chainConfigProtocolVersion); version > 0; version-- {
+		version := version
+		protos = append(protos, p2p.Protocol{
+			Name:    chainConfigProtocolName,
+			Version: version,
+			Length:  chainConfigProtocolLengths[version],
+			Run: func(p *p2p.Peer, rw p2p.MsgReadWriter) error {
+				return c.handle(p.ID(), version, rw)
+			},
+		})
+	}
+	return protos
+}
+
+// handle registers a chaincfg peer, checks its configuration and serves its
+// messages until disconnection.
+func (c *chainConfigChecker) handle(id enode.ID, version uint, rw p2p.MsgReadWriter) error {
+	peer := &chainConfigPeer{
+		id:       id,
+		version:  version,
+		rw:       rw,
+		pending:  make(map[uint64]chan []byte),
+		attested: make(map[uint64]chan *nodeVersionResponse),
+	}
+	c.lock.Lock()
+	c.peers[id] = peer
+	c.lock.Unlock()
+
+	defer func() {
+		c.lock.Lock()
+		delete(c.peers, id)
+		delete(c.mismatches, id)
+		chainConfigMismatchGauge.Update(int64(len(c.mismatches)))
+		c.lock.Unlock()
+	}()
+	go c.check(context.Background(), peer)
+
+	for {
+		msg, err := rw.ReadMsg()
+		if err != nil {
+			return err
+		}
+		if msg.Size > ProtocolMaxMsgSize {
+			msg.Discard()
+			return errResp(ErrMsgTooLarge, "%v > %v", msg.Size, ProtocolMaxMsgSize)
+		}
+		switch msg.Code {
+		case GetChainConfigMsg:
+			var req chainConfigRequest
+			if err := msg.Decode(&req); err != nil {
+				return errResp(ErrDecode, "msg %v: %v", msg, err)
+			}
+			blob, _, err := c.config.EncodeJSON()
+			if err != nil {
+				return err
+			}
+			if err := p2p.Send(rw, ChainConfigMsg, &chainConfigResponse{ID: req.ID, Config: blob}); err != nil {
+				return err
+			}
+
+		case ChainConfigMsg:
+			var res chainConfigResponse
+			if err := msg.Decode(&res); err != nil {
+				return errResp(ErrDecode, "msg %v: %v", msg, err)
+			}
+			if !json.Valid(res.Config) {
+				return errResp(ErrDecode, "invalid chain config")
+			}
+			peer.lock.Lock()
+			ch := peer.pending[res.ID]
+			delete(peer.pending, res.ID)
+			peer.lock.Unlock()
+
+			if ch != nil {
+				ch <- res.Config
+			}
+
+		case GetNodeVersionMsg:
+			if version < 2 {
+				return errResp(ErrInvalidMsgCode, "%v", msg.Code)
+			}
+			var req nodeVersionRequest
+			if err := msg.Decode(&req); err != nil {
+				return errResp(ErrDecode, "msg %v: %v", msg, err)
+			}
+			res, err := c.attestVersion(req)
+			if err != nil {
+				return err
+			}
+			if err := p2p.Send(rw, NodeVersionMsg, res); err != nil {
+				return err
+			}
+
+		case NodeVersionMsg:
+			if version < 2 {
+				return errResp(ErrInvalidMsgCode, "%v", msg.Code)
+			}
+			var res nodeVersionResponse
+			if err := msg.Decode(&res); err != nil {
+				return errResp(ErrDecode, "msg %v: %v", msg, err)
+			}
+			peer.lock.Lock()
+			ch := peer.attested[res.ID]
+			delete(peer.attested, res.ID)
+			peer.lock.Unlock()
+
+			if ch != nil {
+				ch <- &res
+			}
+
+		default:
+			return errResp(ErrInvalidMsgCode, "%v", msg.Code)
+		}
+	}
+}
+
+// requestConfig asks a peer for its chain configuration and waits for the reply.
+func (c *chainConfigChecker) requestConfig(ctx context.Context, peer *chainConfigPeer) ([]byte, error) {
+	c.lock.Lock()
+	c.nextID++
+	id := c.nextID
+	c.lock.Unlock()
+
+	ch := make(chan []byte, 1)
+	peer.lock.Lock()
+	peer.pending[id] = ch
+	peer.lock.Unlock()
+
+	defer func() {
+		peer.lock.Lock()
+		delete(peer.pending, id)
+		peer.lock.Unlock()
+	}()
+	if err := p2p.Send(peer.rw, GetChainConfigMsg, &chainConfigRequest{ID: id}); err != nil {
+		return nil, err
+	}
+	timeout := time.NewTimer(chainConfigTimeout)
+	defer timeout.Stop()
+
+	select {
+	case config := <-ch:
+		return config, nil
+	case <-timeout.C:
+		return nil, errChainConfigTimeout
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// findPeer looks up a peer by its node id, or by an unambiguous prefix of it.
+func (c *chainConfigChecker) findPeer(id string) (*chainConfigPeer, error) {
+	id = strings.TrimPrefix(strings.ToLower(id), "0x")
+	if strings.HasPrefix(id, "enode://") {
+		node, err := enode.ParseV4(id)
+		if err != nil {
+			return nil, err
+		}
+		id = node.ID().String()
+	}
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	var found *chainConfigPeer
+	for pid, peer := range c.peers {
+		if id == "" || !strings.HasPrefix(pid.String(), id) {
+			continue
+		}
+		if found != nil {
+			return nil, errAmbiguousPeer
+		}
+		found = peer
+	}
+	if found == nil {
+		return nil, errUnknownConfigPeer
+	}
+	return found, nil
+}
+
+// Compare fetches the chain configuration of a peer and reports the fields
+// which differ from the local configuration.
+func (c *chainConfigChecker) Compare(ctx context.Context, id string) (*ChainConfigComparison, error) {
+	peer, err := c.findPeer(id)
+	if err != nil {
+		return nil, err
+	}
+	return c.check(ctx, peer)
+}
+
+// check compares the configuration of a peer with the local one, tracking and
+// reporting peers whose configuration changes from or to a mismatch.
+func (c *chainConfigChecker) check(ctx context.Context, peer *chainConfigPeer) (*ChainConfigComparison, error) {
+	local, localHash, err := c.config.EncodeJSON()
+	if err != nil {
+		return nil, err
+	}
+	remote, err := c.requestConfig(ctx, peer)
+	if err != nil {
+		log.Debug("Failed to fetch peer chain config", "peer", peer.id, "err", err)
+		return nil, err
+	}
+	report := &ChainConfigComparison{
+		Peer:       peer.id.String(),
+		LocalHash:  localHash,
+		RemoteHash: crypto.Keccak256Hash(remote),
+	}
+	report.Match = report.LocalHash == report.RemoteHash
+	if !report.Match {
+		if report.Differences, err = diffChainConfigs(local, remote); err != nil {
+			return nil, err
+		}
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if _, ok := c.peers[peer.id]; !ok {
+		return report, nil // Peer dropped in the meantime
+	}
+	prev, known := c.mismatches[peer.id]
+	switch {
+	case !report.Match && (!known || prev != report.RemoteHash):
+		fields := make([]string, len(report.Differences))
+		for i, diff := range report.Differences {
+			fields[i] = diff.Field
+		}
+		log.Warn("Peer chain config mismatch", "peer", peer.id, "local", localHash, "remote", report.RemoteHash, "fields", strings.Join(fields, ","))
+		chainConfigMismatchMeter.Mark(1)
+		c.mismatches[peer.id] = report.RemoteHash
+
+	case report.Match && known:
+		log.Info("Peer chain config mismatch resolved", "peer", peer.id)
+		delete(c.mismatches, peer.id)
+	}
+	chainConfigMismatchGauge.Update(int64(len(c.mismatches)))
+	return report, nil
+}
+
+// loop periodically checks the configurations of all connected peers, until
+// quit is closed.
+func (c *chainConfigChecker) loop(quit chan bool) {
+	ticker := time.NewTicker(chainConfigCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			c.lock.RLock()
+			peers := make([]*chainConfigPeer, 0, len(c.peers))
+			for _, peer := range c.peers {
+				peers = append(peers, peer)
+			}
+			c.lock.RUnlock()
+
+			for _, peer := range peers {
+				go c.check(context.Background(), peer)
+			}
+		case <-quit:
+			return
+		}
+	}
+}
+
+// diffChainConfigs returns the fields of two JSON encoded chain configurations
+// that differ, nested fields being named by their dotted path.
+func diffChainConfigs(local, remote []byte) ([]ChainConfigDifference, error) {
+	var diffs []ChainConfigDifference
+	if err := diffJSONObjects("", local, remote, &diffs); err != nil {
+		return nil, err
+	}
+	sort.Slice(diffs, func(i, j int) bool { return diffs[i].Field < diffs[j].Field })
+	return diffs, nil
+}
+
+func diffJSONObjects(prefix string, local, remote json.RawMessage, diffs *[]ChainConfigDifference) error {
+	var lfields, rfields map[string]json.RawMessage
+	if err := json.Unmarshal(local, &lfields); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(remote, &rfields); err != nil {
+		return fmt.Errorf("invalid remote chain config: %v", err)
+	}
+	null := json.RawMessage("null")
+	for name := range rfields {
+		if _, ok := lfields[name]; !ok {
+			lfields[name] = null
+		}
+	}
+	for name, lval := range lfields {
+		rval, ok := rfields[name]
+		if !ok {
+			rval = null
+		}
+		if bytes.Equal(lval, rval) {
+			continue
+		}
+		// Descend into objects present on both sides
+		if isJSONObject(lval) && isJSONObject(rval) {
+			if err := diffJSONObjects(prefix+name+".", lval, rval, diffs); err != nil {
+				return err
+			}
+			continue
+		}
+		*diffs = append(*diffs, ChainConfigDifference{Field: prefix + name, Local: lval, Remote: rval})
+	}
+	return nil
+}
+
+func isJSONObject(val json.RawMessage) bool {
+	val = bytes.TrimSpace(val)
+	return len(val) > 0 && val[0] == '{'
+}
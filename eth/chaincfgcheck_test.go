@@ -0,0 +1,137 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// queuedPipeRW is a message pipe endpoint queueing its writes like a network
+// connection, so that both ends may reply to each other at once.
+type queuedPipeRW struct {
+	*p2p.MsgPipeRW
+	queue chan p2p.Msg
+}
+
+// newQueuedPipe creates a message pipe with queued writes.
+func newQueuedPipe() (*queuedPipeRW, *queuedPipeRW) {
+	rwA, rwB := p2p.MsgPipe()
+	return newQueuedPipeRW(rwA), newQueuedPipeRW(rwB)
+}
+
+func newQueuedPipeRW(rw *p2p.MsgPipeRW) *queuedPipeRW {
+	q := &queuedPipeRW{MsgPipeRW: rw, queue: make(chan p2p.Msg, 64)}
+	go func() {
+		for msg := range q.queue {
+			if rw.WriteMsg(msg) != nil {
+				return
+			}
+		}
+	}()
+	return q
+}
+
+func (q *queuedPipeRW) WriteMsg(msg p2p.Msg) error {
+	payload, err := ioutil.ReadAll(msg.Payload)
+	if err != nil {
+		return err
+	}
+	msg.Payload = bytes.NewReader(payload)
+	q.queue <- msg
+	return nil
+}
+
+// Tests that two nodes exchanging their chain configurations report the fields
+// which differ, and track the peer as mismatched.
+func TestCompareChainConfig(t *testing.T) {
+	configA := *params.AllCliqueProtocolChanges
+	configB := *params.AllCliqueProtocolChanges
+	configB.ConstantinopleBlock = big.NewInt(100)
+	configB.Clique = &params.CliqueConfig{Period: 5, Epoch: params.AllCliqueProtocolChanges.Clique.Epoch}
+
+	checkA := newChainConfigChecker(&configA)
+	checkB := newChainConfigChecker(&configB)
+
+	rwA, rwB := newQueuedPipe()
+	defer rwA.Close()
+	go checkA.handle(enode.ID{0x0b}, chainConfigProtocolVersion, rwA)
+	go checkB.handle(enode.ID{0x0a}, chainConfigProtocolVersion, rwB)
+
+	// Wait for both sides to register their peer
+	for {
+		checkA.lock.RLock()
+		n := len(checkA.peers)
+		checkA.lock.RUnlock()
+		if n == 1 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if _, err := checkA.Compare(context.Background(), "0c"); err != errUnknownConfigPeer {
+		t.Fatalf("unknown peer error mismatch: have %v, want %v", err, errUnknownConfigPeer)
+	}
+	report, err := checkA.Compare(context.Background(), "0x0b")
+	if err != nil {
+		t.Fatalf("failed to compare chain config: %v", err)
+	}
+	if report.Match {
+		t.Fatalf("different configurations reported as matching")
+	}
+	want := []ChainConfigDifference{
+		{Field: "clique.period", Local: []byte("0"), Remote: []byte("5")},
+		{Field: "constantinopleBlock", Local: []byte("0"), Remote: []byte("100")},
+	}
+	if len(report.Differences) != len(want) {
+		t.Fatalf("difference count mismatch: have %+v, want %+v", report.Differences, want)
+	}
+	for i, diff := range report.Differences {
+		if diff.Field != want[i].Field || string(diff.Local) != string(want[i].Local) || string(diff.Remote) != string(want[i].Remote) {
+			t.Errorf("difference %d mismatch: have %s %s/%s, want %s %s/%s", i, diff.Field, diff.Local, diff.Remote, want[i].Field, want[i].Local, want[i].Remote)
+		}
+	}
+	checkA.lock.RLock()
+	hash, ok := checkA.mismatches[enode.ID{0x0b}]
+	checkA.lock.RUnlock()
+	if !ok || hash != report.RemoteHash {
+		t.Errorf("mismatched peer not tracked: have %x, want %x", hash, report.RemoteHash)
+	}
+}
+
+func TestDiffChainConfigs(t *testing.T) {
+	diffs, err := diffChainConfigs([]byte(`{"a":1,"b":{"c":2},"d":3}`), []byte(`{"a":1,"b":{"c":3},"e":4}`))
+	if err != nil {
+		t.Fatalf("failed to diff configs: %v", err)
+	}
+	want := []string{"b.c 2/3", "d 3/null", "e null/4"}
+	if len(diffs) != len(want) {
+		t.Fatalf("difference count mismatch: have %d, want %d", len(diffs), len(want))
+	}
+	for i, diff := range diffs {
+		if have := diff.Field + " " + string(diff.Local) + "/" + string(diff.Remote); have != want[i] {
+			t.Errorf("difference %d mismatch: have %s, want %s", i, have, want[i])
+		}
+	}
+}
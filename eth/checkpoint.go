@@ -0,0 +1,315 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	istanbulBackend "github.com/ethereum/go-ethereum/consensus/istanbul/backend"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/kms"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// checkpointExportTimeout is the timeout of the exports of a checkpoint to the
+// remote sinks.
+const checkpointExportTimeout = 30 * time.Second
+
+// checkpointSink is a destination the Istanbul checkpoints are exported to.
+type checkpointSink interface {
+	// Export stores the JSON encoded checkpoint of the given block.
+	Export(number uint64, blob []byte) error
+}
+
+// newCheckpointSink creates the checkpoint sink of a URL:
+//
+//	file:///path/to/dir                     writes checkpoint-<number>.json files to the directory
+//	http(s)://host/path                     posts the checkpoints to a webhook
+//	s3://bucket/prefix[?region=&endpoint=]  puts the checkpoints as objects of an S3 bucket
+//
+// The S3 credentials are read from the AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY
+// and AWS_SESSION_TOKEN environment variables, the region from AWS_REGION if not
+// given in the URL.
+func newCheckpointSink(rawurl string) (checkpointSink, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, fmt.Errorf("invalid checkpoint sink %q: %v", rawurl, err)
+	}
+	switch u.Scheme {
+	case "file":
+		if err := os.MkdirAll(u.Path, 0700); err != nil {
+			return nil, err
+		}
+		return &fileCheckpointSink{dir: u.Path}, nil
+
+	case "http", "https":
+		return &webhookCheckpointSink{url: rawurl, client: &http.Client{Timeout: checkpointExportTimeout}}, nil
+
+	case "s3":
+		sink := &s3CheckpointSink{
+			bucket:    u.Host,
+			prefix:    strings.Trim(u.Path, "/"),
+			region:    u.Query().Get("region"),
+			endpoint:  strings.TrimSuffix(u.Query().Get("endpoint"), "/"),
+			accessKey: os.Getenv("AWS_ACCESS_KEY_ID"),
+			secretKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			token:     os.Getenv("AWS_SESSION_TOKEN"),
+			client:    &http.Client{Timeout: checkpointExportTimeout},
+		}
+		if sink.region == "" {
+			sink.region = os.Getenv("AWS_REGION")
+		}
+		if sink.bucket == "" || sink.region == "" {
+			return nil, fmt.Errorf("checkpoint sink %q misses the bucket or region", rawurl)
+		}
+		if sink.accessKey == "" || sink.secretKey == "" {
+			return nil, fmt.Errorf("checkpoint sink %q requires AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY", rawurl)
+		}
+		return sink, nil
+	}
+	return nil, fmt.Errorf("unsupported checkpoint sink %q", rawurl)
+}
+
+// checkpointName is the name of the file or object holding a checkpoint.
+func checkpointName(number uint64) string {
+	return fmt.Sprintf("checkpoint-%d.json", number)
+}
+
+// fileCheckpointSink writes the checkpoints to a local directory.
+type fileCheckpointSink struct {
+	dir string
+}
+
+func (s *fileCheckpointSink) Export(number uint64, blob []byte) error {
+	// Write atomically, for readers never to see partial checkpoints
+	path := filepath.Join(s.dir, checkpointName(number))
+	if err := ioutil.WriteFile(path+".tmp", blob, 0644); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// webhookCheckpointSink posts the checkpoints to a URL.
+type webhookCheckpointSink struct {
+	url    string
+	client *http.Client
+}
+
+func (s *webhookCheckpointSink) Export(number uint64, blob []byte) error {
+	resp, err := s.client.Post(s.url, "application/json", bytes.NewReader(blob))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+// s3CheckpointSink puts the checkpoints in an S3 bucket, or a compatible store
+// reached at a custom endpoint with path-style addressing.
+type s3CheckpointSink struct {
+	bucket, prefix   string
+	region, endpoint string
+
+	accessKey, secretKey, token string
+
+	client *http.Client
+}
+
+func (s *s3CheckpointSink) Export(number uint64, blob []byte) error {
+	key := path.Join(s.prefix, checkpointName(number))
+	target := fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", s.bucket, s.region, key)
+	if s.endpoint != "" {
+		target = fmt.Sprintf("%s/%s/%s", s.endpoint, s.bucket, key)
+	}
+	req, err := http.NewRequest("PUT", target, bytes.NewReader(blob))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.token != "" {
+		req.Header.Set("X-Amz-Security-Token", s.token)
+	}
+	s.sign(req, blob, time.Now().UTC())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("S3 returned %s", resp.Status)
+	}
+	return nil
+}
+
+// sign authenticates a request with AWS signature version 4, signing the host
+// and every header of the request.
+func (s *s3CheckpointSink) sign(req *http.Request, payload []byte, now time.Time) {
+	var (
+		amzDate     = now.Format("20060102T150405Z")
+		date        = now.Format("20060102")
+		scope       = date + "/" + s.region + "/s3/aws4_request"
+		payloadHash = sha256Hex(payload)
+	)
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+	canonicalRequest := strings.Join([]string{
+		req.Method, req.URL.EscapedPath(), req.URL.RawQuery, canonicalHeaders.String(), signedHeaders, payloadHash,
+	}, "\n")
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, sha256Hex([]byte(canonicalRequest))}, "\n")
+
+	key := []byte("AWS4" + s.secretKey)
+	for _, part := range []string{date, s.region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s", s.accessKey, scope, signedHeaders, signature))
+}
+
+func sha256Hex(data []byte) string {
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// checkpointExporter exports a signed checkpoint of each Istanbul epoch block,
+// for systems off the chain to anchor and verify its history independently.
+type checkpointExporter struct {
+	chain  *core.BlockChain
+	epoch  uint64
+	signer kms.Signer // Signer with the node key, signing the checkpoints
+	sink   checkpointSink
+	next   uint64 // Next epoch block to export
+}
+
+// newCheckpointExporter creates an exporter of the checkpoints of the epoch
+// blocks imported from now on.
+func newCheckpointExporter(chain *core.BlockChain, epoch uint64, signer kms.Signer, sink checkpointSink) *checkpointExporter {
+	head := chain.CurrentBlock().NumberU64()
+	return &checkpointExporter{
+		chain:  chain,
+		epoch:  epoch,
+		signer: signer,
+		sink:   sink,
+		next:   (head/epoch + 1) * epoch,
+	}
+}
+
+// loop exports the checkpoints of the epoch blocks as the chain reaches them.
+// The exports run on a worker, for a slow sink not to hold up the chain head
+// events, the worker being only handed the latest head.
+func (e *checkpointExporter) loop(quit chan bool) {
+	heads := make(chan core.ChainHeadEvent, 16)
+	sub := e.chain.SubscribeChainHeadEvent(heads)
+	defer sub.Unsubscribe()
+
+	pending := make(chan uint64, 1)
+	defer close(pending)
+	go e.worker(pending)
+
+	for {
+		select {
+		case ev := <-heads:
+			// Replace the head still waiting for the worker, if any
+			select {
+			case <-pending:
+			default:
+			}
+			pending <- ev.Block.NumberU64()
+		case <-sub.Err():
+			return
+		case <-quit:
+			return
+		}
+	}
+}
+
+// worker exports the checkpoints up to the heads it is handed, until the
+// channel is closed.
+func (e *checkpointExporter) worker(pending chan uint64) {
+	for head := range pending {
+		e.export(head)
+	}
+}
+
+// export exports the checkpoints of the epoch blocks up to the given head. A
+// failed export is retried at the next head.
+func (e *checkpointExporter) export(head uint64) {
+	for ; e.next <= head; e.next += e.epoch {
+		header := e.chain.GetHeaderByNumber(e.next)
+		if header == nil {
+			return
+		}
+		cp, err := istanbulBackend.NewCheckpoint(header)
+		if err != nil {
+			log.Error("Failed to create Istanbul checkpoint", "number", e.next, "err", err)
+			continue
+		}
+		if err := cp.Sign(e.signer); err != nil {
+			log.Error("Failed to sign Istanbul checkpoint", "number", e.next, "err", err)
+			return
+		}
+		blob, err := json.MarshalIndent(cp, "", "  ")
+		if err != nil {
+			log.Error("Failed to encode Istanbul checkpoint", "number", e.next, "err", err)
+			continue
+		}
+		if err := e.sink.Export(e.next, blob); err != nil {
+			log.Warn("Failed to export Istanbul checkpoint, retrying at next block", "number", e.next, "err", err)
+			return
+		}
+		log.Info("Exported Istanbul checkpoint", "number", e.next, "hash", cp.Hash)
+	}
+}
@@ -0,0 +1,202 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"bytes"
+	"compress/flate"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/golang/snappy"
+)
+
+const (
+	// compressionProtocolName is the name of the capability advertising the
+	// support of compressed block and transaction messages. The capability has
+	// no messages of its own, the compressed messages being sent over eth.
+	compressionProtocolName    = "ethz"
+	compressionProtocolVersion = 1
+)
+
+// Codecs of the compressed messages, the first byte of their payload.
+const (
+	compressionSnappy  byte = 1
+	compressionDeflate byte = 2
+)
+
+var errUnknownCodec = errors.New("unknown compression codec")
+
+var (
+	compressedRawMeter = metrics.NewRegisteredMeter("eth/compression/raw", nil)        // Size of the messages compressed
+	compressedOutMeter = metrics.NewRegisteredMeter("eth/compression/compressed", nil) // Size of the compressed messages sent
+	decompressedMeter  = metrics.NewRegisteredMeter("eth/compression/decompressed", nil)
+)
+
+// CompressionConfig are the settings of the compression of the block and
+// transaction messages between the peers supporting it.
+type CompressionConfig struct {
+	Codec     string // Codec of the sent messages, "snappy" or "deflate", empty to disable
+	Threshold int    // Size of the encoded messages from which they are compressed
+}
+
+// DefaultCompressionConfig contains the default compression settings.
+var DefaultCompressionConfig = CompressionConfig{
+	Threshold: 16 * 1024,
+}
+
+// compressionCodec returns the codec of a compression configuration, zero if
+// disabled.
+func compressionCodec(config CompressionConfig) (byte, error) {
+	switch config.Codec {
+	case "":
+		return 0, nil
+	case "snappy":
+		return compressionSnappy, nil
+	case "deflate":
+		return compressionDeflate, nil
+	}
+	return 0, fmt.Errorf("unknown compression codec %q", config.Codec)
+}
+
+// compressionProtocol returns the capability advertising the support of the
+// compressed messages, which runs until the peer disconnects.
+func compressionProtocol() p2p.Protocol {
+	return p2p.Protocol{
+		Name:    compressionProtocolName,
+		Version: compressionProtocolVersion,
+		Length:  0,
+		Run: func(p *p2p.Peer, rw p2p.MsgReadWriter) error {
+			for {
+				msg, err := rw.ReadMsg()
+				if err != nil {
+					return err
+				}
+				msg.Discard()
+			}
+		},
+	}
+}
+
+// supportsCompression reports whether a peer advertises the support of the
+// compressed messages.
+func supportsCompression(p *p2p.Peer) bool {
+	for _, cap := range p.Caps() {
+		if cap.Name == compressionProtocolName && cap.Version == compressionProtocolVersion {
+			return true
+		}
+	}
+	return false
+}
+
+// compressible reports whether the messages with the given code may be sent
+// compressed.
+func compressible(code uint64) bool {
+	return code == TxMsg || code == NewBlockMsg || code == BlockBodiesMsg
+}
+
+// compress encodes a message, compressed as an RLP string holding the codec and
+// the compressed RLP list if the peer supports it and it's worth it. The plain
+// messages being RLP lists, the two are told apart by their first byte.
+func (p *peer) compress(msgcode uint64, data interface{}) (p2p.Msg, error) {
+	size, r, err := rlp.EncodeToReader(data)
+	if err != nil {
+		return p2p.Msg{}, err
+	}
+	if p.compression == 0 || size < p.compressionThreshold || !compressible(msgcode) {
+		return p2p.Msg{Code: msgcode, Size: uint32(size), Payload: r}, nil
+	}
+	raw, err := ioutil.ReadAll(r)
+	if err != nil {
+		return p2p.Msg{}, err
+	}
+	compressed := []byte{p.compression}
+	switch p.compression {
+	case compressionSnappy:
+		compressed = append(compressed, snappy.Encode(nil, raw)...)
+	case compressionDeflate:
+		buf := bytes.NewBuffer(compressed)
+		w, _ := flate.NewWriter(buf, flate.DefaultCompression)
+		w.Write(raw)
+		w.Close()
+		compressed = buf.Bytes()
+	}
+	if len(compressed) >= len(raw) {
+		return p2p.Msg{Code: msgcode, Size: uint32(len(raw)), Payload: bytes.NewReader(raw)}, nil
+	}
+	payload, err := rlp.EncodeToBytes(compressed)
+	if err != nil {
+		return p2p.Msg{}, err
+	}
+	compressedRawMeter.Mark(int64(len(raw)))
+	compressedOutMeter.Mark(int64(len(payload)))
+	return p2p.Msg{Code: msgcode, Size: uint32(len(payload)), Payload: bytes.NewReader(payload)}, nil
+}
+
+// decompress returns a received message decompressed, or as is if not
+// compressed. The decompressed messages are held to the protocol size limit.
+func decompress(msg p2p.Msg) (p2p.Msg, error) {
+	raw, err := ioutil.ReadAll(msg.Payload)
+	if err != nil {
+		return msg, err
+	}
+	msg.Payload = bytes.NewReader(raw)
+	if kind, _, _, err := rlp.Split(raw); err != nil || kind == rlp.List {
+		return msg, nil
+	}
+	var compressed []byte
+	if err := rlp.DecodeBytes(raw, &compressed); err != nil {
+		return msg, err
+	}
+	if len(compressed) == 0 {
+		return msg, errUnknownCodec
+	}
+	var plain []byte
+	switch compressed[0] {
+	case compressionSnappy:
+		size, err := snappy.DecodedLen(compressed[1:])
+		if err != nil {
+			return msg, err
+		}
+		if size > ProtocolMaxMsgSize {
+			return msg, fmt.Errorf("decompressed message too large: %d > %d", size, ProtocolMaxMsgSize)
+		}
+		if plain, err = snappy.Decode(nil, compressed[1:]); err != nil {
+			return msg, err
+		}
+	case compressionDeflate:
+		r := flate.NewReader(bytes.NewReader(compressed[1:]))
+		defer r.Close()
+
+		if plain, err = ioutil.ReadAll(io.LimitReader(r, ProtocolMaxMsgSize+1)); err != nil {
+			return msg, err
+		}
+		if len(plain) > ProtocolMaxMsgSize {
+			return msg, fmt.Errorf("decompressed message too large: > %d", ProtocolMaxMsgSize)
+		}
+	default:
+		return msg, errUnknownCodec
+	}
+	decompressedMeter.Mark(int64(len(plain)))
+	msg.Size, msg.Payload = uint32(len(plain)), bytes.NewReader(plain)
+	return msg, nil
+}
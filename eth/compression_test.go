@@ -0,0 +1,85 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"bytes"
+	"compress/flate"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// Tests that the large messages are compressed with the codec of the peer and
+// decompressed back, and that the small ones are sent plain.
+func TestCompression(t *testing.T) {
+	payload := [][]byte{bytes.Repeat([]byte{0x42}, 64*1024)}
+	plain, _ := rlp.EncodeToBytes(payload)
+
+	tests := []struct {
+		codec      byte
+		threshold  int
+		code       uint64
+		compressed bool
+	}{
+		{compressionSnappy, 1024, BlockBodiesMsg, true},
+		{compressionDeflate, 1024, BlockBodiesMsg, true},
+		{compressionDeflate, 1024, TxMsg, true},
+		{compressionDeflate, 128 * 1024, BlockBodiesMsg, false}, // below the threshold
+		{compressionDeflate, 1024, GetBlockBodiesMsg, false},    // not compressible
+		{0, 1024, BlockBodiesMsg, false},                        // not supported by the peer
+	}
+	for i, tt := range tests {
+		p := &peer{compression: tt.codec, compressionThreshold: tt.threshold}
+		msg, err := p.compress(tt.code, payload)
+		if err != nil {
+			t.Fatalf("test %d: failed to compress: %v", i, err)
+		}
+		if compressed := int(msg.Size) < len(plain); compressed != tt.compressed {
+			t.Errorf("test %d: compression mismatch: have %v, want %v", i, compressed, tt.compressed)
+		}
+		msg, err = decompress(msg)
+		if err != nil {
+			t.Fatalf("test %d: failed to decompress: %v", i, err)
+		}
+		var decoded [][]byte
+		if err := msg.Decode(&decoded); err != nil {
+			t.Fatalf("test %d: failed to decode: %v", i, err)
+		}
+		if len(decoded) != 1 || !bytes.Equal(decoded[0], payload[0]) {
+			t.Errorf("test %d: payload mismatch", i)
+		}
+	}
+}
+
+// Tests that the messages decompressing beyond the protocol size limit are
+// rejected.
+func TestDecompressionLimit(t *testing.T) {
+	raw, _ := rlp.EncodeToBytes([][]byte{make([]byte, ProtocolMaxMsgSize)})
+
+	buf := bytes.NewBuffer([]byte{compressionDeflate})
+	w, _ := flate.NewWriter(buf, flate.BestCompression)
+	w.Write(raw)
+	w.Close()
+
+	payload, _ := rlp.EncodeToBytes(buf.Bytes())
+	msg := p2p.Msg{Code: BlockBodiesMsg, Size: uint32(len(payload)), Payload: bytes.NewReader(payload)}
+	if _, err := decompress(msg); err == nil {
+		t.Fatalf("oversized message decompressed")
+	}
+}
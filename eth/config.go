@@ -31,6 +31,7 @@ import (
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/eth/downloader"
 	"github.com/ethereum/go-ethereum/eth/gasprice"
+	"github.com/ethereum/go-ethereum/miner"
 	"github.com/ethereum/go-ethereum/params"
 )
 
@@ -54,13 +55,25 @@ var DefaultConfig = Config{
 	MinerGasPrice: big.NewInt(params.GWei),
 	MinerRecommit: 3 * time.Second,
 
+	MinerBuilderBudget: miner.DefaultBuilderBudget,
+
 	TxPool: core.DefaultTxPoolConfig,
 	GPO: gasprice.Config{
 		Blocks:     20,
 		Percentile: 60,
 	},
 
-	Istanbul: *istanbul.DefaultConfig,
+	Istanbul:    *istanbul.DefaultConfig,
+	Health:      DefaultHealthConfig,
+	Gossip:      DefaultGossipConfig,
+	Compression: DefaultCompressionConfig,
+
+	StandbyLease: defaultStandbyLease,
+
+	PTMOffloadThreshold: 256 * 1024,
+	PTMCacheTTL:         24 * time.Hour,
+
+	StateRangeRate: 10000,
 }
 
 func init() {
@@ -93,12 +106,27 @@ type Config struct {
 	LightServ  int `toml:",omitempty"` // Maximum percentage of time allowed for serving LES requests
 	LightPeers int `toml:",omitempty"` // Maximum number of LES client peers
 
+	// Trusted CHT checkpoint for light clients of chains without a built-in one,
+	// as reported by the les protocol section of admin.nodeInfo on a server
+	Checkpoint *params.TrustedCheckpoint `toml:",omitempty"`
+
 	// Database options
 	SkipBcVersionCheck bool `toml:"-"`
 	DatabaseHandles    int  `toml:"-"`
 	DatabaseCache      int
 	TrieCache          int
 	TrieTimeout        time.Duration
+	Snapshot           bool `toml:",omitempty"` // Whether to read the states from flat snapshots
+	LogIndex           bool `toml:",omitempty"` // Whether to index the blocks holding the logs of each contract
+	StateSize          bool `toml:",omitempty"` // Whether to count the storage and code size of each contract
+	ImportPipeline     int  `toml:",omitempty"` // Number of blocks executed ahead of their write during chain imports (0 = sequential)
+	RPCCacheSize       int  `toml:",omitempty"` // Megabytes of memory caching the responses to immutable RPC queries (0 = disabled)
+	StateRangeRate     int  `toml:",omitempty"` // Trie entries per second read by the state range RPC calls (0 = unlimited)
+
+	// Executions of eth_call and eth_estimateGas on dedicated threads, isolated
+	// from block processing
+	RPCCallWorkers  int           `toml:",omitempty"` // Threads executing the calls (0 = execute on the requesting goroutine)
+	RPCCallCPUQuota time.Duration `toml:",omitempty"` // CPU time after which a call is aborted (0 = unlimited)
 
 	// Mining-related options
 	Etherbase      common.Address `toml:",omitempty"`
@@ -110,6 +138,10 @@ type Config struct {
 	MinerRecommit  time.Duration
 	MinerNoverify  bool
 
+	// Quorum
+	MinerBuilder       string        `toml:",omitempty"` // JSON-RPC endpoint of the external block builder
+	MinerBuilderBudget time.Duration `toml:",omitempty"` // Time the block builder is given to order a block
+
 	// Ethash options
 	Ethash ethash.Config
 
@@ -121,12 +153,78 @@ type Config struct {
 
 	// Enables tracking of SHA3 preimages in the VM
 	EnablePreimageRecording bool
+	PreimageMaxSize         int    `toml:",omitempty"` // Maximum size of a stored preimage (0 = unlimited)
+	PreimageRetention       uint64 `toml:",omitempty"` // Number of blocks preimages are retained for (0 = forever)
 
 	RaftMode             bool
 	EnableNodePermission bool
+
+	// Meter the cumulative gas used by each sender, for off-chain cost allocation
+	GasAccounting          bool   `toml:",omitempty"`
+	GasAccountingExportDir string `toml:",omitempty"` // Directory the gas accounting CSV exports are written to (empty = export disabled)
+
+	// Propagate the new blocks to the nearest peers first, by measured round-trip time
+	LatencyAwarePropagation bool `toml:",omitempty"`
+
+	// Propagation of the blocks and transactions through gossip topics
+	Gossip GossipConfig
+
+	// Peers the private transactions are propagated to, by role of the node
+	PrivateGossip PrivateGossipConfig
+
+	// Compression of the block and transaction messages between the peers supporting it
+	Compression CompressionConfig
+
+	// Alert thresholds of the consensus health watchdog
+	Health HealthConfig
+
 	// Istanbul options
 	Istanbul istanbul.Config
 
+	// URL of the sink the Istanbul epoch checkpoints are exported to (empty = disabled)
+	IstanbulCheckpointSink string `toml:",omitempty"`
+
+	// URLs notified of the node events
+	Webhooks []WebhookConfig `toml:",omitempty"`
+
+	// Historical blocks re-validated per hour by the integrity verifier (0 = disabled)
+	IntegritySamples int `toml:",omitempty"`
+
+	// Candidate chain configuration the new blocks are replayed under (nil = disabled)
+	DryRunConfig *params.ChainConfig `toml:",omitempty"`
+
+	// Postgres URL the blocks are exported to, or file or named pipe they are streamed to as statements (empty = disabled)
+	SQLExport        string `toml:",omitempty"`
+	SQLExportPrivate bool   `toml:",omitempty"` // Whether to export the private payloads, receipts and logs
+
+	// Address the private transaction manager pushes the available payloads to (empty = disabled)
+	PTMPushAddr   string `toml:",omitempty"`
+	PTMPushSecret string `toml:",omitempty"` // File holding the bearer token of the private transaction manager
+
+	// Number of blocks on top of a block for it to be final, on chains without
+	// instant finality (0 = default)
+	FinalityConfirmations uint64 `toml:",omitempty"`
+
+	// External store of the large private payloads, distributed by reference (empty = disabled)
+	PTMOffloadStore     string `toml:",omitempty"`
+	PTMOffloadThreshold int    `toml:",omitempty"` // Size from which the payloads sent are offloaded (0 = never)
+
+	// Size in MB of the on-disk cache of the received private payloads (0 = disabled)
+	PTMCacheSize    int           `toml:",omitempty"`
+	PTMCacheTTL     time.Duration `toml:",omitempty"` // Time a payload is cached for (0 = until evicted)
+	PTMCacheEncrypt bool          `toml:",omitempty"` // Whether to encrypt the cached payloads with a key derived from the node key
+
+	// Number of recent blocks whose bodies and receipts are retained (0 = all)
+	HistoryRetention uint64           `toml:",omitempty"`
+	HistoryProtected []common.Address `toml:",omitempty"` // Contracts whose blocks are retained regardless
+
+	// Role of the node in a hot standby pair: "primary", "standby" or empty
+	StandbyRole      string        `toml:",omitempty"`
+	StandbyPeer      string        `toml:",omitempty"` // RPC endpoint of the standby the primary renews its lease with
+	StandbyEscrowKey string        `toml:",omitempty"` // File holding the node key of the primary, taken over by the standby
+	StandbyLease     time.Duration `toml:",omitempty"` // Time the primary signs for after renewing its lease
+	StandbyPasswords []string      `toml:"-" json:"-"` // Passwords unlocking the accounts of the primary on promotion
+
 	// Miscellaneous options
 	DocRoot string `toml:"-"`
 
@@ -0,0 +1,235 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/consensus/clique"
+	"github.com/ethereum/go-ethereum/consensus/istanbul"
+	istanbulBackend "github.com/ethereum/go-ethereum/consensus/istanbul/backend"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+const (
+	// dryRunRejectionsLimit is the number of the most recent rejections kept
+	// for the dry run report.
+	dryRunRejectionsLimit = 256
+
+	// dryRunBlocksLimit is the maximum number of blocks replayed on demand.
+	dryRunBlocksLimit = 1024
+)
+
+// ConfigDryRunReport sums up the replay of the new blocks under the candidate
+// chain configuration since the node started.
+type ConfigDryRunReport struct {
+	Checked    uint64               `json:"checked"`    // Blocks replayed
+	Rejected   uint64               `json:"rejected"`   // Blocks the candidate configuration would reject
+	LastBlock  uint64               `json:"lastBlock"`  // Last block replayed
+	LastRun    time.Time            `json:"lastRun"`    // Time of the last replay
+	Activated  []string             `json:"activated"`  // Transitions activated early by the last replay
+	Rejections []*core.DryRunResult `json:"rejections"` // Most recent rejections, oldest first
+}
+
+// configDryRun replays every new head under a candidate chain configuration,
+// with its scheduled transitions activated, reporting the blocks this node
+// would reject once they activate. The blocks are executed twice, the private
+// transactions fetching their payloads twice from the transaction manager.
+type configDryRun struct {
+	chain     *core.BlockChain
+	candidate *params.ChainConfig
+	engine    core.DryRunEngine // Creates the engines verifying the replayed blocks
+
+	lock sync.RWMutex
+	rep  ConfigDryRunReport
+}
+
+// newConfigDryRun creates a dry run of a candidate chain configuration, the
+// blocks verified by an engine created with the candidate engine settings.
+func newConfigDryRun(chain *core.BlockChain, candidate *params.ChainConfig, config *Config, db ethdb.Database) (*configDryRun, error) {
+	if err := candidate.IsValid(); err != nil {
+		return nil, err
+	}
+	if candidate.ChainID != nil && chain.Config().ChainID != nil && candidate.ChainID.Cmp(chain.Config().ChainID) != 0 {
+		return nil, errors.New("dry run chain configuration of another chain")
+	}
+	return &configDryRun{chain: chain, candidate: candidate, engine: dryRunEngine(chain.Engine(), config.Istanbul, db)}, nil
+}
+
+// dryRunEngine creates the engines verifying the blocks replayed under candidate
+// configurations: the Clique and Istanbul engines are created anew with the
+// settings of the candidate, while ethash, configured by the chain
+// configuration passed along, is the running engine.
+func dryRunEngine(running consensus.Engine, istanbulBase istanbul.Config, db ethdb.Database) core.DryRunEngine {
+	return func(config *params.ChainConfig) consensus.Engine {
+		switch {
+		case config.Clique != nil:
+			return clique.New(config.Clique, db)
+		case config.Istanbul != nil:
+			cfg := istanbulConfig(istanbulBase, config.Istanbul)
+			return istanbulBackend.New(&cfg, nil, db)
+		}
+		return running
+	}
+}
+
+// LoadDryRunConfig reads a candidate chain configuration from a genesis file,
+// or from a file holding the chain configuration alone. As for the genesis
+// files, the configuration is of a Quorum chain unless isQuorum is false.
+func LoadDryRunConfig(path string) (*params.ChainConfig, error) {
+	blob, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	genesis := struct {
+		Config *params.ChainConfig `json:"config"`
+	}{Config: &params.ChainConfig{IsQuorum: true}}
+	if err := json.Unmarshal(blob, &genesis); err != nil {
+		return nil, fmt.Errorf("invalid chain configuration file %s: %v", path, err)
+	}
+	config := genesis.Config
+	if config.ChainID == nil {
+		config = &params.ChainConfig{IsQuorum: true}
+		if err := json.Unmarshal(blob, config); err != nil {
+			return nil, fmt.Errorf("invalid chain configuration file %s: %v", path, err)
+		}
+		if config.ChainID == nil {
+			return nil, fmt.Errorf("invalid chain configuration file %s: chain ID missing", path)
+		}
+	}
+	// Default the size limits as the genesis block does
+	if config.TransactionSizeLimit == 0 {
+		config.TransactionSizeLimit = core.DefaultTxPoolConfig.TransactionSizeLimit
+	}
+	if config.MaxCodeSize == 0 {
+		config.MaxCodeSize = core.DefaultTxPoolConfig.MaxCodeSize
+	}
+	return config, nil
+}
+
+// loop replays the new heads as the chain reaches them. A burst of heads is
+// replayed from the newest only.
+func (d *configDryRun) loop(quit chan bool) {
+	heads := make(chan core.ChainHeadEvent, 16)
+	sub := d.chain.SubscribeChainHeadEvent(heads)
+	defer sub.Unsubscribe()
+
+	for {
+		select {
+		case ev := <-heads:
+			if number := ev.Block.NumberU64(); number > 0 && d.chain.CurrentBlock().NumberU64() == number {
+				d.replay(number)
+			}
+		case <-sub.Err():
+			return
+		case <-quit:
+			return
+		}
+	}
+}
+
+// replay replays a block and records the outcome.
+func (d *configDryRun) replay(number uint64) {
+	report, err := d.chain.DryRun(d.candidate, d.engine, number, number)
+	if err != nil {
+		log.Debug("Failed to replay block under the candidate chain configuration", "number", number, "err", err)
+		return
+	}
+	d.record(report)
+}
+
+// record adds the outcome of a replay to the report.
+func (d *configDryRun) record(report *core.DryRunReport) {
+	dryRunCheckedMeter.Mark(int64(len(report.Blocks)))
+	dryRunRejectedMeter.Mark(int64(report.Rejected))
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.rep.Checked += uint64(len(report.Blocks))
+	d.rep.Rejected += uint64(report.Rejected)
+	d.rep.LastBlock, d.rep.LastRun, d.rep.Activated = report.To, time.Now(), report.Activated
+	if report.Incompatible != "" {
+		log.Warn("Candidate chain configuration conflicts with the imported blocks", "err", report.Incompatible)
+	}
+	for _, block := range report.Blocks {
+		if block.Error == "" {
+			continue
+		}
+		log.Warn("Block rejected under the candidate chain configuration", "number", block.Number, "hash", block.Hash, "activated", len(report.Activated), "err", block.Error)
+		d.rep.Rejections = append(d.rep.Rejections, block)
+	}
+	if n := len(d.rep.Rejections); n > dryRunRejectionsLimit {
+		d.rep.Rejections = append([]*core.DryRunResult(nil), d.rep.Rejections[n-dryRunRejectionsLimit:]...)
+	}
+}
+
+// Report returns the outcome of the replays since the node started.
+func (d *configDryRun) Report() *ConfigDryRunReport {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+
+	rep := d.rep
+	rep.Activated = append([]string{}, d.rep.Activated...)
+	rep.Rejections = append([]*core.DryRunResult{}, d.rep.Rejections...)
+	return &rep
+}
+
+// PrivateConfigDryRunAPI provides an API to replay the blocks under the
+// candidate chain configuration.
+type PrivateConfigDryRunAPI struct {
+	dryRun *configDryRun
+}
+
+// NewPrivateConfigDryRunAPI creates a new API definition for the chain
+// configuration dry run of the Ethereum service.
+func NewPrivateConfigDryRunAPI(dryRun *configDryRun) *PrivateConfigDryRunAPI {
+	return &PrivateConfigDryRunAPI{dryRun: dryRun}
+}
+
+// ConfigDryRunReport returns the number of new blocks replayed under the
+// candidate chain configuration since the node started, along with the most
+// recent ones it would reject.
+func (api *PrivateConfigDryRunAPI) ConfigDryRunReport() *ConfigDryRunReport {
+	return api.dryRun.Report()
+}
+
+// DryRunConfig replays the last blocks of the chain under the candidate chain
+// configuration, the transitions scheduled after the head activated from the
+// first of them. Their parent states must be available.
+func (api *PrivateConfigDryRunAPI) DryRunConfig(blocks uint64) (*core.DryRunReport, error) {
+	if blocks > dryRunBlocksLimit {
+		return nil, fmt.Errorf("too many blocks to replay: %d > %d", blocks, dryRunBlocksLimit)
+	}
+	head := api.dryRun.chain.CurrentBlock().NumberU64()
+	if blocks == 0 || blocks > head {
+		blocks = head
+	}
+	if blocks == 0 {
+		return nil, errors.New("no block to replay")
+	}
+	return api.dryRun.chain.DryRun(api.dryRun.candidate, api.dryRun.engine, head-blocks+1, head)
+}
@@ -21,9 +21,11 @@ import (
 	"errors"
 	"math/big"
 
+	"github.com/ethereum/go-ethereum/abiregistry"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/bloombits"
+	"github.com/ethereum/go-ethereum/core/logindex"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/event"
@@ -45,6 +47,10 @@ type Backend interface {
 
 	BloomStatus() (uint64, uint64)
 	ServiceFilter(ctx context.Context, session *bloombits.MatcherSession)
+
+	// Quorum
+	LogIndex() *logindex.Index          // nil if the logs aren't indexed
+	ABIRegistry() *abiregistry.Registry // nil if the logs aren't decoded
 }
 
 // Filter can be used to retrieve and filter logs.
@@ -59,6 +65,10 @@ type Filter struct {
 	begin, end int64       // Range interval if filtering multiple blocks
 
 	matcher *bloombits.Matcher
+
+	// Quorum
+	match func(*types.Log) bool // Selects the logs matching the criteria, all if nil
+	limit int                   // Number of logs the search stops at, none if 0
 }
 
 // NewRangeFilter creates a new filter which uses a bloom filter on blocks to
@@ -138,10 +148,47 @@ func (f *Filter) Logs(ctx context.Context) ([]*types.Log, error) {
 	if f.begin == -1 {
 		f.begin = int64(head)
 	}
+	// Quorum: resolve the finalized block through the consensus aware backend
+	if f.begin == rpc.FinalizedBlockNumber.Int64() {
+		if header, _ = f.backend.HeaderByNumber(ctx, rpc.FinalizedBlockNumber); header == nil {
+			return nil, nil
+		}
+		f.begin = header.Number.Int64()
+	}
 	end := uint64(f.end)
 	if f.end == -1 {
 		end = head
 	}
+	if f.end == rpc.FinalizedBlockNumber.Int64() {
+		if header, _ = f.backend.HeaderByNumber(ctx, rpc.FinalizedBlockNumber); header == nil {
+			return nil, nil
+		}
+		end = header.Number.Uint64()
+	}
+	// Quorum
+	// Serve the blocks covered by the log index from the index when the filter is
+	// restricted to some contracts, leaving the older blocks to the bloom bits
+	if idx := f.backend.LogIndex(); idx != nil && len(f.addresses) > 0 {
+		if tail, ok := idx.Tail(); ok && tail <= end {
+			var logs []*types.Log
+			if uint64(f.begin) < tail {
+				found, err := f.bloomLogs(ctx, tail-1)
+				if err != nil || f.full(found) {
+					return found, err
+				}
+				logs = found
+			}
+			rest, err := f.logIndexLogs(ctx, idx, end, len(logs))
+			logs = append(logs, rest...)
+			return logs, err
+		}
+	}
+	return f.bloomLogs(ctx, end)
+}
+
+// bloomLogs returns the logs matching the filter criteria up to the given block,
+// using the bloom bits for the indexed sections and the block blooms after.
+func (f *Filter) bloomLogs(ctx context.Context, end uint64) ([]*types.Log, error) {
 	// Gather all indexed logs, and finish with non indexed ones
 	var (
 		logs []*types.Log
@@ -154,11 +201,11 @@ func (f *Filter) Logs(ctx context.Context) ([]*types.Log, error) {
 		} else {
 			logs, err = f.indexedLogs(ctx, indexed-1)
 		}
-		if err != nil {
+		if err != nil || f.full(logs) {
 			return logs, err
 		}
 	}
-	rest, err := f.unindexedLogs(ctx, end)
+	rest, err := f.unindexedLogs(ctx, end, len(logs))
 	logs = append(logs, rest...)
 	return logs, err
 }
@@ -202,17 +249,57 @@ func (f *Filter) indexedLogs(ctx context.Context, end uint64) ([]*types.Log, err
 			if err != nil {
 				return logs, err
 			}
-			logs = append(logs, found...)
+			if logs = append(logs, found...); f.full(logs) {
+				return logs[:f.limit], nil
+			}
+
+		case <-ctx.Done():
+			return logs, ctx.Err()
+		}
+	}
+}
 
+// logIndexLogs returns the logs matching the filter criteria up to the given
+// block, based on the blocks the log index lists for the filtered contracts. The
+// number of logs already found counts towards the limit of the filter.
+func (f *Filter) logIndexLogs(ctx context.Context, idx *logindex.Index, end uint64, found int) ([]*types.Log, error) {
+	var topics []common.Hash
+	if len(f.topics) > 0 {
+		topics = f.topics[0]
+	}
+	numbers, err := idx.Blocks(f.addresses, topics, uint64(f.begin), end)
+	if err != nil {
+		return nil, err
+	}
+	var logs []*types.Log
+	for _, number := range numbers {
+		select {
 		case <-ctx.Done():
 			return logs, ctx.Err()
+		default:
+		}
+		f.begin = int64(number) + 1
+
+		header, err := f.backend.HeaderByNumber(ctx, rpc.BlockNumber(number))
+		if header == nil || err != nil {
+			return logs, err
+		}
+		matches, err := f.checkMatches(ctx, header)
+		if err != nil {
+			return logs, err
+		}
+		if logs = append(logs, matches...); f.limit > 0 && found+len(logs) >= f.limit {
+			return logs[:f.limit-found], nil
 		}
 	}
+	f.begin = int64(end) + 1
+	return logs, nil
 }
 
 // indexedLogs returns the logs matching the filter criteria based on raw block
-// iteration and bloom matching.
-func (f *Filter) unindexedLogs(ctx context.Context, end uint64) ([]*types.Log, error) {
+// iteration and bloom matching. The number of logs already found counts towards
+// the limit of the filter.
+func (f *Filter) unindexedLogs(ctx context.Context, end uint64, found int) ([]*types.Log, error) {
 	var logs []*types.Log
 
 	for ; f.begin <= int64(end); f.begin++ {
@@ -220,11 +307,14 @@ func (f *Filter) unindexedLogs(ctx context.Context, end uint64) ([]*types.Log, e
 		if header == nil || err != nil {
 			return logs, err
 		}
-		found, err := f.blockLogs(ctx, header)
+		matches, err := f.blockLogs(ctx, header)
 		if err != nil {
 			return logs, err
 		}
-		logs = append(logs, found...)
+		if logs = append(logs, matches...); f.limit > 0 && found+len(logs) >= f.limit {
+			f.begin++
+			return logs[:f.limit-found], nil
+		}
 	}
 	return logs, nil
 }
@@ -271,11 +361,26 @@ func (f *Filter) checkMatches(ctx context.Context, header *types.Header) (logs [
 			}
 			logs = filterLogs(unfiltered, nil, nil, f.addresses, f.topics)
 		}
+		// Quorum: apply the selection beyond the addresses and topics
+		if f.match != nil {
+			selected := logs[:0]
+			for _, log := range logs {
+				if f.match(log) {
+					selected = append(selected, log)
+				}
+			}
+			logs = selected
+		}
 		return logs, nil
 	}
 	return nil, nil
 }
 
+// full reports whether the logs found reach the limit of the filter.
+func (f *Filter) full(logs []*types.Log) bool {
+	return f.limit > 0 && len(logs) >= f.limit
+}
+
 func includes(addresses []common.Address, a common.Address) bool {
 	for _, addr := range addresses {
 		if addr == a {
@@ -21,11 +21,13 @@ import (
 	"io/ioutil"
 	"math/big"
 	"os"
+	"reflect"
 	"testing"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/logindex"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
@@ -247,6 +249,19 @@ func TestFilters(t *testing.T) {
 
 	// Quorum
 
+	// The search stops at the limit, counting the logs selected beyond the topics only
+	filter = NewRangeFilter(backend, 0, -1, []common.Address{addr}, [][]common.Hash{{hash1, hash2, hash3, hash4}})
+	filter.limit = 1
+	filter.match = func(log *types.Log) bool { return log.Topics[0] != hash1 }
+
+	logs, _ = filter.Logs(context.Background())
+	if len(logs) != 1 || logs[0].Topics[0] != hash2 {
+		t.Errorf("limited logs mismatch: have %v, want the one of %x", logs, hash2)
+	}
+	if filter.begin != 4 {
+		t.Errorf("search not stopped at the limit: continued to block %d, want 4", filter.begin)
+	}
+
 	// Test individual private log with NewBlockFilter (query filter with block hash)
 	filter = NewBlockFilter(backend, chain[998].Hash(), nil, [][]common.Hash{{hash5}})
 
@@ -267,3 +282,104 @@ func TestFilters(t *testing.T) {
 	}
 
 }
+
+// indexedBackend is a test backend whose logs are indexed.
+type indexedBackend struct {
+	*testBackend
+	index *logindex.Index
+}
+
+func (b *indexedBackend) LogIndex() *logindex.Index {
+	return b.index
+}
+
+// Tests that the blocks covered by the log index are served from the index, and
+// the older blocks from the blooms.
+func TestIndexedFilters(t *testing.T) {
+	var (
+		db      = ethdb.NewMemDatabase()
+		backend = &indexedBackend{testBackend: &testBackend{new(event.TypeMux), db, 0, new(event.Feed), new(event.Feed), new(event.Feed), new(event.Feed)}, index: logindex.New(db)}
+		addr1   = common.BytesToAddress([]byte("contract1"))
+		addr2   = common.BytesToAddress([]byte("contract2"))
+		topic1  = common.BytesToHash([]byte("topic1"))
+		topic2  = common.BytesToHash([]byte("topic2"))
+	)
+	logged := map[int][]*types.Log{
+		10:  {{Address: addr1, Topics: []common.Hash{topic1}}},
+		60:  {{Address: addr1, Topics: []common.Hash{topic2}}, {Address: addr2, Topics: []common.Hash{topic1}}},
+		80:  {{Address: addr2, Topics: []common.Hash{topic2}}},
+		150: {{Address: addr1, Topics: []common.Hash{topic1}}},
+	}
+	genesis := core.GenesisBlockForTesting(db, common.Address{}, big.NewInt(1000000))
+	chain, receipts := core.GenerateChain(params.TestChainConfig, genesis, ethash.NewFaker(), db, 200, func(i int, gen *core.BlockGen) {
+		if logs, ok := logged[i]; ok {
+			for _, l := range logs {
+				l.BlockNumber = gen.Number().Uint64()
+			}
+			receipt := types.NewReceipt(nil, false, 0)
+			receipt.Logs = logs
+			gen.AddUncheckedReceipt(receipt)
+		}
+	})
+	for i, block := range chain {
+		rawdb.WriteBlock(db, block)
+		rawdb.WriteCanonicalHash(db, block.Hash(), block.NumberU64())
+		rawdb.WriteHeadBlockHash(db, block.Hash())
+		rawdb.WriteReceipts(db, block.Hash(), block.NumberU64(), receipts[i])
+
+		// Index the blocks after the 50th only, the older ones being left to the blooms
+		if i == 50 {
+			if err := backend.index.Open(block.NumberU64()); err != nil {
+				t.Fatalf("failed to open index: %v", err)
+			}
+		}
+		if i > 50 {
+			if err := backend.index.Index(block.NumberU64(), receipts[i]); err != nil {
+				t.Fatalf("failed to index block %d: %v", block.NumberU64(), err)
+			}
+		}
+	}
+	tests := []struct {
+		begin, end int64
+		addresses  []common.Address
+		topics     [][]common.Hash
+		want       []uint64
+	}{
+		{0, -1, []common.Address{addr1}, nil, []uint64{11, 61, 151}},
+		{0, -1, []common.Address{addr1}, [][]common.Hash{{topic1}}, []uint64{11, 151}},
+		{0, -1, []common.Address{addr1, addr2}, [][]common.Hash{{topic2}}, []uint64{61, 81}},
+		{70, 160, []common.Address{addr1, addr2}, nil, []uint64{81, 151}},
+		{0, -1, []common.Address{addr2}, [][]common.Hash{nil, {topic1}}, nil},
+		{0, -1, nil, [][]common.Hash{{topic1}}, []uint64{11, 61, 151}},
+	}
+	for i, tt := range tests {
+		logs, err := NewRangeFilter(backend, tt.begin, tt.end, tt.addresses, tt.topics).Logs(context.Background())
+		if err != nil {
+			t.Fatalf("test %d: failed to filter logs: %v", i, err)
+		}
+		var have []uint64
+		for _, l := range logs {
+			have = append(have, l.BlockNumber)
+		}
+		if !reflect.DeepEqual(have, tt.want) {
+			t.Errorf("test %d: log blocks mismatch: have %v, want %v", i, have, tt.want)
+		}
+	}
+	// The limit spans the blocks served from the blooms and from the index
+	for limit, want := range map[int][]uint64{1: {11}, 2: {11, 61}, 3: {11, 61, 151}} {
+		filter := NewRangeFilter(backend, 0, -1, []common.Address{addr1}, nil)
+		filter.limit = limit
+
+		logs, err := filter.Logs(context.Background())
+		if err != nil {
+			t.Fatalf("limit %d: failed to filter logs: %v", limit, err)
+		}
+		var have []uint64
+		for _, l := range logs {
+			have = append(have, l.BlockNumber)
+		}
+		if !reflect.DeepEqual(have, want) {
+			t.Errorf("limit %d: log blocks mismatch: have %v, want %v", limit, have, want)
+		}
+	}
+}
@@ -0,0 +1,771 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package filters
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// maxQueryResults is the maximum number of logs returned by a query.
+const maxQueryResults = 10000
+
+// PublicLogQueryAPI offers the queries of the logs evaluated on the node, for
+// the clients to only receive the logs and fields they need.
+type PublicLogQueryAPI struct {
+	backend Backend
+}
+
+// NewPublicLogQueryAPI creates the log query API.
+func NewPublicLogQueryAPI(backend Backend) *PublicLogQueryAPI {
+	return &PublicLogQueryAPI{backend: backend}
+}
+
+// QueryLogs returns the logs of a range of blocks selected by a query, in the
+// order of the chain, up to the limit of the query.
+func (api *PublicLogQueryAPI) QueryLogs(ctx context.Context, query LogQuery) ([]*LogRow, error) {
+	q, err := compileQuery(query.ABI, query.Where)
+	if err != nil {
+		return nil, err
+	}
+	limit := query.Limit
+	if limit <= 0 || limit > maxQueryResults {
+		limit = maxQueryResults
+	}
+	begin := rpc.LatestBlockNumber.Int64()
+	if query.FromBlock != nil {
+		begin = query.FromBlock.Int64()
+	}
+	end := rpc.LatestBlockNumber.Int64()
+	if query.ToBlock != nil {
+		end = query.ToBlock.Int64()
+	}
+	// Select the logs while searching, to stop at the limit
+	addresses, topics := q.prefilter()
+	filter := NewRangeFilter(api.backend, begin, end, addresses, topics)
+	filter.match = func(log *types.Log) bool { return q.where.match(q, log) }
+	filter.limit = limit
+
+	logs, err := filter.Logs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rows := make([]*LogRow, 0, len(logs))
+	for _, log := range logs {
+		rows = append(rows, q.row(log))
+	}
+	return rows, nil
+}
+
+// LogQuery selects the logs of a range of blocks by an expression over their
+// fields, evaluated on the node:
+//
+//	expr       = and { OR and }
+//	and        = unary { AND unary }
+//	unary      = NOT unary | "(" expr ")" | comparison
+//	comparison = field ( "=" | "!=" | "<>" | "<" | "<=" | ">" | ">=" ) value
+//	           | field [ NOT ] IN "(" value { "," value } ")"
+//
+// The fields are address, blockNumber, event (the name of an event of the ABI),
+// topic0 to topic3 and the indexed arguments of the events of the ABI, by name.
+// Values are decimal or 0x-prefixed hex numbers, 'quoted' strings, TRUE and
+// FALSE, converted to the type of the field: strings and byte arrays of the
+// dynamic types are compared by hash, as they are indexed. The ordering
+// operators only apply to numbers. A comparison on an argument the event of a
+// log doesn't have is false.
+type LogQuery struct {
+	FromBlock *rpc.BlockNumber `json:"fromBlock"`
+	ToBlock   *rpc.BlockNumber `json:"toBlock"`
+	ABI       json.RawMessage  `json:"abi"`   // Events decoding the topics, optional
+	Where     string           `json:"where"` // Expression selecting the logs, all if empty
+	Limit     int              `json:"limit"` // Maximum number of logs returned, up to maxQueryResults
+}
+
+// LogRow is a log selected by a query, with its indexed arguments decoded if
+// its event is in the ABI of the query.
+type LogRow struct {
+	Address     common.Address         `json:"address"`
+	BlockNumber hexutil.Uint64         `json:"blockNumber"`
+	TxHash      common.Hash            `json:"transactionHash"`
+	LogIndex    hexutil.Uint           `json:"logIndex"`
+	Event       string                 `json:"event,omitempty"`
+	Args        map[string]interface{} `json:"args,omitempty"`
+	Topics      []common.Hash          `json:"topics,omitempty"` // Only if the event is unknown
+}
+
+// logQuery is a compiled query expression.
+type logQuery struct {
+	events  map[common.Hash]*abi.Event // Non-anonymous events by ID
+	anon    *abi.Event                 // Event of the logs not identified by ID, if the ABI has a single anonymous one
+	byName  map[string]*abi.Event
+	single  bool // Whether the ABI has a single event
+	where   queryNode
+	clauses []*queryCond // Top-level conjuncts, narrowing the logs searched
+}
+
+// compileQuery parses the expression of a query against its ABI.
+func compileQuery(abiJSON json.RawMessage, where string) (*logQuery, error) {
+	q := &logQuery{
+		events: make(map[common.Hash]*abi.Event),
+		byName: make(map[string]*abi.Event),
+	}
+	if len(abiJSON) > 0 && string(abiJSON) != "null" {
+		parsed, err := abi.JSON(bytes.NewReader(abiJSON))
+		if err != nil {
+			return nil, fmt.Errorf("invalid ABI: %v", err)
+		}
+		for name := range parsed.Events {
+			ev := parsed.Events[name]
+			q.byName[name] = &ev
+			if !ev.Anonymous {
+				q.events[ev.Id()] = &ev
+			}
+		}
+		q.single = len(parsed.Events) == 1
+		if q.single && len(q.events) == 0 {
+			for _, ev := range q.byName {
+				q.anon = ev
+			}
+		}
+	}
+	if strings.TrimSpace(where) == "" {
+		q.where = queryTrue{}
+		return q, nil
+	}
+	tokens, err := lexQuery(where)
+	if err != nil {
+		return nil, err
+	}
+	p := &queryParser{query: q, tokens: tokens}
+	if q.where, err = p.parseOr(); err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokEOF {
+		return nil, fmt.Errorf("unexpected %q at offset %d", tok.text, tok.pos)
+	}
+	q.clauses = conjuncts(q.where, nil)
+	return q, nil
+}
+
+// eventOf returns the event of the ABI a log was emitted by, nil if unknown.
+func (q *logQuery) eventOf(log *types.Log) *abi.Event {
+	if len(log.Topics) > 0 {
+		if ev := q.events[log.Topics[0]]; ev != nil {
+			return ev
+		}
+	}
+	return q.anon
+}
+
+// prefilter returns the addresses and topics all the selected logs match, from
+// the top-level equalities of the expression, for the log index to narrow the
+// search.
+func (q *logQuery) prefilter() ([]common.Address, [][]common.Hash) {
+	var (
+		addresses []common.Address
+		topics    [][]common.Hash
+	)
+	for _, cond := range q.clauses {
+		if cond.op != "=" {
+			continue
+		}
+		pos := cond.field.topic
+		if pos < 0 {
+			if cond.field.name == "address" && addresses == nil {
+				for _, value := range cond.values {
+					addresses = append(addresses, common.BytesToAddress(value[:]))
+				}
+			}
+			continue
+		}
+		for len(topics) <= pos {
+			topics = append(topics, nil)
+		}
+		if topics[pos] == nil {
+			topics[pos] = cond.values
+		}
+	}
+	return addresses, topics
+}
+
+// row returns the selected form of a log.
+func (q *logQuery) row(log *types.Log) *LogRow {
+	row := &LogRow{
+		Address:     log.Address,
+		BlockNumber: hexutil.Uint64(log.BlockNumber),
+		TxHash:      log.TxHash,
+		LogIndex:    hexutil.Uint(log.Index),
+	}
+	ev := q.eventOf(log)
+	if ev == nil {
+		row.Topics = log.Topics
+		return row
+	}
+	row.Event = ev.Name
+	row.Args = make(map[string]interface{})
+	for _, arg := range ev.Inputs {
+		if pos := topicOf(ev, arg.Name); arg.Indexed && pos < len(log.Topics) {
+			row.Args[arg.Name] = decodeTopicValue(arg.Type, log.Topics[pos])
+		}
+	}
+	return row
+}
+
+// topicOf returns the position of the topic holding an indexed argument of an
+// event, -1 if the event has no such argument.
+func topicOf(ev *abi.Event, name string) int {
+	pos := 0
+	if !ev.Anonymous {
+		pos = 1
+	}
+	for _, arg := range ev.Inputs {
+		if !arg.Indexed {
+			continue
+		}
+		if arg.Name == name {
+			return pos
+		}
+		pos++
+	}
+	return -1
+}
+
+// decodeTopicValue decodes an indexed argument from its topic.
+func decodeTopicValue(typ abi.Type, topic common.Hash) interface{} {
+	switch typ.T {
+	case abi.UintTy, abi.IntTy:
+		return (*hexutil.Big)(wordNumber(typ, topic))
+	case abi.BoolTy:
+		return topic[common.HashLength-1] != 0
+	case abi.AddressTy:
+		return common.BytesToAddress(topic[:])
+	case abi.FixedBytesTy:
+		return hexutil.Bytes(topic[:typ.Size])
+	default:
+		return topic
+	}
+}
+
+// wordNumber decodes a numeric word, in two's complement for signed integers.
+func wordNumber(typ abi.Type, word common.Hash) *big.Int {
+	n := new(big.Int).SetBytes(word[:])
+	if typ.T == abi.IntTy {
+		n = math.S256(n)
+	}
+	return n
+}
+
+// queryField is a field of the logs a query compares.
+type queryField struct {
+	name  string
+	typ   abi.Type
+	event bool // Whether the field is the event name, compared by ID
+	topic int  // Position of the topic holding the field for all logs, -1 if none or event dependent
+
+	get func(q *logQuery, log *types.Log) (common.Hash, bool)
+}
+
+var (
+	uint64Type, _  = abi.NewType("uint64")
+	addressType, _ = abi.NewType("address")
+	bytes32Type, _ = abi.NewType("bytes32")
+)
+
+// resolveField returns the field of the given name, the built-in ones shadowing
+// the arguments of the events.
+func (q *logQuery) resolveField(name string) (*queryField, error) {
+	switch name {
+	case "address":
+		return &queryField{name: name, typ: addressType, topic: -1, get: func(q *logQuery, log *types.Log) (common.Hash, bool) {
+			return common.BytesToHash(log.Address[:]), true
+		}}, nil
+	case "blockNumber":
+		return &queryField{name: name, typ: uint64Type, topic: -1, get: func(q *logQuery, log *types.Log) (common.Hash, bool) {
+			return common.BigToHash(new(big.Int).SetUint64(log.BlockNumber)), true
+		}}, nil
+	case "event":
+		if len(q.byName) == 0 {
+			return nil, fmt.Errorf("field %q requires an ABI", name)
+		}
+		topic := 0
+		if q.anon != nil {
+			topic = -1
+		}
+		return &queryField{name: name, event: true, topic: topic, get: func(q *logQuery, log *types.Log) (common.Hash, bool) {
+			if ev := q.eventOf(log); ev != nil {
+				return ev.Id(), true
+			}
+			return common.Hash{}, false
+		}}, nil
+	case "topic0", "topic1", "topic2", "topic3":
+		pos := int(name[5] - '0')
+		return &queryField{name: name, typ: bytes32Type, topic: pos, get: func(q *logQuery, log *types.Log) (common.Hash, bool) {
+			if pos < len(log.Topics) {
+				return log.Topics[pos], true
+			}
+			return common.Hash{}, false
+		}}, nil
+	}
+	// Not a built-in field, look for an indexed argument of the same type in the events
+	var field *queryField
+	for _, ev := range q.byName {
+		for _, arg := range ev.Inputs {
+			if !arg.Indexed || arg.Name != name {
+				continue
+			}
+			if field != nil && field.typ.String() != arg.Type.String() {
+				return nil, fmt.Errorf("argument %q has different types in the events", name)
+			}
+			field = &queryField{name: name, typ: arg.Type, topic: -1}
+
+			if q.single {
+				field.topic = topicOf(ev, name)
+			}
+		}
+	}
+	if field == nil {
+		return nil, fmt.Errorf("unknown field %q", name)
+	}
+	field.get = func(q *logQuery, log *types.Log) (common.Hash, bool) {
+		ev := q.eventOf(log)
+		if ev == nil {
+			return common.Hash{}, false
+		}
+		if pos := topicOf(ev, name); pos >= 0 && pos < len(log.Topics) {
+			return log.Topics[pos], true
+		}
+		return common.Hash{}, false
+	}
+	return field, nil
+}
+
+// numeric reports whether a field can be ordered.
+func (f *queryField) numeric() bool {
+	return !f.event && (f.typ.T == abi.UintTy || f.typ.T == abi.IntTy)
+}
+
+// word converts a value to the topic it is compared with.
+func (f *queryField) word(q *logQuery, tok queryToken) (common.Hash, error) {
+	mismatch := fmt.Errorf("invalid value %s for %s at offset %d", tok.text, f.name, tok.pos)
+	if f.event {
+		name := tok.text
+		if tok.kind == tokString {
+			name = tok.value
+		} else if tok.kind != tokIdent {
+			return common.Hash{}, mismatch
+		}
+		ev := q.byName[name]
+		if ev == nil {
+			return common.Hash{}, fmt.Errorf("unknown event %q at offset %d", name, tok.pos)
+		}
+		return ev.Id(), nil
+	}
+	switch f.typ.T {
+	case abi.UintTy, abi.IntTy:
+		if tok.kind != tokNumber && tok.kind != tokHex {
+			return common.Hash{}, mismatch
+		}
+		n, ok := math.ParseBig256(tok.text)
+		if strings.HasPrefix(tok.text, "-") {
+			n, ok = math.ParseBig256(tok.text[1:])
+			if ok {
+				n.Neg(n)
+			}
+		}
+		if !ok || !fitsType(f.typ, n) {
+			return common.Hash{}, mismatch
+		}
+		return common.BigToHash(math.U256(n)), nil
+	case abi.BoolTy:
+		if tok.kind != tokIdent || (!strings.EqualFold(tok.text, "true") && !strings.EqualFold(tok.text, "false")) {
+			return common.Hash{}, mismatch
+		}
+		if strings.EqualFold(tok.text, "true") {
+			return common.BigToHash(common.Big1), nil
+		}
+		return common.Hash{}, nil
+	case abi.AddressTy:
+		if tok.kind != tokHex {
+			return common.Hash{}, mismatch
+		}
+		addr, err := decodeAddress(tok.text)
+		if err != nil {
+			return common.Hash{}, mismatch
+		}
+		return common.BytesToHash(addr[:]), nil
+	case abi.FixedBytesTy:
+		blob, err := hexutil.Decode(tok.text)
+		if tok.kind != tokHex || err != nil || len(blob) > f.typ.Size {
+			return common.Hash{}, mismatch
+		}
+		var word common.Hash
+		copy(word[:], blob)
+		return word, nil
+	case abi.StringTy:
+		if tok.kind != tokString {
+			return common.Hash{}, mismatch
+		}
+		return crypto.Keccak256Hash([]byte(tok.value)), nil
+	case abi.BytesTy:
+		blob, err := hexutil.Decode(tok.text)
+		if tok.kind != tokHex || err != nil {
+			return common.Hash{}, mismatch
+		}
+		return crypto.Keccak256Hash(blob), nil
+	default:
+		// Arrays and the like are only compared by their topic
+		if tok.kind != tokHex {
+			return common.Hash{}, mismatch
+		}
+		topic, err := decodeTopic(tok.text)
+		if err != nil {
+			return common.Hash{}, mismatch
+		}
+		return topic, nil
+	}
+}
+
+// fitsType reports whether a number is in the range of an integer type.
+func fitsType(typ abi.Type, n *big.Int) bool {
+	if typ.T == abi.UintTy {
+		return n.Sign() >= 0 && n.BitLen() <= typ.Size
+	}
+	limit := new(big.Int).Lsh(common.Big1, uint(typ.Size-1))
+	return n.Cmp(new(big.Int).Neg(limit)) >= 0 && n.Cmp(limit) < 0
+}
+
+// queryNode is a node of a query expression.
+type queryNode interface {
+	match(q *logQuery, log *types.Log) bool
+}
+
+type queryTrue struct{}
+
+func (queryTrue) match(q *logQuery, log *types.Log) bool { return true }
+
+type queryAnd struct{ left, right queryNode }
+
+func (n *queryAnd) match(q *logQuery, log *types.Log) bool {
+	return n.left.match(q, log) && n.right.match(q, log)
+}
+
+type queryOr struct{ left, right queryNode }
+
+func (n *queryOr) match(q *logQuery, log *types.Log) bool {
+	return n.left.match(q, log) || n.right.match(q, log)
+}
+
+type queryNot struct{ node queryNode }
+
+func (n *queryNot) match(q *logQuery, log *types.Log) bool {
+	return !n.node.match(q, log)
+}
+
+// queryCond compares a field with values: "=" and "!=" for the membership in
+// the values, the ordering operators with the single value.
+type queryCond struct {
+	field  *queryField
+	op     string
+	values []common.Hash
+}
+
+func (c *queryCond) match(q *logQuery, log *types.Log) bool {
+	word, ok := c.field.get(q, log)
+	if !ok {
+		return false
+	}
+	switch c.op {
+	case "=", "!=":
+		in := false
+		for _, value := range c.values {
+			if value == word {
+				in = true
+				break
+			}
+		}
+		return in == (c.op == "=")
+	}
+	cmp := wordNumber(c.field.typ, word).Cmp(wordNumber(c.field.typ, c.values[0]))
+	switch c.op {
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	default:
+		return cmp >= 0
+	}
+}
+
+// conjuncts collects the comparisons a node requires all of.
+func conjuncts(node queryNode, conds []*queryCond) []*queryCond {
+	switch n := node.(type) {
+	case *queryAnd:
+		return conjuncts(n.right, conjuncts(n.left, conds))
+	case *queryCond:
+		return append(conds, n)
+	}
+	return conds
+}
+
+const (
+	tokEOF = iota
+	tokIdent
+	tokNumber
+	tokHex
+	tokString
+	tokOp
+)
+
+// queryToken is a lexical token of a query expression.
+type queryToken struct {
+	kind  int
+	text  string // Source text of the token
+	value string // Unquoted value of a string
+	pos   int
+}
+
+// lexQuery splits a query expression into tokens.
+func lexQuery(src string) ([]queryToken, error) {
+	var tokens []queryToken
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case isIdentChar(c) && !isDigit(c):
+			start := i
+			for i < len(src) && isIdentChar(src[i]) {
+				i++
+			}
+			tokens = append(tokens, queryToken{kind: tokIdent, text: src[start:i], pos: start})
+		case isDigit(c) || (c == '-' && i+1 < len(src) && isDigit(src[i+1])):
+			start, kind := i, tokNumber
+			if c == '0' && i+1 < len(src) && (src[i+1] == 'x' || src[i+1] == 'X') {
+				i, kind = i+2, tokHex
+			} else {
+				i++
+			}
+			for i < len(src) && isIdentChar(src[i]) {
+				i++
+			}
+			tokens = append(tokens, queryToken{kind: kind, text: src[start:i], pos: start})
+		case c == '\'':
+			start := i
+			var value strings.Builder
+			for i++; ; i++ {
+				if i >= len(src) {
+					return nil, fmt.Errorf("unterminated string at offset %d", start)
+				}
+				if src[i] == '\'' {
+					// Quotes are escaped by doubling them
+					if i+1 < len(src) && src[i+1] == '\'' {
+						value.WriteByte('\'')
+						i++
+						continue
+					}
+					i++
+					break
+				}
+				value.WriteByte(src[i])
+			}
+			tokens = append(tokens, queryToken{kind: tokString, text: src[start:i], value: value.String(), pos: start})
+		case c == '(' || c == ')' || c == ',' || c == '=':
+			tokens = append(tokens, queryToken{kind: tokOp, text: src[i : i+1], pos: i})
+			i++
+		case c == '<' || c == '>' || c == '!':
+			start := i
+			i++
+			if i < len(src) && (src[i] == '=' || (c == '<' && src[i] == '>')) {
+				i++
+			}
+			if src[start:i] == "!" {
+				return nil, fmt.Errorf("unexpected %q at offset %d", "!", start)
+			}
+			tokens = append(tokens, queryToken{kind: tokOp, text: src[start:i], pos: start})
+		default:
+			return nil, fmt.Errorf("unexpected %q at offset %d", c, i)
+		}
+	}
+	return append(tokens, queryToken{kind: tokEOF, text: "end of query", pos: len(src)}), nil
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isIdentChar(c byte) bool {
+	return c == '_' || isDigit(c) || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// queryParser parses a query expression by recursive descent.
+type queryParser struct {
+	query  *logQuery
+	tokens []queryToken
+	next   int
+}
+
+func (p *queryParser) peek() queryToken {
+	return p.tokens[p.next]
+}
+
+func (p *queryParser) take() queryToken {
+	tok := p.tokens[p.next]
+	if tok.kind != tokEOF {
+		p.next++
+	}
+	return tok
+}
+
+// keyword reports whether the next token is the given keyword, consuming it if so.
+func (p *queryParser) keyword(word string) bool {
+	if tok := p.peek(); tok.kind == tokIdent && strings.EqualFold(tok.text, word) {
+		p.next++
+		return true
+	}
+	return false
+}
+
+// expect consumes the next token, failing unless it is the given operator.
+func (p *queryParser) expect(op string) error {
+	if tok := p.take(); tok.kind != tokOp || tok.text != op {
+		return fmt.Errorf("expected %q, found %q at offset %d", op, tok.text, tok.pos)
+	}
+	return nil
+}
+
+func (p *queryParser) parseOr() (queryNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("or") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &queryOr{left, right}
+	}
+	return left, nil
+}
+
+func (p *queryParser) parseAnd() (queryNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("and") {
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &queryAnd{left, right}
+	}
+	return left, nil
+}
+
+func (p *queryParser) parseUnary() (queryNode, error) {
+	if p.keyword("not") {
+		node, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &queryNot{node}, nil
+	}
+	if tok := p.peek(); tok.kind == tokOp && tok.text == "(" {
+		p.take()
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		return node, p.expect(")")
+	}
+	return p.parseComparison()
+}
+
+func (p *queryParser) parseComparison() (queryNode, error) {
+	tok := p.take()
+	if tok.kind != tokIdent {
+		return nil, fmt.Errorf("expected a field, found %q at offset %d", tok.text, tok.pos)
+	}
+	field, err := p.query.resolveField(tok.text)
+	if err != nil {
+		return nil, fmt.Errorf("%v at offset %d", err, tok.pos)
+	}
+	// Membership in a list of values
+	negated := p.keyword("not")
+	if p.keyword("in") {
+		cond := &queryCond{field: field, op: "="}
+		if negated {
+			cond.op = "!="
+		}
+		if err := p.expect("("); err != nil {
+			return nil, err
+		}
+		for {
+			word, err := field.word(p.query, p.take())
+			if err != nil {
+				return nil, err
+			}
+			cond.values = append(cond.values, word)
+			if next := p.peek(); next.kind == tokOp && next.text == "," {
+				p.take()
+				continue
+			}
+			return cond, p.expect(")")
+		}
+	}
+	if negated {
+		return nil, fmt.Errorf("expected IN at offset %d", p.peek().pos)
+	}
+	// Comparison with a single value
+	op := p.take()
+	switch op.text {
+	case "=", "!=", "<>":
+	case "<", "<=", ">", ">=":
+		if !field.numeric() {
+			return nil, fmt.Errorf("%s is not ordered, at offset %d", field.name, op.pos)
+		}
+	default:
+		return nil, fmt.Errorf("expected an operator, found %q at offset %d", op.text, op.pos)
+	}
+	if op.kind != tokOp {
+		return nil, fmt.Errorf("expected an operator, found %q at offset %d", op.text, op.pos)
+	}
+	word, err := field.word(p.query, p.take())
+	if err != nil {
+		return nil, err
+	}
+	cond := &queryCond{field: field, op: op.text, values: []common.Hash{word}}
+	if cond.op == "<>" {
+		cond.op = "!="
+	}
+	return cond, nil
+}
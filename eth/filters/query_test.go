@@ -0,0 +1,122 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package filters
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const queryTestABI = `[
+	{"type":"event","name":"Transfer","inputs":[{"name":"from","type":"address","indexed":true},{"name":"to","type":"address","indexed":true},{"name":"value","type":"uint256","indexed":false}]},
+	{"type":"event","name":"Rated","inputs":[{"name":"score","type":"int8","indexed":true},{"name":"tag","type":"string","indexed":true},{"name":"final","type":"bool","indexed":true}]}
+]`
+
+func TestLogQuery(t *testing.T) {
+	var (
+		token    = common.HexToAddress("0x1000000000000000000000000000000000000001")
+		other    = common.HexToAddress("0x1000000000000000000000000000000000000002")
+		alice    = common.HexToAddress("0xa11ce00000000000000000000000000000000000")
+		bob      = common.HexToAddress("0xb0b0000000000000000000000000000000000000")
+		transfer = crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+		rated    = crypto.Keccak256Hash([]byte("Rated(int8,string,bool)"))
+		word     = func(addr common.Address) common.Hash { return common.BytesToHash(addr[:]) }
+		score    = func(n int64) common.Hash { return common.BigToHash(math.U256(big.NewInt(n))) }
+	)
+	logs := []*types.Log{
+		{Address: token, BlockNumber: 1, Topics: []common.Hash{transfer, word(alice), word(bob)}},
+		{Address: token, BlockNumber: 2, Topics: []common.Hash{transfer, word(bob), word(alice)}},
+		{Address: other, BlockNumber: 3, Topics: []common.Hash{transfer, word(alice), word(alice)}},
+		{Address: other, BlockNumber: 4, Topics: []common.Hash{rated, score(-5), crypto.Keccak256Hash([]byte("it's")), common.BigToHash(common.Big1)}},
+		{Address: other, BlockNumber: 5, Topics: []common.Hash{rated, score(7), crypto.Keccak256Hash([]byte("ok")), {}}},
+		{Address: token, BlockNumber: 6, Topics: []common.Hash{common.HexToHash("0xff")}},
+	}
+	tests := []struct {
+		where string
+		want  []uint64 // Block numbers of the selected logs
+	}{
+		{"", []uint64{1, 2, 3, 4, 5, 6}},
+		{"event = 'Transfer'", []uint64{1, 2, 3}},
+		{"event = Rated AND final = TRUE", []uint64{4}},
+		{"from = 0xa11ce00000000000000000000000000000000000", []uint64{1, 3}},
+		{"address = 0x1000000000000000000000000000000000000001 and not event IN ('Transfer')", []uint64{6}},
+		{"to IN (0xa11ce00000000000000000000000000000000000, 0xb0b0000000000000000000000000000000000000) AND blockNumber >= 2", []uint64{2, 3}},
+		{"score < 0 OR tag = 'ok'", []uint64{4, 5}},
+		{"score > -10 and score <> 7", []uint64{4}},
+		{"tag = 'it''s'", []uint64{4}},
+		{"NOT (from = 0xa11ce00000000000000000000000000000000000 OR blockNumber > 3)", []uint64{2}},
+		{"from NOT IN (0xa11ce00000000000000000000000000000000000)", []uint64{2}},
+		{"topic0 = 0x00000000000000000000000000000000000000000000000000000000000000ff", []uint64{6}},
+	}
+	for _, test := range tests {
+		q, err := compileQuery([]byte(queryTestABI), test.where)
+		if err != nil {
+			t.Errorf("%q: failed to compile: %v", test.where, err)
+			continue
+		}
+		var have []uint64
+		for _, log := range logs {
+			if q.where.match(q, log) {
+				have = append(have, log.BlockNumber)
+			}
+		}
+		if !reflect.DeepEqual(have, test.want) {
+			t.Errorf("%q: selected blocks mismatch: have %v, want %v", test.where, have, test.want)
+		}
+	}
+	// Invalid expressions are rejected
+	for _, where := range []string{
+		"from = 0x01",
+		"from > 0xa11ce00000000000000000000000000000000000",
+		"score = 128",
+		"value = 1",
+		"event = 'Approval'",
+		"final = 1",
+		"blockNumber = 1 AND",
+		"(blockNumber = 1",
+		"blockNumber ! 1",
+		"tag = 'open",
+	} {
+		if _, err := compileQuery([]byte(queryTestABI), where); err == nil {
+			t.Errorf("%q: invalid expression accepted", where)
+		}
+	}
+	// The top-level equalities narrow the logs searched
+	q, _ := compileQuery([]byte(queryTestABI), "address IN (0x1000000000000000000000000000000000000001) AND event = 'Transfer' AND (to = 0xb0b0000000000000000000000000000000000000 OR blockNumber = 1)")
+	addresses, topics := q.prefilter()
+	if !reflect.DeepEqual(addresses, []common.Address{token}) {
+		t.Errorf("prefilter addresses mismatch: have %v, want %v", addresses, []common.Address{token})
+	}
+	if !reflect.DeepEqual(topics, [][]common.Hash{{transfer}}) {
+		t.Errorf("prefilter topics mismatch: have %v, want %v", topics, [][]common.Hash{{transfer}})
+	}
+	// The indexed arguments are decoded
+	row := q.row(logs[3])
+	if row.Event != "Rated" || row.Args["score"].(*hexutil.Big).ToInt().Int64() != -5 || row.Args["final"] != true {
+		t.Errorf("decoded log mismatch: have %s %v", row.Event, row.Args)
+	}
+	if row := q.row(logs[5]); row.Event != "" || len(row.Topics) != 1 {
+		t.Errorf("unknown event decoded: have %s %v", row.Event, row.Args)
+	}
+}
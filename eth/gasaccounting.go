@@ -0,0 +1,452 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+var (
+	// gasHeadKey is the database key of the last block accounted for.
+	gasHeadKey = []byte("gas-accounting-head")
+
+	gasSenderPrefix  = []byte("gas-accounting-sender-")  // gasSenderPrefix + index (uint64 big endian) -> sender address
+	gasUsagePrefix   = []byte("gas-accounting-usage-")   // gasUsagePrefix + address -> gas usage of the sender
+	gasJournalPrefix = []byte("gas-accounting-journal-") // gasJournalPrefix + num (uint64 big endian) -> usage changes of the block
+)
+
+// gasJournalDepth is the number of blocks the usage changes are kept for, to be
+// reverted if the blocks are reorganised out of the chain.
+const gasJournalDepth = 128
+
+var errGasExportDisabled = errors.New("gas accounting export directory not configured")
+
+// GasUsage is the cumulative gas used by the transactions of a sender.
+type GasUsage struct {
+	Sender              common.Address `json:"sender"`
+	GasUsed             hexutil.Uint64 `json:"gasUsed"`
+	Transactions        hexutil.Uint64 `json:"transactions"`
+	PrivateTransactions hexutil.Uint64 `json:"privateTransactions"`
+	LastBlock           hexutil.Uint64 `json:"lastBlock"` // Last block containing a transaction of the sender
+}
+
+// gasHead is the persisted position of the gas accounting.
+type gasHead struct {
+	Number  uint64      // Number of the last block accounted for
+	Hash    common.Hash // Hash of the last block accounted for
+	Senders uint64      // Number of senders ever accounted for
+}
+
+// gasDelta is the change of the usage of a sender by a block.
+type gasDelta struct {
+	Sender              common.Address
+	GasUsed             uint64
+	Transactions        uint64
+	PrivateTransactions uint64
+	PrevLastBlock       uint64 // Last block of the sender before the block
+}
+
+// gasJournalEntry holds the usage changes of a block, to revert them.
+type gasJournalEntry struct {
+	Hash   common.Hash
+	Parent common.Hash
+	Deltas []gasDelta
+}
+
+// gasAccountant meters the gas used by each sender in the canonical chain,
+// independently of the gas price, so that the cost of a network running with
+// zero gas price can be allocated to its users off-chain. The gas of private
+// transactions is the gas of their public marker transaction.
+//
+// Only the usages changed by a block are persisted, along with the changes
+// themselves, so the blocks reorganised out of the chain less than
+// gasJournalDepth blocks deep are reverted.
+type gasAccountant struct {
+	config    *params.ChainConfig
+	chain     *core.BlockChain
+	db        ethdb.Database
+	exportDir string // Directory the CSV exports are written to, export disabled if empty
+
+	lock    sync.RWMutex
+	head    gasHead
+	usages  map[common.Address]*GasUsage
+	senders uint64 // Number of persisted senders, only accessed by the updater
+}
+
+// newGasAccountant creates a gas accountant, resuming from its persisted
+// usages.
+func newGasAccountant(config *params.ChainConfig, chain *core.BlockChain, db ethdb.Database, exportDir string) *gasAccountant {
+	a := &gasAccountant{
+		config:    config,
+		chain:     chain,
+		db:        db,
+		exportDir: exportDir,
+		usages:    make(map[common.Address]*GasUsage),
+	}
+	blob, err := db.Get(gasHeadKey)
+	if err != nil {
+		return a
+	}
+	if err := rlp.DecodeBytes(blob, &a.head); err != nil {
+		log.Error("Invalid gas accounting head, starting over", "err", err)
+		a.head = gasHead{}
+		return a
+	}
+	for i := uint64(0); i < a.head.Senders; i++ {
+		blob, err := db.Get(gasSenderKey(i))
+		if err != nil {
+			log.Error("Missing gas accounting sender", "index", i, "err", err)
+			continue
+		}
+		sender := common.BytesToAddress(blob)
+		usage := &GasUsage{Sender: sender}
+		if blob, err := db.Get(gasUsageKey(sender)); err == nil {
+			if err := rlp.DecodeBytes(blob, usage); err != nil {
+				log.Error("Invalid gas accounting usage", "sender", sender, "err", err)
+			}
+		}
+		a.usages[sender] = usage
+	}
+	a.senders = a.head.Senders
+	return a
+}
+
+func gasSenderKey(index uint64) []byte {
+	key := make([]byte, len(gasSenderPrefix)+8)
+	copy(key, gasSenderPrefix)
+	binary.BigEndian.PutUint64(key[len(gasSenderPrefix):], index)
+	return key
+}
+
+func gasUsageKey(sender common.Address) []byte {
+	return append(append([]byte{}, gasUsagePrefix...), sender.Bytes()...)
+}
+
+func gasJournalKey(number uint64) []byte {
+	key := make([]byte, len(gasJournalPrefix)+8)
+	copy(key, gasJournalPrefix)
+	binary.BigEndian.PutUint64(key[len(gasJournalPrefix):], number)
+	return key
+}
+
+// loop accounts for the canonical blocks as the chain head moves, until quit is
+// closed.
+func (a *gasAccountant) loop(quit chan bool) {
+	heads := make(chan core.ChainHeadEvent, 16)
+	sub := a.chain.SubscribeChainHeadEvent(heads)
+	defer sub.Unsubscribe()
+
+	a.update(a.chain.CurrentBlock().NumberU64())
+	for {
+		select {
+		case head := <-heads:
+			a.update(head.Block.NumberU64())
+		case <-sub.Err():
+			return
+		case <-quit:
+			return
+		}
+	}
+}
+
+// update reverts the accounted blocks which left the canonical chain, then
+// accounts for all the canonical blocks up to the given number. The lock is
+// only held while a block changes the usages, not through a catch-up.
+func (a *gasAccountant) update(number uint64) {
+	batch := a.db.NewBatch()
+
+	// Revert the blocks reorganised out of the chain, or above a rewound head
+	for a.head.Number > 0 && rawdb.ReadCanonicalHash(a.db, a.head.Number) != a.head.Hash {
+		if !a.revert(batch) {
+			break
+		}
+	}
+	for a.head.Number < number {
+		next := a.head.Number + 1
+		hash := rawdb.ReadCanonicalHash(a.db, next)
+		block := rawdb.ReadBlock(a.db, hash, next)
+		if block == nil {
+			log.Error("Missing block for gas accounting", "number", next)
+			break
+		}
+		deltas, err := a.deltas(block, rawdb.ReadReceipts(a.db, hash, next))
+		if err != nil {
+			log.Error("Failed to account block gas", "number", next, "hash", hash, "err", err)
+			break
+		}
+		a.apply(batch, block, deltas)
+
+		if batch.ValueSize() >= ethdb.IdealBatchSize {
+			a.write(batch)
+			batch.Reset()
+		}
+	}
+	a.write(batch)
+}
+
+// deltas computes the usage changes of the transactions of a block.
+func (a *gasAccountant) deltas(block *types.Block, receipts types.Receipts) ([]gasDelta, error) {
+	txs := block.Transactions()
+	if len(txs) != len(receipts) {
+		return nil, fmt.Errorf("receipt count mismatch: have %d, want %d", len(receipts), len(txs))
+	}
+	var (
+		signer = types.MakeSigner(a.config, block.Number())
+		deltas []gasDelta
+		index  = make(map[common.Address]int)
+	)
+	for i, tx := range txs {
+		sender, err := types.Sender(signer, tx)
+		if err != nil {
+			return nil, err
+		}
+		pos, ok := index[sender]
+		if !ok {
+			// The updater is the only writer, reading the usages is safe
+			delta := gasDelta{Sender: sender}
+			if usage := a.usages[sender]; usage != nil {
+				delta.PrevLastBlock = uint64(usage.LastBlock)
+			}
+			pos, index[sender] = len(deltas), len(deltas)
+			deltas = append(deltas, delta)
+		}
+		deltas[pos].GasUsed += receipts[i].GasUsed
+		deltas[pos].Transactions++
+		if tx.IsPrivate() {
+			deltas[pos].PrivateTransactions++
+		}
+	}
+	return deltas, nil
+}
+
+// apply adds the usage changes of a block, journaling them and the changed
+// usages into the batch.
+func (a *gasAccountant) apply(batch ethdb.Batch, block *types.Block, deltas []gasDelta) {
+	number := block.NumberU64()
+
+	a.lock.Lock()
+	for _, delta := range deltas {
+		usage := a.usages[delta.Sender]
+		if usage == nil {
+			usage = &GasUsage{Sender: delta.Sender}
+			a.usages[delta.Sender] = usage
+			batch.Put(gasSenderKey(a.senders), delta.Sender.Bytes())
+			a.senders++
+		}
+		usage.GasUsed += hexutil.Uint64(delta.GasUsed)
+		usage.Transactions += hexutil.Uint64(delta.Transactions)
+		usage.PrivateTransactions += hexutil.Uint64(delta.PrivateTransactions)
+		usage.LastBlock = hexutil.Uint64(number)
+		a.putUsage(batch, usage)
+	}
+	a.head = gasHead{Number: number, Hash: block.Hash(), Senders: a.senders}
+	a.lock.Unlock()
+
+	if blob, err := rlp.EncodeToBytes(&gasJournalEntry{Hash: block.Hash(), Parent: block.ParentHash(), Deltas: deltas}); err != nil {
+		log.Error("Failed to encode gas accounting journal", "number", number, "err", err)
+	} else {
+		batch.Put(gasJournalKey(number), blob)
+	}
+	if number > gasJournalDepth {
+		batch.Delete(gasJournalKey(number - gasJournalDepth))
+	}
+}
+
+// revert undoes the usage changes of the last block accounted for, returning
+// false if they are no longer journaled.
+func (a *gasAccountant) revert(batch ethdb.Batch) bool {
+	// Flush the pending changes for the journal to be read back
+	a.write(batch)
+	batch.Reset()
+
+	var entry gasJournalEntry
+	blob, err := a.db.Get(gasJournalKey(a.head.Number))
+	if err == nil {
+		err = rlp.DecodeBytes(blob, &entry)
+	}
+	if err != nil || entry.Hash != a.head.Hash {
+		log.Error("Gas accounting reorg too deep to revert", "number", a.head.Number, "hash", a.head.Hash)
+		return false
+	}
+	a.lock.Lock()
+	for _, delta := range entry.Deltas {
+		usage := a.usages[delta.Sender]
+		if usage == nil {
+			continue
+		}
+		usage.GasUsed -= hexutil.Uint64(delta.GasUsed)
+		usage.Transactions -= hexutil.Uint64(delta.Transactions)
+		usage.PrivateTransactions -= hexutil.Uint64(delta.PrivateTransactions)
+		usage.LastBlock = hexutil.Uint64(delta.PrevLastBlock)
+		a.putUsage(batch, usage)
+	}
+	batch.Delete(gasJournalKey(a.head.Number))
+	a.head = gasHead{Number: a.head.Number - 1, Hash: entry.Parent, Senders: a.senders}
+	a.lock.Unlock()
+
+	return true
+}
+
+// putUsage stores the usage of a sender into the batch.
+func (a *gasAccountant) putUsage(batch ethdb.Batch, usage *GasUsage) {
+	blob, err := rlp.EncodeToBytes(usage)
+	if err != nil {
+		log.Error("Failed to encode gas accounting usage", "sender", usage.Sender, "err", err)
+		return
+	}
+	batch.Put(gasUsageKey(usage.Sender), blob)
+}
+
+// write persists the batch along with the head it leads to.
+func (a *gasAccountant) write(batch ethdb.Batch) {
+	if batch.ValueSize() == 0 {
+		return
+	}
+	a.lock.RLock()
+	head := a.head
+	a.lock.RUnlock()
+
+	blob, err := rlp.EncodeToBytes(&head)
+	if err != nil {
+		log.Error("Failed to encode gas accounting head", "err", err)
+		return
+	}
+	batch.Put(gasHeadKey, blob)
+	if err := batch.Write(); err != nil {
+		log.Error("Failed to store gas accounting", "err", err)
+	}
+}
+
+// sortedUsages returns copies of the usages ordered by sender, leaving out the
+// senders whose transactions were all reverted, assuming the lock is held.
+func (a *gasAccountant) sortedUsages() []*GasUsage {
+	usages := make([]*GasUsage, 0, len(a.usages))
+	for _, usage := range a.usages {
+		if usage.Transactions == 0 {
+			continue
+		}
+		cpy := *usage
+		usages = append(usages, &cpy)
+	}
+	sort.Slice(usages, func(i, j int) bool { return bytes.Compare(usages[i].Sender[:], usages[j].Sender[:]) < 0 })
+	return usages
+}
+
+// Usage returns the cumulative gas used by a sender.
+func (a *gasAccountant) Usage(sender common.Address) *GasUsage {
+	a.lock.RLock()
+	defer a.lock.RUnlock()
+
+	if usage := a.usages[sender]; usage != nil {
+		cpy := *usage
+		return &cpy
+	}
+	return &GasUsage{Sender: sender}
+}
+
+// Usages returns the cumulative gas used by all senders, and the number of the
+// last block accounted for.
+func (a *gasAccountant) Usages() ([]*GasUsage, uint64) {
+	a.lock.RLock()
+	defer a.lock.RUnlock()
+
+	return a.sortedUsages(), a.head.Number
+}
+
+// WriteCSV writes the gas used by all senders in CSV format, with a header row.
+func (a *gasAccountant) WriteCSV(w io.Writer) error {
+	usages, head := a.Usages()
+
+	out := csv.NewWriter(w)
+	out.Write([]string{"sender", "gasUsed", "transactions", "privateTransactions", "lastBlock", "head"})
+	for _, usage := range usages {
+		out.Write([]string{
+			usage.Sender.Hex(),
+			strconv.FormatUint(uint64(usage.GasUsed), 10),
+			strconv.FormatUint(uint64(usage.Transactions), 10),
+			strconv.FormatUint(uint64(usage.PrivateTransactions), 10),
+			strconv.FormatUint(uint64(usage.LastBlock), 10),
+			strconv.FormatUint(head, 10),
+		})
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// PrivateGasAccountingAPI exposes the gas accounting of the senders.
+type PrivateGasAccountingAPI struct {
+	accountant *gasAccountant
+}
+
+// NewPrivateGasAccountingAPI creates a new API definition for the gas
+// accounting methods of the Ethereum service.
+func NewPrivateGasAccountingAPI(accountant *gasAccountant) *PrivateGasAccountingAPI {
+	return &PrivateGasAccountingAPI{accountant: accountant}
+}
+
+// GasUsage returns the cumulative gas used by the transactions of a sender.
+func (api *PrivateGasAccountingAPI) GasUsage(sender common.Address) *GasUsage {
+	return api.accountant.Usage(sender)
+}
+
+// GasUsages returns the cumulative gas used by the transactions of all senders.
+func (api *PrivateGasAccountingAPI) GasUsages() []*GasUsage {
+	usages, _ := api.accountant.Usages()
+	return usages
+}
+
+// ExportCSV writes the cumulative gas used by all senders into a CSV file of the
+// configured export directory, the name holding no path.
+func (api *PrivateGasAccountingAPI) ExportCSV(name string) (bool, error) {
+	dir := api.accountant.exportDir
+	if dir == "" {
+		return false, errGasExportDisabled
+	}
+	if name == "" || name == "." || name == ".." || filepath.Base(name) != name {
+		return false, fmt.Errorf("invalid export file name %q", name)
+	}
+	out, err := os.OpenFile(filepath.Join(dir, name), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return false, err
+	}
+	defer out.Close()
+
+	if err := api.accountant.WriteCSV(out); err != nil {
+		return false, err
+	}
+	return true, nil
+}
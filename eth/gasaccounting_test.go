@@ -0,0 +1,145 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth/downloader"
+)
+
+// Tests that the gas used by zero gas price transactions is accounted to their
+// senders, and that the ledger survives a restart.
+func TestGasAccounting(t *testing.T) {
+	otherKey, _ := crypto.GenerateKey()
+	other := crypto.PubkeyToAddress(otherKey.PublicKey)
+
+	generator := func(i int, block *core.BlockGen) {
+		block.AddTx(newTestTransaction(testBankKey, uint64(i), 0))
+		if i == 2 {
+			block.AddTx(newTestTransaction(otherKey, 0, 0))
+		}
+	}
+	pm, db := newTestProtocolManagerMust(t, downloader.FullSync, 3, generator, nil)
+	defer pm.Stop()
+
+	accountant := newGasAccountant(pm.blockchain.Config(), pm.blockchain, db, "")
+	accountant.update(pm.blockchain.CurrentBlock().NumberU64())
+
+	if usage := accountant.Usage(testBank); usage.GasUsed != 3*21000 || usage.Transactions != 3 || usage.LastBlock != 3 {
+		t.Errorf("bank usage mismatch: have %+v", usage)
+	}
+	if usage := accountant.Usage(other); usage.GasUsed != 21000 || usage.Transactions != 1 || usage.LastBlock != 3 {
+		t.Errorf("other usage mismatch: have %+v", usage)
+	}
+	// Reload the ledger and check the export
+	accountant = newGasAccountant(pm.blockchain.Config(), pm.blockchain, db, "")
+	if usages, head := accountant.Usages(); len(usages) != 2 || head != 3 {
+		t.Fatalf("reloaded ledger mismatch: have %d usages at block %d, want 2 at 3", len(usages), head)
+	}
+	buf := new(bytes.Buffer)
+	if err := accountant.WriteCSV(buf); err != nil {
+		t.Fatalf("failed to export csv: %v", err)
+	}
+	want := "sender,gasUsed,transactions,privateTransactions,lastBlock,head\n"
+	if bytes.Compare(testBank[:], other[:]) < 0 {
+		want += fmt.Sprintf("%s,63000,3,0,3,3\n%s,21000,1,0,3,3\n", testBank.Hex(), other.Hex())
+	} else {
+		want += fmt.Sprintf("%s,21000,1,0,3,3\n%s,63000,3,0,3,3\n", other.Hex(), testBank.Hex())
+	}
+	if buf.String() != want {
+		t.Errorf("csv mismatch:\nhave %s\nwant %s", buf.String(), want)
+	}
+}
+
+// Tests that the gas used by the blocks reorganised out of the chain is
+// reverted, also across a restart.
+func TestGasAccountingReorg(t *testing.T) {
+	otherKey, _ := crypto.GenerateKey()
+	other := crypto.PubkeyToAddress(otherKey.PublicKey)
+
+	generator := func(i int, block *core.BlockGen) {
+		block.AddTx(newTestTransaction(testBankKey, uint64(i), 0))
+		if i == 2 {
+			block.AddTx(newTestTransaction(otherKey, 0, 0))
+		}
+	}
+	pm, db := newTestProtocolManagerMust(t, downloader.FullSync, 3, generator, nil)
+	defer pm.Stop()
+
+	accountant := newGasAccountant(pm.blockchain.Config(), pm.blockchain, db, "")
+	accountant.update(pm.blockchain.CurrentBlock().NumberU64())
+
+	// Replace the blocks 2 and 3 by a longer fork without transactions
+	fork, _ := core.GenerateChain(pm.blockchain.Config(), pm.blockchain.GetBlockByNumber(1), ethash.NewFaker(), db, 3, func(i int, block *core.BlockGen) {
+		block.SetCoinbase(common.Address{1})
+	})
+	if _, err := pm.blockchain.InsertChain(fork); err != nil {
+		t.Fatalf("failed to insert fork: %v", err)
+	}
+	accountant.update(pm.blockchain.CurrentBlock().NumberU64())
+
+	for i, accountant := range []*gasAccountant{accountant, newGasAccountant(pm.blockchain.Config(), pm.blockchain, db, "")} {
+		if usage := accountant.Usage(testBank); usage.GasUsed != 21000 || usage.Transactions != 1 || usage.LastBlock != 1 {
+			t.Errorf("accountant %d: bank usage mismatch: have %+v", i, usage)
+		}
+		if usage := accountant.Usage(other); usage.Transactions != 0 || usage.LastBlock != 0 {
+			t.Errorf("accountant %d: other usage mismatch: have %+v", i, usage)
+		}
+		if usages, head := accountant.Usages(); len(usages) != 1 || head != 4 {
+			t.Errorf("accountant %d: ledger mismatch: have %d usages at block %d, want 1 at 4", i, len(usages), head)
+		}
+	}
+}
+
+// Tests that the CSV exports are confined to the configured directory.
+func TestGasAccountingExport(t *testing.T) {
+	pm, db := newTestProtocolManagerMust(t, downloader.FullSync, 0, nil, nil)
+	defer pm.Stop()
+
+	api := NewPrivateGasAccountingAPI(newGasAccountant(pm.blockchain.Config(), pm.blockchain, db, ""))
+	if _, err := api.ExportCSV("usage.csv"); err != errGasExportDisabled {
+		t.Errorf("export without directory: have %v, want %v", err, errGasExportDisabled)
+	}
+	dir, err := ioutil.TempDir("", "gasaccounting")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	api = NewPrivateGasAccountingAPI(newGasAccountant(pm.blockchain.Config(), pm.blockchain, db, dir))
+	for _, name := range []string{"", "..", "../usage.csv", filepath.Join(dir, "usage.csv")} {
+		if _, err := api.ExportCSV(name); err == nil {
+			t.Errorf("export to %q accepted", name)
+		}
+	}
+	if _, err := api.ExportCSV("usage.csv"); err != nil {
+		t.Fatalf("failed to export: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "usage.csv")); err != nil {
+		t.Errorf("export not written: %v", err)
+	}
+}
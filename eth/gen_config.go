@@ -13,6 +13,7 @@ import (
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/eth/downloader"
 	"github.com/ethereum/go-ethereum/eth/gasprice"
+	"github.com/ethereum/go-ethereum/params"
 )
 
 var _ = (*configMarshaling)(nil)
@@ -24,13 +25,22 @@ func (c Config) MarshalTOML() (interface{}, error) {
 		NetworkId               uint64
 		SyncMode                downloader.SyncMode
 		NoPruning               bool
-		LightServ               int  `toml:",omitempty"`
-		LightPeers              int  `toml:",omitempty"`
-		SkipBcVersionCheck      bool `toml:"-"`
-		DatabaseHandles         int  `toml:"-"`
+		LightServ               int                       `toml:",omitempty"`
+		LightPeers              int                       `toml:",omitempty"`
+		Checkpoint              *params.TrustedCheckpoint `toml:",omitempty"`
+		SkipBcVersionCheck      bool                      `toml:"-"`
+		DatabaseHandles         int                       `toml:"-"`
 		DatabaseCache           int
 		TrieCache               int
 		TrieTimeout             time.Duration
+		Snapshot                bool           `toml:",omitempty"`
+		LogIndex                bool           `toml:",omitempty"`
+		StateSize               bool           `toml:",omitempty"`
+		ImportPipeline          int            `toml:",omitempty"`
+		RPCCacheSize            int            `toml:",omitempty"`
+		StateRangeRate          int            `toml:",omitempty"`
+		RPCCallWorkers          int            `toml:",omitempty"`
+		RPCCallCPUQuota         time.Duration  `toml:",omitempty"`
 		Etherbase               common.Address `toml:",omitempty"`
 		MinerNotify             []string       `toml:",omitempty"`
 		MinerExtraData          hexutil.Bytes  `toml:",omitempty"`
@@ -39,12 +49,43 @@ func (c Config) MarshalTOML() (interface{}, error) {
 		MinerGasPrice           *big.Int
 		MinerRecommit           time.Duration
 		MinerNoverify           bool
+		MinerBuilder            string        `toml:",omitempty"`
+		MinerBuilderBudget      time.Duration `toml:",omitempty"`
 		Ethash                  ethash.Config
 		TxPool                  core.TxPoolConfig
 		GPO                     gasprice.Config
 		EnablePreimageRecording bool
+		PreimageMaxSize         int    `toml:",omitempty"`
+		PreimageRetention       uint64 `toml:",omitempty"`
+		GasAccounting           bool   `toml:",omitempty"`
+		GasAccountingExportDir  string `toml:",omitempty"`
+		LatencyAwarePropagation bool   `toml:",omitempty"`
+		Gossip                  GossipConfig
+		PrivateGossip           PrivateGossipConfig
+		Health                  HealthConfig
 		Istanbul                istanbul.Config
-		DocRoot                 string `toml:"-"`
+		IstanbulCheckpointSink  string              `toml:",omitempty"`
+		Webhooks                []WebhookConfig     `toml:",omitempty"`
+		IntegritySamples        int                 `toml:",omitempty"`
+		DryRunConfig            *params.ChainConfig `toml:",omitempty"`
+		SQLExport               string              `toml:",omitempty"`
+		SQLExportPrivate        bool                `toml:",omitempty"`
+		PTMPushAddr             string              `toml:",omitempty"`
+		PTMPushSecret           string              `toml:",omitempty"`
+		FinalityConfirmations   uint64              `toml:",omitempty"`
+		PTMOffloadStore         string              `toml:",omitempty"`
+		PTMOffloadThreshold     int                 `toml:",omitempty"`
+		PTMCacheSize            int                 `toml:",omitempty"`
+		PTMCacheTTL             time.Duration       `toml:",omitempty"`
+		PTMCacheEncrypt         bool                `toml:",omitempty"`
+		HistoryRetention        uint64              `toml:",omitempty"`
+		HistoryProtected        []common.Address    `toml:",omitempty"`
+		StandbyRole             string              `toml:",omitempty"`
+		StandbyPeer             string              `toml:",omitempty"`
+		StandbyEscrowKey        string              `toml:",omitempty"`
+		StandbyLease            time.Duration       `toml:",omitempty"`
+		StandbyPasswords        []string            `toml:"-" json:"-"`
+		DocRoot                 string              `toml:"-"`
 	}
 	var enc Config
 	enc.Genesis = c.Genesis
@@ -53,11 +94,20 @@ func (c Config) MarshalTOML() (interface{}, error) {
 	enc.NoPruning = c.NoPruning
 	enc.LightServ = c.LightServ
 	enc.LightPeers = c.LightPeers
+	enc.Checkpoint = c.Checkpoint
 	enc.SkipBcVersionCheck = c.SkipBcVersionCheck
 	enc.DatabaseHandles = c.DatabaseHandles
 	enc.DatabaseCache = c.DatabaseCache
 	enc.TrieCache = c.TrieCache
 	enc.TrieTimeout = c.TrieTimeout
+	enc.Snapshot = c.Snapshot
+	enc.LogIndex = c.LogIndex
+	enc.StateSize = c.StateSize
+	enc.ImportPipeline = c.ImportPipeline
+	enc.RPCCacheSize = c.RPCCacheSize
+	enc.StateRangeRate = c.StateRangeRate
+	enc.RPCCallWorkers = c.RPCCallWorkers
+	enc.RPCCallCPUQuota = c.RPCCallCPUQuota
 	enc.Etherbase = c.Etherbase
 	enc.MinerNotify = c.MinerNotify
 	enc.MinerExtraData = c.MinerExtraData
@@ -66,11 +116,42 @@ func (c Config) MarshalTOML() (interface{}, error) {
 	enc.MinerGasPrice = c.MinerGasPrice
 	enc.MinerRecommit = c.MinerRecommit
 	enc.MinerNoverify = c.MinerNoverify
+	enc.MinerBuilder = c.MinerBuilder
+	enc.MinerBuilderBudget = c.MinerBuilderBudget
 	enc.Ethash = c.Ethash
 	enc.TxPool = c.TxPool
 	enc.GPO = c.GPO
 	enc.EnablePreimageRecording = c.EnablePreimageRecording
+	enc.PreimageMaxSize = c.PreimageMaxSize
+	enc.PreimageRetention = c.PreimageRetention
+	enc.GasAccounting = c.GasAccounting
+	enc.GasAccountingExportDir = c.GasAccountingExportDir
+	enc.LatencyAwarePropagation = c.LatencyAwarePropagation
+	enc.Gossip = c.Gossip
+	enc.PrivateGossip = c.PrivateGossip
+	enc.Health = c.Health
 	enc.Istanbul = c.Istanbul
+	enc.IstanbulCheckpointSink = c.IstanbulCheckpointSink
+	enc.Webhooks = c.Webhooks
+	enc.IntegritySamples = c.IntegritySamples
+	enc.DryRunConfig = c.DryRunConfig
+	enc.SQLExport = c.SQLExport
+	enc.SQLExportPrivate = c.SQLExportPrivate
+	enc.PTMPushAddr = c.PTMPushAddr
+	enc.PTMPushSecret = c.PTMPushSecret
+	enc.FinalityConfirmations = c.FinalityConfirmations
+	enc.PTMOffloadStore = c.PTMOffloadStore
+	enc.PTMOffloadThreshold = c.PTMOffloadThreshold
+	enc.PTMCacheSize = c.PTMCacheSize
+	enc.PTMCacheTTL = c.PTMCacheTTL
+	enc.PTMCacheEncrypt = c.PTMCacheEncrypt
+	enc.HistoryRetention = c.HistoryRetention
+	enc.HistoryProtected = c.HistoryProtected
+	enc.StandbyRole = c.StandbyRole
+	enc.StandbyPeer = c.StandbyPeer
+	enc.StandbyEscrowKey = c.StandbyEscrowKey
+	enc.StandbyLease = c.StandbyLease
+	enc.StandbyPasswords = c.StandbyPasswords
 	enc.DocRoot = c.DocRoot
 	return &enc, nil
 }
@@ -82,13 +163,22 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 		NetworkId               *uint64
 		SyncMode                *downloader.SyncMode
 		NoPruning               *bool
-		LightServ               *int  `toml:",omitempty"`
-		LightPeers              *int  `toml:",omitempty"`
-		SkipBcVersionCheck      *bool `toml:"-"`
-		DatabaseHandles         *int  `toml:"-"`
+		LightServ               *int                      `toml:",omitempty"`
+		LightPeers              *int                      `toml:",omitempty"`
+		Checkpoint              *params.TrustedCheckpoint `toml:",omitempty"`
+		SkipBcVersionCheck      *bool                     `toml:"-"`
+		DatabaseHandles         *int                      `toml:"-"`
 		DatabaseCache           *int
 		TrieCache               *int
 		TrieTimeout             *time.Duration
+		Snapshot                *bool           `toml:",omitempty"`
+		LogIndex                *bool           `toml:",omitempty"`
+		StateSize               *bool           `toml:",omitempty"`
+		ImportPipeline          *int            `toml:",omitempty"`
+		RPCCacheSize            *int            `toml:",omitempty"`
+		StateRangeRate          *int            `toml:",omitempty"`
+		RPCCallWorkers          *int            `toml:",omitempty"`
+		RPCCallCPUQuota         *time.Duration  `toml:",omitempty"`
 		Etherbase               *common.Address `toml:",omitempty"`
 		MinerNotify             []string        `toml:",omitempty"`
 		MinerExtraData          *hexutil.Bytes  `toml:",omitempty"`
@@ -97,12 +187,43 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 		MinerGasPrice           *big.Int
 		MinerRecommit           *time.Duration
 		MinerNoverify           *bool
+		MinerBuilder            *string        `toml:",omitempty"`
+		MinerBuilderBudget      *time.Duration `toml:",omitempty"`
 		Ethash                  *ethash.Config
 		TxPool                  *core.TxPoolConfig
 		GPO                     *gasprice.Config
 		EnablePreimageRecording *bool
+		PreimageMaxSize         *int    `toml:",omitempty"`
+		PreimageRetention       *uint64 `toml:",omitempty"`
+		GasAccounting           *bool   `toml:",omitempty"`
+		GasAccountingExportDir  *string `toml:",omitempty"`
+		LatencyAwarePropagation *bool   `toml:",omitempty"`
+		Gossip                  *GossipConfig
+		PrivateGossip           *PrivateGossipConfig
+		Health                  *HealthConfig
 		Istanbul                *istanbul.Config
-		DocRoot                 *string `toml:"-"`
+		IstanbulCheckpointSink  *string             `toml:",omitempty"`
+		Webhooks                []WebhookConfig     `toml:",omitempty"`
+		IntegritySamples        *int                `toml:",omitempty"`
+		DryRunConfig            *params.ChainConfig `toml:",omitempty"`
+		SQLExport               *string             `toml:",omitempty"`
+		SQLExportPrivate        *bool               `toml:",omitempty"`
+		PTMPushAddr             *string             `toml:",omitempty"`
+		PTMPushSecret           *string             `toml:",omitempty"`
+		FinalityConfirmations   *uint64             `toml:",omitempty"`
+		PTMOffloadStore         *string             `toml:",omitempty"`
+		PTMOffloadThreshold     *int                `toml:",omitempty"`
+		PTMCacheSize            *int                `toml:",omitempty"`
+		PTMCacheTTL             *time.Duration      `toml:",omitempty"`
+		PTMCacheEncrypt         *bool               `toml:",omitempty"`
+		HistoryRetention        *uint64             `toml:",omitempty"`
+		HistoryProtected        []common.Address    `toml:",omitempty"`
+		StandbyRole             *string             `toml:",omitempty"`
+		StandbyPeer             *string             `toml:",omitempty"`
+		StandbyEscrowKey        *string             `toml:",omitempty"`
+		StandbyLease            *time.Duration      `toml:",omitempty"`
+		StandbyPasswords        []string            `toml:"-" json:"-"`
+		DocRoot                 *string             `toml:"-"`
 	}
 	var dec Config
 	if err := unmarshal(&dec); err != nil {
@@ -126,6 +247,9 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.LightPeers != nil {
 		c.LightPeers = *dec.LightPeers
 	}
+	if dec.Checkpoint != nil {
+		c.Checkpoint = dec.Checkpoint
+	}
 	if dec.SkipBcVersionCheck != nil {
 		c.SkipBcVersionCheck = *dec.SkipBcVersionCheck
 	}
@@ -141,6 +265,30 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.TrieTimeout != nil {
 		c.TrieTimeout = *dec.TrieTimeout
 	}
+	if dec.Snapshot != nil {
+		c.Snapshot = *dec.Snapshot
+	}
+	if dec.LogIndex != nil {
+		c.LogIndex = *dec.LogIndex
+	}
+	if dec.StateSize != nil {
+		c.StateSize = *dec.StateSize
+	}
+	if dec.ImportPipeline != nil {
+		c.ImportPipeline = *dec.ImportPipeline
+	}
+	if dec.RPCCacheSize != nil {
+		c.RPCCacheSize = *dec.RPCCacheSize
+	}
+	if dec.StateRangeRate != nil {
+		c.StateRangeRate = *dec.StateRangeRate
+	}
+	if dec.RPCCallWorkers != nil {
+		c.RPCCallWorkers = *dec.RPCCallWorkers
+	}
+	if dec.RPCCallCPUQuota != nil {
+		c.RPCCallCPUQuota = *dec.RPCCallCPUQuota
+	}
 	if dec.Etherbase != nil {
 		c.Etherbase = *dec.Etherbase
 	}
@@ -165,6 +313,12 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.MinerNoverify != nil {
 		c.MinerNoverify = *dec.MinerNoverify
 	}
+	if dec.MinerBuilder != nil {
+		c.MinerBuilder = *dec.MinerBuilder
+	}
+	if dec.MinerBuilderBudget != nil {
+		c.MinerBuilderBudget = *dec.MinerBuilderBudget
+	}
 	if dec.Ethash != nil {
 		c.Ethash = *dec.Ethash
 	}
@@ -177,9 +331,96 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.EnablePreimageRecording != nil {
 		c.EnablePreimageRecording = *dec.EnablePreimageRecording
 	}
+	if dec.PreimageMaxSize != nil {
+		c.PreimageMaxSize = *dec.PreimageMaxSize
+	}
+	if dec.PreimageRetention != nil {
+		c.PreimageRetention = *dec.PreimageRetention
+	}
+	if dec.GasAccounting != nil {
+		c.GasAccounting = *dec.GasAccounting
+	}
+	if dec.GasAccountingExportDir != nil {
+		c.GasAccountingExportDir = *dec.GasAccountingExportDir
+	}
+	if dec.LatencyAwarePropagation != nil {
+		c.LatencyAwarePropagation = *dec.LatencyAwarePropagation
+	}
+	if dec.Gossip != nil {
+		c.Gossip = *dec.Gossip
+	}
+	if dec.PrivateGossip != nil {
+		c.PrivateGossip = *dec.PrivateGossip
+	}
+	if dec.Health != nil {
+		c.Health = *dec.Health
+	}
 	if dec.Istanbul != nil {
 		c.Istanbul = *dec.Istanbul
 	}
+	if dec.IstanbulCheckpointSink != nil {
+		c.IstanbulCheckpointSink = *dec.IstanbulCheckpointSink
+	}
+	if dec.Webhooks != nil {
+		c.Webhooks = dec.Webhooks
+	}
+	if dec.IntegritySamples != nil {
+		c.IntegritySamples = *dec.IntegritySamples
+	}
+	if dec.DryRunConfig != nil {
+		c.DryRunConfig = dec.DryRunConfig
+	}
+	if dec.SQLExport != nil {
+		c.SQLExport = *dec.SQLExport
+	}
+	if dec.SQLExportPrivate != nil {
+		c.SQLExportPrivate = *dec.SQLExportPrivate
+	}
+	if dec.PTMPushAddr != nil {
+		c.PTMPushAddr = *dec.PTMPushAddr
+	}
+	if dec.PTMPushSecret != nil {
+		c.PTMPushSecret = *dec.PTMPushSecret
+	}
+	if dec.FinalityConfirmations != nil {
+		c.FinalityConfirmations = *dec.FinalityConfirmations
+	}
+	if dec.PTMOffloadStore != nil {
+		c.PTMOffloadStore = *dec.PTMOffloadStore
+	}
+	if dec.PTMOffloadThreshold != nil {
+		c.PTMOffloadThreshold = *dec.PTMOffloadThreshold
+	}
+	if dec.PTMCacheSize != nil {
+		c.PTMCacheSize = *dec.PTMCacheSize
+	}
+	if dec.PTMCacheTTL != nil {
+		c.PTMCacheTTL = *dec.PTMCacheTTL
+	}
+	if dec.PTMCacheEncrypt != nil {
+		c.PTMCacheEncrypt = *dec.PTMCacheEncrypt
+	}
+	if dec.HistoryRetention != nil {
+		c.HistoryRetention = *dec.HistoryRetention
+	}
+	if dec.HistoryProtected != nil {
+		c.HistoryProtected = dec.HistoryProtected
+	}
+	if dec.StandbyRole != nil {
+		c.StandbyRole = *dec.StandbyRole
+	}
+	if dec.StandbyPeer != nil {
+		c.StandbyPeer = *dec.StandbyPeer
+	}
+	if dec.StandbyEscrowKey != nil {
+		c.StandbyEscrowKey = *dec.StandbyEscrowKey
+	}
+	if dec.StandbyLease != nil {
+		c.StandbyLease = *dec.StandbyLease
+	}
+	if dec.StandbyPasswords != nil {
+		c.StandbyPasswords = dec.StandbyPasswords
+	}
 	if dec.DocRoot != nil {
 		c.DocRoot = *dec.DocRoot
 	}
@@ -0,0 +1,392 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+)
+
+// The gossip protocol cuts the duplicate traffic of densely connected networks,
+// where flooding sends every transaction to a node by each of its peers. New
+// items of a topic are pushed in full to a few random peers only, the fanout,
+// and the other peers are sent IHAVE announcements of their hashes, to which
+// they answer with IWANT requests for the items the pushes didn't deliver.
+//
+// Blocks already have announcements in eth, recovered by the fetcher, so their
+// topic only limits the full propagation to the fanout, and the protocol carries
+// the transaction topic. Every peer still ends up with all the transactions, as
+// required by Raft.
+const (
+	gossipProtocolName    = "gossip"
+	gossipProtocolVersion = 1
+	gossipProtocolLength  = 2
+
+	IHaveMsg = 0x00
+	IWantMsg = 0x01
+)
+
+// Gossip topics, each with its own fanout.
+const (
+	GossipTopicBlocks = "blocks"
+	GossipTopicTxs    = "txs"
+)
+
+const (
+	gossipMaxHashes     = 4096                   // Maximum number of hashes in an announcement or request
+	gossipMaxAnnounced  = 65536                  // Maximum number of announced transactions awaited
+	gossipMaxAnnouncers = 8                      // Maximum number of peers a transaction is requested from
+	gossipMaxQueued     = 128                    // Maximum number of messages queued to a peer
+	gossipWantInterval  = 100 * time.Millisecond // Time between two checks of the announced transactions
+	gossipWantTimeout   = 2 * time.Second        // Time a requested transaction is awaited from a peer
+)
+
+var errInvalidGossipFanout = errors.New("gossip fanout must be positive")
+
+// GossipConfig are the settings of the topic-based propagation.
+type GossipConfig struct {
+	Enabled     bool          // Whether to gossip the topics instead of flooding the peers
+	BlockFanout int           // Number of peers the new blocks are pushed to
+	TxFanout    int           // Number of peers the new transactions are pushed to
+	WantDelay   time.Duration // Time an announced transaction is awaited from the pushes before requesting it
+}
+
+// DefaultGossipConfig contains the default gossip settings.
+var DefaultGossipConfig = GossipConfig{
+	BlockFanout: 4,
+	TxFanout:    4,
+	WantDelay:   500 * time.Millisecond,
+}
+
+// fanout returns the number of peers the items of a topic are pushed to.
+func (c *GossipConfig) fanout(topic string) int {
+	if topic == GossipTopicBlocks {
+		return c.BlockFanout
+	}
+	return c.TxFanout
+}
+
+// gossipTxPool is the transaction pool the announced transactions are looked
+// up in.
+type gossipTxPool interface {
+	txPool
+
+	// Get should return the pooled transaction with the given hash, or nil.
+	Get(hash common.Hash) *types.Transaction
+}
+
+// gossipMsg is a message queued to a gossip peer.
+type gossipMsg struct {
+	code   uint64
+	hashes []common.Hash
+}
+
+// gossipPeer is a connected peer speaking the gossip protocol.
+type gossipPeer struct {
+	id     string
+	rw     p2p.MsgReadWriter
+	queued chan gossipMsg
+	term   chan struct{}
+}
+
+// send queues a message to the peer, dropping it if the queue is full.
+func (p *gossipPeer) send(code uint64, hashes []common.Hash) {
+	for len(hashes) > 0 {
+		n := len(hashes)
+		if n > gossipMaxHashes {
+			n = gossipMaxHashes
+		}
+		select {
+		case p.queued <- gossipMsg{code, hashes[:n]}:
+		default:
+			log.Debug("Dropping gossip message", "peer", p.id, "code", code, "count", n)
+		}
+		hashes = hashes[n:]
+	}
+}
+
+// broadcast writes the queued messages to the peer until it disconnects.
+func (p *gossipPeer) broadcast() {
+	for {
+		select {
+		case msg := <-p.queued:
+			if err := p2p.Send(p.rw, msg.code, msg.hashes); err != nil {
+				return
+			}
+		case <-p.term:
+			return
+		}
+	}
+}
+
+// txAnnouncement tracks a transaction announced by peers but not received yet.
+type txAnnouncement struct {
+	peers []string  // Announcing peers not requested yet, in announcement order
+	due   time.Time // Time to request the transaction from the next peer
+}
+
+// gossipRouter pushes the new items of each topic to the fanout, announces them
+// to the other peers, and requests the announced transactions missed by the
+// pushes.
+type gossipRouter struct {
+	config  GossipConfig
+	txpool  gossipTxPool
+	peers   *peerSet       // Peers of the eth protocol, which the items are pushed through
+	private *privateGossip // Restricts the peers the private transactions are served to, nil if unrestricted
+
+	lock      sync.Mutex
+	gossipers map[string]*gossipPeer
+	announced map[common.Hash]*txAnnouncement
+}
+
+func newGossipRouter(config GossipConfig, txpool gossipTxPool, peers *peerSet) (*gossipRouter, error) {
+	if config.BlockFanout < 1 || config.TxFanout < 1 {
+		return nil, errInvalidGossipFanout
+	}
+	return &gossipRouter{
+		config:    config,
+		txpool:    txpool,
+		peers:     peers,
+		gossipers: make(map[string]*gossipPeer),
+		announced: make(map[common.Hash]*txAnnouncement),
+	}, nil
+}
+
+// Protocol returns the gossip devp2p sub-protocol.
+func (r *gossipRouter) Protocol() p2p.Protocol {
+	return p2p.Protocol{
+		Name:    gossipProtocolName,
+		Version: gossipProtocolVersion,
+		Length:  gossipProtocolLength,
+		Run: func(p *p2p.Peer, rw p2p.MsgReadWriter) error {
+			return r.handle(p.ID(), rw)
+		},
+	}
+}
+
+// handle registers a gossip peer and serves its messages until disconnection.
+func (r *gossipRouter) handle(id enode.ID, rw p2p.MsgReadWriter) error {
+	peer := &gossipPeer{
+		id:     fmt.Sprintf("%x", id[:8]),
+		rw:     rw,
+		queued: make(chan gossipMsg, gossipMaxQueued),
+		term:   make(chan struct{}),
+	}
+	r.lock.Lock()
+	r.gossipers[peer.id] = peer
+	r.lock.Unlock()
+
+	defer func() {
+		r.lock.Lock()
+		delete(r.gossipers, peer.id)
+		r.lock.Unlock()
+		close(peer.term)
+	}()
+	go peer.broadcast()
+
+	for {
+		msg, err := rw.ReadMsg()
+		if err != nil {
+			return err
+		}
+		if msg.Size > ProtocolMaxMsgSize {
+			msg.Discard()
+			return errResp(ErrMsgTooLarge, "%v > %v", msg.Size, ProtocolMaxMsgSize)
+		}
+		var hashes []common.Hash
+		switch msg.Code {
+		case IHaveMsg:
+			if err := msg.Decode(&hashes); err != nil {
+				return errResp(ErrDecode, "msg %v: %v", msg, err)
+			}
+			r.announce(peer.id, hashes, time.Now())
+
+		case IWantMsg:
+			if err := msg.Decode(&hashes); err != nil {
+				return errResp(ErrDecode, "msg %v: %v", msg, err)
+			}
+			r.serve(peer.id, hashes)
+
+		default:
+			return errResp(ErrInvalidMsgCode, "%v", msg.Code)
+		}
+	}
+}
+
+// announce records the transactions announced by a peer, to request the ones
+// still missing once the delay given to the pushes elapsed.
+func (r *gossipRouter) announce(peer string, hashes []common.Hash, now time.Time) {
+	gossipHaveInMeter.Mark(int64(len(hashes)))
+
+	p := r.peers.Peer(peer)
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	for _, hash := range hashes {
+		if p != nil {
+			p.MarkTransaction(hash)
+		}
+		if r.txpool.Get(hash) != nil {
+			continue
+		}
+		ann := r.announced[hash]
+		if ann == nil {
+			if len(r.announced) >= gossipMaxAnnounced {
+				continue
+			}
+			ann = &txAnnouncement{due: now.Add(r.config.WantDelay)}
+			r.announced[hash] = ann
+		}
+		if len(ann.peers) < gossipMaxAnnouncers {
+			ann.peers = append(ann.peers, peer)
+		}
+	}
+}
+
+// serve pushes the requested transactions still pooled to a peer.
+func (r *gossipRouter) serve(peer string, hashes []common.Hash) {
+	p := r.peers.Peer(peer)
+	if p == nil {
+		return
+	}
+	var txs types.Transactions
+	for _, hash := range hashes {
+		if tx := r.txpool.Get(hash); tx != nil {
+			txs = append(txs, tx)
+		}
+	}
+	if r.private != nil {
+		txs = r.private.filter(p, txs)
+	}
+	if len(txs) > 0 {
+		gossipWantServedMeter.Mark(int64(len(txs)))
+		p.AsyncSendTransactions(txs)
+	}
+}
+
+// received drops the announcements of transactions delivered by a peer, which
+// must be called before adding them to the pool to meter the duplicates.
+func (r *gossipRouter) received(txs []*types.Transaction) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	for _, tx := range txs {
+		hash := tx.Hash()
+		if r.txpool.Get(hash) != nil {
+			gossipDuplicateMeter.Mark(1)
+		}
+		delete(r.announced, hash)
+	}
+}
+
+// request asks the announcing peers for the transactions still missing once
+// due, moving on to the next announcer if a request times out.
+func (r *gossipRouter) request(now time.Time) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	wants := make(map[*gossipPeer][]common.Hash)
+	for hash, ann := range r.announced {
+		if r.txpool.Get(hash) != nil {
+			delete(r.announced, hash)
+			continue
+		}
+		if now.Before(ann.due) {
+			continue
+		}
+		var target *gossipPeer
+		for target == nil && len(ann.peers) > 0 {
+			target, ann.peers = r.gossipers[ann.peers[0]], ann.peers[1:]
+		}
+		if target == nil {
+			delete(r.announced, hash)
+			continue
+		}
+		wants[target] = append(wants[target], hash)
+		ann.due = now.Add(gossipWantTimeout)
+	}
+	for peer, hashes := range wants {
+		gossipWantOutMeter.Mark(int64(len(hashes)))
+		peer.send(IWantMsg, hashes)
+	}
+}
+
+// broadcastTxs pushes each transaction to the fanout of the peers not knowing
+// about it and announces it to the others. Peers not speaking the gossip
+// protocol can't request the announced transactions, so all of them are pushed.
+func (r *gossipRouter) broadcastTxs(txs types.Transactions) {
+	var (
+		pushes = make(map[*peer]types.Transactions)
+		haves  = make(map[*gossipPeer][]common.Hash)
+	)
+	r.lock.Lock()
+	for _, tx := range txs {
+		hash := tx.Hash()
+		peers := r.peers.PeersWithoutTx(hash)
+
+		pushed := 0
+		for _, i := range rand.Perm(len(peers)) {
+			p := peers[i]
+			gossiper := r.gossipers[p.id]
+			switch {
+			case gossiper == nil:
+				pushes[p] = append(pushes[p], tx)
+			case pushed < r.config.fanout(GossipTopicTxs):
+				pushes[p] = append(pushes[p], tx)
+				pushed++
+			default:
+				p.MarkTransaction(hash)
+				haves[gossiper] = append(haves[gossiper], hash)
+			}
+		}
+		log.Trace("Gossip transaction", "hash", hash, "pushed", pushed, "recipients", len(peers))
+	}
+	r.lock.Unlock()
+
+	for p, txs := range pushes {
+		p.AsyncSendTransactions(txs)
+	}
+	for peer, hashes := range haves {
+		gossipHaveOutMeter.Mark(int64(len(hashes)))
+		peer.send(IHaveMsg, hashes)
+	}
+}
+
+// loop requests the missing announced transactions until quit is closed.
+func (r *gossipRouter) loop(quit chan bool) {
+	ticker := time.NewTicker(gossipWantInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case now := <-ticker.C:
+			r.request(now)
+		case <-quit:
+			return
+		}
+	}
+}
@@ -0,0 +1,224 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+)
+
+// gossipTestMsg is a message received by a peer of the tested router, with the
+// hashes of the transactions or announcements it carries.
+type gossipTestMsg struct {
+	peer   int
+	code   uint64
+	hashes []common.Hash
+}
+
+// gossipTestPeers connects peers to a gossip router, the first ones over both
+// the eth and gossip protocols and the others over eth only. The messages they
+// receive are collected on the returned channel.
+func gossipTestPeers(t *testing.T, router *gossipRouter, gossipers, others int) ([]p2p.MsgReadWriter, <-chan gossipTestMsg, func()) {
+	var (
+		conns  []p2p.MsgReadWriter
+		closed []*queuedPipeRW
+		msgs   = make(chan gossipTestMsg, 64)
+	)
+	collect := func(peer int, rw p2p.MsgReadWriter) {
+		for {
+			msg, err := rw.ReadMsg()
+			if err != nil {
+				return
+			}
+			var hashes []common.Hash
+			if msg.Code == TxMsg {
+				var txs []*types.Transaction
+				if err := msg.Decode(&txs); err != nil {
+					t.Errorf("peer %d: invalid transactions: %v", peer, err)
+				}
+				for _, tx := range txs {
+					hashes = append(hashes, tx.Hash())
+				}
+			} else if err := msg.Decode(&hashes); err != nil {
+				t.Errorf("peer %d: invalid hashes: %v", peer, err)
+			}
+			msgs <- gossipTestMsg{peer, msg.Code, hashes}
+		}
+	}
+	for i := 0; i < gossipers+others; i++ {
+		id := enode.ID{byte(i + 1)}
+		local, remote := newQueuedPipe()
+		closed = append(closed, local)
+		router.peers.Register(newPeer(eth63, p2p.NewPeer(id, "test", nil), local))
+		go collect(i, remote)
+
+		if i < gossipers {
+			local, remote := newQueuedPipe()
+			closed = append(closed, local)
+			go router.handle(id, local)
+			go collect(i, remote)
+			conns = append(conns, remote)
+		}
+	}
+	for start := time.Now(); ; time.Sleep(10 * time.Millisecond) {
+		router.lock.Lock()
+		n := len(router.gossipers)
+		router.lock.Unlock()
+		if n == gossipers {
+			break
+		}
+		if time.Since(start) > 5*time.Second {
+			t.Fatalf("gossip peers not registered")
+		}
+	}
+	return conns, msgs, func() {
+		router.peers.Close()
+		for _, rw := range closed {
+			rw.Close()
+		}
+	}
+}
+
+// waitGossipMsg waits for the next message received by the peers.
+func waitGossipMsg(t *testing.T, msgs <-chan gossipTestMsg) gossipTestMsg {
+	select {
+	case msg := <-msgs:
+		return msg
+	case <-time.After(5 * time.Second):
+		t.Fatalf("message not received")
+	}
+	return gossipTestMsg{}
+}
+
+// Tests that new transactions are pushed to the fanout of the gossip peers and
+// to all the other peers, announced to the remaining gossip peers, and served
+// on request.
+func TestGossipBroadcast(t *testing.T) {
+	tx := newTestTransaction(testAccount, 0, 0)
+	config := GossipConfig{Enabled: true, BlockFanout: 2, TxFanout: 2}
+	router, err := newGossipRouter(config, &testTxPool{pool: []*types.Transaction{tx}}, newPeerSet())
+	if err != nil {
+		t.Fatal(err)
+	}
+	conns, msgs, stop := gossipTestPeers(t, router, 5, 1)
+	defer stop()
+
+	router.broadcastTxs(types.Transactions{tx})
+
+	var pushed, announced []int
+	for i := 0; i < 6; i++ {
+		msg := waitGossipMsg(t, msgs)
+		if len(msg.hashes) != 1 || msg.hashes[0] != tx.Hash() {
+			t.Fatalf("peer %d: hashes mismatch: have %x, want %x", msg.peer, msg.hashes, tx.Hash())
+		}
+		switch msg.code {
+		case TxMsg:
+			pushed = append(pushed, msg.peer)
+		case IHaveMsg:
+			announced = append(announced, msg.peer)
+		default:
+			t.Fatalf("peer %d: unexpected message %d", msg.peer, msg.code)
+		}
+	}
+	if len(pushed) != 3 || len(announced) != 3 {
+		t.Fatalf("propagation mismatch: pushed to %v, announced to %v", pushed, announced)
+	}
+	for _, peer := range announced {
+		if peer == 5 {
+			t.Errorf("transaction announced to a peer not speaking gossip")
+		}
+	}
+	// Announced transactions are served on request
+	if err := p2p.Send(conns[announced[0]], IWantMsg, []common.Hash{tx.Hash()}); err != nil {
+		t.Fatal(err)
+	}
+	if msg := waitGossipMsg(t, msgs); msg.code != TxMsg || msg.peer != announced[0] {
+		t.Errorf("request answer mismatch: have message %d to peer %d, want %d to peer %d", msg.code, msg.peer, TxMsg, announced[0])
+	}
+}
+
+// Tests that announced transactions missing after the delay are requested from
+// the announcing peers in turn, until received.
+func TestGossipRequest(t *testing.T) {
+	var (
+		known   = newTestTransaction(testAccount, 0, 0)
+		missing = newTestTransaction(testAccount, 1, 0)
+		pool    = &testTxPool{pool: []*types.Transaction{known}}
+	)
+	config := GossipConfig{Enabled: true, BlockFanout: 2, TxFanout: 2, WantDelay: time.Second}
+	router, err := newGossipRouter(config, pool, newPeerSet())
+	if err != nil {
+		t.Fatal(err)
+	}
+	conns, msgs, stop := gossipTestPeers(t, router, 2, 0)
+	defer stop()
+
+	announcers := func() int {
+		router.lock.Lock()
+		defer router.lock.Unlock()
+
+		if _, ok := router.announced[known.Hash()]; ok {
+			t.Errorf("pooled transaction awaited")
+		}
+		if ann := router.announced[missing.Hash()]; ann != nil {
+			return len(ann.peers)
+		}
+		return 0
+	}
+	for i, conn := range conns {
+		if err := p2p.Send(conn, IHaveMsg, []common.Hash{known.Hash(), missing.Hash()}); err != nil {
+			t.Fatal(err)
+		}
+		for start := time.Now(); announcers() != i+1; time.Sleep(10 * time.Millisecond) {
+			if time.Since(start) > 5*time.Second {
+				t.Fatalf("announcement %d not recorded", i)
+			}
+		}
+	}
+	now := time.Now()
+	router.request(now)
+	select {
+	case msg := <-msgs:
+		t.Fatalf("transaction requested before the delay: %+v", msg)
+	case <-time.After(100 * time.Millisecond):
+	}
+	// The first announcer is asked, then the second one once timed out
+	for i := range conns {
+		now = now.Add(gossipWantTimeout)
+		router.request(now)
+		msg := waitGossipMsg(t, msgs)
+		if msg.code != IWantMsg || msg.peer != i || len(msg.hashes) != 1 || msg.hashes[0] != missing.Hash() {
+			t.Fatalf("request %d mismatch: have %+v", i, msg)
+		}
+	}
+	// Received transactions are not awaited anymore
+	router.received([]*types.Transaction{missing})
+	if n := announcers(); n != 0 {
+		t.Errorf("received transaction still awaited")
+	}
+	router.request(now.Add(gossipWantTimeout))
+	select {
+	case msg := <-msgs:
+		t.Fatalf("received transaction requested: %+v", msg)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
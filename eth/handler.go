@@ -22,6 +22,7 @@ import (
 	"fmt"
 	"math"
 	"math/big"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -103,6 +104,14 @@ type ProtocolManager struct {
 
 	raftMode bool
 	engine   consensus.Engine
+
+	latencyAware  bool           // Quorum: whether to propagate the blocks to the nearest peers first
+	gossip        *gossipRouter  // Quorum: nil unless topic-based gossip is enabled
+	privateGossip *privateGossip // Quorum: nil unless the private transactions are restricted to some peers
+	healer        *stateHealer   // Quorum: fetches the missing state entries from the peers
+
+	compression          byte // Quorum: codec of the compressed messages, zero if disabled
+	compressionThreshold int  // Quorum: size of the messages from which they are compressed
 }
 
 // NewProtocolManager returns a new Ethereum sub protocol manager. The Ethereum sub protocol manages peers capable
@@ -123,6 +132,7 @@ func NewProtocolManager(config *params.ChainConfig, mode downloader.SyncMode, ne
 		raftMode:    raftMode,
 		engine:      engine,
 	}
+	manager.healer = newStateHealer(manager)
 
 	if handler, ok := manager.engine.(consensus.Handler); ok {
 		handler.SetBroadcaster(manager)
@@ -269,7 +279,11 @@ func (pm *ProtocolManager) Stop() {
 }
 
 func (pm *ProtocolManager) newPeer(pv int, p *p2p.Peer, rw p2p.MsgReadWriter) *peer {
-	return newPeer(pv, p, newMeteredMsgWriter(rw))
+	peer := newPeer(pv, p, newMeteredMsgWriter(rw))
+	if pm.compression != 0 && supportsCompression(p) {
+		peer.compression, peer.compressionThreshold = pm.compression, pm.compressionThreshold
+	}
+	return peer
 }
 
 // handle is the callback invoked to manage the life cycle of an eth peer. When
@@ -352,6 +366,12 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 	}
 	defer msg.Discard()
 
+	// Quorum: decompress the messages of the peers sending them compressed
+	if p.compression != 0 && compressible(msg.Code) {
+		if msg, err = decompress(msg); err != nil {
+			return errResp(ErrDecode, "%v: %v", msg, err)
+		}
+	}
 	if pm.raftMode {
 		if msg.Code != TxMsg &&
 			msg.Code != GetBlockHeadersMsg && msg.Code != BlockHeadersMsg &&
@@ -603,7 +623,10 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 		if err := msg.Decode(&data); err != nil {
 			return errResp(ErrDecode, "msg %v: %v", msg, err)
 		}
-		// Deliver all to the downloader
+		// Deliver to the state healing if it requested them outside of a sync, all others to the downloader
+		if pm.healer.deliver(p.id, data) {
+			break
+		}
 		if err := pm.downloader.DeliverNodeData(p.id, data); err != nil {
 			log.Debug("Failed to deliver node state data", "err", err)
 		}
@@ -724,6 +747,10 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 			}
 			p.MarkTransaction(tx.Hash())
 		}
+		// Quorum
+		if pm.gossip != nil {
+			pm.gossip.received(txs)
+		}
 		pm.txpool.AddRemotes(txs)
 
 	default:
@@ -757,9 +784,17 @@ func (pm *ProtocolManager) BroadcastBlock(block *types.Block, propagate bool) {
 		if transferLen < minBroadcastPeers {
 			transferLen = minBroadcastPeers
 		}
+		// Quorum: gossip pushes to the fanout only, the announcements reaching the rest
+		if pm.gossip != nil {
+			transferLen = pm.gossip.config.fanout(GossipTopicBlocks)
+		}
 		if transferLen > len(peers) {
 			transferLen = len(peers)
 		}
+		// Quorum
+		if pm.latencyAware {
+			sortByLatency(peers, transferLen)
+		}
 		transfer := peers[:transferLen]
 		for _, peer := range transfer {
 			peer.AsyncSendNewBlock(block, td)
@@ -776,9 +811,45 @@ func (pm *ProtocolManager) BroadcastBlock(block *types.Block, propagate bool) {
 	}
 }
 
+// Quorum
+// sortByLatency orders the peers by ascending round-trip time, the peers not
+// measured yet last, so that blocks reach the nearest ones first. The farthest
+// measured peer then takes the last of the first n slots, for the blocks to reach
+// the other regions directly too.
+func sortByLatency(peers []*peer, n int) {
+	sort.SliceStable(peers, func(i, j int) bool {
+		ri, rj := peers[i].RTT(), peers[j].RTT()
+		if ri == 0 || rj == 0 {
+			return rj == 0 && ri != 0
+		}
+		return ri < rj
+	})
+	if n <= 1 || n >= len(peers) {
+		return
+	}
+	farthest := -1
+	for i := len(peers) - 1; i >= n; i-- {
+		if peers[i].RTT() != 0 {
+			farthest = i
+			break
+		}
+	}
+	if farthest >= 0 {
+		peers[n-1], peers[farthest] = peers[farthest], peers[n-1]
+	}
+}
+
 // BroadcastTxs will propagate a batch of transactions to all peers which are not known to
 // already have the given transaction.
 func (pm *ProtocolManager) BroadcastTxs(txs types.Transactions) {
+	// Quorum
+	if pm.privateGossip != nil {
+		txs = pm.broadcastPrivateTxs(txs)
+	}
+	if pm.gossip != nil {
+		pm.gossip.broadcastTxs(txs)
+		return
+	}
 	var txset = make(map[*peer]types.Transactions)
 
 	// Broadcast transactions to a batch of peers not knowing about it
@@ -0,0 +1,292 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth/downloader"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+const (
+	// healBatchSize is the number of trie entries looked up at once.
+	healBatchSize = 1024
+
+	// healRequestTimeout is the time a peer has to serve a node data request.
+	healRequestTimeout = 10 * time.Second
+
+	// healUnavailableLimit is the number of entries no peer could serve listed
+	// in the healing result.
+	healUnavailableLimit = 256
+)
+
+var (
+	errHealRunning     = errors.New("state healing already running")
+	errHealSyncing     = errors.New("state healing unavailable while synchronising")
+	errHealPeerBusy    = errors.New("peer busy with another node data request")
+	errHealPeerTimeout = errors.New("node data request timed out")
+)
+
+// HealStateResult sums up the healing of a state trie.
+type HealStateResult struct {
+	Root        common.Hash   `json:"root"`
+	Entries     uint64        `json:"entries"`     // Trie nodes and contract codes found in the database
+	Missing     uint64        `json:"missing"`     // Entries missing from the database
+	Healed      uint64        `json:"healed"`      // Missing entries fetched from the peers and written
+	Unavailable []common.Hash `json:"unavailable"` // Missing entries no peer could serve, up to healUnavailableLimit
+}
+
+// stateHealer walks a state trie, fetching the entries missing from the
+// database from the peers. The fetched entries are verified against their hash,
+// itself referenced by their parent, for every entry written to be proven
+// against the root. The healing stops as soon as a synchronisation starts, the
+// node data responses then being the downloader's.
+type stateHealer struct {
+	pm      *ProtocolManager
+	running int32 // Whether a healing is running, only one at a time
+
+	lock    sync.Mutex
+	pending map[string]chan [][]byte // Deliveries of the outstanding requests by peer id
+}
+
+func newStateHealer(pm *ProtocolManager) *stateHealer {
+	return &stateHealer{pm: pm, pending: make(map[string]chan [][]byte)}
+}
+
+// healReader is the database of the trie scheduler, which knows no entry for
+// the scheduler to walk the whole trie rather than stop at the first entry
+// present.
+type healReader struct{}
+
+func (healReader) Get(key []byte) ([]byte, error) { return nil, errors.New("not found") }
+func (healReader) Has(key []byte) (bool, error)   { return false, nil }
+
+// heal walks the state of the given root, looking its entries up with the local
+// function and, if remote is set, fetching those missing from the peers into
+// the database. Without remote, the missing entries are only reported, which is
+// the case of the private states: requesting their entries would disclose the
+// hashes of private state to every peer, none of which serves them anyway.
+func (h *stateHealer) heal(ctx context.Context, root common.Hash, local func(common.Hash) ([]byte, error), db ethdb.Database, remote bool) (*HealStateResult, error) {
+	if !atomic.CompareAndSwapInt32(&h.running, 0, 1) {
+		return nil, errHealRunning
+	}
+	defer atomic.StoreInt32(&h.running, 0)
+
+	if h.pm.downloader.Synchronising() {
+		return nil, errHealSyncing
+	}
+	var (
+		result = &HealStateResult{Root: root, Unavailable: []common.Hash{}}
+		sched  = state.NewStateSync(root, healReader{})
+		batch  = db.NewBatch()
+		start  = time.Now()
+	)
+	log.Info("Healing state", "root", root)
+	for {
+		hashes := sched.Missing(healBatchSize)
+		if len(hashes) == 0 {
+			break
+		}
+		var (
+			results []trie.SyncResult
+			missing []common.Hash
+		)
+		for _, hash := range hashes {
+			if blob, err := local(hash); err == nil && len(blob) > 0 {
+				results = append(results, trie.SyncResult{Hash: hash, Data: blob})
+			} else {
+				missing = append(missing, hash)
+			}
+		}
+		result.Entries += uint64(len(results))
+		if len(missing) > 0 {
+			result.Missing += uint64(len(missing))
+			log.Warn("Found missing state entries", "root", root, "count", len(missing))
+
+			fetched := make(map[common.Hash][]byte)
+			if remote {
+				var err error
+				if fetched, err = h.fetch(ctx, missing); err != nil {
+					return result, err
+				}
+			}
+			for _, hash := range missing {
+				blob, ok := fetched[hash]
+				if !ok {
+					// Leave the entry pending, the scheduler won't return it again
+					if len(result.Unavailable) < healUnavailableLimit {
+						result.Unavailable = append(result.Unavailable, hash)
+					}
+					continue
+				}
+				results = append(results, trie.SyncResult{Hash: hash, Data: blob})
+				batch.Put(hash[:], blob)
+				result.Healed++
+			}
+		}
+		if _, index, err := sched.Process(results); err != nil {
+			return result, fmt.Errorf("invalid state entry %x: %v", results[index].Hash, err)
+		}
+		// The entries present are written already, only release them from memory
+		if _, err := sched.Commit(ethdb.NewMemDatabase()); err != nil {
+			return result, err
+		}
+		if batch.ValueSize() >= ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return result, err
+			}
+			batch.Reset()
+		}
+		select {
+		case <-ctx.Done():
+			return result, ctx.Err()
+		default:
+		}
+	}
+	if err := batch.Write(); err != nil {
+		return result, err
+	}
+	log.Info("Healed state", "root", root, "entries", result.Entries, "missing", result.Missing, "healed", result.Healed, "elapsed", common.PrettyDuration(time.Since(start)))
+	return result, nil
+}
+
+// fetch requests entries from the peers in turn, the best first, until all are
+// served or no peer has them.
+func (h *stateHealer) fetch(ctx context.Context, hashes []common.Hash) (map[common.Hash][]byte, error) {
+	wanted := make(map[common.Hash]bool, len(hashes))
+	for _, hash := range hashes {
+		wanted[hash] = true
+	}
+	fetched := make(map[common.Hash][]byte)
+	for _, p := range h.peers() {
+		for len(wanted) > 0 {
+			if h.pm.downloader.Synchronising() {
+				return nil, errHealSyncing
+			}
+			request := make([]common.Hash, 0, downloader.MaxStateFetch)
+			for hash := range wanted {
+				if request = append(request, hash); len(request) == cap(request) {
+					break
+				}
+			}
+			data, err := h.request(ctx, p, request)
+			if err != nil {
+				if ctx.Err() != nil {
+					return nil, ctx.Err()
+				}
+				p.Log().Debug("Failed to fetch state entries", "err", err)
+				break
+			}
+			// Keep the requested entries only, verified against their hash
+			served := 0
+			for _, blob := range data {
+				if hash := crypto.Keccak256Hash(blob); wanted[hash] {
+					fetched[hash] = blob
+					delete(wanted, hash)
+					served++
+				}
+			}
+			if served == 0 {
+				break
+			}
+		}
+		if len(wanted) == 0 {
+			break
+		}
+	}
+	return fetched, nil
+}
+
+// peers returns the peers able to serve state entries, by decreasing total
+// difficulty.
+func (h *stateHealer) peers() []*peer {
+	var peers []*peer
+	for _, p := range h.pm.peers.Peers() {
+		if p.version >= eth63 {
+			peers = append(peers, p)
+		}
+	}
+	sort.Slice(peers, func(i, j int) bool {
+		_, tdi := peers[i].Head()
+		_, tdj := peers[j].Head()
+		return tdi.Cmp(tdj) > 0
+	})
+	return peers
+}
+
+// request sends a node data request to a peer and waits for its response.
+func (h *stateHealer) request(ctx context.Context, p *peer, hashes []common.Hash) ([][]byte, error) {
+	ch := make(chan [][]byte, 1)
+
+	h.lock.Lock()
+	if _, busy := h.pending[p.id]; busy {
+		h.lock.Unlock()
+		return nil, errHealPeerBusy
+	}
+	h.pending[p.id] = ch
+	h.lock.Unlock()
+
+	defer func() {
+		h.lock.Lock()
+		delete(h.pending, p.id)
+		h.lock.Unlock()
+	}()
+	if err := p.RequestNodeData(hashes); err != nil {
+		return nil, err
+	}
+	timer := time.NewTimer(healRequestTimeout)
+	defer timer.Stop()
+
+	select {
+	case data := <-ch:
+		return data, nil
+	case <-timer.C:
+		return nil, errHealPeerTimeout
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// deliver hands a node data response to the healing if it requested it from
+// the peer, returning whether it did. The responses are left to the downloader
+// while it synchronises, the healing aborting then.
+func (h *stateHealer) deliver(peer string, data [][]byte) bool {
+	if h.pm.downloader.Synchronising() {
+		return false
+	}
+	h.lock.Lock()
+	ch, ok := h.pending[peer]
+	delete(h.pending, peer)
+	h.lock.Unlock()
+
+	if ok {
+		ch <- data
+	}
+	return ok
+}
@@ -0,0 +1,139 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/eth/downloader"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// Tests that the state entries missing from the database are fetched from the
+// peers, and that those no peer has are reported.
+func TestHealState(t *testing.T) {
+	// Fund enough accounts for the state trie to have a few levels
+	generator := func(i int, block *core.BlockGen) {
+		if i == 0 {
+			for j := 0; j < 32; j++ {
+				tx, _ := types.SignTx(types.NewTransaction(block.TxNonce(testBank), common.BigToAddress(big.NewInt(int64(j+1))), big.NewInt(1000), params.TxGas, nil, nil), types.HomesteadSigner{}, testBankKey)
+				block.AddTx(tx)
+			}
+		}
+	}
+	pm, db := newTestProtocolManagerMust(t, downloader.FullSync, 1, generator, nil)
+	peer, _ := newTestPeer("peer", eth63, pm, true)
+	defer peer.close()
+
+	// Keep a copy of the database for the peer to serve, then corrupt the local
+	// one, losing the root and a few inner nodes, one of them for good
+	source := ethdb.NewMemDatabase()
+	for _, key := range db.Keys() {
+		value, _ := db.Get(key)
+		source.Put(key, value)
+	}
+	root := pm.blockchain.CurrentBlock().Root()
+	var nodes []common.Hash
+	tr, err := trie.New(root, trie.NewDatabase(db))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for nit := tr.NodeIterator(nil); nit.Next(true); {
+		if hash := nit.Hash(); hash != (common.Hash{}) {
+			nodes = append(nodes, hash)
+		}
+	}
+	if len(nodes) < 4 {
+		t.Fatalf("state trie too small: %d nodes", len(nodes))
+	}
+	lost := nodes[len(nodes)-1]
+	lostBlob, _ := db.Get(lost[:])
+	for _, hash := range []common.Hash{nodes[0], nodes[1], nodes[len(nodes)-2], lost} {
+		db.Delete(hash[:])
+	}
+	source.Delete(lost[:])
+
+	// Check the state without fetching, as done for the private states, no
+	// request being sent to the peer: the walk stops at the root missing
+	local := func(hash common.Hash) ([]byte, error) { return db.Get(hash[:]) }
+	result, err := pm.healer.heal(context.Background(), root, local, db, false)
+	if err != nil {
+		t.Fatalf("failed to check state: %v", err)
+	}
+	if result.Missing != 1 || result.Healed != 0 || len(result.Unavailable) != 1 || result.Unavailable[0] != root {
+		t.Errorf("check mismatch: have %d missing, %d healed, unavailable %x, want 1, 0 and [%x]", result.Missing, result.Healed, result.Unavailable, root)
+	}
+	// Serve the node data requests of the healing from the copy
+	go func() {
+		for {
+			msg, err := peer.app.ReadMsg()
+			if err != nil {
+				return
+			}
+			if msg.Code != GetNodeDataMsg {
+				msg.Discard()
+				continue
+			}
+			var hashes []common.Hash
+			msg.Decode(&hashes)
+
+			var data [][]byte
+			for _, hash := range hashes {
+				if blob, err := source.Get(hash[:]); err == nil {
+					data = append(data, blob)
+				}
+			}
+			p2p.Send(peer.app, NodeDataMsg, data)
+		}
+	}()
+	result, err = pm.healer.heal(context.Background(), root, local, db, true)
+	if err != nil {
+		t.Fatalf("failed to heal state: %v", err)
+	}
+	if result.Missing != 4 || result.Healed != 3 {
+		t.Errorf("healing mismatch: have %d missing, %d healed, want 4 and 3", result.Missing, result.Healed)
+	}
+	if len(result.Unavailable) != 1 || result.Unavailable[0] != lost {
+		t.Errorf("unavailable entries mismatch: have %x, want [%x]", result.Unavailable, lost)
+	}
+	for _, hash := range nodes[:len(nodes)-1] {
+		if ok, _ := db.Has(hash[:]); !ok {
+			t.Errorf("node %x not healed", hash)
+		}
+	}
+	// Heal the last node once a peer has it, making the state whole again
+	source.Put(lost[:], lostBlob)
+	if result, err = pm.healer.heal(context.Background(), root, local, db, true); err != nil || result.Healed != 1 {
+		t.Fatalf("failed to heal the last node: %v, %+v", err, result)
+	}
+	statedb, err := state.New(root, state.NewDatabase(db))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if balance := statedb.GetBalance(common.BigToAddress(big.NewInt(32))); balance.Cmp(big.NewInt(1000)) != 0 {
+		t.Errorf("balance mismatch: have %v, want 1000", balance)
+	}
+}
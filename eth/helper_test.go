@@ -181,6 +181,19 @@ func (p *testTxPool) Pending() (map[common.Address]types.Transactions, error) {
 	return batches, nil
 }
 
+// Get returns the pooled transaction with the given hash, or nil.
+func (p *testTxPool) Get(hash common.Hash) *types.Transaction {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	for _, tx := range p.pool {
+		if tx.Hash() == hash {
+			return tx
+		}
+	}
+	return nil
+}
+
 func (p *testTxPool) SubscribeNewTxsEvent(ch chan<- core.NewTxsEvent) event.Subscription {
 	return p.txFeed.Subscribe(ch)
 }
@@ -0,0 +1,299 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// integrityFindingsLimit is the number of the most recent findings kept for
+// the integrity report.
+const integrityFindingsLimit = 256
+
+// Integrity checks of a block.
+const (
+	IntegrityBody            = "body"            // Block and receipts present, matching the transactions
+	IntegritySeal            = "seal"            // Header valid and sealed as per the consensus engine
+	IntegrityTxRoot          = "txRoot"          // Transactions matching the transaction root
+	IntegrityReceiptRoot     = "receiptRoot"     // Receipts matching the receipt root
+	IntegrityPrivateReceipts = "privateReceipts" // Private receipts matching the private block bloom
+	IntegrityState           = "state"           // Public state reachable, on archive nodes
+	IntegrityPrivateState    = "privateState"    // Private state reachable, on archive nodes
+)
+
+// IntegrityFinding is a check a historical block failed.
+type IntegrityFinding struct {
+	Number hexutil.Uint64 `json:"number"`
+	Hash   common.Hash    `json:"hash"`
+	Check  string         `json:"check"`
+	Error  string         `json:"error"`
+	Time   time.Time      `json:"time"`
+}
+
+// IntegrityReport sums up the verification of the historical blocks since the
+// node started.
+type IntegrityReport struct {
+	Checked  uint64              `json:"checked"`  // Blocks verified
+	Faulty   uint64              `json:"faulty"`   // Blocks failing a check
+	LastRun  time.Time           `json:"lastRun"`  // Time of the last verification
+	Findings []*IntegrityFinding `json:"findings"` // Most recent findings, oldest first
+}
+
+// integrityVerifier re-validates a random sample of the historical blocks, to
+// detect the silent corruption of the chain data before it's needed.
+type integrityVerifier struct {
+	chain   *core.BlockChain
+	engine  consensus.Engine
+	db      ethdb.Database
+	archive bool          // Whether the historical states are kept
+	period  time.Duration // Interval between two verifications
+
+	rand *rand.Rand
+	lock sync.RWMutex
+	rep  IntegrityReport
+}
+
+// newIntegrityVerifier creates a verifier of the given number of blocks per
+// hour.
+func newIntegrityVerifier(chain *core.BlockChain, engine consensus.Engine, db ethdb.Database, archive bool, samples int) *integrityVerifier {
+	return &integrityVerifier{
+		chain:   chain,
+		engine:  engine,
+		db:      db,
+		archive: archive,
+		period:  time.Hour / time.Duration(samples),
+		rand:    rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// loop verifies a random canonical block every period until quit is closed.
+func (v *integrityVerifier) loop(quit chan bool) {
+	ticker := time.NewTicker(v.period)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			head := v.chain.CurrentBlock().NumberU64()
+			if head == 0 {
+				continue
+			}
+			// The genesis block has no seal or receipts to verify
+			number := 1 + uint64(v.rand.Int63n(int64(head)))
+			v.record(number, v.verify(number))
+
+		case <-quit:
+			return
+		}
+	}
+}
+
+// record adds the findings of the verification of a block to the report.
+func (v *integrityVerifier) record(number uint64, findings []*IntegrityFinding) {
+	integrityCheckedMeter.Mark(1)
+	if len(findings) > 0 {
+		integrityFaultMeter.Mark(1)
+	}
+	v.lock.Lock()
+	defer v.lock.Unlock()
+
+	v.rep.Checked++
+	v.rep.LastRun = time.Now()
+	if len(findings) == 0 {
+		return
+	}
+	v.rep.Faulty++
+	for _, finding := range findings {
+		log.Error("Chain data integrity check failed", "number", number, "hash", finding.Hash, "check", finding.Check, "err", finding.Error)
+	}
+	v.rep.Findings = append(v.rep.Findings, findings...)
+	if n := len(v.rep.Findings); n > integrityFindingsLimit {
+		v.rep.Findings = append([]*IntegrityFinding(nil), v.rep.Findings[n-integrityFindingsLimit:]...)
+	}
+}
+
+// verify runs all the checks of a canonical block, returning the failed ones.
+func (v *integrityVerifier) verify(number uint64) []*IntegrityFinding {
+	hash := rawdb.ReadCanonicalHash(v.db, number)
+
+	var findings []*IntegrityFinding
+	fail := func(check string, format string, args ...interface{}) {
+		findings = append(findings, &IntegrityFinding{
+			Number: hexutil.Uint64(number),
+			Hash:   hash,
+			Check:  check,
+			Error:  fmt.Sprintf(format, args...),
+			Time:   time.Now(),
+		})
+	}
+	if hash == (common.Hash{}) {
+		fail(IntegrityBody, "canonical hash missing")
+		return findings
+	}
+	block := rawdb.ReadBlock(v.db, hash, number)
+	if block == nil && core.HistoryPruned(v.db, number) {
+		// Quorum: only the header is left of the blocks pruned by the history retention
+		if header := rawdb.ReadHeader(v.db, hash, number); header == nil {
+			fail(IntegrityBody, "header missing")
+		} else if err := v.engine.VerifyHeader(v.chain, header, true); err != nil {
+			fail(IntegritySeal, "%v", err)
+		}
+		return findings
+	}
+	if block == nil {
+		fail(IntegrityBody, "block missing")
+		return findings
+	}
+	if err := v.engine.VerifyHeader(v.chain, block.Header(), true); err != nil {
+		fail(IntegritySeal, "%v", err)
+	}
+	if root := types.DeriveSha(block.Transactions()); root != block.TxHash() {
+		fail(IntegrityTxRoot, "transaction root mismatch: have %x, want %x", root, block.TxHash())
+	}
+	receipts := rawdb.ReadReceipts(v.db, hash, number)
+	if err := checkReceipts(block, receipts); err != nil {
+		fail(IntegrityBody, "%v", err)
+	} else {
+		// The stored receipts of the private transactions are the private ones,
+		// the public ones being rebuilt to check the receipt root
+		var private types.Receipts
+		for i, tx := range block.Transactions() {
+			if tx.IsPrivate() {
+				private = append(private, receipts[i])
+			}
+		}
+		if public, ok := publicReceipts(v.chain.Config().IsByzantium(block.Number()), block, receipts); ok {
+			if root := types.DeriveSha(public); root != block.ReceiptHash() {
+				fail(IntegrityReceiptRoot, "receipt root mismatch: have %x, want %x", root, block.ReceiptHash())
+			}
+		}
+		if len(private) > 0 {
+			if err := checkPrivateReceipts(private, core.GetPrivateBlockBloom(v.db, number)); err != nil {
+				fail(IntegrityPrivateReceipts, "%v", err)
+			}
+		}
+	}
+	// The historical states are pruned unless running an archive node
+	if v.archive {
+		if !v.chain.HasState(block.Root()) {
+			fail(IntegrityState, "state %x unreachable", block.Root())
+		}
+		if root := core.GetPrivateStateRoot(v.db, block.Root()); root != (common.Hash{}) && !v.chain.HasState(root) {
+			fail(IntegrityPrivateState, "private state %x unreachable", root)
+		}
+	}
+	return findings
+}
+
+// checkReceipts checks there is a stored receipt for every transaction.
+func checkReceipts(block *types.Block, receipts types.Receipts) error {
+	txs := block.Transactions()
+	if len(receipts) != len(txs) {
+		return fmt.Errorf("receipts mismatch: have %d, want %d", len(receipts), len(txs))
+	}
+	for i, tx := range txs {
+		if receipts[i].TxHash != tx.Hash() {
+			return fmt.Errorf("receipt %d of transaction %x, want %x", i, receipts[i].TxHash, tx.Hash())
+		}
+	}
+	return nil
+}
+
+// publicReceipts returns the receipts of a block the receipt root is derived
+// from, replacing the stored receipts of the private transactions by the public
+// ones. The public receipt of a private transaction always succeeds and holds no
+// logs, the execution being confined to the private state, but the gas used is
+// the same. Before Byzantium, the receipts hold the intermediate state roots
+// instead of the statuses and the public roots of the private transactions are
+// lost, so the receipts can't be rebuilt.
+func publicReceipts(byzantium bool, block *types.Block, receipts types.Receipts) (types.Receipts, bool) {
+	public := make(types.Receipts, len(receipts))
+	for i, tx := range block.Transactions() {
+		if !tx.IsPrivate() {
+			public[i] = receipts[i]
+			continue
+		}
+		if !byzantium {
+			return nil, false
+		}
+		public[i] = &types.Receipt{
+			Status:            types.ReceiptStatusSuccessful,
+			CumulativeGasUsed: receipts[i].CumulativeGasUsed,
+			TxHash:            receipts[i].TxHash,
+			GasUsed:           receipts[i].GasUsed,
+		}
+	}
+	return public, true
+}
+
+// checkPrivateReceipts checks the blooms of the private receipts match their
+// logs and are part of the private block bloom. The logs of the purged private
+// contracts are scrubbed from their receipts, leaving the block bloom a
+// superset rather than the exact bloom of the receipts.
+func checkPrivateReceipts(receipts types.Receipts, blockBloom types.Bloom) error {
+	for _, receipt := range receipts {
+		if bloom := types.CreateBloom(types.Receipts{receipt}); bloom != receipt.Bloom {
+			return fmt.Errorf("private receipt of transaction %x: bloom mismatch", receipt.TxHash)
+		}
+		for i, b := range receipt.Bloom {
+			if b&^blockBloom[i] != 0 {
+				return fmt.Errorf("private receipt of transaction %x: logs missing from the private block bloom", receipt.TxHash)
+			}
+		}
+	}
+	return nil
+}
+
+// Report returns the findings of the verification since the node started.
+func (v *integrityVerifier) Report() *IntegrityReport {
+	v.lock.RLock()
+	defer v.lock.RUnlock()
+
+	rep := v.rep
+	rep.Findings = append([]*IntegrityFinding{}, v.rep.Findings...)
+	return &rep
+}
+
+// PrivateIntegrityAPI provides an API to access the findings of the chain data
+// integrity verification.
+type PrivateIntegrityAPI struct {
+	verifier *integrityVerifier
+}
+
+// NewPrivateIntegrityAPI creates a new API definition for the chain data
+// integrity verification of the Ethereum service.
+func NewPrivateIntegrityAPI(verifier *integrityVerifier) *PrivateIntegrityAPI {
+	return &PrivateIntegrityAPI{verifier: verifier}
+}
+
+// IntegrityReport returns the number of historical blocks verified since the
+// node started, along with the most recent checks they failed.
+func (api *PrivateIntegrityAPI) IntegrityReport() *IntegrityReport {
+	return api.verifier.Report()
+}
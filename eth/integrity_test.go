@@ -0,0 +1,124 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/eth/downloader"
+)
+
+// Tests that the corrupted receipts of historical blocks are detected and
+// reported.
+func TestIntegrityVerifier(t *testing.T) {
+	generator := func(i int, block *core.BlockGen) {
+		block.AddTx(newTestTransaction(testBankKey, uint64(i), 0))
+	}
+	pm, db := newTestProtocolManagerMust(t, downloader.FullSync, 3, generator, nil)
+	defer pm.Stop()
+
+	v := newIntegrityVerifier(pm.blockchain, ethash.NewFaker(), db, true, 60)
+	for number := uint64(1); number <= 3; number++ {
+		if findings := v.verify(number); len(findings) != 0 {
+			t.Fatalf("block %d: intact block reported: %+v", number, findings[0])
+		}
+	}
+	// Drop the receipts of a block and tamper with those of another
+	block2, block3 := pm.blockchain.GetBlockByNumber(2), pm.blockchain.GetBlockByNumber(3)
+	rawdb.WriteReceipts(db, block2.Hash(), 2, nil)
+
+	receipts := rawdb.ReadReceipts(db, block3.Hash(), 3)
+	receipts[0].CumulativeGasUsed++
+	rawdb.WriteReceipts(db, block3.Hash(), 3, receipts)
+
+	for number, want := range map[uint64]string{2: IntegrityBody, 3: IntegrityReceiptRoot} {
+		findings := v.verify(number)
+		if len(findings) != 1 || findings[0].Check != want {
+			t.Fatalf("block %d: findings mismatch: have %+v, want one %s", number, findings, want)
+		}
+		v.record(number, findings)
+	}
+	if report := v.Report(); report.Checked != 2 || report.Faulty != 2 || len(report.Findings) != 2 {
+		t.Errorf("report mismatch: have %d checked, %d faulty, %d findings", report.Checked, report.Faulty, len(report.Findings))
+	}
+}
+
+// Tests that the private receipts are checked against the private block bloom.
+func TestCheckPrivateReceipts(t *testing.T) {
+	receipt := &types.Receipt{Logs: []*types.Log{{Address: common.Address{0x01}}}}
+	receipt.Bloom = types.CreateBloom(types.Receipts{receipt})
+
+	if err := checkPrivateReceipts(types.Receipts{receipt}, receipt.Bloom); err != nil {
+		t.Errorf("consistent receipt rejected: %v", err)
+	}
+	if err := checkPrivateReceipts(types.Receipts{receipt}, types.Bloom{}); err == nil {
+		t.Errorf("receipt missing from the block bloom accepted")
+	}
+	// The scrubbed receipts of purged contracts are part of the block bloom
+	scrubbed := &types.Receipt{}
+	if err := checkPrivateReceipts(types.Receipts{scrubbed}, receipt.Bloom); err != nil {
+		t.Errorf("scrubbed receipt rejected: %v", err)
+	}
+	scrubbed.Bloom = receipt.Bloom
+	if err := checkPrivateReceipts(types.Receipts{scrubbed}, receipt.Bloom); err == nil {
+		t.Errorf("receipt with a bloom not matching its logs accepted")
+	}
+}
+
+// Tests that the public receipts of the private transactions are rebuilt to
+// check the receipt root.
+func TestPublicReceipts(t *testing.T) {
+	publicTx := types.NewTransaction(0, common.Address{0x01}, new(big.Int), 21000, new(big.Int), nil)
+	privateTx := types.NewTransaction(1, common.Address{0x02}, new(big.Int), 100000, new(big.Int), []byte{0x01})
+	privateTx.SetPrivate()
+
+	publicReceipt := &types.Receipt{Status: types.ReceiptStatusSuccessful, CumulativeGasUsed: 21000, TxHash: publicTx.Hash(), GasUsed: 21000}
+	privatePublicReceipt := &types.Receipt{Status: types.ReceiptStatusSuccessful, CumulativeGasUsed: 50000, TxHash: privateTx.Hash(), GasUsed: 29000}
+	block := types.NewBlock(&types.Header{}, types.Transactions{publicTx, privateTx}, nil, types.Receipts{publicReceipt, privatePublicReceipt})
+
+	// The stored receipt of the private transaction is the private one
+	privateReceipt := &types.Receipt{Status: types.ReceiptStatusFailed, CumulativeGasUsed: 50000, TxHash: privateTx.Hash(), GasUsed: 29000}
+	privateReceipt.Logs = []*types.Log{{Address: common.Address{0x02}}}
+	privateReceipt.Bloom = types.CreateBloom(types.Receipts{privateReceipt})
+	stored := types.Receipts{publicReceipt, privateReceipt}
+
+	if root := types.DeriveSha(stored); root == block.ReceiptHash() {
+		t.Fatalf("stored receipts match the receipt root")
+	}
+	public, ok := publicReceipts(true, block, stored)
+	if !ok {
+		t.Fatalf("public receipts not rebuilt")
+	}
+	if root := types.DeriveSha(public); root != block.ReceiptHash() {
+		t.Errorf("receipt root mismatch: have %x, want %x", root, block.ReceiptHash())
+	}
+	// A tampered gas usage is detected
+	privateReceipt.CumulativeGasUsed++
+	if public, _ := publicReceipts(true, block, stored); types.DeriveSha(public) == block.ReceiptHash() {
+		t.Errorf("tampered receipt matches the receipt root")
+	}
+	// The receipts holding intermediate roots can't be rebuilt
+	if _, ok := publicReceipts(false, block, stored); ok {
+		t.Errorf("public receipts rebuilt before Byzantium")
+	}
+}
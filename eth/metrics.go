@@ -55,6 +55,26 @@ var (
 	miscInTrafficMeter        = metrics.NewRegisteredMeter("eth/misc/in/traffic", nil)
 	miscOutPacketsMeter       = metrics.NewRegisteredMeter("eth/misc/out/packets", nil)
 	miscOutTrafficMeter       = metrics.NewRegisteredMeter("eth/misc/out/traffic", nil)
+
+	// Quorum
+	chainConfigMismatchGauge = metrics.NewRegisteredGauge("eth/chainconfig/mismatch/peers", nil)
+	chainConfigMismatchMeter = metrics.NewRegisteredMeter("eth/chainconfig/mismatch/detected", nil)
+	healthAlertsGauge        = metrics.NewRegisteredGauge("eth/health/alerts", nil)
+	webhookDeliveredMeter    = metrics.NewRegisteredMeter("eth/webhooks/delivered", nil)
+	webhookRetryMeter        = metrics.NewRegisteredMeter("eth/webhooks/retried", nil)
+	webhookDeadLetterMeter   = metrics.NewRegisteredMeter("eth/webhooks/deadletters", nil)
+	integrityCheckedMeter    = metrics.NewRegisteredMeter("eth/integrity/checked", nil)
+	integrityFaultMeter      = metrics.NewRegisteredMeter("eth/integrity/faulty", nil)
+	dryRunCheckedMeter       = metrics.NewRegisteredMeter("eth/dryrun/checked", nil)
+	dryRunRejectedMeter      = metrics.NewRegisteredMeter("eth/dryrun/rejected", nil)
+	gossipHaveInMeter        = metrics.NewRegisteredMeter("eth/gossip/ihave/in", nil)
+	gossipHaveOutMeter       = metrics.NewRegisteredMeter("eth/gossip/ihave/out", nil)
+	gossipWantOutMeter       = metrics.NewRegisteredMeter("eth/gossip/iwant/out", nil)
+	gossipWantServedMeter    = metrics.NewRegisteredMeter("eth/gossip/iwant/served", nil)
+	gossipDuplicateMeter     = metrics.NewRegisteredMeter("eth/gossip/duplicates", nil)
+	gossipWithheldMeter      = metrics.NewRegisteredMeter("eth/gossip/private/withheld", nil)
+	pendingBuildTimer        = metrics.NewRegisteredTimer("eth/pending/build", nil)
+	historyPrunedMeter       = metrics.NewRegisteredMeter("eth/history/pruned", nil)
 )
 
 // meteredMsgReadWriter is a wrapper around a p2p.MsgReadWriter, capable of
@@ -0,0 +1,256 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// healthCheckInterval is the interval at which the consensus health is
+// evaluated, to raise and clear alerts.
+const healthCheckInterval = 5 * time.Second
+
+// Reasons of the consensus health alerts.
+const (
+	HealthBlockStall   = "blockStall"   // No new block for too long
+	HealthRoundChanges = "roundChanges" // Too many Istanbul round changes at the current height
+	HealthLeaderChurn  = "leaderChurn"  // Too many Raft leader changes within the churn window
+	HealthRaftLag      = "raftLag"      // A Raft follower too far behind the leader
+)
+
+// HealthConfig are the alert thresholds of the consensus health watchdog. Zero
+// thresholds are not checked.
+type HealthConfig struct {
+	BlockStall    time.Duration // Time without new block, with transactions pending for Raft
+	MaxRound      uint64        // Istanbul round at a single height
+	LeaderChanges uint64        // Raft leader changes within the churn window
+	ChurnWindow   time.Duration // Window over which Raft leader changes are counted
+	RaftLag       uint64        // Raft entries a follower is behind the leader
+}
+
+// DefaultHealthConfig contains the default consensus health alert thresholds.
+var DefaultHealthConfig = HealthConfig{
+	BlockStall:    time.Minute,
+	MaxRound:      3,
+	LeaderChanges: 3,
+	ChurnWindow:   10 * time.Minute,
+	RaftLag:       1000,
+}
+
+// HealthAlert is a consensus health check exceeding its threshold.
+type HealthAlert struct {
+	Reason    string `json:"reason"`
+	Value     uint64 `json:"value"`
+	Threshold uint64 `json:"threshold"`
+}
+
+// NetworkHealth is the consensus health as seen by the node. Durations are in
+// seconds.
+type NetworkHealth struct {
+	Healthy        bool            `json:"healthy"`
+	Head           hexutil.Uint64  `json:"head"`
+	SinceLastBlock uint64          `json:"sinceLastBlock"`
+	Round          *hexutil.Uint64 `json:"round,omitempty"`         // Istanbul round at the current height
+	LeaderChanges  *hexutil.Uint64 `json:"leaderChanges,omitempty"` // Raft leader changes within the churn window
+	RaftLag        *hexutil.Uint64 `json:"raftLag,omitempty"`       // Largest lag of a Raft follower, measured by the leader
+	Alerts         []HealthAlert   `json:"alerts"`
+}
+
+// roundReporter is implemented by the consensus engines running several rounds
+// to agree on a block, such as Istanbul.
+type roundReporter interface {
+	CurrentRound() uint64
+}
+
+// healthWatchdog detects a loss of quorum from the blocks no longer produced,
+// the consensus rounds failing and the Raft leaders changing, as none of them
+// is noticed by the node otherwise.
+type healthWatchdog struct {
+	config HealthConfig
+	chain  *core.BlockChain
+	txPool *core.TxPool
+	engine consensus.Engine
+	raft   bool
+
+	lock          sync.Mutex
+	lastBlock     time.Time       // Time the current head was imported
+	leader        uint64          // Current Raft leader
+	leaderChanges []time.Time     // Times of the Raft leader changes within the churn window
+	raftLag       uint64          // Largest lag of a Raft follower at the last measure
+	alerts        map[string]bool // Reasons of the alerts raised at the last check
+
+	alertFeed event.Feed // Alerts newly raised
+}
+
+func newHealthWatchdog(config HealthConfig, chain *core.BlockChain, txPool *core.TxPool, engine consensus.Engine, raft bool) *healthWatchdog {
+	return &healthWatchdog{
+		config:    config,
+		chain:     chain,
+		txPool:    txPool,
+		engine:    engine,
+		raft:      raft,
+		lastBlock: time.Now(),
+	}
+}
+
+// reportLeader records the Raft leader, counting its changes. No leader, while
+// electing one, isn't a change: a leader re-elected after it isn't counted.
+func (w *healthWatchdog) reportLeader(leader uint64) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if leader == 0 || leader == w.leader {
+		return
+	}
+	w.leader = leader
+	w.leaderChanges = append(w.leaderChanges, time.Now())
+}
+
+// reportRaftLag records the largest number of entries a Raft follower is
+// behind the leader, zero on the followers.
+func (w *healthWatchdog) reportRaftLag(lag uint64) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	w.raftLag = lag
+}
+
+// health evaluates the consensus health checks at the given time.
+func (w *healthWatchdog) health(now time.Time) *NetworkHealth {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	h := &NetworkHealth{
+		Head:           hexutil.Uint64(w.chain.CurrentBlock().NumberU64()),
+		SinceLastBlock: uint64(now.Sub(w.lastBlock) / time.Second),
+		Alerts:         []HealthAlert{},
+	}
+	// Raft only mints blocks for transactions, so an idle network isn't stalled
+	if w.config.BlockStall > 0 && now.Sub(w.lastBlock) > w.config.BlockStall {
+		if pending, _ := w.txPool.Stats(); !w.raft || pending > 0 {
+			h.Alerts = append(h.Alerts, HealthAlert{HealthBlockStall, h.SinceLastBlock, uint64(w.config.BlockStall / time.Second)})
+		}
+	}
+	if r, ok := w.engine.(roundReporter); ok {
+		round := r.CurrentRound()
+		h.Round = (*hexutil.Uint64)(&round)
+		if w.config.MaxRound > 0 && round > w.config.MaxRound {
+			h.Alerts = append(h.Alerts, HealthAlert{HealthRoundChanges, round, w.config.MaxRound})
+		}
+	}
+	if w.raft {
+		for len(w.leaderChanges) > 0 && now.Sub(w.leaderChanges[0]) > w.config.ChurnWindow {
+			w.leaderChanges = w.leaderChanges[1:]
+		}
+		changes := uint64(len(w.leaderChanges))
+		h.LeaderChanges = (*hexutil.Uint64)(&changes)
+		if w.config.LeaderChanges > 0 && changes > w.config.LeaderChanges {
+			h.Alerts = append(h.Alerts, HealthAlert{HealthLeaderChurn, changes, w.config.LeaderChanges})
+		}
+		lag := w.raftLag
+		h.RaftLag = (*hexutil.Uint64)(&lag)
+		if w.config.RaftLag > 0 && lag > w.config.RaftLag {
+			h.Alerts = append(h.Alerts, HealthAlert{HealthRaftLag, lag, w.config.RaftLag})
+		}
+	}
+	h.Healthy = len(h.Alerts) == 0
+	return h
+}
+
+// check evaluates the consensus health, updating the metric, logging the
+// raised and cleared alerts and posting the raised ones.
+func (w *healthWatchdog) check() {
+	h := w.health(time.Now())
+	healthAlertsGauge.Update(int64(len(h.Alerts)))
+
+	w.lock.Lock()
+	raised := make(map[string]bool)
+	var fresh []HealthAlert
+	for _, alert := range h.Alerts {
+		raised[alert.Reason] = true
+		if !w.alerts[alert.Reason] {
+			log.Warn("Consensus health alert raised", "reason", alert.Reason, "value", alert.Value, "threshold", alert.Threshold)
+			fresh = append(fresh, alert)
+		}
+	}
+	for reason := range w.alerts {
+		if !raised[reason] {
+			log.Info("Consensus health alert cleared", "reason", reason)
+		}
+	}
+	w.alerts = raised
+	w.lock.Unlock()
+
+	for _, alert := range fresh {
+		w.alertFeed.Send(alert)
+	}
+}
+
+// subscribeAlerts registers a subscription of the consensus health alerts, sent
+// when raised.
+func (w *healthWatchdog) subscribeAlerts(ch chan<- HealthAlert) event.Subscription {
+	return w.alertFeed.Subscribe(ch)
+}
+
+// loop tracks the imported blocks and periodically checks the consensus
+// health, until quit is closed.
+func (w *healthWatchdog) loop(quit chan bool) {
+	heads := make(chan core.ChainHeadEvent, 10)
+	sub := w.chain.SubscribeChainHeadEvent(heads)
+	defer sub.Unsubscribe()
+
+	ticker := time.NewTicker(healthCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-heads:
+			w.lock.Lock()
+			w.lastBlock = time.Now()
+			w.lock.Unlock()
+		case <-ticker.C:
+			w.check()
+		case <-sub.Err():
+			return
+		case <-quit:
+			return
+		}
+	}
+}
+
+// PublicNetworkHealthAPI provides the consensus health of the network.
+type PublicNetworkHealthAPI struct {
+	watchdog *healthWatchdog
+}
+
+// NewPublicNetworkHealthAPI creates a new consensus health API.
+func NewPublicNetworkHealthAPI(watchdog *healthWatchdog) *PublicNetworkHealthAPI {
+	return &PublicNetworkHealthAPI{watchdog}
+}
+
+// NetworkHealth returns the consensus health checks, along with the alerts
+// raised by those exceeding their thresholds.
+func (api *PublicNetworkHealthAPI) NetworkHealth() *NetworkHealth {
+	return api.watchdog.health(time.Now())
+}
@@ -0,0 +1,90 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/eth/downloader"
+)
+
+// roundEngine is a consensus engine reporting a fixed round.
+type roundEngine struct {
+	consensus.Engine
+	round uint64
+}
+
+func (e *roundEngine) CurrentRound() uint64 { return e.round }
+
+// Tests that the consensus health alerts are raised by the checks exceeding
+// their thresholds only.
+func TestNetworkHealth(t *testing.T) {
+	pm, _ := newTestProtocolManagerMust(t, downloader.FullSync, 0, nil, nil)
+	defer pm.Stop()
+
+	poolConfig := core.DefaultTxPoolConfig
+	poolConfig.Journal = ""
+	pool := core.NewTxPool(poolConfig, pm.blockchain.Config(), pm.blockchain)
+	defer pool.Stop()
+
+	reasons := func(h *NetworkHealth) []string {
+		var reasons []string
+		for _, alert := range h.Alerts {
+			reasons = append(reasons, alert.Reason)
+		}
+		return reasons
+	}
+	// Istanbul: stalled chain and failing rounds
+	engine := &roundEngine{round: 2}
+	w := newHealthWatchdog(DefaultHealthConfig, pm.blockchain, pool, engine, false)
+	start := w.lastBlock
+	if h := w.health(start); !h.Healthy || h.Round == nil || *h.Round != 2 || h.LeaderChanges != nil {
+		t.Errorf("fresh istanbul health mismatch: have %+v", h)
+	}
+	engine.round = 4
+	h := w.health(start.Add(2 * time.Minute))
+	if have := reasons(h); h.Healthy || len(have) != 2 || have[0] != HealthBlockStall || have[1] != HealthRoundChanges {
+		t.Errorf("stalled istanbul alerts mismatch: have %v", have)
+	}
+	if h.SinceLastBlock != 120 {
+		t.Errorf("time since last block mismatch: have %d, want 120", h.SinceLastBlock)
+	}
+	// Raft: idle chain isn't stalled, leader churn expires with the window
+	w = newHealthWatchdog(DefaultHealthConfig, pm.blockchain, pool, pm.blockchain.Engine(), true)
+	for leader := uint64(1); leader <= 4; leader++ {
+		w.reportLeader(leader)
+		w.reportLeader(0) // Elections without a leader aren't changes
+		w.reportLeader(leader)
+	}
+	h = w.health(start.Add(2 * time.Minute))
+	if have := reasons(h); len(have) != 1 || have[0] != HealthLeaderChurn || *h.LeaderChanges != 4 || h.Round != nil {
+		t.Errorf("churning raft health mismatch: have %+v", h)
+	}
+	if h := w.health(time.Now().Add(DefaultHealthConfig.ChurnWindow + time.Minute)); !h.Healthy || *h.LeaderChanges != 0 {
+		t.Errorf("settled raft health mismatch: have %+v", h)
+	}
+	// Raft: followers lagging behind the leader
+	w.reportRaftLag(DefaultHealthConfig.RaftLag + 1)
+	h = w.health(time.Now().Add(DefaultHealthConfig.ChurnWindow + time.Minute))
+	if have := reasons(h); len(have) != 1 || have[0] != HealthRaftLag || *h.RaftLag != hexutil.Uint64(DefaultHealthConfig.RaftLag+1) {
+		t.Errorf("lagging raft health mismatch: have %+v", h)
+	}
+}
@@ -0,0 +1,278 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/istanbul"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/kms"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+var (
+	errVersionNotSupported = errors.New("peer doesn't attest its version, upgrade required")
+	errVersionTimeout      = errors.New("version request timed out")
+	errInvalidAttestation  = errors.New("version not signed by the peer")
+)
+
+// NodeVersion is the software version and consensus setup attested by a node.
+type NodeVersion struct {
+	Version         string          `json:"version"`
+	QuorumVersion   string          `json:"quorumVersion"`
+	Go              string          `json:"go"`
+	ChainConfigHash common.Hash     `json:"chainConfigHash"`
+	Consensus       string          `json:"consensus"`
+	ConsensusParams json.RawMessage `json:"consensusParams,omitempty"`
+}
+
+// matches returns whether two nodes run the same release with the same chain
+// configuration and consensus parameters, regardless of their Go version.
+func (v *NodeVersion) matches(other *NodeVersion) bool {
+	return v.Version == other.Version && v.QuorumVersion == other.QuorumVersion &&
+		v.ChainConfigHash == other.ChainConfigHash && v.Consensus == other.Consensus &&
+		bytes.Equal(v.ConsensusParams, other.ConsensusParams)
+}
+
+// PeerVersion is the version attested by a peer, or the reason it couldn't be
+// obtained.
+type PeerVersion struct {
+	Peer      string        `json:"peer"`
+	Version   *NodeVersion  `json:"version,omitempty"`
+	Signature hexutil.Bytes `json:"signature,omitempty"` // Node key signature of the challenge and version
+	Match     bool          `json:"match"`
+	Error     string        `json:"error,omitempty"`
+}
+
+// NetworkVersions are the versions attested by the connected peers, compared
+// with the local one.
+type NetworkVersions struct {
+	Local      *NodeVersion   `json:"local"`
+	Peers      []*PeerVersion `json:"peers"`
+	Consistent bool           `json:"consistent"` // Whether every peer runs the local version and setup
+}
+
+// nodeVersionRequest is the payload of GetNodeVersionMsg, the challenge making
+// the attestation fresh.
+type nodeVersionRequest struct {
+	ID        uint64
+	Challenge common.Hash
+}
+
+// nodeVersionResponse is the payload of NodeVersionMsg, carrying the JSON
+// encoded version signed along with the challenge by the node key.
+type nodeVersionResponse struct {
+	ID        uint64
+	Version   []byte
+	Signature []byte
+}
+
+// nodeVersionHash is the hash signed to attest a version.
+func nodeVersionHash(challenge common.Hash, version []byte) []byte {
+	return crypto.Keccak256(challenge[:], version)
+}
+
+// nodeAttestor signs the local version with the node key.
+type nodeAttestor struct {
+	signer  kms.Signer
+	version []byte // JSON encoded local version
+}
+
+// newNodeAttestor creates an attestor of the running release, the chain
+// configuration and the given consensus engine parameters.
+func newNodeAttestor(signer kms.Signer, config *params.ChainConfig, consensus string, consensusParams interface{}) (*nodeAttestor, error) {
+	_, hash, err := config.EncodeJSON()
+	if err != nil {
+		return nil, err
+	}
+	version := &NodeVersion{
+		Version:         params.VersionWithMeta,
+		QuorumVersion:   params.QuorumVersion,
+		Go:              runtime.Version(),
+		ChainConfigHash: hash,
+		Consensus:       consensus,
+	}
+	if consensusParams != nil {
+		if version.ConsensusParams, err = json.Marshal(consensusParams); err != nil {
+			return nil, err
+		}
+	}
+	blob, err := json.Marshal(version)
+	if err != nil {
+		return nil, err
+	}
+	return &nodeAttestor{signer: signer, version: blob}, nil
+}
+
+// consensusSetup returns the consensus engine of the node along with the
+// parameters configured locally, which the nodes must agree on beside the chain
+// configuration.
+func consensusSetup(chainConfig *params.ChainConfig, config *Config) (string, interface{}) {
+	switch {
+	case chainConfig.Istanbul != nil:
+		return "istanbul", &struct {
+			RequestTimeout uint64                  `json:"requestTimeout"`
+			BlockPeriod    uint64                  `json:"blockPeriod"`
+			ProposerPolicy istanbul.ProposerPolicy `json:"proposerPolicy"`
+			Epoch          uint64                  `json:"epoch"`
+			Ceil2Nby3Block *big.Int                `json:"ceil2Nby3Block"`
+		}{config.Istanbul.RequestTimeout, config.Istanbul.BlockPeriod, config.Istanbul.ProposerPolicy, config.Istanbul.Epoch, config.Istanbul.Ceil2Nby3Block}
+	case config.RaftMode:
+		return "raft", nil
+	case chainConfig.Clique != nil:
+		return "clique", nil
+	}
+	return "ethash", nil
+}
+
+// setAttestor sets up the attestation of the local version to the peers.
+func (c *chainConfigChecker) setAttestor(attest *nodeAttestor) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.attest = attest
+}
+
+// attestVersion answers a version request of a peer.
+func (c *chainConfigChecker) attestVersion(req nodeVersionRequest) (*nodeVersionResponse, error) {
+	c.lock.RLock()
+	attest := c.attest
+	c.lock.RUnlock()
+
+	res := &nodeVersionResponse{ID: req.ID}
+	if attest == nil {
+		return res, nil
+	}
+	sig, err := attest.signer.Sign(nodeVersionHash(req.Challenge, attest.version))
+	if err != nil {
+		return nil, err
+	}
+	res.Version, res.Signature = attest.version, sig
+	return res, nil
+}
+
+// requestVersion asks a peer to attest its version and checks the attestation
+// is signed by the key of the peer.
+func (c *chainConfigChecker) requestVersion(ctx context.Context, peer *chainConfigPeer) (*PeerVersion, error) {
+	if peer.version < 2 {
+		return nil, errVersionNotSupported
+	}
+	req := nodeVersionRequest{}
+	rand.Read(req.Challenge[:])
+
+	c.lock.Lock()
+	c.nextID++
+	req.ID = c.nextID
+	c.lock.Unlock()
+
+	ch := make(chan *nodeVersionResponse, 1)
+	peer.lock.Lock()
+	peer.attested[req.ID] = ch
+	peer.lock.Unlock()
+
+	defer func() {
+		peer.lock.Lock()
+		delete(peer.attested, req.ID)
+		peer.lock.Unlock()
+	}()
+	if err := p2p.Send(peer.rw, GetNodeVersionMsg, &req); err != nil {
+		return nil, err
+	}
+	timeout := time.NewTimer(chainConfigTimeout)
+	defer timeout.Stop()
+
+	var res *nodeVersionResponse
+	select {
+	case res = <-ch:
+	case <-timeout.C:
+		return nil, errVersionTimeout
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if len(res.Signature) == 0 {
+		return nil, errInvalidAttestation
+	}
+	pub, err := crypto.SigToPub(nodeVersionHash(req.Challenge, res.Version), res.Signature)
+	if err != nil || enode.PubkeyToIDV4(pub) != peer.id {
+		return nil, errInvalidAttestation
+	}
+	version := new(NodeVersion)
+	if err := json.Unmarshal(res.Version, version); err != nil {
+		return nil, err
+	}
+	return &PeerVersion{Peer: peer.id.String(), Version: version, Signature: res.Signature}, nil
+}
+
+// NetworkVersions collects the versions attested by all the connected peers and
+// compares them with the local one.
+func (c *chainConfigChecker) NetworkVersions(ctx context.Context) (*NetworkVersions, error) {
+	c.lock.RLock()
+	attest := c.attest
+	peers := make([]*chainConfigPeer, 0, len(c.peers))
+	for _, peer := range c.peers {
+		peers = append(peers, peer)
+	}
+	c.lock.RUnlock()
+
+	if attest == nil {
+		return nil, errors.New("version attestation not set up")
+	}
+	local := new(NodeVersion)
+	if err := json.Unmarshal(attest.version, local); err != nil {
+		return nil, err
+	}
+	report := &NetworkVersions{Local: local, Peers: make([]*PeerVersion, len(peers)), Consistent: true}
+
+	var wg sync.WaitGroup
+	for i, peer := range peers {
+		wg.Add(1)
+		go func(i int, peer *chainConfigPeer) {
+			defer wg.Done()
+
+			res, err := c.requestVersion(ctx, peer)
+			if err != nil {
+				res = &PeerVersion{Peer: peer.id.String(), Error: err.Error()}
+			} else {
+				res.Match = local.matches(res.Version)
+			}
+			report.Peers[i] = res
+		}(i, peer)
+	}
+	wg.Wait()
+
+	sort.Slice(report.Peers, func(i, j int) bool { return report.Peers[i].Peer < report.Peers[j].Peer })
+	for _, peer := range report.Peers {
+		if !peer.Match {
+			report.Consistent = false
+		}
+	}
+	return report, nil
+}
@@ -0,0 +1,112 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/kms"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// newAttestingChecker creates a chain config checker attesting the given
+// consensus parameters with a new node key.
+func newAttestingChecker(t *testing.T, epoch uint64) (*chainConfigChecker, *ecdsa.PrivateKey) {
+	key, _ := crypto.GenerateKey()
+	checker := newChainConfigChecker(params.AllCliqueProtocolChanges)
+	attestor, err := newNodeAttestor(kms.NewLocalSigner(key), params.AllCliqueProtocolChanges, "clique", map[string]uint64{"epoch": epoch})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checker.setAttestor(attestor)
+	return checker, key
+}
+
+// connectCheckers runs the chaincfg protocol between a local checker and its
+// peers, returning once all of them are registered.
+func connectCheckers(t *testing.T, local *chainConfigChecker, localKey *ecdsa.PrivateKey, version uint, peerIDs []enode.ID, peers []*chainConfigChecker) {
+	for i, peer := range peers {
+		rwA, rwB := newQueuedPipe()
+		go local.handle(peerIDs[i], version, rwA)
+		go peer.handle(enode.PubkeyToIDV4(&localKey.PublicKey), version, rwB)
+	}
+	for start := time.Now(); ; time.Sleep(10 * time.Millisecond) {
+		local.lock.RLock()
+		n := len(local.peers)
+		local.lock.RUnlock()
+		if n == len(peers) {
+			return
+		}
+		if time.Since(start) > 5*time.Second {
+			t.Fatalf("peers not registered")
+		}
+	}
+}
+
+// Tests that the versions attested by the peers are collected, checked against
+// their node keys and compared with the local version.
+func TestNetworkVersions(t *testing.T) {
+	local, localKey := newAttestingChecker(t, 30000)
+	same, sameKey := newAttestingChecker(t, 30000)
+	other, otherKey := newAttestingChecker(t, 100)
+	forged, _ := newAttestingChecker(t, 30000)
+	legacy, legacyKey := newAttestingChecker(t, 30000)
+
+	ids := []enode.ID{
+		enode.PubkeyToIDV4(&sameKey.PublicKey),
+		enode.PubkeyToIDV4(&otherKey.PublicKey),
+		{0xff}, // Not the key signing the attestation of forged
+	}
+	connectCheckers(t, local, localKey, chainConfigProtocolVersion, ids, []*chainConfigChecker{same, other, forged})
+
+	report, err := local.NetworkVersions(context.Background())
+	if err != nil {
+		t.Fatalf("failed to collect versions: %v", err)
+	}
+	if report.Consistent {
+		t.Errorf("network with a different and a forged version reported consistent")
+	}
+	results := make(map[string]*PeerVersion)
+	for _, peer := range report.Peers {
+		results[peer.Peer] = peer
+	}
+	if res := results[ids[0].String()]; res == nil || !res.Match || res.Version.Version != params.VersionWithMeta {
+		t.Errorf("matching peer mismatch: have %+v", res)
+	}
+	if res := results[ids[1].String()]; res == nil || res.Match || res.Error != "" {
+		t.Errorf("peer with other consensus parameters mismatch: have %+v", res)
+	}
+	if res := results[ids[2].String()]; res == nil || res.Error != errInvalidAttestation.Error() {
+		t.Errorf("forged attestation mismatch: have %+v", res)
+	}
+	// Peers running the previous protocol version can't attest
+	oldLocal, oldKey := newAttestingChecker(t, 30000)
+	connectCheckers(t, oldLocal, oldKey, 1, []enode.ID{enode.PubkeyToIDV4(&legacyKey.PublicKey)}, []*chainConfigChecker{legacy})
+
+	report, err = oldLocal.NetworkVersions(context.Background())
+	if err != nil {
+		t.Fatalf("failed to collect versions: %v", err)
+	}
+	if report.Consistent || len(report.Peers) != 1 || report.Peers[0].Error != errVersionNotSupported.Error() {
+		t.Errorf("legacy peer mismatch: have %+v", report.Peers)
+	}
+}
@@ -0,0 +1,200 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"runtime"
+
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const (
+	GCModeArchive = "archive"
+	GCModeFull    = "full"
+
+	RoleValidator = "validator"
+	RoleObserver  = "observer"
+)
+
+// nodeModeKey is the database key under which the last operational mode set
+// through the admin API is persisted, so it survives a restart.
+var nodeModeKey = []byte("quorum-node-mode")
+
+var errRoleNotSupported = errors.New("consensus engine does not support switching between validator and observer")
+
+// NodeMode describes the operational mode of a node.
+type NodeMode struct {
+	GCMode string `json:"gcmode"`
+	Mining bool   `json:"mining"`
+	Role   string `json:"role"`
+}
+
+// NodeModeArgs carries the requested changes to the operational mode of a
+// node. Fields left nil are not changed.
+type NodeModeArgs struct {
+	GCMode *string `json:"gcmode"`
+	Mining *bool   `json:"mining"`
+	Role   *string `json:"role"`
+}
+
+// readNodeMode retrieves the persisted operational mode, or nil if none was
+// ever set.
+func readNodeMode(db ethdb.Database) *NodeMode {
+	data, _ := db.Get(nodeModeKey)
+	if len(data) == 0 {
+		return nil
+	}
+	var mode NodeMode
+	if err := json.Unmarshal(data, &mode); err != nil {
+		log.Error("Invalid persisted node mode", "err", err)
+		return nil
+	}
+	return &mode
+}
+
+// writeNodeMode persists the operational mode.
+func writeNodeMode(db ethdb.Database, mode *NodeMode) error {
+	data, err := json.Marshal(mode)
+	if err != nil {
+		return err
+	}
+	return db.Put(nodeModeKey, data)
+}
+
+// checkGCModeSwitch compares the configured pruning mode with the one last set
+// through the admin API, warning if the switch leaves the state history
+// incomplete. The persisted mode is left as is, it only changes on an explicit
+// switch at runtime.
+func checkGCModeSwitch(db ethdb.Database, archive bool) {
+	gcmode := GCModeFull
+	if archive {
+		gcmode = GCModeArchive
+	}
+	mode := readNodeMode(db)
+	if mode == nil {
+		return
+	}
+	switch {
+	case mode.GCMode == gcmode:
+	case mode.GCMode == GCModeFull && archive:
+		log.Warn("Switching to archive mode after pruning, the state of past blocks is incomplete", "previous", mode.GCMode, "gcmode", gcmode)
+	case mode.GCMode != "":
+		log.Warn("Configured pruning mode differs from the one set at runtime", "previous", mode.GCMode, "gcmode", gcmode)
+	}
+}
+
+// PersistedNodeMode returns the operational mode last set through the admin
+// API, or nil if it was never changed at runtime.
+func (s *Ethereum) PersistedNodeMode() *NodeMode {
+	return readNodeMode(s.chainDb)
+}
+
+// NodeMode returns the current operational mode of the node.
+func (s *Ethereum) NodeMode() *NodeMode {
+	mode := &NodeMode{
+		GCMode: GCModeFull,
+		Mining: s.IsMining(),
+		Role:   RoleObserver,
+	}
+	if s.blockchain.ArchiveMode() {
+		mode.GCMode = GCModeArchive
+	}
+	if s.canSwitchRole() && mode.Mining {
+		mode.Role = RoleValidator
+	}
+	return mode
+}
+
+// canSwitchRole reports whether the consensus engine lets a node step in and
+// out of block production on its own. Istanbul and Clique validators only
+// participate while the miner is running; Raft membership and roles are
+// decided by the cluster.
+func (s *Ethereum) canSwitchRole() bool {
+	if s.config.RaftMode {
+		return false
+	}
+	switch s.engine.(type) {
+	case consensus.Istanbul:
+		return true
+	}
+	return s.chainConfig.Clique != nil
+}
+
+// SetNodeMode applies the requested operational mode changes and persists the
+// resulting mode. Pruning changes are applied between two block writes, and
+// the miner only stops once the block it is working on has been abandoned, so
+// the switch never happens half way through a block.
+func (s *Ethereum) SetNodeMode(args NodeModeArgs) (*NodeMode, error) {
+	s.modeLock.Lock()
+	defer s.modeLock.Unlock()
+
+	mining := s.IsMining()
+	if args.Mining != nil {
+		if s.config.RaftMode {
+			return nil, errors.New("block creation is managed by raft and cannot be toggled")
+		}
+		mining = *args.Mining
+	}
+	if args.Role != nil {
+		if !s.canSwitchRole() {
+			return nil, errRoleNotSupported
+		}
+		switch *args.Role {
+		case RoleValidator:
+			mining = true
+		case RoleObserver:
+			mining = false
+		default:
+			return nil, fmt.Errorf("invalid role %q, must be %q or %q", *args.Role, RoleValidator, RoleObserver)
+		}
+	}
+	if args.GCMode != nil {
+		switch *args.GCMode {
+		case GCModeArchive, GCModeFull:
+		default:
+			return nil, fmt.Errorf("invalid gcmode %q, must be %q or %q", *args.GCMode, GCModeArchive, GCModeFull)
+		}
+		if err := s.blockchain.SetArchiveMode(*args.GCMode == GCModeArchive); err != nil {
+			return nil, err
+		}
+	}
+	if mining != s.IsMining() {
+		if mining {
+			if err := s.StartMining(runtime.NumCPU()); err != nil {
+				return nil, err
+			}
+		} else {
+			s.StopMining()
+		}
+	}
+	mode := s.NodeMode()
+	// The miner may defer its start until sync completes, persist the intent
+	mode.Mining = mining
+	if s.canSwitchRole() && mining {
+		mode.Role = RoleValidator
+	}
+	if err := writeNodeMode(s.chainDb, mode); err != nil {
+		return nil, err
+	}
+	log.Info("Node operational mode updated", "gcmode", mode.GCMode, "mining", mode.Mining, "role", mode.Role)
+	return mode, nil
+}
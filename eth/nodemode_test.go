@@ -0,0 +1,57 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+func TestNodeModePersistence(t *testing.T) {
+	db := ethdb.NewMemDatabase()
+	if mode := readNodeMode(db); mode != nil {
+		t.Fatalf("unexpected mode in empty database: %+v", mode)
+	}
+	want := &NodeMode{GCMode: GCModeArchive, Mining: true, Role: RoleValidator}
+	if err := writeNodeMode(db, want); err != nil {
+		t.Fatalf("failed to write node mode: %v", err)
+	}
+	if have := readNodeMode(db); !reflect.DeepEqual(have, want) {
+		t.Fatalf("node mode mismatch: have %+v, want %+v", have, want)
+	}
+}
+
+func TestGCModeSwitch(t *testing.T) {
+	db := ethdb.NewMemDatabase()
+
+	// Starting without a mode set at runtime persists nothing
+	checkGCModeSwitch(db, true)
+	if mode := readNodeMode(db); mode != nil {
+		t.Fatalf("unexpected mode persisted on start: %+v", mode)
+	}
+	// Starting with another mode than the one set at runtime leaves it as is
+	want := &NodeMode{GCMode: GCModeArchive, Mining: true}
+	if err := writeNodeMode(db, want); err != nil {
+		t.Fatalf("failed to write node mode: %v", err)
+	}
+	checkGCModeSwitch(db, false)
+	if have := readNodeMode(db); !reflect.DeepEqual(have, want) {
+		t.Fatalf("node mode mismatch: have %+v, want %+v", have, want)
+	}
+}
@@ -0,0 +1,200 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"math/big"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// pendingView is the block the pending transactions of the pool would make on
+// top of the latest block, executed in nonce order. The miner only maintains a
+// pending block while mining, and Raft doesn't run it, so the view is built on
+// demand for the RPC calls on the pending block to agree with each other and
+// with the next block. It is cached until the pool content or the head changes.
+type pendingView struct {
+	chain    *core.BlockChain
+	pool     *core.TxPool
+	coinbase func() common.Address // Beneficiary of the next block
+	gasLimit func(parent *types.Block) uint64
+	raftMode bool // Whether block times are in nanoseconds
+
+	version uint64 // Number of pool and chain changes seen, accessed atomically
+
+	lock    sync.Mutex
+	built   uint64 // Version the cached view was built at
+	block   *types.Block
+	public  *state.StateDB
+	private *state.StateDB
+	nonces  map[common.Address]uint64 // Pending nonces of the senders beyond the gas limit
+}
+
+func newPendingView(chain *core.BlockChain, pool *core.TxPool, coinbase func() common.Address, gasLimit func(parent *types.Block) uint64, raftMode bool) *pendingView {
+	return &pendingView{
+		chain:    chain,
+		pool:     pool,
+		coinbase: coinbase,
+		gasLimit: gasLimit,
+		raftMode: raftMode,
+	}
+}
+
+// get returns the pending block with copies of its public and private states,
+// free for the caller to modify.
+func (v *pendingView) get() (*types.Block, *state.StateDB, *state.StateDB, error) {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+
+	if err := v.refresh(); err != nil {
+		return nil, nil, nil, err
+	}
+	return v.block, v.public.Copy(), v.private.Copy(), nil
+}
+
+// nonce returns the pending nonce of an account. The transactions left out of
+// the pending block for want of gas still count, as they only wait for a later
+// block: the nonce is bounded by the gaps and the failures, not the gas limit.
+func (v *pendingView) nonce(addr common.Address) (uint64, error) {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+
+	if err := v.refresh(); err != nil {
+		return 0, err
+	}
+	if nonce, ok := v.nonces[addr]; ok {
+		return nonce, nil
+	}
+	return v.public.GetNonce(addr), nil
+}
+
+// refresh rebuilds the cached view if the pool or the head changed since.
+func (v *pendingView) refresh() error {
+	version, head := atomic.LoadUint64(&v.version), v.chain.CurrentBlock()
+	if v.block != nil && v.built == version && v.block.ParentHash() == head.Hash() {
+		return nil
+	}
+	block, public, private, nonces, err := v.build(head)
+	if err != nil {
+		return err
+	}
+	v.built, v.block, v.public, v.private, v.nonces = version, block, public, private, nonces
+	return nil
+}
+
+// build executes the pending transactions on the state of the parent block,
+// leaving out the ones failing as the miner does. It also returns the nonces
+// following the pending transactions of the senders cut off by the gas limit.
+func (v *pendingView) build(parent *types.Block) (*types.Block, *state.StateDB, *state.StateDB, map[common.Address]uint64, error) {
+	defer pendingBuildTimer.UpdateSince(time.Now())
+
+	public, private, err := v.chain.StateAt(parent.Root())
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	timestamp := time.Now().Unix()
+	if v.raftMode {
+		timestamp = time.Now().UnixNano()
+	}
+	if parent.Time().Int64() >= timestamp {
+		timestamp = parent.Time().Int64() + 1
+	}
+	header := &types.Header{
+		ParentHash: parent.Hash(),
+		Number:     new(big.Int).Add(parent.Number(), common.Big1),
+		GasLimit:   v.gasLimit(parent),
+		Time:       big.NewInt(timestamp),
+		Coinbase:   v.coinbase(),
+		Difficulty: parent.Difficulty(),
+	}
+	pending, err := v.pool.Pending()
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	// The iteration below consumes the lists, so keep the nonces following them
+	next := make(map[common.Address]uint64, len(pending))
+	for from, list := range pending {
+		next[from] = list[len(list)-1].Nonce() + 1
+	}
+	var (
+		config   = v.chain.Config()
+		signer   = types.MakeSigner(config, header.Number)
+		txs      = types.NewTransactionsByPriceAndNonce(signer, pending)
+		gasPool  = new(core.GasPool).AddGas(header.GasLimit)
+		included types.Transactions
+		receipts types.Receipts
+		nonces   = make(map[common.Address]uint64)
+	)
+	for tx := txs.Peek(); tx != nil; tx = txs.Peek() {
+		publicSnap, privateSnap := public.Snapshot(), private.Snapshot()
+		public.Prepare(tx.Hash(), common.Hash{}, len(included))
+		private.Prepare(tx.Hash(), common.Hash{}, len(included))
+
+		receipt, _, _, err := core.ApplyTransaction(config, v.chain, &header.Coinbase, gasPool, public, private, header, tx, &header.GasUsed, vm.Config{})
+		if err != nil {
+			// The later transactions of the sender can't execute either
+			log.Trace("Leaving transaction out of the pending block", "hash", tx.Hash(), "err", err)
+			if err == core.ErrGasLimitReached {
+				from, _ := types.Sender(signer, tx)
+				nonces[from] = next[from]
+			}
+			public.RevertToSnapshot(publicSnap)
+			private.RevertToSnapshot(privateSnap)
+			txs.Pop()
+			continue
+		}
+		included, receipts = append(included, tx), append(receipts, receipt)
+		txs.Shift()
+	}
+	header.Root = public.IntermediateRoot(config.IsEIP158(header.Number))
+	return types.NewBlock(header, included, nil, receipts), public, private, nonces, nil
+}
+
+// loop invalidates the view on the changes of the pool content and of the head
+// until quit is closed.
+func (v *pendingView) loop(quit chan bool) {
+	diffs := make(chan core.TxPoolDiffEvent, 16)
+	diffSub := v.pool.SubscribeTxPoolDiffEvent(diffs)
+	defer diffSub.Unsubscribe()
+
+	heads := make(chan core.ChainHeadEvent, 16)
+	headSub := v.chain.SubscribeChainHeadEvent(heads)
+	defer headSub.Unsubscribe()
+
+	for {
+		select {
+		case <-diffs:
+			atomic.AddUint64(&v.version, 1)
+		case <-heads:
+			atomic.AddUint64(&v.version, 1)
+		case <-diffSub.Err():
+			return
+		case <-headSub.Err():
+			return
+		case <-quit:
+			return
+		}
+	}
+}
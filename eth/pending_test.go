@@ -0,0 +1,105 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that the pending view holds the pending transactions fitting in the next
+// block, and follows the changes of the pool.
+func TestPendingView(t *testing.T) {
+	var (
+		db    = ethdb.NewMemDatabase()
+		gspec = &core.Genesis{
+			Config:   params.TestChainConfig,
+			GasLimit: 100000,
+			Alloc:    core.GenesisAlloc{testBank: {Balance: big.NewInt(1000000000)}},
+		}
+		genesis  = gspec.MustCommit(db)
+		chain, _ = core.NewBlockChain(db, nil, gspec.Config, ethash.NewFaker(), vm.Config{}, nil)
+	)
+	defer chain.Stop()
+
+	config := core.DefaultTxPoolConfig
+	config.Journal = ""
+	pool := core.NewTxPool(config, gspec.Config, chain)
+	defer pool.Stop()
+
+	coinbase := common.HexToAddress("0xc0ffee")
+	view := newPendingView(chain, pool, func() common.Address { return coinbase }, func(parent *types.Block) uint64 {
+		return parent.GasLimit()
+	}, false)
+	quit := make(chan bool)
+	defer close(quit)
+	go view.loop(quit)
+
+	// Wait for the subscriptions of the loop before changing the pool
+	time.Sleep(50 * time.Millisecond)
+
+	// Only the two first transactions fit in the gas limit of the block
+	signer := types.NewEIP155Signer(gspec.Config.ChainID)
+	for nonce := uint64(0); nonce < 3; nonce++ {
+		tx, _ := types.SignTx(types.NewTransaction(nonce, common.Address{0x01}, big.NewInt(1), 60000, big.NewInt(1), nil), signer, testBankKey)
+		if err := pool.AddLocal(tx); err != nil {
+			t.Fatalf("failed to add transaction %d: %v", nonce, err)
+		}
+	}
+	var block *types.Block
+	for start := time.Now(); time.Since(start) < time.Second; time.Sleep(10 * time.Millisecond) {
+		if b, _, _, err := view.get(); err != nil {
+			t.Fatalf("failed to get the pending block: %v", err)
+		} else if block = b; len(block.Transactions()) > 0 {
+			break
+		}
+	}
+	if have := len(block.Transactions()); have != 2 {
+		t.Fatalf("pending transactions: have %d, want 2", have)
+	}
+	if block.ParentHash() != genesis.Hash() || block.NumberU64() != 1 || block.Coinbase() != coinbase {
+		t.Errorf("pending block not on top of the latest: parent %x, number %d", block.ParentHash(), block.NumberU64())
+	}
+	_, public, _, _ := view.get()
+	if nonce := public.GetNonce(testBank); nonce != 2 {
+		t.Errorf("pending nonce: have %d, want 2", nonce)
+	}
+	if balance := public.GetBalance(common.Address{0x01}); balance.Cmp(big.NewInt(2)) != 0 {
+		t.Errorf("pending balance: have %v, want 2", balance)
+	}
+	// The transaction beyond the gas limit still counts in the pending nonce
+	if nonce, err := view.nonce(testBank); err != nil || nonce != 3 {
+		t.Errorf("pending nonce beyond the gas limit: have %d, %v, want 3", nonce, err)
+	}
+	if nonce, err := view.nonce(coinbase); err != nil || nonce != 0 {
+		t.Errorf("pending nonce of an idle account: have %d, %v, want 0", nonce, err)
+	}
+	// The view is cached until the pool changes, and hands out copies
+	public.SetNonce(testBank, 10)
+	if cached, public, _, _ := view.get(); cached != block || public.GetNonce(testBank) != 2 {
+		t.Errorf("pending view rebuilt or modified without changes")
+	}
+}
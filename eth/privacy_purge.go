@@ -0,0 +1,230 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/private"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+func init() {
+	rpc.RegisterErrorMatcher(rpc.CodeServerError, "NOT_A_PARTY", func(err error) bool {
+		_, ok := err.(*notPartyError)
+		return ok
+	})
+}
+
+// notPartyError is returned when the sender of a transaction to purge isn't a
+// party of the agreement.
+type notPartyError struct {
+	sender common.Address
+	tx     common.Hash
+}
+
+func (e *notPartyError) Error() string {
+	return fmt.Sprintf("sender %x of transaction %x is not a party", e.sender, e.tx)
+}
+
+// errPurgeNoManager is returned if a purge is requested without the private
+// transaction manager, which the participants of the transactions are checked
+// against.
+var errPurgeNoManager = errors.New("purge requires the private transaction manager")
+
+// notRecipientError is returned when a participant of a transaction to purge
+// isn't a recipient of the agreement.
+type notRecipientError struct {
+	participant string
+	tx          common.Hash
+}
+
+func (e *notRecipientError) Error() string {
+	return fmt.Sprintf("participant %s of transaction %x is not a recipient of the agreement", e.participant, e.tx)
+}
+
+// payloadEraser erases private payloads from the private transaction manager.
+type payloadEraser interface {
+	Participants(data []byte) ([]string, error)
+	Delete(data []byte) error
+}
+
+// purgePrivateContract checks that all the parties of a private contract agreed
+// to purge it, then erases the payloads of its transactions and its private
+// state, and records the tombstone of the contract. The sender of every listed
+// transaction must be a party of the agreement, and every participant of its
+// payload known to the private transaction manager one of its recipients.
+func purgePrivateContract(chain *core.BlockChain, db ethdb.Database, ptm payloadEraser, agreement *core.PurgeAgreement, sigs []hexutil.Bytes) (*core.PrivateTombstone, error) {
+	if ptm == nil {
+		return nil, errPurgeNoManager
+	}
+	if agreement.Contract == (common.Address{}) {
+		return nil, errors.New("purge agreement without contract")
+	}
+	if len(agreement.Transactions) == 0 {
+		return nil, errors.New("purge agreement without transactions")
+	}
+	if err := core.VerifyPurgeAgreement(agreement, sigs); err != nil {
+		return nil, err
+	}
+	if chain.PrivateTombstone(agreement.Contract) != nil {
+		return nil, core.ErrContractPurged
+	}
+	parties := make(map[common.Address]bool)
+	for _, party := range agreement.Parties {
+		parties[party] = true
+	}
+	recipients := make(map[string]bool)
+	for _, recipient := range agreement.Recipients {
+		recipients[recipient] = true
+	}
+	txs := make([]*types.Transaction, 0, len(agreement.Transactions))
+	for _, hash := range agreement.Transactions {
+		tx, _, number, _ := rawdb.ReadTransaction(db, hash)
+		if tx == nil {
+			return nil, fmt.Errorf("unknown transaction %x", hash)
+		}
+		if !tx.IsPrivate() {
+			return nil, fmt.Errorf("transaction %x is not private", hash)
+		}
+		from, err := types.Sender(types.MakeSigner(chain.Config(), new(big.Int).SetUint64(number)), tx)
+		if err != nil {
+			return nil, err
+		}
+		target := crypto.CreateAddress(from, tx.Nonce())
+		if to := tx.To(); to != nil {
+			target = *to
+		}
+		if target != agreement.Contract {
+			return nil, fmt.Errorf("transaction %x is not sent to contract %x", hash, agreement.Contract)
+		}
+		if !parties[from] {
+			return nil, &notPartyError{from, hash}
+		}
+		participants, err := ptm.Participants(tx.Data())
+		if err != nil {
+			return nil, fmt.Errorf("failed to retrieve the participants of transaction %x: %v", hash, err)
+		}
+		if len(participants) == 0 {
+			return nil, fmt.Errorf("payload of transaction %x unknown to the private transaction manager", hash)
+		}
+		for _, participant := range participants {
+			if !recipients[participant] {
+				return nil, &notRecipientError{participant, hash}
+			}
+		}
+		txs = append(txs, tx)
+	}
+	tombstone := &core.PrivateTombstone{
+		Contract:     agreement.Contract,
+		Agreement:    core.PurgeHash(agreement),
+		Transactions: agreement.Transactions,
+		Parties:      agreement.Parties,
+		Recipients:   agreement.Recipients,
+		Signatures:   sigs,
+		Reason:       agreement.Reason,
+		Erased:       []common.Hash{},
+		Time:         hexutil.Uint64(time.Now().Unix()),
+	}
+	for _, tx := range txs {
+		if err := ptm.Delete(tx.Data()); err != nil {
+			log.Warn("Failed to erase private payload", "contract", agreement.Contract, "tx", tx.Hash(), "err", err)
+			continue
+		}
+		tombstone.Erased = append(tombstone.Erased, tx.Hash())
+	}
+	if err := chain.PurgePrivateContract(tombstone); err != nil {
+		return nil, err
+	}
+	return tombstone, nil
+}
+
+// PublicPurgeAPI provides an API to prepare the purge of private contracts and
+// audit the purged ones.
+type PublicPurgeAPI struct {
+	e *Ethereum
+}
+
+// NewPublicPurgeAPI creates a new private contract purge API.
+func NewPublicPurgeAPI(e *Ethereum) *PublicPurgeAPI {
+	return &PublicPurgeAPI{e}
+}
+
+// PurgeHash returns the hash every party of a purge agreement signs, the eth_sign
+// hash of "purge:<contract>:<agreement digest>".
+func (api *PublicPurgeAPI) PurgeHash(agreement core.PurgeAgreement) common.Hash {
+	return core.PurgeHash(&agreement)
+}
+
+// Tombstone returns the audit record of a purged private contract.
+func (api *PublicPurgeAPI) Tombstone(contract common.Address) (*core.PrivateTombstone, error) {
+	tombstone := api.e.blockchain.PrivateTombstone(contract)
+	if tombstone == nil {
+		return nil, fmt.Errorf("private contract %x not purged", contract)
+	}
+	return tombstone, nil
+}
+
+// Tombstones returns the audit records of all the purged private contracts, in
+// the order they were purged.
+func (api *PublicPurgeAPI) Tombstones() []*core.PrivateTombstone {
+	tombstones := []*core.PrivateTombstone{}
+	for _, contract := range core.ReadPrivateTombstoneList(api.e.chainDb) {
+		if tombstone := api.e.blockchain.PrivateTombstone(contract); tombstone != nil {
+			tombstones = append(tombstones, tombstone)
+		}
+	}
+	return tombstones
+}
+
+// PrivatePurgeAPI provides an API to purge private contracts from this node.
+type PrivatePurgeAPI struct {
+	e *Ethereum
+}
+
+// NewPrivatePurgeAPI creates a new private contract purge API.
+func NewPrivatePurgeAPI(e *Ethereum) *PrivatePurgeAPI {
+	return &PrivatePurgeAPI{e}
+}
+
+// PurgeContract erases a private contract from this node on the agreement of all
+// its parties, given their signatures over quorumPrivacy_purgeHash. The payloads
+// of the listed transactions are erased from the private transaction manager, the
+// contract is erased from the private state and the logs of the transactions from
+// their receipts. The returned tombstone is kept for audit.
+//
+// Only the private state of the head block and the later ones is purged, the
+// contract remaining in the private states of the earlier blocks until they are
+// pruned.
+func (api *PrivatePurgeAPI) PurgeContract(agreement core.PurgeAgreement, signatures []hexutil.Bytes) (*core.PrivateTombstone, error) {
+	var ptm payloadEraser
+	if private.IsEnabled() {
+		ptm = private.P
+	}
+	return purgePrivateContract(api.e.blockchain, api.e.chainDb, ptm, &agreement, signatures)
+}
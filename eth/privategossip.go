@@ -0,0 +1,181 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+)
+
+// Peers the private transactions are sent to. The payloads stay with the
+// private transaction manager, but which parties transact how often can be
+// told from the transactions, so networks may keep them to the nodes needing
+// them to produce the blocks.
+const (
+	PrivateGossipAll        = "all"        // Every peer, as the public transactions
+	PrivateGossipValidators = "validators" // The current validators and the relays
+	PrivateGossipRelays     = "relays"     // The relays only
+	PrivateGossipNone       = "none"       // No peer, the node producing the blocks itself
+)
+
+// PrivateGossipConfig is the policy of the propagation of the private
+// transactions, by role of the node. The validators policy requires an engine
+// with a validator set, which Raft, where any node may become the minter, has
+// not.
+type PrivateGossipConfig struct {
+	Validator string   `toml:",omitempty"` // Peers a validator sends the private transactions to (empty = all)
+	Observer  string   `toml:",omitempty"` // Peers the other nodes send the private transactions to (empty = all)
+	Relays    []string `toml:",omitempty"` // Enode URLs of the relays
+}
+
+// enabled returns whether the private transactions are restricted to some
+// peers in any role.
+func (c *PrivateGossipConfig) enabled() bool {
+	return (c.Validator != "" && c.Validator != PrivateGossipAll) || (c.Observer != "" && c.Observer != PrivateGossipAll)
+}
+
+// errPrivateGossipNoValidators is returned if the private transactions are to be
+// sent to the validators under an engine without validator set.
+var errPrivateGossipNoValidators = errors.New("private transaction gossip to the validators requires an engine with a validator set")
+
+// privateGossip restricts the peers the private transactions are sent to,
+// according to the policy of the current role of the node.
+type privateGossip struct {
+	config     PrivateGossipConfig
+	relays     map[enode.ID]bool
+	role       func() string           // Current role of the node, RoleValidator or RoleObserver
+	validators func() []common.Address // Current validators, nil if the engine has no validator set, returning nil if unknown
+}
+
+func newPrivateGossip(config PrivateGossipConfig, role func() string, validators func() []common.Address) (*privateGossip, error) {
+	for _, policy := range []string{config.Validator, config.Observer} {
+		switch policy {
+		case "", PrivateGossipAll, PrivateGossipRelays, PrivateGossipNone:
+		case PrivateGossipValidators:
+			if validators == nil {
+				return nil, errPrivateGossipNoValidators
+			}
+		default:
+			return nil, fmt.Errorf("invalid private transaction gossip policy %q", policy)
+		}
+	}
+	relays := make(map[enode.ID]bool)
+	for _, url := range config.Relays {
+		node, err := enode.ParseV4(url)
+		if err != nil {
+			return nil, fmt.Errorf("invalid private transaction relay %q: %v", url, err)
+		}
+		relays[node.ID()] = true
+	}
+	return &privateGossip{config: config, relays: relays, role: role, validators: validators}, nil
+}
+
+// policy returns the policy of the current role of the node.
+func (g *privateGossip) policy() string {
+	policy := g.config.Observer
+	if g.role() == RoleValidator {
+		policy = g.config.Validator
+	}
+	if policy == "" {
+		return PrivateGossipAll
+	}
+	return policy
+}
+
+// recipients returns whether a peer may be sent the private transactions under
+// the current policy.
+func (g *privateGossip) recipients() func(p *peer) bool {
+	switch g.policy() {
+	case PrivateGossipNone:
+		return func(p *peer) bool { return false }
+
+	case PrivateGossipRelays:
+		return func(p *peer) bool { return g.relays[p.ID()] }
+
+	case PrivateGossipValidators:
+		validators := g.validators()
+		if validators == nil {
+			log.Warn("Validators of the private transaction gossip unknown, sending to the relays only")
+			return func(p *peer) bool { return g.relays[p.ID()] }
+		}
+		set := make(map[common.Address]bool)
+		for _, validator := range validators {
+			set[validator] = true
+		}
+		return func(p *peer) bool {
+			if g.relays[p.ID()] {
+				return true
+			}
+			key := p.Node().Pubkey()
+			return key != nil && set[crypto.PubkeyToAddress(*key)]
+		}
+	}
+	return func(p *peer) bool { return true }
+}
+
+// filter returns the transactions a peer may be sent, dropping the private
+// ones if it isn't a recipient of those.
+func (g *privateGossip) filter(p *peer, txs types.Transactions) types.Transactions {
+	if g.recipients()(p) {
+		return txs
+	}
+	kept := make(types.Transactions, 0, len(txs))
+	for _, tx := range txs {
+		if !tx.IsPrivate() {
+			kept = append(kept, tx)
+		}
+	}
+	gossipWithheldMeter.Mark(int64(len(txs) - len(kept)))
+	return kept
+}
+
+// broadcastPrivateTxs sends the private transactions to the peers not knowing
+// about them the policy allows, returning the public transactions left to
+// propagate as usual.
+func (pm *ProtocolManager) broadcastPrivateTxs(txs types.Transactions) types.Transactions {
+	var (
+		public    = make(types.Transactions, 0, len(txs))
+		txset     = make(map[*peer]types.Transactions)
+		recipient = pm.privateGossip.recipients()
+	)
+	for _, tx := range txs {
+		if !tx.IsPrivate() {
+			public = append(public, tx)
+			continue
+		}
+		sent := 0
+		for _, peer := range pm.peers.PeersWithoutTx(tx.Hash()) {
+			if recipient(peer) {
+				txset[peer] = append(txset[peer], tx)
+				sent++
+			} else {
+				gossipWithheldMeter.Mark(1)
+			}
+		}
+		log.Trace("Broadcast private transaction", "hash", tx.Hash(), "recipients", sent)
+	}
+	for peer, txs := range txset {
+		peer.AsyncSendTransactions(txs)
+	}
+	return public
+}
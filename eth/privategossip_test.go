@@ -0,0 +1,164 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+)
+
+// Tests that the private transactions are only sent to the peers the policy of
+// the current role of the node allows, the public ones being left to the usual
+// propagation.
+func TestPrivateGossipPolicy(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	relayKey, _ := crypto.GenerateKey()
+	relay := enode.NewV4(&relayKey.PublicKey, nil, 30303, 30303)
+
+	role := RoleObserver
+	gossip, err := newPrivateGossip(PrivateGossipConfig{
+		Validator: PrivateGossipNone,
+		Observer:  PrivateGossipRelays,
+		Relays:    []string{relay.String()},
+	}, func() string { return role }, nil)
+	if err != nil {
+		t.Fatalf("failed to create private gossip: %v", err)
+	}
+	pm := &ProtocolManager{peers: newPeerSet(), privateGossip: gossip}
+	peers := []*peer{
+		newPeer(eth63, p2p.NewPeer(relay.ID(), "relay", nil), nil),
+		newPeer(eth63, p2p.NewPeer(enode.ID{1}, "other", nil), nil),
+	}
+	// The peers are added without their broadcast loop, which would drain the
+	// queues checked below
+	for _, p := range peers {
+		pm.peers.peers[p.id] = p
+	}
+	public := newTestTransaction(key, 0, 0)
+	private := newTestTransaction(key, 1, 0)
+	private.SetPrivate()
+
+	// As an observer, the private transactions go to the relays only
+	left := pm.broadcastPrivateTxs(types.Transactions{public, private})
+	if len(left) != 1 || left[0] != public {
+		t.Fatalf("public transactions mismatch: have %v, want [%x]", left, public.Hash())
+	}
+	select {
+	case txs := <-peers[0].queuedTxs:
+		if len(txs) != 1 || txs[0] != private {
+			t.Errorf("relay sent %d transactions, want the private one", len(txs))
+		}
+	default:
+		t.Errorf("relay not sent the private transaction")
+	}
+	select {
+	case <-peers[1].queuedTxs:
+		t.Errorf("private transaction sent to a non-relay")
+	default:
+	}
+	if txs := gossip.filter(peers[1], types.Transactions{public, private}); len(txs) != 1 || txs[0] != public {
+		t.Errorf("filtered transactions of a non-relay mismatch: have %d, want the public one", len(txs))
+	}
+	if txs := gossip.filter(peers[0], types.Transactions{public, private}); len(txs) != 2 {
+		t.Errorf("filtered transactions of a relay mismatch: have %d, want 2", len(txs))
+	}
+	// As a validator, they are sent to no peer
+	role = RoleValidator
+	private2 := newTestTransaction(key, 2, 0)
+	private2.SetPrivate()
+	pm.broadcastPrivateTxs(types.Transactions{private2})
+	for i, p := range peers {
+		select {
+		case <-p.queuedTxs:
+			t.Errorf("peer %d: private transaction sent by a validator", i)
+		default:
+		}
+	}
+}
+
+// Tests that the validators policy sends the private transactions to the
+// current validators and the relays, or to the relays only while the validators
+// are unknown.
+func TestPrivateGossipValidators(t *testing.T) {
+	relayKey, _ := crypto.GenerateKey()
+	relay := enode.NewV4(&relayKey.PublicKey, nil, 30303, 30303)
+
+	peers := []*peer{
+		newPeer(eth63, p2p.NewPeer(relay.ID(), "relay", nil), nil),
+		newPeer(eth63, p2p.NewPeer(enode.ID{1}, "other", nil), nil),
+	}
+
+	tests := []struct {
+		validators func() []common.Address
+		want       []bool
+	}{
+		{func() []common.Address { return nil }, []bool{true, false}},
+		{func() []common.Address { return []common.Address{} }, []bool{true, false}},
+		{func() []common.Address { return []common.Address{{0xff}} }, []bool{true, false}},
+	}
+	for i, tt := range tests {
+		config := PrivateGossipConfig{Observer: PrivateGossipValidators, Relays: []string{relay.String()}}
+		gossip, err := newPrivateGossip(config, func() string { return RoleObserver }, tt.validators)
+		if err != nil {
+			t.Fatalf("test %d: failed to create private gossip: %v", i, err)
+		}
+		recipient := gossip.recipients()
+		for j, p := range peers {
+			if have := recipient(p); have != tt.want[j] {
+				t.Errorf("test %d, peer %d: recipient mismatch: have %v, want %v", i, j, have, tt.want[j])
+			}
+		}
+	}
+}
+
+// Tests that invalid policies and relays are rejected.
+func TestPrivateGossipConfig(t *testing.T) {
+	tests := []struct {
+		config PrivateGossipConfig
+		valid  bool
+	}{
+		{PrivateGossipConfig{}, true},
+		{PrivateGossipConfig{Validator: PrivateGossipNone, Observer: PrivateGossipValidators}, true},
+		{PrivateGossipConfig{Validator: "some"}, false},
+		{PrivateGossipConfig{Observer: PrivateGossipRelays, Relays: []string{"enode://invalid"}}, false},
+	}
+	validators := func() []common.Address { return nil }
+	for i, tt := range tests {
+		_, err := newPrivateGossip(tt.config, func() string { return RoleObserver }, validators)
+		if valid := err == nil; valid != tt.valid {
+			t.Errorf("test %d: validity mismatch: have %v, want %v (%v)", i, valid, tt.valid, err)
+		}
+	}
+}
+
+// Tests that the validators policy is rejected under engines without validator
+// set.
+func TestPrivateGossipNoValidatorSet(t *testing.T) {
+	config := PrivateGossipConfig{Validator: PrivateGossipValidators}
+	if _, err := newPrivateGossip(config, func() string { return RoleValidator }, nil); err != errPrivateGossipNoValidators {
+		t.Fatalf("error mismatch: have %v, want %v", err, errPrivateGossipNoValidators)
+	}
+	config = PrivateGossipConfig{Validator: PrivateGossipRelays}
+	if _, err := newPrivateGossip(config, func() string { return RoleValidator }, nil); err != nil {
+		t.Fatalf("failed to create private gossip: %v", err)
+	}
+}
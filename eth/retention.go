@@ -0,0 +1,106 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const (
+	// minHistoryRetention is the minimum number of blocks retained whole, for
+	// the reorganisations and the syncing peers to find them.
+	minHistoryRetention = 1024
+
+	// historyPruneBatch is the maximum number of blocks pruned at once, not to
+	// hold the chain insertion for long.
+	historyPruneBatch = 256
+)
+
+// historyPruner deletes the bodies and receipts of the blocks older than the
+// retention period, leaving their headers, as the head moves.
+type historyPruner struct {
+	chain     *core.BlockChain
+	db        ethdb.Database
+	retention uint64
+	protected map[common.Address]bool // Contracts whose blocks are never pruned
+}
+
+func newHistoryPruner(chain *core.BlockChain, db ethdb.Database, retention uint64, protected []common.Address) *historyPruner {
+	p := &historyPruner{
+		chain:     chain,
+		db:        db,
+		retention: retention,
+		protected: make(map[common.Address]bool),
+	}
+	for _, addr := range protected {
+		p.protected[addr] = true
+	}
+	return p
+}
+
+// prune deletes the blocks out of the retention period from the given head, in
+// batches until quit is closed.
+func (p *historyPruner) prune(head uint64, quit chan bool) {
+	if head <= p.retention {
+		return
+	}
+	limit := head - p.retention
+	for tail := core.ReadHistoryTail(p.db); tail < limit; {
+		pruned, err := p.chain.PruneHistory(limit, p.protected, historyPruneBatch)
+		if err != nil {
+			log.Error("Failed to prune block history", "err", err)
+			return
+		}
+		historyPrunedMeter.Mark(int64(pruned))
+
+		// Stop if the canonical chain ends short of the limit
+		next := core.ReadHistoryTail(p.db)
+		if next == tail {
+			return
+		}
+		tail = next
+
+		select {
+		case <-quit:
+			return
+		default:
+		}
+	}
+	log.Debug("Pruned block history", "tail", limit)
+}
+
+// loop prunes the history on every new head until quit is closed.
+func (p *historyPruner) loop(quit chan bool) {
+	heads := make(chan core.ChainHeadEvent, 16)
+	sub := p.chain.SubscribeChainHeadEvent(heads)
+	defer sub.Unsubscribe()
+
+	p.prune(p.chain.CurrentBlock().NumberU64(), quit)
+	for {
+		select {
+		case head := <-heads:
+			p.prune(head.Block.NumberU64(), quit)
+		case <-sub.Err():
+			return
+		case <-quit:
+			return
+		}
+	}
+}
@@ -0,0 +1,511 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/private"
+	_ "github.com/lib/pq" // Postgres driver of the SQL export
+)
+
+const (
+	// sqlExportBatch is the number of blocks exported within a database
+	// transaction, along with the checkpoint they reach.
+	sqlExportBatch = 256
+
+	// sqlExportReorgDepth is the number of recently exported blocks whose hash
+	// is remembered, to replace them if reorganised out of the chain.
+	sqlExportReorgDepth = 128
+)
+
+// SQLExportSchema is the Postgres schema the blocks are exported to. Exporting
+// a block replaces its rows, so that a range can be exported again after an
+// interruption or a reorganisation. The checkpoint is the last block exported,
+// updated in the same database transaction as the blocks up to it.
+const SQLExportSchema = `CREATE TABLE IF NOT EXISTS blocks (
+	number       BIGINT PRIMARY KEY,
+	hash         BYTEA NOT NULL UNIQUE,
+	parent_hash  BYTEA NOT NULL,
+	time         NUMERIC(20) NOT NULL, -- Seconds, nanoseconds on Raft chains
+	coinbase     BYTEA NOT NULL,
+	gas_limit    NUMERIC(20) NOT NULL,
+	gas_used     NUMERIC(20) NOT NULL,
+	tx_count     INTEGER NOT NULL,
+	extra        BYTEA NOT NULL
+);
+CREATE TABLE IF NOT EXISTS transactions (
+	hash         BYTEA PRIMARY KEY,
+	block_number BIGINT NOT NULL REFERENCES blocks (number) ON DELETE CASCADE,
+	tx_index     INTEGER NOT NULL,
+	sender       BYTEA NOT NULL,
+	recipient    BYTEA,                -- NULL for contract creations
+	nonce        NUMERIC(20) NOT NULL,
+	value        NUMERIC(78) NOT NULL,
+	gas          NUMERIC(20) NOT NULL,
+	gas_price    NUMERIC(78) NOT NULL,
+	input        BYTEA NOT NULL,       -- Hash of the payload for private transactions
+	private      BOOLEAN NOT NULL,
+	payload      BYTEA                 -- Private payload, if exported and the local party is a recipient
+);
+CREATE TABLE IF NOT EXISTS receipts (
+	tx_hash             BYTEA PRIMARY KEY REFERENCES transactions (hash) ON DELETE CASCADE,
+	block_number        BIGINT NOT NULL,
+	status              SMALLINT NOT NULL,
+	gas_used            NUMERIC(20) NOT NULL,
+	cumulative_gas_used NUMERIC(20) NOT NULL,
+	contract_address    BYTEA,         -- NULL unless a contract was created
+	private             BOOLEAN NOT NULL
+);
+CREATE TABLE IF NOT EXISTS logs (
+	block_number BIGINT NOT NULL REFERENCES blocks (number) ON DELETE CASCADE,
+	log_index    INTEGER NOT NULL,     -- Index among the exported logs of the block
+	tx_hash      BYTEA NOT NULL,
+	address      BYTEA NOT NULL,
+	topics       BYTEA[] NOT NULL,
+	data         BYTEA NOT NULL,
+	private      BOOLEAN NOT NULL,
+	PRIMARY KEY (block_number, log_index)
+);
+CREATE INDEX IF NOT EXISTS transactions_block ON transactions (block_number);
+CREATE INDEX IF NOT EXISTS transactions_sender ON transactions (sender);
+CREATE INDEX IF NOT EXISTS transactions_recipient ON transactions (recipient);
+CREATE INDEX IF NOT EXISTS logs_address ON logs (address);
+CREATE TABLE IF NOT EXISTS export_checkpoint (
+	id     BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (id),
+	number BIGINT NOT NULL,
+	hash   BYTEA NOT NULL
+);
+`
+
+// SQLExporter writes the blocks, transactions, receipts and logs of the chain
+// to a Postgres database, or as Postgres statements to be piped into psql. The
+// receipts and logs of the private transactions, which are those of their
+// private execution, are only exported along with the private payloads if
+// requested.
+type SQLExporter struct {
+	chain      *core.BlockChain
+	out        io.Writer // Stream the statements are written to, nil if run on db
+	db         *sql.DB   // Database the statements are run on, nil if written to out
+	private    bool
+	checkpoint string // File holding the number of the last block exported, empty if none
+
+	batch  bytes.Buffer           // Statements of the database transaction being built
+	recent map[uint64]common.Hash // Hashes of the blocks recently exported, to detect reorganisations
+	stale  []common.Hash          // Hashes of the exported blocks reorganised out of the chain, to delete
+}
+
+// NewSQLExporter creates an exporter writing to the given stream.
+func NewSQLExporter(chain *core.BlockChain, out io.Writer, private bool, checkpoint string) *SQLExporter {
+	return &SQLExporter{
+		chain:      chain,
+		out:        out,
+		private:    private,
+		checkpoint: checkpoint,
+		recent:     make(map[uint64]common.Hash),
+	}
+}
+
+// NewSQLDatabaseExporter creates an exporter running the statements on a
+// Postgres database, the checkpoint being kept in the database.
+func NewSQLDatabaseExporter(chain *core.BlockChain, db *sql.DB, private bool) *SQLExporter {
+	return &SQLExporter{
+		chain:   chain,
+		db:      db,
+		private: private,
+		recent:  make(map[uint64]common.Hash),
+	}
+}
+
+// commit runs or writes the statements of the batch as one transaction.
+func (e *SQLExporter) commit() error {
+	defer e.batch.Reset()
+	if e.db != nil {
+		_, err := e.db.Exec(e.batch.String())
+		return err
+	}
+	_, err := e.out.Write(e.batch.Bytes())
+	return err
+}
+
+// Checkpoint returns the last block exported, as recorded in the checkpoint
+// file or the database, and false if there is none.
+func (e *SQLExporter) Checkpoint() (uint64, bool, error) {
+	if e.db != nil {
+		var number uint64
+		switch err := e.db.QueryRow("SELECT number FROM export_checkpoint").Scan(&number); err {
+		case nil:
+			return number, true, nil
+		case sql.ErrNoRows:
+			return 0, false, nil
+		default:
+			return 0, false, err
+		}
+	}
+	if e.checkpoint == "" {
+		return 0, false, nil
+	}
+	blob, err := ioutil.ReadFile(e.checkpoint)
+	if os.IsNotExist(err) {
+		return 0, false, nil
+	} else if err != nil {
+		return 0, false, err
+	}
+	number, err := strconv.ParseUint(strings.TrimSpace(string(blob)), 10, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("invalid checkpoint %s: %v", e.checkpoint, err)
+	}
+	return number, true, nil
+}
+
+// setCheckpoint records the last block exported in the checkpoint file.
+func (e *SQLExporter) setCheckpoint(number uint64) error {
+	if e.checkpoint == "" {
+		return nil
+	}
+	tmp := e.checkpoint + ".tmp"
+	if err := ioutil.WriteFile(tmp, []byte(strconv.FormatUint(number, 10)+"\n"), 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, e.checkpoint)
+}
+
+// WriteSchema writes the statements creating the tables missing.
+func (e *SQLExporter) WriteSchema() error {
+	e.batch.WriteString(SQLExportSchema)
+	return e.commit()
+}
+
+// loadRecent remembers the hashes of the blocks last exported to the database,
+// to detect the reorganisations which happened while the node was down.
+func (e *SQLExporter) loadRecent(checkpoint uint64) error {
+	if e.db == nil {
+		return nil
+	}
+	from := int64(checkpoint) - sqlExportReorgDepth
+	rows, err := e.db.Query("SELECT number, hash FROM blocks WHERE number > $1", from)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			number uint64
+			hash   []byte
+		)
+		if err := rows.Scan(&number, &hash); err != nil {
+			return err
+		}
+		e.recent[number] = common.BytesToHash(hash)
+	}
+	return rows.Err()
+}
+
+// Export writes the canonical blocks in the given range, in batches each
+// committed along with its checkpoint.
+func (e *SQLExporter) Export(first, last uint64) error {
+	for start := first; start <= last; start += sqlExportBatch {
+		end := start + sqlExportBatch - 1
+		if end > last || end < start {
+			end = last
+		}
+		fmt.Fprintln(&e.batch, "BEGIN;")
+		if len(e.stale) > 0 {
+			hashes := make([]string, len(e.stale))
+			for i, hash := range e.stale {
+				hashes[i] = sqlBytes(hash.Bytes())
+			}
+			fmt.Fprintf(&e.batch, "DELETE FROM blocks WHERE hash IN (%s);\n", strings.Join(hashes, ", "))
+		}
+		var block *types.Block
+		for number := start; number <= end; number++ {
+			if block = e.chain.GetBlockByNumber(number); block == nil {
+				e.batch.Reset()
+				return fmt.Errorf("block #%d not found", number)
+			}
+			if err := e.writeBlock(block); err != nil {
+				e.batch.Reset()
+				return err
+			}
+		}
+		fmt.Fprintf(&e.batch, "INSERT INTO export_checkpoint (number, hash) VALUES (%d, %s) ON CONFLICT (id) DO UPDATE SET number = EXCLUDED.number, hash = EXCLUDED.hash;\n", end, sqlBytes(block.Hash().Bytes()))
+		fmt.Fprintln(&e.batch, "COMMIT;")
+		if err := e.commit(); err != nil {
+			return err
+		}
+		e.stale = nil
+		if err := e.setCheckpoint(end); err != nil {
+			return err
+		}
+		log.Debug("Exported blocks to SQL", "first", start, "last", end)
+		if end == last {
+			break
+		}
+	}
+	return nil
+}
+
+// writeBlock writes the statements replacing the rows of a block. The rows of
+// its transactions are replaced too, wherever they were exported before, the
+// transactions of a block reorganised out of the chain possibly moving to
+// another block.
+func (e *SQLExporter) writeBlock(block *types.Block) error {
+	number := block.NumberU64()
+	e.recent[number] = block.Hash()
+	delete(e.recent, number-sqlExportReorgDepth)
+
+	fmt.Fprintf(&e.batch, "DELETE FROM blocks WHERE number = %d OR hash = %s;\n", number, sqlBytes(block.Hash().Bytes()))
+	fmt.Fprintf(&e.batch, "INSERT INTO blocks VALUES (%d, %s, %s, %s, %s, %d, %d, %d, %s);\n",
+		number, sqlBytes(block.Hash().Bytes()), sqlBytes(block.ParentHash().Bytes()), block.Time(),
+		sqlBytes(block.Coinbase().Bytes()), block.GasLimit(), block.GasUsed(), len(block.Transactions()), sqlBytes(block.Extra()))
+
+	receipts := e.chain.GetReceiptsByHash(block.Hash())
+	if len(receipts) != len(block.Transactions()) {
+		return fmt.Errorf("receipts of block #%d not found", number)
+	}
+	signer := types.MakeSigner(e.chain.Config(), block.Number())
+	logIndex := 0
+	for i, tx := range block.Transactions() {
+		sender, err := types.Sender(signer, tx)
+		if err != nil {
+			return fmt.Errorf("transaction %x: %v", tx.Hash(), err)
+		}
+		var recipient []byte
+		if to := tx.To(); to != nil {
+			recipient = to.Bytes()
+		}
+		var payload []byte
+		if tx.IsPrivate() && e.private && private.P != nil {
+			if payload, err = private.P.Receive(tx.Data()); err != nil {
+				return fmt.Errorf("payload of transaction %x: %v", tx.Hash(), err)
+			}
+		}
+		fmt.Fprintf(&e.batch, "DELETE FROM transactions WHERE hash = %s;\n", sqlBytes(tx.Hash().Bytes()))
+		fmt.Fprintf(&e.batch, "INSERT INTO transactions VALUES (%s, %d, %d, %s, %s, %d, %s, %d, %s, %s, %t, %s);\n",
+			sqlBytes(tx.Hash().Bytes()), number, i, sqlBytes(sender.Bytes()), sqlNullBytes(recipient), tx.Nonce(),
+			sqlBig(tx.Value()), tx.Gas(), sqlBig(tx.GasPrice()), sqlBytes(tx.Data()), tx.IsPrivate(), sqlNullBytes(payload))
+
+		// The stored receipts of the private transactions are the private ones
+		if tx.IsPrivate() && !e.private {
+			continue
+		}
+		receipt := receipts[i]
+		var contract []byte
+		if receipt.ContractAddress != (common.Address{}) {
+			contract = receipt.ContractAddress.Bytes()
+		}
+		fmt.Fprintf(&e.batch, "INSERT INTO receipts VALUES (%s, %d, %d, %d, %d, %s, %t);\n",
+			sqlBytes(tx.Hash().Bytes()), number, receipt.Status, receipt.GasUsed, receipt.CumulativeGasUsed, sqlNullBytes(contract), tx.IsPrivate())
+
+		for _, l := range receipt.Logs {
+			topics := make([]string, len(l.Topics))
+			for j, topic := range l.Topics {
+				topics[j] = sqlBytes(topic.Bytes())
+			}
+			fmt.Fprintf(&e.batch, "INSERT INTO logs VALUES (%d, %d, %s, %s, ARRAY[%s]::BYTEA[], %s, %t);\n",
+				number, logIndex, sqlBytes(tx.Hash().Bytes()), sqlBytes(l.Address.Bytes()), strings.Join(topics, ", "), sqlBytes(l.Data), tx.IsPrivate())
+			logIndex++
+		}
+	}
+	return nil
+}
+
+// loop exports the canonical blocks as the chain head moves, resuming after the
+// checkpoint, until quit is closed. The exports run on a worker, for a slow
+// database or a pipe nobody reads not to hold up the chain head events.
+func (e *SQLExporter) loop(quit chan bool) {
+	heads := make(chan core.ChainHeadEvent, 16)
+	sub := e.chain.SubscribeChainHeadEvent(heads)
+	defer sub.Unsubscribe()
+
+	pending := make(chan struct{}, 1)
+	pending <- struct{}{}
+	defer close(pending)
+
+	done := make(chan struct{})
+	go func() {
+		e.worker(pending)
+		close(done)
+	}()
+	for {
+		select {
+		case <-heads:
+			select {
+			case pending <- struct{}{}:
+			default: // The worker is already due to export up to the new head
+			}
+		case <-done:
+			return
+		case <-sub.Err():
+			return
+		case <-quit:
+			return
+		}
+	}
+}
+
+// worker exports the canonical blocks up to the head whenever signalled, until
+// the channel is closed or an export fails. The blocks reorganised out of the
+// chain are replaced, as far as the recently exported ones are remembered.
+func (e *SQLExporter) worker(pending chan struct{}) {
+	if e.db != nil {
+		if err := e.WriteSchema(); err != nil {
+			log.Error("Failed to create SQL export schema", "err", err)
+			return
+		}
+	}
+	next := uint64(0)
+	if number, ok, err := e.Checkpoint(); err != nil {
+		log.Error("Failed to read SQL export checkpoint", "err", err)
+		return
+	} else if ok {
+		next = number + 1
+		if err := e.loadRecent(number); err != nil {
+			log.Error("Failed to read the blocks exported to SQL", "err", err)
+			return
+		}
+	} else if e.db == nil {
+		if err := e.WriteSchema(); err != nil {
+			log.Error("Failed to export SQL schema", "err", err)
+			return
+		}
+	}
+	for range pending {
+		head := e.chain.CurrentBlock().NumberU64()
+		if next > head+1 {
+			if err := e.truncate(head); err != nil {
+				log.Error("Failed to truncate SQL export", "head", head, "err", err)
+				return
+			}
+			next = head + 1
+		}
+		for next > 0 {
+			hash, ok := e.recent[next-1]
+			if !ok || hash == e.canonicalHash(next-1) {
+				break
+			}
+			e.stale = append(e.stale, hash)
+			delete(e.recent, next-1)
+			next--
+		}
+		if next <= head {
+			if err := e.Export(next, head); err != nil {
+				log.Error("Failed to export blocks to SQL", "first", next, "last", head, "err", err)
+				return
+			}
+			next = head + 1
+		}
+	}
+}
+
+// canonicalHash returns the hash of the canonical block of the given number.
+func (e *SQLExporter) canonicalHash(number uint64) common.Hash {
+	if header := e.chain.GetHeaderByNumber(number); header != nil {
+		return header.Hash()
+	}
+	return common.Hash{}
+}
+
+// truncate writes the statements removing the blocks after the given head.
+func (e *SQLExporter) truncate(head uint64) error {
+	fmt.Fprintf(&e.batch, "BEGIN;\nDELETE FROM blocks WHERE number > %d;\nUPDATE export_checkpoint SET number = %d, hash = %s;\nCOMMIT;\n", head, head, sqlBytes(e.canonicalHash(head).Bytes()))
+	if err := e.commit(); err != nil {
+		return err
+	}
+	for number := range e.recent {
+		if number > head {
+			delete(e.recent, number)
+		}
+	}
+	return e.setCheckpoint(head)
+}
+
+// sqlExportStream exports the blocks of the running node to a Postgres
+// database, or to a file or a named pipe read by psql.
+type sqlExportStream struct {
+	chain      *core.BlockChain
+	path       string // Postgres URL, or path of the file or pipe
+	private    bool
+	checkpoint string // Checkpoint file of the exports to a file or pipe
+}
+
+// isPostgresURL returns whether an export target is a Postgres database.
+func isPostgresURL(target string) bool {
+	return strings.HasPrefix(target, "postgres://") || strings.HasPrefix(target, "postgresql://")
+}
+
+// loop connects the database or opens the export stream, and exports the
+// canonical blocks until quit is closed. Opening a named pipe blocks until its
+// reader opens it.
+func (s *sqlExportStream) loop(quit chan bool) {
+	if isPostgresURL(s.path) {
+		db, err := sql.Open("postgres", s.path)
+		if err != nil {
+			log.Error("Failed to open SQL export database", "err", err)
+			return
+		}
+		defer db.Close()
+
+		log.Info("Exporting blocks to Postgres", "private", s.private)
+		NewSQLDatabaseExporter(s.chain, db, s.private).loop(quit)
+		return
+	}
+	out, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		log.Error("Failed to open SQL export stream", "path", s.path, "err", err)
+		return
+	}
+	defer out.Close()
+
+	log.Info("Exporting blocks to SQL", "path", s.path, "private", s.private, "checkpoint", s.checkpoint)
+	NewSQLExporter(s.chain, out, s.private, s.checkpoint).loop(quit)
+}
+
+// sqlBytes formats a byte slice as a Postgres bytea literal.
+func sqlBytes(b []byte) string {
+	return `'\x` + hex.EncodeToString(b) + `'`
+}
+
+// sqlNullBytes formats a byte slice as a Postgres bytea literal, NULL if nil.
+func sqlNullBytes(b []byte) string {
+	if b == nil {
+		return "NULL"
+	}
+	return sqlBytes(b)
+}
+
+// sqlBig formats a big integer as a Postgres numeric literal.
+func sqlBig(n *big.Int) string {
+	if n == nil {
+		return "0"
+	}
+	return n.String()
+}
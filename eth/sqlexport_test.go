@@ -0,0 +1,120 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/eth/downloader"
+)
+
+// Tests that the blocks are exported as SQL statements, in batches committed
+// along with their checkpoint, and that the export resumes from it.
+func TestSQLExport(t *testing.T) {
+	generator := func(i int, block *core.BlockGen) {
+		block.AddTx(newTestTransaction(testBankKey, uint64(i), 0))
+	}
+	pm, _ := newTestProtocolManagerMust(t, downloader.FullSync, sqlExportBatch+2, generator, nil)
+	defer pm.Stop()
+
+	dir, err := ioutil.TempDir("", "sqlexport")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	out := new(bytes.Buffer)
+	exporter := NewSQLExporter(pm.blockchain, out, false, filepath.Join(dir, "checkpoint"))
+	if _, ok, err := exporter.Checkpoint(); ok || err != nil {
+		t.Fatalf("checkpoint before export: have %v, %v", ok, err)
+	}
+	if err := exporter.Export(1, sqlExportBatch+1); err != nil {
+		t.Fatalf("failed to export: %v", err)
+	}
+	sql := out.String()
+	for stmt, want := range map[string]int{
+		"BEGIN;":                        2,
+		"COMMIT;":                       2,
+		"INSERT INTO blocks ":           sqlExportBatch + 1,
+		"INSERT INTO transactions ":     sqlExportBatch + 1,
+		"INSERT INTO receipts ":         sqlExportBatch + 1,
+		"INSERT INTO export_checkpoint": 2,
+	} {
+		if have := strings.Count(sql, stmt); have != want {
+			t.Errorf("%q statements mismatch: have %d, want %d", stmt, have, want)
+		}
+	}
+	block := pm.blockchain.GetBlockByNumber(sqlExportBatch + 1)
+	if want := fmt.Sprintf("VALUES (%d, '\\x%x') ON CONFLICT", sqlExportBatch+1, block.Hash()); !strings.Contains(sql, want) {
+		t.Errorf("final checkpoint missing: want %s", want)
+	}
+	// Resume after the checkpoint
+	exporter = NewSQLExporter(pm.blockchain, new(bytes.Buffer), false, filepath.Join(dir, "checkpoint"))
+	if number, ok, err := exporter.Checkpoint(); !ok || err != nil || number != sqlExportBatch+1 {
+		t.Fatalf("checkpoint mismatch: have %d, %v, %v, want %d", number, ok, err, sqlExportBatch+1)
+	}
+}
+
+// Tests that the blocks exported before a reorganisation are deleted by hash and
+// the new canonical blocks exported in their place.
+func TestSQLExportReorg(t *testing.T) {
+	generator := func(i int, block *core.BlockGen) {
+		block.AddTx(newTestTransaction(testBankKey, uint64(i), 0))
+	}
+	pm, _ := newTestProtocolManagerMust(t, downloader.FullSync, 4, generator, nil)
+	defer pm.Stop()
+
+	dir, err := ioutil.TempDir("", "sqlexport")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	out := new(bytes.Buffer)
+	exporter := NewSQLExporter(pm.blockchain, out, false, filepath.Join(dir, "checkpoint"))
+	if err := exporter.Export(1, 2); err != nil {
+		t.Fatalf("failed to export: %v", err)
+	}
+	// Pretend block #2 was exported from a side chain since reorganised away
+	stale := common.HexToHash("0xdeadbeef")
+	exporter.recent[2] = stale
+	out.Reset()
+
+	pending := make(chan struct{}, 1)
+	pending <- struct{}{}
+	close(pending)
+	exporter.worker(pending)
+
+	sql := out.String()
+	if want := fmt.Sprintf("DELETE FROM blocks WHERE hash IN ('\\x%x');", stale); !strings.Contains(sql, want) {
+		t.Errorf("stale block not deleted: want %s in %s", want, sql)
+	}
+	if have := strings.Count(sql, "INSERT INTO blocks "); have != 3 {
+		t.Errorf("reexported blocks mismatch: have %d, want 3", have)
+	}
+	if exporter.recent[2] != pm.blockchain.GetBlockByNumber(2).Hash() {
+		t.Errorf("canonical block #2 not remembered")
+	}
+}
@@ -0,0 +1,522 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/adminauth"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/kms"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const (
+	// StandbyPrimary is the role of the signing node of a hot standby pair.
+	StandbyPrimary = "primary"
+
+	// StandbyPassive is the role of the node of a pair taking over the identity
+	// of the primary on promotion.
+	StandbyPassive = "standby"
+
+	// defaultStandbyLease is the time the primary signs for after renewing its
+	// lease with the standby.
+	defaultStandbyLease = 10 * time.Second
+
+	// standbyLeaseMargin is waited out on top of the lease by a promoted standby,
+	// for the clock drift between the nodes and the messages in flight.
+	standbyLeaseMargin = time.Second
+
+	// maxLeaseRequestAge is the maximum age of a lease request.
+	maxLeaseRequestAge = 30 * time.Second
+)
+
+var (
+	errStandbyPassive      = errors.New("standby node not promoted")
+	errStandbyFenced       = errors.New("primary fenced by the promoted standby")
+	errStandbyLeaseExpired = errors.New("standby lease expired")
+	errStandbyNotPassive   = errors.New("node is not a standby")
+	errLeaseSigner         = errors.New("lease request not signed by the primary")
+	errLeaseStale          = errors.New("stale lease request")
+)
+
+// LeaseRequest renews the lease of the primary node of a standby pair. It is
+// signed with the node key of the primary, which the standby holds in escrow.
+type LeaseRequest struct {
+	Nonce     uint64           `json:"nonce"`    // Time of the request in nanoseconds, increasing
+	Unlocked  []common.Address `json:"unlocked"` // Accounts unlocked on the primary
+	Signature hexutil.Bytes    `json:"signature"`
+}
+
+// hash returns the hash the primary signs.
+func (r *LeaseRequest) hash() common.Hash {
+	blob, _ := rlp.EncodeToBytes([]interface{}{"standby-lease", r.Nonce, r.Unlocked})
+	return crypto.Keccak256Hash(blob)
+}
+
+// LeaseGrant is the lease granted to the primary by the standby.
+type LeaseGrant struct {
+	Lease uint64 `json:"lease"` // Milliseconds the primary may sign for, from the request
+}
+
+// StandbyStatus is the state of a node of a hot standby pair.
+type StandbyStatus struct {
+	Role        string           `json:"role"`
+	Primary     common.Address   `json:"primary"`               // Validator identity of the pair
+	Lease       uint64           `json:"lease"`                 // Lease duration in milliseconds
+	LeaseExpiry *time.Time       `json:"leaseExpiry,omitempty"` // Primary: end of the signing lease, standby: end of the lease granted
+	Fenced      bool             `json:"fenced,omitempty"`      // Primary: whether the standby took over
+	Promoted    bool             `json:"promoted,omitempty"`    // Standby: whether the node took over
+	Unlocked    []common.Address `json:"unlocked"`              // Accounts unlocked on the primary
+	LastError   string           `json:"lastError,omitempty"`
+}
+
+// standbyPrimary holds the lease the primary node of a pair signs under. The
+// lease is renewed with the standby, and lost when it can't be renewed or the
+// standby took over, so that the primary never signs once the standby may.
+type standbyPrimary struct {
+	peer     string        // RPC endpoint of the standby
+	lease    time.Duration // Time signed for after a renewal
+	signer   kms.Signer    // Node key signing the lease requests
+	unlocked func() []common.Address
+	fence    func() // Stops the signing activities once fenced
+
+	lock    sync.Mutex
+	expiry  time.Time // End of the current lease, zero if none
+	fenced  bool
+	lastErr error
+}
+
+// allowed returns an error unless the primary holds the lease.
+func (p *standbyPrimary) allowed() error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.fenced {
+		return errStandbyFenced
+	}
+	if !time.Now().Before(p.expiry) {
+		return errStandbyLeaseExpired
+	}
+	return nil
+}
+
+// renew renews the lease with the standby.
+func (p *standbyPrimary) renew(client *rpc.Client) error {
+	sent := time.Now()
+	req := &LeaseRequest{Nonce: uint64(sent.UnixNano()), Unlocked: p.unlocked()}
+	sig, err := p.signer.Sign(req.hash().Bytes())
+	if err != nil {
+		return err
+	}
+	req.Signature = sig
+
+	ctx, cancel := context.WithTimeout(context.Background(), p.lease)
+	defer cancel()
+
+	var grant LeaseGrant
+	err = client.CallContext(ctx, &grant, "standby_renewLease", req)
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.lastErr = err
+	if err != nil {
+		if err.Error() == errStandbyFenced.Error() && !p.fenced {
+			p.fenced = true
+			log.Error("Standby took over, stopped signing")
+			go p.fence()
+		}
+		return err
+	}
+	// The lease runs from the request, not the response, to stay within the
+	// one of the standby
+	lease := time.Duration(grant.Lease) * time.Millisecond
+	if lease > p.lease {
+		lease = p.lease
+	}
+	p.expiry = sent.Add(lease)
+	return nil
+}
+
+// loop renews the lease until fenced or quit is closed.
+func (p *standbyPrimary) loop(quit chan bool) {
+	ticker := time.NewTicker(p.lease / 3)
+	defer ticker.Stop()
+
+	var client *rpc.Client
+	defer func() {
+		if client != nil {
+			client.Close()
+		}
+	}()
+	for {
+		if client == nil {
+			var err error
+			if client, err = rpc.Dial(p.peer); err != nil {
+				log.Warn("Failed to connect to the standby", "peer", p.peer, "err", err)
+				client = nil
+			}
+		}
+		if client != nil {
+			if err := p.renew(client); err != nil {
+				log.Warn("Failed to renew the standby lease", "err", err)
+			}
+		}
+		p.lock.Lock()
+		fenced := p.fenced
+		p.lock.Unlock()
+		if fenced {
+			return
+		}
+		select {
+		case <-ticker.C:
+		case <-quit:
+			return
+		}
+	}
+}
+
+func (p *standbyPrimary) status() *StandbyStatus {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	status := &StandbyStatus{
+		Role:     StandbyPrimary,
+		Primary:  crypto.PubkeyToAddress(*p.signer.PublicKey()),
+		Lease:    uint64(p.lease / time.Millisecond),
+		Fenced:   p.fenced,
+		Unlocked: p.unlocked(),
+	}
+	if !p.expiry.IsZero() {
+		expiry := p.expiry
+		status.LeaseExpiry = &expiry
+	}
+	if p.lastErr != nil {
+		status.LastError = p.lastErr.Error()
+	}
+	return status
+}
+
+// fencedSigner signs the consensus messages and seals of the primary while it
+// holds the lease.
+type fencedSigner struct {
+	kms.Signer
+	primary *standbyPrimary
+}
+
+func (s *fencedSigner) Sign(hash []byte) ([]byte, error) {
+	if err := s.primary.allowed(); err != nil {
+		return nil, err
+	}
+	return s.Signer.Sign(hash)
+}
+
+// standbyNode is the passive node of a pair. It grants the leases of the
+// primary, and takes over its identity on promotion once the last lease
+// granted expired.
+type standbyNode struct {
+	key       *ecdsa.PrivateKey // Node key of the primary, held in escrow
+	primary   common.Address
+	lease     time.Duration
+	passwords []string // Passwords unlocking the accounts of the primary
+	takeOver  func(key *ecdsa.PrivateKey, unlocked []common.Address, passwords []string) error
+
+	lock      sync.Mutex
+	nonce     uint64    // Nonce of the last lease request
+	renewed   time.Time // Time the last lease was granted
+	unlocked  []common.Address
+	promoted  bool
+	promoting bool
+	lastErr   error
+}
+
+func newStandbyNode(keyfile string, lease time.Duration, passwords []string) (*standbyNode, error) {
+	key, err := crypto.LoadECDSA(keyfile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the escrowed node key: %v", err)
+	}
+	return &standbyNode{
+		key:       key,
+		primary:   crypto.PubkeyToAddress(key.PublicKey),
+		lease:     lease,
+		passwords: passwords,
+	}, nil
+}
+
+// renewLease grants a lease to the primary, unless the node was promoted.
+func (n *standbyNode) renewLease(req *LeaseRequest) (*LeaseGrant, error) {
+	if len(req.Signature) != 65 {
+		return nil, errLeaseSigner
+	}
+	pub, err := crypto.SigToPub(req.hash().Bytes(), req.Signature)
+	if err != nil || crypto.PubkeyToAddress(*pub) != n.primary {
+		return nil, errLeaseSigner
+	}
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	if n.promoted || n.promoting {
+		return nil, errStandbyFenced
+	}
+	if sent := time.Unix(0, int64(req.Nonce)); req.Nonce <= n.nonce || time.Since(sent) > maxLeaseRequestAge {
+		return nil, errLeaseStale
+	}
+	n.nonce, n.renewed, n.unlocked = req.Nonce, time.Now(), req.Unlocked
+	return &LeaseGrant{Lease: uint64(n.lease / time.Millisecond)}, nil
+}
+
+// promote takes over the identity of the primary. The leases are refused from
+// then on, and the last one granted is waited out before signing anything.
+func (n *standbyNode) promote() error {
+	n.lock.Lock()
+	if n.promoted || n.promoting {
+		n.lock.Unlock()
+		return errors.New("standby already promoted")
+	}
+	n.promoting = true
+	wait := time.Until(n.renewed.Add(n.lease + standbyLeaseMargin))
+	unlocked := n.unlocked
+	n.lock.Unlock()
+
+	if wait > 0 {
+		log.Info("Waiting out the lease of the primary", "wait", common.PrettyDuration(wait))
+		time.Sleep(wait)
+	}
+	err := n.takeOver(n.key, unlocked, n.passwords)
+
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	n.lastErr = err
+	if err != nil {
+		// Leases stay refused, the primary must not resume after a partial takeover
+		return err
+	}
+	n.promoting, n.promoted = false, true
+	log.Info("Standby promoted", "identity", n.primary)
+	return nil
+}
+
+func (n *standbyNode) status() *StandbyStatus {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	status := &StandbyStatus{
+		Role:     StandbyPassive,
+		Primary:  n.primary,
+		Lease:    uint64(n.lease / time.Millisecond),
+		Promoted: n.promoted,
+		Unlocked: n.unlocked,
+	}
+	if !n.renewed.IsZero() {
+		expiry := n.renewed.Add(n.lease)
+		status.LeaseExpiry = &expiry
+	}
+	if status.Unlocked == nil {
+		status.Unlocked = []common.Address{}
+	}
+	if n.lastErr != nil {
+		status.LastError = n.lastErr.Error()
+	}
+	return status
+}
+
+// takeOverPrimary switches the node to the identity of the primary of its
+// pair, and starts signing: the consensus engine and the p2p server change
+// keys, the accounts unlocked on the primary are unlocked.
+func (s *Ethereum) takeOverPrimary(key *ecdsa.PrivateKey, unlocked []common.Address, passwords []string) error {
+	type nodeKeyed interface {
+		SetNodeKey(key *ecdsa.PrivateKey) error
+	}
+	engine, ok := s.engine.(nodeKeyed)
+	if !ok {
+		return errors.New("consensus engine can't change identity")
+	}
+	if err := engine.SetNodeKey(key); err != nil {
+		return err
+	}
+	if err := s.p2pServer.Rekey(key); err != nil {
+		return fmt.Errorf("failed to restart networking: %v", err)
+	}
+	address := crypto.PubkeyToAddress(key.PublicKey)
+	s.lock.Lock()
+	s.etherbase = address
+	s.lock.Unlock()
+
+	if ks := s.keystore(); ks != nil {
+		for _, addr := range unlocked {
+			done := false
+			for _, password := range passwords {
+				if ks.Unlock(accounts.Account{Address: addr}, password) == nil {
+					done = true
+					break
+				}
+			}
+			if !done {
+				log.Warn("Failed to unlock account of the primary", "address", addr)
+			}
+		}
+	}
+	s.standby.promoted()
+	log.Warn("Took over the identity of the primary, restart with it as node key", "address", address)
+	return s.StartMining(1)
+}
+
+// keystore returns the keystore of the node, nil if there is none.
+func (s *Ethereum) keystore() *keystore.KeyStore {
+	if backends := s.accountManager.Backends(keystore.KeyStoreType); len(backends) > 0 {
+		return backends[0].(*keystore.KeyStore)
+	}
+	return nil
+}
+
+// newStandbyPair sets up the role of the node in a hot standby pair. Only the
+// Istanbul validators pair, their identity being their node key.
+func (s *Ethereum) newStandbyPair(ctx *node.ServiceContext, config *Config) (*standbyPair, error) {
+	if s.chainConfig.Istanbul == nil {
+		return nil, errors.New("hot standby pairs require Istanbul")
+	}
+	lease := config.StandbyLease
+	if lease <= 0 {
+		lease = defaultStandbyLease
+	}
+	switch config.StandbyRole {
+	case StandbyPrimary:
+		if config.StandbyPeer == "" {
+			return nil, errors.New("standby primary requires the endpoint of the standby")
+		}
+		primary := &standbyPrimary{
+			peer:     config.StandbyPeer,
+			lease:    lease,
+			signer:   ctx.NodeSigner(),
+			unlocked: s.unlockedAccounts,
+			fence:    s.StopMining,
+		}
+		// The consensus messages and seals are only signed under the lease
+		type nodeSigned interface {
+			SetNodeSigner(signer kms.Signer)
+		}
+		engine, ok := s.engine.(nodeSigned)
+		if !ok {
+			return nil, errors.New("consensus engine can't be fenced")
+		}
+		engine.SetNodeSigner(&fencedSigner{Signer: ctx.NodeSigner(), primary: primary})
+		return &standbyPair{primary: primary, active: true}, nil
+
+	case StandbyPassive:
+		if !s.operatorAuth.Enabled() {
+			return nil, errors.New("standby promotion requires operator keys")
+		}
+		if config.StandbyEscrowKey == "" {
+			return nil, errors.New("standby requires the escrowed node key of the primary")
+		}
+		standby, err := newStandbyNode(config.StandbyEscrowKey, lease, config.StandbyPasswords)
+		if err != nil {
+			return nil, err
+		}
+		standby.takeOver = s.takeOverPrimary
+		return &standbyPair{standby: standby}, nil
+	}
+	return nil, fmt.Errorf("unknown standby role %q", config.StandbyRole)
+}
+
+// unlockedAccounts returns the accounts unlocked in the keystore.
+func (s *Ethereum) unlockedAccounts() []common.Address {
+	if ks := s.keystore(); ks != nil {
+		return ks.Unlocked()
+	}
+	return []common.Address{}
+}
+
+// standbyPair is the role of the node in a hot standby pair.
+type standbyPair struct {
+	primary *standbyPrimary // nil unless the node is the primary
+	standby *standbyNode    // nil unless the node is the standby
+
+	lock   sync.Mutex
+	active bool // Whether the node may sign
+}
+
+// passive reports whether the node is a standby not yet promoted.
+func (p *standbyPair) passive() bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.standby != nil && !p.active
+}
+
+func (p *standbyPair) promoted() {
+	p.lock.Lock()
+	p.active = true
+	p.lock.Unlock()
+}
+
+// PublicStandbyAPI offers the state of a node of a hot standby pair, the lease
+// renewals of the primary and the promotion of the standby.
+type PublicStandbyAPI struct {
+	pair *standbyPair
+	auth *adminauth.Authenticator
+}
+
+// NewPublicStandbyAPI creates the API of a node of a standby pair.
+func NewPublicStandbyAPI(pair *standbyPair, auth *adminauth.Authenticator) *PublicStandbyAPI {
+	return &PublicStandbyAPI{pair: pair, auth: auth}
+}
+
+// Status returns the state of the node in its pair.
+func (api *PublicStandbyAPI) Status() *StandbyStatus {
+	if api.pair.primary != nil {
+		return api.pair.primary.status()
+	}
+	return api.pair.standby.status()
+}
+
+// RenewLease grants a lease to the primary of the pair, refused once the
+// standby is promoted.
+func (api *PublicStandbyAPI) RenewLease(req LeaseRequest) (*LeaseGrant, error) {
+	if api.pair.standby == nil {
+		return nil, errStandbyNotPassive
+	}
+	return api.pair.standby.renewLease(&req)
+}
+
+// Promote makes the standby take over the identity of the primary, once the
+// last lease of the primary expired. It requires an operator signature.
+func (api *PublicStandbyAPI) Promote(sig *adminauth.Signature) (*StandbyStatus, error) {
+	if api.pair.standby == nil {
+		return nil, errStandbyNotPassive
+	}
+	if err := api.auth.Verify("standby_promote", sig); err != nil {
+		return nil, err
+	}
+	if err := api.pair.standby.promote(); err != nil {
+		return nil, err
+	}
+	return api.pair.standby.status(), nil
+}
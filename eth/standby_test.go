@@ -0,0 +1,137 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"crypto/ecdsa"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/kms"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+func TestStandbyFencing(t *testing.T) {
+	dir, err := ioutil.TempDir("", "standby")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	key, _ := crypto.GenerateKey()
+	keyfile := filepath.Join(dir, "escrow.key")
+	if err := crypto.SaveECDSA(keyfile, key); err != nil {
+		t.Fatal(err)
+	}
+	const lease = 200 * time.Millisecond
+
+	// Pair a standby holding the key of the primary in escrow with the primary
+	standby, err := newStandbyNode(keyfile, lease, []string{"secret"})
+	if err != nil {
+		t.Fatalf("failed to create standby: %v", err)
+	}
+	var (
+		takenOver  = make(chan time.Time, 1)
+		takenKey   *ecdsa.PrivateKey
+		takenAccts []common.Address
+	)
+	standby.takeOver = func(key *ecdsa.PrivateKey, unlocked []common.Address, passwords []string) error {
+		takenKey, takenAccts = key, unlocked
+		takenOver <- time.Now()
+		return nil
+	}
+	server := rpc.NewServer()
+	if err := server.RegisterName("standby", NewPublicStandbyAPI(&standbyPair{standby: standby}, nil)); err != nil {
+		t.Fatal(err)
+	}
+	client := rpc.DialInProc(server)
+	defer client.Close()
+
+	fenced := make(chan struct{})
+	account := common.HexToAddress("0xa11ce")
+	primary := &standbyPrimary{
+		lease:    lease,
+		signer:   kms.NewLocalSigner(key),
+		unlocked: func() []common.Address { return []common.Address{account} },
+		fence:    func() { close(fenced) },
+	}
+	signer := &fencedSigner{Signer: primary.signer, primary: primary}
+	hash := crypto.Keccak256([]byte("block"))
+
+	// The primary only signs while holding the lease
+	if _, err := signer.Sign(hash); err != errStandbyLeaseExpired {
+		t.Fatalf("signed without lease: have %v, want %v", err, errStandbyLeaseExpired)
+	}
+	if err := primary.renew(client); err != nil {
+		t.Fatalf("failed to renew lease: %v", err)
+	}
+	if _, err := signer.Sign(hash); err != nil {
+		t.Fatalf("failed to sign under lease: %v", err)
+	}
+	// Only the primary renews its lease, once per request
+	otherKey, _ := crypto.GenerateKey()
+	other := &standbyPrimary{lease: lease, signer: kms.NewLocalSigner(otherKey), unlocked: primary.unlocked}
+	if err := other.renew(client); err == nil || err.Error() != errLeaseSigner.Error() {
+		t.Errorf("lease granted to a foreign key: %v", err)
+	}
+	req := &LeaseRequest{Nonce: uint64(time.Now().UnixNano()), Unlocked: []common.Address{account}}
+	req.Signature, _ = primary.signer.Sign(req.hash().Bytes())
+
+	// The lease runs from within the renewal, never before this point
+	renewed := time.Now()
+	if _, err := standby.renewLease(req); err != nil {
+		t.Fatalf("failed to renew lease: %v", err)
+	}
+	if _, err := standby.renewLease(req); err != errLeaseStale {
+		t.Errorf("replayed lease request: have %v, want %v", err, errLeaseStale)
+	}
+
+	// Promoting the standby fences the primary, and waits out its lease
+	promoted := make(chan error, 1)
+	go func() { promoted <- standby.promote() }()
+	time.Sleep(50 * time.Millisecond)
+
+	if err := primary.renew(client); err == nil || err.Error() != errStandbyFenced.Error() {
+		t.Fatalf("lease renewed during promotion: %v", err)
+	}
+	select {
+	case <-fenced:
+	case <-time.After(time.Second):
+		t.Fatalf("primary not fenced")
+	}
+	if _, err := signer.Sign(hash); err != errStandbyFenced {
+		t.Errorf("fenced primary signed: have %v, want %v", err, errStandbyFenced)
+	}
+	if err := <-promoted; err != nil {
+		t.Fatalf("failed to promote: %v", err)
+	}
+	if at := <-takenOver; at.Sub(renewed) < lease+standbyLeaseMargin {
+		t.Errorf("took over %v after the last lease, want at least %v", at.Sub(renewed), lease+standbyLeaseMargin)
+	}
+	if crypto.PubkeyToAddress(takenKey.PublicKey) != crypto.PubkeyToAddress(key.PublicKey) || !reflect.DeepEqual(takenAccts, []common.Address{account}) {
+		t.Errorf("takeover mismatch: identity %x, accounts %v", crypto.PubkeyToAddress(takenKey.PublicKey), takenAccts)
+	}
+	if status := standby.status(); !status.Promoted || status.Primary != crypto.PubkeyToAddress(key.PublicKey) {
+		t.Errorf("standby status mismatch: %+v", status)
+	}
+}
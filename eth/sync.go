@@ -49,6 +49,10 @@ func (pm *ProtocolManager) syncTransactions(p *peer) {
 	for _, batch := range pending {
 		txs = append(txs, batch...)
 	}
+	// Quorum
+	if pm.privateGossip != nil {
+		txs = pm.privateGossip.filter(p, txs)
+	}
 	if len(txs) == 0 {
 		return
 	}
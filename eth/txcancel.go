@@ -0,0 +1,148 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+)
+
+// The txcancel protocol propagates the cancellations of pooled transactions
+// signed by their senders. Every node verifies a cancellation against the
+// transaction in its own pool before relaying it, so cancellations of unknown
+// or already removed transactions stop spreading.
+const (
+	txCancelProtocolName    = "txcancel"
+	txCancelProtocolVersion = 1
+	txCancelProtocolLength  = 1
+
+	CancelTxMsg = 0x00
+)
+
+// txCanceller removes transactions from the pool on behalf of their senders.
+type txCanceller interface {
+	Cancel(hash common.Hash, sig []byte) (*types.Transaction, error)
+}
+
+// txCancellation is the payload of CancelTxMsg.
+type txCancellation struct {
+	Hash      common.Hash
+	Signature []byte
+}
+
+// txCancellations cancels pooled transactions and relays the cancellations to
+// the peers.
+type txCancellations struct {
+	txpool txCanceller
+
+	lock  sync.RWMutex
+	peers map[string]p2p.MsgReadWriter
+}
+
+func newTxCancellations(txpool txCanceller) *txCancellations {
+	return &txCancellations{
+		txpool: txpool,
+		peers:  make(map[string]p2p.MsgReadWriter),
+	}
+}
+
+// Protocol returns the txcancel devp2p sub-protocol.
+func (c *txCancellations) Protocol() p2p.Protocol {
+	return p2p.Protocol{
+		Name:    txCancelProtocolName,
+		Version: txCancelProtocolVersion,
+		Length:  txCancelProtocolLength,
+		Run: func(p *p2p.Peer, rw p2p.MsgReadWriter) error {
+			return c.handle(p.ID(), rw)
+		},
+	}
+}
+
+// handle registers a txcancel peer and serves its messages until disconnection.
+func (c *txCancellations) handle(id enode.ID, rw p2p.MsgReadWriter) error {
+	peer := fmt.Sprintf("%x", id[:8])
+
+	c.lock.Lock()
+	c.peers[peer] = rw
+	c.lock.Unlock()
+
+	defer func() {
+		c.lock.Lock()
+		delete(c.peers, peer)
+		c.lock.Unlock()
+	}()
+	for {
+		msg, err := rw.ReadMsg()
+		if err != nil {
+			return err
+		}
+		if msg.Size > ProtocolMaxMsgSize {
+			msg.Discard()
+			return errResp(ErrMsgTooLarge, "%v > %v", msg.Size, ProtocolMaxMsgSize)
+		}
+		switch msg.Code {
+		case CancelTxMsg:
+			var req txCancellation
+			if err := msg.Decode(&req); err != nil {
+				return errResp(ErrDecode, "msg %v: %v", msg, err)
+			}
+			// Cancellations not matching a pooled transaction are dropped silently,
+			// as they commonly arrive after the transaction left the pool
+			if _, err := c.txpool.Cancel(req.Hash, req.Signature); err != nil {
+				log.Trace("Ignoring transaction cancellation", "peer", peer, "hash", req.Hash, "err", err)
+				continue
+			}
+			c.broadcast(&req, peer)
+
+		default:
+			return errResp(ErrInvalidMsgCode, "%v", msg.Code)
+		}
+	}
+}
+
+// Cancel removes a transaction from the local pool, given the signature of its
+// sender over core.CancellationHash, and relays the cancellation to the peers.
+func (c *txCancellations) Cancel(hash common.Hash, sig []byte) error {
+	if _, err := c.txpool.Cancel(hash, sig); err != nil {
+		return err
+	}
+	c.broadcast(&txCancellation{Hash: hash, Signature: sig}, "")
+	return nil
+}
+
+// broadcast sends a cancellation to all the peers but the one it came from.
+func (c *txCancellations) broadcast(req *txCancellation, origin string) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	for peer, rw := range c.peers {
+		if peer == origin {
+			continue
+		}
+		go func(peer string, rw p2p.MsgReadWriter) {
+			if err := p2p.Send(rw, CancelTxMsg, req); err != nil {
+				log.Debug("Failed to relay transaction cancellation", "peer", peer, "hash", req.Hash, "err", err)
+			}
+		}(peer, rw)
+	}
+}
@@ -0,0 +1,100 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+)
+
+// testCanceller is a transaction pool accepting any cancellation of the
+// transactions it holds.
+type testCanceller struct {
+	lock sync.Mutex
+	txs  map[common.Hash]bool
+}
+
+func (c *testCanceller) Cancel(hash common.Hash, sig []byte) (*types.Transaction, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if !c.txs[hash] {
+		return nil, core.ErrCancelUnknownTx
+	}
+	delete(c.txs, hash)
+	return nil, nil
+}
+
+func (c *testCanceller) has(hash common.Hash) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.txs[hash]
+}
+
+// Tests that cancellations are relayed by the nodes holding the transaction.
+func TestTxCancelRelay(t *testing.T) {
+	hash := common.HexToHash("0x01")
+	pools := []*testCanceller{
+		{txs: map[common.Hash]bool{hash: true}},
+		{txs: map[common.Hash]bool{hash: true}},
+		{txs: map[common.Hash]bool{hash: true}},
+	}
+	nodes := make([]*txCancellations, len(pools))
+	for i, pool := range pools {
+		nodes[i] = newTxCancellations(pool)
+	}
+	// Connect the nodes in a line, the first one not knowing the last one
+	for i := 0; i < len(nodes)-1; i++ {
+		rw1, rw2 := p2p.MsgPipe()
+		defer rw1.Close()
+		go nodes[i].handle(enode.ID{byte(i + 1)}, rw1)
+		go nodes[i+1].handle(enode.ID{byte(i)}, rw2)
+	}
+	for _, node := range nodes {
+		for {
+			node.lock.RLock()
+			n := len(node.peers)
+			node.lock.RUnlock()
+			if n > 0 {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	if err := nodes[0].Cancel(hash, nil); err != nil {
+		t.Fatalf("failed to cancel: %v", err)
+	}
+	for i := 0; i < 100 && pools[2].has(hash); i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	for i, pool := range pools {
+		if pool.has(hash) {
+			t.Errorf("node %d: transaction not cancelled", i)
+		}
+	}
+	if err := nodes[0].Cancel(hash, nil); err != core.ErrCancelUnknownTx {
+		t.Errorf("repeated cancellation: have %v, want %v", err, core.ErrCancelUnknownTx)
+	}
+}
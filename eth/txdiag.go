@@ -0,0 +1,359 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+)
+
+// The txdiag protocol lets nodes compare their pending transaction sets to
+// diagnose transactions that never reach some of the validators. Instead of
+// full hash lists, peers exchange compact sketches of their pools.
+const (
+	txDiagProtocolName    = "txdiag"
+	txDiagProtocolVersion = 1
+	txDiagProtocolLength  = 2
+
+	GetTxSketchMsg = 0x00
+	TxSketchMsg    = 0x01
+)
+
+const (
+	sketchBuckets   = 256  // Number of hash buckets a sketch is split into
+	sketchSampleMax = 1024 // Maximum number of transaction hashes sampled in a sketch
+	sketchSampleMod = 16   // Hashes with a first byte below 256/sketchSampleMod are sampled
+
+	sketchTimeout  = 5 * time.Second // Maximum time to wait for the sketches of the peers
+	sketchCacheTTL = time.Second     // Time the local sketch is reused for, rather than recreated
+	sketchInterval = time.Second     // Minimum time between two sketch requests of a peer served
+)
+
+var errSketchTimeout = errors.New("sketch request timed out")
+
+// txSketch summarises a pending transaction set. Each bucket holds the XOR of
+// all the hashes starting with the bucket index, so two sets can be compared
+// bucket by bucket, and a deterministic sample of the hashes allows naming some
+// of the transactions that differ.
+type txSketch struct {
+	Count   uint64
+	Buckets []common.Hash
+	Sample  []common.Hash
+}
+
+// newTxSketch creates the sketch of a set of transaction hashes.
+func newTxSketch(hashes []common.Hash) *txSketch {
+	sketch := &txSketch{
+		Count:   uint64(len(hashes)),
+		Buckets: make([]common.Hash, sketchBuckets),
+	}
+	sort.Slice(hashes, func(i, j int) bool { return bytes.Compare(hashes[i][:], hashes[j][:]) < 0 })
+	for _, hash := range hashes {
+		bucket := &sketch.Buckets[hash[0]]
+		for i := range bucket {
+			bucket[i] ^= hash[i]
+		}
+		if hash[0] < sketchBuckets/sketchSampleMod && len(sketch.Sample) < sketchSampleMax {
+			sketch.Sample = append(sketch.Sample, hash)
+		}
+	}
+	return sketch
+}
+
+// txSketchRequest is the payload of GetTxSketchMsg.
+type txSketchRequest struct {
+	ID uint64
+}
+
+// txSketchResponse is the payload of TxSketchMsg.
+type txSketchResponse struct {
+	ID     uint64
+	Sketch *txSketch
+}
+
+// TxDivergence reports how the pending transactions of a peer differ from the
+// local ones.
+type TxDivergence struct {
+	Peer             string        `json:"peer"`
+	LocalPending     uint64        `json:"localPending"`
+	RemotePending    uint64        `json:"remotePending"`
+	DivergentBuckets int           `json:"divergentBuckets"` // Number of the 256 sketch buckets that differ
+	MissingLocally   []common.Hash `json:"missingLocally"`   // Sampled transactions only the peer has
+	MissingRemotely  []common.Hash `json:"missingRemotely"`  // Sampled transactions only the local node has
+	Error            string        `json:"error,omitempty"`  // Reason the peer could not be compared
+}
+
+// txDiagPeer is a connected peer speaking the txdiag protocol.
+type txDiagPeer struct {
+	id     string
+	rw     p2p.MsgReadWriter
+	served time.Time // Time the last sketch request of the peer was served at
+
+	lock    sync.Mutex
+	pending map[uint64]chan *txSketch // Outstanding sketch requests by id
+}
+
+// txDiagnostics serves and collects the pending transaction sketches.
+type txDiagnostics struct {
+	txpool txPool
+
+	lock   sync.RWMutex
+	peers  map[string]*txDiagPeer
+	nextID uint64
+
+	sketchLock sync.Mutex // Serialises the creation of the local sketch
+	sketch     *txSketch  // Last local sketch created, shared by the requests
+	sketchTime time.Time  // Time the last local sketch was created at
+}
+
+func newTxDiagnostics(txpool txPool) *txDiagnostics {
+	return &txDiagnostics{
+		txpool: txpool,
+		peers:  make(map[string]*txDiagPeer),
+	}
+}
+
+// Protocol returns the txdiag devp2p sub-protocol.
+func (d *txDiagnostics) Protocol() p2p.Protocol {
+	return p2p.Protocol{
+		Name:    txDiagProtocolName,
+		Version: txDiagProtocolVersion,
+		Length:  txDiagProtocolLength,
+		Run: func(p *p2p.Peer, rw p2p.MsgReadWriter) error {
+			return d.handle(p.ID(), rw)
+		},
+	}
+}
+
+// handle registers a txdiag peer and serves its messages until disconnection.
+func (d *txDiagnostics) handle(id enode.ID, rw p2p.MsgReadWriter) error {
+	peer := &txDiagPeer{
+		id:      fmt.Sprintf("%x", id[:8]),
+		rw:      rw,
+		pending: make(map[uint64]chan *txSketch),
+	}
+	d.lock.Lock()
+	d.peers[peer.id] = peer
+	d.lock.Unlock()
+
+	defer func() {
+		d.lock.Lock()
+		delete(d.peers, peer.id)
+		d.lock.Unlock()
+	}()
+	for {
+		msg, err := rw.ReadMsg()
+		if err != nil {
+			return err
+		}
+		if msg.Size > ProtocolMaxMsgSize {
+			msg.Discard()
+			return errResp(ErrMsgTooLarge, "%v > %v", msg.Size, ProtocolMaxMsgSize)
+		}
+		switch msg.Code {
+		case GetTxSketchMsg:
+			var req txSketchRequest
+			if err := msg.Decode(&req); err != nil {
+				return errResp(ErrDecode, "msg %v: %v", msg, err)
+			}
+			// Sketching walks the whole pool, don't let a peer request it at will
+			if now := time.Now(); now.Sub(peer.served) < sketchInterval {
+				log.Trace("Dropping too frequent sketch request", "peer", peer.id)
+				break
+			} else {
+				peer.served = now
+			}
+			sketch, err := d.localSketch()
+			if err != nil {
+				return err
+			}
+			if err := p2p.Send(rw, TxSketchMsg, &txSketchResponse{ID: req.ID, Sketch: sketch}); err != nil {
+				return err
+			}
+
+		case TxSketchMsg:
+			var res txSketchResponse
+			if err := msg.Decode(&res); err != nil {
+				return errResp(ErrDecode, "msg %v: %v", msg, err)
+			}
+			if res.Sketch == nil || len(res.Sketch.Buckets) != sketchBuckets || len(res.Sketch.Sample) > sketchSampleMax {
+				return errResp(ErrDecode, "invalid sketch")
+			}
+			peer.lock.Lock()
+			ch := peer.pending[res.ID]
+			delete(peer.pending, res.ID)
+			peer.lock.Unlock()
+
+			if ch != nil {
+				ch <- res.Sketch
+			}
+
+		default:
+			return errResp(ErrInvalidMsgCode, "%v", msg.Code)
+		}
+	}
+}
+
+// localSketch returns the sketch of the local pending transactions, reusing the
+// last one created if recent enough, so that concurrent requests share a single
+// walk of the pool.
+func (d *txDiagnostics) localSketch() (*txSketch, error) {
+	d.sketchLock.Lock()
+	defer d.sketchLock.Unlock()
+
+	if d.sketch != nil && time.Since(d.sketchTime) < sketchCacheTTL {
+		return d.sketch, nil
+	}
+	pending, err := d.txpool.Pending()
+	if err != nil {
+		return nil, err
+	}
+	var hashes []common.Hash
+	for _, txs := range pending {
+		for _, tx := range txs {
+			hashes = append(hashes, tx.Hash())
+		}
+	}
+	d.sketch, d.sketchTime = newTxSketch(hashes), time.Now()
+	return d.sketch, nil
+}
+
+// requestSketch asks a peer for its sketch and waits for the reply.
+func (d *txDiagnostics) requestSketch(ctx context.Context, peer *txDiagPeer) (*txSketch, error) {
+	d.lock.Lock()
+	d.nextID++
+	id := d.nextID
+	d.lock.Unlock()
+
+	ch := make(chan *txSketch, 1)
+	peer.lock.Lock()
+	peer.pending[id] = ch
+	peer.lock.Unlock()
+
+	defer func() {
+		peer.lock.Lock()
+		delete(peer.pending, id)
+		peer.lock.Unlock()
+	}()
+	if err := p2p.Send(peer.rw, GetTxSketchMsg, &txSketchRequest{ID: id}); err != nil {
+		return nil, err
+	}
+	timeout := time.NewTimer(sketchTimeout)
+	defer timeout.Stop()
+
+	select {
+	case sketch := <-ch:
+		return sketch, nil
+	case <-timeout.C:
+		return nil, errSketchTimeout
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Divergence samples the pending transactions of all connected txdiag peers
+// and reports how they differ from the local ones. The pools keep changing as
+// blocks are imported, so small differences are expected; transactions that
+// stay missing on a peer over several samples point to a propagation problem.
+func (d *txDiagnostics) Divergence(ctx context.Context) ([]*TxDivergence, error) {
+	local, err := d.localSketch()
+	if err != nil {
+		return nil, err
+	}
+	d.lock.RLock()
+	peers := make([]*txDiagPeer, 0, len(d.peers))
+	for _, peer := range d.peers {
+		peers = append(peers, peer)
+	}
+	d.lock.RUnlock()
+
+	reports := make([]*TxDivergence, len(peers))
+
+	var wg sync.WaitGroup
+	for i, peer := range peers {
+		wg.Add(1)
+		go func(i int, peer *txDiagPeer) {
+			defer wg.Done()
+
+			report := &TxDivergence{Peer: peer.id, LocalPending: local.Count}
+			if remote, err := d.requestSketch(ctx, peer); err != nil {
+				report.Error = err.Error()
+			} else {
+				compareSketches(report, local, remote)
+			}
+			reports[i] = report
+		}(i, peer)
+	}
+	wg.Wait()
+
+	sort.Slice(reports, func(i, j int) bool { return reports[i].Peer < reports[j].Peer })
+	for _, report := range reports {
+		if report.DivergentBuckets > 0 {
+			log.Debug("Pending transactions diverge from peer", "peer", report.Peer, "local", report.LocalPending, "remote", report.RemotePending, "buckets", report.DivergentBuckets)
+		}
+	}
+	return reports, nil
+}
+
+// compareSketches fills in the differences between the local and remote
+// sketches.
+func compareSketches(report *TxDivergence, local, remote *txSketch) {
+	report.RemotePending = remote.Count
+	for i := range local.Buckets {
+		if local.Buckets[i] != remote.Buckets[i] {
+			report.DivergentBuckets++
+		}
+	}
+	// Samples are only complete up to the largest hash both sides included
+	var bound *common.Hash
+	for _, sample := range [][]common.Hash{local.Sample, remote.Sample} {
+		if len(sample) >= sketchSampleMax {
+			if last := sample[len(sample)-1]; bound == nil || bytes.Compare(last[:], bound[:]) < 0 {
+				bound = &last
+			}
+		}
+	}
+	sampled := func(hash common.Hash) bool {
+		return bound == nil || bytes.Compare(hash[:], bound[:]) <= 0
+	}
+	inLocal := make(map[common.Hash]struct{}, len(local.Sample))
+	for _, hash := range local.Sample {
+		inLocal[hash] = struct{}{}
+	}
+	inRemote := make(map[common.Hash]struct{}, len(remote.Sample))
+	for _, hash := range remote.Sample {
+		inRemote[hash] = struct{}{}
+		if _, ok := inLocal[hash]; !ok && sampled(hash) {
+			report.MissingLocally = append(report.MissingLocally, hash)
+		}
+	}
+	for _, hash := range local.Sample {
+		if _, ok := inRemote[hash]; !ok && sampled(hash) {
+			report.MissingRemotely = append(report.MissingRemotely, hash)
+		}
+	}
+}
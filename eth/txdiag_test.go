@@ -0,0 +1,131 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+)
+
+// Tests that two nodes exchanging pending transaction sketches detect the
+// transactions only one of them knows about.
+func TestMempoolDivergence(t *testing.T) {
+	var shared, onlyA, onlyB []*types.Transaction
+	for i := uint64(0); i < 64; i++ {
+		shared = append(shared, newTestTransaction(testAccount, i, 0))
+	}
+	for i := uint64(64); len(onlyA) == 0 || len(onlyB) == 0; i++ {
+		// Only sampled transactions can be named in the report
+		tx := newTestTransaction(testAccount, i, 0)
+		if tx.Hash()[0] >= sketchBuckets/sketchSampleMod {
+			continue
+		}
+		if len(onlyA) == 0 {
+			onlyA = append(onlyA, tx)
+		} else {
+			onlyB = append(onlyB, tx)
+		}
+	}
+	diagA := newTxDiagnostics(&testTxPool{pool: append(append([]*types.Transaction{}, shared...), onlyA...)})
+	diagB := newTxDiagnostics(&testTxPool{pool: append(append([]*types.Transaction{}, shared...), onlyB...)})
+
+	rwA, rwB := p2p.MsgPipe()
+	defer rwA.Close()
+	go diagA.handle(enode.ID{0x0b}, rwA)
+	go diagB.handle(enode.ID{0x0a}, rwB)
+
+	// Wait for both sides to register their peer
+	for {
+		diagA.lock.RLock()
+		n := len(diagA.peers)
+		diagA.lock.RUnlock()
+		if n == 1 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	reports, err := diagA.Divergence(context.Background())
+	if err != nil {
+		t.Fatalf("failed to sample divergence: %v", err)
+	}
+	if len(reports) != 1 {
+		t.Fatalf("report count mismatch: have %d, want 1", len(reports))
+	}
+	report := reports[0]
+	if report.Error != "" {
+		t.Fatalf("peer comparison failed: %s", report.Error)
+	}
+	if report.LocalPending != 65 || report.RemotePending != 65 {
+		t.Errorf("pending count mismatch: have %d/%d, want 65/65", report.LocalPending, report.RemotePending)
+	}
+	if report.DivergentBuckets == 0 {
+		t.Errorf("no divergent buckets reported")
+	}
+	if len(report.MissingLocally) != 1 || report.MissingLocally[0] != onlyB[0].Hash() {
+		t.Errorf("missing local transactions mismatch: have %x, want %x", report.MissingLocally, onlyB[0].Hash())
+	}
+	if len(report.MissingRemotely) != 1 || report.MissingRemotely[0] != onlyA[0].Hash() {
+		t.Errorf("missing remote transactions mismatch: have %x, want %x", report.MissingRemotely, onlyA[0].Hash())
+	}
+}
+
+// Tests that the sketches served are shared between close requests, and that
+// a peer requesting them too often is ignored.
+func TestTxSketchThrottling(t *testing.T) {
+	pool := &testTxPool{pool: []*types.Transaction{newTestTransaction(testAccount, 0, 0)}}
+	diag := newTxDiagnostics(pool)
+
+	rwA, rwB := p2p.MsgPipe()
+	defer rwA.Close()
+	go diag.handle(enode.ID{0x0b}, rwA)
+
+	if err := p2p.Send(rwB, GetTxSketchMsg, &txSketchRequest{ID: 1}); err != nil {
+		t.Fatalf("failed to request sketch: %v", err)
+	}
+	msg, err := rwB.ReadMsg()
+	if err != nil {
+		t.Fatalf("failed to read sketch: %v", err)
+	}
+	var res txSketchResponse
+	if err := msg.Decode(&res); err != nil || res.ID != 1 || res.Sketch.Count != 1 {
+		t.Fatalf("sketch mismatch: have %+v, err %v", res, err)
+	}
+	// The pool changes are only sketched once the cached sketch expires
+	pool.AddRemotes([]*types.Transaction{newTestTransaction(testAccount, 1, 0)})
+	if sketch, _ := diag.localSketch(); sketch.Count != 1 {
+		t.Errorf("cached sketch count mismatch: have %d, want 1", sketch.Count)
+	}
+	// A request right after the first is dropped, a later one served afresh
+	if err := p2p.Send(rwB, GetTxSketchMsg, &txSketchRequest{ID: 2}); err != nil {
+		t.Fatalf("failed to request sketch: %v", err)
+	}
+	time.Sleep(sketchInterval)
+	if err := p2p.Send(rwB, GetTxSketchMsg, &txSketchRequest{ID: 3}); err != nil {
+		t.Fatalf("failed to request sketch: %v", err)
+	}
+	if msg, err = rwB.ReadMsg(); err != nil {
+		t.Fatalf("failed to read sketch: %v", err)
+	}
+	if err := msg.Decode(&res); err != nil || res.ID != 3 || res.Sketch.Count != 2 {
+		t.Fatalf("sketch mismatch: have %+v, err %v", res, err)
+	}
+}
@@ -0,0 +1,518 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/private"
+)
+
+// Events the webhooks are notified of.
+const (
+	WebhookNewBlock       = "newBlock"       // Block added to the canonical chain
+	WebhookContractLog    = "contractLog"    // Log emitted by a watched contract
+	WebhookConsensusFault = "consensusFault" // Consensus health alert raised
+	WebhookPTMFailure     = "ptmFailure"     // Call to the private transaction manager failed
+)
+
+const (
+	webhookTimeout         = 10 * time.Second // Timeout of a single delivery attempt
+	webhookAttempts        = 5                // Delivery attempts before a payload is dead-lettered
+	webhookBackoff         = time.Second      // Delay before the first retry, doubled at each attempt
+	webhookQueueSize       = 256              // Payloads waiting for delivery to a webhook
+	webhookDeadLetterLimit = 1024             // Dead letters kept, the oldest being dropped
+)
+
+// webhookDeadLettersKey is the database key of the undelivered payloads.
+var webhookDeadLettersKey = []byte("webhook-dead-letters")
+
+// WebhookConfig registers a URL notified of node events by signed JSON posts.
+type WebhookConfig struct {
+	URL       string           `json:"url"`
+	Events    []string         `json:"events"`                                // Events posted to the URL
+	Contracts []common.Address `json:"contracts,omitempty" toml:",omitempty"` // Contracts whose logs are posted
+	Topics    []common.Hash    `json:"topics,omitempty" toml:",omitempty"`    // Event signatures of the posted logs, all if empty
+	Secret    string           `json:"secret,omitempty" toml:",omitempty"`    // Key of the HMAC-SHA256 signature of the payloads
+}
+
+// LoadWebhooks reads a JSON list of webhooks from a file.
+func LoadWebhooks(path string) ([]WebhookConfig, error) {
+	blob, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var hooks []WebhookConfig
+	if err := json.Unmarshal(blob, &hooks); err != nil {
+		return nil, fmt.Errorf("invalid webhooks file %s: %v", path, err)
+	}
+	return hooks, nil
+}
+
+// WebhookPayload is the body posted to a webhook. The delivery is authenticated
+// by the X-Quorum-Signature header, "sha256=" followed by the hex encoded
+// HMAC-SHA256 of the body keyed with the secret of the webhook.
+type WebhookPayload struct {
+	ID    string          `json:"id"` // Unique to the event, for receivers to drop repeated deliveries
+	Event string          `json:"event"`
+	Time  uint64          `json:"time"`
+	Data  json.RawMessage `json:"data"`
+}
+
+// WebhookDeadLetter is a payload which couldn't be delivered to a webhook.
+type WebhookDeadLetter struct {
+	URL      string          `json:"url"`
+	Payload  *WebhookPayload `json:"payload"`
+	Attempts int             `json:"attempts"`
+	Error    string          `json:"error"`
+	Time     uint64          `json:"time"`
+}
+
+// webhookBlock is the data of a newBlock event.
+type webhookBlock struct {
+	Number       hexutil.Uint64 `json:"number"`
+	Hash         common.Hash    `json:"hash"`
+	ParentHash   common.Hash    `json:"parentHash"`
+	Timestamp    *hexutil.Big   `json:"timestamp"`
+	Transactions int            `json:"transactions"`
+}
+
+// webhookPTMFailure is the data of a ptmFailure event.
+type webhookPTMFailure struct {
+	Op    string `json:"op"`
+	Error string `json:"error"`
+}
+
+// webhook is a registered URL along with its pending deliveries.
+type webhook struct {
+	config    WebhookConfig
+	events    map[string]bool
+	contracts map[common.Address]bool
+	topics    map[common.Hash]bool
+	queue     chan *WebhookPayload
+}
+
+// newWebhook validates the configuration of a webhook.
+func newWebhook(config WebhookConfig) (*webhook, error) {
+	u, err := url.Parse(config.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, fmt.Errorf("invalid webhook URL %q", config.URL)
+	}
+	if len(config.Events) == 0 {
+		return nil, fmt.Errorf("webhook %s has no events", config.URL)
+	}
+	hook := &webhook{
+		config:    config,
+		events:    make(map[string]bool),
+		contracts: make(map[common.Address]bool),
+		topics:    make(map[common.Hash]bool),
+		queue:     make(chan *WebhookPayload, webhookQueueSize),
+	}
+	for _, event := range config.Events {
+		switch event {
+		case WebhookNewBlock, WebhookContractLog, WebhookConsensusFault, WebhookPTMFailure:
+			hook.events[event] = true
+		default:
+			return nil, fmt.Errorf("webhook %s has unknown event %q", config.URL, event)
+		}
+	}
+	if hook.events[WebhookContractLog] && len(config.Contracts) == 0 {
+		return nil, fmt.Errorf("webhook %s watches logs without contracts", config.URL)
+	}
+	for _, contract := range config.Contracts {
+		hook.contracts[contract] = true
+	}
+	for _, topic := range config.Topics {
+		hook.topics[topic] = true
+	}
+	return hook, nil
+}
+
+// watches returns whether a log is emitted by a watched contract with a watched
+// event signature.
+func (h *webhook) watches(l *types.Log) bool {
+	if !h.contracts[l.Address] {
+		return false
+	}
+	return len(h.topics) == 0 || (len(l.Topics) > 0 && h.topics[l.Topics[0]])
+}
+
+// webhookDispatcher posts the node events to the registered webhooks, retrying
+// the failed deliveries with an exponential backoff and keeping the payloads
+// still undelivered in a dead-letter queue, for teams without streaming
+// infrastructure to react to the node.
+type webhookDispatcher struct {
+	hooks   []*webhook
+	chain   *core.BlockChain
+	health  *healthWatchdog
+	db      ethdb.Database
+	client  *http.Client
+	backoff time.Duration
+
+	deadLock    sync.Mutex           // Serialises the updates of the persisted dead letters
+	pendingLock sync.Mutex           // Protects the dead letters not yet persisted
+	pending     []*WebhookDeadLetter // Dead letters waiting to be persisted
+	deadWake    chan struct{}        // Notifies the dead letter loop of pending letters
+}
+
+// newWebhookDispatcher creates a dispatcher of the events of the chain and of
+// the consensus health watchdog to the given webhooks.
+func newWebhookDispatcher(configs []WebhookConfig, chain *core.BlockChain, health *healthWatchdog, db ethdb.Database) (*webhookDispatcher, error) {
+	d := &webhookDispatcher{
+		chain:   chain,
+		health:  health,
+		db:      db,
+		client:   &http.Client{Timeout: webhookTimeout},
+		backoff:  webhookBackoff,
+		deadWake: make(chan struct{}, 1),
+	}
+	for _, config := range configs {
+		hook, err := newWebhook(config)
+		if err != nil {
+			return nil, err
+		}
+		d.hooks = append(d.hooks, hook)
+	}
+	return d, nil
+}
+
+// loop dispatches the node events to the webhooks until quit is closed. The
+// payloads left undelivered at that point are persisted before returning.
+func (d *webhookDispatcher) loop(quit chan bool) {
+	var delivering sync.WaitGroup
+	for _, hook := range d.hooks {
+		delivering.Add(1)
+		go func(hook *webhook) {
+			defer delivering.Done()
+			d.deliver(hook, quit)
+		}(hook)
+	}
+	go d.deadLetterLoop(quit)
+
+	var (
+		chainCh   = make(chan core.ChainEvent, 64)
+		chainSub  = d.chain.SubscribeChainEvent(chainCh)
+		alertCh   = make(chan HealthAlert, 16)
+		alertSub  = d.health.subscribeAlerts(alertCh)
+		failureCh = make(chan private.FailureEvent, 16)
+		failSub   = private.SubscribeFailureEvent(failureCh)
+	)
+	defer chainSub.Unsubscribe()
+	defer alertSub.Unsubscribe()
+	defer failSub.Unsubscribe()
+
+	for {
+		select {
+		case ev := <-chainCh:
+			header := ev.Block.Header()
+			d.dispatch(WebhookNewBlock, &webhookBlock{
+				Number:       hexutil.Uint64(header.Number.Uint64()),
+				Hash:         ev.Hash,
+				ParentHash:   header.ParentHash,
+				Timestamp:    (*hexutil.Big)(header.Time),
+				Transactions: len(ev.Block.Transactions()),
+			}, nil)
+			for _, l := range ev.Logs {
+				d.dispatch(WebhookContractLog, l, l)
+			}
+		case alert := <-alertCh:
+			d.dispatch(WebhookConsensusFault, alert, nil)
+		case failure := <-failureCh:
+			d.dispatch(WebhookPTMFailure, &webhookPTMFailure{Op: failure.Op, Error: failure.Err.Error()}, nil)
+		case <-chainSub.Err():
+			return
+		case <-quit:
+			delivering.Wait()
+			d.flushDeadLetters()
+			return
+		}
+	}
+}
+
+// deadLetterLoop persists the dead letters off the event loop, until quit is
+// closed.
+func (d *webhookDispatcher) deadLetterLoop(quit chan bool) {
+	for {
+		select {
+		case <-d.deadWake:
+			d.flushDeadLetters()
+		case <-quit:
+			return
+		}
+	}
+}
+
+// dispatch queues an event for delivery to the webhooks notified of it, only
+// those watching the log for a contract log. An event not fitting in the queue
+// of a webhook is dead-lettered.
+func (d *webhookDispatcher) dispatch(event string, data interface{}, l *types.Log) {
+	var payload *WebhookPayload
+	for _, hook := range d.hooks {
+		if !hook.events[event] || (l != nil && !hook.watches(l)) {
+			continue
+		}
+		if payload == nil {
+			blob, err := json.Marshal(data)
+			if err != nil {
+				log.Error("Failed to encode webhook payload", "event", event, "err", err)
+				return
+			}
+			id := make([]byte, 16)
+			rand.Read(id)
+			payload = &WebhookPayload{ID: hex.EncodeToString(id), Event: event, Time: uint64(time.Now().Unix()), Data: blob}
+		}
+		select {
+		case hook.queue <- payload:
+		default:
+			d.deadLetter(hook, payload, 0, "delivery queue full")
+		}
+	}
+}
+
+// deliver posts the payloads queued for a webhook in order, until quit is
+// closed. The payloads still queued at that point are dead-lettered.
+func (d *webhookDispatcher) deliver(hook *webhook, quit chan bool) {
+	for {
+		select {
+		case payload := <-hook.queue:
+			if !d.attempt(hook, payload, quit) {
+				d.drain(hook)
+				return
+			}
+		case <-quit:
+			d.drain(hook)
+			return
+		}
+	}
+}
+
+// attempt posts a payload to a webhook until delivered or out of attempts, in
+// which case it is dead-lettered. It returns false if quit was closed meanwhile.
+func (d *webhookDispatcher) attempt(hook *webhook, payload *WebhookPayload, quit chan bool) bool {
+	delay := d.backoff
+	for attempt := 1; ; attempt++ {
+		err := d.post(hook, payload)
+		if err == nil {
+			webhookDeliveredMeter.Mark(1)
+			return true
+		}
+		if attempt == webhookAttempts {
+			log.Warn("Webhook delivery failed, dead-lettering payload", "url", hook.config.URL, "event", payload.Event, "id", payload.ID, "err", err)
+			d.deadLetter(hook, payload, attempt, err.Error())
+			return true
+		}
+		webhookRetryMeter.Mark(1)
+		select {
+		case <-time.After(delay):
+			delay *= 2
+		case <-quit:
+			d.deadLetter(hook, payload, attempt, err.Error())
+			return false
+		}
+	}
+}
+
+// drain dead-letters the payloads left in the queue of a webhook.
+func (d *webhookDispatcher) drain(hook *webhook) {
+	for {
+		select {
+		case payload := <-hook.queue:
+			d.deadLetter(hook, payload, 0, "node stopped")
+		default:
+			return
+		}
+	}
+}
+
+// post makes a single delivery attempt of a payload.
+func (d *webhookDispatcher) post(hook *webhook, payload *WebhookPayload) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", hook.config.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Quorum-Event", payload.Event)
+	req.Header.Set("X-Quorum-Delivery", payload.ID)
+	if hook.config.Secret != "" {
+		req.Header.Set("X-Quorum-Signature", "sha256="+hex.EncodeToString(hmacSHA256([]byte(hook.config.Secret), string(body))))
+	}
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+// storedDeadLetters returns the persisted undelivered payloads, oldest first.
+func (d *webhookDispatcher) storedDeadLetters() []*WebhookDeadLetter {
+	letters := []*WebhookDeadLetter{}
+	if blob, err := d.db.Get(webhookDeadLettersKey); err == nil {
+		if err := json.Unmarshal(blob, &letters); err != nil {
+			log.Error("Invalid webhook dead letters", "err", err)
+		}
+	}
+	return letters
+}
+
+// storeDeadLetters persists the undelivered payloads, keeping the most recent
+// ones up to the limit.
+func (d *webhookDispatcher) storeDeadLetters(letters []*WebhookDeadLetter) {
+	if len(letters) > webhookDeadLetterLimit {
+		letters = letters[len(letters)-webhookDeadLetterLimit:]
+	}
+	blob, err := json.Marshal(letters)
+	if err == nil {
+		err = d.db.Put(webhookDeadLettersKey, blob)
+	}
+	if err != nil {
+		log.Error("Failed to store webhook dead letters", "err", err)
+	}
+}
+
+// deadLetters returns the undelivered payloads, persisted or not, oldest first.
+func (d *webhookDispatcher) deadLetters() []*WebhookDeadLetter {
+	d.deadLock.Lock()
+	defer d.deadLock.Unlock()
+
+	d.pendingLock.Lock()
+	defer d.pendingLock.Unlock()
+
+	letters := append(d.storedDeadLetters(), d.pending...)
+	if len(letters) > webhookDeadLetterLimit {
+		letters = letters[len(letters)-webhookDeadLetterLimit:]
+	}
+	return letters
+}
+
+// deadLetter queues a payload which couldn't be delivered to a webhook, for the
+// dead letter loop to persist it. It never touches the database, being called
+// from the event loop.
+func (d *webhookDispatcher) deadLetter(hook *webhook, payload *WebhookPayload, attempts int, reason string) {
+	webhookDeadLetterMeter.Mark(1)
+
+	d.pendingLock.Lock()
+	d.pending = append(d.pending, &WebhookDeadLetter{
+		URL:      hook.config.URL,
+		Payload:  payload,
+		Attempts: attempts,
+		Error:    reason,
+		Time:     uint64(time.Now().Unix()),
+	})
+	d.pendingLock.Unlock()
+
+	select {
+	case d.deadWake <- struct{}{}:
+	default:
+	}
+}
+
+// flushDeadLetters persists the queued dead letters.
+func (d *webhookDispatcher) flushDeadLetters() {
+	d.deadLock.Lock()
+	defer d.deadLock.Unlock()
+
+	d.pendingLock.Lock()
+	pending := d.pending
+	d.pending = nil
+	d.pendingLock.Unlock()
+
+	if len(pending) == 0 {
+		return
+	}
+	d.storeDeadLetters(append(d.storedDeadLetters(), pending...))
+}
+
+// retryDeadLetters queues the undelivered payloads for delivery again, returning
+// how many were queued. The payloads of webhooks no longer registered, or not
+// fitting in their queue, are kept.
+func (d *webhookDispatcher) retryDeadLetters() int {
+	d.flushDeadLetters()
+
+	d.deadLock.Lock()
+	defer d.deadLock.Unlock()
+
+	hooks := make(map[string]*webhook)
+	for _, hook := range d.hooks {
+		hooks[hook.config.URL] = hook
+	}
+	var (
+		kept   []*WebhookDeadLetter
+		queued int
+	)
+	for _, letter := range d.storedDeadLetters() {
+		if hook := hooks[letter.URL]; hook != nil {
+			select {
+			case hook.queue <- letter.Payload:
+				queued++
+				continue
+			default:
+			}
+		}
+		kept = append(kept, letter)
+	}
+	if kept == nil {
+		kept = []*WebhookDeadLetter{}
+	}
+	d.storeDeadLetters(kept)
+	return queued
+}
+
+// PrivateWebhookAPI provides the payloads which couldn't be delivered to the
+// webhooks.
+type PrivateWebhookAPI struct {
+	dispatcher *webhookDispatcher
+}
+
+// NewPrivateWebhookAPI creates a new webhook API.
+func NewPrivateWebhookAPI(dispatcher *webhookDispatcher) *PrivateWebhookAPI {
+	return &PrivateWebhookAPI{dispatcher}
+}
+
+// WebhookDeadLetters returns the payloads which couldn't be delivered to the
+// webhooks, oldest first.
+func (api *PrivateWebhookAPI) WebhookDeadLetters() []*WebhookDeadLetter {
+	return api.dispatcher.deadLetters()
+}
+
+// RetryWebhookDeadLetters delivers the dead-lettered payloads again, returning
+// how many were queued.
+func (api *PrivateWebhookAPI) RetryWebhookDeadLetters() int {
+	return api.dispatcher.retryDeadLetters()
+}
@@ -0,0 +1,151 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+// Tests that invalid webhooks are rejected.
+func TestWebhookConfig(t *testing.T) {
+	tests := []WebhookConfig{
+		{URL: "ftp://example.com", Events: []string{WebhookNewBlock}},
+		{URL: "http://example.com"},
+		{URL: "http://example.com", Events: []string{"unknown"}},
+		{URL: "http://example.com", Events: []string{WebhookContractLog}},
+	}
+	for i, config := range tests {
+		if _, err := newWebhook(config); err == nil {
+			t.Errorf("test %d: invalid webhook accepted", i)
+		}
+	}
+}
+
+// Tests that the payloads are signed and delivered to the webhooks notified of
+// their event, retried on failure and dead-lettered once out of attempts.
+func TestWebhookDelivery(t *testing.T) {
+	var (
+		failing  int32 = 1 // Number of requests failed before succeeding
+		received       = make(chan *WebhookPayload, 16)
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&failing, -1) >= 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		if want := "sha256=" + hex.EncodeToString(hmacSHA256([]byte("secret"), string(body))); r.Header.Get("X-Quorum-Signature") != want {
+			t.Errorf("signature mismatch: have %q, want %q", r.Header.Get("X-Quorum-Signature"), want)
+		}
+		payload := new(WebhookPayload)
+		if err := json.Unmarshal(body, payload); err != nil {
+			t.Errorf("invalid payload: %v", err)
+		}
+		received <- payload
+	}))
+	defer server.Close()
+
+	contract := common.Address{0x01}
+	d, err := newWebhookDispatcher([]WebhookConfig{
+		{URL: server.URL, Events: []string{WebhookNewBlock, WebhookContractLog}, Contracts: []common.Address{contract}, Secret: "secret"},
+	}, nil, nil, ethdb.NewMemDatabase())
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.backoff = time.Millisecond
+	quit := make(chan bool)
+	defer close(quit)
+	go d.deliver(d.hooks[0], quit)
+
+	// The failed first attempt is retried, the unwatched events skipped
+	d.dispatch(WebhookPTMFailure, &webhookPTMFailure{Op: "send"}, nil)
+	d.dispatch(WebhookContractLog, &types.Log{Address: common.Address{0x02}}, &types.Log{Address: common.Address{0x02}})
+	d.dispatch(WebhookContractLog, &types.Log{Address: contract}, &types.Log{Address: contract})
+	select {
+	case payload := <-received:
+		if payload.Event != WebhookContractLog {
+			t.Errorf("event mismatch: have %s, want %s", payload.Event, WebhookContractLog)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("payload not delivered")
+	}
+	// A payload failing every attempt is dead-lettered, and delivered on retry
+	atomic.StoreInt32(&failing, webhookAttempts)
+	d.dispatch(WebhookNewBlock, &webhookBlock{Number: 1}, nil)
+	for start := time.Now(); len(d.deadLetters()) == 0; time.Sleep(10 * time.Millisecond) {
+		if time.Since(start) > 5*time.Second {
+			t.Fatalf("payload not dead-lettered")
+		}
+	}
+	if letter := d.deadLetters()[0]; letter.Attempts != webhookAttempts || letter.Payload.Event != WebhookNewBlock {
+		t.Errorf("dead letter mismatch: have %d attempts of %s", letter.Attempts, letter.Payload.Event)
+	}
+	if queued := d.retryDeadLetters(); queued != 1 {
+		t.Fatalf("queued dead letters mismatch: have %d, want 1", queued)
+	}
+	select {
+	case payload := <-received:
+		if payload.Event != WebhookNewBlock {
+			t.Errorf("event mismatch: have %s, want %s", payload.Event, WebhookNewBlock)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("dead letter not delivered on retry")
+	}
+	if letters := d.deadLetters(); len(letters) != 0 {
+		t.Errorf("dead letters left: %d", len(letters))
+	}
+}
+
+// Tests that the payloads dead-lettered by the event loop are persisted off it.
+func TestWebhookDeadLetterOffLoop(t *testing.T) {
+	d, err := newWebhookDispatcher([]WebhookConfig{
+		{URL: "http://localhost:1", Events: []string{WebhookNewBlock}},
+	}, nil, nil, ethdb.NewMemDatabase())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < webhookQueueSize+1; i++ {
+		d.dispatch(WebhookNewBlock, &webhookBlock{Number: hexutil.Uint64(i)}, nil)
+	}
+	if stored := d.storedDeadLetters(); len(stored) != 0 {
+		t.Fatalf("dead letter persisted by the event loop")
+	}
+	if letters := d.deadLetters(); len(letters) != 1 || letters[0].Error != "delivery queue full" {
+		t.Fatalf("pending dead letters mismatch: have %d", len(letters))
+	}
+	quit := make(chan bool)
+	go d.deadLetterLoop(quit)
+	defer close(quit)
+
+	for start := time.Now(); len(d.storedDeadLetters()) == 0; time.Sleep(10 * time.Millisecond) {
+		if time.Since(start) > 5*time.Second {
+			t.Fatalf("dead letter not persisted")
+		}
+	}
+}
@@ -0,0 +1,252 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package debug
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// captureSuffix is the extension of the profiles captured, the only files of
+// the capture directory deleted to respect its quota.
+const captureSuffix = ".pprof"
+
+// captureProfiles are the profiles captured by debug_capturePprof.
+var captureProfiles = map[string]bool{
+	"cpu": true, "block": true, "mutex": true, "heap": true, "allocs": true, "goroutine": true,
+}
+
+var (
+	errCaptureDisabled = errors.New("profile capture disabled (--pprof.capturedir)")
+	errCaptureBusy     = errors.New("too many profile captures in progress")
+)
+
+// CaptureConfig holds the safety rails of the profiles captured over RPC.
+type CaptureConfig struct {
+	Dir         string        // Directory the profiles are written to, capture disabled if empty
+	Quota       int64         // Bytes the captured profiles may take, the oldest deleted beyond (0 = unlimited)
+	Concurrency int           // Number of captures running at once
+	MaxDuration time.Duration // Longest capture of the profiles sampled over a duration (0 = unlimited)
+}
+
+// PprofCapture describes a captured profile.
+type PprofCapture struct {
+	Profile  string `json:"profile"`
+	File     string `json:"file"`
+	Size     int64  `json:"size"`
+	Duration string `json:"duration"`
+}
+
+// profileCapturer captures the profiles requested over RPC, one at a time for
+// each profile, as the runtime profiling rates are process-wide.
+type profileCapturer struct {
+	lock   sync.Mutex
+	config CaptureConfig
+	slots  chan struct{}
+	active map[string]bool
+}
+
+// SetCaptureConfig configures the capture of profiles over RPC.
+func (h *HandlerT) SetCaptureConfig(config CaptureConfig) error {
+	if config.Dir != "" {
+		config.Dir = expandHome(config.Dir)
+		if err := os.MkdirAll(config.Dir, 0700); err != nil {
+			return err
+		}
+	}
+	if config.Concurrency <= 0 {
+		config.Concurrency = 1
+	}
+	h.capture.lock.Lock()
+	defer h.capture.lock.Unlock()
+
+	h.capture.config = config
+	h.capture.slots = make(chan struct{}, config.Concurrency)
+	h.capture.active = make(map[string]bool)
+	return nil
+}
+
+// CapturePprof captures a CPU, heap, allocs, goroutine, block or mutex profile
+// to the configured directory, returning the file written. The CPU, block and
+// mutex profiles are sampled for the given number of seconds, capped by the
+// configured maximum, the others taken at once. The oldest profiles captured
+// are deleted to keep the directory within its quota.
+func (h *HandlerT) CapturePprof(ctx context.Context, profile string, nsec uint) (*PprofCapture, error) {
+	if !captureProfiles[profile] {
+		return nil, fmt.Errorf("unknown profile %q", profile)
+	}
+	c := &h.capture
+	c.lock.Lock()
+	config, slots := c.config, c.slots
+	if config.Dir == "" {
+		c.lock.Unlock()
+		return nil, errCaptureDisabled
+	}
+	if c.active[profile] {
+		c.lock.Unlock()
+		return nil, fmt.Errorf("%s profile capture already in progress", profile)
+	}
+	select {
+	case slots <- struct{}{}:
+	default:
+		c.lock.Unlock()
+		return nil, errCaptureBusy
+	}
+	c.active[profile] = true
+	c.lock.Unlock()
+
+	defer func() {
+		c.lock.Lock()
+		delete(c.active, profile)
+		c.lock.Unlock()
+		<-slots
+	}()
+	duration := time.Duration(nsec) * time.Second
+	if config.MaxDuration > 0 && duration > config.MaxDuration {
+		duration = config.MaxDuration
+	}
+	file := filepath.Join(config.Dir, fmt.Sprintf("%s-%s%s", profile, time.Now().UTC().Format("20060102T150405.000"), captureSuffix))
+	f, err := os.Create(file)
+	if err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	switch profile {
+	case "cpu":
+		err = h.captureCPU(ctx, f, duration)
+	case "block":
+		runtime.SetBlockProfileRate(1)
+		sleepCtx(ctx, duration)
+		h.restoreBlockProfileRate()
+		err = pprof.Lookup(profile).WriteTo(f, 0)
+	case "mutex":
+		prev := runtime.SetMutexProfileFraction(1)
+		sleepCtx(ctx, duration)
+		runtime.SetMutexProfileFraction(prev)
+		err = pprof.Lookup(profile).WriteTo(f, 0)
+	default:
+		err = pprof.Lookup(profile).WriteTo(f, 0)
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(file)
+		return nil, err
+	}
+	info, err := os.Stat(file)
+	if err != nil {
+		return nil, err
+	}
+	log.Info("Captured profile", "profile", profile, "file", file, "size", info.Size(), "elapsed", time.Since(start))
+	if config.Quota > 0 {
+		pruneCaptures(config.Dir, config.Quota, file)
+	}
+	return &PprofCapture{
+		Profile:  profile,
+		File:     file,
+		Size:     info.Size(),
+		Duration: time.Since(start).String(),
+	}, nil
+}
+
+// captureCPU samples the CPU profile for the given duration, unless profiling
+// the CPU already.
+func (h *HandlerT) captureCPU(ctx context.Context, f *os.File, duration time.Duration) error {
+	h.mu.Lock()
+	if h.cpuW != nil {
+		h.mu.Unlock()
+		return errors.New("CPU profiling already in progress")
+	}
+	if err := pprof.StartCPUProfile(f); err != nil {
+		h.mu.Unlock()
+		return err
+	}
+	h.mu.Unlock()
+
+	sleepCtx(ctx, duration)
+	pprof.StopCPUProfile()
+	return nil
+}
+
+// restoreBlockProfileRate sets the block profile rate back to the one last set,
+// which the runtime does not report.
+func (h *HandlerT) restoreBlockProfileRate() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	runtime.SetBlockProfileRate(h.blockRate)
+}
+
+// sleepCtx waits for the given duration, or until the context is done.
+func sleepCtx(ctx context.Context, duration time.Duration) {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+}
+
+// pruneCaptures deletes the oldest profiles of the capture directory until they
+// take no more than the quota, sparing the one just captured.
+func pruneCaptures(dir string, quota int64, keep string) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		log.Warn("Failed to list captured profiles", "dir", dir, "err", err)
+		return
+	}
+	var (
+		captures []os.FileInfo
+		total    int64
+	)
+	for _, info := range infos {
+		if info.Mode().IsRegular() && strings.HasSuffix(info.Name(), captureSuffix) {
+			captures = append(captures, info)
+			total += info.Size()
+		}
+	}
+	sort.Slice(captures, func(i, j int) bool { return captures[i].ModTime().Before(captures[j].ModTime()) })
+	for _, info := range captures {
+		if total <= quota {
+			return
+		}
+		file := filepath.Join(dir, info.Name())
+		if file == keep {
+			continue
+		}
+		if err := os.Remove(file); err != nil {
+			log.Warn("Failed to delete captured profile", "file", file, "err", err)
+			continue
+		}
+		log.Debug("Deleted captured profile over quota", "file", file, "size", info.Size())
+		total -= info.Size()
+	}
+}
@@ -0,0 +1,191 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package debug
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"testing"
+	"time"
+)
+
+func newCaptureHandler(t *testing.T, config CaptureConfig) *HandlerT {
+	h := new(HandlerT)
+	if err := h.SetCaptureConfig(config); err != nil {
+		t.Fatalf("failed to configure capture: %v", err)
+	}
+	return h
+}
+
+// Tests that the profiles are captured to the configured directory.
+func TestCapturePprof(t *testing.T) {
+	dir, err := ioutil.TempDir("", "capture")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if _, err := new(HandlerT).CapturePprof(context.Background(), "heap", 0); err != errCaptureDisabled {
+		t.Fatalf("capture error mismatch: have %v, want %v", err, errCaptureDisabled)
+	}
+	h := newCaptureHandler(t, CaptureConfig{Dir: dir})
+	if _, err := h.CapturePprof(context.Background(), "threadcreate", 0); err == nil {
+		t.Fatalf("unknown profile captured")
+	}
+	for _, profile := range []string{"heap", "allocs", "goroutine", "block", "mutex", "cpu"} {
+		capture, err := h.CapturePprof(context.Background(), profile, 0)
+		if err != nil {
+			t.Fatalf("failed to capture %s profile: %v", profile, err)
+		}
+		if filepath.Dir(capture.File) != dir {
+			t.Errorf("%s profile captured outside of the directory: %s", profile, capture.File)
+		}
+		info, err := os.Stat(capture.File)
+		if err != nil {
+			t.Fatalf("%s profile missing: %v", profile, err)
+		}
+		if info.Size() != capture.Size {
+			t.Errorf("%s profile size mismatch: have %d, want %d", profile, capture.Size, info.Size())
+		}
+	}
+}
+
+// Tests that the captures restore the profiling rates set beforehand.
+func TestCapturePprofRates(t *testing.T) {
+	dir, err := ioutil.TempDir("", "capture")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	defer runtime.SetMutexProfileFraction(runtime.SetMutexProfileFraction(5))
+
+	h := newCaptureHandler(t, CaptureConfig{Dir: dir})
+	h.SetBlockProfileRate(7)
+	defer h.SetBlockProfileRate(0)
+
+	for _, profile := range []string{"block", "mutex"} {
+		if _, err := h.CapturePprof(context.Background(), profile, 0); err != nil {
+			t.Fatalf("failed to capture %s profile: %v", profile, err)
+		}
+	}
+	if rate := runtime.SetMutexProfileFraction(-1); rate != 5 {
+		t.Errorf("mutex profile fraction mismatch: have %d, want 5", rate)
+	}
+	if h.blockRate != 7 {
+		t.Errorf("block profile rate mismatch: have %d, want 7", h.blockRate)
+	}
+}
+
+// Tests that the captures beyond the concurrency limit, or of a profile being
+// captured already, are rejected.
+func TestCapturePprofConcurrency(t *testing.T) {
+	dir, err := ioutil.TempDir("", "capture")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, concurrency := range []int{1, 2} {
+		h := newCaptureHandler(t, CaptureConfig{Dir: dir, Concurrency: concurrency})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error)
+		go func() {
+			_, err := h.CapturePprof(ctx, "block", 60)
+			done <- err
+		}()
+		for {
+			h.capture.lock.Lock()
+			active := h.capture.active["block"]
+			h.capture.lock.Unlock()
+			if active {
+				break
+			}
+			time.Sleep(time.Millisecond)
+		}
+		if _, err := h.CapturePprof(context.Background(), "block", 0); err == nil {
+			t.Errorf("concurrency %d: profile captured twice at once", concurrency)
+		}
+		_, err := h.CapturePprof(context.Background(), "heap", 0)
+		if concurrency == 1 && err != errCaptureBusy {
+			t.Errorf("concurrency %d: capture error mismatch: have %v, want %v", concurrency, err, errCaptureBusy)
+		}
+		if concurrency > 1 && err != nil {
+			t.Errorf("concurrency %d: failed to capture: %v", concurrency, err)
+		}
+		cancel()
+		if err := <-done; err != nil {
+			t.Fatalf("concurrency %d: failed to capture: %v", concurrency, err)
+		}
+		// The slot of the capture must be released once done
+		if _, err := h.CapturePprof(context.Background(), "heap", 0); err != nil {
+			t.Errorf("concurrency %d: failed to capture after release: %v", concurrency, err)
+		}
+	}
+}
+
+// Tests that the oldest profiles are deleted to keep within the quota, sparing
+// the one just captured and the other files.
+func TestPruneCaptures(t *testing.T) {
+	dir, err := ioutil.TempDir("", "capture")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := []string{"a" + captureSuffix, "b" + captureSuffix, "c" + captureSuffix, "d" + captureSuffix, "notes.txt"}
+	base := time.Now().Add(-time.Hour)
+	for i, name := range files {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, make([]byte, 100), 0600); err != nil {
+			t.Fatal(err)
+		}
+		// The profile just captured is the oldest, to check it is spared
+		modified := base.Add(time.Duration(i) * time.Minute)
+		if name == "b"+captureSuffix {
+			modified = base.Add(-time.Minute)
+		}
+		if err := os.Chtimes(path, modified, modified); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pruneCaptures(dir, 250, filepath.Join(dir, "b"+captureSuffix))
+
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var left []string
+	for _, info := range infos {
+		left = append(left, info.Name())
+	}
+	sort.Strings(left)
+	want := []string{"b" + captureSuffix, "d" + captureSuffix, "notes.txt"}
+	if len(left) != len(want) {
+		t.Fatalf("files left mismatch: have %v, want %v", left, want)
+	}
+	for i := range want {
+		if left[i] != want[i] {
+			t.Fatalf("files left mismatch: have %v, want %v", left, want)
+		}
+	}
+}
@@ -23,6 +23,7 @@ import (
 	_ "net/http/pprof"
 	"os"
 	"runtime"
+	"time"
 
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/metrics"
@@ -86,6 +87,53 @@ var (
 		Name:  "trace",
 		Usage: "Write execution trace to the given file",
 	}
+	// Quorum
+	pprofCaptureDirFlag = cli.StringFlag{
+		Name:  "pprof.capturedir",
+		Usage: "Directory the profiles captured by debug_capturePprof are written to (disabled if empty)",
+	}
+	pprofCaptureQuotaFlag = cli.Uint64Flag{
+		Name:  "pprof.capturequota",
+		Usage: "Megabytes the captured profiles may take, the oldest deleted beyond (0 = unlimited)",
+		Value: 512,
+	}
+	pprofCaptureConcurrencyFlag = cli.IntFlag{
+		Name:  "pprof.captureconcurrency",
+		Usage: "Number of profile captures running at once",
+		Value: 1,
+	}
+	pprofCaptureMaxDurationFlag = cli.DurationFlag{
+		Name:  "pprof.capturemaxduration",
+		Usage: "Longest capture of the CPU, block and mutex profiles (0 = unlimited)",
+		Value: 5 * time.Minute,
+	}
+	logFormatFlag = cli.StringFlag{
+		Name:  "log.format",
+		Usage: "Log format of the standard error and log file: terminal or json (stable field names)",
+		Value: "terminal",
+	}
+	logFileFlag = cli.StringFlag{
+		Name:  "log.file",
+		Usage: "File the logs are also written to, rotated according to the log.rotate flags",
+	}
+	logRotateSizeFlag = cli.Uint64Flag{
+		Name:  "log.rotate.maxsize",
+		Usage: "Size in megabytes the log file is rotated at (0 = unlimited)",
+		Value: 100,
+	}
+	logRotateAgeFlag = cli.DurationFlag{
+		Name:  "log.rotate.maxage",
+		Usage: "Age the log file is rotated at (0 = unlimited)",
+	}
+	logRotateBackupsFlag = cli.IntFlag{
+		Name:  "log.rotate.maxbackups",
+		Usage: "Number of rotated log files kept (0 = all)",
+		Value: 10,
+	}
+	logRotateCompressFlag = cli.BoolFlag{
+		Name:  "log.rotate.compress",
+		Usage: "Compress the rotated log files with gzip",
+	}
 )
 
 // Flags holds all command-line flags required for debugging.
@@ -93,6 +141,8 @@ var Flags = []cli.Flag{
 	verbosityFlag, vmoduleFlag, backtraceAtFlag, debugFlag,
 	pprofFlag, pprofAddrFlag, pprofPortFlag,
 	memprofilerateFlag, blockprofilerateFlag, cpuprofileFlag, traceFlag,
+	pprofCaptureDirFlag, pprofCaptureQuotaFlag, pprofCaptureConcurrencyFlag, pprofCaptureMaxDurationFlag,
+	logFormatFlag, logFileFlag, logRotateSizeFlag, logRotateAgeFlag, logRotateBackupsFlag, logRotateCompressFlag,
 }
 
 var (
@@ -115,6 +165,17 @@ func init() {
 func Setup(ctx *cli.Context, logdir string) error {
 	// logging
 	log.PrintOrigins(ctx.GlobalBool(debugFlag.Name))
+
+	handlers := []log.Handler{ostream}
+	format := log.Format(nil)
+	switch f := ctx.GlobalString(logFormatFlag.Name); f {
+	case "terminal":
+	case "json":
+		format = log.StructuredJSONFormat()
+		handlers[0] = log.StreamHandler(os.Stderr, format)
+	default:
+		return fmt.Errorf("unknown log format %q", f)
+	}
 	if logdir != "" {
 		rfh, err := log.RotatingFileHandler(
 			logdir,
@@ -124,8 +185,24 @@ func Setup(ctx *cli.Context, logdir string) error {
 		if err != nil {
 			return err
 		}
-		glogger.SetHandler(log.MultiHandler(ostream, rfh))
+		handlers = append(handlers, rfh)
 	}
+	if logfile := ctx.GlobalString(logFileFlag.Name); logfile != "" {
+		if format == nil {
+			format = log.LogfmtFormat()
+		}
+		rfh, err := log.RotatingFileHandlerEx(logfile, log.RotateConfig{
+			MaxSize:    ctx.GlobalUint64(logRotateSizeFlag.Name) * 1024 * 1024,
+			MaxAge:     ctx.GlobalDuration(logRotateAgeFlag.Name),
+			MaxBackups: ctx.GlobalInt(logRotateBackupsFlag.Name),
+			Compress:   ctx.GlobalBool(logRotateCompressFlag.Name),
+		}, format)
+		if err != nil {
+			return err
+		}
+		handlers = append(handlers, rfh)
+	}
+	glogger.SetHandler(log.MultiHandler(handlers...))
 	glogger.Verbosity(log.Lvl(ctx.GlobalInt(verbosityFlag.Name)))
 	glogger.Vmodule(ctx.GlobalString(vmoduleFlag.Name))
 	glogger.BacktraceAt(ctx.GlobalString(backtraceAtFlag.Name))
@@ -145,6 +222,17 @@ func Setup(ctx *cli.Context, logdir string) error {
 		}
 	}
 
+	// Quorum
+	err := Handler.SetCaptureConfig(CaptureConfig{
+		Dir:         ctx.GlobalString(pprofCaptureDirFlag.Name),
+		Quota:       int64(ctx.GlobalUint64(pprofCaptureQuotaFlag.Name)) * 1024 * 1024,
+		Concurrency: ctx.GlobalInt(pprofCaptureConcurrencyFlag.Name),
+		MaxDuration: ctx.GlobalDuration(pprofCaptureMaxDurationFlag.Name),
+	})
+	if err != nil {
+		return err
+	}
+
 	// pprof server
 	if ctx.GlobalBool(pprofFlag.Name) {
 		address := fmt.Sprintf("%s:%d", ctx.GlobalString(pprofAddrFlag.Name), ctx.GlobalInt(pprofPortFlag.Name))
@@ -41,13 +41,16 @@ import (
 	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/txtrail"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/p2p"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/private"
+	"github.com/ethereum/go-ethereum/private/privatetransactionmanager"
 	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/syndtr/goleveldb/leveldb"
@@ -61,6 +64,10 @@ const (
 	maxPrivateIntrinsicDataHex = "11111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111"
 )
 
+// errPrivacyGroupAndPrivateFor is returned when a private transaction is sent
+// to both a privacy group and a list of recipients.
+var errPrivacyGroupAndPrivateFor = errors.New("privacyGroupId and privateFor are mutually exclusive")
+
 // PublicEthereumAPI provides an API to access Ethereum related information.
 // It offers only methods that operate on public data that is freely available to anyone.
 type PublicEthereumAPI struct {
@@ -188,6 +195,17 @@ func (s *PublicTxPoolAPI) Inspect() map[string]map[string]map[string]string {
 	return content
 }
 
+// Check runs the admission checks of the transaction pool on a signed, RLP
+// encoded transaction without adding it to the pool, reporting the outcome of
+// each check.
+func (s *PublicTxPoolAPI) Check(encodedTx hexutil.Bytes) ([]core.TxCheckResult, error) {
+	tx := new(types.Transaction)
+	if err := rlp.DecodeBytes(encodedTx, tx); err != nil {
+		return nil, err
+	}
+	return s.b.CheckTx(tx)
+}
+
 // PublicAccountAPI provides an API to access accounts managed by this node.
 // It offers only methods that can retrieve accounts.
 type PublicAccountAPI struct {
@@ -328,7 +346,7 @@ func (s *PrivateAccountAPI) ImportRawKey(privkey string, password string) (commo
 // UnlockAccount will unlock the account associated with the given address with
 // the given password for duration seconds. If duration is nil it will use a
 // default of 300 seconds. It returns an indication if the account was unlocked.
-func (s *PrivateAccountAPI) UnlockAccount(addr common.Address, password string, duration *uint64) (bool, error) {
+func (s *PrivateAccountAPI) UnlockAccount(ctx context.Context, addr common.Address, password string, duration *uint64) (bool, error) {
 	const max = uint64(time.Duration(math.MaxInt64) / time.Second)
 	var d time.Duration
 	if duration == nil {
@@ -338,7 +356,15 @@ func (s *PrivateAccountAPI) UnlockAccount(addr common.Address, password string,
 	} else {
 		d = time.Duration(*duration) * time.Second
 	}
-	err := fetchKeystore(s.am).TimedUnlock(accounts.Account{Address: addr}, password, d)
+	// Quorum: unlocking an account under an approval policy awaits its approvers
+	remote := rpcRemote(ctx)
+	err := s.am.RequireApproval(ctx, accounts.ApprovalUnlock, accounts.Account{Address: addr}, "personal_unlockAccount", remote, []interface{}{addr, duration}, func(ctx context.Context) (interface{}, error) {
+		return s.UnlockAccount(withRemote(ctx, remote), addr, password, duration)
+	})
+	if err != nil {
+		return false, err
+	}
+	err = fetchKeystore(s.am).TimedUnlock(accounts.Account{Address: addr}, password, d)
 	if err != nil {
 		log.Warn("Failed account unlock attempt", "address", addr, "err", err)
 	}
@@ -382,6 +408,9 @@ func (s *PrivateAccountAPI) signTransaction(ctx context.Context, args *SendTxArg
 // tries to sign it with the key associated with args.To. If the given passwd isn't
 // able to decrypt the key it fails.
 func (s *PrivateAccountAPI) SendTransaction(ctx context.Context, args SendTxArgs, passwd string) (common.Hash, error) {
+	trail := txtrail.New(txtrail.StageReceived, "method", "personal_sendTransaction", "from", args.From)
+	ctx = txtrail.NewContext(ctx, trail)
+
 	// Look up the wallet containing the requested signer
 	account := accounts.Account{Address: args.From}
 
@@ -389,6 +418,12 @@ func (s *PrivateAccountAPI) SendTransaction(ctx context.Context, args SendTxArgs
 	if err != nil {
 		return common.Hash{}, err
 	}
+	// Quorum: sending from an account under an approval policy awaits its approvers
+	if err := s.am.RequireApproval(ctx, accounts.ApprovalSend, account, "personal_sendTransaction", rpcRemote(ctx), []interface{}{args}, approvedSend(ctx, func(ctx context.Context, args SendTxArgs) (interface{}, error) {
+		return s.SendTransaction(ctx, args, passwd)
+	}, args)); err != nil {
+		return common.Hash{}, err
+	}
 
 	if args.Nonce == nil {
 		// Hold the addresse's mutex around signing to prevent concurrent assignment of
@@ -411,10 +446,12 @@ func (s *PrivateAccountAPI) SendTransaction(ctx context.Context, args SendTxArgs
 		if len(data) > 0 {
 			log.Info("sending private tx", "data", fmt.Sprintf("%x", data), "privatefrom", args.PrivateFrom, "privatefor", args.PrivateFor)
 			data, err = private.P.Send(data, args.PrivateFrom, args.PrivateFor)
-			log.Info("sent private tx", "data", fmt.Sprintf("%x", data), "privatefrom", args.PrivateFrom, "privatefor", args.PrivateFor)
+			log.Info("sent private tx", "data", fmt.Sprintf("%x", data), "privatefrom", args.PrivateFrom, "privatefor", args.PrivateFor, "trace", trail.ID)
 			if err != nil {
+				trail.Record(txtrail.StagePrivacy, "err", err)
 				return common.Hash{}, err
 			}
+			trail.Record(txtrail.StagePrivacy, "key", fmt.Sprintf("%x", data))
 		}
 		// zekun: HACK
 		d := hexutil.Bytes(data)
@@ -449,6 +486,12 @@ func (s *PrivateAccountAPI) SignTransaction(ctx context.Context, args SendTxArgs
 	if args.Nonce == nil {
 		return nil, fmt.Errorf("nonce not specified")
 	}
+	// Quorum: signing for an account under an approval policy awaits its approvers
+	if err := s.am.RequireApproval(ctx, accounts.ApprovalSend, accounts.Account{Address: args.From}, "personal_signTransaction", rpcRemote(ctx), []interface{}{args}, approvedSend(ctx, func(ctx context.Context, args SendTxArgs) (interface{}, error) {
+		return s.SignTransaction(ctx, args, passwd)
+	}, args)); err != nil {
+		return nil, err
+	}
 	signed, err := s.signTransaction(ctx, &args, passwd)
 	if err != nil {
 		log.Warn("Failed transaction sign attempt", "from", args.From, "to", args.To, "value", args.Value.ToInt(), "err", err)
@@ -643,12 +686,28 @@ func (s *PublicBlockChainAPI) GetBlockByNumber(ctx context.Context, blockNr rpc.
 	return nil, err
 }
 
+// GetFinalizedBlock returns the latest block final according to the consensus
+// engine: the head of the chain with Raft and Istanbul, the block the configured
+// number of confirmations below it otherwise. When fullTx is true all transactions
+// in the block are returned in full detail, otherwise only their hashes.
+func (s *PublicBlockChainAPI) GetFinalizedBlock(ctx context.Context, fullTx bool) (map[string]interface{}, error) {
+	return s.GetBlockByNumber(ctx, rpc.FinalizedBlockNumber, fullTx)
+}
+
 // GetBlockByHash returns the requested block. When fullTx is true all transactions in the block are returned in full
 // detail, otherwise only the transaction hash is returned.
 func (s *PublicBlockChainAPI) GetBlockByHash(ctx context.Context, blockHash common.Hash, fullTx bool) (map[string]interface{}, error) {
+	cache := s.b.ResponseCache()
+	if response, ok := cache.get("eth_getBlockByHash", blockHash, fullTx); ok {
+		return response.(map[string]interface{}), nil
+	}
 	block, err := s.b.GetBlock(ctx, blockHash)
 	if block != nil {
-		return s.rpcOutputBlock(block, true, fullTx)
+		response, err := s.rpcOutputBlock(block, true, fullTx)
+		if err == nil {
+			cache.add(response, "eth_getBlockByHash", blockHash, fullTx)
+		}
+		return response, err
 	}
 	return nil, err
 }
@@ -705,11 +764,22 @@ func (s *PublicBlockChainAPI) GetUncleCountByBlockHash(ctx context.Context, bloc
 
 // GetCode returns the code stored at the given address in the state for the given block number.
 func (s *PublicBlockChainAPI) GetCode(ctx context.Context, address common.Address, blockNr rpc.BlockNumber) (hexutil.Bytes, error) {
+	// The code at a final block never changes
+	cache := s.b.ResponseCache()
+	final := blockNr >= 0 && cache.final(uint64(blockNr), s.b.CurrentBlock().NumberU64())
+	if final {
+		if code, ok := cache.get("eth_getCode", address, blockNr); ok {
+			return code.(hexutil.Bytes), nil
+		}
+	}
 	state, _, err := s.b.StateAndHeaderByNumber(ctx, blockNr)
 	if state == nil || err != nil {
 		return nil, err
 	}
-	code := state.GetCode(address)
+	code := hexutil.Bytes(state.GetCode(address))
+	if final {
+		cache.add(code, "eth_getCode", address, blockNr)
+	}
 	return code, nil
 }
 
@@ -725,6 +795,15 @@ func (s *PublicBlockChainAPI) GetStorageAt(ctx context.Context, address common.A
 	return res[:], nil
 }
 
+// ComputeCreate2Address returns the address of the contract deployed with
+// CREATE2 by the given deployer, the deterministic deployment proxy if omitted.
+func (s *PublicBlockChainAPI) ComputeCreate2Address(salt common.Hash, initCode hexutil.Bytes, deployer *common.Address) common.Address {
+	if deployer == nil {
+		deployer = &params.DeterministicDeploymentProxy
+	}
+	return crypto.CreateAddress2(*deployer, salt, crypto.Keccak256(initCode))
+}
+
 // CallArgs represents the arguments for a call.
 type CallArgs struct {
 	From     common.Address  `json:"from"`
@@ -733,8 +812,20 @@ type CallArgs struct {
 	GasPrice hexutil.Big     `json:"gasPrice"`
 	Value    hexutil.Big     `json:"value"`
 	Data     hexutil.Bytes   `json:"data"`
+
+	// Quorum
+	PrivateFor []string `json:"privateFor"` // Execute as a private transaction for these recipients
 }
 
+// privateCallMessage marks a call message as private, for the backend to run it
+// against the private state merged with the public one. The payload is given in
+// clear, so the message itself is applied as a public one.
+type privateCallMessage struct {
+	types.Message
+}
+
+func (privateCallMessage) IsPrivate() bool { return true }
+
 func (s *PublicBlockChainAPI) doCall(ctx context.Context, args CallArgs, blockNr rpc.BlockNumber, vmCfg vm.Config, timeout time.Duration) ([]byte, uint64, bool, error) {
 	defer func(start time.Time) { log.Debug("Executing EVM call finished", "runtime", time.Since(start)) }(time.Now())
 
@@ -761,8 +852,33 @@ func (s *PublicBlockChainAPI) doCall(ctx context.Context, args CallArgs, blockNr
 		gasPrice = new(big.Int).SetUint64(defaultGasPrice)
 	}
 
+	// Quorum
+	// Private transactions pay the intrinsic gas of the payload hash sent in
+	// their place, rather than of the payload itself
+	var hashGas, dataGas uint64
+	private := args.PrivateFor != nil
+	if private {
+		if args.Value.ToInt().Sign() != 0 {
+			return nil, 0, false, core.ErrEtherValueUnsupported
+		}
+		homestead := s.b.ChainConfig().IsHomestead(header.Number)
+		hashGas, _ = core.IntrinsicGas(common.Hex2Bytes(maxPrivateIntrinsicDataHex), args.To == nil, homestead)
+		if dataGas, err = core.IntrinsicGas(args.Data, args.To == nil, homestead); err != nil {
+			return nil, 0, false, err
+		}
+		if gas < hashGas {
+			return nil, 0, false, core.ErrIntrinsicGas
+		}
+		gas = gas - hashGas + dataGas
+	}
+	// End Quorum
+
 	// Create new call message
 	msg := types.NewMessage(addr, args.To, 0, args.Value.ToInt(), gas, gasPrice, args.Data, false)
+	var evmMsg core.Message = msg
+	if private {
+		evmMsg = privateCallMessage{msg}
+	}
 
 	// Setup context so it may be cancelled the call has completed
 	// or, in case of unmetered gas, setup a context with a timeout.
@@ -777,7 +893,7 @@ func (s *PublicBlockChainAPI) doCall(ctx context.Context, args CallArgs, blockNr
 	defer cancel()
 
 	// Get a new instance of the EVM.
-	evm, vmError, err := s.b.GetEVM(ctx, msg, state, header, vmCfg)
+	evm, vmError, err := s.b.GetEVM(ctx, evmMsg, state, header, vmCfg)
 	if err != nil {
 		return nil, 0, false, err
 	}
@@ -795,19 +911,86 @@ func (s *PublicBlockChainAPI) doCall(ctx context.Context, args CallArgs, blockNr
 	if err := vmError(); err != nil {
 		return nil, 0, false, err
 	}
+	if private && err == nil {
+		gas = gas - dataGas + hashGas
+	}
 	return res, gas, failed, err
 }
 
 // Call executes the given transaction on the state for the given block number.
 // It doesn't make and changes in the state/blockchain and is useful to execute and retrieve values.
 func (s *PublicBlockChainAPI) Call(ctx context.Context, args CallArgs, blockNr rpc.BlockNumber) (hexutil.Bytes, error) {
-	result, _, _, err := s.doCall(ctx, args, blockNr, vm.Config{}, 5*time.Second)
-	return (hexutil.Bytes)(result), err
+	var result []byte
+	err := s.b.CallPool().run(ctx, func(ctx context.Context) (err error) {
+		var failed bool
+		result, _, failed, err = s.doCall(ctx, args, blockNr, vm.Config{}, 5*time.Second)
+		// Quorum: report the reason of the reverted calls as an error, the calls
+		// failing without data keep returning empty results
+		if err == nil && failed && len(result) > 0 {
+			return newRevertError(s.b.ABIRegistry(), rpc.TenantFromContext(ctx), args.To, result)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return (hexutil.Bytes)(result), nil
 }
 
 // EstimateGas returns an estimate of the amount of gas needed to execute the
-// given transaction against the current pending block.
+// given transaction against the current pending block. Transactions with
+// privateFor set are estimated as private ones, executing the payload against
+// the private state merged with the public one.
 func (s *PublicBlockChainAPI) EstimateGas(ctx context.Context, args CallArgs) (hexutil.Uint64, error) {
+	var gas hexutil.Uint64
+	err := s.b.CallPool().run(ctx, func(ctx context.Context) (err error) {
+		gas, err = s.estimateGas(ctx, args)
+		return err
+	})
+	return gas, err
+}
+
+// Quorum
+// accessListResult is the result of eth_createAccessList.
+type accessListResult struct {
+	AccessList        vm.AccessList  `json:"accessList"`
+	PrivateAccessList vm.AccessList  `json:"privateAccessList"`
+	Error             string         `json:"error,omitempty"`
+	GasUsed           hexutil.Uint64 `json:"gasUsed"`
+}
+
+// CreateAccessList simulates the given transaction on the state of the given
+// block, the pending one by default, and returns the accounts and storage slots
+// it accesses in the public and private states. Besides EIP-2930 transactions,
+// the lists let the submitters of concurrent transactions predict conflicts.
+func (s *PublicBlockChainAPI) CreateAccessList(ctx context.Context, args CallArgs, blockNr *rpc.BlockNumber) (*accessListResult, error) {
+	number := rpc.PendingBlockNumber
+	if blockNr != nil {
+		number = *blockNr
+	}
+	var result *accessListResult
+	err := s.b.CallPool().run(ctx, func(ctx context.Context) error {
+		tracer := vm.NewAccessListTracer()
+		_, gas, failed, err := s.doCall(ctx, args, number, vm.Config{Debug: true, Tracer: tracer}, 5*time.Second)
+		if err != nil {
+			return err
+		}
+		result = &accessListResult{
+			AccessList:        tracer.AccessList(),
+			PrivateAccessList: tracer.PrivateAccessList(),
+			GasUsed:           hexutil.Uint64(gas),
+		}
+		if failed {
+			result.Error = "execution failed"
+		}
+		return nil
+	})
+	return result, err
+}
+
+// estimateGas binary searches the gas needed to execute the given transaction,
+// with all the executions sharing the CPU quota of the call.
+func (s *PublicBlockChainAPI) estimateGas(ctx context.Context, args CallArgs) (hexutil.Uint64, error) {
 	// Binary search the gas requirement, as it may be higher than the amount used
 	var (
 		lo  uint64 = params.TxGas - 1
@@ -826,12 +1009,18 @@ func (s *PublicBlockChainAPI) EstimateGas(ctx context.Context, args CallArgs) (h
 	}
 	cap = hi
 
-	// Create a helper to check if a gas allowance results in an executable transaction
+	// Create a helper to check if a gas allowance results in an executable transaction,
+	// keeping the data returned by the last failed execution
+	var reverted []byte
 	executable := func(gas uint64) bool {
 		args.Gas = hexutil.Uint64(gas)
 
-		_, _, failed, err := s.doCall(ctx, args, rpc.PendingBlockNumber, vm.Config{}, 0)
+		ret, _, failed, err := s.doCall(ctx, args, rpc.PendingBlockNumber, vm.Config{}, 0)
 		if err != nil || failed {
+			reverted = nil
+			if err == nil {
+				reverted = ret
+			}
 			return false
 		}
 		return true
@@ -848,6 +1037,10 @@ func (s *PublicBlockChainAPI) EstimateGas(ctx context.Context, args CallArgs) (h
 	// Reject the transaction as invalid if it still fails at the highest allowance
 	if hi == cap {
 		if !executable(hi) {
+			// Quorum: report the reason of the transactions reverting at any gas
+			if len(reverted) > 0 {
+				return 0, newRevertError(s.b.ABIRegistry(), rpc.TenantFromContext(ctx), args.To, reverted)
+			}
 			return 0, fmt.Errorf("gas required exceeds allowance or always failing transaction")
 		}
 	}
@@ -861,7 +1054,8 @@ func (s *PublicBlockChainAPI) EstimateGas(ctx context.Context, args CallArgs) (h
 	//This makes the return value a potential over-estimate of gas, rather than the exact cost to run right now
 
 	//if the transaction has a value then it cannot be private, so we can skip this check
-	if args.Value.ToInt().Cmp(big.NewInt(0)) == 0 {
+	//private estimates already account for the payload hash
+	if args.PrivateFor == nil && args.Value.ToInt().Cmp(big.NewInt(0)) == 0 {
 
 		isHomestead := s.b.ChainConfig().IsHomestead(new(big.Int).SetInt64(int64(rpc.PendingBlockNumber)))
 		intrinsicGasPublic, _ := core.IntrinsicGas(args.Data, args.To == nil, isHomestead)
@@ -1157,6 +1351,17 @@ func (s *PublicTransactionPoolAPI) GetRawTransactionByBlockHashAndIndex(ctx cont
 func (s *PublicTransactionPoolAPI) GetTransactionCount(ctx context.Context, address common.Address, blockNr rpc.BlockNumber) (*hexutil.Uint64, error) {
 	// Ask transaction pool for the nonce which includes pending transactions
 	if blockNr == rpc.PendingBlockNumber {
+		// Quorum: backends executing the pending transactions answer with the
+		// nonce after them, leaving out the transactions that fail to execute
+		if b, ok := s.b.(interface {
+			PendingNonce(ctx context.Context, addr common.Address) (uint64, error)
+		}); ok {
+			nonce, err := b.PendingNonce(ctx, address)
+			if err != nil {
+				return nil, err
+			}
+			return (*hexutil.Uint64)(&nonce), nil
+		}
 		nonce, err := s.b.GetPoolNonce(ctx, address)
 		if err != nil {
 			return nil, err
@@ -1174,17 +1379,29 @@ func (s *PublicTransactionPoolAPI) GetTransactionCount(ctx context.Context, addr
 }
 
 // GetTransactionByHash returns the transaction for the given hash
-func (s *PublicTransactionPoolAPI) GetTransactionByHash(ctx context.Context, hash common.Hash) *RPCTransaction {
+func (s *PublicTransactionPoolAPI) GetTransactionByHash(ctx context.Context, hash common.Hash) (*RPCTransaction, error) {
 	// Try to return an already finalized transaction
 	if tx, blockHash, blockNumber, index := rawdb.ReadTransaction(s.b.ChainDb(), hash); tx != nil {
-		return newRPCTransaction(tx, blockHash, blockNumber, index)
+		return newRPCTransaction(tx, blockHash, blockNumber, index), nil
 	}
 	// No finalized transaction, try to retrieve it from the pool
 	if tx := s.b.GetPoolTransaction(hash); tx != nil {
-		return newRPCPendingTransaction(tx)
+		return newRPCPendingTransaction(tx), nil
+	}
+	// Quorum: the transaction may be final in a pruned block
+	if txPruned(s.b.ChainDb(), hash) {
+		return nil, core.ErrHistoryPruned
 	}
 	// Transaction unknown, return as such
-	return nil
+	return nil, nil
+}
+
+// Quorum
+// txPruned tells whether the transaction of the given hash was included in a
+// block whose body was pruned by the history retention.
+func txPruned(db ethdb.Database, hash common.Hash) bool {
+	blockHash, blockNumber, _ := rawdb.ReadTxLookupEntry(db, hash)
+	return blockHash != (common.Hash{}) && core.HistoryPruned(db, blockNumber)
 }
 
 // GetRawTransactionByHash returns the bytes of the transaction for the given hash.
@@ -1194,6 +1411,10 @@ func (s *PublicTransactionPoolAPI) GetRawTransactionByHash(ctx context.Context,
 	// Retrieve a finalized transaction, or a pooled otherwise
 	if tx, _, _, _ = rawdb.ReadTransaction(s.b.ChainDb(), hash); tx == nil {
 		if tx = s.b.GetPoolTransaction(hash); tx == nil {
+			// Quorum: the transaction may be final in a pruned block
+			if txPruned(s.b.ChainDb(), hash) {
+				return nil, core.ErrHistoryPruned
+			}
 			// Transaction not found anywhere, abort
 			return nil, nil
 		}
@@ -1204,8 +1425,16 @@ func (s *PublicTransactionPoolAPI) GetRawTransactionByHash(ctx context.Context,
 
 // GetTransactionReceipt returns the transaction receipt for the given transaction hash.
 func (s *PublicTransactionPoolAPI) GetTransactionReceipt(ctx context.Context, hash common.Hash) (map[string]interface{}, error) {
+	// Only the receipts of final blocks are cached, as the others may be reorged
+	cache := s.b.ResponseCache()
+	if fields, ok := cache.get("eth_getTransactionReceipt", hash); ok {
+		return fields.(map[string]interface{}), nil
+	}
 	tx, blockHash, blockNumber, index := rawdb.ReadTransaction(s.b.ChainDb(), hash)
 	if tx == nil {
+		if txPruned(s.b.ChainDb(), hash) {
+			return nil, core.ErrHistoryPruned
+		}
 		return nil, nil
 	}
 	receipts, err := s.b.GetReceipts(ctx, blockHash)
@@ -1250,12 +1479,72 @@ func (s *PublicTransactionPoolAPI) GetTransactionReceipt(ctx context.Context, ha
 	if receipt.ContractAddress != (common.Address{}) {
 		fields["contractAddress"] = receipt.ContractAddress
 	}
+	// Quorum: only the parties of a reverted private transaction know its reason
+	if receipt.RevertReason != "" {
+		fields["revertReason"] = receipt.RevertReason
+	}
+	if cache.final(blockNumber, s.b.CurrentBlock().NumberU64()) {
+		cache.add(fields, "eth_getTransactionReceipt", hash)
+	}
 	return fields, nil
 }
 
+// Quorum
+//
+// GetDecodedTransactionReceipt returns the receipt of a transaction with its
+// logs decoded by the contract ABIs registered by the calling tenant.
+func (s *PublicTransactionPoolAPI) GetDecodedTransactionReceipt(ctx context.Context, hash common.Hash) (map[string]interface{}, error) {
+	receipt, err := s.GetTransactionReceipt(ctx, hash)
+	if receipt == nil || err != nil {
+		return receipt, err
+	}
+	registry := s.b.ABIRegistry()
+	if registry == nil {
+		return receipt, nil
+	}
+	// The receipt may be cached, decode into a copy
+	fields := make(map[string]interface{}, len(receipt))
+	for key, value := range receipt {
+		fields[key] = value
+	}
+	if logs, ok := receipt["logs"].([]*types.Log); ok {
+		fields["logs"] = registry.DecodeLogs(rpc.TenantFromContext(ctx), logs)
+	}
+	return fields, nil
+}
+
+// Quorum
+//
+// rpcRemote returns the address of the RPC client making a call, empty for the
+// calls over IPC and in-process.
+func rpcRemote(ctx context.Context) string {
+	remote, _ := ctx.Value("remote").(string)
+	return remote
+}
+
+// Quorum
+//
+// withRemote returns a context carrying the remote address of an RPC client,
+// for an approved action to execute on behalf of the client which requested it.
+func withRemote(ctx context.Context, remote string) context.Context {
+	return context.WithValue(ctx, "remote", remote)
+}
+
+// Quorum
+//
+// approvedSend returns the execution of a transaction awaiting approvals, on
+// behalf of the requesting RPC client and with the arguments as requested,
+// before any defaults are filled in.
+func approvedSend(ctx context.Context, send func(context.Context, SendTxArgs) (interface{}, error), args SendTxArgs) func(context.Context) (interface{}, error) {
+	remote := rpcRemote(ctx)
+	return func(ctx context.Context) (interface{}, error) {
+		return send(withRemote(ctx, remote), args)
+	}
+}
+
 // quorum: if signing a private TX set with tx.SetPrivate() before calling this method.
 // sign is a helper function that signs a transaction with the private key of the given address.
-func (s *PublicTransactionPoolAPI) sign(addr common.Address, tx *types.Transaction) (*types.Transaction, error) {
+func (s *PublicTransactionPoolAPI) sign(ctx context.Context, addr common.Address, tx *types.Transaction) (*types.Transaction, error) {
 	// Look up the wallet containing the requested signer
 	account := accounts.Account{Address: addr}
 
@@ -1263,6 +1552,9 @@ func (s *PublicTransactionPoolAPI) sign(addr common.Address, tx *types.Transacti
 	if err != nil {
 		return nil, err
 	}
+	if err := s.b.AccountManager().AuthorizeTx(rpcRemote(ctx), account, tx.To()); err != nil {
+		return nil, err
+	}
 	// Request the wallet to sign the transaction
 	var chainID *big.Int
 	if config := s.b.ChainConfig(); config.IsEIP155(s.b.CurrentBlock().Number()) && !tx.IsPrivate() {
@@ -1288,6 +1580,8 @@ type SendTxArgs struct {
 	PrivateFrom   string   `json:"privateFrom"`
 	PrivateFor    []string `json:"privateFor"`
 	PrivateTxType string   `json:"restriction"`
+
+	PrivacyGroupId string `json:"privacyGroupId"` // Privacy group sent to instead of privateFor
 	//End-Quorum
 }
 
@@ -1295,9 +1589,34 @@ func (s SendTxArgs) IsPrivate() bool {
 	return s.PrivateFor != nil
 }
 
+// resolvePrivacyGroup sets the recipients of a transaction sent to a privacy
+// group to the members of the group.
+func (args *SendTxArgs) resolvePrivacyGroup() error {
+	members, err := resolvePrivacyGroup(args.PrivacyGroupId, args.PrivateFor)
+	if err != nil {
+		return err
+	}
+	// The group is resolved once, the arguments being passed along
+	args.PrivateFor, args.PrivacyGroupId = members, ""
+	return nil
+}
+
 // SendRawTxArgs represents the arguments to submit a new signed private transaction into the transaction pool.
 type SendRawTxArgs struct {
-	PrivateFor []string `json:"privateFor"`
+	PrivateFor     []string `json:"privateFor"`
+	PrivacyGroupId string   `json:"privacyGroupId"` // Privacy group sent to instead of privateFor
+}
+
+// resolvePrivacyGroup returns the members of the privacy group of the given ID,
+// or else the recipients listed by privateFor.
+func resolvePrivacyGroup(id string, privateFor []string) ([]string, error) {
+	if id == "" {
+		return privateFor, nil
+	}
+	if privateFor != nil {
+		return nil, errPrivacyGroupAndPrivateFor
+	}
+	return private.ResolvePrivacyGroup(id)
 }
 
 // setDefaults is a helper function that fills in default values for unspecified tx fields.
@@ -1327,6 +1646,9 @@ func (args *SendTxArgs) setDefaults(ctx context.Context, b Backend) error {
 	if args.PrivateTxType == "" {
 		args.PrivateTxType = "restricted"
 	}
+	if err := args.resolvePrivacyGroup(); err != nil {
+		return err
+	}
 	//End-Quorum
 	return nil
 }
@@ -1347,7 +1669,16 @@ func (args *SendTxArgs) toTransaction() *types.Transaction {
 // TODO: this submits a signed transaction, if it is a signed private transaction that should already be recorded in the tx.
 // submitTransaction is a helper function that submits tx to txPool and logs a message.
 func submitTransaction(ctx context.Context, b Backend, tx *types.Transaction) (common.Hash, error) {
+	// Quorum: trace the transaction through the pool and into the chain
+	trail := txtrail.FromContext(ctx)
+	if trail == nil {
+		trail = txtrail.New(txtrail.StageReceived)
+	}
+	trail.Bind(tx.Hash())
+	trail.Record(txtrail.StageSubmit, "private", tx.IsPrivate())
+
 	if err := b.SendTx(ctx, tx); err != nil {
+		trail.Record(txtrail.StageRejected, "err", err)
 		return common.Hash{}, err
 	}
 	if tx.To() == nil {
@@ -1362,10 +1693,10 @@ func submitTransaction(ctx context.Context, b Backend, tx *types.Transaction) (c
 			return common.Hash{}, err
 		}
 		addr := crypto.CreateAddress(from, tx.Nonce())
-		log.Info("Submitted contract creation", "fullhash", tx.Hash().Hex(), "to", addr.Hex())
+		log.Info("Submitted contract creation", "fullhash", tx.Hash().Hex(), "to", addr.Hex(), "trace", trail.ID)
 		log.EmitCheckpoint(log.TxCreated, "tx", tx.Hash().Hex(), "to", addr.Hex())
 	} else {
-		log.Info("Submitted transaction", "fullhash", tx.Hash().Hex(), "recipient", tx.To())
+		log.Info("Submitted transaction", "fullhash", tx.Hash().Hex(), "recipient", tx.To(), "trace", trail.ID)
 		log.EmitCheckpoint(log.TxCreated, "tx", tx.Hash().Hex(), "to", tx.To().Hex())
 	}
 	return tx.Hash(), nil
@@ -1374,6 +1705,8 @@ func submitTransaction(ctx context.Context, b Backend, tx *types.Transaction) (c
 // SendTransaction creates a transaction for the given argument, sign it and submit it to the
 // transaction pool.
 func (s *PublicTransactionPoolAPI) SendTransaction(ctx context.Context, args SendTxArgs) (common.Hash, error) {
+	trail := txtrail.New(txtrail.StageReceived, "method", "eth_sendTransaction", "from", args.From)
+	ctx = txtrail.NewContext(ctx, trail)
 
 	// Look up the wallet containing the requested signer
 	account := accounts.Account{Address: args.From}
@@ -1382,6 +1715,15 @@ func (s *PublicTransactionPoolAPI) SendTransaction(ctx context.Context, args Sen
 	if err != nil {
 		return common.Hash{}, err
 	}
+	// Quorum: sending from an account under an approval policy awaits its approvers
+	if err := s.b.AccountManager().RequireApproval(ctx, accounts.ApprovalSend, account, "eth_sendTransaction", rpcRemote(ctx), []interface{}{args}, approvedSend(ctx, func(ctx context.Context, args SendTxArgs) (interface{}, error) {
+		return s.SendTransaction(ctx, args)
+	}, args)); err != nil {
+		return common.Hash{}, err
+	}
+	if err := s.b.AccountManager().AuthorizeTx(rpcRemote(ctx), account, args.To); err != nil {
+		return common.Hash{}, err
+	}
 
 	if args.Nonce == nil {
 		// Hold the addresse's mutex around signing to prevent concurrent assignment of
@@ -1390,6 +1732,10 @@ func (s *PublicTransactionPoolAPI) SendTransaction(ctx context.Context, args Sen
 		defer s.nonceLock.UnlockAddr(args.From)
 	}
 
+	// Quorum: transactions sent to a privacy group are sent to its members
+	if err := args.resolvePrivacyGroup(); err != nil {
+		return common.Hash{}, err
+	}
 	isPrivate := args.IsPrivate()
 	var data []byte
 	if isPrivate {
@@ -1403,10 +1749,12 @@ func (s *PublicTransactionPoolAPI) SendTransaction(ctx context.Context, args Sen
 			//Send private transaction to local Constellation node
 			log.Info("sending private tx", "data", fmt.Sprintf("%x", data), "privatefrom", args.PrivateFrom, "privatefor", args.PrivateFor)
 			data, err = private.P.Send(data, args.PrivateFrom, args.PrivateFor)
-			log.Info("sent private tx", "data", fmt.Sprintf("%x", data), "privatefrom", args.PrivateFrom, "privatefor", args.PrivateFor)
+			log.Info("sent private tx", "data", fmt.Sprintf("%x", data), "privatefrom", args.PrivateFrom, "privatefor", args.PrivateFor, "trace", trail.ID)
 			if err != nil {
+				trail.Record(txtrail.StagePrivacy, "err", err)
 				return common.Hash{}, err
 			}
+			trail.Record(txtrail.StagePrivacy, "key", fmt.Sprintf("%x", data))
 		}
 		// zekun: HACK
 		d := hexutil.Bytes(data)
@@ -1437,42 +1785,131 @@ func (s *PublicTransactionPoolAPI) SendTransaction(ctx context.Context, args Sen
 
 }
 
+var (
+	// errAnchorsInactive is returned when anchoring a document on a chain without
+	// document anchors.
+	errAnchorsInactive = errors.New("document anchors not active on this chain")
+
+	// errNotAnchor is returned when the anchor of a transaction not sent to the
+	// anchor address of the chain is requested.
+	errNotAnchor = errors.New("transaction is not a document anchor")
+)
+
+// SendAnchorArgs represents the arguments to anchor a document stored off-chain.
+type SendAnchorArgs struct {
+	From  common.Address  `json:"from"`
+	Gas   *hexutil.Uint64 `json:"gas"`
+	Nonce *hexutil.Uint64 `json:"nonce"`
+
+	Hash common.Hash    `json:"hash"` // Hash of the content of the document
+	Size hexutil.Uint64 `json:"size"` // Size of the content of the document
+	URI  string         `json:"uri"`  // Storage URI the document can be retrieved from
+}
+
+// SendDocumentAnchor anchors a document stored off-chain, sending a transaction
+// that carries its hash, size and storage URI instead of its content.
+func (s *PublicTransactionPoolAPI) SendDocumentAnchor(ctx context.Context, args SendAnchorArgs) (common.Hash, error) {
+	to := s.b.ChainConfig().DocumentAnchorAddress(new(big.Int).Add(s.b.CurrentBlock().Number(), big.NewInt(1)))
+	if to == nil {
+		return common.Hash{}, errAnchorsInactive
+	}
+	anchor := &types.DocumentAnchor{Hash: args.Hash, Size: uint64(args.Size), URI: args.URI}
+	if err := anchor.Validate(); err != nil {
+		return common.Hash{}, err
+	}
+	data, err := rlp.EncodeToBytes(anchor)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	input := hexutil.Bytes(data)
+	return s.SendTransaction(ctx, SendTxArgs{
+		From:  args.From,
+		To:    to,
+		Gas:   args.Gas,
+		Nonce: args.Nonce,
+		Data:  &input,
+	})
+}
+
+// GetDocumentAnchor returns the document anchored by a transaction, final or
+// pooled.
+func (s *PublicTransactionPoolAPI) GetDocumentAnchor(ctx context.Context, hash common.Hash) (*types.DocumentAnchor, error) {
+	tx, _, _, _ := rawdb.ReadTransaction(s.b.ChainDb(), hash)
+	if tx == nil {
+		if tx = s.b.GetPoolTransaction(hash); tx == nil {
+			return nil, nil
+		}
+	}
+	if anchors := s.b.ChainConfig().DocumentAnchors; anchors == nil || tx.To() == nil || *tx.To() != anchors.Address {
+		return nil, errNotAnchor
+	}
+	return tx.DocumentAnchor()
+}
+
 // SendRawTransaction will add the signed transaction to the transaction pool.
 // The sender is responsible for signing the transaction and using the correct nonce.
 func (s *PublicTransactionPoolAPI) SendRawTransaction(ctx context.Context, encodedTx hexutil.Bytes) (common.Hash, error) {
+	ctx = txtrail.NewContext(ctx, txtrail.New(txtrail.StageReceived, "method", "eth_sendRawTransaction"))
+
 	tx := new(types.Transaction)
 	if err := rlp.DecodeBytes(encodedTx, tx); err != nil {
 		return common.Hash{}, err
 	}
+	// Quorum: the payload of a private transaction must have been distributed
+	if err := checkPrivatePayload(tx); err != nil {
+		return common.Hash{}, err
+	}
 	return submitTransaction(ctx, s.b, tx)
 }
 
 // SendRawPrivateTransaction will add the signed transaction to the transaction pool.
 // The sender is responsible for signing the transaction and using the correct nonce.
 func (s *PublicTransactionPoolAPI) SendRawPrivateTransaction(ctx context.Context, encodedTx hexutil.Bytes, args SendRawTxArgs) (common.Hash, error) {
+	trail := txtrail.New(txtrail.StageReceived, "method", "eth_sendRawPrivateTransaction")
+	ctx = txtrail.NewContext(ctx, trail)
 
 	tx := new(types.Transaction)
 	if err := rlp.DecodeBytes(encodedTx, tx); err != nil {
 		return common.Hash{}, err
 	}
 
+	// Quorum: transactions sent to a privacy group are sent to its members
+	privateFor, err := resolvePrivacyGroup(args.PrivacyGroupId, args.PrivateFor)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	args.PrivateFor = privateFor
+
 	txHash := []byte(tx.Data())
 	isPrivate := (args.PrivateFor != nil) && tx.IsPrivate()
 
+	// Quorum: the payload stored beforehand is pushed again to the recipients
+	// unreachable now, but only once the transaction is submitted
+	var undelivered *privatetransactionmanager.UndeliveredError
 	if isPrivate {
 		if len(txHash) > 0 {
 			//Send private transaction to privacy manager
 			log.Info("sending private tx", "data", fmt.Sprintf("%x", txHash), "privatefor", args.PrivateFor)
 			result, err := private.P.SendSignedTx(txHash, args.PrivateFor)
-			log.Info("sent private tx", "result", fmt.Sprintf("%x", result), "privatefor", args.PrivateFor)
+			log.Info("sent private tx", "result", fmt.Sprintf("%x", result), "privatefor", args.PrivateFor, "trace", trail.ID)
 			if err != nil {
-				return common.Hash{}, err
+				trail.Record(txtrail.StagePrivacy, "err", err)
+				var ok bool
+				if undelivered, ok = err.(*privatetransactionmanager.UndeliveredError); !ok {
+					return common.Hash{}, err
+				}
+				log.Warn("Private payload not pushed to its recipients", "data", fmt.Sprintf("%x", txHash), "recipients", undelivered.Recipients, "err", err)
 			}
+			trail.Record(txtrail.StagePrivacy, "key", fmt.Sprintf("%x", txHash))
 		}
 	} else {
 		return common.Hash{}, fmt.Errorf("transaction is not private")
 	}
-	return submitTransaction(ctx, s.b, tx)
+	hash, err := submitTransaction(ctx, s.b, tx)
+	if err == nil && undelivered != nil {
+		undelivered.Redistribute()
+	}
+	return hash, err
 }
 
 // Sign calculates an ECDSA signature for:
@@ -1484,7 +1921,7 @@ func (s *PublicTransactionPoolAPI) SendRawPrivateTransaction(ctx context.Context
 // The account associated with addr must be unlocked.
 //
 // https://github.com/ethereum/wiki/wiki/JSON-RPC#eth_sign
-func (s *PublicTransactionPoolAPI) Sign(addr common.Address, data hexutil.Bytes) (hexutil.Bytes, error) {
+func (s *PublicTransactionPoolAPI) Sign(ctx context.Context, addr common.Address, data hexutil.Bytes) (hexutil.Bytes, error) {
 	// Look up the wallet containing the requested signer
 	account := accounts.Account{Address: addr}
 
@@ -1492,6 +1929,9 @@ func (s *PublicTransactionPoolAPI) Sign(addr common.Address, data hexutil.Bytes)
 	if err != nil {
 		return nil, err
 	}
+	if err := s.b.AccountManager().AuthorizeSign(rpcRemote(ctx), account); err != nil {
+		return nil, err
+	}
 	// Sign the requested hash with the wallet
 	signature, err := wallet.SignHash(account, signHash(data))
 	if err == nil {
@@ -1519,6 +1959,12 @@ func (s *PublicTransactionPoolAPI) SignTransaction(ctx context.Context, args Sen
 	if args.Nonce == nil {
 		return nil, fmt.Errorf("nonce not specified")
 	}
+	// Quorum: signing for an account under an approval policy awaits its approvers
+	if err := s.b.AccountManager().RequireApproval(ctx, accounts.ApprovalSend, accounts.Account{Address: args.From}, "eth_signTransaction", rpcRemote(ctx), []interface{}{args}, approvedSend(ctx, func(ctx context.Context, args SendTxArgs) (interface{}, error) {
+		return s.SignTransaction(ctx, args)
+	}, args)); err != nil {
+		return nil, err
+	}
 	if err := args.setDefaults(ctx, s.b); err != nil {
 		return nil, err
 	}
@@ -1529,7 +1975,7 @@ func (s *PublicTransactionPoolAPI) SignTransaction(ctx context.Context, args Sen
 		toSign.SetPrivate()
 	}
 
-	tx, err := s.sign(args.From, toSign)
+	tx, err := s.sign(ctx, args.From, toSign)
 	if err != nil {
 		return nil, err
 	}
@@ -1573,6 +2019,13 @@ func (s *PublicTransactionPoolAPI) Resend(ctx context.Context, sendArgs SendTxAr
 	if sendArgs.Nonce == nil {
 		return common.Hash{}, fmt.Errorf("missing transaction nonce in transaction spec")
 	}
+	// Quorum: resending from an account under an approval policy awaits its
+	// approvers too, the new transaction being signed anew
+	if err := s.b.AccountManager().RequireApproval(ctx, accounts.ApprovalSend, accounts.Account{Address: sendArgs.From}, "eth_resend", rpcRemote(ctx), []interface{}{sendArgs, gasPrice, gasLimit}, approvedSend(ctx, func(ctx context.Context, sendArgs SendTxArgs) (interface{}, error) {
+		return s.Resend(ctx, sendArgs, gasPrice, gasLimit)
+	}, sendArgs)); err != nil {
+		return common.Hash{}, err
+	}
 	if err := sendArgs.setDefaults(ctx, s.b); err != nil {
 		return common.Hash{}, err
 	}
@@ -1604,7 +2057,7 @@ func (s *PublicTransactionPoolAPI) Resend(ctx context.Context, sendArgs SendTxAr
 			if sendArgs.PrivateFor != nil {
 				newTx.SetPrivate()
 			}
-			signedTx, err := s.sign(sendArgs.From, newTx)
+			signedTx, err := s.sign(ctx, sendArgs.From, newTx)
 			if err != nil {
 				return common.Hash{}, err
 			}
@@ -1712,6 +2165,15 @@ func (api *PrivateDebugAPI) SetHead(number hexutil.Uint64) {
 	api.b.SetHead(uint64(number))
 }
 
+// TxTrail returns the recorded lifecycle of a transaction submitted through
+// this node, with the trace ID found in the logs of each of its stages.
+func (api *PrivateDebugAPI) TxTrail(hash common.Hash) (*txtrail.Trail, error) {
+	if trail := txtrail.Get(hash); trail != nil {
+		return trail, nil
+	}
+	return nil, fmt.Errorf("no trail recorded for transaction %x", hash)
+}
+
 // PublicNetAPI offers network related RPC methods
 type PublicNetAPI struct {
 	net            *p2p.Server
@@ -1833,7 +2295,7 @@ func (s *PublicTransactionPoolAPI) SendTransactionAsync(ctx context.Context, arg
 // GetQuorumPayload returns the contents of a private transaction
 func (s *PublicBlockChainAPI) GetQuorumPayload(digestHex string) (string, error) {
 	if private.P == nil {
-		return "", fmt.Errorf("PrivateTransactionManager is not enabled")
+		return "", private.ErrPrivateTransactionManagerNotEnabled
 	}
 	if len(digestHex) < 3 {
 		return "", fmt.Errorf("Invalid digest hex")
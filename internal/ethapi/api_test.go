@@ -0,0 +1,140 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Tests that resending from an account under an approval policy awaits its
+// approvers, as sending does.
+func TestResendApproval(t *testing.T) {
+	am := accounts.NewManager()
+	defer am.Close()
+
+	treasury := common.HexToAddress("0x01")
+	policy := &accounts.ApprovalPolicy{Approvers: []common.Address{{0x02}}, Threshold: 1, Actions: []string{accounts.ApprovalSend}}
+	if err := am.SetApprovalPolicies(map[common.Address]*accounts.ApprovalPolicy{treasury: policy}); err != nil {
+		t.Fatal(err)
+	}
+	api := NewPublicTransactionPoolAPI(&testBackend{am: am}, nil)
+
+	nonce := hexutil.Uint64(0)
+	_, err := api.Resend(context.Background(), SendTxArgs{From: treasury, Nonce: &nonce}, nil, nil)
+	if _, ok := err.(*accounts.ApprovalRequiredError); !ok {
+		t.Fatalf("resend error mismatch: have %v, want approval required", err)
+	}
+	if approvals := am.Approvals(false); len(approvals) != 1 || approvals[0].Method != "eth_resend" {
+		t.Errorf("pending approvals mismatch: have %v", approvals)
+	}
+}
+
+// dualState is the public state of a call along with the private one.
+type dualState struct {
+	*state.StateDB
+	private *state.StateDB
+}
+
+// dualStateBackend executes the calls on a public and a private state, the
+// private messages and the calls to private contracts against the private state
+// merged with the public one, as the eth backend does.
+type dualStateBackend struct {
+	Backend
+
+	public, private *state.StateDB
+}
+
+func (b *dualStateBackend) ChainConfig() *params.ChainConfig { return params.TestChainConfig }
+
+func (b *dualStateBackend) CallPool() *CallPool { return nil }
+
+func (b *dualStateBackend) StateAndHeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (vm.MinimalApiState, *types.Header, error) {
+	header := &types.Header{Number: big.NewInt(1), Time: new(big.Int), Difficulty: new(big.Int), GasLimit: 8000000}
+	return dualState{b.public.Copy(), b.private.Copy()}, header, nil
+}
+
+func (b *dualStateBackend) GetEVM(ctx context.Context, msg core.Message, state vm.MinimalApiState, header *types.Header, vmCfg vm.Config) (*vm.EVM, func() error, error) {
+	statedb := state.(dualState)
+	statedb.GetOrNewStateObject(msg.From()).SetBalance(math.MaxBig256)
+
+	privateState := statedb.private
+	if pm, ok := msg.(core.PrivateMessage); (!ok || !pm.IsPrivate()) && !privateState.Exist(*msg.To()) {
+		privateState = statedb.StateDB
+	}
+	context := core.NewEVMContext(msg, header, nil, &common.Address{})
+	return vm.NewEVM(context, statedb.StateDB, privateState, params.TestChainConfig, vmCfg), func() error { return nil }, nil
+}
+
+// Tests that the gas of a private transaction is estimated against the private
+// state merged with the public one, and accounts for the payload hash sent in
+// place of the payload.
+func TestEstimateGasPrivate(t *testing.T) {
+	var (
+		sender   = common.HexToAddress("0x1000")
+		public   = common.HexToAddress("0x2000")
+		contract = common.HexToAddress("0x3000")
+	)
+	publicState, _ := state.New(common.Hash{}, state.NewDatabase(ethdb.NewMemDatabase()))
+	privateState, _ := state.New(common.Hash{}, state.NewDatabase(ethdb.NewMemDatabase()))
+
+	// The public contract reads a slot, the private one calls the public one
+	publicState.SetCode(public, common.Hex2Bytes("60005450"+"00"))
+	privateState.SetCode(contract, common.Hex2Bytes("60006000600060006000"+"73"+common.Bytes2Hex(public.Bytes())+"5af150"+"00"))
+
+	estimate := func(private *state.StateDB, args CallArgs) uint64 {
+		api := NewPublicBlockChainAPI(&dualStateBackend{public: publicState, private: private})
+		args.From, args.Gas = sender, 1000000
+		gas, err := api.EstimateGas(context.Background(), args)
+		if err != nil {
+			t.Fatalf("failed to estimate gas: %v", err)
+		}
+		return uint64(gas)
+	}
+	// The public estimate of the public contract alone already counts the
+	// intrinsic gas of the payload hash the transaction may carry instead
+	publicOnly := estimate(privateState, CallArgs{To: &public})
+
+	hashGas, _ := core.IntrinsicGas(common.Hex2Bytes(maxPrivateIntrinsicDataHex), false, true)
+	if publicOnly <= hashGas {
+		t.Fatalf("public estimate too low: have %d, want above %d", publicOnly, hashGas)
+	}
+	// The private estimate runs the private contract calling the public one,
+	// costing more, which neither the public view nor dropping the intrinsic
+	// gas of the payload hash would
+	args := CallArgs{To: &contract, PrivateFor: []string{"Ym9i"}}
+	if private := estimate(privateState, args); private <= publicOnly {
+		t.Errorf("private estimate mismatch: have %d, want above the public only %d", private, publicOnly)
+	}
+	empty, _ := state.New(common.Hash{}, state.NewDatabase(ethdb.NewMemDatabase()))
+	if unknown := estimate(empty, args); unknown >= publicOnly {
+		t.Errorf("estimate without the private contract mismatch: have %d, want below %d", unknown, publicOnly)
+	}
+}
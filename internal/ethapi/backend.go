@@ -21,6 +21,7 @@ import (
 	"context"
 	"math/big"
 
+	"github.com/ethereum/go-ethereum/abiregistry"
 	"github.com/ethereum/go-ethereum/accounts"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core"
@@ -30,6 +31,7 @@ import (
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/private"
 	"github.com/ethereum/go-ethereum/rpc"
 )
 
@@ -43,6 +45,10 @@ type Backend interface {
 	ChainDb() ethdb.Database
 	EventMux() *event.TypeMux
 	AccountManager() *accounts.Manager
+	ResponseCache() *ResponseCache      // nil if responses aren't cached
+	CallPool() *CallPool                // nil if calls execute on the requesting goroutine
+	ABIRegistry() *abiregistry.Registry // nil if receipts aren't decoded
+	NonceLock() *AddrLocker             // Serialises the nonce assignment, shared by the RPC APIs and in-process clients
 
 	// BlockChain API
 	SetHead(number uint64)
@@ -64,6 +70,7 @@ type Backend interface {
 	GetPoolNonce(ctx context.Context, addr common.Address) (uint64, error)
 	Stats() (pending int, queued int)
 	TxPoolContent() (map[common.Address]types.Transactions, map[common.Address]types.Transactions)
+	CheckTx(tx *types.Transaction) ([]core.TxCheckResult, error)
 	SubscribeNewTxsEvent(chan<- core.NewTxsEvent) event.Subscription
 
 	ChainConfig() *params.ChainConfig
@@ -71,7 +78,7 @@ type Backend interface {
 }
 
 func GetAPIs(apiBackend Backend) []rpc.API {
-	nonceLock := new(AddrLocker)
+	nonceLock := apiBackend.NonceLock()
 	return []rpc.API{
 		{
 			Namespace: "eth",
@@ -112,6 +119,16 @@ func GetAPIs(apiBackend Backend) []rpc.API {
 			Version:   "1.0",
 			Service:   NewPrivateAccountAPI(apiBackend, nonceLock),
 			Public:    false,
+		}, {
+			Namespace: "quorumPrivacy",
+			Version:   "1.0",
+			Service:   private.NewPublicPrivacyAPI(),
+			Public:    true,
+		}, {
+			Namespace: "priv",
+			Version:   "1.0",
+			Service:   NewPublicPrivateTransactionAPI(apiBackend),
+			Public:    true,
 		},
 	}
 }
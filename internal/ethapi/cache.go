@@ -0,0 +1,120 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/hashicorp/golang-lru/simplelru"
+)
+
+var (
+	cacheHitMeter   = metrics.NewRegisteredMeter("rpc/cache/hit", nil)
+	cacheMissMeter  = metrics.NewRegisteredMeter("rpc/cache/miss", nil)
+	cacheEvictMeter = metrics.NewRegisteredMeter("rpc/cache/evict", nil)
+	cacheSizeGauge  = metrics.NewRegisteredGauge("rpc/cache/size", nil)
+)
+
+// ResponseCache caches the responses to the RPC queries whose result never
+// changes, within a memory budget, to absorb the bursts of identical queries.
+type ResponseCache struct {
+	budget        int    // Memory allowance in bytes
+	confirmations uint64 // Blocks on top of a block for its receipts and state to be final
+
+	lock sync.Mutex
+	lru  *simplelru.LRU // Cached responses by query, evicted by size
+	size int            // Approximate memory used by the cached responses
+}
+
+// cachedResponse is a response along with its approximate memory use.
+type cachedResponse struct {
+	value interface{}
+	size  int
+}
+
+// NewResponseCache creates a response cache of the given memory allowance in
+// bytes. The receipts and states of blocks are cached once the given number of
+// blocks are built on them.
+func NewResponseCache(budget int, confirmations uint64) *ResponseCache {
+	c := &ResponseCache{budget: budget, confirmations: confirmations}
+	c.lru, _ = simplelru.NewLRU(math.MaxInt32, func(key, value interface{}) {
+		c.size -= value.(*cachedResponse).size
+	})
+	return c
+}
+
+// final reports whether the receipts and state of a block can no longer change,
+// given the current head.
+func (c *ResponseCache) final(number, head uint64) bool {
+	return c != nil && number+c.confirmations <= head
+}
+
+// get retrieves the cached response to a query. A nil cache caches nothing.
+func (c *ResponseCache) get(method string, args ...interface{}) (interface{}, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if res, ok := c.lru.Get(cacheKey(method, args)); ok {
+		cacheHitMeter.Mark(1)
+		return res.(*cachedResponse).value, true
+	}
+	cacheMissMeter.Mark(1)
+	return nil, false
+}
+
+// add caches the response to a query, evicting the least recently used ones to
+// stay within the memory allowance. The response must not be modified anymore.
+func (c *ResponseCache) add(value interface{}, method string, args ...interface{}) {
+	if c == nil {
+		return
+	}
+	// Approximate the memory use by the encoded response
+	blob, err := json.Marshal(value)
+	if err != nil {
+		return
+	}
+	key := cacheKey(method, args)
+	size := len(key) + len(blob)
+	if size > c.budget {
+		return
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.lru.Contains(key) {
+		return
+	}
+	c.lru.Add(key, &cachedResponse{value: value, size: size})
+	c.size += size
+	for c.size > c.budget {
+		c.lru.RemoveOldest()
+		cacheEvictMeter.Mark(1)
+	}
+	cacheSizeGauge.Update(int64(c.size))
+}
+
+// cacheKey is the cache key of a query.
+func cacheKey(method string, args []interface{}) string {
+	return fmt.Sprintf("%s%v", method, args)
+}
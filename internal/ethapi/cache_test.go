@@ -0,0 +1,186 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"bytes"
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// testBackend serves the state of a single block at any height, below a head
+// the tests move. The methods not overridden aren't meant to be called.
+type testBackend struct {
+	Backend
+
+	am    *accounts.Manager
+	cache *ResponseCache
+	head  uint64
+	state *state.StateDB
+}
+
+func (b *testBackend) AccountManager() *accounts.Manager { return b.am }
+
+func (b *testBackend) ResponseCache() *ResponseCache { return b.cache }
+
+func (b *testBackend) CurrentBlock() *types.Block {
+	return types.NewBlockWithHeader(&types.Header{Number: new(big.Int).SetUint64(b.head)})
+}
+
+func (b *testBackend) StateAndHeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (vm.MinimalApiState, *types.Header, error) {
+	return b.state, &types.Header{Number: big.NewInt(blockNr.Int64())}, nil
+}
+
+func newTestBackend(cache *ResponseCache, head uint64) *testBackend {
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(ethdb.NewMemDatabase()))
+	return &testBackend{cache: cache, head: head, state: statedb}
+}
+
+// Tests that the responses are cached by method and by every parameter.
+func TestResponseCacheKeys(t *testing.T) {
+	cache := NewResponseCache(1024*1024, 0)
+
+	var (
+		hash  = common.HexToHash("0x01")
+		other = common.HexToHash("0x02")
+	)
+	cache.add("block", "eth_getBlockByHash", hash, false)
+
+	if value, ok := cache.get("eth_getBlockByHash", hash, false); !ok || value != "block" {
+		t.Fatalf("cached response mismatch: have %v, %v", value, ok)
+	}
+	misses := []struct {
+		method string
+		args   []interface{}
+	}{
+		{"eth_getBlockByHash", []interface{}{hash, true}},
+		{"eth_getBlockByHash", []interface{}{other, false}},
+		{"eth_getUncleByBlockHash", []interface{}{hash, false}},
+		{"eth_getBlockByHash", []interface{}{hash}},
+	}
+	for i, miss := range misses {
+		if value, ok := cache.get(miss.method, miss.args...); ok {
+			t.Errorf("miss %d: response of another query served: %v", i, value)
+		}
+	}
+	// Responses of the same parameters at other blocks are distinct
+	address := common.HexToAddress("0x0a")
+	cache.add(hexutil.Bytes{1}, "eth_getCode", address, rpc.BlockNumber(1))
+	cache.add(hexutil.Bytes{2}, "eth_getCode", address, rpc.BlockNumber(2))
+
+	for number, want := range map[rpc.BlockNumber]byte{1: 1, 2: 2} {
+		value, ok := cache.get("eth_getCode", address, number)
+		if !ok || !bytes.Equal(value.(hexutil.Bytes), []byte{want}) {
+			t.Errorf("block %d: cached code mismatch: have %v, %v", number, value, ok)
+		}
+	}
+	// A nil cache caches nothing
+	var none *ResponseCache
+	none.add("block", "eth_getBlockByHash", hash, false)
+	if _, ok := none.get("eth_getBlockByHash", hash, false); ok {
+		t.Errorf("nil cache served a response")
+	}
+}
+
+// Tests that the least recently used responses are evicted to stay within the
+// memory allowance.
+func TestResponseCacheBudget(t *testing.T) {
+	key := cacheKey("eth_getCode", []interface{}{0})
+	size := len(key) + len(`"0x0000"`)
+	cache := NewResponseCache(2*size, 0)
+
+	for i := 0; i < 3; i++ {
+		cache.add(hexutil.Bytes{0, 0}, "eth_getCode", i)
+		if i == 1 {
+			cache.get("eth_getCode", 0) // Used last, so evicted after the second
+		}
+	}
+	if _, ok := cache.get("eth_getCode", 1); ok {
+		t.Errorf("least recently used response not evicted")
+	}
+	for _, i := range []int{0, 2} {
+		if _, ok := cache.get("eth_getCode", i); !ok {
+			t.Errorf("response %d evicted", i)
+		}
+	}
+	if cache.size > cache.budget {
+		t.Errorf("cache size %d beyond budget %d", cache.size, cache.budget)
+	}
+	// Responses larger than the allowance aren't cached at all
+	cache.add(make(hexutil.Bytes, 2*size), "eth_getCode", 3)
+	if _, ok := cache.get("eth_getCode", 3); ok {
+		t.Errorf("oversized response cached")
+	}
+}
+
+// Tests that only the responses about final blocks are cached, the others being
+// served afresh until enough new heads are built on them.
+func TestResponseCacheNewHeads(t *testing.T) {
+	backend := newTestBackend(NewResponseCache(1024*1024, 3), 10)
+	api := NewPublicBlockChainAPI(backend)
+
+	address := common.HexToAddress("0x0a")
+	code := func(number rpc.BlockNumber) hexutil.Bytes {
+		code, err := api.GetCode(context.Background(), address, number)
+		if err != nil {
+			t.Fatalf("failed to get code at %d: %v", number, err)
+		}
+		return code
+	}
+	backend.state.SetCode(address, []byte{1})
+	if have := code(7); !bytes.Equal(have, []byte{1}) {
+		t.Fatalf("code mismatch: have %x, want 01", have)
+	}
+	if have := code(8); !bytes.Equal(have, []byte{1}) {
+		t.Fatalf("code mismatch: have %x, want 01", have)
+	}
+	// The final block is served from the cache, the other one afresh
+	backend.state.SetCode(address, []byte{2})
+	if have := code(7); !bytes.Equal(have, []byte{1}) {
+		t.Errorf("final block not served from the cache: have %x, want 01", have)
+	}
+	if have := code(8); !bytes.Equal(have, []byte{2}) {
+		t.Errorf("non-final block served from the cache: have %x, want 02", have)
+	}
+	// Once final with a new head, the block is cached
+	backend.head = 11
+	if have := code(8); !bytes.Equal(have, []byte{2}) {
+		t.Fatalf("code mismatch: have %x, want 02", have)
+	}
+	backend.state.SetCode(address, []byte{3})
+	if have := code(8); !bytes.Equal(have, []byte{2}) {
+		t.Errorf("block final with the new head not cached: have %x, want 02", have)
+	}
+	// The meta block numbers are never cached
+	if have := code(rpc.LatestBlockNumber); !bytes.Equal(have, []byte{3}) {
+		t.Fatalf("code mismatch: have %x, want 03", have)
+	}
+	backend.state.SetCode(address, []byte{4})
+	if have := code(rpc.LatestBlockNumber); !bytes.Equal(have, []byte{4}) {
+		t.Errorf("latest block served from the cache: have %x, want 04", have)
+	}
+}
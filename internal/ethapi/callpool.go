@@ -0,0 +1,204 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+const (
+	// callQueuePerWorker is the number of calls waiting for each worker, beyond
+	// which new calls are rejected.
+	callQueuePerWorker = 16
+
+	// callQuotaInterval is the interval at which the CPU time of the executing
+	// calls is checked against the quota.
+	callQuotaInterval = 10 * time.Millisecond
+)
+
+var (
+	callQueueTimer     = metrics.NewRegisteredTimer("rpc/calls/queue", nil)
+	callRejectedMeter  = metrics.NewRegisteredMeter("rpc/calls/rejected", nil)
+	callExceededMeter  = metrics.NewRegisteredMeter("rpc/calls/exceeded", nil)
+	callCancelledMeter = metrics.NewRegisteredMeter("rpc/calls/cancelled", nil)
+)
+
+var (
+	errCallPoolBusy    = errors.New("too many calls queued")
+	errCallPoolStopped = errors.New("call execution stopped")
+)
+
+// Job states, a queued job being either executed by a worker or abandoned by
+// its caller.
+const (
+	callJobQueued int32 = iota
+	callJobRunning
+	callJobAbandoned
+)
+
+// callJob is an execution waiting for a worker.
+type callJob struct {
+	ctx    context.Context
+	fn     func(ctx context.Context) error
+	queued time.Time
+	state  int32
+	err    error
+	done   chan struct{}
+}
+
+// CallPool executes the eth_call and eth_estimateGas requests on a bounded set
+// of dedicated threads, isolated from block processing, aborting the executions
+// using more CPU time than their quota.
+type CallPool struct {
+	quota time.Duration // CPU time allowed to an execution, zero if unlimited
+	queue chan *callJob
+
+	quit     chan struct{}
+	quitOnce sync.Once
+}
+
+// NewCallPool creates a call pool of the given number of workers and starts
+// them. The executions are aborted once they used the given CPU time, or run
+// for it on the platforms not accounting the CPU time of threads.
+func NewCallPool(workers int, quota time.Duration) *CallPool {
+	p := &CallPool{
+		quota: quota,
+		queue: make(chan *callJob, workers*callQueuePerWorker),
+		quit:  make(chan struct{}),
+	}
+	for i := 0; i < workers; i++ {
+		go p.loop()
+	}
+	return p
+}
+
+// Stop terminates the workers, failing the executions still waiting.
+func (p *CallPool) Stop() {
+	if p != nil {
+		p.quitOnce.Do(func() { close(p.quit) })
+	}
+}
+
+// loop executes the queued calls on its own thread until the pool is stopped.
+// The thread is left locked on return for the runtime to dispose of it along
+// with its lowered priority.
+func (p *CallPool) loop() {
+	thread := lockCallThread()
+	for {
+		select {
+		case job := <-p.queue:
+			if atomic.CompareAndSwapInt32(&job.state, callJobQueued, callJobRunning) {
+				callQueueTimer.UpdateSince(job.queued)
+				job.err = p.execute(thread, job)
+				close(job.done)
+			}
+		case <-p.quit:
+			return
+		}
+	}
+}
+
+// execute runs a call on the thread of a worker, cancelling it once it exceeds
+// the CPU quota.
+func (p *CallPool) execute(thread callThread, job *callJob) error {
+	if p.quota == 0 {
+		return job.fn(job.ctx)
+	}
+	ctx, cancel := context.WithCancel(job.ctx)
+	defer cancel()
+
+	var (
+		exceeded int32
+		done     = make(chan struct{})
+	)
+	go func() {
+		start, startCPU := time.Now(), thread.cpuTime()
+		ticker := time.NewTicker(callQuotaInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				used := time.Since(start)
+				if startCPU >= 0 {
+					used = thread.cpuTime() - startCPU
+				}
+				if used > p.quota {
+					atomic.StoreInt32(&exceeded, 1)
+					cancel()
+					return
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	err := job.fn(ctx)
+	close(done)
+
+	if atomic.LoadInt32(&exceeded) == 1 {
+		callExceededMeter.Mark(1)
+		return fmt.Errorf("execution aborted (cpu quota = %v)", p.quota)
+	}
+	return err
+}
+
+// run executes a call on a worker, or on the calling goroutine if there is no
+// pool. The context given to the call is cancelled if the caller's is, or if
+// the call exceeds its CPU quota.
+func (p *CallPool) run(ctx context.Context, fn func(ctx context.Context) error) error {
+	if p == nil {
+		return fn(ctx)
+	}
+	job := &callJob{ctx: ctx, fn: fn, queued: time.Now(), done: make(chan struct{})}
+	select {
+	case p.queue <- job:
+	case <-p.quit:
+		return errCallPoolStopped
+	default:
+		callRejectedMeter.Mark(1)
+		return errCallPoolBusy
+	}
+	// Abandon the call if it's still queued when the caller gives up, waiting
+	// for it otherwise as it holds the results of the caller
+	select {
+	case <-job.done:
+		return job.err
+	case <-ctx.Done():
+		if atomic.CompareAndSwapInt32(&job.state, callJobQueued, callJobAbandoned) {
+			callCancelledMeter.Mark(1)
+			return ctx.Err()
+		}
+	case <-p.quit:
+		if atomic.CompareAndSwapInt32(&job.state, callJobQueued, callJobAbandoned) {
+			return errCallPoolStopped
+		}
+	}
+	<-job.done
+	if ctx.Err() != nil && job.err == nil {
+		callCancelledMeter.Mark(1)
+		return ctx.Err()
+	}
+	return job.err
+}
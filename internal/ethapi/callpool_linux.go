@@ -0,0 +1,67 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// +build linux
+
+package ethapi
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"runtime"
+	"strconv"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+	"golang.org/x/sys/unix"
+)
+
+// callThreadNice is the niceness of the call worker threads, yielding the CPU
+// to block processing and consensus whenever they compete for it.
+const callThreadNice = 10
+
+// callThread is the OS thread of a call worker.
+type callThread int
+
+// lockCallThread locks the calling goroutine to its thread and lowers the
+// priority of the thread.
+func lockCallThread() callThread {
+	runtime.LockOSThread()
+
+	tid := unix.Gettid()
+	if err := unix.Setpriority(unix.PRIO_PROCESS, tid, callThreadNice); err != nil {
+		log.Debug("Failed to lower call worker priority", "err", err)
+	}
+	return callThread(tid)
+}
+
+// cpuTime returns the CPU time used by the thread, or -1 if unknown.
+func (t callThread) cpuTime() time.Duration {
+	blob, err := ioutil.ReadFile(fmt.Sprintf("/proc/self/task/%d/schedstat", t))
+	if err != nil {
+		return -1
+	}
+	fields := bytes.Fields(blob)
+	if len(fields) == 0 {
+		return -1
+	}
+	ns, err := strconv.ParseInt(string(fields[0]), 10, 64)
+	if err != nil {
+		return -1
+	}
+	return time.Duration(ns)
+}
@@ -0,0 +1,39 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// +build !linux
+
+package ethapi
+
+import (
+	"runtime"
+	"time"
+)
+
+// callThread is the OS thread of a call worker.
+type callThread struct{}
+
+// lockCallThread locks the calling goroutine to its thread.
+func lockCallThread() callThread {
+	runtime.LockOSThread()
+	return callThread{}
+}
+
+// cpuTime returns -1 as the CPU time of threads isn't accounted, the quota
+// bounding the execution time instead.
+func (t callThread) cpuTime() time.Duration {
+	return -1
+}
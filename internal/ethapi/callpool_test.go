@@ -0,0 +1,188 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+// blockCallPool occupies the single worker of a pool until the returned channel
+// is closed.
+func blockCallPool(t *testing.T, pool *CallPool) (chan struct{}, chan error) {
+	var (
+		started = make(chan struct{})
+		release = make(chan struct{})
+		result  = make(chan error, 1)
+	)
+	go func() {
+		result <- pool.run(context.Background(), func(ctx context.Context) error {
+			close(started)
+			<-release
+			return nil
+		})
+	}()
+	select {
+	case <-started:
+	case <-time.After(time.Second):
+		t.Fatalf("blocking call not started")
+	}
+	return release, result
+}
+
+// Tests that the calls beyond the queue bound are rejected while the workers
+// are busy, and the queued ones are executed once the workers are free.
+func TestCallPoolSaturation(t *testing.T) {
+	pool := NewCallPool(1, 0)
+	defer pool.Stop()
+
+	release, blocked := blockCallPool(t, pool)
+
+	executed := make(chan struct{}, callQueuePerWorker)
+	results := make(chan error, callQueuePerWorker)
+	for i := 0; i < callQueuePerWorker; i++ {
+		go func() {
+			results <- pool.run(context.Background(), func(ctx context.Context) error {
+				executed <- struct{}{}
+				return nil
+			})
+		}()
+	}
+	for deadline := time.Now().Add(time.Second); len(pool.queue) < callQueuePerWorker; {
+		if time.Now().After(deadline) {
+			t.Fatalf("queued calls mismatch: have %d, want %d", len(pool.queue), callQueuePerWorker)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	err := pool.run(context.Background(), func(ctx context.Context) error {
+		t.Errorf("rejected call executed")
+		return nil
+	})
+	if err != errCallPoolBusy {
+		t.Fatalf("saturated pool error mismatch: have %v, want %v", err, errCallPoolBusy)
+	}
+	close(release)
+	if err := <-blocked; err != nil {
+		t.Fatalf("blocking call failed: %v", err)
+	}
+	for i := 0; i < callQueuePerWorker; i++ {
+		select {
+		case err := <-results:
+			if err != nil {
+				t.Errorf("queued call %d failed: %v", i, err)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("queued call %d not executed", i)
+		}
+	}
+	if len(executed) != callQueuePerWorker {
+		t.Errorf("executed calls mismatch: have %d, want %d", len(executed), callQueuePerWorker)
+	}
+}
+
+// Tests that a call running beyond its CPU quota is cancelled and fails.
+func TestCallPoolQuota(t *testing.T) {
+	pool := NewCallPool(1, 20*time.Millisecond)
+	defer pool.Stop()
+
+	start := time.Now()
+	err := pool.run(context.Background(), func(ctx context.Context) error {
+		// Spin on the worker thread until cancelled, bailing out if never
+		for deadline := time.Now().Add(5 * time.Second); ctx.Err() == nil; {
+			if time.Now().After(deadline) {
+				return nil
+			}
+		}
+		return ctx.Err()
+	})
+	if err == nil || !strings.Contains(err.Error(), "cpu quota") {
+		t.Fatalf("exceeding call error mismatch: have %v, want cpu quota error", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("exceeding call aborted too late: %v", elapsed)
+	}
+	// The calls within the quota are unaffected
+	if err := pool.run(context.Background(), func(ctx context.Context) error { return nil }); err != nil {
+		t.Errorf("call within quota failed: %v", err)
+	}
+}
+
+// Tests that a caller giving up cancels its running call and frees the worker
+// for the next one, and that an abandoned queued call is never executed.
+func TestCallPoolCancellation(t *testing.T) {
+	pool := NewCallPool(1, 0)
+	defer pool.Stop()
+
+	// Cancel a running call, which must stop and free the worker
+	ctx, cancel := context.WithCancel(context.Background())
+	started := make(chan struct{})
+	result := make(chan error, 1)
+	go func() {
+		result <- pool.run(ctx, func(ctx context.Context) error {
+			close(started)
+			<-ctx.Done()
+			return nil
+		})
+	}()
+	<-started
+	cancel()
+	select {
+	case err := <-result:
+		if err != context.Canceled {
+			t.Fatalf("cancelled call error mismatch: have %v, want %v", err, context.Canceled)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("cancelled call not stopped")
+	}
+	if err := pool.run(context.Background(), func(ctx context.Context) error { return nil }); err != nil {
+		t.Fatalf("call after cancellation failed: %v", err)
+	}
+	// Cancel a queued call, which must return at once and never execute
+	release, blocked := blockCallPool(t, pool)
+
+	ctx, cancel = context.WithCancel(context.Background())
+	go func() {
+		result <- pool.run(ctx, func(ctx context.Context) error {
+			t.Errorf("abandoned call executed")
+			return nil
+		})
+	}()
+	for deadline := time.Now().Add(time.Second); len(pool.queue) == 0; {
+		if time.Now().After(deadline) {
+			t.Fatalf("call not queued")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	select {
+	case err := <-result:
+		if err != context.Canceled {
+			t.Fatalf("abandoned call error mismatch: have %v, want %v", err, context.Canceled)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("abandoned call not returned")
+	}
+	close(release)
+	if err := <-blocked; err != nil {
+		t.Fatalf("blocking call failed: %v", err)
+	}
+	if err := pool.run(context.Background(), func(ctx context.Context) error { return nil }); err != nil {
+		t.Fatalf("call after abandonment failed: %v", err)
+	}
+}
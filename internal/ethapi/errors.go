@@ -0,0 +1,52 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/adminauth"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/private"
+	"github.com/ethereum/go-ethereum/private/privatetransactionmanager"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Classify the errors of the chain, the pool, the private transaction manager,
+// the operator signatures and the account approvals under stable reasons, for
+// the clients not to match their messages.
+func init() {
+	rpc.RegisterError(rpc.CodeServerError, "PERMISSION_DENIED",
+		core.ErrReadOnlyAccount, core.ErrContractCreateDenied,
+		adminauth.ErrSignatureRequired, adminauth.ErrSignatureExpired, adminauth.ErrSignatureReplayed, adminauth.ErrUnknownOperator)
+	rpc.RegisterError(rpc.CodeServerError, "NONCE_TOO_LOW", core.ErrNonceTooLow)
+	rpc.RegisterError(rpc.CodeServerError, "NONCE_TOO_HIGH", core.ErrNonceTooHigh)
+	rpc.RegisterError(rpc.CodeServerError, "UNDERPRICED", core.ErrUnderpriced)
+	rpc.RegisterError(rpc.CodeServerError, "REPLACEMENT_UNDERPRICED", core.ErrReplaceUnderpriced)
+	rpc.RegisterError(rpc.CodeServerError, "INSUFFICIENT_FUNDS", core.ErrInsufficientFunds)
+	rpc.RegisterError(rpc.CodeServerError, "GAS_LIMIT_EXCEEDED", core.ErrGasLimit)
+	rpc.RegisterError(rpc.CodeServerError, "INTRINSIC_GAS_TOO_LOW", core.ErrIntrinsicGas)
+	rpc.RegisterError(rpc.CodeServerError, "PTM_DISABLED", private.ErrPrivateTransactionManagerNotEnabled)
+	rpc.RegisterErrorMatcher(rpc.CodeServerError, "PTM_UNREACHABLE", func(err error) bool {
+		_, ok := err.(*privatetransactionmanager.UnreachableError)
+		return ok
+	})
+	rpc.RegisterError(rpc.CodeHistoryPruned, "HISTORY_PRUNED", core.ErrHistoryPruned)
+	rpc.RegisterErrorMatcher(rpc.CodeApprovalRequired, "APPROVAL_REQUIRED", func(err error) bool {
+		_, ok := err.(*accounts.ApprovalRequiredError)
+		return ok
+	})
+}
@@ -0,0 +1,105 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/private"
+)
+
+var (
+	// errNoPrivateRecipients is returned when a private payload is distributed
+	// without recipients.
+	errNoPrivateRecipients = errors.New("privateFor or privacyGroupId required")
+
+	// errUnknownPrivatePayload is returned when a private transaction refers to a
+	// payload the private transaction manager doesn't hold.
+	errUnknownPrivatePayload = errors.New("private payload unknown to the private transaction manager, distribute it with priv_distributeRawTransaction first")
+)
+
+// PublicPrivateTransactionAPI provides the two-phase submission of the private
+// transactions signed outside of the node: the payload is first distributed by
+// priv_distributeRawTransaction, returning the hash the transaction carries as
+// data, then the transaction signed as private is submitted by
+// eth_sendRawTransaction.
+type PublicPrivateTransactionAPI struct {
+	b Backend
+}
+
+// NewPublicPrivateTransactionAPI creates a new API for the private transactions
+// signed outside of the node.
+func NewPublicPrivateTransactionAPI(b Backend) *PublicPrivateTransactionAPI {
+	return &PublicPrivateTransactionAPI{b: b}
+}
+
+// DistributeRawTxArgs are the arguments distributing the payload of a private
+// transaction signed outside of the node.
+type DistributeRawTxArgs struct {
+	Data           hexutil.Bytes `json:"data"`           // Contract code or call data
+	PrivateFrom    string        `json:"privateFrom"`    // Key of the sender, default one if empty
+	PrivateFor     []string      `json:"privateFor"`     // Keys of the recipients
+	PrivacyGroupId string        `json:"privacyGroupId"` // Privacy group sent to instead of privateFor
+}
+
+// DistributeRawTransaction encrypts the payload of a private transaction and
+// distributes it to its recipients, returning the hash of the payload. The
+// transaction is then to be signed with the hash as data and the private
+// transaction V value (37 or 38), and submitted by eth_sendRawTransaction.
+func (s *PublicPrivateTransactionAPI) DistributeRawTransaction(ctx context.Context, args DistributeRawTxArgs) (hexutil.Bytes, error) {
+	if !private.IsEnabled() {
+		return nil, private.ErrPrivateTransactionManagerNotEnabled
+	}
+	if len(args.Data) == 0 {
+		return nil, errors.New("private payload missing")
+	}
+	privateFor, err := resolvePrivacyGroup(args.PrivacyGroupId, args.PrivateFor)
+	if err != nil {
+		return nil, err
+	}
+	if privateFor == nil {
+		return nil, errNoPrivateRecipients
+	}
+	hash, err := private.P.Send(args.Data, args.PrivateFrom, privateFor)
+	if err != nil {
+		return nil, err
+	}
+	log.Info("Distributed private payload", "hash", fmt.Sprintf("%x", hash), "privatefor", privateFor)
+	return hash, nil
+}
+
+// checkPrivatePayload checks the private transaction manager holds the payload
+// of a private transaction signed outside of the node, for the transaction not
+// to be mined with a payload nobody can execute.
+func checkPrivatePayload(tx *types.Transaction) error {
+	if !tx.IsPrivate() || !private.IsEnabled() {
+		return nil
+	}
+	payload, err := private.P.Receive(tx.Data())
+	if err != nil {
+		return err
+	}
+	if len(payload) == 0 {
+		return errUnknownPrivatePayload
+	}
+	return nil
+}
@@ -0,0 +1,179 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math/big"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/private"
+	"github.com/ethereum/go-ethereum/private/privatetransactionmanager"
+)
+
+// testPrivateTransactionManager holds the payloads sent to it by hash. The
+// methods not overridden aren't meant to be called.
+type testPrivateTransactionManager struct {
+	private.PrivateTransactionManager
+
+	payloads map[string][]byte
+	groups   map[string][]string
+	sentTo   []string
+	err      error
+}
+
+func newTestPrivateTransactionManager() *testPrivateTransactionManager {
+	return &testPrivateTransactionManager{
+		payloads: make(map[string][]byte),
+		groups:   make(map[string][]string),
+	}
+}
+
+func (m *testPrivateTransactionManager) Send(data []byte, from string, to []string) ([]byte, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	hash := crypto.Keccak512(data)
+	m.payloads[string(hash)] = data
+	m.sentTo = to
+	return hash, nil
+}
+
+func (m *testPrivateTransactionManager) Receive(data []byte) ([]byte, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return m.payloads[string(data)], nil
+}
+
+func (m *testPrivateTransactionManager) ResolvePrivacyGroup(id string) (*privatetransactionmanager.PrivacyGroup, error) {
+	members, ok := m.groups[id]
+	if !ok {
+		return nil, errors.New("privacy group not found")
+	}
+	return &privatetransactionmanager.PrivacyGroup{PrivacyGroupId: id, Members: members}, nil
+}
+
+func TestDistributeRawTransaction(t *testing.T) {
+	api := NewPublicPrivateTransactionAPI(nil)
+	data := []byte{0x60, 0x80}
+
+	defer func(ptm private.PrivateTransactionManager) { private.P = ptm }(private.P)
+
+	private.P = nil
+	if _, err := api.DistributeRawTransaction(context.Background(), DistributeRawTxArgs{Data: data, PrivateFor: []string{"Ym9i"}}); err != private.ErrPrivateTransactionManagerNotEnabled {
+		t.Fatalf("error mismatch without manager: have %v, want %v", err, private.ErrPrivateTransactionManagerNotEnabled)
+	}
+	ptm := newTestPrivateTransactionManager()
+	ptm.groups["Z3JvdXA="] = []string{"Ym9i", "Y2Fyb2w="}
+	private.P = ptm
+
+	failures := []struct {
+		args DistributeRawTxArgs
+		want error
+	}{
+		{DistributeRawTxArgs{PrivateFor: []string{"Ym9i"}}, nil},
+		{DistributeRawTxArgs{Data: data}, errNoPrivateRecipients},
+		{DistributeRawTxArgs{Data: data, PrivateFor: []string{"Ym9i"}, PrivacyGroupId: "Z3JvdXA="}, errPrivacyGroupAndPrivateFor},
+		{DistributeRawTxArgs{Data: data, PrivacyGroupId: "unknown"}, nil},
+	}
+	for i, test := range failures {
+		_, err := api.DistributeRawTransaction(context.Background(), test.args)
+		if err == nil || (test.want != nil && err != test.want) {
+			t.Errorf("failure %d: error mismatch: have %v, want %v", i, err, test.want)
+		}
+	}
+	// The payload is sent to the recipients, or to the members of the group
+	for i, test := range []struct {
+		args DistributeRawTxArgs
+		want []string
+	}{
+		{DistributeRawTxArgs{Data: data, PrivateFor: []string{"ZGF2ZQ=="}}, []string{"ZGF2ZQ=="}},
+		{DistributeRawTxArgs{Data: data, PrivacyGroupId: "Z3JvdXA="}, []string{"Ym9i", "Y2Fyb2w="}},
+	} {
+		hash, err := api.DistributeRawTransaction(context.Background(), test.args)
+		if err != nil {
+			t.Fatalf("test %d: failed to distribute payload: %v", i, err)
+		}
+		if !bytes.Equal(ptm.payloads[string(hash)], data) {
+			t.Errorf("test %d: payload not held under the hash returned", i)
+		}
+		if !reflect.DeepEqual(ptm.sentTo, test.want) {
+			t.Errorf("test %d: recipients mismatch: have %v, want %v", i, ptm.sentTo, test.want)
+		}
+	}
+	// Failures of the private transaction manager are reported
+	ptm.err = errors.New("unreachable")
+	if _, err := api.DistributeRawTransaction(context.Background(), DistributeRawTxArgs{Data: data, PrivateFor: []string{"Ym9i"}}); err != ptm.err {
+		t.Errorf("error mismatch: have %v, want %v", err, ptm.err)
+	}
+}
+
+func TestCheckPrivatePayload(t *testing.T) {
+	ptm := newTestPrivateTransactionManager()
+	hash, _ := ptm.Send([]byte{0x60, 0x80}, "", []string{"Ym9i"})
+
+	newTx := func(data []byte, private bool) *types.Transaction {
+		tx := types.NewTransaction(0, common.Address{}, new(big.Int), 100000, new(big.Int), data)
+		if private {
+			tx.SetPrivate()
+		}
+		return tx
+	}
+	// Without private transaction manager, nothing is checked
+	defer func(ptm private.PrivateTransactionManager) { private.P = ptm }(private.P)
+
+	private.P = nil
+	if err := checkPrivatePayload(newTx([]byte{0x01}, true)); err != nil {
+		t.Fatalf("payload checked without manager: %v", err)
+	}
+	private.P = ptm
+
+	tests := []struct {
+		tx   *types.Transaction
+		want error
+	}{
+		{newTx(hash, true), nil},
+		{newTx([]byte{0x01}, false), nil},
+		{newTx([]byte{0x01}, true), errUnknownPrivatePayload},
+	}
+	for i, test := range tests {
+		if err := checkPrivatePayload(test.tx); err != test.want {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, test.want)
+		}
+	}
+	// Failures of the private transaction manager are reported
+	ptm.err = errors.New("unreachable")
+	if err := checkPrivatePayload(newTx(hash, true)); err != ptm.err {
+		t.Errorf("error mismatch: have %v, want %v", err, ptm.err)
+	}
+	ptm.err = nil
+
+	// Payloads aren't checked with the private transaction manager ignored
+	defer os.Setenv("PRIVATE_CONFIG", os.Getenv("PRIVATE_CONFIG"))
+	os.Setenv("PRIVATE_CONFIG", "ignore")
+	if err := checkPrivatePayload(newTx([]byte{0x01}, true)); err != nil {
+		t.Errorf("payload checked with the manager ignored: %v", err)
+	}
+}
@@ -0,0 +1,85 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/abiregistry"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// revertError is the error of a reverted call, carrying its decoded reason.
+type revertError struct {
+	message string
+	data    *revertData
+}
+
+// revertData is the data of the error of a reverted call.
+type revertData struct {
+	Reason       string        `json:"reason"`                 // Stable reason of the error, as the classified errors
+	RevertReason string        `json:"revertReason,omitempty"` // Decoded reason of the revert
+	Signature    string        `json:"signature,omitempty"`    // Error(string), Panic(uint256) or the custom error
+	Args         []interface{} `json:"args,omitempty"`         // Arguments of the error
+	Data         hexutil.Bytes `json:"data"`                   // Data returned by the execution
+}
+
+// newRevertError decodes the data returned by a reverted call to a contract into
+// an error, against the custom errors of the contract ABIs registered by the
+// calling tenant besides the standard ones. The data is kept as returned if not
+// decodable.
+func newRevertError(registry *abiregistry.Registry, tenant string, to *common.Address, ret []byte) *revertError {
+	err := &revertError{
+		message: "execution reverted",
+		data:    &revertData{Reason: "EXECUTION_REVERTED", Data: ret},
+	}
+	var (
+		revert *abi.Revert
+		uerr   error
+	)
+	if registry != nil {
+		revert, uerr = registry.UnpackRevert(tenant, to, ret)
+	} else {
+		revert, uerr = abi.UnpackRevert(ret, nil)
+	}
+	if uerr != nil {
+		return err
+	}
+	err.message += ": " + revert.Reason
+	err.data.RevertReason, err.data.Signature = revert.Reason, revert.Signature
+	for _, arg := range revert.Args {
+		// Encode the values JSON doesn't carry faithfully as hex
+		switch arg := arg.(type) {
+		case *big.Int:
+			err.data.Args = append(err.data.Args, (*hexutil.Big)(arg))
+		case []byte:
+			err.data.Args = append(err.data.Args, hexutil.Bytes(arg))
+		default:
+			err.data.Args = append(err.data.Args, arg)
+		}
+	}
+	return err
+}
+
+func (e *revertError) Error() string { return e.message }
+
+func (e *revertError) ErrorCode() int { return rpc.CodeExecutionReverted }
+
+func (e *revertError) ErrorData() interface{} { return e.data }
@@ -34,6 +34,13 @@ var Modules = map[string]string{
 	"raft":             Raft_JS,
 	"istanbul":         Istanbul_JS,
 	"quorumPermission": QUORUM_NODE_JS,
+	"quorumPrivacy":    QuorumPrivacy_JS,
+	"priv":             Priv_JS,
+	"accounting":       Accounting_JS,
+	"quorum":           Quorum_JS,
+	"faultinject":      FaultInject_JS,
+	"permsync":         PermSync_JS,
+	"web3":             Web3_JS,
 }
 
 const Chequebook_JS = `
@@ -170,6 +177,15 @@ web3._extend({
 			call: 'admin_removeTrustedPeer',
 			params: 1
 		}),
+		new web3._extend.Method({
+			name: 'compareChainConfig',
+			call: 'admin_compareChainConfig',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'networkVersions',
+			call: 'admin_networkVersions',
+		}),
 		new web3._extend.Method({
 			name: 'exportChain',
 			call: 'admin_exportChain',
@@ -206,6 +222,54 @@ web3._extend({
 			name: 'stopWS',
 			call: 'admin_stopWS'
 		}),
+		new web3._extend.Method({
+			name: 'exportConfiguration',
+			call: 'admin_exportConfiguration',
+		}),
+		new web3._extend.Method({
+			name: 'apiKeyUsage',
+			call: 'admin_apiKeyUsage',
+		}),
+		new web3._extend.Method({
+			name: 'pendingApprovals',
+			call: 'admin_pendingApprovals',
+			params: 1,
+			inputFormatter: [null]
+		}),
+		new web3._extend.Method({
+			name: 'approval',
+			call: 'admin_approval',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'approve',
+			call: 'admin_approve',
+			params: 2
+		}),
+		new web3._extend.Method({
+			name: 'rejectApproval',
+			call: 'admin_rejectApproval',
+			params: 2
+		}),
+		new web3._extend.Method({
+			name: 'setNodeMode',
+			call: 'admin_setNodeMode',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'retryWebhookDeadLetters',
+			call: 'admin_retryWebhookDeadLetters',
+		}),
+		new web3._extend.Method({
+			name: 'registerABI',
+			call: 'admin_registerABI',
+			params: 2
+		}),
+		new web3._extend.Method({
+			name: 'unregisterABI',
+			call: 'admin_unregisterABI',
+			params: 1
+		}),
 	],
 	properties: [
 		new web3._extend.Property({
@@ -216,10 +280,26 @@ web3._extend({
 			name: 'peers',
 			getter: 'admin_peers'
 		}),
+		new web3._extend.Property({
+			name: 'staticPeerStatus',
+			getter: 'admin_staticPeerStatus'
+		}),
 		new web3._extend.Property({
 			name: 'datadir',
 			getter: 'admin_datadir'
 		}),
+		new web3._extend.Property({
+			name: 'nodeMode',
+			getter: 'admin_nodeMode'
+		}),
+		new web3._extend.Property({
+			name: 'webhookDeadLetters',
+			getter: 'admin_webhookDeadLetters'
+		}),
+		new web3._extend.Property({
+			name: 'selfMonitor',
+			getter: 'admin_selfMonitor'
+		}),
 	]
 });
 `
@@ -314,6 +394,11 @@ web3._extend({
 			call: 'debug_cpuProfile',
 			params: 2
 		}),
+		new web3._extend.Method({
+			name: 'capturePprof',
+			call: 'debug_capturePprof',
+			params: 2
+		}),
 		new web3._extend.Method({
 			name: 'startCPUProfile',
 			call: 'debug_startCPUProfile',
@@ -416,6 +501,44 @@ web3._extend({
 			params: 1,
 			inputFormatter: [null]
 		}),
+		new web3._extend.Method({
+			name: 'mempoolDivergence',
+			call: 'debug_mempoolDivergence',
+		}),
+		new web3._extend.Method({
+			name: 'healState',
+			call: 'debug_healState',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'stateSize',
+			call: 'debug_stateSize',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'stateSizeReport',
+			call: 'debug_stateSizeReport',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'txTrail',
+			call: 'debug_txTrail',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'integrityReport',
+			call: 'debug_integrityReport',
+		}),
+		new web3._extend.Method({
+			name: 'configDryRunReport',
+			call: 'debug_configDryRunReport',
+		}),
+		new web3._extend.Method({
+			name: 'dryRunConfig',
+			call: 'debug_dryRunConfig',
+			params: 1
+		}),
 		new web3._extend.Method({
 			name: 'getBadBlocks',
 			call: 'debug_getBadBlocks',
@@ -426,6 +549,18 @@ web3._extend({
 			call: 'debug_storageRangeAt',
 			params: 5,
 		}),
+		new web3._extend.Method({
+			name: 'accountRange',
+			call: 'debug_accountRange',
+			params: 4,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter, null, null, null]
+		}),
+		new web3._extend.Method({
+			name: 'storageRange',
+			call: 'debug_storageRange',
+			params: 5,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter, null, null, null, null]
+		}),
 		new web3._extend.Method({
 			name: 'getModifiedAccountsByNumber',
 			call: 'debug_getModifiedAccountsByNumber',
@@ -487,6 +622,16 @@ web3._extend({
 			call: 'eth_getRawTransactionByHash',
 			params: 1
 		}),
+		new web3._extend.Method({
+			name: 'getFinalizedBlock',
+			call: 'eth_getFinalizedBlock',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'getDecodedTransactionReceipt',
+			call: 'eth_getDecodedTransactionReceipt',
+			params: 1
+		}),
 		new web3._extend.Method({
 			name: 'getRawTransactionFromBlock',
 			call: function(args) {
@@ -520,6 +665,34 @@ web3._extend({
 			params: 1,
 			inputFormatter: [null]
 		}),
+		new web3._extend.Method({
+			name: 'computeCreate2Address',
+			call: 'eth_computeCreate2Address',
+			params: 3,
+			inputFormatter: [null, null, null]
+		}),
+		new web3._extend.Method({
+			name: 'cancelTransaction',
+			call: 'eth_cancelTransaction',
+			params: 2,
+			inputFormatter: [null, null]
+		}),
+		new web3._extend.Method({
+			name: 'sendDocumentAnchor',
+			call: 'eth_sendDocumentAnchor',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'getDocumentAnchor',
+			call: 'eth_getDocumentAnchor',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'createAccessList',
+			call: 'eth_createAccessList',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputCallFormatter, web3._extend.formatters.inputBlockNumberFormatter]
+		}),
 		// END-QUORUM
 	],
 	properties: [
@@ -703,7 +876,14 @@ web3._extend({
 const TxPool_JS = `
 web3._extend({
 	property: 'txpool',
-	methods: [],
+	methods:
+	[
+		new web3._extend.Method({
+			name: 'check',
+			call: 'txpool_check',
+			params: 1
+		}),
+	],
 	properties:
 	[
 		new web3._extend.Property({
@@ -742,22 +922,22 @@ web3._extend({
                new web3._extend.Method({
                        name: 'addPeer',
                        call: 'raft_addPeer',
-                       params: 1
+                       params: 2
                }),
                new web3._extend.Method({
                        name: 'addLearner',
                        call: 'raft_addLearner',
-                       params: 1
+                       params: 2
                }),
                new web3._extend.Method({
                        name: 'promoteToPeer',
                        call: 'raft_promoteToPeer',
-                       params: 1
+                       params: 2
                }),
                new web3._extend.Method({
                        name: 'removePeer',
                        call: 'raft_removePeer',
-                       params: 1
+                       params: 2
                }),
                new web3._extend.Property({
                        name: 'leader',
@@ -767,6 +947,10 @@ web3._extend({
                        name: 'cluster',
                        getter: 'raft_cluster'
                }),
+               new web3._extend.Property({
+                       name: 'clusterDetailed',
+                       getter: 'raft_clusterDetailed'
+               }),
        ]
 })
 `
@@ -779,110 +963,110 @@ web3._extend({
 				new web3._extend.Method({
                        name: 'addOrg',
                        call: 'quorumPermission_addOrg',
-                       params: 4,
-                       inputFormatter: [null,null,web3._extend.formatters.inputAddressFormatter,web3._extend.formatters.inputTransactionFormatter]
+                       params: 5,
+                       inputFormatter: [null,null,web3._extend.formatters.inputAddressFormatter,web3._extend.formatters.inputTransactionFormatter,null]
                }),
 			   new web3._extend.Method({
                        name: 'approveOrg',
                        call: 'quorumPermission_approveOrg',
-                       params: 4,
-                       inputFormatter: [null,null,web3._extend.formatters.inputAddressFormatter,web3._extend.formatters.inputTransactionFormatter]
+                       params: 5,
+                       inputFormatter: [null,null,web3._extend.formatters.inputAddressFormatter,web3._extend.formatters.inputTransactionFormatter,null]
                }),
 				new web3._extend.Method({
                        name: 'addSubOrg',
                        call: 'quorumPermission_addSubOrg',
-                       params: 4,
-                       inputFormatter: [null,null,null,web3._extend.formatters.inputTransactionFormatter]
+                       params: 5,
+                       inputFormatter: [null,null,null,web3._extend.formatters.inputTransactionFormatter,null]
                }),
                new web3._extend.Method({
                        name: 'updateOrgStatus',
                        call: 'quorumPermission_updateOrgStatus',
-                       params: 3,
-                       inputFormatter: [null,null,web3._extend.formatters.inputTransactionFormatter]
+                       params: 4,
+                       inputFormatter: [null,null,web3._extend.formatters.inputTransactionFormatter,null]
                }),
                new web3._extend.Method({
                        name: 'approveOrgStatus',
                        call: 'quorumPermission_approveOrgStatus',
-                       params: 3,
-                       inputFormatter: [null,null,web3._extend.formatters.inputTransactionFormatter]
+                       params: 4,
+                       inputFormatter: [null,null,web3._extend.formatters.inputTransactionFormatter,null]
                }),
                new web3._extend.Method({
                        name: 'addNode',
                        call: 'quorumPermission_addNode',
-                       params: 3,
-                       inputFormatter: [null,null,web3._extend.formatters.inputTransactionFormatter]
+                       params: 4,
+                       inputFormatter: [null,null,web3._extend.formatters.inputTransactionFormatter,null]
                }),
                new web3._extend.Method({
                        name: 'updateNodeStatus',
                        call: 'quorumPermission_updateNodeStatus',
-                       params: 4,
-                       inputFormatter: [null,null,null,web3._extend.formatters.inputTransactionFormatter]
+                       params: 5,
+                       inputFormatter: [null,null,null,web3._extend.formatters.inputTransactionFormatter,null]
                }),
                new web3._extend.Method({
                        name: 'assignAdminRole',
                        call: 'quorumPermission_assignAdminRole',
-                       params: 4,
-                       inputFormatter: [null,web3._extend.formatters.inputAddressFormatter,null, web3._extend.formatters.inputTransactionFormatter]
+                       params: 5,
+                       inputFormatter: [null,web3._extend.formatters.inputAddressFormatter,null, web3._extend.formatters.inputTransactionFormatter,null]
                }),
                new web3._extend.Method({
                        name: 'approveAdminRole',
                        call: 'quorumPermission_approveAdminRole',
-                       params: 3,
-                       inputFormatter: [null, web3._extend.formatters.inputAddressFormatter,web3._extend.formatters.inputTransactionFormatter]
+                       params: 4,
+                       inputFormatter: [null, web3._extend.formatters.inputAddressFormatter,web3._extend.formatters.inputTransactionFormatter,null]
                }),
                new web3._extend.Method({
                        name: 'addNewRole',
                        call: 'quorumPermission_addNewRole',
-                       params: 6,
-                       inputFormatter: [null,null,null,null,null,web3._extend.formatters.inputTransactionFormatter]
+                       params: 7,
+                       inputFormatter: [null,null,null,null,null,web3._extend.formatters.inputTransactionFormatter,null]
                }),
                new web3._extend.Method({
                        name: 'removeRole',
                        call: 'quorumPermission_removeRole',
-                       params: 3,
-                       inputFormatter: [null,null,web3._extend.formatters.inputTransactionFormatter]
+                       params: 4,
+                       inputFormatter: [null,null,web3._extend.formatters.inputTransactionFormatter,null]
                }),
                new web3._extend.Method({
                        name: 'addAccountToOrg',
                        call: 'quorumPermission_addAccountToOrg',
-                       params: 4,
-                       inputFormatter: [web3._extend.formatters.inputAddressFormatter,null,null,web3._extend.formatters.inputTransactionFormatter]
+                       params: 5,
+                       inputFormatter: [web3._extend.formatters.inputAddressFormatter,null,null,web3._extend.formatters.inputTransactionFormatter,null]
                }),
                new web3._extend.Method({
                        name: 'changeAccountRole',
                        call: 'quorumPermission_changeAccountRole',
-                       params: 4,
-                       inputFormatter: [web3._extend.formatters.inputAddressFormatter,null,null,web3._extend.formatters.inputTransactionFormatter]
+                       params: 5,
+                       inputFormatter: [web3._extend.formatters.inputAddressFormatter,null,null,web3._extend.formatters.inputTransactionFormatter,null]
                }),	
 			   new web3._extend.Method({
                        name: 'updateAccountStatus',
                        call: 'quorumPermission_updateAccountStatus',
-                       params: 4,
-                       inputFormatter: [null, web3._extend.formatters.inputAddressFormatter,null,web3._extend.formatters.inputTransactionFormatter]
+                       params: 5,
+                       inputFormatter: [null, web3._extend.formatters.inputAddressFormatter,null,web3._extend.formatters.inputTransactionFormatter,null]
                }),
 			   new web3._extend.Method({
                        name: 'recoverBlackListedNode',
                        call: 'quorumPermission_recoverBlackListedNode',
-                       params: 3,
-                       inputFormatter: [null, null, web3._extend.formatters.inputTransactionFormatter]
+                       params: 4,
+                       inputFormatter: [null, null, web3._extend.formatters.inputTransactionFormatter,null]
                }),
 			   new web3._extend.Method({
                        name: 'approveBlackListedNodeRecovery',
                        call: 'quorumPermission_approveBlackListedNodeRecovery',
-                       params: 3,
-                       inputFormatter: [null, null, web3._extend.formatters.inputTransactionFormatter]
+                       params: 4,
+                       inputFormatter: [null, null, web3._extend.formatters.inputTransactionFormatter,null]
                }),
 			   new web3._extend.Method({
                        name: 'recoverBlackListedAccount',
                        call: 'quorumPermission_recoverBlackListedAccount',
-                       params: 3,
-                       inputFormatter: [null, web3._extend.formatters.inputAddressFormatter, web3._extend.formatters.inputTransactionFormatter]
+                       params: 4,
+                       inputFormatter: [null, web3._extend.formatters.inputAddressFormatter, web3._extend.formatters.inputTransactionFormatter,null]
                }),
 			   new web3._extend.Method({
                        name: 'approveBlackListedAccountRecovery',
                        call: 'quorumPermission_approveBlackListedAccountRecovery',
-                       params: 3,
-                       inputFormatter: [null, web3._extend.formatters.inputAddressFormatter, web3._extend.formatters.inputTransactionFormatter]
+                       params: 4,
+                       inputFormatter: [null, web3._extend.formatters.inputAddressFormatter, web3._extend.formatters.inputTransactionFormatter,null]
                }),
                new web3._extend.Method({
                        name: 'getOrgDetails',
@@ -910,6 +1094,10 @@ web3._extend({
 					   name: 'acctList',
 				       getter: 'quorumPermission_acctList'
 			  }), 
+              new web3._extend.Property({
+					   name: 'orgQuotas',
+				       getter: 'quorumPermission_orgQuotas'
+			  }),
        ]
 })
 `
@@ -944,12 +1132,22 @@ web3._extend({
 		new web3._extend.Method({
 			name: 'propose',
 			call: 'istanbul_propose',
-			params: 2
+			params: 3
 		}),
 		new web3._extend.Method({
 			name: 'discard',
 			call: 'istanbul_discard',
-			params: 1
+			params: 2
+		}),
+		new web3._extend.Method({
+			name: 'setVanity',
+			call: 'istanbul_setVanity',
+			params: 2
+		}),
+		new web3._extend.Method({
+			name: 'simulateValidatorChange',
+			call: 'istanbul_simulateValidatorChange',
+			params: 2
 		}),
 
 		new web3._extend.Method({
@@ -974,6 +1172,237 @@ web3._extend({
 			name: 'nodeAddress',
 			getter: 'istanbul_nodeAddress'
 		}),
+		new web3._extend.Property({
+			name: 'shadowReport',
+			getter: 'istanbul_shadowReport'
+		}),
+		new web3._extend.Property({
+			name: 'networkTime',
+			getter: 'istanbul_networkTime'
+		}),
+		new web3._extend.Property({
+			name: 'vanity',
+			getter: 'istanbul_vanity'
+		}),
+	]
+});
+`
+
+const QuorumPrivacy_JS = `
+web3._extend({
+	property: 'quorumPrivacy',
+	methods: [
+		new web3._extend.Method({
+			name: 'purgeHash',
+			call: 'quorumPrivacy_purgeHash',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'purgeContract',
+			call: 'quorumPrivacy_purgeContract',
+			params: 2
+		}),
+		new web3._extend.Method({
+			name: 'tombstone',
+			call: 'quorumPrivacy_tombstone',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'createPrivacyGroup',
+			call: 'quorumPrivacy_createPrivacyGroup',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'findPrivacyGroup',
+			call: 'quorumPrivacy_findPrivacyGroup',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'retrievePrivacyGroup',
+			call: 'quorumPrivacy_retrievePrivacyGroup',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'addToPrivacyGroup',
+			call: 'quorumPrivacy_addToPrivacyGroup',
+			params: 3
+		}),
+		new web3._extend.Method({
+			name: 'removeFromPrivacyGroup',
+			call: 'quorumPrivacy_removeFromPrivacyGroup',
+			params: 3
+		}),
+		new web3._extend.Method({
+			name: 'deletePrivacyGroup',
+			call: 'quorumPrivacy_deletePrivacyGroup',
+			params: 2
+		}),
+	],
+	properties:
+	[
+		new web3._extend.Property({
+			name: 'pendingDistributions',
+			getter: 'quorumPrivacy_pendingDistributions'
+		}),
+		new web3._extend.Property({
+			name: 'tombstones',
+			getter: 'quorumPrivacy_tombstones'
+		}),
+	]
+});
+`
+
+const Priv_JS = `
+web3._extend({
+	property: 'priv',
+	methods: [
+		new web3._extend.Method({
+			name: 'distributeRawTransaction',
+			call: 'priv_distributeRawTransaction',
+			params: 1
+		}),
+	]
+});
+`
+
+const Accounting_JS = `
+web3._extend({
+	property: 'accounting',
+	methods: [
+		new web3._extend.Method({
+			name: 'gasUsage',
+			call: 'accounting_gasUsage',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'exportCSV',
+			call: 'accounting_exportCSV',
+			params: 1
+		}),
+	],
+	properties: [
+		new web3._extend.Property({
+			name: 'gasUsages',
+			getter: 'accounting_gasUsages'
+		}),
+	]
+});
+`
+
+const Quorum_JS = `
+web3._extend({
+	property: 'quorum',
+	methods: [
+		new web3._extend.Method({
+			name: 'queryLogs',
+			call: 'quorum_queryLogs',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'registerABI',
+			call: 'quorum_registerABI',
+			params: 2
+		}),
+		new web3._extend.Method({
+			name: 'unregisterABI',
+			call: 'quorum_unregisterABI',
+			params: 1
+		}),
+	],
+	properties: [
+		new web3._extend.Property({
+			name: 'networkHealth',
+			getter: 'quorum_networkHealth'
+		}),
+		new web3._extend.Property({
+			name: 'registeredABIs',
+			getter: 'quorum_registeredABIs'
+		}),
+	]
+});
+`
+
+const FaultInject_JS = `
+web3._extend({
+	property: 'faultinject',
+	methods: [
+		new web3._extend.Method({
+			name: 'addRule',
+			call: 'faultinject_addRule',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'removeRule',
+			call: 'faultinject_removeRule',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'setClockSkew',
+			call: 'faultinject_setClockSkew',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'reset',
+			call: 'faultinject_reset',
+			params: 0
+		}),
+	],
+	properties: [
+		new web3._extend.Property({
+			name: 'rules',
+			getter: 'faultinject_rules'
+		}),
+		new web3._extend.Property({
+			name: 'clockSkew',
+			getter: 'faultinject_clockSkew'
+		}),
+	]
+});
+`
+
+const PermSync_JS = `
+web3._extend({
+	property: 'permsync',
+	methods: [
+		new web3._extend.Method({
+			name: 'addNode',
+			call: 'permsync_addNode',
+			params: 2
+		}),
+		new web3._extend.Method({
+			name: 'removeNode',
+			call: 'permsync_removeNode',
+			params: 2
+		}),
+		new web3._extend.Method({
+			name: 'reconcile',
+			call: 'permsync_reconcile',
+			params: 0
+		}),
+	],
+	properties: [
+		new web3._extend.Property({
+			name: 'deltas',
+			getter: 'permsync_deltas'
+		}),
+		new web3._extend.Property({
+			name: 'status',
+			getter: 'permsync_status'
+		}),
+	]
+});
+`
+
+const Web3_JS = `
+web3._extend({
+	methods: [
+		new web3._extend.Method({
+			name: 'nodeInfoExtended',
+			call: 'web3_nodeInfoExtended',
+			params: 0
+		}),
 	]
 });
 `
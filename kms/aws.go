@@ -0,0 +1,156 @@
+package kms
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// awsBackend signs with an ECC_SECG_P256K1 key of AWS KMS.
+type awsBackend struct {
+	keyID    string
+	region   string
+	endpoint string
+
+	accessKey, secretKey, token string
+
+	client *http.Client
+}
+
+func newAWSBackend(key string, query url.Values) (*awsBackend, error) {
+	b := &awsBackend{
+		keyID:     key,
+		region:    query.Get("region"),
+		endpoint:  strings.TrimSuffix(query.Get("endpoint"), "/"),
+		accessKey: os.Getenv("AWS_ACCESS_KEY_ID"),
+		secretKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		token:     os.Getenv("AWS_SESSION_TOKEN"),
+		client:    new(http.Client),
+	}
+	// ARNs carry the region: arn:aws:kms:<region>:<account>:key/<id>
+	if parts := strings.Split(b.keyID, ":"); b.region == "" && len(parts) > 3 && parts[0] == "arn" {
+		b.region = parts[3]
+	}
+	if b.region == "" {
+		b.region = os.Getenv("AWS_REGION")
+	}
+	if b.region == "" {
+		return nil, fmt.Errorf("AWS KMS key %q misses the region", key)
+	}
+	if b.accessKey == "" || b.secretKey == "" {
+		return nil, fmt.Errorf("AWS KMS key %q requires AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY", key)
+	}
+	if b.endpoint == "" {
+		b.endpoint = fmt.Sprintf("https://kms.%s.amazonaws.com", b.region)
+	}
+	return b, nil
+}
+
+func (b *awsBackend) publicKey(ctx context.Context) (*ecdsa.PublicKey, error) {
+	var result struct {
+		PublicKey []byte
+		KeySpec   string
+	}
+	if err := b.call(ctx, "GetPublicKey", map[string]interface{}{"KeyId": b.keyID}, &result); err != nil {
+		return nil, err
+	}
+	return parsePublicKey(result.PublicKey)
+}
+
+func (b *awsBackend) sign(ctx context.Context, digest []byte) (*big.Int, *big.Int, error) {
+	params := map[string]interface{}{
+		"KeyId":            b.keyID,
+		"Message":          digest,
+		"MessageType":      "DIGEST",
+		"SigningAlgorithm": "ECDSA_SHA_256",
+	}
+	var result struct {
+		Signature []byte
+	}
+	if err := b.call(ctx, "Sign", params, &result); err != nil {
+		return nil, nil, err
+	}
+	return parseDERSignature(result.Signature)
+}
+
+// call invokes an action of the KMS JSON API.
+func (b *awsBackend) call(ctx context.Context, action string, params interface{}, result interface{}) error {
+	body, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", b.endpoint+"/", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "TrentService."+action)
+	if b.token != "" {
+		req.Header.Set("X-Amz-Security-Token", b.token)
+	}
+	b.signRequest(req, body, time.Now().UTC())
+
+	return doJSON(b.client, req.WithContext(ctx), result)
+}
+
+// signRequest authenticates a request with AWS signature version 4, signing the
+// host and every header of the request.
+func (b *awsBackend) signRequest(req *http.Request, payload []byte, now time.Time) {
+	var (
+		amzDate     = now.Format("20060102T150405Z")
+		date        = now.Format("20060102")
+		scope       = date + "/" + b.region + "/kms/aws4_request"
+		payloadHash = sha256Hex(payload)
+	)
+	req.Header.Set("X-Amz-Date", amzDate)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+	canonicalRequest := strings.Join([]string{
+		req.Method, req.URL.EscapedPath(), req.URL.RawQuery, canonicalHeaders.String(), signedHeaders, payloadHash,
+	}, "\n")
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, sha256Hex([]byte(canonicalRequest))}, "\n")
+
+	key := []byte("AWS4" + b.secretKey)
+	for _, part := range []string{date, b.region, "kms", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s", b.accessKey, scope, signedHeaders, signature))
+}
+
+func sha256Hex(data []byte) string {
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
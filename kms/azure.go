@@ -0,0 +1,121 @@
+package kms
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const (
+	azureAPIVersion    = "7.0"
+	azureMetadataToken = "http://169.254.169.254/metadata/identity/oauth2/token?api-version=2018-02-01&resource=https%3A%2F%2Fvault.azure.net"
+)
+
+// azureBackend signs with a P-256K key of Azure Key Vault.
+type azureBackend struct {
+	path     string // Path of the key version in the vault
+	endpoint string
+	tokens   *tokenSource
+	client   *http.Client
+}
+
+func newAzureBackend(key string, query url.Values) (*azureBackend, error) {
+	parts := strings.SplitN(key, "/", 2)
+	if len(parts) != 2 || !strings.HasPrefix(parts[1], "keys/") {
+		return nil, fmt.Errorf("Azure Key Vault key %q is not a vault key", key)
+	}
+	b := &azureBackend{
+		path:     "/" + parts[1],
+		endpoint: strings.TrimSuffix(query.Get("endpoint"), "/"),
+		client:   new(http.Client),
+	}
+	if b.endpoint == "" {
+		b.endpoint = "https://" + parts[0]
+	}
+	b.tokens = &tokenSource{
+		static: os.Getenv("AZURE_ACCESS_TOKEN"),
+		fetch: func(ctx context.Context) (string, time.Duration, error) {
+			return metadataToken(ctx, b.client, azureMetadataToken, "Metadata", "true")
+		},
+	}
+	return b, nil
+}
+
+func (b *azureBackend) publicKey(ctx context.Context) (*ecdsa.PublicKey, error) {
+	var result struct {
+		Key struct {
+			Kty string `json:"kty"`
+			Crv string `json:"crv"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		} `json:"key"`
+	}
+	if err := b.call(ctx, "GET", b.path, nil, &result); err != nil {
+		return nil, err
+	}
+	if (result.Key.Kty != "EC" && result.Key.Kty != "EC-HSM") || result.Key.Crv != "P-256K" {
+		return nil, errInvalidKey
+	}
+	x, errX := base64.RawURLEncoding.DecodeString(result.Key.X)
+	y, errY := base64.RawURLEncoding.DecodeString(result.Key.Y)
+	if errX != nil || errY != nil || len(x) > 32 || len(y) > 32 {
+		return nil, errInvalidKey
+	}
+	pub := make([]byte, 65)
+	pub[0] = 4
+	copy(pub[33-len(x):33], x)
+	copy(pub[65-len(y):], y)
+	return crypto.UnmarshalPubkey(pub)
+}
+
+func (b *azureBackend) sign(ctx context.Context, digest []byte) (*big.Int, *big.Int, error) {
+	params := map[string]string{
+		"alg":   "ES256K",
+		"value": base64.RawURLEncoding.EncodeToString(digest),
+	}
+	var result struct {
+		Value string `json:"value"`
+	}
+	if err := b.call(ctx, "POST", b.path+"/sign", params, &result); err != nil {
+		return nil, nil, err
+	}
+	// The signature is the concatenation of R and S, not DER encoded
+	sig, err := base64.RawURLEncoding.DecodeString(result.Value)
+	if err != nil || len(sig) != 64 {
+		return nil, nil, errInvalidSig
+	}
+	return new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:]), nil
+}
+
+// call invokes an operation of the Key Vault REST API.
+func (b *azureBackend) call(ctx context.Context, method, path string, params interface{}, result interface{}) error {
+	token, err := b.tokens.get(ctx)
+	if err != nil {
+		return err
+	}
+	var body []byte
+	if params != nil {
+		if body, err = json.Marshal(params); err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequest(method, b.endpoint+path+"?api-version="+azureAPIVersion, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+
+	return doJSON(b.client, req.WithContext(ctx), result)
+}
@@ -0,0 +1,378 @@
+// Package kms signs with the node key held in a cloud key management service.
+//
+// The key stays in AWS KMS, Google Cloud KMS or Azure Key Vault, which sign
+// the hashes remotely: the consensus messages and seals, the raft block
+// signatures and the node attestations of the handshake. Each signature has a
+// latency budget, and the failover policy decides whether a signature the KMS
+// fails to deliver within it is made with the local copy of the key or fails.
+//
+// The RLPx handshake agrees on the session keys by ECDH with the node key, which
+// none of the KMS performs with a signing key: an AWS KMS key has a single
+// usage, and secp256k1 keys can only sign and verify. The node key file is thus
+// still needed for the handshake, and must match the KMS key.
+package kms
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"encoding/asn1"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/crypto/ecies"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+const (
+	// DefaultBudget is the default latency budget of a remote signature.
+	DefaultBudget = time.Second
+
+	// FailoverNone fails the signatures the KMS doesn't deliver in time.
+	FailoverNone = "none"
+
+	// FailoverLocal signs with the local key when the KMS doesn't deliver in time.
+	FailoverLocal = "local"
+
+	// setupTimeout is the time given to the KMS to return the public key on start
+	setupTimeout = 10 * time.Second
+)
+
+var (
+	errKeyMismatch     = errors.New("KMS key is not the node key")
+	errNoNodeKey       = errors.New("KMS signing requires the node key for the handshake")
+	errInvalidKey      = errors.New("KMS key is not a secp256k1 key")
+	errInvalidSig      = errors.New("invalid KMS signature")
+	errUnknownFailover = errors.New("unknown KMS failover policy")
+
+	secp256k1N     = crypto.S256().Params().N
+	secp256k1HalfN = new(big.Int).Rsh(secp256k1N, 1)
+	secp256k1OID   = asn1.ObjectIdentifier{1, 3, 132, 0, 10}
+)
+
+var (
+	signTimer     = metrics.NewRegisteredTimer("kms/sign", nil)
+	errorMeter    = metrics.NewRegisteredMeter("kms/errors", nil)
+	failoverMeter = metrics.NewRegisteredMeter("kms/failover", nil)
+)
+
+// Config locates the key in a KMS and sets the signing policy.
+//
+// The key is given by a URL of one of the forms:
+//
+//	awskms://<key id or ARN>[?region=&endpoint=]
+//	gcpkms://projects/<p>/locations/<l>/keyRings/<r>/cryptoKeys/<k>/cryptoKeyVersions/<v>[?endpoint=]
+//	azurekv://<vault host>/keys/<name>/<version>[?endpoint=]
+//
+// The AWS credentials are read from the AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY
+// and AWS_SESSION_TOKEN environment variables, the region from AWS_REGION if not
+// given in the URL or ARN. The Google and Azure access tokens are read from the
+// GOOGLE_OAUTH_ACCESS_TOKEN and AZURE_ACCESS_TOKEN environment variables, or
+// requested from the metadata service of the instance.
+type Config struct {
+	URL      string        // Location of the key
+	Budget   time.Duration // Maximum latency of a remote signature
+	Failover string        // Policy when the budget is exceeded: none or local
+}
+
+// Signer signs hashes with a key of the node.
+type Signer interface {
+	// PublicKey returns the public key of the signer.
+	PublicKey() *ecdsa.PublicKey
+
+	// Sign returns the 65 byte [R || S || V] signature of a 32 byte hash, in the
+	// format of crypto.Sign.
+	Sign(hash []byte) ([]byte, error)
+
+	// ECDH returns the 32 byte X coordinate of the product of the key with a
+	// public key, the shared secret of the RLPx handshake.
+	ECDH(pub *ecdsa.PublicKey) ([]byte, error)
+}
+
+// localSigner signs with a key held in memory.
+type localSigner struct {
+	key *ecdsa.PrivateKey
+}
+
+// NewLocalSigner returns a signer using a key held in memory.
+func NewLocalSigner(key *ecdsa.PrivateKey) Signer {
+	return &localSigner{key: key}
+}
+
+func (s *localSigner) PublicKey() *ecdsa.PublicKey {
+	return &s.key.PublicKey
+}
+
+func (s *localSigner) Sign(hash []byte) ([]byte, error) {
+	return crypto.Sign(hash, s.key)
+}
+
+func (s *localSigner) ECDH(pub *ecdsa.PublicKey) ([]byte, error) {
+	return localECDH(s.key, pub)
+}
+
+// localECDH computes the shared secret of a key held in memory.
+func localECDH(key *ecdsa.PrivateKey, pub *ecdsa.PublicKey) ([]byte, error) {
+	return ecies.ImportECDSA(key).GenerateShared(ecies.ImportECDSAPublic(pub), 16, 16)
+}
+
+// backend is a KMS holding a secp256k1 key.
+type backend interface {
+	// publicKey returns the public key of the held key.
+	publicKey(ctx context.Context) (*ecdsa.PublicKey, error)
+
+	// sign returns the ECDSA signature of a digest by the held key.
+	sign(ctx context.Context, digest []byte) (r, s *big.Int, err error)
+}
+
+// remoteSigner signs with a key held in a KMS, within a latency budget.
+type remoteSigner struct {
+	backend  backend
+	pub      *ecdsa.PublicKey
+	key      *ecdsa.PrivateKey // Copy of the key agreeing on the handshake secrets
+	local    *ecdsa.PrivateKey // Copy of the key signing on failover, nil if disabled
+	budget   time.Duration
+	location string
+}
+
+// New returns a signer using the key held in the KMS located by the config. The
+// local node key is required, to agree on the handshake secrets and to sign on
+// failover if the policy is local, and must match the KMS key.
+func New(config Config, nodeKey *ecdsa.PrivateKey) (Signer, error) {
+	scheme, key, query, err := parseKeyURL(config.URL)
+	if err != nil {
+		return nil, err
+	}
+	var b backend
+	switch scheme {
+	case "awskms":
+		b, err = newAWSBackend(key, query)
+	case "gcpkms":
+		b, err = newGCPBackend(key, query)
+	case "azurekv":
+		b, err = newAzureBackend(key, query)
+	default:
+		err = fmt.Errorf("unsupported KMS %q", scheme)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return newRemoteSigner(b, config, nodeKey)
+}
+
+// parseKeyURL splits a key URL into its scheme, key and query. The key isn't
+// parsed as a URL host and path, AWS ARNs not being valid hosts.
+func parseKeyURL(rawurl string) (string, string, url.Values, error) {
+	sep := strings.Index(rawurl, "://")
+	if sep < 0 {
+		return "", "", nil, fmt.Errorf("invalid KMS key %q", rawurl)
+	}
+	scheme, key, rawQuery := rawurl[:sep], rawurl[sep+3:], ""
+	if q := strings.Index(key, "?"); q >= 0 {
+		key, rawQuery = key[:q], key[q+1:]
+	}
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil || key == "" {
+		return "", "", nil, fmt.Errorf("invalid KMS key %q", rawurl)
+	}
+	return scheme, strings.Trim(key, "/"), query, nil
+}
+
+func newRemoteSigner(b backend, config Config, nodeKey *ecdsa.PrivateKey) (*remoteSigner, error) {
+	if nodeKey == nil {
+		return nil, errNoNodeKey
+	}
+	signer := &remoteSigner{backend: b, key: nodeKey, budget: config.Budget, location: config.URL}
+	if signer.budget <= 0 {
+		signer.budget = DefaultBudget
+	}
+	switch config.Failover {
+	case "", FailoverNone:
+	case FailoverLocal:
+		signer.local = nodeKey
+	default:
+		return nil, fmt.Errorf("%v: %q", errUnknownFailover, config.Failover)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), setupTimeout)
+	defer cancel()
+
+	pub, err := b.publicKey(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve the KMS key: %v", err)
+	}
+	if !bytes.Equal(crypto.FromECDSAPub(pub), crypto.FromECDSAPub(&nodeKey.PublicKey)) {
+		return nil, errKeyMismatch
+	}
+	signer.pub = pub
+	return signer, nil
+}
+
+func (s *remoteSigner) PublicKey() *ecdsa.PublicKey {
+	return s.pub
+}
+
+// Sign requests the signature of the hash from the KMS, falling back to the
+// local key if the policy allows it when the KMS fails or exceeds the budget.
+func (s *remoteSigner) Sign(hash []byte) ([]byte, error) {
+	start := time.Now()
+	sig, err := s.remoteSign(hash)
+	if err == nil {
+		signTimer.UpdateSince(start)
+		return sig, nil
+	}
+	errorMeter.Mark(1)
+	if s.local == nil {
+		log.Error("KMS signature failed", "key", s.location, "elapsed", time.Since(start), "err", err)
+		return nil, err
+	}
+	failoverMeter.Mark(1)
+	log.Warn("KMS signature failed, signing with the local key", "key", s.location, "elapsed", time.Since(start), "err", err)
+	return crypto.Sign(hash, s.local)
+}
+
+// ECDH computes the shared secret with the local copy of the key, the KMS not
+// performing key agreement with a signing key.
+func (s *remoteSigner) ECDH(pub *ecdsa.PublicKey) ([]byte, error) {
+	return localECDH(s.key, pub)
+}
+
+func (s *remoteSigner) remoteSign(hash []byte) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.budget)
+	defer cancel()
+
+	r, sv, err := s.backend.sign(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	return recoverableSignature(hash, r, sv, s.pub)
+}
+
+// recoverableSignature converts an ECDSA signature of the hash by the public
+// key into the [R || S || V] format, with S in the lower half of the order as
+// required by Ethereum and the recovery id found by trial.
+func recoverableSignature(hash []byte, r, s *big.Int, pub *ecdsa.PublicKey) ([]byte, error) {
+	if r.Sign() <= 0 || s.Sign() <= 0 || r.Cmp(secp256k1N) >= 0 || s.Cmp(secp256k1N) >= 0 {
+		return nil, errInvalidSig
+	}
+	if s.Cmp(secp256k1HalfN) > 0 {
+		s = new(big.Int).Sub(secp256k1N, s)
+	}
+	sig := make([]byte, 65)
+	copy(sig[32-len(r.Bytes()):32], r.Bytes())
+	copy(sig[64-len(s.Bytes()):64], s.Bytes())
+
+	want := crypto.FromECDSAPub(pub)
+	for v := byte(0); v < 2; v++ {
+		sig[64] = v
+		if have, err := crypto.Ecrecover(hash, sig); err == nil && bytes.Equal(have, want) {
+			return sig, nil
+		}
+	}
+	return nil, errInvalidSig
+}
+
+// parseDERSignature decodes an ASN.1 DER encoded ECDSA signature.
+func parseDERSignature(der []byte) (*big.Int, *big.Int, error) {
+	var sig struct{ R, S *big.Int }
+	if rest, err := asn1.Unmarshal(der, &sig); err != nil || len(rest) > 0 {
+		return nil, nil, errInvalidSig
+	}
+	return sig.R, sig.S, nil
+}
+
+// parsePublicKey decodes a DER encoded SubjectPublicKeyInfo of a secp256k1
+// key, which the x509 package doesn't support.
+func parsePublicKey(der []byte) (*ecdsa.PublicKey, error) {
+	var spki struct {
+		Algorithm struct {
+			Algorithm  asn1.ObjectIdentifier
+			Parameters asn1.ObjectIdentifier
+		}
+		PublicKey asn1.BitString
+	}
+	if rest, err := asn1.Unmarshal(der, &spki); err != nil || len(rest) > 0 {
+		return nil, errInvalidKey
+	}
+	if !spki.Algorithm.Parameters.Equal(secp256k1OID) {
+		return nil, errInvalidKey
+	}
+	return crypto.UnmarshalPubkey(spki.PublicKey.Bytes)
+}
+
+// doJSON sends a request with a JSON body, if any, and decodes the JSON answer.
+func doJSON(client *http.Client, req *http.Request, result interface{}) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("KMS returned %s: %s", resp.Status, bytes.TrimSpace(body))
+	}
+	return json.Unmarshal(body, result)
+}
+
+// tokenSource caches the access token returned by a metadata service.
+type tokenSource struct {
+	static string // Token given by the environment, used as is
+	fetch  func(ctx context.Context) (token string, expiry time.Duration, err error)
+
+	lock    sync.Mutex
+	token   string
+	expires time.Time
+}
+
+// get returns a valid access token, fetching a new one a minute before the
+// cached one expires.
+func (t *tokenSource) get(ctx context.Context) (string, error) {
+	if t.static != "" {
+		return t.static, nil
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.token != "" && time.Now().Before(t.expires) {
+		return t.token, nil
+	}
+	token, expiry, err := t.fetch(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to get a KMS access token: %v", err)
+	}
+	t.token, t.expires = token, time.Now().Add(expiry-time.Minute)
+	return token, nil
+}
+
+// metadataToken requests an access token from a metadata service of a cloud
+// instance, answering in the OAuth2 token format.
+func metadataToken(ctx context.Context, client *http.Client, target string, header, value string) (string, time.Duration, error) {
+	req, err := http.NewRequest("GET", target, nil)
+	if err != nil {
+		return "", 0, err
+	}
+	req.Header.Set(header, value)
+
+	var result struct {
+		AccessToken string      `json:"access_token"`
+		ExpiresIn   json.Number `json:"expires_in"`
+	}
+	if err := doJSON(client, req.WithContext(ctx), &result); err != nil {
+		return "", 0, err
+	}
+	expiry, _ := result.ExpiresIn.Int64()
+	return result.AccessToken, time.Duration(expiry) * time.Second, nil
+}
@@ -0,0 +1,249 @@
+package kms
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// fakeKMS serves the public key and signatures of a key the way the KMS of a
+// provider does, optionally delaying the signatures.
+type fakeKMS struct {
+	key   *ecdsa.PrivateKey
+	delay time.Duration
+}
+
+func (f *fakeKMS) spki() []byte {
+	var spki struct {
+		Algorithm struct {
+			Algorithm  asn1.ObjectIdentifier
+			Parameters asn1.ObjectIdentifier
+		}
+		PublicKey asn1.BitString
+	}
+	spki.Algorithm.Algorithm = asn1.ObjectIdentifier{1, 2, 840, 10045, 2, 1}
+	spki.Algorithm.Parameters = secp256k1OID
+	pub := crypto.FromECDSAPub(&f.key.PublicKey)
+	spki.PublicKey = asn1.BitString{Bytes: pub, BitLength: 8 * len(pub)}
+	der, _ := asn1.Marshal(spki)
+	return der
+}
+
+// signature signs a digest, leaving S in the upper half of the order half of
+// the time as real KMS do.
+func (f *fakeKMS) signature(t *testing.T, digest []byte) (*big.Int, *big.Int) {
+	time.Sleep(f.delay)
+	r, s, err := ecdsa.Sign(rand.Reader, f.key, digest)
+	if err != nil {
+		t.Errorf("failed to sign: %v", err)
+	}
+	return r, s
+}
+
+func (f *fakeKMS) der(t *testing.T, digest []byte) []byte {
+	r, s := f.signature(t, digest)
+	der, _ := asn1.Marshal(struct{ R, S *big.Int }{r, s})
+	return der
+}
+
+func (f *fakeKMS) aws(t *testing.T) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=access/") {
+			http.Error(w, "unauthenticated", http.StatusForbidden)
+			return
+		}
+		var params struct {
+			KeyId   string
+			Message []byte
+		}
+		json.NewDecoder(r.Body).Decode(&params)
+		if params.KeyId != "arn:aws:kms:eu-west-1:111122223333:key/node" {
+			http.Error(w, "unknown key", http.StatusBadRequest)
+			return
+		}
+		switch r.Header.Get("X-Amz-Target") {
+		case "TrentService.GetPublicKey":
+			json.NewEncoder(w).Encode(map[string]interface{}{"PublicKey": f.spki(), "KeySpec": "ECC_SECG_P256K1"})
+		case "TrentService.Sign":
+			json.NewEncoder(w).Encode(map[string]interface{}{"Signature": f.der(t, params.Message)})
+		default:
+			http.Error(w, "unknown action", http.StatusBadRequest)
+		}
+	}
+}
+
+func (f *fakeKMS) gcp(t *testing.T) http.HandlerFunc {
+	const name = "/v1/projects/p/locations/global/keyRings/r/cryptoKeys/node/cryptoKeyVersions/1"
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer gcp-token" {
+			http.Error(w, "unauthenticated", http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case name + "/publicKey":
+			block := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: f.spki()})
+			json.NewEncoder(w).Encode(map[string]string{"pem": string(block)})
+		case name + ":asymmetricSign":
+			var params struct {
+				Digest struct {
+					Sha256 []byte `json:"sha256"`
+				} `json:"digest"`
+			}
+			json.NewDecoder(r.Body).Decode(&params)
+			json.NewEncoder(w).Encode(map[string]interface{}{"signature": f.der(t, params.Digest.Sha256)})
+		default:
+			http.NotFound(w, r)
+		}
+	}
+}
+
+func (f *fakeKMS) azure(t *testing.T) http.HandlerFunc {
+	encode := base64.RawURLEncoding.EncodeToString
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer azure-token" || r.URL.Query().Get("api-version") == "" {
+			http.Error(w, "unauthenticated", http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/keys/node/1":
+			pub := crypto.FromECDSAPub(&f.key.PublicKey)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"key": map[string]string{"kty": "EC-HSM", "crv": "P-256K", "x": encode(pub[1:33]), "y": encode(pub[33:])},
+			})
+		case "/keys/node/1/sign":
+			var params struct {
+				Alg   string `json:"alg"`
+				Value string `json:"value"`
+			}
+			json.NewDecoder(r.Body).Decode(&params)
+			digest, _ := base64.RawURLEncoding.DecodeString(params.Value)
+			rr, s := f.signature(t, digest)
+			sig := make([]byte, 64)
+			copy(sig[32-len(rr.Bytes()):32], rr.Bytes())
+			copy(sig[64-len(s.Bytes()):], s.Bytes())
+			json.NewEncoder(w).Encode(map[string]string{"kid": "node", "value": encode(sig)})
+		default:
+			http.NotFound(w, r)
+		}
+	}
+}
+
+// checkSigner checks that the signatures of a signer are recoverable to the key
+// and in the canonical form.
+func checkSigner(t *testing.T, signer Signer, key *ecdsa.PrivateKey) {
+	for i := 0; i < 8; i++ {
+		hash := crypto.Keccak256([]byte{byte(i)})
+		sig, err := signer.Sign(hash)
+		if err != nil {
+			t.Fatalf("failed to sign: %v", err)
+		}
+		pub, err := crypto.SigToPub(hash, sig)
+		if err != nil {
+			t.Fatalf("unrecoverable signature: %v", err)
+		}
+		if crypto.PubkeyToAddress(*pub) != crypto.PubkeyToAddress(key.PublicKey) {
+			t.Fatalf("signature by the wrong key")
+		}
+		if !crypto.ValidateSignatureValues(sig[64], new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:64]), true) {
+			t.Fatalf("signature not canonical: %x", sig)
+		}
+	}
+}
+
+func TestProviders(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	kms := &fakeKMS{key: key}
+
+	os.Setenv("AWS_ACCESS_KEY_ID", "access")
+	os.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	os.Setenv("GOOGLE_OAUTH_ACCESS_TOKEN", "gcp-token")
+	os.Setenv("AZURE_ACCESS_TOKEN", "azure-token")
+	defer func() {
+		for _, name := range []string{"AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY", "GOOGLE_OAUTH_ACCESS_TOKEN", "AZURE_ACCESS_TOKEN"} {
+			os.Unsetenv(name)
+		}
+	}()
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		url     string
+	}{
+		{"aws", kms.aws(t), "awskms://arn:aws:kms:eu-west-1:111122223333:key/node?endpoint="},
+		{"gcp", kms.gcp(t), "gcpkms://projects/p/locations/global/keyRings/r/cryptoKeys/node/cryptoKeyVersions/1?endpoint="},
+		{"azure", kms.azure(t), "azurekv://node.vault.azure.net/keys/node/1?endpoint="},
+	}
+	peer, _ := crypto.GenerateKey()
+	want, _ := localECDH(peer, &key.PublicKey)
+
+	for _, test := range tests {
+		server := httptest.NewServer(test.handler)
+
+		signer, err := New(Config{URL: test.url + server.URL}, key)
+		if err != nil {
+			t.Fatalf("%s: failed to create signer: %v", test.name, err)
+		}
+		checkSigner(t, signer, key)
+		if secret, err := signer.ECDH(&peer.PublicKey); err != nil || !bytes.Equal(secret, want) {
+			t.Errorf("%s: shared secret mismatch: have %x, %v, want %x", test.name, secret, err, want)
+		}
+		// The node key is required for the handshake and checked
+		other, _ := crypto.GenerateKey()
+		if _, err := New(Config{URL: test.url + server.URL}, nil); err != errNoNodeKey {
+			t.Errorf("%s: missing node key: have %v, want %v", test.name, err, errNoNodeKey)
+		}
+		if _, err := New(Config{URL: test.url + server.URL}, other); err != errKeyMismatch {
+			t.Errorf("%s: foreign node key: have %v, want %v", test.name, err, errKeyMismatch)
+		}
+		if _, err := New(Config{URL: test.url + server.URL, Failover: FailoverLocal}, other); err != errKeyMismatch {
+			t.Errorf("%s: foreign failover key: have %v, want %v", test.name, err, errKeyMismatch)
+		}
+		if _, err := New(Config{URL: test.url + server.URL, Failover: FailoverLocal}, nil); err != errNoNodeKey {
+			t.Errorf("%s: missing failover key: have %v, want %v", test.name, err, errNoNodeKey)
+		}
+		server.Close()
+	}
+}
+
+func TestFailover(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	kms := &fakeKMS{key: key}
+
+	os.Setenv("AZURE_ACCESS_TOKEN", "azure-token")
+	defer os.Unsetenv("AZURE_ACCESS_TOKEN")
+
+	server := httptest.NewServer(kms.azure(t))
+	defer server.Close()
+
+	url := "azurekv://node.vault.azure.net/keys/node/1?endpoint=" + server.URL
+	strict, err := New(Config{URL: url, Budget: 50 * time.Millisecond, Failover: FailoverNone}, key)
+	if err != nil {
+		t.Fatalf("failed to create signer: %v", err)
+	}
+	failover, err := New(Config{URL: url, Budget: 50 * time.Millisecond, Failover: FailoverLocal}, key)
+	if err != nil {
+		t.Fatalf("failed to create signer: %v", err)
+	}
+	// Signatures over budget fail, unless signed with the local key
+	kms.delay = 200 * time.Millisecond
+	if _, err := strict.Sign(crypto.Keccak256(nil)); err == nil {
+		t.Errorf("signature over budget delivered")
+	}
+	checkSigner(t, failover, key)
+
+	if _, err := New(Config{URL: url, Failover: "retry"}, key); err == nil {
+		t.Errorf("unknown failover policy accepted")
+	}
+}
@@ -18,13 +18,16 @@ package les
 
 import (
 	"context"
+	"fmt"
 	"math/big"
 
+	"github.com/ethereum/go-ethereum/abiregistry"
 	"github.com/ethereum/go-ethereum/accounts"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/math"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/bloombits"
+	"github.com/ethereum/go-ethereum/core/logindex"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
@@ -33,14 +36,17 @@ import (
 	"github.com/ethereum/go-ethereum/eth/gasprice"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
 	"github.com/ethereum/go-ethereum/light"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rpc"
 )
 
 type LesApiBackend struct {
-	eth *LightEthereum
-	gpo *gasprice.Oracle
+	eth       *LightEthereum
+	gpo       *gasprice.Oracle
+	finality  uint64            // Number of blocks on top of a block for it to be final
+	nonceLock ethapi.AddrLocker // Serialises the nonce assignment of the transactions sent
 }
 
 func (b *LesApiBackend) ChainConfig() *params.ChainConfig {
@@ -60,6 +66,13 @@ func (b *LesApiBackend) HeaderByNumber(ctx context.Context, blockNr rpc.BlockNum
 	if blockNr == rpc.LatestBlockNumber || blockNr == rpc.PendingBlockNumber {
 		return b.eth.blockchain.CurrentHeader(), nil
 	}
+	if blockNr == rpc.FinalizedBlockNumber {
+		head := b.eth.blockchain.CurrentHeader().Number.Uint64()
+		if head < b.finality {
+			head = b.finality
+		}
+		blockNr = rpc.BlockNumber(head - b.finality)
+	}
 	return b.eth.blockchain.GetHeaderByNumberOdr(ctx, uint64(blockNr))
 }
 
@@ -140,6 +153,10 @@ func (b *LesApiBackend) TxPoolContent() (map[common.Address]types.Transactions,
 	return b.eth.txPool.Content()
 }
 
+func (b *LesApiBackend) CheckTx(tx *types.Transaction) ([]core.TxCheckResult, error) {
+	return nil, fmt.Errorf("not supported")
+}
+
 func (b *LesApiBackend) SubscribeNewTxsEvent(ch chan<- core.NewTxsEvent) event.Subscription {
 	return b.eth.txPool.SubscribeNewTxsEvent(ch)
 }
@@ -188,6 +205,14 @@ func (b *LesApiBackend) AccountManager() *accounts.Manager {
 	return b.eth.accountManager
 }
 
+func (b *LesApiBackend) ResponseCache() *ethapi.ResponseCache {
+	return nil
+}
+
+func (b *LesApiBackend) CallPool() *ethapi.CallPool {
+	return nil
+}
+
 func (b *LesApiBackend) BloomStatus() (uint64, uint64) {
 	if b.eth.bloomIndexer == nil {
 		return 0, 0
@@ -201,3 +226,17 @@ func (b *LesApiBackend) ServiceFilter(ctx context.Context, session *bloombits.Ma
 		go session.Multiplex(bloomRetrievalBatch, bloomRetrievalWait, b.eth.bloomRequests)
 	}
 }
+
+// Quorum
+// Light clients don't hold the receipts, hence can't index the logs.
+func (b *LesApiBackend) LogIndex() *logindex.Index {
+	return nil
+}
+
+func (b *LesApiBackend) ABIRegistry() *abiregistry.Registry {
+	return nil
+}
+
+func (b *LesApiBackend) NonceLock() *ethapi.AddrLocker {
+	return &b.nonceLock
+}
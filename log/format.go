@@ -286,6 +286,86 @@ func JSONFormatEx(pretty, lineSeparated bool) Format {
 	})
 }
 
+// structuredFields are the stable top level fields of the structured JSON
+// format, along with the context keys they are taken from.
+var structuredFields = []struct {
+	name string
+	keys []string
+}{
+	{"component", []string{"component", "module"}},
+	{"peer", []string{"peer", "peerid"}},
+	{"block", []string{"block", "number", "blocknum"}},
+	{"txhash", []string{"txhash", "txHash", "tx"}},
+	{"traceID", []string{"traceID", "traceid", "trace"}},
+}
+
+// StructuredJSONFormat formats log records as JSON objects separated by
+// newlines, with stable field names for log processors to rely on: time, level,
+// msg, component, peer, block, txhash and traceID, the rest of the context
+// being nested under fields. The component defaults to the package logging the
+// record.
+func StructuredJSONFormat() Format {
+	aliases := make(map[string]string)
+	for _, field := range structuredFields {
+		for _, key := range field.keys {
+			aliases[key] = field.name
+		}
+	}
+	return FormatFunc(func(r *Record) []byte {
+		props := map[string]interface{}{
+			"time":  r.Time,
+			"level": r.Lvl.String(),
+			"msg":   r.Msg,
+		}
+		fields := make(map[string]interface{})
+		for i := 0; i < len(r.Ctx); i += 2 {
+			k, ok := r.Ctx[i].(string)
+			if !ok {
+				props[errorKey] = fmt.Sprintf("%+v is not a string key", r.Ctx[i])
+				continue
+			}
+			if name, ok := aliases[k]; ok {
+				if _, set := props[name]; !set {
+					props[name] = formatJSONValue(r.Ctx[i+1])
+					continue
+				}
+			}
+			fields[k] = formatJSONValue(r.Ctx[i+1])
+		}
+		if _, ok := props["component"]; !ok {
+			if component := recordComponent(r); component != "" {
+				props["component"] = component
+			}
+		}
+		if len(fields) > 0 {
+			props["fields"] = fields
+		}
+		b, err := json.Marshal(props)
+		if err != nil {
+			b, _ = json.Marshal(map[string]string{
+				errorKey: err.Error(),
+			})
+		}
+		return append(b, '\n')
+	})
+}
+
+// recordComponent returns the package of the call site of a record, relative to
+// the go-ethereum root.
+func recordComponent(r *Record) string {
+	if r.Call.PC() == 0 {
+		return ""
+	}
+	file := fmt.Sprintf("%+s", r.Call)
+	for _, prefix := range locationTrims {
+		file = strings.TrimPrefix(file, prefix)
+	}
+	if i := strings.LastIndex(file, "/"); i >= 0 {
+		return file[:i]
+	}
+	return ""
+}
+
 func formatShared(value interface{}) (result interface{}) {
 	defer func() {
 		if err := recover(); err != nil {
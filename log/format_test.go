@@ -0,0 +1,51 @@
+package log
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/go-stack/stack"
+)
+
+func TestStructuredJSONFormat(t *testing.T) {
+	r := &Record{
+		Time: time.Unix(0, 0).UTC(),
+		Lvl:  LvlWarn,
+		Msg:  "Imported block",
+		Ctx:  []interface{}{"number", 5, "txHash", "0x01", "peer", "abc", "elapsed", "1s", "block", 6},
+		Call: stack.Caller(0),
+	}
+	var have map[string]interface{}
+	if err := json.Unmarshal(StructuredJSONFormat().Format(r), &have); err != nil {
+		t.Fatalf("invalid JSON record: %v", err)
+	}
+	want := map[string]interface{}{
+		"time":      "1970-01-01T00:00:00Z",
+		"level":     "warn",
+		"msg":       "Imported block",
+		"block":     float64(5),
+		"txhash":    "0x01",
+		"peer":      "abc",
+		"component": "log",
+	}
+	for key, value := range want {
+		if have[key] != value {
+			t.Errorf("field %s mismatch: have %v, want %v", key, have[key], value)
+		}
+	}
+	// The other keys, and the aliases of fields already set, are nested
+	fields, _ := have["fields"].(map[string]interface{})
+	if fields["elapsed"] != "1s" || fields["block"] != float64(6) {
+		t.Errorf("nested fields mismatch: have %v", have["fields"])
+	}
+	// An explicit component wins over the logging package
+	r.Ctx = []interface{}{"module", "p2p"}
+	have = nil
+	if err := json.Unmarshal(StructuredJSONFormat().Format(r), &have); err != nil {
+		t.Fatalf("invalid JSON record: %v", err)
+	}
+	if have["component"] != "p2p" || have["fields"] != nil {
+		t.Errorf("component mismatch: have %v", have)
+	}
+}
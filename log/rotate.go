@@ -0,0 +1,204 @@
+package log
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// backupTimeFormat names the rotated log files after the time of their rotation.
+const backupTimeFormat = "2006-01-02T15-04-05.000"
+
+// RotateConfig configures the rotation of a log file. The file is rotated once
+// it reaches the maximum size or age, whichever comes first.
+type RotateConfig struct {
+	MaxSize    uint64        // Size in bytes a file is rotated at (0 = unlimited)
+	MaxAge     time.Duration // Age a file is rotated at (0 = unlimited)
+	MaxBackups int           // Number of rotated files kept, the oldest ones being deleted (0 = all)
+	Compress   bool          // Whether to gzip the rotated files
+}
+
+// RotatingWriter is a log file rotated according to its configuration. The
+// rotated files are renamed after the time of their rotation, next to the log
+// file, and compressed in the background if requested.
+type RotatingWriter struct {
+	path   string
+	config RotateConfig
+
+	lock   sync.Mutex
+	file   *os.File
+	size   uint64
+	opened time.Time
+
+	cleanup sync.Mutex // Serializes the compression and pruning of the rotated files
+}
+
+// NewRotatingWriter opens the log file at the given path, appending to it if it
+// already exists.
+func NewRotatingWriter(path string, config RotateConfig) (*RotatingWriter, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	f, size, err := openLogFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return &RotatingWriter{path: path, config: config, file: f, size: size, opened: time.Now()}, nil
+}
+
+// openLogFile opens a log file for appending, returning its current size.
+func openLogFile(path string) (*os.File, uint64, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, 0, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, 0, err
+	}
+	return f, uint64(info.Size()), nil
+}
+
+// Write implements io.Writer, rotating the log file beforehand if writing to it
+// would exceed its limits.
+func (w *RotatingWriter) Write(p []byte) (int, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.file == nil {
+		return 0, os.ErrClosed
+	}
+	if w.size > 0 && ((w.config.MaxSize > 0 && w.size+uint64(len(p)) > w.config.MaxSize) ||
+		(w.config.MaxAge > 0 && time.Since(w.opened) >= w.config.MaxAge)) {
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := w.file.Write(p)
+	w.size += uint64(n)
+	return n, err
+}
+
+// Rotate rotates the log file right away.
+func (w *RotatingWriter) Rotate() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.file == nil {
+		return os.ErrClosed
+	}
+	return w.rotate()
+}
+
+// rotate renames the log file after the current time and opens a new one. The
+// current file is only closed once the new one is open, so that a failed
+// rotation leaves the log writing to it.
+func (w *RotatingWriter) rotate() error {
+	backup := w.path + "." + time.Now().Format(backupTimeFormat)
+	if err := os.Rename(w.path, backup); err != nil {
+		return err
+	}
+	f, size, err := openLogFile(w.path)
+	if err != nil {
+		os.Rename(backup, w.path)
+		return err
+	}
+	old := w.file
+	w.file, w.size, w.opened = f, size, time.Now()
+	if err := old.Close(); err != nil {
+		os.Stderr.WriteString("Failed to close rotated log file " + backup + ": " + err.Error() + "\n")
+	}
+	go w.clean(backup)
+	return nil
+}
+
+// clean compresses a rotated file if requested, and deletes the rotated files
+// exceeding the number kept.
+func (w *RotatingWriter) clean(backup string) {
+	w.cleanup.Lock()
+	defer w.cleanup.Unlock()
+
+	if w.config.Compress {
+		if err := compressFile(backup); err != nil {
+			// The log can't be logged to, report on the standard error instead
+			os.Stderr.WriteString("Failed to compress rotated log file " + backup + ": " + err.Error() + "\n")
+		}
+	}
+	if w.config.MaxBackups <= 0 {
+		return
+	}
+	backups, err := filepath.Glob(w.path + ".*")
+	if err != nil {
+		return
+	}
+	// Leave out the files being compressed, the names sort in rotation order
+	kept := backups[:0]
+	for _, name := range backups {
+		if !strings.HasSuffix(name, ".gz.tmp") {
+			kept = append(kept, name)
+		}
+	}
+	sort.Strings(kept)
+	for len(kept) > w.config.MaxBackups {
+		os.Remove(kept[0])
+		kept = kept[1:]
+	}
+}
+
+// Close implements io.Closer, closing the log file.
+func (w *RotatingWriter) Close() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.file == nil {
+		return nil
+	}
+	err := w.file.Close()
+	w.file = nil
+	return err
+}
+
+// compressFile gzips a file, replacing it with the compressed one.
+func compressFile(path string) error {
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(path+".gz.tmp", os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(out)
+	if _, err = io.Copy(gz, in); err == nil {
+		err = gz.Close()
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path + ".gz.tmp")
+		return err
+	}
+	if err := os.Rename(path+".gz.tmp", path+".gz"); err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+// RotatingFileHandlerEx returns a handler which writes log records to the file
+// at the given path, rotating it according to the configuration.
+func RotatingFileHandlerEx(path string, config RotateConfig, fmtr Format) (Handler, error) {
+	w, err := NewRotatingWriter(path, config)
+	if err != nil {
+		return nil, err
+	}
+	return closingHandler{w, StreamHandler(w, fmtr)}, nil
+}
@@ -0,0 +1,142 @@
+package log
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// waitBackups waits for the rotated files of a log to match the given count,
+// the cleanup running in the background.
+func waitBackups(t *testing.T, path string, count int) []string {
+	for start := time.Now(); ; time.Sleep(10 * time.Millisecond) {
+		backups, _ := filepath.Glob(path + ".*")
+		if len(backups) == count {
+			return backups
+		}
+		if time.Since(start) > 5*time.Second {
+			t.Fatalf("rotated files mismatch: have %v, want %d", backups, count)
+		}
+	}
+}
+
+func TestRotatingWriterSize(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rotate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "geth.log")
+	w, err := NewRotatingWriter(path, RotateConfig{MaxSize: 10})
+	if err != nil {
+		t.Fatalf("failed to open log: %v", err)
+	}
+	defer w.Close()
+
+	for _, line := range []string{"first\n", "second\n"} {
+		if _, err := w.Write([]byte(line)); err != nil {
+			t.Fatalf("failed to write: %v", err)
+		}
+	}
+	backups := waitBackups(t, path, 1)
+	if data, _ := ioutil.ReadFile(backups[0]); string(data) != "first\n" {
+		t.Errorf("rotated file mismatch: have %q", data)
+	}
+	if data, _ := ioutil.ReadFile(path); string(data) != "second\n" {
+		t.Errorf("log file mismatch: have %q", data)
+	}
+}
+
+func TestRotatingWriterBackups(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rotate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "geth.log")
+	w, err := NewRotatingWriter(path, RotateConfig{MaxBackups: 2, Compress: true})
+	if err != nil {
+		t.Fatalf("failed to open log: %v", err)
+	}
+	defer w.Close()
+
+	for i := 0; i < 4; i++ {
+		w.Write([]byte{'0' + byte(i), '\n'})
+		if err := w.Rotate(); err != nil {
+			t.Fatalf("failed to rotate: %v", err)
+		}
+		// The rotated files are named after the millisecond of their rotation
+		time.Sleep(5 * time.Millisecond)
+	}
+	// Only the two most recent rotated files are kept, compressed
+	for start := time.Now(); ; time.Sleep(10 * time.Millisecond) {
+		contents, err := readBackups(path)
+		if err == nil && len(contents) == 2 && contents[0] == "2\n" && contents[1] == "3\n" {
+			break
+		}
+		if time.Since(start) > 5*time.Second {
+			t.Fatalf("rotated files mismatch: have %q, %v", contents, err)
+		}
+	}
+}
+
+// readBackups decompresses the rotated files of a log, in rotation order.
+func readBackups(path string) ([]string, error) {
+	backups, err := filepath.Glob(path + ".*")
+	if err != nil {
+		return nil, err
+	}
+	var contents []string
+	for _, backup := range backups {
+		if !strings.HasSuffix(backup, ".gz") {
+			return nil, fmt.Errorf("rotated file %s not compressed", backup)
+		}
+		f, err := os.Open(backup)
+		if err != nil {
+			return nil, err
+		}
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		data, err := ioutil.ReadAll(gz)
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+		contents = append(contents, string(data))
+	}
+	return contents, nil
+}
+
+// Tests that a failed rotation leaves the log writing to the current file.
+func TestRotatingWriterFailedRotate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rotate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	w, err := NewRotatingWriter(filepath.Join(dir, "geth.log"), RotateConfig{})
+	if err != nil {
+		t.Fatalf("failed to open log: %v", err)
+	}
+	defer w.Close()
+
+	// The open file outlives its directory, which can't be rotated in anymore
+	os.RemoveAll(dir)
+	if err := w.Rotate(); err == nil {
+		t.Fatalf("rotation succeeded without directory")
+	}
+	if _, err := w.Write([]byte("still logging\n")); err != nil {
+		t.Errorf("failed to write after a failed rotation: %v", err)
+	}
+}
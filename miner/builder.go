@@ -0,0 +1,286 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// DefaultBuilderBudget is the time a block builder is given to order the
+// candidate transactions of a block if not configured.
+const DefaultBuilderBudget = 200 * time.Millisecond
+
+var (
+	builderTimer    = metrics.NewRegisteredTimer("miner/builder/order", nil)
+	builderFallback = metrics.NewRegisteredCounter("miner/builder/fallback", nil)
+
+	errBuilderTimeout = errors.New("block builder exceeded its time budget")
+)
+
+// TransactionSource yields the transactions to commit to a block, in order.
+type TransactionSource interface {
+	// Peek returns the next transaction, nil when done.
+	Peek() *types.Transaction
+
+	// Shift moves to the transaction following the peeked one.
+	Shift()
+
+	// Pop drops the remaining transactions of the sender of the peeked one.
+	Pop()
+}
+
+// BlockBuilder reorders or filters the candidate transactions of a block, for
+// instance to enforce a fair ordering by arrival time attested by the
+// validators. The builder is given a strict time budget, after which the block
+// is built from the candidates in the default order.
+type BlockBuilder interface {
+	// Order returns the transactions to include in the block built on parent, in
+	// order. It may drop candidates but must not add any, and should keep the
+	// transactions of a sender in nonce order.
+	Order(ctx context.Context, parent *types.Header, candidates []*types.Transaction) ([]*types.Transaction, error)
+}
+
+// builderHook holds the block builder shared by the block producers.
+type builderHook struct {
+	lock    sync.RWMutex
+	builder BlockBuilder
+	budget  time.Duration
+}
+
+// set replaces the block builder, nil to build blocks in the default order.
+func (h *builderHook) set(builder BlockBuilder, budget time.Duration) {
+	if budget <= 0 {
+		budget = DefaultBuilderBudget
+	}
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	h.builder, h.budget = builder, budget
+}
+
+// order has the block builder order the transactions of the given sources. The
+// sources keep their precedence, the priority lanes and the local transactions
+// ahead of the remote ones, the builder only ordering the transactions within
+// each of them. It returns the sources unchanged if no builder is set, and the
+// candidates in the default order if the builder fails.
+func (h *builderHook) order(parent *types.Header, signer types.Signer, sources []TransactionSource) []TransactionSource {
+	h.lock.RLock()
+	builder, budget := h.builder, h.budget
+	h.lock.RUnlock()
+
+	if builder == nil {
+		return sources
+	}
+	var (
+		candidates []*types.Transaction
+		origin     = make(map[common.Hash]int) // Source index of each candidate
+	)
+	for i, source := range sources {
+		for tx := source.Peek(); tx != nil; tx = source.Peek() {
+			candidates = append(candidates, tx)
+			origin[tx.Hash()] = i
+			source.Shift()
+		}
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+	start := time.Now()
+	ordered, err := callBuilder(builder, budget, parent, candidates)
+	if err == nil {
+		err = checkOrder(candidates, ordered)
+	}
+	builderTimer.UpdateSince(start)
+
+	if err != nil {
+		builderFallback.Inc(1)
+		log.Warn("Block builder failed, using default order", "number", new(big.Int).Add(parent.Number, common.Big1), "candidates", len(candidates), "err", err)
+		ordered = candidates
+	}
+	// Restore the precedence of the sources, keeping the order of the builder
+	// within each of them
+	classes := make([][]*types.Transaction, len(sources))
+	for _, tx := range ordered {
+		i := origin[tx.Hash()]
+		classes[i] = append(classes[i], tx)
+	}
+	var ordereds []TransactionSource
+	for _, txs := range classes {
+		if len(txs) > 0 {
+			ordereds = append(ordereds, newOrderedTransactions(signer, txs))
+		}
+	}
+	return ordereds
+}
+
+// callBuilder runs the block builder within its budget, abandoning it once the
+// budget is over even if it doesn't honour the context.
+func callBuilder(builder BlockBuilder, budget time.Duration, parent *types.Header, candidates []*types.Transaction) ([]*types.Transaction, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), budget)
+	defer cancel()
+
+	type result struct {
+		txs []*types.Transaction
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		txs, err := builder.Order(ctx, parent, append([]*types.Transaction{}, candidates...))
+		done <- result{txs, err}
+	}()
+	select {
+	case res := <-done:
+		return res.txs, res.err
+	case <-ctx.Done():
+		return nil, errBuilderTimeout
+	}
+}
+
+// checkOrder checks that the block builder only kept candidates, once each.
+func checkOrder(candidates, ordered []*types.Transaction) error {
+	known := make(map[common.Hash]bool, len(candidates))
+	for _, tx := range candidates {
+		known[tx.Hash()] = true
+	}
+	for _, tx := range ordered {
+		hash := tx.Hash()
+		seen, ok := known[hash]
+		if !ok {
+			return fmt.Errorf("block builder added transaction %x", hash)
+		}
+		if !seen {
+			return fmt.Errorf("block builder duplicated transaction %x", hash)
+		}
+		known[hash] = false
+	}
+	return nil
+}
+
+// orderedTransactions is a transaction source following a fixed order.
+type orderedTransactions struct {
+	signer  types.Signer
+	txs     []*types.Transaction
+	dropped map[common.Address]bool // Senders whose remaining transactions are dropped
+}
+
+func newOrderedTransactions(signer types.Signer, txs []*types.Transaction) *orderedTransactions {
+	return &orderedTransactions{signer: signer, txs: txs, dropped: make(map[common.Address]bool)}
+}
+
+// Peek returns the next transaction, skipping the dropped senders.
+func (o *orderedTransactions) Peek() *types.Transaction {
+	for len(o.txs) > 0 {
+		if from, _ := types.Sender(o.signer, o.txs[0]); !o.dropped[from] {
+			return o.txs[0]
+		}
+		o.txs = o.txs[1:]
+	}
+	return nil
+}
+
+// Shift moves to the next transaction.
+func (o *orderedTransactions) Shift() {
+	if len(o.txs) > 0 {
+		o.txs = o.txs[1:]
+	}
+}
+
+// Pop drops the remaining transactions of the sender of the next transaction,
+// as they would have a nonce gap.
+func (o *orderedTransactions) Pop() {
+	if len(o.txs) > 0 {
+		from, _ := types.Sender(o.signer, o.txs[0])
+		o.dropped[from] = true
+		o.txs = o.txs[1:]
+	}
+}
+
+// builderCandidate is the JSON encoding of a candidate transaction sent to an
+// external block builder.
+type builderCandidate struct {
+	Hash    common.Hash    `json:"hash"`
+	From    common.Address `json:"from"`
+	Nonce   hexutil.Uint64 `json:"nonce"`
+	Raw     hexutil.Bytes  `json:"raw"`
+	Private bool           `json:"private"`
+}
+
+// RPCBlockBuilder orders the candidate transactions through the
+// builder_orderTransactions method of an external JSON-RPC service, which is
+// given the parent header and the candidates and returns the hashes of the
+// transactions to include, in order.
+type RPCBlockBuilder struct {
+	client *rpc.Client
+	signer func(number *big.Int) types.Signer
+}
+
+// NewRPCBlockBuilder connects to an external block builder.
+func NewRPCBlockBuilder(endpoint string, signer func(number *big.Int) types.Signer) (*RPCBlockBuilder, error) {
+	client, err := rpc.Dial(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	return &RPCBlockBuilder{client: client, signer: signer}, nil
+}
+
+// Order implements BlockBuilder.
+func (b *RPCBlockBuilder) Order(ctx context.Context, parent *types.Header, candidates []*types.Transaction) ([]*types.Transaction, error) {
+	signer := b.signer(new(big.Int).Add(parent.Number, common.Big1))
+
+	byHash := make(map[common.Hash]*types.Transaction, len(candidates))
+	encoded := make([]builderCandidate, len(candidates))
+	for i, tx := range candidates {
+		raw, err := rlp.EncodeToBytes(tx)
+		if err != nil {
+			return nil, err
+		}
+		from, _ := types.Sender(signer, tx)
+		encoded[i] = builderCandidate{Hash: tx.Hash(), From: from, Nonce: hexutil.Uint64(tx.Nonce()), Raw: raw, Private: tx.IsPrivate()}
+		byHash[tx.Hash()] = tx
+	}
+	var hashes []common.Hash
+	if err := b.client.CallContext(ctx, &hashes, "builder_orderTransactions", parent, encoded); err != nil {
+		return nil, err
+	}
+	ordered := make([]*types.Transaction, 0, len(hashes))
+	for _, hash := range hashes {
+		tx, ok := byHash[hash]
+		if !ok {
+			return nil, fmt.Errorf("block builder returned unknown transaction %x", hash)
+		}
+		ordered = append(ordered, tx)
+	}
+	return ordered, nil
+}
+
+// Close disconnects from the external block builder.
+func (b *RPCBlockBuilder) Close() {
+	b.client.Close()
+}
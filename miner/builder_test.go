@@ -0,0 +1,223 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// builderFunc adapts a function to the BlockBuilder interface.
+type builderFunc func(ctx context.Context, parent *types.Header, candidates []*types.Transaction) ([]*types.Transaction, error)
+
+func (f builderFunc) Order(ctx context.Context, parent *types.Header, candidates []*types.Transaction) ([]*types.Transaction, error) {
+	return f(ctx, parent, candidates)
+}
+
+// builderTestSources creates the pending transactions of two senders, three
+// each, returning them along with the senders and the signer.
+func builderTestSources(t *testing.T) (func() []TransactionSource, []*types.Transaction, types.Signer) {
+	signer := types.HomesteadSigner{}
+	var txs []*types.Transaction
+	pending := make(map[common.Address]types.Transactions)
+	for i := 0; i < 2; i++ {
+		key, _ := crypto.GenerateKey()
+		from := crypto.PubkeyToAddress(key.PublicKey)
+		for nonce := uint64(0); nonce < 3; nonce++ {
+			tx, err := types.SignTx(types.NewTransaction(nonce, common.Address{}, big.NewInt(0), 21000, big.NewInt(int64(1+i)), nil), signer, key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			pending[from] = append(pending[from], tx)
+			txs = append(txs, tx)
+		}
+	}
+	sources := func() []TransactionSource {
+		copied := make(map[common.Address]types.Transactions)
+		for from, txs := range pending {
+			copied[from] = txs
+		}
+		return []TransactionSource{types.NewTransactionsByPriceAndNonce(signer, copied)}
+	}
+	return sources, txs, signer
+}
+
+func drain(sources []TransactionSource) []*types.Transaction {
+	var txs []*types.Transaction
+	for _, source := range sources {
+		for tx := source.Peek(); tx != nil; tx = source.Peek() {
+			txs = append(txs, tx)
+			source.Shift()
+		}
+	}
+	return txs
+}
+
+func sameOrder(have, want []*types.Transaction) bool {
+	if len(have) != len(want) {
+		return false
+	}
+	for i := range have {
+		if have[i].Hash() != want[i].Hash() {
+			return false
+		}
+	}
+	return true
+}
+
+// Tests that the block builder orders the candidates, and that the default order
+// is kept if it fails, misbehaves or exceeds its budget.
+func TestBuilderHookOrder(t *testing.T) {
+	sources, txs, signer := builderTestSources(t)
+	parent := &types.Header{Number: big.NewInt(1)}
+
+	// Without builder the sources are untouched
+	var hook builderHook
+	defaultOrder := drain(hook.order(parent, signer, sources()))
+
+	// The lower priced sender first, the last transaction dropped
+	wanted := []*types.Transaction{txs[0], txs[1], txs[2], txs[3], txs[4]}
+	hook.set(builderFunc(func(ctx context.Context, parent *types.Header, candidates []*types.Transaction) ([]*types.Transaction, error) {
+		return wanted, nil
+	}), time.Second)
+	if have := drain(hook.order(parent, signer, sources())); !sameOrder(have, wanted) {
+		t.Errorf("builder order not applied")
+	}
+	tests := []struct {
+		name    string
+		builder builderFunc
+	}{
+		{"slow", func(ctx context.Context, parent *types.Header, candidates []*types.Transaction) ([]*types.Transaction, error) {
+			time.Sleep(time.Second)
+			return wanted, nil
+		}},
+		{"added", func(ctx context.Context, parent *types.Header, candidates []*types.Transaction) ([]*types.Transaction, error) {
+			extra := types.NewTransaction(9, common.Address{}, big.NewInt(0), 21000, big.NewInt(1), nil)
+			return append(candidates, extra), nil
+		}},
+		{"duplicated", func(ctx context.Context, parent *types.Header, candidates []*types.Transaction) ([]*types.Transaction, error) {
+			return append(candidates, candidates[0]), nil
+		}},
+	}
+	for _, tt := range tests {
+		hook.set(tt.builder, 50*time.Millisecond)
+		if have := drain(hook.order(parent, signer, sources())); !sameOrder(have, defaultOrder) {
+			t.Errorf("%s builder: default order not restored", tt.name)
+		}
+	}
+}
+
+// Tests that the block builder orders the transactions within the priority
+// lanes and the local and remote classes, never across them.
+func TestBuilderHookPrecedence(t *testing.T) {
+	signer := types.HomesteadSigner{}
+	var (
+		txs     []*types.Transaction
+		pending []map[common.Address]types.Transactions
+	)
+	for i := 0; i < 3; i++ {
+		key, _ := crypto.GenerateKey()
+		tx, err := types.SignTx(types.NewTransaction(0, common.Address{}, big.NewInt(0), 21000, big.NewInt(1), nil), signer, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		txs = append(txs, tx)
+		pending = append(pending, map[common.Address]types.Transactions{crypto.PubkeyToAddress(key.PublicKey): {tx}})
+	}
+	// The lane, local and remote sources, the builder reversing them
+	var sources []TransactionSource
+	for _, txs := range pending {
+		sources = append(sources, types.NewTransactionsByPriceAndNonce(signer, txs))
+	}
+	var hook builderHook
+	hook.set(builderFunc(func(ctx context.Context, parent *types.Header, candidates []*types.Transaction) ([]*types.Transaction, error) {
+		reversed := make([]*types.Transaction, 0, len(candidates))
+		for i := len(candidates) - 1; i >= 0; i-- {
+			reversed = append(reversed, candidates[i])
+		}
+		return reversed, nil
+	}), time.Second)
+
+	if have := drain(hook.order(&types.Header{Number: big.NewInt(1)}, signer, sources)); !sameOrder(have, txs) {
+		t.Errorf("builder reordered the transactions across their sources")
+	}
+}
+
+// Tests that popping a transaction of an ordered source drops the remaining
+// transactions of its sender.
+func TestOrderedTransactionsPop(t *testing.T) {
+	_, txs, signer := builderTestSources(t)
+
+	ordered := newOrderedTransactions(signer, []*types.Transaction{txs[0], txs[3], txs[1], txs[4], txs[2]})
+	ordered.Pop()
+	var have []*types.Transaction
+	for tx := ordered.Peek(); tx != nil; tx = ordered.Peek() {
+		have = append(have, tx)
+		ordered.Shift()
+	}
+	if !sameOrder(have, []*types.Transaction{txs[3], txs[4]}) {
+		t.Errorf("transactions of the popped sender not dropped")
+	}
+}
+
+// BuilderTestCandidate is the part of a candidate transaction the test builder
+// reads.
+type BuilderTestCandidate struct {
+	Hash common.Hash `json:"hash"`
+}
+
+// BuilderTestService is an external block builder serving the transactions in
+// reverse order.
+type BuilderTestService struct{}
+
+func (BuilderTestService) OrderTransactions(parent *types.Header, candidates []BuilderTestCandidate) []common.Hash {
+	hashes := make([]common.Hash, len(candidates))
+	for i, c := range candidates {
+		hashes[len(candidates)-1-i] = c.Hash
+	}
+	return hashes
+}
+
+// Tests that the transactions are ordered by an external builder over JSON-RPC.
+func TestRPCBlockBuilder(t *testing.T) {
+	_, txs, signer := builderTestSources(t)
+
+	server := rpc.NewServer()
+	if err := server.RegisterName("builder", BuilderTestService{}); err != nil {
+		t.Fatal(err)
+	}
+	defer server.Stop()
+
+	builder := &RPCBlockBuilder{client: rpc.DialInProc(server), signer: func(*big.Int) types.Signer { return signer }}
+	defer builder.Close()
+
+	parent := &types.Header{Number: big.NewInt(1), Difficulty: big.NewInt(1), Time: big.NewInt(1)}
+	ordered, err := builder.Order(context.Background(), parent, txs[:3])
+	if err != nil {
+		t.Fatalf("failed to order transactions: %v", err)
+	}
+	if !sameOrder(ordered, []*types.Transaction{txs[2], txs[1], txs[0]}) {
+		t.Errorf("external order not applied")
+	}
+}
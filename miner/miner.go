@@ -152,6 +152,20 @@ func (self *Miner) SetRecommitInterval(interval time.Duration) {
 	self.worker.setRecommitInterval(interval)
 }
 
+// SetBlockBuilder sets the block builder reordering or filtering the candidate
+// transactions of the blocks produced, given the time budget of each call. A nil
+// builder restores the default order.
+func (self *Miner) SetBlockBuilder(builder BlockBuilder, budget time.Duration) {
+	self.worker.builder.set(builder, budget)
+}
+
+// OrderTransactions has the block builder, if any, order the candidate
+// transactions of the block built on parent, for block producers outside of the
+// miner.
+func (self *Miner) OrderTransactions(parent *types.Header, signer types.Signer, sources []TransactionSource) []TransactionSource {
+	return self.worker.builder.order(parent, signer, sources)
+}
+
 // Pending returns the currently pending block and associated state.
 func (self *Miner) Pending() (*types.Block, *state.StateDB, *state.StateDB) {
 	return self.worker.pending()
@@ -95,6 +95,9 @@ type environment struct {
 	privateReceipts []*types.Receipt
 	// Leave this publicState named state, add privateState which most code paths can just ignore
 	privateState *state.StateDB
+
+	quota    *types.OrgQuotaUsage // block space used by each permissioned organization
+	accessed state.StateAccesses  // public state accessed by the public transactions
 }
 
 // task contains all information for consensus engine sealing and result submitting.
@@ -139,6 +142,8 @@ type worker struct {
 	gasFloor uint64
 	gasCeil  uint64
 
+	builder builderHook // Block builder reordering or filtering the candidate transactions
+
 	// Subscriptions
 	mux          *event.TypeMux
 	txsCh        chan core.NewTxsEvent
@@ -665,6 +670,7 @@ func (w *worker) makeCurrent(parent *types.Block, header *types.Header) error {
 		uncles:       mapset.NewSet(),
 		header:       header,
 		privateState: privateState,
+		quota:        types.NewOrgQuotaUsage(),
 	}
 
 	// when 08 is processed ancestors contain 07 (quick block)
@@ -735,6 +741,12 @@ func (w *worker) updateSnapshot() {
 }
 
 func (w *worker) commitTransaction(tx *types.Transaction, coinbase common.Address) ([]*types.Log, error) {
+	// Quorum - bound the public state accessed by the public transactions of the
+	// block, checking the transaction on a copy of the state before applying it
+	accessed, err := core.CheckStateAccessLimits(w.config, w.chain, &coinbase, w.current.gasPool, w.current.state, w.current.header, tx, w.current.accessed, vm.Config{})
+	if err != nil {
+		return nil, err
+	}
 	snap := w.current.state.Snapshot()
 	privateSnap := w.current.privateState.Snapshot()
 
@@ -744,6 +756,7 @@ func (w *worker) commitTransaction(tx *types.Transaction, coinbase common.Addres
 		w.current.privateState.RevertToSnapshot(privateSnap)
 		return nil, err
 	}
+	w.current.accessed = accessed
 	w.current.txs = append(w.current.txs, tx)
 	w.current.receipts = append(w.current.receipts, receipt)
 
@@ -755,7 +768,7 @@ func (w *worker) commitTransaction(tx *types.Transaction, coinbase common.Addres
 	return logs, nil
 }
 
-func (w *worker) commitTransactions(txs *types.TransactionsByPriceAndNonce, coinbase common.Address, interrupt *int32) bool {
+func (w *worker) commitTransactions(txs TransactionSource, coinbase common.Address, interrupt *int32) bool {
 	// Short circuit if current is nil
 	if w.current == nil {
 		return true
@@ -811,10 +824,17 @@ func (w *worker) commitTransactions(txs *types.TransactionsByPriceAndNonce, coin
 			txs.Pop()
 			continue
 		}
+		// Quorum - skip organizations that used up their block quota
+		if !w.current.quota.Allow(from, tx.Gas()) {
+			log.Trace("Organization quota exceeded for current block", "sender", from)
+			txs.Pop()
+			continue
+		}
 		// Start executing the transaction
 		w.current.state.Prepare(tx.Hash(), common.Hash{}, w.current.tcount)
 		w.current.privateState.Prepare(tx.Hash(), common.Hash{}, w.current.tcount)
 
+		gas := w.current.gasPool.Gas()
 		logs, err := w.commitTransaction(tx, coinbase)
 		switch err {
 		case core.ErrGasLimitReached:
@@ -822,6 +842,11 @@ func (w *worker) commitTransactions(txs *types.TransactionsByPriceAndNonce, coin
 			log.Trace("Gas limit exceeded for current block", "sender", from)
 			txs.Pop()
 
+		case core.ErrStateAccessLimitReached:
+			// Pop the transaction accessing too much state, smaller ones may still fit
+			log.Trace("State access limit exceeded for current block", "sender", from)
+			txs.Pop()
+
 		case core.ErrNonceTooLow:
 			// New head notification data race between the transaction pool and miner, shift
 			log.Trace("Skipping transaction with low nonce", "sender", from, "nonce", tx.Nonce())
@@ -836,6 +861,7 @@ func (w *worker) commitTransactions(txs *types.TransactionsByPriceAndNonce, coin
 			// Everything ok, collect the logs and shift in the next transaction from the same account
 			coalescedLogs = append(coalescedLogs, logs...)
 			w.current.tcount++
+			w.current.quota.Record(from, gas-w.current.gasPool.Gas())
 			txs.Shift()
 
 		default:
@@ -931,6 +957,11 @@ func (w *worker) commitNewWork(interrupt *int32, noempty bool, timestamp int64)
 	if w.config.DAOForkSupport && w.config.DAOForkBlock != nil && w.config.DAOForkBlock.Cmp(header.Number) == 0 {
 		misc.ApplyDAOHardFork(env.state)
 	}
+	// Quorum
+	if w.config.IsDeterministicDeploymentBlock(header.Number) {
+		misc.ApplyDeterministicDeployment(env.state)
+		misc.ApplyDeterministicDeployment(env.privateState)
+	}
 	// Accumulate the uncles for the current block
 	uncles := make([]*types.Header, 0, 2)
 	commitUncles := func(blocks map[common.Hash]*types.Block) {
@@ -963,16 +994,28 @@ func (w *worker) commitNewWork(interrupt *int32, noempty bool, timestamp int64)
 	}
 
 	// Fill the block with all available pending transactions.
-	pending, err := w.eth.TxPool().Pending()
+	lanes, err := w.eth.TxPool().PendingLanes()
 	if err != nil {
 		log.Error("Failed to fetch pending transactions", "err", err)
 		return
 	}
 	// Short circuit if there is no available pending transactions
-	if len(pending) == 0 {
+	empty := true
+	for _, txs := range lanes {
+		empty = empty && len(txs) == 0
+	}
+	if empty {
 		w.updateSnapshot()
 		return
 	}
+	// Commit the priority lanes ahead of any bulk traffic
+	pending := lanes[len(lanes)-1]
+	var sources []TransactionSource
+	for _, txs := range lanes[:len(lanes)-1] {
+		if len(txs) > 0 {
+			sources = append(sources, types.NewTransactionsByPriceAndNonce(w.current.signer, txs))
+		}
+	}
 	// Split the pending transactions into locals and remotes
 	localTxs, remoteTxs := make(map[common.Address]types.Transactions), pending
 	for _, account := range w.eth.TxPool().Locals() {
@@ -982,13 +1025,13 @@ func (w *worker) commitNewWork(interrupt *int32, noempty bool, timestamp int64)
 		}
 	}
 	if len(localTxs) > 0 {
-		txs := types.NewTransactionsByPriceAndNonce(w.current.signer, localTxs)
-		if w.commitTransactions(txs, w.coinbase, interrupt) {
-			return
-		}
+		sources = append(sources, types.NewTransactionsByPriceAndNonce(w.current.signer, localTxs))
 	}
 	if len(remoteTxs) > 0 {
-		txs := types.NewTransactionsByPriceAndNonce(w.current.signer, remoteTxs)
+		sources = append(sources, types.NewTransactionsByPriceAndNonce(w.current.signer, remoteTxs))
+	}
+	// Let the block builder, if any, reorder or filter the candidates
+	for _, txs := range w.builder.order(parent.Header(), w.current.signer, sources) {
 		if w.commitTransactions(txs, w.coinbase, interrupt) {
 			return
 		}
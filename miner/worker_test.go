@@ -453,3 +453,46 @@ func testAdjustInterval(t *testing.T, chainConfig *params.ChainConfig, engine co
 		t.Error("interval reset timeout")
 	}
 }
+
+// Tests that the public transactions making the block exceed the state access
+// limits are skipped, leaving the block being built untouched.
+func TestStateAccessLimits(t *testing.T) {
+	var (
+		db       = ethdb.NewMemDatabase()
+		config   = *params.TestChainConfig
+		contract = common.HexToAddress("0xc0de")
+	)
+	config.StateAccessLimits = &params.StateAccessLimitsConfig{Block: big.NewInt(0), MaxWrites: 3}
+
+	// Writes the slots 0 and 1
+	gspec := core.Genesis{Config: &config, Alloc: core.GenesisAlloc{
+		testBankAddress: {Balance: testBankFunds},
+		contract:        {Code: common.FromHex("6001600055600160015500"), Balance: new(big.Int)},
+	}}
+	genesis := gspec.MustCommit(db)
+	chain, _ := core.NewBlockChain(db, nil, gspec.Config, ethash.NewFaker(), vm.Config{}, nil)
+	defer chain.Stop()
+
+	w := &worker{config: &config, chain: chain}
+	header := &types.Header{ParentHash: genesis.Hash(), Number: big.NewInt(1), GasLimit: genesis.GasLimit(), Difficulty: big.NewInt(1), Time: big.NewInt(1)}
+	if err := w.makeCurrent(genesis, header); err != nil {
+		t.Fatalf("failed to prepare block: %v", err)
+	}
+	w.current.gasPool = new(core.GasPool).AddGas(header.GasLimit)
+
+	for i, want := range []error{nil, core.ErrStateAccessLimitReached} {
+		tx, _ := types.SignTx(types.NewTransaction(uint64(i), contract, new(big.Int), 100000, new(big.Int), nil), types.HomesteadSigner{}, testBankKey)
+		if _, err := w.commitTransaction(tx, testBankAddress); err != want {
+			t.Fatalf("transaction %d: error mismatch: have %v, want %v", i, err, want)
+		}
+	}
+	if len(w.current.txs) != 1 || w.current.state.GetNonce(testBankAddress) != 1 {
+		t.Errorf("over-limit transaction included: %d transactions, nonce %d", len(w.current.txs), w.current.state.GetNonce(testBankAddress))
+	}
+	if used := header.GasLimit - w.current.gasPool.Gas(); used != header.GasUsed || used != w.current.receipts[0].GasUsed {
+		t.Errorf("gas accounting mismatch: pool used %d, header %d, receipt %d", used, header.GasUsed, w.current.receipts[0].GasUsed)
+	}
+	if w.current.accessed.Writes != 2 {
+		t.Errorf("write count mismatch: have %d, want 2", w.current.accessed.Writes)
+	}
+}
@@ -19,14 +19,20 @@ package node
 import (
 	"context"
 	"fmt"
+	"runtime"
 	"strings"
 	"time"
 
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/adminauth"
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/metrics"
 	"github.com/ethereum/go-ethereum/p2p"
 	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/plugin"
 	"github.com/ethereum/go-ethereum/rpc"
 )
 
@@ -260,6 +266,68 @@ func (api *PrivateAdminAPI) StopWS() (bool, error) {
 	return true, nil
 }
 
+// ExportConfiguration returns the full effective configuration of the node,
+// combining the defaults, the configuration file and the command line flags,
+// with secrets redacted.
+func (api *PrivateAdminAPI) ExportConfiguration() (interface{}, error) {
+	export := api.node.ConfigurationExport()
+	if export == nil {
+		return nil, fmt.Errorf("configuration export not available")
+	}
+	return export, nil
+}
+
+// APIKeyUsage returns the usage of the API keys of the HTTP and WebSocket RPC
+// endpoints against their daily quotas.
+func (api *PrivateAdminAPI) APIKeyUsage() ([]rpc.APIKeyUsage, error) {
+	api.node.lock.RLock()
+	keys := api.node.rpcKeys
+	api.node.lock.RUnlock()
+
+	if keys == nil {
+		return nil, fmt.Errorf("API keys not configured")
+	}
+	return keys.Usage(), nil
+}
+
+// SelfMonitor returns the last sample of the goroutines, file descriptors,
+// heap and garbage collection pauses of the process, and whether the node is
+// protecting itself from their exhaustion.
+func (api *PrivateAdminAPI) SelfMonitor() (*SelfMonitorStatus, error) {
+	api.node.lock.RLock()
+	monitor := api.node.monitor
+	api.node.lock.RUnlock()
+
+	if monitor == nil {
+		return nil, fmt.Errorf("self-monitor not enabled")
+	}
+	return monitor.Status(), nil
+}
+
+// PendingApprovals returns the actions on the accounts awaiting the approvals of
+// their approvers, along with the recently decided ones if all is set.
+func (api *PrivateAdminAPI) PendingApprovals(all *bool) []*accounts.Approval {
+	return api.node.accman.Approvals(all != nil && *all)
+}
+
+// Approval returns an action on an account awaiting approvals or decided
+// recently.
+func (api *PrivateAdminAPI) Approval(id common.Hash) (*accounts.Approval, error) {
+	return api.node.accman.Approval(id)
+}
+
+// Approve records the approval of an action on an account by the approver who
+// signed the call, executing the action once approved by enough approvers.
+func (api *PrivateAdminAPI) Approve(id common.Hash, sig *adminauth.Signature) (*accounts.Approval, error) {
+	return api.node.accman.Approve(id, sig)
+}
+
+// RejectApproval cancels an action on an account awaiting approvals, rejected
+// by the approver who signed the call.
+func (api *PrivateAdminAPI) RejectApproval(id common.Hash, sig *adminauth.Signature) (*accounts.Approval, error) {
+	return api.node.accman.RejectApproval(id, sig)
+}
+
 // PublicAdminAPI is the collection of administrative API methods exposed over
 // both secure and unsecure RPC channels.
 type PublicAdminAPI struct {
@@ -288,6 +356,17 @@ func (api *PublicAdminAPI) Peers() ([]*p2p.PeerInfo, error) {
 	return server.PeersInfo(), nil
 }
 
+// Quorum
+// StaticPeerStatus retrieves the dial status of the static peers, with the last
+// dial error, the number of failed attempts and the time of the next retry.
+func (api *PublicAdminAPI) StaticPeerStatus() ([]*p2p.StaticPeerStatus, error) {
+	server := api.node.Server()
+	if server == nil {
+		return nil, ErrNodeStopped
+	}
+	return server.StaticPeerStatus(), nil
+}
+
 // NodeInfo retrieves all the information we know about the host node at the
 // protocol granularity.
 func (api *PublicAdminAPI) NodeInfo() (*QuorumNodeInfo, error) {
@@ -466,6 +545,40 @@ func (s *PublicWeb3API) ClientVersion() string {
 	return s.stack.Server().Name
 }
 
+// Quorum
+//
+// NodeInfoExtended describes the build of a node and the features it enables.
+type NodeInfoExtended struct {
+	Version       string                `json:"version"`
+	QuorumVersion string                `json:"quorumVersion"`
+	GitCommit     string                `json:"gitCommit"`
+	Go            string                `json:"go"`
+	OS            string                `json:"os"`
+	Arch          string                `json:"arch"`
+	Features      map[string]bool       `json:"features"`
+	Plugins       []plugin.LoadedPlugin `json:"plugins"`
+}
+
+// NodeInfoExtended returns the build commit of the node, the optional features
+// it enables and the plugins it loaded with their checksums, for auditors to
+// verify the capabilities of the node.
+func (s *PublicWeb3API) NodeInfoExtended() *NodeInfoExtended {
+	info := &NodeInfoExtended{
+		Version:       params.VersionWithMeta,
+		QuorumVersion: params.QuorumVersion,
+		GitCommit:     s.stack.config.GitCommit,
+		Go:            runtime.Version(),
+		OS:            runtime.GOOS,
+		Arch:          runtime.GOARCH,
+		Features:      s.stack.Features(),
+		Plugins:       []plugin.LoadedPlugin{},
+	}
+	if pm := s.stack.PluginManager(); pm != nil {
+		info.Plugins = pm.LoadedPlugins()
+	}
+	return info
+}
+
 // Sha3 applies the ethereum sha3 implementation on the input.
 // It assumes the input is hex encoded.
 func (s *PublicWeb3API) Sha3(input hexutil.Bytes) hexutil.Bytes {
@@ -30,9 +30,11 @@ import (
 	"github.com/ethereum/go-ethereum/accounts/usbwallet"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/kms"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/p2p"
 	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/netutil"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/plugin"
 	"github.com/ethereum/go-ethereum/rpc"
@@ -62,6 +64,9 @@ type Config struct {
 	// in the devp2p node identifier.
 	Version string `toml:"-"`
 
+	// GitCommit is the commit the program was built from, empty if unknown.
+	GitCommit string `toml:"-"`
+
 	// DataDir is the file system folder the node should use for any data storage
 	// requirements. The configured data directory will not be directly shared with
 	// registered services, instead those can use utility methods to create/access
@@ -85,6 +90,13 @@ type Config struct {
 	// scrypt KDF at the expense of security.
 	UseLightweightKDF bool `toml:",omitempty"`
 
+	// Quorum
+	// KeyStoreScryptN and KeyStoreScryptP override the scrypt parameters the key
+	// store encrypts the keys with, KeyStoreArgon2 replaces scrypt by Argon2id.
+	KeyStoreScryptN int                    `toml:",omitempty"`
+	KeyStoreScryptP int                    `toml:",omitempty"`
+	KeyStoreArgon2  *keystore.Argon2Params `toml:",omitempty"`
+
 	// NoUSB disables hardware wallet monitoring and connectivity.
 	NoUSB bool `toml:",omitempty"`
 
@@ -152,6 +164,51 @@ type Config struct {
 	// private APIs to untrusted users is a major security risk.
 	WSExposeAll bool `toml:",omitempty"`
 
+	// Quorum
+	// RPCAuth enables the JWT authentication of the HTTP and websocket RPC
+	// requests, authorizing the namespaces they may call from the token claims.
+	RPCAuth *rpc.JWTConfig `toml:",omitempty"`
+
+	// Quorum
+	// RPCAPIKeys is the JSON file listing the API keys the HTTP and websocket
+	// RPC requests must carry, with their daily quotas. Empty accepts requests
+	// without API keys.
+	RPCAPIKeys string `toml:",omitempty"`
+
+	// Quorum
+	// RPCMethodRules is the JSON file of the HTTP and websocket RPC methods, or
+	// namespaces, disabled, aliased to other methods or deprecated. Empty serves
+	// the methods as registered.
+	RPCMethodRules string `toml:",omitempty"`
+
+	// Quorum
+	// RPCListeners is the number of accept loops of the HTTP and websocket RPC
+	// endpoints, each with its own SO_REUSEPORT socket where supported.
+	RPCListeners int `toml:",omitempty"`
+
+	// Quorum
+	// RPCProxyProtocol reads the PROXY protocol v2 header of the connections to
+	// the HTTP and websocket RPC endpoints from the RPCProxyTrusted networks,
+	// which must be given, taking the remote IP of the requests from it.
+	RPCProxyProtocol bool             `toml:",omitempty"`
+	RPCProxyTrusted  *netutil.Netlist `toml:",omitempty"`
+
+	// Quorum
+	// RPCWorkers bounds the concurrent calls of each of the HTTP and websocket
+	// RPC endpoints, with per-method limits keeping slow calls from starving the
+	// others. Nil executes every call as soon as it is received.
+	RPCWorkers *rpc.WorkerConfig `toml:",omitempty"`
+
+	// Quorum
+	// SelfMonitor watches the goroutines, file descriptors, heap and garbage
+	// collection pauses of the process, shedding the RPC load at its thresholds.
+	SelfMonitor SelfMonitorConfig `toml:",omitempty"`
+
+	// Quorum
+	// NodeKeyKMS signs with the node key held in a cloud KMS instead of the node
+	// key file, which is still needed for the handshake and must match it.
+	NodeKeyKMS *kms.Config `toml:",omitempty"`
+
 	Plugins *plugin.Settings `toml:",omitempty"`
 
 	EnableNodePermission bool `toml:",omitempty"`
@@ -340,6 +397,38 @@ func (c *Config) NodeKey() *ecdsa.PrivateKey {
 	return key
 }
 
+// Quorum
+//
+// NodeKeySigner connects the node key in the KMS, returning its signer and the
+// local copy of the key, which the handshake needs. The copy isn't generated if
+// missing, as it must match the KMS key.
+func (c *Config) NodeKeySigner() (kms.Signer, *ecdsa.PrivateKey, error) {
+	local := c.existingNodeKey()
+	signer, err := kms.New(*c.NodeKeyKMS, local)
+	if err != nil {
+		return nil, nil, err
+	}
+	return signer, local, nil
+}
+
+// Quorum
+//
+// existingNodeKey returns the configured private key of the node or the one
+// found in the data folder, without generating any.
+func (c *Config) existingNodeKey() *ecdsa.PrivateKey {
+	if c.P2P.PrivateKey != nil {
+		return c.P2P.PrivateKey
+	}
+	if c.DataDir == "" {
+		return nil
+	}
+	key, err := crypto.LoadECDSA(c.ResolvePath(datadirPrivateKey))
+	if err != nil {
+		return nil
+	}
+	return key
+}
+
 // StaticNodes returns a list of node enode URLs configured as static nodes.
 func (c *Config) StaticNodes() []*enode.Node {
 	return c.parsePersistentNodes(c.ResolvePath(datadirStaticNodes))
@@ -390,6 +479,13 @@ func (c *Config) AccountConfig() (int, int, string, error) {
 		scryptN = keystore.LightScryptN
 		scryptP = keystore.LightScryptP
 	}
+	// Quorum
+	if c.KeyStoreScryptN != 0 {
+		scryptN = c.KeyStoreScryptN
+	}
+	if c.KeyStoreScryptP != 0 {
+		scryptP = c.KeyStoreScryptP
+	}
 
 	var (
 		keydir string
@@ -447,6 +543,80 @@ func (c *Config) IsPermissionEnabled() bool {
 	return false
 }
 
+// Quorum
+//
+// OperatorKeys returns the addresses of the operators allowed to authorise
+// administrative RPCs, as listed in the operator keys file in the data directory.
+func (c *Config) OperatorKeys() []common.Address {
+	if c.DataDir == "" {
+		return nil
+	}
+	path := filepath.Join(c.DataDir, params.OPERATOR_KEYS_CONFIG)
+	if _, err := os.Stat(path); err != nil {
+		return nil
+	}
+	var list []string
+	if err := common.LoadJSON(path, &list); err != nil {
+		log.Error(fmt.Sprintf("Can't load operator keys file %s: %v", path, err))
+		return nil
+	}
+	var operators []common.Address
+	for _, addr := range list {
+		if !common.IsHexAddress(addr) {
+			log.Error("Invalid operator address", "address", addr)
+			continue
+		}
+		operators = append(operators, common.HexToAddress(addr))
+	}
+	return operators
+}
+
+// Quorum
+//
+// IPAccessList returns the access list filtering the connections to the p2p and
+// RPC listeners by remote IP, as configured in the data directory, or nil if not
+// configured.
+func (c *Config) IPAccessList() (*netutil.AccessList, error) {
+	if c.DataDir == "" {
+		return nil, nil
+	}
+	path := filepath.Join(c.DataDir, params.IP_ACCESS_CONFIG)
+	if _, err := os.Stat(path); err != nil {
+		return nil, nil
+	}
+	return netutil.NewAccessList(path)
+}
+
+// Quorum
+//
+// UnlockPolicies returns the policies restricting the use of the unlocked
+// accounts, as configured in the data directory, or nil if not configured.
+func (c *Config) UnlockPolicies() (map[common.Address]*accounts.UnlockPolicy, error) {
+	if c.DataDir == "" {
+		return nil, nil
+	}
+	path := filepath.Join(c.DataDir, params.UNLOCK_POLICY_CONFIG)
+	if _, err := os.Stat(path); err != nil {
+		return nil, nil
+	}
+	return accounts.LoadUnlockPolicies(path)
+}
+
+// Quorum
+//
+// ApprovalPolicies returns the policies requiring approvals for the actions on
+// the accounts, as configured in the data directory, or nil if not configured.
+func (c *Config) ApprovalPolicies() (map[common.Address]*accounts.ApprovalPolicy, error) {
+	if c.DataDir == "" {
+		return nil, nil
+	}
+	path := filepath.Join(c.DataDir, params.APPROVAL_POLICY_CONFIG)
+	if _, err := os.Stat(path); err != nil {
+		return nil, nil
+	}
+	return accounts.LoadApprovalPolicies(path)
+}
+
 func makeAccountManager(conf *Config) (*accounts.Manager, string, error) {
 	scryptN, scryptP, keydir, err := conf.AccountConfig()
 	var ephemeral string
@@ -466,6 +636,10 @@ func makeAccountManager(conf *Config) (*accounts.Manager, string, error) {
 	backends := []accounts.Backend{
 		keystore.NewKeyStore(keydir, scryptN, scryptP),
 	}
+	// Quorum
+	if conf.KeyStoreArgon2 != nil {
+		backends[0] = keystore.NewKeyStoreArgon2(keydir, *conf.KeyStoreArgon2)
+	}
 	if !conf.NoUSB {
 		// Start a USB hub for Ledger hardware wallets
 		if ledgerhub, err := usbwallet.NewLedgerHub(); err != nil {
@@ -30,15 +30,26 @@ import (
 	"github.com/ethereum/go-ethereum/plugin"
 
 	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/adminauth"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/internal/debug"
+	"github.com/ethereum/go-ethereum/kms"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
 	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/p2p/netutil"
+	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/prometheus/prometheus/util/flock"
 )
 
+// Quorum
+var (
+	httpRejectedCounter = metrics.NewRegisteredCounter("rpc/http/rejected", nil) // Counter of the HTTP RPC connections rejected by the access list
+	wsRejectedCounter   = metrics.NewRegisteredCounter("rpc/ws/rejected", nil)   // Counter of the websocket RPC connections rejected by the access list
+)
+
 // Node is a container on which services can be registered.
 type Node struct {
 	eventmux *event.TypeMux // Event multiplexer used between the services of a stack
@@ -70,7 +81,15 @@ type Node struct {
 	wsListener net.Listener // Websocket RPC listener socket to server API requests
 	wsHandler  *rpc.Server  // Websocket RPC request handler to process the API requests
 
-	pluginManager *plugin.PluginManager // Manage all plugins for this node. If plugin is not enabled, an EmptyPluginManager is set.
+	pluginManager *plugin.PluginManager    // Manage all plugins for this node. If plugin is not enabled, an EmptyPluginManager is set.
+	operatorAuth  *adminauth.Authenticator // Verifies operator signatures on administrative calls
+	nodeSigner    kms.Signer               // Signs with the node key, locally or in a KMS
+	configExport  interface{}              // Effective launch configuration, exported through the admin API
+	accessList    *netutil.AccessList      // Filters the p2p and RPC connections by remote IP, nil if not configured
+	rpcAuth       *rpc.JWTAuth             // Authenticates the HTTP and WebSocket RPC requests, nil if not configured
+	rpcKeys       *rpc.APIKeys             // Meters the HTTP and WebSocket RPC requests by API key, nil if not configured
+	rpcMethods    *rpc.MethodRules         // Disables, aliases or deprecates the HTTP and WebSocket RPC methods, nil if not configured
+	monitor       *selfMonitor             // Watches the resources of the process, nil if disabled
 
 	stop chan struct{} // Channel to wait for termination notifications
 	lock sync.RWMutex
@@ -155,7 +174,15 @@ func (n *Node) Start() error {
 	// Initialize the p2p server. This creates the node key and
 	// discovery databases.
 	n.serverConfig = n.config.P2P
-	n.serverConfig.PrivateKey = n.config.NodeKey()
+	// Quorum
+	if n.config.NodeKeyKMS != nil {
+		if err := n.setupNodeSigner(); err != nil {
+			return err
+		}
+	} else {
+		n.serverConfig.PrivateKey = n.config.NodeKey()
+		n.nodeSigner = kms.NewLocalSigner(n.serverConfig.PrivateKey)
+	}
 	n.serverConfig.Name = n.config.NodeName()
 	n.serverConfig.Logger = n.log
 	if n.serverConfig.StaticNodes == nil {
@@ -169,9 +196,63 @@ func (n *Node) Start() error {
 	}
 	n.serverConfig.EnableNodePermission = n.config.EnableNodePermission
 	n.serverConfig.DataDir = n.config.DataDir
+	// Quorum
+	accessList, err := n.config.IPAccessList()
+	if err != nil {
+		return err
+	}
+	if accessList != nil {
+		n.log.Info("Filtering p2p and RPC connections by IP", "config", params.IP_ACCESS_CONFIG)
+	}
+	n.accessList = accessList
+	n.serverConfig.AccessList = accessList
+	policies, err := n.config.UnlockPolicies()
+	if err != nil {
+		return err
+	}
+	if err := n.accman.SetUnlockPolicies(policies); err != nil {
+		return err
+	}
+	if len(policies) > 0 {
+		n.log.Info("Restricting the use of unlocked accounts", "config", params.UNLOCK_POLICY_CONFIG, "accounts", len(policies))
+	}
+	approvals, err := n.config.ApprovalPolicies()
+	if err != nil {
+		return err
+	}
+	if err := n.accman.SetApprovalPolicies(approvals); err != nil {
+		return err
+	}
+	if len(approvals) > 0 {
+		n.log.Info("Requiring approvals for actions on accounts", "config", params.APPROVAL_POLICY_CONFIG, "accounts", len(approvals))
+	}
+	if n.config.RPCAuth != nil {
+		if n.rpcAuth, err = rpc.NewJWTAuth(*n.config.RPCAuth); err != nil {
+			return err
+		}
+		n.log.Info("Authenticating HTTP and WebSocket RPC requests with JWT", "claim", n.config.RPCAuth.NamespaceClaim)
+	}
+	if n.config.RPCAPIKeys != "" {
+		if n.rpcKeys, err = rpc.NewAPIKeys(n.config.RPCAPIKeys); err != nil {
+			return err
+		}
+		n.log.Info("Metering HTTP and WebSocket RPC requests by API key", "keys", n.config.RPCAPIKeys)
+	}
+	if n.config.RPCMethodRules != "" {
+		if n.rpcMethods, err = rpc.NewMethodRules(n.config.RPCMethodRules); err != nil {
+			return err
+		}
+		n.log.Info("Applying RPC method rules to HTTP and WebSocket requests", "rules", n.config.RPCMethodRules)
+	}
 	running := &p2p.Server{Config: n.serverConfig}
 	n.log.Info("Starting peer-to-peer node", "instance", n.serverConfig.Name)
 
+	// Quorum
+	n.operatorAuth = adminauth.New(n.config.OperatorKeys(), n.config.ResolvePath(params.OPERATOR_AUDIT_LOG))
+	if n.operatorAuth.Enabled() {
+		n.log.Info("Operator signatures required for administrative calls", "keys", params.OPERATOR_KEYS_CONFIG)
+	}
+
 	// Otherwise copy and specialize the P2P configuration
 	services := make(map[reflect.Type]Service)
 	for _, constructor := range n.serviceFuncs {
@@ -181,6 +262,9 @@ func (n *Node) Start() error {
 			services:       make(map[reflect.Type]Service),
 			EventMux:       n.eventmux,
 			AccountManager: n.accman,
+			operatorAuth:   n.operatorAuth,
+			nodeSigner:     n.nodeSigner,
+			nodeKey:        n.serverConfig.PrivateKey,
 		}
 		for kind, s := range services { // copy needed for threaded access
 			ctx.services[kind] = s
@@ -199,6 +283,9 @@ func (n *Node) Start() error {
 	// Gather the protocols and start the freshly assembled P2P server
 	for _, service := range services {
 		running.Protocols = append(running.Protocols, service.Protocols()...)
+		if provider, ok := service.(QuorumCapabilitiesProvider); ok {
+			running.QuorumCapabilities = provider.QuorumCapabilities()
+		}
 	}
 	if err := running.Start(); err != nil {
 		return convertFileLockError(err)
@@ -237,9 +324,32 @@ func (n *Node) Start() error {
 	n.server = running
 	n.stop = make(chan struct{})
 
+	// Quorum: watch the resources of the process, shedding the RPC load when exhausted
+	n.monitor = nil
+	if n.config.SelfMonitor.enabled() || metrics.Enabled {
+		n.monitor = newSelfMonitor(n.config.SelfMonitor, n.protectRPC)
+		go n.monitor.loop(n.stop)
+	}
 	return nil
 }
 
+// Quorum
+//
+// protectRPC pauses or resumes the calls of the HTTP and WebSocket endpoints,
+// and the WebSocket subscriptions. The IPC endpoint is left for the operator.
+func (n *Node) protectRPC(pauseCalls, pauseSubscriptions bool) {
+	n.lock.RLock()
+	defer n.lock.RUnlock()
+
+	if n.httpHandler != nil {
+		n.httpHandler.PauseCalls(pauseCalls)
+	}
+	if n.wsHandler != nil {
+		n.wsHandler.PauseCalls(pauseCalls)
+		n.wsHandler.PauseSubscriptions(pauseSubscriptions)
+	}
+}
+
 func (n *Node) openDataDir() error {
 	if n.config.DataDir == "" {
 		return nil // ephemeral
@@ -349,8 +459,13 @@ func (n *Node) startHTTP(endpoint string, apis []rpc.API, modules []string, cors
 	if endpoint == "" {
 		return nil
 	}
-	listener, handler, err := rpc.StartHTTPEndpoint(endpoint, apis, modules, cors, vhosts, timeouts)
+	listener, err := n.listenRPC(endpoint, httpRejectedCounter)
+	if err != nil {
+		return err
+	}
+	handler, err := rpc.ServeHTTPEndpoint(listener, apis, modules, cors, vhosts, timeouts, n.rpcAuth, n.rpcKeys, n.config.RPCWorkers, n.rpcMethods)
 	if err != nil {
+		listener.Close()
 		return err
 	}
 	n.log.Info("HTTP endpoint opened", "url", fmt.Sprintf("http://%s", endpoint), "cors", strings.Join(cors, ","), "vhosts", strings.Join(vhosts, ","))
@@ -382,10 +497,15 @@ func (n *Node) startWS(endpoint string, apis []rpc.API, modules []string, wsOrig
 	if endpoint == "" {
 		return nil
 	}
-	listener, handler, err := rpc.StartWSEndpoint(endpoint, apis, modules, wsOrigins, exposeAll)
+	listener, err := n.listenRPC(endpoint, wsRejectedCounter)
 	if err != nil {
 		return err
 	}
+	handler, err := rpc.ServeWSEndpoint(listener, apis, modules, wsOrigins, exposeAll, n.rpcAuth, n.rpcKeys, n.config.RPCWorkers, n.rpcMethods)
+	if err != nil {
+		listener.Close()
+		return err
+	}
 	n.log.Info("WebSocket endpoint opened", "url", fmt.Sprintf("ws://%s", listener.Addr()))
 	// All listeners booted successfully
 	n.wsEndpoint = endpoint
@@ -395,6 +515,30 @@ func (n *Node) startWS(endpoint string, apis []rpc.API, modules []string, wsOrig
 	return nil
 }
 
+// Quorum
+//
+// listenRPC opens a TCP listener for an RPC endpoint with the configured number
+// of accept loops, reading the PROXY protocol headers and filtering the accepted
+// connections by the IP access list if configured.
+func (n *Node) listenRPC(endpoint string, rejected metrics.Counter) (net.Listener, error) {
+	listener, err := rpc.ListenTCP(endpoint, n.config.RPCListeners)
+	if err != nil {
+		return nil, err
+	}
+	if n.config.RPCProxyProtocol {
+		proxied, err := netutil.ProxyListener(listener, n.config.RPCProxyTrusted, 0)
+		if err != nil {
+			listener.Close()
+			return nil, err
+		}
+		listener = proxied
+	}
+	if n.accessList != nil {
+		listener = n.accessList.Listener(listener, rejected)
+	}
+	return listener, nil
+}
+
 // stopWS terminates the websocket RPC endpoint.
 func (n *Node) stopWS() {
 	if n.wsListener != nil {
@@ -539,6 +683,50 @@ func (n *Node) Service(service interface{}) error {
 	return ErrServiceUnknown
 }
 
+// Quorum
+//
+// Features reports the optional features enabled on the node and by its running
+// services.
+func (n *Node) Features() map[string]bool {
+	n.lock.RLock()
+	defer n.lock.RUnlock()
+
+	features := map[string]bool{
+		"nodePermission": n.config.EnableNodePermission,
+		"operatorAuth":   n.operatorAuth.Enabled(),
+		"rpcJWTAuth":     n.config.RPCAuth != nil,
+		"rpcAPIKeys":     n.config.RPCAPIKeys != "",
+		"rpcMethodRules": n.config.RPCMethodRules != "",
+		"ipAccessList":   n.accessList != nil,
+		"plugins":        n.config.Plugins != nil && len(n.config.Plugins.Providers) > 0,
+	}
+	for _, service := range n.services {
+		if provider, ok := service.(FeatureProvider); ok {
+			for name, enabled := range provider.Features() {
+				features[name] = enabled
+			}
+		}
+	}
+	return features
+}
+
+// Quorum
+//
+// setupNodeSigner connects the node key in the KMS, the p2p server running the
+// handshake through it.
+func (n *Node) setupNodeSigner() error {
+	config := n.config.NodeKeyKMS
+	signer, local, err := n.config.NodeKeySigner()
+	if err != nil {
+		return err
+	}
+	n.serverConfig.PrivateKey = local
+	n.serverConfig.Signer = signer
+	n.nodeSigner = signer
+	n.log.Info("Signing with the node key in a KMS", "key", config.URL, "budget", config.Budget, "failover", config.Failover)
+	return nil
+}
+
 // Quorum
 //
 // delegate call to node.Config
@@ -550,9 +738,49 @@ func (n *Node) IsPermissionEnabled() bool {
 //
 // delegate call to node.Config
 func (n *Node) GetNodeKey() *ecdsa.PrivateKey {
+	if n.config.NodeKeyKMS != nil {
+		return n.serverConfig.PrivateKey
+	}
 	return n.config.NodeKey()
 }
 
+// Quorum
+//
+// GetNodeSigner returns the signer with the node key, which is held in a KMS if
+// configured.
+func (n *Node) GetNodeSigner() kms.Signer {
+	if n.nodeSigner == nil {
+		return kms.NewLocalSigner(n.config.NodeKey())
+	}
+	return n.nodeSigner
+}
+
+// Quorum
+//
+// OperatorAuthenticator returns the verifier of operator signatures on
+// administrative RPCs.
+func (n *Node) OperatorAuthenticator() *adminauth.Authenticator {
+	return n.operatorAuth
+}
+
+// SetConfigurationExport records the effective configuration the node was
+// launched with, secrets already redacted, for admin_exportConfiguration.
+func (n *Node) SetConfigurationExport(export interface{}) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	n.configExport = export
+}
+
+// ConfigurationExport retrieves the effective configuration the node was
+// launched with, or nil if none was recorded.
+func (n *Node) ConfigurationExport() interface{} {
+	n.lock.RLock()
+	defer n.lock.RUnlock()
+
+	return n.configExport
+}
+
 // DataDir retrieves the current datadir used by the protocol stack.
 // Deprecated: No files should be stored in this directory, use InstanceDir instead.
 func (n *Node) DataDir() string {
@@ -573,3 +573,49 @@ func TestAPIGather(t *testing.T) {
 		}
 	}
 }
+
+// featureService is a service enabling an optional feature.
+type featureService struct{ NoopService }
+
+func (s *featureService) Features() map[string]bool { return map[string]bool{"test": true} }
+
+// Tests that the extended node info reports the build commit along with the
+// features enabled by the node and its services.
+func TestNodeInfoExtended(t *testing.T) {
+	config := testNodeConfig()
+	config.GitCommit = "0123456789abcdef"
+	stack, err := New(config)
+	if err != nil {
+		t.Fatalf("failed to create protocol stack: %v", err)
+	}
+	if err := stack.Register(func(*ServiceContext) (Service, error) { return new(featureService), nil }); err != nil {
+		t.Fatalf("failed to register service: %v", err)
+	}
+	if err := stack.Start(); err != nil {
+		t.Fatalf("failed to start protocol stack: %v", err)
+	}
+	defer stack.Stop()
+
+	client, err := stack.Attach()
+	if err != nil {
+		t.Fatalf("failed to connect to the inproc API server: %v", err)
+	}
+	defer client.Close()
+
+	var info NodeInfoExtended
+	if err := client.Call(&info, "web3_nodeInfoExtended"); err != nil {
+		t.Fatalf("failed to retrieve extended node info: %v", err)
+	}
+	if info.GitCommit != config.GitCommit {
+		t.Errorf("git commit mismatch: have %q, want %q", info.GitCommit, config.GitCommit)
+	}
+	if enabled, ok := info.Features["test"]; !ok || !enabled {
+		t.Errorf("service feature not reported: %v", info.Features)
+	}
+	if enabled, ok := info.Features["rpcJWTAuth"]; !ok || enabled {
+		t.Errorf("node feature mismatch: %v", info.Features)
+	}
+	if info.Plugins == nil || len(info.Plugins) != 0 {
+		t.Errorf("plugins mismatch: have %v, want none", info.Plugins)
+	}
+}
@@ -0,0 +1,257 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"os"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+const (
+	// defaultSelfMonitorInterval is the interval between two samples of the
+	// resources of the process if not configured.
+	defaultSelfMonitorInterval = 10 * time.Second
+
+	// selfMonitorRelease is the fraction of the thresholds every resource must
+	// fall under for the protection to be released, so that it doesn't flap.
+	selfMonitorRelease = 0.9
+
+	// heapTrendWeight is the weight of the latest sample in the moving average
+	// of the heap growth.
+	heapTrendWeight = 0.2
+)
+
+// Resources of the process watched by the self-monitor.
+const (
+	ResourceGoroutines = "goroutines"
+	ResourceOpenFiles  = "openFiles"
+	ResourceHeap       = "heap"
+	ResourceGCPause    = "gcPause"
+)
+
+var (
+	goroutinesGauge  = metrics.NewRegisteredGauge("system/goroutines", nil)
+	openFilesGauge   = metrics.NewRegisteredGauge("system/openfiles", nil)
+	heapGauge        = metrics.NewRegisteredGauge("system/memory/heap", nil)
+	heapTrendGauge   = metrics.NewRegisteredGauge("system/memory/heap/trend", nil)
+	gcPauseGauge     = metrics.NewRegisteredGauge("system/memory/gcpause", nil)
+	protectionGauge  = metrics.NewRegisteredGauge("system/protection", nil)
+	protectionsMeter = metrics.NewRegisteredMeter("system/protection/engaged", nil)
+)
+
+// SelfMonitorConfig are the thresholds of the resources of the process beyond
+// which the node protects itself, shedding the load of the RPC clients before
+// it runs out of memory or file descriptors. Zero thresholds aren't enforced.
+type SelfMonitorConfig struct {
+	Interval      time.Duration `toml:",omitempty"` // Interval between two samples (0 = 10s)
+	MaxGoroutines int           `toml:",omitempty"` // Goroutines running
+	MaxOpenFiles  int           `toml:",omitempty"` // File descriptors open, on Linux
+	MaxHeap       uint64        `toml:",omitempty"` // Bytes of heap allocated
+	MaxGCPause    time.Duration `toml:",omitempty"` // Longest garbage collection pause since the previous sample
+
+	PauseRPC            bool `toml:",omitempty"` // Whether to reject the HTTP and WebSocket calls while protecting
+	RejectSubscriptions bool `toml:",omitempty"` // Whether to reject the new WebSocket subscriptions while protecting
+}
+
+// enabled returns whether any threshold is set.
+func (c *SelfMonitorConfig) enabled() bool {
+	return c.MaxGoroutines > 0 || c.MaxOpenFiles > 0 || c.MaxHeap > 0 || c.MaxGCPause > 0
+}
+
+// SelfMonitorStatus is the last sample of the resources of the process, and
+// the protection it triggered.
+type SelfMonitorStatus struct {
+	Goroutines int           `json:"goroutines"`
+	OpenFiles  int           `json:"openFiles"` // -1 if unknown on the platform
+	Heap       uint64        `json:"heap"`
+	HeapTrend  int64         `json:"heapTrend"` // Moving average of the heap growth, in bytes per minute
+	GCPause    time.Duration `json:"gcPause"`   // Longest garbage collection pause since the previous sample
+	Sampled    time.Time     `json:"sampled"`
+
+	Exceeded   []string   `json:"exceeded"`          // Resources over their threshold
+	Protecting bool       `json:"protecting"`        // Whether the protective actions are engaged
+	Since      *time.Time `json:"since,omitempty"`   // Time the protection was engaged
+	Actions    []string   `json:"actions,omitempty"` // Protective actions taken
+}
+
+// selfMonitor samples the resources of the process, exports them as metrics
+// and engages the configured protections when they exceed their thresholds,
+// releasing them once every resource is back well under its threshold.
+type selfMonitor struct {
+	config SelfMonitorConfig
+	apply  func(pauseCalls, pauseSubscriptions bool) // Applies the protections to the RPC servers
+
+	lock    sync.RWMutex
+	status  SelfMonitorStatus
+	numGC   uint32 // Garbage collections seen by the previous sample
+	started bool   // Whether a sample was taken
+}
+
+// newSelfMonitor creates a self-monitor applying its protections with the
+// given function.
+func newSelfMonitor(config SelfMonitorConfig, apply func(pauseCalls, pauseSubscriptions bool)) *selfMonitor {
+	if config.Interval <= 0 {
+		config.Interval = defaultSelfMonitorInterval
+	}
+	return &selfMonitor{config: config, apply: apply}
+}
+
+// loop samples the resources every interval until quit is closed. The
+// protections are applied at every sample, for the RPC servers started
+// meanwhile to get them.
+func (m *selfMonitor) loop(quit chan struct{}) {
+	ticker := time.NewTicker(m.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		m.check(sampleResources(&m.numGC))
+		select {
+		case <-ticker.C:
+		case <-quit:
+			return
+		}
+	}
+}
+
+// check records a sample and engages or releases the protections.
+func (m *selfMonitor) check(sample SelfMonitorStatus) {
+	goroutinesGauge.Update(int64(sample.Goroutines))
+	openFilesGauge.Update(int64(sample.OpenFiles))
+	heapGauge.Update(int64(sample.Heap))
+	gcPauseGauge.Update(int64(sample.GCPause))
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	prev := m.status
+	if m.started && sample.Sampled.After(prev.Sampled) {
+		growth := float64(int64(sample.Heap)-int64(prev.Heap)) * float64(time.Minute) / float64(sample.Sampled.Sub(prev.Sampled))
+		sample.HeapTrend = int64(heapTrendWeight*growth + (1-heapTrendWeight)*float64(prev.HeapTrend))
+	}
+	heapTrendGauge.Update(sample.HeapTrend)
+	m.started = true
+
+	sample.Exceeded = m.exceeded(sample, 1)
+	switch {
+	case !prev.Protecting && len(sample.Exceeded) > 0:
+		now := sample.Sampled
+		sample.Protecting, sample.Since = true, &now
+		protectionsMeter.Mark(1)
+		log.Warn("Node resources exhausted, protecting the node", "exceeded", sample.Exceeded, "goroutines", sample.Goroutines,
+			"files", sample.OpenFiles, "heap", sample.Heap, "gcpause", sample.GCPause, "pauserpc", m.config.PauseRPC, "rejectsubs", m.config.RejectSubscriptions)
+
+	case prev.Protecting && len(m.exceeded(sample, selfMonitorRelease)) > 0:
+		sample.Protecting, sample.Since = true, prev.Since
+
+	case prev.Protecting:
+		log.Info("Node resources recovered, protection released", "since", *prev.Since)
+	}
+	if sample.Protecting {
+		protectionGauge.Update(1)
+		if m.config.PauseRPC {
+			sample.Actions = append(sample.Actions, "pauseRPC")
+		}
+		if m.config.RejectSubscriptions {
+			sample.Actions = append(sample.Actions, "rejectSubscriptions")
+		}
+	} else {
+		protectionGauge.Update(0)
+	}
+	m.status = sample
+	m.apply(sample.Protecting && m.config.PauseRPC, sample.Protecting && m.config.RejectSubscriptions)
+}
+
+// exceeded returns the resources of a sample over the given fraction of their
+// thresholds.
+func (m *selfMonitor) exceeded(sample SelfMonitorStatus, fraction float64) []string {
+	over := func(value, limit float64) bool {
+		return limit > 0 && value > limit*fraction
+	}
+	exceeded := []string{}
+	if over(float64(sample.Goroutines), float64(m.config.MaxGoroutines)) {
+		exceeded = append(exceeded, ResourceGoroutines)
+	}
+	if sample.OpenFiles >= 0 && over(float64(sample.OpenFiles), float64(m.config.MaxOpenFiles)) {
+		exceeded = append(exceeded, ResourceOpenFiles)
+	}
+	if over(float64(sample.Heap), float64(m.config.MaxHeap)) {
+		exceeded = append(exceeded, ResourceHeap)
+	}
+	if over(float64(sample.GCPause), float64(m.config.MaxGCPause)) {
+		exceeded = append(exceeded, ResourceGCPause)
+	}
+	return exceeded
+}
+
+// Status returns the last sample of the resources.
+func (m *selfMonitor) Status() *SelfMonitorStatus {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	status := m.status
+	status.Exceeded = append([]string{}, m.status.Exceeded...)
+	status.Actions = append([]string(nil), m.status.Actions...)
+	return &status
+}
+
+// sampleResources samples the resources of the process. The longest garbage
+// collection pause is the one of the collections after numGC, updated.
+func sampleResources(numGC *uint32) SelfMonitorStatus {
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+
+	sample := SelfMonitorStatus{
+		Goroutines: runtime.NumGoroutine(),
+		OpenFiles:  countOpenFiles(),
+		Heap:       stats.HeapAlloc,
+		Sampled:    time.Now(),
+	}
+	// The recent pauses are kept in a circular buffer
+	collections := stats.NumGC - *numGC
+	if collections > uint32(len(stats.PauseNs)) {
+		collections = uint32(len(stats.PauseNs))
+	}
+	for i := uint32(0); i < collections; i++ {
+		pause := time.Duration(stats.PauseNs[(stats.NumGC-i+255)%256])
+		if pause > sample.GCPause {
+			sample.GCPause = pause
+		}
+	}
+	*numGC = stats.NumGC
+	return sample
+}
+
+// countOpenFiles returns the number of file descriptors open by the process,
+// -1 where unknown.
+func countOpenFiles() int {
+	dir, err := os.Open("/proc/self/fd")
+	if err != nil {
+		return -1
+	}
+	defer dir.Close()
+
+	names, err := dir.Readdirnames(-1)
+	if err != nil {
+		return -1
+	}
+	return len(names) - 1 // Not counting the directory itself
+}
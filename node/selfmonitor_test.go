@@ -0,0 +1,77 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// Tests that the protections are engaged once a resource exceeds its threshold,
+// and released only once it's back well under it.
+func TestSelfMonitorProtection(t *testing.T) {
+	var pauseCalls, pauseSubs bool
+	monitor := newSelfMonitor(SelfMonitorConfig{MaxGoroutines: 1000, MaxHeap: 1 << 30, PauseRPC: true}, func(calls, subs bool) {
+		pauseCalls, pauseSubs = calls, subs
+	})
+	start := time.Now()
+	samples := []struct {
+		goroutines int
+		heap       uint64
+		protecting bool
+		exceeded   []string
+	}{
+		{goroutines: 500, heap: 1 << 20, protecting: false, exceeded: []string{}},
+		{goroutines: 1200, heap: 1 << 20, protecting: true, exceeded: []string{ResourceGoroutines}},
+		{goroutines: 950, heap: 1 << 20, protecting: true, exceeded: []string{}},             // Above the release level
+		{goroutines: 950, heap: 2 << 30, protecting: true, exceeded: []string{ResourceHeap}}, // Another resource exhausted
+		{goroutines: 800, heap: 1 << 20, protecting: false, exceeded: []string{}},
+	}
+	for i, sample := range samples {
+		monitor.check(SelfMonitorStatus{
+			Goroutines: sample.goroutines,
+			OpenFiles:  -1,
+			Heap:       sample.heap,
+			Sampled:    start.Add(time.Duration(i) * time.Minute),
+		})
+		status := monitor.Status()
+		if status.Protecting != sample.protecting || pauseCalls != sample.protecting || pauseSubs {
+			t.Errorf("sample %d: protection mismatch: have %v (calls %v, subscriptions %v), want %v", i, status.Protecting, pauseCalls, pauseSubs, sample.protecting)
+		}
+		if !reflect.DeepEqual(status.Exceeded, sample.exceeded) {
+			t.Errorf("sample %d: exceeded mismatch: have %v, want %v", i, status.Exceeded, sample.exceeded)
+		}
+		if sample.protecting && (status.Since == nil || !status.Since.Equal(start.Add(time.Minute))) {
+			t.Errorf("sample %d: protection start mismatch: have %v", i, status.Since)
+		}
+	}
+	if status := monitor.Status(); status.HeapTrend >= 0 {
+		t.Errorf("heap trend not decreasing after the heap shrank: %d", status.HeapTrend)
+	}
+}
+
+func TestSampleResources(t *testing.T) {
+	var numGC uint32
+	sample := sampleResources(&numGC)
+	if sample.Goroutines <= 0 || sample.Heap == 0 || sample.Sampled.IsZero() {
+		t.Errorf("invalid sample: %+v", sample)
+	}
+	if sample.OpenFiles == 0 {
+		t.Errorf("no open file counted")
+	}
+}
@@ -21,8 +21,10 @@ import (
 	"reflect"
 
 	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/adminauth"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/kms"
 	"github.com/ethereum/go-ethereum/p2p"
 	"github.com/ethereum/go-ethereum/rpc"
 )
@@ -33,6 +35,9 @@ import (
 type ServiceContext struct {
 	config         *Config
 	services       map[reflect.Type]Service // Index of the already constructed services
+	operatorAuth   *adminauth.Authenticator // Verifier of operator signatures on administrative calls
+	nodeSigner     kms.Signer               // Signer with the node key, locally or in a KMS
+	nodeKey        *ecdsa.PrivateKey        // Local copy of the node key, nil if only held in a KMS
 	EventMux       *event.TypeMux           // Event multiplexer used for decoupled notifications
 	AccountManager *accounts.Manager        // Account manager created by the node.
 }
@@ -70,9 +75,32 @@ func (ctx *ServiceContext) Service(service interface{}) error {
 
 // NodeKey returns node key from config
 func (ctx *ServiceContext) NodeKey() *ecdsa.PrivateKey {
+	// Quorum: with the node key in a KMS, there may be no local copy
+	if ctx.nodeSigner != nil {
+		return ctx.nodeKey
+	}
 	return ctx.config.NodeKey()
 }
 
+// Quorum
+//
+// OperatorAuthenticator returns the verifier of operator signatures on
+// administrative RPCs.
+func (ctx *ServiceContext) OperatorAuthenticator() *adminauth.Authenticator {
+	return ctx.operatorAuth
+}
+
+// Quorum
+//
+// NodeSigner returns the signer with the node key, which is held in a KMS if
+// configured.
+func (ctx *ServiceContext) NodeSigner() kms.Signer {
+	if ctx.nodeSigner == nil {
+		return kms.NewLocalSigner(ctx.NodeKey())
+	}
+	return ctx.nodeSigner
+}
+
 // ServiceConstructor is the function signature of the constructors needed to be
 // registered for service instantiation.
 type ServiceConstructor func(ctx *ServiceContext) (Service, error)
@@ -102,3 +130,19 @@ type Service interface {
 	// are all terminated.
 	Stop() error
 }
+
+// Quorum
+//
+// QuorumCapabilitiesProvider is implemented by services which describe the
+// chain setup of the node, advertised to peers in the devp2p handshake.
+type QuorumCapabilitiesProvider interface {
+	QuorumCapabilities() *p2p.QuorumCapabilities
+}
+
+// Quorum
+//
+// FeatureProvider is implemented by services reporting which of their optional
+// features are enabled, for auditors to verify the capabilities of the node.
+type FeatureProvider interface {
+	Features() map[string]bool
+}
@@ -0,0 +1,166 @@
+package p2p
+
+import (
+	"crypto/ecdsa"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+var (
+	errPeerCertMissing  = errors.New("peer sent no certificate")
+	errPeerCertIdentity = errors.New("peer certificate not issued for its node key")
+	errPeerCertNoOrg    = errors.New("peer certificate names no organization")
+)
+
+// PeerCertificates binds the devp2p identity of the nodes to X.509 certificates
+// issued by the consortium CA. The certificate chain of a node is sent in its
+// protocol handshake, and names the node key in a URI SAN of the form
+// enode://<hex node key>. The organization of its subject is the organization
+// of the peer, which permissioning rules can reference.
+//
+// The node keys being secp256k1 keys, which X.509 doesn't support, the
+// certificates aren't issued for them: the RLPx handshake proves the possession
+// of the node key, and the certificate binds it to the organization.
+type PeerCertificates struct {
+	roots *x509.CertPool
+	chain [][]byte // DER certificates of the node, leaf first
+}
+
+// LoadPeerCertificates loads the PEM encoded certificates of the consortium CA
+// and the certificate chain of the node, leaf first.
+func LoadPeerCertificates(caFile, certFile string) (*PeerCertificates, error) {
+	ca, err := ioutil.ReadFile(caFile)
+	if err != nil {
+		return nil, err
+	}
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(ca) {
+		return nil, fmt.Errorf("no certificates in %s", caFile)
+	}
+	pc := &PeerCertificates{roots: roots}
+	if certFile == "" {
+		return pc, nil
+	}
+	blob, err := ioutil.ReadFile(certFile)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		var block *pem.Block
+		if block, blob = pem.Decode(blob); block == nil {
+			break
+		}
+		if block.Type == "CERTIFICATE" {
+			pc.chain = append(pc.chain, block.Bytes)
+		}
+	}
+	if len(pc.chain) == 0 {
+		return nil, fmt.Errorf("no certificates in %s", certFile)
+	}
+	return pc, nil
+}
+
+// verify checks the certificate chain sent by a peer against the consortium CA
+// and the node key the peer proved, returning the organization of the peer.
+func (pc *PeerCertificates) verify(chain [][]byte, pubkey *ecdsa.PublicKey) (string, error) {
+	if len(chain) == 0 {
+		return "", errPeerCertMissing
+	}
+	certs := make([]*x509.Certificate, len(chain))
+	for i, der := range chain {
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return "", err
+		}
+		certs[i] = cert
+	}
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+	leaf := certs[0]
+	_, err := leaf.Verify(x509.VerifyOptions{
+		Roots:         pc.roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	if err != nil {
+		return "", err
+	}
+	if !certNamesNodeKey(leaf, pubkey) {
+		return "", errPeerCertIdentity
+	}
+	if len(leaf.Subject.Organization) == 0 || leaf.Subject.Organization[0] == "" {
+		return "", errPeerCertNoOrg
+	}
+	return leaf.Subject.Organization[0], nil
+}
+
+// certNamesNodeKey reports whether the certificate names the node key in a URI
+// SAN, either as enode://<key> or as a full enode URL.
+func certNamesNodeKey(cert *x509.Certificate, pubkey *ecdsa.PublicKey) bool {
+	want := "enode://" + hex.EncodeToString(crypto.FromECDSAPub(pubkey)[1:])
+	for _, uri := range cert.URIs {
+		s := strings.ToLower(uri.String())
+		if s == want || strings.HasPrefix(s, want+"@") {
+			return true
+		}
+	}
+	return false
+}
+
+// encodePeerCertificates appends the certificate chain of the node to the
+// additional fields of its protocol handshake, after the Quorum capabilities.
+func encodePeerCertificates(hs *protoHandshake, chain [][]byte) error {
+	if len(hs.Rest) == 0 {
+		// No capabilities, the empty list doesn't decode as any
+		hs.Rest = []rlp.RawValue{rlp.EmptyList}
+	}
+	enc, err := rlp.EncodeToBytes(chain)
+	if err != nil {
+		return err
+	}
+	hs.Rest = append(hs.Rest[:1], enc)
+	return nil
+}
+
+// decodePeerCertificates extracts the certificate chain from the additional
+// fields of a protocol handshake, returning nil if the peer didn't send any.
+func decodePeerCertificates(hs *protoHandshake) [][]byte {
+	if len(hs.Rest) < 2 {
+		return nil
+	}
+	var chain [][]byte
+	if err := rlp.DecodeBytes(hs.Rest[1], &chain); err != nil {
+		return nil
+	}
+	return chain
+}
+
+// checkPeerCertificate verifies the certificate chain sent by a peer in its
+// handshake, recording its organization. Peers must present a certificate once
+// the node is configured with the consortium CA.
+func (srv *Server) checkPeerCertificate(c *conn, hs *protoHandshake) error {
+	if srv.peerCerts == nil {
+		return nil
+	}
+	pubkey := new(ecdsa.PublicKey)
+	if err := c.node.Load((*enode.Secp256k1)(pubkey)); err != nil {
+		return err
+	}
+	org, err := srv.peerCerts.verify(decodePeerCertificates(hs), pubkey)
+	if err != nil {
+		return err
+	}
+	c.org = org
+	return nil
+}
@@ -0,0 +1,230 @@
+package p2p
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// testCA is a certificate authority issuing node certificates.
+type testCA struct {
+	key  *ecdsa.PrivateKey
+	cert *x509.Certificate
+	der  []byte
+}
+
+func newTestCA(t *testing.T) *testCA {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "consortium CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return &testCA{key: key, cert: cert, der: der}
+}
+
+// issue creates a certificate of the organization naming the node key.
+func (ca *testCA) issue(t *testing.T, org string, nodekey *ecdsa.PublicKey) []byte {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	uri, _ := url.Parse("enode://" + hex.EncodeToString(crypto.FromECDSAPub(nodekey)[1:]))
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "node", Organization: []string{org}},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		URIs:         []*url.URL{uri},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return der
+}
+
+func (ca *testCA) certificates() *PeerCertificates {
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	return &PeerCertificates{roots: roots}
+}
+
+func TestPeerCertificateVerify(t *testing.T) {
+	var (
+		ca, other = newTestCA(t), newTestCA(t)
+		nodekey   = newkey()
+		pc        = ca.certificates()
+	)
+	org, err := pc.verify([][]byte{ca.issue(t, "ACME", &nodekey.PublicKey)}, &nodekey.PublicKey)
+	if err != nil {
+		t.Fatalf("failed to verify certificate: %v", err)
+	}
+	if org != "ACME" {
+		t.Errorf("organization mismatch: have %q, want ACME", org)
+	}
+	if _, err := pc.verify(nil, &nodekey.PublicKey); err != errPeerCertMissing {
+		t.Errorf("missing certificate error mismatch: have %v", err)
+	}
+	// Certificates must name the node key the peer proved
+	if _, err := pc.verify([][]byte{ca.issue(t, "ACME", &newkey().PublicKey)}, &nodekey.PublicKey); err != errPeerCertIdentity {
+		t.Errorf("identity error mismatch: have %v", err)
+	}
+	// Certificates must be issued by the consortium CA
+	if _, err := pc.verify([][]byte{other.issue(t, "ACME", &nodekey.PublicKey)}, &nodekey.PublicKey); err == nil {
+		t.Errorf("certificate of another CA accepted")
+	}
+	if _, err := pc.verify([][]byte{ca.issue(t, "", &nodekey.PublicKey)}, &nodekey.PublicKey); err != errPeerCertNoOrg {
+		t.Errorf("organization error mismatch: have %v", err)
+	}
+}
+
+func TestServerSetupConn_permissionedOrg(t *testing.T) {
+	dir, err := ioutil.TempDir("", "p2p-certs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ca := newTestCA(t)
+	caFile := filepath.Join(dir, "ca.pem")
+	if err := ioutil.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	permissioned := []byte(`["org:ACME"]`)
+	if err := ioutil.WriteFile(filepath.Join(dir, params.PERMISSIONED_CONFIG), permissioned, 0600); err != nil {
+		t.Fatal(err)
+	}
+	clientkey, srvkey := newkey(), newkey()
+	tests := []struct {
+		org          string
+		wantCloseErr error
+	}{
+		// Peers of permissioned organizations pass the check, only to be
+		// dropped for lack of common protocols
+		{"ACME", DiscUselessPeer},
+		{"Other", nil},
+		{"", DiscUnexpectedIdentity},
+	}
+	for i, test := range tests {
+		phs := protoHandshake{ID: crypto.FromECDSAPub(&clientkey.PublicKey)[1:]}
+		if test.org != "" {
+			encodePeerCertificates(&phs, [][]byte{ca.issue(t, test.org, &clientkey.PublicKey)})
+		}
+		tt := &setupTransport{pubkey: &clientkey.PublicKey, phs: phs}
+		srv := &Server{
+			Config: Config{
+				PrivateKey:           srvkey,
+				MaxPeers:             10,
+				NoDial:               true,
+				Protocols:            []Protocol{discard},
+				EnableNodePermission: true,
+				DataDir:              dir,
+				PeerCAFile:           caFile,
+			},
+			newTransport: func(fd net.Conn) transport { return tt },
+			log:          log.New(),
+		}
+		if err := srv.Start(); err != nil {
+			t.Fatalf("couldn't start server: %v", err)
+		}
+		p1, _ := net.Pipe()
+		err := srv.SetupConn(p1, inboundConn, nil)
+		if test.wantCloseErr == nil {
+			if perr, ok := err.(*peerError); !ok || perr.code != errPermissionDenied {
+				t.Errorf("test %d: error mismatch: have %v, want permission denied", i, err)
+			}
+		} else if tt.closeErr != test.wantCloseErr {
+			t.Errorf("test %d: close error mismatch: got %q, want %q", i, tt.closeErr, test.wantCloseErr)
+		}
+		srv.Stop()
+	}
+}
+
+type testOrgPermissions []string
+
+func (orgs testOrgPermissions) PermissionedOrgs() []string { return orgs }
+
+// Tests that the organizations of the permission service replace those listed
+// in the permissioned nodes.
+func TestServerSetupConn_orgPermissions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "p2p-certs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ca := newTestCA(t)
+	caFile := filepath.Join(dir, "ca.pem")
+	if err := ioutil.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	permissioned := []byte(`["org:ACME"]`)
+	if err := ioutil.WriteFile(filepath.Join(dir, params.PERMISSIONED_CONFIG), permissioned, 0600); err != nil {
+		t.Fatal(err)
+	}
+	clientkey, srvkey := newkey(), newkey()
+	tests := []struct {
+		org          string
+		wantCloseErr error
+	}{
+		{"OTHER", DiscUselessPeer},
+		{"ACME", nil},
+	}
+	for i, test := range tests {
+		phs := protoHandshake{ID: crypto.FromECDSAPub(&clientkey.PublicKey)[1:]}
+		encodePeerCertificates(&phs, [][]byte{ca.issue(t, test.org, &clientkey.PublicKey)})
+
+		tt := &setupTransport{pubkey: &clientkey.PublicKey, phs: phs}
+		srv := &Server{
+			Config: Config{
+				PrivateKey:           srvkey,
+				MaxPeers:             10,
+				NoDial:               true,
+				Protocols:            []Protocol{discard},
+				EnableNodePermission: true,
+				DataDir:              dir,
+				PeerCAFile:           caFile,
+			},
+			newTransport: func(fd net.Conn) transport { return tt },
+			log:          log.New(),
+		}
+		if err := srv.Start(); err != nil {
+			t.Fatalf("couldn't start server: %v", err)
+		}
+		srv.SetOrgPermissions(testOrgPermissions{"OTHER"})
+
+		p1, _ := net.Pipe()
+		err := srv.SetupConn(p1, inboundConn, nil)
+		if test.wantCloseErr == nil {
+			if perr, ok := err.(*peerError); !ok || perr.code != errPermissionDenied {
+				t.Errorf("test %d: error mismatch: have %v, want permission denied", i, err)
+			}
+		} else if tt.closeErr != test.wantCloseErr {
+			t.Errorf("test %d: close error mismatch: got %q, want %q", i, tt.closeErr, test.wantCloseErr)
+		}
+		srv.Stop()
+	}
+}
@@ -23,6 +23,7 @@ import (
 	"net"
 	"time"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/p2p/enode"
 	"github.com/ethereum/go-ethereum/p2p/netutil"
@@ -78,10 +79,13 @@ type dialstate struct {
 	lookupBuf     []*enode.Node // current discovery lookup results
 	randomNodes   []*enode.Node // filled from Table
 	static        map[enode.ID]*dialTask
+	redials       map[enode.ID]*redialState // Quorum: backoff of the static dials
 	hist          *dialHistory
 
 	start     time.Time     // time when the dialer was first used
 	bootnodes []*enode.Node // default dials when there are no peers
+
+	latency *latencyPolicy // Quorum: orders the dynamic dials by round-trip time, nil if disabled
 }
 
 type discoverTable interface {
@@ -111,6 +115,7 @@ type dialTask struct {
 	dest         *enode.Node
 	lastResolved time.Time
 	resolveDelay time.Duration
+	err          error // Quorum: error of the last run, for the static dials backoff
 }
 
 // discoverTask runs discovery table operations.
@@ -133,6 +138,7 @@ func newDialState(self enode.ID, static []*enode.Node, bootnodes []*enode.Node,
 		self:        self,
 		netrestrict: netrestrict,
 		static:      make(map[enode.ID]*dialTask),
+		redials:     make(map[enode.ID]*redialState),
 		dialing:     make(map[enode.ID]connFlag),
 		bootnodes:   make([]*enode.Node, len(bootnodes)),
 		randomNodes: make([]*enode.Node, maxdyn/2),
@@ -154,6 +160,7 @@ func (s *dialstate) addStatic(n *enode.Node) {
 func (s *dialstate) removeStatic(n *enode.Node) {
 	// This removes a task so future attempts to connect will not be made.
 	delete(s.static, n.ID())
+	delete(s.redials, n.ID())
 	// This removes a previous dial timestamp so that application
 	// can force a server to reconnect with chosen peer immediately.
 	s.hist.remove(n.ID())
@@ -191,6 +198,12 @@ func (s *dialstate) newTasks(nRunning int, peers map[enode.ID]*Peer, now time.Ti
 	// Expire the dial history on every invocation.
 	s.hist.expire(now)
 
+	// Quorum
+	var missingCross int
+	if s.latency != nil {
+		missingCross = s.latency.update(peers)
+	}
+
 	// Create dials for static nodes if they are not connected.
 	for id, t := range s.static {
 		err := s.checkDial(t.dest, peers)
@@ -198,6 +211,7 @@ func (s *dialstate) newTasks(nRunning int, peers map[enode.ID]*Peer, now time.Ti
 		case errNotWhitelisted, errSelf:
 			log.Warn("Removing static dial candidate", "id", t.dest.ID, "addr", &net.TCPAddr{IP: t.dest.IP(), Port: t.dest.TCP()}, "err", err)
 			delete(s.static, t.dest.ID())
+			delete(s.redials, t.dest.ID())
 		case nil:
 			s.dialing[id] = t.flags
 			newtasks = append(newtasks, t)
@@ -220,6 +234,9 @@ func (s *dialstate) newTasks(nRunning int, peers map[enode.ID]*Peer, now time.Ti
 	randomCandidates := needDynDials / 2
 	if randomCandidates > 0 {
 		n := s.ntab.ReadRandomNodes(s.randomNodes)
+		if s.latency != nil {
+			s.latency.sort(s.randomNodes[:n], missingCross)
+		}
 		for i := 0; i < randomCandidates && i < n; i++ {
 			if addDial(dynDialedConn, s.randomNodes[i]) {
 				needDynDials--
@@ -228,6 +245,9 @@ func (s *dialstate) newTasks(nRunning int, peers map[enode.ID]*Peer, now time.Ti
 	}
 	// Create dynamic dials from random lookup results, removing tried
 	// items from the result buffer.
+	if s.latency != nil {
+		s.latency.sort(s.lookupBuf, missingCross)
+	}
 	i := 0
 	for ; i < len(s.lookupBuf) && needDynDials > 0; i++ {
 		if addDial(dynDialedConn, s.lookupBuf[i]) {
@@ -280,7 +300,22 @@ func (s *dialstate) checkDial(n *enode.Node, peers map[enode.ID]*Peer) error {
 func (s *dialstate) taskDone(t task, now time.Time) {
 	switch t := t.(type) {
 	case *dialTask:
-		s.hist.add(t.dest.ID(), now.Add(dialHistoryExpiration))
+		expiration := dialHistoryExpiration
+		// Quorum: back off from the static nodes failing to connect
+		if _, ok := s.static[t.dest.ID()]; ok && t.flags&staticDialedConn != 0 {
+			r := s.redials[t.dest.ID()]
+			if r == nil {
+				r = new(redialState)
+				s.redials[t.dest.ID()] = r
+			}
+			if t.err != nil {
+				expiration = r.fail(t.err, now)
+				log.Debug("Static dial failed", "id", t.dest.ID(), "attempts", r.attempts, "retry", common.PrettyDuration(expiration), "err", t.err)
+			} else {
+				r.succeed(now)
+			}
+		}
+		s.hist.add(t.dest.ID(), now.Add(expiration))
 		delete(s.dialing, t.dest.ID())
 	case *discoverTask:
 		s.lookupRunning = false
@@ -289,8 +324,10 @@ func (s *dialstate) taskDone(t task, now time.Time) {
 }
 
 func (t *dialTask) Do(srv *Server) {
+	t.err = nil
 	if t.dest.Incomplete() {
 		if !t.resolve(srv) {
+			t.err = errUnresolved
 			return
 		}
 	}
@@ -300,10 +337,11 @@ func (t *dialTask) Do(srv *Server) {
 		// Try resolving the ID of static nodes if dialing failed.
 		if _, ok := err.(*dialError); ok && t.flags&staticDialedConn != 0 {
 			if t.resolve(srv) {
-				t.dial(srv, t.dest)
+				err = t.dial(srv, t.dest)
 			}
 		}
 	}
+	t.err = err
 }
 
 // resolve attempts to find the current endpoint for the destination
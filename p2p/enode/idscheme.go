@@ -43,14 +43,24 @@ type V4ID struct{}
 
 // SignV4 signs a record using the v4 scheme.
 func SignV4(r *enr.Record, privkey *ecdsa.PrivateKey) error {
+	return SignV4With(r, &privkey.PublicKey, func(hash []byte) ([]byte, error) {
+		return crypto.Sign(hash, privkey)
+	})
+}
+
+// Quorum
+//
+// SignV4With signs a record using the v4 scheme, with a key held elsewhere
+// signing hashes in the format of crypto.Sign.
+func SignV4With(r *enr.Record, pub *ecdsa.PublicKey, signer func(hash []byte) ([]byte, error)) error {
 	// Copy r to avoid modifying it if signing fails.
 	cpy := *r
 	cpy.Set(enr.ID("v4"))
-	cpy.Set(Secp256k1(privkey.PublicKey))
+	cpy.Set(Secp256k1(*pub))
 
 	h := sha3.NewKeccak256()
 	rlp.Encode(h, cpy.AppendElements(nil))
-	sig, err := crypto.Sign(h.Sum(nil), privkey)
+	sig, err := signer(h.Sum(nil))
 	if err != nil {
 		return err
 	}
@@ -26,6 +26,7 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/p2p/enr"
 	"github.com/ethereum/go-ethereum/p2p/netutil"
@@ -42,15 +43,17 @@ const (
 // current process. Setting ENR entries via the Set method updates the record. A new version
 // of the record is signed on demand when the Node method is called.
 type LocalNode struct {
-	cur atomic.Value // holds a non-nil node pointer while the record is up-to-date.
-	id  ID
-	key *ecdsa.PrivateKey
-	db  *DB
+	cur    atomic.Value // holds a non-nil node pointer while the record is up-to-date.
+	id     ID
+	pub    *ecdsa.PublicKey
+	signer func(hash []byte) ([]byte, error)
+	db     *DB
 
 	// everything below is protected by a lock
 	mu          sync.Mutex
 	seq         uint64
 	entries     map[string]enr.Entry
+	last        *Node              // Quorum: last signed record, kept if signing a new one fails
 	udpTrack    *netutil.IPTracker // predicts external UDP endpoint
 	staticIP    net.IP
 	fallbackIP  net.IP
@@ -59,10 +62,21 @@ type LocalNode struct {
 
 // NewLocalNode creates a local node.
 func NewLocalNode(db *DB, key *ecdsa.PrivateKey) *LocalNode {
+	return NewLocalNodeWithSigner(db, &key.PublicKey, func(hash []byte) ([]byte, error) {
+		return crypto.Sign(hash, key)
+	})
+}
+
+// Quorum
+//
+// NewLocalNodeWithSigner creates a local node whose key is held elsewhere, such
+// as in a KMS, signing the records with the given function.
+func NewLocalNodeWithSigner(db *DB, pub *ecdsa.PublicKey, signer func(hash []byte) ([]byte, error)) *LocalNode {
 	ln := &LocalNode{
-		id:       PubkeyToIDV4(&key.PublicKey),
+		id:       PubkeyToIDV4(pub),
 		db:       db,
-		key:      key,
+		pub:      pub,
+		signer:   signer,
 		udpTrack: netutil.NewIPTracker(iptrackWindow, iptrackContactWindow, iptrackMinStatements),
 		entries:  make(map[string]enr.Entry),
 	}
@@ -86,7 +100,10 @@ func (ln *LocalNode) Node() *Node {
 	ln.mu.Lock()
 	defer ln.mu.Unlock()
 	ln.sign()
-	return ln.cur.Load().(*Node)
+	if n := ln.cur.Load().(*Node); n != nil {
+		return n
+	}
+	return ln.last
 }
 
 // ID returns the local node ID.
@@ -229,7 +246,13 @@ func (ln *LocalNode) sign() {
 	}
 	ln.bumpSeq()
 	r.SetSeq(ln.seq)
-	if err := SignV4(&r, ln.key); err != nil {
+	if err := SignV4With(&r, ln.pub, ln.signer); err != nil {
+		// Quorum: a remote signer may fail transiently, the record is signed
+		// again on the next call meanwhile
+		if ln.last != nil {
+			log.Warn("Failed to sign the local node record, keeping the previous one", "err", err)
+			return
+		}
 		panic(fmt.Errorf("enode: can't sign record: %v", err))
 	}
 	n, err := New(ValidSchemes, &r)
@@ -237,6 +260,7 @@ func (ln *LocalNode) sign() {
 		panic(fmt.Errorf("enode: can't verify local record: %v", err))
 	}
 	ln.cur.Store(n)
+	ln.last = n
 	log.Info("New local node record", "seq", ln.seq, "id", n.ID(), "ip", n.IP(), "udp", n.UDP(), "tcp", n.TCP())
 }
 
@@ -17,6 +17,7 @@
 package enode
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/ethereum/go-ethereum/crypto"
@@ -61,7 +62,7 @@ func TestLocalNodeSeqPersist(t *testing.T) {
 	// Create a new instance, it should reload the sequence number.
 	// The number increases just after that because a new record is
 	// created without the "x" entry.
-	ln2 := NewLocalNode(db, ln.key)
+	ln2 := NewLocalNodeWithSigner(db, ln.pub, ln.signer)
 	if s := ln2.Node().Seq(); s != 3 {
 		t.Fatalf("wrong seq %d on new instance, want 3", s)
 	}
@@ -74,3 +75,32 @@ func TestLocalNodeSeqPersist(t *testing.T) {
 		t.Fatalf("wrong seq %d on instance with changed key, want 1", s)
 	}
 }
+
+// Tests that a record failing to be signed by a remote signer leaves the
+// previous one in place until signing succeeds again.
+func TestLocalNodeSignerFailure(t *testing.T) {
+	db, _ := OpenDB("")
+	defer db.Close()
+
+	key, _ := crypto.GenerateKey()
+	failing := false
+	ln := NewLocalNodeWithSigner(db, &key.PublicKey, func(hash []byte) ([]byte, error) {
+		if failing {
+			return nil, errors.New("unavailable")
+		}
+		return crypto.Sign(hash, key)
+	})
+	if ln.Node().ID() != PubkeyToIDV4(&key.PublicKey) {
+		t.Fatal("inconsistent ID")
+	}
+	failing = true
+	ln.Set(enr.WithEntry("x", uint(1)))
+	if n := ln.Node(); n == nil || n.Load(enr.WithEntry("x", new(uint))) == nil {
+		t.Fatalf("previous record not kept: %v", n)
+	}
+	failing = false
+	var x uint
+	if err := ln.Node().Load(enr.WithEntry("x", &x)); err != nil || x != 1 {
+		t.Fatalf("record not signed again: %v %d", err, x)
+	}
+}
@@ -0,0 +1,125 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"sort"
+	"time"
+
+	"github.com/ethereum/go-ethereum/p2p/enode"
+)
+
+const (
+	// defaultCrossRegionRTT is the round-trip time from which a node is considered
+	// in another region if not configured.
+	defaultCrossRegionRTT = 100 * time.Millisecond
+
+	// maxLatencyEntries is the maximum number of nodes whose round-trip time is
+	// remembered.
+	maxLatencyEntries = 4096
+)
+
+// latencyPolicy orders the dial candidates by the round-trip time measured when
+// they were last connected, preferring the nearest nodes while keeping a number
+// of links to the nodes of other regions.
+type latencyPolicy struct {
+	crossRTT   time.Duration // Round-trip time from which a node is in another region
+	crossPeers int           // Number of peers in other regions to keep connected
+
+	rtt map[enode.ID]time.Duration // Last round-trip time measured of each node
+}
+
+func newLatencyPolicy(crossRTT time.Duration, crossPeers int) *latencyPolicy {
+	if crossRTT <= 0 {
+		crossRTT = defaultCrossRegionRTT
+	}
+	return &latencyPolicy{
+		crossRTT:   crossRTT,
+		crossPeers: crossPeers,
+		rtt:        make(map[enode.ID]time.Duration),
+	}
+}
+
+// update records the round-trip times of the connected peers, returning the
+// number of cross-region peers missing.
+func (l *latencyPolicy) update(peers map[enode.ID]*Peer) int {
+	missing := l.crossPeers
+	for id, p := range peers {
+		rtt := p.RTT()
+		if rtt == 0 {
+			continue
+		}
+		if _, ok := l.rtt[id]; !ok && len(l.rtt) >= maxLatencyEntries {
+			for old := range l.rtt {
+				delete(l.rtt, old)
+				break
+			}
+		}
+		l.rtt[id] = rtt
+		if rtt >= l.crossRTT {
+			missing--
+		}
+	}
+	if missing < 0 {
+		missing = 0
+	}
+	return missing
+}
+
+// rank returns the dial priority class of a node, lower first: the nearest
+// measured nodes, then the nodes never measured, then the cross-region ones.
+func (l *latencyPolicy) rank(n *enode.Node) (int, time.Duration) {
+	rtt, ok := l.rtt[n.ID()]
+	switch {
+	case !ok:
+		return 1, 0
+	case rtt >= l.crossRTT:
+		return 2, rtt
+	default:
+		return 0, rtt
+	}
+}
+
+// sort orders the dial candidates by priority, moving the nearest of the known
+// cross-region nodes first if cross-region peers are missing.
+func (l *latencyPolicy) sort(nodes []*enode.Node, missingCross int) {
+	sort.SliceStable(nodes, func(i, j int) bool {
+		ci, ri := l.rank(nodes[i])
+		cj, rj := l.rank(nodes[j])
+		if ci != cj {
+			return ci < cj
+		}
+		return ri < rj
+	})
+	if missingCross == 0 {
+		return
+	}
+	// The cross-region nodes are last, bring the nearest of them to the front
+	first := len(nodes)
+	for first > 0 {
+		if class, _ := l.rank(nodes[first-1]); class != 2 {
+			break
+		}
+		first--
+	}
+	if cross := len(nodes) - first; missingCross > cross {
+		missingCross = cross
+	}
+	promoted := append([]*enode.Node{}, nodes[first:first+missingCross]...)
+	copy(nodes[missingCross:], nodes[:first])
+	copy(nodes, promoted)
+}
@@ -0,0 +1,93 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/enr"
+)
+
+func latencyTestNode(i byte) *enode.Node {
+	return enode.SignNull(new(enr.Record), enode.ID{i})
+}
+
+// Tests that the dial candidates are ordered nearest first, the cross-region
+// ones promoted only while cross-region peers are missing.
+func TestLatencyPolicySort(t *testing.T) {
+	policy := newLatencyPolicy(100*time.Millisecond, 2)
+
+	// Measure the nodes 1-4 as connected peers, 1 and 3 in other regions
+	peers := map[enode.ID]*Peer{
+		{1}: {rtt: int64(250 * time.Millisecond)},
+		{2}: {rtt: int64(20 * time.Millisecond)},
+		{3}: {rtt: int64(150 * time.Millisecond)},
+		{4}: {rtt: int64(5 * time.Millisecond)},
+		{5}: {},
+	}
+	if missing := policy.update(peers); missing != 0 {
+		t.Fatalf("missing cross-region peers mismatch: have %d, want 0", missing)
+	}
+	order := func(missingCross int) []byte {
+		nodes := []*enode.Node{latencyTestNode(1), latencyTestNode(5), latencyTestNode(3), latencyTestNode(2), latencyTestNode(4)}
+		policy.sort(nodes, missingCross)
+		ids := make([]byte, len(nodes))
+		for i, n := range nodes {
+			ids[i] = n.ID()[0]
+		}
+		return ids
+	}
+	tests := []struct {
+		missing int
+		want    string
+	}{
+		{0, "\x04\x02\x05\x03\x01"},
+		{1, "\x03\x04\x02\x05\x01"},
+		{5, "\x03\x01\x04\x02\x05"},
+	}
+	for _, tt := range tests {
+		if have := string(order(tt.missing)); have != tt.want {
+			t.Errorf("order with %d missing mismatch: have %v, want %v", tt.missing, []byte(have), []byte(tt.want))
+		}
+	}
+	// Dropping the cross-region peers must report them missing
+	if missing := policy.update(map[enode.ID]*Peer{{2}: {rtt: int64(20 * time.Millisecond)}}); missing != 2 {
+		t.Errorf("missing cross-region peers mismatch: have %d, want 2", missing)
+	}
+}
+
+// Tests that the round-trip time is measured from the answered pings only.
+func TestPeerRTT(t *testing.T) {
+	p := new(Peer)
+	p.updateRTT(time.Now())
+	if rtt := p.RTT(); rtt != 0 {
+		t.Fatalf("unsolicited pong measured: %v", rtt)
+	}
+	sent := time.Now()
+	p.pingSent = sent.UnixNano()
+	p.updateRTT(sent.Add(40 * time.Millisecond))
+	if rtt := p.RTT(); rtt != 40*time.Millisecond {
+		t.Fatalf("first sample mismatch: have %v, want 40ms", rtt)
+	}
+	p.pingSent = sent.UnixNano()
+	p.updateRTT(sent.Add(80 * time.Millisecond))
+	if rtt := p.RTT(); rtt != 50*time.Millisecond {
+		t.Fatalf("smoothed sample mismatch: have %v, want 50ms", rtt)
+	}
+}
@@ -37,6 +37,8 @@ const (
 	MetricsInboundTraffic   = "p2p/InboundTraffic"   // Name for the registered inbound traffic meter
 	MetricsOutboundConnects = "p2p/OutboundConnects" // Name for the registered outbound connects meter
 	MetricsOutboundTraffic  = "p2p/OutboundTraffic"  // Name for the registered outbound traffic meter
+	MetricsInboundRejected  = "p2p/InboundRejected"  // Name for the registered counter of the inbound connections rejected by the access list
+	MetricsInboundThrottled = "p2p/InboundThrottled" // Name for the registered counter of the inbound connections rejected by the rate limits
 
 	MeteredPeerLimit = 1024 // This amount of peers are individually metered
 )
@@ -47,6 +49,9 @@ var (
 	egressConnectMeter  = metrics.NewRegisteredMeter(MetricsOutboundConnects, nil) // Meter counting the egress connections
 	egressTrafficMeter  = metrics.NewRegisteredMeter(MetricsOutboundTraffic, nil)  // Meter metering the cumulative egress traffic
 
+	ingressRejectedCounter  = metrics.NewRegisteredCounter(MetricsInboundRejected, nil)  // Counter of the ingress connections rejected by the access list
+	ingressThrottledCounter = metrics.NewRegisteredCounter(MetricsInboundThrottled, nil) // Counter of the ingress connections rejected by the rate limits
+
 	PeerIngressRegistry = metrics.NewPrefixedChildRegistry(metrics.EphemeralRegistry, MetricsInboundTraffic+"/")  // Registry containing the peer ingress
 	PeerEgressRegistry  = metrics.NewPrefixedChildRegistry(metrics.EphemeralRegistry, MetricsOutboundTraffic+"/") // Registry containing the peer egress
 
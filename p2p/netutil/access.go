@@ -0,0 +1,155 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package netutil
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+// accessReloadInterval is the minimum time between two checks of the access
+// list file for changes.
+const accessReloadInterval = time.Second
+
+// accessFile is the JSON format of an access list file.
+type accessFile struct {
+	Allow []string `json:"allow"` // CIDR masks of the networks allowed, all if empty
+	Deny  []string `json:"deny"`  // CIDR masks of the networks denied, even if allowed
+}
+
+// AccessList decides which remote IPs may connect, from the networks allowed and
+// denied by a JSON file. The file is reloaded whenever it changes, so that the
+// lists can be updated without restarting the node.
+type AccessList struct {
+	path string
+
+	lock    sync.Mutex
+	allow   *Netlist // Networks allowed, nil if all are
+	deny    *Netlist // Networks denied
+	modTime time.Time
+	checked time.Time
+}
+
+// NewAccessList loads the access list file at the given path.
+func NewAccessList(path string) (*AccessList, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	al := &AccessList{path: path, checked: time.Now()}
+	if err := al.load(info.ModTime()); err != nil {
+		return nil, err
+	}
+	return al, nil
+}
+
+// load reads the access list file, replacing the current lists if valid.
+func (al *AccessList) load(modTime time.Time) error {
+	blob, err := ioutil.ReadFile(al.path)
+	if err != nil {
+		return err
+	}
+	var file accessFile
+	if err := json.Unmarshal(blob, &file); err != nil {
+		return fmt.Errorf("invalid access list %s: %v", al.path, err)
+	}
+	var allow, deny *Netlist
+	if len(file.Allow) > 0 {
+		if allow, err = ParseNetlist(strings.Join(file.Allow, ",")); err != nil {
+			return fmt.Errorf("invalid allowed network in %s: %v", al.path, err)
+		}
+	}
+	if deny, err = ParseNetlist(strings.Join(file.Deny, ",")); err != nil {
+		return fmt.Errorf("invalid denied network in %s: %v", al.path, err)
+	}
+	al.allow, al.deny, al.modTime = allow, deny, modTime
+	return nil
+}
+
+// maybeReload reloads the access list file if it changed since last loaded. An
+// invalid or missing file leaves the current lists in force.
+func (al *AccessList) maybeReload() {
+	if time.Since(al.checked) < accessReloadInterval {
+		return
+	}
+	al.checked = time.Now()
+
+	info, err := os.Stat(al.path)
+	if err != nil {
+		log.Warn("Access list unavailable, keeping current lists", "path", al.path, "err", err)
+		return
+	}
+	if info.ModTime().Equal(al.modTime) {
+		return
+	}
+	if err := al.load(info.ModTime()); err != nil {
+		log.Warn("Failed to reload access list, keeping current lists", "err", err)
+		return
+	}
+	log.Info("Reloaded access list", "path", al.path)
+}
+
+// Allowed reports whether the given remote IP may connect.
+func (al *AccessList) Allowed(ip net.IP) bool {
+	al.lock.Lock()
+	defer al.lock.Unlock()
+
+	al.maybeReload()
+	if al.deny.Contains(ip) {
+		return false
+	}
+	return al.allow == nil || al.allow.Contains(ip)
+}
+
+// Listener wraps a listener to close the connections accepted from remote IPs
+// the access list rejects, counting them in the given counter.
+func (al *AccessList) Listener(listener net.Listener, rejected metrics.Counter) net.Listener {
+	return &accessListener{Listener: listener, access: al, rejected: rejected}
+}
+
+// accessListener is a listener filtering the accepted connections by remote IP.
+type accessListener struct {
+	net.Listener
+	access   *AccessList
+	rejected metrics.Counter
+}
+
+// Accept waits for and returns the next connection the access list allows.
+func (l *accessListener) Accept() (net.Conn, error) {
+	for {
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+		if tcp, ok := conn.RemoteAddr().(*net.TCPAddr); ok && !l.access.Allowed(tcp.IP) {
+			log.Debug("Rejected connection by access list", "addr", conn.RemoteAddr())
+			l.rejected.Inc(1)
+			conn.Close()
+			continue
+		}
+		return conn, nil
+	}
+}
@@ -0,0 +1,129 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package netutil
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+func writeAccessFile(t *testing.T, path, content string, modTime time.Time) {
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAccessList(t *testing.T) {
+	dir, err := ioutil.TempDir("", "accesslist")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "ip-access.json")
+
+	start := time.Now().Add(-time.Hour)
+	writeAccessFile(t, path, `{"allow": ["10.0.0.0/8", "127.0.0.1/32"], "deny": ["10.1.0.0/16"]}`, start)
+	al, err := NewAccessList(path)
+	if err != nil {
+		t.Fatalf("failed to load access list: %v", err)
+	}
+	check := func(ip string, want bool) {
+		t.Helper()
+		if have := al.Allowed(net.ParseIP(ip)); have != want {
+			t.Errorf("%s allowed: have %v, want %v", ip, have, want)
+		}
+	}
+	check("10.2.3.4", true)
+	check("10.1.3.4", false)
+	check("192.168.1.1", false)
+
+	// Changes are picked up once the reload interval elapsed
+	writeAccessFile(t, path, `{"deny": ["10.2.0.0/16"]}`, start.Add(time.Minute))
+	check("192.168.1.1", false)
+	al.checked = time.Time{}
+	check("192.168.1.1", true)
+	check("10.1.3.4", true)
+	check("10.2.3.4", false)
+
+	// Invalid files leave the current lists in force
+	writeAccessFile(t, path, `{"deny": ["10.2.0.0"]}`, start.Add(2*time.Minute))
+	al.checked = time.Time{}
+	check("10.2.3.4", false)
+	check("192.168.1.1", true)
+
+	writeAccessFile(t, path, `{"allow": ["bogus"]}`, start)
+	if _, err := NewAccessList(path); err == nil {
+		t.Fatalf("invalid access list loaded")
+	}
+}
+
+func TestAccessListener(t *testing.T) {
+	dir, err := ioutil.TempDir("", "accesslist")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "ip-access.json")
+
+	writeAccessFile(t, path, `{"deny": ["127.0.0.0/8"]}`, time.Now().Add(-time.Hour))
+	al, err := NewAccessList(path)
+	if err != nil {
+		t.Fatalf("failed to load access list: %v", err)
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rejected := metrics.NewCounterForced()
+	listener := al.Listener(ln, rejected)
+	defer listener.Close()
+
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		if conn, err := listener.Accept(); err == nil {
+			accepted <- conn
+		}
+	}()
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// The denied connection must be closed by the listener
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := conn.Read(make([]byte, 1)); err == nil {
+		t.Fatalf("denied connection left open")
+	}
+	select {
+	case <-accepted:
+		t.Fatalf("denied connection accepted")
+	default:
+	}
+	if count := rejected.Count(); count != 1 {
+		t.Fatalf("rejected count mismatch: have %d, want 1", count)
+	}
+}
@@ -0,0 +1,232 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package netutil
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const (
+	// proxyHeaderTimeout is the time a proxy has to send the PROXY protocol
+	// header of a connection.
+	proxyHeaderTimeout = 5 * time.Second
+
+	// defaultProxyPending is the number of headers read concurrently if no
+	// limit is given.
+	defaultProxyPending = 50
+)
+
+// proxySignature leads the PROXY protocol v2 headers.
+var proxySignature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+
+// PROXY protocol v2 commands and address families.
+const (
+	proxyCommandLocal = 0x0
+	proxyCommandProxy = 0x1
+
+	proxyFamilyTCP4 = 0x11
+	proxyFamilyTCP6 = 0x21
+)
+
+var (
+	errProxySignature = errors.New("missing PROXY protocol v2 signature")
+	errProxyVersion   = errors.New("unsupported PROXY protocol version")
+	errProxyCommand   = errors.New("unknown PROXY protocol command")
+	errProxyAddress   = errors.New("truncated PROXY protocol addresses")
+	errProxyUntrusted = errors.New("PROXY protocol requires a list of trusted proxies")
+
+	errListenerClosed = errors.New("listener closed")
+)
+
+// ProxyListener wraps a listener behind layer-4 load balancers speaking the
+// PROXY protocol v2, replacing the remote address of the connections accepted
+// from the trusted proxies with the address of the client given in their
+// header. The connections of the other sources are accepted as is. The list of
+// trusted proxies is mandatory, as any source could otherwise spoof its address.
+// At most maxPending headers are read concurrently, the default if zero, no more
+// connections being accepted until one of them completes.
+func ProxyListener(listener net.Listener, trusted *Netlist, maxPending int) (net.Listener, error) {
+	if trusted == nil || len(*trusted) == 0 {
+		return nil, errProxyUntrusted
+	}
+	if maxPending <= 0 {
+		maxPending = defaultProxyPending
+	}
+	l := &proxyListener{
+		Listener: listener,
+		trusted:  trusted,
+		slots:    make(chan struct{}, maxPending),
+		conns:    make(chan net.Conn),
+		errc:     make(chan error),
+		closed:   make(chan struct{}),
+	}
+	go l.acceptLoop()
+	return l, nil
+}
+
+// proxyListener reads the PROXY protocol headers of the accepted connections
+// concurrently, so that a proxy slow to send a header doesn't hold the others.
+type proxyListener struct {
+	net.Listener
+	trusted *Netlist
+	slots   chan struct{} // Handshakes in progress, bounding the accepted connections
+
+	conns     chan net.Conn
+	errc      chan error
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+// acceptLoop accepts the connections until the listener fails permanently or
+// is closed, reading the header of each in its own goroutine once a handshake
+// slot is free.
+func (l *proxyListener) acceptLoop() {
+	for {
+		select {
+		case l.slots <- struct{}{}:
+		case <-l.closed:
+			return
+		}
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			<-l.slots
+			select {
+			case l.errc <- err:
+			case <-l.closed:
+				return
+			}
+			if ne, ok := err.(net.Error); ok && ne.Temporary() {
+				continue
+			}
+			return
+		}
+		go l.handshake(conn)
+	}
+}
+
+// handshake reads the PROXY protocol header of a connection from a trusted
+// proxy, closing the connection if the header is missing or invalid. The slot
+// of the handshake is freed once the connection is handed over.
+func (l *proxyListener) handshake(conn net.Conn) {
+	defer func() { <-l.slots }()
+
+	if tcp, ok := conn.RemoteAddr().(*net.TCPAddr); ok && l.trusted.Contains(tcp.IP) {
+		conn.SetReadDeadline(time.Now().Add(proxyHeaderTimeout))
+		remote, err := readProxyHeader(conn)
+		if err != nil {
+			log.Debug("Rejected connection without valid PROXY header", "addr", conn.RemoteAddr(), "err", err)
+			conn.Close()
+			return
+		}
+		conn.SetReadDeadline(time.Time{})
+		if remote != nil {
+			conn = &proxyConn{Conn: conn, remote: remote}
+		}
+	}
+	select {
+	case l.conns <- conn:
+	case <-l.closed:
+		conn.Close()
+	}
+}
+
+// Accept waits for and returns the next connection, its PROXY header read.
+func (l *proxyListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case err := <-l.errc:
+		return nil, err
+	case <-l.closed:
+		return nil, errListenerClosed
+	}
+}
+
+// Close closes the listener, dropping the connections not yet accepted.
+func (l *proxyListener) Close() error {
+	var err error
+	l.closeOnce.Do(func() {
+		close(l.closed)
+		err = l.Listener.Close()
+	})
+	return err
+}
+
+// proxyConn is a connection relayed by a proxy, reporting the address of the
+// client as its remote address.
+type proxyConn struct {
+	net.Conn
+	remote net.Addr
+}
+
+// RemoteAddr returns the address of the client the proxy relays.
+func (c *proxyConn) RemoteAddr() net.Addr {
+	return c.remote
+}
+
+// readProxyHeader reads the PROXY protocol v2 header leading a connection,
+// returning the address of the client, or nil if the proxy sent the connection
+// on its own behalf or relays a protocol other than TCP. Exactly the header is
+// read, leaving the data following it on the connection.
+func readProxyHeader(r io.Reader) (net.Addr, error) {
+	var header [16]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(header[:12], proxySignature) {
+		return nil, errProxySignature
+	}
+	if header[12]>>4 != 2 {
+		return nil, errProxyVersion
+	}
+	payload := make([]byte, binary.BigEndian.Uint16(header[14:]))
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, err
+	}
+	switch header[12] & 0xf {
+	case proxyCommandLocal:
+		return nil, nil
+	case proxyCommandProxy:
+	default:
+		return nil, errProxyCommand
+	}
+	// The source address and port lead the destination ones, TLVs following
+	var size int
+	switch header[13] {
+	case proxyFamilyTCP4:
+		size = net.IPv4len
+	case proxyFamilyTCP6:
+		size = net.IPv6len
+	default:
+		return nil, nil
+	}
+	if len(payload) < 2*size+4 {
+		return nil, errProxyAddress
+	}
+	return &net.TCPAddr{
+		IP:   net.IP(payload[:size]),
+		Port: int(binary.BigEndian.Uint16(payload[2*size:])),
+	}, nil
+}
@@ -0,0 +1,237 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package netutil
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"net"
+	"testing"
+	"time"
+)
+
+// proxyHeader builds the PROXY protocol v2 header of a TCP connection from src
+// to dst, or of a local connection if src is nil.
+func proxyHeader(src, dst *net.TCPAddr) []byte {
+	header := append([]byte{}, proxySignature...)
+	if src == nil {
+		return append(header, 0x20, 0x00, 0x00, 0x00)
+	}
+	var addrs []byte
+	family := byte(proxyFamilyTCP4)
+	if ip := src.IP.To4(); ip != nil {
+		addrs = append(append(addrs, ip...), dst.IP.To4()...)
+	} else {
+		family = proxyFamilyTCP6
+		addrs = append(append(addrs, src.IP.To16()...), dst.IP.To16()...)
+	}
+	addrs = append(addrs, byte(src.Port>>8), byte(src.Port), byte(dst.Port>>8), byte(dst.Port))
+	addrs = append(addrs, 0x04, 0x00, 0x01, 0xff) // A TLV to skip
+
+	header = append(header, 0x21, family, 0, 0)
+	binary.BigEndian.PutUint16(header[14:], uint16(len(addrs)))
+	return append(header, addrs...)
+}
+
+func TestReadProxyHeader(t *testing.T) {
+	dst := &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 30303}
+	tests := []struct {
+		header []byte
+		remote string
+		err    error
+	}{
+		{proxyHeader(&net.TCPAddr{IP: net.ParseIP("192.0.2.7"), Port: 4711}, dst), "192.0.2.7:4711", nil},
+		{proxyHeader(&net.TCPAddr{IP: net.ParseIP("2001:db8::7"), Port: 4711}, &net.TCPAddr{IP: net.ParseIP("2001:db8::1"), Port: 30303}), "[2001:db8::7]:4711", nil},
+		{proxyHeader(nil, nil), "", nil},
+		{[]byte("GET / HTTP/1.1\r\nHost: x\r\n\r\n"), "", errProxySignature},
+		{append(append([]byte{}, proxySignature...), 0x11, 0x11, 0, 0), "", errProxyVersion},
+		{append(append([]byte{}, proxySignature...), 0x21, 0x11, 0, 4, 1, 2, 3, 4), "", errProxyAddress},
+	}
+	for i, tt := range tests {
+		r := bytes.NewReader(append(tt.header, "payload"...))
+		remote, err := readProxyHeader(r)
+		if err != tt.err {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if (remote == nil && tt.remote != "") || (remote != nil && remote.String() != tt.remote) {
+			t.Errorf("test %d: remote mismatch: have %v, want %q", i, remote, tt.remote)
+		}
+		if rest, _ := ioutil.ReadAll(r); string(rest) != "payload" {
+			t.Errorf("test %d: data following the header mismatch: have %q", i, rest)
+		}
+	}
+}
+
+func TestProxyListener(t *testing.T) {
+	inner, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	listener, err := ProxyListener(inner, mustParseNetlist(t, "127.0.0.0/8"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	// A connection without a valid header is dropped, not holding the others
+	silent, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer silent.Close()
+
+	invalid, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer invalid.Close()
+	invalid.Write([]byte("GET / HTTP/1.1\r\n\r\n"))
+
+	client := &net.TCPAddr{IP: net.ParseIP("192.0.2.7"), Port: 4711}
+	proxied, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer proxied.Close()
+	proxied.Write(append(proxyHeader(client, inner.Addr().(*net.TCPAddr)), "hello"...))
+
+	conn, err := listener.Accept()
+	if err != nil {
+		t.Fatalf("failed to accept connection: %v", err)
+	}
+	defer conn.Close()
+	if conn.RemoteAddr().String() != client.String() {
+		t.Fatalf("remote address mismatch: have %v, want %v", conn.RemoteAddr(), client)
+	}
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	buf := make([]byte, 5)
+	if _, err := io.ReadFull(conn, buf); err != nil || string(buf) != "hello" {
+		t.Fatalf("data mismatch: have %q, %v", buf, err)
+	}
+	invalid.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err := invalid.Read(buf); err == nil {
+		t.Fatalf("invalid connection not closed")
+	}
+}
+
+func TestProxyListenerUntrusted(t *testing.T) {
+	inner, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	listener, err := ProxyListener(inner, mustParseNetlist(t, "10.0.0.0/8"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	// Connections from outside the trusted networks are accepted as is
+	direct, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer direct.Close()
+
+	conn, err := listener.Accept()
+	if err != nil {
+		t.Fatalf("failed to accept connection: %v", err)
+	}
+	defer conn.Close()
+	if conn.RemoteAddr().String() != direct.LocalAddr().String() {
+		t.Fatalf("remote address mismatch: have %v, want %v", conn.RemoteAddr(), direct.LocalAddr())
+	}
+}
+
+func TestProxyListenerRequiresTrusted(t *testing.T) {
+	inner, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer inner.Close()
+
+	for _, trusted := range []*Netlist{nil, mustParseNetlist(t, "")} {
+		if _, err := ProxyListener(inner, trusted, 0); err != errProxyUntrusted {
+			t.Errorf("trusted %v: error mismatch: have %v, want %v", trusted, err, errProxyUntrusted)
+		}
+	}
+}
+
+func TestProxyListenerPending(t *testing.T) {
+	inner, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	listener, err := ProxyListener(inner, mustParseNetlist(t, "127.0.0.0/8"), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	// A proxy slow to send its header holds the only handshake slot, the next
+	// connection not being accepted until the first one completes
+	silent, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer silent.Close()
+
+	client := &net.TCPAddr{IP: net.ParseIP("192.0.2.7"), Port: 4711}
+	proxied, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer proxied.Close()
+	proxied.Write(proxyHeader(client, inner.Addr().(*net.TCPAddr)))
+
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		if conn, err := listener.Accept(); err == nil {
+			accepted <- conn
+		}
+	}()
+	select {
+	case conn := <-accepted:
+		conn.Close()
+		t.Fatalf("connection accepted while the handshake slot was held")
+	case <-time.After(100 * time.Millisecond):
+	}
+	silent.Close()
+
+	select {
+	case conn := <-accepted:
+		defer conn.Close()
+		if conn.RemoteAddr().String() != client.String() {
+			t.Fatalf("remote address mismatch: have %v, want %v", conn.RemoteAddr(), client)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("connection not accepted after the handshake slot was freed")
+	}
+}
+
+func mustParseNetlist(t *testing.T, s string) *Netlist {
+	list, err := ParseNetlist(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return list
+}
@@ -23,6 +23,7 @@ import (
 	"net"
 	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common/mclock"
@@ -45,6 +46,10 @@ const (
 	snappyProtocolVersion = 5
 
 	pingInterval = 15 * time.Second
+
+	// rttSmoothing is the weight of the previous round-trip time in the moving
+	// average with a new sample.
+	rttSmoothing = 4
 )
 
 const (
@@ -101,6 +106,10 @@ type PeerEvent struct {
 
 // Peer represents a connected remote node.
 type Peer struct {
+	// Quorum: accessed atomically, kept first for 64-bit alignment
+	pingSent int64 // Unix time in nanoseconds of the unanswered ping, zero if none
+	rtt      int64 // Smoothed round-trip time in nanoseconds, zero until measured
+
 	rw      *conn
 	running map[string]*protoRW
 	log     log.Logger
@@ -251,6 +260,7 @@ func (p *Peer) pingLoop() {
 	for {
 		select {
 		case <-ping.C:
+			atomic.StoreInt64(&p.pingSent, time.Now().UnixNano())
 			if err := SendItems(p.rw, pingMsg); err != nil {
 				p.protoErr <- err
 				return
@@ -262,6 +272,30 @@ func (p *Peer) pingLoop() {
 	}
 }
 
+// Quorum
+// updateRTT measures the round-trip time of the ping answered by a pong received
+// at the given time.
+func (p *Peer) updateRTT(received time.Time) {
+	sent := atomic.SwapInt64(&p.pingSent, 0)
+	if sent == 0 {
+		return // unsolicited pong
+	}
+	sample := received.UnixNano() - sent
+	if sample <= 0 {
+		return
+	}
+	if rtt := atomic.LoadInt64(&p.rtt); rtt != 0 {
+		sample = (rtt*(rttSmoothing-1) + sample) / rttSmoothing
+	}
+	atomic.StoreInt64(&p.rtt, sample)
+}
+
+// RTT returns the smoothed round-trip time measured by pinging the peer, zero
+// until the first ping is answered.
+func (p *Peer) RTT() time.Duration {
+	return time.Duration(atomic.LoadInt64(&p.rtt))
+}
+
 func (p *Peer) readLoop(errc chan<- error) {
 	defer p.wg.Done()
 	for {
@@ -283,6 +317,9 @@ func (p *Peer) handle(msg Msg) error {
 	case msg.Code == pingMsg:
 		msg.Discard()
 		go SendItems(p.rw, pongMsg)
+	case msg.Code == pongMsg:
+		msg.Discard()
+		p.updateRTT(msg.ReceivedAt)
 	case msg.Code == discMsg:
 		var reason [1]DiscReason
 		// This is the last message. We don't need to discard or
@@ -435,8 +472,12 @@ type PeerInfo struct {
 		Inbound       bool   `json:"inbound"`
 		Trusted       bool   `json:"trusted"`
 		Static        bool   `json:"static"`
+		RTT           string `json:"rtt,omitempty"` // Quorum: smoothed round-trip time, if measured
 	} `json:"network"`
-	Protocols map[string]interface{} `json:"protocols"` // Sub-protocol specific metadata fields
+	Protocols map[string]interface{} `json:"protocols"`        // Sub-protocol specific metadata fields
+	Quorum    *QuorumCapabilities    `json:"quorum,omitempty"` // Quorum capabilities advertised by this peer
+
+	Organization string `json:"organization,omitempty"` // Quorum: organization of the certificate of the peer
 }
 
 // Info gathers and returns a collection of metadata known about a peer.
@@ -453,12 +494,18 @@ func (p *Peer) Info() *PeerInfo {
 		Name:      p.Name(),
 		Caps:      caps,
 		Protocols: make(map[string]interface{}),
+		Quorum:    p.rw.quorum,
+
+		Organization: p.rw.org,
 	}
 	info.Network.LocalAddress = p.LocalAddr().String()
 	info.Network.RemoteAddress = p.RemoteAddr().String()
 	info.Network.Inbound = p.rw.is(inboundConn)
 	info.Network.Trusted = p.rw.is(trustedConn)
 	info.Network.Static = p.rw.is(staticDialedConn)
+	if rtt := p.RTT(); rtt != 0 {
+		info.Network.RTT = rtt.String()
+	}
 
 	// Gather all the running protocol infos
 	for _, proto := range p.running {
@@ -83,6 +83,9 @@ const (
 	DiscSelf
 	DiscReadTimeout
 	DiscSubprotocolError = 0x10
+
+	// Quorum
+	DiscIncompatibleQuorum DiscReason = 0x11
 )
 
 var discReasonToString = [...]string{
@@ -99,6 +102,7 @@ var discReasonToString = [...]string{
 	DiscSelf:                "connected to self",
 	DiscReadTimeout:         "read timeout",
 	DiscSubprotocolError:    "subprotocol error",
+	DiscIncompatibleQuorum:  "incompatible quorum capabilities",
 }
 
 func (d DiscReason) String() string {
@@ -14,6 +14,10 @@ import (
 
 const (
 	NODE_NAME_LENGTH = 32
+
+	// ORG_PREFIX marks the entries of the permissioned and disallowed nodes
+	// which name the organization of the peer certificates, rather than nodes.
+	ORG_PREFIX = "org:"
 )
 
 //TODO update this based on permission changes
@@ -71,6 +75,9 @@ func ParsePermissionedNodes(DataDir string) []*enode.Node {
 			log.Error("parsePermissionedNodes: Node URL blank")
 			continue
 		}
+		if strings.HasPrefix(url, ORG_PREFIX) {
+			continue
+		}
 		node, err := enode.ParseV4(url)
 		if err != nil {
 			log.Error("parsePermissionedNodes: Node URL", "url", url, "err", err)
@@ -110,3 +117,89 @@ func isNodeBlackListed(nodeName, dataDir string) bool {
 	}
 	return false
 }
+
+// Quorum
+//
+// ParsePermissionedOrgs returns the organizations permissioned to connect, as
+// listed with the org: prefix in the permissioned nodes. Their nodes must
+// present a certificate of the consortium CA naming the organization.
+func ParsePermissionedOrgs(dataDir string) []string {
+	path := filepath.Join(dataDir, params.PERMISSIONED_CONFIG)
+	blob, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	nodelist := []string{}
+	if err := json.Unmarshal(blob, &nodelist); err != nil {
+		return nil
+	}
+	var orgs []string
+	for _, entry := range nodelist {
+		if strings.HasPrefix(entry, ORG_PREFIX) && len(entry) > len(ORG_PREFIX) {
+			orgs = append(orgs, entry[len(ORG_PREFIX):])
+		}
+	}
+	return orgs
+}
+
+// OrgPermissions reports the organizations permissioned to connect. The
+// permission service sets it on the server when enabled, its organizations
+// replacing the org: entries of the permissioned nodes.
+type OrgPermissions interface {
+	PermissionedOrgs() []string
+}
+
+// SetOrgPermissions sets the source of the organizations permissioned to
+// connect, nil reverting to the permissioned nodes.
+func (srv *Server) SetOrgPermissions(perms OrgPermissions) {
+	srv.orgLock.Lock()
+	defer srv.orgLock.Unlock()
+	srv.orgPerms = perms
+}
+
+// permissionedOrgs returns the organizations permissioned to connect, from the
+// permission service if set, or else from the permissioned nodes.
+func (srv *Server) permissionedOrgs() []string {
+	srv.orgLock.RLock()
+	perms := srv.orgPerms
+	srv.orgLock.RUnlock()
+
+	if perms != nil {
+		return perms.PermissionedOrgs()
+	}
+	return ParsePermissionedOrgs(srv.DataDir)
+}
+
+// isOrgPermissioned checks if the organization of a peer certificate is
+// permissioned to connect, and not disallowed.
+func (srv *Server) isOrgPermissioned(org string) bool {
+	if org == "" {
+		return false
+	}
+	for _, permitted := range srv.permissionedOrgs() {
+		if permitted == org {
+			return !isOrgBlackListed(org, srv.DataDir)
+		}
+	}
+	log.Debug("isOrgPermissioned", "org", org, "DENIED", true)
+	return false
+}
+
+// isOrgBlackListed checks if an organization is listed with the org: prefix in
+// the disallowed nodes.
+func isOrgBlackListed(org, dataDir string) bool {
+	blob, err := ioutil.ReadFile(filepath.Join(dataDir, params.BLACKLIST_CONFIG))
+	if err != nil {
+		return false
+	}
+	nodelist := []string{}
+	if err := json.Unmarshal(blob, &nodelist); err != nil {
+		return true
+	}
+	for _, v := range nodelist {
+		if v == ORG_PREFIX+org {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,72 @@
+package p2p
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// QuorumCapabilities describes the chain and privacy setup of a node. It is
+// appended to the devp2p handshake, so that nodes which could never agree on
+// the chain are disconnected with a clear reason instead of failing later on
+// block verification. Nodes not sending the record are accepted as before.
+type QuorumCapabilities struct {
+	Consensus       string      `json:"consensus"`       // Consensus engine: istanbul, raft, clique or ethash
+	ChainConfigHash common.Hash `json:"chainConfigHash"` // Hash of the chain configuration
+	Privacy         bool        `json:"privacy"`         // Whether a private transaction manager is attached
+	PSV             bool        `json:"psv"`             // Whether private state validation is supported
+	MultiTenant     bool        `json:"multiTenant"`     // Whether the node serves multiple tenants
+
+	// Ignore additional fields (for forward compatibility).
+	Rest []rlp.RawValue `json:"-" rlp:"tail"`
+}
+
+// quorumCapabilityError is returned when the capabilities of a peer are
+// incompatible with the local ones.
+type quorumCapabilityError struct {
+	field         string
+	local, remote interface{}
+}
+
+func (e *quorumCapabilityError) Error() string {
+	return fmt.Sprintf("incompatible quorum %s: local %v, remote %v", e.field, e.local, e.remote)
+}
+
+// CheckCompatible returns an error if a node with the remote capabilities can't
+// take part in the same network. Privacy related flags only restrict which
+// private transactions a node can process, so they don't prevent connecting.
+// Neither does the chain configuration, which legitimately differs between the
+// nodes during a rolling upgrade scheduling a fork.
+func (c *QuorumCapabilities) CheckCompatible(remote *QuorumCapabilities) error {
+	if c.Consensus != remote.Consensus {
+		return &quorumCapabilityError{"consensus", c.Consensus, remote.Consensus}
+	}
+	return nil
+}
+
+// decodeQuorumCapabilities extracts the capabilities from the additional
+// fields of a protocol handshake, returning nil if the peer didn't send any.
+func decodeQuorumCapabilities(hs *protoHandshake) *QuorumCapabilities {
+	if len(hs.Rest) == 0 {
+		return nil
+	}
+	caps := new(QuorumCapabilities)
+	if err := rlp.DecodeBytes(hs.Rest[0], caps); err != nil {
+		return nil
+	}
+	return caps
+}
+
+// checkQuorumCapabilities verifies that the capabilities sent by a peer in its
+// handshake are compatible with the local ones.
+func (srv *Server) checkQuorumCapabilities(c *conn, hs *protoHandshake) error {
+	c.quorum = decodeQuorumCapabilities(hs)
+	if srv.QuorumCapabilities == nil || c.quorum == nil {
+		return nil
+	}
+	if local := srv.QuorumCapabilities.ChainConfigHash; local != c.quorum.ChainConfigHash {
+		srv.log.Debug("Peer chain config mismatch", "id", c.node.ID(), "local", local.TerminalString(), "remote", c.quorum.ChainConfigHash.TerminalString())
+	}
+	return srv.QuorumCapabilities.CheckCompatible(c.quorum)
+}
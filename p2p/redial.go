@@ -0,0 +1,120 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"errors"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/ethereum/go-ethereum/p2p/enode"
+)
+
+const (
+	// initialRedialDelay is the time waited before redialing a static node after
+	// its first failed dial.
+	initialRedialDelay = 5 * time.Second
+
+	// maxRedialDelay is the maximum time waited before redialing a static node.
+	maxRedialDelay = 5 * time.Minute
+
+	// redialJitter is the fraction of the redial delay randomised, for the
+	// nodes of a consortium not to redial each other in lockstep.
+	redialJitter = 0.2
+)
+
+var errUnresolved = errors.New("endpoint not resolved")
+
+// redialState tracks the failed dials of a static node since it was last
+// connected.
+type redialState struct {
+	attempts    int       // Failed dials since the last successful one
+	lastErr     error     // Error of the last dial, nil if it succeeded
+	lastAttempt time.Time // Time the last dial completed
+	nextRetry   time.Time // Earliest time of the next dial
+}
+
+// fail records a failed dial, returning the time to wait for before the next
+// one: doubling on every failure up to maxRedialDelay, with some jitter.
+func (r *redialState) fail(err error, now time.Time) time.Duration {
+	r.attempts++
+	r.lastErr, r.lastAttempt = err, now
+
+	delay := maxRedialDelay
+	if r.attempts <= 16 {
+		if d := initialRedialDelay << uint(r.attempts-1); d < maxRedialDelay {
+			delay = d
+		}
+	}
+	delay += time.Duration((rand.Float64()*2 - 1) * redialJitter * float64(delay))
+	r.nextRetry = now.Add(delay)
+	return delay
+}
+
+// succeed records a successful dial, resetting the backoff.
+func (r *redialState) succeed(now time.Time) {
+	r.attempts, r.lastErr, r.lastAttempt = 0, nil, now
+	r.nextRetry = now.Add(dialHistoryExpiration)
+}
+
+// StaticPeerStatus is the dial status of a static node.
+type StaticPeerStatus struct {
+	Enode       string     `json:"enode"`
+	ID          string     `json:"id"`
+	Trusted     bool       `json:"trusted"`
+	Connected   bool       `json:"connected"`
+	Dialing     bool       `json:"dialing"`
+	Attempts    int        `json:"attempts"` // Failed dials since the last successful one
+	LastError   string     `json:"lastError,omitempty"`
+	LastAttempt *time.Time `json:"lastAttempt,omitempty"`
+	NextRetry   *time.Time `json:"nextRetry,omitempty"` // Unset if connected or dialing
+}
+
+// staticStatus reports the dial status of the static nodes, sorted by ID.
+func (s *dialstate) staticStatus(peers map[enode.ID]*Peer, trusted map[enode.ID]bool, now time.Time) []*StaticPeerStatus {
+	status := make([]*StaticPeerStatus, 0, len(s.static))
+	for id, t := range s.static {
+		_, dialing := s.dialing[id]
+		st := &StaticPeerStatus{
+			Enode:     t.dest.String(),
+			ID:        id.String(),
+			Trusted:   trusted[id],
+			Connected: peers[id] != nil,
+			Dialing:   dialing,
+		}
+		if r := s.redials[id]; r != nil {
+			st.Attempts = r.attempts
+			if r.lastErr != nil {
+				st.LastError = r.lastErr.Error()
+			}
+			lastAttempt := r.lastAttempt
+			st.LastAttempt = &lastAttempt
+
+			if !st.Connected && !st.Dialing {
+				nextRetry := r.nextRetry
+				if nextRetry.Before(now) {
+					nextRetry = now
+				}
+				st.NextRetry = &nextRetry
+			}
+		}
+		status = append(status, st)
+	}
+	sort.Slice(status, func(i, j int) bool { return status[i].ID < status[j].ID })
+	return status
+}
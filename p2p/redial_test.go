@@ -0,0 +1,73 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/p2p/enode"
+)
+
+// Tests that the failing static dials back off exponentially, and that the
+// backoff resets once the node connects.
+func TestStaticRedialBackoff(t *testing.T) {
+	var (
+		node  = newNode(uintID(1), nil)
+		state = newDialState(enode.ID{}, []*enode.Node{node}, nil, fakeTable{}, 0, nil)
+		now   = time.Unix(1000000, 0)
+		fail  = errors.New("connection refused")
+	)
+	var last time.Duration
+	for attempt := 1; attempt <= 10; attempt++ {
+		tasks := state.newTasks(0, nil, now)
+		if len(tasks) != 1 {
+			t.Fatalf("attempt %d: have %d tasks, want the static dial", attempt, len(tasks))
+		}
+		task := tasks[0].(*dialTask)
+		task.err = fail
+		state.taskDone(task, now)
+
+		status := state.staticStatus(nil, map[enode.ID]bool{node.ID(): true}, now)
+		if len(status) != 1 || status[0].Attempts != attempt || status[0].LastError != fail.Error() || !status[0].Trusted {
+			t.Fatalf("attempt %d: wrong status %+v", attempt, status[0])
+		}
+		delay := status[0].NextRetry.Sub(now)
+		if delay > maxRedialDelay+time.Duration(redialJitter*float64(maxRedialDelay)) {
+			t.Fatalf("attempt %d: delay %v over the maximum", attempt, delay)
+		}
+		if attempt > 1 && delay <= last && last < maxRedialDelay/2 {
+			t.Errorf("attempt %d: delay %v not longer than %v", attempt, delay, last)
+		}
+		// No redial before the delay expires
+		if tasks := state.newTasks(1, nil, now.Add(delay-time.Second)); len(tasks) != 0 {
+			t.Fatalf("attempt %d: redialed before the delay", attempt)
+		}
+		last, now = delay, now.Add(delay+time.Second)
+	}
+	// A successful dial resets the backoff
+	task := state.newTasks(0, nil, now)[0].(*dialTask)
+	task.err = nil
+	state.taskDone(task, now)
+
+	peers := map[enode.ID]*Peer{node.ID(): {rw: &conn{flags: staticDialedConn, node: node}}}
+	status := state.staticStatus(peers, nil, now)
+	if status[0].Attempts != 0 || status[0].LastError != "" || !status[0].Connected || status[0].NextRetry != nil {
+		t.Fatalf("wrong status after connecting: %+v", status[0])
+	}
+}
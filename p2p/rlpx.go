@@ -175,15 +175,15 @@ func readProtocolHandshake(rw MsgReader, our *protoHandshake) (*protoHandshake,
 // messages. the protocol handshake is the first authenticated message
 // and also verifies whether the encryption handshake 'worked' and the
 // remote side actually provided the right public key.
-func (t *rlpx) doEncHandshake(prv *ecdsa.PrivateKey, dial *ecdsa.PublicKey) (*ecdsa.PublicKey, error) {
+func (t *rlpx) doEncHandshake(key NodeSigner, dial *ecdsa.PublicKey) (*ecdsa.PublicKey, error) {
 	var (
 		sec secrets
 		err error
 	)
 	if dial == nil {
-		sec, err = receiverEncHandshake(t.fd, prv)
+		sec, err = receiverEncHandshake(t.fd, key)
 	} else {
-		sec, err = initiatorEncHandshake(t.fd, prv, dial)
+		sec, err = initiatorEncHandshake(t.fd, key, dial)
 	}
 	if err != nil {
 		return nil, err
@@ -270,17 +270,17 @@ func (h *encHandshake) secrets(auth, authResp []byte) (secrets, error) {
 
 // staticSharedSecret returns the static shared secret, the result
 // of key agreement between the local and remote static node key.
-func (h *encHandshake) staticSharedSecret(prv *ecdsa.PrivateKey) ([]byte, error) {
-	return ecies.ImportECDSA(prv).GenerateShared(h.remote, sskLen, sskLen)
+func (h *encHandshake) staticSharedSecret(key NodeSigner) ([]byte, error) {
+	return sharedKeyFunc(key)(h.remote, sskLen, sskLen)
 }
 
 // initiatorEncHandshake negotiates a session token on conn.
 // it should be called on the dialing side of the connection.
 //
-// prv is the local client's private key.
-func initiatorEncHandshake(conn io.ReadWriter, prv *ecdsa.PrivateKey, remote *ecdsa.PublicKey) (s secrets, err error) {
+// key holds the local client's private key.
+func initiatorEncHandshake(conn io.ReadWriter, key NodeSigner, remote *ecdsa.PublicKey) (s secrets, err error) {
 	h := &encHandshake{initiator: true, remote: ecies.ImportECDSAPublic(remote)}
-	authMsg, err := h.makeAuthMsg(prv)
+	authMsg, err := h.makeAuthMsg(key)
 	if err != nil {
 		return s, err
 	}
@@ -293,7 +293,7 @@ func initiatorEncHandshake(conn io.ReadWriter, prv *ecdsa.PrivateKey, remote *ec
 	}
 
 	authRespMsg := new(authRespV4)
-	authRespPacket, err := readHandshakeMsg(authRespMsg, encAuthRespLen, prv, conn)
+	authRespPacket, err := readHandshakeMsg(authRespMsg, encAuthRespLen, key, conn)
 	if err != nil {
 		return s, err
 	}
@@ -304,7 +304,7 @@ func initiatorEncHandshake(conn io.ReadWriter, prv *ecdsa.PrivateKey, remote *ec
 }
 
 // makeAuthMsg creates the initiator handshake message.
-func (h *encHandshake) makeAuthMsg(prv *ecdsa.PrivateKey) (*authMsgV4, error) {
+func (h *encHandshake) makeAuthMsg(key NodeSigner) (*authMsgV4, error) {
 	// Generate random initiator nonce.
 	h.initNonce = make([]byte, shaLen)
 	_, err := rand.Read(h.initNonce)
@@ -318,7 +318,7 @@ func (h *encHandshake) makeAuthMsg(prv *ecdsa.PrivateKey) (*authMsgV4, error) {
 	}
 
 	// Sign known message: static-shared-secret ^ nonce
-	token, err := h.staticSharedSecret(prv)
+	token, err := h.staticSharedSecret(key)
 	if err != nil {
 		return nil, err
 	}
@@ -330,7 +330,7 @@ func (h *encHandshake) makeAuthMsg(prv *ecdsa.PrivateKey) (*authMsgV4, error) {
 
 	msg := new(authMsgV4)
 	copy(msg.Signature[:], signature)
-	copy(msg.InitiatorPubkey[:], crypto.FromECDSAPub(&prv.PublicKey)[1:])
+	copy(msg.InitiatorPubkey[:], crypto.FromECDSAPub(key.PublicKey())[1:])
 	copy(msg.Nonce[:], h.initNonce)
 	msg.Version = 4
 	return msg, nil
@@ -345,15 +345,15 @@ func (h *encHandshake) handleAuthResp(msg *authRespV4) (err error) {
 // receiverEncHandshake negotiates a session token on conn.
 // it should be called on the listening side of the connection.
 //
-// prv is the local client's private key.
-func receiverEncHandshake(conn io.ReadWriter, prv *ecdsa.PrivateKey) (s secrets, err error) {
+// key holds the local client's private key.
+func receiverEncHandshake(conn io.ReadWriter, key NodeSigner) (s secrets, err error) {
 	authMsg := new(authMsgV4)
-	authPacket, err := readHandshakeMsg(authMsg, encAuthMsgLen, prv, conn)
+	authPacket, err := readHandshakeMsg(authMsg, encAuthMsgLen, key, conn)
 	if err != nil {
 		return s, err
 	}
 	h := new(encHandshake)
-	if err := h.handleAuthMsg(authMsg, prv); err != nil {
+	if err := h.handleAuthMsg(authMsg, key); err != nil {
 		return s, err
 	}
 
@@ -376,7 +376,7 @@ func receiverEncHandshake(conn io.ReadWriter, prv *ecdsa.PrivateKey) (s secrets,
 	return h.secrets(authPacket, authRespPacket)
 }
 
-func (h *encHandshake) handleAuthMsg(msg *authMsgV4, prv *ecdsa.PrivateKey) error {
+func (h *encHandshake) handleAuthMsg(msg *authMsgV4, key NodeSigner) error {
 	// Import the remote identity.
 	rpub, err := importPublicKey(msg.InitiatorPubkey[:])
 	if err != nil {
@@ -395,7 +395,7 @@ func (h *encHandshake) handleAuthMsg(msg *authMsgV4, prv *ecdsa.PrivateKey) erro
 	}
 
 	// Check the signature.
-	token, err := h.staticSharedSecret(prv)
+	token, err := h.staticSharedSecret(key)
 	if err != nil {
 		return err
 	}
@@ -476,14 +476,14 @@ type plainDecoder interface {
 	decodePlain([]byte)
 }
 
-func readHandshakeMsg(msg plainDecoder, plainSize int, prv *ecdsa.PrivateKey, r io.Reader) ([]byte, error) {
+func readHandshakeMsg(msg plainDecoder, plainSize int, key NodeSigner, r io.Reader) ([]byte, error) {
 	buf := make([]byte, plainSize)
 	if _, err := io.ReadFull(r, buf); err != nil {
 		return buf, err
 	}
 	// Attempt decoding pre-EIP-8 "plain" format.
-	key := ecies.ImportECDSA(prv)
-	if dec, err := key.Decrypt(buf, nil, nil); err == nil {
+	pub, shared := ecies.ImportECDSAPublic(key.PublicKey()), sharedKeyFunc(key)
+	if dec, err := ecies.DecryptShared(pub, shared, buf, nil, nil); err == nil {
 		msg.decodePlain(dec)
 		return buf, nil
 	}
@@ -497,7 +497,7 @@ func readHandshakeMsg(msg plainDecoder, plainSize int, prv *ecdsa.PrivateKey, r
 	if _, err := io.ReadFull(r, buf[plainSize:]); err != nil {
 		return buf, err
 	}
-	dec, err := key.Decrypt(buf[2:], nil, prefix)
+	dec, err := ecies.DecryptShared(pub, shared, buf[2:], nil, prefix)
 	if err != nil {
 		return buf, err
 	}
@@ -97,7 +97,7 @@ func testEncHandshake(token []byte) error {
 		defer func() { output <- r }()
 		defer fd0.Close()
 
-		r.pubkey, r.err = c0.doEncHandshake(prv0, &prv1.PublicKey)
+		r.pubkey, r.err = c0.doEncHandshake(&localSigner{prv0}, &prv1.PublicKey)
 		if r.err != nil {
 			return
 		}
@@ -110,7 +110,7 @@ func testEncHandshake(token []byte) error {
 		defer func() { output <- r }()
 		defer fd1.Close()
 
-		r.pubkey, r.err = c1.doEncHandshake(prv1, nil)
+		r.pubkey, r.err = c1.doEncHandshake(&localSigner{prv1}, nil)
 		if r.err != nil {
 			return
 		}
@@ -167,7 +167,7 @@ func TestProtocolHandshake(t *testing.T) {
 		defer wg.Done()
 		defer fd0.Close()
 		rlpx := newRLPX(fd0)
-		rpubkey, err := rlpx.doEncHandshake(prv0, &prv1.PublicKey)
+		rpubkey, err := rlpx.doEncHandshake(&localSigner{prv0}, &prv1.PublicKey)
 		if err != nil {
 			t.Errorf("dial side enc handshake failed: %v", err)
 			return
@@ -193,7 +193,7 @@ func TestProtocolHandshake(t *testing.T) {
 		defer wg.Done()
 		defer fd1.Close()
 		rlpx := newRLPX(fd1)
-		rpubkey, err := rlpx.doEncHandshake(prv1, nil)
+		rpubkey, err := rlpx.doEncHandshake(&localSigner{prv1}, nil)
 		if err != nil {
 			t.Errorf("listen side enc handshake failed: %v", err)
 			return
@@ -533,7 +533,7 @@ func TestHandshakeForwardCompatibility(t *testing.T) {
 	for _, test := range eip8HandshakeAuthTests {
 		r := bytes.NewReader(unhex(test.input))
 		msg := new(authMsgV4)
-		ciphertext, err := readHandshakeMsg(msg, encAuthMsgLen, keyB, r)
+		ciphertext, err := readHandshakeMsg(msg, encAuthMsgLen, &localSigner{keyB}, r)
 		if err != nil {
 			t.Errorf("error for input %x:\n  %v", unhex(test.input), err)
 			continue
@@ -552,7 +552,7 @@ func TestHandshakeForwardCompatibility(t *testing.T) {
 		input := unhex(test.input)
 		r := bytes.NewReader(input)
 		msg := new(authRespV4)
-		ciphertext, err := readHandshakeMsg(msg, encAuthRespLen, keyA, r)
+		ciphertext, err := readHandshakeMsg(msg, encAuthRespLen, &localSigner{keyA}, r)
 		if err != nil {
 			t.Errorf("error for input %x:\n  %v", input, err)
 			continue
@@ -580,7 +580,7 @@ func TestHandshakeForwardCompatibility(t *testing.T) {
 		wantMAC            = unhex("2ea74ec5dae199227dff1af715362700e989d889d7a493cb0639691efb8e5f98")
 		wantFooIngressHash = unhex("0c7ec6340062cc46f5e9f1e3cf86f8c8c403c5a0964f5df0ebd34a75ddc86db5")
 	)
-	if err := hs.handleAuthMsg(authMsg, keyB); err != nil {
+	if err := hs.handleAuthMsg(authMsg, &localSigner{keyB}); err != nil {
 		t.Fatalf("handleAuthMsg: %v", err)
 	}
 	derived, err := hs.secrets(authCiphertext, authRespCiphertext)
@@ -66,6 +66,13 @@ type Config struct {
 	// This field must be set to a valid secp256k1 private key.
 	PrivateKey *ecdsa.PrivateKey `toml:"-"`
 
+	// Quorum
+	//
+	// Signer, if set, performs the RLPx key agreement and signs the node record
+	// in place of PrivateKey, for a node key held in a KMS. The discovery still
+	// needs PrivateKey, which may be left unset with NoDiscovery.
+	Signer NodeSigner `toml:"-"`
+
 	// MaxPeers is the maximum number of peers that can be
 	// connected. It must be greater than zero.
 	MaxPeers int
@@ -150,6 +157,45 @@ type Config struct {
 	EnableNodePermission bool `toml:",omitempty"`
 
 	DataDir string `toml:",omitempty"`
+
+	// QuorumCapabilities, if set, is advertised in the protocol handshake and
+	// peers with incompatible capabilities are disconnected.
+	QuorumCapabilities *QuorumCapabilities `toml:"-"`
+
+	// AccessList, if set, filters the inbound connections by remote IP.
+	AccessList *netutil.AccessList `toml:"-"`
+
+	// ProxyProtocol reads the PROXY protocol v2 header of the inbound connections
+	// from the ProxyTrusted networks, which must be given, taking the remote IP
+	// from it for the listeners behind layer-4 load balancers.
+	ProxyProtocol bool             `toml:",omitempty"`
+	ProxyTrusted  *netutil.Netlist `toml:",omitempty"`
+
+	// PeerCAFile, if set, holds the certificates of the consortium CA, which the
+	// peers must present a certificate issued by. PeerCertFile holds the
+	// certificate chain of the node, presented to the peers.
+	PeerCAFile   string `toml:",omitempty"`
+	PeerCertFile string `toml:",omitempty"`
+
+	// LatencyAware orders the dynamic dials by the round-trip time measured of the
+	// nodes, preferring the nearest ones while keeping CrossRegionPeers connected
+	// to nodes at least CrossRegionRTT away.
+	LatencyAware     bool          `toml:",omitempty"`
+	CrossRegionRTT   time.Duration `toml:",omitempty"`
+	CrossRegionPeers int           `toml:",omitempty"`
+
+	// InboundRateLimit is the number of inbound connections accepted from an IP
+	// per minute, and MaxHandshakesPerIP the number of handshakes run
+	// concurrently with an IP. Zero disables the limits.
+	InboundRateLimit   int `toml:",omitempty"`
+	MaxHandshakesPerIP int `toml:",omitempty"`
+
+	// HandshakePuzzle, if non-zero, is the difficulty in bits of the proof of
+	// work the connecting nodes solve before the encryption handshake. The nodes
+	// dialing out solve the puzzle of the nodes they dial, so all the nodes of the
+	// network must have the same setting.
+	HandshakePuzzle uint `toml:",omitempty"`
+
 	// Logger is a custom logger to use with the p2p.Server.
 	Logger log.Logger `toml:",omitempty"`
 }
@@ -172,12 +218,17 @@ type Server struct {
 	ntab         discoverTable
 	listener     net.Listener
 	ourHandshake *protoHandshake
+	peerCerts    *PeerCertificates // Quorum: nil unless the peers present certificates
+	throttle     *inboundThrottle  // Quorum: limits the inbound connections of each IP
+	orgLock      sync.RWMutex      // Quorum: protects orgPerms
+	orgPerms     OrgPermissions    // Quorum: nil unless set by the permission service
 	lastLookup   time.Time
 	DiscV5       *discv5.Network
 
 	// These are for Peers, PeerCount (and nothing else).
 	peerOp     chan peerOpFunc
 	peerOpDone chan struct{}
+	staticOp   chan chan []*StaticPeerStatus // Quorum: for StaticPeerStatus
 
 	quit          chan struct{}
 	addstatic     chan *enode.Node
@@ -222,11 +273,14 @@ type conn struct {
 	cont  chan error // The run loop uses cont to signal errors to SetupConn.
 	caps  []Cap      // valid after the protocol handshake
 	name  string     // valid after the protocol handshake
+
+	quorum *QuorumCapabilities // valid after the protocol handshake, nil if not sent
+	org    string              // valid after the protocol handshake, organization of the peer certificate
 }
 
 type transport interface {
 	// The two handshakes.
-	doEncHandshake(prv *ecdsa.PrivateKey, dialDest *ecdsa.PublicKey) (*ecdsa.PublicKey, error)
+	doEncHandshake(key NodeSigner, dialDest *ecdsa.PublicKey) (*ecdsa.PublicKey, error)
 	doProtoHandshake(our *protoHandshake) (*protoHandshake, error)
 	// The MsgReadWriter can only be used after the encryption
 	// handshake has completed. The code uses conn.id to track this
@@ -316,6 +370,19 @@ func (srv *Server) PeerCount() int {
 	return count
 }
 
+// Quorum
+// StaticPeerStatus returns the dial status of the static nodes: whether they are
+// connected and, if not, why the last dial failed and when the next one is.
+func (srv *Server) StaticPeerStatus() []*StaticPeerStatus {
+	ch := make(chan []*StaticPeerStatus, 1)
+	select {
+	case srv.staticOp <- ch:
+		return <-ch
+	case <-srv.quit:
+		return nil
+	}
+}
+
 // AddPeer connects to the given node and maintains the connection until the
 // server is shut down. If the connection fails for any reason, the server will
 // attempt to reconnect the peer.
@@ -363,7 +430,7 @@ func (srv *Server) Self() *enode.Node {
 	srv.lock.Unlock()
 
 	if ln == nil {
-		return enode.NewV4(&srv.PrivateKey.PublicKey, net.ParseIP("0.0.0.0"), 0, 0)
+		return enode.NewV4(srv.nodeSigner().PublicKey(), net.ParseIP("0.0.0.0"), 0, 0)
 	}
 	return ln.Node()
 }
@@ -386,6 +453,28 @@ func (srv *Server) Stop() {
 	srv.loopWG.Wait()
 }
 
+// Quorum
+//
+// Rekey restarts the server with a new node key, dropping all the peers. It is
+// used by a standby node taking over the identity of its primary.
+func (srv *Server) Rekey(key *ecdsa.PrivateKey) error {
+	srv.Stop()
+	srv.PrivateKey = key
+	srv.Signer = nil
+	return srv.Start()
+}
+
+// Quorum
+//
+// nodeSigner returns the signer of the node key, wrapping PrivateKey if there
+// is no Signer.
+func (srv *Server) nodeSigner() NodeSigner {
+	if srv.Signer != nil {
+		return srv.Signer
+	}
+	return &localSigner{key: srv.PrivateKey}
+}
+
 // sharedUDPConn implements a shared connection. Write sends messages to the underlying connection while read returns
 // messages that were found unprocessable and sent to the unhandled channel by the primary listener.
 type sharedUDPConn struct {
@@ -430,9 +519,12 @@ func (srv *Server) Start() (err error) {
 	}
 
 	// static fields
-	if srv.PrivateKey == nil {
+	if srv.PrivateKey == nil && srv.Signer == nil {
 		return fmt.Errorf("Server.PrivateKey must be set to a non-nil key")
 	}
+	if srv.PrivateKey == nil && (!srv.NoDiscovery || srv.DiscoveryV5) {
+		return fmt.Errorf("Server.PrivateKey must be set for the discovery")
+	}
 	if srv.newTransport == nil {
 		srv.newTransport = newRLPX
 	}
@@ -449,6 +541,11 @@ func (srv *Server) Start() (err error) {
 	srv.removetrusted = make(chan *enode.Node)
 	srv.peerOp = make(chan peerOpFunc)
 	srv.peerOpDone = make(chan struct{})
+	srv.staticOp = make(chan chan []*StaticPeerStatus)
+	if srv.HandshakePuzzle > maxPuzzleDifficulty {
+		return fmt.Errorf("handshake puzzle difficulty %d above %d", srv.HandshakePuzzle, maxPuzzleDifficulty)
+	}
+	srv.throttle = newInboundThrottle(srv.InboundRateLimit, srv.MaxHandshakesPerIP, mclock.System{})
 
 	if err := srv.setupLocalNode(); err != nil {
 		return err
@@ -464,6 +561,10 @@ func (srv *Server) Start() (err error) {
 
 	dynPeers := srv.maxDialedConns()
 	dialer := newDialState(srv.localnode.ID(), srv.StaticNodes, srv.BootstrapNodes, srv.ntab, dynPeers, srv.NetRestrict)
+	// Quorum
+	if srv.LatencyAware {
+		dialer.latency = newLatencyPolicy(srv.CrossRegionRTT, srv.CrossRegionPeers)
+	}
 	srv.loopWG.Add(1)
 	go srv.run(dialer)
 	return nil
@@ -471,12 +572,31 @@ func (srv *Server) Start() (err error) {
 
 func (srv *Server) setupLocalNode() error {
 	// Create the devp2p handshake.
-	pubkey := crypto.FromECDSAPub(&srv.PrivateKey.PublicKey)
+	pubkey := crypto.FromECDSAPub(srv.nodeSigner().PublicKey())
 	srv.ourHandshake = &protoHandshake{Version: baseProtocolVersion, Name: srv.Name, ID: pubkey[1:]}
 	for _, p := range srv.Protocols {
 		srv.ourHandshake.Caps = append(srv.ourHandshake.Caps, p.cap())
 	}
 	sort.Sort(capsByNameAndVersion(srv.ourHandshake.Caps))
+	if srv.QuorumCapabilities != nil {
+		enc, err := rlp.EncodeToBytes(srv.QuorumCapabilities)
+		if err != nil {
+			return err
+		}
+		srv.ourHandshake.Rest = []rlp.RawValue{enc}
+	}
+	if srv.PeerCAFile != "" {
+		peerCerts, err := LoadPeerCertificates(srv.PeerCAFile, srv.PeerCertFile)
+		if err != nil {
+			return fmt.Errorf("failed to load the peer certificates: %v", err)
+		}
+		srv.peerCerts = peerCerts
+		if len(srv.peerCerts.chain) > 0 {
+			if err := encodePeerCertificates(srv.ourHandshake, srv.peerCerts.chain); err != nil {
+				return err
+			}
+		}
+	}
 
 	// Create the local node.
 	db, err := enode.OpenDB(srv.Config.NodeDatabase)
@@ -484,7 +604,8 @@ func (srv *Server) setupLocalNode() error {
 		return err
 	}
 	srv.nodedb = db
-	srv.localnode = enode.NewLocalNode(db, srv.PrivateKey)
+	signer := srv.nodeSigner()
+	srv.localnode = enode.NewLocalNodeWithSigner(db, signer.PublicKey(), signer.Sign)
 	srv.localnode.SetFallbackIP(net.IP{127, 0, 0, 1})
 	srv.localnode.Set(capsByNameAndVersion(srv.ourHandshake.Caps))
 	// TODO: check conflicts
@@ -584,6 +705,22 @@ func (srv *Server) setupListening() error {
 	}
 	laddr := listener.Addr().(*net.TCPAddr)
 	srv.ListenAddr = laddr.String()
+	// Quorum
+	if srv.ProxyProtocol {
+		tokens := defaultMaxPendingPeers
+		if srv.MaxPendingPeers > 0 {
+			tokens = srv.MaxPendingPeers
+		}
+		proxied, err := netutil.ProxyListener(listener, srv.ProxyTrusted, tokens)
+		if err != nil {
+			listener.Close()
+			return err
+		}
+		listener = proxied
+	}
+	if srv.AccessList != nil {
+		listener = srv.AccessList.Listener(listener, ingressRejectedCounter)
+	}
 	srv.listener = listener
 	srv.localnode.Set(enr.TCP(laddr.Port))
 
@@ -606,6 +743,7 @@ type dialer interface {
 	taskDone(task, time.Time)
 	addStatic(*enode.Node)
 	removeStatic(*enode.Node)
+	staticStatus(peers map[enode.ID]*Peer, trusted map[enode.ID]bool, now time.Time) []*StaticPeerStatus // Quorum
 }
 
 func (srv *Server) run(dialstate dialer) {
@@ -704,6 +842,9 @@ running:
 			// This channel is used by Peers and PeerCount.
 			op(peers)
 			srv.peerOpDone <- struct{}{}
+		case ch := <-srv.staticOp:
+			// Quorum: this channel is used by StaticPeerStatus.
+			ch <- dialstate.staticStatus(peers, trusted, time.Now())
 		case t := <-taskdone:
 			// A task got done. Tell dialstate about it so it
 			// can update its state and remove it from the active
@@ -874,10 +1015,19 @@ func (srv *Server) listenLoop() {
 		if tcp, ok := fd.RemoteAddr().(*net.TCPAddr); ok {
 			ip = tcp.IP
 		}
+		// Quorum: reject the IPs connecting too often or with too many handshakes
+		if err := srv.throttle.admit(ip); err != nil {
+			srv.log.Debug("Rejected conn (throttled)", "addr", fd.RemoteAddr(), "err", err)
+			ingressThrottledCounter.Inc(1)
+			fd.Close()
+			slots <- struct{}{}
+			continue
+		}
 		fd = newMeteredConn(fd, true, ip)
 		srv.log.Trace("Accepted connection", "addr", fd.RemoteAddr())
 		go func() {
 			srv.SetupConn(fd, inboundConn, nil)
+			srv.throttle.release(ip)
 			slots <- struct{}{}
 		}()
 	}
@@ -912,8 +1062,21 @@ func (srv *Server) setupConn(c *conn, flags connFlag, dialDest *enode.Node) erro
 			return fmt.Errorf("dial destination doesn't have a secp256k1 public key")
 		}
 	}
+	// Quorum: have the connecting node solve the handshake puzzle first
+	if srv.HandshakePuzzle > 0 {
+		var err error
+		if dialDest == nil {
+			err = issuePuzzle(c.fd, srv.HandshakePuzzle)
+		} else {
+			err = solvePuzzle(c.fd)
+		}
+		if err != nil {
+			srv.log.Trace("Failed handshake puzzle", "addr", c.fd.RemoteAddr(), "conn", c.flags, "err", err)
+			return err
+		}
+	}
 	// Run the encryption handshake.
-	remotePubkey, err := c.doEncHandshake(srv.PrivateKey, dialPubkey)
+	remotePubkey, err := c.doEncHandshake(srv.nodeSigner(), dialPubkey)
 	if err != nil {
 		srv.log.Trace("Failed RLPx handshake", "addr", c.fd.RemoteAddr(), "conn", c.flags, "err", err)
 		return err
@@ -950,10 +1113,11 @@ func (srv *Server) setupConn(c *conn, flags connFlag, dialDest *enode.Node) erro
 		"Connection ID", c.node.ID(),
 		"Connection String", c.node.ID().String())
 
+	var permitByOrg bool // Quorum: node only permissioned by organization, checked after the protocol handshake
+	node := c.node.ID().String()
+	direction := "INCOMING"
 	if srv.EnableNodePermission {
 		clog.Trace("Node Permissioning is Enabled.")
-		node := c.node.ID().String()
-		direction := "INCOMING"
 		if dialDest != nil {
 			node = dialDest.ID().String()
 			direction = "OUTGOING"
@@ -961,7 +1125,11 @@ func (srv *Server) setupConn(c *conn, flags connFlag, dialDest *enode.Node) erro
 		}
 
 		if !isNodePermissioned(node, currentNode, srv.DataDir, direction) {
-			return newPeerError(errPermissionDenied, "id=%s…%s %s id=%s…%s", currentNode[:4], currentNode[len(currentNode)-4:], direction, node[:4], node[len(node)-4:])
+			// The organization of the peer is known once its certificate is received
+			if srv.peerCerts == nil || len(srv.permissionedOrgs()) == 0 || isNodeBlackListed(node, srv.DataDir) {
+				return newPeerError(errPermissionDenied, "id=%s…%s %s id=%s…%s", currentNode[:4], currentNode[len(currentNode)-4:], direction, node[:4], node[len(node)-4:])
+			}
+			permitByOrg = true
 		}
 	} else {
 		clog.Trace("Node Permissioning is Disabled.")
@@ -989,6 +1157,17 @@ func (srv *Server) setupConn(c *conn, flags connFlag, dialDest *enode.Node) erro
 		return DiscUnexpectedIdentity
 	}
 	c.caps, c.name = phs.Caps, phs.Name
+	if err := srv.checkPeerCertificate(c, phs); err != nil {
+		clog.Debug("Rejected peer with invalid certificate", "err", err)
+		return DiscUnexpectedIdentity
+	}
+	if permitByOrg && !srv.isOrgPermissioned(c.org) {
+		return newPeerError(errPermissionDenied, "id=%s…%s %s id=%s…%s org=%q", currentNode[:4], currentNode[len(currentNode)-4:], direction, node[:4], node[len(node)-4:], c.org)
+	}
+	if err := srv.checkQuorumCapabilities(c, phs); err != nil {
+		clog.Warn("Rejected peer with incompatible quorum capabilities", "err", err)
+		return DiscIncompatibleQuorum
+	}
 	err = srv.checkpoint(c, srv.addpeer)
 	if err != nil {
 		clog.Trace("Rejected peer", "err", err)
@@ -1075,6 +1254,7 @@ type NodeInfo struct {
 	} `json:"ports"`
 	ListenAddr string                 `json:"listenAddr"`
 	Protocols  map[string]interface{} `json:"protocols"`
+	Quorum     *QuorumCapabilities    `json:"quorum,omitempty"` // Capabilities advertised to peers
 }
 
 // NodeInfo gathers and returns a collection of metadata known about the host.
@@ -1088,6 +1268,7 @@ func (srv *Server) NodeInfo() *NodeInfo {
 		IP:         node.IP().String(),
 		ListenAddr: srv.ListenAddr,
 		Protocols:  make(map[string]interface{}),
+		Quorum:     srv.QuorumCapabilities,
 	}
 	info.Ports.Discovery = node.UDP()
 	info.Ports.Listener = node.TCP()
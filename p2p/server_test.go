@@ -25,15 +25,18 @@ import (
 	"os"
 	"path"
 	"reflect"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/crypto/sha3"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/p2p/enode"
 	"github.com/ethereum/go-ethereum/p2p/enr"
 	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -59,7 +62,7 @@ func newTestTransport(rpub *ecdsa.PublicKey, fd net.Conn) transport {
 	return &testTransport{rpub: rpub, rlpx: wrapped}
 }
 
-func (c *testTransport) doEncHandshake(prv *ecdsa.PrivateKey, dialDest *ecdsa.PublicKey) (*ecdsa.PublicKey, error) {
+func (c *testTransport) doEncHandshake(key NodeSigner, dialDest *ecdsa.PublicKey) (*ecdsa.PublicKey, error) {
 	return c.rpub, nil
 }
 
@@ -343,6 +346,9 @@ func (tg taskgen) addStatic(*enode.Node) {
 }
 func (tg taskgen) removeStatic(*enode.Node) {
 }
+func (tg taskgen) staticStatus(map[enode.ID]*Peer, map[enode.ID]bool, time.Time) []*StaticPeerStatus {
+	return nil
+}
 
 type testTask struct {
 	index  int
@@ -640,6 +646,55 @@ func TestServerSetupConn_whenNotPermissioned(t *testing.T) {
 	assert.Equal(t, errPermissionDenied, perr.code)
 }
 
+func TestServerSetupConn_quorumCapabilities(t *testing.T) {
+	var (
+		clientkey, srvkey = newkey(), newkey()
+		clientpub         = &clientkey.PublicKey
+		local             = &QuorumCapabilities{Consensus: "istanbul", ChainConfigHash: common.HexToHash("0x01"), Privacy: true}
+	)
+	encode := func(caps *QuorumCapabilities) []rlp.RawValue {
+		enc, _ := rlp.EncodeToBytes(caps)
+		return []rlp.RawValue{enc}
+	}
+	tests := []struct {
+		rest         []rlp.RawValue
+		wantCloseErr error
+	}{
+		// Peers without capabilities and compatible ones pass the check, only to
+		// be dropped for lack of common protocols
+		{nil, DiscUselessPeer},
+		{encode(&QuorumCapabilities{Consensus: "istanbul", ChainConfigHash: common.HexToHash("0x01")}), DiscUselessPeer},
+		{encode(&QuorumCapabilities{Consensus: "raft", ChainConfigHash: common.HexToHash("0x01")}), DiscIncompatibleQuorum},
+		{encode(&QuorumCapabilities{Consensus: "istanbul", ChainConfigHash: common.HexToHash("0x02")}), DiscUselessPeer},
+	}
+	for i, test := range tests {
+		tt := &setupTransport{pubkey: clientpub, phs: protoHandshake{ID: crypto.FromECDSAPub(clientpub)[1:], Rest: test.rest}}
+		srv := &Server{
+			Config: Config{
+				PrivateKey:         srvkey,
+				MaxPeers:           10,
+				NoDial:             true,
+				Protocols:          []Protocol{discard},
+				QuorumCapabilities: local,
+			},
+			newTransport: func(fd net.Conn) transport { return tt },
+			log:          log.New(),
+		}
+		if err := srv.Start(); err != nil {
+			t.Fatalf("couldn't start server: %v", err)
+		}
+		if have := decodeQuorumCapabilities(srv.ourHandshake); have == nil || have.CheckCompatible(local) != nil || have.Privacy != local.Privacy {
+			t.Errorf("test %d: advertised capabilities mismatch: have %+v, want %+v", i, have, local)
+		}
+		p1, _ := net.Pipe()
+		srv.SetupConn(p1, inboundConn, nil)
+		if tt.closeErr != test.wantCloseErr {
+			t.Errorf("test %d: close error mismatch: got %q, want %q", i, tt.closeErr, test.wantCloseErr)
+		}
+		srv.Stop()
+	}
+}
+
 type setupTransport struct {
 	pubkey            *ecdsa.PublicKey
 	encHandshakeErr   error
@@ -650,7 +705,7 @@ type setupTransport struct {
 	closeErr error
 }
 
-func (c *setupTransport) doEncHandshake(prv *ecdsa.PrivateKey, dialDest *ecdsa.PublicKey) (*ecdsa.PublicKey, error) {
+func (c *setupTransport) doEncHandshake(key NodeSigner, dialDest *ecdsa.PublicKey) (*ecdsa.PublicKey, error) {
 	c.calls += "doEncHandshake,"
 	return c.pubkey, c.encHandshakeErr
 }
@@ -689,3 +744,66 @@ func randomID() (id enode.ID) {
 	}
 	return id
 }
+
+// countingSigner is a node signer holding the key out of the server's reach,
+// counting the key agreements.
+type countingSigner struct {
+	localSigner
+	agreed int32
+}
+
+func (s *countingSigner) ECDH(pub *ecdsa.PublicKey) ([]byte, error) {
+	atomic.AddInt32(&s.agreed, 1)
+	return s.localSigner.ECDH(pub)
+}
+
+// Tests that a server with its node key in a signer only runs the handshake
+// through it.
+func TestServerSigner(t *testing.T) {
+	signer := &countingSigner{localSigner: localSigner{key: newkey()}}
+	srv := &Server{Config: Config{
+		Name:        "test",
+		MaxPeers:    10,
+		ListenAddr:  "127.0.0.1:0",
+		Signer:      signer,
+		NoDiscovery: true,
+	}}
+	if err := srv.Start(); err != nil {
+		t.Fatalf("could not start server: %v", err)
+	}
+	defer srv.Stop()
+
+	if srv.Self().ID() != enode.PubkeyToIDV4(signer.PublicKey()) {
+		t.Fatalf("server identity %v is not the signer key", srv.Self().ID())
+	}
+	conn, err := net.DialTimeout("tcp", srv.ListenAddr, 5*time.Second)
+	if err != nil {
+		t.Fatalf("could not dial: %v", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	remote, err := newRLPX(conn).doEncHandshake(&localSigner{key: newkey()}, signer.PublicKey())
+	if err != nil {
+		t.Fatalf("handshake failed: %v", err)
+	}
+	if !reflect.DeepEqual(remote, signer.PublicKey()) {
+		t.Errorf("remote key mismatch: got %v, want %v", remote, signer.PublicKey())
+	}
+	if atomic.LoadInt32(&signer.agreed) == 0 {
+		t.Error("handshake did not agree on the keys through the signer")
+	}
+}
+
+// Tests that the discovery requires the private node key.
+func TestServerSignerDiscovery(t *testing.T) {
+	srv := &Server{Config: Config{
+		MaxPeers:   10,
+		ListenAddr: "127.0.0.1:0",
+		Signer:     &localSigner{key: newkey()},
+	}}
+	if err := srv.Start(); err == nil {
+		srv.Stop()
+		t.Fatal("server started the discovery without a private key")
+	}
+}
@@ -0,0 +1,71 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"crypto/ecdsa"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/crypto/ecies"
+)
+
+// NodeSigner performs the operations with the node key the server needs, the
+// RLPx key agreement and the signature of the node record, for the key to be
+// held outside of the process, such as in a KMS.
+type NodeSigner interface {
+	// PublicKey returns the public node key.
+	PublicKey() *ecdsa.PublicKey
+
+	// Sign returns the 65 byte [R || S || V] signature of a 32 byte hash, in the
+	// format of crypto.Sign.
+	Sign(hash []byte) ([]byte, error)
+
+	// ECDH returns the 32 byte X coordinate of the product of the node key with
+	// a public key.
+	ECDH(pub *ecdsa.PublicKey) ([]byte, error)
+}
+
+// localSigner performs the operations with a node key held in memory.
+type localSigner struct {
+	key *ecdsa.PrivateKey
+}
+
+func (s *localSigner) PublicKey() *ecdsa.PublicKey {
+	return &s.key.PublicKey
+}
+
+func (s *localSigner) Sign(hash []byte) ([]byte, error) {
+	return crypto.Sign(hash, s.key)
+}
+
+func (s *localSigner) ECDH(pub *ecdsa.PublicKey) ([]byte, error) {
+	return ecies.ImportECDSA(s.key).GenerateShared(ecies.ImportECDSAPublic(pub), sskLen, sskLen)
+}
+
+// sharedKeyFunc adapts the key agreement of a signer to the ECIES functions,
+// which all agree on 32 byte secrets on secp256k1.
+func sharedKeyFunc(key NodeSigner) ecies.SharedKeyFunc {
+	return func(pub *ecies.PublicKey, skLen, macLen int) ([]byte, error) {
+		if pub.Curve != crypto.S256() {
+			return nil, ecies.ErrInvalidCurve
+		}
+		if skLen+macLen != 2*sskLen {
+			return nil, ecies.ErrSharedKeyTooBig
+		}
+		return key.ECDH(pub.ExportECDSA())
+	}
+}
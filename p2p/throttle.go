@@ -0,0 +1,205 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/mclock"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const (
+	// inboundRateWindow is the interval the inbound connection rate of an IP is
+	// limited over.
+	inboundRateWindow = time.Minute
+
+	// maxThrottleEntries is the maximum number of IPs whose connection rate is
+	// remembered.
+	maxThrottleEntries = 4096
+
+	// maxPuzzleDifficulty is the highest difficulty of the handshake puzzle the
+	// dialing nodes solve, harder ones being unsolvable in the handshake timeout.
+	maxPuzzleDifficulty = 22
+
+	puzzleNonceLength = 32
+)
+
+var (
+	errPuzzleTooHard  = errors.New("handshake puzzle too hard")
+	errPuzzleSolution = errors.New("invalid handshake puzzle solution")
+)
+
+// inboundThrottle limits the rate of the inbound connections of each IP and
+// the number of handshakes run concurrently with each, before the remote node
+// is known.
+type inboundThrottle struct {
+	rate       int // Connections accepted from an IP per window, zero for unlimited
+	maxPending int // Handshakes run concurrently with an IP, zero for unlimited
+	clock      mclock.Clock
+
+	lock    sync.Mutex
+	buckets map[string]*throttleBucket
+	pending map[string]int
+}
+
+// throttleBucket is the token bucket of the connections of an IP.
+type throttleBucket struct {
+	tokens float64
+	last   mclock.AbsTime
+}
+
+func newInboundThrottle(rate, maxPending int, clock mclock.Clock) *inboundThrottle {
+	return &inboundThrottle{
+		rate:       rate,
+		maxPending: maxPending,
+		clock:      clock,
+		buckets:    make(map[string]*throttleBucket),
+		pending:    make(map[string]int),
+	}
+}
+
+// admit returns an error if a connection of an IP is to be rejected, and
+// otherwise counts its handshake as pending until released.
+func (t *inboundThrottle) admit(ip net.IP) error {
+	key := string(ip.To16())
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.maxPending > 0 && t.pending[key] >= t.maxPending {
+		return fmt.Errorf("too many pending handshakes (%d)", t.pending[key])
+	}
+	if t.rate > 0 {
+		now := t.clock.Now()
+		bucket := t.buckets[key]
+		if bucket == nil {
+			if len(t.buckets) >= maxThrottleEntries {
+				t.expire(now)
+			}
+			bucket = &throttleBucket{tokens: float64(t.rate), last: now}
+			t.buckets[key] = bucket
+		}
+		// Refill the bucket for the time elapsed, up to the rate
+		bucket.tokens += float64(t.rate) * float64(now-bucket.last) / float64(inboundRateWindow)
+		if bucket.tokens > float64(t.rate) {
+			bucket.tokens = float64(t.rate)
+		}
+		bucket.last = now
+		if bucket.tokens < 1 {
+			return fmt.Errorf("too many connections (over %d per %v)", t.rate, inboundRateWindow)
+		}
+		bucket.tokens--
+	}
+	t.pending[key]++
+	return nil
+}
+
+// release ends the pending handshake of a connection admitted.
+func (t *inboundThrottle) release(ip net.IP) {
+	key := string(ip.To16())
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.pending[key]--; t.pending[key] <= 0 {
+		delete(t.pending, key)
+	}
+}
+
+// expire drops the buckets refilled by now, the oldest one if none is.
+func (t *inboundThrottle) expire(now mclock.AbsTime) {
+	var (
+		oldest     string
+		oldestTime mclock.AbsTime
+	)
+	for key, bucket := range t.buckets {
+		if now-bucket.last >= mclock.AbsTime(inboundRateWindow) {
+			delete(t.buckets, key)
+			continue
+		}
+		if oldest == "" || bucket.last < oldestTime {
+			oldest, oldestTime = key, bucket.last
+		}
+	}
+	if len(t.buckets) >= maxThrottleEntries {
+		delete(t.buckets, oldest)
+	}
+}
+
+// issuePuzzle sends a connecting node a nonce and the difficulty of the proof
+// of work it has to solve before the encryption handshake, and verifies its
+// solution. It costs the listening node a hash per connection, while the
+// dialing one has to compute about 2^difficulty of them.
+func issuePuzzle(rw io.ReadWriter, difficulty uint) error {
+	challenge := make([]byte, puzzleNonceLength+1)
+	if _, err := rand.Read(challenge[:puzzleNonceLength]); err != nil {
+		return err
+	}
+	challenge[puzzleNonceLength] = byte(difficulty)
+	if _, err := rw.Write(challenge); err != nil {
+		return err
+	}
+	solution := make([]byte, 8)
+	if _, err := io.ReadFull(rw, solution); err != nil {
+		return err
+	}
+	if !puzzleSolved(challenge[:puzzleNonceLength], solution, difficulty) {
+		return errPuzzleSolution
+	}
+	return nil
+}
+
+// solvePuzzle receives the puzzle of the node dialed and sends its solution.
+func solvePuzzle(rw io.ReadWriter) error {
+	challenge := make([]byte, puzzleNonceLength+1)
+	if _, err := io.ReadFull(rw, challenge); err != nil {
+		return err
+	}
+	difficulty := uint(challenge[puzzleNonceLength])
+	if difficulty > maxPuzzleDifficulty {
+		return errPuzzleTooHard
+	}
+	solution := make([]byte, 8)
+	for counter := uint64(0); ; counter++ {
+		binary.BigEndian.PutUint64(solution, counter)
+		if puzzleSolved(challenge[:puzzleNonceLength], solution, difficulty) {
+			break
+		}
+	}
+	_, err := rw.Write(solution)
+	return err
+}
+
+// puzzleSolved reports whether the hash of a nonce and a solution starts with
+// difficulty zero bits.
+func puzzleSolved(nonce, solution []byte, difficulty uint) bool {
+	hash := crypto.Keccak256(nonce, solution)
+	for i := uint(0); i < difficulty; i++ {
+		if hash[i/8]&(0x80>>(i%8)) != 0 {
+			return false
+		}
+	}
+	return true
+}
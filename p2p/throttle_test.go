@@ -0,0 +1,98 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/mclock"
+)
+
+func TestInboundThrottleRate(t *testing.T) {
+	clock := new(mclock.Simulated)
+	throttle := newInboundThrottle(2, 0, clock)
+	ip, other := net.ParseIP("10.0.0.1"), net.ParseIP("10.0.0.2")
+
+	for i := 0; i < 2; i++ {
+		if err := throttle.admit(ip); err != nil {
+			t.Fatalf("connection %d rejected: %v", i, err)
+		}
+		throttle.release(ip)
+	}
+	if err := throttle.admit(ip); err == nil {
+		t.Fatalf("connection over the rate admitted")
+	}
+	if err := throttle.admit(other); err != nil {
+		t.Fatalf("connection of another IP rejected: %v", err)
+	}
+	clock.Run(inboundRateWindow / 2)
+	if err := throttle.admit(ip); err != nil {
+		t.Fatalf("connection rejected after the refill: %v", err)
+	}
+	if err := throttle.admit(ip); err == nil {
+		t.Fatalf("connection over the refilled rate admitted")
+	}
+}
+
+func TestInboundThrottlePending(t *testing.T) {
+	throttle := newInboundThrottle(0, 2, new(mclock.Simulated))
+	ip := net.ParseIP("10.0.0.1")
+
+	for i := 0; i < 2; i++ {
+		if err := throttle.admit(ip); err != nil {
+			t.Fatalf("handshake %d rejected: %v", i, err)
+		}
+	}
+	if err := throttle.admit(ip); err == nil {
+		t.Fatalf("handshake over the limit admitted")
+	}
+	throttle.release(ip)
+	if err := throttle.admit(ip); err != nil {
+		t.Fatalf("handshake rejected after a release: %v", err)
+	}
+}
+
+func TestHandshakePuzzle(t *testing.T) {
+	listener, dialer := net.Pipe()
+	defer listener.Close()
+	defer dialer.Close()
+
+	deadline := time.Now().Add(5 * time.Second)
+	listener.SetDeadline(deadline)
+	dialer.SetDeadline(deadline)
+
+	errc := make(chan error, 1)
+	go func() { errc <- solvePuzzle(dialer) }()
+	if err := issuePuzzle(listener, 12); err != nil {
+		t.Fatalf("puzzle not solved: %v", err)
+	}
+	if err := <-errc; err != nil {
+		t.Fatalf("failed to solve the puzzle: %v", err)
+	}
+
+	// A wrong solution is rejected
+	go func() {
+		challenge := make([]byte, puzzleNonceLength+1)
+		dialer.Read(challenge)
+		dialer.Write(make([]byte, 8))
+	}()
+	if err := issuePuzzle(listener, 20); err != errPuzzleSolution {
+		t.Fatalf("wrong solution error mismatch: have %v, want %v", err, errPuzzleSolution)
+	}
+}
@@ -17,12 +17,14 @@
 package params
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"math"
 	"math/big"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
 )
 
 // Genesis hashes to enforce below configs on.
@@ -133,19 +135,19 @@ var (
 	//
 	// This configuration is intentionally not using keyed fields to force anyone
 	// adding flags to the config to also have to set these fields.
-	AllEthashProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, new(EthashConfig), nil, nil, false, 32, 50, big.NewInt(0), big.NewInt(0)}
+	AllEthashProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, new(EthashConfig), nil, nil, false, 32, 50, big.NewInt(0), big.NewInt(0), nil, nil, nil, nil, nil, nil, nil, nil}
 
 	// AllCliqueProtocolChanges contains every protocol change (EIPs) introduced
 	// and accepted by the Ethereum core developers into the Clique consensus.
 	//
 	// This configuration is intentionally not using keyed fields to force anyone
 	// adding flags to the config to also have to set these fields.
-	AllCliqueProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil, &CliqueConfig{Period: 0, Epoch: 30000}, nil, false, 32, 32, big.NewInt(0), big.NewInt(0)}
+	AllCliqueProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil, &CliqueConfig{Period: 0, Epoch: 30000}, nil, false, 32, 32, big.NewInt(0), big.NewInt(0), nil, nil, nil, nil, nil, nil, nil, nil}
 
-	TestChainConfig = &ChainConfig{big.NewInt(10), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, new(EthashConfig), nil, nil, false, 32, 32, big.NewInt(0), big.NewInt(0)}
+	TestChainConfig = &ChainConfig{big.NewInt(10), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, new(EthashConfig), nil, nil, false, 32, 32, big.NewInt(0), big.NewInt(0), nil, nil, nil, nil, nil, nil, nil, nil}
 	TestRules       = TestChainConfig.Rules(new(big.Int))
 
-	QuorumTestChainConfig = &ChainConfig{big.NewInt(10), big.NewInt(0), nil, false, nil, common.Hash{}, nil, nil, nil, nil, nil, new(EthashConfig), nil, nil, true, 64, 32, big.NewInt(0), big.NewInt(0)}
+	QuorumTestChainConfig = &ChainConfig{big.NewInt(10), big.NewInt(0), nil, false, nil, common.Hash{}, nil, nil, nil, nil, nil, new(EthashConfig), nil, nil, true, 64, 32, big.NewInt(0), big.NewInt(0), nil, nil, nil, nil, nil, nil, nil, nil}
 )
 
 // TrustedCheckpoint represents a set of post-processed trie roots (CHT and
@@ -197,6 +199,33 @@ type ChainConfig struct {
 	// QIP714Block implements the permissions related changes
 	QIP714Block *big.Int `json:"qip714Block,omitempty"`
 	MaxCodeSizeChangeBlock *big.Int `json:"maxCodeSizeChangeBlock,omitempty"`
+	// ExperimentalInterpreters activates alternative contract interpreters
+	// registered with the vm package, by name, from the given block.
+	ExperimentalInterpreters map[string]*big.Int `json:"experimentalInterpreters,omitempty"`
+	// ZKPrecompilesBlock activates the Poseidon hash pre-compiled contract
+	// (nil = no fork)
+	ZKPrecompilesBlock *big.Int `json:"zkPrecompilesBlock,omitempty"`
+	// StateAccessLimits bounds the public state accessed by the public
+	// transactions of a block, beyond gas (nil = unlimited)
+	StateAccessLimits *StateAccessLimitsConfig `json:"stateAccessLimits,omitempty"`
+	// DeterministicDeploymentBlock installs the deterministic deployment proxy
+	// in the public and private states at the start of the given block (nil = no
+	// proxy). The genesis block has no private state, so it must be positive.
+	DeterministicDeploymentBlock *big.Int `json:"deterministicDeploymentBlock,omitempty"`
+	// GasScheduleOverrides reprice selected opcodes from their blocks, in
+	// ascending block order, each replacing the previous one (nil = protocol
+	// gas costs)
+	GasScheduleOverrides []*GasScheduleOverride `json:"gasScheduleOverrides,omitempty"`
+	// StrictEIP155Block requires the public transactions to carry EIP-155
+	// replay protected signatures from the given block, rejecting the legacy
+	// ones (nil = legacy signatures accepted)
+	StrictEIP155Block *big.Int `json:"strictEIP155Block,omitempty"`
+	// Bridges activate pre-compiled contracts verifying the headers and Merkle
+	// proofs of other chains, one per chain (nil = no bridge)
+	Bridges []*BridgeConfig `json:"bridges,omitempty"`
+	// DocumentAnchors turn the transactions sent to an address into document
+	// anchors, validated by the pools and the blocks (nil = no anchors)
+	DocumentAnchors *DocumentAnchorsConfig `json:"documentAnchors,omitempty"`
 }
 
 // EthashConfig is the consensus engine configs for proof-of-work based sealing.
@@ -230,6 +259,91 @@ func (c *IstanbulConfig) String() string {
 	return "istanbul"
 }
 
+// StateAccessLimitsConfig bounds the public state accessed by the public
+// transactions of a block, to keep block execution time bounded on networks
+// with huge gas limits. The limits are enforced when mining and when importing
+// blocks. The private transactions aren't counted, since they access public
+// state on their parties only. Zero limits are not enforced.
+type StateAccessLimitsConfig struct {
+	Block              *big.Int `json:"block"`                        // Activation block
+	MaxReads           uint64   `json:"maxReads,omitempty"`           // Maximum storage slots read
+	MaxWrites          uint64   `json:"maxWrites,omitempty"`          // Maximum storage slots written
+	MaxCreatedAccounts uint64   `json:"maxCreatedAccounts,omitempty"` // Maximum accounts created
+}
+
+// DocumentAnchorsConfig turns the transactions sent to an address without code
+// into document anchors from its activation block: public transactions carrying
+// no value, their data the RLP encoded anchor of a document stored off-chain.
+type DocumentAnchorsConfig struct {
+	Address common.Address `json:"address"` // Recipient of the anchoring transactions
+	Block   *big.Int       `json:"block"`   // Activation block
+}
+
+// BridgeConfig activates a pre-compiled contract verifying the block headers
+// and the Merkle proofs of another chain, with the consensus parameters of the
+// chain. The validators the headers are verified against are given by the
+// caller, which keeps track of the trusted ones.
+//
+// Only the chains sealed by validators, under istanbul or clique, are supported.
+// The headers of proof-of-work (ethash) chains, such as the Ethereum mainnet,
+// carry no signature to check: a single header proves nothing without the
+// total difficulty of the chain it extends, and checking its work requires the
+// ethash caches of its epoch, far too heavy to generate in a contract call.
+type BridgeConfig struct {
+	Address        common.Address `json:"address"`                  // Address of the pre-compiled contract
+	Block          *big.Int       `json:"block"`                    // Activation block
+	ChainID        *big.Int       `json:"chainId,omitempty"`        // Chain ID of the other chain, for reference
+	Consensus      string         `json:"consensus"`                // Consensus engine of the other chain: istanbul or clique
+	Ceil2Nby3Block *big.Int       `json:"ceil2Nby3Block,omitempty"` // Istanbul block of the other chain from which ceil(2N/3) committed seals are required instead of 2F+1
+}
+
+// equal returns whether two bridge configurations are the same.
+func (b *BridgeConfig) equal(other *BridgeConfig) bool {
+	return b.Address == other.Address && configNumEqual(b.Block, other.Block) && configNumEqual(b.ChainID, other.ChainID) &&
+		b.Consensus == other.Consensus && configNumEqual(b.Ceil2Nby3Block, other.Ceil2Nby3Block)
+}
+
+// GasScheduleOverride reprices selected opcodes from its activation block, for
+// instance to discourage storage heavy contracts. Unset costs keep the protocol
+// value of the block.
+type GasScheduleOverride struct {
+	Block       *big.Int `json:"block"`                 // Activation block
+	SLoad       *uint64  `json:"sload,omitempty"`       // SLOAD cost
+	SStoreSet   *uint64  `json:"sstoreSet,omitempty"`   // SSTORE cost setting a zero slot
+	SStoreReset *uint64  `json:"sstoreReset,omitempty"` // SSTORE cost changing an existing slot
+	Call        *uint64  `json:"call,omitempty"`        // Base cost of CALL, CALLCODE, DELEGATECALL and STATICCALL
+}
+
+// apply returns a copy of a gas table with the overridden costs.
+func (o *GasScheduleOverride) apply(gt GasTable) GasTable {
+	if o.SLoad != nil {
+		gt.SLoad = *o.SLoad
+	}
+	if o.SStoreSet != nil {
+		gt.SStoreSet = *o.SStoreSet
+	}
+	if o.SStoreReset != nil {
+		gt.SStoreReset = *o.SStoreReset
+	}
+	if o.Call != nil {
+		gt.Calls = *o.Call
+	}
+	return gt
+}
+
+// Quorum
+//
+// EncodeJSON returns the JSON encoding of the chain configuration, as exchanged
+// with the peers and pinned by the signing policies of the validators, together
+// with its hash.
+func (c *ChainConfig) EncodeJSON() ([]byte, common.Hash, error) {
+	blob, err := json.Marshal(c)
+	if err != nil {
+		return nil, common.Hash{}, err
+	}
+	return blob, crypto.Keccak256Hash(blob), nil
+}
+
 // String implements the fmt.Stringer interface.
 func (c *ChainConfig) String() string {
 	var engine interface{}
@@ -270,6 +384,62 @@ func (c *ChainConfig) IsValid() error {
 		return errors.New("Genesis max code size must be between 24 and 128")
 	}
 
+	if c.DeterministicDeploymentBlock != nil {
+		if c.DeterministicDeploymentBlock.Sign() <= 0 {
+			return errors.New("Genesis deterministic deployment block must be positive")
+		}
+		// The proxy deploys with CREATE2, which Constantinople introduces
+		if !c.IsConstantinople(c.DeterministicDeploymentBlock) {
+			return errors.New("Genesis deterministic deployment block must not precede the Constantinople block")
+		}
+	}
+
+	var last *big.Int
+	for i, override := range c.GasScheduleOverrides {
+		if override == nil || override.Block == nil {
+			return fmt.Errorf("Genesis gas schedule override %d has no block", i)
+		}
+		if last != nil && override.Block.Cmp(last) <= 0 {
+			return errors.New("Genesis gas schedule overrides must be in ascending block order")
+		}
+		for _, cost := range []*uint64{override.SLoad, override.SStoreSet, override.SStoreReset, override.Call} {
+			if cost != nil && *cost == 0 {
+				return fmt.Errorf("Genesis gas schedule override %d has a zero cost", i)
+			}
+		}
+		last = override.Block
+	}
+
+	addresses := make(map[common.Address]bool)
+	for i, bridge := range c.Bridges {
+		if bridge == nil || bridge.Block == nil {
+			return fmt.Errorf("Genesis bridge %d has no block", i)
+		}
+		if bridge.Consensus == "ethash" {
+			return fmt.Errorf("Genesis bridge %d has unsupported consensus %q, only the chains sealed by validators (istanbul or clique) can be bridged", i, bridge.Consensus)
+		}
+		if bridge.Consensus != "istanbul" && bridge.Consensus != "clique" {
+			return fmt.Errorf("Genesis bridge %d has unsupported consensus %q", i, bridge.Consensus)
+		}
+		// Keep clear of the addresses of the standard pre-compiled contracts
+		if new(big.Int).SetBytes(bridge.Address[:]).BitLen() <= 16 {
+			return fmt.Errorf("Genesis bridge %d address %s reserved for the pre-compiled contracts", i, bridge.Address.Hex())
+		}
+		if addresses[bridge.Address] {
+			return fmt.Errorf("Genesis bridge %d address %s duplicated", i, bridge.Address.Hex())
+		}
+		addresses[bridge.Address] = true
+	}
+
+	if c.StrictEIP155Block != nil {
+		if c.ChainID == nil || c.ChainID.Sign() <= 0 {
+			return errors.New("Genesis strict EIP155 requires a chain ID")
+		}
+		if c.EIP155Block == nil || c.EIP155Block.Cmp(c.StrictEIP155Block) > 0 {
+			return errors.New("Genesis strict EIP155 block must not precede the EIP155 block")
+		}
+	}
+
 	return nil
 }
 
@@ -327,6 +497,87 @@ func (c *ChainConfig) IsMaxCodeSizeChangeBlock(num *big.Int) bool {
 	return isForked(c.MaxCodeSizeChangeBlock, num)
 }
 
+// Quorum
+//
+// IsExperimentalInterpreter returns whether num represents a block number
+// after the alternative interpreter with the given name was activated
+func (c *ChainConfig) IsExperimentalInterpreter(name string, num *big.Int) bool {
+	return isForked(c.ExperimentalInterpreters[name], num)
+}
+
+// Quorum
+//
+// IsZKPrecompiles returns whether num is either equal to the zk precompiles fork
+// block or greater.
+func (c *ChainConfig) IsZKPrecompiles(num *big.Int) bool {
+	return isForked(c.ZKPrecompilesBlock, num)
+}
+
+// Quorum
+//
+// StateAccessLimitsAt returns the state access limits of the block num, or nil
+// if they are not activated.
+func (c *ChainConfig) StateAccessLimitsAt(num *big.Int) *StateAccessLimitsConfig {
+	if c.StateAccessLimits == nil || !isForked(c.StateAccessLimits.Block, num) {
+		return nil
+	}
+	return c.StateAccessLimits
+}
+
+// Quorum
+//
+// IsDeterministicDeploymentBlock returns whether num is the block installing
+// the deterministic deployment proxy, which takes Constantinople for CREATE2.
+func (c *ChainConfig) IsDeterministicDeploymentBlock(num *big.Int) bool {
+	return c.DeterministicDeploymentBlock != nil && c.DeterministicDeploymentBlock.Cmp(num) == 0 && c.IsConstantinople(num)
+}
+
+// Quorum
+//
+// IsStrictEIP155 returns whether num is either equal to the strict EIP155 block
+// or greater, from which legacy signatures of public transactions are rejected.
+func (c *ChainConfig) IsStrictEIP155(num *big.Int) bool {
+	return isForked(c.StrictEIP155Block, num)
+}
+
+// Quorum
+//
+// DocumentAnchorAddress returns the recipient of the document anchoring
+// transactions of the block num, or nil if anchors aren't active.
+func (c *ChainConfig) DocumentAnchorAddress(num *big.Int) *common.Address {
+	if c.DocumentAnchors == nil || !isForked(c.DocumentAnchors.Block, num) {
+		return nil
+	}
+	return &c.DocumentAnchors.Address
+}
+
+// Quorum
+//
+// Bridge returns the bridge pre-compiled contract active at the given address
+// at block num, or nil if there is none.
+func (c *ChainConfig) Bridge(addr common.Address, num *big.Int) *BridgeConfig {
+	for _, bridge := range c.Bridges {
+		if bridge != nil && bridge.Address == addr && isForked(bridge.Block, num) {
+			return bridge
+		}
+	}
+	return nil
+}
+
+// Quorum
+//
+// GasScheduleOverrideAt returns the gas schedule override in force at the block
+// num, or nil if there is none.
+func (c *ChainConfig) GasScheduleOverrideAt(num *big.Int) *GasScheduleOverride {
+	var active *GasScheduleOverride
+	for _, override := range c.GasScheduleOverrides {
+		if override != nil && isForked(override.Block, num) {
+			active = override
+		}
+	}
+	return active
+}
+
 // GasTable returns the gas table corresponding to the current phase (homestead or homestead reprice).
 //
 // The returned GasTable's fields shouldn't, under any circumstances, be changed.
@@ -334,16 +585,22 @@ func (c *ChainConfig) GasTable(num *big.Int) GasTable {
 	if num == nil {
 		return GasTableHomestead
 	}
+	var gt GasTable
 	switch {
 	case c.IsConstantinople(num):
-		return GasTableConstantinople
+		gt = GasTableConstantinople
 	case c.IsEIP158(num):
-		return GasTableEIP158
+		gt = GasTableEIP158
 	case c.IsEIP150(num):
-		return GasTableEIP150
+		gt = GasTableEIP150
 	default:
-		return GasTableHomestead
+		gt = GasTableHomestead
 	}
+	// Quorum: the tables are copied by value, the overrides don't change them
+	if override := c.GasScheduleOverrideAt(num); override != nil {
+		gt = override.apply(gt)
+	}
+	return gt
 }
 
 // CheckCompatible checks whether scheduled fork transitions have been imported
@@ -407,9 +664,132 @@ func (c *ChainConfig) checkCompatible(newcfg *ChainConfig, head *big.Int, isQuor
 	if isForkIncompatible(c.MaxCodeSizeChangeBlock, newcfg.MaxCodeSizeChangeBlock, head) {
 		return newCompatError("max code size change fork block", c.MaxCodeSizeChangeBlock, newcfg.MaxCodeSizeChangeBlock)
 	}
+	for name, block := range c.ExperimentalInterpreters {
+		if isForkIncompatible(block, newcfg.ExperimentalInterpreters[name], head) {
+			return newCompatError(fmt.Sprintf("%s interpreter fork block", name), block, newcfg.ExperimentalInterpreters[name])
+		}
+	}
+	for name, block := range newcfg.ExperimentalInterpreters {
+		if isForkIncompatible(c.ExperimentalInterpreters[name], block, head) {
+			return newCompatError(fmt.Sprintf("%s interpreter fork block", name), c.ExperimentalInterpreters[name], block)
+		}
+	}
+	if isForkIncompatible(c.ZKPrecompilesBlock, newcfg.ZKPrecompilesBlock, head) {
+		return newCompatError("zk precompiles fork block", c.ZKPrecompilesBlock, newcfg.ZKPrecompilesBlock)
+	}
+	if isStateAccessLimitsIncompatible(c.StateAccessLimitsAt(head), newcfg.StateAccessLimitsAt(head)) {
+		return newCompatError("state access limits fork block", c.stateAccessLimitsBlock(), newcfg.stateAccessLimitsBlock())
+	}
+	if isForkIncompatible(c.DeterministicDeploymentBlock, newcfg.DeterministicDeploymentBlock, head) {
+		return newCompatError("deterministic deployment fork block", c.DeterministicDeploymentBlock, newcfg.DeterministicDeploymentBlock)
+	}
+	if isForkIncompatible(c.StrictEIP155Block, newcfg.StrictEIP155Block, head) {
+		return newCompatError("strict EIP155 fork block", c.StrictEIP155Block, newcfg.StrictEIP155Block)
+	}
+	if stored, updated := c.gasScheduleOverridesUntil(head), newcfg.gasScheduleOverridesUntil(head); firstOverrideBlock(stored, updated) != nil || len(stored) != len(updated) {
+		return newCompatError("gas schedule override block", firstOverrideBlock(stored, updated), firstOverrideBlock(updated, stored))
+	}
+	if block, newblock := c.bridgeChange(newcfg, head), newcfg.bridgeChange(c, head); block != nil || newblock != nil {
+		return newCompatError("bridge fork block", block, newblock)
+	}
+	if block, newblock := c.documentAnchorsBlock(), newcfg.documentAnchorsBlock(); isForkIncompatible(block, newblock, head) ||
+		(c.DocumentAnchorAddress(head) != nil && newcfg.DocumentAnchorAddress(head) != nil && c.DocumentAnchors.Address != newcfg.DocumentAnchors.Address) {
+		return newCompatError("document anchors fork block", block, newblock)
+	}
 	return nil
 }
 
+// Quorum
+//
+// documentAnchorsBlock returns the activation block of the document anchors,
+// nil if not configured.
+func (c *ChainConfig) documentAnchorsBlock() *big.Int {
+	if c.DocumentAnchors == nil {
+		return nil
+	}
+	return c.DocumentAnchors.Block
+}
+
+// Quorum
+//
+// bridgeChange returns the block of the first bridge activated at or before
+// head missing or configured differently in other, nil if there is none.
+func (c *ChainConfig) bridgeChange(other *ChainConfig, head *big.Int) *big.Int {
+	for _, bridge := range c.Bridges {
+		if bridge == nil || !isForked(bridge.Block, head) {
+			continue
+		}
+		found := false
+		for _, o := range other.Bridges {
+			if o != nil && bridge.equal(o) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return bridge.Block
+		}
+	}
+	return nil
+}
+
+// Quorum
+//
+// gasScheduleOverridesUntil returns the gas schedule overrides activated at or
+// before head, which blocks were already executed with.
+func (c *ChainConfig) gasScheduleOverridesUntil(head *big.Int) []*GasScheduleOverride {
+	var overrides []*GasScheduleOverride
+	for _, override := range c.GasScheduleOverrides {
+		if override != nil && isForked(override.Block, head) {
+			overrides = append(overrides, override)
+		}
+	}
+	return overrides
+}
+
+// firstOverrideBlock returns the block of the first override of o1 differing
+// from the one at the same position in o2, nil if there is none.
+func firstOverrideBlock(o1, o2 []*GasScheduleOverride) *big.Int {
+	for i := range o1 {
+		if i >= len(o2) || !o1[i].equal(o2[i]) {
+			return o1[i].Block
+		}
+	}
+	return nil
+}
+
+// equal returns whether two gas schedule overrides are the same.
+func (o *GasScheduleOverride) equal(other *GasScheduleOverride) bool {
+	costEqual := func(c1, c2 *uint64) bool {
+		return (c1 == nil) == (c2 == nil) && (c1 == nil || *c1 == *c2)
+	}
+	return configNumEqual(o.Block, other.Block) && costEqual(o.SLoad, other.SLoad) && costEqual(o.SStoreSet, other.SStoreSet) &&
+		costEqual(o.SStoreReset, other.SStoreReset) && costEqual(o.Call, other.Call)
+}
+
+// Quorum
+//
+// stateAccessLimitsBlock returns the activation block of the state access
+// limits, nil if there are none.
+func (c *ChainConfig) stateAccessLimitsBlock() *big.Int {
+	if c.StateAccessLimits == nil {
+		return nil
+	}
+	return c.StateAccessLimits.Block
+}
+
+// isStateAccessLimitsIncompatible returns true if the state access limits in
+// force at the head can't be changed, as blocks were verified against them.
+func isStateAccessLimitsIncompatible(l1, l2 *StateAccessLimitsConfig) bool {
+	switch {
+	case l1 == nil && l2 == nil:
+		return false
+	case l1 == nil || l2 == nil:
+		return true
+	}
+	return l1.Block.Cmp(l2.Block) != 0 || l1.MaxReads != l2.MaxReads || l1.MaxWrites != l2.MaxWrites || l1.MaxCreatedAccounts != l2.MaxCreatedAccounts
+}
+
 // isForkIncompatible returns true if a fork scheduled at s1 cannot be rescheduled to
 // block s2 because head is already past the fork.
 func isForkIncompatible(s1, s2, head *big.Int) bool {
@@ -20,6 +20,8 @@ import (
 	"math/big"
 	"reflect"
 	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
 )
 
 func TestCheckCompatible(t *testing.T) {
@@ -28,6 +30,7 @@ func TestCheckCompatible(t *testing.T) {
 		head        uint64
 		wantErr     *ConfigCompatError
 	}
+	sload, sstore := uint64(800), uint64(40000)
 	tests := []test{
 		{stored: AllEthashProtocolChanges, new: AllEthashProtocolChanges, head: 0, wantErr: nil},
 		{stored: AllEthashProtocolChanges, new: AllEthashProtocolChanges, head: 100, wantErr: nil},
@@ -121,6 +124,73 @@ func TestCheckCompatible(t *testing.T) {
 			head:    4,
 			wantErr: nil,
 		},
+		{
+			stored: &ChainConfig{DocumentAnchors: &DocumentAnchorsConfig{Address: common.HexToAddress("0x0a"), Block: big.NewInt(10)}},
+			new:    &ChainConfig{DocumentAnchors: &DocumentAnchorsConfig{Address: common.HexToAddress("0x0b"), Block: big.NewInt(10)}},
+			head:   30,
+			wantErr: &ConfigCompatError{
+				What:         "document anchors fork block",
+				StoredConfig: big.NewInt(10),
+				NewConfig:    big.NewInt(10),
+				RewindTo:     9,
+			},
+		},
+		{
+			stored: &ChainConfig{StateAccessLimits: &StateAccessLimitsConfig{Block: big.NewInt(10), MaxWrites: 100}},
+			new:    &ChainConfig{StateAccessLimits: &StateAccessLimitsConfig{Block: big.NewInt(10), MaxWrites: 200}},
+			head:   30,
+			wantErr: &ConfigCompatError{
+				What:         "state access limits fork block",
+				StoredConfig: big.NewInt(10),
+				NewConfig:    big.NewInt(10),
+				RewindTo:     9,
+			},
+		},
+		{
+			stored:  &ChainConfig{StateAccessLimits: &StateAccessLimitsConfig{Block: big.NewInt(10), MaxWrites: 100}},
+			new:     &ChainConfig{StateAccessLimits: &StateAccessLimitsConfig{Block: big.NewInt(20), MaxWrites: 200}},
+			head:    4,
+			wantErr: nil,
+		},
+		{
+			stored: &ChainConfig{GasScheduleOverrides: []*GasScheduleOverride{{Block: big.NewInt(10), SLoad: &sload}}},
+			new:    &ChainConfig{GasScheduleOverrides: []*GasScheduleOverride{{Block: big.NewInt(10), SLoad: &sstore}}},
+			head:   30,
+			wantErr: &ConfigCompatError{
+				What:         "gas schedule override block",
+				StoredConfig: big.NewInt(10),
+				NewConfig:    big.NewInt(10),
+				RewindTo:     9,
+			},
+		},
+		{
+			stored:  &ChainConfig{GasScheduleOverrides: []*GasScheduleOverride{{Block: big.NewInt(10), SLoad: &sload}}},
+			new:     &ChainConfig{GasScheduleOverrides: []*GasScheduleOverride{{Block: big.NewInt(10), SLoad: &sload}, {Block: big.NewInt(40), SStoreSet: &sstore}}},
+			head:    30,
+			wantErr: nil,
+		},
+		{
+			stored: &ChainConfig{GasScheduleOverrides: []*GasScheduleOverride{{Block: big.NewInt(10), SLoad: &sload}}},
+			new:    &ChainConfig{GasScheduleOverrides: []*GasScheduleOverride{{Block: big.NewInt(10), SLoad: &sload}, {Block: big.NewInt(20), SStoreSet: &sstore}}},
+			head:   30,
+			wantErr: &ConfigCompatError{
+				What:         "gas schedule override block",
+				StoredConfig: nil,
+				NewConfig:    big.NewInt(20),
+				RewindTo:     19,
+			},
+		},
+		{
+			stored: &ChainConfig{DeterministicDeploymentBlock: big.NewInt(10)},
+			new:    &ChainConfig{},
+			head:   30,
+			wantErr: &ConfigCompatError{
+				What:         "deterministic deployment fork block",
+				StoredConfig: big.NewInt(10),
+				NewConfig:    nil,
+				RewindTo:     9,
+			},
+		},
 
 	}
 
@@ -131,3 +201,140 @@ func TestCheckCompatible(t *testing.T) {
 		}
 	}
 }
+
+func TestGasScheduleOverrides(t *testing.T) {
+	sload, call := uint64(800), uint64(1000)
+	config := *TestChainConfig
+	config.GasScheduleOverrides = []*GasScheduleOverride{
+		{Block: big.NewInt(10), SLoad: &sload},
+		{Block: big.NewInt(20), Call: &call},
+	}
+	if err := config.IsValid(); err != nil {
+		t.Fatalf("valid overrides rejected: %v", err)
+	}
+	if gt := config.GasTable(big.NewInt(9)); gt != GasTableConstantinople {
+		t.Errorf("gas table overridden before the first override")
+	}
+	if gt := config.GasTable(big.NewInt(10)); gt.SLoad != sload || gt.Calls != GasTableConstantinople.Calls {
+		t.Errorf("first override mismatch: have %+v", gt)
+	}
+	// Every override replaces the previous one
+	if gt := config.GasTable(big.NewInt(20)); gt.SLoad != GasTableConstantinople.SLoad || gt.Calls != call {
+		t.Errorf("second override mismatch: have %+v", gt)
+	}
+	if GasTableConstantinople.SLoad != 200 {
+		t.Errorf("protocol gas table modified")
+	}
+	config.GasScheduleOverrides = []*GasScheduleOverride{
+		{Block: big.NewInt(20), Call: &call},
+		{Block: big.NewInt(10), SLoad: &sload},
+	}
+	if err := config.IsValid(); err == nil {
+		t.Errorf("unordered overrides accepted")
+	}
+	zero := uint64(0)
+	config.GasScheduleOverrides = []*GasScheduleOverride{{Block: big.NewInt(10), SStoreSet: &zero}}
+	if err := config.IsValid(); err == nil {
+		t.Errorf("zero cost override accepted")
+	}
+}
+
+func TestDeterministicDeployment(t *testing.T) {
+	config := *TestChainConfig
+	config.DeterministicDeploymentBlock = big.NewInt(10)
+	if err := config.IsValid(); err != nil {
+		t.Fatalf("valid deterministic deployment block rejected: %v", err)
+	}
+	config.DeterministicDeploymentBlock = big.NewInt(0)
+	if err := config.IsValid(); err == nil {
+		t.Errorf("deterministic deployment at genesis accepted")
+	}
+	// The proxy can't deploy anything before CREATE2 exists
+	config.DeterministicDeploymentBlock, config.ConstantinopleBlock = big.NewInt(10), big.NewInt(11)
+	if err := config.IsValid(); err == nil {
+		t.Errorf("deterministic deployment block preceding Constantinople accepted")
+	}
+	if config.IsDeterministicDeploymentBlock(big.NewInt(10)) {
+		t.Errorf("proxy installed before Constantinople")
+	}
+	config.ConstantinopleBlock = nil
+	if err := config.IsValid(); err == nil {
+		t.Errorf("deterministic deployment without Constantinople accepted")
+	}
+}
+
+func TestStrictEIP155(t *testing.T) {
+	config := *TestChainConfig
+	config.StrictEIP155Block = big.NewInt(10)
+	if err := config.IsValid(); err != nil {
+		t.Fatalf("valid strict EIP155 block rejected: %v", err)
+	}
+	if config.IsStrictEIP155(big.NewInt(9)) || !config.IsStrictEIP155(big.NewInt(10)) {
+		t.Errorf("strict EIP155 activation mismatch")
+	}
+	config.EIP155Block = big.NewInt(11)
+	if err := config.IsValid(); err == nil {
+		t.Errorf("strict EIP155 block preceding the EIP155 block accepted")
+	}
+	config.EIP155Block, config.ChainID = big.NewInt(0), nil
+	if err := config.IsValid(); err == nil {
+		t.Errorf("strict EIP155 without chain ID accepted")
+	}
+}
+
+func TestBridgeConsensus(t *testing.T) {
+	config := *TestChainConfig
+	for _, consensus := range []string{"istanbul", "clique"} {
+		config.Bridges = []*BridgeConfig{{Address: common.HexToAddress("0x0b00000000000000000000000000000000000000"), Block: big.NewInt(10), Consensus: consensus}}
+		if err := config.IsValid(); err != nil {
+			t.Errorf("%s bridge rejected: %v", consensus, err)
+		}
+	}
+	// Proof-of-work headers can't be verified
+	config.Bridges[0].Consensus = "ethash"
+	if err := config.IsValid(); err == nil {
+		t.Errorf("ethash bridge accepted")
+	}
+}
+
+func TestActivateScheduled(t *testing.T) {
+	sload := uint64(800)
+	config := *TestChainConfig
+	config.StrictEIP155Block = big.NewInt(100)
+	config.ExperimentalInterpreters = map[string]*big.Int{"early": big.NewInt(5), "late": big.NewInt(200)}
+	config.GasScheduleOverrides = []*GasScheduleOverride{{Block: big.NewInt(150), SLoad: &sload}}
+	config.Istanbul = &IstanbulConfig{Epoch: 30000, Ceil2Nby3Block: big.NewInt(120)}
+	config.Bridges = []*BridgeConfig{{Block: big.NewInt(10), Consensus: "istanbul", Ceil2Nby3Block: big.NewInt(300)}}
+
+	activated, names, err := config.ActivateScheduled(big.NewInt(50), big.NewInt(40))
+	if err != nil {
+		t.Fatalf("failed to activate transitions: %v", err)
+	}
+	want := []string{"experimentalInterpreters.late", "gasScheduleOverrides[0].block", "istanbul.ceil2Nby3Block", "strictEIP155Block"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("activated transitions mismatch: have %v, want %v", names, want)
+	}
+	if !activated.IsStrictEIP155(big.NewInt(40)) || activated.IsStrictEIP155(big.NewInt(39)) {
+		t.Errorf("strict EIP155 not activated at block 40")
+	}
+	if activated.GasScheduleOverrideAt(big.NewInt(40)) == nil {
+		t.Errorf("gas schedule override not activated at block 40")
+	}
+	if activated.Istanbul.Ceil2Nby3Block.Uint64() != 40 || activated.Istanbul.Epoch != 30000 {
+		t.Errorf("istanbul config mismatch: %+v", activated.Istanbul)
+	}
+	if !activated.IsExperimentalInterpreter("early", big.NewInt(5)) || !activated.IsExperimentalInterpreter("late", big.NewInt(40)) {
+		t.Errorf("experimental interpreters mismatch: %v", activated.ExperimentalInterpreters)
+	}
+	// The past transitions and the original configuration are left untouched
+	if activated.ByzantiumBlock.Sign() != 0 || activated.ChainID.Cmp(config.ChainID) != 0 || activated.TransactionSizeLimit != config.TransactionSizeLimit {
+		t.Errorf("past transitions changed")
+	}
+	// The Istanbul block of the bridged chain is none of this chain
+	if activated.Bridges[0].Ceil2Nby3Block.Uint64() != 300 {
+		t.Errorf("bridged chain transition rescheduled: %v", activated.Bridges[0].Ceil2Nby3Block)
+	}
+	if config.StrictEIP155Block.Uint64() != 100 {
+		t.Errorf("original configuration changed")
+	}
+}
@@ -0,0 +1,30 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package params
+
+import "github.com/ethereum/go-ethereum/common"
+
+// DeterministicDeploymentProxy is the address of the deterministic deployment
+// proxy. Public networks share it, as the proxy is deployed there by a keyless
+// transaction, so contracts deployed through it get the same address on every
+// chain.
+var DeterministicDeploymentProxy = common.HexToAddress("0x4e59b44847b379578588920ca78fbf26c0b4956c")
+
+// DeterministicDeploymentProxyCode is the runtime code of the deterministic
+// deployment proxy. Called with a 32 byte salt followed by init code, it
+// deploys the init code with CREATE2 and returns the 20 byte contract address.
+var DeterministicDeploymentProxyCode = common.FromHex("0x7fffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffe03601600081602082378035828234f58015156039578182fd5b8082525050506014600cf3")
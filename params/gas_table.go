@@ -34,6 +34,14 @@ type GasTable struct {
 	// to call. May be left nil. Nil means
 	// not charged.
 	CreateBySuicide uint64
+
+	// Quorum
+	//
+	// SStoreSet and SStoreReset reprice the storage writes setting a zero slot
+	// and changing an existing one, overriding the protocol costs when set by
+	// the gas schedule overrides of the chain config. Zero means not overridden.
+	SStoreSet   uint64
+	SStoreReset uint64
 }
 
 // Variables containing gas prices for different ethereum phases.
@@ -96,6 +96,12 @@ const (
 	Bn256PairingBaseGas        uint64 = 100000 // Base price for an elliptic curve pairing check
 	Bn256PairingPerPointGas    uint64 = 80000  // Per-point price for an elliptic curve pairing check
 	QuorumMaximumExtraDataSize uint64 = 65     // Maximum size extra data may be after Genesis.
+
+	PoseidonBaseGas      uint64 = 8000 // Base price for a Poseidon hash
+	PoseidonQuadInputGas uint64 = 750  // Price for a Poseidon hash, multiplied by the squared number of inputs
+
+	BridgeVerifyBaseGas    uint64 = 20000 // Base price for a bridge header or Merkle proof verification
+	BridgeVerifyPerWordGas uint64 = 1500  // Per word price for a bridge verification, covering the signature recoveries and hashing
 )
 
 var (
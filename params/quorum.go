@@ -1,7 +1,13 @@
 package params
 
 const (
-	PERMISSIONED_CONFIG = "permissioned-nodes.json"
-	BLACKLIST_CONFIG    = "disallowed-nodes.json"
+	PERMISSIONED_CONFIG     = "permissioned-nodes.json"
+	BLACKLIST_CONFIG        = "disallowed-nodes.json"
 	PERMISSION_MODEL_CONFIG = "permission-config.json"
+	OPERATOR_KEYS_CONFIG    = "operator-keys.json"
+	OPERATOR_AUDIT_LOG      = "operator-audit.log"
+	IP_ACCESS_CONFIG        = "ip-access.json"
+	UNLOCK_POLICY_CONFIG    = "unlock-policies.json"
+	APPROVAL_POLICY_CONFIG  = "approval-policies.json"
+	PERMISSIONED_DELTAS     = "permissioned-nodes-deltas.json"
 )
@@ -0,0 +1,105 @@
+// Copyright 2016 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package params
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sort"
+)
+
+// ActivateScheduled returns a copy of the configuration with the transitions
+// scheduled after the head block activated at the given block instead, to run
+// the recent blocks under the rules of the upcoming transitions. The names of
+// the rescheduled transitions are returned, sorted.
+//
+// The transitions are those listed by transitions, and the experimental
+// interpreters.
+func (c *ChainConfig) ActivateScheduled(head, at *big.Int) (*ChainConfig, []string, error) {
+	// Copy the configuration deeply, its blocks being pointers
+	blob, err := json.Marshal(c)
+	if err != nil {
+		return nil, nil, err
+	}
+	activated := new(ChainConfig)
+	if err := json.Unmarshal(blob, activated); err != nil {
+		return nil, nil, err
+	}
+	var names []string
+	for _, t := range activated.transitions() {
+		if *t.block != nil && (*t.block).Cmp(head) > 0 {
+			*t.block = new(big.Int).Set(at)
+			names = append(names, t.name)
+		}
+	}
+	for name, block := range activated.ExperimentalInterpreters {
+		if block != nil && block.Cmp(head) > 0 {
+			activated.ExperimentalInterpreters[name] = new(big.Int).Set(at)
+			names = append(names, "experimentalInterpreters."+name)
+		}
+	}
+	sort.Strings(names)
+	return activated, names, nil
+}
+
+// transition is a block of the configuration from which the rules change.
+type transition struct {
+	name  string    // Path of the field in the JSON configuration
+	block **big.Int // Field holding the block
+}
+
+// transitions lists the transition blocks of the configuration. The blocks of
+// the other chains, such as the Istanbul block of a bridge, are not transitions
+// of this one and must not be listed.
+func (c *ChainConfig) transitions() []transition {
+	transitions := []transition{
+		{"homesteadBlock", &c.HomesteadBlock},
+		{"daoForkBlock", &c.DAOForkBlock},
+		{"eip150Block", &c.EIP150Block},
+		{"eip155Block", &c.EIP155Block},
+		{"eip158Block", &c.EIP158Block},
+		{"byzantiumBlock", &c.ByzantiumBlock},
+		{"constantinopleBlock", &c.ConstantinopleBlock},
+		{"ewasmBlock", &c.EWASMBlock},
+		{"qip714Block", &c.QIP714Block},
+		{"maxCodeSizeChangeBlock", &c.MaxCodeSizeChangeBlock},
+		{"zkPrecompilesBlock", &c.ZKPrecompilesBlock},
+		{"deterministicDeploymentBlock", &c.DeterministicDeploymentBlock},
+		{"strictEIP155Block", &c.StrictEIP155Block},
+	}
+	if c.Istanbul != nil {
+		transitions = append(transitions, transition{"istanbul.ceil2Nby3Block", &c.Istanbul.Ceil2Nby3Block})
+	}
+	if c.StateAccessLimits != nil {
+		transitions = append(transitions, transition{"stateAccessLimits.block", &c.StateAccessLimits.Block})
+	}
+	if c.DocumentAnchors != nil {
+		transitions = append(transitions, transition{"documentAnchors.block", &c.DocumentAnchors.Block})
+	}
+	for i, override := range c.GasScheduleOverrides {
+		if override != nil {
+			transitions = append(transitions, transition{fmt.Sprintf("gasScheduleOverrides[%d].block", i), &override.Block})
+		}
+	}
+	for i, bridge := range c.Bridges {
+		if bridge != nil {
+			transitions = append(transitions, transition{fmt.Sprintf("bridges[%d].block", i), &bridge.Block})
+		}
+	}
+	return transitions
+}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/ethereum/go-ethereum/accounts"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/adminauth"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/internal/ethapi"
@@ -170,6 +171,11 @@ func (q *QuorumControlsAPI) AcctList() []types.AccountInfo {
 	return types.AcctInfoMap.GetAcctList()
 }
 
+// OrgQuotas returns the block space quotas of the organizations
+func (q *QuorumControlsAPI) OrgQuotas() map[string]types.OrgQuota {
+	return types.GetOrgQuotas()
+}
+
 func (q *QuorumControlsAPI) GetOrgDetails(orgId string) (types.OrgDetailInfo, error) {
 	if o := types.OrgInfoMap.GetOrg(orgId); o == nil {
 		return types.OrgDetailInfo{}, errors.New("org does not exist")
@@ -214,7 +220,10 @@ func reportExecError(action PermAction, err error) (string, error) {
 	return ExecStatus{false, msg}.OpStatus()
 }
 
-func (q *QuorumControlsAPI) AddOrg(orgId string, url string, acct common.Address, txa ethapi.SendTxArgs) (string, error) {
+func (q *QuorumControlsAPI) AddOrg(orgId string, url string, acct common.Address, txa ethapi.SendTxArgs, sig *adminauth.Signature) (string, error) {
+	if err := q.permCtrl.node.OperatorAuthenticator().Verify("quorumPermission_addOrg", sig, orgId, url, acct); err != nil {
+		return "", err
+	}
 	pinterf, execStatus := q.initOp(txa)
 	if execStatus != ExecSuccess {
 		return execStatus.OpStatus()
@@ -232,7 +241,10 @@ func (q *QuorumControlsAPI) AddOrg(orgId string, url string, acct common.Address
 	return ExecSuccess.OpStatus()
 }
 
-func (q *QuorumControlsAPI) AddSubOrg(porgId, orgId string, url string, txa ethapi.SendTxArgs) (string, error) {
+func (q *QuorumControlsAPI) AddSubOrg(porgId, orgId string, url string, txa ethapi.SendTxArgs, sig *adminauth.Signature) (string, error) {
+	if err := q.permCtrl.node.OperatorAuthenticator().Verify("quorumPermission_addSubOrg", sig, porgId, orgId, url); err != nil {
+		return "", err
+	}
 	pinterf, execStatus := q.initOp(txa)
 	if execStatus != ExecSuccess {
 		return execStatus.OpStatus()
@@ -250,7 +262,10 @@ func (q *QuorumControlsAPI) AddSubOrg(porgId, orgId string, url string, txa etha
 	return ExecSuccess.OpStatus()
 }
 
-func (q *QuorumControlsAPI) ApproveOrg(orgId string, url string, acct common.Address, txa ethapi.SendTxArgs) (string, error) {
+func (q *QuorumControlsAPI) ApproveOrg(orgId string, url string, acct common.Address, txa ethapi.SendTxArgs, sig *adminauth.Signature) (string, error) {
+	if err := q.permCtrl.node.OperatorAuthenticator().Verify("quorumPermission_approveOrg", sig, orgId, url, acct); err != nil {
+		return "", err
+	}
 	pinterf, execStatus := q.initOp(txa)
 	if execStatus != ExecSuccess {
 		return execStatus.OpStatus()
@@ -267,7 +282,10 @@ func (q *QuorumControlsAPI) ApproveOrg(orgId string, url string, acct common.Add
 	return ExecSuccess.OpStatus()
 }
 
-func (q *QuorumControlsAPI) UpdateOrgStatus(orgId string, status uint8, txa ethapi.SendTxArgs) (string, error) {
+func (q *QuorumControlsAPI) UpdateOrgStatus(orgId string, status uint8, txa ethapi.SendTxArgs, sig *adminauth.Signature) (string, error) {
+	if err := q.permCtrl.node.OperatorAuthenticator().Verify("quorumPermission_updateOrgStatus", sig, orgId, status); err != nil {
+		return "", err
+	}
 	pinterf, execStatus := q.initOp(txa)
 	if execStatus != ExecSuccess {
 		return execStatus.OpStatus()
@@ -285,7 +303,10 @@ func (q *QuorumControlsAPI) UpdateOrgStatus(orgId string, status uint8, txa etha
 	return ExecSuccess.OpStatus()
 }
 
-func (q *QuorumControlsAPI) AddNode(orgId string, url string, txa ethapi.SendTxArgs) (string, error) {
+func (q *QuorumControlsAPI) AddNode(orgId string, url string, txa ethapi.SendTxArgs, sig *adminauth.Signature) (string, error) {
+	if err := q.permCtrl.node.OperatorAuthenticator().Verify("quorumPermission_addNode", sig, orgId, url); err != nil {
+		return "", err
+	}
 	pinterf, execStatus := q.initOp(txa)
 	if execStatus != ExecSuccess {
 		return execStatus.OpStatus()
@@ -303,7 +324,10 @@ func (q *QuorumControlsAPI) AddNode(orgId string, url string, txa ethapi.SendTxA
 	return ExecSuccess.OpStatus()
 }
 
-func (q *QuorumControlsAPI) UpdateNodeStatus(orgId string, url string, action uint8, txa ethapi.SendTxArgs) (string, error) {
+func (q *QuorumControlsAPI) UpdateNodeStatus(orgId string, url string, action uint8, txa ethapi.SendTxArgs, sig *adminauth.Signature) (string, error) {
+	if err := q.permCtrl.node.OperatorAuthenticator().Verify("quorumPermission_updateNodeStatus", sig, orgId, url, action); err != nil {
+		return "", err
+	}
 	pinterf, execStatus := q.initOp(txa)
 	if execStatus != ExecSuccess {
 		return execStatus.OpStatus()
@@ -321,7 +345,10 @@ func (q *QuorumControlsAPI) UpdateNodeStatus(orgId string, url string, action ui
 	return ExecSuccess.OpStatus()
 }
 
-func (q *QuorumControlsAPI) ApproveOrgStatus(orgId string, status uint8, txa ethapi.SendTxArgs) (string, error) {
+func (q *QuorumControlsAPI) ApproveOrgStatus(orgId string, status uint8, txa ethapi.SendTxArgs, sig *adminauth.Signature) (string, error) {
+	if err := q.permCtrl.node.OperatorAuthenticator().Verify("quorumPermission_approveOrgStatus", sig, orgId, status); err != nil {
+		return "", err
+	}
 	pinterf, execStatus := q.initOp(txa)
 	if execStatus != ExecSuccess {
 		return execStatus.OpStatus()
@@ -339,7 +366,10 @@ func (q *QuorumControlsAPI) ApproveOrgStatus(orgId string, status uint8, txa eth
 	return ExecSuccess.OpStatus()
 }
 
-func (q *QuorumControlsAPI) AssignAdminRole(orgId string, acct common.Address, roleId string, txa ethapi.SendTxArgs) (string, error) {
+func (q *QuorumControlsAPI) AssignAdminRole(orgId string, acct common.Address, roleId string, txa ethapi.SendTxArgs, sig *adminauth.Signature) (string, error) {
+	if err := q.permCtrl.node.OperatorAuthenticator().Verify("quorumPermission_assignAdminRole", sig, orgId, acct, roleId); err != nil {
+		return "", err
+	}
 	pinterf, execStatus := q.initOp(txa)
 	if execStatus != ExecSuccess {
 		return execStatus.OpStatus()
@@ -357,7 +387,10 @@ func (q *QuorumControlsAPI) AssignAdminRole(orgId string, acct common.Address, r
 	return ExecSuccess.OpStatus()
 }
 
-func (q *QuorumControlsAPI) ApproveAdminRole(orgId string, acct common.Address, txa ethapi.SendTxArgs) (string, error) {
+func (q *QuorumControlsAPI) ApproveAdminRole(orgId string, acct common.Address, txa ethapi.SendTxArgs, sig *adminauth.Signature) (string, error) {
+	if err := q.permCtrl.node.OperatorAuthenticator().Verify("quorumPermission_approveAdminRole", sig, orgId, acct); err != nil {
+		return "", err
+	}
 	pinterf, execStatus := q.initOp(txa)
 	if execStatus != ExecSuccess {
 		return execStatus.OpStatus()
@@ -375,7 +408,10 @@ func (q *QuorumControlsAPI) ApproveAdminRole(orgId string, acct common.Address,
 	return ExecSuccess.OpStatus()
 }
 
-func (q *QuorumControlsAPI) AddNewRole(orgId string, roleId string, access uint8, isVoter bool, isAdmin bool, txa ethapi.SendTxArgs) (string, error) {
+func (q *QuorumControlsAPI) AddNewRole(orgId string, roleId string, access uint8, isVoter bool, isAdmin bool, txa ethapi.SendTxArgs, sig *adminauth.Signature) (string, error) {
+	if err := q.permCtrl.node.OperatorAuthenticator().Verify("quorumPermission_addNewRole", sig, orgId, roleId, access, isVoter, isAdmin); err != nil {
+		return "", err
+	}
 	pinterf, execStatus := q.initOp(txa)
 	if execStatus != ExecSuccess {
 		return execStatus.OpStatus()
@@ -393,7 +429,10 @@ func (q *QuorumControlsAPI) AddNewRole(orgId string, roleId string, access uint8
 	return ExecSuccess.OpStatus()
 }
 
-func (q *QuorumControlsAPI) RemoveRole(orgId string, roleId string, txa ethapi.SendTxArgs) (string, error) {
+func (q *QuorumControlsAPI) RemoveRole(orgId string, roleId string, txa ethapi.SendTxArgs, sig *adminauth.Signature) (string, error) {
+	if err := q.permCtrl.node.OperatorAuthenticator().Verify("quorumPermission_removeRole", sig, orgId, roleId); err != nil {
+		return "", err
+	}
 	pinterf, execStatus := q.initOp(txa)
 	if execStatus != ExecSuccess {
 		return execStatus.OpStatus()
@@ -411,7 +450,10 @@ func (q *QuorumControlsAPI) RemoveRole(orgId string, roleId string, txa ethapi.S
 	return ExecSuccess.OpStatus()
 }
 
-func (q *QuorumControlsAPI) AddAccountToOrg(acct common.Address, orgId string, roleId string, txa ethapi.SendTxArgs) (string, error) {
+func (q *QuorumControlsAPI) AddAccountToOrg(acct common.Address, orgId string, roleId string, txa ethapi.SendTxArgs, sig *adminauth.Signature) (string, error) {
+	if err := q.permCtrl.node.OperatorAuthenticator().Verify("quorumPermission_addAccountToOrg", sig, acct, orgId, roleId); err != nil {
+		return "", err
+	}
 	pinterf, execStatus := q.initOp(txa)
 	if execStatus != ExecSuccess {
 		return execStatus.OpStatus()
@@ -428,7 +470,10 @@ func (q *QuorumControlsAPI) AddAccountToOrg(acct common.Address, orgId string, r
 	log.Debug("executed permission action", "action", AddAccountToOrg, "tx", tx)
 	return ExecSuccess.OpStatus()
 }
-func (q *QuorumControlsAPI) ChangeAccountRole(acct common.Address, orgId string, roleId string, txa ethapi.SendTxArgs) (string, error) {
+func (q *QuorumControlsAPI) ChangeAccountRole(acct common.Address, orgId string, roleId string, txa ethapi.SendTxArgs, sig *adminauth.Signature) (string, error) {
+	if err := q.permCtrl.node.OperatorAuthenticator().Verify("quorumPermission_changeAccountRole", sig, acct, orgId, roleId); err != nil {
+		return "", err
+	}
 	pinterf, execStatus := q.initOp(txa)
 	if execStatus != ExecSuccess {
 		return execStatus.OpStatus()
@@ -446,7 +491,10 @@ func (q *QuorumControlsAPI) ChangeAccountRole(acct common.Address, orgId string,
 	return ExecSuccess.OpStatus()
 }
 
-func (q *QuorumControlsAPI) UpdateAccountStatus(orgId string, acct common.Address, status uint8, txa ethapi.SendTxArgs) (string, error) {
+func (q *QuorumControlsAPI) UpdateAccountStatus(orgId string, acct common.Address, status uint8, txa ethapi.SendTxArgs, sig *adminauth.Signature) (string, error) {
+	if err := q.permCtrl.node.OperatorAuthenticator().Verify("quorumPermission_updateAccountStatus", sig, orgId, acct, status); err != nil {
+		return "", err
+	}
 	pinterf, execStatus := q.initOp(txa)
 	if execStatus != ExecSuccess {
 		return execStatus.OpStatus()
@@ -464,7 +512,10 @@ func (q *QuorumControlsAPI) UpdateAccountStatus(orgId string, acct common.Addres
 	return ExecSuccess.OpStatus()
 }
 
-func (q *QuorumControlsAPI) RecoverBlackListedNode(orgId string, enodeId string, txa ethapi.SendTxArgs) (string, error) {
+func (q *QuorumControlsAPI) RecoverBlackListedNode(orgId string, enodeId string, txa ethapi.SendTxArgs, sig *adminauth.Signature) (string, error) {
+	if err := q.permCtrl.node.OperatorAuthenticator().Verify("quorumPermission_recoverBlackListedNode", sig, orgId, enodeId); err != nil {
+		return "", err
+	}
 	pinterf, execStatus := q.initOp(txa)
 	if execStatus != ExecSuccess {
 		return execStatus.OpStatus()
@@ -482,7 +533,10 @@ func (q *QuorumControlsAPI) RecoverBlackListedNode(orgId string, enodeId string,
 	return ExecSuccess.OpStatus()
 }
 
-func (q *QuorumControlsAPI) ApproveBlackListedNodeRecovery(orgId string, enodeId string, txa ethapi.SendTxArgs) (string, error) {
+func (q *QuorumControlsAPI) ApproveBlackListedNodeRecovery(orgId string, enodeId string, txa ethapi.SendTxArgs, sig *adminauth.Signature) (string, error) {
+	if err := q.permCtrl.node.OperatorAuthenticator().Verify("quorumPermission_approveBlackListedNodeRecovery", sig, orgId, enodeId); err != nil {
+		return "", err
+	}
 	pinterf, execStatus := q.initOp(txa)
 	if execStatus != ExecSuccess {
 		return execStatus.OpStatus()
@@ -500,7 +554,10 @@ func (q *QuorumControlsAPI) ApproveBlackListedNodeRecovery(orgId string, enodeId
 	return ExecSuccess.OpStatus()
 }
 
-func (q *QuorumControlsAPI) RecoverBlackListedAccount(orgId string, acctId common.Address, txa ethapi.SendTxArgs) (string, error) {
+func (q *QuorumControlsAPI) RecoverBlackListedAccount(orgId string, acctId common.Address, txa ethapi.SendTxArgs, sig *adminauth.Signature) (string, error) {
+	if err := q.permCtrl.node.OperatorAuthenticator().Verify("quorumPermission_recoverBlackListedAccount", sig, orgId, acctId); err != nil {
+		return "", err
+	}
 	pinterf, execStatus := q.initOp(txa)
 	if execStatus != ExecSuccess {
 		return execStatus.OpStatus()
@@ -518,7 +575,10 @@ func (q *QuorumControlsAPI) RecoverBlackListedAccount(orgId string, acctId commo
 	return ExecSuccess.OpStatus()
 }
 
-func (q *QuorumControlsAPI) ApproveBlackListedAccountRecovery(orgId string, acctId common.Address, txa ethapi.SendTxArgs) (string, error) {
+func (q *QuorumControlsAPI) ApproveBlackListedAccountRecovery(orgId string, acctId common.Address, txa ethapi.SendTxArgs, sig *adminauth.Signature) (string, error) {
+	if err := q.permCtrl.node.OperatorAuthenticator().Verify("quorumPermission_approveBlackListedAccountRecovery", sig, orgId, acctId); err != nil {
+		return "", err
+	}
 	pinterf, execStatus := q.initOp(txa)
 	if execStatus != ExecSuccess {
 		return execStatus.OpStatus()
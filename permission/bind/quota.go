@@ -0,0 +1,522 @@
+// Code generated - DO NOT EDIT.
+// This file is a generated binding and any manual changes will be lost.
+
+package permission
+
+import (
+	"math/big"
+	"strings"
+
+	ethereum "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var (
+	_ = big.NewInt
+	_ = strings.NewReader
+	_ = ethereum.NotFound
+	_ = abi.U256
+	_ = bind.Bind
+	_ = common.Big1
+	_ = types.BloomLookup
+	_ = event.NewSubscription
+)
+
+// OrgQuotaManagerABI is the input ABI used to generate the binding from.
+const OrgQuotaManagerABI = "[{\"constant\":false,\"inputs\":[{\"name\":\"_orgId\",\"type\":\"string\"},{\"name\":\"_txPerBlock\",\"type\":\"uint256\"},{\"name\":\"_gasPerBlock\",\"type\":\"uint256\"},{\"name\":\"_maxPending\",\"type\":\"uint256\"}],\"name\":\"setOrgQuota\",\"outputs\":[],\"payable\":false,\"stateMutability\":\"nonpayable\",\"type\":\"function\"},{\"constant\":false,\"inputs\":[{\"name\":\"_orgId\",\"type\":\"string\"}],\"name\":\"removeOrgQuota\",\"outputs\":[],\"payable\":false,\"stateMutability\":\"nonpayable\",\"type\":\"function\"},{\"constant\":true,\"inputs\":[],\"name\":\"getNumberOfQuotas\",\"outputs\":[{\"name\":\"\",\"type\":\"uint256\"}],\"payable\":false,\"stateMutability\":\"view\",\"type\":\"function\"},{\"constant\":true,\"inputs\":[{\"name\":\"_index\",\"type\":\"uint256\"}],\"name\":\"getQuotaByIndex\",\"outputs\":[{\"name\":\"\",\"type\":\"string\"},{\"name\":\"\",\"type\":\"uint256\"},{\"name\":\"\",\"type\":\"uint256\"},{\"name\":\"\",\"type\":\"uint256\"}],\"payable\":false,\"stateMutability\":\"view\",\"type\":\"function\"},{\"inputs\":[{\"name\":\"_permUpgradable\",\"type\":\"address\"}],\"payable\":false,\"stateMutability\":\"nonpayable\",\"type\":\"constructor\"},{\"anonymous\":false,\"inputs\":[{\"indexed\":false,\"name\":\"_orgId\",\"type\":\"string\"},{\"indexed\":false,\"name\":\"_txPerBlock\",\"type\":\"uint256\"},{\"indexed\":false,\"name\":\"_gasPerBlock\",\"type\":\"uint256\"},{\"indexed\":false,\"name\":\"_maxPending\",\"type\":\"uint256\"}],\"name\":\"OrgQuotaSet\",\"type\":\"event\"},{\"anonymous\":false,\"inputs\":[{\"indexed\":false,\"name\":\"_orgId\",\"type\":\"string\"}],\"name\":\"OrgQuotaRemoved\",\"type\":\"event\"}]"
+
+// OrgQuotaManager is an auto generated Go binding around an Ethereum contract.
+type OrgQuotaManager struct {
+	OrgQuotaManagerCaller     // Read-only binding to the contract
+	OrgQuotaManagerTransactor // Write-only binding to the contract
+	OrgQuotaManagerFilterer   // Log filterer for contract events
+}
+
+// OrgQuotaManagerCaller is an auto generated read-only Go binding around an Ethereum contract.
+type OrgQuotaManagerCaller struct {
+	contract *bind.BoundContract // Generic contract wrapper for the low level calls
+}
+
+// OrgQuotaManagerTransactor is an auto generated write-only Go binding around an Ethereum contract.
+type OrgQuotaManagerTransactor struct {
+	contract *bind.BoundContract // Generic contract wrapper for the low level calls
+}
+
+// OrgQuotaManagerFilterer is an auto generated log filtering Go binding around an Ethereum contract events.
+type OrgQuotaManagerFilterer struct {
+	contract *bind.BoundContract // Generic contract wrapper for the low level calls
+}
+
+// OrgQuotaManagerSession is an auto generated Go binding around an Ethereum contract,
+// with pre-set call and transact options.
+type OrgQuotaManagerSession struct {
+	Contract     *OrgQuotaManager  // Generic contract binding to set the session for
+	CallOpts     bind.CallOpts     // Call options to use throughout this session
+	TransactOpts bind.TransactOpts // Transaction auth options to use throughout this session
+}
+
+// OrgQuotaManagerCallerSession is an auto generated read-only Go binding around an Ethereum contract,
+// with pre-set call options.
+type OrgQuotaManagerCallerSession struct {
+	Contract *OrgQuotaManagerCaller // Generic contract caller binding to set the session for
+	CallOpts bind.CallOpts          // Call options to use throughout this session
+}
+
+// OrgQuotaManagerTransactorSession is an auto generated write-only Go binding around an Ethereum contract,
+// with pre-set transact options.
+type OrgQuotaManagerTransactorSession struct {
+	Contract     *OrgQuotaManagerTransactor // Generic contract transactor binding to set the session for
+	TransactOpts bind.TransactOpts          // Transaction auth options to use throughout this session
+}
+
+// OrgQuotaManagerRaw is an auto generated low-level Go binding around an Ethereum contract.
+type OrgQuotaManagerRaw struct {
+	Contract *OrgQuotaManager // Generic contract binding to access the raw methods on
+}
+
+// OrgQuotaManagerCallerRaw is an auto generated low-level read-only Go binding around an Ethereum contract.
+type OrgQuotaManagerCallerRaw struct {
+	Contract *OrgQuotaManagerCaller // Generic read-only contract binding to access the raw methods on
+}
+
+// OrgQuotaManagerTransactorRaw is an auto generated low-level write-only Go binding around an Ethereum contract.
+type OrgQuotaManagerTransactorRaw struct {
+	Contract *OrgQuotaManagerTransactor // Generic write-only contract binding to access the raw methods on
+}
+
+// NewOrgQuotaManager creates a new instance of OrgQuotaManager, bound to a specific deployed contract.
+func NewOrgQuotaManager(address common.Address, backend bind.ContractBackend) (*OrgQuotaManager, error) {
+	contract, err := bindOrgQuotaManager(address, backend, backend, backend)
+	if err != nil {
+		return nil, err
+	}
+	return &OrgQuotaManager{OrgQuotaManagerCaller: OrgQuotaManagerCaller{contract: contract}, OrgQuotaManagerTransactor: OrgQuotaManagerTransactor{contract: contract}, OrgQuotaManagerFilterer: OrgQuotaManagerFilterer{contract: contract}}, nil
+}
+
+// NewOrgQuotaManagerCaller creates a new read-only instance of OrgQuotaManager, bound to a specific deployed contract.
+func NewOrgQuotaManagerCaller(address common.Address, caller bind.ContractCaller) (*OrgQuotaManagerCaller, error) {
+	contract, err := bindOrgQuotaManager(address, caller, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &OrgQuotaManagerCaller{contract: contract}, nil
+}
+
+// NewOrgQuotaManagerTransactor creates a new write-only instance of OrgQuotaManager, bound to a specific deployed contract.
+func NewOrgQuotaManagerTransactor(address common.Address, transactor bind.ContractTransactor) (*OrgQuotaManagerTransactor, error) {
+	contract, err := bindOrgQuotaManager(address, nil, transactor, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &OrgQuotaManagerTransactor{contract: contract}, nil
+}
+
+// NewOrgQuotaManagerFilterer creates a new log filterer instance of OrgQuotaManager, bound to a specific deployed contract.
+func NewOrgQuotaManagerFilterer(address common.Address, filterer bind.ContractFilterer) (*OrgQuotaManagerFilterer, error) {
+	contract, err := bindOrgQuotaManager(address, nil, nil, filterer)
+	if err != nil {
+		return nil, err
+	}
+	return &OrgQuotaManagerFilterer{contract: contract}, nil
+}
+
+// bindOrgQuotaManager binds a generic wrapper to an already deployed contract.
+func bindOrgQuotaManager(address common.Address, caller bind.ContractCaller, transactor bind.ContractTransactor, filterer bind.ContractFilterer) (*bind.BoundContract, error) {
+	parsed, err := abi.JSON(strings.NewReader(OrgQuotaManagerABI))
+	if err != nil {
+		return nil, err
+	}
+	return bind.NewBoundContract(address, parsed, caller, transactor, filterer), nil
+}
+
+// Call invokes the (constant) contract method with params as input values and
+// sets the output to result. The result type might be a single field for simple
+// returns, a slice of interfaces for anonymous returns and a struct for named
+// returns.
+func (_OrgQuotaManager *OrgQuotaManagerRaw) Call(opts *bind.CallOpts, result interface{}, method string, params ...interface{}) error {
+	return _OrgQuotaManager.Contract.OrgQuotaManagerCaller.contract.Call(opts, result, method, params...)
+}
+
+// Transfer initiates a plain transaction to move funds to the contract, calling
+// its default method if one is available.
+func (_OrgQuotaManager *OrgQuotaManagerRaw) Transfer(opts *bind.TransactOpts) (*types.Transaction, error) {
+	return _OrgQuotaManager.Contract.OrgQuotaManagerTransactor.contract.Transfer(opts)
+}
+
+// Transact invokes the (paid) contract method with params as input values.
+func (_OrgQuotaManager *OrgQuotaManagerRaw) Transact(opts *bind.TransactOpts, method string, params ...interface{}) (*types.Transaction, error) {
+	return _OrgQuotaManager.Contract.OrgQuotaManagerTransactor.contract.Transact(opts, method, params...)
+}
+
+// Call invokes the (constant) contract method with params as input values and
+// sets the output to result. The result type might be a single field for simple
+// returns, a slice of interfaces for anonymous returns and a struct for named
+// returns.
+func (_OrgQuotaManager *OrgQuotaManagerCallerRaw) Call(opts *bind.CallOpts, result interface{}, method string, params ...interface{}) error {
+	return _OrgQuotaManager.Contract.contract.Call(opts, result, method, params...)
+}
+
+// Transfer initiates a plain transaction to move funds to the contract, calling
+// its default method if one is available.
+func (_OrgQuotaManager *OrgQuotaManagerTransactorRaw) Transfer(opts *bind.TransactOpts) (*types.Transaction, error) {
+	return _OrgQuotaManager.Contract.contract.Transfer(opts)
+}
+
+// Transact invokes the (paid) contract method with params as input values.
+func (_OrgQuotaManager *OrgQuotaManagerTransactorRaw) Transact(opts *bind.TransactOpts, method string, params ...interface{}) (*types.Transaction, error) {
+	return _OrgQuotaManager.Contract.contract.Transact(opts, method, params...)
+}
+
+// GetNumberOfQuotas is a free data retrieval call binding the contract method 0xc7fda467.
+//
+// Solidity: function getNumberOfQuotas() constant returns(uint256)
+func (_OrgQuotaManager *OrgQuotaManagerCaller) GetNumberOfQuotas(opts *bind.CallOpts) (*big.Int, error) {
+	var (
+		ret0 = new(*big.Int)
+	)
+	out := ret0
+	err := _OrgQuotaManager.contract.Call(opts, out, "getNumberOfQuotas")
+	return *ret0, err
+}
+
+// GetNumberOfQuotas is a free data retrieval call binding the contract method 0xc7fda467.
+//
+// Solidity: function getNumberOfQuotas() constant returns(uint256)
+func (_OrgQuotaManager *OrgQuotaManagerSession) GetNumberOfQuotas() (*big.Int, error) {
+	return _OrgQuotaManager.Contract.GetNumberOfQuotas(&_OrgQuotaManager.CallOpts)
+}
+
+// GetNumberOfQuotas is a free data retrieval call binding the contract method 0xc7fda467.
+//
+// Solidity: function getNumberOfQuotas() constant returns(uint256)
+func (_OrgQuotaManager *OrgQuotaManagerCallerSession) GetNumberOfQuotas() (*big.Int, error) {
+	return _OrgQuotaManager.Contract.GetNumberOfQuotas(&_OrgQuotaManager.CallOpts)
+}
+
+// GetQuotaByIndex is a free data retrieval call binding the contract method 0xabb0c961.
+//
+// Solidity: function getQuotaByIndex(_index uint256) constant returns(string, uint256, uint256, uint256)
+func (_OrgQuotaManager *OrgQuotaManagerCaller) GetQuotaByIndex(opts *bind.CallOpts, _index *big.Int) (string, *big.Int, *big.Int, *big.Int, error) {
+	var (
+		ret0 = new(string)
+		ret1 = new(*big.Int)
+		ret2 = new(*big.Int)
+		ret3 = new(*big.Int)
+	)
+	out := &[]interface{}{
+		ret0,
+		ret1,
+		ret2,
+		ret3,
+	}
+	err := _OrgQuotaManager.contract.Call(opts, out, "getQuotaByIndex", _index)
+	return *ret0, *ret1, *ret2, *ret3, err
+}
+
+// GetQuotaByIndex is a free data retrieval call binding the contract method 0xabb0c961.
+//
+// Solidity: function getQuotaByIndex(_index uint256) constant returns(string, uint256, uint256, uint256)
+func (_OrgQuotaManager *OrgQuotaManagerSession) GetQuotaByIndex(_index *big.Int) (string, *big.Int, *big.Int, *big.Int, error) {
+	return _OrgQuotaManager.Contract.GetQuotaByIndex(&_OrgQuotaManager.CallOpts, _index)
+}
+
+// GetQuotaByIndex is a free data retrieval call binding the contract method 0xabb0c961.
+//
+// Solidity: function getQuotaByIndex(_index uint256) constant returns(string, uint256, uint256, uint256)
+func (_OrgQuotaManager *OrgQuotaManagerCallerSession) GetQuotaByIndex(_index *big.Int) (string, *big.Int, *big.Int, *big.Int, error) {
+	return _OrgQuotaManager.Contract.GetQuotaByIndex(&_OrgQuotaManager.CallOpts, _index)
+}
+
+// RemoveOrgQuota is a paid mutator transaction binding the contract method 0xc8284ee8.
+//
+// Solidity: function removeOrgQuota(_orgId string) returns()
+func (_OrgQuotaManager *OrgQuotaManagerTransactor) RemoveOrgQuota(opts *bind.TransactOpts, _orgId string) (*types.Transaction, error) {
+	return _OrgQuotaManager.contract.Transact(opts, "removeOrgQuota", _orgId)
+}
+
+// RemoveOrgQuota is a paid mutator transaction binding the contract method 0xc8284ee8.
+//
+// Solidity: function removeOrgQuota(_orgId string) returns()
+func (_OrgQuotaManager *OrgQuotaManagerSession) RemoveOrgQuota(_orgId string) (*types.Transaction, error) {
+	return _OrgQuotaManager.Contract.RemoveOrgQuota(&_OrgQuotaManager.TransactOpts, _orgId)
+}
+
+// RemoveOrgQuota is a paid mutator transaction binding the contract method 0xc8284ee8.
+//
+// Solidity: function removeOrgQuota(_orgId string) returns()
+func (_OrgQuotaManager *OrgQuotaManagerTransactorSession) RemoveOrgQuota(_orgId string) (*types.Transaction, error) {
+	return _OrgQuotaManager.Contract.RemoveOrgQuota(&_OrgQuotaManager.TransactOpts, _orgId)
+}
+
+// SetOrgQuota is a paid mutator transaction binding the contract method 0xf92f5495.
+//
+// Solidity: function setOrgQuota(_orgId string, _txPerBlock uint256, _gasPerBlock uint256, _maxPending uint256) returns()
+func (_OrgQuotaManager *OrgQuotaManagerTransactor) SetOrgQuota(opts *bind.TransactOpts, _orgId string, _txPerBlock *big.Int, _gasPerBlock *big.Int, _maxPending *big.Int) (*types.Transaction, error) {
+	return _OrgQuotaManager.contract.Transact(opts, "setOrgQuota", _orgId, _txPerBlock, _gasPerBlock, _maxPending)
+}
+
+// SetOrgQuota is a paid mutator transaction binding the contract method 0xf92f5495.
+//
+// Solidity: function setOrgQuota(_orgId string, _txPerBlock uint256, _gasPerBlock uint256, _maxPending uint256) returns()
+func (_OrgQuotaManager *OrgQuotaManagerSession) SetOrgQuota(_orgId string, _txPerBlock *big.Int, _gasPerBlock *big.Int, _maxPending *big.Int) (*types.Transaction, error) {
+	return _OrgQuotaManager.Contract.SetOrgQuota(&_OrgQuotaManager.TransactOpts, _orgId, _txPerBlock, _gasPerBlock, _maxPending)
+}
+
+// SetOrgQuota is a paid mutator transaction binding the contract method 0xf92f5495.
+//
+// Solidity: function setOrgQuota(_orgId string, _txPerBlock uint256, _gasPerBlock uint256, _maxPending uint256) returns()
+func (_OrgQuotaManager *OrgQuotaManagerTransactorSession) SetOrgQuota(_orgId string, _txPerBlock *big.Int, _gasPerBlock *big.Int, _maxPending *big.Int) (*types.Transaction, error) {
+	return _OrgQuotaManager.Contract.SetOrgQuota(&_OrgQuotaManager.TransactOpts, _orgId, _txPerBlock, _gasPerBlock, _maxPending)
+}
+
+// OrgQuotaManagerOrgQuotaRemovedIterator is returned from FilterOrgQuotaRemoved and is used to iterate over the raw logs and unpacked data for OrgQuotaRemoved events raised by the OrgQuotaManager contract.
+type OrgQuotaManagerOrgQuotaRemovedIterator struct {
+	Event *OrgQuotaManagerOrgQuotaRemoved // Event containing the contract specifics and raw log
+
+	contract *bind.BoundContract // Generic contract to use for unpacking event data
+	event    string              // Event name to use for unpacking event data
+
+	logs chan types.Log        // Log channel receiving the found contract events
+	sub  ethereum.Subscription // Subscription for errors, completion and termination
+	done bool                  // Whether the subscription completed delivering logs
+	fail error                 // Occurred error to stop iteration
+}
+
+// Next advances the iterator to the subsequent event, returning whether there
+// are any more events found. In case of a retrieval or parsing error, false is
+// returned and Error() can be queried for the exact failure.
+func (it *OrgQuotaManagerOrgQuotaRemovedIterator) Next() bool {
+	// If the iterator failed, stop iterating
+	if it.fail != nil {
+		return false
+	}
+	// If the iterator completed, deliver directly whatever's available
+	if it.done {
+		select {
+		case log := <-it.logs:
+			it.Event = new(OrgQuotaManagerOrgQuotaRemoved)
+			if err := it.contract.UnpackLog(it.Event, it.event, log); err != nil {
+				it.fail = err
+				return false
+			}
+			it.Event.Raw = log
+			return true
+
+		default:
+			return false
+		}
+	}
+	// Iterator still in progress, wait for either a data or an error event
+	select {
+	case log := <-it.logs:
+		it.Event = new(OrgQuotaManagerOrgQuotaRemoved)
+		if err := it.contract.UnpackLog(it.Event, it.event, log); err != nil {
+			it.fail = err
+			return false
+		}
+		it.Event.Raw = log
+		return true
+
+	case err := <-it.sub.Err():
+		it.done = true
+		it.fail = err
+		return it.Next()
+	}
+}
+
+// Error returns any retrieval or parsing error occurred during filtering.
+func (it *OrgQuotaManagerOrgQuotaRemovedIterator) Error() error {
+	return it.fail
+}
+
+// Close terminates the iteration process, releasing any pending underlying
+// resources.
+func (it *OrgQuotaManagerOrgQuotaRemovedIterator) Close() error {
+	it.sub.Unsubscribe()
+	return nil
+}
+
+// OrgQuotaManagerOrgQuotaRemoved represents a OrgQuotaRemoved event raised by the OrgQuotaManager contract.
+type OrgQuotaManagerOrgQuotaRemoved struct {
+	OrgId string
+	Raw   types.Log // Blockchain specific contextual infos
+}
+
+// FilterOrgQuotaRemoved is a free log retrieval operation binding the contract event 0x196727d3748abe0dc061b247ae9a7e2fa00fe042ce7da262b44927790f9a88e5.
+//
+// Solidity: e OrgQuotaRemoved(_orgId string)
+func (_OrgQuotaManager *OrgQuotaManagerFilterer) FilterOrgQuotaRemoved(opts *bind.FilterOpts) (*OrgQuotaManagerOrgQuotaRemovedIterator, error) {
+
+	logs, sub, err := _OrgQuotaManager.contract.FilterLogs(opts, "OrgQuotaRemoved")
+	if err != nil {
+		return nil, err
+	}
+	return &OrgQuotaManagerOrgQuotaRemovedIterator{contract: _OrgQuotaManager.contract, event: "OrgQuotaRemoved", logs: logs, sub: sub}, nil
+}
+
+// WatchOrgQuotaRemoved is a free log subscription operation binding the contract event 0x196727d3748abe0dc061b247ae9a7e2fa00fe042ce7da262b44927790f9a88e5.
+//
+// Solidity: e OrgQuotaRemoved(_orgId string)
+func (_OrgQuotaManager *OrgQuotaManagerFilterer) WatchOrgQuotaRemoved(opts *bind.WatchOpts, sink chan<- *OrgQuotaManagerOrgQuotaRemoved) (event.Subscription, error) {
+
+	logs, sub, err := _OrgQuotaManager.contract.WatchLogs(opts, "OrgQuotaRemoved")
+	if err != nil {
+		return nil, err
+	}
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		defer sub.Unsubscribe()
+		for {
+			select {
+			case log := <-logs:
+				// New log arrived, parse the event and forward to the user
+				event := new(OrgQuotaManagerOrgQuotaRemoved)
+				if err := _OrgQuotaManager.contract.UnpackLog(event, "OrgQuotaRemoved", log); err != nil {
+					return err
+				}
+				event.Raw = log
+
+				select {
+				case sink <- event:
+				case err := <-sub.Err():
+					return err
+				case <-quit:
+					return nil
+				}
+			case err := <-sub.Err():
+				return err
+			case <-quit:
+				return nil
+			}
+		}
+	}), nil
+}
+
+// OrgQuotaManagerOrgQuotaSetIterator is returned from FilterOrgQuotaSet and is used to iterate over the raw logs and unpacked data for OrgQuotaSet events raised by the OrgQuotaManager contract.
+type OrgQuotaManagerOrgQuotaSetIterator struct {
+	Event *OrgQuotaManagerOrgQuotaSet // Event containing the contract specifics and raw log
+
+	contract *bind.BoundContract // Generic contract to use for unpacking event data
+	event    string              // Event name to use for unpacking event data
+
+	logs chan types.Log        // Log channel receiving the found contract events
+	sub  ethereum.Subscription // Subscription for errors, completion and termination
+	done bool                  // Whether the subscription completed delivering logs
+	fail error                 // Occurred error to stop iteration
+}
+
+// Next advances the iterator to the subsequent event, returning whether there
+// are any more events found. In case of a retrieval or parsing error, false is
+// returned and Error() can be queried for the exact failure.
+func (it *OrgQuotaManagerOrgQuotaSetIterator) Next() bool {
+	// If the iterator failed, stop iterating
+	if it.fail != nil {
+		return false
+	}
+	// If the iterator completed, deliver directly whatever's available
+	if it.done {
+		select {
+		case log := <-it.logs:
+			it.Event = new(OrgQuotaManagerOrgQuotaSet)
+			if err := it.contract.UnpackLog(it.Event, it.event, log); err != nil {
+				it.fail = err
+				return false
+			}
+			it.Event.Raw = log
+			return true
+
+		default:
+			return false
+		}
+	}
+	// Iterator still in progress, wait for either a data or an error event
+	select {
+	case log := <-it.logs:
+		it.Event = new(OrgQuotaManagerOrgQuotaSet)
+		if err := it.contract.UnpackLog(it.Event, it.event, log); err != nil {
+			it.fail = err
+			return false
+		}
+		it.Event.Raw = log
+		return true
+
+	case err := <-it.sub.Err():
+		it.done = true
+		it.fail = err
+		return it.Next()
+	}
+}
+
+// Error returns any retrieval or parsing error occurred during filtering.
+func (it *OrgQuotaManagerOrgQuotaSetIterator) Error() error {
+	return it.fail
+}
+
+// Close terminates the iteration process, releasing any pending underlying
+// resources.
+func (it *OrgQuotaManagerOrgQuotaSetIterator) Close() error {
+	it.sub.Unsubscribe()
+	return nil
+}
+
+// OrgQuotaManagerOrgQuotaSet represents a OrgQuotaSet event raised by the OrgQuotaManager contract.
+type OrgQuotaManagerOrgQuotaSet struct {
+	OrgId       string
+	TxPerBlock  *big.Int
+	GasPerBlock *big.Int
+	MaxPending  *big.Int
+	Raw         types.Log // Blockchain specific contextual infos
+}
+
+// FilterOrgQuotaSet is a free log retrieval operation binding the contract event 0x7aa081ce00a9f97bff2bd29d13afd05e879989b397c63b199ef5683e7e384e53.
+//
+// Solidity: e OrgQuotaSet(_orgId string, _txPerBlock uint256, _gasPerBlock uint256, _maxPending uint256)
+func (_OrgQuotaManager *OrgQuotaManagerFilterer) FilterOrgQuotaSet(opts *bind.FilterOpts) (*OrgQuotaManagerOrgQuotaSetIterator, error) {
+
+	logs, sub, err := _OrgQuotaManager.contract.FilterLogs(opts, "OrgQuotaSet")
+	if err != nil {
+		return nil, err
+	}
+	return &OrgQuotaManagerOrgQuotaSetIterator{contract: _OrgQuotaManager.contract, event: "OrgQuotaSet", logs: logs, sub: sub}, nil
+}
+
+// WatchOrgQuotaSet is a free log subscription operation binding the contract event 0x7aa081ce00a9f97bff2bd29d13afd05e879989b397c63b199ef5683e7e384e53.
+//
+// Solidity: e OrgQuotaSet(_orgId string, _txPerBlock uint256, _gasPerBlock uint256, _maxPending uint256)
+func (_OrgQuotaManager *OrgQuotaManagerFilterer) WatchOrgQuotaSet(opts *bind.WatchOpts, sink chan<- *OrgQuotaManagerOrgQuotaSet) (event.Subscription, error) {
+
+	logs, sub, err := _OrgQuotaManager.contract.WatchLogs(opts, "OrgQuotaSet")
+	if err != nil {
+		return nil, err
+	}
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		defer sub.Unsubscribe()
+		for {
+			select {
+			case log := <-logs:
+				// New log arrived, parse the event and forward to the user
+				event := new(OrgQuotaManagerOrgQuotaSet)
+				if err := _OrgQuotaManager.contract.UnpackLog(event, "OrgQuotaSet", log); err != nil {
+					return err
+				}
+				event.Raw = log
+
+				select {
+				case sink <- event:
+				case err := <-sub.Err():
+					return err
+				case <-quit:
+					return nil
+				}
+			case err := <-sub.Err():
+				return err
+			case <-quit:
+				return nil
+			}
+		}
+	}), nil
+}
@@ -0,0 +1,106 @@
+pragma solidity ^0.5.3;
+
+import "./PermissionsUpgradable.sol";
+import "./PermissionsImplementation.sol";
+
+/** @title Organization quota manager contract
+  * @notice This contract holds the block space quotas of the organizations,
+    keyed by their ultimate parent organization. The quotas are set by the
+    network admin accounts. quorum reads them at start up and follows their
+    changes to enforce them in the transaction pool and when minting blocks.
+  * @dev a quota limits the transactions per block, the gas per block and
+    the transactions in the pool of an organization, a value of zero
+    meaning no limit. A quota must set at least one limit.
+  */
+contract OrgQuotaManager {
+    PermissionsUpgradable private permUpgradable;
+    struct OrgQuota {
+        string orgId;
+        uint256 txPerBlock;
+        uint256 gasPerBlock;
+        uint256 maxPending;
+    }
+
+    OrgQuota [] private quotaList;
+    // index of the quota of an org in the list, plus one
+    mapping(bytes32 => uint256) private QuotaIndex;
+
+    // events related to the quotas of the organizations
+    event OrgQuotaSet(string _orgId, uint256 _txPerBlock, uint256 _gasPerBlock,
+        uint256 _maxPending);
+    event OrgQuotaRemoved(string _orgId);
+
+    /** @notice confirms that the caller is a network admin account
+    */
+    modifier onlyNetworkAdmin {
+        require(PermissionsImplementation(permUpgradable.getPermImpl()).isNetworkAdmin(msg.sender),
+            "account is not a network admin account");
+        _;
+    }
+
+    /** @notice constructor. sets the permissions upgradable contract address
+      * @param _permUpgradable permissions upgradable contract address
+      */
+    constructor (address _permUpgradable) public {
+        permUpgradable = PermissionsUpgradable(_permUpgradable);
+    }
+
+    /** @notice sets the quota of an organization, replacing the existing one
+      * @param _orgId ultimate parent organization id
+      * @param _txPerBlock maximum number of transactions per block
+      * @param _gasPerBlock maximum gas used per block
+      * @param _maxPending maximum number of transactions in the pool
+      */
+    function setOrgQuota(string calldata _orgId, uint256 _txPerBlock,
+        uint256 _gasPerBlock, uint256 _maxPending) external onlyNetworkAdmin {
+        require(_txPerBlock != 0 || _gasPerBlock != 0 || _maxPending != 0,
+            "quota sets no limit");
+        bytes32 key = keccak256(abi.encode(_orgId));
+        if (QuotaIndex[key] == 0) {
+            quotaList.push(OrgQuota(_orgId, _txPerBlock, _gasPerBlock, _maxPending));
+            QuotaIndex[key] = quotaList.length;
+        } else {
+            OrgQuota storage quota = quotaList[QuotaIndex[key] - 1];
+            quota.txPerBlock = _txPerBlock;
+            quota.gasPerBlock = _gasPerBlock;
+            quota.maxPending = _maxPending;
+        }
+        emit OrgQuotaSet(_orgId, _txPerBlock, _gasPerBlock, _maxPending);
+    }
+
+    /** @notice removes the quota of an organization
+      * @param _orgId ultimate parent organization id
+      */
+    function removeOrgQuota(string calldata _orgId) external onlyNetworkAdmin {
+        bytes32 key = keccak256(abi.encode(_orgId));
+        uint256 index = QuotaIndex[key];
+        require(index != 0, "org has no quota");
+        // move the last quota into the slot of the removed one
+        uint256 last = quotaList.length;
+        if (index != last) {
+            quotaList[index - 1] = quotaList[last - 1];
+            QuotaIndex[keccak256(abi.encode(quotaList[index - 1].orgId))] = index;
+        }
+        quotaList.length--;
+        delete QuotaIndex[key];
+        emit OrgQuotaRemoved(_orgId);
+    }
+
+    /** @notice returns the total number of quotas
+      * @return total number of quotas
+      */
+    function getNumberOfQuotas() external view returns (uint256) {
+        return quotaList.length;
+    }
+
+    /** @notice returns the quota at a given index
+      * @param _index index of the quota
+      * @return org id, transactions per block, gas per block and pending
+        transactions
+      */
+    function getQuotaByIndex(uint256 _index) external view returns (string memory,
+        uint256, uint256, uint256) {
+        OrgQuota storage quota = quotaList[_index];
+        return (quota.orgId, quota.txPerBlock, quota.gasPerBlock, quota.maxPending);
+    }
+}
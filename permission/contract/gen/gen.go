@@ -14,6 +14,7 @@
 //go:generate solc --abi --bin -o . --overwrite ../PermissionsUpgradable.sol
 //go:generate solc --abi --bin -o . --overwrite ../RoleManager.sol
 //go:generate solc --abi --bin -o . --overwrite ../VoterManager.sol
+//go:generate solc --abi --bin -o . --overwrite ../OrgQuotaManager.sol
 
 //go:generate abigen -pkg permission -abi  ./AccountManager.abi            -bin  ./AccountManager.bin            -type AcctManager   -out ../../bind/accounts.go
 //go:generate abigen -pkg permission -abi  ./NodeManager.abi               -bin  ./NodeManager.bin               -type NodeManager   -out ../../bind/nodes.go
@@ -23,5 +24,7 @@
 //go:generate abigen -pkg permission -abi  ./PermissionsUpgradable.abi     -bin  ./PermissionsUpgradable.bin     -type PermUpgr      -out ../../bind/permission_upgr.go
 //go:generate abigen -pkg permission -abi  ./RoleManager.abi               -bin  ./RoleManager.bin               -type RoleManager   -out ../../bind/roles.go
 //go:generate abigen -pkg permission -abi  ./VoterManager.abi              -bin  ./VoterManager.bin              -type VoterManager  -out ../../bind/voter.go
+// the quota manager is only called, not deployed by quorum
+//go:generate abigen -pkg permission -abi  ./OrgQuotaManager.abi                                                 -type OrgQuotaManager -out ../../bind/quota.go
 
 package gen
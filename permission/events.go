@@ -0,0 +1,145 @@
+package permission
+
+import (
+	"context"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Kinds of the permissioning changes.
+const (
+	PermissionKindOrg     = "org"
+	PermissionKindNode    = "node"
+	PermissionKindAccount = "account"
+	PermissionKindRole    = "role"
+)
+
+// PermissionEvent is a change of the permissions, as processed from an event
+// of the permissioning contracts. It carries the state of the org, node,
+// account or role changed after the change.
+type PermissionEvent struct {
+	Kind        string      `json:"kind"`  // Kind of the entity changed: org, node, account or role
+	Event       string      `json:"event"` // Name of the contract event, such as NodeApproved
+	OrgId       string      `json:"orgId"`
+	BlockNumber uint64      `json:"blockNumber"`
+	BlockHash   common.Hash `json:"blockHash"`
+	TxHash      common.Hash `json:"transactionHash"`
+	Removed     bool        `json:"removed"` // Whether the event was reverted by a reorg
+
+	Org     *types.OrgInfo     `json:"org,omitempty"`
+	Node    *types.NodeInfo    `json:"node,omitempty"`
+	Account *types.AccountInfo `json:"account,omitempty"`
+	Role    *types.RoleInfo    `json:"role,omitempty"`
+}
+
+// PermissionEventFilter restricts the permissioning changes notified to some
+// kinds or orgs, empty for all.
+type PermissionEventFilter struct {
+	Kinds []string `json:"kinds"`
+	Orgs  []string `json:"orgs"`
+}
+
+// matches returns whether a permissioning change passes the filter.
+func (f *PermissionEventFilter) matches(event *PermissionEvent) bool {
+	if f == nil {
+		return true
+	}
+	return filterIncludes(f.Kinds, event.Kind) && filterIncludes(f.Orgs, event.OrgId)
+}
+
+func filterIncludes(values []string, value string) bool {
+	if len(values) == 0 {
+		return true
+	}
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func newPermissionEvent(kind, name, orgId string, raw types.Log) *PermissionEvent {
+	return &PermissionEvent{
+		Kind:        kind,
+		Event:       name,
+		OrgId:       orgId,
+		BlockNumber: raw.BlockNumber,
+		BlockHash:   raw.BlockHash,
+		TxHash:      raw.TxHash,
+		Removed:     raw.Removed,
+	}
+}
+
+// postOrgEvent notifies the change of an org, once processed.
+func (p *PermissionCtrl) postOrgEvent(name, orgId string, raw types.Log) {
+	event := newPermissionEvent(PermissionKindOrg, name, orgId, raw)
+	if org := types.OrgInfoMap.GetOrg(orgId); org != nil {
+		info := *org
+		event.Org = &info
+	}
+	p.eventFeed.Send(event)
+}
+
+// postNodeEvent notifies the change of a node, once processed.
+func (p *PermissionCtrl) postNodeEvent(name, orgId, url string, raw types.Log) {
+	event := newPermissionEvent(PermissionKindNode, name, orgId, raw)
+	if node := types.NodeInfoMap.GetNodeByUrl(url); node != nil {
+		info := *node
+		event.Node = &info
+	}
+	p.eventFeed.Send(event)
+}
+
+// postAccountEvent notifies the change of an account, once processed.
+func (p *PermissionCtrl) postAccountEvent(name, orgId string, account common.Address, raw types.Log) {
+	event := newPermissionEvent(PermissionKindAccount, name, orgId, raw)
+	if acct := types.AcctInfoMap.GetAccount(account); acct != nil {
+		info := *acct
+		event.Account = &info
+	}
+	p.eventFeed.Send(event)
+}
+
+// postRoleEvent notifies the change of a role, once processed.
+func (p *PermissionCtrl) postRoleEvent(name, orgId, roleId string, raw types.Log) {
+	event := newPermissionEvent(PermissionKindRole, name, orgId, raw)
+	if role := types.RoleInfoMap.GetRole(orgId, roleId); role != nil {
+		info := *role
+		event.Role = &info
+	}
+	p.eventFeed.Send(event)
+}
+
+// PermissionChanges creates a subscription notifying the changes of the orgs,
+// nodes, accounts and roles as they are processed from the permissioning
+// contracts, for the IAM systems to mirror the permissions without polling.
+func (q *QuorumControlsAPI) PermissionChanges(ctx context.Context, filter *PermissionEventFilter) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		events := make(chan *PermissionEvent, 64)
+		sub := q.permCtrl.eventFeed.Subscribe(events)
+		defer sub.Unsubscribe()
+
+		for {
+			select {
+			case event := <-events:
+				if filter.matches(event) {
+					notifier.Notify(rpcSub.ID, event)
+				}
+			case <-rpcSub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			}
+		}
+	}()
+	return rpcSub, nil
+}
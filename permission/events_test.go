@@ -0,0 +1,46 @@
+package permission
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPermissionEventFilter_matches(t *testing.T) {
+	event := &PermissionEvent{Kind: PermissionKindNode, OrgId: "ORG1"}
+
+	var nilFilter *PermissionEventFilter
+	assert.True(t, nilFilter.matches(event))
+	assert.True(t, (&PermissionEventFilter{}).matches(event))
+	assert.True(t, (&PermissionEventFilter{Kinds: []string{PermissionKindOrg, PermissionKindNode}}).matches(event))
+	assert.False(t, (&PermissionEventFilter{Kinds: []string{PermissionKindRole}}).matches(event))
+	assert.True(t, (&PermissionEventFilter{Kinds: []string{PermissionKindNode}, Orgs: []string{"ORG1"}}).matches(event))
+	assert.False(t, (&PermissionEventFilter{Kinds: []string{PermissionKindNode}, Orgs: []string{"ORG2"}}).matches(event))
+}
+
+func TestPermissionCtrl_postOrgEvent(t *testing.T) {
+	p := &PermissionCtrl{}
+	events := make(chan *PermissionEvent, 1)
+	sub := p.eventFeed.Subscribe(events)
+	defer sub.Unsubscribe()
+
+	// Keep the org out of the cache shared with the other tests
+	orgs := types.OrgInfoMap
+	types.OrgInfoMap = types.NewOrgCache()
+	t.Cleanup(func() { types.OrgInfoMap = orgs })
+
+	types.OrgInfoMap.UpsertOrg("EVENTORG", "", "EVENTORG", nil, types.OrgApproved)
+	raw := types.Log{BlockNumber: 7, TxHash: common.HexToHash("0x01")}
+	p.postOrgEvent("OrgApproved", "EVENTORG", raw)
+
+	event := <-events
+	assert.Equal(t, PermissionKindOrg, event.Kind)
+	assert.Equal(t, "OrgApproved", event.Event)
+	assert.Equal(t, uint64(7), event.BlockNumber)
+	assert.Equal(t, raw.TxHash, event.TxHash)
+	if assert.NotNil(t, event.Org) {
+		assert.Equal(t, types.OrgApproved, event.Org.Status)
+	}
+}
@@ -1,8 +1,8 @@
 package permission
 
 import (
-	"crypto/ecdsa"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/ethereum/go-ethereum/core"
 	"io/ioutil"
@@ -21,8 +21,11 @@ import (
 	"github.com/ethereum/go-ethereum/rpc"
 
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/eth"
+	"github.com/ethereum/go-ethereum/kms"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/node"
 	"github.com/ethereum/go-ethereum/p2p"
@@ -42,7 +45,7 @@ type PermissionCtrl struct {
 	node       *node.Node
 	ethClnt    bind.ContractBackend
 	eth        *eth.Ethereum
-	key        *ecdsa.PrivateKey
+	signer     kms.Signer // Quorum: signer with the node key, possibly held in a KMS
 	dataDir    string
 	permUpgr   *pbind.PermUpgr
 	permInterf *pbind.PermInterface
@@ -50,11 +53,13 @@ type PermissionCtrl struct {
 	permAcct   *pbind.AcctManager
 	permRole   *pbind.RoleManager
 	permOrg    *pbind.OrgManager
+	permQuota  *pbind.OrgQuotaManager // Quorum: nil without org quota manager
 	permConfig *types.PermissionConfig
 
 	startWaitGroup *sync.WaitGroup // waitgroup to make sure all dependenies are ready before we start the service
 	stopFeed       event.Feed      // broadcasting stopEvent when service is being stopped
 	errorChan      chan error      // channel to capture error when starting aysnc
+	eventFeed      event.Feed      // broadcasting the permissioning changes processed
 
 	mux sync.Mutex
 }
@@ -96,7 +101,6 @@ func ParsePermissionConfig(dir string) (types.PermissionConfig, error) {
 	if permConfig.IsEmpty() {
 		return types.PermissionConfig{}, fmt.Errorf("missing contract addresses in %s", params.PERMISSION_MODEL_CONFIG)
 	}
-
 	return permConfig, nil
 }
 
@@ -111,7 +115,7 @@ func NewQuorumPermissionCtrl(stack *node.Node, pconfig *types.PermissionConfig)
 	wg.Add(1)
 	p := &PermissionCtrl{
 		node:           stack,
-		key:            stack.GetNodeKey(),
+		signer:         stack.GetNodeSigner(),
 		dataDir:        stack.DataDir(),
 		permConfig:     pconfig,
 		startWaitGroup: wg,
@@ -174,6 +178,11 @@ func (p *PermissionCtrl) AfterStart() error {
 	if err := p.bindContract(&p.permOrg, func() (interface{}, error) { return pbind.NewOrgManager(p.permConfig.OrgAddress, p.ethClnt) }); err != nil {
 		return err
 	}
+	if p.permConfig.QuotaAddress != (common.Address{}) {
+		if err := p.bindContract(&p.permQuota, func() (interface{}, error) { return pbind.NewOrgQuotaManager(p.permConfig.QuotaAddress, p.ethClnt) }); err != nil {
+			return err
+		}
+	}
 
 	// populate the initial list of permissioned nodes and account accesses
 	if err := p.populateInitPermissions(); err != nil {
@@ -189,6 +198,7 @@ func (p *PermissionCtrl) AfterStart() error {
 		p.manageNodePermissions,    // monitor org  level node management events
 		p.manageRolePermissions,    // monitor org level role management events
 		p.manageAccountPermissions, // monitor org level account management events
+		p.manageOrgQuotas,          // monitor org quota events
 	} {
 		if err := f(); err != nil {
 			return err
@@ -250,6 +260,8 @@ func (p *PermissionCtrl) asyncStart() {
 
 func (p *PermissionCtrl) Start(srvr *p2p.Server) error {
 	log.Debug("permission service: starting")
+	// Quorum: the peers of the approved organizations are permissioned
+	srvr.SetOrgPermissions(p)
 	go func() {
 		log.Debug("permission service: starting async")
 		p.asyncStart()
@@ -257,6 +269,27 @@ func (p *PermissionCtrl) Start(srvr *p2p.Server) error {
 	return nil
 }
 
+// PermissionedOrgs returns the organizations permissioned to connect, those
+// approved or pending suspension along with their ultimate parent.
+func (p *PermissionCtrl) PermissionedOrgs() []string {
+	var orgs []string
+	for _, org := range types.OrgInfoMap.GetOrgList() {
+		if !orgActive(&org) {
+			continue
+		}
+		if parent := types.OrgInfoMap.GetOrg(org.UltimateParent); parent == nil || !orgActive(parent) {
+			continue
+		}
+		orgs = append(orgs, org.FullOrgId)
+	}
+	return orgs
+}
+
+// orgActive checks if an organization is approved or pending suspension.
+func orgActive(org *types.OrgInfo) bool {
+	return org.Status == types.OrgApproved || org.Status == types.OrgPendingSuspension
+}
+
 func (p *PermissionCtrl) APIs() []rpc.API {
 	return []rpc.API{
 		{
@@ -344,15 +377,19 @@ func (p *PermissionCtrl) manageOrgPermissions() error {
 			select {
 			case evtPendingApproval := <-chPendingApproval:
 				types.OrgInfoMap.UpsertOrg(evtPendingApproval.OrgId, evtPendingApproval.PorgId, evtPendingApproval.UltParent, evtPendingApproval.Level, types.OrgStatus(evtPendingApproval.Status.Uint64()))
+				p.postOrgEvent("OrgPendingApproval", evtPendingApproval.OrgId, evtPendingApproval.Raw)
 
 			case evtOrgApproved := <-chOrgApproved:
 				types.OrgInfoMap.UpsertOrg(evtOrgApproved.OrgId, evtOrgApproved.PorgId, evtOrgApproved.UltParent, evtOrgApproved.Level, types.OrgApproved)
+				p.postOrgEvent("OrgApproved", evtOrgApproved.OrgId, evtOrgApproved.Raw)
 
 			case evtOrgSuspended := <-chOrgSuspended:
 				types.OrgInfoMap.UpsertOrg(evtOrgSuspended.OrgId, evtOrgSuspended.PorgId, evtOrgSuspended.UltParent, evtOrgSuspended.Level, types.OrgSuspended)
+				p.postOrgEvent("OrgSuspended", evtOrgSuspended.OrgId, evtOrgSuspended.Raw)
 
 			case evtOrgReactivated := <-chOrgReactivated:
 				types.OrgInfoMap.UpsertOrg(evtOrgReactivated.OrgId, evtOrgReactivated.PorgId, evtOrgReactivated.UltParent, evtOrgReactivated.Level, types.OrgApproved)
+				p.postOrgEvent("OrgSuspensionRevoked", evtOrgReactivated.OrgId, evtOrgReactivated.Raw)
 			case <-stopChan:
 				log.Info("quit org contract watch")
 				return
@@ -417,30 +454,37 @@ func (p *PermissionCtrl) manageNodePermissions() error {
 			case evtNodeApproved := <-chNodeApproved:
 				p.updatePermissionedNodes(evtNodeApproved.EnodeId, NodeAdd)
 				types.NodeInfoMap.UpsertNode(evtNodeApproved.OrgId, evtNodeApproved.EnodeId, types.NodeApproved)
+				p.postNodeEvent("NodeApproved", evtNodeApproved.OrgId, evtNodeApproved.EnodeId, evtNodeApproved.Raw)
 
 			case evtNodeProposed := <-chNodeProposed:
 				types.NodeInfoMap.UpsertNode(evtNodeProposed.OrgId, evtNodeProposed.EnodeId, types.NodePendingApproval)
+				p.postNodeEvent("NodeProposed", evtNodeProposed.OrgId, evtNodeProposed.EnodeId, evtNodeProposed.Raw)
 
 			case evtNodeDeactivated := <-chNodeDeactivated:
 				p.updatePermissionedNodes(evtNodeDeactivated.EnodeId, NodeDelete)
 				types.NodeInfoMap.UpsertNode(evtNodeDeactivated.OrgId, evtNodeDeactivated.EnodeId, types.NodeDeactivated)
+				p.postNodeEvent("NodeDeactivated", evtNodeDeactivated.OrgId, evtNodeDeactivated.EnodeId, evtNodeDeactivated.Raw)
 
 			case evtNodeActivated := <-chNodeActivated:
 				p.updatePermissionedNodes(evtNodeActivated.EnodeId, NodeAdd)
 				types.NodeInfoMap.UpsertNode(evtNodeActivated.OrgId, evtNodeActivated.EnodeId, types.NodeApproved)
+				p.postNodeEvent("NodeActivated", evtNodeActivated.OrgId, evtNodeActivated.EnodeId, evtNodeActivated.Raw)
 
 			case evtNodeBlacklisted := <-chNodeBlacklisted:
 				types.NodeInfoMap.UpsertNode(evtNodeBlacklisted.OrgId, evtNodeBlacklisted.EnodeId, types.NodeBlackListed)
 				p.updateDisallowedNodes(evtNodeBlacklisted.EnodeId, NodeAdd)
 				p.updatePermissionedNodes(evtNodeBlacklisted.EnodeId, NodeDelete)
+				p.postNodeEvent("NodeBlacklisted", evtNodeBlacklisted.OrgId, evtNodeBlacklisted.EnodeId, evtNodeBlacklisted.Raw)
 
 			case evtNodeRecoveryInit := <-chNodeRecoveryInit:
 				types.NodeInfoMap.UpsertNode(evtNodeRecoveryInit.OrgId, evtNodeRecoveryInit.EnodeId, types.NodeRecoveryInitiated)
+				p.postNodeEvent("NodeRecoveryInitiated", evtNodeRecoveryInit.OrgId, evtNodeRecoveryInit.EnodeId, evtNodeRecoveryInit.Raw)
 
 			case evtNodeRecoveryDone := <-chNodeRecoveryDone:
 				types.NodeInfoMap.UpsertNode(evtNodeRecoveryDone.OrgId, evtNodeRecoveryDone.EnodeId, types.NodeApproved)
 				p.updateDisallowedNodes(evtNodeRecoveryDone.EnodeId, NodeDelete)
 				p.updatePermissionedNodes(evtNodeRecoveryDone.EnodeId, NodeAdd)
+				p.postNodeEvent("NodeRecoveryCompleted", evtNodeRecoveryDone.OrgId, evtNodeRecoveryDone.EnodeId, evtNodeRecoveryDone.Raw)
 
 			case <-stopChan:
 				log.Info("quit node contract watch")
@@ -567,13 +611,16 @@ func (p *PermissionCtrl) manageAccountPermissions() error {
 			select {
 			case evtAccessModified := <-chAccessModified:
 				types.AcctInfoMap.UpsertAccount(evtAccessModified.OrgId, evtAccessModified.RoleId, evtAccessModified.Account, evtAccessModified.OrgAdmin, types.AcctStatus(int(evtAccessModified.Status.Uint64())))
+				p.postAccountEvent("AccountAccessModified", evtAccessModified.OrgId, evtAccessModified.Account, evtAccessModified.Raw)
 
 			case evtAccessRevoked := <-chAccessRevoked:
 				types.AcctInfoMap.UpsertAccount(evtAccessRevoked.OrgId, evtAccessRevoked.RoleId, evtAccessRevoked.Account, evtAccessRevoked.OrgAdmin, types.AcctActive)
+				p.postAccountEvent("AccountAccessRevoked", evtAccessRevoked.OrgId, evtAccessRevoked.Account, evtAccessRevoked.Raw)
 
 			case evtStatusChanged := <-chStatusChanged:
 				ac := types.AcctInfoMap.GetAccount(evtStatusChanged.Account)
 				types.AcctInfoMap.UpsertAccount(evtStatusChanged.OrgId, ac.RoleId, evtStatusChanged.Account, ac.IsOrgAdmin, types.AcctStatus(int(evtStatusChanged.Status.Uint64())))
+				p.postAccountEvent("AccountStatusChanged", evtStatusChanged.OrgId, evtStatusChanged.Account, evtStatusChanged.Raw)
 			case <-stopChan:
 				log.Info("quit account contract watch")
 				return
@@ -583,6 +630,50 @@ func (p *PermissionCtrl) manageAccountPermissions() error {
 	return nil
 }
 
+// Monitors the org quota events and updates the quotas accordingly
+func (p *PermissionCtrl) manageOrgQuotas() error {
+	if p.permQuota == nil {
+		return nil
+	}
+	chQuotaSet := make(chan *pbind.OrgQuotaManagerOrgQuotaSet, 1)
+	chQuotaRemoved := make(chan *pbind.OrgQuotaManagerOrgQuotaRemoved, 1)
+
+	opts := &bind.WatchOpts{}
+	var blockNumber uint64 = 1
+	opts.Start = &blockNumber
+
+	if _, err := p.permQuota.OrgQuotaManagerFilterer.WatchOrgQuotaSet(opts, chQuotaSet); err != nil {
+		return fmt.Errorf("failed WatchOrgQuotaSet: %v", err)
+	}
+
+	if _, err := p.permQuota.OrgQuotaManagerFilterer.WatchOrgQuotaRemoved(opts, chQuotaRemoved); err != nil {
+		return fmt.Errorf("failed WatchOrgQuotaRemoved: %v", err)
+	}
+
+	go func() {
+		stopChan, stopSubscription := p.subscribeStopEvent()
+		defer stopSubscription.Unsubscribe()
+		for {
+			select {
+			case evtQuotaSet := <-chQuotaSet:
+				if quota, ok := orgQuota(evtQuotaSet.OrgId, evtQuotaSet.TxPerBlock, evtQuotaSet.GasPerBlock, evtQuotaSet.MaxPending); ok {
+					types.SetOrgQuota(evtQuotaSet.OrgId, quota)
+				} else {
+					types.RemoveOrgQuota(evtQuotaSet.OrgId)
+				}
+
+			case evtQuotaRemoved := <-chQuotaRemoved:
+				types.RemoveOrgQuota(evtQuotaRemoved.OrgId)
+
+			case <-stopChan:
+				log.Info("quit org quota contract watch")
+				return
+			}
+		}
+	}()
+	return nil
+}
+
 // Disconnect the node from the network
 func (p *PermissionCtrl) disconnectNode(enodeId string) {
 	if p.eth.ChainConfig().Istanbul == nil && p.eth.ChainConfig().Clique == nil {
@@ -593,7 +684,9 @@ func (p *PermissionCtrl) disconnectNode(enodeId string) {
 			//get the raftId for the given enodeId
 			raftId, err := raftApi.GetRaftId(enodeId)
 			if err == nil {
-				raftApi.RemovePeer(raftId)
+				if err := raftService.RemovePeer("permission", raftId); err != nil {
+					log.Error("failed to remove raft peer", "err", err, "enodeId", enodeId)
+				}
 			} else {
 				log.Error("failed to get raft id", "err", err, "enodeId", enodeId)
 			}
@@ -613,10 +706,31 @@ func (p *PermissionCtrl) disconnectNode(enodeId string) {
 
 }
 
+// Quorum
+//
+// nodeTransactor returns the transaction signer of the node account, signing
+// with the node key wherever it is held.
+func (p *PermissionCtrl) nodeTransactor() *bind.TransactOpts {
+	from := crypto.PubkeyToAddress(*p.signer.PublicKey())
+	return &bind.TransactOpts{
+		From: from,
+		Signer: func(signer types.Signer, address common.Address, tx *types.Transaction) (*types.Transaction, error) {
+			if address != from {
+				return nil, errors.New("not authorized to sign this account")
+			}
+			signature, err := p.signer.Sign(signer.Hash(tx).Bytes())
+			if err != nil {
+				return nil, err
+			}
+			return tx.WithSignature(signer, signature)
+		},
+	}
+}
+
 // Thus function checks if the initial network boot up status and if no
 // populates permissions model with details from permission-config.json
 func (p *PermissionCtrl) populateInitPermissions() error {
-	auth := bind.NewKeyedTransactor(p.key)
+	auth := p.nodeTransactor()
 	permInterfSession := &pbind.PermInterfaceSession{
 		Contract: p.permInterf,
 		CallOpts: bind.CallOpts{
@@ -648,6 +762,7 @@ func (p *PermissionCtrl) populateInitPermissions() error {
 			p.populateNodesFromContract,
 			p.populateRolesFromContract,
 			p.populateAccountsFromContract,
+			p.populateQuotasFromContract,
 		} {
 			if err := f(auth); err != nil {
 				return err
@@ -778,6 +893,50 @@ func (p *PermissionCtrl) populateOrgsFromContract(auth *bind.TransactOpts) error
 	return nil
 }
 
+// populates the block space quotas of the organizations from the contract
+func (p *PermissionCtrl) populateQuotasFromContract(auth *bind.TransactOpts) error {
+	if p.permQuota == nil {
+		return nil
+	}
+	permQuotaSession := &pbind.OrgQuotaManagerSession{
+		Contract: p.permQuota,
+		CallOpts: bind.CallOpts{
+			Pending: true,
+		},
+	}
+	numberOfQuotas, err := permQuotaSession.GetNumberOfQuotas()
+	if err != nil {
+		return err
+	}
+	quotas := make(map[string]types.OrgQuota)
+	for k := uint64(0); k < numberOfQuotas.Uint64(); k++ {
+		if orgId, txPerBlock, gasPerBlock, maxPending, err := permQuotaSession.GetQuotaByIndex(big.NewInt(int64(k))); err == nil {
+			if quota, ok := orgQuota(orgId, txPerBlock, gasPerBlock, maxPending); ok {
+				quotas[orgId] = quota
+			}
+		}
+	}
+	types.SetOrgQuotas(quotas)
+	return nil
+}
+
+// orgQuota converts a quota read from the contract, which is ignored if it
+// can't be enforced.
+func orgQuota(orgId string, txPerBlock, gasPerBlock, maxPending *big.Int) (types.OrgQuota, bool) {
+	for _, limit := range []*big.Int{txPerBlock, gasPerBlock, maxPending} {
+		if !limit.IsUint64() {
+			log.Warn("Ignoring out of range org quota", "org", orgId, "limit", limit)
+			return types.OrgQuota{}, false
+		}
+	}
+	quota := types.OrgQuota{TxPerBlock: txPerBlock.Uint64(), GasPerBlock: gasPerBlock.Uint64(), MaxPending: maxPending.Uint64()}
+	if err := quota.Validate(); err != nil {
+		log.Warn("Ignoring invalid org quota", "org", orgId, "err", err)
+		return types.OrgQuota{}, false
+	}
+	return quota, true
+}
+
 // Reads the node list from static-nodes.json and populates into the contract
 func (p *PermissionCtrl) populateStaticNodesToContract(permissionsSession *pbind.PermInterfaceSession) error {
 	nodes := p.node.Server().Config.StaticNodes
@@ -840,10 +999,12 @@ func (p *PermissionCtrl) manageRolePermissions() error {
 			select {
 			case evtRoleCreated := <-chRoleCreated:
 				types.RoleInfoMap.UpsertRole(evtRoleCreated.OrgId, evtRoleCreated.RoleId, evtRoleCreated.IsVoter, evtRoleCreated.IsAdmin, types.AccessType(int(evtRoleCreated.BaseAccess.Uint64())), true)
+				p.postRoleEvent("RoleCreated", evtRoleCreated.OrgId, evtRoleCreated.RoleId, evtRoleCreated.Raw)
 
 			case evtRoleRevoked := <-chRoleRevoked:
 				if r := types.RoleInfoMap.GetRole(evtRoleRevoked.OrgId, evtRoleRevoked.RoleId); r != nil {
 					types.RoleInfoMap.UpsertRole(evtRoleRevoked.OrgId, evtRoleRevoked.RoleId, r.IsVoter, r.IsAdmin, r.Access, false)
+					p.postRoleEvent("RoleRevoked", evtRoleRevoked.OrgId, evtRoleRevoked.RoleId, evtRoleRevoked.Raw)
 				} else {
 					log.Error("Revoke role - cache is missing role", "org", evtRoleRevoked.OrgId, "role", evtRoleRevoked.RoleId)
 				}
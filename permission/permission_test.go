@@ -9,6 +9,7 @@ import (
 	"log"
 	"math/big"
 	"os"
+	"sort"
 	"testing"
 
 	"github.com/ethereum/go-ethereum/accounts"
@@ -277,50 +278,50 @@ func TestQuorumControlsAPI_OrgAPIs(t *testing.T) {
 	orgAdminKey, _ := crypto.GenerateKey()
 	orgAdminAddress := crypto.PubkeyToAddress(orgAdminKey.PublicKey)
 
-	_, err := testObject.AddOrg(arbitraryOrgToAdd, arbitraryNode1, orgAdminAddress, invalidTxa)
+	_, err := testObject.AddOrg(arbitraryOrgToAdd, arbitraryNode1, orgAdminAddress, invalidTxa, nil)
 	assert.Equal(t, err, errors.New("Invalid account id"))
 
-	_, err = testObject.AddOrg(arbitraryOrgToAdd, arbitraryNode1, orgAdminAddress, txa)
+	_, err = testObject.AddOrg(arbitraryOrgToAdd, arbitraryNode1, orgAdminAddress, txa, nil)
 	assert.NoError(t, err)
 
-	_, err = testObject.AddOrg(arbitraryOrgToAdd, arbitraryNode1, orgAdminAddress, txa)
+	_, err = testObject.AddOrg(arbitraryOrgToAdd, arbitraryNode1, orgAdminAddress, txa, nil)
 	assert.Equal(t, err, ErrPendingApproval)
 
-	_, err = testObject.ApproveOrg(arbitraryOrgToAdd, arbitraryNode1, orgAdminAddress, invalidTxa)
+	_, err = testObject.ApproveOrg(arbitraryOrgToAdd, arbitraryNode1, orgAdminAddress, invalidTxa, nil)
 	assert.Equal(t, err, errors.New("Invalid account id"))
 
-	_, err = testObject.ApproveOrg("XYZ", arbitraryNode1, orgAdminAddress, txa)
+	_, err = testObject.ApproveOrg("XYZ", arbitraryNode1, orgAdminAddress, txa, nil)
 	assert.Equal(t, err, errors.New("Nothing to approve"))
 
-	_, err = testObject.ApproveOrg(arbitraryOrgToAdd, arbitraryNode1, orgAdminAddress, txa)
+	_, err = testObject.ApproveOrg(arbitraryOrgToAdd, arbitraryNode1, orgAdminAddress, txa, nil)
 	assert.NoError(t, err)
 
 	types.OrgInfoMap.UpsertOrg(arbitraryOrgToAdd, "", arbitraryOrgToAdd, big.NewInt(1), types.OrgApproved)
-	_, err = testObject.UpdateOrgStatus(arbitraryOrgToAdd, uint8(SuspendOrg), invalidTxa)
+	_, err = testObject.UpdateOrgStatus(arbitraryOrgToAdd, uint8(SuspendOrg), invalidTxa, nil)
 	assert.Equal(t, err, errors.New("Invalid account id"))
 
-	_, err = testObject.UpdateOrgStatus(arbitraryOrgToAdd, uint8(SuspendOrg), txa)
+	_, err = testObject.UpdateOrgStatus(arbitraryOrgToAdd, uint8(SuspendOrg), txa, nil)
 	assert.NoError(t, err)
 
 	types.OrgInfoMap.UpsertOrg(arbitraryOrgToAdd, "", arbitraryOrgToAdd, big.NewInt(1), types.OrgSuspended)
-	_, err = testObject.ApproveOrgStatus(arbitraryOrgToAdd, uint8(SuspendOrg), invalidTxa)
+	_, err = testObject.ApproveOrgStatus(arbitraryOrgToAdd, uint8(SuspendOrg), invalidTxa, nil)
 	assert.Equal(t, err, errors.New("Invalid account id"))
 
-	_, err = testObject.ApproveOrgStatus(arbitraryOrgToAdd, uint8(SuspendOrg), txa)
+	_, err = testObject.ApproveOrgStatus(arbitraryOrgToAdd, uint8(SuspendOrg), txa, nil)
 	assert.NoError(t, err)
 
-	_, err = testObject.AddSubOrg(arbitraryNetworkAdminOrg, arbitrarySubOrg, "", invalidTxa)
+	_, err = testObject.AddSubOrg(arbitraryNetworkAdminOrg, arbitrarySubOrg, "", invalidTxa, nil)
 	assert.Equal(t, err, errors.New("Invalid account id"))
 
-	_, err = testObject.AddSubOrg(arbitraryNetworkAdminOrg, arbitrarySubOrg, "", txa)
+	_, err = testObject.AddSubOrg(arbitraryNetworkAdminOrg, arbitrarySubOrg, "", txa, nil)
 	assert.NoError(t, err)
 	types.OrgInfoMap.UpsertOrg(arbitrarySubOrg, arbitraryNetworkAdminOrg, arbitraryNetworkAdminOrg, big.NewInt(2), types.OrgApproved)
 
 	suborg := "ABC.12345"
-	_, err = testObject.AddSubOrg(arbitraryNetworkAdminOrg, suborg, "", txa)
+	_, err = testObject.AddSubOrg(arbitraryNetworkAdminOrg, suborg, "", txa, nil)
 	assert.Equal(t, err, errors.New("Org id cannot contain special characters"))
 
-	_, err = testObject.AddSubOrg(arbitraryNetworkAdminOrg, "", "", txa)
+	_, err = testObject.AddSubOrg(arbitraryNetworkAdminOrg, "", "", txa, nil)
 	assert.Equal(t, err, errors.New("Invalid input"))
 
 	_, err = testObject.GetOrgDetails(arbitraryOrgToAdd)
@@ -333,42 +334,42 @@ func TestQuorumControlsAPI_NodeAPIs(t *testing.T) {
 	invalidTxa := ethapi.SendTxArgs{From: getArbitraryAccount()}
 	txa := ethapi.SendTxArgs{From: guardianAddress}
 
-	_, err := testObject.AddNode(arbitraryNetworkAdminOrg, arbitraryNode2, invalidTxa)
+	_, err := testObject.AddNode(arbitraryNetworkAdminOrg, arbitraryNode2, invalidTxa, nil)
 	assert.Equal(t, err, errors.New("Invalid account id"))
 
-	_, err = testObject.AddNode(arbitraryNetworkAdminOrg, arbitraryNode2, txa)
+	_, err = testObject.AddNode(arbitraryNetworkAdminOrg, arbitraryNode2, txa, nil)
 	assert.NoError(t, err)
 	types.NodeInfoMap.UpsertNode(arbitraryNetworkAdminOrg, arbitraryNode2, types.NodeApproved)
 
-	_, err = testObject.UpdateNodeStatus(arbitraryNetworkAdminOrg, arbitraryNode2, uint8(SuspendNode), invalidTxa)
+	_, err = testObject.UpdateNodeStatus(arbitraryNetworkAdminOrg, arbitraryNode2, uint8(SuspendNode), invalidTxa, nil)
 	assert.Equal(t, err, errors.New("Invalid account id"))
 
-	_, err = testObject.UpdateNodeStatus(arbitraryNetworkAdminOrg, arbitraryNode2, uint8(SuspendNode), txa)
+	_, err = testObject.UpdateNodeStatus(arbitraryNetworkAdminOrg, arbitraryNode2, uint8(SuspendNode), txa, nil)
 	assert.NoError(t, err)
 	types.NodeInfoMap.UpsertNode(arbitraryNetworkAdminOrg, arbitraryNode2, types.NodeDeactivated)
 
-	_, err = testObject.UpdateNodeStatus(arbitraryNetworkAdminOrg, arbitraryNode2, uint8(ActivateSuspendedNode), txa)
+	_, err = testObject.UpdateNodeStatus(arbitraryNetworkAdminOrg, arbitraryNode2, uint8(ActivateSuspendedNode), txa, nil)
 	assert.NoError(t, err)
 	types.NodeInfoMap.UpsertNode(arbitraryNetworkAdminOrg, arbitraryNode2, types.NodeApproved)
 
-	_, err = testObject.UpdateNodeStatus(arbitraryNetworkAdminOrg, arbitraryNode2, uint8(BlacklistNode), txa)
+	_, err = testObject.UpdateNodeStatus(arbitraryNetworkAdminOrg, arbitraryNode2, uint8(BlacklistNode), txa, nil)
 	assert.NoError(t, err)
 	types.NodeInfoMap.UpsertNode(arbitraryNetworkAdminOrg, arbitraryNode2, types.NodeBlackListed)
 
-	_, err = testObject.UpdateNodeStatus(arbitraryNetworkAdminOrg, arbitraryNode2, uint8(ActivateSuspendedNode), txa)
+	_, err = testObject.UpdateNodeStatus(arbitraryNetworkAdminOrg, arbitraryNode2, uint8(ActivateSuspendedNode), txa, nil)
 	assert.Equal(t, err, ErrNodeBlacklisted)
 
-	_, err = testObject.RecoverBlackListedNode(arbitraryNetworkAdminOrg, arbitraryNode2, invalidTxa)
+	_, err = testObject.RecoverBlackListedNode(arbitraryNetworkAdminOrg, arbitraryNode2, invalidTxa, nil)
 	assert.Equal(t, err, errors.New("Invalid account id"))
 
-	_, err = testObject.RecoverBlackListedNode(arbitraryNetworkAdminOrg, arbitraryNode2, txa)
+	_, err = testObject.RecoverBlackListedNode(arbitraryNetworkAdminOrg, arbitraryNode2, txa, nil)
 	assert.NoError(t, err)
 	types.NodeInfoMap.UpsertNode(arbitraryNetworkAdminOrg, arbitraryNode2, types.NodeRecoveryInitiated)
 
-	_, err = testObject.ApproveBlackListedNodeRecovery(arbitraryNetworkAdminOrg, arbitraryNode2, invalidTxa)
+	_, err = testObject.ApproveBlackListedNodeRecovery(arbitraryNetworkAdminOrg, arbitraryNode2, invalidTxa, nil)
 	assert.Equal(t, err, errors.New("Invalid account id"))
 
-	_, err = testObject.ApproveBlackListedNodeRecovery(arbitraryNetworkAdminOrg, arbitraryNode2, txa)
+	_, err = testObject.ApproveBlackListedNodeRecovery(arbitraryNetworkAdminOrg, arbitraryNode2, txa, nil)
 	assert.NoError(t, err)
 	types.NodeInfoMap.UpsertNode(arbitraryNetworkAdminOrg, arbitraryNode2, types.NodeApproved)
 }
@@ -379,81 +380,81 @@ func TestQuorumControlsAPI_RoleAndAccountsAPIs(t *testing.T) {
 	txa := ethapi.SendTxArgs{From: guardianAddress}
 	acct := getArbitraryAccount()
 
-	_, err := testObject.AssignAdminRole(arbitraryNetworkAdminOrg, acct, arbitraryNetworkAdminRole, invalidTxa)
+	_, err := testObject.AssignAdminRole(arbitraryNetworkAdminOrg, acct, arbitraryNetworkAdminRole, invalidTxa, nil)
 	assert.Equal(t, err, errors.New("Invalid account id"))
 
-	_, err = testObject.AssignAdminRole(arbitraryNetworkAdminOrg, acct, arbitraryNetworkAdminRole, txa)
+	_, err = testObject.AssignAdminRole(arbitraryNetworkAdminOrg, acct, arbitraryNetworkAdminRole, txa, nil)
 	types.AcctInfoMap.UpsertAccount(arbitraryNetworkAdminOrg, arbitraryNetworkAdminRole, acct, true, types.AcctPendingApproval)
 
-	_, err = testObject.ApproveAdminRole(arbitraryNetworkAdminOrg, acct, invalidTxa)
+	_, err = testObject.ApproveAdminRole(arbitraryNetworkAdminOrg, acct, invalidTxa, nil)
 	assert.Equal(t, err, errors.New("Invalid account id"))
 
-	_, err = testObject.ApproveAdminRole(arbitraryNetworkAdminOrg, acct, invalidTxa)
+	_, err = testObject.ApproveAdminRole(arbitraryNetworkAdminOrg, acct, invalidTxa, nil)
 	assert.Equal(t, err, errors.New("Invalid account id"))
 
-	_, err = testObject.ApproveAdminRole(arbitraryNetworkAdminOrg, acct, txa)
+	_, err = testObject.ApproveAdminRole(arbitraryNetworkAdminOrg, acct, txa, nil)
 	assert.NoError(t, err)
 	types.AcctInfoMap.UpsertAccount(arbitraryNetworkAdminOrg, arbitraryNetworkAdminRole, acct, true, types.AcctActive)
 
-	_, err = testObject.AddNewRole(arbitraryNetworkAdminOrg, arbitrartNewRole1, uint8(types.FullAccess), false, false, invalidTxa)
+	_, err = testObject.AddNewRole(arbitraryNetworkAdminOrg, arbitrartNewRole1, uint8(types.FullAccess), false, false, invalidTxa, nil)
 	assert.Equal(t, err, errors.New("Invalid account id"))
 
-	_, err = testObject.AddNewRole(arbitraryNetworkAdminOrg, arbitrartNewRole1, uint8(types.FullAccess), false, false, txa)
+	_, err = testObject.AddNewRole(arbitraryNetworkAdminOrg, arbitrartNewRole1, uint8(types.FullAccess), false, false, txa, nil)
 	assert.NoError(t, err)
 	types.RoleInfoMap.UpsertRole(arbitraryNetworkAdminOrg, arbitrartNewRole1, false, false, types.FullAccess, true)
 
 	acct = getArbitraryAccount()
-	_, err = testObject.AddAccountToOrg(acct, arbitraryNetworkAdminOrg, arbitrartNewRole1, invalidTxa)
+	_, err = testObject.AddAccountToOrg(acct, arbitraryNetworkAdminOrg, arbitrartNewRole1, invalidTxa, nil)
 	assert.Equal(t, err, errors.New("Invalid account id"))
 
-	_, err = testObject.AddAccountToOrg(acct, arbitraryNetworkAdminOrg, arbitrartNewRole1, txa)
+	_, err = testObject.AddAccountToOrg(acct, arbitraryNetworkAdminOrg, arbitrartNewRole1, txa, nil)
 	assert.NoError(t, err)
 	types.AcctInfoMap.UpsertAccount(arbitraryNetworkAdminOrg, arbitrartNewRole1, acct, true, types.AcctActive)
 
-	_, err = testObject.RemoveRole(arbitraryNetworkAdminOrg, arbitrartNewRole1, invalidTxa)
+	_, err = testObject.RemoveRole(arbitraryNetworkAdminOrg, arbitrartNewRole1, invalidTxa, nil)
 	assert.Equal(t, err, errors.New("Invalid account id"))
 
-	_, err = testObject.RemoveRole(arbitraryNetworkAdminOrg, arbitrartNewRole1, txa)
+	_, err = testObject.RemoveRole(arbitraryNetworkAdminOrg, arbitrartNewRole1, txa, nil)
 	assert.Equal(t, err, ErrAccountsLinked)
 
-	_, err = testObject.AddNewRole(arbitraryNetworkAdminOrg, arbitrartNewRole2, uint8(types.FullAccess), false, false, txa)
+	_, err = testObject.AddNewRole(arbitraryNetworkAdminOrg, arbitrartNewRole2, uint8(types.FullAccess), false, false, txa, nil)
 	assert.NoError(t, err)
 	types.RoleInfoMap.UpsertRole(arbitraryNetworkAdminOrg, arbitrartNewRole2, false, false, types.FullAccess, true)
 
-	_, err = testObject.ChangeAccountRole(acct, arbitraryNetworkAdminOrg, arbitrartNewRole2, invalidTxa)
+	_, err = testObject.ChangeAccountRole(acct, arbitraryNetworkAdminOrg, arbitrartNewRole2, invalidTxa, nil)
 	assert.Equal(t, err, errors.New("Invalid account id"))
 
-	_, err = testObject.ChangeAccountRole(acct, arbitraryNetworkAdminOrg, arbitrartNewRole2, txa)
+	_, err = testObject.ChangeAccountRole(acct, arbitraryNetworkAdminOrg, arbitrartNewRole2, txa, nil)
 	assert.NoError(t, err)
 
-	_, err = testObject.RemoveRole(arbitraryNetworkAdminOrg, arbitrartNewRole1, txa)
+	_, err = testObject.RemoveRole(arbitraryNetworkAdminOrg, arbitrartNewRole1, txa, nil)
 	assert.Equal(t, err, ErrAccountsLinked)
 
-	_, err = testObject.UpdateAccountStatus(arbitraryNetworkAdminOrg, acct, uint8(SuspendAccount), invalidTxa)
+	_, err = testObject.UpdateAccountStatus(arbitraryNetworkAdminOrg, acct, uint8(SuspendAccount), invalidTxa, nil)
 	assert.Equal(t, err, errors.New("Invalid account id"))
 
-	_, err = testObject.UpdateAccountStatus(arbitraryNetworkAdminOrg, acct, uint8(SuspendAccount), txa)
+	_, err = testObject.UpdateAccountStatus(arbitraryNetworkAdminOrg, acct, uint8(SuspendAccount), txa, nil)
 	assert.NoError(t, err)
 	types.AcctInfoMap.UpsertAccount(arbitraryNetworkAdminOrg, arbitrartNewRole2, acct, true, types.AcctSuspended)
 
-	_, err = testObject.UpdateAccountStatus(arbitraryNetworkAdminOrg, acct, uint8(ActivateSuspendedAccount), txa)
+	_, err = testObject.UpdateAccountStatus(arbitraryNetworkAdminOrg, acct, uint8(ActivateSuspendedAccount), txa, nil)
 	assert.NoError(t, err)
 	types.AcctInfoMap.UpsertAccount(arbitraryNetworkAdminOrg, arbitrartNewRole2, acct, true, types.AcctActive)
 
-	_, err = testObject.UpdateAccountStatus(arbitraryNetworkAdminOrg, acct, uint8(BlacklistAccount), txa)
+	_, err = testObject.UpdateAccountStatus(arbitraryNetworkAdminOrg, acct, uint8(BlacklistAccount), txa, nil)
 	assert.NoError(t, err)
 	types.AcctInfoMap.UpsertAccount(arbitraryNetworkAdminOrg, arbitrartNewRole2, acct, true, types.AcctBlacklisted)
 
-	_, err = testObject.UpdateAccountStatus(arbitraryNetworkAdminOrg, acct, uint8(ActivateSuspendedAccount), txa)
+	_, err = testObject.UpdateAccountStatus(arbitraryNetworkAdminOrg, acct, uint8(ActivateSuspendedAccount), txa, nil)
 	assert.Equal(t, err, ErrAcctBlacklisted)
 
-	_, err = testObject.RecoverBlackListedAccount(arbitraryNetworkAdminOrg, acct, invalidTxa)
+	_, err = testObject.RecoverBlackListedAccount(arbitraryNetworkAdminOrg, acct, invalidTxa, nil)
 	assert.Equal(t, err, errors.New("Invalid account id"))
 
-	_, err = testObject.RecoverBlackListedAccount(arbitraryNetworkAdminOrg, acct, txa)
+	_, err = testObject.RecoverBlackListedAccount(arbitraryNetworkAdminOrg, acct, txa, nil)
 	assert.NoError(t, err)
 	types.AcctInfoMap.UpsertAccount(arbitraryNetworkAdminOrg, arbitrartNewRole2, acct, true, types.AcctRecoveryInitiated)
-	_, err = testObject.ApproveBlackListedAccountRecovery(arbitraryNetworkAdminOrg, acct, txa)
+	_, err = testObject.ApproveBlackListedAccountRecovery(arbitraryNetworkAdminOrg, acct, txa, nil)
 	assert.NoError(t, err)
 	types.AcctInfoMap.UpsertAccount(arbitraryNetworkAdminOrg, arbitrartNewRole2, acct, true, types.AcctActive)
 
@@ -621,3 +622,32 @@ func TestParsePermissionConfig(t *testing.T) {
 	permConfig, err := ParsePermissionConfig(d)
 	assert.False(t, permConfig.IsEmpty(), "expected non empty object")
 }
+
+func TestPermissionCtrl_PermissionedOrgs(t *testing.T) {
+	defer func(orgs *types.OrgCache) { types.OrgInfoMap = orgs }(types.OrgInfoMap)
+	types.OrgInfoMap = types.NewOrgCache()
+
+	types.OrgInfoMap.UpsertOrg("ACME", "", "ACME", big.NewInt(1), types.OrgApproved)
+	types.OrgInfoMap.UpsertOrg("SALES", "ACME", "ACME", big.NewInt(2), types.OrgApproved)
+	types.OrgInfoMap.UpsertOrg("PENDING", "", "PENDING", big.NewInt(1), types.OrgPendingApproval)
+	types.OrgInfoMap.UpsertOrg("SUSPENDED", "", "SUSPENDED", big.NewInt(1), types.OrgSuspended)
+	types.OrgInfoMap.UpsertOrg("UNITS", "SUSPENDED", "SUSPENDED", big.NewInt(2), types.OrgApproved)
+
+	orgs := new(PermissionCtrl).PermissionedOrgs()
+	sort.Strings(orgs)
+	assert.Equal(t, []string{"ACME", "ACME.SALES"}, orgs)
+}
+
+func TestOrgQuota(t *testing.T) {
+	quota, ok := orgQuota("ACME", big.NewInt(10), big.NewInt(1000000), big.NewInt(0))
+	assert.True(t, ok)
+	assert.Equal(t, types.OrgQuota{TxPerBlock: 10, GasPerBlock: 1000000}, quota)
+
+	// The quotas that can't be enforced are ignored
+	_, ok = orgQuota("ACME", big.NewInt(0), big.NewInt(0), big.NewInt(0))
+	assert.False(t, ok, "expected quota without limit to be ignored")
+	_, ok = orgQuota("ACME", big.NewInt(0), big.NewInt(20000), big.NewInt(0))
+	assert.False(t, ok, "expected gas quota below a transfer to be ignored")
+	_, ok = orgQuota("ACME", new(big.Int).Lsh(big.NewInt(1), 64), big.NewInt(0), big.NewInt(0))
+	assert.False(t, ok, "expected out of range quota to be ignored")
+}
@@ -0,0 +1,84 @@
+package permsync
+
+import (
+	"github.com/ethereum/go-ethereum/adminauth"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+)
+
+// PrivatePermSyncAPI offers the operators to add or remove permissioned nodes
+// across the network, and to reconcile the deltas with the peers.
+type PrivatePermSyncAPI struct {
+	syncer *Syncer
+}
+
+// NewPrivatePermSyncAPI creates the API of a syncer.
+func NewPrivatePermSyncAPI(syncer *Syncer) *PrivatePermSyncAPI {
+	return &PrivatePermSyncAPI{syncer: syncer}
+}
+
+// SyncStatus is the digest of the deltas known to the node.
+type SyncStatus struct {
+	Deltas hexutil.Uint64 `json:"deltas"`
+	Digest common.Hash    `json:"digest"`
+	Peers  int            `json:"peers"`
+}
+
+// AddNode permissions a node on every node of the network, returning the hash
+// of the delta gossiped.
+func (api *PrivatePermSyncAPI) AddNode(url string, sig *adminauth.Signature) (common.Hash, error) {
+	return api.submit(url, false, sig)
+}
+
+// RemoveNode removes a node from the permissioned nodes of every node of the
+// network, returning the hash of the delta gossiped.
+func (api *PrivatePermSyncAPI) RemoveNode(url string, sig *adminauth.Signature) (common.Hash, error) {
+	return api.submit(url, true, sig)
+}
+
+// submit applies the delta signed by an operator and relays it to the peers.
+// The signature must be fresh and not replayed, as for any call of an operator.
+func (api *PrivatePermSyncAPI) submit(url string, remove bool, sig *adminauth.Signature) (common.Hash, error) {
+	if _, err := enode.ParseV4(url); err != nil {
+		return common.Hash{}, err
+	}
+	delta := &Delta{Node: url, Remove: remove}
+	if err := api.syncer.auth.Verify(delta.method(), sig, url); err != nil {
+		return common.Hash{}, err
+	}
+	delta.Timestamp, delta.Signature = sig.Timestamp, sig.Signature
+
+	fresh, err := api.syncer.Apply(delta)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if fresh {
+		api.syncer.broadcast([]*Delta{delta}, nil)
+	}
+	return delta.Hash(), nil
+}
+
+// Deltas returns the deltas known to the node, by signing time.
+func (api *PrivatePermSyncAPI) Deltas() []*Delta {
+	api.syncer.lock.Lock()
+	defer api.syncer.lock.Unlock()
+
+	return api.syncer.sorted()
+}
+
+// Status returns the digest of the deltas known to the node, equal on the nodes
+// knowing the same deltas.
+func (api *PrivatePermSyncAPI) Status() *SyncStatus {
+	api.syncer.lock.Lock()
+	defer api.syncer.lock.Unlock()
+
+	count, digest := api.syncer.digest()
+	return &SyncStatus{Deltas: hexutil.Uint64(count), Digest: digest, Peers: len(api.syncer.peers)}
+}
+
+// Reconcile requests all the deltas known to the peers, applying those missed,
+// and returns the number of peers requested.
+func (api *PrivatePermSyncAPI) Reconcile() int {
+	return api.syncer.reconcile()
+}
@@ -0,0 +1,282 @@
+// Package permsync gossips the changes of the permissioned nodes across the
+// network.
+//
+// An operator signs the addition or removal of a node as a delta, which the
+// node it is submitted to applies to its permissioned-nodes.json and relays to
+// its peers, each applying it in turn once they checked the signature against
+// their own operator keys. The latest delta of a node, by signing time, wins,
+// so that the nodes converge whatever the order they receive the deltas in.
+// The nodes compare the digests of their deltas on connection, and exchange
+// them in full when they differ, so that a node missing some deltas catches up
+// without the updated files distributed out of band.
+package permsync
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/adminauth"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// The operators sign the deltas as they sign the calls of the same name.
+const (
+	addNodeMethod    = "permsync_addNode"
+	removeNodeMethod = "permsync_removeNode"
+)
+
+var errOperatorKeysRequired = errors.New("permissioned nodes sync requires operator keys")
+
+// Delta is the addition or removal of a permissioned node, signed by an
+// operator.
+type Delta struct {
+	Node      string         `json:"node"`      // Enode URL of the node
+	Remove    bool           `json:"remove"`    // Whether the node is removed, rather than added
+	Timestamp hexutil.Uint64 `json:"timestamp"` // Unix time the delta was signed at, ordering the deltas of a node
+	Signature hexutil.Bytes  `json:"signature"` // Operator signature, as for the permsync_addNode or permsync_removeNode call
+}
+
+// Hash returns the hash identifying the delta.
+func (d *Delta) Hash() common.Hash {
+	blob, _ := rlp.EncodeToBytes(d)
+	return crypto.Keccak256Hash(blob)
+}
+
+func (d *Delta) method() string {
+	if d.Remove {
+		return removeNodeMethod
+	}
+	return addNodeMethod
+}
+
+// newer reports whether the delta supersedes another delta of the same node,
+// signed earlier, or at the same time with a greater hash.
+func (d *Delta) newer(other *Delta) bool {
+	if d.Timestamp != other.Timestamp {
+		return d.Timestamp > other.Timestamp
+	}
+	hash, otherHash := d.Hash(), other.Hash()
+	return bytes.Compare(hash[:], otherHash[:]) > 0
+}
+
+// Syncer applies the deltas of the permissioned nodes and exchanges them with
+// the peers.
+type Syncer struct {
+	dataDir string
+	auth    *adminauth.Authenticator
+	server  *p2p.Server
+
+	lock   sync.Mutex
+	deltas map[common.Hash]*Delta // All the deltas known, by hash
+	latest map[enode.ID]*Delta    // Latest delta of each node, the one applied
+	peers  map[*peer]struct{}
+}
+
+// New creates a syncer of the permissioned nodes of the given data directory,
+// accepting the deltas signed by the configured operators, and loads the
+// deltas known.
+func New(dataDir string, auth *adminauth.Authenticator) (*Syncer, error) {
+	if !auth.Enabled() {
+		return nil, errOperatorKeysRequired
+	}
+	s := &Syncer{
+		dataDir: dataDir,
+		auth:    auth,
+		deltas:  make(map[common.Hash]*Delta),
+		latest:  make(map[enode.ID]*Delta),
+		peers:   make(map[*peer]struct{}),
+	}
+	blob, err := ioutil.ReadFile(filepath.Join(dataDir, params.PERMISSIONED_DELTAS))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if len(blob) > 0 {
+		var deltas []*Delta
+		if err := json.Unmarshal(blob, &deltas); err != nil {
+			return nil, fmt.Errorf("invalid %s: %v", params.PERMISSIONED_DELTAS, err)
+		}
+		for _, delta := range deltas {
+			id, err := s.verify(delta)
+			if err != nil {
+				log.Warn("Dropping invalid permissioned node delta", "node", delta.Node, "err", err)
+				continue
+			}
+			s.add(id, delta)
+		}
+	}
+	return s, nil
+}
+
+// verify checks the delta is signed by an operator, returning the ID of its
+// node.
+func (s *Syncer) verify(delta *Delta) (enode.ID, error) {
+	node, err := enode.ParseV4(delta.Node)
+	if err != nil {
+		return enode.ID{}, err
+	}
+	if _, err := s.auth.Signer(delta.method(), &adminauth.Signature{Timestamp: delta.Timestamp, Signature: delta.Signature}, delta.Node); err != nil {
+		return enode.ID{}, err
+	}
+	return node.ID(), nil
+}
+
+// add records a verified delta, reporting whether it is the latest of its node.
+// The caller must hold the lock.
+func (s *Syncer) add(id enode.ID, delta *Delta) bool {
+	s.deltas[delta.Hash()] = delta
+	if latest, ok := s.latest[id]; ok && !delta.newer(latest) {
+		return false
+	}
+	s.latest[id] = delta
+	return true
+}
+
+// verifiedDelta is a delta along with the ID of its node, once verified.
+type verifiedDelta struct {
+	id    enode.ID
+	delta *Delta
+}
+
+// Apply verifies a delta and, unless known already, records it and updates the
+// permissioned nodes if it is the latest of its node. It reports whether the
+// delta was new, to be relayed.
+func (s *Syncer) Apply(delta *Delta) (bool, error) {
+	id, err := s.verify(delta)
+	if err != nil {
+		return false, err
+	}
+	fresh, err := s.record([]verifiedDelta{{id, delta}})
+	return len(fresh) > 0, err
+}
+
+// record records the verified deltas not known already, updating the
+// permissioned nodes with the latest deltas of their nodes. The deltas and the
+// permissioned nodes are persisted once for the whole batch. It returns the new
+// deltas, to be relayed.
+func (s *Syncer) record(deltas []verifiedDelta) ([]*Delta, error) {
+	var (
+		fresh  []*Delta
+		latest = make(map[enode.ID]*Delta)
+	)
+	s.lock.Lock()
+	for _, v := range deltas {
+		if _, ok := s.deltas[v.delta.Hash()]; ok {
+			continue
+		}
+		if s.add(v.id, v.delta) {
+			latest[v.id] = v.delta
+		} else {
+			log.Debug("Recorded superseded permissioned node delta", "node", v.delta.Node, "remove", v.delta.Remove)
+		}
+		fresh = append(fresh, v.delta)
+	}
+	if len(fresh) == 0 {
+		s.lock.Unlock()
+		return nil, nil
+	}
+	err := s.store()
+	if err == nil && len(latest) > 0 {
+		err = s.updatePermissionedNodes(latest)
+	}
+	server := s.server
+	s.lock.Unlock()
+
+	if err != nil {
+		return nil, err
+	}
+	for _, delta := range latest {
+		log.Info("Applied permissioned node delta", "node", delta.Node, "remove", delta.Remove, "timestamp", uint64(delta.Timestamp))
+		if delta.Remove && server != nil {
+			server.RemovePeer(enode.MustParseV4(delta.Node))
+		}
+	}
+	return fresh, nil
+}
+
+// store writes the deltas known to the data directory. The caller must hold the
+// lock.
+func (s *Syncer) store() error {
+	blob, err := json.MarshalIndent(s.sorted(), "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(s.dataDir, params.PERMISSIONED_DELTAS), blob, 0600)
+}
+
+// sorted returns the deltas known by signing time. The caller must hold the
+// lock.
+func (s *Syncer) sorted() []*Delta {
+	deltas := make([]*Delta, 0, len(s.deltas))
+	for _, delta := range s.deltas {
+		deltas = append(deltas, delta)
+	}
+	sort.Slice(deltas, func(i, j int) bool { return deltas[j].newer(deltas[i]) })
+	return deltas
+}
+
+// digest returns the number of deltas known and the hash of their sorted
+// hashes, equal on the nodes knowing the same deltas. The caller must hold the
+// lock.
+func (s *Syncer) digest() (uint64, common.Hash) {
+	hashes := make([]common.Hash, 0, len(s.deltas))
+	for hash := range s.deltas {
+		hashes = append(hashes, hash)
+	}
+	sort.Slice(hashes, func(i, j int) bool { return bytes.Compare(hashes[i][:], hashes[j][:]) < 0 })
+
+	blob := make([]byte, 0, len(hashes)*common.HashLength)
+	for _, hash := range hashes {
+		blob = append(blob, hash[:]...)
+	}
+	return uint64(len(hashes)), crypto.Keccak256Hash(blob)
+}
+
+// updatePermissionedNodes replaces the entries of the nodes of the given deltas
+// in the permissioned nodes with the URLs of the added nodes, dropping those of
+// the removed nodes. The caller must hold the lock.
+func (s *Syncer) updatePermissionedNodes(deltas map[enode.ID]*Delta) error {
+	path := filepath.Join(s.dataDir, params.PERMISSIONED_CONFIG)
+	var entries []string
+	blob, err := ioutil.ReadFile(path)
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(blob, &entries); err != nil {
+			return fmt.Errorf("invalid %s: %v", params.PERMISSIONED_CONFIG, err)
+		}
+	case !os.IsNotExist(err):
+		return err
+	}
+	updated := make([]string, 0, len(entries)+len(deltas))
+	for _, entry := range entries {
+		if !strings.HasPrefix(entry, p2p.ORG_PREFIX) {
+			if node, err := enode.ParseV4(entry); err == nil && deltas[node.ID()] != nil {
+				continue
+			}
+		}
+		updated = append(updated, entry)
+	}
+	for _, delta := range deltas {
+		if !delta.Remove {
+			updated = append(updated, delta.Node)
+		}
+	}
+	if blob, err = json.MarshalIndent(updated, "", "  "); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, blob, 0644)
+}
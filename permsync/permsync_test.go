@@ -0,0 +1,204 @@
+package permsync
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/adminauth"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+var (
+	operatorKey, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	otherKey, _    = crypto.HexToECDSA("8a1f9a8f95be41cd7ccb6168179afb4504aefe388d1e14474d32c45c72ce7b7a")
+
+	nodeA = "enode://6f8a80d14311c39f35f516fa664deaaaa13e85b2f7493f37f6144d86991ec012937307647bd3b9a82abe2974e1407241d54947bbb39763a4cac9f77166ad92a0@127.0.0.1:30303"
+	nodeB = "enode://3d9ca5956b38557aba991e31cf510d4df641dce9cc26bfeb7de082f0c07abb6ede3a58410c8f249dabeecee4ad3979929ac4c7c496ad20b8cfdd061b7401b4f5@127.0.0.1:30304"
+)
+
+func signDelta(t *testing.T, key []byte, url string, remove bool, timestamp uint64) *Delta {
+	delta := &Delta{Node: url, Remove: remove, Timestamp: hexutil.Uint64(timestamp)}
+	hash, _, err := adminauth.SigningHash(delta.method(), timestamp, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	priv, _ := crypto.ToECDSA(key)
+	if delta.Signature, err = crypto.Sign(hash.Bytes(), priv); err != nil {
+		t.Fatal(err)
+	}
+	return delta
+}
+
+func newTestSyncer(t *testing.T, nodes ...string) (*Syncer, string) {
+	dir, err := ioutil.TempDir("", "permsync")
+	if err != nil {
+		t.Fatal(err)
+	}
+	blob, _ := json.Marshal(append([]string{"org:acme"}, nodes...))
+	if err := ioutil.WriteFile(filepath.Join(dir, params.PERMISSIONED_CONFIG), blob, 0644); err != nil {
+		t.Fatal(err)
+	}
+	auth := adminauth.New([]common.Address{crypto.PubkeyToAddress(operatorKey.PublicKey)}, "")
+	syncer, err := New(dir, auth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return syncer, dir
+}
+
+func readPermissionedNodes(t *testing.T, dir string) []string {
+	blob, err := ioutil.ReadFile(filepath.Join(dir, params.PERMISSIONED_CONFIG))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entries []string
+	if err := json.Unmarshal(blob, &entries); err != nil {
+		t.Fatal(err)
+	}
+	return entries
+}
+
+// Tests that the nodes converge on the latest delta of each node whatever the
+// order they apply the deltas in, and refuse those of unknown operators.
+func TestApplyConverges(t *testing.T) {
+	key := crypto.FromECDSA(operatorKey)
+	deltas := []*Delta{
+		signDelta(t, key, nodeA, false, 100),
+		signDelta(t, key, nodeB, false, 100),
+		signDelta(t, key, nodeA, true, 200),
+	}
+	first, firstDir := newTestSyncer(t)
+	defer os.RemoveAll(firstDir)
+	second, secondDir := newTestSyncer(t, nodeA)
+	defer os.RemoveAll(secondDir)
+
+	for _, delta := range deltas {
+		if fresh, err := first.Apply(delta); err != nil || !fresh {
+			t.Fatalf("failed to apply delta: fresh %v, err %v", fresh, err)
+		}
+	}
+	for i := len(deltas) - 1; i >= 0; i-- {
+		if _, err := second.Apply(deltas[i]); err != nil {
+			t.Fatalf("failed to apply delta: %v", err)
+		}
+	}
+	if fresh, err := first.Apply(deltas[0]); err != nil || fresh {
+		t.Errorf("known delta reapplied: fresh %v, err %v", fresh, err)
+	}
+	want := []string{"org:acme", nodeB}
+	for _, dir := range []string{firstDir, secondDir} {
+		if have := readPermissionedNodes(t, dir); len(have) != len(want) || have[0] != want[0] || have[1] != want[1] {
+			t.Errorf("permissioned nodes mismatch: have %v, want %v", have, want)
+		}
+	}
+	firstCount, firstDigest := first.digest()
+	secondCount, secondDigest := second.digest()
+	if firstCount != 3 || firstCount != secondCount || firstDigest != secondDigest {
+		t.Errorf("digest mismatch: have %d %x and %d %x", firstCount, firstDigest, secondCount, secondDigest)
+	}
+	// The deltas of unknown operators, or tampered with, are refused
+	if _, err := first.Apply(signDelta(t, crypto.FromECDSA(otherKey), nodeA, false, 300)); err == nil {
+		t.Errorf("delta of unknown operator applied")
+	}
+	tampered := signDelta(t, key, nodeA, false, 300)
+	tampered.Node = nodeB
+	if _, err := first.Apply(tampered); err == nil {
+		t.Errorf("tampered delta applied")
+	}
+	// The deltas are reloaded on restart
+	reloaded, err := New(firstDir, first.auth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count, digest := reloaded.digest(); count != firstCount || digest != firstDigest {
+		t.Errorf("reloaded digest mismatch: have %d %x, want %d %x", count, digest, firstCount, firstDigest)
+	}
+}
+
+// Tests that the nodes exchange the deltas they miss on connection.
+func TestReconcileOnConnect(t *testing.T) {
+	first, firstDir := newTestSyncer(t)
+	defer os.RemoveAll(firstDir)
+	second, secondDir := newTestSyncer(t)
+	defer os.RemoveAll(secondDir)
+
+	key := crypto.FromECDSA(operatorKey)
+	if _, err := first.Apply(signDelta(t, key, nodeA, false, 100)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := second.Apply(signDelta(t, key, nodeB, false, 100)); err != nil {
+		t.Fatal(err)
+	}
+	app, net := p2p.MsgPipe()
+	defer app.Close()
+
+	caps := []p2p.Cap{{Name: protocolName, Version: protocolVersion}}
+	go first.runPeer(p2p.NewPeer(enode.ID{1}, "second", caps), app)
+	go second.runPeer(p2p.NewPeer(enode.ID{2}, "first", caps), net)
+
+	for start := time.Now(); time.Since(start) < 5*time.Second; time.Sleep(10 * time.Millisecond) {
+		first.lock.Lock()
+		firstCount, firstDigest := first.digest()
+		first.lock.Unlock()
+		second.lock.Lock()
+		secondCount, secondDigest := second.digest()
+		second.lock.Unlock()
+
+		if firstCount == 2 && secondCount == 2 && firstDigest == secondDigest {
+			if nodes := readPermissionedNodes(t, secondDir); len(nodes) != 3 {
+				t.Errorf("permissioned nodes mismatch: have %v", nodes)
+			}
+			return
+		}
+	}
+	t.Fatalf("deltas not reconciled")
+}
+
+// Tests that a peer missing more deltas than fit in its queue receives them all,
+// the reply being streamed at its pace rather than dropped.
+func TestReconcileStream(t *testing.T) {
+	first, firstDir := newTestSyncer(t)
+	defer os.RemoveAll(firstDir)
+	second, secondDir := newTestSyncer(t)
+	defer os.RemoveAll(secondDir)
+
+	deltas := make([]verifiedDelta, (peerQueueSize+1)*maxDeltasPerMsg)
+	for i := range deltas {
+		key, _ := crypto.GenerateKey()
+		url := enode.NewV4(&key.PublicKey, net.IP{127, 0, 0, 1}, 30303, 30303).String()
+		deltas[i] = verifiedDelta{enode.PubkeyToIDV4(&key.PublicKey), signDelta(t, crypto.FromECDSA(operatorKey), url, false, uint64(100+i))}
+	}
+	if fresh, err := first.record(deltas); err != nil || len(fresh) != len(deltas) {
+		t.Fatalf("failed to record deltas: %d, %v", len(fresh), err)
+	}
+	local, remote := p2p.MsgPipe()
+	defer local.Close()
+
+	caps := []p2p.Cap{{Name: protocolName, Version: protocolVersion}}
+	go first.runPeer(p2p.NewPeer(enode.ID{1}, "second", caps), local)
+	go second.runPeer(p2p.NewPeer(enode.ID{2}, "first", caps), remote)
+
+	for start := time.Now(); time.Since(start) < 20*time.Second; time.Sleep(50 * time.Millisecond) {
+		second.lock.Lock()
+		count, _ := second.digest()
+		second.lock.Unlock()
+
+		if count == uint64(len(deltas)) {
+			if nodes := readPermissionedNodes(t, secondDir); len(nodes) != len(deltas)+1 {
+				t.Errorf("permissioned nodes mismatch: have %d, want %d", len(nodes), len(deltas)+1)
+			}
+			return
+		}
+	}
+	t.Fatalf("deltas not streamed")
+}
@@ -0,0 +1,263 @@
+package permsync
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Constants to match up protocol versions and messages
+const (
+	protocolName    = "psync"
+	protocolVersion = 1
+	protocolLength  = 3 // Number of messages of the protocol
+
+	statusMsg    = 0x00 // Digest of the deltas known, sent on connection
+	deltasMsg    = 0x01 // Deltas relayed, or sent on request
+	getDeltasMsg = 0x02 // Request of all the deltas known
+)
+
+const (
+	maxMsgSize      = 1024 * 1024 // Maximum size of a protocol message
+	maxDeltasPerMsg = 256         // Maximum number of deltas of a message
+	peerQueueSize   = 16          // Messages queued to a peer before dropping the relays
+)
+
+// errPeerClosed is returned when streaming to a peer whose connection ended.
+var errPeerClosed = errors.New("peer connection closed")
+
+// statusData is the digest of the deltas known to a node.
+type statusData struct {
+	Count  uint64
+	Digest common.Hash
+}
+
+// queuedMsg is a message queued to a peer.
+type queuedMsg struct {
+	code uint64
+	data interface{}
+}
+
+// peer is a node running the protocol. Its messages are sent in turn from a
+// queue, lest a slow peer block the others.
+type peer struct {
+	*p2p.Peer
+	rw     p2p.MsgReadWriter
+	queue  chan queuedMsg
+	closed chan struct{} // Closed once the messages are no longer sent
+
+	streaming int32 // Whether the deltas are being streamed to the peer (atomic)
+}
+
+// send queues a message to the peer, dropping it if the queue is full, as the
+// deltas missed are recovered on reconciliation.
+func (p *peer) send(code uint64, data interface{}) {
+	select {
+	case p.queue <- queuedMsg{code, data}:
+	default:
+		p.Log().Debug("Dropping permissioned node sync message", "code", code)
+	}
+}
+
+// stream queues a message to the peer, waiting for room in the queue rather
+// than dropping it, so that a reply of many messages is sent at the pace of the
+// peer.
+func (p *peer) stream(code uint64, data interface{}) error {
+	select {
+	case p.queue <- queuedMsg{code, data}:
+		return nil
+	case <-p.closed:
+		return errPeerClosed
+	}
+}
+
+// Protocols implements node.Service, returning the protocol exchanging the
+// deltas.
+func (s *Syncer) Protocols() []p2p.Protocol {
+	return []p2p.Protocol{{
+		Name:    protocolName,
+		Version: protocolVersion,
+		Length:  protocolLength,
+		Run:     s.runPeer,
+	}}
+}
+
+// APIs implements node.Service, returning the RPC API of the syncer.
+func (s *Syncer) APIs() []rpc.API {
+	return []rpc.API{{
+		Namespace: "permsync",
+		Version:   "1.0",
+		Service:   NewPrivatePermSyncAPI(s),
+		Public:    false,
+	}}
+}
+
+// Start implements node.Service, disconnecting the nodes removed through the
+// given server.
+func (s *Syncer) Start(server *p2p.Server) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.server = server
+	return nil
+}
+
+// Stop implements node.Service.
+func (s *Syncer) Stop() error {
+	return nil
+}
+
+// runPeer exchanges the deltas with a peer, sending the digest of the deltas
+// known first.
+func (s *Syncer) runPeer(p *p2p.Peer, rw p2p.MsgReadWriter) error {
+	peer := &peer{Peer: p, rw: rw, queue: make(chan queuedMsg, peerQueueSize), closed: make(chan struct{})}
+
+	s.lock.Lock()
+	count, digest := s.digest()
+	s.peers[peer] = struct{}{}
+	s.lock.Unlock()
+
+	defer func() {
+		s.lock.Lock()
+		delete(s.peers, peer)
+		s.lock.Unlock()
+	}()
+	peer.send(statusMsg, &statusData{Count: count, Digest: digest})
+
+	quit := make(chan struct{})
+	defer close(quit)
+	go func() {
+		defer close(peer.closed)
+		for {
+			select {
+			case msg := <-peer.queue:
+				// A failed write breaks the connection, ending the read loop
+				if err := p2p.Send(rw, msg.code, msg.data); err != nil {
+					return
+				}
+			case <-quit:
+				return
+			}
+		}
+	}()
+	for {
+		if err := s.handleMsg(peer); err != nil {
+			p.Log().Debug("Permissioned node sync failed", "err", err)
+			return err
+		}
+	}
+}
+
+// handleMsg handles the next message of a peer.
+func (s *Syncer) handleMsg(p *peer) error {
+	msg, err := p.rw.ReadMsg()
+	if err != nil {
+		return err
+	}
+	defer msg.Discard()
+
+	if msg.Size > maxMsgSize {
+		return fmt.Errorf("message too large: %v > %v", msg.Size, maxMsgSize)
+	}
+	switch msg.Code {
+	case statusMsg:
+		var status statusData
+		if err := msg.Decode(&status); err != nil {
+			return fmt.Errorf("invalid status: %v", err)
+		}
+		s.lock.Lock()
+		count, digest := s.digest()
+		s.lock.Unlock()
+
+		if status.Count != count || status.Digest != digest {
+			p.Log().Debug("Permissioned node deltas differing, requesting them", "count", count, "remote", status.Count)
+			p.send(getDeltasMsg, []interface{}{})
+		}
+
+	case getDeltasMsg:
+		// The deltas are streamed in the background, the peer may be streaming
+		// its own meanwhile, and a request arriving during a stream is served
+		// by it already
+		if !atomic.CompareAndSwapInt32(&p.streaming, 0, 1) {
+			break
+		}
+		s.lock.Lock()
+		deltas := s.sorted()
+		s.lock.Unlock()
+
+		go func() {
+			defer atomic.StoreInt32(&p.streaming, 0)
+			for len(deltas) > 0 {
+				n := len(deltas)
+				if n > maxDeltasPerMsg {
+					n = maxDeltasPerMsg
+				}
+				if err := p.stream(deltasMsg, deltas[:n]); err != nil {
+					return
+				}
+				deltas = deltas[n:]
+			}
+		}()
+
+	case deltasMsg:
+		var deltas []*Delta
+		if err := msg.Decode(&deltas); err != nil {
+			return fmt.Errorf("invalid deltas: %v", err)
+		}
+		if len(deltas) > maxDeltasPerMsg {
+			return fmt.Errorf("too many deltas: %d > %d", len(deltas), maxDeltasPerMsg)
+		}
+		verified := make([]verifiedDelta, 0, len(deltas))
+		for _, delta := range deltas {
+			// The deltas of unknown operators are ignored, the operator keys of
+			// the nodes may differ while they are being rotated
+			id, err := s.verify(delta)
+			if err != nil {
+				p.Log().Debug("Ignoring permissioned node delta", "node", delta.Node, "err", err)
+				continue
+			}
+			verified = append(verified, verifiedDelta{id, delta})
+		}
+		fresh, err := s.record(verified)
+		if err != nil {
+			p.Log().Warn("Failed to record permissioned node deltas", "err", err)
+		}
+		if len(fresh) > 0 {
+			s.broadcast(fresh, p)
+		}
+
+	default:
+		return fmt.Errorf("invalid message code %d", msg.Code)
+	}
+	return nil
+}
+
+// broadcast relays deltas to the peers, but the one they were received from.
+func (s *Syncer) broadcast(deltas []*Delta, from *peer) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for p := range s.peers {
+		if p != from {
+			p.send(deltasMsg, deltas)
+		}
+	}
+}
+
+// reconcile requests all the deltas known to the peers, returning the number of
+// peers requested.
+func (s *Syncer) reconcile() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for p := range s.peers {
+		p.send(getDeltasMsg, []interface{}{})
+	}
+	log.Info("Reconciling permissioned node deltas", "peers", len(s.peers))
+	return len(s.peers)
+}
@@ -46,6 +46,7 @@ type basePlugin struct {
 	gateways         plugin.PluginSet // gateways to invoke RPC API implementation of interfaces supported by this plugin
 	pluginWorkspace  string           // plugin workspace
 	commands         []string         // plugin executable commands
+	distChecksum     string           // SHA256 checksum of the verified plugin distribution
 	logger           log.Logger
 }
 
@@ -81,6 +82,7 @@ func (bp *basePlugin) load() error {
 	if err := bp.pm.verifier.VerifySignature(bp.pluginDefinition, pluginChecksum); err != nil {
 		return err
 	}
+	bp.distChecksum = pluginChecksum
 	bp.logger.Info("unpacking plugin", "checksum", pluginChecksum)
 	// Unpack plugin
 	unPackDir, pluginMeta, err := unpackPlugin(pluginDistFilePath)
@@ -204,6 +206,7 @@ func (bp *basePlugin) Info() (PluginInterfaceName, interface{}) {
 	info["version"] = bp.pluginDefinition.Version
 	info["config"] = bp.pluginDefinition.Config
 	info["executable"] = bp.commands
+	info["checksum"] = bp.distChecksum
 	return bp.pluginInterface, info
 }
 
@@ -11,9 +11,11 @@
 
 // generate stubs
 //go:generate protoc -I ../../vendor/github.com/jpmorganchase/quorum-plugin-definitions -I ../../vendor --go_out=plugins=grpc:proto_common init.proto
+//go:generate protoc -I . --go_out=plugins=grpc:proto_proposalvalidator proposal_validator.proto
 
 // generate mocks for unit testing
 //go:generate mockgen -package proto_common -destination proto_common/mock_init.go -source proto_common/init.pb.go
+//go:generate mockgen -package proto_proposalvalidator -destination proto_proposalvalidator/mock_proposal_validator.go -source proto_proposalvalidator/proposal_validator.pb.go
 
 // fix fmt
 //go:generate goimports -w ./
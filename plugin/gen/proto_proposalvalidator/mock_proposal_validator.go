@@ -0,0 +1,94 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: proto_proposalvalidator/proposal_validator.pb.go
+
+// Package proto_proposalvalidator is a generated GoMock package.
+package proto_proposalvalidator
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+	grpc "google.golang.org/grpc"
+)
+
+// MockPluginProposalValidatorClient is a mock of PluginProposalValidatorClient interface
+type MockPluginProposalValidatorClient struct {
+	ctrl     *gomock.Controller
+	recorder *MockPluginProposalValidatorClientMockRecorder
+}
+
+// MockPluginProposalValidatorClientMockRecorder is the mock recorder for MockPluginProposalValidatorClient
+type MockPluginProposalValidatorClientMockRecorder struct {
+	mock *MockPluginProposalValidatorClient
+}
+
+// NewMockPluginProposalValidatorClient creates a new mock instance
+func NewMockPluginProposalValidatorClient(ctrl *gomock.Controller) *MockPluginProposalValidatorClient {
+	mock := &MockPluginProposalValidatorClient{ctrl: ctrl}
+	mock.recorder = &MockPluginProposalValidatorClientMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockPluginProposalValidatorClient) EXPECT() *MockPluginProposalValidatorClientMockRecorder {
+	return m.recorder
+}
+
+// ValidateProposal mocks base method
+func (m *MockPluginProposalValidatorClient) ValidateProposal(ctx context.Context, in *PluginProposalValidation_Request, opts ...grpc.CallOption) (*PluginProposalValidation_Response, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "ValidateProposal", varargs...)
+	ret0, _ := ret[0].(*PluginProposalValidation_Response)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ValidateProposal indicates an expected call of ValidateProposal
+func (mr *MockPluginProposalValidatorClientMockRecorder) ValidateProposal(ctx, in interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ValidateProposal", reflect.TypeOf((*MockPluginProposalValidatorClient)(nil).ValidateProposal), varargs...)
+}
+
+// MockPluginProposalValidatorServer is a mock of PluginProposalValidatorServer interface
+type MockPluginProposalValidatorServer struct {
+	ctrl     *gomock.Controller
+	recorder *MockPluginProposalValidatorServerMockRecorder
+}
+
+// MockPluginProposalValidatorServerMockRecorder is the mock recorder for MockPluginProposalValidatorServer
+type MockPluginProposalValidatorServerMockRecorder struct {
+	mock *MockPluginProposalValidatorServer
+}
+
+// NewMockPluginProposalValidatorServer creates a new mock instance
+func NewMockPluginProposalValidatorServer(ctrl *gomock.Controller) *MockPluginProposalValidatorServer {
+	mock := &MockPluginProposalValidatorServer{ctrl: ctrl}
+	mock.recorder = &MockPluginProposalValidatorServerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockPluginProposalValidatorServer) EXPECT() *MockPluginProposalValidatorServerMockRecorder {
+	return m.recorder
+}
+
+// ValidateProposal mocks base method
+func (m *MockPluginProposalValidatorServer) ValidateProposal(arg0 context.Context, arg1 *PluginProposalValidation_Request) (*PluginProposalValidation_Response, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ValidateProposal", arg0, arg1)
+	ret0, _ := ret[0].(*PluginProposalValidation_Response)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ValidateProposal indicates an expected call of ValidateProposal
+func (mr *MockPluginProposalValidatorServerMockRecorder) ValidateProposal(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ValidateProposal", reflect.TypeOf((*MockPluginProposalValidatorServer)(nil).ValidateProposal), arg0, arg1)
+}
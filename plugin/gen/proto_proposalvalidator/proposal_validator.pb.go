@@ -0,0 +1,276 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// source: proposal_validator.proto
+
+package proto_proposalvalidator
+
+import (
+	context "context"
+	fmt "fmt"
+	math "math"
+
+	proto "github.com/golang/protobuf/proto"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
+
+//*
+// A wrapper message to logically group other messages
+type PluginProposalValidation struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *PluginProposalValidation) Reset()         { *m = PluginProposalValidation{} }
+func (m *PluginProposalValidation) String() string { return proto.CompactTextString(m) }
+func (*PluginProposalValidation) ProtoMessage()    {}
+func (*PluginProposalValidation) Descriptor() ([]byte, []int) {
+	return fileDescriptor_b7eb3460e5463e3e, []int{0}
+}
+
+func (m *PluginProposalValidation) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PluginProposalValidation.Unmarshal(m, b)
+}
+func (m *PluginProposalValidation) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PluginProposalValidation.Marshal(b, m, deterministic)
+}
+func (m *PluginProposalValidation) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PluginProposalValidation.Merge(m, src)
+}
+func (m *PluginProposalValidation) XXX_Size() int {
+	return xxx_messageInfo_PluginProposalValidation.Size(m)
+}
+func (m *PluginProposalValidation) XXX_DiscardUnknown() {
+	xxx_messageInfo_PluginProposalValidation.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PluginProposalValidation proto.InternalMessageInfo
+
+//
+// A block proposed to the validator
+type PluginProposalValidation_Request struct {
+	// RLP encoding of the proposed block, including its transactions
+	Block []byte `protobuf:"bytes,1,opt,name=block,proto3" json:"block,omitempty"`
+	// Number of the proposed block
+	Number uint64 `protobuf:"varint,2,opt,name=number,proto3" json:"number,omitempty"`
+	// Hash of the proposed block
+	Hash                 []byte   `protobuf:"bytes,3,opt,name=hash,proto3" json:"hash,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *PluginProposalValidation_Request) Reset()         { *m = PluginProposalValidation_Request{} }
+func (m *PluginProposalValidation_Request) String() string { return proto.CompactTextString(m) }
+func (*PluginProposalValidation_Request) ProtoMessage()    {}
+func (*PluginProposalValidation_Request) Descriptor() ([]byte, []int) {
+	return fileDescriptor_b7eb3460e5463e3e, []int{0, 0}
+}
+
+func (m *PluginProposalValidation_Request) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PluginProposalValidation_Request.Unmarshal(m, b)
+}
+func (m *PluginProposalValidation_Request) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PluginProposalValidation_Request.Marshal(b, m, deterministic)
+}
+func (m *PluginProposalValidation_Request) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PluginProposalValidation_Request.Merge(m, src)
+}
+func (m *PluginProposalValidation_Request) XXX_Size() int {
+	return xxx_messageInfo_PluginProposalValidation_Request.Size(m)
+}
+func (m *PluginProposalValidation_Request) XXX_DiscardUnknown() {
+	xxx_messageInfo_PluginProposalValidation_Request.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PluginProposalValidation_Request proto.InternalMessageInfo
+
+func (m *PluginProposalValidation_Request) GetBlock() []byte {
+	if m != nil {
+		return m.Block
+	}
+	return nil
+}
+
+func (m *PluginProposalValidation_Request) GetNumber() uint64 {
+	if m != nil {
+		return m.Number
+	}
+	return 0
+}
+
+func (m *PluginProposalValidation_Request) GetHash() []byte {
+	if m != nil {
+		return m.Hash
+	}
+	return nil
+}
+
+type PluginProposalValidation_Response struct {
+	// Whether the validator may commit to the proposed block
+	Accepted bool `protobuf:"varint,1,opt,name=accepted,proto3" json:"accepted,omitempty"`
+	// Reason the proposed block is rejected, logged by `geth`
+	Reason               string   `protobuf:"bytes,2,opt,name=reason,proto3" json:"reason,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *PluginProposalValidation_Response) Reset()         { *m = PluginProposalValidation_Response{} }
+func (m *PluginProposalValidation_Response) String() string { return proto.CompactTextString(m) }
+func (*PluginProposalValidation_Response) ProtoMessage()    {}
+func (*PluginProposalValidation_Response) Descriptor() ([]byte, []int) {
+	return fileDescriptor_b7eb3460e5463e3e, []int{0, 1}
+}
+
+func (m *PluginProposalValidation_Response) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PluginProposalValidation_Response.Unmarshal(m, b)
+}
+func (m *PluginProposalValidation_Response) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PluginProposalValidation_Response.Marshal(b, m, deterministic)
+}
+func (m *PluginProposalValidation_Response) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PluginProposalValidation_Response.Merge(m, src)
+}
+func (m *PluginProposalValidation_Response) XXX_Size() int {
+	return xxx_messageInfo_PluginProposalValidation_Response.Size(m)
+}
+func (m *PluginProposalValidation_Response) XXX_DiscardUnknown() {
+	xxx_messageInfo_PluginProposalValidation_Response.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PluginProposalValidation_Response proto.InternalMessageInfo
+
+func (m *PluginProposalValidation_Response) GetAccepted() bool {
+	if m != nil {
+		return m.Accepted
+	}
+	return false
+}
+
+func (m *PluginProposalValidation_Response) GetReason() string {
+	if m != nil {
+		return m.Reason
+	}
+	return ""
+}
+
+func init() {
+	proto.RegisterType((*PluginProposalValidation)(nil), "proto_proposalvalidator.PluginProposalValidation")
+	proto.RegisterType((*PluginProposalValidation_Request)(nil), "proto_proposalvalidator.PluginProposalValidation.Request")
+	proto.RegisterType((*PluginProposalValidation_Response)(nil), "proto_proposalvalidator.PluginProposalValidation.Response")
+}
+
+func init() { proto.RegisterFile("proposal_validator.proto", fileDescriptor_b7eb3460e5463e3e) }
+
+var fileDescriptor_b7eb3460e5463e3e = []byte{
+	// 249 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x9c, 0x91, 0xb1, 0x4e, 0xc3, 0x30,
+	0x10, 0x86, 0x65, 0x28, 0x25, 0x9c, 0x18, 0xc0, 0x42, 0xc4, 0xca, 0x54, 0x31, 0x75, 0xf2, 0x00,
+	0x13, 0x0c, 0x0c, 0x95, 0x98, 0x58, 0x2a, 0x0f, 0x0c, 0x2c, 0x95, 0x93, 0x9e, 0x68, 0x44, 0xe2,
+	0x73, 0xed, 0x98, 0x77, 0xe0, 0x41, 0x10, 0xaf, 0x89, 0xb0, 0xdd, 0x2e, 0x90, 0xa5, 0x93, 0xfd,
+	0xe9, 0x3b, 0xff, 0xba, 0x5f, 0x06, 0x61, 0x1d, 0x59, 0xf2, 0xba, 0x5b, 0x7d, 0xe8, 0xae, 0x5d,
+	0xeb, 0x81, 0x9c, 0xb4, 0x8e, 0x06, 0xe2, 0x65, 0x3c, 0x56, 0x3b, 0xbf, 0xd7, 0x37, 0xdf, 0x0c,
+	0xc4, 0xb2, 0x0b, 0x6f, 0xad, 0x59, 0x66, 0xf7, 0x92, 0x5c, 0x4b, 0xa6, 0x7a, 0x86, 0x53, 0x85,
+	0xdb, 0x80, 0x7e, 0xe0, 0x57, 0x70, 0x52, 0x77, 0xd4, 0xbc, 0x0b, 0x36, 0x63, 0xf3, 0x73, 0x95,
+	0x80, 0x5f, 0xc3, 0xd4, 0x84, 0xbe, 0x46, 0x27, 0x8e, 0x66, 0x6c, 0x3e, 0x51, 0x99, 0x38, 0x87,
+	0xc9, 0x46, 0xfb, 0x8d, 0x38, 0x8e, 0xc3, 0xf1, 0x5e, 0x3d, 0x42, 0xa1, 0xd0, 0x5b, 0x32, 0x1e,
+	0x79, 0x05, 0x85, 0x6e, 0x1a, 0xb4, 0x03, 0xae, 0x63, 0x60, 0xa1, 0xf6, 0xfc, 0x9b, 0xe9, 0x50,
+	0x7b, 0x32, 0x31, 0xf3, 0x4c, 0x65, 0xba, 0xfd, 0x62, 0x50, 0xfe, 0xbb, 0x29, 0x39, 0xfe, 0xc9,
+	0xe0, 0x22, 0x13, 0xee, 0x2c, 0xbf, 0x97, 0x23, 0xa5, 0xe5, 0x58, 0x61, 0x99, 0xdb, 0x56, 0x0f,
+	0x87, 0x3c, 0x4d, 0xdd, 0x16, 0x4f, 0x50, 0x36, 0xd4, 0xcb, 0x6d, 0x20, 0x17, 0x7a, 0x69, 0xe3,
+	0x7c, 0x8a, 0x5b, 0x5c, 0xfe, 0xd9, 0xfc, 0x75, 0xec, 0x63, 0xea, 0x69, 0x14, 0x77, 0x3f, 0x03,
+	0x00, 0xaa, 0xb9, 0x03, 0x64, 0xd4, 0x01, 0x00, 0x00,
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConn
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion4
+
+// PluginProposalValidatorClient is the client API for PluginProposalValidator service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type PluginProposalValidatorClient interface {
+	ValidateProposal(ctx context.Context, in *PluginProposalValidation_Request, opts ...grpc.CallOption) (*PluginProposalValidation_Response, error)
+}
+
+type pluginProposalValidatorClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewPluginProposalValidatorClient(cc *grpc.ClientConn) PluginProposalValidatorClient {
+	return &pluginProposalValidatorClient{cc}
+}
+
+func (c *pluginProposalValidatorClient) ValidateProposal(ctx context.Context, in *PluginProposalValidation_Request, opts ...grpc.CallOption) (*PluginProposalValidation_Response, error) {
+	out := new(PluginProposalValidation_Response)
+	err := c.cc.Invoke(ctx, "/proto_proposalvalidator.PluginProposalValidator/ValidateProposal", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// PluginProposalValidatorServer is the server API for PluginProposalValidator service.
+type PluginProposalValidatorServer interface {
+	ValidateProposal(context.Context, *PluginProposalValidation_Request) (*PluginProposalValidation_Response, error)
+}
+
+// UnimplementedPluginProposalValidatorServer can be embedded to have forward compatible implementations.
+type UnimplementedPluginProposalValidatorServer struct {
+}
+
+func (*UnimplementedPluginProposalValidatorServer) ValidateProposal(ctx context.Context, req *PluginProposalValidation_Request) (*PluginProposalValidation_Response, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ValidateProposal not implemented")
+}
+
+func RegisterPluginProposalValidatorServer(s *grpc.Server, srv PluginProposalValidatorServer) {
+	s.RegisterService(&_PluginProposalValidator_serviceDesc, srv)
+}
+
+func _PluginProposalValidator_ValidateProposal_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PluginProposalValidation_Request)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PluginProposalValidatorServer).ValidateProposal(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto_proposalvalidator.PluginProposalValidator/ValidateProposal",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PluginProposalValidatorServer).ValidateProposal(ctx, req.(*PluginProposalValidation_Request))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _PluginProposalValidator_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "proto_proposalvalidator.PluginProposalValidator",
+	HandlerType: (*PluginProposalValidatorServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ValidateProposal",
+			Handler:    _PluginProposalValidator_ValidateProposal_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "proposal_validator.proto",
+}
@@ -1,6 +1,9 @@
 package plugin
 
-import "github.com/ethereum/go-ethereum/plugin/helloworld"
+import (
+	"github.com/ethereum/go-ethereum/plugin/helloworld"
+	"github.com/ethereum/go-ethereum/plugin/proposalvalidator"
+)
 
 // a template that returns the hello world plugin instance
 type HelloWorldPluginTemplate struct {
@@ -18,3 +21,20 @@ func (p *HelloWorldPluginTemplate) Get() (helloworld.PluginHelloWorld, error) {
 		},
 	}, nil
 }
+
+// a template that returns the proposal validator plugin instance
+type ProposalValidatorPluginTemplate struct {
+	*basePlugin
+}
+
+func (p *ProposalValidatorPluginTemplate) Get() (proposalvalidator.PluginProposalValidator, error) {
+	return &proposalvalidator.ReloadablePluginProposalValidator{
+		DeferFunc: func() (proposalvalidator.PluginProposalValidator, error) {
+			raw, err := p.dispense(proposalvalidator.ConnectorName)
+			if err != nil {
+				return nil, err
+			}
+			return raw.(proposalvalidator.PluginProposalValidator), nil
+		},
+	}, nil
+}
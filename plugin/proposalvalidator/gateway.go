@@ -0,0 +1,29 @@
+package proposalvalidator
+
+import (
+	"context"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/plugin/gen/proto_proposalvalidator"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+type PluginGateway struct {
+	client proto_proposalvalidator.PluginProposalValidatorClient
+}
+
+func (g *PluginGateway) ValidateProposal(ctx context.Context, block *types.Block) (bool, string, error) {
+	blob, err := rlp.EncodeToBytes(block)
+	if err != nil {
+		return false, "", err
+	}
+	resp, err := g.client.ValidateProposal(ctx, &proto_proposalvalidator.PluginProposalValidation_Request{
+		Block:  blob,
+		Number: block.NumberU64(),
+		Hash:   block.Hash().Bytes(),
+	})
+	if err != nil {
+		return false, "", err
+	}
+	return resp.Accepted, resp.Reason, nil
+}
@@ -0,0 +1,44 @@
+package proposalvalidator
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/plugin/gen/proto_proposalvalidator"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPluginGateway_ValidateProposal(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	block := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(42), Difficulty: big.NewInt(1)})
+	blob, err := rlp.EncodeToBytes(block)
+	assert.NoError(t, err)
+	req := &proto_proposalvalidator.PluginProposalValidation_Request{
+		Block:  blob,
+		Number: 42,
+		Hash:   block.Hash().Bytes(),
+	}
+
+	mockClient := proto_proposalvalidator.NewMockPluginProposalValidatorClient(ctrl)
+	mockClient.
+		EXPECT().
+		ValidateProposal(gomock.Any(), gomock.Eq(req)).
+		Return(&proto_proposalvalidator.PluginProposalValidation_Response{
+			Accepted: false,
+			Reason:   "arbitrary reason",
+		}, nil)
+
+	testObject := &PluginGateway{client: mockClient}
+
+	accepted, reason, err := testObject.ValidateProposal(context.Background(), block)
+
+	assert.NoError(t, err)
+	assert.False(t, accepted)
+	assert.Equal(t, "arbitrary reason", reason)
+}
@@ -0,0 +1,27 @@
+package proposalvalidator
+
+import (
+	"context"
+
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// PluginProposalValidator vets the blocks proposed to an Istanbul validator,
+// returning whether it may commit to the block and, if not, why.
+type PluginProposalValidator interface {
+	ValidateProposal(ctx context.Context, block *types.Block) (bool, string, error)
+}
+
+type PluginProposalValidatorDeferFunc func() (PluginProposalValidator, error)
+
+type ReloadablePluginProposalValidator struct {
+	DeferFunc PluginProposalValidatorDeferFunc
+}
+
+func (d *ReloadablePluginProposalValidator) ValidateProposal(ctx context.Context, block *types.Block) (bool, string, error) {
+	p, err := d.DeferFunc()
+	if err != nil {
+		return false, "", err
+	}
+	return p.ValidateProposal(ctx, block)
+}
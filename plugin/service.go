@@ -3,6 +3,7 @@ package plugin
 import (
 	"fmt"
 	"reflect"
+	"sort"
 	"sync"
 	"unsafe"
 
@@ -157,6 +158,30 @@ func (s *PluginManager) PluginsInfo() interface{} {
 	return info
 }
 
+// LoadedPlugin describes a plugin configured on the node.
+type LoadedPlugin struct {
+	Interface PluginInterfaceName `json:"interface"`
+	Name      string              `json:"name"`
+	Version   Version             `json:"version"`
+	Checksum  string              `json:"checksum,omitempty"` // SHA256 checksum of the distribution, once verified
+}
+
+// LoadedPlugins describes the configured plugins, sorted by interface.
+func (s *PluginManager) LoadedPlugins() []LoadedPlugin {
+	plugins := make([]LoadedPlugin, 0, len(s.initializedPlugins))
+	for name, p := range s.initializedPlugins {
+		loaded := LoadedPlugin{Interface: name}
+		if bp, ok := p.(*basePlugin); ok {
+			loaded.Name = bp.pluginDefinition.Name
+			loaded.Version = bp.pluginDefinition.Version
+			loaded.Checksum = bp.distChecksum
+		}
+		plugins = append(plugins, loaded)
+	}
+	sort.Slice(plugins, func(i, j int) bool { return plugins[i].Interface < plugins[j].Interface })
+	return plugins
+}
+
 func NewPluginManager(nodeName string, settings *Settings, skipVerify bool, localVerify bool, publicKey string) (*PluginManager, error) {
 	pm := &PluginManager{
 		nodeName:           nodeName,
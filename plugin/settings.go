@@ -12,13 +12,15 @@ import (
 	"strings"
 
 	"github.com/ethereum/go-ethereum/plugin/helloworld"
+	"github.com/ethereum/go-ethereum/plugin/proposalvalidator"
 	"github.com/hashicorp/go-plugin"
 
 	"github.com/naoina/toml"
 )
 
 const (
-	HelloWorldPluginInterfaceName = PluginInterfaceName("helloworld") // lower-case always
+	HelloWorldPluginInterfaceName        = PluginInterfaceName("helloworld")        // lower-case always
+	ProposalValidatorPluginInterfaceName = PluginInterfaceName("proposalvalidator") // lower-case always
 )
 
 var (
@@ -27,6 +29,9 @@ var (
 		HelloWorldPluginInterfaceName: {
 			helloworld.ConnectorName: &helloworld.PluginConnector{},
 		},
+		ProposalValidatorPluginInterfaceName: {
+			proposalvalidator.ConnectorName: &proposalvalidator.PluginConnector{},
+		},
 	}
 
 	// this is the place holder for future solution of the plugin central
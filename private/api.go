@@ -0,0 +1,114 @@
+package private
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/private/privatetransactionmanager"
+)
+
+// ErrPrivateTransactionManagerNotEnabled is returned when a private transaction
+// manager is required but none is configured.
+var ErrPrivateTransactionManagerNotEnabled = errors.New("PrivateTransactionManager is not enabled")
+
+var errNoPrivacyGroupMembers = errors.New("no privacy group members given")
+
+// PublicPrivacyAPI provides an API to inspect the private transaction manager.
+type PublicPrivacyAPI struct{}
+
+// NewPublicPrivacyAPI creates a new private transaction manager API.
+func NewPublicPrivacyAPI() *PublicPrivacyAPI {
+	return &PublicPrivacyAPI{}
+}
+
+// PendingDistributions returns the recipients which were unreachable when
+// private payloads were sent to them, and whose payloads are waiting to be
+// pushed to them again.
+func (api *PublicPrivacyAPI) PendingDistributions() ([]privatetransactionmanager.PendingDistribution, error) {
+	if P == nil {
+		return nil, ErrPrivateTransactionManagerNotEnabled
+	}
+	return P.PendingDistributions(), nil
+}
+
+// PrivacyGroupArgs are the arguments creating a privacy group.
+type PrivacyGroupArgs struct {
+	From        string   `json:"from"` // Key of the node managing the group, default one if empty
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Members     []string `json:"members"`
+}
+
+// CreatePrivacyGroup creates a privacy group, which private transactions can
+// then be sent to with privacyGroupId instead of privateFor.
+func (api *PublicPrivacyAPI) CreatePrivacyGroup(args PrivacyGroupArgs) (*privatetransactionmanager.PrivacyGroup, error) {
+	if P == nil {
+		return nil, ErrPrivateTransactionManagerNotEnabled
+	}
+	if len(args.Members) == 0 {
+		return nil, errNoPrivacyGroupMembers
+	}
+	return P.CreatePrivacyGroup(args.From, args.Name, args.Description, args.Members)
+}
+
+// FindPrivacyGroup returns the privacy groups made of exactly the given members.
+func (api *PublicPrivacyAPI) FindPrivacyGroup(members []string) ([]*privatetransactionmanager.PrivacyGroup, error) {
+	if P == nil {
+		return nil, ErrPrivateTransactionManagerNotEnabled
+	}
+	return P.FindPrivacyGroups(members)
+}
+
+// RetrievePrivacyGroup returns the privacy group of the given ID.
+func (api *PublicPrivacyAPI) RetrievePrivacyGroup(id string) (*privatetransactionmanager.PrivacyGroup, error) {
+	if P == nil {
+		return nil, ErrPrivateTransactionManagerNotEnabled
+	}
+	return P.RetrievePrivacyGroup(id)
+}
+
+// AddToPrivacyGroup adds members to a privacy group.
+func (api *PublicPrivacyAPI) AddToPrivacyGroup(from, id string, members []string) (*privatetransactionmanager.PrivacyGroup, error) {
+	if P == nil {
+		return nil, ErrPrivateTransactionManagerNotEnabled
+	}
+	if len(members) == 0 {
+		return nil, errNoPrivacyGroupMembers
+	}
+	return P.AddToPrivacyGroup(from, id, members)
+}
+
+// RemoveFromPrivacyGroup removes members from a privacy group.
+func (api *PublicPrivacyAPI) RemoveFromPrivacyGroup(from, id string, members []string) (*privatetransactionmanager.PrivacyGroup, error) {
+	if P == nil {
+		return nil, ErrPrivateTransactionManagerNotEnabled
+	}
+	if len(members) == 0 {
+		return nil, errNoPrivacyGroupMembers
+	}
+	return P.RemoveFromPrivacyGroup(from, id, members)
+}
+
+// DeletePrivacyGroup deletes a privacy group.
+func (api *PublicPrivacyAPI) DeletePrivacyGroup(from, id string) (bool, error) {
+	if P == nil {
+		return false, ErrPrivateTransactionManagerNotEnabled
+	}
+	if err := P.DeletePrivacyGroup(from, id); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// ResolvePrivacyGroup returns the public keys of the members of a privacy
+// group, for a private transaction sent to the group.
+func ResolvePrivacyGroup(id string) ([]string, error) {
+	if P == nil {
+		return nil, ErrPrivateTransactionManagerNotEnabled
+	}
+	group, err := P.ResolvePrivacyGroup(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve privacy group %s: %v", id, err)
+	}
+	return group.Members, nil
+}
@@ -0,0 +1,69 @@
+package private
+
+import (
+	"github.com/ethereum/go-ethereum/event"
+)
+
+// FailureEvent is posted when a call to the private transaction manager fails.
+type FailureEvent struct {
+	Op  string // Failed operation: send, sendSignedTx, storeRaw, receive or delete
+	Err error
+}
+
+var failureFeed event.Feed
+
+// SubscribeFailureEvent registers a subscription of the failed calls to the
+// private transaction manager.
+func SubscribeFailureEvent(ch chan<- FailureEvent) event.Subscription {
+	return failureFeed.Subscribe(ch)
+}
+
+// reportFailures wraps a private transaction manager to post its failed calls,
+// returning nil if there is no manager.
+func reportFailures(ptm PrivateTransactionManager) PrivateTransactionManager {
+	if ptm == nil {
+		return nil
+	}
+	return &failureReporter{ptm}
+}
+
+// failureReporter posts the failed calls of a private transaction manager.
+type failureReporter struct {
+	PrivateTransactionManager
+}
+
+func (r *failureReporter) report(op string, err error) {
+	if err != nil {
+		failureFeed.Send(FailureEvent{Op: op, Err: err})
+	}
+}
+
+func (r *failureReporter) Send(data []byte, from string, to []string) ([]byte, error) {
+	out, err := r.PrivateTransactionManager.Send(data, from, to)
+	r.report("send", err)
+	return out, err
+}
+
+func (r *failureReporter) SendSignedTx(data []byte, to []string) ([]byte, error) {
+	out, err := r.PrivateTransactionManager.SendSignedTx(data, to)
+	r.report("sendSignedTx", err)
+	return out, err
+}
+
+func (r *failureReporter) StoreRaw(data []byte, from string) ([]byte, error) {
+	out, err := r.PrivateTransactionManager.StoreRaw(data, from)
+	r.report("storeRaw", err)
+	return out, err
+}
+
+func (r *failureReporter) Receive(data []byte) ([]byte, error) {
+	out, err := r.PrivateTransactionManager.Receive(data)
+	r.report("receive", err)
+	return out, err
+}
+
+func (r *failureReporter) Delete(data []byte) error {
+	err := r.PrivateTransactionManager.Delete(data)
+	r.report("delete", err)
+	return err
+}
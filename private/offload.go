@@ -0,0 +1,221 @@
+package private
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+// offloadMagic prefixes the references to the payloads held in an external
+// store, distributed through the private transaction manager in their place.
+var offloadMagic = []byte("\x00quorum-offload-v1\x00")
+
+const (
+	offloadRetries = 3 // Retrievals of an offloaded payload retried before giving up
+)
+
+// offloadRetryDelay is the delay before the first retry of a retrieval, doubled
+// on every further retry.
+var offloadRetryDelay = time.Second
+
+var (
+	errOffloadCorrupted    = errors.New("externally stored private payload doesn't match its reference")
+	errOffloadStoreMissing = errors.New("no private payload store configured, set --ptm.offload.store")
+
+	offloadStoredMeter   = metrics.NewRegisteredMeter("ptm/offload/stored", nil)
+	offloadResolvedMeter = metrics.NewRegisteredMeter("ptm/offload/resolved", nil)
+)
+
+// offloadReference locates a payload in the external store along with the key
+// it is encrypted with. As the reference is only distributed to the recipients
+// of the payload, the store doesn't need to be trusted with its content.
+type offloadReference struct {
+	Object common.Hash   `json:"object"` // Hash of the encrypted payload, naming it in the store
+	Key    hexutil.Bytes `json:"key"`    // AES-256-GCM key of the payload
+	Size   int           `json:"size"`   // Size of the plain payload
+}
+
+// OffloadError is returned when the payload a reference points to can't be
+// resolved. As the node is a recipient of the payload, its transaction can't be
+// skipped as if it weren't: the processing of the block has to fail, not to
+// diverge from the private state of the other parties.
+type OffloadError struct {
+	Object common.Hash // Name of the payload in the store
+	Err    error
+}
+
+func (e *OffloadError) Error() string {
+	return fmt.Sprintf("failed to resolve offloaded private payload %x: %v", e.Object, e.Err)
+}
+
+// offloadPayloads wraps a private transaction manager to hold the payloads over
+// the threshold in an external store, distributing only their references. The
+// references are resolved on retrieval whatever the threshold, a zero threshold
+// only resolving the payloads offloaded by other nodes.
+func offloadPayloads(ptm PrivateTransactionManager, store PayloadStore, threshold int) PrivateTransactionManager {
+	if ptm == nil {
+		return nil
+	}
+	return &payloadOffloader{PrivateTransactionManager: ptm, store: store, threshold: threshold}
+}
+
+// OffloadPayloads configures the private transaction manager to hold the
+// payloads of threshold bytes or more in the given external store. Without a
+// store, the references received can't be resolved, failing their blocks.
+func OffloadPayloads(store PayloadStore, threshold int) {
+	ptm := P
+	if r, ok := ptm.(*failureReporter); ok {
+		ptm = r.PrivateTransactionManager
+	}
+	if o, ok := ptm.(*payloadOffloader); ok {
+		o.store, o.threshold = store, threshold
+		return
+	}
+	P = offloadPayloads(P, store, threshold)
+}
+
+// payloadOffloader holds the large payloads of a private transaction manager in
+// an external store.
+type payloadOffloader struct {
+	PrivateTransactionManager
+	store     PayloadStore
+	threshold int
+}
+
+// offload stores a payload over the threshold, returning its reference in its
+// place.
+func (o *payloadOffloader) offload(data []byte) ([]byte, error) {
+	if o.threshold == 0 || len(data) < o.threshold {
+		return data, nil
+	}
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	gcm, err := newOffloadCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	// Every payload has its own key, so a zero nonce is never reused
+	sealed := gcm.Seal(nil, make([]byte, gcm.NonceSize()), data, nil)
+
+	ref := offloadReference{Object: crypto.Keccak256Hash(sealed), Key: key, Size: len(data)}
+	if err := o.store.Put(ref.Object.Hex(), sealed); err != nil {
+		return nil, fmt.Errorf("failed to offload private payload: %v", err)
+	}
+	blob, err := json.Marshal(ref)
+	if err != nil {
+		return nil, err
+	}
+	offloadStoredMeter.Mark(1)
+	log.Debug("Offloaded private payload", "object", ref.Object, "size", len(data))
+	return append(append([]byte{}, offloadMagic...), blob...), nil
+}
+
+// reference decodes the reference to an offloaded payload, or returns nil if
+// the payload is held by the private transaction manager itself.
+func (o *payloadOffloader) reference(data []byte) (*offloadReference, error) {
+	if !bytes.HasPrefix(data, offloadMagic) {
+		return nil, nil
+	}
+	ref := new(offloadReference)
+	if err := json.Unmarshal(data[len(offloadMagic):], ref); err != nil {
+		return nil, fmt.Errorf("invalid offloaded private payload reference: %v", err)
+	}
+	return ref, nil
+}
+
+// resolve retrieves and decrypts the payload a reference points to, returning
+// any other payload as is. The retrieval is retried a few times before failing
+// with an *OffloadError.
+func (o *payloadOffloader) resolve(data []byte) ([]byte, error) {
+	ref, err := o.reference(data)
+	if ref == nil || err != nil {
+		return data, err
+	}
+	if o.store == nil {
+		log.Error("Received an offloaded private payload without a payload store", "object", ref.Object)
+		return nil, &OffloadError{Object: ref.Object, Err: errOffloadStoreMissing}
+	}
+	var sealed []byte
+	for attempt := 0; ; attempt++ {
+		if sealed, err = o.store.Get(ref.Object.Hex()); err == nil {
+			break
+		}
+		if attempt == offloadRetries {
+			return nil, &OffloadError{Object: ref.Object, Err: err}
+		}
+		log.Warn("Failed to retrieve offloaded private payload, retrying", "object", ref.Object, "err", err)
+		time.Sleep(offloadRetryDelay << uint(attempt))
+	}
+	if crypto.Keccak256Hash(sealed) != ref.Object {
+		return nil, &OffloadError{Object: ref.Object, Err: errOffloadCorrupted}
+	}
+	gcm, err := newOffloadCipher(ref.Key)
+	if err != nil {
+		return nil, &OffloadError{Object: ref.Object, Err: err}
+	}
+	payload, err := gcm.Open(nil, make([]byte, gcm.NonceSize()), sealed, nil)
+	if err != nil || len(payload) != ref.Size {
+		return nil, &OffloadError{Object: ref.Object, Err: errOffloadCorrupted}
+	}
+	offloadResolvedMeter.Mark(1)
+	return payload, nil
+}
+
+func (o *payloadOffloader) Send(data []byte, from string, to []string) ([]byte, error) {
+	data, err := o.offload(data)
+	if err != nil {
+		return nil, err
+	}
+	return o.PrivateTransactionManager.Send(data, from, to)
+}
+
+func (o *payloadOffloader) StoreRaw(data []byte, from string) ([]byte, error) {
+	data, err := o.offload(data)
+	if err != nil {
+		return nil, err
+	}
+	return o.PrivateTransactionManager.StoreRaw(data, from)
+}
+
+func (o *payloadOffloader) Receive(data []byte) ([]byte, error) {
+	payload, err := o.PrivateTransactionManager.Receive(data)
+	if err != nil {
+		return nil, err
+	}
+	return o.resolve(payload)
+}
+
+// Delete erases the payload from the private transaction manager, and from the
+// external store if it was offloaded.
+func (o *payloadOffloader) Delete(data []byte) error {
+	if payload, err := o.PrivateTransactionManager.Receive(data); err == nil {
+		if ref, _ := o.reference(payload); ref != nil && o.store != nil {
+			if err := o.store.Delete(ref.Object.Hex()); err != nil {
+				log.Warn("Failed to delete offloaded private payload", "object", ref.Object, "err", err)
+			}
+		}
+	}
+	return o.PrivateTransactionManager.Delete(data)
+}
+
+// newOffloadCipher creates the cipher of an offloaded payload.
+func newOffloadCipher(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,229 @@
+package private
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-storage-blob-go/2018-03-28/azblob"
+)
+
+// PayloadStore is an external store holding the encrypted private payloads too
+// large to be kept by the private transaction manager.
+type PayloadStore interface {
+	Put(name string, data []byte) error
+	Get(name string) ([]byte, error)
+	Delete(name string) error
+}
+
+// NewPayloadStore opens the external payload store at the given URL:
+//
+//	s3://bucket/prefix?region=eu-west-1  Amazon S3, authenticated by the AWS_ACCESS_KEY_ID
+//	                                     and AWS_SECRET_ACCESS_KEY environment variables
+//	azure://account/container/prefix     Azure Blob Storage, authenticated by the
+//	                                     AZURE_STORAGE_KEY environment variable
+//	file:///path                         a directory, e.g. a volume shared across nodes
+func NewPayloadStore(rawurl string) (PayloadStore, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, err
+	}
+	prefix := strings.Trim(u.Path, "/")
+	switch u.Scheme {
+	case "s3":
+		region := u.Query().Get("region")
+		if region == "" {
+			region = "us-east-1"
+		}
+		store := &s3Store{
+			endpoint: fmt.Sprintf("https://%s.s3.%s.amazonaws.com", u.Host, region),
+			prefix:   prefix,
+			region:   region,
+			keyID:    os.Getenv("AWS_ACCESS_KEY_ID"),
+			secret:   os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			client:   &http.Client{Timeout: time.Minute},
+		}
+		if store.keyID == "" || store.secret == "" {
+			return nil, fmt.Errorf("S3 payload store requires AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY")
+		}
+		return store, nil
+
+	case "azure":
+		key := os.Getenv("AZURE_STORAGE_KEY")
+		if key == "" {
+			return nil, fmt.Errorf("Azure payload store requires AZURE_STORAGE_KEY")
+		}
+		parts := strings.SplitN(prefix, "/", 2)
+		if parts[0] == "" {
+			return nil, fmt.Errorf("Azure payload store %q has no container", rawurl)
+		}
+		pipeline := azblob.NewPipeline(azblob.NewSharedKeyCredential(u.Host, key), azblob.PipelineOptions{})
+		service, _ := url.Parse(fmt.Sprintf("https://%s.blob.core.windows.net", u.Host))
+
+		store := &azureStore{container: azblob.NewServiceURL(*service, pipeline).NewContainerURL(parts[0])}
+		if len(parts) > 1 {
+			store.prefix = parts[1]
+		}
+		return store, nil
+
+	case "file":
+		if err := os.MkdirAll(u.Path, 0700); err != nil {
+			return nil, err
+		}
+		return fileStore(u.Path), nil
+	}
+	return nil, fmt.Errorf("unsupported payload store %q", rawurl)
+}
+
+// fileStore holds the payloads as files of a directory.
+type fileStore string
+
+func (s fileStore) Put(name string, data []byte) error {
+	// Write to a temporary file first, for readers to never see a partial payload
+	tmp := filepath.Join(string(s), name+".tmp")
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(string(s), name))
+}
+
+func (s fileStore) Get(name string) ([]byte, error) {
+	return ioutil.ReadFile(filepath.Join(string(s), name))
+}
+
+func (s fileStore) Delete(name string) error {
+	if err := os.Remove(filepath.Join(string(s), name)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// azureStore holds the payloads as block blobs of an Azure storage container.
+type azureStore struct {
+	container azblob.ContainerURL
+	prefix    string
+}
+
+func (s *azureStore) blob(name string) azblob.BlockBlobURL {
+	return s.container.NewBlockBlobURL(path.Join(s.prefix, name))
+}
+
+func (s *azureStore) Put(name string, data []byte) error {
+	_, err := s.blob(name).Upload(context.Background(), bytes.NewReader(data), azblob.BlobHTTPHeaders{}, azblob.Metadata{}, azblob.BlobAccessConditions{})
+	return err
+}
+
+func (s *azureStore) Get(name string) ([]byte, error) {
+	res, err := s.blob(name).Download(context.Background(), 0, azblob.CountToEnd, azblob.BlobAccessConditions{}, false)
+	if err != nil {
+		return nil, err
+	}
+	body := res.Body(azblob.RetryReaderOptions{MaxRetryRequests: 3})
+	defer body.Close()
+	return ioutil.ReadAll(body)
+}
+
+func (s *azureStore) Delete(name string) error {
+	_, err := s.blob(name).Delete(context.Background(), azblob.DeleteSnapshotsOptionNone, azblob.BlobAccessConditions{})
+	return err
+}
+
+// s3Store holds the payloads as objects of an S3 bucket, signing its requests
+// with AWS Signature Version 4.
+type s3Store struct {
+	endpoint string
+	prefix   string
+	region   string
+	keyID    string
+	secret   string
+	client   *http.Client
+}
+
+func (s *s3Store) Put(name string, data []byte) error {
+	_, err := s.do(http.MethodPut, name, data)
+	return err
+}
+
+func (s *s3Store) Get(name string) ([]byte, error) {
+	return s.do(http.MethodGet, name, nil)
+}
+
+func (s *s3Store) Delete(name string) error {
+	_, err := s.do(http.MethodDelete, name, nil)
+	return err
+}
+
+// do sends a signed request for an object, returning the response body.
+func (s *s3Store) do(method, name string, body []byte) ([]byte, error) {
+	req, err := http.NewRequest(method, s.endpoint+"/"+path.Join(s.prefix, name), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	s.sign(req, body, time.Now().UTC())
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	out, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode/100 != 2 {
+		return nil, fmt.Errorf("S3 %s %s: %s", method, name, res.Status)
+	}
+	return out, nil
+}
+
+// sign adds the AWS Signature Version 4 authorization to a request.
+func (s *s3Store) sign(req *http.Request, body []byte, now time.Time) {
+	var (
+		date      = now.Format("20060102")
+		timestamp = now.Format("20060102T150405Z")
+		scope     = date + "/" + s.region + "/s3/aws4_request"
+		bodyHash  = sha256.Sum256(body)
+	)
+	req.Header.Set("X-Amz-Date", timestamp)
+	req.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(bodyHash[:]))
+
+	signed := "host;x-amz-content-sha256;x-amz-date"
+	canonical := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		"",
+		"host:" + req.URL.Host,
+		"x-amz-content-sha256:" + hex.EncodeToString(bodyHash[:]),
+		"x-amz-date:" + timestamp,
+		"",
+		signed,
+		hex.EncodeToString(bodyHash[:]),
+	}, "\n")
+	canonicalHash := sha256.Sum256([]byte(canonical))
+	toSign := strings.Join([]string{"AWS4-HMAC-SHA256", timestamp, scope, hex.EncodeToString(canonicalHash[:])}, "\n")
+
+	key := []byte("AWS4" + s.secret)
+	for _, part := range []string{date, s.region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, toSign))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s", s.keyID, scope, signed, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,102 @@
+package private
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// failingStore is a payload store which is down.
+type failingStore struct {
+	PayloadStore
+	gets int
+}
+
+func (s *failingStore) Get(name string) ([]byte, error) {
+	s.gets++
+	return nil, errors.New("store unavailable")
+}
+
+// Tests that the payloads over the threshold are held encrypted in the external
+// store, with only their references going through the private transaction
+// manager, and are resolved on retrieval.
+func TestOffloadPayloads(t *testing.T) {
+	dir, err := ioutil.TempDir("", "offload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	store, err := NewPayloadStore("file://" + dir)
+	if err != nil {
+		t.Fatalf("failed to open store: %v", err)
+	}
+	manager := newStubManager("a")
+	ptm := offloadPayloads(manager, store, 1024)
+
+	// Small payloads are left to the private transaction manager
+	small := []byte("small payload")
+	hash, err := ptm.Send(small, "", nil)
+	if err != nil {
+		t.Fatalf("failed to send small payload: %v", err)
+	}
+	if !bytes.Equal(manager.payloads[string(hash)], small) {
+		t.Fatalf("small payload offloaded")
+	}
+	// Large payloads are only distributed by reference
+	large := bytes.Repeat([]byte("large payload "), 1000)
+	if hash, err = ptm.Send(large, "", nil); err != nil {
+		t.Fatalf("failed to send large payload: %v", err)
+	}
+	ref := manager.payloads[string(hash)]
+	if !bytes.HasPrefix(ref, offloadMagic) || len(ref) > 256 {
+		t.Fatalf("large payload not offloaded: %d bytes distributed", len(ref))
+	}
+	objects, _ := filepath.Glob(filepath.Join(dir, "*"))
+	if len(objects) != 1 {
+		t.Fatalf("stored object count mismatch: have %d, want 1", len(objects))
+	}
+	if sealed, _ := ioutil.ReadFile(objects[0]); bytes.Contains(sealed, []byte("large payload")) {
+		t.Fatalf("offloaded payload stored in the clear")
+	}
+	// The references are resolved on retrieval, even without offloading
+	receiver := offloadPayloads(manager, store, 0)
+	if payload, err := receiver.Receive(hash); err != nil || !bytes.Equal(payload, large) {
+		t.Fatalf("large payload mismatch: have %d bytes, %v", len(payload), err)
+	}
+	if payload, err := receiver.Receive([]byte("unknown")); err != nil || payload != nil {
+		t.Fatalf("unknown payload: have %x, %v", payload, err)
+	}
+	// Tampered payloads are refused
+	if err := ioutil.WriteFile(objects[0], []byte("tampered"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ptm.Receive(hash); err == nil || err.(*OffloadError).Err != errOffloadCorrupted {
+		t.Fatalf("tampered payload: have %v, want %v", err, errOffloadCorrupted)
+	}
+	// References can't be resolved without a store, nor with the store down
+	offloadRetryDelay = time.Millisecond
+	if _, err := offloadPayloads(manager, nil, 0).Receive(hash); err == nil || err.(*OffloadError).Err != errOffloadStoreMissing {
+		t.Fatalf("missing store: have %v, want %v", err, errOffloadStoreMissing)
+	}
+	down := &failingStore{PayloadStore: store}
+	if _, err := offloadPayloads(manager, down, 0).Receive(hash); err == nil {
+		t.Fatalf("unavailable store: payload resolved")
+	} else if _, ok := err.(*OffloadError); !ok {
+		t.Fatalf("unavailable store: unexpected error %v", err)
+	}
+	if down.gets != offloadRetries+1 {
+		t.Errorf("retrieval attempts mismatch: have %d, want %d", down.gets, offloadRetries+1)
+	}
+	// Deleting the payload removes it from the store too
+	if err := ptm.Delete(hash); err != nil {
+		t.Fatalf("failed to delete payload: %v", err)
+	}
+	if objects, _ = filepath.Glob(filepath.Join(dir, "*")); len(objects) != 0 {
+		t.Fatalf("offloaded payload not deleted: %v", objects)
+	}
+}
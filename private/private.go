@@ -2,6 +2,7 @@ package private
 
 import (
 	"os"
+	"strings"
 
 	"github.com/ethereum/go-ethereum/private/privatetransactionmanager"
 )
@@ -9,15 +10,66 @@ import (
 type PrivateTransactionManager interface {
 	Send(data []byte, from string, to []string) ([]byte, error)
 	SendSignedTx(data []byte, to []string) ([]byte, error)
+	StoreRaw(data []byte, from string) ([]byte, error)
 	Receive(data []byte) ([]byte, error)
+
+	// Delete erases a payload from the private transaction manager, for the
+	// purge of a private contract.
+	Delete(data []byte) error
+
+	// Participants returns the public keys of the parties of a payload, the
+	// sender and the recipients, for the purge of a private contract to check
+	// that all of them agreed.
+	Participants(data []byte) ([]string, error)
+
+	// PendingDistributions returns the payloads waiting to be pushed again to
+	// recipients which were unreachable when they were sent.
+	PendingDistributions() []privatetransactionmanager.PendingDistribution
+
+	// Close stops the background work of the private transaction manager,
+	// such as pushing the payloads again to unreachable recipients.
+	Close() error
+
+	// Notifications returns the notifications of available payloads numbered
+	// after the given sequence number, for the push endpoint to replay the
+	// ones it missed.
+	Notifications(since uint64) ([]privatetransactionmanager.Notification, error)
+
+	// Privacy groups, which private transactions can be sent to by ID rather
+	// than by listing the public keys of their members.
+	CreatePrivacyGroup(from, name, description string, members []string) (*privatetransactionmanager.PrivacyGroup, error)
+	FindPrivacyGroups(members []string) ([]*privatetransactionmanager.PrivacyGroup, error)
+	RetrievePrivacyGroup(id string) (*privatetransactionmanager.PrivacyGroup, error)
+	// ResolvePrivacyGroup returns a privacy group for a transaction sent to it,
+	// never from a cache, for the members changed through other nodes to be
+	// seen.
+	ResolvePrivacyGroup(id string) (*privatetransactionmanager.PrivacyGroup, error)
+	AddToPrivacyGroup(from, id string, members []string) (*privatetransactionmanager.PrivacyGroup, error)
+	RemoveFromPrivacyGroup(from, id string, members []string) (*privatetransactionmanager.PrivacyGroup, error)
+	DeletePrivacyGroup(from, id string) error
+}
+
+// IsEnabled returns whether a private transaction manager is attached, as
+// opposed to missing or configured to be ignored.
+func IsEnabled() bool {
+	return P != nil && !strings.EqualFold(os.Getenv("PRIVATE_CONFIG"), "ignore")
 }
 
+// FromEnvironmentOrNil connects to the private transaction manager configured
+// by the given environment variable. The configuration file may also define
+// several managers, which private transactions are routed to.
 func FromEnvironmentOrNil(name string) PrivateTransactionManager {
 	cfgPath := os.Getenv(name)
 	if cfgPath == "" {
 		return nil
 	}
+	if cfg, err := LoadRouterConfig(cfgPath); err == nil && len(cfg.Managers) > 0 {
+		return MustNewRouter(cfg)
+	}
 	return privatetransactionmanager.MustNew(cfgPath)
 }
 
-var P = FromEnvironmentOrNil("PRIVATE_CONFIG")
+// P is the private transaction manager of the node. The references to offloaded
+// payloads are always recognised, failing until a store is configured rather
+// than being taken for the payloads themselves.
+var P = reportFailures(offloadPayloads(receivePushed(FromEnvironmentOrNil("PRIVATE_CONFIG")), nil, 0))
@@ -0,0 +1,208 @@
+package privatetransactionmanager
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/patrickmn/go-cache"
+)
+
+// privacyGroupRetention is the time the retrieved privacy groups are cached for.
+// The members changed through other nodes are seen once it elapsed, the sends
+// resolving the groups afresh.
+const privacyGroupRetention = time.Minute
+
+// errPrivacyGroupNotFound is returned when a privacy group is unknown to the
+// private transaction manager.
+var errPrivacyGroupNotFound = errors.New("privacy group not found")
+
+// PrivacyGroup is a set of public keys which private transactions can be sent
+// to as a whole, by the ID of the group.
+type PrivacyGroup struct {
+	PrivacyGroupId string   `json:"privacyGroupId"`
+	Name           string   `json:"name"`
+	Description    string   `json:"description"`
+	Type           string   `json:"type"`
+	Members        []string `json:"members"`
+}
+
+// privacyGroupRequest is the body of the privacy group requests, the fields
+// unused by a request being omitted.
+type privacyGroupRequest struct {
+	PrivacyGroupId string   `json:"privacyGroupId,omitempty"`
+	From           string   `json:"from,omitempty"`
+	Name           string   `json:"name,omitempty"`
+	Description    string   `json:"description,omitempty"`
+	Members        []string `json:"members,omitempty"`
+	Addresses      []string `json:"addresses,omitempty"`
+}
+
+// privacyGroupCall sends a privacy group request, decoding the response into
+// out unless it is nil.
+func (c *Client) privacyGroupCall(path string, req *privacyGroupRequest, out interface{}) error {
+	res, err := c.doJson(path, req)
+	if res != nil {
+		defer res.Body.Close()
+	}
+	if err != nil {
+		return err
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(res.Body).Decode(out)
+}
+
+// CreatePrivacyGroup creates a privacy group of the given members, from
+// included.
+func (c *Client) CreatePrivacyGroup(from, name, description string, members []string) (*PrivacyGroup, error) {
+	group := new(PrivacyGroup)
+	req := &privacyGroupRequest{From: from, Name: name, Description: description, Members: members}
+	if err := c.privacyGroupCall("createPrivacyGroup", req, group); err != nil {
+		return nil, err
+	}
+	return group, nil
+}
+
+// FindPrivacyGroups returns the privacy groups made of exactly the given
+// members.
+func (c *Client) FindPrivacyGroups(members []string) ([]*PrivacyGroup, error) {
+	var groups []*PrivacyGroup
+	if err := c.privacyGroupCall("findPrivacyGroup", &privacyGroupRequest{Addresses: members}, &groups); err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
+
+// RetrievePrivacyGroup returns the privacy group of the given ID.
+func (c *Client) RetrievePrivacyGroup(id string) (*PrivacyGroup, error) {
+	group := new(PrivacyGroup)
+	if err := c.privacyGroupCall("retrievePrivacyGroup", &privacyGroupRequest{PrivacyGroupId: id}, group); err != nil {
+		return nil, err
+	}
+	if group.PrivacyGroupId == "" {
+		return nil, errPrivacyGroupNotFound
+	}
+	return group, nil
+}
+
+// AddToPrivacyGroup adds members to a privacy group, returning the group.
+func (c *Client) AddToPrivacyGroup(from, id string, members []string) (*PrivacyGroup, error) {
+	group := new(PrivacyGroup)
+	req := &privacyGroupRequest{PrivacyGroupId: id, From: from, Members: members}
+	if err := c.privacyGroupCall("addToPrivacyGroup", req, group); err != nil {
+		return nil, err
+	}
+	return group, nil
+}
+
+// RemoveFromPrivacyGroup removes members from a privacy group, returning the
+// group.
+func (c *Client) RemoveFromPrivacyGroup(from, id string, members []string) (*PrivacyGroup, error) {
+	group := new(PrivacyGroup)
+	req := &privacyGroupRequest{PrivacyGroupId: id, From: from, Members: members}
+	if err := c.privacyGroupCall("removeFromPrivacyGroup", req, group); err != nil {
+		return nil, err
+	}
+	return group, nil
+}
+
+// DeletePrivacyGroup deletes a privacy group.
+func (c *Client) DeletePrivacyGroup(from, id string) error {
+	return c.privacyGroupCall("deletePrivacyGroup", &privacyGroupRequest{PrivacyGroupId: id, From: from}, nil)
+}
+
+// CreatePrivacyGroup creates a privacy group, caching it for the transactions
+// sent to it.
+func (g *PrivateTransactionManager) CreatePrivacyGroup(from, name, description string, members []string) (*PrivacyGroup, error) {
+	if g.isPrivateTransactionManagerNotInUse {
+		return nil, errPrivateTransactionManagerNotUsed
+	}
+	group, err := g.node.CreatePrivacyGroup(from, name, description, members)
+	if err != nil {
+		return nil, err
+	}
+	g.groups.Set(group.PrivacyGroupId, group, cache.DefaultExpiration)
+	return group, nil
+}
+
+// FindPrivacyGroups returns the privacy groups made of exactly the given
+// members.
+func (g *PrivateTransactionManager) FindPrivacyGroups(members []string) ([]*PrivacyGroup, error) {
+	if g.isPrivateTransactionManagerNotInUse {
+		return nil, errPrivateTransactionManagerNotUsed
+	}
+	return g.node.FindPrivacyGroups(members)
+}
+
+// RetrievePrivacyGroup returns a privacy group, from the cache if it was
+// resolved recently.
+func (g *PrivateTransactionManager) RetrievePrivacyGroup(id string) (*PrivacyGroup, error) {
+	if g.isPrivateTransactionManagerNotInUse {
+		return nil, errPrivateTransactionManagerNotUsed
+	}
+	if group, ok := g.groups.Get(id); ok {
+		return group.(*PrivacyGroup), nil
+	}
+	group, err := g.node.RetrievePrivacyGroup(id)
+	if err != nil {
+		return nil, err
+	}
+	g.groups.Set(id, group, cache.DefaultExpiration)
+	return group, nil
+}
+
+// ResolvePrivacyGroup returns a privacy group for a transaction sent to it,
+// retrieving it from the private transaction manager and refreshing the cache.
+func (g *PrivateTransactionManager) ResolvePrivacyGroup(id string) (*PrivacyGroup, error) {
+	if g.isPrivateTransactionManagerNotInUse {
+		return nil, errPrivateTransactionManagerNotUsed
+	}
+	group, err := g.node.RetrievePrivacyGroup(id)
+	if err != nil {
+		g.groups.Delete(id)
+		return nil, err
+	}
+	g.groups.Set(id, group, cache.DefaultExpiration)
+	return group, nil
+}
+
+// AddToPrivacyGroup adds members to a privacy group, refreshing its cached
+// membership.
+func (g *PrivateTransactionManager) AddToPrivacyGroup(from, id string, members []string) (*PrivacyGroup, error) {
+	if g.isPrivateTransactionManagerNotInUse {
+		return nil, errPrivateTransactionManagerNotUsed
+	}
+	g.groups.Delete(id)
+	group, err := g.node.AddToPrivacyGroup(from, id, members)
+	if err != nil {
+		return nil, err
+	}
+	g.groups.Set(id, group, cache.DefaultExpiration)
+	return group, nil
+}
+
+// RemoveFromPrivacyGroup removes members from a privacy group, refreshing its
+// cached membership.
+func (g *PrivateTransactionManager) RemoveFromPrivacyGroup(from, id string, members []string) (*PrivacyGroup, error) {
+	if g.isPrivateTransactionManagerNotInUse {
+		return nil, errPrivateTransactionManagerNotUsed
+	}
+	g.groups.Delete(id)
+	group, err := g.node.RemoveFromPrivacyGroup(from, id, members)
+	if err != nil {
+		return nil, err
+	}
+	g.groups.Set(id, group, cache.DefaultExpiration)
+	return group, nil
+}
+
+// DeletePrivacyGroup deletes a privacy group and drops it from the cache.
+func (g *PrivateTransactionManager) DeletePrivacyGroup(from, id string) error {
+	if g.isPrivateTransactionManagerNotInUse {
+		return errPrivateTransactionManagerNotUsed
+	}
+	g.groups.Delete(id)
+	return g.node.DeletePrivacyGroup(from, id)
+}
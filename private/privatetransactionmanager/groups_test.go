@@ -0,0 +1,95 @@
+package privatetransactionmanager
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/patrickmn/go-cache"
+)
+
+func TestPrivacyGroupResolutionCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ptm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	socket := filepath.Join(dir, "tm.ipc")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	var (
+		retrievals int
+		group      = &PrivacyGroup{PrivacyGroupId: "Z3JvdXA=", Name: "ops", Type: "PANTHEON", Members: []string{"Ym9i", "Y2Fyb2w="}}
+	)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/retrievePrivacyGroup", func(w http.ResponseWriter, r *http.Request) {
+		var req privacyGroupRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.PrivacyGroupId != group.PrivacyGroupId {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		retrievals++
+		json.NewEncoder(w).Encode(group)
+	})
+	mux.HandleFunc("/addToPrivacyGroup", func(w http.ResponseWriter, r *http.Request) {
+		var req privacyGroupRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		group.Members = append(group.Members, req.Members...)
+		json.NewEncoder(w).Encode(group)
+	})
+	go http.Serve(listener, mux)
+
+	client, _ := NewClient(socket)
+	ptm := &PrivateTransactionManager{node: client, groups: cache.New(time.Minute, time.Minute)}
+
+	// Groups must be resolved once and served from the cache afterwards
+	for i := 0; i < 3; i++ {
+		have, err := ptm.RetrievePrivacyGroup(group.PrivacyGroupId)
+		if err != nil {
+			t.Fatalf("failed to retrieve group: %v", err)
+		}
+		if !reflect.DeepEqual(have.Members, []string{"Ym9i", "Y2Fyb2w="}) {
+			t.Fatalf("members mismatch: have %v", have.Members)
+		}
+	}
+	if retrievals != 1 {
+		t.Errorf("retrievals mismatch: have %d, want 1", retrievals)
+	}
+	// Membership changes must refresh the cached group
+	if _, err := ptm.AddToPrivacyGroup("Ym9i", group.PrivacyGroupId, []string{"ZGF2ZQ=="}); err != nil {
+		t.Fatalf("failed to add member: %v", err)
+	}
+	if have, _ := ptm.RetrievePrivacyGroup(group.PrivacyGroupId); len(have.Members) != 3 {
+		t.Errorf("cached group not refreshed: %v", have.Members)
+	}
+	// Sends must see the members changed through other nodes at once
+	group.Members = append(group.Members, "ZXZl")
+	have, err := ptm.ResolvePrivacyGroup(group.PrivacyGroupId)
+	if err != nil {
+		t.Fatalf("failed to resolve group: %v", err)
+	}
+	if len(have.Members) != 4 {
+		t.Errorf("stale group resolved for send: %v", have.Members)
+	}
+	if have, _ := ptm.RetrievePrivacyGroup(group.PrivacyGroupId); len(have.Members) != 4 {
+		t.Errorf("cached group not refreshed by send: %v", have.Members)
+	}
+	if _, err := ptm.RetrievePrivacyGroup("unknown"); err == nil {
+		t.Errorf("unknown group resolved")
+	}
+	if _, err := ptm.ResolvePrivacyGroup("unknown"); err == nil {
+		t.Errorf("unknown group resolved for send")
+	}
+}
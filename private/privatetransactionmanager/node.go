@@ -9,6 +9,7 @@ import (
 	"io"
 	"io/ioutil"
 	"net/http"
+	"net/url"
 	"os"
 	"os/exec"
 	"strings"
@@ -63,6 +64,24 @@ type Client struct {
 	httpClient *http.Client
 }
 
+// UnreachableError is returned when the private transaction manager doesn't
+// answer on its socket.
+type UnreachableError struct {
+	Err error
+}
+
+func (e *UnreachableError) Error() string { return e.Err.Error() }
+
+// do sends a request to the private transaction manager, telling the failures
+// to reach it apart from the others.
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return res, &UnreachableError{err}
+	}
+	return res, nil
+}
+
 func (c *Client) doJson(path string, apiReq interface{}) (*http.Response, error) {
 	buf := new(bytes.Buffer)
 	err := json.NewEncoder(buf).Encode(apiReq)
@@ -74,49 +93,125 @@ func (c *Client) doJson(path string, apiReq interface{}) (*http.Response, error)
 		return nil, err
 	}
 	req.Header.Set("Content-Type", "application/json")
-	res, err := c.httpClient.Do(req)
+	res, err := c.do(req)
 	if err == nil && res.StatusCode != 200 {
 		return nil, fmt.Errorf("Non-200 status code: %+v", res)
 	}
 	return res, err
 }
 
-func (c *Client) SendPayload(pl []byte, b64From string, b64To []string) ([]byte, error) {
+func (c *Client) SendPayload(pl []byte, b64From string, b64To []string) ([]byte, []string, error) {
 	buf := bytes.NewBuffer(pl)
 	req, err := http.NewRequest("POST", "http+unix://c/sendraw", buf)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	if b64From != "" {
 		req.Header.Set("c11n-from", b64From)
 	}
 	req.Header.Set("c11n-to", strings.Join(b64To, ","))
 	req.Header.Set("Content-Type", "application/octet-stream")
-	res, err := c.httpClient.Do(req)
+	return c.send(req, b64To)
+}
+
+func (c *Client) SendSignedPayload(signedPayload []byte, b64To []string) ([]byte, []string, error) {
+	buf := bytes.NewBuffer(signedPayload)
+	req, err := http.NewRequest("POST", "http+unix://c/sendsignedtx", buf)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	req.Header.Set("c11n-to", strings.Join(b64To, ","))
+	req.Header.Set("Content-Type", "application/octet-stream")
+	return c.send(req, b64To)
+}
+
+// send executes a send request, returning the key of the stored payload. If the
+// private transaction manager stored the payload but failed to push it to the
+// recipients, which it reports with a server error status, the recipients are
+// returned along with the error, for the payload to be pushed to them again once
+// its transaction is submitted.
+// The recipients can't be told apart, so all of them are returned.
+func (c *Client) send(req *http.Request, b64To []string) ([]byte, []string, error) {
+	res, err := c.do(req)
 
 	if res != nil {
 		defer res.Body.Close()
 	}
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	if res.StatusCode != 200 {
-		return nil, fmt.Errorf("Non-200 status code: %+v", res)
+	switch {
+	case res.StatusCode == http.StatusOK:
+	case res.StatusCode >= http.StatusInternalServerError && res.StatusCode != http.StatusNotImplemented:
+		body, _ := ioutil.ReadAll(io.LimitReader(res.Body, 1024))
+		return nil, b64To, fmt.Errorf("failed to distribute private payload: %s: %s", res.Status, bytes.TrimSpace(body))
+	default:
+		return nil, nil, fmt.Errorf("Non-200 status code: %+v", res)
+	}
+	key, err := ioutil.ReadAll(base64.NewDecoder(base64.StdEncoding, res.Body))
+	if err != nil {
+		return nil, nil, err
 	}
+	return key, nil, nil
+}
 
-	return ioutil.ReadAll(base64.NewDecoder(base64.StdEncoding, res.Body))
+// Resend asks the private transaction manager to push all the payloads it holds
+// for the given recipient again.
+func (c *Client) Resend(b64Recipient string) error {
+	res, err := c.doJson("resend", map[string]string{
+		"type":      "ALL",
+		"publicKey": b64Recipient,
+	})
+	if res != nil {
+		defer res.Body.Close()
+	}
+	return err
 }
 
-func (c *Client) SendSignedPayload(signedPayload []byte, b64To []string) ([]byte, error) {
-	buf := bytes.NewBuffer(signedPayload)
-	req, err := http.NewRequest("POST", "http+unix://c/sendsignedtx", buf)
+// StoreRawPayload stores a payload without distributing it, returning its key.
+// The payload is sent to its recipients when the transaction signed over the
+// key is submitted with SendSignedPayload.
+func (c *Client) StoreRawPayload(pl []byte, b64From string) ([]byte, error) {
+	res, err := c.doJson("storeraw", map[string]string{
+		"payload": base64.StdEncoding.EncodeToString(pl),
+		"from":    b64From,
+	})
+	if res != nil {
+		defer res.Body.Close()
+	}
 	if err != nil {
 		return nil, err
 	}
+	var stored struct {
+		Key string `json:"key"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&stored); err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(stored.Key)
+}
 
-	req.Header.Set("c11n-to", strings.Join(b64To, ","))
-	req.Header.Set("Content-Type", "application/octet-stream")
-	res, err := c.httpClient.Do(req)
+// DeletePayload erases a payload from the private transaction manager.
+func (c *Client) DeletePayload(key []byte) error {
+	res, err := c.doJson("delete", map[string]string{
+		"key": base64.StdEncoding.EncodeToString(key),
+	})
+	if res != nil {
+		defer res.Body.Close()
+	}
+	return err
+}
+
+// ReceivePayload retrieves a payload by its hash, returning nil if the node is
+// not one of its recipients.
+func (c *Client) ReceivePayload(key []byte) ([]byte, error) {
+	req, err := http.NewRequest("GET", "http+unix://c/receiveraw", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("c11n-key", base64.StdEncoding.EncodeToString(key))
+	res, err := c.do(req)
 
 	if res != nil {
 		defer res.Body.Close()
@@ -124,20 +219,27 @@ func (c *Client) SendSignedPayload(signedPayload []byte, b64To []string) ([]byte
 	if err != nil {
 		return nil, err
 	}
+	// The payloads the node isn't a recipient of are not found
+	if res.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
 	if res.StatusCode != 200 {
 		return nil, fmt.Errorf("Non-200 status code: %+v", res)
 	}
 
-	return ioutil.ReadAll(base64.NewDecoder(base64.StdEncoding, res.Body))
+	return ioutil.ReadAll(res.Body)
 }
 
-func (c *Client) ReceivePayload(key []byte) ([]byte, error) {
-	req, err := http.NewRequest("GET", "http+unix://c/receiveraw", nil)
+// PayloadParticipants returns the public keys of the parties of a payload, the
+// sender and the recipients, which the private transaction manager only knows if
+// the node is one of them.
+func (c *Client) PayloadParticipants(key []byte) ([]string, error) {
+	path := url.PathEscape(base64.StdEncoding.EncodeToString(key))
+	req, err := http.NewRequest("GET", "http+unix://c/transaction/"+path+"/participants", nil)
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Set("c11n-key", base64.StdEncoding.EncodeToString(key))
-	res, err := c.httpClient.Do(req)
+	res, err := c.do(req)
 
 	if res != nil {
 		defer res.Body.Close()
@@ -148,8 +250,55 @@ func (c *Client) ReceivePayload(key []byte) ([]byte, error) {
 	if res.StatusCode != 200 {
 		return nil, fmt.Errorf("Non-200 status code: %+v", res)
 	}
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	return splitAndTrim(string(body)), nil
+}
 
-	return ioutil.ReadAll(res.Body)
+// splitAndTrim splits a comma separated list, dropping the empty entries.
+func splitAndTrim(input string) []string {
+	var list []string
+	for _, entry := range strings.Split(input, ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			list = append(list, entry)
+		}
+	}
+	return list
+}
+
+// Notification is the notice, pushed by the private transaction manager, that
+// a payload addressed to the node is available. Notifications are numbered by
+// a sequence number which never decreases, for the missed ones to be replayed.
+type Notification struct {
+	Seq     uint64 `json:"seq"`
+	Key     []byte `json:"key"`               // Hash of the payload
+	Payload []byte `json:"payload,omitempty"` // Payload, if pushed along with the notice
+}
+
+// Notifications asks the private transaction manager for the notifications
+// numbered after the given sequence number.
+func (c *Client) Notifications(since uint64) ([]Notification, error) {
+	req, err := http.NewRequest("GET", fmt.Sprintf("http+unix://c/notifications?since=%d", since), nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := c.do(req)
+	if res != nil {
+		defer res.Body.Close()
+	}
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode != 200 {
+		return nil, fmt.Errorf("Non-200 status code: %+v", res)
+	}
+	var notifications []Notification
+	if err := json.NewDecoder(res.Body).Decode(&notifications); err != nil {
+		return nil, err
+	}
+	return notifications, nil
 }
 
 func NewClient(socketPath string) (*Client, error) {
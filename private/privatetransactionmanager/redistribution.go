@@ -0,0 +1,188 @@
+package privatetransactionmanager
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const (
+	redistributeMinBackoff = 5 * time.Second
+	redistributeMaxBackoff = 10 * time.Minute
+)
+
+// PendingDistribution describes a recipient the private payloads could not be
+// delivered to, which are waiting to be pushed to it again.
+type PendingDistribution struct {
+	Recipient   string    `json:"recipient"`
+	Failures    int       `json:"failures"` // Number of sends which failed to reach the recipient
+	Attempts    int       `json:"attempts"`
+	NextAttempt time.Time `json:"nextAttempt"`
+	LastError   string    `json:"lastError,omitempty"`
+}
+
+// redistributor keeps track of the recipients the private transaction manager
+// failed to push a payload to when sending, and asks it to push their payloads
+// again with an exponential backoff until it succeeds.
+//
+// The payloads of a recipient are recovered all at once, since the transaction
+// manager resends every payload it holds for the recipient.
+type redistributor struct {
+	resend func(recipient string) error
+
+	pending map[string]*PendingDistribution
+	lock    sync.Mutex
+	wake    chan struct{}
+	quit    chan struct{} // Closed to stop the retry loop, nil if not running
+	done    chan struct{} // Closed once the retry loop stopped
+}
+
+func newRedistributor(resend func(recipient string) error) *redistributor {
+	return &redistributor{
+		resend:  resend,
+		pending: make(map[string]*PendingDistribution),
+		wake:    make(chan struct{}, 1),
+	}
+}
+
+// enqueue schedules the payloads of the given recipients to be pushed to them
+// again.
+func (r *redistributor) enqueue(recipients []string, now time.Time) {
+	r.lock.Lock()
+	for _, recipient := range recipients {
+		log.Warn("Private payload recipient unreachable, scheduling redistribution", "recipient", recipient)
+		dist := r.pending[recipient]
+		if dist == nil {
+			dist = &PendingDistribution{Recipient: recipient, NextAttempt: now.Add(redistributeMinBackoff)}
+			r.pending[recipient] = dist
+		}
+		dist.Failures++
+	}
+	r.lock.Unlock()
+
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+// list returns the pending distributions, sorted by recipient.
+func (r *redistributor) list() []PendingDistribution {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	dists := make([]PendingDistribution, 0, len(r.pending))
+	for _, dist := range r.pending {
+		dists = append(dists, *dist)
+	}
+	sort.Slice(dists, func(i, j int) bool { return dists[i].Recipient < dists[j].Recipient })
+	return dists
+}
+
+// redistribute pushes the payloads of the recipients whose retry is due, and
+// returns the time of the next scheduled attempt, if any.
+func (r *redistributor) redistribute(now time.Time) (time.Time, bool) {
+	r.lock.Lock()
+	var due []string
+	for recipient, dist := range r.pending {
+		if !dist.NextAttempt.After(now) {
+			due = append(due, recipient)
+		}
+	}
+	r.lock.Unlock()
+
+	for _, recipient := range due {
+		err := r.resend(recipient)
+
+		r.lock.Lock()
+		dist := r.pending[recipient]
+		if err == nil {
+			log.Info("Redistributed private payloads", "recipient", recipient, "failures", dist.Failures)
+			delete(r.pending, recipient)
+		} else {
+			dist.Attempts++
+			dist.LastError = err.Error()
+			dist.NextAttempt = now.Add(redistributeBackoff(dist.Attempts))
+			log.Debug("Failed to redistribute private payloads", "recipient", recipient, "attempts", dist.Attempts, "err", err)
+		}
+		r.lock.Unlock()
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	var next time.Time
+	for _, dist := range r.pending {
+		if next.IsZero() || dist.NextAttempt.Before(next) {
+			next = dist.NextAttempt
+		}
+	}
+	return next, !next.IsZero()
+}
+
+// start runs the retry loop, unless already running.
+func (r *redistributor) start() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.quit == nil {
+		r.quit, r.done = make(chan struct{}), make(chan struct{})
+		go r.loop(r.quit, r.done)
+	}
+}
+
+// stop terminates the retry loop, if running, the pending distributions being
+// retried again once the loop is restarted by a new one.
+func (r *redistributor) stop() {
+	r.lock.Lock()
+	quit, done := r.quit, r.done
+	r.quit, r.done = nil, nil
+	r.lock.Unlock()
+
+	if quit != nil {
+		close(quit)
+		<-done
+	}
+}
+
+// loop retries the pending distributions whenever they are due, until quit is
+// closed.
+func (r *redistributor) loop(quit, done chan struct{}) {
+	defer close(done)
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-quit:
+			return
+		case <-timer.C:
+		case <-r.wake:
+			if !timer.Stop() {
+				select {
+				case <-timer.C:
+				default:
+				}
+			}
+		}
+		if next, ok := r.redistribute(time.Now()); ok {
+			timer.Reset(time.Until(next))
+		}
+	}
+}
+
+// redistributeBackoff returns the delay before the next attempt, doubling with
+// each failed one.
+func redistributeBackoff(attempts int) time.Duration {
+	backoff := redistributeMinBackoff
+	for i := 0; i < attempts && backoff < redistributeMaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > redistributeMaxBackoff {
+		backoff = redistributeMaxBackoff
+	}
+	return backoff
+}
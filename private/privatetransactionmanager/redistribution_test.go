@@ -0,0 +1,176 @@
+package privatetransactionmanager
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/patrickmn/go-cache"
+)
+
+func TestRedistributorBackoff(t *testing.T) {
+	var (
+		fail     = true
+		resent   []string
+		start    = time.Unix(1000000, 0)
+		recovery = errors.New("recipient unreachable")
+	)
+	r := newRedistributor(func(recipient string) error {
+		resent = append(resent, recipient)
+		if fail {
+			return recovery
+		}
+		return nil
+	})
+	r.enqueue([]string{"bob", "alice"}, start)
+	r.enqueue([]string{"bob"}, start)
+
+	dists := r.list()
+	if len(dists) != 2 || dists[0].Recipient != "alice" || dists[1].Recipient != "bob" {
+		t.Fatalf("pending distributions mismatch: %+v", dists)
+	}
+	if dists[1].Failures != 2 {
+		t.Fatalf("failures mismatch: have %d, want 2", dists[1].Failures)
+	}
+	// Nothing may be resent before the first backoff elapses
+	if next, ok := r.redistribute(start); !ok || !next.Equal(start.Add(redistributeMinBackoff)) {
+		t.Fatalf("next attempt mismatch: have %v/%v, want %v", next, ok, start.Add(redistributeMinBackoff))
+	}
+	if len(resent) != 0 {
+		t.Fatalf("resent too early: %v", resent)
+	}
+	// Failed attempts must double the backoff
+	now := start.Add(redistributeMinBackoff)
+	next, _ := r.redistribute(now)
+	if len(resent) != 2 {
+		t.Fatalf("resent recipients mismatch: have %v, want 2", resent)
+	}
+	if want := now.Add(2 * redistributeMinBackoff); !next.Equal(want) {
+		t.Fatalf("next attempt mismatch: have %v, want %v", next, want)
+	}
+	for _, dist := range r.list() {
+		if dist.Attempts != 1 || dist.LastError != recovery.Error() {
+			t.Errorf("failed attempt not recorded: %+v", dist)
+		}
+	}
+	// A successful attempt must drop the recipient
+	fail, resent = false, nil
+	if _, ok := r.redistribute(next); ok {
+		t.Fatalf("retry scheduled after successful redistribution")
+	}
+	if len(resent) != 2 || len(r.list()) != 0 {
+		t.Fatalf("redistribution not completed: resent %v, pending %v", resent, r.list())
+	}
+	if backoff := redistributeBackoff(100); backoff != redistributeMaxBackoff {
+		t.Errorf("backoff not capped: have %v, want %v", backoff, redistributeMaxBackoff)
+	}
+}
+
+func TestClientUnreachableRecipients(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ptm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	socket := filepath.Join(dir, "tm.ipc")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	resent := make(chan map[string]string, 1)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/sendraw", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("c11n-from") == "fail" {
+			http.Error(w, "Unable to push payload to recipient", http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte(base64.StdEncoding.EncodeToString([]byte{0xde, 0xad})))
+	})
+	mux.HandleFunc("/resend", func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]string
+		json.NewDecoder(r.Body).Decode(&req)
+		resent <- req
+	})
+	go http.Serve(listener, mux)
+
+	client, _ := NewClient(socket)
+	key, unreachable, err := client.SendPayload([]byte("payload"), "", []string{"Ym9i", "Y2Fyb2w="})
+	if err != nil {
+		t.Fatalf("failed to send payload: %v", err)
+	}
+	if !reflect.DeepEqual(key, []byte{0xde, 0xad}) || unreachable != nil {
+		t.Errorf("payload key mismatch: have %x %v, want dead", key, unreachable)
+	}
+	// A failure to push the payload reports all the recipients unreachable
+	if _, unreachable, err = client.SendPayload([]byte("payload"), "fail", []string{"Ym9i", "Y2Fyb2w="}); err == nil {
+		t.Fatalf("failed push not reported")
+	}
+	if !reflect.DeepEqual(unreachable, []string{"Ym9i", "Y2Fyb2w="}) {
+		t.Errorf("unreachable recipients mismatch: have %v", unreachable)
+	}
+	if err := client.Resend("Ym9i"); err != nil {
+		t.Fatalf("failed to request resend: %v", err)
+	}
+	if req := <-resent; req["type"] != "ALL" || req["publicKey"] != "Ym9i" {
+		t.Errorf("resend request mismatch: %v", req)
+	}
+	// A failed send must only be redistributed once its transaction is submitted
+	ptm := &PrivateTransactionManager{
+		node:          client,
+		c:             cache.New(time.Minute, time.Minute),
+		redistributor: newRedistributor(func(string) error { return errors.New("unreachable") }),
+	}
+	defer ptm.Close()
+
+	_, err = ptm.Send([]byte("payload"), "fail", []string{"Ym9i"})
+	undelivered, ok := err.(*UndeliveredError)
+	if !ok {
+		t.Fatalf("undelivered payload not reported: %v", err)
+	}
+	if pending := ptm.PendingDistributions(); len(pending) != 0 {
+		t.Fatalf("failed send scheduled for redistribution: %v", pending)
+	}
+	undelivered.Redistribute()
+	if pending := ptm.PendingDistributions(); len(pending) != 1 || pending[0].Recipient != "Ym9i" {
+		t.Errorf("submitted transaction not scheduled for redistribution: %v", pending)
+	}
+}
+
+func TestRedistributorStop(t *testing.T) {
+	resent := make(chan string, 1)
+	r := newRedistributor(func(recipient string) error {
+		resent <- recipient
+		return nil
+	})
+	// The loop retries once the backoff elapses
+	r.enqueue([]string{"bob"}, time.Now().Add(-redistributeMinBackoff))
+	r.start()
+	r.start() // Starting twice is harmless
+	select {
+	case recipient := <-resent:
+		if recipient != "bob" {
+			t.Fatalf("resent recipient mismatch: have %s, want bob", recipient)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("redistribution not attempted")
+	}
+	r.stop()
+	r.lock.Lock()
+	running := r.quit != nil
+	r.lock.Unlock()
+	if running {
+		t.Fatalf("redistribution loop not stopped")
+	}
+	r.stop() // Stopping twice is harmless
+}
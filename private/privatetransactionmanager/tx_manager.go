@@ -14,6 +14,8 @@ import (
 type PrivateTransactionManager struct {
 	node                                *Client
 	c                                   *cache.Cache
+	groups                              *cache.Cache // Privacy groups resolved, by ID
+	redistributor                       *redistributor
 	isPrivateTransactionManagerNotInUse bool
 }
 
@@ -25,9 +27,9 @@ func (g *PrivateTransactionManager) Send(data []byte, from string, to []string)
 	if g.isPrivateTransactionManagerNotInUse {
 		return nil, errPrivateTransactionManagerNotUsed
 	}
-	out, err = g.node.SendPayload(data, from, to)
+	out, unreachable, err := g.node.SendPayload(data, from, to)
 	if err != nil {
-		return nil, err
+		return nil, g.undeliveredError(unreachable, err)
 	}
 	g.c.Set(string(out), data, cache.DefaultExpiration)
 	return out, nil
@@ -37,13 +39,84 @@ func (g *PrivateTransactionManager) SendSignedTx(data []byte, to []string) (out
 	if g.isPrivateTransactionManagerNotInUse {
 		return nil, errPrivateTransactionManagerNotUsed
 	}
-	out, err = g.node.SendSignedPayload(data, to)
+	out, unreachable, err := g.node.SendSignedPayload(data, to)
+	if err != nil {
+		return nil, g.undeliveredError(unreachable, err)
+	}
+	return out, nil
+}
+
+// StoreRaw stores a payload in the private transaction manager without
+// distributing it, for transactions signed outside of the node.
+func (g *PrivateTransactionManager) StoreRaw(data []byte, from string) ([]byte, error) {
+	if g.isPrivateTransactionManagerNotInUse {
+		return nil, errPrivateTransactionManagerNotUsed
+	}
+	out, err := g.node.StoreRawPayload(data, from)
 	if err != nil {
 		return nil, err
 	}
+	g.c.Set(string(out), data, cache.DefaultExpiration)
 	return out, nil
 }
 
+// UndeliveredError is returned when the private transaction manager failed to
+// push a payload to its recipients. Their payloads are only pushed to them again
+// with Redistribute, once the transaction of the payload has been submitted.
+type UndeliveredError struct {
+	Recipients []string
+
+	err          error
+	redistribute func(recipients []string)
+}
+
+func (e *UndeliveredError) Error() string {
+	return e.err.Error()
+}
+
+// Redistribute schedules the payloads of the unreachable recipients to be
+// pushed to them again.
+func (e *UndeliveredError) Redistribute() {
+	e.redistribute(e.Recipients)
+}
+
+// undeliveredError wraps a failed send with the recipients it didn't reach,
+// if any.
+func (g *PrivateTransactionManager) undeliveredError(unreachable []string, err error) error {
+	if len(unreachable) == 0 {
+		return err
+	}
+	return &UndeliveredError{Recipients: unreachable, err: err, redistribute: g.redistribute}
+}
+
+// redistribute schedules the payloads of the recipients the private transaction
+// manager could not reach to be pushed to them again.
+func (g *PrivateTransactionManager) redistribute(unreachable []string) {
+	if len(unreachable) > 0 {
+		g.redistributor.enqueue(unreachable, time.Now())
+		g.redistributor.start()
+	}
+}
+
+// Close stops pushing the payloads again to the recipients which were
+// unreachable.
+func (g *PrivateTransactionManager) Close() error {
+	if g.redistributor != nil {
+		g.redistributor.stop()
+	}
+	return nil
+}
+
+// PendingDistributions returns the recipients which were unreachable when a
+// private payload was sent to them, and whose payloads are yet to be pushed
+// again.
+func (g *PrivateTransactionManager) PendingDistributions() []PendingDistribution {
+	if g.isPrivateTransactionManagerNotInUse {
+		return []PendingDistribution{}
+	}
+	return g.redistributor.list()
+}
+
 func (g *PrivateTransactionManager) Receive(data []byte) ([]byte, error) {
 	if g.isPrivateTransactionManagerNotInUse {
 		return nil, nil
@@ -51,20 +124,51 @@ func (g *PrivateTransactionManager) Receive(data []byte) ([]byte, error) {
 	if len(data) == 0 {
 		return data, nil
 	}
-	// Ignore this error since not being a recipient of
-	// a payload isn't an error.
-	// TODO: Return an error if it's anything OTHER than
-	// 'you are not a recipient.'
+	// Not being a recipient of a payload isn't an error, the payload is nil.
+	// The other failures are returned, and not cached.
 	dataStr := string(data)
 	x, found := g.c.Get(dataStr)
 	if found {
 		return x.([]byte), nil
 	}
-	pl, _ := g.node.ReceivePayload(data)
+	pl, err := g.node.ReceivePayload(data)
+	if err != nil {
+		return nil, err
+	}
 	g.c.Set(dataStr, pl, cache.DefaultExpiration)
 	return pl, nil
 }
 
+// Delete erases a payload from the private transaction manager and from the
+// cache.
+func (g *PrivateTransactionManager) Delete(data []byte) error {
+	if g.isPrivateTransactionManagerNotInUse {
+		return errPrivateTransactionManagerNotUsed
+	}
+	if err := g.node.DeletePayload(data); err != nil {
+		return err
+	}
+	g.c.Delete(string(data))
+	return nil
+}
+
+// Participants returns the public keys of the parties of a payload.
+func (g *PrivateTransactionManager) Participants(data []byte) ([]string, error) {
+	if g.isPrivateTransactionManagerNotInUse {
+		return nil, errPrivateTransactionManagerNotUsed
+	}
+	return g.node.PayloadParticipants(data)
+}
+
+// Notifications returns the notifications of available payloads numbered
+// after the given sequence number, to replay the ones missed by the node.
+func (g *PrivateTransactionManager) Notifications(since uint64) ([]Notification, error) {
+	if g.isPrivateTransactionManagerNotInUse {
+		return nil, errPrivateTransactionManagerNotUsed
+	}
+	return g.node.Notifications(since)
+}
+
 func New(path string) (*PrivateTransactionManager, error) {
 	info, err := os.Lstat(path)
 	if err != nil {
@@ -88,9 +192,13 @@ func New(path string) (*PrivateTransactionManager, error) {
 	if err != nil {
 		return nil, err
 	}
+	r := newRedistributor(n.Resend)
+
 	return &PrivateTransactionManager{
 		node:                                n,
 		c:                                   cache.New(5*time.Minute, 5*time.Minute),
+		groups:                              cache.New(privacyGroupRetention, privacyGroupRetention),
+		redistributor:                       r,
 		isPrivateTransactionManagerNotInUse: false,
 	}, nil
 }
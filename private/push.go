@@ -0,0 +1,345 @@
+package private
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/private/privatetransactionmanager"
+	"github.com/patrickmn/go-cache"
+)
+
+const (
+	// pushRetention is the time the pushed payloads are kept for, waiting for the
+	// blocks holding their transactions.
+	pushRetention = 10 * time.Minute
+
+	// maxPushSize is the maximum size of a pushed notification.
+	maxPushSize = 16 * 1024 * 1024
+
+	// maxPrefetches is the maximum number of payloads fetched concurrently for
+	// the notifications pushed without them.
+	maxPrefetches = 16
+
+	// noReplay is the replay sequence number when no replay is requested.
+	noReplay = math.MaxUint64
+)
+
+// replayRetryDelay is the time waited for before replaying the missed
+// notifications again after a failure.
+var replayRetryDelay = 5 * time.Second
+
+var (
+	errPushSecretMissing = errors.New("push endpoint secret is empty")
+
+	pushReceivedMeter = metrics.NewRegisteredMeter("ptm/push/received", nil)
+	pushReplayedMeter = metrics.NewRegisteredMeter("ptm/push/replayed", nil)
+	pushHitMeter      = metrics.NewRegisteredMeter("ptm/push/hits", nil)
+	pushForgedMeter   = metrics.NewRegisteredMeter("ptm/push/forged", nil)
+)
+
+// pushed holds the payloads announced by the private transaction manager, by
+// hash, for the execution of private transactions to skip receiving them. Only
+// the payloads received from the manager itself are kept, the pushed ones being
+// checked against them.
+var pushed = cache.New(pushRetention, pushRetention)
+
+// receivePushed wraps a private transaction manager to serve the payloads it
+// pushed, returning nil if there is no manager.
+func receivePushed(ptm PrivateTransactionManager) PrivateTransactionManager {
+	if ptm == nil {
+		return nil
+	}
+	return &pushReceiver{ptm}
+}
+
+// pushReceiver serves the pushed payloads before polling the private
+// transaction manager for the others.
+type pushReceiver struct {
+	PrivateTransactionManager
+}
+
+// pushSource returns the manager the payloads served by the push receiver
+// wrapped in a private transaction manager are received from, skipping the
+// layers transforming them, or the manager itself if there is no receiver.
+func pushSource(ptm PrivateTransactionManager) PrivateTransactionManager {
+	for inner := ptm; ; {
+		switch m := inner.(type) {
+		case *pushReceiver:
+			return m.PrivateTransactionManager
+		case *failureReporter:
+			inner = m.PrivateTransactionManager
+		case *payloadOffloader:
+			inner = m.PrivateTransactionManager
+		default:
+			return ptm
+		}
+	}
+}
+
+func (r *pushReceiver) Receive(data []byte) ([]byte, error) {
+	if payload, ok := pushed.Get(string(data)); ok {
+		pushHitMeter.Mark(1)
+		return payload.([]byte), nil
+	}
+	return r.PrivateTransactionManager.Receive(data)
+}
+
+func (r *pushReceiver) Delete(data []byte) error {
+	pushed.Delete(string(data))
+	return r.PrivateTransactionManager.Delete(data)
+}
+
+// PushConfig configures the endpoint the private transaction manager pushes the
+// notifications of available payloads to.
+type PushConfig struct {
+	Addr       string // Listening address of the endpoint
+	SecretFile string // File holding the bearer token of the manager
+	SeqFile    string // File keeping the last sequence number across restarts
+}
+
+// PushEndpoint receives the notifications of the payloads available to the
+// node, pushed by the private transaction manager on POST /notify, so that the
+// execution of private transactions doesn't wait on receiving them. A payload
+// pushed along a notification is only served once the manager returned the
+// same one, so that a leaked push secret can't alter private state.
+//
+// Notifications are numbered. A gap in the sequence numbers, left by a
+// disconnection from the manager or a restart of the node, is filled by
+// replaying the missed notifications from the manager, until it succeeds.
+type PushEndpoint struct {
+	ptm      PrivateTransactionManager // Manager the payloads are received from
+	addr     string
+	secret   []byte
+	seqFile  string
+	server   *http.Server
+	prefetch chan struct{} // Semaphore bounding the concurrent prefetches
+	quit     chan struct{}
+
+	lock       sync.Mutex
+	seq        uint64 // Highest sequence number received, 0 if none
+	replayFrom uint64 // Sequence number to replay the notifications after
+	replaying  bool
+	saved      uint64 // Sequence number last persisted
+}
+
+// NewPushEndpoint creates the push endpoint of a private transaction manager,
+// resuming from the last sequence number it received.
+func NewPushEndpoint(config PushConfig, ptm PrivateTransactionManager) (*PushEndpoint, error) {
+	if ptm == nil {
+		return nil, ErrPrivateTransactionManagerNotEnabled
+	}
+	secret, err := ioutil.ReadFile(config.SecretFile)
+	if err != nil {
+		return nil, err
+	}
+	if secret = bytes.TrimSpace(secret); len(secret) == 0 {
+		return nil, errPushSecretMissing
+	}
+	e := &PushEndpoint{
+		ptm:        pushSource(ptm),
+		addr:       config.Addr,
+		secret:     secret,
+		seqFile:    config.SeqFile,
+		prefetch:   make(chan struct{}, maxPrefetches),
+		quit:       make(chan struct{}),
+		replayFrom: noReplay,
+	}
+	if blob, err := ioutil.ReadFile(config.SeqFile); err == nil {
+		e.seq, _ = strconv.ParseUint(strings.TrimSpace(string(blob)), 10, 64)
+		e.saved = e.seq
+	}
+	return e, nil
+}
+
+// Start opens the endpoint, and replays the notifications missed since the last
+// run of the node.
+func (e *PushEndpoint) Start() error {
+	listener, err := net.Listen("tcp", e.addr)
+	if err != nil {
+		return err
+	}
+	e.server = &http.Server{Handler: e}
+	go e.server.Serve(listener)
+	log.Info("Private transaction manager push endpoint opened", "addr", listener.Addr(), "seq", e.seq)
+
+	e.lock.Lock()
+	if e.seq > 0 {
+		e.requestReplay(e.seq)
+	}
+	e.lock.Unlock()
+	return nil
+}
+
+// Stop closes the endpoint, abandoning a replay in progress.
+func (e *PushEndpoint) Stop() {
+	if e.server != nil {
+		e.server.Close()
+	}
+	e.lock.Lock()
+	select {
+	case <-e.quit:
+	default:
+		close(e.quit)
+	}
+	e.lock.Unlock()
+}
+
+// saveSeq persists the sequence number the notifications are received up to,
+// short of the ones waiting to be replayed, for the next run to resume from.
+//
+// Note, this method assumes the lock is held!
+func (e *PushEndpoint) saveSeq() {
+	seq := e.seq
+	if e.replayFrom < seq {
+		seq = e.replayFrom
+	}
+	if seq == e.saved || e.seqFile == "" {
+		return
+	}
+	if err := ioutil.WriteFile(e.seqFile, []byte(strconv.FormatUint(seq, 10)), 0600); err != nil {
+		log.Warn("Failed to save the push sequence number", "err", err)
+		return
+	}
+	e.saved = seq
+}
+
+// ServeHTTP implements http.Handler, accepting the notifications authenticated
+// by the bearer token of the manager.
+func (e *PushEndpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/notify" {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != "POST" {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if subtle.ConstantTimeCompare([]byte(token), e.secret) != 1 {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var n privatetransactionmanager.Notification
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxPushSize)).Decode(&n); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(n.Key) == 0 {
+		http.Error(w, "notification without key", http.StatusBadRequest)
+		return
+	}
+	pushReceivedMeter.Mark(1)
+	e.ingest(n)
+
+	e.lock.Lock()
+	if e.seq > 0 && n.Seq > e.seq+1 {
+		log.Debug("Missed private payload notifications", "from", e.seq+1, "to", n.Seq-1)
+		e.requestReplay(e.seq)
+	}
+	if n.Seq > e.seq {
+		e.seq = n.Seq
+	}
+	e.saveSeq()
+	e.lock.Unlock()
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// ingest fetches the payload of a notification from the manager in the
+// background, dropping the payload pushed along if the manager returns another.
+func (e *PushEndpoint) ingest(n privatetransactionmanager.Notification) {
+	if _, ok := pushed.Get(string(n.Key)); ok {
+		return
+	}
+	select {
+	case e.prefetch <- struct{}{}:
+		go func() {
+			defer func() { <-e.prefetch }()
+
+			payload, err := e.ptm.Receive(n.Key)
+			if err != nil || len(payload) == 0 {
+				return
+			}
+			if len(n.Payload) > 0 && !bytes.Equal(payload, n.Payload) {
+				pushForgedMeter.Mark(1)
+				log.Warn("Dropped pushed private payload not matching the manager", "seq", n.Seq)
+				return
+			}
+			pushed.Set(string(n.Key), payload, cache.DefaultExpiration)
+		}()
+	default:
+		// Too many fetches in flight, the payload is received on execution
+	}
+}
+
+// requestReplay schedules the replay of the notifications after the given
+// sequence number.
+//
+// Note, this method assumes the lock is held!
+func (e *PushEndpoint) requestReplay(since uint64) {
+	if since < e.replayFrom {
+		e.replayFrom = since
+	}
+	if !e.replaying {
+		e.replaying = true
+		go e.replay()
+	}
+}
+
+// replay fetches the missed notifications from the manager until no more
+// replays are requested. The replay cursor is only cleared once the manager
+// returned the notifications, a failed replay being retried.
+func (e *PushEndpoint) replay() {
+	for {
+		e.lock.Lock()
+		since := e.replayFrom
+		if since == noReplay {
+			e.replaying = false
+			e.lock.Unlock()
+			return
+		}
+		e.lock.Unlock()
+
+		notifications, err := e.ptm.Notifications(since)
+		if err != nil {
+			log.Warn("Failed to replay private payload notifications", "since", since, "err", err)
+			select {
+			case <-time.After(replayRetryDelay):
+				continue
+			case <-e.quit:
+				e.lock.Lock()
+				e.replaying = false
+				e.lock.Unlock()
+				return
+			}
+		}
+		for _, n := range notifications {
+			e.ingest(n)
+		}
+		pushReplayedMeter.Mark(int64(len(notifications)))
+
+		e.lock.Lock()
+		for _, n := range notifications {
+			if n.Seq > e.seq {
+				e.seq = n.Seq
+			}
+		}
+		if e.replayFrom == since {
+			e.replayFrom = noReplay
+		}
+		e.saveSeq()
+		e.lock.Unlock()
+	}
+}
@@ -0,0 +1,172 @@
+package private
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/private/privatetransactionmanager"
+)
+
+// notify pushes a notification to the endpoint, returning the response status.
+func notify(t *testing.T, url, token string, n privatetransactionmanager.Notification) int {
+	body, _ := json.Marshal(n)
+	req, _ := http.NewRequest("POST", url+"/notify", bytes.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+token)
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("failed to push notification: %v", err)
+	}
+	res.Body.Close()
+	return res.StatusCode
+}
+
+// waitPushed waits for a payload to be served, from the pushed ones if the
+// manager is empty.
+func waitPushed(t *testing.T, ptm PrivateTransactionManager, key string, want string) {
+	for start := time.Now(); time.Since(start) < time.Second; time.Sleep(10 * time.Millisecond) {
+		if payload, _ := ptm.Receive([]byte(key)); string(payload) == want {
+			return
+		}
+	}
+	t.Errorf("payload %q not pushed", key)
+}
+
+func TestPushEndpoint(t *testing.T) {
+	pushed.Flush()
+	dir, _ := ioutil.TempDir("", "ptm-push")
+	defer os.RemoveAll(dir)
+
+	secret := filepath.Join(dir, "secret")
+	ioutil.WriteFile(secret, []byte("token\n"), 0600)
+
+	manager := newStubManager("a")
+	manager.payloads["fetched"] = []byte("fetched payload")
+	for _, key := range []string{"1", "3", "4", "5", "6"} {
+		manager.payloads["k"+key] = []byte("p" + key)
+	}
+	manager.notifications = []privatetransactionmanager.Notification{
+		{Seq: 3, Key: []byte("k3"), Payload: []byte("p3")},
+		{Seq: 4, Key: []byte("k4"), Payload: []byte("p4")},
+	}
+	ptm, empty := receivePushed(manager), receivePushed(newStubManager("b"))
+
+	endpoint, err := NewPushEndpoint(PushConfig{SecretFile: secret, SeqFile: filepath.Join(dir, "seq")}, ptm)
+	if err != nil {
+		t.Fatalf("failed to create endpoint: %v", err)
+	}
+	server := httptest.NewServer(endpoint)
+	defer server.Close()
+
+	// Notifications are only accepted from the manager
+	if status := notify(t, server.URL, "forged", privatetransactionmanager.Notification{Seq: 1, Key: []byte("k1"), Payload: []byte("p1")}); status != http.StatusUnauthorized {
+		t.Errorf("unauthenticated push: have status %d, want %d", status, http.StatusUnauthorized)
+	}
+	if payload, _ := empty.Receive([]byte("k1")); payload != nil {
+		t.Errorf("unauthenticated payload served: %q", payload)
+	}
+	// Pushed payloads are served once checked, the others are fetched in the background
+	if status := notify(t, server.URL, "token", privatetransactionmanager.Notification{Seq: 1, Key: []byte("k1"), Payload: []byte("p1")}); status != http.StatusOK {
+		t.Fatalf("push: have status %d, want %d", status, http.StatusOK)
+	}
+	waitPushed(t, empty, "k1", "p1")
+
+	notify(t, server.URL, "token", privatetransactionmanager.Notification{Seq: 2, Key: []byte("fetched")})
+	waitPushed(t, empty, "fetched", "fetched payload")
+
+	// Missed notifications are replayed on the next one
+	notify(t, server.URL, "token", privatetransactionmanager.Notification{Seq: 5, Key: []byte("k5"), Payload: []byte("p5")})
+	waitPushed(t, empty, "k3", "p3")
+	waitPushed(t, empty, "k4", "p4")
+
+	// Payloads not matching the manager are never served
+	notify(t, server.URL, "token", privatetransactionmanager.Notification{Seq: 6, Key: []byte("k6"), Payload: []byte("forged")})
+	time.Sleep(50 * time.Millisecond)
+	if payload, _ := empty.Receive([]byte("k6")); payload != nil {
+		t.Errorf("forged payload served: %q", payload)
+	}
+	// The sequence number is kept for the next run as it advances
+	waitSeq(t, filepath.Join(dir, "seq"), "6")
+	endpoint.Stop()
+	if endpoint, err = NewPushEndpoint(PushConfig{SecretFile: secret, SeqFile: filepath.Join(dir, "seq")}, ptm); err != nil {
+		t.Fatalf("failed to create endpoint: %v", err)
+	}
+	if endpoint.seq != 6 {
+		t.Errorf("restored sequence number: have %d, want 6", endpoint.seq)
+	}
+}
+
+// failingNotifier fails to return the notifications a number of times.
+type failingNotifier struct {
+	*stubManager
+	lock  sync.Mutex
+	fails int
+}
+
+func (m *failingNotifier) Notifications(since uint64) ([]privatetransactionmanager.Notification, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if m.fails > 0 {
+		m.fails--
+		return nil, errors.New("unreachable")
+	}
+	return m.stubManager.Notifications(since)
+}
+
+// waitSeq waits for the sequence number to be persisted.
+func waitSeq(t *testing.T, file string, want string) {
+	for start := time.Now(); time.Since(start) < time.Second; time.Sleep(10 * time.Millisecond) {
+		if blob, _ := ioutil.ReadFile(file); string(blob) == want {
+			return
+		}
+	}
+	blob, _ := ioutil.ReadFile(file)
+	t.Errorf("persisted sequence number mismatch: have %q, want %q", blob, want)
+}
+
+func TestPushReplayRetry(t *testing.T) {
+	pushed.Flush()
+	defer func(delay time.Duration) { replayRetryDelay = delay }(replayRetryDelay)
+	replayRetryDelay = 10 * time.Millisecond
+
+	dir, _ := ioutil.TempDir("", "ptm-push")
+	defer os.RemoveAll(dir)
+
+	secret, seq := filepath.Join(dir, "secret"), filepath.Join(dir, "seq")
+	ioutil.WriteFile(secret, []byte("token"), 0600)
+	ioutil.WriteFile(seq, []byte("1"), 0600)
+
+	manager := &failingNotifier{stubManager: newStubManager("a"), fails: 3}
+	manager.payloads["r2"] = []byte("replayed")
+	manager.payloads["r3"] = []byte("pushed")
+	manager.notifications = []privatetransactionmanager.Notification{{Seq: 2, Key: []byte("r2")}}
+
+	endpoint, err := NewPushEndpoint(PushConfig{Addr: "127.0.0.1:0", SecretFile: secret, SeqFile: seq}, receivePushed(manager))
+	if err != nil {
+		t.Fatalf("failed to create endpoint: %v", err)
+	}
+	if err := endpoint.Start(); err != nil {
+		t.Fatalf("failed to start endpoint: %v", err)
+	}
+	defer endpoint.Stop()
+
+	// The notifications received while the replay fails don't move the
+	// persisted sequence number past the missed ones
+	server := httptest.NewServer(endpoint)
+	defer server.Close()
+	notify(t, server.URL, "token", privatetransactionmanager.Notification{Seq: 3, Key: []byte("r3")})
+	if blob, _ := ioutil.ReadFile(seq); string(blob) != "1" {
+		t.Errorf("sequence number persisted past the replay: %q", blob)
+	}
+	// The replay is retried until it succeeds
+	waitPushed(t, receivePushed(newStubManager("b")), "r2", "replayed")
+	waitSeq(t, seq, "3")
+}
@@ -0,0 +1,324 @@
+package private
+
+import (
+	"container/list"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+// Formats of the cached payloads, the first byte of their files.
+const (
+	receiveCachePlain     byte = 0
+	receiveCacheEncrypted byte = 1
+)
+
+var (
+	errReceiveCacheFormat = errors.New("cached private payload in another format")
+
+	receiveCacheHitMeter     = metrics.NewRegisteredMeter("ptm/cache/hits", nil)
+	receiveCacheMissMeter    = metrics.NewRegisteredMeter("ptm/cache/misses", nil)
+	receiveCacheEvictedMeter = metrics.NewRegisteredMeter("ptm/cache/evicted", nil)
+	receiveCacheSizeGauge    = metrics.NewRegisteredGauge("ptm/cache/size", nil)
+)
+
+// ReceiveCacheConfig configures the on-disk cache of the received payloads.
+type ReceiveCacheConfig struct {
+	Dir     string        // Directory holding the cached payloads
+	MaxSize int64         // Size in bytes of the cached payloads, from which the least recently used are evicted
+	TTL     time.Duration // Time a payload is cached for after being received (0 = no expiry)
+	Key     []byte        // AES-256 key encrypting the payloads at rest, nil to store them as is
+}
+
+// ReceiveCache keeps the decrypted private payloads on disk, for the
+// re-execution of blocks, the tracing of transactions and the calls of private
+// contracts not to retrieve the same payloads from the private transaction
+// manager over and over. The payloads are evicted after their TTL, or as least
+// recently used once the cache is full.
+type ReceiveCache struct {
+	dir     string
+	maxSize int64
+	ttl     time.Duration
+	aead    cipher.AEAD // Cipher of the payloads at rest, nil if stored as is
+
+	lock    sync.Mutex
+	lru     *list.List               // Cached payloads, the most recently used first
+	entries map[string]*list.Element // Cached payloads by file name
+	size    int64
+}
+
+// receiveCacheEntry is a cached payload.
+type receiveCacheEntry struct {
+	name   string
+	size   int64
+	stored time.Time
+}
+
+// NewReceiveCache opens the cache of the received payloads, indexing those
+// cached by the previous runs of the node.
+func NewReceiveCache(config ReceiveCacheConfig) (*ReceiveCache, error) {
+	if err := os.MkdirAll(config.Dir, 0700); err != nil {
+		return nil, err
+	}
+	c := &ReceiveCache{
+		dir:     config.Dir,
+		maxSize: config.MaxSize,
+		ttl:     config.TTL,
+		lru:     list.New(),
+		entries: make(map[string]*list.Element),
+	}
+	if config.Key != nil {
+		block, err := aes.NewCipher(config.Key)
+		if err != nil {
+			return nil, err
+		}
+		if c.aead, err = cipher.NewGCM(block); err != nil {
+			return nil, err
+		}
+	}
+	files, err := ioutil.ReadDir(config.Dir)
+	if err != nil {
+		return nil, err
+	}
+	// Index the payloads from the oldest, by the time they were cached
+	sort.Slice(files, func(i, j int) bool { return files[i].ModTime().Before(files[j].ModTime()) })
+	for _, file := range files {
+		if !file.Mode().IsRegular() {
+			continue
+		}
+		if strings.HasSuffix(file.Name(), ".tmp") {
+			os.Remove(filepath.Join(config.Dir, file.Name()))
+			continue
+		}
+		entry := &receiveCacheEntry{name: file.Name(), size: file.Size(), stored: file.ModTime()}
+		c.entries[entry.name] = c.lru.PushFront(entry)
+		c.size += entry.size
+	}
+	c.lock.Lock()
+	c.evict(time.Now())
+	c.lock.Unlock()
+
+	log.Info("Opened private payload cache", "dir", config.Dir, "payloads", c.lru.Len(), "size", c.size, "encrypted", c.aead != nil)
+	return c, nil
+}
+
+// receiveCacheName returns the name of the file caching the payload of a hash.
+func receiveCacheName(hash []byte) string {
+	return hex.EncodeToString(crypto.Keccak256(hash))
+}
+
+// Get returns the cached payload of a hash, if any and not expired.
+func (c *ReceiveCache) Get(hash []byte) ([]byte, bool) {
+	name := receiveCacheName(hash)
+
+	c.lock.Lock()
+	elem, ok := c.entries[name]
+	if ok && c.expired(elem.Value.(*receiveCacheEntry), time.Now()) {
+		c.remove(elem)
+		ok = false
+	}
+	if ok {
+		c.lru.MoveToFront(elem)
+	}
+	c.lock.Unlock()
+
+	if !ok {
+		return nil, false
+	}
+	blob, err := ioutil.ReadFile(filepath.Join(c.dir, name))
+	if err == nil {
+		blob, err = c.open(name, blob)
+	}
+	if err != nil {
+		log.Debug("Dropping unreadable cached private payload", "name", name, "err", err)
+		c.Delete(hash)
+		return nil, false
+	}
+	return blob, true
+}
+
+// Put caches the payload of a hash, evicting the least recently used payloads
+// over the size of the cache.
+func (c *ReceiveCache) Put(hash []byte, payload []byte) error {
+	name := receiveCacheName(hash)
+	blob, err := c.seal(name, payload)
+	if err != nil {
+		return err
+	}
+	// Write to a temporary file first, for readers to never see a partial payload
+	tmp := filepath.Join(c.dir, name+".tmp")
+	if err := ioutil.WriteFile(tmp, blob, 0600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, filepath.Join(c.dir, name)); err != nil {
+		return err
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if elem, ok := c.entries[name]; ok {
+		c.lru.Remove(elem)
+		c.size -= elem.Value.(*receiveCacheEntry).size
+	}
+	entry := &receiveCacheEntry{name: name, size: int64(len(blob)), stored: time.Now()}
+	c.entries[name] = c.lru.PushFront(entry)
+	c.size += entry.size
+	c.evict(entry.stored)
+	return nil
+}
+
+// Delete drops the cached payload of a hash.
+func (c *ReceiveCache) Delete(hash []byte) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if elem, ok := c.entries[receiveCacheName(hash)]; ok {
+		c.remove(elem)
+	}
+}
+
+// expired returns whether a cached payload is past its TTL.
+func (c *ReceiveCache) expired(entry *receiveCacheEntry, now time.Time) bool {
+	return c.ttl > 0 && now.Sub(entry.stored) > c.ttl
+}
+
+// evict drops the expired payloads, and the least recently used ones over the
+// size of the cache. The lock must be held.
+func (c *ReceiveCache) evict(now time.Time) {
+	for elem := c.lru.Back(); elem != nil; {
+		prev := elem.Prev()
+		if c.size > c.maxSize || c.expired(elem.Value.(*receiveCacheEntry), now) {
+			c.remove(elem)
+			receiveCacheEvictedMeter.Mark(1)
+		}
+		elem = prev
+	}
+	receiveCacheSizeGauge.Update(c.size)
+}
+
+// remove drops a cached payload and its file. The lock must be held.
+func (c *ReceiveCache) remove(elem *list.Element) {
+	entry := c.lru.Remove(elem).(*receiveCacheEntry)
+	delete(c.entries, entry.name)
+	c.size -= entry.size
+
+	if err := os.Remove(filepath.Join(c.dir, entry.name)); err != nil && !os.IsNotExist(err) {
+		log.Warn("Failed to remove cached private payload", "name", entry.name, "err", err)
+	}
+}
+
+// seal encodes a payload for its file, encrypted if the cache has a key. The
+// name is authenticated along, for a file not to be swapped for another.
+func (c *ReceiveCache) seal(name string, payload []byte) ([]byte, error) {
+	if c.aead == nil {
+		return append([]byte{receiveCachePlain}, payload...), nil
+	}
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	blob := append([]byte{receiveCacheEncrypted}, nonce...)
+	return c.aead.Seal(blob, nonce, payload, []byte(name)), nil
+}
+
+// open decodes the file of a cached payload, rejecting those in another format
+// than that of the cache.
+func (c *ReceiveCache) open(name string, blob []byte) ([]byte, error) {
+	if c.aead == nil {
+		if len(blob) == 0 || blob[0] != receiveCachePlain {
+			return nil, errReceiveCacheFormat
+		}
+		return blob[1:], nil
+	}
+	if len(blob) < 1+c.aead.NonceSize() || blob[0] != receiveCacheEncrypted {
+		return nil, errReceiveCacheFormat
+	}
+	nonce, sealed := blob[1:1+c.aead.NonceSize()], blob[1+c.aead.NonceSize():]
+	return c.aead.Open(nil, nonce, sealed, []byte(name))
+}
+
+// cacheReceives wraps a private transaction manager to serve the payloads from
+// the cache, returning nil if there is no manager.
+func cacheReceives(ptm PrivateTransactionManager, cache *ReceiveCache) PrivateTransactionManager {
+	if ptm == nil {
+		return nil
+	}
+	return &receiveCacher{PrivateTransactionManager: ptm, cache: cache}
+}
+
+// CacheReceives wraps the private transaction manager to keep the payloads it
+// serves in the given cache.
+func CacheReceives(cache *ReceiveCache) {
+	P = cacheReceives(P, cache)
+}
+
+// receiveCacher serves the cached payloads before receiving the others from the
+// private transaction manager. The payloads sent are cached too, the node
+// executing the transactions it submits.
+type receiveCacher struct {
+	PrivateTransactionManager
+	cache *ReceiveCache
+}
+
+// store caches a payload, failures only costing a later receive.
+func (r *receiveCacher) store(hash, payload []byte) {
+	if len(hash) == 0 || len(payload) == 0 {
+		return
+	}
+	if err := r.cache.Put(hash, payload); err != nil {
+		log.Warn("Failed to cache private payload", "err", err)
+	}
+}
+
+func (r *receiveCacher) Send(data []byte, from string, to []string) ([]byte, error) {
+	out, err := r.PrivateTransactionManager.Send(data, from, to)
+	if err == nil {
+		r.store(out, data)
+	}
+	return out, err
+}
+
+func (r *receiveCacher) StoreRaw(data []byte, from string) ([]byte, error) {
+	out, err := r.PrivateTransactionManager.StoreRaw(data, from)
+	if err == nil {
+		r.store(out, data)
+	}
+	return out, err
+}
+
+// Receive serves a payload from the cache, or else from the private transaction
+// manager. The payloads the node isn't a party to aren't cached, not being told
+// apart from the ones the manager failed to serve.
+func (r *receiveCacher) Receive(data []byte) ([]byte, error) {
+	if len(data) == 0 {
+		return r.PrivateTransactionManager.Receive(data)
+	}
+	if payload, ok := r.cache.Get(data); ok {
+		receiveCacheHitMeter.Mark(1)
+		return payload, nil
+	}
+	receiveCacheMissMeter.Mark(1)
+	payload, err := r.PrivateTransactionManager.Receive(data)
+	if err == nil {
+		r.store(data, payload)
+	}
+	return payload, err
+}
+
+func (r *receiveCacher) Delete(data []byte) error {
+	r.cache.Delete(data)
+	return r.PrivateTransactionManager.Delete(data)
+}
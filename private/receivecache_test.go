@@ -0,0 +1,124 @@
+package private
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Tests that the received and sent payloads are served from the cache, stored
+// encrypted if the cache has a key, and that the cache is reloaded on restart.
+func TestReceiveCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "receivecache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	key := crypto.Keccak256([]byte("node key"))
+	cache, err := NewReceiveCache(ReceiveCacheConfig{Dir: dir, MaxSize: 1024 * 1024, Key: key})
+	if err != nil {
+		t.Fatalf("failed to open cache: %v", err)
+	}
+	manager := newStubManager("a")
+	ptm := cacheReceives(manager, cache)
+
+	sent := []byte("sent payload")
+	hash, err := ptm.Send(sent, "", nil)
+	if err != nil {
+		t.Fatalf("failed to send payload: %v", err)
+	}
+	received, _ := manager.StoreRaw([]byte("received payload"), "")
+
+	// Both payloads are served from the cache once the manager lost them
+	if payload, err := ptm.Receive(received); err != nil || string(payload) != "received payload" {
+		t.Fatalf("received payload mismatch: have %q, %v", payload, err)
+	}
+	manager.payloads = make(map[string][]byte)
+	if payload, err := ptm.Receive(hash); err != nil || !bytes.Equal(payload, sent) {
+		t.Fatalf("sent payload mismatch: have %q, %v", payload, err)
+	}
+	if payload, err := ptm.Receive(received); err != nil || string(payload) != "received payload" {
+		t.Fatalf("cached payload mismatch: have %q, %v", payload, err)
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "*"))
+	if len(files) != 2 {
+		t.Fatalf("cached file count mismatch: have %d, want 2", len(files))
+	}
+	for _, file := range files {
+		if blob, _ := ioutil.ReadFile(file); bytes.Contains(blob, []byte("payload")) {
+			t.Fatalf("payload cached in the clear")
+		}
+	}
+	// The cache is reloaded with the same key, but not read without it
+	reopened, err := NewReceiveCache(ReceiveCacheConfig{Dir: dir, MaxSize: 1024 * 1024, Key: key})
+	if err != nil {
+		t.Fatalf("failed to reopen cache: %v", err)
+	}
+	if payload, ok := reopened.Get(hash); !ok || !bytes.Equal(payload, sent) {
+		t.Fatalf("reloaded payload mismatch: have %q, %v", payload, ok)
+	}
+	plain, err := NewReceiveCache(ReceiveCacheConfig{Dir: dir, MaxSize: 1024 * 1024})
+	if err != nil {
+		t.Fatalf("failed to reopen cache: %v", err)
+	}
+	if payload, ok := plain.Get(hash); ok {
+		t.Fatalf("encrypted payload served without key: %q", payload)
+	}
+	// Deleting a payload drops it from the cache
+	if err := ptm.Delete(received); err != nil {
+		t.Fatalf("failed to delete payload: %v", err)
+	}
+	if payload, _ := ptm.Receive(received); payload != nil {
+		t.Fatalf("deleted payload served: %q", payload)
+	}
+}
+
+// Tests that the least recently used payloads are evicted over the size of the
+// cache, and the payloads past their TTL on access.
+func TestReceiveCacheEviction(t *testing.T) {
+	dir, err := ioutil.TempDir("", "receivecache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Every payload takes 101 bytes on disk, the cache holding 3 of them
+	cache, err := NewReceiveCache(ReceiveCacheConfig{Dir: dir, MaxSize: 350, TTL: time.Hour})
+	if err != nil {
+		t.Fatalf("failed to open cache: %v", err)
+	}
+	payload := make([]byte, 100)
+	for i := byte(0); i < 3; i++ {
+		if err := cache.Put([]byte{i}, payload); err != nil {
+			t.Fatalf("failed to cache payload %d: %v", i, err)
+		}
+	}
+	if _, ok := cache.Get([]byte{0}); !ok {
+		t.Fatalf("payload 0 not cached")
+	}
+	if err := cache.Put([]byte{3}, payload); err != nil {
+		t.Fatalf("failed to cache payload 3: %v", err)
+	}
+	for i, want := range []bool{true, false, true, true} {
+		if _, ok := cache.Get([]byte{byte(i)}); ok != want {
+			t.Errorf("payload %d: cached mismatch: have %v, want %v", i, ok, want)
+		}
+	}
+	// Expire a payload by backdating it
+	cache.lock.Lock()
+	cache.entries[receiveCacheName([]byte{3})].Value.(*receiveCacheEntry).stored = time.Now().Add(-2 * time.Hour)
+	cache.lock.Unlock()
+
+	if _, ok := cache.Get([]byte{3}); ok {
+		t.Errorf("expired payload served")
+	}
+	if files, _ := filepath.Glob(filepath.Join(dir, "*")); len(files) != 2 {
+		t.Errorf("cached file count mismatch: have %d, want 2", len(files))
+	}
+}
@@ -0,0 +1,395 @@
+package private
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/private/privatetransactionmanager"
+	"github.com/patrickmn/go-cache"
+)
+
+// groupOwnerPrefix prefixes the IDs of the privacy groups in the managers known
+// to hold them, apart from the payload hashes.
+const groupOwnerPrefix = "group-"
+
+var (
+	errNoDefaultManager     = errors.New("no private transaction manager matches the transaction and none is the default")
+	errReplayAcrossManagers = errors.New("notifications can't be replayed across several private transaction managers")
+)
+
+// RouterConfig configures several private transaction managers, so that a
+// single node can serve parties relying on separate privacy infrastructure.
+type RouterConfig struct {
+	Managers []ManagerConfig `toml:"manager"`
+}
+
+// ManagerConfig is a private transaction manager along with the rules routing
+// private transactions to it. A transaction is routed by its sender key if it
+// has one, by its recipients otherwise.
+type ManagerConfig struct {
+	Name       string   `toml:"name"`
+	Config     string   `toml:"config"`     // Socket or configuration file of the manager
+	Keys       []string `toml:"keys"`       // Public keys hosted by the manager
+	Recipients []string `toml:"recipients"` // Members of the privacy groups served by the manager
+	Default    bool     `toml:"default"`    // Whether transactions matching no rule are routed to the manager
+}
+
+// LoadRouterConfig reads a routing configuration file. It returns no managers
+// for the configuration file of a single private transaction manager.
+func LoadRouterConfig(path string) (*RouterConfig, error) {
+	cfg := new(RouterConfig)
+	if _, err := toml.DecodeFile(path, cfg); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// route is a private transaction manager with its routing rules.
+type route struct {
+	name       string
+	manager    PrivateTransactionManager
+	keys       map[string]bool
+	recipients map[string]bool
+}
+
+// Router dispatches private transactions across several private transaction
+// managers.
+type Router struct {
+	routes []*route
+	deflt  *route
+	owners *cache.Cache // manager name of the payloads sent or stored and of the privacy groups, by hash or ID
+}
+
+// NewRouter connects to the configured private transaction managers.
+func NewRouter(cfg *RouterConfig) (*Router, error) {
+	managers := make(map[string]PrivateTransactionManager)
+	for _, m := range cfg.Managers {
+		manager, err := privatetransactionmanager.New(m.Config)
+		if err != nil {
+			return nil, fmt.Errorf("private transaction manager %q: %v", m.Name, err)
+		}
+		managers[m.Name] = manager
+	}
+	return newRouter(cfg, managers)
+}
+
+// newRouter creates a router across the given managers, by name.
+func newRouter(cfg *RouterConfig, managers map[string]PrivateTransactionManager) (*Router, error) {
+	r := &Router{owners: cache.New(5*time.Minute, 5*time.Minute)}
+	names := make(map[string]bool)
+	for _, m := range cfg.Managers {
+		if m.Name == "" {
+			return nil, errors.New("private transaction manager without name")
+		}
+		if names[m.Name] {
+			return nil, fmt.Errorf("duplicate private transaction manager %q", m.Name)
+		}
+		names[m.Name] = true
+		rt := &route{
+			name:       m.Name,
+			manager:    managers[m.Name],
+			keys:       make(map[string]bool),
+			recipients: make(map[string]bool),
+		}
+		for _, key := range m.Keys {
+			rt.keys[key] = true
+		}
+		for _, key := range m.Recipients {
+			rt.recipients[key] = true
+		}
+		if m.Default {
+			if r.deflt != nil {
+				return nil, fmt.Errorf("private transaction managers %q and %q are both the default", r.deflt.name, m.Name)
+			}
+			r.deflt = rt
+		}
+		r.routes = append(r.routes, rt)
+	}
+	return r, nil
+}
+
+// MustNewRouter connects to the configured private transaction managers, or
+// panics.
+func MustNewRouter(cfg *RouterConfig) *Router {
+	r, err := NewRouter(cfg)
+	if err != nil {
+		panic(fmt.Sprintf("MustNewRouter: Failed to connect to private transaction managers: %v", err))
+	}
+	return r
+}
+
+// route selects the manager of a transaction: the one hosting the sender key,
+// or else the first one serving a recipient, or else the default one.
+func (r *Router) route(from string, to []string) (*route, error) {
+	if from != "" {
+		for _, rt := range r.routes {
+			if rt.keys[from] {
+				return rt, nil
+			}
+		}
+	} else {
+		for _, rt := range r.routes {
+			for _, key := range to {
+				if rt.recipients[key] {
+					return rt, nil
+				}
+			}
+		}
+	}
+	if r.deflt == nil {
+		return nil, errNoDefaultManager
+	}
+	return r.deflt, nil
+}
+
+// owner returns the manager which sent or stored a payload, if known.
+func (r *Router) owner(hash []byte) *route {
+	if name, ok := r.owners.Get(string(hash)); ok {
+		for _, rt := range r.routes {
+			if rt.name == name.(string) {
+				return rt
+			}
+		}
+	}
+	return nil
+}
+
+func (r *Router) Send(data []byte, from string, to []string) ([]byte, error) {
+	rt, err := r.route(from, to)
+	if err != nil {
+		return nil, err
+	}
+	out, err := rt.manager.Send(data, from, to)
+	if err != nil {
+		return nil, err
+	}
+	log.Debug("Routed private payload", "manager", rt.name, "hash", fmt.Sprintf("0x%x", out))
+	r.owners.Set(string(out), rt.name, cache.DefaultExpiration)
+	return out, nil
+}
+
+func (r *Router) SendSignedTx(data []byte, to []string) ([]byte, error) {
+	rt := r.owner(data)
+	if rt == nil {
+		var err error
+		if rt, err = r.route("", to); err != nil {
+			return nil, err
+		}
+	}
+	return rt.manager.SendSignedTx(data, to)
+}
+
+func (r *Router) StoreRaw(data []byte, from string) ([]byte, error) {
+	rt, err := r.route(from, nil)
+	if err != nil {
+		return nil, err
+	}
+	out, err := rt.manager.StoreRaw(data, from)
+	if err != nil {
+		return nil, err
+	}
+	r.owners.Set(string(out), rt.name, cache.DefaultExpiration)
+	return out, nil
+}
+
+// Receive retrieves a payload from the manager which sent it, or else from the
+// first manager it is known to. If none knows it and a manager failed, the
+// failure is returned, the payload possibly being held by that manager.
+func (r *Router) Receive(data []byte) ([]byte, error) {
+	if len(data) == 0 {
+		return data, nil
+	}
+	if rt := r.owner(data); rt != nil {
+		return rt.manager.Receive(data)
+	}
+	var lastErr error
+	for _, rt := range r.routes {
+		pl, err := rt.manager.Receive(data)
+		if err == nil && len(pl) > 0 {
+			return pl, nil
+		}
+		if err != nil {
+			lastErr = err
+		}
+	}
+	return nil, lastErr
+}
+
+// Participants returns the parties of a payload from the manager which sent
+// it, or else from the first manager it is known to.
+func (r *Router) Participants(data []byte) ([]string, error) {
+	if rt := r.owner(data); rt != nil {
+		return rt.manager.Participants(data)
+	}
+	var lastErr error
+	for _, rt := range r.routes {
+		participants, err := rt.manager.Participants(data)
+		if err == nil && len(participants) > 0 {
+			return participants, nil
+		}
+		lastErr = err
+	}
+	if lastErr != nil {
+		return nil, lastErr
+	}
+	return nil, nil
+}
+
+// Delete erases a payload from every manager holding it.
+func (r *Router) Delete(data []byte) error {
+	var (
+		deleted bool
+		lastErr error
+	)
+	for _, rt := range r.routes {
+		if err := rt.manager.Delete(data); err != nil {
+			lastErr = err
+			continue
+		}
+		deleted = true
+	}
+	if !deleted && lastErr != nil {
+		return lastErr
+	}
+	r.owners.Delete(string(data))
+	return nil
+}
+
+func (r *Router) PendingDistributions() []privatetransactionmanager.PendingDistribution {
+	pending := []privatetransactionmanager.PendingDistribution{}
+	for _, rt := range r.routes {
+		pending = append(pending, rt.manager.PendingDistributions()...)
+	}
+	return pending
+}
+
+// Close closes all the managers routed to.
+func (r *Router) Close() error {
+	var lastErr error
+	for _, rt := range r.routes {
+		if err := rt.manager.Close(); err != nil {
+			lastErr = err
+		}
+	}
+	return lastErr
+}
+
+// Notifications replays the notifications of the single manager routed to.
+// Managers number their notifications independently, so they can't be replayed
+// from a single sequence number across several managers.
+func (r *Router) Notifications(since uint64) ([]privatetransactionmanager.Notification, error) {
+	if len(r.routes) != 1 {
+		return nil, errReplayAcrossManagers
+	}
+	return r.routes[0].manager.Notifications(since)
+}
+
+// groupRoute selects the manager of a privacy group: the one hosting the key
+// managing the group, or else the one which created or resolved it, or else
+// the default one.
+func (r *Router) groupRoute(from, id string) (*route, error) {
+	if from == "" && id != "" {
+		if rt := r.owner([]byte(groupOwnerPrefix + id)); rt != nil {
+			return rt, nil
+		}
+	}
+	return r.route(from, nil)
+}
+
+func (r *Router) CreatePrivacyGroup(from, name, description string, members []string) (*privatetransactionmanager.PrivacyGroup, error) {
+	rt, err := r.route(from, nil)
+	if err != nil {
+		return nil, err
+	}
+	group, err := rt.manager.CreatePrivacyGroup(from, name, description, members)
+	if err != nil {
+		return nil, err
+	}
+	r.owners.Set(groupOwnerPrefix+group.PrivacyGroupId, rt.name, cache.DefaultExpiration)
+	return group, nil
+}
+
+// FindPrivacyGroups returns the groups of the given members known to any of
+// the managers.
+func (r *Router) FindPrivacyGroups(members []string) ([]*privatetransactionmanager.PrivacyGroup, error) {
+	var (
+		groups  = []*privatetransactionmanager.PrivacyGroup{}
+		found   bool
+		lastErr error
+	)
+	for _, rt := range r.routes {
+		list, err := rt.manager.FindPrivacyGroups(members)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		found = true
+		groups = append(groups, list...)
+	}
+	if !found && lastErr != nil {
+		return nil, lastErr
+	}
+	return groups, nil
+}
+
+// RetrievePrivacyGroup resolves a group from the manager known to hold it, or
+// else from the first one knowing it.
+func (r *Router) RetrievePrivacyGroup(id string) (*privatetransactionmanager.PrivacyGroup, error) {
+	return r.privacyGroup(id, PrivateTransactionManager.RetrievePrivacyGroup)
+}
+
+// ResolvePrivacyGroup resolves a group for a send, bypassing the cache of the
+// manager holding it.
+func (r *Router) ResolvePrivacyGroup(id string) (*privatetransactionmanager.PrivacyGroup, error) {
+	return r.privacyGroup(id, PrivateTransactionManager.ResolvePrivacyGroup)
+}
+
+// privacyGroup looks a group up with the given method of the manager known to
+// hold it, or else of the first one knowing it.
+func (r *Router) privacyGroup(id string, lookup func(PrivateTransactionManager, string) (*privatetransactionmanager.PrivacyGroup, error)) (*privatetransactionmanager.PrivacyGroup, error) {
+	if rt := r.owner([]byte(groupOwnerPrefix + id)); rt != nil {
+		return lookup(rt.manager, id)
+	}
+	var lastErr error
+	for _, rt := range r.routes {
+		group, err := lookup(rt.manager, id)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		r.owners.Set(groupOwnerPrefix+id, rt.name, cache.DefaultExpiration)
+		return group, nil
+	}
+	return nil, lastErr
+}
+
+func (r *Router) AddToPrivacyGroup(from, id string, members []string) (*privatetransactionmanager.PrivacyGroup, error) {
+	rt, err := r.groupRoute(from, id)
+	if err != nil {
+		return nil, err
+	}
+	return rt.manager.AddToPrivacyGroup(from, id, members)
+}
+
+func (r *Router) RemoveFromPrivacyGroup(from, id string, members []string) (*privatetransactionmanager.PrivacyGroup, error) {
+	rt, err := r.groupRoute(from, id)
+	if err != nil {
+		return nil, err
+	}
+	return rt.manager.RemoveFromPrivacyGroup(from, id, members)
+}
+
+func (r *Router) DeletePrivacyGroup(from, id string) error {
+	rt, err := r.groupRoute(from, id)
+	if err != nil {
+		return err
+	}
+	if err := rt.manager.DeletePrivacyGroup(from, id); err != nil {
+		return err
+	}
+	r.owners.Delete(groupOwnerPrefix + id)
+	return nil
+}
@@ -0,0 +1,259 @@
+package private
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/private/privatetransactionmanager"
+)
+
+// stubManager stores payloads in memory under its name followed by the payload.
+type stubManager struct {
+	name          string
+	payloads      map[string][]byte
+	groups        map[string]*privatetransactionmanager.PrivacyGroup
+	notifications []privatetransactionmanager.Notification
+	receiveErr    error // Failure of the receive calls, if set
+}
+
+func newStubManager(name string) *stubManager {
+	return &stubManager{
+		name:     name,
+		payloads: make(map[string][]byte),
+		groups:   make(map[string]*privatetransactionmanager.PrivacyGroup),
+	}
+}
+
+func (m *stubManager) Send(data []byte, from string, to []string) ([]byte, error) {
+	return m.StoreRaw(data, from)
+}
+
+func (m *stubManager) SendSignedTx(data []byte, to []string) ([]byte, error) {
+	if _, ok := m.payloads[string(data)]; !ok {
+		return nil, nil
+	}
+	return data, nil
+}
+
+func (m *stubManager) StoreRaw(data []byte, from string) ([]byte, error) {
+	hash := append([]byte(m.name), data...)
+	m.payloads[string(hash)] = data
+	return hash, nil
+}
+
+func (m *stubManager) Receive(data []byte) ([]byte, error) {
+	if m.receiveErr != nil {
+		return nil, m.receiveErr
+	}
+	return m.payloads[string(data)], nil
+}
+
+func (m *stubManager) Participants(data []byte) ([]string, error) {
+	if _, ok := m.payloads[string(data)]; !ok {
+		return nil, nil
+	}
+	return []string{m.name}, nil
+}
+
+func (m *stubManager) Delete(data []byte) error {
+	delete(m.payloads, string(data))
+	return nil
+}
+
+func (m *stubManager) PendingDistributions() []privatetransactionmanager.PendingDistribution {
+	return nil
+}
+
+func (m *stubManager) Close() error {
+	return nil
+}
+
+func (m *stubManager) Notifications(since uint64) ([]privatetransactionmanager.Notification, error) {
+	var notifications []privatetransactionmanager.Notification
+	for _, n := range m.notifications {
+		if n.Seq > since {
+			notifications = append(notifications, n)
+		}
+	}
+	return notifications, nil
+}
+
+func (m *stubManager) CreatePrivacyGroup(from, name, description string, members []string) (*privatetransactionmanager.PrivacyGroup, error) {
+	group := &privatetransactionmanager.PrivacyGroup{PrivacyGroupId: m.name + name, Name: name, Members: members}
+	m.groups[group.PrivacyGroupId] = group
+	return group, nil
+}
+
+func (m *stubManager) FindPrivacyGroups(members []string) ([]*privatetransactionmanager.PrivacyGroup, error) {
+	var groups []*privatetransactionmanager.PrivacyGroup
+	for _, group := range m.groups {
+		if reflect.DeepEqual(group.Members, members) {
+			groups = append(groups, group)
+		}
+	}
+	return groups, nil
+}
+
+func (m *stubManager) RetrievePrivacyGroup(id string) (*privatetransactionmanager.PrivacyGroup, error) {
+	if group, ok := m.groups[id]; ok {
+		return group, nil
+	}
+	return nil, errors.New("privacy group not found")
+}
+
+func (m *stubManager) ResolvePrivacyGroup(id string) (*privatetransactionmanager.PrivacyGroup, error) {
+	return m.RetrievePrivacyGroup(id)
+}
+
+func (m *stubManager) AddToPrivacyGroup(from, id string, members []string) (*privatetransactionmanager.PrivacyGroup, error) {
+	group, err := m.RetrievePrivacyGroup(id)
+	if err != nil {
+		return nil, err
+	}
+	group.Members = append(group.Members, members...)
+	return group, nil
+}
+
+func (m *stubManager) RemoveFromPrivacyGroup(from, id string, members []string) (*privatetransactionmanager.PrivacyGroup, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (m *stubManager) DeletePrivacyGroup(from, id string) error {
+	if _, ok := m.groups[id]; !ok {
+		return errors.New("privacy group not found")
+	}
+	delete(m.groups, id)
+	return nil
+}
+
+func TestRouter(t *testing.T) {
+	a, b := newStubManager("a"), newStubManager("b")
+	router, err := newRouter(&RouterConfig{Managers: []ManagerConfig{
+		{Name: "a", Keys: []string{"keyA"}, Default: true},
+		{Name: "b", Keys: []string{"keyB"}, Recipients: []string{"groupB1", "groupB2"}},
+	}}, map[string]PrivateTransactionManager{"a": a, "b": b})
+	if err != nil {
+		t.Fatalf("failed to create router: %v", err)
+	}
+	tests := []struct {
+		from  string
+		to    []string
+		owner string
+	}{
+		{"keyB", []string{"other"}, "b"},   // by sender
+		{"keyA", []string{"groupB1"}, "a"}, // sender before recipients
+		{"", []string{"other", "groupB2"}, "b"},
+		{"", []string{"other"}, "a"},        // default
+		{"unknown", []string{"other"}, "a"}, // default
+	}
+	for i, tt := range tests {
+		payload := []byte{byte(i)}
+		hash, err := router.Send(payload, tt.from, tt.to)
+		if err != nil {
+			t.Fatalf("test %d: failed to send: %v", i, err)
+		}
+		if owner := string(hash[:1]); owner != tt.owner {
+			t.Errorf("test %d: routed to %s, want %s", i, owner, tt.owner)
+		}
+		if pl, _ := router.Receive(hash); !bytes.Equal(pl, payload) {
+			t.Errorf("test %d: received %x, want %x", i, pl, payload)
+		}
+	}
+	// Payloads sent by another node are looked up in all managers
+	hash, _ := b.StoreRaw([]byte("remote"), "")
+	fresh, _ := newRouter(&RouterConfig{Managers: []ManagerConfig{{Name: "a"}, {Name: "b"}}}, map[string]PrivateTransactionManager{"a": a, "b": b})
+	if pl, _ := fresh.Receive(hash); string(pl) != "remote" {
+		t.Errorf("remote payload mismatch: have %q", pl)
+	}
+	// Transactions matching no rule need a default manager
+	if _, err := fresh.Send([]byte("x"), "", nil); err != errNoDefaultManager {
+		t.Errorf("error mismatch: have %v, want %v", err, errNoDefaultManager)
+	}
+	// Raw payloads are distributed by the manager storing them
+	hash, _ = router.StoreRaw([]byte("raw"), "keyB")
+	if out, _ := router.SendSignedTx(hash, []string{"other"}); !bytes.Equal(out, hash) {
+		t.Errorf("signed transaction not sent by the storing manager")
+	}
+	// Purged payloads are erased from the managers holding them
+	if err := fresh.Delete(hash); err != nil {
+		t.Fatalf("failed to delete payload: %v", err)
+	}
+	if pl, _ := router.Receive(hash); pl != nil {
+		t.Errorf("deleted payload still received: %q", pl)
+	}
+}
+
+// Tests that the failures of the managers to serve a payload are returned, and
+// reported to the failure subscribers.
+func TestRouterReceiveFailure(t *testing.T) {
+	a, b := newStubManager("a"), newStubManager("b")
+	a.receiveErr = errors.New("manager down")
+	router, err := newRouter(&RouterConfig{Managers: []ManagerConfig{{Name: "a"}, {Name: "b"}}}, map[string]PrivateTransactionManager{"a": a, "b": b})
+	if err != nil {
+		t.Fatalf("failed to create router: %v", err)
+	}
+	// A payload served by another manager hides the failure
+	hash, _ := b.StoreRaw([]byte("remote"), "")
+	if pl, err := router.Receive(hash); err != nil || string(pl) != "remote" {
+		t.Errorf("remote payload mismatch: have %q, %v", pl, err)
+	}
+	// A payload known to no manager reports the failure
+	failures := make(chan FailureEvent, 1)
+	sub := SubscribeFailureEvent(failures)
+	defer sub.Unsubscribe()
+
+	if _, err := reportFailures(router).Receive([]byte("unknown")); err != a.receiveErr {
+		t.Fatalf("error mismatch: have %v, want %v", err, a.receiveErr)
+	}
+	select {
+	case failure := <-failures:
+		if failure.Op != "receive" || failure.Err != a.receiveErr {
+			t.Errorf("failure mismatch: have %s %v", failure.Op, failure.Err)
+		}
+	default:
+		t.Errorf("receive failure not reported")
+	}
+}
+
+func TestRouterPrivacyGroups(t *testing.T) {
+	a, b := newStubManager("a"), newStubManager("b")
+	router, err := newRouter(&RouterConfig{Managers: []ManagerConfig{
+		{Name: "a", Keys: []string{"keyA"}, Default: true},
+		{Name: "b", Keys: []string{"keyB"}},
+	}}, map[string]PrivateTransactionManager{"a": a, "b": b})
+	if err != nil {
+		t.Fatalf("failed to create router: %v", err)
+	}
+	// Groups are created by the manager hosting the managing key
+	group, err := router.CreatePrivacyGroup("keyB", "ops", "", []string{"keyB", "other"})
+	if err != nil {
+		t.Fatalf("failed to create group: %v", err)
+	}
+	if group.PrivacyGroupId != "bops" {
+		t.Fatalf("group created by the wrong manager: %s", group.PrivacyGroupId)
+	}
+	// Groups are changed by the manager holding them, even without a key
+	if _, err := router.AddToPrivacyGroup("", "bops", []string{"third"}); err != nil {
+		t.Fatalf("failed to add member: %v", err)
+	}
+	if have, _ := router.RetrievePrivacyGroup("bops"); !reflect.DeepEqual(have.Members, []string{"keyB", "other", "third"}) {
+		t.Errorf("members mismatch: have %v", have.Members)
+	}
+	// Groups created by other nodes are looked up in all managers
+	a.CreatePrivacyGroup("keyA", "remote", "", []string{"x"})
+	fresh, _ := newRouter(&RouterConfig{Managers: []ManagerConfig{{Name: "a"}, {Name: "b"}}}, map[string]PrivateTransactionManager{"a": a, "b": b})
+	if members, err := fresh.RetrievePrivacyGroup("aremote"); err != nil || !reflect.DeepEqual(members.Members, []string{"x"}) {
+		t.Errorf("remote group mismatch: have %v, %v", members, err)
+	}
+	if groups, _ := fresh.FindPrivacyGroups([]string{"x"}); len(groups) != 1 || groups[0].PrivacyGroupId != "aremote" {
+		t.Errorf("found groups mismatch: have %v", groups)
+	}
+	if err := fresh.DeletePrivacyGroup("", "aremote"); err != nil {
+		t.Fatalf("failed to delete group: %v", err)
+	}
+	if _, err := router.RetrievePrivacyGroup("aremote"); err == nil {
+		t.Errorf("deleted group still resolved")
+	}
+}
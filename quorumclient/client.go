@@ -0,0 +1,390 @@
+// Package quorumclient provides an in-process client for Go services embedding
+// a Quorum node.
+//
+// The client offers the typed methods of ethclient, and can be used as a
+// contract backend for the abigen bindings, but calls into the node directly
+// instead of going through an RPC transport, so values are never serialized.
+// Private transactions are supported both signed by the node and signed by
+// the service.
+package quorumclient
+
+import (
+	"context"
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/eth"
+	"github.com/ethereum/go-ethereum/eth/filters"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/private"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+var (
+	// ErrNotFound is returned when a requested block, transaction or receipt
+	// is not known to the node.
+	ErrNotFound = errors.New("not found")
+
+	errNoPrivateTransactionManager = errors.New("no private transaction manager attached to the node")
+)
+
+// Client is an in-process handle to the Ethereum service of a node.
+type Client struct {
+	backend *eth.EthAPIBackend
+	chain   *ethapi.PublicBlockChainAPI
+	txs     *ethapi.PublicTransactionPoolAPI
+	events  *filters.EventSystem
+}
+
+// New creates a client for the Ethereum service registered in a node. The node
+// must be started.
+func New(stack *node.Node) (*Client, error) {
+	var ethereum *eth.Ethereum
+	if err := stack.Service(&ethereum); err != nil {
+		return nil, err
+	}
+	backend := ethereum.APIBackend
+	return &Client{
+		backend: backend,
+		chain:   ethapi.NewPublicBlockChainAPI(backend),
+		txs:     ethapi.NewPublicTransactionPoolAPI(backend, backend.NonceLock()),
+		events:  filters.NewEventSystem(ethereum.EventMux(), backend, false),
+	}, nil
+}
+
+// Compile time check that the client can back contract bindings.
+var _ bind.ContractBackend = (*Client)(nil)
+
+// toBlockNumber converts a block number, nil meaning the latest block.
+func toBlockNumber(number *big.Int) rpc.BlockNumber {
+	if number == nil {
+		return rpc.LatestBlockNumber
+	}
+	return rpc.BlockNumber(number.Int64())
+}
+
+// Blockchain Access
+
+// BlockByHash returns the given full block.
+func (c *Client) BlockByHash(ctx context.Context, hash common.Hash) (*types.Block, error) {
+	block, err := c.backend.GetBlock(ctx, hash)
+	if block == nil && err == nil {
+		err = ErrNotFound
+	}
+	return block, err
+}
+
+// BlockByNumber returns a block from the current canonical chain. If number is
+// nil, the latest known block is returned.
+func (c *Client) BlockByNumber(ctx context.Context, number *big.Int) (*types.Block, error) {
+	block, err := c.backend.BlockByNumber(ctx, toBlockNumber(number))
+	if block == nil && err == nil {
+		err = ErrNotFound
+	}
+	return block, err
+}
+
+// HeaderByNumber returns a block header from the current canonical chain. If
+// number is nil, the latest known header is returned.
+func (c *Client) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	header, err := c.backend.HeaderByNumber(ctx, toBlockNumber(number))
+	if header == nil && err == nil {
+		err = ErrNotFound
+	}
+	return header, err
+}
+
+// TransactionByHash returns the transaction with the given hash, and whether it
+// is still pending.
+func (c *Client) TransactionByHash(ctx context.Context, hash common.Hash) (*types.Transaction, bool, error) {
+	if tx, _, _, _ := rawdb.ReadTransaction(c.backend.ChainDb(), hash); tx != nil {
+		return tx, false, nil
+	}
+	if tx := c.backend.GetPoolTransaction(hash); tx != nil {
+		return tx, true, nil
+	}
+	return nil, false, ErrNotFound
+}
+
+// TransactionReceipt returns the receipt of a mined transaction. The receipt of
+// a private transaction is the private one if the node is party to it.
+func (c *Client) TransactionReceipt(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+	tx, blockHash, _, index := rawdb.ReadTransaction(c.backend.ChainDb(), hash)
+	if tx == nil {
+		return nil, ErrNotFound
+	}
+	receipts, err := c.backend.GetReceipts(ctx, blockHash)
+	if err != nil {
+		return nil, err
+	}
+	if len(receipts) <= int(index) {
+		return nil, ErrNotFound
+	}
+	return receipts[index], nil
+}
+
+// State Access
+
+// BalanceAt returns the wei balance of the given account. The block number can
+// be nil, in which case the balance is taken from the latest known block.
+func (c *Client) BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error) {
+	balance, err := c.chain.GetBalance(ctx, account, toBlockNumber(blockNumber))
+	if err != nil {
+		return nil, err
+	}
+	return balance.ToInt(), nil
+}
+
+// StorageAt returns the value of key in the contract storage of the given
+// account, private contracts included. The block number can be nil, in which
+// case the value is taken from the latest known block.
+func (c *Client) StorageAt(ctx context.Context, account common.Address, key common.Hash, blockNumber *big.Int) ([]byte, error) {
+	return c.chain.GetStorageAt(ctx, account, key.Hex(), toBlockNumber(blockNumber))
+}
+
+// CodeAt returns the contract code of the given account, private contracts
+// included. The block number can be nil, in which case the code is taken from
+// the latest known block.
+func (c *Client) CodeAt(ctx context.Context, account common.Address, blockNumber *big.Int) ([]byte, error) {
+	return c.chain.GetCode(ctx, account, toBlockNumber(blockNumber))
+}
+
+// NonceAt returns the account nonce of the given account. The block number can
+// be nil, in which case the nonce is taken from the latest known block.
+func (c *Client) NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error) {
+	nonce, err := c.txs.GetTransactionCount(ctx, account, toBlockNumber(blockNumber))
+	if err != nil {
+		return 0, err
+	}
+	return uint64(*nonce), nil
+}
+
+// PendingCodeAt returns the contract code of the given account in the pending
+// state.
+func (c *Client) PendingCodeAt(ctx context.Context, account common.Address) ([]byte, error) {
+	return c.chain.GetCode(ctx, account, rpc.PendingBlockNumber)
+}
+
+// PendingNonceAt returns the account nonce of the given account in the pending
+// state. This is the nonce that should be used for the next transaction.
+func (c *Client) PendingNonceAt(ctx context.Context, account common.Address) (uint64, error) {
+	return c.backend.GetPoolNonce(ctx, account)
+}
+
+// Contract Calling
+
+// CallContract executes a message call transaction, which is directly executed
+// in the VM of the node, but never mined into the blockchain.
+func (c *Client) CallContract(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	return c.chain.Call(ctx, toCallArgs(msg), toBlockNumber(blockNumber))
+}
+
+// PendingCallContract executes a message call transaction using the pending
+// state.
+func (c *Client) PendingCallContract(ctx context.Context, msg ethereum.CallMsg) ([]byte, error) {
+	return c.chain.Call(ctx, toCallArgs(msg), rpc.PendingBlockNumber)
+}
+
+// SuggestGasPrice retrieves the currently suggested gas price.
+func (c *Client) SuggestGasPrice(ctx context.Context) (*big.Int, error) {
+	return c.backend.SuggestPrice(ctx)
+}
+
+// EstimateGas tries to estimate the gas needed to execute a specific transaction
+// based on the current pending state of the node.
+func (c *Client) EstimateGas(ctx context.Context, msg ethereum.CallMsg) (uint64, error) {
+	gas, err := c.chain.EstimateGas(ctx, toCallArgs(msg))
+	return uint64(gas), err
+}
+
+func toCallArgs(msg ethereum.CallMsg) ethapi.CallArgs {
+	args := ethapi.CallArgs{
+		From: msg.From,
+		To:   msg.To,
+		Gas:  hexutil.Uint64(msg.Gas),
+		Data: msg.Data,
+	}
+	if msg.GasPrice != nil {
+		args.GasPrice = hexutil.Big(*msg.GasPrice)
+	}
+	if msg.Value != nil {
+		args.Value = hexutil.Big(*msg.Value)
+	}
+	return args
+}
+
+// Transaction Submission
+
+// TransactionArgs are the arguments of a transaction signed by the node. Unset
+// optional fields are filled in by the node, as for eth_sendTransaction.
+type TransactionArgs struct {
+	From     common.Address
+	To       *common.Address // Nil for contract creations
+	Gas      *uint64
+	GasPrice *big.Int
+	Value    *big.Int
+	Nonce    *uint64
+	Data     []byte
+
+	// Private transactions, leave PrivateFor nil for public ones
+	PrivateFrom string
+	PrivateFor  []string
+}
+
+// SubmitTransaction signs a transaction with an unlocked account of the node
+// and injects it into the pending pool. The payload of private transactions is
+// first stored in the private transaction manager.
+func (c *Client) SubmitTransaction(ctx context.Context, args TransactionArgs) (common.Hash, error) {
+	sendArgs := ethapi.SendTxArgs{
+		From:        args.From,
+		To:          args.To,
+		Gas:         (*hexutil.Uint64)(args.Gas),
+		GasPrice:    (*hexutil.Big)(args.GasPrice),
+		Value:       (*hexutil.Big)(args.Value),
+		Nonce:       (*hexutil.Uint64)(args.Nonce),
+		PrivateFrom: args.PrivateFrom,
+		PrivateFor:  args.PrivateFor,
+	}
+	if args.Data != nil {
+		data := hexutil.Bytes(args.Data)
+		sendArgs.Data = &data
+	}
+	return c.txs.SendTransaction(ctx, sendArgs)
+}
+
+// SendTransaction injects a signed transaction into the pending pool. Private
+// transactions, whose payload was stored with PreparePrivateTransaction, are
+// distributed to the recipients listed in args.
+func (c *Client) SendTransaction(ctx context.Context, tx *types.Transaction, args bind.PrivateTxArgs) error {
+	data, err := rlp.EncodeToBytes(tx)
+	if err != nil {
+		return err
+	}
+	if args.PrivateFor != nil {
+		_, err = c.txs.SendRawPrivateTransaction(ctx, data, ethapi.SendRawTxArgs{PrivateFor: args.PrivateFor})
+	} else {
+		_, err = c.txs.SendRawTransaction(ctx, data)
+	}
+	return err
+}
+
+// PreparePrivateTransaction stores the payload of a private transaction in the
+// private transaction manager of the node, returning the hash to be used as the
+// data of the transaction before signing it.
+func (c *Client) PreparePrivateTransaction(data []byte, privateFrom string) ([]byte, error) {
+	if !private.IsEnabled() {
+		return nil, errNoPrivateTransactionManager
+	}
+	return private.P.StoreRaw(data, privateFrom)
+}
+
+// Filters and Subscriptions
+
+// FilterLogs executes a filter query.
+func (c *Client) FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error) {
+	var filter *filters.Filter
+	if q.BlockHash != nil {
+		filter = filters.NewBlockFilter(c.backend, *q.BlockHash, q.Addresses, q.Topics)
+	} else {
+		begin, end := rpc.LatestBlockNumber.Int64(), rpc.LatestBlockNumber.Int64()
+		if q.FromBlock != nil {
+			begin = q.FromBlock.Int64()
+		}
+		if q.ToBlock != nil {
+			end = q.ToBlock.Int64()
+		}
+		filter = filters.NewRangeFilter(c.backend, begin, end, q.Addresses, q.Topics)
+	}
+	logs, err := filter.Logs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]types.Log, len(logs))
+	for i, log := range logs {
+		result[i] = *log
+	}
+	return result, nil
+}
+
+// SubscribeFilterLogs subscribes to the results of a streaming filter query.
+func (c *Client) SubscribeFilterLogs(ctx context.Context, q ethereum.FilterQuery, ch chan<- types.Log) (ethereum.Subscription, error) {
+	logs := make(chan []*types.Log)
+	sub, err := c.events.SubscribeLogs(q, logs)
+	if err != nil {
+		return nil, err
+	}
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		defer sub.Unsubscribe()
+		for {
+			select {
+			case batch := <-logs:
+				for _, log := range batch {
+					select {
+					case ch <- *log:
+					case <-quit:
+						return nil
+					}
+				}
+			case <-quit:
+				return nil
+			}
+		}
+	}), nil
+}
+
+// SubscribeNewHead subscribes to notifications about the current blockchain
+// head.
+func (c *Client) SubscribeNewHead(ctx context.Context, ch chan<- *types.Header) (ethereum.Subscription, error) {
+	headers := make(chan *types.Header)
+	sub := c.events.SubscribeNewHeads(headers)
+
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		defer sub.Unsubscribe()
+		for {
+			select {
+			case header := <-headers:
+				select {
+				case ch <- header:
+				case <-quit:
+					return nil
+				}
+			case <-quit:
+				return nil
+			}
+		}
+	}), nil
+}
+
+// SubscribePendingTransactions subscribes to the hashes of the transactions
+// entering the pending pool.
+func (c *Client) SubscribePendingTransactions(ctx context.Context, ch chan<- common.Hash) (ethereum.Subscription, error) {
+	hashes := make(chan []common.Hash)
+	sub := c.events.SubscribePendingTxs(hashes)
+
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		defer sub.Unsubscribe()
+		for {
+			select {
+			case batch := <-hashes:
+				for _, hash := range batch {
+					select {
+					case ch <- hash:
+					case <-quit:
+						return nil
+					}
+				}
+			case <-quit:
+				return nil
+			}
+		}
+	}), nil
+}
@@ -0,0 +1,114 @@
+package quorumclient
+
+import (
+	"context"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth"
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+var (
+	testKey, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	testAddress = crypto.PubkeyToAddress(testKey.PublicKey)
+)
+
+// newTestNode starts a networkless node funding the test account.
+func newTestNode(t *testing.T) (*node.Node, func()) {
+	workspace, err := ioutil.TempDir("", "quorumclient-")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %v", err)
+	}
+	stack, err := node.New(&node.Config{DataDir: workspace, UseLightweightKDF: true})
+	if err != nil {
+		t.Fatalf("failed to create node: %v", err)
+	}
+	ethConf := &eth.Config{
+		Genesis: core.DeveloperGenesisBlock(15, testAddress),
+		Ethash:  ethash.Config{PowMode: ethash.ModeTest},
+	}
+	if err := stack.Register(func(ctx *node.ServiceContext) (node.Service, error) { return eth.New(ctx, ethConf) }); err != nil {
+		t.Fatalf("failed to register Ethereum protocol: %v", err)
+	}
+	if err := stack.Start(); err != nil {
+		t.Fatalf("failed to start test stack: %v", err)
+	}
+	return stack, func() {
+		stack.Stop()
+		os.RemoveAll(workspace)
+	}
+}
+
+func TestClientSendTransaction(t *testing.T) {
+	stack, closer := newTestNode(t)
+	defer closer()
+
+	client, err := New(stack)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	ctx := context.Background()
+
+	balance, err := client.BalanceAt(ctx, testAddress, nil)
+	if err != nil {
+		t.Fatalf("failed to retrieve balance: %v", err)
+	}
+	if balance.Sign() <= 0 {
+		t.Fatalf("test account not funded: balance %v", balance)
+	}
+	head, err := client.HeaderByNumber(ctx, nil)
+	if err != nil || head.Number.Sign() != 0 {
+		t.Fatalf("head mismatch: have %v, %v, want genesis", head, err)
+	}
+	if _, err := client.BlockByNumber(ctx, big.NewInt(1)); err != ErrNotFound {
+		t.Fatalf("missing block error mismatch: have %v, want %v", err, ErrNotFound)
+	}
+
+	hashes := make(chan common.Hash, 1)
+	sub, err := client.SubscribePendingTransactions(ctx, hashes)
+	if err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+	defer sub.Unsubscribe()
+
+	nonce, err := client.PendingNonceAt(ctx, testAddress)
+	if err != nil {
+		t.Fatalf("failed to retrieve nonce: %v", err)
+	}
+	tx := types.NewTransaction(nonce, common.Address{0x01}, big.NewInt(1), params.TxGas, big.NewInt(1), nil)
+	tx, err = types.SignTx(tx, types.HomesteadSigner{}, testKey)
+	if err != nil {
+		t.Fatalf("failed to sign transaction: %v", err)
+	}
+	if err := client.SendTransaction(ctx, tx, bind.PrivateTxArgs{}); err != nil {
+		t.Fatalf("failed to send transaction: %v", err)
+	}
+	select {
+	case hash := <-hashes:
+		if hash != tx.Hash() {
+			t.Errorf("pending hash mismatch: have %x, want %x", hash, tx.Hash())
+		}
+	case <-time.After(5 * time.Second):
+		t.Errorf("pending transaction not announced")
+	}
+	if next, _ := client.PendingNonceAt(ctx, testAddress); next != nonce+1 {
+		t.Errorf("pending nonce mismatch: have %d, want %d", next, nonce+1)
+	}
+	if pooled, pending, err := client.TransactionByHash(ctx, tx.Hash()); err != nil || !pending || pooled.Hash() != tx.Hash() {
+		t.Errorf("pooled transaction mismatch: have %v, %v, %v", pooled, pending, err)
+	}
+	if _, err := client.PreparePrivateTransaction([]byte{0x01}, ""); err != errNoPrivateTransactionManager {
+		t.Errorf("private payload error mismatch: have %v, want %v", err, errNoPrivateTransactionManager)
+	}
+}
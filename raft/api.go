@@ -2,7 +2,9 @@ package raft
 
 import (
 	"errors"
+
 	"github.com/coreos/etcd/pkg/types"
+	"github.com/ethereum/go-ethereum/adminauth"
 )
 
 type RaftNodeInfo struct {
@@ -42,34 +44,59 @@ func (s *PublicRaftAPI) checkIfNodeInCluster() error {
 	return nil
 }
 
-func (s *PublicRaftAPI) AddPeer(enodeId string) (uint16, error) {
+func (s *PublicRaftAPI) AddPeer(enodeId string, sig *adminauth.Signature) (uint16, error) {
 	if err := s.checkIfNodeInCluster(); err != nil {
 		return 0, err
 	}
+	if err := s.raftService.operatorAuth.Verify("raft_addPeer", sig, enodeId); err != nil {
+		return 0, err
+	}
 	return s.raftService.raftProtocolManager.ProposeNewPeer(enodeId, false)
 }
 
-func (s *PublicRaftAPI) AddLearner(enodeId string) (uint16, error) {
+func (s *PublicRaftAPI) AddLearner(enodeId string, sig *adminauth.Signature) (uint16, error) {
 	if err := s.checkIfNodeInCluster(); err != nil {
 		return 0, err
 	}
+	if err := s.raftService.operatorAuth.Verify("raft_addLearner", sig, enodeId); err != nil {
+		return 0, err
+	}
 	return s.raftService.raftProtocolManager.ProposeNewPeer(enodeId, true)
 }
 
-func (s *PublicRaftAPI) PromoteToPeer(raftId uint16) (bool, error) {
+func (s *PublicRaftAPI) PromoteToPeer(raftId uint16, sig *adminauth.Signature) (bool, error) {
 	if err := s.checkIfNodeInCluster(); err != nil {
 		return false, err
 	}
+	if err := s.raftService.operatorAuth.Verify("raft_promoteToPeer", sig, raftId); err != nil {
+		return false, err
+	}
 	return s.raftService.raftProtocolManager.PromoteToPeer(raftId)
 }
 
-func (s *PublicRaftAPI) RemovePeer(raftId uint16) error {
+func (s *PublicRaftAPI) RemovePeer(raftId uint16, sig *adminauth.Signature) error {
 	if err := s.checkIfNodeInCluster(); err != nil {
 		return err
 	}
+	if err := s.raftService.operatorAuth.Verify("raft_removePeer", sig, raftId); err != nil {
+		return err
+	}
 	return s.raftService.raftProtocolManager.ProposePeerRemoval(raftId)
 }
 
+// RemovePeer proposes the removal of a peer on behalf of an internal component,
+// such as the permissioning service acting on an on-chain decision. Lacking an
+// operator signature, the call is authorised and audited as made by origin.
+func (service *RaftService) RemovePeer(origin string, raftId uint16) error {
+	if err := NewPublicRaftAPI(service).checkIfNodeInCluster(); err != nil {
+		return err
+	}
+	if err := service.operatorAuth.VerifyInternal(origin, "raft_removePeer", raftId); err != nil {
+		return err
+	}
+	return service.raftProtocolManager.ProposePeerRemoval(raftId)
+}
+
 func (s *PublicRaftAPI) Leader() (string, error) {
 
 	addr, err := s.raftService.raftProtocolManager.LeaderAddress()
@@ -118,6 +145,15 @@ func (s *PublicRaftAPI) Cluster() ([]ClusterInfo, error) {
 	return clustInfo, nil
 }
 
+// ClusterDetailed returns the state of the raft log as seen by the node, with
+// the replication lag and heartbeat latency of each peer when run on the leader.
+func (s *PublicRaftAPI) ClusterDetailed() (*ClusterDetailed, error) {
+	if err := s.checkIfNodeInCluster(); err != nil {
+		return nil, err
+	}
+	return s.raftService.raftProtocolManager.ClusterDetailed(), nil
+}
+
 // checkIfNodeIsActive checks if the raft node is active
 // if the raft node is active ActiveSince returns non-zero time
 func (s *PublicRaftAPI) checkIfNodeIsActive(raftId uint16) bool {
@@ -1,18 +1,20 @@
 package raft
 
 import (
-	"crypto/ecdsa"
 	"sync"
 	"time"
 
 	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/adminauth"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/eth"
 	"github.com/ethereum/go-ethereum/eth/downloader"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/kms"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/miner"
 	"github.com/ethereum/go-ethereum/node"
 	"github.com/ethereum/go-ethereum/p2p"
 	"github.com/ethereum/go-ethereum/p2p/enode"
@@ -34,8 +36,10 @@ type RaftService struct {
 	// we need an event mux to instantiate the blockchain
 	eventMux         *event.TypeMux
 	minter           *minter
-	nodeKey          *ecdsa.PrivateKey
+	nodeSigner       kms.Signer // Signs the minted blocks with the node key
 	calcGasLimitFunc func(block *types.Block) uint64
+	operatorAuth     *adminauth.Authenticator
+	miner            *miner.Miner // Orders the minted transactions with the configured block builder
 }
 
 func New(ctx *node.ServiceContext, chainConfig *params.ChainConfig, raftId, raftPort uint16, joinExisting bool, blockTime time.Duration, e *eth.Ethereum, startPeers []*enode.Node, datadir string, useDns bool) (*RaftService, error) {
@@ -47,8 +51,10 @@ func New(ctx *node.ServiceContext, chainConfig *params.ChainConfig, raftId, raft
 		accountManager:   e.AccountManager(),
 		downloader:       e.Downloader(),
 		startPeers:       startPeers,
-		nodeKey:          ctx.NodeKey(),
+		nodeSigner:       ctx.NodeSigner(),
 		calcGasLimitFunc: e.CalcGasLimit,
+		operatorAuth:     ctx.OperatorAuthenticator(),
+		miner:            e.Miner(),
 	}
 
 	service.minter = newMinter(chainConfig, service, blockTime)
@@ -57,6 +63,8 @@ func New(ctx *node.ServiceContext, chainConfig *params.ChainConfig, raftId, raft
 	if service.raftProtocolManager, err = NewProtocolManager(raftId, raftPort, service.blockchain, service.eventMux, startPeers, joinExisting, datadir, service.minter, service.downloader, useDns); err != nil {
 		return nil, err
 	}
+	service.raftProtocolManager.leaderHook = e.ReportLeaderChange
+	service.raftProtocolManager.lagHook = e.ReportRaftLag
 
 	return service, nil
 }
@@ -23,6 +23,7 @@ import (
 	mapset "github.com/deckarep/golang-set"
 	"github.com/syndtr/goleveldb/leveldb"
 
+	"github.com/ethereum/go-ethereum/consensus/faultinject"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/eth/downloader"
@@ -56,6 +57,10 @@ type ProtocolManager struct {
 	peers        map[uint16]*Peer
 	removedPeers mapset.Set // *Permanently removed* peers
 
+	leaderHook func(leader uint64) // Notified of the leader reported by each raft soft state
+	lagHook    func(lag uint64)    // Notified of the largest lag of the peers, measured by the leader
+	observer   *replicationObserver
+
 	// P2P transport
 	p2pServer *p2p.Server // Initialized in start()
 	useDns    bool
@@ -126,6 +131,7 @@ func NewProtocolManager(raftId uint16, raftPort uint16, blockchain *core.BlockCh
 		minter:              minter,
 		downloader:          downloader,
 		useDns:              useDns,
+		observer:            newReplicationObserver(),
 	}
 
 	if db, err := openQuorumRaftDb(quorumRaftDbLoc); err != nil {
@@ -146,6 +152,7 @@ func (pm *ProtocolManager) Start(p2pServer *p2p.Server) {
 	// update raft peers info to p2p server
 	pm.p2pServer.SetCheckPeerInRaft(pm.peerExist)
 	go pm.minedBroadcastLoop()
+	go pm.observeLoop()
 }
 
 func (pm *ProtocolManager) Stop() {
@@ -410,9 +417,32 @@ func (pm *ProtocolManager) WriteMsg(msg p2p.Msg) error {
 //
 
 func (pm *ProtocolManager) Process(ctx context.Context, m raftpb.Message) error {
+	pm.observer.received(m, time.Now())
+	if faultinject.Enabled {
+		// The message may be stepped after the request carrying it is done
+		faultinject.Deliver(faultinject.Raft, faultinject.Inbound, strconv.FormatUint(m.From, 10), func() {
+			pm.rawNode().Step(context.Background(), m)
+		})
+		return nil
+	}
 	return pm.rawNode().Step(ctx, m)
 }
 
+// sendMessages sends raft messages to the peers named in their To field,
+// through the fault injection layer in the binaries built with it.
+func (pm *ProtocolManager) sendMessages(msgs []raftpb.Message) {
+	if !faultinject.Enabled {
+		pm.transport.Send(msgs)
+		return
+	}
+	for _, msg := range msgs {
+		msg := msg
+		faultinject.Deliver(faultinject.Raft, faultinject.Outbound, strconv.FormatUint(msg.To, 10), func() {
+			pm.transport.Send([]raftpb.Message{msg})
+		})
+	}
+}
+
 func (pm *ProtocolManager) IsIDRemoved(id uint64) bool {
 	return pm.isRaftIdRemoved(uint16(id))
 }
@@ -818,6 +848,7 @@ func (pm *ProtocolManager) removePeer(raftId uint16) {
 
 		delete(pm.peers, raftId)
 	}
+	pm.observer.forget(uint64(raftId))
 
 	// This is only necessary sometimes, but it's idempotent. Also, we *always*
 	// do this, and not just when there's still a peer in the map, because we
@@ -860,7 +891,8 @@ func (pm *ProtocolManager) eventLoop() {
 			pm.raftStorage.Append(rd.Entries)
 
 			// 2: Send all Messages to the nodes named in the To field.
-			pm.transport.Send(rd.Messages)
+			pm.observer.sent(rd.Messages, time.Now())
+			pm.sendMessages(rd.Messages)
 
 			// 3: Apply Snapshot (if any) and CommittedEntries to the state machine.
 			for _, entry := range pm.entriesToApply(rd.CommittedEntries) {
@@ -1061,9 +1093,12 @@ func (pm *ProtocolManager) advanceAppliedIndex(index uint64) {
 
 func (pm *ProtocolManager) updateLeader(leader uint64) {
 	pm.mu.Lock()
-	defer pm.mu.Unlock()
-
 	pm.leader = uint16(leader)
+	pm.mu.Unlock()
+
+	if pm.leaderHook != nil {
+		pm.leaderHook(leader)
+	}
 }
 
 // The Address for the current leader, or an error if no leader is elected.
@@ -27,14 +27,16 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/consensus/faultinject"
+	"github.com/ethereum/go-ethereum/consensus/misc"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
-	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/miner"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rlp"
 )
@@ -50,6 +52,7 @@ type work struct {
 	privateState *state.StateDB
 	Block        *types.Block
 	header       *types.Header
+	accessed     state.StateAccesses // Quorum: public state accessed by the public transactions
 }
 
 type minter struct {
@@ -242,7 +245,7 @@ func (minter *minter) mintingLoop() {
 
 func generateNanoTimestamp(parent *types.Block) (tstamp int64) {
 	parentTime := parent.Time().Int64()
-	tstamp = time.Now().UnixNano()
+	tstamp = faultinject.Now().UnixNano()
 
 	if parentTime >= tstamp {
 		// Each successive block needs to be after its predecessor.
@@ -272,6 +275,10 @@ func (minter *minter) createWork() *work {
 	if err != nil {
 		panic(fmt.Sprint("failed to get parent state: ", err))
 	}
+	if minter.config.IsDeterministicDeploymentBlock(header.Number) {
+		misc.ApplyDeterministicDeployment(publicState)
+		misc.ApplyDeterministicDeployment(privateState)
+	}
 
 	return &work{
 		config:       minter.config,
@@ -281,14 +288,24 @@ func (minter *minter) createWork() *work {
 	}
 }
 
-func (minter *minter) getTransactions() *types.TransactionsByPriceAndNonce {
-	allAddrTxes, err := minter.eth.TxPool().Pending()
+// getTransactions returns the pending transactions not yet proposed, one set
+// per transaction pool priority lane, highest priority first, as ordered by the
+// block builder if one is configured.
+func (minter *minter) getTransactions() []miner.TransactionSource {
+	lanes, err := minter.eth.TxPool().PendingLanes()
 	if err != nil { // TODO: handle
 		panic(err)
 	}
-	addrTxes := minter.speculativeChain.withoutProposedTxes(allAddrTxes)
 	signer := types.MakeSigner(minter.chain.Config(), minter.chain.CurrentBlock().Number())
-	return types.NewTransactionsByPriceAndNonce(signer, addrTxes)
+	txes := make([]miner.TransactionSource, len(lanes))
+	for i, allAddrTxes := range lanes {
+		addrTxes := minter.speculativeChain.withoutProposedTxes(allAddrTxes)
+		txes[i] = types.NewTransactionsByPriceAndNonce(signer, addrTxes)
+	}
+	if minter.eth.miner != nil {
+		return minter.eth.miner.OrderTransactions(minter.speculativeChain.head.Header(), signer, txes)
+	}
+	return txes
 }
 
 // Sends-off events asynchronously.
@@ -364,7 +381,7 @@ func (minter *minter) mintNewBlock() {
 	log.Info("🔨  Mined block", "number", block.Number(), "hash", fmt.Sprintf("%x", block.Hash().Bytes()[:4]), "elapsed", elapsed)
 }
 
-func (env *work) commitTransactions(txes *types.TransactionsByPriceAndNonce, bc *core.BlockChain) (types.Transactions, types.Receipts, types.Receipts, []*types.Log) {
+func (env *work) commitTransactions(lanes []miner.TransactionSource, bc *core.BlockChain) (types.Transactions, types.Receipts, types.Receipts, []*types.Log) {
 	var allLogs []*types.Log
 	var committedTxes types.Transactions
 	var publicReceipts types.Receipts
@@ -372,33 +389,45 @@ func (env *work) commitTransactions(txes *types.TransactionsByPriceAndNonce, bc
 
 	gp := new(core.GasPool).AddGas(env.header.GasLimit)
 	txCount := 0
-
-	for {
-		tx := txes.Peek()
-		if tx == nil {
-			break
-		}
-
-		env.publicState.Prepare(tx.Hash(), common.Hash{}, txCount)
-
-		publicReceipt, privateReceipt, err := env.commitTransaction(tx, bc, gp)
-		switch {
-		case err != nil:
-			log.Info("TX failed, will be removed", "hash", tx.Hash(), "err", err)
-			txes.Pop() // skip rest of txes from this account
-		default:
-			txCount++
-			committedTxes = append(committedTxes, tx)
-
-			publicReceipts = append(publicReceipts, publicReceipt)
-			allLogs = append(allLogs, publicReceipt.Logs...)
-
-			if privateReceipt != nil {
-				privateReceipts = append(privateReceipts, privateReceipt)
-				allLogs = append(allLogs, privateReceipt.Logs...)
+	quota := types.NewOrgQuotaUsage()
+	signer := types.MakeSigner(env.config, env.header.Number)
+
+	for _, txes := range lanes {
+		for {
+			tx := txes.Peek()
+			if tx == nil {
+				break
 			}
 
-			txes.Shift()
+			// Skip organizations that used up their block quota
+			from, _ := types.Sender(signer, tx)
+			if !quota.Allow(from, tx.Gas()) {
+				log.Trace("Organization quota exceeded for current block", "sender", from)
+				txes.Pop()
+				continue
+			}
+			env.publicState.Prepare(tx.Hash(), common.Hash{}, txCount)
+
+			publicReceipt, privateReceipt, err := env.commitTransaction(tx, bc, gp)
+			switch {
+			case err != nil:
+				log.Info("TX failed, will be removed", "hash", tx.Hash(), "err", err)
+				txes.Pop() // skip rest of txes from this account
+			default:
+				txCount++
+				committedTxes = append(committedTxes, tx)
+				quota.Record(from, publicReceipt.GasUsed)
+
+				publicReceipts = append(publicReceipts, publicReceipt)
+				allLogs = append(allLogs, publicReceipt.Logs...)
+
+				if privateReceipt != nil {
+					privateReceipts = append(privateReceipts, privateReceipt)
+					allLogs = append(allLogs, privateReceipt.Logs...)
+				}
+
+				txes.Shift()
+			}
 		}
 	}
 
@@ -406,11 +435,18 @@ func (env *work) commitTransactions(txes *types.TransactionsByPriceAndNonce, bc
 }
 
 func (env *work) commitTransaction(tx *types.Transaction, bc *core.BlockChain, gp *core.GasPool) (*types.Receipt, *types.Receipt, error) {
+	var author *common.Address
+	var vmConf vm.Config
+
+	// Quorum - bound the public state accessed by the public transactions of the
+	// block, checking the transaction on a copy of the state before applying it
+	accessed, err := core.CheckStateAccessLimits(env.config, bc, author, gp, env.publicState, env.header, tx, env.accessed, vmConf)
+	if err != nil {
+		return nil, nil, err
+	}
 	publicSnapshot := env.publicState.Snapshot()
 	privateSnapshot := env.privateState.Snapshot()
 
-	var author *common.Address
-	var vmConf vm.Config
 	publicReceipt, privateReceipt, _, err := core.ApplyTransaction(env.config, bc, author, gp, env.publicState, env.privateState, env.header, tx, &env.header.GasUsed, vmConf)
 	if err != nil {
 		env.publicState.RevertToSnapshot(publicSnapshot)
@@ -418,14 +454,14 @@ func (env *work) commitTransaction(tx *types.Transaction, bc *core.BlockChain, g
 
 		return nil, nil, err
 	}
+	env.accessed = accessed
 
 	return publicReceipt, privateReceipt, nil
 }
 
 func (minter *minter) buildExtraSeal(headerHash common.Hash) []byte {
 	//Sign the headerHash
-	nodeKey := minter.eth.nodeKey
-	sig, err := crypto.Sign(headerHash.Bytes(), nodeKey)
+	sig, err := minter.eth.nodeSigner.Sign(headerHash.Bytes())
 	if err != nil {
 		log.Warn("Block sealing failed", "err", err)
 	}
@@ -10,6 +10,7 @@ import (
 
 	"github.com/coreos/etcd/raft/raftpb"
 	"github.com/deckarep/golang-set"
+	"github.com/ethereum/go-ethereum/kms"
 	"github.com/ethereum/go-ethereum/p2p/enode"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -30,7 +31,7 @@ func TestSignHeader(t *testing.T) {
 	nodeKey := config.NodeKey()
 
 	raftProtocolManager := &ProtocolManager{raftId: testRaftId}
-	raftService := &RaftService{nodeKey: nodeKey, raftProtocolManager: raftProtocolManager}
+	raftService := &RaftService{nodeSigner: kms.NewLocalSigner(nodeKey), raftProtocolManager: raftProtocolManager}
 	minter := minter{eth: raftService}
 
 	//create some fake header to sign
@@ -200,6 +201,6 @@ func newTestRaftService(t *testing.T, raftId uint16, nodes []uint64, learners []
 		removedPeers:        mapset.NewSet(),
 		confState:           raftpb.ConfState{Nodes: nodes, Learners: learners},
 	}
-	raftService := &RaftService{nodeKey: nodeKey, raftProtocolManager: raftProtocolManager}
+	raftService := &RaftService{nodeSigner: kms.NewLocalSigner(nodeKey), raftProtocolManager: raftProtocolManager}
 	return raftService
 }
@@ -0,0 +1,227 @@
+package raft
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	raftTypes "github.com/coreos/etcd/pkg/types"
+	etcdRaft "github.com/coreos/etcd/raft"
+	"github.com/coreos/etcd/raft/raftpb"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+const (
+	// Interval at which the replication of the raft log is measured
+	observeInterval = time.Second
+
+	// Time after which an unanswered heartbeat is no longer awaited, so that a
+	// lost response doesn't count as a huge latency
+	heartbeatExpiry = 5 * time.Second
+)
+
+var (
+	commitIndexGauge  = metrics.NewRegisteredGauge("raft/index/commit", nil)
+	appliedIndexGauge = metrics.NewRegisteredGauge("raft/index/applied", nil)
+	maxLagGauge       = metrics.NewRegisteredGauge("raft/lag/max", nil)
+)
+
+// PeerReplication is the replication of the raft log to a peer, only measured
+// by the leader.
+type PeerReplication struct {
+	ClusterInfo
+	MatchIndex       *uint64  `json:"matchIndex,omitempty"`       // Highest entry known to be replicated to the peer
+	Lag              *uint64  `json:"lag,omitempty"`              // Entries the peer is behind the leader
+	State            string   `json:"state,omitempty"`            // Replication state: probe, replicate or snapshot
+	HeartbeatLatency *float64 `json:"heartbeatLatency,omitempty"` // Latest heartbeat round-trip time, in milliseconds
+}
+
+// ClusterDetailed is the state of the raft log as seen by the node, and its
+// replication to the peers.
+type ClusterDetailed struct {
+	RaftId       uint16             `json:"raftId"`
+	Leader       uint16             `json:"leader"`
+	Term         uint64             `json:"term"`
+	LastIndex    uint64             `json:"lastIndex"`
+	CommitIndex  uint64             `json:"commitIndex"`
+	AppliedIndex uint64             `json:"appliedIndex"`
+	Peers        []*PeerReplication `json:"peers"`
+}
+
+// replicationObserver measures the heartbeat latency of the peers, from the
+// time between a heartbeat sent by the leader and its response.
+type replicationObserver struct {
+	lock      sync.Mutex
+	pending   map[uint64]time.Time     // Heartbeats awaiting a response by peer
+	latencies map[uint64]time.Duration // Latest heartbeat round-trip time by peer
+}
+
+func newReplicationObserver() *replicationObserver {
+	return &replicationObserver{
+		pending:   make(map[uint64]time.Time),
+		latencies: make(map[uint64]time.Duration),
+	}
+}
+
+// sent records the heartbeats among the messages sent to the peers. Only the
+// first of several unanswered heartbeats is timed, as the responses don't say
+// which heartbeat they answer.
+func (o *replicationObserver) sent(msgs []raftpb.Message, now time.Time) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	for _, msg := range msgs {
+		if msg.Type != raftpb.MsgHeartbeat {
+			continue
+		}
+		if sent, ok := o.pending[msg.To]; !ok || now.Sub(sent) > heartbeatExpiry {
+			o.pending[msg.To] = now
+		}
+	}
+}
+
+// received times the heartbeat answered by a message from a peer.
+func (o *replicationObserver) received(msg raftpb.Message, now time.Time) {
+	if msg.Type != raftpb.MsgHeartbeatResp {
+		return
+	}
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	if sent, ok := o.pending[msg.From]; ok {
+		o.latencies[msg.From] = now.Sub(sent)
+		delete(o.pending, msg.From)
+	}
+}
+
+// latency returns the latest heartbeat round-trip time of a peer, if measured.
+func (o *replicationObserver) latency(id uint64) (time.Duration, bool) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	latency, ok := o.latencies[id]
+	return latency, ok
+}
+
+// forget drops the measurements of a removed peer.
+func (o *replicationObserver) forget(id uint64) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	delete(o.pending, id)
+	delete(o.latencies, id)
+}
+
+// replicationLags returns the number of entries each peer is behind the last
+// entry of the leader, given its raft status. Followers don't track the peers.
+func replicationLags(status etcdRaft.Status, lastIndex uint64) map[uint64]uint64 {
+	lags := make(map[uint64]uint64)
+	for id, progress := range status.Progress {
+		if id == status.ID {
+			continue
+		}
+		if progress.Match < lastIndex {
+			lags[id] = lastIndex - progress.Match
+		} else {
+			lags[id] = 0
+		}
+	}
+	return lags
+}
+
+// ClusterDetailed returns the state of the raft log and its replication to the
+// peers.
+func (pm *ProtocolManager) ClusterDetailed() *ClusterDetailed {
+	status := pm.rawNode().Status()
+	lastIndex, _ := pm.raftStorage.LastIndex()
+	lags := replicationLags(status, lastIndex)
+
+	nodeInfo := pm.NodeInfo()
+	detailed := &ClusterDetailed{
+		RaftId:       pm.raftId,
+		Leader:       uint16(status.Lead),
+		Term:         status.Term,
+		LastIndex:    lastIndex,
+		CommitIndex:  status.Commit,
+		AppliedIndex: nodeInfo.AppliedIndex,
+		Peers:        []*PeerReplication{},
+	}
+	for _, address := range nodeInfo.PeerAddresses {
+		id := uint64(address.RaftId)
+		active := !pm.transport.ActiveSince(raftTypes.ID(id)).IsZero()
+		peer := &PeerReplication{ClusterInfo: ClusterInfo{Address: *address, NodeActive: active}}
+		switch {
+		case status.Lead == etcdRaft.None:
+		case id == status.Lead:
+			peer.Role = "minter"
+		case pm.isLearner(address.RaftId):
+			peer.Role = "learner"
+		default:
+			peer.Role = "verifier"
+		}
+		if progress, ok := status.Progress[id]; ok {
+			match, lag := progress.Match, lags[id]
+			peer.MatchIndex, peer.Lag, peer.State = &match, &lag, progressState(progress.State)
+		}
+		if latency, ok := pm.observer.latency(id); ok {
+			ms := float64(latency) / float64(time.Millisecond)
+			peer.HeartbeatLatency = &ms
+		}
+		detailed.Peers = append(detailed.Peers, peer)
+	}
+	return detailed
+}
+
+// progressState returns the short name of a replication state.
+func progressState(state etcdRaft.ProgressStateType) string {
+	switch state {
+	case etcdRaft.ProgressStateProbe:
+		return "probe"
+	case etcdRaft.ProgressStateReplicate:
+		return "replicate"
+	case etcdRaft.ProgressStateSnapshot:
+		return "snapshot"
+	}
+	return state.String()
+}
+
+// observe updates the replication metrics and reports the largest lag of the
+// peers to the lag hook.
+func (pm *ProtocolManager) observe() {
+	status := pm.rawNode().Status()
+	lastIndex, _ := pm.raftStorage.LastIndex()
+
+	commitIndexGauge.Update(int64(status.Commit))
+	appliedIndexGauge.Update(int64(pm.NodeInfo().AppliedIndex))
+
+	var maxLag uint64
+	for id, lag := range replicationLags(status, lastIndex) {
+		metrics.GetOrRegisterGauge(fmt.Sprintf("raft/peers/%d/lag", id), nil).Update(int64(lag))
+		if latency, ok := pm.observer.latency(id); ok {
+			metrics.GetOrRegisterGauge(fmt.Sprintf("raft/peers/%d/heartbeat", id), nil).Update(int64(latency))
+		}
+		if lag > maxLag {
+			maxLag = lag
+		}
+	}
+	maxLagGauge.Update(int64(maxLag))
+	if pm.lagHook != nil {
+		pm.lagHook(maxLag)
+	}
+}
+
+// observeLoop measures the replication of the raft log until the protocol
+// manager stops.
+func (pm *ProtocolManager) observeLoop() {
+	ticker := time.NewTicker(observeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			pm.observe()
+		case <-pm.quitSync:
+			return
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package raft
+
+import (
+	"testing"
+	"time"
+
+	etcdRaft "github.com/coreos/etcd/raft"
+	"github.com/coreos/etcd/raft/raftpb"
+)
+
+func TestReplicationLags_whenTypical(t *testing.T) {
+	status := etcdRaft.Status{ID: 1, Progress: map[uint64]etcdRaft.Progress{
+		1: {Match: 100},
+		2: {Match: 100},
+		3: {Match: 40},
+	}}
+	lags := replicationLags(status, 100)
+	if len(lags) != 2 || lags[2] != 0 || lags[3] != 60 {
+		t.Errorf("lags mismatch: have %v, want map[2:0 3:60]", lags)
+	}
+	// Followers don't track the progress of the peers
+	if lags := replicationLags(etcdRaft.Status{ID: 2}, 100); len(lags) != 0 {
+		t.Errorf("follower lags mismatch: have %v", lags)
+	}
+}
+
+func TestHeartbeatLatency_whenTypical(t *testing.T) {
+	o := newReplicationObserver()
+	start := time.Now()
+
+	// Only the first of the unanswered heartbeats is timed
+	o.sent([]raftpb.Message{{Type: raftpb.MsgHeartbeat, To: 2}, {Type: raftpb.MsgApp, To: 3}}, start)
+	o.sent([]raftpb.Message{{Type: raftpb.MsgHeartbeat, To: 2}}, start.Add(100*time.Millisecond))
+	o.received(raftpb.Message{Type: raftpb.MsgAppResp, From: 3}, start.Add(150*time.Millisecond))
+	o.received(raftpb.Message{Type: raftpb.MsgHeartbeatResp, From: 2}, start.Add(150*time.Millisecond))
+
+	if latency, ok := o.latency(2); !ok || latency != 150*time.Millisecond {
+		t.Errorf("latency mismatch: have %v, want %v", latency, 150*time.Millisecond)
+	}
+	if _, ok := o.latency(3); ok {
+		t.Errorf("latency measured without heartbeat")
+	}
+	// Expired heartbeats are no longer awaited
+	o.sent([]raftpb.Message{{Type: raftpb.MsgHeartbeat, To: 2}}, start)
+	o.sent([]raftpb.Message{{Type: raftpb.MsgHeartbeat, To: 2}}, start.Add(heartbeatExpiry+time.Second))
+	o.received(raftpb.Message{Type: raftpb.MsgHeartbeatResp, From: 2}, start.Add(heartbeatExpiry+time.Second+10*time.Millisecond))
+	if latency, _ := o.latency(2); latency != 10*time.Millisecond {
+		t.Errorf("latency after expiry mismatch: have %v, want %v", latency, 10*time.Millisecond)
+	}
+	o.forget(2)
+	if _, ok := o.latency(2); ok {
+		t.Errorf("latency of forgotten peer kept")
+	}
+}
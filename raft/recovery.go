@@ -0,0 +1,324 @@
+package raft
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/coreos/etcd/raft/raftpb"
+	"github.com/coreos/etcd/snap"
+	"github.com/coreos/etcd/wal"
+	"github.com/coreos/etcd/wal/walpb"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/syndtr/goleveldb/leveldb"
+	leveldbErrors "github.com/syndtr/goleveldb/leveldb/errors"
+)
+
+// recoveryLogName is the file of the datadir the recovery records are appended
+// to, one JSON object per line.
+const recoveryLogName = "raft-recovery.log"
+
+// ErrRecoveryAborted is returned if the operator didn't confirm a recovery.
+var ErrRecoveryAborted = errors.New("raft cluster recovery aborted")
+
+// RecoveryRecord is the audit record of a raft cluster forcibly rebuilt from a
+// single surviving node.
+type RecoveryRecord struct {
+	Time             time.Time   `json:"time"`
+	Operator         string      `json:"operator"`
+	Reason           string      `json:"reason"`
+	RaftId           uint16      `json:"raftId"`           // Surviving node, the only member of the new cluster
+	DroppedMembers   []uint16    `json:"droppedMembers"`   // Previous members, permanently removed
+	Term             uint64      `json:"term"`             // Term of the surviving node
+	SnapshotIndex    uint64      `json:"snapshotIndex"`    // Last applied entry, snapshotted with the new membership
+	CommitIndex      uint64      `json:"commitIndex"`      // Last committed entry, kept in the new log
+	DiscardedEntries int         `json:"discardedEntries"` // Uncommitted entries, discarded
+	HeadBlockHash    common.Hash `json:"headBlockHash"`    // Last block applied by the surviving node
+	Backups          []string    `json:"backups"`          // Directories the previous raft state was moved to
+}
+
+// ForceNewCluster rebuilds a single-node raft cluster from the write-ahead log
+// and snapshot of the surviving node identified by self, after the loss of the
+// majority of the cluster. The node must be stopped.
+//
+// The entries the node applied are snapshotted with the node as the only
+// member, the other members being permanently removed so that they can only
+// rejoin as new peers. The committed entries it didn't apply yet are kept,
+// without their membership changes, while the uncommitted ones are discarded.
+// The previous raft state is moved aside rather than deleted.
+//
+// The recovery is described to confirm before anything is changed, and aborted
+// unless it returns true. The record of a completed recovery is appended to the
+// recovery log of the datadir.
+func ForceNewCluster(datadir string, self enode.EnodeID, operator, reason string, confirm func(*RecoveryRecord) bool) (*RecoveryRecord, error) {
+	waldir := fmt.Sprintf("%s/raft-wal", datadir)
+	snapdir := fmt.Sprintf("%s/raft-snap", datadir)
+	quorumRaftDbLoc := fmt.Sprintf("%s/quorum-raft-state", datadir)
+
+	if !wal.Exist(waldir) {
+		return nil, fmt.Errorf("no raft log in %s", waldir)
+	}
+	// The raft state database and log are locked while the node runs
+	db, err := openQuorumRaftDb(quorumRaftDbLoc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open raft state, is the node stopped? %v", err)
+	}
+	defer db.Close()
+
+	applied, err := readAppliedIndex(db)
+	if err != nil {
+		return nil, err
+	}
+	snapshot, err := snap.New(snapdir).Load()
+	if err != nil && err != snap.ErrNoSnapshot {
+		return nil, fmt.Errorf("failed to load raft snapshot: %v", err)
+	}
+	walsnap := walpb.Snapshot{}
+	if snapshot != nil {
+		walsnap.Index, walsnap.Term = snapshot.Metadata.Index, snapshot.Metadata.Term
+	}
+	w, err := wal.Open(waldir, walsnap)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open raft log, is the node stopped? %v", err)
+	}
+	_, hardState, entries, err := w.ReadAll()
+	w.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read raft log: %v", err)
+	}
+	plan, err := planRecovery(snapshot, hardState, entries, applied, self)
+	if err != nil {
+		return nil, err
+	}
+	record := plan.record
+	record.Time = time.Now().UTC()
+	record.Operator = operator
+	record.Reason = reason
+
+	if confirm != nil && !confirm(record) {
+		return nil, ErrRecoveryAborted
+	}
+	// Move the previous state aside and write the new one
+	suffix := fmt.Sprintf(".recovered-%d", record.Time.Unix())
+	for _, dir := range []string{waldir, snapdir} {
+		if _, err := os.Stat(dir); os.IsNotExist(err) {
+			continue
+		}
+		if err := os.Rename(dir, dir+suffix); err != nil {
+			return nil, err
+		}
+		record.Backups = append(record.Backups, dir+suffix)
+	}
+	if err := os.Mkdir(snapdir, 0750); err != nil {
+		return nil, err
+	}
+	if err := snap.New(snapdir).SaveSnap(plan.snapshot); err != nil {
+		return nil, err
+	}
+	if w, err = wal.Create(waldir, nil); err != nil {
+		return nil, err
+	}
+	defer w.Close()
+
+	if err := w.SaveSnapshot(walpb.Snapshot{Index: plan.snapshot.Metadata.Index, Term: plan.snapshot.Metadata.Term}); err != nil {
+		return nil, err
+	}
+	if err := w.Save(plan.hardState, plan.entries); err != nil {
+		return nil, err
+	}
+	if err := appendRecoveryRecord(fmt.Sprintf("%s/%s", datadir, recoveryLogName), record); err != nil {
+		return nil, err
+	}
+	log.Warn("Forced a new single-node raft cluster", "raft id", record.RaftId, "dropped", record.DroppedMembers, "index", record.CommitIndex, "operator", operator, "reason", reason)
+	return record, nil
+}
+
+// recoveryPlan is the raft state of a recovered cluster.
+type recoveryPlan struct {
+	record    *RecoveryRecord
+	snapshot  raftpb.Snapshot
+	hardState raftpb.HardState
+	entries   []raftpb.Entry
+}
+
+// planRecovery computes the state of the single-node cluster recovered from the
+// raft log and snapshot of the surviving node.
+func planRecovery(snapshot *raftpb.Snapshot, hardState raftpb.HardState, entries []raftpb.Entry, applied uint64, self enode.EnodeID) (*recoveryPlan, error) {
+	var (
+		members  = make(map[uint16]*Address)
+		learners = make(map[uint16]bool)
+		removed  = make(map[uint16]bool)
+
+		headBlockHash          common.Hash
+		snapIndex, snapTerm    uint64
+		commitIndex, discarded = hardState.Commit, 0
+	)
+	if snapshot != nil {
+		data := bytesToSnapshot(snapshot.Data)
+		for i := range data.Addresses {
+			members[data.Addresses[i].RaftId] = &data.Addresses[i]
+		}
+		for _, id := range snapshot.Metadata.ConfState.Learners {
+			learners[uint16(id)] = true
+		}
+		for _, id := range data.RemovedRaftIds {
+			removed[id] = true
+		}
+		headBlockHash = data.HeadBlockHash
+		snapIndex, snapTerm = snapshot.Metadata.Index, snapshot.Metadata.Term
+	}
+	// Recover from the last entry applied, at least the snapshot, at most the
+	// last committed entry
+	index := applied
+	if index < snapIndex {
+		index = snapIndex
+	}
+	if index > commitIndex {
+		index = commitIndex
+	}
+	if index == 0 {
+		return nil, errors.New("no applied raft entry to recover from")
+	}
+	term := snapTerm
+	var kept []raftpb.Entry
+	for _, entry := range entries {
+		if entry.Index > commitIndex {
+			discarded++
+			continue
+		}
+		switch entry.Type {
+		case raftpb.EntryNormal:
+			if entry.Index <= index && len(entry.Data) > 0 {
+				var block types.Block
+				if err := rlp.DecodeBytes(entry.Data, &block); err != nil {
+					return nil, fmt.Errorf("invalid block in raft entry %d: %v", entry.Index, err)
+				}
+				headBlockHash = block.Hash()
+			}
+		case raftpb.EntryConfChange:
+			var cc raftpb.ConfChange
+			if err := cc.Unmarshal(entry.Data); err != nil {
+				return nil, fmt.Errorf("invalid membership change in raft entry %d: %v", entry.Index, err)
+			}
+			raftId := uint16(cc.NodeID)
+			switch cc.Type {
+			case raftpb.ConfChangeAddNode, raftpb.ConfChangeAddLearnerNode:
+				if removed[raftId] {
+					break
+				}
+				if members[raftId] == nil && len(cc.Context) > 0 {
+					members[raftId] = bytesToAddress(cc.Context)
+				}
+				learners[raftId] = cc.Type == raftpb.ConfChangeAddLearnerNode
+			case raftpb.ConfChangeRemoveNode:
+				delete(members, raftId)
+				removed[raftId] = true
+			}
+			// Membership changes past the snapshot would undo the recovery
+			if entry.Index > index {
+				entry = raftpb.Entry{Type: raftpb.EntryNormal, Term: entry.Term, Index: entry.Index}
+			}
+		}
+		if entry.Index == index {
+			term = entry.Term
+		}
+		if entry.Index > index {
+			kept = append(kept, entry)
+		}
+	}
+	if headBlockHash == (common.Hash{}) {
+		return nil, errors.New("no block in the raft log to recover from")
+	}
+	var address *Address
+	for _, member := range members {
+		if member.NodeId == self {
+			address = member
+		}
+	}
+	switch {
+	case address == nil:
+		return nil, errors.New("this node is not a member of the raft cluster")
+	case learners[address.RaftId]:
+		return nil, errors.New("a learner can't recover the raft cluster, recover from a voter")
+	}
+	record := &RecoveryRecord{
+		RaftId:           address.RaftId,
+		DroppedMembers:   []uint16{},
+		Term:             hardState.Term,
+		SnapshotIndex:    index,
+		CommitIndex:      commitIndex,
+		DiscardedEntries: discarded,
+		HeadBlockHash:    headBlockHash,
+		Backups:          []string{},
+	}
+	for id := range members {
+		if id != address.RaftId {
+			record.DroppedMembers = append(record.DroppedMembers, id)
+			removed[id] = true
+		}
+	}
+	sort.Slice(record.DroppedMembers, func(i, j int) bool { return record.DroppedMembers[i] < record.DroppedMembers[j] })
+
+	removedIds := make([]uint16, 0, len(removed))
+	for id := range removed {
+		removedIds = append(removedIds, id)
+	}
+	sort.Slice(removedIds, func(i, j int) bool { return removedIds[i] < removedIds[j] })
+
+	data := &SnapshotWithHostnames{
+		Addresses:      []Address{*address},
+		RemovedRaftIds: removedIds,
+		HeadBlockHash:  headBlockHash,
+	}
+	return &recoveryPlan{
+		record: record,
+		snapshot: raftpb.Snapshot{
+			Data: data.toBytes(),
+			Metadata: raftpb.SnapshotMetadata{
+				ConfState: raftpb.ConfState{Nodes: []uint64{uint64(address.RaftId)}},
+				Index:     index,
+				Term:      term,
+			},
+		},
+		hardState: raftpb.HardState{Term: hardState.Term, Vote: uint64(address.RaftId), Commit: commitIndex},
+		entries:   kept,
+	}, nil
+}
+
+// readAppliedIndex reads the last raft entry applied by the node.
+func readAppliedIndex(db *leveldb.DB) (uint64, error) {
+	dat, err := db.Get(appliedDbKey, nil)
+	switch {
+	case err == leveldbErrors.ErrNotFound:
+		return 0, nil
+	case err != nil:
+		return 0, err
+	}
+	return binary.LittleEndian.Uint64(dat), nil
+}
+
+// appendRecoveryRecord appends a recovery record to the recovery log.
+func appendRecoveryRecord(path string, record *RecoveryRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	return f.Sync()
+}
@@ -0,0 +1,157 @@
+package raft
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/coreos/etcd/raft/raftpb"
+	"github.com/coreos/etcd/snap"
+	"github.com/coreos/etcd/wal"
+	"github.com/coreos/etcd/wal/walpb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/enr"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+func recoveryTestAddress(raftId uint16) Address {
+	return Address{RaftId: raftId, NodeId: enode.EnodeID{byte(raftId)}, P2pPort: enr.TCP(21000 + raftId), RaftPort: enr.RaftPort(50000 + raftId), Hostname: "127.0.0.1"}
+}
+
+func recoveryTestBlock(t *testing.T, number int64) []byte {
+	data, err := rlp.EncodeToBytes(types.NewBlockWithHeader(&types.Header{Number: big.NewInt(number), Difficulty: big.NewInt(0)}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// writeRecoveryTestState writes the raft state of node 2 of a three-node
+// cluster: a snapshot at index 5, a block applied at index 6, a learner added
+// and a block committed but not applied, and an uncommitted block.
+func writeRecoveryTestState(t *testing.T, datadir string) {
+	addresses := []Address{recoveryTestAddress(1), recoveryTestAddress(2), recoveryTestAddress(3)}
+	snapshot := raftpb.Snapshot{
+		Data: (&SnapshotWithHostnames{Addresses: addresses, RemovedRaftIds: []uint16{}, HeadBlockHash: types.NewBlockWithHeader(&types.Header{Number: big.NewInt(1)}).Hash()}).toBytes(),
+		Metadata: raftpb.SnapshotMetadata{
+			ConfState: raftpb.ConfState{Nodes: []uint64{1, 2, 3}},
+			Index:     5,
+			Term:      2,
+		},
+	}
+	os.Mkdir(datadir+"/raft-snap", 0750)
+	if err := snap.New(datadir + "/raft-snap").SaveSnap(snapshot); err != nil {
+		t.Fatal(err)
+	}
+	learner := recoveryTestAddress(4)
+	cc := raftpb.ConfChange{Type: raftpb.ConfChangeAddLearnerNode, NodeID: 4, Context: learner.toBytes()}
+	ccData, _ := cc.Marshal()
+
+	w, err := wal.Create(datadir+"/raft-wal", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.SaveSnapshot(walpb.Snapshot{Index: 5, Term: 2})
+	entries := []raftpb.Entry{
+		{Type: raftpb.EntryNormal, Term: 2, Index: 6, Data: recoveryTestBlock(t, 2)},
+		{Type: raftpb.EntryConfChange, Term: 3, Index: 7, Data: ccData},
+		{Type: raftpb.EntryNormal, Term: 3, Index: 8, Data: recoveryTestBlock(t, 3)},
+		{Type: raftpb.EntryNormal, Term: 3, Index: 9, Data: recoveryTestBlock(t, 4)},
+	}
+	if err := w.Save(raftpb.HardState{Term: 3, Vote: 1, Commit: 8}, entries); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+
+	db, err := openQuorumRaftDb(datadir + "/quorum-raft-state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 8)
+	binary.LittleEndian.PutUint64(buf, 6)
+	db.Put(appliedDbKey, buf, nil)
+	db.Close()
+}
+
+func TestForceNewCluster_whenMajorityLost(t *testing.T) {
+	datadir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(datadir)
+	writeRecoveryTestState(t, datadir)
+
+	self := recoveryTestAddress(2)
+	if _, err := ForceNewCluster(datadir, self.NodeId, "operator", "test", func(*RecoveryRecord) bool { return false }); err != ErrRecoveryAborted {
+		t.Fatalf("error mismatch: have %v, want %v", err, ErrRecoveryAborted)
+	}
+	record, err := ForceNewCluster(datadir, self.NodeId, "operator", "test", func(*RecoveryRecord) bool { return true })
+	if err != nil {
+		t.Fatalf("failed to recover: %v", err)
+	}
+	if record.RaftId != 2 || !reflect.DeepEqual(record.DroppedMembers, []uint16{1, 3, 4}) {
+		t.Errorf("membership mismatch: have raft id %d, dropped %v", record.RaftId, record.DroppedMembers)
+	}
+	if record.SnapshotIndex != 6 || record.CommitIndex != 8 || record.DiscardedEntries != 1 || len(record.Backups) != 2 {
+		t.Errorf("record mismatch: have %+v", record)
+	}
+	if want := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(2), Difficulty: big.NewInt(0)}).Hash(); record.HeadBlockHash != want {
+		t.Errorf("head block mismatch: have %x, want %x", record.HeadBlockHash, want)
+	}
+	// The new snapshot holds the surviving node alone
+	snapshot, err := snap.New(datadir + "/raft-snap").Load()
+	if err != nil {
+		t.Fatalf("failed to load the new snapshot: %v", err)
+	}
+	if !reflect.DeepEqual(snapshot.Metadata.ConfState.Nodes, []uint64{2}) || snapshot.Metadata.Index != 6 || snapshot.Metadata.Term != 2 {
+		t.Errorf("snapshot metadata mismatch: have %+v", snapshot.Metadata)
+	}
+	data := bytesToSnapshot(snapshot.Data)
+	if len(data.Addresses) != 1 || data.Addresses[0].RaftId != 2 || !reflect.DeepEqual(data.RemovedRaftIds, []uint16{1, 3, 4}) {
+		t.Errorf("snapshot membership mismatch: have %+v", data)
+	}
+	// The committed entries are kept without the membership change
+	w, err := wal.Open(datadir+"/raft-wal", walpb.Snapshot{Index: 6, Term: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, hardState, entries, err := w.ReadAll()
+	w.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hardState.Commit != 8 || hardState.Vote != 2 || hardState.Term != 3 {
+		t.Errorf("hard state mismatch: have %+v", hardState)
+	}
+	if len(entries) != 2 || entries[0].Type != raftpb.EntryNormal || len(entries[0].Data) != 0 || entries[1].Index != 8 {
+		t.Errorf("entries mismatch: have %+v", entries)
+	}
+	if _, err := os.Stat(datadir + "/" + recoveryLogName); err != nil {
+		t.Errorf("recovery not recorded: %v", err)
+	}
+}
+
+func TestForceNewCluster_fromLearner(t *testing.T) {
+	datadir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(datadir)
+	writeRecoveryTestState(t, datadir)
+
+	learner := recoveryTestAddress(4)
+	if _, err := ForceNewCluster(datadir, learner.NodeId, "operator", "test", nil); err == nil {
+		t.Errorf("recovery from a learner accepted")
+	}
+	stranger := recoveryTestAddress(5)
+	if _, err := ForceNewCluster(datadir, stranger.NodeId, "operator", "test", nil); err == nil {
+		t.Errorf("recovery from a non-member accepted")
+	}
+	if _, err := os.Stat(datadir + "/" + recoveryLogName); !os.IsNotExist(err) {
+		t.Errorf("failed recovery recorded")
+	}
+}
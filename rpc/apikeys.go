@@ -0,0 +1,279 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+// APIKeyHeader is the HTTP header carrying the API key of a request. Keys are
+// never accepted in the URL, where they would end up in the logs and the
+// histories of the proxies and browsers.
+const APIKeyHeader = "X-API-Key"
+
+var (
+	errAPIKeyMissing = errors.New("missing API key")
+	errAPIKeyUnknown = errors.New("unknown API key")
+	errAPIKeyInURL   = errors.New("API key must be sent in the " + APIKeyHeader + " header")
+)
+
+// APIKey is an API key of the HTTP and WebSocket endpoints, with its daily
+// quotas.
+type APIKey struct {
+	Key           string `json:"key"`
+	Name          string `json:"name"`          // Name of the key in the usage reports and metrics
+	DailyRequests uint64 `json:"dailyRequests"` // Calls allowed per UTC day (0 = unlimited)
+	DailyBytes    uint64 `json:"dailyBytes"`    // Request and response bytes allowed per UTC day (0 = unlimited)
+}
+
+// APIKeys authenticates the RPC requests by their API key, and accounts for
+// their usage against the daily quotas of their key.
+type APIKeys struct {
+	keys map[[sha256.Size]byte]*apiKeyUsage // Usage by hash of the key, not to leak the keys through lookup timing
+}
+
+// NewAPIKeys loads the API keys listed in a JSON file:
+//
+//	[{"key": "...", "name": "team-a", "dailyRequests": 100000, "dailyBytes": 1073741824}]
+func NewAPIKeys(file string) (*APIKeys, error) {
+	blob, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var keys []APIKey
+	if err := json.Unmarshal(blob, &keys); err != nil {
+		return nil, fmt.Errorf("invalid API keys in %s: %v", file, err)
+	}
+	return newAPIKeys(keys)
+}
+
+func newAPIKeys(keys []APIKey) (*APIKeys, error) {
+	ks := &APIKeys{keys: make(map[[sha256.Size]byte]*apiKeyUsage)}
+	names := make(map[string]bool)
+	for _, key := range keys {
+		switch {
+		case key.Key == "":
+			return nil, fmt.Errorf("empty API key %q", key.Name)
+		case key.Name == "":
+			return nil, errors.New("unnamed API key")
+		case names[key.Name]:
+			return nil, fmt.Errorf("duplicate API key name %q", key.Name)
+		}
+		hash := sha256.Sum256([]byte(key.Key))
+		if _, ok := ks.keys[hash]; ok {
+			return nil, fmt.Errorf("duplicate API key %q", key.Name)
+		}
+		names[key.Name] = true
+		ks.keys[hash] = &apiKeyUsage{
+			name:          key.Name,
+			dailyRequests: key.DailyRequests,
+			dailyBytes:    key.DailyBytes,
+			requestMeter:  metrics.GetOrRegisterCounter("rpc/apikey/"+key.Name+"/requests", nil),
+			bytesMeter:    metrics.GetOrRegisterCounter("rpc/apikey/"+key.Name+"/bytes", nil),
+			rejectMeter:   metrics.GetOrRegisterCounter("rpc/apikey/"+key.Name+"/rejected", nil),
+		}
+	}
+	return ks, nil
+}
+
+// authenticate returns the usage of the API key of a request.
+func (ks *APIKeys) authenticate(r *http.Request) (*apiKeyUsage, error) {
+	if _, ok := r.URL.Query()["apikey"]; ok {
+		return nil, errAPIKeyInURL
+	}
+	key := r.Header.Get(APIKeyHeader)
+	if key == "" {
+		return nil, errAPIKeyMissing
+	}
+	usage, ok := ks.keys[sha256.Sum256([]byte(key))]
+	if !ok {
+		return nil, errAPIKeyUnknown
+	}
+	return usage, nil
+}
+
+// APIKeyUsage is the usage of an API key.
+type APIKeyUsage struct {
+	Name          string `json:"name"`
+	Day           string `json:"day"`           // UTC day of the daily counters
+	Requests      uint64 `json:"requests"`      // Calls served today
+	Bytes         uint64 `json:"bytes"`         // Request and response bytes today
+	Rejected      uint64 `json:"rejected"`      // Calls rejected today, over the quotas
+	DailyRequests uint64 `json:"dailyRequests"` // Quota of calls (0 = unlimited)
+	DailyBytes    uint64 `json:"dailyBytes"`    // Quota of bytes (0 = unlimited)
+	TotalRequests uint64 `json:"totalRequests"` // Calls served since the node started
+	TotalBytes    uint64 `json:"totalBytes"`    // Bytes since the node started
+}
+
+// Usage returns the usage of every API key, sorted by name.
+func (ks *APIKeys) Usage() []APIKeyUsage {
+	usages := make([]APIKeyUsage, 0, len(ks.keys))
+	for _, usage := range ks.keys {
+		usages = append(usages, usage.report(time.Now()))
+	}
+	sort.Slice(usages, func(i, j int) bool { return usages[i].Name < usages[j].Name })
+	return usages
+}
+
+// apiKeyUsageKey is the context key of the usage of the API key of a connection.
+type apiKeyUsageKey struct{}
+
+// apiKeyUsageFromContext returns the usage of the API key of the connection
+// serving a request, nil if the endpoint doesn't require API keys.
+func apiKeyUsageFromContext(ctx context.Context) *apiKeyUsage {
+	usage, _ := ctx.Value(apiKeyUsageKey{}).(*apiKeyUsage)
+	return usage
+}
+
+// apiKeyUsage counts the usage of an API key, resetting the daily counters at
+// midnight UTC.
+type apiKeyUsage struct {
+	name          string
+	dailyRequests uint64
+	dailyBytes    uint64
+
+	requestMeter metrics.Counter
+	bytesMeter   metrics.Counter
+	rejectMeter  metrics.Counter
+
+	lock          sync.Mutex
+	day           string
+	requests      uint64
+	bytes         uint64
+	rejected      uint64
+	totalRequests uint64
+	totalBytes    uint64
+}
+
+// roll resets the daily counters if the day changed. The lock must be held.
+func (u *apiKeyUsage) roll(now time.Time) {
+	if day := now.UTC().Format("2006-01-02"); day != u.day {
+		u.day, u.requests, u.bytes, u.rejected = day, 0, 0, 0
+	}
+}
+
+// exhausted returns the quota of the key exhausted today, if any. The lock must
+// be held.
+func (u *apiKeyUsage) exhausted() string {
+	switch {
+	case u.dailyRequests > 0 && u.requests >= u.dailyRequests:
+		return "requests"
+	case u.dailyBytes > 0 && u.bytes >= u.dailyBytes:
+		return "bytes"
+	}
+	return ""
+}
+
+// charge counts a call against the quotas of the key, rejecting it if one of
+// them is exhausted.
+func (u *apiKeyUsage) charge(now time.Time) Error {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+
+	u.roll(now)
+	if quota := u.exhausted(); quota != "" {
+		u.rejected++
+		u.rejectMeter.Inc(1)
+		return &quotaExceededError{quota}
+	}
+	u.requests++
+	u.totalRequests++
+	u.requestMeter.Inc(1)
+	return nil
+}
+
+// admit reports whether the key may still make calls today.
+func (u *apiKeyUsage) admit(now time.Time) bool {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+
+	u.roll(now)
+	if u.exhausted() != "" {
+		u.rejected++
+		u.rejectMeter.Inc(1)
+		return false
+	}
+	return true
+}
+
+// transfer counts request or response bytes, against the day of the last call.
+func (u *apiKeyUsage) transfer(n int) {
+	if n <= 0 {
+		return
+	}
+	u.lock.Lock()
+	defer u.lock.Unlock()
+
+	u.bytes += uint64(n)
+	u.totalBytes += uint64(n)
+	u.bytesMeter.Inc(int64(n))
+}
+
+// report returns the usage of the key.
+func (u *apiKeyUsage) report(now time.Time) APIKeyUsage {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+
+	u.roll(now)
+	return APIKeyUsage{
+		Name:          u.name,
+		Day:           u.day,
+		Requests:      u.requests,
+		Bytes:         u.bytes,
+		Rejected:      u.rejected,
+		DailyRequests: u.dailyRequests,
+		DailyBytes:    u.dailyBytes,
+		TotalRequests: u.totalRequests,
+		TotalBytes:    u.totalBytes,
+	}
+}
+
+// meteredReader counts the bytes read against an API key.
+type meteredReader struct {
+	io.Reader
+	usage *apiKeyUsage
+}
+
+func (r *meteredReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.usage.transfer(n)
+	return n, err
+}
+
+// meteredWriter counts the bytes written against an API key.
+type meteredWriter struct {
+	io.Writer
+	usage *apiKeyUsage
+}
+
+func (w *meteredWriter) Write(p []byte) (int, error) {
+	n, err := w.Writer.Write(p)
+	w.usage.transfer(n)
+	return n, err
+}
@@ -0,0 +1,143 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Tests that HTTP requests are served only with a known API key, within the
+// daily quotas of the key.
+func TestAPIKeysHTTP(t *testing.T) {
+	keys, err := newAPIKeys([]APIKey{
+		{Key: "limited", Name: "team-a", DailyRequests: 2},
+		{Key: "unlimited", Name: "team-b"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := newTestServer("service", new(Service))
+	server.keys = keys
+	defer server.Stop()
+
+	httpsrv := httptest.NewServer(server)
+	defer httpsrv.Close()
+
+	call := `{"jsonrpc":"2.0","id":1,"method":"service_noArgsRets"}`
+	tests := []struct {
+		key    string
+		query  string
+		body   string
+		status int
+		errors int
+	}{
+		{"", "", call, http.StatusUnauthorized, 0},
+		{"forged", "", call, http.StatusUnauthorized, 0},
+		{"", "?apikey=unlimited", call, http.StatusUnauthorized, 0}, // Keys in the URL are refused
+		{"unlimited", "?apikey=unlimited", call, http.StatusUnauthorized, 0},
+		{"unlimited", "", call, http.StatusOK, 0},
+		{"limited", "", call, http.StatusOK, 0},
+		{"limited", "", "[" + call + "," + call + "]", http.StatusOK, 1}, // Second call over the quota
+		{"limited", "", call, http.StatusTooManyRequests, 0},
+		{"unlimited", "", call, http.StatusOK, 0},
+	}
+	for i, tt := range tests {
+		req, _ := http.NewRequest("POST", httpsrv.URL+tt.query, strings.NewReader(tt.body))
+		req.Header.Set("content-type", contentType)
+		if tt.key != "" {
+			req.Header.Set(APIKeyHeader, tt.key)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("test %d: request failed: %v", i, err)
+		}
+		var replies []jsonErrResponse
+		if strings.HasPrefix(tt.body, "[") {
+			json.NewDecoder(resp.Body).Decode(&replies)
+		} else {
+			var reply jsonErrResponse
+			json.NewDecoder(resp.Body).Decode(&reply)
+			replies = append(replies, reply)
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode != tt.status {
+			t.Errorf("test %d: status mismatch: have %d, want %d", i, resp.StatusCode, tt.status)
+		}
+		if resp.StatusCode != http.StatusOK {
+			continue
+		}
+		errors := 0
+		for _, reply := range replies {
+			if reply.Error.Code != 0 {
+				if reply.Error.Code != CodeQuotaExceeded {
+					t.Errorf("test %d: unexpected error %v", i, reply.Error)
+				}
+				errors++
+			}
+		}
+		if errors != tt.errors {
+			t.Errorf("test %d: error count mismatch: have %d, want %d", i, errors, tt.errors)
+		}
+	}
+	usage := keys.Usage()
+	if len(usage) != 2 || usage[0].Name != "team-a" || usage[1].Name != "team-b" {
+		t.Fatalf("usage mismatch: %+v", usage)
+	}
+	if usage[0].Requests != 2 || usage[0].Rejected != 2 || usage[1].Requests != 2 {
+		t.Errorf("request counts mismatch: %+v", usage)
+	}
+	if usage[0].Bytes == 0 || usage[0].Bytes != usage[0].TotalBytes {
+		t.Errorf("byte counts mismatch: %+v", usage[0])
+	}
+}
+
+// Tests that the daily counters of an API key reset at midnight UTC, and that
+// the byte quota rejects the calls once exhausted.
+func TestAPIKeyDailyQuota(t *testing.T) {
+	keys, err := newAPIKeys([]APIKey{{Key: "key", Name: "team", DailyBytes: 100}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var (
+		usage = keys.keys[sha256.Sum256([]byte("key"))]
+		today = time.Date(2019, 6, 1, 23, 59, 0, 0, time.UTC)
+	)
+	if err := usage.charge(today); err != nil {
+		t.Fatalf("call rejected: %v", err)
+	}
+	usage.transfer(100)
+	if err := usage.charge(today); err == nil || err.ErrorCode() != CodeQuotaExceeded {
+		t.Fatalf("call over the byte quota not rejected: %v", err)
+	}
+	if err := usage.charge(today.Add(2 * time.Minute)); err != nil {
+		t.Fatalf("call rejected the next day: %v", err)
+	}
+	report := usage.report(today.Add(2 * time.Minute))
+	if report.Day != "2019-06-02" || report.Requests != 1 || report.Bytes != 0 || report.TotalRequests != 2 || report.TotalBytes != 100 {
+		t.Errorf("usage mismatch: %+v", report)
+	}
+	if _, err := newAPIKeys([]APIKey{{Key: "a", Name: "team"}, {Key: "b", Name: "team"}}); err == nil {
+		t.Errorf("duplicate key names accepted")
+	}
+}
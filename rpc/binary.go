@@ -0,0 +1,1019 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"bufio"
+	"bytes"
+	"encoding"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"mime"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+const (
+	cborContentType    = "application/cbor"
+	msgpackContentType = "application/msgpack"
+)
+
+// binaryMaxDepth is the maximum nesting of arrays and maps accepted in binary
+// encoded requests.
+const binaryMaxDepth = 128
+
+var (
+	errBinaryDepth  = errors.New("binary encoded value nested too deeply")
+	errBinaryLength = errors.New("binary encoded value too large")
+	errBinaryMapKey = errors.New("binary encoded map key is not a string")
+)
+
+// binaryEncoding is a binary serialization usable as an alternative to JSON on
+// the HTTP transport.
+//
+// Requests are decoded into generic values, byte strings and integers kept as
+// such, which are then assigned to the arguments of the methods called.
+// Responses are written from the Go values returned, byte strings and integers
+// hex encoded in JSON being written as native byte strings and integers. Only
+// the values of types with their own JSON marshalling, or unmarshalling, go
+// through their JSON form.
+type binaryEncoding struct {
+	// decode reads a single value from the stream. Maps are returned as
+	// map[string]interface{}, arrays as []interface{}, byte strings as []byte and
+	// integers as uint64, int64 or *big.Int.
+	decode func(r *bufio.Reader) (interface{}, error)
+
+	// writer returns the writer of values in the encoding.
+	writer func(w *bufio.Writer) binaryWriter
+}
+
+// binaryWriter writes the items of an encoding. Maps and arrays are written as
+// their size, followed by their items.
+type binaryWriter interface {
+	writeNull()
+	writeBool(v bool)
+	writeInt(v int64)
+	writeUint(v uint64)
+	writeBigInt(v *big.Int)
+	writeFloat(v float64)
+	writeString(s string)
+	writeBytes(b []byte)
+	writeArrayHead(size int)
+	writeMapHead(size int)
+}
+
+// binaryEncodings are the supported encodings by their media types.
+var binaryEncodings = map[string]*binaryEncoding{
+	cborContentType:         {decode: decodeCBOR, writer: newCBORWriter},
+	msgpackContentType:      {decode: decodeMsgpack, writer: newMsgpackWriter},
+	"application/x-msgpack": {decode: decodeMsgpack, writer: newMsgpackWriter},
+}
+
+// supportedContentType reports whether the media type can be used to encode
+// requests and responses.
+func supportedContentType(mt string) bool {
+	return mt == contentType || binaryEncodings[mt] != nil
+}
+
+// negotiateContentType returns the media type of the response to a request of
+// the given media type: the first supported one listed in the Accept header, or
+// the type of the request itself.
+func negotiateContentType(reqType, accept string) string {
+	for _, part := range strings.Split(accept, ",") {
+		if mt, _, err := mime.ParseMediaType(strings.TrimSpace(part)); err == nil && supportedContentType(mt) {
+			return mt
+		}
+	}
+	return reqType
+}
+
+// newNegotiatedCodec creates a codec reading requests in the reqType encoding
+// and writing responses in the respType one.
+func newNegotiatedCodec(rwc io.ReadWriteCloser, reqType, respType string) ServerCodec {
+	if reqType == contentType && respType == contentType {
+		return NewJSONCodec(rwc)
+	}
+	encode := json.NewEncoder(rwc).Encode
+	if enc := binaryEncodings[respType]; enc != nil {
+		encode = func(v interface{}) error {
+			bw := bufio.NewWriter(rwc)
+			if err := writeBinary(enc.writer(bw), reflect.ValueOf(v)); err != nil {
+				return err
+			}
+			return bw.Flush()
+		}
+	}
+	enc := binaryEncodings[reqType]
+	if enc == nil {
+		dec := json.NewDecoder(rwc)
+		dec.UseNumber()
+		return NewCodec(rwc, encode, dec.Decode)
+	}
+	br := bufio.NewReader(rwc)
+	return &binaryCodec{
+		jsonCodec: NewCodec(rwc, encode, nil).(*jsonCodec),
+		read:      func() (interface{}, error) { return enc.decode(br) },
+	}
+}
+
+// binaryCodec reads binary encoded requests, and writes the responses as a JSON
+// codec does, with its encoding function.
+type binaryCodec struct {
+	*jsonCodec
+	read func() (interface{}, error)
+}
+
+// ReadRequestHeaders reads the next request, or batch of requests, without
+// converting their arguments.
+func (c *binaryCodec) ReadRequestHeaders() ([]rpcRequest, bool, Error) {
+	c.decMu.Lock()
+	defer c.decMu.Unlock()
+
+	msg, err := c.read()
+	if err != nil {
+		return nil, false, &invalidRequestError{err.Error()}
+	}
+	batch, ok := msg.([]interface{})
+	if !ok {
+		req, err := parseBinaryRequest(msg, false)
+		if err != nil {
+			return nil, false, err
+		}
+		return []rpcRequest{req}, false, nil
+	}
+	requests := make([]rpcRequest, len(batch))
+	for i, msg := range batch {
+		req, err := parseBinaryRequest(msg, true)
+		if err != nil {
+			return nil, false, err
+		}
+		requests[i] = req
+	}
+	return requests, true, nil
+}
+
+// parseBinaryRequest converts a decoded request. Within a batch, the request of
+// an invalid method carries its error rather than failing the batch.
+func parseBinaryRequest(msg interface{}, batch bool) (rpcRequest, Error) {
+	fields, ok := msg.(map[string]interface{})
+	if !ok {
+		return rpcRequest{}, &invalidMessageError{"request is not a map"}
+	}
+	id, ok := fields["id"]
+	if !ok {
+		return rpcRequest{}, &invalidMessageError{"missing request id"}
+	}
+	switch id.(type) {
+	case nil, string, uint64, int64, *big.Int, float64:
+	default:
+		return rpcRequest{}, &invalidMessageError{"invalid request id"}
+	}
+	method, _ := fields["method"].(string)
+	params := fields["params"]
+
+	// Subscriptions are special, the name of the subscription being their first
+	// argument
+	if strings.HasSuffix(method, subscribeMethodSuffix) {
+		if args, ok := params.([]interface{}); ok && len(args) > 0 {
+			if name, ok := args[0].(string); ok {
+				service := strings.TrimSuffix(method, subscribeMethodSuffix)
+				return rpcRequest{service: service, method: name, id: id, isPubSub: true, params: params}, nil
+			}
+		}
+		return rpcRequest{}, &invalidRequestError{"Unable to parse subscription request"}
+	}
+	if strings.HasSuffix(method, unsubscribeMethodSuffix) {
+		return rpcRequest{method: method, id: id, isPubSub: true, params: params}, nil
+	}
+	req := rpcRequest{id: id, params: params}
+	if elems := strings.Split(method, serviceMethodSeparator); len(elems) == 2 {
+		req.service, req.method = elems[0], elems[1]
+	} else if batch {
+		req.err = &methodNotFoundError{method, ""}
+	} else {
+		return rpcRequest{}, &methodNotFoundError{method, ""}
+	}
+	return req, nil
+}
+
+// ParseRequestArguments assigns the decoded arguments of a request to values of
+// the given types. Missing optional arguments are returned as reflect.Zero
+// values.
+func (c *binaryCodec) ParseRequestArguments(argTypes []reflect.Type, params interface{}) ([]reflect.Value, Error) {
+	items, ok := params.([]interface{})
+	if !ok {
+		return nil, &invalidParamsError{"non-array args"}
+	}
+	if len(items) > len(argTypes) {
+		return nil, &invalidParamsError{fmt.Sprintf("too many arguments, want at most %d", len(argTypes))}
+	}
+	args := make([]reflect.Value, 0, len(argTypes))
+	for i, item := range items {
+		arg := reflect.New(argTypes[i]).Elem()
+		if err := assignBinary(arg, item); err != nil {
+			return nil, &invalidParamsError{fmt.Sprintf("invalid argument %d: %v", i, err)}
+		}
+		args = append(args, arg)
+	}
+	for i := len(args); i < len(argTypes); i++ {
+		if argTypes[i].Kind() != reflect.Ptr {
+			return nil, &invalidParamsError{fmt.Sprintf("missing value for required argument %d", i)}
+		}
+		args = append(args, reflect.Zero(argTypes[i]))
+	}
+	return args, nil
+}
+
+var (
+	bigIntType          = reflect.TypeOf(big.Int{})
+	hexBigType          = reflect.TypeOf(hexutil.Big{})
+	bigIntPtrType       = reflect.TypeOf((*big.Int)(nil))
+	rawMessageType      = reflect.TypeOf(json.RawMessage(nil))
+	jsonMarshalerType   = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// writeBinary writes a Go value as encoding/json would marshal it, but for the
+// big integers, and the byte strings and integers whose text form is their hex
+// encoding, which are written natively. The values of other types with their
+// own JSON marshalling are written from their JSON form.
+func writeBinary(w binaryWriter, v reflect.Value) error {
+	for v.IsValid() && (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			break
+		}
+		// The marshallers of pointer types are found on the addressable elements
+		v = v.Elem()
+	}
+	if !v.IsValid() || ((v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && v.IsNil()) {
+		w.writeNull()
+		return nil
+	}
+	t := v.Type()
+	if !v.CanAddr() && implementsMarshaler(reflect.PtrTo(t)) {
+		// Copy the value, for the marshallers of the pointer type to be found
+		copied := reflect.New(t).Elem()
+		copied.Set(v)
+		v = copied
+	}
+	switch {
+	case t == bigIntType || t == hexBigType:
+		w.writeBigInt(v.Addr().Convert(bigIntPtrType).Interface().(*big.Int))
+		return nil
+
+	case t == rawMessageType:
+		return writeBinaryJSON(w, v.Bytes())
+
+	case isByteSequence(t) && (t.Kind() == reflect.Slice || implementsText(t)):
+		if t.Kind() == reflect.Slice && v.IsNil() && !implementsText(t) {
+			w.writeNull()
+			return nil
+		}
+		b := make([]byte, v.Len())
+		reflect.Copy(reflect.ValueOf(b), v)
+		if text, ok := marshalText(v); ok && text != hexBytes(b) {
+			w.writeString(text)
+		} else if !ok && implementsMarshaler(t) {
+			return writeBinaryMarshaler(w, v)
+		} else {
+			w.writeBytes(b)
+		}
+		return nil
+
+	case isIntegerKind(t.Kind()) && implementsMarshaler(t):
+		// Integers are written natively if their text form is the hex one
+		if text, ok := marshalText(v); ok {
+			if n, err := hexutil.DecodeBig(text); err == nil {
+				w.writeBigInt(n)
+			} else {
+				w.writeString(text)
+			}
+			return nil
+		}
+		return writeBinaryMarshaler(w, v)
+
+	case implementsMarshaler(t):
+		return writeBinaryMarshaler(w, v)
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		w.writeBool(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		w.writeInt(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		w.writeUint(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		w.writeFloat(v.Float())
+	case reflect.String:
+		w.writeString(v.String())
+
+	case reflect.Slice:
+		if v.IsNil() {
+			w.writeNull()
+			return nil
+		}
+		fallthrough
+	case reflect.Array:
+		w.writeArrayHead(v.Len())
+		for i := 0; i < v.Len(); i++ {
+			if err := writeBinary(w, v.Index(i)); err != nil {
+				return err
+			}
+		}
+
+	case reflect.Map:
+		if v.IsNil() {
+			w.writeNull()
+			return nil
+		}
+		keys := make([]string, 0, v.Len())
+		values := make(map[string]reflect.Value, v.Len())
+		for _, key := range v.MapKeys() {
+			name, err := binaryMapKey(key)
+			if err != nil {
+				return err
+			}
+			keys = append(keys, name)
+			values[name] = v.MapIndex(key)
+		}
+		sort.Strings(keys)
+		w.writeMapHead(len(keys))
+		for _, key := range keys {
+			w.writeString(key)
+			if err := writeBinary(w, values[key]); err != nil {
+				return err
+			}
+		}
+
+	case reflect.Struct:
+		var (
+			fields = binaryFields(t)
+			names  = make([]string, 0, len(fields))
+			values = make([]reflect.Value, 0, len(fields))
+		)
+		for _, field := range fields {
+			fv := fieldByIndex(v, field.index, false)
+			if !fv.IsValid() || (field.omitEmpty && isEmptyValue(fv)) {
+				continue
+			}
+			names, values = append(names, field.name), append(values, fv)
+		}
+		w.writeMapHead(len(names))
+		for i, name := range names {
+			w.writeString(name)
+			if err := writeBinary(w, values[i]); err != nil {
+				return err
+			}
+		}
+
+	default:
+		return &json.UnsupportedTypeError{Type: t}
+	}
+	return nil
+}
+
+// writeBinaryMarshaler writes a value of a type with its own JSON marshalling.
+func writeBinaryMarshaler(w binaryWriter, v reflect.Value) error {
+	if text, ok := marshalText(v); ok && !implementsJSON(v.Type()) {
+		w.writeString(text)
+		return nil
+	}
+	m, ok := v.Interface().(json.Marshaler)
+	if !ok {
+		m = v.Addr().Interface().(json.Marshaler)
+	}
+	blob, err := m.MarshalJSON()
+	if err != nil {
+		return err
+	}
+	return writeBinaryJSON(w, blob)
+}
+
+// writeBinaryJSON writes a JSON document.
+func writeBinaryJSON(w binaryWriter, blob []byte) error {
+	if len(blob) == 0 {
+		w.writeNull()
+		return nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(blob))
+	dec.UseNumber()
+
+	val, err := readJSONValue(dec)
+	if err != nil {
+		return err
+	}
+	writeJSONValue(w, val)
+	return nil
+}
+
+// writeJSONValue writes a value returned by readJSONValue.
+func writeJSONValue(w binaryWriter, val interface{}) {
+	switch val := val.(type) {
+	case []jsonField:
+		w.writeMapHead(len(val))
+		for _, field := range val {
+			w.writeString(field.key)
+			writeJSONValue(w, field.value)
+		}
+	case []interface{}:
+		w.writeArrayHead(len(val))
+		for _, item := range val {
+			writeJSONValue(w, item)
+		}
+	case json.Number:
+		if n, ok := new(big.Int).SetString(string(val), 10); ok {
+			w.writeBigInt(n)
+		} else {
+			f, _ := val.Float64()
+			w.writeFloat(f)
+		}
+	case string:
+		w.writeString(val)
+	case bool:
+		w.writeBool(val)
+	case nil:
+		w.writeNull()
+	}
+}
+
+// assignBinary sets an addressable value to a decoded one, as encoding/json
+// would unmarshal it, but for the byte strings and integers, which are assigned
+// natively to the byte sequences, big integers and integers. The values of other
+// types with their own JSON unmarshalling are assigned from their JSON form.
+func assignBinary(dst reflect.Value, src interface{}) error {
+	if src == nil {
+		dst.Set(reflect.Zero(dst.Type()))
+		return nil
+	}
+	if dst.Kind() == reflect.Ptr {
+		if dst.IsNil() {
+			dst.Set(reflect.New(dst.Type().Elem()))
+		}
+		return assignBinary(dst.Elem(), src)
+	}
+	if dst.CanAddr() {
+		if ok, err := assignTyped(dst.Addr().Interface(), src); ok {
+			return err
+		}
+	}
+	t := dst.Type()
+	if b, ok := src.([]byte); ok {
+		if !isByteSequence(t) {
+			// Byte strings are hex strings elsewhere
+			return assignBinary(dst, hexBytes(b))
+		}
+		if t.Kind() == reflect.Array {
+			if len(b) != t.Len() {
+				return fmt.Errorf("byte string of %d bytes for %v of %d", len(b), t, t.Len())
+			}
+			reflect.Copy(dst, reflect.ValueOf(b))
+		} else {
+			dst.SetBytes(append([]byte{}, b...))
+		}
+		return nil
+	}
+	if t == bigIntType || t == hexBigType {
+		if n, ok := binaryBigInt(src); ok {
+			dst.Addr().Convert(bigIntPtrType).Interface().(*big.Int).Set(n)
+			return nil
+		}
+	}
+	if reflect.PtrTo(t).Implements(jsonUnmarshalerType) {
+		if n, ok := binaryBigInt(src); ok && isIntegerKind(t.Kind()) {
+			return setBinaryInt(dst, n)
+		}
+		blob, err := json.Marshal(plainValue(src, true))
+		if err != nil {
+			return err
+		}
+		return dst.Addr().Interface().(json.Unmarshaler).UnmarshalJSON(blob)
+	}
+	if s, ok := src.(string); ok && reflect.PtrTo(t).Implements(textUnmarshalerType) {
+		return dst.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+	}
+	switch t.Kind() {
+	case reflect.Interface:
+		if t.NumMethod() != 0 {
+			break
+		}
+		dst.Set(reflect.ValueOf(plainValue(src, false)))
+		return nil
+
+	case reflect.Bool:
+		if b, ok := src.(bool); ok {
+			dst.SetBool(b)
+			return nil
+		}
+	case reflect.String:
+		if s, ok := src.(string); ok {
+			dst.SetString(s)
+			return nil
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if n, ok := binaryBigInt(src); ok {
+			return setBinaryInt(dst, n)
+		}
+		if f, ok := src.(float64); ok && f == math.Trunc(f) && !math.IsInf(f, 0) {
+			n, _ := big.NewFloat(f).Int(nil)
+			return setBinaryInt(dst, n)
+		}
+	case reflect.Float32, reflect.Float64:
+		if n, ok := binaryBigInt(src); ok {
+			f, _ := new(big.Float).SetInt(n).Float64()
+			dst.SetFloat(f)
+			return nil
+		}
+		if f, ok := src.(float64); ok {
+			dst.SetFloat(f)
+			return nil
+		}
+
+	case reflect.Slice, reflect.Array:
+		items, ok := src.([]interface{})
+		if !ok {
+			break
+		}
+		if t.Kind() == reflect.Slice {
+			dst.Set(reflect.MakeSlice(t, len(items), len(items)))
+		} else if len(items) > t.Len() {
+			return fmt.Errorf("array of %d items for %v", len(items), t)
+		}
+		for i, item := range items {
+			if err := assignBinary(dst.Index(i), item); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case reflect.Map:
+		fields, ok := src.(map[string]interface{})
+		if !ok || t.Key().Kind() != reflect.String {
+			break
+		}
+		if dst.IsNil() {
+			dst.Set(reflect.MakeMap(t))
+		}
+		for key, value := range fields {
+			elem := reflect.New(t.Elem()).Elem()
+			if err := assignBinary(elem, value); err != nil {
+				return err
+			}
+			dst.SetMapIndex(reflect.ValueOf(key).Convert(t.Key()), elem)
+		}
+		return nil
+
+	case reflect.Struct:
+		fields, ok := src.(map[string]interface{})
+		if !ok {
+			break
+		}
+		for _, field := range binaryFields(t) {
+			value, ok := fields[field.name]
+			if !ok {
+				// Names are matched case insensitively, as by encoding/json
+				for key, v := range fields {
+					if strings.EqualFold(key, field.name) {
+						value, ok = v, true
+						break
+					}
+				}
+			}
+			if ok {
+				if err := assignBinary(fieldByIndex(dst, field.index, true), value); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("cannot assign %s to %v", binaryKind(src), t)
+}
+
+// assignTyped sets the common argument types of the RPC methods through typed
+// conversions, without reflecting on them. It reports whether the destination
+// and the decoded value were handled, the others are left to assignBinary.
+func assignTyped(dst interface{}, src interface{}) (bool, error) {
+	switch dst := dst.(type) {
+	case *string:
+		if s, ok := src.(string); ok {
+			*dst = s
+			return true, nil
+		}
+	case *bool:
+		if b, ok := src.(bool); ok {
+			*dst = b
+			return true, nil
+		}
+	case *interface{}:
+		*dst = plainValue(src, false)
+		return true, nil
+
+	case *[]byte:
+		if b, ok := src.([]byte); ok {
+			*dst = append([]byte{}, b...)
+			return true, nil
+		}
+	case *hexutil.Bytes:
+		if b, ok := src.([]byte); ok {
+			*dst = append(hexutil.Bytes{}, b...)
+			return true, nil
+		}
+	case *common.Hash:
+		if b, ok := src.([]byte); ok {
+			if len(b) != common.HashLength {
+				return true, fmt.Errorf("byte string of %d bytes for hash", len(b))
+			}
+			dst.SetBytes(b)
+			return true, nil
+		}
+	case *common.Address:
+		if b, ok := src.([]byte); ok {
+			if len(b) != common.AddressLength {
+				return true, fmt.Errorf("byte string of %d bytes for address", len(b))
+			}
+			dst.SetBytes(b)
+			return true, nil
+		}
+
+	case *uint64:
+		if n, ok := binaryBigInt(src); ok {
+			v, err := binaryUint64(n)
+			*dst = v
+			return true, err
+		}
+	case *hexutil.Uint64:
+		if n, ok := binaryBigInt(src); ok {
+			v, err := binaryUint64(n)
+			*dst = hexutil.Uint64(v)
+			return true, err
+		}
+	case *int:
+		if n, ok := binaryBigInt(src); ok {
+			if !n.IsInt64() || int64(int(n.Int64())) != n.Int64() {
+				return true, fmt.Errorf("integer %v overflows int", n)
+			}
+			*dst = int(n.Int64())
+			return true, nil
+		}
+	case *BlockNumber:
+		if n, ok := binaryBigInt(src); ok {
+			if !n.IsInt64() {
+				return true, fmt.Errorf("integer %v overflows block number", n)
+			}
+			*dst = BlockNumber(n.Int64())
+			return true, nil
+		}
+	case *big.Int:
+		if n, ok := binaryBigInt(src); ok {
+			dst.Set(n)
+			return true, nil
+		}
+	case *hexutil.Big:
+		if n, ok := binaryBigInt(src); ok {
+			(*big.Int)(dst).Set(n)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// binaryUint64 returns a decoded integer as an uint64, failing if it overflows.
+func binaryUint64(n *big.Int) (uint64, error) {
+	if !n.IsUint64() {
+		return 0, fmt.Errorf("integer %v overflows uint64", n)
+	}
+	return n.Uint64(), nil
+}
+
+// setBinaryInt sets an integer value, failing if it overflows.
+func setBinaryInt(dst reflect.Value, n *big.Int) error {
+	switch dst.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if n.IsInt64() && !dst.OverflowInt(n.Int64()) {
+			dst.SetInt(n.Int64())
+			return nil
+		}
+	default:
+		if n.IsUint64() && !dst.OverflowUint(n.Uint64()) {
+			dst.SetUint(n.Uint64())
+			return nil
+		}
+	}
+	return fmt.Errorf("integer %v overflows %v", n, dst.Type())
+}
+
+// binaryBigInt returns a decoded integer as a big integer.
+func binaryBigInt(src interface{}) (*big.Int, bool) {
+	switch src := src.(type) {
+	case uint64:
+		return new(big.Int).SetUint64(src), true
+	case int64:
+		return big.NewInt(src), true
+	case *big.Int:
+		return src, true
+	}
+	return nil, false
+}
+
+// plainValue converts a decoded value into the one encoding/json produces for
+// an interface{}: byte strings become hex strings and integers float64. Numbers
+// are kept exact as json.Number instead if requested, to be marshalled back.
+func plainValue(src interface{}, exact bool) interface{} {
+	switch src := src.(type) {
+	case []byte:
+		return hexBytes(src)
+	case uint64, int64, *big.Int:
+		n, _ := binaryBigInt(src)
+		if exact {
+			return json.Number(n.String())
+		}
+		f, _ := new(big.Float).SetInt(n).Float64()
+		return f
+	case float64:
+		if exact {
+			return json.Number(strconv.FormatFloat(src, 'g', -1, 64))
+		}
+	case []interface{}:
+		items := make([]interface{}, len(src))
+		for i, item := range src {
+			items[i] = plainValue(item, exact)
+		}
+		return items
+	case map[string]interface{}:
+		fields := make(map[string]interface{}, len(src))
+		for key, value := range src {
+			fields[key] = plainValue(value, exact)
+		}
+		return fields
+	}
+	return src
+}
+
+// binaryKind names the kind of a decoded value, for errors.
+func binaryKind(src interface{}) string {
+	switch src.(type) {
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case uint64, int64, *big.Int:
+		return "integer"
+	case float64:
+		return "float"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "map"
+	}
+	return fmt.Sprintf("%T", src)
+}
+
+// binaryField is a struct field as named in JSON.
+type binaryField struct {
+	name      string
+	index     []int
+	omitEmpty bool
+}
+
+var binaryFieldCache sync.Map // reflect.Type -> []binaryField
+
+// binaryFields returns the fields of a struct type marshalled by encoding/json,
+// the fields of embedded structs included unless shadowed.
+func binaryFields(t reflect.Type) []binaryField {
+	if fields, ok := binaryFieldCache.Load(t); ok {
+		return fields.([]binaryField)
+	}
+	var fields, promoted []binaryField
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts := tag, ""
+		if idx := strings.Index(tag, ","); idx >= 0 {
+			name, opts = tag[:idx], tag[idx:]
+		}
+		if sf.Anonymous && name == "" {
+			ft := sf.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				for _, field := range binaryFields(ft) {
+					index := append([]int{i}, field.index...)
+					promoted = append(promoted, binaryField{field.name, index, field.omitEmpty})
+				}
+				continue
+			}
+		}
+		if sf.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		fields = append(fields, binaryField{name, []int{i}, strings.Contains(opts+",", ",omitempty,")})
+	}
+	for _, field := range promoted {
+		shadowed := false
+		for _, f := range fields {
+			if f.name == field.name {
+				shadowed = true
+				break
+			}
+		}
+		if !shadowed {
+			fields = append(fields, field)
+		}
+	}
+	// Fields are ordered as declared, the promoted ones in place of their struct
+	sort.SliceStable(fields, func(i, j int) bool {
+		a, b := fields[i].index, fields[j].index
+		for k := 0; k < len(a) && k < len(b); k++ {
+			if a[k] != b[k] {
+				return a[k] < b[k]
+			}
+		}
+		return len(a) < len(b)
+	})
+	binaryFieldCache.Store(t, fields)
+	return fields
+}
+
+// fieldByIndex returns a possibly promoted field of a struct value, allocating
+// the nil embedded structs on the way if requested, or returning an invalid
+// value otherwise.
+func fieldByIndex(v reflect.Value, index []int, alloc bool) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				if !alloc {
+					return reflect.Value{}
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
+
+// binaryMapKey returns the name of a map key, as encoding/json would.
+func binaryMapKey(key reflect.Value) (string, error) {
+	if key.Kind() == reflect.String {
+		return key.String(), nil
+	}
+	if text, ok := marshalText(key); ok {
+		return text, nil
+	}
+	switch key.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(key.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(key.Uint(), 10), nil
+	}
+	return "", &json.UnsupportedTypeError{Type: key.Type()}
+}
+
+// isEmptyValue reports whether a value is omitted by the omitempty option.
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+	return false
+}
+
+func isByteSequence(t reflect.Type) bool {
+	return (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) && t.Elem().Kind() == reflect.Uint8
+}
+
+func isIntegerKind(k reflect.Kind) bool {
+	return k >= reflect.Int && k <= reflect.Uintptr
+}
+
+func implementsJSON(t reflect.Type) bool {
+	return t.Implements(jsonMarshalerType) || reflect.PtrTo(t).Implements(jsonMarshalerType)
+}
+
+func implementsText(t reflect.Type) bool {
+	return t.Implements(textMarshalerType) || reflect.PtrTo(t).Implements(textMarshalerType)
+}
+
+func implementsMarshaler(t reflect.Type) bool {
+	return implementsJSON(t) || implementsText(t)
+}
+
+// marshalText returns the text form of a value, if its type has one.
+func marshalText(v reflect.Value) (string, bool) {
+	m, ok := v.Interface().(encoding.TextMarshaler)
+	if !ok && v.CanAddr() {
+		m, ok = v.Addr().Interface().(encoding.TextMarshaler)
+	}
+	if !ok {
+		return "", false
+	}
+	text, err := m.MarshalText()
+	if err != nil {
+		return "", false
+	}
+	return string(text), true
+}
+
+// hexBytes converts a binary byte string into the JSON representation used for
+// binary data throughout the APIs.
+func hexBytes(b []byte) string {
+	return "0x" + hex.EncodeToString(b)
+}
+
+// jsonField is a member of a JSON object, keeping the order of the document.
+type jsonField struct {
+	key   string
+	value interface{}
+}
+
+// readJSONValue reads a whole JSON value from the token stream. Objects are
+// returned as []jsonField, arrays as []interface{} and numbers as json.Number.
+func readJSONValue(dec *json.Decoder) (interface{}, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch tok {
+	case json.Delim('{'):
+		var fields []jsonField
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := readJSONValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			fields = append(fields, jsonField{key.(string), value})
+		}
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+		return fields, nil
+
+	case json.Delim('['):
+		items := []interface{}{}
+		for dec.More() {
+			item, err := readJSONValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+		return items, nil
+
+	case json.Delim('}'), json.Delim(']'):
+		return nil, fmt.Errorf("unexpected %v in JSON document", tok)
+	}
+	return tok, nil
+}
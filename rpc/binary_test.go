@@ -0,0 +1,275 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// encode writes a Go value with the given encoding.
+func encode(t *testing.T, enc *binaryEncoding, v interface{}) []byte {
+	buf := new(bytes.Buffer)
+	w := bufio.NewWriter(buf)
+	if err := writeBinary(enc.writer(w), reflect.ValueOf(v)); err != nil {
+		t.Fatalf("failed to encode %v: %v", v, err)
+	}
+	w.Flush()
+	return buf.Bytes()
+}
+
+// transcode converts a JSON document with the given encoding.
+func transcode(t *testing.T, enc *binaryEncoding, doc string) []byte {
+	return encode(t, enc, json.RawMessage(doc))
+}
+
+// decodeJSON decodes a binary value and returns it as JSON, byte strings as hex.
+func decodeJSON(t *testing.T, enc *binaryEncoding, data []byte) string {
+	val, err := enc.decode(bufio.NewReader(bytes.NewReader(data)))
+	if err != nil {
+		t.Fatalf("failed to decode %x: %v", data, err)
+	}
+	blob, err := json.Marshal(plainValue(val, true))
+	if err != nil {
+		t.Fatalf("failed to encode %v: %v", val, err)
+	}
+	return string(blob)
+}
+
+func TestBinaryTranscoding(t *testing.T) {
+	tests := []struct {
+		doc           string
+		cbor, msgpack string
+	}{
+		{`0`, "00", "00"},
+		{`-1`, "20", "ff"},
+		{`1000`, "1903e8", "cd03e8"},
+		{`-1000`, "3903e7", "d1fc18"},
+		{`1.5`, "fb3ff8000000000000", "cb3ff8000000000000"},
+		{`18446744073709551616`, "c249010000000000000000", "b43138343436373434303733373039353531363136"},
+		{`"a"`, "6161", "a161"},
+		{`[1,[2,3],{"a":null}]`, "8301820203a16161f6", "930192020381a161c0"},
+		{`[true,false]`, "82f5f4", "92c3c2"},
+		{`{"b":1,"a":2}`, "a2616201616102", "82a16201a16102"},
+	}
+	for _, tt := range tests {
+		if have := hex.EncodeToString(transcode(t, binaryEncodings[cborContentType], tt.doc)); have != tt.cbor {
+			t.Errorf("%s: cbor mismatch: have %s, want %s", tt.doc, have, tt.cbor)
+		}
+		if have := hex.EncodeToString(transcode(t, binaryEncodings[msgpackContentType], tt.doc)); have != tt.msgpack {
+			t.Errorf("%s: msgpack mismatch: have %s, want %s", tt.doc, have, tt.msgpack)
+		}
+	}
+}
+
+func TestBinaryValueEncoding(t *testing.T) {
+	type embedded struct {
+		A uint64 `json:"a"`
+		B string `json:"b"`
+	}
+	type object struct {
+		embedded
+		B    hexutil.Uint64 `json:"b"`
+		C    *int           `json:"c,omitempty"`
+		Data hexutil.Bytes  `json:"data"`
+		skip bool
+	}
+	tests := []struct {
+		val  interface{}
+		cbor string
+	}{
+		// Hex encoded byte strings and integers are written natively
+		{hexutil.Bytes{1, 2, 3}, "43010203"},
+		{[]byte{1, 2, 3}, "43010203"},
+		{[]byte(nil), "f6"},
+		{hexutil.Uint64(1000), "1903e8"},
+		{(*hexutil.Big)(big.NewInt(-1)), "20"},
+		{new(big.Int).Lsh(big.NewInt(1), 64), "c249010000000000000000"},
+		// Others as encoding/json would marshal them
+		{BlockNumber(1), "01"},
+		{map[int]bool{2: true}, "a16132f5"},
+		{object{embedded{1, "x"}, 2, nil, hexutil.Bytes{}, true}, "a3616101616202646461746140"},
+		{json.RawMessage(`{"a":"0x01"}`), "a161616430783031"},
+	}
+	for _, tt := range tests {
+		if have := hex.EncodeToString(encode(t, binaryEncodings[cborContentType], tt.val)); have != tt.cbor {
+			t.Errorf("%#v: cbor mismatch: have %s, want %s", tt.val, have, tt.cbor)
+		}
+	}
+}
+
+func TestBinaryAssignment(t *testing.T) {
+	var (
+		data   hexutil.Bytes
+		num    hexutil.Uint64
+		bigint *big.Int
+		block  BlockNumber
+		hash   [2]byte
+		addr   common.Address
+		count  uint64
+		any    interface{}
+	)
+	tests := []struct {
+		dst, src interface{}
+		want     interface{}
+	}{
+		{&data, []byte{1, 2}, hexutil.Bytes{1, 2}},
+		{&data, "0x0102", hexutil.Bytes{1, 2}},
+		{&num, uint64(1000), hexutil.Uint64(1000)},
+		{&num, "0x3e8", hexutil.Uint64(1000)},
+		{&bigint, new(big.Int).Lsh(big.NewInt(1), 64), new(big.Int).Lsh(big.NewInt(1), 64)},
+		{&block, "latest", LatestBlockNumber},
+		{&block, uint64(5), BlockNumber(5)},
+		{&hash, []byte{1, 2}, [2]byte{1, 2}},
+		{&addr, common.Address{1}.Bytes(), common.Address{1}},
+		{&addr, "0x0100000000000000000000000000000000000000", common.Address{1}},
+		{&count, uint64(7), uint64(7)},
+		{&any, map[string]interface{}{"a": []byte{1}, "b": uint64(2)}, map[string]interface{}{"a": "0x01", "b": 2.0}},
+	}
+	for _, tt := range tests {
+		dst := reflect.ValueOf(tt.dst).Elem()
+		if err := assignBinary(dst, tt.src); err != nil {
+			t.Errorf("%v -> %T: assignment failed: %v", tt.src, tt.dst, err)
+			continue
+		}
+		if !reflect.DeepEqual(dst.Interface(), tt.want) {
+			t.Errorf("%v -> %T: value mismatch: have %v, want %v", tt.src, tt.dst, dst.Interface(), tt.want)
+		}
+	}
+	// Mismatched values must be rejected
+	var small uint8
+	for _, tt := range []struct{ dst, src interface{} }{
+		{&small, uint64(256)},
+		{&hash, []byte{1, 2, 3}},
+		{&addr, []byte{1, 2, 3}},
+		{&count, int64(-1)},
+		{&block, new(big.Int).Lsh(big.NewInt(1), 64)},
+		{&num, true},
+	} {
+		if err := assignBinary(reflect.ValueOf(tt.dst).Elem(), tt.src); err == nil {
+			t.Errorf("%v -> %T: invalid assignment accepted", tt.src, tt.dst)
+		}
+	}
+}
+
+func TestBinaryDecoding(t *testing.T) {
+	tests := []struct {
+		enc  string
+		data string
+		doc  string
+	}{
+		// Byte strings are turned into hex
+		{cborContentType, "43010203", `"0x010203"`},
+		{cborContentType, "5f41014102ff", `"0x0102"`},
+		{msgpackContentType, "c403010203", `"0x010203"`},
+		// Numbers of all sizes
+		{cborContentType, "3bffffffffffffffff", `-18446744073709551616`},
+		{cborContentType, "c3490100000000000000ff", `-18446744073709551872`},
+		{cborContentType, "f93e00", `1.5`},
+		{msgpackContentType, "d3ffffffffffffff00", `-256`},
+		{msgpackContentType, "ca3fc00000", `1.5`},
+		// Containers, with indefinite lengths and unknown tags
+		{cborContentType, "a2616101d82061629f7f61636164ffff", `{"a":1,"b":["cd"]}`},
+		{msgpackContentType, "82a16101a16291a26364", `{"a":1,"b":["cd"]}`},
+	}
+	for _, tt := range tests {
+		data, _ := hex.DecodeString(tt.data)
+		if have := decodeJSON(t, binaryEncodings[tt.enc], data); have != tt.doc {
+			t.Errorf("%s %s: decoded mismatch: have %s, want %s", tt.enc, tt.data, have, tt.doc)
+		}
+	}
+	// Malformed values must be rejected
+	for enc, data := range map[string]string{
+		cborContentType:    "a10102",     // integer map key
+		msgpackContentType: "dbffffffff", // oversized string
+	} {
+		blob, _ := hex.DecodeString(data)
+		if _, err := binaryEncodings[enc].decode(bufio.NewReader(bytes.NewReader(blob))); err == nil {
+			t.Errorf("%s %s: malformed value accepted", enc, data)
+		}
+	}
+	nested := bytes.Repeat([]byte{0x81}, binaryMaxDepth+2)
+	if _, err := decodeCBOR(bufio.NewReader(bytes.NewReader(nested))); err != errBinaryDepth {
+		t.Errorf("nesting error mismatch: have %v, want %v", err, errBinaryDepth)
+	}
+}
+
+func TestHTTPContentNegotiation(t *testing.T) {
+	server := newTestServer("test", new(Service))
+	defer server.Stop()
+	hs := httptest.NewServer(server)
+	defer hs.Close()
+
+	var (
+		request  = `{"jsonrpc":"2.0","id":1,"method":"test_echo","params":["x",-5,{"S":"y"}]}`
+		response = `{"id":1,"jsonrpc":"2.0","result":{"Args":{"S":"y"},"Int":-5,"String":"x"}}`
+	)
+	tests := []struct {
+		reqType, accept, respType string
+	}{
+		{cborContentType, "", cborContentType},
+		{msgpackContentType, "", msgpackContentType},
+		{contentType, cborContentType, cborContentType},
+		{cborContentType, "text/html, application/msgpack;q=0.9", msgpackContentType},
+		{msgpackContentType, contentType, contentType},
+	}
+	for _, tt := range tests {
+		body := []byte(request)
+		if enc := binaryEncodings[tt.reqType]; enc != nil {
+			body = transcode(t, enc, request)
+		}
+		req, _ := http.NewRequest(http.MethodPost, hs.URL, bytes.NewReader(body))
+		req.Header.Set("content-type", tt.reqType)
+		if tt.accept != "" {
+			req.Header.Set("accept", tt.accept)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s->%s: request failed: %v", tt.reqType, tt.respType, err)
+		}
+		buf := new(bytes.Buffer)
+		buf.ReadFrom(resp.Body)
+		resp.Body.Close()
+
+		if have := resp.Header.Get("content-type"); have != tt.respType {
+			t.Errorf("%s->%s: content type mismatch: have %s", tt.reqType, tt.respType, have)
+			continue
+		}
+		var have string
+		if enc := binaryEncodings[tt.respType]; enc != nil {
+			have = decodeJSON(t, enc, buf.Bytes())
+		} else {
+			var val interface{}
+			json.Unmarshal(buf.Bytes(), &val)
+			blob, _ := json.Marshal(val)
+			have = string(blob)
+		}
+		if have != response {
+			t.Errorf("%s->%s: response mismatch: have %s, want %s", tt.reqType, tt.respType, have, response)
+		}
+	}
+}
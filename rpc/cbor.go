@@ -0,0 +1,370 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+)
+
+// CBOR (RFC 7049) major types.
+const (
+	cborUint   = 0
+	cborNegint = 1
+	cborBytes  = 2
+	cborText   = 3
+	cborArray  = 4
+	cborMap    = 5
+	cborTag    = 6
+	cborSimple = 7
+)
+
+const (
+	cborFalse      = 0xf4
+	cborTrue       = 0xf5
+	cborNull       = 0xf6
+	cborUndefined  = 0xf7
+	cborFloat64    = 0xfb
+	cborBreak      = 0xff
+	cborIndefinite = 31
+
+	cborTagPosBignum = 2
+	cborTagNegBignum = 3
+)
+
+// cborWriter writes CBOR items, containers with their definite length.
+type cborWriter struct {
+	*bufio.Writer
+}
+
+func newCBORWriter(w *bufio.Writer) binaryWriter {
+	return cborWriter{w}
+}
+
+func (w cborWriter) writeNull() {
+	w.WriteByte(cborNull)
+}
+
+func (w cborWriter) writeBool(v bool) {
+	if v {
+		w.WriteByte(cborTrue)
+	} else {
+		w.WriteByte(cborFalse)
+	}
+}
+
+func (w cborWriter) writeInt(v int64) {
+	if v >= 0 {
+		w.writeHead(cborUint, uint64(v))
+	} else {
+		w.writeHead(cborNegint, uint64(-(v + 1)))
+	}
+}
+
+func (w cborWriter) writeUint(v uint64) {
+	w.writeHead(cborUint, v)
+}
+
+// writeBigInt writes a big integer as an integer if it fits, or as a bignum.
+func (w cborWriter) writeBigInt(v *big.Int) {
+	switch {
+	case v.IsUint64():
+		w.writeHead(cborUint, v.Uint64())
+	case v.IsInt64():
+		w.writeInt(v.Int64())
+	case v.Sign() > 0:
+		w.writeHead(cborTag, cborTagPosBignum)
+		w.writeBytes(v.Bytes())
+	default:
+		w.writeHead(cborTag, cborTagNegBignum)
+		w.writeBytes(new(big.Int).Sub(new(big.Int).Neg(v), big.NewInt(1)).Bytes())
+	}
+}
+
+func (w cborWriter) writeFloat(v float64) {
+	var buf [9]byte
+	buf[0] = cborFloat64
+	binary.BigEndian.PutUint64(buf[1:], math.Float64bits(v))
+	w.Write(buf[:])
+}
+
+func (w cborWriter) writeString(s string) {
+	w.writeHead(cborText, uint64(len(s)))
+	w.WriteString(s)
+}
+
+func (w cborWriter) writeBytes(b []byte) {
+	w.writeHead(cborBytes, uint64(len(b)))
+	w.Write(b)
+}
+
+func (w cborWriter) writeArrayHead(size int) {
+	w.writeHead(cborArray, uint64(size))
+}
+
+func (w cborWriter) writeMapHead(size int) {
+	w.writeHead(cborMap, uint64(size))
+}
+
+// writeHead writes the initial bytes of an item of the given major type.
+func (w cborWriter) writeHead(major byte, arg uint64) {
+	var buf [9]byte
+	switch {
+	case arg < 24:
+		w.WriteByte(major<<5 | byte(arg))
+		return
+	case arg <= math.MaxUint8:
+		buf[0], buf[1] = major<<5|24, byte(arg)
+		w.Write(buf[:2])
+	case arg <= math.MaxUint16:
+		buf[0] = major<<5 | 25
+		binary.BigEndian.PutUint16(buf[1:], uint16(arg))
+		w.Write(buf[:3])
+	case arg <= math.MaxUint32:
+		buf[0] = major<<5 | 26
+		binary.BigEndian.PutUint32(buf[1:], uint32(arg))
+		w.Write(buf[:5])
+	default:
+		buf[0] = major<<5 | 27
+		binary.BigEndian.PutUint64(buf[1:], arg)
+		w.Write(buf[:9])
+	}
+}
+
+// decodeCBOR reads a single CBOR item.
+func decodeCBOR(r *bufio.Reader) (interface{}, error) {
+	return decodeCBORItem(r, 0)
+}
+
+func decodeCBORItem(r *bufio.Reader, depth int) (interface{}, error) {
+	if depth > binaryMaxDepth {
+		return nil, errBinaryDepth
+	}
+	initial, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	major, info := initial>>5, initial&0x1f
+	if major == cborSimple {
+		return decodeCBORSimple(r, initial)
+	}
+	var arg uint64
+	if info != cborIndefinite {
+		if arg, err = readCBORArg(r, info); err != nil {
+			return nil, err
+		}
+	} else if major == cborUint || major == cborNegint || major == cborTag {
+		return nil, fmt.Errorf("invalid indefinite length CBOR item %#x", initial)
+	}
+	switch major {
+	case cborUint:
+		return arg, nil
+
+	case cborNegint:
+		if arg <= math.MaxInt64 {
+			return -int64(arg) - 1, nil
+		}
+		v := new(big.Int).SetUint64(arg)
+		return v.Neg(v).Sub(v, big.NewInt(1)), nil
+
+	case cborBytes, cborText:
+		var data []byte
+		if info == cborIndefinite {
+			// Indefinite strings are made of definite chunks of the same type
+			for {
+				chunk, err := r.ReadByte()
+				if err != nil {
+					return nil, err
+				}
+				if chunk == cborBreak {
+					break
+				}
+				if chunk>>5 != major || chunk&0x1f == cborIndefinite {
+					return nil, fmt.Errorf("invalid CBOR string chunk %#x", chunk)
+				}
+				size, err := readCBORArg(r, chunk&0x1f)
+				if err != nil {
+					return nil, err
+				}
+				if data, err = readBinaryString(r, data, size); err != nil {
+					return nil, err
+				}
+			}
+		} else if data, err = readBinaryString(r, nil, arg); err != nil {
+			return nil, err
+		}
+		if major == cborBytes {
+			return data, nil
+		}
+		return string(data), nil
+
+	case cborArray:
+		items := []interface{}{}
+		for i := uint64(0); info == cborIndefinite || i < arg; i++ {
+			if info == cborIndefinite {
+				if next, err := r.Peek(1); err != nil {
+					return nil, err
+				} else if next[0] == cborBreak {
+					r.ReadByte()
+					break
+				}
+			} else if i >= maxRequestContentLength {
+				return nil, errBinaryLength
+			}
+			item, err := decodeCBORItem(r, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		return items, nil
+
+	case cborMap:
+		fields := make(map[string]interface{})
+		for i := uint64(0); info == cborIndefinite || i < arg; i++ {
+			if info == cborIndefinite {
+				if next, err := r.Peek(1); err != nil {
+					return nil, err
+				} else if next[0] == cborBreak {
+					r.ReadByte()
+					break
+				}
+			} else if i >= maxRequestContentLength {
+				return nil, errBinaryLength
+			}
+			key, err := decodeCBORItem(r, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			name, ok := key.(string)
+			if !ok {
+				return nil, errBinaryMapKey
+			}
+			if fields[name], err = decodeCBORItem(r, depth+1); err != nil {
+				return nil, err
+			}
+		}
+		return fields, nil
+
+	default: // cborTag
+		// Bignums are the only tags understood, others are dropped
+		bignum := arg == cborTagPosBignum || arg == cborTagNegBignum
+		if next, err := r.Peek(1); err != nil {
+			return nil, err
+		} else if next[0]>>5 != cborBytes {
+			bignum = false
+		}
+		item, err := decodeCBORItem(r, depth+1)
+		if err != nil || !bignum {
+			return item, err
+		}
+		v := new(big.Int).SetBytes(item.([]byte))
+		if arg == cborTagNegBignum {
+			v.Neg(v).Sub(v, big.NewInt(1))
+		}
+		return v, nil
+	}
+}
+
+// decodeCBORSimple decodes an item of the simple values and floats major type.
+func decodeCBORSimple(r *bufio.Reader, initial byte) (interface{}, error) {
+	switch initial {
+	case cborFalse:
+		return false, nil
+	case cborTrue:
+		return true, nil
+	case cborNull, cborUndefined:
+		return nil, nil
+	}
+	bits, err := readCBORArg(r, initial&0x1f)
+	if err != nil {
+		return nil, err
+	}
+	switch initial & 0x1f {
+	case 25:
+		return float16ToFloat64(uint16(bits)), nil
+	case 26:
+		return float64(math.Float32frombits(uint32(bits))), nil
+	case 27:
+		return math.Float64frombits(bits), nil
+	}
+	return nil, fmt.Errorf("unsupported CBOR simple value %#x", initial)
+}
+
+// readCBORArg reads the argument of an item following its initial byte.
+func readCBORArg(r *bufio.Reader, info byte) (uint64, error) {
+	if info < 24 {
+		return uint64(info), nil
+	}
+	var size int
+	switch info {
+	case 24:
+		size = 1
+	case 25:
+		size = 2
+	case 26:
+		size = 4
+	case 27:
+		size = 8
+	default:
+		return 0, fmt.Errorf("invalid CBOR additional information %d", info)
+	}
+	var buf [8]byte
+	if _, err := io.ReadFull(r, buf[8-size:]); err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint64(buf[:]), nil
+}
+
+// readBinaryString appends size bytes read from r to data.
+func readBinaryString(r io.Reader, data []byte, size uint64) ([]byte, error) {
+	if size > maxRequestContentLength || uint64(len(data))+size > maxRequestContentLength {
+		return nil, errBinaryLength
+	}
+	start := len(data)
+	data = append(data, make([]byte, size)...)
+	if _, err := io.ReadFull(r, data[start:]); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// float16ToFloat64 converts an IEEE 754 half precision float.
+func float16ToFloat64(h uint16) float64 {
+	var (
+		sign = 1.0
+		exp  = int(h>>10) & 0x1f
+		frac = float64(h & 0x3ff)
+	)
+	if h&0x8000 != 0 {
+		sign = -1
+	}
+	switch exp {
+	case 0:
+		return sign * math.Ldexp(frac, -24)
+	case 0x1f:
+		if frac == 0 {
+			return sign * math.Inf(1)
+		}
+		return math.NaN()
+	}
+	return sign * math.Ldexp(frac+1024, exp-25)
+}
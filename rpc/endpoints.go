@@ -24,6 +24,23 @@ import (
 
 // StartHTTPEndpoint starts the HTTP RPC endpoint, configured with cors/vhosts/modules
 func StartHTTPEndpoint(endpoint string, apis []API, modules []string, cors []string, vhosts []string, timeouts HTTPTimeouts) (net.Listener, *Server, error) {
+	listener, err := net.Listen("tcp", endpoint)
+	if err != nil {
+		return nil, nil, err
+	}
+	handler, err := ServeHTTPEndpoint(listener, apis, modules, cors, vhosts, timeouts, nil, nil, nil, nil)
+	if err != nil {
+		listener.Close()
+		return nil, nil, err
+	}
+	return listener, handler, nil
+}
+
+// ServeHTTPEndpoint serves the HTTP RPC endpoint on the given listener, configured
+// with cors/vhosts/modules, authenticating the requests if auth is not nil,
+// metering them by API key if keys is not nil, bounding the concurrent calls if
+// workers is not nil and applying the method rules if methods is not nil.
+func ServeHTTPEndpoint(listener net.Listener, apis []API, modules []string, cors []string, vhosts []string, timeouts HTTPTimeouts, auth *JWTAuth, keys *APIKeys, workers *WorkerConfig, methods *MethodRules) (*Server, error) {
 	// Generate the whitelist based on the allowed modules
 	whitelist := make(map[string]bool)
 	for _, module := range modules {
@@ -31,29 +48,42 @@ func StartHTTPEndpoint(endpoint string, apis []API, modules []string, cors []str
 	}
 	// Register all the APIs exposed by the services
 	handler := NewServer()
+	handler.auth, handler.keys, handler.methods = auth, keys, methods
+	if workers != nil {
+		handler.SetWorkers(*workers)
+	}
 	for _, api := range apis {
 		if whitelist[api.Namespace] || (len(whitelist) == 0 && api.Public) {
 			if err := handler.RegisterName(api.Namespace, api.Service); err != nil {
-				return nil, nil, err
+				return nil, err
 			}
 			log.Debug("HTTP registered", "namespace", api.Namespace)
 		}
 	}
-	// All APIs registered, start the HTTP listener
-	var (
-		listener net.Listener
-		err      error
-	)
-	if listener, err = net.Listen("tcp", endpoint); err != nil {
-		return nil, nil, err
-	}
+	// All APIs registered, start serving the HTTP listener
 	go NewHTTPServer(cors, vhosts, timeouts, handler).Serve(listener)
-	return listener, handler, err
+	return handler, nil
 }
 
 // StartWSEndpoint starts a websocket endpoint
 func StartWSEndpoint(endpoint string, apis []API, modules []string, wsOrigins []string, exposeAll bool) (net.Listener, *Server, error) {
+	listener, err := net.Listen("tcp", endpoint)
+	if err != nil {
+		return nil, nil, err
+	}
+	handler, err := ServeWSEndpoint(listener, apis, modules, wsOrigins, exposeAll, nil, nil, nil, nil)
+	if err != nil {
+		listener.Close()
+		return nil, nil, err
+	}
+	return listener, handler, nil
+}
 
+// ServeWSEndpoint serves a websocket endpoint on the given listener,
+// authenticating the connections if auth is not nil, metering them by API key if
+// keys is not nil, bounding the concurrent calls if workers is not nil and
+// applying the method rules if methods is not nil.
+func ServeWSEndpoint(listener net.Listener, apis []API, modules []string, wsOrigins []string, exposeAll bool, auth *JWTAuth, keys *APIKeys, workers *WorkerConfig, methods *MethodRules) (*Server, error) {
 	// Generate the whitelist based on the allowed modules
 	whitelist := make(map[string]bool)
 	for _, module := range modules {
@@ -61,25 +91,21 @@ func StartWSEndpoint(endpoint string, apis []API, modules []string, wsOrigins []
 	}
 	// Register all the APIs exposed by the services
 	handler := NewServer()
+	handler.auth, handler.keys, handler.methods = auth, keys, methods
+	if workers != nil {
+		handler.SetWorkers(*workers)
+	}
 	for _, api := range apis {
 		if exposeAll || whitelist[api.Namespace] || (len(whitelist) == 0 && api.Public) {
 			if err := handler.RegisterName(api.Namespace, api.Service); err != nil {
-				return nil, nil, err
+				return nil, err
 			}
 			log.Debug("WebSocket registered", "service", api.Service, "namespace", api.Namespace)
 		}
 	}
-	// All APIs registered, start the HTTP listener
-	var (
-		listener net.Listener
-		err      error
-	)
-	if listener, err = net.Listen("tcp", endpoint); err != nil {
-		return nil, nil, err
-	}
+	// All APIs registered, start serving the HTTP listener
 	go NewWSServer(wsOrigins, handler).Serve(listener)
-	return listener, handler, err
-
+	return handler, nil
 }
 
 // StartIPCEndpoint starts an IPC endpoint.
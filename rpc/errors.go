@@ -16,7 +16,10 @@
 
 package rpc
 
-import "fmt"
+import (
+	"fmt"
+	"sync"
+)
 
 // request is for an unknown service
 type methodNotFoundError struct {
@@ -64,3 +67,167 @@ type shutdownError struct{}
 func (e *shutdownError) ErrorCode() int { return -32000 }
 
 func (e *shutdownError) Error() string { return "server is shutting down" }
+
+// Quorum
+// issued when the token of a connection doesn't allow a request.
+type unauthorizedError struct{ message string }
+
+func (e *unauthorizedError) ErrorCode() int { return CodeServerError }
+
+func (e *unauthorizedError) Error() string { return "unauthorized: " + e.message }
+
+func (e *unauthorizedError) ErrorData() interface{} { return &errorData{Reason: "PERMISSION_DENIED"} }
+
+// Quorum
+// issued when a call can't be queued for execution.
+type serverBusyError struct{ method string }
+
+func (e *serverBusyError) ErrorCode() int { return -32005 }
+
+func (e *serverBusyError) Error() string { return "server busy, call of " + e.method + " rejected" }
+
+func (e *serverBusyError) ErrorData() interface{} { return &errorData{Reason: "SERVER_BUSY"} }
+
+// Quorum
+// issued when a queued call is dropped, its client having gone.
+type callCancelledError struct{ method string }
+
+func (e *callCancelledError) ErrorCode() int { return CodeServerError }
+
+func (e *callCancelledError) Error() string { return "call of " + e.method + " cancelled" }
+
+func (e *callCancelledError) ErrorData() interface{} { return &errorData{Reason: "CALL_CANCELLED"} }
+
+// Quorum
+// issued when the node sheds the calls or subscriptions to protect itself from
+// running out of resources.
+type overloadedError struct{ what string }
+
+func (e *overloadedError) ErrorCode() int { return -32005 }
+
+func (e *overloadedError) Error() string { return "node overloaded, new " + e.what + " paused" }
+
+func (e *overloadedError) ErrorData() interface{} { return &errorData{Reason: "NODE_OVERLOADED"} }
+
+// Quorum
+// issued when a method is disabled by the method rules of the server.
+type methodDisabledError struct{ method, message string }
+
+func (e *methodDisabledError) ErrorCode() int { return -32601 }
+
+func (e *methodDisabledError) Error() string {
+	if e.message == "" {
+		return "The method " + e.method + " is disabled"
+	}
+	return "The method " + e.method + " is disabled: " + e.message
+}
+
+func (e *methodDisabledError) ErrorData() interface{} { return &errorData{Reason: "METHOD_DISABLED"} }
+
+// Quorum
+// issued when the daily quota of the API key of a connection is exhausted.
+type quotaExceededError struct{ quota string }
+
+func (e *quotaExceededError) ErrorCode() int { return CodeQuotaExceeded }
+
+func (e *quotaExceededError) Error() string { return "daily " + e.quota + " quota of the API key exceeded" }
+
+func (e *quotaExceededError) ErrorData() interface{} { return &errorData{Reason: "QUOTA_EXCEEDED"} }
+
+// Quorum
+// Codes of the errors clients need to tell apart. The errors which were always
+// sent with the generic server error code keep it, for the existing clients,
+// and are told apart by the stable reason string in their data. Only the new
+// errors get codes of their own, in the range reserved for implementation
+// defined server errors.
+const (
+	CodeServerError      = -32000 // The generic code, of the errors told apart by their reason
+	CodeHistoryPruned    = -32030 // The block data was pruned by the history retention
+	CodeQuotaExceeded    = -32040 // The daily quota of the API key is exhausted
+	CodeApprovalRequired = -32050 // The action awaits the approvals of the approvers of the account
+)
+
+// Quorum
+// CodeExecutionReverted is the code of the errors of the reverted calls, the
+// one of go-ethereum upstream, which the client libraries expect.
+const CodeExecutionReverted = 3
+
+// Quorum
+// DataError is an error carrying machine-readable data, sent in the data field
+// of the error response.
+type DataError interface {
+	Error() string          // returns the message
+	ErrorData() interface{} // returns the data
+}
+
+// errorData is the data of the errors classified under a stable code.
+type errorData struct {
+	Reason string `json:"reason"`
+}
+
+// errorClass is a stable code and reason the errors returned by the API methods
+// are classified under.
+type errorClass struct {
+	code   int
+	reason string
+	match  func(error) bool
+}
+
+var (
+	errorClassesLock sync.RWMutex
+	errorClasses     []errorClass
+)
+
+// RegisterError classifies the given error values, when returned by the API
+// methods, under a stable code and reason.
+func RegisterError(code int, reason string, errs ...error) {
+	RegisterErrorMatcher(code, reason, func(err error) bool {
+		for _, e := range errs {
+			if err == e {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// RegisterErrorMatcher classifies the errors matched by the given function,
+// when returned by the API methods, under a stable code and reason.
+func RegisterErrorMatcher(code int, reason string, match func(error) bool) {
+	errorClassesLock.Lock()
+	defer errorClassesLock.Unlock()
+
+	errorClasses = append(errorClasses, errorClass{code: code, reason: reason, match: match})
+}
+
+// classifiedError is an error returned by an API method, classified under a
+// stable code and reason.
+type classifiedError struct {
+	code    int
+	message string
+	reason  string
+}
+
+func (e *classifiedError) ErrorCode() int { return e.code }
+
+func (e *classifiedError) Error() string { return e.message }
+
+func (e *classifiedError) ErrorData() interface{} { return &errorData{Reason: e.reason} }
+
+// classifyError converts an error returned by an API method to an RPC error,
+// keeping the code of the RPC errors and applying the registered classes to the
+// others, or the generic callback error code otherwise.
+func classifyError(err error) Error {
+	if rpcErr, ok := err.(Error); ok {
+		return rpcErr
+	}
+	errorClassesLock.RLock()
+	defer errorClassesLock.RUnlock()
+
+	for _, class := range errorClasses {
+		if class.match(err) {
+			return &classifiedError{code: class.code, message: err.Error(), reason: class.reason}
+		}
+	}
+	return &callbackError{err.Error()}
+}
@@ -0,0 +1,79 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"errors"
+	"testing"
+)
+
+var (
+	errClassified   = errors.New("classified failure")
+	errReasoned     = errors.New("failure told apart by its reason")
+	errUnclassified = errors.New("other failure")
+)
+
+type ErrorService struct{}
+
+func (s *ErrorService) FailReasoned() (bool, error) {
+	return false, errReasoned
+}
+
+func (s *ErrorService) Fail(classified bool) (bool, error) {
+	if classified {
+		return false, errClassified
+	}
+	return false, errUnclassified
+}
+
+// Tests that the registered errors are sent with their code and reason, and the
+// others with the generic code.
+func TestClassifiedErrors(t *testing.T) {
+	RegisterError(CodeHistoryPruned, "TEST_CLASSIFIED", errClassified)
+	RegisterError(CodeServerError, "TEST_REASONED", errReasoned)
+
+	server := NewServer()
+	server.RegisterName("test", new(ErrorService))
+	defer server.Stop()
+
+	client := DialInProc(server)
+	defer client.Close()
+
+	err := client.Call(nil, "test_fail", true)
+	jerr, ok := err.(*jsonError)
+	if !ok {
+		t.Fatalf("unexpected error type %T: %v", err, err)
+	}
+	if jerr.Code != CodeHistoryPruned || jerr.Message != errClassified.Error() {
+		t.Errorf("classified error: have %d %q, want %d %q", jerr.Code, jerr.Message, CodeHistoryPruned, errClassified.Error())
+	}
+	if data, ok := jerr.Data.(map[string]interface{}); !ok || data["reason"] != "TEST_CLASSIFIED" {
+		t.Errorf("classified error data mismatch: %v", jerr.Data)
+	}
+	// The errors of the generic code keep it, with their reason alongside
+	err = client.Call(nil, "test_failReasoned")
+	if jerr, ok = err.(*jsonError); !ok || jerr.Code != -32000 {
+		t.Fatalf("reasoned error mismatch: %+v", err)
+	}
+	if data, ok := jerr.Data.(map[string]interface{}); !ok || data["reason"] != "TEST_REASONED" {
+		t.Errorf("reasoned error data mismatch: %v", jerr.Data)
+	}
+	err = client.Call(nil, "test_fail", false)
+	if jerr, ok = err.(*jsonError); !ok || jerr.Code != -32000 || jerr.Data != nil {
+		t.Errorf("unclassified error mismatch: %+v", err)
+	}
+}
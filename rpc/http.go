@@ -231,6 +231,27 @@ func (srv *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), code)
 		return
 	}
+	// Quorum
+	var grant *jwtGrant
+	if srv.auth != nil {
+		var err error
+		if grant, err = srv.auth.authenticate(r); err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+	}
+	var usage *apiKeyUsage
+	if srv.keys != nil {
+		var err error
+		if usage, err = srv.keys.authenticate(r); err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		if !usage.admit(time.Now()) {
+			http.Error(w, "daily quota of the API key exceeded", http.StatusTooManyRequests)
+			return
+		}
+	}
 	// All checks passed, create a codec that reads direct from the request body
 	// untilEOF and writes the response to w and order the server to process a
 	// single request.
@@ -244,12 +265,27 @@ func (srv *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	if origin := r.Header.Get("Origin"); origin != "" {
 		ctx = context.WithValue(ctx, "Origin", origin)
 	}
+	if grant != nil {
+		ctx = context.WithValue(ctx, jwtGrantKey{}, grant)
+	}
+	var (
+		body io.Reader = io.LimitReader(r.Body, maxRequestContentLength)
+		out  io.Writer = w
+	)
+	if usage != nil {
+		ctx = context.WithValue(ctx, apiKeyUsageKey{}, usage)
+		body, out = &meteredReader{body, usage}, &meteredWriter{w, usage}
+	}
+
+	// Decode the request in its own encoding, and reply in the one the client
+	// accepts if supported
+	reqType, _, _ := mime.ParseMediaType(r.Header.Get("content-type"))
+	respType := negotiateContentType(reqType, r.Header.Get("accept"))
 
-	body := io.LimitReader(r.Body, maxRequestContentLength)
-	codec := NewJSONCodec(&httpReadWriteNopCloser{body, w})
+	codec := newNegotiatedCodec(&httpReadWriteNopCloser{body, out}, reqType, respType)
 	defer codec.Close()
 
-	w.Header().Set("content-type", contentType)
+	w.Header().Set("content-type", respType)
 	srv.ServeSingleRequest(ctx, codec, OptionMethodInvocation)
 }
 
@@ -264,8 +300,8 @@ func validateRequest(r *http.Request) (int, error) {
 		return http.StatusRequestEntityTooLarge, err
 	}
 	mt, _, err := mime.ParseMediaType(r.Header.Get("content-type"))
-	if r.Method != http.MethodOptions && (err != nil || mt != contentType) {
-		err := fmt.Errorf("invalid content type, only %s is supported", contentType)
+	if r.Method != http.MethodOptions && (err != nil || !supportedContentType(mt)) {
+		err := fmt.Errorf("invalid content type, only %s, %s and %s are supported", contentType, cborContentType, msgpackContentType)
 		return http.StatusUnsupportedMediaType, err
 	}
 	return 0, nil
@@ -325,6 +325,10 @@ func (c *jsonCodec) CreateResponse(id interface{}, reply interface{}) interface{
 
 // CreateErrorResponse will create a JSON-RPC error response with the given id and error.
 func (c *jsonCodec) CreateErrorResponse(id interface{}, err Error) interface{} {
+	// Quorum: send the data of the errors carrying some
+	if de, ok := err.(DataError); ok {
+		return c.CreateErrorResponseWithInfo(id, err, de.ErrorData())
+	}
 	return &jsonErrResponse{Version: jsonrpcVersion, Id: id, Error: jsonError{Code: err.ErrorCode(), Message: err.Error()}}
 }
 
@@ -0,0 +1,293 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const (
+	// jwksRefreshInterval is the interval at which the JSON web key set is
+	// fetched again, to follow key rotations.
+	jwksRefreshInterval = time.Hour
+
+	// jwksMinRefreshInterval is the minimum time between two fetches of the JSON
+	// web key set, triggered by tokens signed with an unknown key.
+	jwksMinRefreshInterval = time.Minute
+
+	// jwksFetchTimeout is the timeout of a JSON web key set request.
+	jwksFetchTimeout = 10 * time.Second
+)
+
+var (
+	errJWTMissing     = errors.New("missing bearer token")
+	errJWTNoKey       = errors.New("no key configured for the token signing method")
+	errJWTUnknownKey  = errors.New("token signed with an unknown key")
+	errJWTInvalidConf = errors.New("JWT authentication needs a secret, a public key or a JWKS URL")
+)
+
+// JWTConfig configures the JWT authentication of the HTTP and WebSocket endpoints.
+type JWTConfig struct {
+	SecretFile     string `toml:",omitempty"` // File holding the shared secret of HS256 tokens
+	PublicKeyFile  string `toml:",omitempty"` // PEM file of the RSA public key of RS256 tokens
+	JWKSURL        string `toml:",omitempty"` // URL of the JSON web key set of RS256 tokens
+	NamespaceClaim string `toml:",omitempty"` // Claim listing the namespaces a token may call, unrestricted if empty
+}
+
+// JWTAuth authenticates the bearer tokens of RPC requests, and authorizes the
+// namespaces they may call from their claims.
+type JWTAuth struct {
+	secret []byte         // Shared secret of HS256 tokens, nil if not accepted
+	rsaKey *rsa.PublicKey // Public key of RS256 tokens, nil if not configured
+	jwks   *jwksCache     // Keys of RS256 tokens by key ID, nil if not configured
+	claim  string         // Claim listing the namespaces a token may call
+}
+
+// NewJWTAuth creates the authenticator of a JWT configuration.
+func NewJWTAuth(config JWTConfig) (*JWTAuth, error) {
+	auth := &JWTAuth{claim: config.NamespaceClaim}
+	if config.SecretFile != "" {
+		secret, err := ioutil.ReadFile(config.SecretFile)
+		if err != nil {
+			return nil, err
+		}
+		if auth.secret = []byte(strings.TrimSpace(string(secret))); len(auth.secret) == 0 {
+			return nil, fmt.Errorf("empty JWT secret in %s", config.SecretFile)
+		}
+	}
+	if config.PublicKeyFile != "" {
+		blob, err := ioutil.ReadFile(config.PublicKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		if auth.rsaKey, err = jwt.ParseRSAPublicKeyFromPEM(blob); err != nil {
+			return nil, fmt.Errorf("invalid JWT public key in %s: %v", config.PublicKeyFile, err)
+		}
+	}
+	if config.JWKSURL != "" {
+		auth.jwks = &jwksCache{url: config.JWKSURL, client: &http.Client{Timeout: jwksFetchTimeout}}
+		if err := auth.jwks.refresh(); err != nil {
+			log.Warn("Failed to fetch JSON web key set, retrying on demand", "url", config.JWKSURL, "err", err)
+		}
+	}
+	if auth.secret == nil && auth.rsaKey == nil && auth.jwks == nil {
+		return nil, errJWTInvalidConf
+	}
+	return auth, nil
+}
+
+// authenticate validates the bearer token of a request, returning the grant of
+// its claims.
+func (a *JWTAuth) authenticate(r *http.Request) (*jwtGrant, error) {
+	header := r.Header.Get("Authorization")
+	if !strings.HasPrefix(header, "Bearer ") {
+		return nil, errJWTMissing
+	}
+	parser := &jwt.Parser{ValidMethods: []string{jwt.SigningMethodHS256.Alg(), jwt.SigningMethodRS256.Alg()}}
+	token, err := parser.Parse(strings.TrimSpace(header[len("Bearer "):]), a.key)
+	if err != nil {
+		return nil, err
+	}
+	claims := token.Claims.(jwt.MapClaims)
+
+	grant := new(jwtGrant)
+	grant.subject, _ = claims["sub"].(string)
+	if exp, ok := claims["exp"].(float64); ok {
+		grant.expiry = time.Unix(int64(exp), 0)
+	}
+	if a.claim != "" {
+		grant.namespaces = make(map[string]bool)
+		switch value := claims[a.claim].(type) {
+		case string:
+			for _, ns := range strings.Fields(value) {
+				grant.namespaces[ns] = true
+			}
+		case []interface{}:
+			for _, ns := range value {
+				if ns, ok := ns.(string); ok {
+					grant.namespaces[ns] = true
+				}
+			}
+		}
+		if grant.namespaces["*"] {
+			grant.namespaces = nil
+		}
+	}
+	return grant, nil
+}
+
+// key returns the key verifying the signature of a token.
+func (a *JWTAuth) key(token *jwt.Token) (interface{}, error) {
+	switch token.Method.(type) {
+	case *jwt.SigningMethodHMAC:
+		if a.secret == nil {
+			return nil, errJWTNoKey
+		}
+		return a.secret, nil
+
+	case *jwt.SigningMethodRSA:
+		if kid, ok := token.Header["kid"].(string); ok && a.jwks != nil {
+			return a.jwks.key(kid)
+		}
+		if a.rsaKey != nil {
+			return a.rsaKey, nil
+		}
+		if a.jwks != nil {
+			return nil, errJWTUnknownKey
+		}
+	}
+	return nil, errJWTNoKey
+}
+
+// jwtGrantKey is the context key of the grant of an authenticated connection.
+type jwtGrantKey struct{}
+
+// jwtGrant is what an authenticated token allows.
+type jwtGrant struct {
+	subject    string          // Subject of the token, identifying the tenant
+	expiry     time.Time       // Expiry of the token, zero if it never expires
+	namespaces map[string]bool // Namespaces the token may call, all if nil
+}
+
+// authorize checks that the token is still valid and allows calling a namespace.
+func (g *jwtGrant) authorize(namespace string) Error {
+	if !g.expiry.IsZero() && time.Now().After(g.expiry) {
+		return &unauthorizedError{"token expired"}
+	}
+	if g.namespaces != nil && !g.namespaces[namespace] {
+		return &unauthorizedError{fmt.Sprintf("token does not grant the %s namespace", namespace)}
+	}
+	return nil
+}
+
+// jwtGrantFromContext returns the grant of the connection serving a request, nil
+// if the connection isn't authenticated.
+func jwtGrantFromContext(ctx context.Context) *jwtGrant {
+	grant, _ := ctx.Value(jwtGrantKey{}).(*jwtGrant)
+	return grant
+}
+
+// TenantFromContext returns the tenant making a request, the subject of the
+// token authenticating its connection. It is empty if the connection isn't
+// authenticated, as for the operator of the node over IPC.
+func TenantFromContext(ctx context.Context) string {
+	if grant := jwtGrantFromContext(ctx); grant != nil {
+		return grant.subject
+	}
+	return ""
+}
+
+// jwksCache holds the RSA keys of a JSON web key set, fetching it again when
+// stale or when asked for an unknown key.
+type jwksCache struct {
+	url    string
+	client *http.Client
+
+	lock    sync.Mutex
+	keys    map[string]*rsa.PublicKey
+	fetched time.Time
+}
+
+// jsonWebKey is the JSON encoding of an RSA key of a JSON web key set.
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// key returns the RSA key with the given ID.
+func (c *jwksCache) key(kid string) (*rsa.PublicKey, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	key, ok := c.keys[kid]
+	if stale := time.Since(c.fetched); (!ok && stale > jwksMinRefreshInterval) || stale > jwksRefreshInterval {
+		if err := c.fetch(); err != nil {
+			log.Warn("Failed to fetch JSON web key set", "url", c.url, "err", err)
+		}
+		key, ok = c.keys[kid]
+	}
+	if !ok {
+		return nil, errJWTUnknownKey
+	}
+	return key, nil
+}
+
+// refresh fetches the key set.
+func (c *jwksCache) refresh() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.fetch()
+}
+
+// fetch fetches the key set, keeping the current keys on failure. The lock
+// must be held.
+func (c *jwksCache) fetch() error {
+	c.fetched = time.Now()
+
+	resp, err := c.client.Get(c.url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("key set request returned %s", resp.Status)
+	}
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return err
+	}
+	keys := make(map[string]*rsa.PublicKey)
+	for _, jwk := range set.Keys {
+		if jwk.Kty != "RSA" {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+		if err != nil {
+			return fmt.Errorf("invalid modulus of key %q: %v", jwk.Kid, err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+		if err != nil {
+			return fmt.Errorf("invalid exponent of key %q: %v", jwk.Kid, err)
+		}
+		exponent := new(big.Int).SetBytes(e)
+		if !exponent.IsInt64() || exponent.Int64() > 1<<31-1 {
+			return fmt.Errorf("invalid exponent of key %q", jwk.Kid)
+		}
+		keys[jwk.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}
+	}
+	c.keys = keys
+	return nil
+}
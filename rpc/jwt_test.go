@@ -0,0 +1,206 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"golang.org/x/net/websocket"
+)
+
+// newTestJWTAuth creates an HS256 authenticator restricting the namespaces by
+// the "namespaces" claim.
+func newTestJWTAuth(t *testing.T, secret string) *JWTAuth {
+	file, err := ioutil.TempFile("", "jwt-secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+	file.WriteString(secret + "\n")
+	file.Close()
+
+	auth, err := NewJWTAuth(JWTConfig{SecretFile: file.Name(), NamespaceClaim: "namespaces"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return auth
+}
+
+func signTestToken(t *testing.T, method jwt.SigningMethod, key interface{}, claims jwt.MapClaims, kid string) string {
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+// Tests that HTTP requests are served only with a valid token, and only for the
+// namespaces it grants.
+func TestJWTHTTP(t *testing.T) {
+	server := newTestServer("service", new(Service))
+	server.auth = newTestJWTAuth(t, "secret")
+	defer server.Stop()
+
+	httpsrv := httptest.NewServer(server)
+	defer httpsrv.Close()
+
+	var (
+		exp     = time.Now().Add(time.Hour).Unix()
+		granted = signTestToken(t, jwt.SigningMethodHS256, []byte("secret"), jwt.MapClaims{"exp": exp, "namespaces": []string{"service"}}, "")
+		denied  = signTestToken(t, jwt.SigningMethodHS256, []byte("secret"), jwt.MapClaims{"exp": exp, "namespaces": "eth net"}, "")
+		all     = signTestToken(t, jwt.SigningMethodHS256, []byte("secret"), jwt.MapClaims{"namespaces": "*"}, "")
+		expired = signTestToken(t, jwt.SigningMethodHS256, []byte("secret"), jwt.MapClaims{"exp": time.Now().Add(-time.Minute).Unix()}, "")
+		forged  = signTestToken(t, jwt.SigningMethodHS256, []byte("other"), jwt.MapClaims{"namespaces": "*"}, "")
+	)
+	tests := []struct {
+		token  string
+		status int
+		error  bool
+	}{
+		{"", http.StatusUnauthorized, false},
+		{forged, http.StatusUnauthorized, false},
+		{expired, http.StatusUnauthorized, false},
+		{denied, http.StatusOK, true},
+		{granted, http.StatusOK, false},
+		{all, http.StatusOK, false},
+	}
+	for i, tt := range tests {
+		req, _ := http.NewRequest("POST", httpsrv.URL, strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"service_noArgsRets"}`))
+		req.Header.Set("content-type", contentType)
+		if tt.token != "" {
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("test %d: request failed: %v", i, err)
+		}
+		var reply jsonErrResponse
+		json.NewDecoder(resp.Body).Decode(&reply)
+		resp.Body.Close()
+
+		if resp.StatusCode != tt.status {
+			t.Errorf("test %d: status mismatch: have %d, want %d", i, resp.StatusCode, tt.status)
+		}
+		if resp.StatusCode == http.StatusOK && (reply.Error.Code != 0) != tt.error {
+			t.Errorf("test %d: error mismatch: have %v, want error %v", i, reply.Error, tt.error)
+		}
+	}
+}
+
+// Tests that RS256 tokens are verified with the keys of a JSON web key set.
+func TestJWTKeySet(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	set := map[string]interface{}{"keys": []map[string]string{{
+		"kty": "RSA",
+		"kid": "key-1",
+		"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}}}
+	jwks := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(set)
+	}))
+	defer jwks.Close()
+
+	auth, err := NewJWTAuth(JWTConfig{JWKSURL: jwks.URL, NamespaceClaim: "namespaces"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	authenticate := func(token string) (*jwtGrant, error) {
+		req, _ := http.NewRequest("POST", "http://localhost", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		return auth.authenticate(req)
+	}
+	grant, err := authenticate(signTestToken(t, jwt.SigningMethodRS256, key, jwt.MapClaims{"namespaces": []string{"eth"}}, "key-1"))
+	if err != nil {
+		t.Fatalf("failed to authenticate token: %v", err)
+	}
+	if grant.authorize("eth") != nil || grant.authorize("admin") == nil {
+		t.Errorf("namespaces mismatch: have %v, want [eth]", grant.namespaces)
+	}
+	if _, err := authenticate(signTestToken(t, jwt.SigningMethodRS256, key, jwt.MapClaims{}, "key-2")); err == nil {
+		t.Errorf("authenticated token of unknown key")
+	}
+	if _, err := authenticate(signTestToken(t, jwt.SigningMethodHS256, []byte("secret"), jwt.MapClaims{}, "")); err == nil {
+		t.Errorf("authenticated HS256 token without secret")
+	}
+}
+
+// Tests that WebSocket connections need a token at the handshake, and are closed
+// once it expires.
+func TestJWTWebsocketExpiry(t *testing.T) {
+	server := newTestServer("service", new(Service))
+	server.auth = newTestJWTAuth(t, "secret")
+	defer server.Stop()
+
+	httpsrv := httptest.NewServer(server.WebsocketHandler([]string{"*"}))
+	defer httpsrv.Close()
+	wsURL := "ws:" + strings.TrimPrefix(httpsrv.URL, "http:")
+
+	config, err := websocket.NewConfig(wsURL, "http://localhost")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := websocket.DialConfig(config); err == nil {
+		t.Fatalf("connected without token")
+	}
+	token := signTestToken(t, jwt.SigningMethodHS256, []byte("secret"), jwt.MapClaims{"exp": time.Now().Add(2 * time.Second).Unix(), "namespaces": "service"}, "")
+	config.Header.Set("Authorization", "Bearer "+token)
+	conn, err := websocket.DialConfig(config)
+	if err != nil {
+		t.Fatalf("failed to connect with token: %v", err)
+	}
+	defer conn.Close()
+
+	if err := websocket.Message.Send(conn, `{"jsonrpc":"2.0","id":1,"method":"service_noArgsRets"}`); err != nil {
+		t.Fatal(err)
+	}
+	var reply string
+	if err := websocket.Message.Receive(conn, &reply); err != nil {
+		t.Fatalf("failed to receive reply: %v", err)
+	}
+	if strings.Contains(reply, "error") {
+		t.Fatalf("call failed: %s", reply)
+	}
+	// The server must close the connection when the token expires
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	start := time.Now()
+	if err := websocket.Message.Receive(conn, &reply); err == nil {
+		t.Fatalf("received message after expiry: %s", reply)
+	}
+	if time.Since(start) >= 5*time.Second {
+		t.Errorf("connection not closed on token expiry")
+	}
+}
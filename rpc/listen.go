@@ -0,0 +1,147 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+)
+
+var errListenerClosed = errors.New("listener closed")
+
+// ListenTCP opens a TCP listener accepting the connections of an endpoint with
+// the given number of accept loops. Where SO_REUSEPORT is supported, every loop
+// has its own socket bound to the endpoint and the kernel balances the incoming
+// connections among them; elsewhere the loops share a single socket.
+func ListenTCP(endpoint string, loops int) (net.Listener, error) {
+	if loops <= 1 {
+		return net.Listen("tcp", endpoint)
+	}
+	if reusePortControl == nil {
+		listener, err := net.Listen("tcp", endpoint)
+		if err != nil {
+			return nil, err
+		}
+		listeners := make([]net.Listener, loops)
+		for i := range listeners {
+			listeners[i] = listener
+		}
+		return newMultiListener(listeners), nil
+	}
+	config := net.ListenConfig{Control: reusePortControl}
+	listeners := make([]net.Listener, 0, loops)
+	for i := 0; i < loops; i++ {
+		listener, err := config.Listen(context.Background(), "tcp", endpoint)
+		if err != nil {
+			for _, l := range listeners {
+				l.Close()
+			}
+			return nil, err
+		}
+		// Bind the other sockets to the port picked for the first one
+		endpoint = listener.Addr().String()
+		listeners = append(listeners, listener)
+	}
+	return newMultiListener(listeners), nil
+}
+
+// multiListener runs an accept loop on each of its listeners, merging the
+// accepted connections. The same listener may be given more than once.
+type multiListener struct {
+	listeners []net.Listener
+	conns     chan net.Conn
+	errc      chan error
+
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func newMultiListener(listeners []net.Listener) *multiListener {
+	l := &multiListener{
+		listeners: listeners,
+		conns:     make(chan net.Conn),
+		errc:      make(chan error),
+		closed:    make(chan struct{}),
+	}
+	for _, listener := range listeners {
+		go l.acceptLoop(listener)
+	}
+	return l
+}
+
+// acceptLoop accepts the connections of a listener until it fails permanently
+// or the multi-listener is closed.
+func (l *multiListener) acceptLoop(listener net.Listener) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			select {
+			case l.errc <- err:
+			case <-l.closed:
+				return
+			}
+			if ne, ok := err.(net.Error); ok && ne.Temporary() {
+				continue
+			}
+			return
+		}
+		select {
+		case l.conns <- conn:
+		case <-l.closed:
+			conn.Close()
+			return
+		}
+	}
+}
+
+// Accept implements net.Listener, returning the next connection accepted by
+// any of the accept loops.
+func (l *multiListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case err := <-l.errc:
+		return nil, err
+	case <-l.closed:
+		return nil, errListenerClosed
+	}
+}
+
+// Close implements net.Listener, closing all the listeners.
+func (l *multiListener) Close() error {
+	var err error
+	l.closeOnce.Do(func() {
+		close(l.closed)
+		closed := make(map[net.Listener]bool)
+		for _, listener := range l.listeners {
+			if !closed[listener] {
+				if cerr := listener.Close(); err == nil {
+					err = cerr
+				}
+				closed[listener] = true
+			}
+		}
+	})
+	return err
+}
+
+// Addr implements net.Listener, returning the address all listeners are bound to.
+func (l *multiListener) Addr() net.Addr {
+	return l.listeners[0].Addr()
+}
@@ -0,0 +1,147 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+// deprecationWarningInterval is the minimum interval between two warnings of
+// the calls of a deprecated method.
+const deprecationWarningInterval = time.Minute
+
+// MethodRule changes how the calls of a method ("eth_sendTransactionAsync"),
+// or of a whole namespace ("personal_*"), are served.
+type MethodRule struct {
+	Disabled   bool   `json:"disabled,omitempty"`   // Reject the calls
+	Alias      string `json:"alias,omitempty"`      // Method serving the calls, with compatible arguments, or namespace for a namespace rule ("new_*")
+	Deprecated bool   `json:"deprecated,omitempty"` // Warn of the calls in the log, once a minute
+	Message    string `json:"message,omitempty"`    // Reason of the rule, returned with the rejected calls and logged with the others
+	Log        bool   `json:"log,omitempty"`        // Log every call
+}
+
+// MethodRules disables, aliases or deprecates the methods of an RPC server,
+// easing the migration of the clients off legacy methods. The subscriptions
+// are only subject to the disabling of <namespace>_subscribe.
+type MethodRules struct {
+	rules map[string]*methodRule
+}
+
+// methodRule is a rule, with the accounting of its calls.
+type methodRule struct {
+	MethodRule
+	pattern string
+	calls   metrics.Counter
+
+	lock   sync.Mutex
+	warned time.Time // Last warning of a call of the deprecated method
+}
+
+// NewMethodRules loads the method rules of a JSON file, by method or namespace:
+//
+//	{
+//	  "eth_sendTransactionAsync": {"alias": "eth_sendTransaction", "deprecated": true, "message": "use eth_sendTransaction"},
+//	  "personal_*": {"disabled": true, "message": "accounts are managed by the signer"}
+//	}
+func NewMethodRules(file string) (*MethodRules, error) {
+	blob, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var rules map[string]MethodRule
+	if err := json.Unmarshal(blob, &rules); err != nil {
+		return nil, fmt.Errorf("invalid method rules in %s: %v", file, err)
+	}
+	return newMethodRules(rules)
+}
+
+func newMethodRules(rules map[string]MethodRule) (*MethodRules, error) {
+	mr := &MethodRules{rules: make(map[string]*methodRule)}
+	for pattern, rule := range rules {
+		namespace := strings.HasSuffix(pattern, serviceMethodSeparator+"*")
+		switch {
+		case strings.Index(pattern, serviceMethodSeparator) <= 0:
+			return nil, fmt.Errorf("invalid method %q, want <namespace>_<method> or <namespace>_*", pattern)
+		case rule.Disabled && rule.Alias != "":
+			return nil, fmt.Errorf("method %s both disabled and aliased", pattern)
+		case rule.Alias != "" && strings.Index(rule.Alias, serviceMethodSeparator) <= 0:
+			return nil, fmt.Errorf("invalid alias %q of method %s", rule.Alias, pattern)
+		case rule.Alias != "" && namespace != strings.HasSuffix(rule.Alias, serviceMethodSeparator+"*"):
+			return nil, fmt.Errorf("alias %s of %s must be a namespace if and only if the rule is", rule.Alias, pattern)
+		}
+		mr.rules[pattern] = &methodRule{
+			MethodRule: rule,
+			pattern:    pattern,
+			calls:      metrics.GetOrRegisterCounter("rpc/methodrules/"+strings.TrimSuffix(pattern, serviceMethodSeparator+"*"), nil),
+		}
+	}
+	return mr, nil
+}
+
+// match returns the rule of a method, that of the method itself or else of its
+// namespace, nil if none.
+func (mr *MethodRules) match(service, method string) *methodRule {
+	if rule, ok := mr.rules[service+serviceMethodSeparator+method]; ok {
+		return rule
+	}
+	return mr.rules[service+serviceMethodSeparator+"*"]
+}
+
+// apply returns the service and method serving a call, or an error if the
+// method is disabled.
+func (mr *MethodRules) apply(service, method string) (string, string, Error) {
+	rule := mr.match(service, method)
+	if rule == nil {
+		return service, method, nil
+	}
+	rule.calls.Inc(1)
+	name := service + serviceMethodSeparator + method
+	if rule.Disabled {
+		if rule.Log {
+			log.Info("Rejected call of disabled RPC method", "method", name, "rule", rule.pattern)
+		}
+		return "", "", &methodDisabledError{method: name, message: rule.Message}
+	}
+	if rule.Alias != "" {
+		alias := strings.SplitN(rule.Alias, serviceMethodSeparator, 2)
+		if alias[1] == "*" {
+			service = alias[0]
+		} else {
+			service, method = alias[0], alias[1]
+		}
+	}
+	if rule.Log {
+		log.Info("Call of mapped RPC method", "method", name, "served", service+serviceMethodSeparator+method, "rule", rule.pattern)
+	}
+	if rule.Deprecated {
+		rule.lock.Lock()
+		if time.Since(rule.warned) >= deprecationWarningInterval {
+			rule.warned = time.Now()
+			log.Warn("Call of deprecated RPC method", "method", name, "message", rule.Message)
+		}
+		rule.lock.Unlock()
+	}
+	return service, method, nil
+}
@@ -0,0 +1,80 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"testing"
+)
+
+func TestMethodRules(t *testing.T) {
+	rules, err := newMethodRules(map[string]MethodRule{
+		"test_legacyEcho":  {Alias: "test_echo", Deprecated: true},
+		"legacy_*":         {Alias: "test_*", Log: true},
+		"test_noArgsRets":  {Disabled: true, Message: "use test_echo"},
+		"blocked_*":        {Disabled: true},
+		"test_subscribe":   {Disabled: true},
+		"test_echoWithCtx": {Deprecated: true},
+	})
+	if err != nil {
+		t.Fatalf("failed to create method rules: %v", err)
+	}
+	server := NewServer()
+	server.RegisterName("test", new(Service))
+	server.RegisterName("blocked", new(Service))
+	server.methods = rules
+	defer server.Stop()
+
+	client := DialInProc(server)
+	defer client.Close()
+
+	for _, method := range []string{"test_echo", "test_legacyEcho", "legacy_echo", "test_echoWithCtx", "blocked_echo"} {
+		var result Result
+		err := client.Call(&result, method, "x", 1, &Args{"y"})
+		if method == "blocked_echo" {
+			if rpcErr, ok := err.(Error); !ok || rpcErr.ErrorCode() != -32601 {
+				t.Errorf("%s: error mismatch: have %v, want method disabled", method, err)
+			}
+			continue
+		}
+		if err != nil || result.String != "x" {
+			t.Errorf("%s: call failed: %v (%+v)", method, err, result)
+		}
+	}
+	err = client.Call(nil, "test_noArgsRets")
+	if err == nil || err.Error() != "The method test_noArgsRets is disabled: use test_echo" {
+		t.Errorf("disabled method error mismatch: have %v", err)
+	}
+	if _, err := client.Subscribe(context.Background(), "test", make(chan int), "subscription"); err == nil {
+		t.Errorf("subscription to disabled namespace accepted")
+	}
+}
+
+func TestMethodRulesValidation(t *testing.T) {
+	invalid := []map[string]MethodRule{
+		{"echo": {Disabled: true}},
+		{"test_echo": {Disabled: true, Alias: "test_echo2"}},
+		{"test_echo": {Alias: "echo2"}},
+		{"test_*": {Alias: "other_echo"}},
+		{"test_echo": {Alias: "other_*"}},
+	}
+	for i, rules := range invalid {
+		if _, err := newMethodRules(rules); err == nil {
+			t.Errorf("test %d: invalid rules accepted: %+v", i, rules)
+		}
+	}
+}
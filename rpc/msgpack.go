@@ -0,0 +1,302 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+)
+
+// msgpackWriter writes MessagePack values. Integers beyond 64 bits, which
+// MessagePack can't represent, are written as decimal strings.
+type msgpackWriter struct {
+	*bufio.Writer
+}
+
+func newMsgpackWriter(w *bufio.Writer) binaryWriter {
+	return msgpackWriter{w}
+}
+
+func (w msgpackWriter) writeNull() {
+	w.WriteByte(0xc0)
+}
+
+func (w msgpackWriter) writeBool(v bool) {
+	if v {
+		w.WriteByte(0xc3)
+	} else {
+		w.WriteByte(0xc2)
+	}
+}
+
+func (w msgpackWriter) writeInt(v int64) {
+	var buf [9]byte
+	switch {
+	case v >= 0:
+		w.writeUint(uint64(v))
+	case v >= -32:
+		w.WriteByte(byte(v))
+	case v >= math.MinInt8:
+		buf[0], buf[1] = 0xd0, byte(v)
+		w.Write(buf[:2])
+	case v >= math.MinInt16:
+		buf[0] = 0xd1
+		binary.BigEndian.PutUint16(buf[1:], uint16(v))
+		w.Write(buf[:3])
+	case v >= math.MinInt32:
+		buf[0] = 0xd2
+		binary.BigEndian.PutUint32(buf[1:], uint32(v))
+		w.Write(buf[:5])
+	default:
+		buf[0] = 0xd3
+		binary.BigEndian.PutUint64(buf[1:], uint64(v))
+		w.Write(buf[:9])
+	}
+}
+
+func (w msgpackWriter) writeUint(v uint64) {
+	var buf [9]byte
+	switch {
+	case v <= math.MaxInt8:
+		w.WriteByte(byte(v))
+	case v <= math.MaxUint8:
+		buf[0], buf[1] = 0xcc, byte(v)
+		w.Write(buf[:2])
+	case v <= math.MaxUint16:
+		buf[0] = 0xcd
+		binary.BigEndian.PutUint16(buf[1:], uint16(v))
+		w.Write(buf[:3])
+	case v <= math.MaxUint32:
+		buf[0] = 0xce
+		binary.BigEndian.PutUint32(buf[1:], uint32(v))
+		w.Write(buf[:5])
+	default:
+		buf[0] = 0xcf
+		binary.BigEndian.PutUint64(buf[1:], v)
+		w.Write(buf[:9])
+	}
+}
+
+func (w msgpackWriter) writeBigInt(v *big.Int) {
+	switch {
+	case v.IsUint64():
+		w.writeUint(v.Uint64())
+	case v.IsInt64():
+		w.writeInt(v.Int64())
+	default:
+		w.writeString(v.String())
+	}
+}
+
+func (w msgpackWriter) writeFloat(v float64) {
+	var buf [9]byte
+	buf[0] = 0xcb
+	binary.BigEndian.PutUint64(buf[1:], math.Float64bits(v))
+	w.Write(buf[:])
+}
+
+func (w msgpackWriter) writeString(s string) {
+	var buf [5]byte
+	switch {
+	case len(s) < 32:
+		w.WriteByte(0xa0 | byte(len(s)))
+	case len(s) <= math.MaxUint8:
+		buf[0], buf[1] = 0xd9, byte(len(s))
+		w.Write(buf[:2])
+	case len(s) <= math.MaxUint16:
+		buf[0] = 0xda
+		binary.BigEndian.PutUint16(buf[1:], uint16(len(s)))
+		w.Write(buf[:3])
+	default:
+		buf[0] = 0xdb
+		binary.BigEndian.PutUint32(buf[1:], uint32(len(s)))
+		w.Write(buf[:5])
+	}
+	w.WriteString(s)
+}
+
+func (w msgpackWriter) writeBytes(b []byte) {
+	var buf [5]byte
+	switch {
+	case len(b) <= math.MaxUint8:
+		buf[0], buf[1] = 0xc4, byte(len(b))
+		w.Write(buf[:2])
+	case len(b) <= math.MaxUint16:
+		buf[0] = 0xc5
+		binary.BigEndian.PutUint16(buf[1:], uint16(len(b)))
+		w.Write(buf[:3])
+	default:
+		buf[0] = 0xc6
+		binary.BigEndian.PutUint32(buf[1:], uint32(len(b)))
+		w.Write(buf[:5])
+	}
+	w.Write(b)
+}
+
+func (w msgpackWriter) writeArrayHead(size int) {
+	w.writeHead(0x90, 0xdc, size)
+}
+
+func (w msgpackWriter) writeMapHead(size int) {
+	w.writeHead(0x80, 0xde, size)
+}
+
+// writeHead writes the size prefix of a map or an array, given the type bytes
+// of their fix and 16 bit forms.
+func (w msgpackWriter) writeHead(fix, code byte, size int) {
+	var buf [5]byte
+	switch {
+	case size < 16:
+		w.WriteByte(fix | byte(size))
+	case size <= math.MaxUint16:
+		buf[0] = code
+		binary.BigEndian.PutUint16(buf[1:], uint16(size))
+		w.Write(buf[:3])
+	default:
+		buf[0] = code + 1
+		binary.BigEndian.PutUint32(buf[1:], uint32(size))
+		w.Write(buf[:5])
+	}
+}
+
+// decodeMsgpack reads a single MessagePack value.
+func decodeMsgpack(r *bufio.Reader) (interface{}, error) {
+	return decodeMsgpackValue(r, 0)
+}
+
+func decodeMsgpackValue(r *bufio.Reader, depth int) (interface{}, error) {
+	if depth > binaryMaxDepth {
+		return nil, errBinaryDepth
+	}
+	code, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case code <= 0x7f:
+		return uint64(code), nil
+	case code >= 0xe0:
+		return int64(int8(code)), nil
+	case code&0xf0 == 0x80:
+		return decodeMsgpackMap(r, uint64(code&0x0f), depth)
+	case code&0xf0 == 0x90:
+		return decodeMsgpackArray(r, uint64(code&0x0f), depth)
+	case code&0xe0 == 0xa0:
+		data, err := readBinaryString(r, nil, uint64(code&0x1f))
+		return string(data), err
+	}
+	switch code {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xc4, 0xc5, 0xc6:
+		size, err := readMsgpackUint(r, 1<<(code-0xc4))
+		if err != nil {
+			return nil, err
+		}
+		return readBinaryString(r, nil, size)
+	case 0xca:
+		bits, err := readMsgpackUint(r, 4)
+		return float64(math.Float32frombits(uint32(bits))), err
+	case 0xcb:
+		bits, err := readMsgpackUint(r, 8)
+		return math.Float64frombits(bits), err
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		return readMsgpackUint(r, 1<<(code-0xcc))
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		size := 1 << (code - 0xd0)
+		v, err := readMsgpackUint(r, size)
+		if err != nil {
+			return nil, err
+		}
+		// Sign extend the value to 64 bits
+		shift := uint(64 - 8*size)
+		return int64(v<<shift) >> shift, nil
+	case 0xd9, 0xda, 0xdb:
+		size, err := readMsgpackUint(r, 1<<(code-0xd9))
+		if err != nil {
+			return nil, err
+		}
+		data, err := readBinaryString(r, nil, size)
+		return string(data), err
+	case 0xdc, 0xdd:
+		size, err := readMsgpackUint(r, 2<<(code-0xdc))
+		if err != nil {
+			return nil, err
+		}
+		return decodeMsgpackArray(r, size, depth)
+	case 0xde, 0xdf:
+		size, err := readMsgpackUint(r, 2<<(code-0xde))
+		if err != nil {
+			return nil, err
+		}
+		return decodeMsgpackMap(r, size, depth)
+	}
+	return nil, fmt.Errorf("unsupported MessagePack type %#x", code)
+}
+
+func decodeMsgpackArray(r *bufio.Reader, size uint64, depth int) (interface{}, error) {
+	if size > maxRequestContentLength {
+		return nil, errBinaryLength
+	}
+	items := []interface{}{}
+	for i := uint64(0); i < size; i++ {
+		item, err := decodeMsgpackValue(r, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+func decodeMsgpackMap(r *bufio.Reader, size uint64, depth int) (interface{}, error) {
+	if size > maxRequestContentLength {
+		return nil, errBinaryLength
+	}
+	fields := make(map[string]interface{})
+	for i := uint64(0); i < size; i++ {
+		key, err := decodeMsgpackValue(r, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		name, ok := key.(string)
+		if !ok {
+			return nil, errBinaryMapKey
+		}
+		if fields[name], err = decodeMsgpackValue(r, depth+1); err != nil {
+			return nil, err
+		}
+	}
+	return fields, nil
+}
+
+// readMsgpackUint reads a big endian unsigned integer of the given byte size.
+func readMsgpackUint(r io.Reader, size int) (uint64, error) {
+	var buf [8]byte
+	if _, err := io.ReadFull(r, buf[8-size:]); err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint64(buf[:]), nil
+}
@@ -24,6 +24,7 @@ import (
 	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	mapset "github.com/deckarep/golang-set"
 	"github.com/ethereum/go-ethereum/log"
@@ -164,7 +165,9 @@ func (s *Server) serveRequest(ctx context.Context, codec ServerCodec, singleShot
 				log.Debug(fmt.Sprintf("read error %v\n", err))
 				codec.Write(codec.CreateErrorResponse(nil, err))
 			}
-			// Error or end of stream, wait for requests and tear down
+			// Error or end of stream, wait for requests and tear down. Quorum:
+			// the calls still queued for a worker are dropped first
+			cancel()
 			pend.Wait()
 			return nil
 		}
@@ -228,6 +231,9 @@ func (s *Server) ServeSingleRequest(ctx context.Context, codec ServerCodec, opti
 func (s *Server) Stop() {
 	if atomic.CompareAndSwapInt32(&s.run, 1, 0) {
 		log.Debug("RPC Server shutdown initiatied")
+		if s.workers != nil {
+			s.workers.stop()
+		}
 		s.codecsMu.Lock()
 		defer s.codecsMu.Unlock()
 		s.codecs.Each(func(c interface{}) bool {
@@ -237,6 +243,37 @@ func (s *Server) Stop() {
 	}
 }
 
+// Quorum
+//
+// SetWorkers bounds the concurrency of the calls served, with per-method limits.
+// It must be called before the server starts serving requests.
+func (s *Server) SetWorkers(config WorkerConfig) {
+	s.workers = newWorkerPool(config)
+}
+
+// Quorum
+//
+// PauseCalls sets whether new calls are rejected, for the node to shed load as
+// its resources run out. The subscriptions and unsubscriptions go through.
+func (s *Server) PauseCalls(paused bool) {
+	atomic.StoreInt32(&s.callsPaused, boolToInt32(paused))
+}
+
+// Quorum
+//
+// PauseSubscriptions sets whether new subscriptions are rejected, for the node
+// to shed load as its resources run out. The active subscriptions go on.
+func (s *Server) PauseSubscriptions(paused bool) {
+	atomic.StoreInt32(&s.subscriptionsPaused, boolToInt32(paused))
+}
+
+func boolToInt32(b bool) int32 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
 // createSubscription will call the subscription callback and returns the subscription id or error.
 func (s *Server) createSubscription(ctx context.Context, c ServerCodec, req *serverRequest) (ID, error) {
 	// subscription have as first argument the context following optional arguments
@@ -256,6 +293,17 @@ func (s *Server) handle(ctx context.Context, codec ServerCodec, req *serverReque
 	if req.err != nil {
 		return codec.CreateErrorResponse(&req.id, req.err), nil
 	}
+	// Quorum
+	if grant := jwtGrantFromContext(ctx); grant != nil && !req.isUnsubscribe {
+		if err := grant.authorize(req.svcname); err != nil {
+			return codec.CreateErrorResponse(&req.id, err), nil
+		}
+	}
+	if usage := apiKeyUsageFromContext(ctx); usage != nil && !req.isUnsubscribe {
+		if err := usage.charge(time.Now()); err != nil {
+			return codec.CreateErrorResponse(&req.id, err), nil
+		}
+	}
 
 	if req.isUnsubscribe { // cancel subscription, first param must be the subscription id
 		if len(req.args) >= 1 && req.args[0].Kind() == reflect.String {
@@ -275,9 +323,14 @@ func (s *Server) handle(ctx context.Context, codec ServerCodec, req *serverReque
 	}
 
 	if req.callb.isSubscribe {
+		// Quorum
+		if atomic.LoadInt32(&s.subscriptionsPaused) != 0 {
+			overloadRejectedMeter.Mark(1)
+			return codec.CreateErrorResponse(&req.id, &overloadedError{"subscriptions"}), nil
+		}
 		subid, err := s.createSubscription(ctx, codec, req)
 		if err != nil {
-			return codec.CreateErrorResponse(&req.id, &callbackError{err.Error()}), nil
+			return codec.CreateErrorResponse(&req.id, classifyError(err)), nil // Quorum
 		}
 
 		// active the subscription after the sub id was successfully sent to the client
@@ -289,6 +342,11 @@ func (s *Server) handle(ctx context.Context, codec ServerCodec, req *serverReque
 		return codec.CreateResponse(req.id, subid), activateSub
 	}
 
+	// Quorum
+	if atomic.LoadInt32(&s.callsPaused) != 0 {
+		overloadRejectedMeter.Mark(1)
+		return codec.CreateErrorResponse(&req.id, &overloadedError{"calls"}), nil
+	}
 	// regular RPC call, prepare arguments
 	if len(req.args) != len(req.callb.argTypes) {
 		rpcErr := &invalidParamsError{fmt.Sprintf("%s%s%s expects %d parameters, got %d",
@@ -310,14 +368,22 @@ func (s *Server) handle(ctx context.Context, codec ServerCodec, req *serverReque
 	}
 
 	// execute RPC method and return result
-	reply := req.callb.method.Func.Call(arguments)
+	var reply []reflect.Value
+	if s.workers != nil { // Quorum
+		method := req.svcname + serviceMethodSeparator + formatName(req.callb.method.Name)
+		if err := s.workers.run(ctx, method, func() { reply = req.callb.method.Func.Call(arguments) }); err != nil {
+			return codec.CreateErrorResponse(&req.id, err), nil
+		}
+	} else {
+		reply = req.callb.method.Func.Call(arguments)
+	}
 	if len(reply) == 0 {
 		return codec.CreateResponse(req.id, nil), nil
 	}
 	if req.callb.errPos >= 0 { // test if method returned an error
 		if !reply[req.callb.errPos].IsNil() {
 			e := reply[req.callb.errPos].Interface().(error)
-			res := codec.CreateErrorResponse(&req.id, &callbackError{e.Error()})
+			res := codec.CreateErrorResponse(&req.id, classifyError(e)) // Quorum
 			return res, nil
 		}
 	}
@@ -404,6 +470,21 @@ func (s *Server) readRequest(codec ServerCodec) ([]*serverRequest, bool, Error)
 			continue
 		}
 
+		// Quorum
+		if s.methods != nil && r.isPubSub {
+			if _, _, err := s.methods.apply(r.service, "subscribe"); err != nil {
+				requests[i] = &serverRequest{id: r.id, err: err}
+				continue
+			}
+		} else if s.methods != nil {
+			service, method, err := s.methods.apply(r.service, r.method)
+			if err != nil {
+				requests[i] = &serverRequest{id: r.id, err: err}
+				continue
+			}
+			r.service, r.method = service, method
+		}
+
 		if svc, ok = s.services[r.service]; !ok { // rpc method isn't available
 			requests[i] = &serverRequest{id: r.id, err: &methodNotFoundError{r.service, r.method}}
 			continue
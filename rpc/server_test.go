@@ -160,3 +160,31 @@ func TestServerMethodExecution(t *testing.T) {
 func TestServerMethodWithCtx(t *testing.T) {
 	testServerMethodExecution(t, "echoWithCtx")
 }
+
+// Tests that the calls and the subscriptions are rejected while paused, and
+// served again once resumed.
+func TestServerPause(t *testing.T) {
+	server := NewServer()
+	server.RegisterName("test", new(Service))
+	defer server.Stop()
+
+	client := DialInProc(server)
+	defer client.Close()
+
+	server.PauseCalls(true)
+	server.PauseSubscriptions(true)
+
+	var result Result
+	err := client.Call(&result, "test_echo", "x", 1, &Args{"y"})
+	if rpcErr, ok := err.(Error); !ok || rpcErr.ErrorCode() != -32005 {
+		t.Fatalf("paused call error mismatch: have %v, want overloaded", err)
+	}
+	_, err = client.Subscribe(context.Background(), "test", make(chan int), "subscription")
+	if rpcErr, ok := err.(Error); !ok || rpcErr.ErrorCode() != -32005 {
+		t.Fatalf("paused subscription error mismatch: have %v, want overloaded", err)
+	}
+	server.PauseCalls(false)
+	if err := client.Call(&result, "test_echo", "x", 1, &Args{"y"}); err != nil {
+		t.Fatalf("resumed call failed: %v", err)
+	}
+}
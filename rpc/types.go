@@ -74,6 +74,14 @@ type Server struct {
 	run      int32
 	codecsMu sync.Mutex
 	codecs   mapset.Set
+
+	auth    *JWTAuth     // Quorum: authenticates the HTTP and WebSocket requests, nil if disabled
+	workers *workerPool  // Quorum: bounds the concurrent calls, nil if unbounded
+	keys    *APIKeys     // Quorum: authenticates and meters the requests by API key, nil if disabled
+	methods *MethodRules // Quorum: disables, aliases or deprecates methods, nil if none
+
+	callsPaused         int32 // Quorum: whether new calls are rejected, to shed load (atomic)
+	subscriptionsPaused int32 // Quorum: whether new subscriptions are rejected, to shed load (atomic)
 }
 
 // rpcRequest represents a raw incoming RPC request
@@ -119,13 +127,14 @@ type ServerCodec interface {
 type BlockNumber int64
 
 const (
-	PendingBlockNumber  = BlockNumber(-2)
-	LatestBlockNumber   = BlockNumber(-1)
-	EarliestBlockNumber = BlockNumber(0)
+	FinalizedBlockNumber = BlockNumber(-3) // Quorum: latest block final according to the consensus engine
+	PendingBlockNumber   = BlockNumber(-2)
+	LatestBlockNumber    = BlockNumber(-1)
+	EarliestBlockNumber  = BlockNumber(0)
 )
 
 // UnmarshalJSON parses the given JSON fragment into a BlockNumber. It supports:
-// - "latest", "earliest" or "pending" as string arguments
+// - "latest", "earliest", "pending" or "finalized" as string arguments
 // - the block number
 // Returned errors:
 // - an invalid block number error when the given argument isn't a known strings
@@ -146,6 +155,9 @@ func (bn *BlockNumber) UnmarshalJSON(data []byte) error {
 	case "pending":
 		*bn = PendingBlockNumber
 		return nil
+	case "finalized":
+		*bn = FinalizedBlockNumber
+		return nil
 	}
 
 	blckNum, err := hexutil.DecodeUint64(input)
@@ -163,3 +175,13 @@ func (bn *BlockNumber) UnmarshalJSON(data []byte) error {
 func (bn BlockNumber) Int64() int64 {
 	return (int64)(bn)
 }
+
+// Quorum
+// Height returns the height of the block number, and whether it is one at all:
+// the meta block numbers are tags to resolve, not heights to convert.
+func (bn BlockNumber) Height() (uint64, bool) {
+	if bn < EarliestBlockNumber {
+		return 0, false
+	}
+	return uint64(bn), true
+}
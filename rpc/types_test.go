@@ -46,6 +46,7 @@ func TestBlockNumberJSONUnmarshal(t *testing.T) {
 		14: {`someString`, true, BlockNumber(0)},
 		15: {`""`, true, BlockNumber(0)},
 		16: {``, true, BlockNumber(0)},
+		17: {`"finalized"`, false, FinalizedBlockNumber},
 	}
 
 	for i, test := range tests {
@@ -64,3 +65,22 @@ func TestBlockNumberJSONUnmarshal(t *testing.T) {
 		}
 	}
 }
+
+func TestBlockNumberHeight(t *testing.T) {
+	tests := []struct {
+		number BlockNumber
+		height uint64
+		ok     bool
+	}{
+		{EarliestBlockNumber, 0, true},
+		{BlockNumber(18), 18, true},
+		{LatestBlockNumber, 0, false},
+		{PendingBlockNumber, 0, false},
+		{FinalizedBlockNumber, 0, false},
+	}
+	for i, test := range tests {
+		if height, ok := test.number.Height(); height != test.height || ok != test.ok {
+			t.Errorf("test %d: height mismatch: have %d, %v, want %d, %v", i, height, ok, test.height, test.ok)
+		}
+	}
+}
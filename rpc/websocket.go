@@ -58,18 +58,67 @@ var websocketJSONCodec = websocket.Codec{
 // To allow connections with any origin, pass "*".
 func (srv *Server) WebsocketHandler(allowedOrigins []string) http.Handler {
 	return websocket.Server{
-		Handshake: wsHandshakeValidator(allowedOrigins),
+		Handshake: wsHandshakeValidator(allowedOrigins, srv.auth, srv.keys),
 		Handler: func(conn *websocket.Conn) {
 			// Create a custom encode/decode pair to enforce payload size and number encoding
 			conn.MaxPayloadBytes = maxRequestContentLength
 
+			// Quorum
+			ctx := context.WithValue(context.Background(), "remote", conn.Request().RemoteAddr)
+			jsonCodec := websocketJSONCodec
+			if srv.keys != nil {
+				usage, err := srv.keys.authenticate(conn.Request())
+				if err != nil {
+					conn.Close()
+					return
+				}
+				ctx = context.WithValue(ctx, apiKeyUsageKey{}, usage)
+				jsonCodec = meteredWebsocketCodec(usage)
+			}
 			encoder := func(v interface{}) error {
-				return websocketJSONCodec.Send(conn, v)
+				return jsonCodec.Send(conn, v)
 			}
 			decoder := func(v interface{}) error {
-				return websocketJSONCodec.Receive(conn, v)
+				return jsonCodec.Receive(conn, v)
+			}
+			if srv.auth != nil {
+				grant, err := srv.auth.authenticate(conn.Request())
+				if err != nil {
+					conn.Close()
+					return
+				}
+				ctx = context.WithValue(ctx, jwtGrantKey{}, grant)
+
+				// Close the connection once its token expires, ending its subscriptions
+				if !grant.expiry.IsZero() {
+					timer := time.AfterFunc(time.Until(grant.expiry), func() {
+						log.Debug("Closing WebSocket connection of expired token", "remote", conn.Request().RemoteAddr)
+						conn.Close()
+					})
+					defer timer.Stop()
+				}
 			}
-			srv.ServeCodec(NewCodec(conn, encoder, decoder), OptionMethodInvocation|OptionSubscriptions)
+			codec := NewCodec(conn, encoder, decoder)
+			defer codec.Close()
+			srv.serveRequest(ctx, codec, false, OptionMethodInvocation|OptionSubscriptions)
+		},
+	}
+}
+
+// Quorum
+//
+// meteredWebsocketCodec returns the JSON codec of the WebSocket connections,
+// counting the bytes of the messages against an API key.
+func meteredWebsocketCodec(usage *apiKeyUsage) websocket.Codec {
+	return websocket.Codec{
+		Marshal: func(v interface{}) ([]byte, byte, error) {
+			msg, payloadType, err := websocketJSONCodec.Marshal(v)
+			usage.transfer(len(msg))
+			return msg, payloadType, err
+		},
+		Unmarshal: func(msg []byte, payloadType byte, v interface{}) error {
+			usage.transfer(len(msg))
+			return websocketJSONCodec.Unmarshal(msg, payloadType, v)
 		},
 	}
 }
@@ -83,8 +132,9 @@ func NewWSServer(allowedOrigins []string, srv *Server) *http.Server {
 
 // wsHandshakeValidator returns a handler that verifies the origin during the
 // websocket upgrade process. When a '*' is specified as an allowed origins all
-// connections are accepted.
-func wsHandshakeValidator(allowedOrigins []string) func(*websocket.Config, *http.Request) error {
+// connections are accepted. The bearer token and the API key are verified too if
+// authentication is enabled.
+func wsHandshakeValidator(allowedOrigins []string, auth *JWTAuth, keys *APIKeys) func(*websocket.Config, *http.Request) error {
 	origins := mapset.NewSet()
 	allowAllOrigins := false
 
@@ -109,11 +159,24 @@ func wsHandshakeValidator(allowedOrigins []string) func(*websocket.Config, *http
 
 	f := func(cfg *websocket.Config, req *http.Request) error {
 		origin := strings.ToLower(req.Header.Get("Origin"))
-		if allowAllOrigins || origins.Contains(origin) {
-			return nil
+		if !allowAllOrigins && !origins.Contains(origin) {
+			log.Warn(fmt.Sprintf("origin '%s' not allowed on WS-RPC interface\n", origin))
+			return fmt.Errorf("origin %s not allowed", origin)
+		}
+		// Quorum
+		if auth != nil {
+			if _, err := auth.authenticate(req); err != nil {
+				log.Warn("Unauthenticated WS-RPC connection", "remote", req.RemoteAddr, "err", err)
+				return err
+			}
+		}
+		if keys != nil {
+			if _, err := keys.authenticate(req); err != nil {
+				log.Warn("WS-RPC connection without a valid API key", "remote", req.RemoteAddr, "err", err)
+				return err
+			}
 		}
-		log.Warn(fmt.Sprintf("origin '%s' not allowed on WS-RPC interface\n", origin))
-		return fmt.Errorf("origin %s not allowed", origin)
+		return nil
 	}
 
 	return f
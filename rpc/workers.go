@@ -0,0 +1,217 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+// defaultWorkerQueue is the number of calls waiting for a worker if not
+// configured.
+const defaultWorkerQueue = 1024
+
+var (
+	queueTimer     = metrics.NewRegisteredTimer("rpc/queue", nil)
+	rejectedMeter  = metrics.NewRegisteredMeter("rpc/rejected", nil)
+	cancelledMeter = metrics.NewRegisteredMeter("rpc/cancelled", nil)
+
+	overloadRejectedMeter = metrics.NewRegisteredMeter("rpc/rejected/overload", nil)
+)
+
+// WorkerConfig bounds the concurrency of the calls served by an RPC endpoint.
+type WorkerConfig struct {
+	// Workers is the number of calls executed concurrently, zero to execute
+	// every call as soon as it is received.
+	Workers int
+
+	// QueueSize is the number of calls waiting for a worker, beyond which new
+	// calls are rejected.
+	QueueSize int
+
+	// MethodLimits bounds the calls of a method ("debug_traceTransaction") or of
+	// a whole namespace ("debug_*") executing or waiting for a worker at once.
+	// The calls over the limit wait without holding a place in the queue, so
+	// that a burst of slow calls can't starve the other methods.
+	MethodLimits map[string]int
+}
+
+// ParseMethodLimits parses a comma separated list of method=limit pairs, the
+// method being a full method name or a namespace followed by "_*".
+func ParseMethodLimits(spec string) (map[string]int, error) {
+	limits := make(map[string]int)
+	for _, entry := range strings.Split(spec, ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 || !strings.Contains(parts[0], serviceMethodSeparator) {
+			return nil, fmt.Errorf("invalid method limit %q, want <method>=<limit>", entry)
+		}
+		limit, err := strconv.Atoi(parts[1])
+		if err != nil || limit <= 0 {
+			return nil, fmt.Errorf("invalid limit of method %s: %q", parts[0], parts[1])
+		}
+		limits[parts[0]] = limit
+	}
+	return limits, nil
+}
+
+// workerJob is a call waiting for a worker.
+type workerJob struct {
+	ctx    context.Context // Context of the connection, cancelled once it's gone
+	fn     func()
+	method string
+	queued time.Time
+	err    Error // Set if the call was dropped, before done is closed
+	done   chan struct{}
+}
+
+// workerPool executes the calls of a server with a bounded number of workers,
+// limiting the concurrent calls of some methods.
+type workerPool struct {
+	queue  chan *workerJob          // Calls waiting for a worker, nil if unbounded
+	limits map[string]chan struct{} // Semaphores of the limited methods and namespaces
+
+	timers map[string]metrics.Timer // Queue time of every method called
+	lock   sync.Mutex
+
+	quit     chan struct{}
+	quitOnce sync.Once
+}
+
+// newWorkerPool creates a worker pool and starts its workers.
+func newWorkerPool(config WorkerConfig) *workerPool {
+	p := &workerPool{
+		limits: make(map[string]chan struct{}),
+		timers: make(map[string]metrics.Timer),
+		quit:   make(chan struct{}),
+	}
+	for method, limit := range config.MethodLimits {
+		p.limits[method] = make(chan struct{}, limit)
+	}
+	if config.Workers > 0 {
+		size := config.QueueSize
+		if size <= 0 {
+			size = defaultWorkerQueue
+		}
+		p.queue = make(chan *workerJob, size)
+		for i := 0; i < config.Workers; i++ {
+			go p.loop()
+		}
+	}
+	return p
+}
+
+// loop executes the queued calls until the pool is stopped.
+func (p *workerPool) loop() {
+	for {
+		select {
+		case job := <-p.queue:
+			// The calls of the clients gone meanwhile have no one to answer
+			if job.ctx.Err() != nil {
+				cancelledMeter.Mark(1)
+				job.err = &callCancelledError{job.method}
+				close(job.done)
+				continue
+			}
+			p.queued(job.method, job.queued)
+			job.fn()
+			close(job.done)
+		case <-p.quit:
+			return
+		}
+	}
+}
+
+// limit returns the semaphore bounding the calls of a method, nil if the
+// method is not limited. A method limit takes precedence over the limit of its
+// namespace.
+func (p *workerPool) limit(method string) chan struct{} {
+	if sem, ok := p.limits[method]; ok {
+		return sem
+	}
+	if i := strings.Index(method, serviceMethodSeparator); i >= 0 {
+		return p.limits[method[:i]+serviceMethodSeparator+"*"]
+	}
+	return nil
+}
+
+// queued records the time a call of a method waited before executing.
+func (p *workerPool) queued(method string, start time.Time) {
+	elapsed := time.Since(start)
+	queueTimer.Update(elapsed)
+
+	p.lock.Lock()
+	timer, ok := p.timers[method]
+	if !ok {
+		timer = metrics.GetOrRegisterTimer("rpc/queue/"+method, nil)
+		p.timers[method] = timer
+	}
+	p.lock.Unlock()
+
+	timer.Update(elapsed)
+}
+
+// run executes a call of a method once the method limit and a worker allow it.
+// It returns an error without executing the call if the queue is full, the
+// context is cancelled before the call starts or the pool stops.
+func (p *workerPool) run(ctx context.Context, method string, fn func()) Error {
+	start := time.Now()
+	if sem := p.limit(method); sem != nil {
+		select {
+		case sem <- struct{}{}:
+			defer func() { <-sem }()
+		case <-ctx.Done():
+			rejectedMeter.Mark(1)
+			return &serverBusyError{method}
+		case <-p.quit:
+			return &shutdownError{}
+		}
+	}
+	if p.queue == nil {
+		p.queued(method, start)
+		fn()
+		return nil
+	}
+	job := &workerJob{ctx: ctx, fn: fn, method: method, queued: start, done: make(chan struct{})}
+	select {
+	case p.queue <- job:
+	case <-p.quit:
+		return &shutdownError{}
+	default:
+		rejectedMeter.Mark(1)
+		return &serverBusyError{method}
+	}
+	select {
+	case <-job.done:
+		return job.err
+	case <-p.quit:
+		return &shutdownError{}
+	}
+}
+
+// stop terminates the workers, failing the calls still waiting.
+func (p *workerPool) stop() {
+	p.quitOnce.Do(func() { close(p.quit) })
+}
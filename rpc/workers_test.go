@@ -0,0 +1,160 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseMethodLimits(t *testing.T) {
+	limits, err := ParseMethodLimits("debug_*=2, eth_call=16,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]int{"debug_*": 2, "eth_call": 16}; !reflect.DeepEqual(limits, want) {
+		t.Errorf("limits mismatch: have %v, want %v", limits, want)
+	}
+	for _, spec := range []string{"debug", "debug_*=0", "eth_call=x"} {
+		if _, err := ParseMethodLimits(spec); err == nil {
+			t.Errorf("invalid limits %q accepted", spec)
+		}
+	}
+}
+
+// Tests that the calls over the limit of their namespace don't hold a worker,
+// leaving the other methods served.
+func TestWorkerMethodLimits(t *testing.T) {
+	server := NewServer()
+	server.SetWorkers(WorkerConfig{Workers: 2, MethodLimits: map[string]int{"slow_*": 1}})
+	server.RegisterName("slow", new(Service))
+	server.RegisterName("test", new(Service))
+	defer server.Stop()
+
+	client := DialInProc(server)
+	defer client.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	for i := 0; i < 4; i++ {
+		go client.CallContext(ctx, nil, "slow_sleep", 5*time.Second)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	done := make(chan error, 1)
+	go func() {
+		var result Result
+		done <- client.Call(&result, "test_echo", "x", 1, &Args{"y"})
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("call failed: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("call starved by the limited namespace")
+	}
+}
+
+// Tests that the calls are rejected once the workers are busy and the queue is
+// full.
+func TestWorkerQueueFull(t *testing.T) {
+	server := NewServer()
+	server.SetWorkers(WorkerConfig{Workers: 1, QueueSize: 1})
+	server.RegisterName("test", new(Service))
+	defer server.Stop()
+
+	client := DialInProc(server)
+	defer client.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	for i := 0; i < 2; i++ {
+		go client.CallContext(ctx, nil, "test_sleep", 5*time.Second)
+		time.Sleep(100 * time.Millisecond)
+	}
+	err := client.Call(nil, "test_sleep", time.Millisecond)
+	if rpcErr, ok := err.(Error); !ok || rpcErr.ErrorCode() != -32005 {
+		t.Fatalf("error mismatch: have %v, want server busy", err)
+	}
+}
+
+// Tests that the calls queued for a client gone meanwhile are dropped instead
+// of holding a worker.
+func TestWorkerCancelledJobs(t *testing.T) {
+	pool := newWorkerPool(WorkerConfig{Workers: 1})
+	defer pool.stop()
+
+	// Hold the only worker while queueing the calls of a client
+	release, started := make(chan struct{}), make(chan struct{})
+	go pool.run(context.Background(), "test_hold", func() {
+		close(started)
+		<-release
+	})
+	<-started
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan Error)
+	go func() {
+		errc <- pool.run(ctx, "test_call", func() { t.Errorf("call of a client gone executed") })
+	}()
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	close(release)
+
+	select {
+	case err := <-errc:
+		if _, ok := err.(*callCancelledError); !ok {
+			t.Errorf("error mismatch: have %v, want call cancelled", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("cancelled call not dropped")
+	}
+}
+
+// Tests that the HTTP server is served by several accept loops.
+func TestListenTCP(t *testing.T) {
+	listener, err := ListenTCP("127.0.0.1:0", 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := NewServer()
+	server.RegisterName("test", new(Service))
+	defer server.Stop()
+
+	go NewHTTPServer(nil, []string{"*"}, DefaultHTTPTimeouts, server).Serve(listener)
+	defer listener.Close()
+
+	for i := 0; i < 8; i++ {
+		// Fresh connections, for the kernel to spread them among the sockets
+		client, err := DialHTTPWithClient("http://"+listener.Addr().String(), &http.Client{Transport: &http.Transport{DisableKeepAlives: true}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var result Result
+		if err := client.Call(&result, "test_echo", "x", i, &Args{"y"}); err != nil {
+			t.Fatalf("call %d failed: %v", i, err)
+		}
+		if result.Int != i {
+			t.Errorf("call %d: result mismatch: have %d", i, result.Int)
+		}
+		client.Close()
+	}
+}